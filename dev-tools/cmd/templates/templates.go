@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build ignore
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/template"
+
+	"github.com/elastic/beats/v7/licenses"
+)
+
+var tmplTemplates = template.Must(template.New("templates").Parse(`
+{{ .License }}
+// Code generated by beats/dev-tools/cmd/templates/templates.go - DO NOT EDIT.
+
+package {{ .Package }}
+
+// builtinTemplates contains the templates of the generators by their path
+// relative to the templates directory.
+var builtinTemplates = map[string]string{
+{{- range .Templates }}
+	{{ printf "%q" .Name }}: {{ printf "%q" .Content }},
+{{- end }}
+}
+`))
+
+var (
+	pkg     string
+	input   string
+	output  string
+	license string
+)
+
+type templateFile struct {
+	Name    string
+	Content string
+}
+
+func init() {
+	flag.StringVar(&pkg, "pkg", "", "Package name")
+	flag.StringVar(&input, "in", "", "Templates directory")
+	flag.StringVar(&output, "out", "-", "Output path. \"-\" means writing to stdout")
+	flag.StringVar(&license, "license", "ASL2", "License header for generated file.")
+}
+
+// Generates a Go source file from the templates found in the given
+// subdirectories of the templates directory, so that generators can be used
+// without the templates being installed.
+func main() {
+	flag.Parse()
+
+	licenseHeader, err := licenses.Find(license)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid license: %s\n", err)
+		os.Exit(1)
+	}
+
+	var templates []templateFile
+	for _, dir := range flag.Args() {
+		err := filepath.Walk(filepath.Join(input, dir), func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			name, err := filepath.Rel(input, path)
+			if err != nil {
+				return err
+			}
+			templates = append(templates, templateFile{Name: filepath.ToSlash(name), Content: string(content)})
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading templates: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+
+	var buf bytes.Buffer
+	err = tmplTemplates.Execute(&buf, struct {
+		License   string
+		Package   string
+		Templates []templateFile
+	}{
+		License:   licenseHeader,
+		Package:   pkg,
+		Templates: templates,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	bs, err := format.Source(buf.Bytes())
+	if err != nil {
+		panic(err)
+	}
+
+	if output == "-" {
+		os.Stdout.Write(bs)
+	} else {
+		ioutil.WriteFile(output, bs, 0640)
+	}
+}
@@ -253,7 +253,7 @@ func (fb *Filebeat) Run(b *beat.Beat) error {
 	defer stateStore.Close()
 
 	// Setup registrar to persist state
-	registrar, err := registrar.New(stateStore, finishedLogger, config.Registry.FlushTimeout)
+	registrar, err := registrar.New(stateStore, finishedLogger, config.Registry)
 	if err != nil {
 		logp.Err("Could not init registrar: %v", err)
 		return err
@@ -335,6 +335,17 @@ func (fb *Filebeat) Run(b *beat.Beat) error {
 		close(outDone) // finally close all active connections to publisher pipeline
 	}()
 
+	// Spool the events still pending after the wait, if enabled, before the
+	// registrar stops, so that the registrar persists the state of the
+	// spooled events.
+	defer func() {
+		if spooler, ok := b.Publisher.(interface{ SpoolPending() error }); ok {
+			if err := spooler.SpoolPending(); err != nil {
+				logp.Err("Failed to spool the pending events: %v", err)
+			}
+		}
+	}()
+
 	// Wait for all events to be processed or timeout
 	defer waitEvents.Wait()
 
@@ -0,0 +1,272 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package beater
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/filebeat/config"
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend"
+)
+
+// replicator periodically copies the states of the local registry to a
+// replica in Elasticsearch. Only the states updated since the last copy are
+// written.
+type replicator struct {
+	log      *logp.Logger
+	interval time.Duration
+
+	// connect opens the replica. It is retried every interval until it
+	// succeeds.
+	connect func() (backend.Registry, backend.Store, error)
+
+	local *statestore.Store
+
+	remoteReg backend.Registry
+	remote    backend.Store
+
+	// replicated holds the states found in the replica.
+	replicated map[string]common.MapStr
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// replicaName returns the name of the store holding the replica in the index.
+func replicaName(info beat.Info, cfg config.RegistryReplication) string {
+	if cfg.Name != "" {
+		return cfg.Name
+	}
+	return info.Hostname
+}
+
+// openReplica opens the replica of the registry, blocking until the lease of
+// the replica is acquired.
+func openReplica(info beat.Info, logger *logp.Logger, cfg config.Registry) (backend.Registry, backend.Store, error) {
+	reg, err := newElasticsearchBackend(info, logger, cfg.Elasticsearch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store, err := reg.Access(replicaName(info, cfg.Replication))
+	if err != nil {
+		reg.Close()
+		return nil, nil, err
+	}
+	return reg, store, nil
+}
+
+// restoreReplica copies all states of the replica into the local store. It
+// returns the number of states restored.
+func restoreReplica(remote backend.Store, local backend.Store) (int, error) {
+	states, err := readStates(remote)
+	if err != nil {
+		return 0, err
+	}
+	for key, value := range states {
+		if err := local.Set(key, value); err != nil {
+			return 0, err
+		}
+	}
+	return len(states), nil
+}
+
+func newReplicator(log *logp.Logger, interval time.Duration, local *statestore.Store, connect func() (backend.Registry, backend.Store, error)) *replicator {
+	return &replicator{
+		log:      log,
+		interval: interval,
+		connect:  connect,
+		local:    local,
+		done:     make(chan struct{}),
+	}
+}
+
+// withReplica sets an already opened replica, so that it is not opened again
+// by the replicator.
+func (r *replicator) withReplica(reg backend.Registry, store backend.Store) *replicator {
+	r.remoteReg, r.remote = reg, store
+	return r
+}
+
+// Start starts the go-routine replicating the states.
+func (r *replicator) Start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.run()
+	}()
+}
+
+// Stop replicates the states one last time, and closes the replica.
+func (r *replicator) Stop() {
+	close(r.done)
+	r.wg.Wait()
+}
+
+func (r *replicator) run() {
+	defer r.close()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			if r.remote != nil {
+				r.replicateAndLog()
+			}
+			return
+		case <-ticker.C:
+			if r.remote == nil && !r.tryConnect() {
+				continue
+			}
+			r.replicateAndLog()
+		}
+	}
+}
+
+func (r *replicator) tryConnect() bool {
+	// Don't block shutdown while waiting for the lease of the replica.
+	type result struct {
+		reg   backend.Registry
+		store backend.Store
+		err   error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		reg, store, err := r.connect()
+		ch <- result{reg, store, err}
+	}()
+
+	select {
+	case <-r.done:
+		// Close the replica once it has been opened.
+		go func() {
+			if res := <-ch; res.err == nil {
+				res.store.Close()
+				res.reg.Close()
+			}
+		}()
+		return false
+	case res := <-ch:
+		if res.err != nil {
+			r.log.Errorf("Failed to open the registry replica: %v", res.err)
+			return false
+		}
+		r.remoteReg, r.remote = res.reg, res.store
+		return true
+	}
+}
+
+func (r *replicator) replicateAndLog() {
+	n, err := r.replicate()
+	if err != nil {
+		// The replica is opened again on the next tick, in case the lease
+		// has been lost.
+		r.log.Errorf("Failed to replicate the registry: %v", err)
+		r.closeReplica()
+		return
+	}
+	if n > 0 {
+		r.log.Debugf("Replicated %d registry updates", n)
+	}
+}
+
+// replicate copies the states that have changed since the last replication
+// to the replica, and removes the states that no longer exist in the local
+// store. It returns the number of updates written to the replica.
+func (r *replicator) replicate() (int, error) {
+	if r.replicated == nil {
+		replicated, err := readStates(r.remote)
+		if err != nil {
+			return 0, err
+		}
+		r.replicated = replicated
+	}
+
+	current := map[string]common.MapStr{}
+	err := r.local.Each(func(key string, dec statestore.ValueDecoder) (bool, error) {
+		var value common.MapStr
+		if err := dec.Decode(&value); err != nil {
+			return false, err
+		}
+		current[key] = value
+		return true, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for key, value := range current {
+		if old, exists := r.replicated[key]; exists && reflect.DeepEqual(old, value) {
+			continue
+		}
+		if err := r.remote.Set(key, value); err != nil {
+			return n, err
+		}
+		r.replicated[key] = value
+		n++
+	}
+	for key := range r.replicated {
+		if _, exists := current[key]; exists {
+			continue
+		}
+		if err := r.remote.Remove(key); err != nil {
+			return n, err
+		}
+		delete(r.replicated, key)
+		n++
+	}
+	return n, nil
+}
+
+func (r *replicator) close() {
+	r.closeReplica()
+	r.local.Close()
+}
+
+func (r *replicator) closeReplica() {
+	if r.remote == nil {
+		return
+	}
+	if err := r.remote.Close(); err != nil {
+		r.log.Errorf("Failed to close the registry replica: %v", err)
+	}
+	r.remoteReg.Close()
+	r.remoteReg, r.remote, r.replicated = nil, nil, nil
+}
+
+func readStates(store backend.Store) (map[string]common.MapStr, error) {
+	states := map[string]common.MapStr{}
+	err := store.Each(func(key string, dec backend.ValueDecoder) (bool, error) {
+		var value common.MapStr
+		if err := dec.Decode(&value); err != nil {
+			return false, err
+		}
+		states[key] = value
+		return true, nil
+	})
+	return states, err
+}
@@ -0,0 +1,141 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package beater
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/filebeat/config"
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend"
+	"github.com/elastic/beats/v7/libbeat/statestore/storetest"
+)
+
+type offsetState struct {
+	Offset int64 `struct:"offset"`
+}
+
+func TestReplicate(t *testing.T) {
+	local := openTestStore(t, storetest.NewMemoryStoreBackend())
+	defer local.Close()
+	remoteReg := storetest.NewMemoryStoreBackend()
+	remote, err := remoteReg.Access("replica")
+	require.NoError(t, err)
+
+	// Stale states in the replica are removed by the first replication.
+	require.NoError(t, remote.Set("stale", offsetState{Offset: 1}))
+	require.NoError(t, local.Set("a", offsetState{Offset: 10}))
+	require.NoError(t, local.Set("b", offsetState{Offset: 20}))
+
+	r := newReplicator(logp.NewLogger("test"), time.Hour, local, nil).withReplica(remoteReg, remote)
+	n, err := r.replicate()
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Equal(t, map[string]int64{"a": 10, "b": 20}, offsets(t, remote))
+
+	// Only the updates are written.
+	require.NoError(t, local.Set("a", offsetState{Offset: 11}))
+	require.NoError(t, local.Remove("b"))
+	n, err = r.replicate()
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, map[string]int64{"a": 11}, offsets(t, remote))
+
+	n, err = r.replicate()
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestReplicatorRetriesConnect(t *testing.T) {
+	local := openTestStore(t, storetest.NewMemoryStoreBackend())
+	require.NoError(t, local.Set("a", offsetState{Offset: 10}))
+
+	remoteReg := storetest.NewMemoryStoreBackend()
+	connected := make(chan *storetest.MapStore, 1)
+	attempts := 0
+	connect := func() (backend.Registry, backend.Store, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, nil, errors.New("connection refused")
+		}
+		store, err := remoteReg.Access("replica")
+		connected <- store.(*storetest.MapStore)
+		return remoteReg, store, err
+	}
+
+	r := newReplicator(logp.NewLogger("test"), 10*time.Millisecond, local, connect)
+	r.Start()
+	remote := <-connected
+	assert.Eventually(t, func() bool {
+		return len(offsets(t, remote)) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// The states are replicated one last time on stop.
+	require.NoError(t, local.Set("b", offsetState{Offset: 20}))
+	r.Stop()
+
+	assert.True(t, remote.IsClosed())
+	remote.Reopen()
+	assert.Equal(t, map[string]int64{"a": 10, "b": 20}, offsets(t, remote))
+}
+
+func TestRestoreReplica(t *testing.T) {
+	replica := &storetest.MapStore{}
+	require.NoError(t, replica.Set("a", offsetState{Offset: 10}))
+	local := &storetest.MapStore{}
+
+	n, err := restoreReplica(replica, local)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, map[string]int64{"a": 10}, offsets(t, local))
+}
+
+func TestOpenStateStoreReplicationUnsupportedBackend(t *testing.T) {
+	cfg := config.DefaultConfig.Registry
+	cfg.Backend = "elasticsearch"
+	cfg.Replication.Enabled = true
+	_, err := openStateStore(beat.Info{Beat: "filebeat"}, logp.NewLogger("test"), cfg)
+	assert.Error(t, err)
+}
+
+func openTestStore(t *testing.T, reg backend.Registry) *statestore.Store {
+	store, err := statestore.NewRegistry(reg).Get("test")
+	require.NoError(t, err)
+	return store
+}
+
+func offsets(t *testing.T, store backend.Store) map[string]int64 {
+	offsets := map[string]int64{}
+	err := store.Each(func(key string, dec backend.ValueDecoder) (bool, error) {
+		var st offsetState
+		if err := dec.Decode(&st); err != nil {
+			return false, err
+		}
+		offsets[key] = st.Offset
+		return true, nil
+	})
+	require.NoError(t, err)
+	return offsets
+}
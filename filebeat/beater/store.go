@@ -18,13 +18,22 @@
 package beater
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/elastic/beats/v7/filebeat/config"
 	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/esleg/eslegclient"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/paths"
 	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend/bolt"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend/elasticsearch"
 	"github.com/elastic/beats/v7/libbeat/statestore/backend/memlog"
 )
 
@@ -32,25 +41,252 @@ type filebeatStore struct {
 	registry      *statestore.Registry
 	storeName     string
 	cleanInterval time.Duration
+	replicator    *replicator
+}
+
+// localBackends lists the registry backends storing the states in the
+// registry directory, with the path of a store inside the directory.
+var localBackends = map[string]func(root, name string) string{
+	"memlog": func(root, name string) string { return filepath.Join(root, name) },
+	"bolt":   bolt.StorePath,
 }
 
 func openStateStore(info beat.Info, logger *logp.Logger, cfg config.Registry) (*filebeatStore, error) {
-	memlog, err := memlog.New(logger, memlog.Settings{
-		Root:     paths.Resolve(paths.Data, cfg.Path),
-		FileMode: cfg.Permissions,
-	})
+	root := paths.Resolve(paths.Data, cfg.Path)
+	replication := cfg.Replication.Enabled
+	if _, local := localBackends[cfg.Backend]; replication && !local {
+		return nil, fmt.Errorf("registry replication is not supported by the %v backend", cfg.Backend)
+	}
+
+	missing := true
+	for _, path := range localBackends {
+		if exists(path(root, info.Beat)) {
+			missing = false
+		}
+	}
+
+	reg, err := newRegistryBackend(info, logger, cfg.Backend, root, cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	return &filebeatStore{
-		registry:      statestore.NewRegistry(memlog),
+	for name, path := range localBackends {
+		if name == cfg.Backend || !exists(path(root, info.Beat)) {
+			continue
+		}
+		if err := migrateStore(info, logger, name, cfg.Backend, root, reg, cfg); err != nil {
+			reg.Close()
+			return nil, err
+		}
+	}
+
+	var replicaReg backend.Registry
+	var replica backend.Store
+	if replication && missing {
+		replicaReg, replica, err = bootstrapFromReplica(info, logger, reg, cfg)
+		if err != nil {
+			reg.Close()
+			return nil, err
+		}
+	}
+
+	store := &filebeatStore{
+		registry:      statestore.NewRegistry(reg),
 		storeName:     info.Beat,
 		cleanInterval: cfg.CleanInterval,
-	}, nil
+	}
+	if replication {
+		local, err := store.Access()
+		if err != nil {
+			if replica != nil {
+				replica.Close()
+				replicaReg.Close()
+			}
+			store.Close()
+			return nil, err
+		}
+
+		replLogger := logger.Named("replication")
+		store.replicator = newReplicator(replLogger, cfg.Replication.Interval, local, func() (backend.Registry, backend.Store, error) {
+			return openReplica(info, replLogger, cfg)
+		})
+		if replica != nil {
+			store.replicator.withReplica(replicaReg, replica)
+		}
+		store.replicator.Start()
+	}
+	return store, nil
+}
+
+// bootstrapFromReplica restores the registry from the replica in
+// Elasticsearch, if the local registry does not exist. The replica is returned
+// open, so that it is not opened again by the replicator.
+func bootstrapFromReplica(info beat.Info, logger *logp.Logger, reg backend.Registry, cfg config.Registry) (backend.Registry, backend.Store, error) {
+	name := replicaName(info, cfg.Replication)
+	logger.Infof("No registry found, restoring the registry from the replica %v", name)
+	replicaReg, replica, err := openReplica(info, logger, cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the registry replica: %v", err)
+	}
+
+	local, err := reg.Access(info.Beat)
+	if err == nil {
+		var n int
+		n, err = restoreReplica(replica, local)
+		local.Close()
+		if err == nil {
+			logger.Infof("Restored %d registry entries from the replica %v", n, name)
+			return replicaReg, replica, nil
+		}
+	}
+
+	replica.Close()
+	replicaReg.Close()
+	return nil, nil, fmt.Errorf("failed to restore the registry from the replica: %v", err)
+}
+
+// OpenRegistryBackend opens the backend configured in cfg, without migrating
+// the states of other backends, for inspecting the registry while Filebeat is
+// stopped. Only the backends storing the registry in the data path are
+// supported, and the registry must exist.
+func OpenRegistryBackend(info beat.Info, logger *logp.Logger, cfg config.Registry) (backend.Registry, error) {
+	path, ok := localBackends[cfg.Backend]
+	if !ok {
+		return nil, fmt.Errorf("registry backend '%v' is not supported, only the memlog and bolt backends can be inspected", cfg.Backend)
+	}
+
+	root := paths.Resolve(paths.Data, cfg.Path)
+	if storePath := path(root, info.Beat); !exists(storePath) {
+		return nil, fmt.Errorf("no registry found in %v", storePath)
+	}
+	return newRegistryBackend(info, logger, cfg.Backend, root, cfg)
+}
+
+func newRegistryBackend(info beat.Info, logger *logp.Logger, name, root string, cfg config.Registry) (backend.Registry, error) {
+	switch name {
+	case "memlog":
+		return memlog.New(logger, memlog.Settings{
+			Root:               root,
+			FileMode:           cfg.Permissions,
+			CheckpointInterval: cfg.CompactionInterval,
+		})
+	case "bolt":
+		return bolt.New(logger, bolt.Settings{
+			Root:     root,
+			FileMode: cfg.Permissions,
+		})
+	case "elasticsearch":
+		return newElasticsearchBackend(info, logger, cfg.Elasticsearch)
+	default:
+		return nil, fmt.Errorf("unknown registry backend '%v'", name)
+	}
+}
+
+func newElasticsearchBackend(info beat.Info, logger *logp.Logger, esConfig *common.Config) (backend.Registry, error) {
+	if esConfig == nil {
+		return nil, errors.New("filebeat.registry.elasticsearch must be configured to use the elasticsearch registry backend")
+	}
+
+	settings := struct {
+		Index         string        `config:"index"`
+		LeaseDuration time.Duration `config:"lease_duration"`
+	}{
+		Index:         info.Beat + "-registry",
+		LeaseDuration: 30 * time.Second,
+	}
+	if err := esConfig.Unpack(&settings); err != nil {
+		return nil, err
+	}
+
+	conn, err := eslegclient.NewConnectedClient(esConfig)
+	if err != nil {
+		return nil, err
+	}
+	return elasticsearch.New(logger, conn, elasticsearch.Settings{
+		Index:         settings.Index,
+		Owner:         fmt.Sprintf("%v/%v", info.Hostname, info.ID),
+		LeaseDuration: settings.LeaseDuration,
+	})
+}
+
+// migrateStore copies all states of a store written by another backend to the
+// configured one, if it doesn't hold any state yet. The old store is kept with
+// the .migrated suffix, so switching back to the old backend migrates the
+// states again.
+func migrateStore(info beat.Info, logger *logp.Logger, from, to, root string, dst backend.Registry, cfg config.Registry) error {
+	name := info.Beat
+	dstStore, err := dst.Access(name)
+	if err != nil {
+		return err
+	}
+	defer dstStore.Close()
+
+	empty := true
+	if err := dstStore.Each(func(string, backend.ValueDecoder) (bool, error) {
+		empty = false
+		return false, nil
+	}); err != nil {
+		return err
+	}
+	path := localBackends[from](root, name)
+	if !empty {
+		logger.Warnf("Registry of the %v backend found in %v, but the %v backend already holds states. The states are not migrated.", from, path, to)
+		return nil
+	}
+
+	logger.Infof("Migrate registry from the %v to the %v backend", from, to)
+	src, err := newRegistryBackend(info, logger, from, root, cfg)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	srcStore, err := src.Access(name)
+	if err != nil {
+		return fmt.Errorf("failed to open %v registry: %v", from, err)
+	}
+
+	var copied []string
+	err = srcStore.Each(func(key string, dec backend.ValueDecoder) (bool, error) {
+		var value map[string]interface{}
+		if err := dec.Decode(&value); err != nil {
+			return false, err
+		}
+		if err := dstStore.Set(key, value); err != nil {
+			return false, err
+		}
+		copied = append(copied, key)
+		return true, nil
+	})
+	srcStore.Close()
+	if err != nil {
+		// Remove the partial copy, so the migration is retried on restart.
+		for _, key := range copied {
+			dstStore.Remove(key)
+		}
+		return fmt.Errorf("failed to migrate registry from the %v backend: %v", from, err)
+	}
+
+	backup := path + ".migrated"
+	if err := os.RemoveAll(backup); err != nil {
+		return err
+	}
+	if err := os.Rename(path, backup); err != nil {
+		return err
+	}
+	logger.Infof("Migrated %d registry entries, previous registry moved to %v", len(copied), backup)
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
 }
 
 func (s *filebeatStore) Close() {
+	if s.replicator != nil {
+		s.replicator.Stop()
+	}
 	s.registry.Close()
 }
 
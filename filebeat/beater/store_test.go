@@ -0,0 +1,92 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package beater
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/filebeat/config"
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend/bolt"
+)
+
+func TestOpenStateStoreMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filebeat-registry")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	info := beat.Info{Beat: "filebeat"}
+	cfg := config.DefaultConfig.Registry
+	cfg.Path = dir
+
+	open := func(backend string) *filebeatStore {
+		cfg.Backend = backend
+		store, err := openStateStore(info, logp.NewLogger("test"), cfg)
+		require.NoError(t, err)
+		return store
+	}
+
+	type state struct {
+		Offset int64 `struct:"offset"`
+	}
+
+	// Write a state with the default backend.
+	fbStore := open("memlog")
+	store, err := fbStore.Access()
+	require.NoError(t, err)
+	require.NoError(t, store.Set("filestream::test", state{Offset: 42}))
+	store.Close()
+	fbStore.Close()
+
+	// The states are copied when switching backends.
+	for _, backend := range []string{"bolt", "memlog"} {
+		fbStore = open(backend)
+		store, err = fbStore.Access()
+		require.NoError(t, err)
+
+		var st state
+		require.NoError(t, store.Get("filestream::test", &st))
+		assert.Equal(t, int64(42), st.Offset, backend)
+		store.Close()
+		fbStore.Close()
+	}
+
+	assert.FileExists(t, bolt.StorePath(dir, "filebeat")+".migrated")
+	assert.DirExists(t, filepath.Join(dir, "filebeat"))
+}
+
+func TestOpenStateStoreUnknownBackend(t *testing.T) {
+	cfg := config.DefaultConfig.Registry
+	cfg.Backend = "unknown"
+	_, err := openStateStore(beat.Info{Beat: "filebeat"}, logp.NewLogger("test"), cfg)
+	assert.Error(t, err)
+}
+
+func TestOpenStateStoreElasticsearchNotConfigured(t *testing.T) {
+	cfg := config.DefaultConfig.Registry
+	cfg.Backend = "elasticsearch"
+	_, err := openStateStore(beat.Info{Beat: "filebeat"}, logp.NewLogger("test"), cfg)
+	assert.Error(t, err)
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/elastic/beats/v7/filebeat/generator/fields"
 	"github.com/elastic/beats/v7/filebeat/generator/fileset"
+	"github.com/elastic/beats/v7/filebeat/generator/input"
 	"github.com/elastic/beats/v7/filebeat/generator/module"
 	"github.com/elastic/beats/v7/libbeat/common/cli"
 	"github.com/elastic/beats/v7/libbeat/paths"
@@ -37,10 +38,11 @@ var (
 func genGenerateCmd() *cobra.Command {
 	generateCmd := cobra.Command{
 		Use:   "generate",
-		Short: "Generate Filebeat modules, filesets and fields.yml",
+		Short: "Generate Filebeat modules, filesets, inputs and fields.yml",
 	}
 	generateCmd.AddCommand(genGenerateModuleCmd())
 	generateCmd.AddCommand(genGenerateFilesetCmd())
+	generateCmd.AddCommand(genGenerateInputCmd())
 	generateCmd.AddCommand(genGenerateFieldsCmd())
 
 	return &generateCmd
@@ -95,6 +97,30 @@ func genGenerateFilesetCmd() *cobra.Command {
 	return genFilesetCmd
 }
 
+func genGenerateInputCmd() *cobra.Command {
+	genInputCmd := &cobra.Command{
+		Use:   "input [input]",
+		Short: "Generates a new input",
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			inputsPath, _ := cmd.Flags().GetString("inputs-path")
+			esBeatsPath, _ := cmd.Flags().GetString("es-beats")
+
+			if len(args) != 1 {
+				fmt.Fprintf(os.Stderr, "Exactly one parameter is required: input name\n")
+				os.Exit(1)
+			}
+			name := args[0]
+
+			return input.Generate(name, inputsPath, esBeatsPath)
+		}),
+	}
+
+	genInputCmd.Flags().String("inputs-path", defaultHomePath, "Path to the directory containing the inputs directory")
+	genInputCmd.Flags().String("es-beats", defaultHomePath, "Path to Elastic Beats")
+
+	return genInputCmd
+}
+
 func genGenerateFieldsCmd() *cobra.Command {
 	genFieldsCmd := &cobra.Command{
 		Use:   "fields [module] [fileset]",
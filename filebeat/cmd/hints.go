@@ -0,0 +1,36 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import "github.com/elastic/beats/v7/libbeat/cmd/wizard"
+
+// moduleHints are used to suggest the modules whose logs are found in their
+// default locations.
+var moduleHints = []wizard.ModuleHint{
+	{Module: "apache", Paths: []string{"/var/log/apache2/access.log*", "/var/log/httpd/access_log*"}},
+	{Module: "auditd", Paths: []string{"/var/log/audit/audit.log*"}},
+	{Module: "elasticsearch", Paths: []string{"/var/log/elasticsearch/*.log"}},
+	{Module: "haproxy", Paths: []string{"/var/log/haproxy.log"}},
+	{Module: "logstash", Paths: []string{"/var/log/logstash/logstash-plain.log*"}},
+	{Module: "mongodb", Paths: []string{"/var/log/mongodb/mongodb.log"}},
+	{Module: "mysql", Paths: []string{"/var/log/mysql/error.log*", "/var/log/mysqld.log*"}},
+	{Module: "nginx", Paths: []string{"/var/log/nginx/access.log*"}},
+	{Module: "postgresql", Paths: []string{"/var/log/postgresql/postgresql-*-*.log*"}},
+	{Module: "redis", Paths: []string{"/var/log/redis/redis-server.log*"}},
+	{Module: "system", Paths: []string{"/var/log/syslog*", "/var/log/messages*", "/var/log/auth.log*", "/var/log/secure*"}},
+}
@@ -0,0 +1,476 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/beats/v7/filebeat/beater"
+	"github.com/elastic/beats/v7/filebeat/config"
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cli"
+	"github.com/elastic/beats/v7/libbeat/common/transform/typeconv"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend"
+)
+
+// registryRepairer is implemented by the registry backends that can check
+// their files for corruption.
+type registryRepairer interface {
+	Verify(name string) ([]string, error)
+	Repair(name string) (int, error)
+}
+
+// registryEntry is a state of the registry, as shown by the registry command.
+type registryEntry struct {
+	Key     string     `json:"key"`
+	Source  string     `json:"source,omitempty"`
+	Offset  *int64     `json:"offset,omitempty"`
+	TTL     string     `json:"ttl,omitempty"`
+	Updated *time.Time `json:"updated,omitempty"`
+}
+
+// storedTimes contains the update timestamps of the states written by the log
+// input and by the inputs based on cursors.
+type storedTimes struct {
+	Timestamp time.Time `struct:"timestamp"`
+	Updated   time.Time `struct:"updated"`
+}
+
+// entrySelector selects the entries modified by the registry command, by key
+// or by a glob pattern matching the source.
+type entrySelector struct {
+	keys map[string]bool
+	path string
+}
+
+func genRegistryCmd(settings instance.Settings) *cobra.Command {
+	registryCmd := cobra.Command{
+		Use:   "registry",
+		Short: "Inspect and repair the registry while Filebeat is stopped",
+	}
+	registryCmd.AddCommand(genRegistryListCmd(settings))
+	registryCmd.AddCommand(genRegistryDeleteCmd(settings))
+	registryCmd.AddCommand(genRegistryResetCmd(settings))
+	registryCmd.AddCommand(genRegistryVerifyCmd(settings))
+	registryCmd.AddCommand(genRegistryRepairCmd(settings))
+	return &registryCmd
+}
+
+func genRegistryListCmd(settings instance.Settings) *cobra.Command {
+	var path string
+	command := &cobra.Command{
+		Use:   "list",
+		Short: "List the entries of the registry",
+		Args:  cobra.NoArgs,
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			if err := checkPattern(path); err != nil {
+				return err
+			}
+			selector := entrySelector{path: path}
+
+			var entries []registryEntry
+			err := withRegistryStore(settings, func(store backend.Store) error {
+				return store.Each(func(key string, dec backend.ValueDecoder) (bool, error) {
+					entry, _, err := decodeEntry(key, dec)
+					if err != nil {
+						return false, err
+					}
+					if path == "" || selector.matches(entry) {
+						entries = append(entries, entry)
+					}
+					return true, nil
+				})
+			})
+			if err != nil {
+				return err
+			}
+
+			sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+			if cli.GetOutputFormat(cmd) == cli.OutputJSON {
+				if entries == nil {
+					entries = []registryEntry{}
+				}
+				return cli.WriteJSON(os.Stdout, entries)
+			}
+			printEntries(os.Stdout, entries)
+			return nil
+		}),
+	}
+	command.Flags().StringVar(&path, "path", "", "Only list the entries of the files matching the glob pattern")
+	return command
+}
+
+func genRegistryDeleteCmd(settings instance.Settings) *cobra.Command {
+	var path string
+	command := &cobra.Command{
+		Use:   "delete [KEY...]",
+		Short: "Delete entries from the registry, the files are read from the beginning on the next start",
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			selector, err := newEntrySelector(args, path)
+			if err != nil {
+				return err
+			}
+			return updateEntries(settings, selector, "Deleted", func(store backend.Store, key string, _ common.MapStr) error {
+				return store.Remove(key)
+			})
+		}),
+	}
+	command.Flags().StringVar(&path, "path", "", "Delete the entries of the files matching the glob pattern")
+	return command
+}
+
+func genRegistryResetCmd(settings instance.Settings) *cobra.Command {
+	var (
+		path   string
+		offset int64
+	)
+	command := &cobra.Command{
+		Use:   "reset [KEY...]",
+		Short: "Reset the offset of entries in the registry",
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			selector, err := newEntrySelector(args, path)
+			if err != nil {
+				return err
+			}
+			if offset < 0 {
+				return errors.New("the offset must not be negative")
+			}
+			return updateEntries(settings, selector, "Reset", func(store backend.Store, key string, state common.MapStr) error {
+				if err := setOffset(state, offset); err != nil {
+					return fmt.Errorf("can not reset entry %v: %v", key, err)
+				}
+				return store.Set(key, state)
+			})
+		}),
+	}
+	command.Flags().StringVar(&path, "path", "", "Reset the entries of the files matching the glob pattern")
+	command.Flags().Int64Var(&offset, "offset", 0, "Offset to continue reading the files from")
+	return command
+}
+
+func genRegistryVerifyCmd(settings instance.Settings) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify",
+		Short: "Check the registry for corruption",
+		Args:  cobra.NoArgs,
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			var problems []string
+			err := withRegistry(settings, func(reg backend.Registry, name string) error {
+				if repairer, ok := reg.(registryRepairer); ok {
+					var err error
+					if problems, err = repairer.Verify(name); err != nil || len(problems) > 0 {
+						// The entries can not be read reliably from a corrupted store.
+						return err
+					}
+				}
+
+				store, err := reg.Access(name)
+				if err != nil {
+					return err
+				}
+				defer store.Close()
+				return store.Each(func(key string, dec backend.ValueDecoder) (bool, error) {
+					if _, _, err := decodeEntry(key, dec); err != nil {
+						problems = append(problems, err.Error())
+					}
+					return true, nil
+				})
+			})
+			if err != nil {
+				return err
+			}
+
+			if cli.GetOutputFormat(cmd) == cli.OutputJSON {
+				if problems == nil {
+					problems = []string{}
+				}
+				if err := cli.WriteJSON(os.Stdout, map[string][]string{"problems": problems}); err != nil {
+					return err
+				}
+			} else {
+				for _, problem := range problems {
+					fmt.Fprintln(os.Stdout, problem)
+				}
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("found %d problems in the registry, run the repair command to fix them", len(problems))
+			}
+			if cli.GetOutputFormat(cmd) != cli.OutputJSON {
+				fmt.Fprintln(os.Stdout, "Registry OK")
+			}
+			return nil
+		}),
+	}
+}
+
+func genRegistryRepairCmd(settings instance.Settings) *cobra.Command {
+	return &cobra.Command{
+		Use:   "repair",
+		Short: "Repair a corrupted registry, keeping the states that can be recovered",
+		Args:  cobra.NoArgs,
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			return withRegistry(settings, func(reg backend.Registry, name string) error {
+				repairer, ok := reg.(registryRepairer)
+				if !ok {
+					return errors.New("the registry backend does not support repairing the registry")
+				}
+				n, err := repairer.Repair(name)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stdout, "Registry repaired, %d entries recovered\n", n)
+				return nil
+			})
+		}),
+	}
+}
+
+// withRegistry opens the registry backend configured for the beat, after
+// locking the data path, so that the registry is not modified by a running
+// beat at the same time.
+func withRegistry(settings instance.Settings, fn func(reg backend.Registry, name string) error) error {
+	b, err := instance.NewInitializedBeat(settings)
+	if err != nil {
+		return fmt.Errorf("error initializing beat: %v", err)
+	}
+
+	unlock, err := b.LockDataPath()
+	if err != nil {
+		return fmt.Errorf("the registry can only be accessed while %v is stopped: %v", b.Info.Beat, err)
+	}
+	defer unlock()
+
+	cfg, err := registryConfig(b)
+	if err != nil {
+		return err
+	}
+	reg, err := beater.OpenRegistryBackend(b.Info, logp.NewLogger("registry"), cfg)
+	if err != nil {
+		return err
+	}
+	defer reg.Close()
+	return fn(reg, b.Info.Beat)
+}
+
+func withRegistryStore(settings instance.Settings, fn func(store backend.Store) error) error {
+	return withRegistry(settings, func(reg backend.Registry, name string) error {
+		store, err := reg.Access(name)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+		return fn(store)
+	})
+}
+
+func registryConfig(b *instance.Beat) (config.Registry, error) {
+	cfg := config.DefaultConfig.Registry
+	beatConfig, err := b.BeatConfig()
+	if err != nil {
+		return cfg, err
+	}
+	if !beatConfig.HasField("registry") {
+		return cfg, nil
+	}
+
+	sub, err := beatConfig.Child("registry", -1)
+	if err != nil {
+		return cfg, err
+	}
+	if err := sub.Unpack(&cfg); err != nil {
+		return cfg, fmt.Errorf("error reading the registry settings: %v", err)
+	}
+	return cfg, nil
+}
+
+// updateEntries calls fn for every entry selected, and prints the number of
+// entries updated. The entries are updated after iterating the store, as not
+// all backends support updates during the iteration.
+func updateEntries(settings instance.Settings, selector entrySelector, verb string, fn func(store backend.Store, key string, state common.MapStr) error) error {
+	n := 0
+	err := withRegistryStore(settings, func(store backend.Store) error {
+		selected := map[string]common.MapStr{}
+		err := store.Each(func(key string, dec backend.ValueDecoder) (bool, error) {
+			entry, state, err := decodeEntry(key, dec)
+			if err != nil {
+				return false, err
+			}
+			if selector.matches(entry) {
+				selected[key] = state
+			}
+			return true, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for key, state := range selected {
+			if err := fn(store, key, state); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stdout, "%v %d entries\n", verb, n)
+	return nil
+}
+
+func newEntrySelector(keys []string, path string) (entrySelector, error) {
+	if len(keys) == 0 && path == "" {
+		return entrySelector{}, errors.New("select the entries by key, or with the --path flag")
+	}
+	if err := checkPattern(path); err != nil {
+		return entrySelector{}, err
+	}
+
+	selector := entrySelector{keys: map[string]bool{}, path: path}
+	for _, key := range keys {
+		selector.keys[key] = true
+	}
+	return selector, nil
+}
+
+func checkPattern(pattern string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid path pattern '%v': %v", pattern, err)
+	}
+	return nil
+}
+
+func (s entrySelector) matches(entry registryEntry) bool {
+	if s.keys[entry.Key] {
+		return true
+	}
+	if s.path == "" || entry.Source == "" {
+		return false
+	}
+	matched, _ := filepath.Match(s.path, entry.Source)
+	return matched
+}
+
+// decodeEntry decodes the state of an entry, and the fields shown by the
+// registry command.
+func decodeEntry(key string, dec backend.ValueDecoder) (registryEntry, common.MapStr, error) {
+	var state common.MapStr
+	if err := dec.Decode(&state); err != nil {
+		return registryEntry{}, nil, fmt.Errorf("invalid value of entry %v: %v", key, err)
+	}
+
+	// Only the timestamps are converted, the other fields of the states can
+	// not be skipped by the decoder.
+	var times storedTimes
+	timeFields := map[string]interface{}{}
+	for _, name := range []string{"timestamp", "updated"} {
+		if v, ok := state[name]; ok {
+			timeFields[name] = v
+		}
+	}
+	if err := typeconv.Convert(&times, timeFields); err != nil {
+		return registryEntry{}, nil, fmt.Errorf("invalid timestamp in entry %v: %v", key, err)
+	}
+
+	entry := registryEntry{Key: key}
+	if source, ok := state["source"].(string); ok {
+		entry.Source = source
+	} else if source, err := state.GetValue("meta.source"); err == nil {
+		entry.Source, _ = source.(string)
+	}
+	if updated := times.Timestamp; !updated.IsZero() {
+		entry.Updated = &updated
+	} else if updated := times.Updated; !updated.IsZero() {
+		entry.Updated = &updated
+	}
+	if ttl, ok := toInt64(state["ttl"]); ok && ttl >= 0 {
+		entry.TTL = time.Duration(ttl).String()
+	}
+
+	offset, ok := toInt64(state["offset"])
+	if !ok {
+		cursorOffset, _ := state.GetValue("cursor.offset")
+		offset, ok = toInt64(cursorOffset)
+	}
+	if ok {
+		entry.Offset = &offset
+	}
+	return entry, state, nil
+}
+
+// setOffset updates the offset of the log input states, or the offset of the
+// cursor of the other inputs.
+func setOffset(state common.MapStr, offset int64) error {
+	if _, ok := state["offset"]; ok {
+		state["offset"] = offset
+		return nil
+	}
+	if cursor, ok := state["cursor"].(map[string]interface{}); ok {
+		if _, ok := cursor["offset"]; ok {
+			cursor["offset"] = offset
+			return nil
+		}
+	}
+	return errors.New("the entry has no offset")
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	default:
+		return 0, false
+	}
+}
+
+func printEntries(w io.Writer, entries []registryEntry) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tSOURCE\tOFFSET\tTTL\tUPDATED")
+	for _, entry := range entries {
+		offset, ttl, updated := "-", entry.TTL, "-"
+		if entry.Offset != nil {
+			offset = fmt.Sprint(*entry.Offset)
+		}
+		if ttl == "" {
+			ttl = "-"
+		}
+		if entry.Updated != nil {
+			updated = entry.Updated.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\n", entry.Key, entry.Source, offset, ttl, updated)
+	}
+	tw.Flush()
+}
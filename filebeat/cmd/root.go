@@ -25,6 +25,7 @@ import (
 	"github.com/elastic/beats/v7/filebeat/beater"
 
 	cmd "github.com/elastic/beats/v7/libbeat/cmd"
+	"github.com/elastic/beats/v7/libbeat/cmd/export"
 	"github.com/elastic/beats/v7/libbeat/cmd/instance"
 
 	// Import processors.
@@ -47,16 +48,22 @@ func FilebeatSettings() instance.Settings {
 		RunFlags:      runFlags,
 		Name:          Name,
 		HasDashboards: true,
+		ModuleHints:   moduleHints,
 	}
 }
 
 // Filebeat build the beat root command for executing filebeat and it's subcommands.
 func Filebeat(inputs beater.PluginFactory, settings instance.Settings) *cmd.BeatsRootCmd {
-	command := cmd.GenRootCmdWithSettings(beater.New(inputs), settings)
+	beatCreator := beater.New(inputs)
+	command := cmd.GenRootCmdWithSettings(beatCreator, settings)
 	command.PersistentFlags().AddGoFlag(flag.CommandLine.Lookup("M"))
 	command.TestCmd.Flags().AddGoFlag(flag.CommandLine.Lookup("modules"))
+	command.TestCmd.AddCommand(genTestInputCmd(settings, beatCreator))
+	command.TestCmd.AddCommand(genTestModulesCmd(settings, beatCreator))
 	command.SetupCmd.Flags().AddGoFlag(flag.CommandLine.Lookup("modules"))
+	command.ExportCmd.AddCommand(export.GenExportSampleEventsCmd(settings, "module/{module}/*/test/*-expected.json"))
 	command.AddCommand(cmd.GenModulesCmd(Name, "", buildModulesManager))
 	command.AddCommand(genGenerateCmd())
+	command.AddCommand(genRegistryCmd(settings))
 	return command
 }
@@ -0,0 +1,130 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/cmd/test"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func genTestInputCmd(settings instance.Settings, beatCreator beat.Creator) *cobra.Command {
+	var dryRun test.DryRun
+
+	command := &cobra.Command{
+		Use:   "input",
+		Short: "Print sample events read by the configured inputs and modules without publishing them",
+		Run: func(cmd *cobra.Command, args []string) {
+			dryRun.Prepare = sandboxRegistry
+			runDryRun(dryRun, settings, beatCreator)
+		},
+	}
+
+	command.Flags().AddGoFlag(flag.CommandLine.Lookup("modules"))
+	addDryRunFlags(command, &dryRun)
+	return command
+}
+
+func genTestModulesCmd(settings instance.Settings, beatCreator beat.Creator) *cobra.Command {
+	var dryRun test.DryRun
+
+	command := &cobra.Command{
+		Use:   "modules [module...]",
+		Short: "Print sample events read by the configured modules without publishing them",
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(args) > 0 {
+				flag.Set("modules", strings.Join(args, ","))
+			}
+
+			dryRun.Prepare = func(b *instance.Beat, dataDir string) error {
+				// Only run the modules, the inputs are tested by `test input`.
+				config, err := b.BeatConfig()
+				if err != nil {
+					return err
+				}
+				if err := removeField(config, "inputs"); err != nil {
+					return err
+				}
+				if config.HasField("config") {
+					reloadConfig, err := config.Child("config", -1)
+					if err != nil {
+						return err
+					}
+					if err := removeField(reloadConfig, "inputs"); err != nil {
+						return err
+					}
+				}
+				return sandboxRegistry(b, dataDir)
+			}
+			runDryRun(dryRun, settings, beatCreator)
+		},
+	}
+
+	addDryRunFlags(command, &dryRun)
+	return command
+}
+
+func removeField(config *common.Config, name string) error {
+	if !config.HasField(name) {
+		return nil
+	}
+	if _, err := config.Remove(name, -1); err != nil {
+		return fmt.Errorf("error disabling %s: %v", config.PathOf(name), err)
+	}
+	return nil
+}
+
+func addDryRunFlags(command *cobra.Command, dryRun *test.DryRun) {
+	command.Flags().IntVar(&dryRun.Events, "events", 5, "Number of events to print")
+	command.Flags().DurationVar(&dryRun.Timeout, "timeout", 10*time.Second, "Maximum time to wait for the events")
+}
+
+// sandboxRegistry stores the registry in the temporary data directory of the
+// dry run, so that the states of the files being read are not updated.
+func sandboxRegistry(b *instance.Beat, dataDir string) error {
+	if err := b.RawConfig.SetString("filebeat.registry.path", -1, filepath.Join(dataDir, "registry")); err != nil {
+		return err
+	}
+	return b.RawConfig.SetString("filebeat.registry.migrate_file", -1, "")
+}
+
+func runDryRun(dryRun test.DryRun, settings instance.Settings, beatCreator beat.Creator) {
+	if dryRun.Events < 1 {
+		fmt.Fprintf(os.Stderr, "The number of events must be at least 1\n")
+		os.Exit(1)
+	}
+
+	printed, err := dryRun.Run(settings, beatCreator, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	if printed < dryRun.Events {
+		fmt.Fprintf(os.Stderr, "Received %d of %d events within %v\n", printed, dryRun.Events, dryRun.Timeout)
+	}
+}
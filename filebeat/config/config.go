@@ -51,20 +51,53 @@ type Config struct {
 }
 
 type Registry struct {
+	Backend       string        `config:"backend"`
 	Path          string        `config:"path"`
 	Permissions   os.FileMode   `config:"file_permissions"`
 	FlushTimeout  time.Duration `config:"flush"`
 	CleanInterval time.Duration `config:"cleanup_interval"`
 	MigrateFile   string        `config:"migrate_file"`
+
+	// TTL removes the finished states that have not been updated for longer
+	// than the given duration, 0 disables the expiration.
+	TTL time.Duration `config:"ttl"`
+
+	// CompactionInterval is the interval at which pending updates are
+	// compacted into the registry data file, 0 disables the compaction.
+	CompactionInterval time.Duration `config:"compaction_interval"`
+
+	// Elasticsearch configures the connection and the index used by the
+	// elasticsearch backend and by the replication.
+	Elasticsearch *common.Config `config:"elasticsearch"`
+
+	// Replication copies the states of a local backend to Elasticsearch.
+	Replication RegistryReplication `config:"replication"`
+}
+
+// RegistryReplication configures the replication of the registry to
+// Elasticsearch, which is used to restore the registry if it is missing on
+// startup.
+type RegistryReplication struct {
+	Enabled  bool          `config:"enabled"`
+	Interval time.Duration `config:"interval" validate:"min=0,nonzero"`
+
+	// Name identifies the replica of this Filebeat in the index. It must not
+	// change when Filebeat is restarted.
+	Name string `config:"name"`
 }
 
 var (
 	DefaultConfig = Config{
 		Registry: Registry{
-			Path:          "registry",
-			Permissions:   0600,
-			MigrateFile:   "",
-			CleanInterval: 5 * time.Minute,
+			Backend:            "memlog",
+			Path:               "registry",
+			Permissions:        0600,
+			MigrateFile:        "",
+			CleanInterval:      5 * time.Minute,
+			CompactionInterval: 1 * time.Hour,
+			Replication: RegistryReplication{
+				Interval: 1 * time.Minute,
+			},
 		},
 		ShutdownTimeout:    0,
 		OverwritePipelines: false,
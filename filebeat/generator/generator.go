@@ -15,6 +15,8 @@
 // specific language governing permissions and limitations
 // under the License.
 
+//go:generate go run ../../dev-tools/cmd/templates/templates.go -pkg generator -in ../scripts -out templates.go module fileset input
+
 package generator
 
 import (
@@ -23,6 +25,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 )
 
 // DirExists check that directory exists
@@ -93,6 +96,11 @@ func copyTemplate(template, dest string, replace map[string]string) error {
 
 func readTemplate(template string, replace map[string]string) ([]byte, error) {
 	c, err := ioutil.ReadFile(template)
+	if os.IsNotExist(err) {
+		if builtin, ok := builtinTemplate(template); ok {
+			c, err = []byte(builtin), nil
+		}
+	}
 	if err != nil {
 		return []byte{}, fmt.Errorf("cannot read template: %v", err)
 	}
@@ -104,6 +112,18 @@ func readTemplate(template string, replace map[string]string) ([]byte, error) {
 	return c, nil
 }
 
+// builtinTemplate returns the template built into the binary for a template
+// of the scripts directory, which is not available in installed packages.
+func builtinTemplate(template string) (string, bool) {
+	template = path.Clean(template)
+	for name, content := range builtinTemplates {
+		if template == path.Join("scripts", name) || strings.HasSuffix(template, "/"+path.Join("scripts", name)) {
+			return content, true
+		}
+	}
+	return "", false
+}
+
 // RenameConfigYml renames config.yml to the name of the fileset, otherwise Filebeat refuses to start
 func RenameConfigYml(modulesPath, module, fileset string) error {
 	old := path.Join(modulesPath, "module", module, fileset, "config", "config.yml")
@@ -0,0 +1,70 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBuiltinTemplatesUpToDate checks that the templates built into the binary
+// match the ones in the scripts directory. Run `go generate` to update them.
+func TestBuiltinTemplatesUpToDate(t *testing.T) {
+	found := 0
+	for _, dir := range []string{"module", "fileset", "input"} {
+		err := filepath.Walk(filepath.Join("..", "scripts", dir), func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+
+			content, err := ioutil.ReadFile(p)
+			require.NoError(t, err)
+
+			name, err := filepath.Rel(filepath.Join("..", "scripts"), p)
+			require.NoError(t, err)
+			assert.Equal(t, string(content), builtinTemplates[filepath.ToSlash(name)], "template %s is outdated", name)
+			found++
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, found, len(builtinTemplates))
+}
+
+func TestCopyTemplatesBuiltin(t *testing.T) {
+	dest, err := ioutil.TempDir("", "generator")
+	require.NoError(t, err)
+	defer os.RemoveAll(dest)
+
+	src := path.Join(dest, "beats", "scripts", "fileset")
+	err = CopyTemplates(src, dest, []string{"module-fileset.yml"}, map[string]string{"module": "foo", "fileset": "bar"})
+	require.NoError(t, err)
+
+	content, err := ioutil.ReadFile(path.Join(dest, "module-fileset.yml"))
+	require.NoError(t, err)
+	assert.Equal(t, "- id: Filebeat-foo-bar-Dashboard\n  file: Filebeat-foo-bar.json\n", string(content))
+
+	err = CopyTemplates(src, dest, []string{"unknown.yml"}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package input
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/elastic/beats/v7/filebeat/generator"
+)
+
+var validName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Generate creates the skeleton of a new input, which must be registered
+// afterwards in the list of inputs of the Beat.
+func Generate(input, inputsPath, beatsPath string) error {
+	if !validName.MatchString(input) {
+		return fmt.Errorf("invalid input name, only lowercase letters, digits and underscores can be used: %s", input)
+	}
+
+	inputPath := path.Join(inputsPath, "input", input)
+	if generator.DirExists(inputPath) {
+		return fmt.Errorf("input already exists: %s", input)
+	}
+
+	err := os.MkdirAll(inputPath, 0750)
+	if err != nil {
+		return err
+	}
+
+	replace := map[string]string{"input": input}
+	templatesPath := path.Join(beatsPath, "scripts", "input")
+	filesToCopy := []string{"input.go.tmpl", "config.go.tmpl", "input_test.go.tmpl"}
+	err = generator.CopyTemplates(templatesPath, inputPath, filesToCopy, replace)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range filesToCopy {
+		err = os.Rename(path.Join(inputPath, f), path.Join(inputPath, strings.TrimSuffix(f, ".tmpl")))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -31,8 +31,5 @@ type osComponents interface {
 }
 
 func osInputs(info beat.Info, log *logp.Logger, components osComponents) []v2.Plugin {
-	return []v2.Plugin{
-		// XXX: journald is currently disable.
-		// journald.Plugin(log, components),
-	}
+	return journaldInputs(log, components)
 }
@@ -0,0 +1,46 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package exec
+
+import "bytes"
+
+// limitedBuffer stores the first bytes written to it, up to limit, and
+// discards the rest. Writes never fail, so the command isn't blocked or
+// killed when it writes more than limit. The buffer isn't embedded so
+// io.Copy can't bypass Write through its ReadFrom method.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if remaining := b.limit - b.buf.Len(); len(p) > remaining {
+		p = p[:remaining]
+		b.truncated = true
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+// Bytes returns the stored bytes.
+func (b *limitedBuffer) Bytes() []byte { return b.buf.Bytes() }
+
+// Len returns the number of stored bytes.
+func (b *limitedBuffer) Len() int { return b.buf.Len() }
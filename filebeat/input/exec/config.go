@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/elastic/beats/v7/heartbeat/scheduler/schedule"
+	"github.com/elastic/beats/v7/libbeat/common/cfgtype"
+)
+
+type config struct {
+	// Command is the program to run.
+	Command string `config:"command" validate:"required"`
+
+	// Args are the arguments passed to the program.
+	Args []string `config:"args"`
+
+	// Env are environment variables set for the program, in the form
+	// KEY=value, in addition to the environment of the Beat.
+	Env []string `config:"env"`
+
+	// WorkingDirectory is the directory the program runs in.
+	WorkingDirectory string `config:"working_directory"`
+
+	// Schedule defines when the program runs, as an interval in the form
+	// "@every 30s" or as a cron expression.
+	Schedule *schedule.Schedule `config:"schedule" validate:"required"`
+
+	// Timeout is the maximum duration of a run, the program is killed if it
+	// doesn't finish in time.
+	Timeout time.Duration `config:"timeout" validate:"positive,nonzero"`
+
+	// MaxOutputSize limits the size of stdout and stderr captured for each
+	// run, the rest of the output is discarded.
+	MaxOutputSize cfgtype.ByteSize `config:"max_output_size" validate:"min=1"`
+
+	// Parser configures how stdout is parsed into events.
+	Parser parserConfig `config:"parser"`
+}
+
+// Types of parsers.
+const (
+	parserNone  = "none"
+	parserJSON  = "json"
+	parserCSV   = "csv"
+	parserRegex = "regex"
+)
+
+type parserConfig struct {
+	// Type is the parser of stdout, one of none, json, csv or regex.
+	Type string `config:"type"`
+
+	// TargetField is the field the parsed values are stored in.
+	TargetField string `config:"target_field"`
+
+	// Separator is the separator of the values of the csv parser.
+	Separator string `config:"separator"`
+
+	// Columns are the names of the columns of the csv parser. The first
+	// record of the output is used as header if they aren't set.
+	Columns []string `config:"columns"`
+
+	// Pattern is the regular expression of the regex parser, the values of
+	// its named groups are stored in the event.
+	Pattern string `config:"pattern"`
+}
+
+func defaultConfig() config {
+	return config{
+		Timeout:       time.Minute,
+		MaxOutputSize: 1024 * 1024,
+		Parser: parserConfig{
+			Type:        parserNone,
+			TargetField: "exec.output",
+			Separator:   ",",
+		},
+	}
+}
+
+func (c *parserConfig) Validate() error {
+	switch c.Type {
+	case parserNone, parserJSON:
+	case parserCSV:
+		if utf8.RuneCountInString(c.Separator) != 1 {
+			return errors.New("the separator of the csv parser must be a single character")
+		}
+	case parserRegex:
+		if c.Pattern == "" {
+			return errors.New("the regex parser requires a pattern")
+		}
+		re, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern of the regex parser: %w", err)
+		}
+		if !hasNamedGroup(re) {
+			return errors.New("the pattern of the regex parser has no named groups")
+		}
+	default:
+		return fmt.Errorf("unknown parser type '%s'", c.Type)
+	}
+	return nil
+}
+
+func hasNamedGroup(re *regexp.Regexp) bool {
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
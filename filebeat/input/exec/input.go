@@ -0,0 +1,221 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"time"
+
+	v2 "github.com/elastic/beats/v7/filebeat/input/v2"
+	stateless "github.com/elastic/beats/v7/filebeat/input/v2/input-stateless"
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/feature"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/go-concert/ctxtool"
+	"github.com/elastic/go-concert/timed"
+)
+
+const pluginName = "exec"
+
+type execInput struct {
+	config config
+	parser parser
+}
+
+// run is the result of an execution of the command.
+type run struct {
+	start    time.Time
+	duration time.Duration
+	stdout   *limitedBuffer
+	stderr   *limitedBuffer
+
+	// exitCode is -1 if the command didn't exit, like when it couldn't start.
+	exitCode int
+	err      error
+}
+
+// Plugin creates a new exec input plugin, running a command on a schedule.
+func Plugin() v2.Plugin {
+	return v2.Plugin{
+		Name:       pluginName,
+		Stability:  feature.Experimental,
+		Deprecated: false,
+		Info:       "exec input",
+		Doc:        "The exec input runs a command on a schedule and publishes its output",
+		Manager:    stateless.NewInputManager(configure),
+	}
+}
+
+func configure(cfg *common.Config) (stateless.Input, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, err
+	}
+
+	p, err := newParser(config.Parser)
+	if err != nil {
+		return nil, err
+	}
+	return &execInput{config: config, parser: p}, nil
+}
+
+func (inp *execInput) Name() string { return pluginName }
+
+func (inp *execInput) Test(ctx v2.TestContext) error {
+	_, err := osexec.LookPath(inp.config.Command)
+	return err
+}
+
+func (inp *execInput) Run(ctx v2.Context, publisher stateless.Publisher) error {
+	log := ctx.Logger.With("command", inp.config.Command)
+	cancelCtx := ctxtool.FromCanceller(ctx.Cancelation)
+
+	next := time.Now()
+	if !inp.config.Schedule.RunOnInit() {
+		next = inp.config.Schedule.Next(next)
+	}
+	for {
+		if err := timed.Wait(cancelCtx, time.Until(next)); err != nil {
+			return nil
+		}
+
+		r := inp.execute(cancelCtx)
+		if cancelCtx.Err() != nil {
+			return nil
+		}
+		if r.err != nil {
+			log.Warnf("Command failed: %v", r.err)
+		}
+		for _, event := range inp.makeEvents(log, r) {
+			publisher.Publish(event)
+		}
+
+		next = inp.config.Schedule.Next(time.Now())
+	}
+}
+
+// execute runs the command and captures its output.
+func (inp *execInput) execute(ctx context.Context) run {
+	ctx, cancel := context.WithTimeout(ctx, inp.config.Timeout)
+	defer cancel()
+
+	cmd := osexec.CommandContext(ctx, inp.config.Command, inp.config.Args...)
+	cmd.Dir = inp.config.WorkingDirectory
+	cmd.Env = append(os.Environ(), inp.config.Env...)
+
+	r := run{
+		stdout:   &limitedBuffer{limit: int(inp.config.MaxOutputSize)},
+		stderr:   &limitedBuffer{limit: int(inp.config.MaxOutputSize)},
+		exitCode: -1,
+	}
+	cmd.Stdout = r.stdout
+	cmd.Stderr = r.stderr
+
+	r.start = time.Now()
+	err := cmd.Run()
+	r.duration = time.Since(r.start)
+
+	var exitErr *osexec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		r.err = fmt.Errorf("command timed out after %v", inp.config.Timeout)
+	case errors.As(err, &exitErr):
+		r.exitCode = exitErr.ExitCode()
+		if r.exitCode == -1 {
+			r.err = fmt.Errorf("command terminated: %v", exitErr)
+		} else {
+			r.err = fmt.Errorf("command exited with code %d", r.exitCode)
+		}
+	case err != nil:
+		r.err = err
+	default:
+		r.exitCode = 0
+	}
+	return r
+}
+
+// makeEvents returns the events of a run, one per record of the output. The
+// output isn't parsed if the command failed.
+func (inp *execInput) makeEvents(log *logp.Logger, r run) []beat.Event {
+	var records []record
+	var parseErr error
+	if r.err == nil {
+		records, parseErr = inp.parser.parse(r.stdout.Bytes())
+		if parseErr != nil {
+			log.Warnf("Failed to parse the output: %v", parseErr)
+		}
+	}
+	if r.err != nil || parseErr != nil {
+		records = []record{{message: string(r.stdout.Bytes())}}
+	}
+
+	events := make([]beat.Event, len(records))
+	for i, rec := range records {
+		events[i] = inp.makeEvent(r, rec, parseErr)
+	}
+	return events
+}
+
+func (inp *execInput) makeEvent(r run, rec record, parseErr error) beat.Event {
+	outcome := "success"
+	if r.err != nil {
+		outcome = "failure"
+	}
+
+	fields := common.MapStr{
+		"message": rec.message,
+		"event": common.MapStr{
+			"kind":     "event",
+			"outcome":  outcome,
+			"duration": r.duration.Nanoseconds(),
+		},
+		"process": common.MapStr{
+			"executable": inp.config.Command,
+			"args":       append([]string{inp.config.Command}, inp.config.Args...),
+		},
+	}
+	if r.exitCode >= 0 {
+		fields.Put("process.exit_code", r.exitCode)
+	}
+	if r.stderr.Len() > 0 {
+		fields.Put("exec.stderr", string(r.stderr.Bytes()))
+	}
+	if rec.fields != nil {
+		fields.Put(inp.config.Parser.TargetField, rec.fields)
+	}
+	if r.stdout.truncated || r.stderr.truncated {
+		fields.Put("log.flags", []string{"truncated"})
+	}
+
+	switch {
+	case r.err != nil:
+		fields.Put("error.message", r.err.Error())
+	case parseErr != nil:
+		fields.Put("error.message", fmt.Sprintf("failed to parse the output: %v", parseErr))
+	}
+
+	return beat.Event{
+		Timestamp: r.start,
+		Fields:    fields,
+	}
+}
@@ -0,0 +1,192 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build !windows
+
+package exec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+func TestInputRun(t *testing.T) {
+	tests := map[string]struct {
+		config map[string]interface{}
+		check  func(t *testing.T, events []beat.Event)
+	}{
+		"output": {
+			config: map[string]interface{}{
+				"args": []string{"-c", "echo hello; echo oops >&2"},
+			},
+			check: func(t *testing.T, events []beat.Event) {
+				require.Len(t, events, 1)
+				fields := events[0].Fields
+				assertField(t, fields, "message", "hello")
+				assertField(t, fields, "exec.stderr", "oops\n")
+				assertField(t, fields, "event.outcome", "success")
+				assertField(t, fields, "process.exit_code", 0)
+				assertField(t, fields, "process.args", []string{"/bin/sh", "-c", "echo hello; echo oops >&2"})
+			},
+		},
+		"environment": {
+			config: map[string]interface{}{
+				"args":              []string{"-c", `echo "$FOO $(pwd)"`},
+				"env":               []string{"FOO=bar"},
+				"working_directory": "/",
+			},
+			check: func(t *testing.T, events []beat.Event) {
+				require.Len(t, events, 1)
+				assertField(t, events[0].Fields, "message", "bar /")
+			},
+		},
+		"json": {
+			config: map[string]interface{}{
+				"args":   []string{"-c", `echo '{"a": 1}'; echo '{"a": 2}'`},
+				"parser": map[string]interface{}{"type": "json", "target_field": "result"},
+			},
+			check: func(t *testing.T, events []beat.Event) {
+				require.Len(t, events, 2)
+				assertField(t, events[0].Fields, "result.a", int64(1))
+				assertField(t, events[1].Fields, "result.a", int64(2))
+				assertField(t, events[1].Fields, "message", `{"a": 2}`)
+			},
+		},
+		"exit code": {
+			config: map[string]interface{}{
+				"args":   []string{"-c", "echo partial; exit 3"},
+				"parser": map[string]interface{}{"type": "json"},
+			},
+			check: func(t *testing.T, events []beat.Event) {
+				require.Len(t, events, 1)
+				fields := events[0].Fields
+				assertField(t, fields, "message", "partial\n")
+				assertField(t, fields, "event.outcome", "failure")
+				assertField(t, fields, "process.exit_code", 3)
+				assertField(t, fields, "error.message", "command exited with code 3")
+			},
+		},
+		"parse error": {
+			config: map[string]interface{}{
+				"args":   []string{"-c", "echo not json"},
+				"parser": map[string]interface{}{"type": "json"},
+			},
+			check: func(t *testing.T, events []beat.Event) {
+				require.Len(t, events, 1)
+				fields := events[0].Fields
+				assertField(t, fields, "message", "not json\n")
+				assertField(t, fields, "event.outcome", "success")
+				assert.Contains(t, fields["error"].(common.MapStr)["message"], "failed to parse the output")
+			},
+		},
+		"timeout": {
+			config: map[string]interface{}{
+				"args":    []string{"-c", "exec sleep 10"},
+				"timeout": "100ms",
+			},
+			check: func(t *testing.T, events []beat.Event) {
+				require.Len(t, events, 1)
+				fields := events[0].Fields
+				assertField(t, fields, "event.outcome", "failure")
+				assertField(t, fields, "error.message", "command timed out after 100ms")
+			},
+		},
+		"truncated output": {
+			config: map[string]interface{}{
+				"args":            []string{"-c", "echo 0123456789"},
+				"max_output_size": "4",
+			},
+			check: func(t *testing.T, events []beat.Event) {
+				require.Len(t, events, 1)
+				assertField(t, events[0].Fields, "message", "0123")
+				assertField(t, events[0].Fields, "log.flags", []string{"truncated"})
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			settings := map[string]interface{}{
+				"command":  "/bin/sh",
+				"schedule": "@every 1m",
+			}
+			for k, v := range test.config {
+				settings[k] = v
+			}
+
+			inp, err := configure(common.MustNewConfigFrom(settings))
+			require.NoError(t, err)
+
+			execInp := inp.(*execInput)
+			r := execInp.execute(context.Background())
+			test.check(t, execInp.makeEvents(logp.NewLogger("exec"), r))
+		})
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := &limitedBuffer{limit: 5}
+
+	n, err := b.Write([]byte("abc"))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.False(t, b.truncated)
+
+	n, err = b.Write([]byte("defg"))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.True(t, b.truncated)
+	assert.Equal(t, "abcde", string(b.Bytes()))
+}
+
+func TestConfigure(t *testing.T) {
+	_, err := configure(common.MustNewConfigFrom(map[string]interface{}{
+		"command": "/bin/true",
+	}))
+	assert.Error(t, err, "schedule is required")
+
+	_, err = configure(common.MustNewConfigFrom(map[string]interface{}{
+		"command":  "/bin/true",
+		"schedule": "@every 1s",
+		"parser":   map[string]interface{}{"type": "regex"},
+	}))
+	assert.Error(t, err, "regex parser requires a pattern")
+
+	inp, err := configure(common.MustNewConfigFrom(map[string]interface{}{
+		"command":  "/bin/true",
+		"schedule": "*/5 * * * * * *",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, inp.(*execInput).config.Timeout)
+}
+
+func assertField(t *testing.T, fields common.MapStr, key string, want interface{}) {
+	t.Helper()
+	v, err := fields.GetValue(key)
+	if assert.NoError(t, err, key) {
+		assert.Equal(t, want, v, key)
+	}
+}
@@ -0,0 +1,217 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/jsontransform"
+)
+
+// record is an entry of the output of a run, published as an event.
+type record struct {
+	// message is the part of the output the record was parsed from.
+	message string
+
+	// fields are the parsed values, nil if the output isn't parsed.
+	fields common.MapStr
+}
+
+// parser splits the output of a run into records.
+type parser interface {
+	parse(output []byte) ([]record, error)
+}
+
+func newParser(config parserConfig) (parser, error) {
+	switch config.Type {
+	case parserNone:
+		return noneParser{}, nil
+	case parserJSON:
+		return jsonParser{}, nil
+	case parserCSV:
+		separator, _ := utf8.DecodeRuneInString(config.Separator)
+		return &csvParser{separator: separator, columns: config.Columns}, nil
+	case parserRegex:
+		re, err := regexp.Compile(config.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return &regexParser{re: re}, nil
+	}
+	return nil, fmt.Errorf("unknown parser type '%s'", config.Type)
+}
+
+// noneParser publishes the whole output of a run in one event.
+type noneParser struct{}
+
+func (noneParser) parse(output []byte) ([]record, error) {
+	return []record{{message: string(bytes.TrimRight(output, "\r\n"))}}, nil
+}
+
+// jsonParser parses an output containing a JSON object, an array of objects,
+// or one object per line.
+type jsonParser struct{}
+
+func (jsonParser) parse(output []byte) ([]record, error) {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return nil, nil
+	}
+
+	var value interface{}
+	if err := decodeJSON(output, &value); err == nil {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			return []record{makeJSONRecord(string(output), v)}, nil
+		case []interface{}:
+			records := make([]record, 0, len(v))
+			for _, elem := range v {
+				obj, ok := elem.(map[string]interface{})
+				if !ok {
+					return nil, errors.New("the JSON array contains values that aren't objects")
+				}
+				b, _ := json.Marshal(obj)
+				records = append(records, makeJSONRecord(string(b), obj))
+			}
+			return records, nil
+		}
+	}
+
+	// Not a single value, try an object per line.
+	var records []record
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var obj map[string]interface{}
+		if err := decodeJSON(line, &obj); err != nil {
+			return nil, fmt.Errorf("error decoding JSON line %q: %w", line, err)
+		}
+		records = append(records, makeJSONRecord(string(line), obj))
+	}
+	return records, nil
+}
+
+func decodeJSON(data []byte, to interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(to); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after the JSON value")
+	}
+	return nil
+}
+
+func makeJSONRecord(message string, obj map[string]interface{}) record {
+	fields := common.MapStr(obj)
+	jsontransform.TransformNumbers(fields)
+	return record{message: message, fields: fields}
+}
+
+// csvParser parses an output of comma separated values, one record per line.
+type csvParser struct {
+	separator rune
+	columns   []string
+}
+
+func (p *csvParser) parse(output []byte) ([]record, error) {
+	r := csv.NewReader(bytes.NewReader(output))
+	r.Comma = p.separator
+	r.FieldsPerRecord = -1
+	r.ReuseRecord = true
+
+	columns := p.columns
+	var records []record
+	for line := 1; ; line++ {
+		values, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if columns == nil {
+			// The first record is the header.
+			columns = append([]string(nil), values...)
+			continue
+		}
+
+		fields := make(common.MapStr, len(values))
+		for i, v := range values {
+			if i >= len(columns) {
+				return nil, fmt.Errorf("record %d has %d values, but there are %d columns", line, len(values), len(columns))
+			}
+			fields[columns[i]] = v
+		}
+		records = append(records, record{message: joinCSV(values, p.separator), fields: fields})
+	}
+}
+
+// joinCSV encodes the values of a record as they were read.
+func joinCSV(values []string, separator rune) string {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = separator
+	w.Write(values)
+	w.Flush()
+	return string(bytes.TrimRight(buf.Bytes(), "\r\n"))
+}
+
+// regexParser parses the lines of the output matching a regular expression,
+// other lines are ignored.
+type regexParser struct {
+	re *regexp.Regexp
+}
+
+func (p *regexParser) parse(output []byte) ([]record, error) {
+	names := p.re.SubexpNames()
+
+	var records []record
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(nil, len(output)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		match := p.re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		fields := common.MapStr{}
+		for i, name := range names {
+			if name != "" {
+				fields[name] = match[i]
+			}
+		}
+		records = append(records, record{message: line, fields: fields})
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,164 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package exec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestParsers(t *testing.T) {
+	tests := map[string]struct {
+		config parserConfig
+		output string
+		want   []record
+	}{
+		"none": {
+			config: parserConfig{Type: parserNone},
+			output: "line 1\nline 2\n",
+			want:   []record{{message: "line 1\nline 2"}},
+		},
+		"json object": {
+			config: parserConfig{Type: parserJSON},
+			output: `{"a": 1, "b": {"c": "d"}}` + "\n",
+			want: []record{
+				{message: `{"a": 1, "b": {"c": "d"}}`, fields: common.MapStr{"a": int64(1), "b": map[string]interface{}{"c": "d"}}},
+			},
+		},
+		"json array": {
+			config: parserConfig{Type: parserJSON},
+			output: `[{"a": 1}, {"a": 2.5}]`,
+			want: []record{
+				{message: `{"a":1}`, fields: common.MapStr{"a": int64(1)}},
+				{message: `{"a":2.5}`, fields: common.MapStr{"a": 2.5}},
+			},
+		},
+		"json lines": {
+			config: parserConfig{Type: parserJSON},
+			output: "{\"a\": \"x\"}\n\n{\"a\": \"y\"}\n",
+			want: []record{
+				{message: `{"a": "x"}`, fields: common.MapStr{"a": "x"}},
+				{message: `{"a": "y"}`, fields: common.MapStr{"a": "y"}},
+			},
+		},
+		"json empty": {
+			config: parserConfig{Type: parserJSON},
+			output: "\n",
+		},
+		"csv with header": {
+			config: parserConfig{Type: parserCSV, Separator: ","},
+			output: "name,size\nfoo,1\n\"bar, baz\",2\n",
+			want: []record{
+				{message: "foo,1", fields: common.MapStr{"name": "foo", "size": "1"}},
+				{message: `"bar, baz",2`, fields: common.MapStr{"name": "bar, baz", "size": "2"}},
+			},
+		},
+		"csv with columns": {
+			config: parserConfig{Type: parserCSV, Separator: ";", Columns: []string{"name", "size"}},
+			output: "foo;1\nbar\n",
+			want: []record{
+				{message: "foo;1", fields: common.MapStr{"name": "foo", "size": "1"}},
+				{message: "bar", fields: common.MapStr{"name": "bar"}},
+			},
+		},
+		"regex": {
+			config: parserConfig{Type: parserRegex, Pattern: `^(?P<name>\S+)\s+(?P<size>\d+)$`},
+			output: "total 3\nheader\nfoo 1\nbar 2",
+			want: []record{
+				{message: "total 3", fields: common.MapStr{"name": "total", "size": "3"}},
+				{message: "foo 1", fields: common.MapStr{"name": "foo", "size": "1"}},
+				{message: "bar 2", fields: common.MapStr{"name": "bar", "size": "2"}},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			p, err := newParser(test.config)
+			require.NoError(t, err)
+
+			records, err := p.parse([]byte(test.output))
+			require.NoError(t, err)
+			assert.Equal(t, test.want, records)
+		})
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	tests := map[string]struct {
+		config parserConfig
+		output string
+	}{
+		"invalid json": {
+			config: parserConfig{Type: parserJSON},
+			output: "{\"a\": 1}\nnot json\n",
+		},
+		"json array of values": {
+			config: parserConfig{Type: parserJSON},
+			output: `[1, 2]`,
+		},
+		"csv with too many values": {
+			config: parserConfig{Type: parserCSV, Separator: ","},
+			output: "a,b\n1,2,3\n",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			p, err := newParser(test.config)
+			require.NoError(t, err)
+
+			_, err = p.parse([]byte(test.output))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParserConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		config parserConfig
+		valid  bool
+	}{
+		"none":                  {config: parserConfig{Type: parserNone}, valid: true},
+		"csv":                   {config: parserConfig{Type: parserCSV, Separator: "\t"}, valid: true},
+		"csv with long sep":     {config: parserConfig{Type: parserCSV, Separator: "::"}},
+		"regex":                 {config: parserConfig{Type: parserRegex, Pattern: `(?P<a>\d+)`}, valid: true},
+		"regex without pattern": {config: parserConfig{Type: parserRegex}},
+		"regex without names":   {config: parserConfig{Type: parserRegex, Pattern: `(\d+)`}},
+		"invalid regex":         {config: parserConfig{Type: parserRegex, Pattern: `(?P<a>`}},
+		"unknown":               {config: parserConfig{Type: "xml"}},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			err := test.config.Validate()
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -150,6 +150,41 @@ func (s *States) CleanupWith(fn func(string)) (int, int) {
 	return statesBefore - L, numCanExpire
 }
 
+// ExpireWith removes the finished states that have not been updated for longer
+// than maxAge, regardless of their TTL. It calls `fn` with the state ID, for
+// each entry to be removed. The number of removed states is returned.
+func (s *States) ExpireWith(maxAge time.Duration, fn func(string)) int {
+	s.Lock()
+	defer s.Unlock()
+
+	deadline := time.Now().Add(-maxAge)
+	statesBefore := len(s.states)
+
+	L := len(s.states)
+	for i := 0; i < L; {
+		state := &s.states[i]
+		if !state.Finished || !state.Timestamp.Before(deadline) {
+			i++
+			continue
+		}
+
+		delete(s.idx, state.Id)
+		if fn != nil {
+			fn(state.Id)
+		}
+		logp.Debug("state", "State removed for %v because it was not updated for %v", state.Source, maxAge)
+
+		L--
+		if L != i {
+			s.states[i] = s.states[L]
+			s.idx[s.states[i].Id] = i
+		}
+	}
+
+	s.states = s.states[:L]
+	return statesBefore - L
+}
+
 // Count returns number of states
 func (s *States) Count() int {
 	s.RLock()
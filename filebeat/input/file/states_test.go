@@ -86,3 +86,22 @@ func TestCleanup(t *testing.T) {
 		})
 	}
 }
+
+func TestExpireWith(t *testing.T) {
+	now := time.Now()
+	states := NewStates()
+	states.SetStates([]State{
+		{Id: "old", TTL: -1, Timestamp: now.Add(-2 * time.Hour), Finished: true},
+		{Id: "old-unfinished", TTL: -1, Timestamp: now.Add(-2 * time.Hour), Finished: false},
+		{Id: "recent", TTL: -1, Timestamp: now, Finished: true},
+	})
+
+	var removed []string
+	count := states.ExpireWith(time.Hour, func(id string) { removed = append(removed, id) })
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{"old"}, removed)
+	assert.Equal(t, 2, states.Count())
+	assert.True(t, states.IsNew(State{Id: "old"}))
+	assert.False(t, states.IsNew(State{Id: "recent"}))
+	assert.False(t, states.IsNew(State{Id: "old-unfinished"}))
+}
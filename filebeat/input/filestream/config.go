@@ -24,6 +24,7 @@ import (
 	"github.com/dustin/go-humanize"
 
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cfgtype"
 	"github.com/elastic/beats/v7/libbeat/common/match"
 	"github.com/elastic/beats/v7/libbeat/reader/readfile"
 )
@@ -40,6 +41,7 @@ type config struct {
 	CleanRemoved   bool                    `config:"clean_removed"`
 	HarvesterLimit uint32                  `config:"harvester_limit" validate:"min=0"`
 	IgnoreOlder    time.Duration           `config:"ignore_older"`
+	MemoryQuota    cfgtype.ByteSize        `config:"memory_quota" validate:"min=0"`
 }
 
 type closerConfig struct {
@@ -65,6 +67,7 @@ type readerConfig struct {
 	Encoding       string                  `config:"encoding"`
 	ExcludeLines   []match.Matcher         `config:"exclude_lines"`
 	IncludeLines   []match.Matcher         `config:"include_lines"`
+	IOBackend      string                  `config:"io_backend"`
 	LineTerminator readfile.LineTerminator `config:"line_terminator"`
 	MaxBytes       int                     `config:"message_max_bytes" validate:"min=0,nonzero"`
 	Tail           bool                    `config:"seek_to_tail"`
@@ -111,6 +114,7 @@ func defaultReaderConfig() readerConfig {
 			Max:  10 * time.Second,
 		},
 		BufferSize:     16 * humanize.KiByte,
+		IOBackend:      ioBackendStandard,
 		LineTerminator: readfile.AutoLineTerminator,
 		MaxBytes:       10 * humanize.MiByte,
 		Tail:           false,
@@ -122,6 +126,9 @@ func (c *config) Validate() error {
 	if len(c.Paths) == 0 {
 		return fmt.Errorf("no path is configured")
 	}
+	if c.IOBackend != ioBackendStandard && c.IOBackend != ioBackendUring {
+		return fmt.Errorf("unknown io_backend '%v', must be %v or %v", c.IOBackend, ioBackendStandard, ioBackendUring)
+	}
 	// TODO
 	//if c.CleanInactive != 0 && c.IgnoreOlder == 0 {
 	//	return fmt.Errorf("ignore_older must be enabled when clean_inactive is used")
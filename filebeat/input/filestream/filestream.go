@@ -22,11 +22,14 @@ import (
 	"errors"
 	"io"
 	"os"
+	"sync"
+	"syscall"
 	"time"
 
 	input "github.com/elastic/beats/v7/filebeat/input/v2"
 	"github.com/elastic/beats/v7/libbeat/common/backoff"
 	"github.com/elastic/beats/v7/libbeat/common/file"
+	"github.com/elastic/beats/v7/libbeat/common/file/uring"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/go-concert/ctxtool"
 	"github.com/elastic/go-concert/unison"
@@ -56,6 +59,11 @@ type logFile struct {
 	lastTimeRead time.Time
 	backoff      backoff.Backoff
 	tg           unison.TaskGroup
+
+	// ring reads the file if the io_uring backend is used, nil otherwise.
+	ring        *uring.Ring
+	rawConn     syscall.RawConn
+	releaseOnce sync.Once
 }
 
 // newFileReader creates a new log instance to read log sources
@@ -86,6 +94,15 @@ func newFileReader(
 		tg:                 unison.TaskGroup{},
 	}
 
+	if config.IOBackend == ioBackendUring {
+		if l.ring = acquireRing(log); l.ring != nil {
+			if l.rawConn, err = f.SyscallConn(); err != nil {
+				releaseRing()
+				return nil, err
+			}
+		}
+	}
+
 	l.ctx, l.cancelReading = ctxtool.WithFunc(ctxtool.FromCanceller(canceler), func() {
 		err := l.tg.Stop()
 		if err != nil {
@@ -104,7 +121,7 @@ func (f *logFile) Read(buf []byte) (int, error) {
 	totalN := 0
 
 	for f.ctx.Err() == nil {
-		n, err := f.file.Read(buf)
+		n, err := f.read(buf)
 		if n > 0 {
 			f.offset += int64(n)
 			f.lastTimeRead = time.Now()
@@ -137,6 +154,27 @@ func (f *logFile) Read(buf []byte) (int, error) {
 	return 0, ErrClosed
 }
 
+// read reads from the file at the current offset, through the ring if the
+// io_uring backend is used.
+func (f *logFile) read(buf []byte) (int, error) {
+	if f.ring == nil {
+		return f.file.Read(buf)
+	}
+
+	var n int
+	var readErr error
+	// The file descriptor can not be closed and reused by another file
+	// while the read is submitted.
+	err := f.rawConn.Read(func(fd uintptr) bool {
+		n, readErr = f.ring.ReadAt(int(fd), buf, f.offset)
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+	return n, readErr
+}
+
 func (f *logFile) startFileMonitoringIfNeeded() {
 	if f.closeInactive > 0 || f.closeRemoved || f.closeRenamed {
 		f.tg.Go(func(ctx unison.Canceler) error {
@@ -268,5 +306,8 @@ func (f *logFile) handleEOF() error {
 // Close
 func (f *logFile) Close() error {
 	f.cancelReading()
+	if f.ring != nil {
+		f.releaseOnce.Do(releaseRing)
+	}
 	return f.file.Close()
 }
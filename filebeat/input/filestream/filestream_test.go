@@ -110,6 +110,38 @@ func TestLogFileTruncated(t *testing.T) {
 	assert.Equal(t, ErrFileTruncate, err)
 }
 
+func TestLogFileUringBackend(t *testing.T) {
+	f := createTestLogFile()
+	defer f.Close()
+	defer os.Remove(f.Name())
+
+	reader, err := newFileReader(
+		logp.L(),
+		context.TODO(),
+		f,
+		readerConfig{IOBackend: ioBackendUring},
+		closerConfig{Reader: readerCloserConfig{OnEOF: true}},
+	)
+	if err != nil {
+		t.Fatalf("error while creating logReader: %+v", err)
+	}
+	if reader.ring == nil {
+		reader.Close()
+		t.Skip("io_uring is not supported")
+	}
+
+	buf := make([]byte, 1024)
+	n, err := reader.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "first log line\nanother interesting line\na third log message\n", string(buf[:n]))
+
+	_, err = reader.Read(buf)
+	assert.Equal(t, io.EOF, err)
+
+	assert.NoError(t, reader.Close())
+	assert.Nil(t, sharedRing.ring)
+}
+
 func createTestLogFile() *os.File {
 	f, err := ioutil.TempFile("", "filestream_reader_test")
 	if err != nil {
@@ -40,6 +40,7 @@ var (
 		nativeName:      newINodeDeviceIdentifier,
 		pathName:        newPathIdentifier,
 		inodeMarkerName: newINodeMarkerIdentifier,
+		fingerprintName: newFingerprintIdentifier,
 	}
 )
 
@@ -0,0 +1,136 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package filestream
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	loginp "github.com/elastic/beats/v7/filebeat/input/filestream/internal/input-logfile"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+const fingerprintName = "fingerprint"
+
+// fingerprintIdentifier identifies files by the hash of their first bytes,
+// instead of their inode and device id. The identity of a file is stable when
+// it is rotated, copied or moved to another device, and inodes being reused
+// after a file is removed do not prevent to read the new file.
+//
+// Files smaller than the fingerprint length can not be identified yet, they
+// are ignored until they are large enough.
+type fingerprintIdentifier struct {
+	log    *logp.Logger
+	name   string
+	offset int64
+	length int64
+
+	// fingerprints holds the last fingerprint computed for each path, to
+	// identify the files that are removed or renamed.
+	mu           sync.Mutex
+	fingerprints map[string]string
+}
+
+func newFingerprintIdentifier(cfg *common.Config) (fileIdentifier, error) {
+	config := struct {
+		Offset int64 `config:"offset" validate:"min=0"`
+		Length int64 `config:"length" validate:"min=64"`
+	}{
+		Length: 1024,
+	}
+	if cfg != nil {
+		if err := cfg.Unpack(&config); err != nil {
+			return nil, fmt.Errorf("error while reading configuration of fingerprint file identity: %v", err)
+		}
+	}
+
+	return &fingerprintIdentifier{
+		log:          logp.NewLogger("fingerprint_identifier"),
+		name:         fingerprintName,
+		offset:       config.Offset,
+		length:       config.Length,
+		fingerprints: map[string]string{},
+	}, nil
+}
+
+// GetSource returns the source of the file of the event. The name of the
+// source is empty if the file can not be identified.
+func (i *fingerprintIdentifier) GetSource(e loginp.FSEvent) fileSource {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var fingerprint string
+	switch e.Op {
+	case loginp.OpDelete:
+		fingerprint = i.fingerprints[e.OldPath]
+		delete(i.fingerprints, e.OldPath)
+	default:
+		if e.Op == loginp.OpRename {
+			delete(i.fingerprints, e.OldPath)
+		}
+
+		var err error
+		fingerprint, err = i.fingerprint(e.NewPath)
+		if err != nil {
+			i.log.Debugf("Failed to compute the fingerprint of %s: %v", e.NewPath, err)
+			delete(i.fingerprints, e.NewPath)
+		} else {
+			i.fingerprints[e.NewPath] = fingerprint
+		}
+	}
+
+	src := fileSource{
+		info:                e.Info,
+		newPath:             e.NewPath,
+		oldPath:             e.OldPath,
+		identifierGenerator: i.name,
+	}
+	if fingerprint != "" {
+		src.name = pluginName + identitySep + i.name + identitySep + fingerprint
+	}
+	return src
+}
+
+// fingerprint returns the hex encoded SHA-256 hash of length bytes of the
+// file, starting at offset.
+func (i *fingerprintIdentifier) fingerprint(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, io.NewSectionReader(f, i.offset, i.length))
+	if err != nil {
+		return "", err
+	}
+	if n < i.length {
+		return "", fmt.Errorf("the file is smaller than the fingerprint length of %d bytes", i.offset+i.length)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (i *fingerprintIdentifier) Name() string {
+	return i.name
+}
@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package filestream
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	loginp "github.com/elastic/beats/v7/filebeat/input/filestream/internal/input-logfile"
+	input "github.com/elastic/beats/v7/filebeat/input/v2"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+func TestFingerprintIdentifier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestream_fingerprint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	content := bytes.Repeat([]byte("line of the log file\n"), 100)
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, data, 0600))
+		return path
+	}
+	log := write("app.log", content)
+	copied := write("copy.log", content)
+	appended := write("appended.log", append(append([]byte{}, content...), "new line\n"...))
+	other := write("other.log", bytes.Repeat([]byte("other line of the log file\n"), 100))
+	small := write("small.log", []byte("too small\n"))
+
+	identifier, err := newFingerprintIdentifier(common.MustNewConfigFrom(map[string]interface{}{
+		"length": 256,
+	}))
+	require.NoError(t, err)
+	name := func(e loginp.FSEvent) string { return identifier.GetSource(e).Name() }
+
+	logName := name(loginp.FSEvent{Op: loginp.OpCreate, NewPath: log})
+	assert.Regexp(t, "^filestream::fingerprint::[0-9a-f]{64}$", logName)
+	assert.Equal(t, logName, name(loginp.FSEvent{Op: loginp.OpCreate, NewPath: copied}))
+	assert.Equal(t, logName, name(loginp.FSEvent{Op: loginp.OpWrite, NewPath: appended}))
+	assert.NotEqual(t, logName, name(loginp.FSEvent{Op: loginp.OpCreate, NewPath: other}))
+	assert.Equal(t, "", name(loginp.FSEvent{Op: loginp.OpCreate, NewPath: small}))
+
+	// rotated files keep their identity
+	rotated := filepath.Join(dir, "app.log.1")
+	require.NoError(t, os.Rename(log, rotated))
+	assert.Equal(t, logName, name(loginp.FSEvent{Op: loginp.OpRename, OldPath: log, NewPath: rotated}))
+
+	// removed files are identified by their last fingerprint
+	require.NoError(t, os.Remove(rotated))
+	assert.Equal(t, logName, name(loginp.FSEvent{Op: loginp.OpDelete, OldPath: rotated}))
+	assert.Equal(t, "", name(loginp.FSEvent{Op: loginp.OpDelete, OldPath: rotated}))
+}
+
+func TestFingerprintIdentifierOffset(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestream_fingerprint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// the files only differ in their header
+	body := bytes.Repeat([]byte("line of the log file\n"), 100)
+	first := filepath.Join(dir, "first.log")
+	second := filepath.Join(dir, "second.log")
+	require.NoError(t, ioutil.WriteFile(first, append([]byte("header 1\n"), body...), 0600))
+	require.NoError(t, ioutil.WriteFile(second, append([]byte("header 2\n"), body...), 0600))
+
+	for offset, same := range map[int]bool{0: false, 9: true} {
+		identifier, err := newFingerprintIdentifier(common.MustNewConfigFrom(map[string]interface{}{
+			"offset": offset,
+			"length": 1024,
+		}))
+		require.NoError(t, err)
+		firstName := identifier.GetSource(loginp.FSEvent{Op: loginp.OpCreate, NewPath: first}).Name()
+		secondName := identifier.GetSource(loginp.FSEvent{Op: loginp.OpCreate, NewPath: second}).Name()
+		assert.Equal(t, same, firstName == secondName, "offset %d", offset)
+	}
+}
+
+func TestProspectorFingerprintIdentity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestream_fingerprint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	log := filepath.Join(dir, "app.log")
+	small := filepath.Join(dir, "small.log")
+	require.NoError(t, ioutil.WriteFile(log, bytes.Repeat([]byte("line of the log file\n"), 100), 0600))
+	require.NoError(t, ioutil.WriteFile(small, []byte("too small\n"), 0600))
+
+	identifier, err := newFingerprintIdentifier(nil)
+	require.NoError(t, err)
+	p := fileProspector{
+		filewatcher: &mockFileWatcher{events: []loginp.FSEvent{
+			{Op: loginp.OpCreate, NewPath: log},
+			{Op: loginp.OpCreate, NewPath: small},
+			{Op: loginp.OpDelete, OldPath: small},
+		}},
+		identifier:   identifier,
+		cleanRemoved: true,
+	}
+	ctx := input.Context{Logger: logp.L(), Cancelation: context.Background()}
+	hg := getTestHarvesterGroup()
+
+	p.Run(ctx, testStateStore(), hg)
+
+	// the file smaller than the fingerprint length is not harvested
+	require.Len(t, hg.encounteredNames, 1)
+	assert.Regexp(t, "^filestream::fingerprint::", hg.encounteredNames[0])
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/common/match"
 	"github.com/elastic/beats/v7/libbeat/feature"
 	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/memgov"
 	"github.com/elastic/beats/v7/libbeat/reader"
 	"github.com/elastic/beats/v7/libbeat/reader/debug"
 	"github.com/elastic/beats/v7/libbeat/reader/readfile"
@@ -59,6 +60,10 @@ type filestream struct {
 	includeLines    []match.Matcher
 	maxBytes        int
 	closerConfig    closerConfig
+
+	// budget limits the memory used by the buffers of the harvesters of the
+	// input.
+	budget *memgov.Budget
 }
 
 // Plugin creates a new filestream input plugin for creating a stateful input.
@@ -108,6 +113,7 @@ func configure(cfg *common.Config) (loginp.Prospector, loginp.Harvester, error)
 		includeLines:    config.IncludeLines,
 		maxBytes:        config.MaxBytes,
 		closerConfig:    config.Close,
+		budget:          memgov.Get(memgov.HarvestersBudget).Child(pluginName, int64(config.MemoryQuota)),
 	}, nil
 }
 
@@ -135,6 +141,17 @@ func (inp *filestream) Run(
 	log := ctx.Logger.With("path", fs.newPath).With("state-id", src.Name())
 	state := initState(log, cursor, fs)
 
+	if inp.budget.Active() {
+		reserved := int64(inp.bufferSize)
+		if !inp.budget.TryReserve(reserved) {
+			log.Infof("Waiting for the memory budget of the harvesters to open the file")
+			if !inp.budget.Reserve(ctx.Cancelation.Done(), reserved) {
+				return nil
+			}
+		}
+		defer inp.budget.Release(reserved)
+	}
+
 	r, err := inp.open(log, ctx.Cancelation, state)
 	if err != nil {
 		log.Errorf("File could not be opened for reading: %v", err)
@@ -343,12 +360,12 @@ func matchAny(matchers []match.Matcher, text string) bool {
 }
 
 func (inp *filestream) eventFromMessage(m reader.Message, path string) beat.Event {
-	fields := common.MapStr{
-		"log": common.MapStr{
-			"offset": m.Bytes, // Offset here is the offset before the starting char.
-			"file": common.MapStr{
-				"path": path,
-			},
+	// The fields are released by the pipeline once the event is acknowledged.
+	fields := common.GetMapStr()
+	fields["log"] = common.MapStr{
+		"offset": m.Bytes, // Offset here is the offset before the starting char.
+		"file": common.MapStr{
+			"path": path,
 		},
 	}
 	fields.DeepUpdate(m.Fields)
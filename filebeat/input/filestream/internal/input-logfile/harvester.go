@@ -81,8 +81,10 @@ func (hg *defaultHarvesterGroup) Run(ctx input.Context, s Source) error {
 	hg.store.UpdateTTL(resource, hg.cleanTimeout)
 
 	client, err := hg.pipeline.ConnectWith(beat.ClientConfig{
-		CloseRef:   ctx.Cancelation,
-		ACKHandler: newInputACKHandler(ctx.Logger),
+		CloseRef:      ctx.Cancelation,
+		ACKHandler:    newInputACKHandler(ctx.Logger),
+		RecycleFields: true,
+		ShardKey:      ctx.ID,
 	})
 	if err != nil {
 		cancelHarvester()
@@ -107,6 +107,11 @@ func (p *fileProspector) Run(ctx input.Context, s *statestore.Store, hg loginp.H
 					log.Debugf("File %s has been updated", fe.NewPath)
 				}
 
+				if src.Name() == "" {
+					log.Debugf("Ignore file because it can not be identified yet. File %s", fe.NewPath)
+					break
+				}
+
 				if p.ignoreOlder > 0 {
 					now := time.Now()
 					if now.Sub(fe.Info.ModTime()) > p.ignoreOlder {
@@ -120,7 +125,7 @@ func (p *fileProspector) Run(ctx input.Context, s *statestore.Store, hg loginp.H
 			case loginp.OpDelete:
 				log.Debugf("File %s has been removed", fe.OldPath)
 
-				if p.cleanRemoved {
+				if p.cleanRemoved && src.Name() != "" {
 					log.Debugf("Remove state for file as file removed: %s", fe.OldPath)
 
 					err := s.Remove(src.Name())
@@ -191,6 +196,9 @@ func (p *fileProspector) updateIdentifiersBetweenRuns(log *logp.Logger, s *state
 			return true, nil
 		}
 		newKey := p.identifier.GetSource(loginp.FSEvent{NewPath: st.Source, Info: fi}).Name()
+		if newKey == "" {
+			return true, nil
+		}
 		st.IdentifierName = p.identifier.Name()
 
 		err = s.Set(newKey, st)
@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package filestream
+
+import (
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/common/file/uring"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+const (
+	// ioBackendStandard reads the files with a read system call per read.
+	ioBackendStandard = "standard"
+
+	// ioBackendUring reads the files of all harvesters through a shared
+	// io_uring ring, batching the reads in a single system call.
+	ioBackendUring = "io_uring"
+)
+
+// ringEntries is the maximum number of reads in flight in the shared ring,
+// the reads requested when it is full are submitted in the next batch.
+const ringEntries = 256
+
+// sharedRing is the ring used by all harvesters configured with the io_uring
+// backend. It is created by the first harvester using it, and closed when the
+// last one is closed.
+var sharedRing struct {
+	sync.Mutex
+	ring *uring.Ring
+	refs int
+
+	// warned is set once the failure to create the ring has been reported.
+	warned bool
+}
+
+// acquireRing returns the shared ring, or nil if io_uring can not be used,
+// in which case the files must be read with the standard backend. Every
+// ring returned must be released with releaseRing.
+func acquireRing(log *logp.Logger) *uring.Ring {
+	sharedRing.Lock()
+	defer sharedRing.Unlock()
+
+	if sharedRing.ring == nil {
+		ring, err := uring.New(ringEntries)
+		if err != nil {
+			if !sharedRing.warned {
+				log.Warnf("Failed to create the io_uring ring, files are read with the %v backend: %v", ioBackendStandard, err)
+				sharedRing.warned = true
+			}
+			return nil
+		}
+		sharedRing.ring = ring
+	}
+	sharedRing.refs++
+	return sharedRing.ring
+}
+
+func releaseRing() {
+	sharedRing.Lock()
+	defer sharedRing.Unlock()
+
+	sharedRing.refs--
+	if sharedRing.refs == 0 {
+		sharedRing.ring.Close()
+		sharedRing.ring = nil
+	}
+}
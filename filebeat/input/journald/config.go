@@ -50,6 +50,21 @@ type config struct {
 
 	// SaveRemoteHostname defines if the original source of the entry needs to be saved.
 	SaveRemoteHostname bool `config:"save_remote_hostname"`
+
+	// Journalbeat configures the import of the positions of Journalbeat.
+	Journalbeat journalbeatConfig `config:"journalbeat"`
+}
+
+// journalbeatConfig configures where to find the positions of Journalbeat in
+// the journals, so the input continues reading where Journalbeat stopped.
+// The positions are imported only if the input has no state.
+type journalbeatConfig struct {
+	// RegistryFile is the path to the registry file of Journalbeat. Relative
+	// paths are resolved against the data path.
+	RegistryFile string `config:"registry_file"`
+
+	// ID is the ID of the Journalbeat input that read the journals.
+	ID string `config:"id"`
 }
 
 var errInvalidSeekFallback = errors.New("invalid setting for cursor_seek_fallback")
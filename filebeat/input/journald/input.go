@@ -20,6 +20,7 @@
 package journald
 
 import (
+	"os"
 	"time"
 
 	"github.com/coreos/go-systemd/v22/sdjournal"
@@ -27,12 +28,14 @@ import (
 
 	input "github.com/elastic/beats/v7/filebeat/input/v2"
 	cursor "github.com/elastic/beats/v7/filebeat/input/v2/input-cursor"
+	jbcheckpoint "github.com/elastic/beats/v7/journalbeat/checkpoint"
 	"github.com/elastic/beats/v7/journalbeat/pkg/journalfield"
 	"github.com/elastic/beats/v7/journalbeat/pkg/journalread"
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/backoff"
 	"github.com/elastic/beats/v7/libbeat/feature"
 	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/paths"
 )
 
 type journald struct {
@@ -42,6 +45,10 @@ type journald struct {
 	CursorSeekFallback journalread.SeekMode
 	Matches            []journalfield.Matcher
 	SaveRemoteHostname bool
+
+	// journalbeatStates are the positions of Journalbeat in the journals,
+	// indexed by path.
+	journalbeatStates map[string]jbcheckpoint.JournalState
 }
 
 type checkpoint struct {
@@ -95,6 +102,11 @@ func configure(cfg *common.Config) ([]cursor.Source, cursor.Input, error) {
 		sources[i] = pathSource(p)
 	}
 
+	journalbeatStates, err := readJournalbeatStates(config.Journalbeat, paths)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	return sources, &journald{
 		Backoff:            config.Backoff,
 		MaxBackoff:         config.MaxBackoff,
@@ -102,9 +114,37 @@ func configure(cfg *common.Config) ([]cursor.Source, cursor.Input, error) {
 		CursorSeekFallback: config.CursorSeekFallback,
 		Matches:            config.Matches,
 		SaveRemoteHostname: config.SaveRemoteHostname,
+		journalbeatStates:  journalbeatStates,
 	}, nil
 }
 
+// readJournalbeatStates reads the positions of Journalbeat in the journals
+// read by the input. The states are stored with the same ID as Journalbeat
+// computes for its readers.
+func readJournalbeatStates(config journalbeatConfig, journals []string) (map[string]jbcheckpoint.JournalState, error) {
+	if config.RegistryFile == "" {
+		return nil, nil
+	}
+
+	file := paths.Resolve(paths.Data, config.RegistryFile)
+	states, err := jbcheckpoint.ReadStates(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logp.NewLogger(pluginName).Warnf("Journalbeat registry file %s not found, no positions are imported", file)
+			return nil, nil
+		}
+		return nil, sderr.Wrap(err, "failed to read Journalbeat registry file %{file}", file)
+	}
+
+	imported := map[string]jbcheckpoint.JournalState{}
+	for _, path := range journals {
+		if state, ok := states[jbcheckpoint.StateID(config.ID, path)]; ok {
+			imported[path] = state
+		}
+	}
+	return imported, nil
+}
+
 func (inp *journald) Name() string { return pluginName }
 
 func (inp *journald) Test(src cursor.Source, ctx input.TestContext) error {
@@ -123,6 +163,12 @@ func (inp *journald) Run(
 ) error {
 	log := ctx.Logger.With("path", src.Name())
 	checkpoint := initCheckpoint(log, cursor)
+	if state, ok := inp.journalbeatStates[src.Name()]; ok && cursor.IsNew() {
+		log.Infof("Continue from the position of Journalbeat at %v.", time.Unix(0, int64(state.RealtimeTimestamp)*1000))
+		checkpoint.Position = state.Cursor
+		checkpoint.RealtimeTimestamp = state.RealtimeTimestamp
+		checkpoint.MonotonicTimestamp = state.MonotonicTimestamp
+	}
 
 	reader, err := inp.open(ctx.Logger, ctx.Cancelation, src)
 	if err != nil {
@@ -26,6 +26,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/common/cfgwarn"
 	"github.com/elastic/beats/v7/libbeat/common/kafka"
+	"github.com/elastic/beats/v7/libbeat/common/match"
 	"github.com/elastic/beats/v7/libbeat/common/transport/kerberos"
 	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
 	"github.com/elastic/beats/v7/libbeat/logp"
@@ -36,7 +37,9 @@ import (
 type kafkaInputConfig struct {
 	// Kafka hosts with port, e.g. "localhost:9092"
 	Hosts                    []string          `config:"hosts" validate:"required"`
-	Topics                   []string          `config:"topics" validate:"required"`
+	Topics                   []string          `config:"topics"`
+	TopicsPattern            *match.Matcher    `config:"topics_pattern"`
+	TopicsRefreshInterval    time.Duration     `config:"topics_refresh_interval" validate:"positive,nonzero"`
 	GroupID                  string            `config:"group_id" validate:"required"`
 	ClientID                 string            `config:"client_id"`
 	Version                  kafka.Version     `config:"version"`
@@ -53,6 +56,13 @@ type kafkaInputConfig struct {
 	Username                 string            `config:"username"`
 	Password                 string            `config:"password"`
 	ExpandEventListFromField string            `config:"expand_event_list_from_field"`
+	HeadersMapping           []headerMapping   `config:"headers_mapping"`
+}
+
+// headerMapping copies the value of a record header to a field of the events.
+type headerMapping struct {
+	Header string `config:"header" validate:"required"`
+	Field  string `config:"field" validate:"required"`
 }
 
 type kafkaFetch struct {
@@ -127,6 +137,7 @@ func defaultConfig() kafkaInputConfig {
 			MaxRetries:   4,
 			RetryBackoff: 2 * time.Second,
 		},
+		TopicsRefreshInterval: time.Minute,
 	}
 }
 
@@ -136,6 +147,13 @@ func (c *kafkaInputConfig) Validate() error {
 		return errors.New("no hosts configured")
 	}
 
+	if len(c.Topics) == 0 && c.TopicsPattern == nil {
+		return errors.New("either topics or topics_pattern must be set")
+	}
+	if len(c.Topics) > 0 && c.TopicsPattern != nil {
+		return errors.New("topics and topics_pattern can't be used together")
+	}
+
 	if err := c.Version.Validate(); err != nil {
 		return err
 	}
@@ -21,6 +21,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -34,6 +35,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/common/acker"
 	"github.com/elastic/beats/v7/libbeat/common/backoff"
 	"github.com/elastic/beats/v7/libbeat/common/kafka"
+	"github.com/elastic/beats/v7/libbeat/common/match"
 	"github.com/elastic/beats/v7/libbeat/logp"
 
 	"github.com/pkg/errors"
@@ -103,19 +105,21 @@ func NewInput(
 }
 
 func (input *kafkaInput) runConsumerGroup(
-	context context.Context, consumerGroup sarama.ConsumerGroup,
+	ctx context.Context, client sarama.Client, consumerGroup sarama.ConsumerGroup,
 ) {
 	handler := &groupHandler{
 		version: input.config.Version,
 		outlet:  input.outlet,
 		// expandEventListFromField will be assigned the configuration option expand_event_list_from_field
 		expandEventListFromField: input.config.ExpandEventListFromField,
+		headersMapping:           input.config.HeadersMapping,
 		log:                      input.log,
 	}
 
 	input.saramaWaitGroup.Add(1)
 	defer func() {
 		consumerGroup.Close()
+		client.Close()
 		input.saramaWaitGroup.Done()
 	}()
 
@@ -126,12 +130,99 @@ func (input *kafkaInput) runConsumerGroup(
 		}
 	}()
 
-	err := consumerGroup.Consume(context, input.config.Topics, handler)
+	topics := input.config.Topics
+	if input.config.TopicsPattern != nil {
+		var err error
+		topics, err = matchingTopics(client, input.config.TopicsPattern)
+		if err != nil || len(topics) == 0 {
+			if err != nil {
+				input.log.Errorw("Error listing kafka topics", "error", err)
+			} else {
+				input.log.Warnw("No kafka topics match topics_pattern")
+			}
+			// Wait for the next listing before connecting again.
+			select {
+			case <-ctx.Done():
+			case <-time.After(input.config.TopicsRefreshInterval):
+			}
+			return
+		}
+
+		// Consume stops when the matching topics change, the consumer group is
+		// then created again with the new topics.
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go input.watchTopics(ctx, cancel, client, topics)
+	}
+
+	err := consumerGroup.Consume(ctx, topics, handler)
 	if err != nil {
 		input.log.Errorw("Kafka consume error", "error", err)
 	}
 }
 
+// watchTopics calls cancel when the topics matching topics_pattern are
+// different from topics.
+func (input *kafkaInput) watchTopics(
+	ctx context.Context, cancel context.CancelFunc, client sarama.Client, topics []string,
+) {
+	ticker := time.NewTicker(input.config.TopicsRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := matchingTopics(client, input.config.TopicsPattern)
+		if err != nil {
+			input.log.Warnw("Error listing kafka topics", "error", err)
+			continue
+		}
+		if !equalTopics(topics, current) {
+			input.log.Infow("Kafka topics matching topics_pattern changed", "topics", current)
+			cancel()
+			return
+		}
+	}
+}
+
+// matchingTopics returns the sorted list of the topics of the cluster matching
+// pattern, the internal topics of Kafka are ignored.
+func matchingTopics(client sarama.Client, pattern *match.Matcher) ([]string, error) {
+	if err := client.RefreshMetadata(); err != nil {
+		return nil, err
+	}
+	all, err := client.Topics()
+	if err != nil {
+		return nil, err
+	}
+
+	var topics []string
+	for _, topic := range all {
+		if !strings.HasPrefix(topic, "__") && pattern.MatchString(topic) {
+			topics = append(topics, topic)
+		}
+	}
+	sort.Strings(topics)
+	return topics, nil
+}
+
+func equalTopics(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // Run starts the input by scanning for incoming messages and errors.
 func (input *kafkaInput) Run() {
 	input.runOnce.Do(func() {
@@ -148,10 +239,19 @@ func (input *kafkaInput) Run() {
 				8*input.config.ConnectBackoff)
 
 			for context.Err() == nil {
-				// Connect to Kafka with a new consumer group.
-				consumerGroup, err := sarama.NewConsumerGroup(
-					input.config.Hosts, input.config.GroupID, input.saramaConfig)
+				// Connect to Kafka with a new consumer group. The client is also
+				// used to list the topics matching topics_pattern.
+				client, err := sarama.NewClient(input.config.Hosts, input.saramaConfig)
+				if err != nil {
+					input.log.Errorw(
+						"Error initializing kafka client", "error", err)
+					backoff.Wait()
+					continue
+				}
+				consumerGroup, err := sarama.NewConsumerGroupFromClient(
+					input.config.GroupID, client)
 				if err != nil {
+					client.Close()
 					input.log.Errorw(
 						"Error initializing kafka consumer group", "error", err)
 					backoff.Wait()
@@ -165,7 +265,7 @@ func (input *kafkaInput) Run() {
 				// In an ideal run, this function never returns until shutdown; if it
 				// does, it means the errors have been logged and the consumer group
 				// has been closed, so we try creating a new one in the next iteration.
-				input.runConsumerGroup(context, consumerGroup)
+				input.runConsumerGroup(context, client, consumerGroup)
 			}
 		}()
 	})
@@ -241,6 +341,7 @@ type groupHandler struct {
 	// if the fileset using this input expects to receive multiple messages bundled under a specific field then this value is assigned
 	// ex. in this case are the azure fielsets where the events are found under the json object "records"
 	expandEventListFromField string
+	headersMapping           []headerMapping
 	log                      *logp.Logger
 }
 
@@ -267,6 +368,7 @@ func (h *groupHandler) createEvents(
 	version, versionOk := h.version.Get()
 	if versionOk && version.IsAtLeast(sarama.V0_10_0_0) {
 		timestamp = message.Timestamp
+		kafkaFields["timestamp"] = message.Timestamp
 		if !message.BlockTimestamp.IsZero() {
 			kafkaFields["block_timestamp"] = message.BlockTimestamp
 		}
@@ -290,17 +392,45 @@ func (h *groupHandler) createEvents(
 				"message": msg,
 				"kafka":   kafkaFields,
 			},
-			Private: eventMeta{
-				handler: h,
-				message: message,
-			},
 		}
+		h.mapHeaders(event.Fields, message.Headers)
 		events = append(events, event)
 
 	}
+
+	// The events are acknowledged in order, so the message is marked as
+	// consumed once its last event is acknowledged. A message without events
+	// is marked along with the next message.
+	if len(events) > 0 {
+		events[len(events)-1].Private = eventMeta{
+			handler: h,
+			message: message,
+		}
+	}
 	return events
 }
 
+// mapHeaders copies the values of the headers configured in headers_mapping to
+// their fields. The values of a header present several times are stored in an
+// array.
+func (h *groupHandler) mapHeaders(fields common.MapStr, headers []*sarama.RecordHeader) {
+	for _, mapping := range h.headersMapping {
+		var values []string
+		for _, header := range headers {
+			if header != nil && string(header.Key) == mapping.Header {
+				values = append(values, string(header.Value))
+			}
+		}
+		switch len(values) {
+		case 0:
+		case 1:
+			fields.Put(mapping.Field, values[0])
+		default:
+			fields.Put(mapping.Field, values)
+		}
+	}
+}
+
 func (h *groupHandler) Setup(session sarama.ConsumerGroupSession) error {
 	h.Lock()
 	h.session = session
@@ -0,0 +1,163 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/kafka"
+	"github.com/elastic/beats/v7/libbeat/common/match"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+type testClaim struct {
+	sarama.ConsumerGroupClaim
+	topic     string
+	partition int32
+}
+
+func (c testClaim) Topic() string    { return c.topic }
+func (c testClaim) Partition() int32 { return c.partition }
+
+type testClient struct {
+	sarama.Client
+	topics []string
+}
+
+func (c *testClient) RefreshMetadata(...string) error { return nil }
+func (c *testClient) Topics() ([]string, error)       { return c.topics, nil }
+
+func TestCreateEvents(t *testing.T) {
+	handler := &groupHandler{
+		version: kafka.Version("1.0.0"),
+		headersMapping: []headerMapping{
+			{Header: "trace-id", Field: "trace.id"},
+			{Header: "tag", Field: "tags"},
+			{Header: "missing", Field: "missing"},
+		},
+		log: logp.NewLogger("test"),
+	}
+	timestamp := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	message := &sarama.ConsumerMessage{
+		Key:       []byte("key"),
+		Value:     []byte("hello"),
+		Offset:    42,
+		Timestamp: timestamp,
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("trace-id"), Value: []byte("abc")},
+			{Key: []byte("tag"), Value: []byte("a")},
+			{Key: []byte("tag"), Value: []byte("b")},
+		},
+	}
+
+	events := handler.createEvents(nil, testClaim{topic: "logs", partition: 3}, message)
+	require.Len(t, events, 1)
+	event := events[0]
+	assert.Equal(t, timestamp, event.Timestamp)
+	assert.Equal(t, common.MapStr{
+		"message": "hello",
+		"kafka": common.MapStr{
+			"topic":     "logs",
+			"partition": int32(3),
+			"offset":    int64(42),
+			"key":       "key",
+			"timestamp": timestamp,
+			"headers":   []string{"trace-id: abc", "tag: a", "tag: b"},
+		},
+		"trace": common.MapStr{"id": "abc"},
+		"tags":  []string{"a", "b"},
+	}, event.Fields)
+	assert.Equal(t, eventMeta{handler: handler, message: message}, event.Private)
+}
+
+func TestCreateEventsExpandedACK(t *testing.T) {
+	handler := &groupHandler{
+		version:                  kafka.Version("1.0.0"),
+		expandEventListFromField: "records",
+		log:                      logp.NewLogger("test"),
+	}
+	message := &sarama.ConsumerMessage{Value: []byte(`{"records": [{"id": 1}, {"id": 2}, {"id": 3}]}`)}
+
+	// The message is marked as consumed when its last event is acknowledged.
+	events := handler.createEvents(nil, testClaim{topic: "logs"}, message)
+	require.Len(t, events, 3)
+	assert.Nil(t, events[0].Private)
+	assert.Nil(t, events[1].Private)
+	assert.Equal(t, eventMeta{handler: handler, message: message}, events[2].Private)
+
+	events = handler.createEvents(nil, testClaim{topic: "logs"}, &sarama.ConsumerMessage{Value: []byte(`not json`)})
+	assert.Empty(t, events)
+}
+
+func TestMatchingTopics(t *testing.T) {
+	client := &testClient{topics: []string{"logs-b", "metrics", "logs-a", "__consumer_offsets"}}
+	pattern := match.MustCompile("^(logs-.*|__.*)$")
+
+	topics, err := matchingTopics(client, &pattern)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"logs-a", "logs-b"}, topics)
+
+	assert.True(t, equalTopics(topics, []string{"logs-a", "logs-b"}))
+	assert.False(t, equalTopics(topics, []string{"logs-a"}))
+	assert.False(t, equalTopics(topics, []string{"logs-a", "logs-c"}))
+}
+
+func TestConfigTopics(t *testing.T) {
+	tests := map[string]struct {
+		config  common.MapStr
+		wantErr string
+	}{
+		"topics": {
+			config: common.MapStr{"topics": []string{"logs"}},
+		},
+		"topics_pattern": {
+			config: common.MapStr{"topics_pattern": "^logs-"},
+		},
+		"missing topics": {
+			config:  common.MapStr{},
+			wantErr: "either topics or topics_pattern must be set",
+		},
+		"topics and topics_pattern": {
+			config:  common.MapStr{"topics": []string{"logs"}, "topics_pattern": "^logs-"},
+			wantErr: "topics and topics_pattern can't be used together",
+		},
+		"headers_mapping without field": {
+			config:  common.MapStr{"topics": []string{"logs"}, "headers_mapping": []common.MapStr{{"header": "trace-id"}}},
+			wantErr: "field",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test.config.Update(common.MapStr{"hosts": []string{"localhost:9092"}, "group_id": "filebeat"})
+			config := defaultConfig()
+			err := common.MustNewConfigFrom(test.config).Unpack(&config)
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.wantErr)
+		})
+	}
+}
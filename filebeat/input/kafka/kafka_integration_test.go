@@ -256,6 +256,83 @@ func TestInputWithMultipleEvents(t *testing.T) {
 	}
 }
 
+func TestInputWithTopicsPattern(t *testing.T) {
+	id := strconv.Itoa(rand.New(rand.NewSource(int64(time.Now().Nanosecond()))).Int())
+	topicPrefix := fmt.Sprintf("Filebeat-TestInputWithTopicsPattern-%s", id)
+	context := input.Context{
+		Done:     make(chan struct{}),
+		BeatDone: make(chan struct{}),
+	}
+
+	// Send test messages to two topics matching the pattern.
+	headers := []sarama.RecordHeader{
+		recordHeader("X-Test-Header", "test header value"),
+	}
+	writeToKafkaTopic(t, topicPrefix+"-a", "first", headers, time.Second*20)
+	writeToKafkaTopic(t, topicPrefix+"-b", "second", headers, time.Second*20)
+
+	// Setup the input config
+	config := common.MustNewConfigFrom(common.MapStr{
+		"hosts":          getTestKafkaHost(),
+		"topics_pattern": "^" + topicPrefix + "-",
+		"group_id":       "filebeat",
+		"wait_close":     0,
+		"headers_mapping": []common.MapStr{
+			{"header": "X-Test-Header", "field": "test.header"},
+		},
+	})
+
+	// Route input events through our capturer instead of sending through ES.
+	events := make(chan beat.Event, 100)
+	defer close(events)
+	capturer := NewEventCapturer(events)
+	defer capturer.Close()
+	connector := channel.ConnectorFunc(func(_ *common.Config, _ beat.ClientConfig) (channel.Outleter, error) {
+		return channel.SubOutlet(capturer), nil
+	})
+
+	input, err := NewInput(config, connector, context)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Run the input and wait for finalization
+	input.Run()
+
+	timeout := time.After(30 * time.Second)
+	var texts []string
+	for len(texts) < 2 {
+		select {
+		case event := <-events:
+			text, err := event.Fields.GetValue("message")
+			if err != nil {
+				t.Fatal(err)
+			}
+			texts = append(texts, text.(string))
+			header, _ := event.Fields.GetValue("test.header")
+			assert.Equal(t, "test header value", header)
+		case <-timeout:
+			t.Fatal("timeout waiting for incoming events")
+		}
+	}
+	assert.ElementsMatch(t, []string{"first", "second"}, texts)
+
+	// Close the done channel and make sure the beat shuts down in a reasonable
+	// amount of time.
+	close(context.Done)
+	didClose := make(chan struct{})
+	go func() {
+		input.Wait()
+		close(didClose)
+	}()
+
+	select {
+	case <-time.After(30 * time.Second):
+		t.Fatal("timeout waiting for beat to shut down")
+	case <-didClose:
+	}
+}
+
 func findMessage(t *testing.T, text string, msgs []testMessage) *testMessage {
 	var msg *testMessage
 	for _, m := range msgs {
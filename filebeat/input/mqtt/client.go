@@ -31,6 +31,10 @@ func createClientOptions(config mqttInputConfig, onConnectHandler func(client li
 		SetConnectRetry(true).
 		SetOnConnectHandler(onConnectHandler)
 
+	// SetProtocolVersion only accepts the versions supported by the library,
+	// the MQTT 5 clients are created by newClient.
+	clientOptions.ProtocolVersion = uint(config.ProtocolVersion)
+
 	for _, host := range config.Hosts {
 		clientOptions.AddBroker(host)
 	}
@@ -45,6 +49,14 @@ func createClientOptions(config mqttInputConfig, onConnectHandler func(client li
 	return clientOptions, nil
 }
 
+// newClient creates a client for the protocol version set in the options.
+func newClient(options *libmqtt.ClientOptions) libmqtt.Client {
+	if options.ProtocolVersion == uint(protocolVersion5) {
+		return newMqtt5Client(options)
+	}
+	return libmqtt.NewClient(options)
+}
+
 func createClientSubscriptions(config mqttInputConfig) map[string]byte {
 	subscriptions := map[string]byte{}
 	for _, topic := range config.Topics {
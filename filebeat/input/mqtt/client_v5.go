@@ -0,0 +1,474 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	"github.com/eclipse/paho.golang/paho"
+	libmqtt "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+// defaultConnectRetryDelay is the delay between connection attempts if the
+// options don't set one.
+const defaultConnectRetryDelay = 10 * time.Second
+
+// mqtt5Client implements the client interface of the MQTT 3 library on top of
+// a MQTT 5 client. It reconnects to the brokers when the connection is lost.
+// Only the options used by the input are supported.
+type mqtt5Client struct {
+	options *libmqtt.ClientOptions
+	logger  *logp.Logger
+
+	connected atomic.Bool
+
+	mu      sync.Mutex
+	client  *paho.Client
+	done    chan struct{}
+	stopped chan struct{}
+	handler libmqtt.MessageHandler
+	routes  map[string]libmqtt.MessageHandler
+}
+
+var _ libmqtt.Client = new(mqtt5Client)
+
+func newMqtt5Client(options *libmqtt.ClientOptions) libmqtt.Client {
+	return &mqtt5Client{
+		options: options,
+		logger:  logp.NewLogger("libmqtt"),
+		routes:  map[string]libmqtt.MessageHandler{},
+	}
+}
+
+func (c *mqtt5Client) IsConnected() bool {
+	return c.connected.Load()
+}
+
+func (c *mqtt5Client) IsConnectionOpen() bool {
+	return c.connected.Load()
+}
+
+// Connect starts connecting to the brokers. The returned token is completed
+// immediately, the client keeps trying to connect in the background.
+func (c *mqtt5Client) Connect() libmqtt.Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.done != nil {
+		return newCompletedToken(nil)
+	}
+
+	c.done = make(chan struct{})
+	c.stopped = make(chan struct{})
+	go c.connectLoop(c.done, c.stopped)
+	return newCompletedToken(nil)
+}
+
+// connectLoop connects to the brokers, and connects again when the connection
+// is lost, until done is closed.
+func (c *mqtt5Client) connectLoop(done, stopped chan struct{}) {
+	defer close(stopped)
+
+	retryDelay := c.options.ConnectRetryInterval
+	if retryDelay <= 0 {
+		retryDelay = defaultConnectRetryDelay
+	}
+
+	for {
+		client, lost, err := c.connect()
+		if err != nil {
+			c.logger.Warnf("Connecting to the broker failed: %v", err)
+		} else {
+			c.setClient(client)
+			c.connected.Store(true)
+			if c.options.OnConnect != nil {
+				// The handler may block until the topics are subscribed, the
+				// connection must be watched meanwhile.
+				go c.options.OnConnect(c)
+			}
+
+			select {
+			case <-lost:
+				c.setClient(nil)
+				c.connected.Store(false)
+				c.logger.Warn("Connection to the broker lost")
+			case <-done:
+				c.setClient(nil)
+				c.connected.Store(false)
+				if err := client.Disconnect(&paho.Disconnect{ReasonCode: 0}); err != nil {
+					c.logger.Warnf("Disconnecting from the broker failed: %v", err)
+				}
+				return
+			}
+		}
+
+		timer := time.NewTimer(retryDelay)
+		select {
+		case <-timer.C:
+		case <-done:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// connect connects to the first broker available. The returned channel is
+// closed when the connection is lost.
+func (c *mqtt5Client) connect() (*paho.Client, <-chan struct{}, error) {
+	if len(c.options.Servers) == 0 {
+		return nil, nil, errors.New("no broker configured")
+	}
+
+	var err error
+	for _, server := range c.options.Servers {
+		var conn net.Conn
+		conn, err = c.dial(server)
+		if err != nil {
+			continue
+		}
+
+		pinger := newMqtt5Pinger()
+		client := paho.NewClient()
+		client.Conn = conn
+		client.PingHandler = pinger
+		client.Router = &mqtt5Router{client: c}
+		client.OnDisconnect = func(d packets.Disconnect) {
+			c.logger.Warnf("Disconnected by the broker (reason: %d)", d.ReasonCode)
+		}
+
+		connect := &paho.Connect{
+			ClientID:   c.options.ClientID,
+			KeepAlive:  uint16(c.options.KeepAlive),
+			CleanStart: c.options.CleanSession,
+		}
+		if c.options.Username != "" {
+			connect.Username = c.options.Username
+			connect.UsernameFlag = true
+		}
+		if c.options.Password != "" {
+			connect.Password = []byte(c.options.Password)
+			connect.PasswordFlag = true
+		}
+
+		// The connection attempt can't be cancelled, the client only handles
+		// its timeout.
+		timeout := c.options.ConnectTimeout
+		if timeout <= 0 {
+			timeout = client.PacketTimeout
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		_, err = client.Connect(ctx, connect)
+		cancel()
+		if err != nil {
+			conn.Close()
+			continue
+		}
+		return client, pinger.lost, nil
+	}
+	return nil, nil, err
+}
+
+func (c *mqtt5Client) dial(server *url.URL) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.options.ConnectTimeout}
+
+	switch server.Scheme {
+	case "tcp", "mqtt":
+		return dialer.Dial("tcp", server.Host)
+	case "ssl", "tls", "tcps", "mqtts":
+		return tls.DialWithDialer(dialer, "tcp", server.Host, c.options.TLSConfig)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q of broker %v", server.Scheme, server)
+	}
+}
+
+// Disconnect stops connecting to the brokers, and waits at most quiesce
+// milliseconds for the connection to be closed.
+func (c *mqtt5Client) Disconnect(quiesce uint) {
+	c.mu.Lock()
+	done, stopped := c.done, c.stopped
+	c.done = nil
+	c.mu.Unlock()
+	if done == nil {
+		return
+	}
+
+	close(done)
+	timer := time.NewTimer(time.Duration(quiesce) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+	case <-timer.C:
+	}
+}
+
+func (c *mqtt5Client) Publish(topic string, qos byte, retained bool, payload interface{}) libmqtt.Token {
+	var data []byte
+	switch p := payload.(type) {
+	case []byte:
+		data = p
+	case string:
+		data = []byte(p)
+	default:
+		return newCompletedToken(fmt.Errorf("unsupported payload type %T", payload))
+	}
+
+	return c.run(func(ctx context.Context, client *paho.Client) error {
+		_, err := client.Publish(ctx, &paho.Publish{
+			Topic:   topic,
+			QoS:     qos,
+			Retain:  retained,
+			Payload: data,
+		})
+		return err
+	})
+}
+
+func (c *mqtt5Client) Subscribe(topic string, qos byte, callback libmqtt.MessageHandler) libmqtt.Token {
+	return c.SubscribeMultiple(map[string]byte{topic: qos}, callback)
+}
+
+// SubscribeMultiple subscribes to the given topics. The callback handles the
+// messages of all the subscribed topics, unless a route matches them.
+func (c *mqtt5Client) SubscribeMultiple(filters map[string]byte, callback libmqtt.MessageHandler) libmqtt.Token {
+	if callback != nil {
+		c.mu.Lock()
+		c.handler = callback
+		c.mu.Unlock()
+	}
+
+	subscriptions := make(map[string]paho.SubscribeOptions, len(filters))
+	for topic, qos := range filters {
+		subscriptions[topic] = paho.SubscribeOptions{QoS: qos}
+	}
+	return c.run(func(ctx context.Context, client *paho.Client) error {
+		_, err := client.Subscribe(ctx, &paho.Subscribe{Subscriptions: subscriptions})
+		return err
+	})
+}
+
+func (c *mqtt5Client) Unsubscribe(topics ...string) libmqtt.Token {
+	return c.run(func(ctx context.Context, client *paho.Client) error {
+		_, err := client.Unsubscribe(ctx, &paho.Unsubscribe{Topics: topics})
+		return err
+	})
+}
+
+func (c *mqtt5Client) AddRoute(topic string, callback libmqtt.MessageHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.routes[topic] = callback
+}
+
+// OptionsReader returns a reader of the options, it can only be created by
+// the clients of the MQTT 3 library.
+func (c *mqtt5Client) OptionsReader() libmqtt.ClientOptionsReader {
+	return libmqtt.NewClient(c.options).OptionsReader()
+}
+
+func (c *mqtt5Client) currentClient() *paho.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.client
+}
+
+func (c *mqtt5Client) setClient(client *paho.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.client = client
+}
+
+// run calls fn in a goroutine with the current connection, the returned token
+// is completed when it returns.
+func (c *mqtt5Client) run(fn func(context.Context, *paho.Client) error) libmqtt.Token {
+	client := c.currentClient()
+	if client == nil {
+		return newCompletedToken(errors.New("client is not connected"))
+	}
+
+	t := newToken()
+	go func() {
+		t.complete(fn(context.Background(), client))
+	}()
+	return t
+}
+
+// route passes the received messages to the handler of their topic.
+func (c *mqtt5Client) route(p *packets.Publish) {
+	c.mu.Lock()
+	handler := c.handler
+	for topic, h := range c.routes {
+		if routeMatches(topic, p.Topic) {
+			handler = h
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	if handler == nil {
+		handler = c.options.DefaultPublishHandler
+	}
+	if handler != nil {
+		handler(c, &mqtt5Message{publish: p})
+	}
+}
+
+// mqtt5Router passes all the messages received by a connection to the routes
+// of the client, registered with AddRoute.
+type mqtt5Router struct {
+	client *mqtt5Client
+}
+
+var _ paho.Router = new(mqtt5Router)
+
+func (r *mqtt5Router) RegisterHandler(string, paho.MessageHandler) {}
+func (r *mqtt5Router) UnregisterHandler(string)                    {}
+func (r *mqtt5Router) Route(p *packets.Publish)                    { r.client.route(p) }
+
+// mqtt5Pinger sends the keep alive pings of a connection. The client stops it
+// when the connection fails, so it closes lost then. It replaces the pinger of
+// the library, that can't be stopped before being started.
+type mqtt5Pinger struct {
+	lost chan struct{}
+	once sync.Once
+}
+
+var _ paho.Pinger = new(mqtt5Pinger)
+
+func newMqtt5Pinger() *mqtt5Pinger {
+	return &mqtt5Pinger{lost: make(chan struct{})}
+}
+
+func (p *mqtt5Pinger) Start(conn net.Conn, keepAlive time.Duration) {
+	if keepAlive <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.lost:
+			return
+		case <-ticker.C:
+			if _, err := packets.NewControlPacket(packets.PINGREQ).WriteTo(conn); err != nil {
+				// The client fails reading from the closed connection, and
+				// stops the pinger.
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+func (p *mqtt5Pinger) Stop() {
+	p.once.Do(func() { close(p.lost) })
+}
+
+func (p *mqtt5Pinger) PingResp() {}
+
+// routeMatches reports whether the topic filter of a route matches topic.
+func routeMatches(route, topic string) bool {
+	routeLevels := strings.Split(route, "/")
+	topicLevels := strings.Split(topic, "/")
+	for i, level := range routeLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+	return len(routeLevels) == len(topicLevels)
+}
+
+// mqtt5Message implements the message interface of the MQTT 3 library for the
+// messages received by a MQTT 5 client. The messages are acknowledged by the
+// client when they are received.
+type mqtt5Message struct {
+	publish *packets.Publish
+}
+
+var _ libmqtt.Message = new(mqtt5Message)
+
+func (m *mqtt5Message) Duplicate() bool   { return m.publish.Duplicate }
+func (m *mqtt5Message) Qos() byte         { return m.publish.QoS }
+func (m *mqtt5Message) Retained() bool    { return m.publish.Retain }
+func (m *mqtt5Message) Topic() string     { return m.publish.Topic }
+func (m *mqtt5Message) MessageID() uint16 { return m.publish.PacketID }
+func (m *mqtt5Message) Payload() []byte   { return m.publish.Payload }
+func (m *mqtt5Message) Ack()              {}
+
+// token implements the token interface of the MQTT 3 library.
+type token struct {
+	done chan struct{}
+	err  error
+}
+
+var _ libmqtt.Token = new(token)
+
+func newToken() *token {
+	return &token{done: make(chan struct{})}
+}
+
+func newCompletedToken(err error) *token {
+	t := newToken()
+	t.complete(err)
+	return t
+}
+
+func (t *token) complete(err error) {
+	t.err = err
+	close(t.done)
+}
+
+func (t *token) Wait() bool {
+	<-t.done
+	return true
+}
+
+func (t *token) WaitTimeout(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-t.done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (t *token) Error() error {
+	select {
+	case <-t.done:
+		return t.err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,191 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mqtt
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.golang/packets"
+	libmqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClient_ProtocolVersion(t *testing.T) {
+	options := libmqtt.NewClientOptions()
+	options.ProtocolVersion = uint(protocolVersion5)
+	require.IsType(t, new(mqtt5Client), newClient(options))
+
+	options.ProtocolVersion = uint(protocolVersion311)
+	_, isMqtt5 := newClient(options).(*mqtt5Client)
+	require.False(t, isMqtt5)
+}
+
+func TestMqtt5Client_Route(t *testing.T) {
+	client := newMqtt5Client(libmqtt.NewClientOptions()).(*mqtt5Client)
+
+	var received []string
+	handler := func(name string) libmqtt.MessageHandler {
+		return func(_ libmqtt.Client, message libmqtt.Message) {
+			received = append(received, name+":"+message.Topic())
+		}
+	}
+	client.handler = handler("default")
+	client.AddRoute("sensors/+/temperature", handler("temperature"))
+
+	client.route(&packets.Publish{Topic: "sensors/kitchen/temperature", QoS: 1, PacketID: 7, Payload: []byte("21")})
+	client.route(&packets.Publish{Topic: "sensors/kitchen/humidity"})
+	require.Equal(t, []string{"temperature:sensors/kitchen/temperature", "default:sensors/kitchen/humidity"}, received)
+}
+
+func TestMqtt5Message(t *testing.T) {
+	message := &mqtt5Message{publish: &packets.Publish{
+		Topic:     "sensors",
+		QoS:       2,
+		Retain:    true,
+		Duplicate: true,
+		PacketID:  3,
+		Payload:   []byte("payload"),
+	}}
+
+	require.Equal(t, "sensors", message.Topic())
+	require.Equal(t, byte(2), message.Qos())
+	require.True(t, message.Retained())
+	require.True(t, message.Duplicate())
+	require.Equal(t, uint16(3), message.MessageID())
+	require.Equal(t, []byte("payload"), message.Payload())
+}
+
+func TestMqtt5Client_NotConnected(t *testing.T) {
+	client := newMqtt5Client(libmqtt.NewClientOptions())
+
+	token := client.SubscribeMultiple(map[string]byte{"#": 0}, nil)
+	require.True(t, token.WaitTimeout(time.Second))
+	require.Error(t, token.Error())
+	require.False(t, client.IsConnected())
+
+	// Disconnecting a client that never connected is a no-op.
+	client.Disconnect(0)
+}
+
+func TestMqtt5Client_Reconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	// The broker sends a message to each connection once subscribed, and closes
+	// the first connection afterwards.
+	disconnected := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveMqtt5(conn, i == 0, disconnected)
+		}
+	}()
+
+	received := make(chan string, 2)
+	options := libmqtt.NewClientOptions().
+		AddBroker("tcp://" + listener.Addr().String()).
+		SetConnectRetryInterval(10 * time.Millisecond)
+	options.SetOnConnectHandler(func(client libmqtt.Client) {
+		client.Subscribe("test", 1, func(_ libmqtt.Client, message libmqtt.Message) {
+			received <- string(message.Payload())
+		})
+	})
+	client := newMqtt5Client(options)
+	require.True(t, client.Connect().Wait())
+
+	for i := 0; i < 2; i++ {
+		select {
+		case payload := <-received:
+			require.Equal(t, "hello", payload)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("message %d not received", i)
+		}
+	}
+
+	client.Disconnect(1000)
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the client did not disconnect")
+	}
+	require.False(t, client.IsConnected())
+}
+
+// serveMqtt5 accepts a MQTT 5 connection, and publishes a message once the
+// client subscribes.
+func serveMqtt5(conn net.Conn, closeAfterPublish bool, disconnected chan<- struct{}) {
+	defer conn.Close()
+	for {
+		packet, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		switch p := packet.Content.(type) {
+		case *packets.Connect:
+			ack := &packets.Connack{Properties: &packets.Properties{}}
+			ack.WriteTo(conn)
+		case *packets.Subscribe:
+			ack := &packets.Suback{Properties: &packets.Properties{}, PacketID: p.PacketID, Reasons: []byte{0}}
+			ack.WriteTo(conn)
+			publish := &packets.Publish{Properties: &packets.Properties{}, Topic: "test", Payload: []byte("hello")}
+			publish.WriteTo(conn)
+			if closeAfterPublish {
+				return
+			}
+		case *packets.Disconnect:
+			close(disconnected)
+			return
+		}
+	}
+}
+
+func TestRouteMatches(t *testing.T) {
+	tests := []struct {
+		route, topic string
+		matches      bool
+	}{
+		{"a/b", "a/b", true},
+		{"a/b", "a/c", false},
+		{"a/+", "a/b", true},
+		{"a/+", "a/b/c", false},
+		{"a/#", "a/b/c", true},
+		{"#", "a", true},
+		{"a/b/c", "a/b", false},
+	}
+	for _, test := range tests {
+		require.Equal(t, test.matches, routeMatches(test.route, test.topic), "%s %s", test.route, test.topic)
+	}
+}
+
+func TestToken(t *testing.T) {
+	token := newToken()
+	require.False(t, token.WaitTimeout(time.Millisecond))
+	require.NoError(t, token.Error())
+
+	err := errors.New("failure")
+	token.complete(err)
+	require.True(t, token.Wait())
+	require.Equal(t, err, token.Error())
+}
@@ -19,14 +19,17 @@ package mqtt
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
 )
 
 type mqttInputConfig struct {
-	Hosts  []string `config:"hosts" validate:"required,min=1"`
-	Topics []string `config:"topics" validate:"required,min=1"`
-	QoS    int      `config:"qos" validate:"min=0,max=2"`
+	Hosts           []string        `config:"hosts" validate:"required,min=1"`
+	Topics          []string        `config:"topics" validate:"required,min=1"`
+	QoS             int             `config:"qos" validate:"min=0,max=2"`
+	ProtocolVersion protocolVersion `config:"protocol_version"`
+	PayloadFormat   payloadFormat   `config:"payload_format"`
 
 	ClientID string `config:"client_id" validate:"nonzero"`
 	Username string `config:"username"`
@@ -38,8 +41,9 @@ type mqttInputConfig struct {
 // The default config for the mqtt input.
 func defaultConfig() mqttInputConfig {
 	return mqttInputConfig{
-		ClientID: "filebeat",
-		Topics:   []string{"#"},
+		ClientID:      "filebeat",
+		Topics:        []string{"#"},
+		PayloadFormat: payloadRaw,
 	}
 }
 
@@ -50,3 +54,53 @@ func (mic *mqttInputConfig) Validate() error {
 	}
 	return nil
 }
+
+// protocolVersion is the version of the MQTT protocol used to connect to the
+// brokers. Its values are the protocol levels sent in the CONNECT packets. If
+// it's not set, the library tries 3.1.1 first and falls back to 3.1.
+type protocolVersion uint
+
+const (
+	protocolVersion31  protocolVersion = 3
+	protocolVersion311 protocolVersion = 4
+	protocolVersion5   protocolVersion = 5
+)
+
+var protocolVersions = map[string]protocolVersion{
+	"3.1":   protocolVersion31,
+	"3.1.1": protocolVersion311,
+	"5":     protocolVersion5,
+}
+
+// Unpack creates the protocol version from the given string.
+func (v *protocolVersion) Unpack(str string) error {
+	version, found := protocolVersions[str]
+	if !found {
+		return fmt.Errorf("invalid protocol version '%s' (valid values are: 3.1, 3.1.1, 5)", str)
+	}
+	*v = version
+	return nil
+}
+
+// payloadFormat defines how the payloads of the messages are published.
+type payloadFormat uint8
+
+const (
+	payloadRaw payloadFormat = iota
+	payloadJSON
+)
+
+var payloadFormats = map[string]payloadFormat{
+	"raw":  payloadRaw,
+	"json": payloadJSON,
+}
+
+// Unpack creates the payload format from the given string.
+func (f *payloadFormat) Unpack(str string) error {
+	format, found := payloadFormats[str]
+	if !found {
+		return fmt.Errorf("invalid payload format '%s' (valid values are: raw, json)", str)
+	}
+	*f = format
+	return nil
+}
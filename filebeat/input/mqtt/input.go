@@ -18,6 +18,9 @@
 package mqtt
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -30,6 +33,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/backoff"
+	"github.com/elastic/beats/v7/libbeat/common/jsontransform"
 	"github.com/elastic/beats/v7/libbeat/logp"
 )
 
@@ -64,7 +68,7 @@ func NewInput(
 	connector channel.Connector,
 	inputContext input.Context,
 ) (input.Input, error) {
-	return newInput(cfg, connector, inputContext, libmqtt.NewClient, backoff.NewEqualJitterBackoff)
+	return newInput(cfg, connector, inputContext, newClient, backoff.NewEqualJitterBackoff)
 }
 
 func newInput(
@@ -90,7 +94,7 @@ func newInput(
 	clientDisconnected := new(sync.WaitGroup)
 	inflightMessages := new(sync.WaitGroup)
 	clientSubscriptions := createClientSubscriptions(config)
-	onMessageHandler := createOnMessageHandler(logger, out, inflightMessages)
+	onMessageHandler := createOnMessageHandler(logger, out, inflightMessages, config.PayloadFormat)
 	onConnectHandler := createOnConnectHandler(logger, &inputContext, onMessageHandler, clientSubscriptions, newBackoff)
 	clientOptions, err := createClientOptions(config, onConnectHandler)
 	if err != nil {
@@ -105,7 +109,7 @@ func newInput(
 	}, nil
 }
 
-func createOnMessageHandler(logger *logp.Logger, outlet channel.Outleter, inflightMessages *sync.WaitGroup) func(client libmqtt.Client, message libmqtt.Message) {
+func createOnMessageHandler(logger *logp.Logger, outlet channel.Outleter, inflightMessages *sync.WaitGroup, format payloadFormat) func(client libmqtt.Client, message libmqtt.Message) {
 	return func(client libmqtt.Client, message libmqtt.Message) {
 		inflightMessages.Add(1)
 
@@ -119,18 +123,50 @@ func createOnMessageHandler(logger *logp.Logger, outlet channel.Outleter, inflig
 			"retained":   message.Retained(),
 			"topic":      message.Topic(),
 		}
+		fields := common.MapStr{
+			"mqtt": mqttFields,
+		}
+		if format == payloadJSON {
+			payload, err := decodeJSONPayload(message.Payload())
+			if err != nil {
+				logger.Debugf("Error decoding the payload of the message on topic '%s': %v", message.Topic(), err)
+				fields["message"] = string(message.Payload())
+				fields["error"] = common.MapStr{
+					"message": fmt.Sprintf("Error decoding JSON payload: %v", err),
+					"type":    "json",
+				}
+			} else {
+				fields["json"] = payload
+			}
+		} else {
+			fields["message"] = string(message.Payload())
+		}
+
 		outlet.OnEvent(beat.Event{
 			Timestamp: time.Now(),
-			Fields: common.MapStr{
-				"message": string(message.Payload()),
-				"mqtt":    mqttFields,
-			},
+			Fields:    fields,
 		})
 
 		inflightMessages.Done()
 	}
 }
 
+// decodeJSONPayload decodes the JSON object in payload. The numbers are
+// decoded as integers when possible.
+func decodeJSONPayload(payload []byte) (common.MapStr, error) {
+	var fields common.MapStr
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		return nil, errors.New("payload is not a JSON object")
+	}
+	jsontransform.TransformNumbers(fields)
+	return fields, nil
+}
+
 func createOnConnectHandler(logger *logp.Logger,
 	inputContext *input.Context,
 	onMessageHandler func(client libmqtt.Client, message libmqtt.Message),
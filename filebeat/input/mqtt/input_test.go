@@ -322,6 +322,57 @@ func TestOnCreateHandler_SubscribeMultiple_BackoffSignalDone(t *testing.T) {
 	require.Equal(t, 1, mockedBackoff.resetCount)
 }
 
+func TestOnMessageHandler_JSONPayload(t *testing.T) {
+	var events []beat.Event
+	outlet := &mockedOutleter{
+		onEventHandler: func(event beat.Event) bool {
+			events = append(events, event)
+			return true
+		},
+	}
+	handler := createOnMessageHandler(logger, outlet, new(sync.WaitGroup), payloadJSON)
+
+	handler(nil, &mockedMessage{topic: "sensors", payload: []byte(`{"temperature": 21.5, "humidity": 40}`)})
+	handler(nil, &mockedMessage{topic: "sensors", payload: []byte(`not json`)})
+	require.Len(t, events, 2)
+
+	require.Equal(t, common.MapStr{"temperature": 21.5, "humidity": int64(40)}, events[0].Fields["json"])
+	require.NotContains(t, events[0].Fields, "message")
+
+	require.Equal(t, "not json", events[1].Fields["message"])
+	errorType, err := events[1].GetValue("error.type")
+	require.NoError(t, err)
+	require.Equal(t, "json", errorType)
+	require.NotContains(t, events[1].Fields, "json")
+}
+
+func TestConfig_ProtocolVersion(t *testing.T) {
+	for value, expected := range map[interface{}]protocolVersion{
+		"3.1":   protocolVersion31,
+		"3.1.1": protocolVersion311,
+		5:       protocolVersion5,
+	} {
+		config := defaultConfig()
+		err := common.MustNewConfigFrom(common.MapStr{
+			"hosts":            "tcp://mocked:1234",
+			"protocol_version": value,
+		}).Unpack(&config)
+		require.NoError(t, err)
+		require.Equal(t, expected, config.ProtocolVersion)
+
+		options, err := createClientOptions(config, nil)
+		require.NoError(t, err)
+		require.Equal(t, uint(expected), options.ProtocolVersion)
+	}
+
+	config := defaultConfig()
+	err := common.MustNewConfigFrom(common.MapStr{
+		"hosts":            "tcp://mocked:1234",
+		"protocol_version": "4",
+	}).Unpack(&config)
+	require.Error(t, err)
+}
+
 func assertEventMatches(t *testing.T, expected mockedMessage, got beat.Event) {
 	topic, err := got.GetValue("mqtt.topic")
 	require.NoError(t, err)
@@ -83,10 +83,19 @@ func (ec *eventCaptor) Done() <-chan struct{} {
 func TestInput(t *testing.T) {
 	logp.TestingSetup(logp.WithSelectors("mqtt input", "libmqtt"))
 
+	for _, version := range []string{"3.1.1", "5"} {
+		t.Run(version, func(t *testing.T) {
+			testInput(t, version)
+		})
+	}
+}
+
+func testInput(t *testing.T, protocolVersion string) {
 	// Setup the input config.
 	config := common.MustNewConfigFrom(common.MapStr{
-		"hosts":  []string{hostPort},
-		"topics": []string{topic},
+		"hosts":            []string{hostPort},
+		"topics":           []string{topic},
+		"protocol_version": protocolVersion,
 	})
 
 	// Route input events through our captor instead of sending through ES.
@@ -47,7 +47,31 @@ var defaultConfig = config{
 
 type syslogTCP struct {
 	tcp.Config    `config:",inline"`
-	LineDelimiter string `config:"line_delimiter" validate:"nonzero"`
+	LineDelimiter string  `config:"line_delimiter" validate:"nonzero"`
+	Framing       framing `config:"framing"`
+}
+
+// framing defines how the messages are delimited in the TCP streams.
+type framing uint8
+
+const (
+	framingDelimiter framing = iota
+	framingRFC6587
+)
+
+var framings = map[string]framing{
+	"delimiter": framingDelimiter,
+	"rfc6587":   framingRFC6587,
+}
+
+// Unpack creates the framing from the given string.
+func (f *framing) Unpack(str string) error {
+	v, found := framings[str]
+	if !found {
+		return fmt.Errorf("invalid framing '%s' (valid values are: delimiter, rfc6587)", str)
+	}
+	*f = v
+	return nil
 }
 
 var defaultTCP = syslogTCP{
@@ -94,6 +118,9 @@ func factory(
 		if splitFunc == nil {
 			return nil, fmt.Errorf("error creating splitFunc from delimiter %s", config.LineDelimiter)
 		}
+		if config.Framing == framingRFC6587 {
+			splitFunc = splitRFC6587(splitFunc)
+		}
 
 		logger := logp.NewLogger("input.syslog.tcp").With("address", config.Config.Host)
 		factory := streaming.SplitHandlerFactory(inputsource.FamilyTCP, logger, tcp.MetadataCallback, nf, splitFunc)
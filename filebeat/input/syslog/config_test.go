@@ -0,0 +1,137 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package syslog
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/filebeat/inputsource"
+	"github.com/elastic/beats/v7/filebeat/inputsource/tcp"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/transport/transptest"
+)
+
+func TestFramingUnpack(t *testing.T) {
+	config := defaultTCP
+	cfg := common.MustNewConfigFrom(map[string]interface{}{
+		"host":    "localhost:0",
+		"framing": "rfc6587",
+	})
+	require.NoError(t, cfg.Unpack(&config))
+	assert.Equal(t, framingRFC6587, config.Framing)
+
+	config = defaultTCP
+	cfg = common.MustNewConfigFrom(map[string]interface{}{
+		"host":    "localhost:0",
+		"framing": "octets",
+	})
+	assert.Error(t, cfg.Unpack(&config))
+}
+
+func TestTCPWithMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syslog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The certificate is self signed, and used by both the server and the
+	// clients.
+	certName := filepath.Join(dir, "cert")
+	transptest.GenCertForTestingPurpose(t, certName, "", "127.0.0.1", "localhost")
+	cert, err := tls.LoadX509KeyPair(certName+".pem", certName+".key")
+	require.NoError(t, err)
+	pem, err := ioutil.ReadFile(certName + ".pem")
+	require.NoError(t, err)
+	rootCAs := x509.NewCertPool()
+	rootCAs.AppendCertsFromPEM(pem)
+
+	var ns common.ConfigNamespace
+	cfg := common.MustNewConfigFrom(map[string]interface{}{
+		"tcp": map[string]interface{}{
+			"host":    "127.0.0.1:0",
+			"framing": "rfc6587",
+			"ssl": map[string]interface{}{
+				"certificate":             certName + ".pem",
+				"key":                     certName + ".key",
+				"certificate_authorities": []string{certName + ".pem"},
+			},
+		},
+	})
+	require.NoError(t, cfg.Unpack(&ns))
+
+	ch := make(chan string, 10)
+	server, err := factory(func(data []byte, _ inputsource.NetworkMetadata) {
+		ch <- string(data)
+	}, ns)
+	require.NoError(t, err)
+	require.NoError(t, server.Start())
+	defer server.Stop()
+	addr := server.(*tcp.Server).Listener.Listener.Addr().String()
+
+	t.Run("with a client certificate", func(t *testing.T) {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{
+			RootCAs:      rootCAs,
+			Certificates: []tls.Certificate{cert},
+		})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		messages := []string{"<13>1 - - - - - - hello", "<13>1 - - - - - - multi\nline"}
+		for _, m := range messages {
+			fmt.Fprintf(conn, "%d %s", len(m), m)
+		}
+
+		for _, m := range messages {
+			select {
+			case data := <-ch:
+				assert.Equal(t, m, data)
+			case <-time.After(10 * time.Second):
+				t.Fatal("timeout waiting for the messages")
+			}
+		}
+	})
+
+	t.Run("without a client certificate", func(t *testing.T) {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{
+			RootCAs: rootCAs,
+		})
+		if err == nil {
+			// With TLS 1.3 the server rejects the client after the handshake.
+			defer conn.Close()
+			fmt.Fprint(conn, "5 hello")
+			conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+			_, err = conn.Read(make([]byte, 1))
+		}
+		assert.Error(t, err)
+
+		select {
+		case data := <-ch:
+			t.Fatalf("unexpected message: %s", data)
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
@@ -0,0 +1,64 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package syslog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// maxOctetCountDigits limits the length of the octet counts.
+const maxOctetCountDigits = 10
+
+// splitRFC6587 returns a function splitting the frames of RFC 6587. The frames
+// starting with an octet count and a space are octet counted, the other frames
+// are split with the non transparent framing function.
+func splitRFC6587(nonTransparent bufio.SplitFunc) bufio.SplitFunc {
+	return func(data []byte, eof bool) (int, []byte, error) {
+		if eof && len(data) == 0 {
+			return 0, nil, nil
+		}
+
+		i := 0
+		for i < len(data) && i < maxOctetCountDigits && isDigit(data[i]) {
+			i++
+		}
+		switch {
+		case i == len(data) && i < maxOctetCountDigits && !eof:
+			// Wait for the end of the octet count.
+			return 0, nil, nil
+		case i == 0 || i == len(data) || data[i] != ' ':
+			return nonTransparent(data, eof)
+		}
+
+		count, err := strconv.Atoi(string(data[:i]))
+		if err != nil || count == 0 {
+			return 0, nil, fmt.Errorf("invalid octet count '%s'", data[:i])
+		}
+		end := i + 1 + count
+		if len(data) < end {
+			if eof {
+				return 0, nil, io.ErrUnexpectedEOF
+			}
+			return 0, nil, nil
+		}
+		return end, data[i+1 : end], nil
+	}
+}
@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/v7/filebeat/inputsource/common/streaming"
+)
+
+func TestSplitRFC6587(t *testing.T) {
+	cases := map[string]struct {
+		data     string
+		expected []string
+		err      error
+	}{
+		"octet counted": {
+			data:     "11 <13>1 - - -12 <13>1 - - \n-",
+			expected: []string{"<13>1 - - -", "<13>1 - - \n-"},
+		},
+		"non transparent": {
+			data:     "<13>Oct 11 22:14:15 hello\n<13>Oct 11 22:14:15 world\n",
+			expected: []string{"<13>Oct 11 22:14:15 hello", "<13>Oct 11 22:14:15 world"},
+		},
+		"mixed": {
+			data:     "5 hello<13>world\n3 foo",
+			expected: []string{"hello", "<13>world", "foo"},
+		},
+		"digits without space": {
+			data:     "2021-01-01 hello\n",
+			expected: []string{"2021-01-01 hello"},
+		},
+		"incomplete frame": {
+			data:     "11 <13>1 -",
+			expected: nil,
+			err:      io.ErrUnexpectedEOF,
+		},
+	}
+
+	for title, c := range cases {
+		t.Run(title, func(t *testing.T) {
+			scanner := bufio.NewScanner(strings.NewReader(c.data))
+			scanner.Split(splitRFC6587(streaming.SplitFunc([]byte("\n"))))
+
+			var frames []string
+			for scanner.Scan() {
+				frames = append(frames, scanner.Text())
+			}
+			assert.Equal(t, c.err, scanner.Err())
+			assert.Equal(t, c.expected, frames)
+		})
+	}
+}
+
+func TestSplitRFC6587ShortReads(t *testing.T) {
+	scanner := bufio.NewScanner(iotest.OneByteReader(strings.NewReader("11 <13>1 - - -5 hello")))
+	scanner.Split(splitRFC6587(streaming.SplitFunc([]byte("\n"))))
+
+	var frames []string
+	for scanner.Scan() {
+		frames = append(frames, scanner.Text())
+	}
+	assert.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"<13>1 - - -", "hello"}, frames)
+}
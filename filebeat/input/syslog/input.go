@@ -18,6 +18,7 @@
 package syslog
 
 import (
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -200,22 +201,93 @@ func createEvent(ev *event, metadata inputsource.NetworkMetadata, timezone *time
 	}
 
 	if ev.HasPriority() {
-		syslog["priority"] = ev.Priority()
+		addPriority(ev.Priority(), syslog, event, log)
+	}
 
-		event["severity"] = ev.Severity()
-		v, err := mapValueToName(ev.Severity(), severityLabels)
-		if err != nil {
-			log.Debugw("could not find severity label", "error", err)
+	f["syslog"] = syslog
+	f["event"] = event
+	if len(process) > 0 {
+		f["process"] = process
+	}
+
+	if ev.Sequence() != -1 {
+		f["event.sequence"] = ev.Sequence()
+	}
+
+	return newBeatEvent(ev.Timestamp(timezone), metadata, f)
+}
+
+// addPriority adds the priority, and the severity and facility derived from it.
+func addPriority(priority int, syslog, event common.MapStr, log *logp.Logger) {
+	severity, facility := priority&severityMask, priority>>facilityShift
+
+	syslog["priority"] = priority
+
+	event["severity"] = severity
+	v, err := mapValueToName(severity, severityLabels)
+	if err != nil {
+		log.Debugw("could not find severity label", "error", err)
+	} else {
+		syslog["severity_label"] = v
+	}
+
+	syslog["facility"] = facility
+	v, err = mapValueToName(facility, facilityLabels)
+	if err != nil {
+		log.Debugw("could not find facility label", "error", err)
+	} else {
+		syslog["facility_label"] = v
+	}
+}
+
+// createEventRFC5424 creates an event from a RFC 5424 message. The parameters
+// of the structured data are stored in syslog.structured_data.<SD-ID>.<name>.
+func createEventRFC5424(msg *rfc5424, metadata inputsource.NetworkMetadata, log *logp.Logger) beat.Event {
+	f := common.MapStr{
+		"message": strings.TrimRight(msg.message, "\n"),
+	}
+
+	syslog := common.MapStr{
+		"version": msg.version,
+	}
+	event := common.MapStr{}
+	process := common.MapStr{}
+
+	addPriority(msg.priority, syslog, event, log)
+
+	if msg.hostname != "" {
+		f["hostname"] = msg.hostname
+	}
+
+	if msg.appName != "" {
+		process["program"] = msg.appName
+	}
+
+	if msg.procID != "" {
+		if pid, err := strconv.Atoi(msg.procID); err == nil {
+			process["pid"] = pid
 		} else {
-			syslog["severity_label"] = v
+			syslog["procid"] = msg.procID
 		}
+	}
 
-		syslog["facility"] = ev.Facility()
-		v, err = mapValueToName(ev.Facility(), facilityLabels)
-		if err != nil {
-			log.Debugw("could not find facility label", "error", err)
-		} else {
-			syslog["facility_label"] = v
+	if msg.msgID != "" {
+		syslog["msgid"] = msg.msgID
+	}
+
+	if len(msg.structuredData) > 0 {
+		sd := common.MapStr{}
+		for id, params := range msg.structuredData {
+			sd[id] = common.MapStr(params)
+		}
+		syslog["structured_data"] = sd
+
+		// The sequenceId parameter of the meta element is the same as the
+		// sequence number of RFC 3164 messages.
+		if s, ok := msg.structuredData["meta"]["sequenceId"].(string); ok {
+			if seq, err := strconv.Atoi(s); err == nil {
+				event["sequence"] = seq
+			}
 		}
 	}
 
@@ -225,14 +297,22 @@ func createEvent(ev *event, metadata inputsource.NetworkMetadata, timezone *time
 		f["process"] = process
 	}
 
-	if ev.Sequence() != -1 {
-		f["event.sequence"] = ev.Sequence()
+	timestamp := msg.timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
 	}
-
-	return newBeatEvent(ev.Timestamp(timezone), metadata, f)
+	return newBeatEvent(timestamp, metadata, f)
 }
 
 func parseAndCreateEvent(data []byte, metadata inputsource.NetworkMetadata, timezone *time.Location, log *logp.Logger) beat.Event {
+	if isRFC5424(data) {
+		msg, err := parseRFC5424(data)
+		if err == nil {
+			return createEventRFC5424(msg, metadata, log)
+		}
+		log.Debugw("can't parse event as syslog rfc5424", "error", err)
+	}
+
 	ev := newEvent()
 	Parse(data, ev)
 	if !ev.IsValid() {
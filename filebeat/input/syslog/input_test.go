@@ -219,6 +219,59 @@ func TestParseAndCreateEvent(t *testing.T) {
 			},
 		},
 
+		"valid rfc5424 data": {
+			data: []byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 8710 ID47 [exampleSDID@32473 iut="3" eventSource="Application"][meta sequenceId="12"] An application event log entry`),
+			expected: common.MapStr{
+				"event":    common.MapStr{"severity": 5, "sequence": 12},
+				"hostname": "mymachine.example.com",
+				"log": common.MapStr{
+					"source": common.MapStr{
+						"address": "127.0.0.1",
+					},
+				},
+				"message": "An application event log entry",
+				"process": common.MapStr{"pid": 8710, "program": "evntslog"},
+				"syslog": common.MapStr{
+					"facility":       20,
+					"facility_label": "local4",
+					"priority":       165,
+					"severity_label": "Notice",
+					"version":        1,
+					"msgid":          "ID47",
+					"structured_data": common.MapStr{
+						"exampleSDID@32473": common.MapStr{
+							"iut":         "3",
+							"eventSource": "Application",
+						},
+						"meta": common.MapStr{
+							"sequenceId": "12",
+						},
+					},
+				},
+			},
+		},
+
+		"rfc5424 data with nil values": {
+			data: []byte("<14>1 - - - worker-1 - - hello"),
+			expected: common.MapStr{
+				"event": common.MapStr{"severity": 6},
+				"log": common.MapStr{
+					"source": common.MapStr{
+						"address": "127.0.0.1",
+					},
+				},
+				"message": "hello",
+				"syslog": common.MapStr{
+					"facility":       1,
+					"facility_label": "user-level",
+					"priority":       14,
+					"severity_label": "Informational",
+					"version":        1,
+					"procid":         "worker-1",
+				},
+			},
+		},
+
 		"invalid data": {
 			data: []byte("invalid"),
 			expected: common.MapStr{
@@ -0,0 +1,305 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package syslog
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Maximal lengths of the header fields, defined in
+// https://tools.ietf.org/html/rfc5424#section-6.
+const (
+	maxHostnameLength = 255
+	maxAppNameLength  = 48
+	maxProcIDLength   = 128
+	maxMsgIDLength    = 32
+	maxSDNameLength   = 32
+)
+
+var bom = []byte("\xEF\xBB\xBF")
+
+// rfc5424 is a message in the format of RFC 5424. The fields with the NILVALUE
+// are empty.
+type rfc5424 struct {
+	priority  int
+	version   int
+	timestamp time.Time
+	hostname  string
+	appName   string
+	procID    string
+	msgID     string
+
+	// structuredData holds the parameters of the SD-ELEMENTs, by SD-ID and
+	// name. The values are strings, or lists of strings for the parameters
+	// repeated in an element.
+	structuredData map[string]map[string]interface{}
+
+	message string
+}
+
+// isRFC5424 returns true if data starts with a priority followed by a version,
+// which can't be the beginning of a RFC 3164 message.
+func isRFC5424(data []byte) bool {
+	if len(data) == 0 || data[0] != '<' {
+		return false
+	}
+	i := 1
+	for i < len(data) && i <= 3 && isDigit(data[i]) {
+		i++
+	}
+	if i == 1 || i == len(data) || data[i] != '>' {
+		return false
+	}
+	i++
+	start := i
+	for i < len(data) && i-start < 3 && isDigit(data[i]) {
+		i++
+	}
+	return i > start && data[start] != '0' && i < len(data) && data[i] == ' '
+}
+
+// parseRFC5424 parses a message in the format of RFC 5424.
+func parseRFC5424(data []byte) (*rfc5424, error) {
+	p := rfc5424Parser{data: data}
+	return p.parse()
+}
+
+type rfc5424Parser struct {
+	data []byte
+	pos  int
+}
+
+func (p *rfc5424Parser) parse() (*rfc5424, error) {
+	var msg rfc5424
+	var err error
+
+	if err = p.expect('<'); err != nil {
+		return nil, err
+	}
+	if msg.priority, err = p.number(3); err != nil {
+		return nil, fmt.Errorf("invalid priority: %v", err)
+	}
+	if msg.priority > 191 {
+		return nil, fmt.Errorf("invalid priority: %d", msg.priority)
+	}
+	if err = p.expect('>'); err != nil {
+		return nil, err
+	}
+	if msg.version, err = p.number(3); err != nil || msg.version == 0 {
+		return nil, fmt.Errorf("invalid version")
+	}
+
+	if err = p.expect(' '); err != nil {
+		return nil, err
+	}
+	timestamp, err := p.field("timestamp", len(p.data))
+	if err != nil {
+		return nil, err
+	}
+	if timestamp != "" {
+		msg.timestamp, err = time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timestamp: %v", err)
+		}
+	}
+
+	for _, f := range []struct {
+		name   string
+		maxLen int
+		value  *string
+	}{
+		{"hostname", maxHostnameLength, &msg.hostname},
+		{"app-name", maxAppNameLength, &msg.appName},
+		{"procid", maxProcIDLength, &msg.procID},
+		{"msgid", maxMsgIDLength, &msg.msgID},
+	} {
+		if err = p.expect(' '); err != nil {
+			return nil, err
+		}
+		if *f.value, err = p.field(f.name, f.maxLen); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = p.expect(' '); err != nil {
+		return nil, err
+	}
+	if msg.structuredData, err = p.structuredData(); err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.data) {
+		if err = p.expect(' '); err != nil {
+			return nil, err
+		}
+		msg.message = string(bytes.TrimPrefix(p.data[p.pos:], bom))
+	}
+	return &msg, nil
+}
+
+// field parses a header field, returning an empty string for the NILVALUE.
+func (p *rfc5424Parser) field(name string, maxLen int) (string, error) {
+	start := p.pos
+	for p.pos < len(p.data) && isPrintUSASCII(p.data[p.pos]) {
+		p.pos++
+	}
+	value := string(p.data[start:p.pos])
+	switch {
+	case value == "":
+		return "", p.errorf("missing %s", name)
+	case len(value) > maxLen:
+		return "", fmt.Errorf("%s is longer than %d characters", name, maxLen)
+	case value == "-":
+		return "", nil
+	}
+	return value, nil
+}
+
+// structuredData parses the SD-ELEMENTs, returning nil for the NILVALUE.
+func (p *rfc5424Parser) structuredData() (map[string]map[string]interface{}, error) {
+	if p.pos < len(p.data) && p.data[p.pos] == '-' {
+		p.pos++
+		return nil, nil
+	}
+	if p.pos == len(p.data) || p.data[p.pos] != '[' {
+		return nil, p.errorf("invalid structured data")
+	}
+
+	sd := map[string]map[string]interface{}{}
+	for p.pos < len(p.data) && p.data[p.pos] == '[' {
+		p.pos++
+		id, err := p.sdName("SD-ID")
+		if err != nil {
+			return nil, err
+		}
+		params, found := sd[id]
+		if !found {
+			params = map[string]interface{}{}
+			sd[id] = params
+		}
+
+		for p.pos < len(p.data) && p.data[p.pos] == ' ' {
+			p.pos++
+			name, err := p.sdName("parameter name")
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect('='); err != nil {
+				return nil, err
+			}
+			value, err := p.paramValue()
+			if err != nil {
+				return nil, err
+			}
+
+			switch v := params[name].(type) {
+			case nil:
+				params[name] = value
+			case string:
+				params[name] = []string{v, value}
+			case []string:
+				params[name] = append(v, value)
+			}
+		}
+
+		if err := p.expect(']'); err != nil {
+			return nil, err
+		}
+	}
+	return sd, nil
+}
+
+func (p *rfc5424Parser) sdName(name string) (string, error) {
+	start := p.pos
+	for p.pos < len(p.data) && isSDNameChar(p.data[p.pos]) {
+		p.pos++
+	}
+	value := string(p.data[start:p.pos])
+	switch {
+	case value == "":
+		return "", p.errorf("missing %s", name)
+	case len(value) > maxSDNameLength:
+		return "", fmt.Errorf("%s is longer than %d characters", name, maxSDNameLength)
+	}
+	return value, nil
+}
+
+// paramValue parses a quoted parameter value, unescaping '"', '\' and ']'.
+func (p *rfc5424Parser) paramValue() (string, error) {
+	if err := p.expect('"'); err != nil {
+		return "", err
+	}
+
+	var value []byte
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			return string(value), nil
+		case '\\':
+			if p.pos < len(p.data) {
+				switch next := p.data[p.pos]; next {
+				case '"', '\\', ']':
+					c = next
+					p.pos++
+				}
+			}
+		}
+		value = append(value, c)
+	}
+	return "", p.errorf("unterminated parameter value")
+}
+
+func (p *rfc5424Parser) number(maxDigits int) (int, error) {
+	start := p.pos
+	for p.pos < len(p.data) && p.pos-start < maxDigits && isDigit(p.data[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return 0, p.errorf("expected a number")
+	}
+	return strconv.Atoi(string(p.data[start:p.pos]))
+}
+
+func (p *rfc5424Parser) expect(c byte) error {
+	if p.pos == len(p.data) || p.data[p.pos] != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *rfc5424Parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isPrintUSASCII(c byte) bool {
+	return c >= 33 && c <= 126
+}
+
+func isSDNameChar(c byte) bool {
+	return isPrintUSASCII(c) && c != '=' && c != ']' && c != '"'
+}
@@ -0,0 +1,151 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRFC5424(t *testing.T) {
+	cases := map[string]bool{
+		"<34>1 2003-10-11T22:14:15.003Z mymachine su - ID47 - hello": true,
+		"<165>12 - - - - - -":                              true,
+		"<34>Oct 11 22:14:15 mymachine su: hello":          false,
+		"<34>2003-10-11T22:14:15.003Z mymachine su: hello": false,
+		"<34>1: Oct 11 22:14:15 mymachine su: hello":       false,
+		"<34>0 - - - - - -":                                false,
+		"1 2003-10-11T22:14:15.003Z mymachine su":          false,
+		"<34>": false,
+		"":     false,
+	}
+
+	for data, expected := range cases {
+		assert.Equal(t, expected, isRFC5424([]byte(data)), data)
+	}
+}
+
+func TestParseRFC5424(t *testing.T) {
+	cases := map[string]struct {
+		data     string
+		expected rfc5424
+	}{
+		"without structured data": {
+			data: "<34>1 2003-10-11T22:14:15.003Z mymachine.example.com su - ID47 - \xEF\xBB\xBF'su root' failed for lonvick on /dev/pts/8",
+			expected: rfc5424{
+				priority:  34,
+				version:   1,
+				timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				hostname:  "mymachine.example.com",
+				appName:   "su",
+				msgID:     "ID47",
+				message:   "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		"with structured data": {
+			data: `<165>1 2003-10-11T22:14:15.003-07:00 mymachine.example.com evntslog 8710 ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high"] An application event log entry...`,
+			expected: rfc5424{
+				priority:  165,
+				version:   1,
+				timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.FixedZone("", -7*60*60)),
+				hostname:  "mymachine.example.com",
+				appName:   "evntslog",
+				procID:    "8710",
+				msgID:     "ID47",
+				structuredData: map[string]map[string]interface{}{
+					"exampleSDID@32473": {
+						"iut":         "3",
+						"eventSource": "Application",
+						"eventID":     "1011",
+					},
+					"examplePriority@32473": {
+						"class": "high",
+					},
+				},
+				message: "An application event log entry...",
+			},
+		},
+		"only structured data": {
+			data: `<165>1 - - - - - [exampleSDID@32473 ip="192.0.2.1" ip="192.0.2.2" ip="192.0.2.3"][origin]`,
+			expected: rfc5424{
+				priority: 165,
+				version:  1,
+				structuredData: map[string]map[string]interface{}{
+					"exampleSDID@32473": {
+						"ip": []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"},
+					},
+					"origin": {},
+				},
+			},
+		},
+		"escaped parameter values": {
+			data: `<14>1 - host app - - [test@32473 quote="a \"b\"" backslash="c:\\dir\\" bracket="[x\]" other="\n"] hello`,
+			expected: rfc5424{
+				priority: 14,
+				version:  1,
+				hostname: "host",
+				appName:  "app",
+				structuredData: map[string]map[string]interface{}{
+					"test@32473": {
+						"quote":     `a "b"`,
+						"backslash": `c:\dir\`,
+						"bracket":   "[x]",
+						"other":     `\n`,
+					},
+				},
+				message: "hello",
+			},
+		},
+	}
+
+	for title, c := range cases {
+		t.Run(title, func(t *testing.T) {
+			msg, err := parseRFC5424([]byte(c.data))
+			require.NoError(t, err)
+
+			assert.True(t, c.expected.timestamp.Equal(msg.timestamp), "timestamp: %v", msg.timestamp)
+			c.expected.timestamp = msg.timestamp
+			assert.Equal(t, c.expected, *msg)
+		})
+	}
+}
+
+func TestParseRFC5424Errors(t *testing.T) {
+	cases := map[string]string{
+		"invalid priority":             "<192>1 - - - - - -",
+		"invalid timestamp":            "<34>1 Oct-11 - - - - -",
+		"missing structured data":      "<34>1 - host app - -",
+		"invalid structured data":      "<34>1 - host app - - hello",
+		"missing SD-ID":                "<34>1 - host app - - [ a=\"b\"]",
+		"unquoted parameter value":     "<34>1 - host app - - [id a=b]",
+		"unterminated parameter value": "<34>1 - host app - - [id a=\"b]",
+		"unterminated element":         "<34>1 - host app - - [id a=\"b\"",
+		"too long msgid":               "<34>1 - host app - 0123456789012345678901234567890123 -",
+		"no space before message":      "<34>1 - host app - - [id]hello",
+	}
+
+	for title, data := range cases {
+		t.Run(title, func(t *testing.T) {
+			_, err := parseRFC5424([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -22,6 +22,10 @@ package cursor
 type Cursor struct {
 	store    *store
 	resource *resource
+
+	// resources gives access to the cursors of the resources collected by the
+	// input for the source. It is nil for the cursors of resources.
+	resources *resourceAccess
 }
 
 func makeCursor(store *store, res *resource) Cursor {
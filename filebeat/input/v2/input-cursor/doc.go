@@ -55,4 +55,10 @@
 // An input that is about to collect a source that is already collected by
 // another input will wait until the other input has returned or the current
 // input did receive a shutdown signal.
+//
+// Inputs collecting multiple resources per source, that are only known at
+// runtime, can lock the cursor of each resource with Cursor.Resource. The
+// returned Resource publishes events and updates the cursor of the resource on
+// ACK, like the Publisher of the source. Resources still locked when an input
+// returns are released by the InputManager.
 package cursor
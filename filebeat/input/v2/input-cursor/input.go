@@ -148,6 +148,7 @@ func (inp *managedInput) runSource(
 	client, err := pipeline.ConnectWith(beat.ClientConfig{
 		CloseRef:   ctx.Cancelation,
 		ACKHandler: newInputACKHandler(ctx.Logger),
+		ShardKey:   ctx.ID,
 	})
 	if err != nil {
 		return err
@@ -164,6 +165,9 @@ func (inp *managedInput) runSource(
 	store.UpdateTTL(resource, inp.cleanTimeout)
 
 	cursor := makeCursor(store, resource)
+	cursor.resources = newResourceAccess(ctx, client, store, resourceKey, inp.cleanTimeout)
+	defer cursor.resources.close()
+
 	publisher := &cursorPublisher{canceler: ctx.Cancelation, client: client, cursor: &cursor}
 	return inp.input.Run(ctx, source, cursor, publisher)
 }
@@ -177,25 +181,39 @@ func (inp *managedInput) createSourceID(s Source) string {
 
 func newInputACKHandler(log *logp.Logger) beat.ACKer {
 	return acker.EventPrivateReporter(func(acked int, private []interface{}) {
-		var n uint
-		var last int
+		// The events of a batch can update the cursors of multiple resources.
+		// The updates are executed once per resource, with the last update of
+		// the resource found in the batch.
+		type resourceUpdates struct {
+			last *updateOp
+			n    uint
+		}
+		var order []*resource
+		updates := map[*resource]*resourceUpdates{}
 		for i := 0; i < len(private); i++ {
 			current := private[i]
 			if current == nil {
 				continue
 			}
 
-			if _, ok := current.(*updateOp); !ok {
+			op, ok := current.(*updateOp)
+			if !ok {
 				continue
 			}
 
-			n++
-			last = i
+			u := updates[op.resource]
+			if u == nil {
+				u = &resourceUpdates{}
+				updates[op.resource] = u
+				order = append(order, op.resource)
+			}
+			u.last = op
+			u.n++
 		}
 
-		if n == 0 {
-			return
+		for _, res := range order {
+			u := updates[res]
+			u.last.Execute(u.n)
 		}
-		private[last].(*updateOp).Execute(n)
 	})
 }
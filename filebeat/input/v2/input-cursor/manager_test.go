@@ -487,6 +487,144 @@ func TestManager_InputsRun(t *testing.T) {
 	})
 }
 
+func TestManager_InputsRunResources(t *testing.T) {
+	t.Run("continue sending from last known position per resource", func(t *testing.T) {
+		log := logp.NewLogger("test")
+
+		manager := constInput(t, sourceList("test"), &fakeTestInput{
+			OnRun: func(_ input.Context, _ Source, cursor Cursor, _ Publisher) error {
+				for _, key := range []string{"a", "b"} {
+					res, err := cursor.Resource(key)
+					if err != nil {
+						return err
+					}
+
+					state := struct{ N int }{}
+					if err := res.Unpack(&state); err != nil {
+						return fmt.Errorf("failed to unpack cursor: %w", err)
+					}
+					for i := 0; i < 2; i++ {
+						event := beat.Event{Fields: common.MapStr{"key": key, "n": state.N}}
+						state.N++
+						res.Publish(event, state)
+					}
+
+					// Resource b is released when Run returns.
+					if key == "a" {
+						res.Release()
+					}
+				}
+				return nil
+			},
+		})
+
+		var published []string
+		pipeline := pubtest.ConstClient(&pubtest.FakeClient{
+			PublishFunc: func(event beat.Event) {
+				published = append(published, fmt.Sprintf("%v%v", event.Fields["key"], event.Fields["n"]))
+			},
+		})
+
+		for i := 0; i < 2; i++ {
+			inp, err := manager.Create(common.NewConfig())
+			require.NoError(t, err)
+			require.NoError(t, inp.Run(input.Context{
+				Logger:      log,
+				Cancelation: context.Background(),
+			}, pipeline))
+		}
+
+		assert.Equal(t, []string{"a0", "a1", "b0", "b1", "a2", "a3", "b2", "b3"}, published)
+	})
+
+	t.Run("event ACK persists resource cursor", func(t *testing.T) {
+		defer resources.NewGoroutinesChecker().Check(t)
+
+		store := createSampleStore(t, nil)
+		var wgSend sync.WaitGroup
+		wgSend.Add(1)
+		manager := constInput(t, sourceList("key"), &fakeTestInput{
+			OnRun: func(ctx input.Context, _ Source, cursor Cursor, pub Publisher) error {
+				defer wgSend.Done()
+				res, err := cursor.Resource("object")
+				if err != nil {
+					return err
+				}
+				defer res.Release()
+
+				fields := common.MapStr{"hello": "world"}
+				res.Publish(beat.Event{Fields: fields}, "object-state1")
+				pub.Publish(beat.Event{Fields: fields}, "source-state1")
+				res.Publish(beat.Event{Fields: fields}, "object-state2")
+				return nil
+			},
+		})
+		manager.StateStore = store
+
+		inp, err := manager.Create(common.NewConfig())
+		require.NoError(t, err)
+
+		var acker beat.ACKer
+		pipeline := &pubtest.FakeConnector{
+			ConnectFunc: func(cfg beat.ClientConfig) (beat.Client, error) {
+				acker = cfg.ACKHandler
+				return &pubtest.FakeClient{
+					PublishFunc: func(event beat.Event) {
+						acker.AddEvent(event, true)
+					},
+				}, nil
+			},
+		}
+
+		require.NoError(t, inp.Run(v2.Context{
+			Logger:      manager.Logger,
+			Cancelation: context.Background(),
+		}, pipeline))
+		wgSend.Wait()
+
+		require.Equal(t, nil, store.snapshot()["test::key::object"].Cursor)
+
+		acker.ACKEvents(2)
+		require.Equal(t, "object-state1", store.snapshot()["test::key::object"].Cursor)
+		require.Equal(t, "source-state1", store.snapshot()["test::key"].Cursor)
+
+		acker.ACKEvents(1)
+		require.Equal(t, "object-state2", store.snapshot()["test::key::object"].Cursor)
+	})
+
+	t.Run("resource can not be locked twice", func(t *testing.T) {
+		manager := constInput(t, sourceList("test"), &fakeTestInput{
+			OnRun: func(_ input.Context, _ Source, cursor Cursor, _ Publisher) error {
+				res, err := cursor.Resource("a")
+				if err != nil {
+					return err
+				}
+
+				_, err = cursor.Resource("a")
+				assert.Error(t, err)
+				_, err = res.Resource("nested")
+				assert.Equal(t, errNoResources, err)
+
+				res.Release()
+				res, err = cursor.Resource("a")
+				if err != nil {
+					return err
+				}
+				res.Release()
+				return nil
+			},
+		})
+
+		inp, err := manager.Create(common.NewConfig())
+		require.NoError(t, err)
+		var clientCounters pubtest.ClientCounter
+		require.NoError(t, inp.Run(v2.Context{
+			Logger:      manager.Logger,
+			Cancelation: context.Background(),
+		}, clientCounters.BuildConnector()))
+	})
+}
+
 func TestLockResource(t *testing.T) {
 	t.Run("can lock unused resource", func(t *testing.T) {
 		store := testOpenStore(t, "test", createSampleStore(t, nil))
@@ -0,0 +1,167 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cursor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	input "github.com/elastic/beats/v7/filebeat/input/v2"
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+// Resource gives access to the cursor of a resource discovered by an input at
+// runtime. Inputs collecting multiple resources per source, like the objects
+// of a bucket or the partitions of a topic, use a Resource per collected
+// resource instead of encoding all positions in the cursor of the source.
+//
+// A Resource is locked by Cursor.Resource, so that only one input collects the
+// resource at a time. Events published with the Resource update its cursor
+// once they have been ACKed, like the events published with the Publisher of
+// the source.
+type Resource struct {
+	Cursor
+	publisher cursorPublisher
+
+	access *resourceAccess
+	key    string
+	once   sync.Once
+}
+
+// resourceAccess tracks the resources locked by an input for a source. All
+// locked resources are released when the input returns.
+type resourceAccess struct {
+	log      *logp.Logger
+	canceler input.Canceler
+	client   beat.Client
+	store    *store
+	prefix   string
+	ttl      time.Duration
+
+	mu     sync.Mutex
+	closed bool
+	active map[string]*Resource
+}
+
+var errNoResources = errors.New("the cursor does not support resources")
+
+var errResourcesClosed = errors.New("the input has returned, no resources can be accessed")
+
+// Resource locks and returns the cursor of a resource, identified by a key
+// that is unique within the source. Resource blocks until the resource is
+// unlocked by other inputs, or the input is stopped. The resource must be
+// released with Release once it has been collected. Resources not released
+// are released when Run returns.
+func (c Cursor) Resource(key string) (*Resource, error) {
+	if c.resources == nil {
+		return nil, errNoResources
+	}
+	return c.resources.lock(key)
+}
+
+// Publish publishes an event. If cursor is not nil, the cursor of the
+// resource is updated in memory, and in the persistent store after the event
+// has been ACKed.
+func (r *Resource) Publish(event beat.Event, cursor interface{}) error {
+	return r.publisher.Publish(event, cursor)
+}
+
+// Release unlocks the resource. Pending cursor updates are still written to
+// the persistent store once their events have been ACKed.
+func (r *Resource) Release() {
+	r.access.release(r)
+}
+
+func newResourceAccess(ctx input.Context, client beat.Client, store *store, prefix string, ttl time.Duration) *resourceAccess {
+	return &resourceAccess{
+		log:      ctx.Logger,
+		canceler: ctx.Cancelation,
+		client:   client,
+		store:    store,
+		prefix:   prefix,
+		ttl:      ttl,
+		active:   map[string]*Resource{},
+	}
+}
+
+func (a *resourceAccess) lock(key string) (*Resource, error) {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil, errResourcesClosed
+	}
+	if _, exists := a.active[key]; exists {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("resource '%v' is already in use by the input", key)
+	}
+	// Reserve the key, so that the resource is not locked twice while
+	// waiting for other inputs to release it.
+	a.active[key] = nil
+	a.mu.Unlock()
+
+	res := a.store.Get(a.prefix + "::" + key)
+	if err := lockResource(a.log, res, a.canceler); err != nil {
+		res.Release()
+		a.mu.Lock()
+		delete(a.active, key)
+		a.mu.Unlock()
+		return nil, err
+	}
+	a.store.UpdateTTL(res, a.ttl)
+
+	r := &Resource{Cursor: makeCursor(a.store, res), access: a, key: key}
+	r.publisher = cursorPublisher{canceler: a.canceler, client: a.client, cursor: &r.Cursor}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		releaseResource(res)
+		return nil, errResourcesClosed
+	}
+	a.active[key] = r
+	return r, nil
+}
+
+func (a *resourceAccess) release(r *Resource) {
+	r.once.Do(func() {
+		a.mu.Lock()
+		delete(a.active, r.key)
+		a.mu.Unlock()
+		releaseResource(r.resource)
+	})
+}
+
+// close releases all resources still locked, and fails future calls to lock.
+func (a *resourceAccess) close() {
+	a.mu.Lock()
+	a.closed = true
+	var active []*Resource
+	for _, r := range a.active {
+		if r != nil {
+			active = append(active, r)
+		}
+	}
+	a.mu.Unlock()
+
+	for _, r := range active {
+		a.release(r)
+	}
+}
@@ -88,6 +88,7 @@ func (si configuredInput) Run(ctx v2.Context, pipeline beat.PipelineConnector) (
 
 		// configure pipeline to disconnect input on stop signal.
 		CloseRef: ctx.Cancelation,
+		ShardKey: ctx.ID,
 	})
 	if err != nil {
 		return err
@@ -25,6 +25,7 @@ import (
 
 	"github.com/pkg/errors"
 
+	"github.com/elastic/beats/v7/filebeat/config"
 	"github.com/elastic/beats/v7/filebeat/input/file"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/monitoring"
@@ -49,6 +50,11 @@ type Registrar struct {
 	store        *statestore.Store // Store keeps states in memory and on disk
 	flushTimeout time.Duration
 
+	// ttl is the maximum age of finished states, 0 disables the expiration.
+	// The states are checked every expireInterval.
+	ttl            time.Duration
+	expireInterval time.Duration
+
 	gcEnabled, gcRequired bool
 }
 
@@ -63,6 +69,7 @@ type StateStore interface {
 var (
 	statesUpdate    = monitoring.NewInt(nil, "registrar.states.update")
 	statesCleanup   = monitoring.NewInt(nil, "registrar.states.cleanup")
+	statesExpired   = monitoring.NewInt(nil, "registrar.states.expired")
 	statesCurrent   = monitoring.NewInt(nil, "registrar.states.current")
 	registryWrites  = monitoring.NewInt(nil, "registrar.writes.total")
 	registryFails   = monitoring.NewInt(nil, "registrar.writes.fail")
@@ -73,21 +80,23 @@ const fileStatePrefix = "filebeat::logs::"
 
 // New creates a new Registrar instance, updating the registry file on
 // `file.State` updates. New fails if the file can not be opened or created.
-func New(stateStore StateStore, out successLogger, flushTimeout time.Duration) (*Registrar, error) {
+func New(stateStore StateStore, out successLogger, cfg config.Registry) (*Registrar, error) {
 	store, err := stateStore.Access()
 	if err != nil {
 		return nil, err
 	}
 
 	r := &Registrar{
-		log:          logp.NewLogger("registrar"),
-		Channel:      make(chan []file.State, 1),
-		out:          out,
-		done:         make(chan struct{}),
-		wg:           sync.WaitGroup{},
-		states:       file.NewStates(),
-		store:        store,
-		flushTimeout: flushTimeout,
+		log:            logp.NewLogger("registrar"),
+		Channel:        make(chan []file.State, 1),
+		out:            out,
+		done:           make(chan struct{}),
+		wg:             sync.WaitGroup{},
+		states:         file.NewStates(),
+		store:          store,
+		flushTimeout:   cfg.FlushTimeout,
+		ttl:            cfg.TTL,
+		expireInterval: cfg.CleanInterval,
 	}
 	return r, nil
 }
@@ -160,6 +169,13 @@ func (r *Registrar) Run() {
 		collectIn = r.Channel
 	}
 
+	var expireC <-chan time.Time
+	if r.ttl > 0 && r.expireInterval > 0 {
+		ticker := time.NewTicker(r.expireInterval)
+		defer ticker.Stop()
+		expireC = ticker.C
+	}
+
 	for {
 		select {
 		case <-r.done:
@@ -186,6 +202,10 @@ func (r *Registrar) Run() {
 
 			flushC = nil
 			timer = nil
+
+		case <-expireC:
+			r.expireStates()
+			statesCurrent.Set(int64(r.states.Count()))
 		}
 	}
 }
@@ -193,6 +213,7 @@ func (r *Registrar) Run() {
 func (r *Registrar) commitStateUpdates() {
 	// First clean up states
 	r.gcStates()
+	r.expireStates()
 	states := r.states.GetStates()
 	statesCurrent.Set(int64(len(states)))
 
@@ -259,6 +280,24 @@ func (r *Registrar) gcStates() {
 	r.gcEnabled = pendingClean > 0
 }
 
+// expireStates removes the finished states that have not been updated within
+// the configured TTL, independently of the TTL of the states set by the inputs.
+func (r *Registrar) expireStates() {
+	if r.ttl <= 0 {
+		return
+	}
+
+	expired := r.states.ExpireWith(r.ttl, func(id string) {
+		if err := r.store.Remove(fileStatePrefix + id); err != nil {
+			r.log.Errorf("Error removing expired state from statestore: %v", err)
+		}
+	})
+	if expired > 0 {
+		statesExpired.Add(int64(expired))
+		r.log.Debugf("Registrar removed %d states not updated for %v", expired, r.ttl)
+	}
+}
+
 // processEventStates gets the states from the events and writes them to the registrar state
 func (r *Registrar) processEventStates(states []file.State) {
 	r.log.Debugf("Processing %d events", len(states))
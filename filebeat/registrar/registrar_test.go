@@ -0,0 +1,70 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package registrar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/filebeat/config"
+	"github.com/elastic/beats/v7/filebeat/input/file"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/elastic/beats/v7/libbeat/statestore/storetest"
+)
+
+type testStateStore struct {
+	registry *statestore.Registry
+}
+
+func (s testStateStore) Access() (*statestore.Store, error) {
+	return s.registry.Get("filebeat")
+}
+
+func TestRegistrarExpireStates(t *testing.T) {
+	backend := storetest.NewMemoryStoreBackend()
+	stateStore := testStateStore{registry: statestore.NewRegistry(backend)}
+	defer stateStore.registry.Close()
+
+	store, err := stateStore.Access()
+	require.NoError(t, err)
+	now := time.Now()
+	for id, ts := range map[string]time.Time{"old": now.Add(-2 * time.Hour), "recent": now} {
+		st := file.State{Id: id, IdentifierName: "test", Source: "/var/log/" + id, Timestamp: ts, TTL: -1, Finished: true}
+		require.NoError(t, store.Set(fileStatePrefix+id, st))
+	}
+	store.Close()
+
+	cfg := config.DefaultConfig.Registry
+	cfg.TTL = time.Hour
+	cfg.CleanInterval = 10 * time.Millisecond
+	r, err := New(stateStore, nil, cfg)
+	require.NoError(t, err)
+	require.NoError(t, r.Start())
+
+	assert.Eventually(t, func() bool {
+		return len(r.GetStates()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	r.Stop()
+
+	table := backend.Stores["filebeat"].Table
+	assert.NotContains(t, table, fileStatePrefix+"old")
+	assert.Contains(t, table, fileStatePrefix+"recent")
+}
@@ -326,11 +326,32 @@ func (c *Checkpoint) read() (*PersistedState, error) {
 	c.fileLock.RLock()
 	defer c.fileLock.RUnlock()
 
-	contents, err := ioutil.ReadFile(c.file)
+	ps, err := readFile(c.file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return ps, err
+}
+
+// ReadStates reads the states persisted in a registry file, indexed by the
+// ID of their state. It doesn't modify the file, so it can be used to import
+// the states of Journalbeat while it's running.
+func ReadStates(file string) (map[string]JournalState, error) {
+	ps, err := readFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]JournalState, len(ps.States))
+	for _, state := range ps.States {
+		states[state.Path] = state
+	}
+	return states, nil
+}
+
+func readFile(file string) (*PersistedState, error) {
+	contents, err := ioutil.ReadFile(file)
 	if err != nil {
-		if os.IsNotExist(err) {
-			err = nil
-		}
 		return nil, err
 	}
 
@@ -343,6 +364,15 @@ func (c *Checkpoint) read() (*PersistedState, error) {
 	return ps, nil
 }
 
+// StateID returns the identifier of the state of a journal read by an input,
+// id is the ID of the input, if any.
+func StateID(id, path string) string {
+	if id == "" {
+		return path
+	}
+	return "journald::" + path + "::" + id
+}
+
 // createDir creates the directory in which the state file will reside if the
 // directory does not already exist.
 func (c *Checkpoint) createDir() error {
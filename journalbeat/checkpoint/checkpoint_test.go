@@ -215,6 +215,60 @@ func TestCreateDirAlreadyExists(t *testing.T) {
 	assert.NoError(t, cp.createDir())
 }
 
+// Test that the states of a registry file are read without modifying it.
+func TestReadStates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jb-checkpoint-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "registry")
+	registry := []byte(`update_time: 2020-10-14T12:24:51.193Z
+journal_entries:
+- path: LOCAL_SYSTEM_JOURNAL
+  cursor: s=abc;i=1
+  realtime_timestamp: 1602678291193000
+  monotonic_timestamp: 1000
+- path: journald::/var/log/journal/remote::my-input
+  cursor: s=def;i=2
+  realtime_timestamp: 1602678292000000
+  monotonic_timestamp: 2000
+`)
+	if err := ioutil.WriteFile(file, registry, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	states, err := ReadStates(file)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]JournalState{
+		"LOCAL_SYSTEM_JOURNAL": {
+			Path:               "LOCAL_SYSTEM_JOURNAL",
+			Cursor:             "s=abc;i=1",
+			RealtimeTimestamp:  1602678291193000,
+			MonotonicTimestamp: 1000,
+		},
+		"journald::/var/log/journal/remote::my-input": {
+			Path:               "journald::/var/log/journal/remote::my-input",
+			Cursor:             "s=def;i=2",
+			RealtimeTimestamp:  1602678292000000,
+			MonotonicTimestamp: 2000,
+		},
+	}, states)
+	assert.Contains(t, states, StateID("", "LOCAL_SYSTEM_JOURNAL"))
+	assert.Contains(t, states, StateID("my-input", "/var/log/journal/remote"))
+
+	contents, err := ioutil.ReadFile(file)
+	if assert.NoError(t, err) {
+		assert.Equal(t, registry, contents)
+	}
+
+	_, err = ReadStates(filepath.Join(dir, "missing"))
+	assert.True(t, os.IsNotExist(err))
+}
+
 // fileExists returns true if the specified file exists.
 func fileExists(file string) bool {
 	_, err := os.Stat(file)
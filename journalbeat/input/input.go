@@ -73,7 +73,7 @@ func New(
 			CursorSeekFallback: config.CursorSeekFallback,
 			Matches:            config.Matches,
 			SaveRemoteHostname: config.SaveRemoteHostname,
-			CheckpointID:       checkpointID(config.ID, reader.LocalSystemJournalID),
+			CheckpointID:       checkpoint.StateID(config.ID, reader.LocalSystemJournalID),
 		}
 
 		state := states[cfg.CheckpointID]
@@ -93,7 +93,7 @@ func New(
 			CursorSeekFallback: config.CursorSeekFallback,
 			Matches:            config.Matches,
 			SaveRemoteHostname: config.SaveRemoteHostname,
-			CheckpointID:       checkpointID(config.ID, p),
+			CheckpointID:       checkpoint.StateID(config.ID, p),
 		}
 
 		state := states[cfg.CheckpointID]
@@ -229,12 +229,3 @@ func processorsForInput(beatInfo beat.Info, config Config) (*processors.Processo
 
 	return procs, nil
 }
-
-// checkpointID returns the identifier used to track persistent state for the
-// input.
-func checkpointID(id, path string) string {
-	if id == "" {
-		return path
-	}
-	return "journald::" + path + "::" + id
-}
@@ -26,6 +26,10 @@ type Config struct {
 	Port               int    `config:"port"`
 	User               string `config:"named_pipe.user"`
 	SecurityDescriptor string `config:"named_pipe.security_descriptor"`
+
+	// AllowRemoteQueueResize allows resizing the queue when the endpoint
+	// accepts remote connections.
+	AllowRemoteQueueResize bool `config:"queue.allow_remote_resize"`
 }
 
 var (
@@ -0,0 +1,102 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// QueueResizer is implemented by the pipelines whose queue can be resized
+// while the Beat is running.
+type QueueResizer interface {
+	QueueSize() int
+	ResizeQueue(events int) error
+}
+
+// QueueInspector is implemented by the pipelines reporting the state of their
+// queue and output workers.
+type QueueInspector interface {
+	InspectQueue() common.MapStr
+}
+
+type queueRequest struct {
+	Events *int `json:"events"`
+}
+
+// MakeQueueHandler returns the handler of the queue route. GET reports the
+// size of the queue, PUT or POST resize it to the events set in the JSON
+// body of the request, like {"events": 8192}, if allowResize is true.
+func MakeQueueHandler(q QueueResizer, allowResize bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			if !allowResize {
+				writeError(w, r, http.StatusForbidden, fmt.Errorf(
+					"the queue can only be resized when the HTTP endpoint listens on localhost, "+
+						"a unix socket or a named pipe, unless http.queue.allow_remote_resize is enabled"))
+				return
+			}
+			var req queueRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, r, http.StatusBadRequest, fmt.Errorf("invalid request body: %v", err))
+				return
+			}
+			if req.Events == nil {
+				writeError(w, r, http.StatusBadRequest, fmt.Errorf("the events of the queue are required"))
+				return
+			}
+			if err := q.ResizeQueue(*req.Events); err != nil {
+				writeError(w, r, http.StatusBadRequest, err)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+
+		prettyPrint(w, common.MapStr{"events": q.QueueSize()}, r.URL)
+	}
+}
+
+// MakeQueueStateHandler returns the handler of the queue state route,
+// reporting the events in the queue, the age of the oldest one, and the
+// batches in flight of each output worker.
+func MakeQueueStateHandler(q QueueInspector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			writeError(w, r, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+			return
+		}
+		prettyPrint(w, q.InspectQueue(), r.URL)
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	w.WriteHeader(status)
+	prettyPrint(w, common.MapStr{"error": err.Error()}, r.URL)
+}
@@ -0,0 +1,102 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+type testQueue struct {
+	size int
+}
+
+func (q *testQueue) QueueSize() int { return q.size }
+
+func (q *testQueue) ResizeQueue(events int) error {
+	if events < 32 {
+		return errors.New("too small")
+	}
+	q.size = events
+	return nil
+}
+
+func TestQueueHandler(t *testing.T) {
+	q := &testQueue{size: 4096}
+	handler := MakeQueueHandler(q, true)
+
+	request := func(method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(method, "/queue", strings.NewReader(body)))
+		return w
+	}
+
+	w := request(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"events":4096}`, w.Body.String())
+
+	w = request(http.MethodPut, `{"events":8192}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"events":8192}`, w.Body.String())
+	assert.Equal(t, 8192, q.size)
+
+	for _, body := range []string{`{"events":10}`, `{}`, `not json`} {
+		w = request(http.MethodPost, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+		assert.Contains(t, w.Body.String(), `"error"`)
+	}
+	assert.Equal(t, 8192, q.size)
+
+	w = request(http.MethodDelete, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	// Resizing the queue can be forbidden.
+	handler = MakeQueueHandler(q, false)
+	w = request(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"events":8192}`, w.Body.String())
+	w = request(http.MethodPut, `{"events":4096}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, 8192, q.size)
+}
+
+type testInspector struct{}
+
+func (testInspector) InspectQueue() common.MapStr {
+	return common.MapStr{"queue": common.MapStr{"events": 10}}
+}
+
+func TestQueueStateHandler(t *testing.T) {
+	handler := MakeQueueStateHandler(testInspector{})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/queue/state", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"queue":{"events":10}}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/queue/state", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
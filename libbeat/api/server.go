@@ -67,6 +67,33 @@ func (s *Server) Start() {
 	}(s.l)
 }
 
+// AttachHandler adds the handler of a route to the running server. It fails if
+// the route has a handler already.
+func (s *Server) AttachHandler(route string, h http.Handler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("could not attach handler for %s: %v", route, r)
+		}
+	}()
+	s.mux.Handle(route, h)
+	s.log.Debugf("Attached handler to route %s", route)
+	return nil
+}
+
+// IsLocal returns true if the server only accepts local connections, as it
+// listens on a loopback address, a unix socket or a named pipe.
+func (s *Server) IsLocal() bool {
+	addr, ok := s.l.Addr().(*net.TCPAddr)
+	return !ok || addr.IP.IsLoopback()
+}
+
+// AllowQueueResize returns true if the queue can be resized through the
+// server, either because it only accepts local connections or because it is
+// allowed explicitly.
+func (s *Server) AllowQueueResize() bool {
+	return s.IsLocal() || s.config.AllowRemoteQueueResize
+}
+
 // Stop stops the API server and free any resource associated with the process like unix sockets.
 func (s *Server) Stop() error {
 	return s.l.Close()
@@ -24,6 +24,7 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 
@@ -177,6 +178,51 @@ func TestHTTP(t *testing.T) {
 	assert.Equal(t, "ehlo!", string(body))
 }
 
+func TestAllowQueueResize(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "testsocket")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	tests := map[string]struct {
+		config map[string]interface{}
+		local  bool
+		allow  bool
+	}{
+		"localhost": {
+			config: map[string]interface{}{"host": "http://localhost:0"},
+			local:  true,
+			allow:  true,
+		},
+		"unix socket": {
+			config: map[string]interface{}{"host": "unix://" + filepath.Join(tmpDir, "test.sock")},
+			local:  true,
+			allow:  true,
+		},
+		"all interfaces": {
+			config: map[string]interface{}{"host": "0.0.0.0", "port": 0},
+		},
+		"all interfaces allowed explicitly": {
+			config: map[string]interface{}{"host": "0.0.0.0", "port": 0, "queue.allow_remote_resize": true},
+			allow:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if name == "unix socket" && runtime.GOOS == "windows" {
+				t.Skip("Unix Sockets don't work under windows")
+			}
+
+			s, err := New(nil, simpleMux(), common.MustNewConfigFrom(test.config))
+			require.NoError(t, err)
+			defer s.Stop()
+
+			assert.Equal(t, test.local, s.IsLocal())
+			assert.Equal(t, test.allow, s.AllowQueueResize())
+		})
+	}
+}
+
 func simpleMux() *http.ServeMux {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/echo-hello", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,81 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package audit reports accesses to secrets and changes to the configuration
+// of the Beat as structured events in the log of the Beat, for compliance
+// review.
+//
+// Audit events are logged at info level by the `audit` logger. They only
+// contain the names of the keys and files involved, never their values.
+package audit
+
+import (
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+const logSelector = "audit"
+
+// Actions reported in the `event.action` field of the audit events.
+const (
+	ActionKeystoreRead   = "keystore-read"
+	ActionKeystoreCreate = "keystore-create"
+	ActionKeystoreAdd    = "keystore-add"
+	ActionKeystoreRemove = "keystore-remove"
+	ActionKeystoreList   = "keystore-list"
+	ActionKeystoreExport = "keystore-export"
+	ActionConfigReload   = "config-reload"
+	ActionConfigWrite    = "config-write"
+	ActionModulesEnable  = "modules-enable"
+	ActionModulesDisable = "modules-disable"
+)
+
+// Config configures the audit log.
+type Config struct {
+	Enabled bool `config:"enabled"`
+}
+
+var enabled = atomic.MakeBool(false)
+
+// Configure enables or disables the audit log. Audit events are disabled by
+// default.
+func Configure(cfg *common.Config) error {
+	config := Config{}
+	if cfg != nil {
+		if err := cfg.Unpack(&config); err != nil {
+			return err
+		}
+	}
+	enabled.Store(config.Enabled)
+	return nil
+}
+
+// Enabled returns true if audit events are logged.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Log reports an audit event for the action. The keysAndValues are added to
+// the event as fields, like in logp.Logger.Infow.
+func Log(action string, keysAndValues ...interface{}) {
+	if !Enabled() {
+		return
+	}
+	fields := append([]interface{}{"event.kind", "event", "event.action", action}, keysAndValues...)
+	logp.NewLogger(logSelector).Infow("Audit event "+action, fields...)
+}
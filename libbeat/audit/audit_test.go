@@ -0,0 +1,50 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+func TestLog(t *testing.T) {
+	require.NoError(t, logp.DevelopmentSetup(logp.ToObserverOutput()))
+	defer Configure(nil)
+
+	Log(ActionKeystoreRead, "keystore.key", "disabled")
+	assert.Equal(t, 0, logp.ObserverLogs().Len())
+
+	require.NoError(t, Configure(common.MustNewConfigFrom(map[string]interface{}{
+		"enabled": true,
+	})))
+	Log(ActionKeystoreRead, "keystore.key", "output.elasticsearch.password")
+
+	logs := logp.ObserverLogs().TakeAll()
+	require.Len(t, logs, 1)
+	assert.Equal(t, "audit", logs[0].LoggerName)
+	assert.Equal(t, map[string]interface{}{
+		"event.kind":   "event",
+		"event.action": ActionKeystoreRead,
+		"keystore.key": "output.elasticsearch.password",
+	}, logs[0].ContextMap())
+}
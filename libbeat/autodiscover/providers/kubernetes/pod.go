@@ -44,6 +44,7 @@ type pod struct {
 	nodeWatcher      kubernetes.Watcher
 	namespaceWatcher kubernetes.Watcher
 	namespaceStore   cache.Store
+	owners           *metadata.OwnerCache
 }
 
 // NewPodEventer creates an eventer that can discover and process pod objects
@@ -96,7 +97,13 @@ func NewPodEventer(uuid uuid.UUID, cfg *common.Config, client k8s.Interface, pub
 	if err != nil {
 		logger.Errorf("couldn't create watcher for %T due to error %+v", &kubernetes.Namespace{}, err)
 	}
-	metaGen := metadata.GetPodMetaGen(cfg, watcher, nodeWatcher, namespaceWatcher, metaConf)
+	var owners metadata.OwnerResolver
+	var ownerCache *metadata.OwnerCache
+	if ownersConfig := metaConf.GetOwnersConfig(); ownersConfig.Enabled {
+		ownerCache = metadata.NewOwnerCache(client, config.Namespace, ownersConfig)
+		owners = ownerCache
+	}
+	metaGen := metadata.GetPodMetaGen(cfg, watcher, nodeWatcher, namespaceWatcher, owners, metaConf)
 
 	p := &pod{
 		config:           config,
@@ -107,6 +114,7 @@ func NewPodEventer(uuid uuid.UUID, cfg *common.Config, client k8s.Interface, pub
 		watcher:          watcher,
 		nodeWatcher:      nodeWatcher,
 		namespaceWatcher: namespaceWatcher,
+		owners:           ownerCache,
 	}
 
 	watcher.AddEventHandler(p)
@@ -240,6 +248,10 @@ func (p *pod) Start() error {
 		}
 	}
 
+	if p.owners != nil {
+		p.owners.Start()
+	}
+
 	return p.watcher.Start()
 }
 
@@ -247,6 +259,10 @@ func (p *pod) Start() error {
 func (p *pod) Stop() {
 	p.watcher.Stop()
 
+	if p.owners != nil {
+		p.owners.Stop()
+	}
+
 	if p.namespaceWatcher != nil {
 		p.namespaceWatcher.Stop()
 	}
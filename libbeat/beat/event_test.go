@@ -137,3 +137,19 @@ func TestEventMetadata(t *testing.T) {
 		assert.Equal(t, newMeta(), evt.Meta)
 	})
 }
+
+func TestEventPriority(t *testing.T) {
+	evt := newEmptyEvent()
+	assert.Equal(t, PriorityNormal, evt.Priority())
+
+	evt.SetPriority(PriorityHigh)
+	assert.Equal(t, common.MapStr{"priority": "high"}, evt.Meta)
+	assert.Equal(t, PriorityHigh, evt.Priority())
+
+	// The priority can be set by processors in @metadata.
+	evt.PutValue("@metadata.priority", "HIGH")
+	assert.Equal(t, PriorityHigh, evt.Priority())
+
+	evt.PutValue("@metadata.priority", "urgent")
+	assert.Equal(t, PriorityNormal, evt.Priority())
+}
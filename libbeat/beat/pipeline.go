@@ -59,6 +59,18 @@ type ClientConfig struct {
 
 	// Events configures callbacks for common client callbacks
 	Events ClientEventer
+
+	// RecycleFields passes the ownership of the Fields of the published events
+	// to the pipeline. Once an event has been acknowledged, or stored by a
+	// persistent queue, its Fields are returned to the pool used by
+	// common.GetMapStr. Set it only if the client does not access the Fields
+	// after publishing an event. Nested objects are never released.
+	RecycleFields bool
+
+	// ShardKey assigns the client to a shard of a sharded pipeline. All clients
+	// with the same key publish to the same shard, clients without key are
+	// distributed between the shards.
+	ShardKey string
 }
 
 // ACKer can be registered with a Client when connecting to the pipeline.
@@ -118,6 +130,13 @@ type ProcessingConfig struct {
 	// KeepNull determines whether published events will keep null values or omit them.
 	KeepNull bool
 
+	// SkipNormalization disables the normalization of the events of the
+	// client. Set it if the events only hold MapStr, map[string]interface{},
+	// slices and primitive values, like events decoded from JSON, to avoid the
+	// cost of copying them. Empty events are still dropped. With debug logging
+	// enabled the events are checked, and normalized if needed.
+	SkipNormalization bool
+
 	// Disables the addition of host.name if it was enabled for the publisher.
 	DisableHost bool
 
@@ -0,0 +1,89 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package beat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// Priority of an event. If the outputs can't keep up, the pipeline publishes
+// the events with a high priority before the other events.
+type Priority uint8
+
+const (
+	// PriorityNormal is the priority of the events without a priority.
+	PriorityNormal Priority = iota
+
+	// PriorityHigh is the priority of the events published first, like
+	// security alerts.
+	PriorityHigh
+)
+
+// PriorityKey is the key of the priority in the events metadata.
+const PriorityKey = "priority"
+
+var priorityNames = map[Priority]string{
+	PriorityNormal: "normal",
+	PriorityHigh:   "high",
+}
+
+// ParsePriority returns the priority with the given name.
+func ParsePriority(name string) (Priority, error) {
+	for p, n := range priorityNames {
+		if strings.EqualFold(name, n) {
+			return p, nil
+		}
+	}
+	return PriorityNormal, fmt.Errorf("unknown priority '%v'", name)
+}
+
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("priority(%d)", uint8(p))
+}
+
+// SetPriority sets the priority of the event in the events metadata.
+// If Meta is nil, a new Meta dictionary is created.
+func (e *Event) SetPriority(p Priority) {
+	if e.Meta == nil {
+		e.Meta = common.MapStr{}
+	}
+	e.Meta[PriorityKey] = p.String()
+}
+
+// Priority returns the priority set in the events metadata. Events without a
+// valid priority have the normal priority.
+func (e *Event) Priority() Priority {
+	if e.Meta == nil {
+		return PriorityNormal
+	}
+	name, ok := e.Meta[PriorityKey].(string)
+	if !ok {
+		return PriorityNormal
+	}
+	p, err := ParsePriority(name)
+	if err != nil {
+		return PriorityNormal
+	}
+	return p
+}
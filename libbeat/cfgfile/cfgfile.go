@@ -94,6 +94,22 @@ func GetDefaultCfgfile() string {
 	return cfg
 }
 
+// ConfigFiles returns the paths of the configuration files given with the `-c`
+// flag, relative paths are resolved against path.config.
+func ConfigFiles() []string {
+	cfgpath := GetPathConfig()
+
+	list := []string{}
+	for _, cfg := range configfiles.List() {
+		if !filepath.IsAbs(cfg) {
+			list = append(list, filepath.Join(cfgpath, cfg))
+		} else {
+			list = append(list, cfg)
+		}
+	}
+	return list
+}
+
 // HandleFlags adapts default config settings based on command line flags.
 func HandleFlags() error {
 	// default for the home path is the binary location
@@ -139,15 +155,7 @@ func Load(path string, beatOverrides []ConditionalOverride) (*common.Config, err
 	cfgpath := GetPathConfig()
 
 	if path == "" {
-		list := []string{}
-		for _, cfg := range configfiles.List() {
-			if !filepath.IsAbs(cfg) {
-				list = append(list, filepath.Join(cfgpath, cfg))
-			} else {
-				list = append(list, cfg)
-			}
-		}
-		config, err = common.LoadFiles(list...)
+		config, err = common.LoadFiles(ConfigFiles()...)
 	} else {
 		if !filepath.IsAbs(path) {
 			path = filepath.Join(cfgpath, path)
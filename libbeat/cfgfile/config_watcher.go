@@ -0,0 +1,155 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cfgfile
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+// ReloadSettings returns the reload settings read from cfg, which has the
+// reload.enabled and reload.period settings. Reloading is disabled by default.
+func ReloadSettings(cfg *common.Config) (Reload, error) {
+	config := DefaultDynamicConfig
+	if cfg != nil {
+		if err := cfg.Unpack(&config); err != nil {
+			return config.Reload, err
+		}
+	}
+	if config.Reload.Period <= 0 {
+		return config.Reload, errors.New("reload.period must be positive")
+	}
+	return config.Reload, nil
+}
+
+// ConfigWatcher periodically checks the configuration files given with the
+// `-c` flag for changes, and calls a function with the configuration loaded
+// again from them.
+type ConfigWatcher struct {
+	watchers []*GlobWatcher
+	period   time.Duration
+	load     func() (*common.Config, error)
+	onChange func(*common.Config)
+	log      *logp.Logger
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewConfigWatcher returns a watcher checking the configuration files for
+// changes every period. The configuration is loaded with the same overrides
+// as on startup.
+func NewConfigWatcher(
+	period time.Duration,
+	beatOverrides []ConditionalOverride,
+	onChange func(*common.Config),
+) *ConfigWatcher {
+	return newConfigWatcher(ConfigFiles(), period, func() (*common.Config, error) {
+		return Load("", beatOverrides)
+	}, onChange)
+}
+
+func newConfigWatcher(
+	files []string,
+	period time.Duration,
+	load func() (*common.Config, error),
+	onChange func(*common.Config),
+) *ConfigWatcher {
+	w := &ConfigWatcher{
+		period:   period,
+		load:     load,
+		onChange: onChange,
+		log:      logp.NewLogger("cfgwatcher"),
+		done:     make(chan struct{}),
+	}
+	for _, f := range files {
+		gw := NewGlobWatcher(f)
+		// The first scan only records the current state of the file.
+		gw.Scan()
+		w.watchers = append(w.watchers, gw)
+	}
+	return w
+}
+
+// Start starts watching the configuration files in the background.
+func (w *ConfigWatcher) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.run()
+	}()
+}
+
+// Stop stops watching the configuration files and waits for a pending call to
+// the change function to return.
+func (w *ConfigWatcher) Stop() {
+	close(w.done)
+	w.wg.Wait()
+}
+
+func (w *ConfigWatcher) run() {
+	ticker := time.NewTicker(w.period)
+	defer ticker.Stop()
+
+	pending := false
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		if w.changed() {
+			pending = true
+		}
+		if !pending {
+			continue
+		}
+
+		config, err := w.load()
+		if err != nil {
+			// Keep the current configuration and try again on the next
+			// tick, the file may be in the middle of being edited.
+			w.log.Warnf("Failed to reload the configuration files: %v", err)
+			continue
+		}
+		pending = false
+		configReloads.Inc()
+		w.onChange(config)
+	}
+}
+
+// changed reports whether one of the files may have changed since the last
+// check. All files are scanned so their state is up to date.
+func (w *ConfigWatcher) changed() bool {
+	configScans.Inc()
+
+	changed := false
+	for _, gw := range w.watchers {
+		_, updated, err := gw.Scan()
+		if err != nil {
+			w.log.Warnf("Failed to check the configuration files for changes: %v", err)
+		}
+		changed = changed || updated
+	}
+	return changed
+}
@@ -0,0 +1,122 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cfgfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestConfigWatcherNotifiesChanges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config_watcher")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "beat.yml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("output.console.pretty: false\n"), 0600))
+
+	changes := make(chan *common.Config, 1)
+	watcher := newConfigWatcher([]string{path}, 10*time.Millisecond, func() (*common.Config, error) {
+		return common.LoadFile(path)
+	}, func(config *common.Config) {
+		select {
+		case changes <- config:
+		default:
+		}
+	})
+	watcher.Start()
+	defer watcher.Stop()
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("output.console.pretty: true\n"), 0600))
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case config := <-changes:
+			pretty, err := config.Bool("output.console.pretty", -1)
+			require.NoError(t, err)
+			if pretty {
+				return
+			}
+		case <-timeout:
+			t.Fatal("the change of the configuration file was not notified")
+		}
+	}
+}
+
+func TestConfigWatcherRetriesFailedLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config_watcher")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "beat.yml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("output.console.pretty: false\n"), 0600))
+
+	attempts := 0
+	changes := make(chan *common.Config, 1)
+	watcher := newConfigWatcher([]string{path}, 10*time.Millisecond, func() (*common.Config, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, assert.AnError
+		}
+		return common.LoadFile(path)
+	}, func(config *common.Config) {
+		select {
+		case changes <- config:
+		default:
+		}
+	})
+	watcher.Start()
+	defer watcher.Stop()
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("output.console.pretty: true\n"), 0600))
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the configuration was not loaded again after a failure")
+	}
+}
+
+func TestReloadSettings(t *testing.T) {
+	settings, err := ReloadSettings(nil)
+	require.NoError(t, err)
+	assert.False(t, settings.Enabled)
+	assert.Equal(t, 10*time.Second, settings.Period)
+
+	settings, err = ReloadSettings(common.MustNewConfigFrom(map[string]interface{}{
+		"reload.enabled": true,
+		"reload.period":  "1m",
+	}))
+	require.NoError(t, err)
+	assert.True(t, settings.Enabled)
+	assert.Equal(t, time.Minute, settings.Period)
+
+	_, err = ReloadSettings(common.MustNewConfigFrom(map[string]interface{}{
+		"reload.period": 0,
+	}))
+	assert.Error(t, err)
+}
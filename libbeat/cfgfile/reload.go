@@ -26,6 +26,7 @@ import (
 	"github.com/joeshaw/multierror"
 	"github.com/pkg/errors"
 
+	"github.com/elastic/beats/v7/libbeat/audit"
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/reload"
@@ -206,6 +207,7 @@ func (rl *Reloader) Run(runnerFactory RunnerFactory) {
 				continue
 			}
 			configReloads.Add(1)
+			audit.Log(audit.ActionConfigReload, "config.path", rl.path, "config.files", files)
 
 			// Load all config objects
 			configs, _ := rl.loadConfigs(files)
@@ -0,0 +1,345 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package bench implements the `bench` command, publishing synthetic events
+// through the publisher pipeline and the configured output to measure the
+// throughput and the resource usage of the Beat.
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/common/acker"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/common/cli"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+)
+
+// sampleInterval is the interval between samples of the queue and memory
+// usage.
+const sampleInterval = 100 * time.Millisecond
+
+// GenBenchCmd generates the command for benchmarking the pipeline and the
+// configured output with synthetic events.
+func GenBenchCmd(settings instance.Settings) *cobra.Command {
+	var (
+		b    benchmark
+		size string
+	)
+
+	command := &cobra.Command{
+		Use:   "bench",
+		Short: "Publish synthetic events to the configured output and report the throughput",
+		Long: `Publish synthetic events through the configured queue, processors and output
+for the given duration, at the given rate, and report the throughput, the queue
+usage and the resource usage of the Beat.`,
+		Args: cobra.NoArgs,
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			var err error
+			if b.sizes, err = parseSizeRange(size); err != nil {
+				return err
+			}
+			if b.workers < 1 {
+				return fmt.Errorf("the number of workers must be at least 1")
+			}
+
+			bt, err := instance.NewInitializedBeat(settings)
+			if err != nil {
+				return fmt.Errorf("error initializing beat: %v", err)
+			}
+
+			b.metrics = monitoring.NewRegistry()
+			pipeline, err := bt.CreateBenchPipeline(b.metrics)
+			if err != nil {
+				return fmt.Errorf("error initializing publisher: %v", err)
+			}
+			defer pipeline.Close()
+
+			b.progress = os.Stderr
+			res, err := b.run(pipeline)
+			if err != nil {
+				return err
+			}
+			res.report(os.Stdout)
+			if res.acked < res.published {
+				return fmt.Errorf("%d events were not acknowledged within %v", res.published-res.acked, b.timeout)
+			}
+			return nil
+		}),
+	}
+
+	command.Flags().Float64Var(&b.rate, "rate", 0, "Target number of events published per second, 0 publishes as fast as possible")
+	command.Flags().DurationVar(&b.duration, "duration", 30*time.Second, "Duration of the benchmark")
+	command.Flags().StringVar(&size, "size", "256", "Size of the event messages in bytes, or range of sizes like 128-4096")
+	command.Flags().IntVar(&b.workers, "workers", 1, "Number of clients publishing events concurrently")
+	command.Flags().DurationVar(&b.timeout, "timeout", 30*time.Second, "Maximum time to wait for the output to acknowledge the events")
+	command.Flags().DurationVar(&b.interval, "report-interval", 5*time.Second, "Interval between progress reports, 0 disables them")
+	return command
+}
+
+// benchmark publishes synthetic events for a given duration.
+type benchmark struct {
+	// rate is the target number of events published per second, or 0 to
+	// publish as fast as possible.
+	rate float64
+
+	duration time.Duration
+	sizes    sizeRange
+	workers  int
+
+	// timeout is the time to wait for the events to be acknowledged.
+	timeout time.Duration
+
+	// interval between the progress reports written to progress.
+	interval time.Duration
+	progress io.Writer
+
+	// metrics of the pipeline and the output, if any.
+	metrics *monitoring.Registry
+}
+
+// result of a benchmark.
+type result struct {
+	// duration of the publishing, and time to wait for the acknowledgement of
+	// the pending events after it.
+	duration time.Duration
+	drain    time.Duration
+
+	published uint64
+	acked     uint64
+	bytes     uint64
+
+	// queue usage, measured as the number of active events in the pipeline.
+	maxActive uint64
+	avgActive float64
+
+	cpu      time.Duration
+	peakHeap uint64
+	sysBytes uint64
+
+	// output metrics, if available.
+	output monitoring.FlatSnapshot
+}
+
+// run publishes the events and waits for them to be acknowledged.
+func (b *benchmark) run(pipeline beat.PipelineConnector) (result, error) {
+	var (
+		res       result
+		seq       atomic.Uint64
+		acked     atomic.Uint64
+		bytes     atomic.Uint64
+		published atomic.Uint64
+	)
+
+	clients := make([]beat.Client, b.workers)
+	for i := range clients {
+		client, err := pipeline.ConnectWith(beat.ClientConfig{
+			PublishMode: beat.GuaranteedSend,
+			WaitClose:   b.timeout,
+			ACKHandler:  acker.RawCounting(func(n int) { acked.Add(uint64(n)) }),
+		})
+		if err != nil {
+			for _, c := range clients[:i] {
+				c.Close()
+			}
+			return res, err
+		}
+		clients[i] = client
+	}
+
+	var limiter *rate.Limiter
+	if b.rate > 0 {
+		// Allow bursts of up to 10ms worth of events to keep the rate at high
+		// targets.
+		limiter = rate.NewLimiter(rate.Limit(b.rate), int(b.rate/100)+1)
+	}
+
+	cpuStart, _ := cpuTime()
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), b.duration)
+	defer cancel()
+
+	sampler := newSampler(b.metrics)
+	samplerDone := make(chan struct{})
+	go func() {
+		defer close(samplerDone)
+		sampler.run(ctx, func() {
+			b.reportProgress(time.Since(start), published.Load(), acked.Load(), sampler.active)
+		}, b.interval)
+	}()
+
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(client beat.Client, seed int64) {
+			defer wg.Done()
+			gen := newGenerator(b.sizes, seed)
+			for ctx.Err() == nil {
+				if limiter != nil && limiter.Wait(ctx) != nil {
+					return
+				}
+				event, size := gen.next(seq.Inc())
+				client.Publish(event)
+				published.Inc()
+				bytes.Add(uint64(size))
+			}
+		}(client, time.Now().UnixNano()+int64(i))
+	}
+	wg.Wait()
+	res.duration = time.Since(start)
+
+	// Closing the clients waits for the pending events to be acknowledged.
+	drainStart := time.Now()
+	for _, client := range clients {
+		client.Close()
+	}
+	res.drain = time.Since(drainStart)
+	<-samplerDone
+
+	if cpuEnd, err := cpuTime(); err == nil {
+		res.cpu = cpuEnd - cpuStart
+	}
+	res.published = published.Load()
+	res.acked = acked.Load()
+	res.bytes = bytes.Load()
+	res.maxActive, res.avgActive = sampler.maxActive, sampler.avgActive()
+	res.peakHeap, res.sysBytes = sampler.peakHeap, sampler.sysBytes
+	if b.metrics != nil {
+		res.output = monitoring.CollectFlatSnapshot(b.metrics, monitoring.Full, false)
+	}
+	return res, nil
+}
+
+func (b *benchmark) reportProgress(elapsed time.Duration, published, acked, active uint64) {
+	if b.progress == nil {
+		return
+	}
+	fmt.Fprintf(b.progress, "%v: published %d events (%.1f/s), acknowledged %d, %d active in the pipeline\n",
+		elapsed.Round(time.Second), published, perSecond(published, elapsed), acked, active)
+}
+
+// sampler samples the number of active events in the pipeline and the memory
+// usage of the process.
+type sampler struct {
+	metrics *monitoring.Registry
+
+	active    uint64
+	maxActive uint64
+	sumActive uint64
+	samples   uint64
+
+	peakHeap uint64
+	sysBytes uint64
+}
+
+func newSampler(metrics *monitoring.Registry) *sampler {
+	return &sampler{metrics: metrics}
+}
+
+// run samples until the context is done, calling progress at every interval.
+func (s *sampler) run(ctx context.Context, progress func(), interval time.Duration) {
+	ticker := time.NewTicker(sampleInterval)
+	defer ticker.Stop()
+
+	lastProgress := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.sample()
+		if interval > 0 && time.Since(lastProgress) >= interval {
+			lastProgress = time.Now()
+			progress()
+		}
+	}
+}
+
+func (s *sampler) sample() {
+	if s.metrics != nil {
+		snapshot := monitoring.CollectFlatSnapshot(s.metrics, monitoring.Full, false)
+		s.active = uint64(snapshot.Ints["pipeline.events.active"])
+		if s.active > s.maxActive {
+			s.maxActive = s.active
+		}
+		s.sumActive += s.active
+		s.samples++
+	}
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	if stats.HeapAlloc > s.peakHeap {
+		s.peakHeap = stats.HeapAlloc
+	}
+	s.sysBytes = stats.Sys
+}
+
+func (s *sampler) avgActive() float64 {
+	if s.samples == 0 {
+		return 0
+	}
+	return float64(s.sumActive) / float64(s.samples)
+}
+
+func (r result) report(w io.Writer) {
+	line := func(name, format string, args ...interface{}) {
+		fmt.Fprintf(w, "%-24s"+format+"\n", append([]interface{}{name}, args...)...)
+	}
+
+	line("Duration", "%v", r.duration.Round(time.Millisecond))
+	line("Published events", "%d (%.1f events/s)", r.published, perSecond(r.published, r.duration))
+	line("Acknowledged events", "%d (%.1f events/s end to end)", r.acked, perSecond(r.acked, r.duration+r.drain))
+	line("Message bytes", "%s (%s/s)", humanize.Bytes(r.bytes), humanize.Bytes(uint64(perSecond(r.bytes, r.duration))))
+	line("Drain time", "%v", r.drain.Round(time.Millisecond))
+	line("Queue active events", "max %d, average %.1f", r.maxActive, r.avgActive)
+	if r.output.Ints != nil {
+		line("Output batches", "%d", r.output.Ints["output.events.batches"])
+		line("Output failed events", "%d", r.output.Ints["output.events.failed"])
+		line("Output dropped events", "%d", r.output.Ints["output.events.dropped"])
+		line("Retried events", "%d", r.output.Ints["pipeline.events.retry"])
+		if written := r.output.Ints["output.write.bytes"]; written > 0 {
+			line("Output bytes written", "%s", humanize.Bytes(uint64(written)))
+		}
+	}
+	if r.cpu > 0 {
+		line("CPU time", "%v (%.1f%% of one core)", r.cpu.Round(time.Millisecond),
+			100*r.cpu.Seconds()/(r.duration+r.drain).Seconds())
+	}
+	line("Peak heap", "%s", humanize.Bytes(r.peakHeap))
+	line("Memory from the OS", "%s", humanize.Bytes(r.sysBytes))
+}
+
+func perSecond(n uint64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
@@ -0,0 +1,115 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bench
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+)
+
+func TestParseSizeRange(t *testing.T) {
+	tests := map[string]sizeRange{
+		"512":      {min: 512, max: 512},
+		"128-4096": {min: 128, max: 4096},
+		"0":        {min: 0, max: 0},
+	}
+	for s, expected := range tests {
+		r, err := parseSizeRange(s)
+		if assert.NoError(t, err, s) {
+			assert.Equal(t, expected, r)
+			assert.Equal(t, s, r.String())
+		}
+	}
+
+	for _, s := range []string{"", "abc", "10-", "-10", "100-10"} {
+		_, err := parseSizeRange(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	gen := newGenerator(sizeRange{min: 10, max: 20}, 1)
+	for i := uint64(1); i <= 100; i++ {
+		event, size := gen.next(i)
+		assert.True(t, size >= 10 && size <= 20, "size %d out of range", size)
+		assert.Len(t, event.Fields["message"], size)
+		seq, _ := event.Fields.GetValue("bench.seq")
+		assert.Equal(t, i, seq)
+	}
+}
+
+func TestBenchmarkRun(t *testing.T) {
+	b := benchmark{
+		rate:     1000,
+		duration: 200 * time.Millisecond,
+		sizes:    sizeRange{min: 100, max: 100},
+		workers:  2,
+		timeout:  time.Second,
+	}
+
+	res, err := b.run(ackingPipeline{})
+	require.NoError(t, err)
+
+	assert.True(t, res.published > 0)
+	// The rate limiter allows a burst of 10ms worth of events.
+	assert.True(t, res.published <= 220, "published %d events", res.published)
+	assert.Equal(t, res.published, res.acked)
+	assert.Equal(t, res.published*100, res.bytes)
+
+	var out bytes.Buffer
+	res.report(&out)
+	assert.Contains(t, out.String(), "Published events")
+	assert.Contains(t, out.String(), "Acknowledged events")
+}
+
+// ackingPipeline acknowledges the events as soon as they are published.
+type ackingPipeline struct{}
+
+func (ackingPipeline) Connect() (beat.Client, error) {
+	return ackingPipeline{}.ConnectWith(beat.ClientConfig{})
+}
+
+func (ackingPipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
+	return &ackingClient{acker: cfg.ACKHandler}, nil
+}
+
+type ackingClient struct {
+	acker beat.ACKer
+}
+
+func (c *ackingClient) Publish(event beat.Event) {
+	c.acker.AddEvent(event, true)
+	c.acker.ACKEvents(1)
+}
+
+func (c *ackingClient) PublishAll(events []beat.Event) {
+	for _, event := range events {
+		c.Publish(event)
+	}
+}
+
+func (c *ackingClient) Close() error {
+	c.acker.Close()
+	return nil
+}
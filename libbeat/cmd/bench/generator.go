@@ -0,0 +1,102 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 "
+
+// sizeRange is the range of sizes of the messages of the synthetic events.
+// Sizes are uniformly distributed between min and max, both included.
+type sizeRange struct {
+	min, max int
+}
+
+// parseSizeRange parses a fixed size, like `512`, or a range of sizes, like
+// `128-4096`.
+func parseSizeRange(s string) (sizeRange, error) {
+	parts := strings.SplitN(s, "-", 2)
+	min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return sizeRange{}, fmt.Errorf("invalid size '%s'", s)
+	}
+	max := min
+	if len(parts) == 2 {
+		if max, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+			return sizeRange{}, fmt.Errorf("invalid size '%s'", s)
+		}
+	}
+	if min < 0 || max < min {
+		return sizeRange{}, fmt.Errorf("invalid size '%s', sizes must be positive and ordered", s)
+	}
+	return sizeRange{min: min, max: max}, nil
+}
+
+func (r sizeRange) String() string {
+	if r.min == r.max {
+		return strconv.Itoa(r.min)
+	}
+	return fmt.Sprintf("%d-%d", r.min, r.max)
+}
+
+// generator creates the synthetic events. It is not safe for concurrent use.
+type generator struct {
+	sizes sizeRange
+	rnd   *rand.Rand
+}
+
+func newGenerator(sizes sizeRange, seed int64) *generator {
+	return &generator{sizes: sizes, rnd: rand.New(rand.NewSource(seed))}
+}
+
+// next returns the event with the given sequence number and the size of its
+// message.
+func (g *generator) next(seq uint64) (beat.Event, int) {
+	size := g.sizes.min
+	if g.sizes.max > g.sizes.min {
+		size += g.rnd.Intn(g.sizes.max - g.sizes.min + 1)
+	}
+
+	message := make([]byte, size)
+	for i := range message {
+		message[i] = letters[g.rnd.Intn(len(letters))]
+	}
+
+	return beat.Event{
+		Timestamp: time.Now(),
+		Fields: common.MapStr{
+			"message": string(message),
+			"event": common.MapStr{
+				"kind":    "event",
+				"dataset": "bench",
+			},
+			"bench": common.MapStr{
+				"seq": seq,
+			},
+		},
+	}, size
+}
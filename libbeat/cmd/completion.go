@@ -19,22 +19,24 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
-
-	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
 )
 
 func genCompletionCmd(_ instance.Settings, rootCmd *BeatsRootCmd) *cobra.Command {
 	completionCmd := cobra.Command{
-		Use:   "completion SHELL",
-		Short: "Output shell completion code for the specified shell (bash and zsh only by the moment)",
-		// We don't want to expose this one in help:
-		Hidden: true,
+		Use:       "completion SHELL",
+		Short:     "Output shell completion code for the specified shell (bash, zsh or fish)",
+		ValidArgs: []string{"bash", "zsh", "fish"},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) != 1 {
-				fmt.Println("Expected one argument with the desired shell")
+				fmt.Fprintln(os.Stderr, "Expected one argument with the desired shell")
 				os.Exit(1)
 			}
 
@@ -43,8 +45,10 @@ func genCompletionCmd(_ instance.Settings, rootCmd *BeatsRootCmd) *cobra.Command
 				rootCmd.GenBashCompletion(os.Stdout)
 			case "zsh":
 				rootCmd.GenZshCompletion(os.Stdout)
+			case "fish":
+				genFishCompletion(os.Stdout, &rootCmd.Command)
 			default:
-				fmt.Printf("Unknown shell %s, only bash and zsh are available\n", args[0])
+				fmt.Fprintf(os.Stderr, "Unknown shell %s, only bash, zsh and fish are available\n", args[0])
 				os.Exit(1)
 			}
 		},
@@ -52,3 +56,84 @@ func genCompletionCmd(_ instance.Settings, rootCmd *BeatsRootCmd) *cobra.Command
 
 	return &completionCmd
 }
+
+// genFishCompletion writes the fish completions for the subcommands and flags
+// of the given command.
+func genFishCompletion(w io.Writer, root *cobra.Command) {
+	name := root.Name()
+	fmt.Fprintf(w, "# fish completion for %s\n", name)
+	fmt.Fprintf(w, "complete -c %s -e\n", name)
+
+	root.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		fishFlag(w, name, "", f)
+	})
+	root.LocalNonPersistentFlags().VisitAll(func(f *pflag.Flag) {
+		fishFlag(w, name, "__fish_use_subcommand", f)
+	})
+	fishSubcommands(w, name, nil, root)
+}
+
+func fishSubcommands(w io.Writer, name string, path []string, parent *cobra.Command) {
+	var siblings []string
+	for _, cmd := range parent.Commands() {
+		if cmd.IsAvailableCommand() {
+			siblings = append(siblings, cmd.Name())
+		}
+	}
+
+	for _, cmd := range parent.Commands() {
+		if !cmd.IsAvailableCommand() {
+			continue
+		}
+
+		condition := fishCondition(path)
+		if len(path) == 0 {
+			condition = "__fish_use_subcommand"
+		} else {
+			condition += "; and not __fish_seen_subcommand_from " + strings.Join(siblings, " ")
+		}
+		fmt.Fprintf(w, "complete -c %s -f -n '%s' -a %s -d '%s'\n", name, condition, cmd.Name(), fishEscape(cmd.Short))
+
+		cmdPath := append(append([]string{}, path...), cmd.Name())
+		cmd.LocalFlags().VisitAll(func(f *pflag.Flag) {
+			fishFlag(w, name, fishCondition(cmdPath), f)
+		})
+		fishSubcommands(w, name, cmdPath, cmd)
+	}
+}
+
+// fishCondition returns the condition matching command lines that contain all
+// the subcommands in path.
+func fishCondition(path []string) string {
+	conditions := make([]string, len(path))
+	for i, cmd := range path {
+		conditions[i] = "__fish_seen_subcommand_from " + cmd
+	}
+	return strings.Join(conditions, "; and ")
+}
+
+func fishFlag(w io.Writer, name, condition string, f *pflag.Flag) {
+	if f.Hidden {
+		return
+	}
+
+	fmt.Fprintf(w, "complete -c %s", name)
+	if condition != "" {
+		fmt.Fprintf(w, " -n '%s'", condition)
+	}
+	if f.Name != f.Shorthand {
+		fmt.Fprintf(w, " -l %s", f.Name)
+	}
+	if f.Shorthand != "" {
+		fmt.Fprintf(w, " -s %s", f.Shorthand)
+	}
+	if f.NoOptDefVal == "" {
+		fmt.Fprint(w, " -r")
+	}
+	fmt.Fprintf(w, " -d '%s'\n", fishEscape(f.Usage))
+}
+
+func fishEscape(s string) string {
+	s = strings.SplitN(s, "\n", 2)[0]
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
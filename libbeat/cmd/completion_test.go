@@ -0,0 +1,53 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenFishCompletion(t *testing.T) {
+	root := &cobra.Command{Use: "testbeat", Run: func(*cobra.Command, []string) {}}
+	root.PersistentFlags().StringP("c", "c", "", "Configuration file")
+	root.Flags().Bool("once", false, "Run only once")
+
+	keystore := &cobra.Command{Use: "keystore", Short: "Manage secrets keystore"}
+	add := &cobra.Command{Use: "add KEY", Short: "Add secret", Run: func(*cobra.Command, []string) {}}
+	add.Flags().Bool("force", false, "Override the existing key")
+	list := &cobra.Command{Use: "list", Short: "List keystore", Run: func(*cobra.Command, []string) {}}
+	hidden := &cobra.Command{Use: "hidden", Hidden: true, Run: func(*cobra.Command, []string) {}}
+	keystore.AddCommand(add, list)
+	root.AddCommand(keystore, hidden)
+
+	var buf bytes.Buffer
+	genFishCompletion(&buf, root)
+
+	assert.Equal(t, `# fish completion for testbeat
+complete -c testbeat -e
+complete -c testbeat -s c -r -d 'Configuration file'
+complete -c testbeat -n '__fish_use_subcommand' -l once -d 'Run only once'
+complete -c testbeat -f -n '__fish_use_subcommand' -a keystore -d 'Manage secrets keystore'
+complete -c testbeat -f -n '__fish_seen_subcommand_from keystore; and not __fish_seen_subcommand_from add list' -a add -d 'Add secret'
+complete -c testbeat -n '__fish_seen_subcommand_from keystore; and __fish_seen_subcommand_from add' -l force -d 'Override the existing key'
+complete -c testbeat -f -n '__fish_seen_subcommand_from keystore; and not __fish_seen_subcommand_from add list' -a list -d 'List keystore'
+`, buf.String())
+}
@@ -27,18 +27,19 @@ import (
 	"github.com/elastic/beats/v7/libbeat/common/cli"
 )
 
-// GenExportConfigCmd write to stdout the current configuration in the YAML format.
+// GenExportConfigCmd write to stdout the current configuration in the YAML
+// format, or in JSON if requested with the output flag.
 func GenExportConfigCmd(settings instance.Settings) *cobra.Command {
 	return &cobra.Command{
 		Use:   "config",
 		Short: "Export current config to stdout",
 		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
-			return exportConfig(settings)
+			return exportConfig(settings, cli.GetOutputFormat(cmd))
 		}),
 	}
 }
 
-func exportConfig(settings instance.Settings) error {
+func exportConfig(settings instance.Settings, format cli.OutputFormat) error {
 	settings.DisableConfigResolver = true
 	b, err := instance.NewInitializedBeat(settings)
 	if err != nil {
@@ -50,6 +51,10 @@ func exportConfig(settings instance.Settings) error {
 	if err != nil {
 		fatalf("Error unpacking config: %+v.", err)
 	}
+	if format == cli.OutputJSON {
+		return cli.WriteJSON(os.Stdout, config)
+	}
+
 	res, err := yaml.Marshal(config)
 	if err != nil {
 		fatalf("Error converting config to YAML format: %+v.", err)
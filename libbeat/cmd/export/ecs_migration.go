@@ -0,0 +1,104 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package export
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/common/cli"
+	"github.com/elastic/beats/v7/libbeat/ecs"
+	"github.com/elastic/beats/v7/libbeat/mapping"
+)
+
+// GenECSMigrationCmd is the command used to report the fields of the Beat that
+// change between its ECS version and a target version.
+func GenECSMigrationCmd(settings instance.Settings) *cobra.Command {
+	var target string
+
+	command := &cobra.Command{
+		Use:   "ecs-migration",
+		Short: "Export the fields changed in the target ECS version",
+		Long: `Export the fields of the Beat that are removed or change their type between the
+ECS version of the Beat and the target version. The target version is set with
+--version, or the ecs.version setting.`,
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			b, err := instance.NewInitializedBeat(settings)
+			if err != nil {
+				return fmt.Errorf("error initializing beat: %v", err)
+			}
+
+			if target == "" {
+				cfg, err := ecs.FromConfig(b.RawConfig)
+				if err != nil {
+					return err
+				}
+				target = cfg.Version
+			}
+			if target == "" {
+				return fmt.Errorf("no target ECS version, set --version or ecs.version")
+			}
+
+			fields, err := mapping.LoadFields(b.Fields)
+			if err != nil {
+				return fmt.Errorf("error loading fields: %v", err)
+			}
+			migration, err := ecs.NewMigration(target, fields)
+			if err != nil {
+				return err
+			}
+
+			if cli.GetOutputFormat(cmd) == cli.OutputJSON {
+				return cli.WriteJSON(os.Stdout, migration)
+			}
+			printECSMigration(os.Stdout, migration)
+			return nil
+		}),
+	}
+
+	command.Flags().StringVar(&target, "version", "", "Target ECS version")
+	return command
+}
+
+func printECSMigration(w io.Writer, m *ecs.Migration) {
+	if len(m.Changes) == 0 {
+		fmt.Fprintf(w, "No fields change between ECS %v and %v\n", m.From, m.To)
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tFIELD\tCHANGE")
+	for _, c := range m.Changes {
+		var change string
+		switch {
+		case c.Target != "":
+			change = "removed, use " + c.Target
+		case c.TargetType != "":
+			change = fmt.Sprintf("type %v, was %v", c.TargetType, c.Type)
+		default:
+			change = fmt.Sprintf("type %v since this version", c.Type)
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", c.Version, c.Field, change)
+	}
+	tw.Flush()
+}
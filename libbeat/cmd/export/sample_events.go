@@ -0,0 +1,344 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/beats/v7/libbeat/cfgfile"
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/mapping"
+	"github.com/elastic/beats/v7/libbeat/paths"
+)
+
+// GenExportSampleEventsCmd generates a command that prints sample events of
+// the enabled modules. The events are read from the test fixtures matching
+// the given glob patterns, relative to the home path, where `{module}` is
+// replaced by the module name. The fixtures contain one event or a list of
+// events. Sample events are generated from the fields.yml of the datasets
+// without fixtures.
+func GenExportSampleEventsCmd(settings instance.Settings, fixtures ...string) *cobra.Command {
+	genSampleEventsCmd := &cobra.Command{
+		Use:   "sample-events [module...]",
+		Short: "Export sample events of the enabled modules to stdout",
+		Run: func(cmd *cobra.Command, args []string) {
+			count, _ := cmd.Flags().GetInt("count")
+
+			b, err := instance.NewInitializedBeat(settings)
+			if err != nil {
+				fatalfInitCmd(err)
+			}
+
+			modules := args
+			if len(modules) == 0 {
+				modules, err = enabledModules(b)
+				if err != nil {
+					fatalf("Error reading the enabled modules: %+v.", err)
+				}
+			}
+
+			keys, err := loadFieldKeys(b.Fields)
+			if err != nil {
+				fatalf("Error loading fields: %+v.", err)
+			}
+
+			gen := sampleGenerator{
+				fixtures: fixtures,
+				fields:   keys,
+				count:    count,
+				now:      common.Time(time.Now()),
+			}
+			for _, module := range modules {
+				events, err := gen.moduleEvents(module)
+				if err != nil {
+					fatalf("Error generating sample events for module %s: %+v.", module, err)
+				}
+				if err := writeEvents(os.Stdout, events); err != nil {
+					fatalf("Error writing sample events: %+v.", err)
+				}
+			}
+		},
+	}
+
+	genSampleEventsCmd.Flags().Int("count", 1, "Maximum number of events per dataset")
+
+	return genSampleEventsCmd
+}
+
+// fieldKey is a top level entry of fields.yml, usually describing the fields
+// of a module.
+type fieldKey struct {
+	Key    string         `config:"key"`
+	Fields mapping.Fields `config:"fields"`
+}
+
+func loadFieldKeys(data []byte) (map[string]mapping.Fields, error) {
+	cfg, err := common.NewConfigWithYAML(data, "fields.yml")
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []fieldKey
+	if err := cfg.Unpack(&keys); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]mapping.Fields{}
+	for _, key := range keys {
+		fields[key.Key] = append(fields[key.Key], key.Fields...)
+	}
+	return fields, nil
+}
+
+// enabledModules returns the names of the modules enabled in the Beat
+// configuration, in the modules list or in the modules directory.
+func enabledModules(b *instance.Beat) ([]string, error) {
+	config, err := b.BeatConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var settings struct {
+		Modules       []*common.Config `config:"modules"`
+		ConfigModules struct {
+			Path string `config:"path"`
+		} `config:"config.modules"`
+	}
+	if err := config.Unpack(&settings); err != nil {
+		return nil, err
+	}
+
+	configs := settings.Modules
+	if glob := settings.ConfigModules.Path; glob != "" {
+		manager, err := cfgfile.NewGlobManager(glob, ".yml", ".disabled")
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range manager.ListEnabled() {
+			cfg, err := common.LoadFile(file.Path)
+			if err != nil {
+				return nil, err
+			}
+			var fileConfigs []*common.Config
+			if err := cfg.Unpack(&fileConfigs); err != nil {
+				return nil, fmt.Errorf("invalid module config %s: %v", file.Path, err)
+			}
+			configs = append(configs, fileConfigs...)
+		}
+	}
+
+	seen := map[string]bool{}
+	var modules []string
+	for _, cfg := range configs {
+		module := struct {
+			Module  string `config:"module"`
+			Enabled bool   `config:"enabled"`
+		}{Enabled: true}
+		if err := cfg.Unpack(&module); err != nil {
+			return nil, err
+		}
+		if module.Module == "" || !module.Enabled || seen[module.Module] {
+			continue
+		}
+		seen[module.Module] = true
+		modules = append(modules, module.Module)
+	}
+	sort.Strings(modules)
+	return modules, nil
+}
+
+type sampleGenerator struct {
+	fixtures []string
+	fields   map[string]mapping.Fields
+	count    int
+	now      common.Time
+}
+
+// moduleEvents returns up to count events per dataset of the module, read
+// from the fixtures or generated from the fields of the dataset.
+func (g sampleGenerator) moduleEvents(module string) ([]common.MapStr, error) {
+	fixtures, err := g.fixtureEvents(module)
+	if err != nil {
+		return nil, err
+	}
+
+	perDataset := map[string]int{}
+	var events []common.MapStr
+	for _, event := range fixtures {
+		dataset, _ := event.GetValue("event.dataset")
+		key := fmt.Sprint(dataset)
+		if perDataset[key] >= g.count {
+			continue
+		}
+		perDataset[key]++
+		event.Put("@timestamp", g.now)
+		events = append(events, event)
+	}
+
+	for _, dataset := range datasets(module, g.fields[module]) {
+		name := module + "." + dataset.Name
+		for i := perDataset[name]; i < g.count; i++ {
+			event := common.MapStr{
+				"@timestamp": g.now,
+				"event": common.MapStr{
+					"module":  module,
+					"dataset": name,
+				},
+			}
+			if fields := sampleFields(dataset.Fields, i); len(fields) > 0 {
+				event.Put(module+"."+dataset.Name, fields)
+			}
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// fixtureEvents reads the events of the fixtures of the module.
+func (g sampleGenerator) fixtureEvents(module string) ([]common.MapStr, error) {
+	var events []common.MapStr
+	for _, pattern := range g.fixtures {
+		pattern = strings.Replace(pattern, "{module}", module, -1)
+		files, err := filepath.Glob(paths.Resolve(paths.Home, pattern))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			fileEvents, err := readFixture(file)
+			if err != nil {
+				return nil, fmt.Errorf("error reading fixture %s: %v", file, err)
+			}
+			events = append(events, fileEvents...)
+		}
+	}
+	return events, nil
+}
+
+func readFixture(path string) ([]common.MapStr, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []map[string]interface{}
+	if err := json.Unmarshal(data, &docs); err != nil {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+
+	events := make([]common.MapStr, 0, len(docs))
+	for _, doc := range docs {
+		// Fixtures can use dotted keys, expand them.
+		event := common.MapStr{}
+		for k, v := range doc {
+			event.Put(k, v)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// datasets returns the groups of fields of the datasets of a module, that are
+// nested under a group with the name of the module.
+func datasets(module string, fields mapping.Fields) mapping.Fields {
+	var groups mapping.Fields
+	for _, field := range fields {
+		if field.Name != module || field.Type != "group" {
+			continue
+		}
+		for _, dataset := range field.Fields {
+			if dataset.Type == "group" {
+				groups = append(groups, dataset)
+			}
+		}
+	}
+	return groups
+}
+
+// sampleFields generates a value for each field, with i being the index of the
+// event in its dataset.
+func sampleFields(fields mapping.Fields, i int) common.MapStr {
+	sample := common.MapStr{}
+	for _, field := range fields {
+		if field.Type == "group" {
+			if nested := sampleFields(field.Fields, i); len(nested) > 0 {
+				sample.Put(field.Name, nested)
+			}
+			continue
+		}
+		if value, ok := sampleValue(field, i); ok {
+			sample.Put(field.Name, value)
+		}
+	}
+	return sample
+}
+
+func sampleValue(field mapping.Field, i int) (interface{}, bool) {
+	switch field.Type {
+	case "alias", "object", "nested", "array", "histogram":
+		return nil, false
+	case "long", "integer", "short", "byte":
+		return i + 1, true
+	case "float", "double", "half_float", "scaled_float":
+		if field.Format == "percent" {
+			return 0.5, true
+		}
+		return float64(i) + 1.5, true
+	case "boolean":
+		return i%2 == 0, true
+	case "date":
+		return common.Time(time.Date(2020, 1, 1, 0, 0, i, 0, time.UTC)), true
+	case "ip":
+		return fmt.Sprintf("192.0.2.%d", i%254+1), true
+	case "geo_point":
+		return common.MapStr{"lat": 52.52, "lon": 13.4}, true
+	case "flattened":
+		return common.MapStr{"key": "value"}, true
+	default:
+		name := field.Name
+		if idx := strings.LastIndex(name, "."); idx >= 0 {
+			name = name[idx+1:]
+		}
+		return fmt.Sprintf("%s-%d", name, i+1), true
+	}
+}
+
+func writeEvents(w io.Writer, events []common.MapStr) error {
+	enc := json.NewEncoder(w)
+	for _, event := range events {
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package export
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+const sampleFieldsYml = `
+- key: foo
+  title: Foo
+  fields:
+    - name: foo
+      type: group
+      fields:
+        - name: status
+          type: group
+          fields:
+            - name: count
+              type: long
+            - name: client.ip
+              type: ip
+            - name: ratio
+              type: scaled_float
+              format: percent
+            - name: alias
+              type: alias
+              path: foo.status.count
+        - name: server
+          type: group
+          fields:
+            - name: name
+              type: keyword
+`
+
+func TestSampleEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sample-events")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fixture := `[
+		{"@timestamp": "2016-12-26T14:16:29.000Z", "event.dataset": "foo.status", "foo.status.count": 42},
+		{"@timestamp": "2016-12-26T14:16:30.000Z", "event.dataset": "foo.status", "foo.status.count": 43}
+	]`
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "foo", "status"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "foo", "status", "expected.json"), []byte(fixture), 0644))
+
+	fields, err := loadFieldKeys([]byte(sampleFieldsYml))
+	require.NoError(t, err)
+
+	now := common.Time(time.Now())
+	gen := sampleGenerator{
+		fixtures: []string{filepath.Join(dir, "{module}", "*", "expected.json")},
+		fields:   fields,
+		count:    1,
+		now:      now,
+	}
+
+	events, err := gen.moduleEvents("foo")
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	// The dataset with a fixture uses the fixture, with the dotted keys expanded.
+	assert.Equal(t, common.MapStr{
+		"@timestamp": now,
+		"event":      common.MapStr{"dataset": "foo.status"},
+		"foo":        common.MapStr{"status": common.MapStr{"count": float64(42)}},
+	}, events[0])
+
+	// The other dataset is generated from the fields.
+	assert.Equal(t, common.MapStr{
+		"@timestamp": now,
+		"event":      common.MapStr{"module": "foo", "dataset": "foo.server"},
+		"foo":        common.MapStr{"server": common.MapStr{"name": "name-1"}},
+	}, events[1])
+
+	gen.count = 2
+	gen.fixtures = nil
+	events, err = gen.moduleEvents("foo")
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	assert.Equal(t, common.MapStr{
+		"count":  2,
+		"client": common.MapStr{"ip": "192.0.2.2"},
+		"ratio":  0.5,
+	}, events[1]["foo"].(common.MapStr)["status"])
+
+	events, err = gen.moduleEvents("unknown")
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
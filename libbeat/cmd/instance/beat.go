@@ -29,8 +29,10 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -39,6 +41,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/api"
 	"github.com/elastic/beats/v7/libbeat/asset"
+	"github.com/elastic/beats/v7/libbeat/audit"
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/cfgfile"
 	"github.com/elastic/beats/v7/libbeat/cloudid"
@@ -55,6 +58,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/logp/configure"
 	"github.com/elastic/beats/v7/libbeat/management"
+	"github.com/elastic/beats/v7/libbeat/memgov"
 	"github.com/elastic/beats/v7/libbeat/metric/system/host"
 	"github.com/elastic/beats/v7/libbeat/monitoring"
 	"github.com/elastic/beats/v7/libbeat/monitoring/report"
@@ -63,6 +67,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/outputs/elasticsearch"
 	"github.com/elastic/beats/v7/libbeat/paths"
 	"github.com/elastic/beats/v7/libbeat/plugin"
+	"github.com/elastic/beats/v7/libbeat/privacy"
 	"github.com/elastic/beats/v7/libbeat/publisher/pipeline"
 	"github.com/elastic/beats/v7/libbeat/publisher/processing"
 	svc "github.com/elastic/beats/v7/libbeat/service"
@@ -100,10 +105,15 @@ type beatConfig struct {
 	Logging         *common.Config         `config:"logging"`
 	MetricLogging   *common.Config         `config:"logging.metrics"`
 	Keystore        *common.Config         `config:"keystore"`
+	OutputReload    *common.Config         `config:"config.output"`
+	Audit           *common.Config         `config:"audit"`
+	Privacy         *common.Config         `config:"privacy"`
+	MemoryGovernor  *common.Config         `config:"memory_governor"`
 	Instrumentation instrumentation.Config `config:"instrumentation"`
 
 	// output/publishing related configurations
 	Pipeline pipeline.Config `config:",inline"`
+	Proxy    *common.Config  `config:"proxy"`
 
 	// monitoring settings
 	MonitoringBeatConfig monitoring.BeatConfig `config:",inline"`
@@ -283,6 +293,17 @@ func (b *Beat) BeatConfig() (*common.Config, error) {
 	return common.NewConfig(), nil
 }
 
+// LockDataPath acquires the lock on the data path that is held by a running
+// Beat, so that commands modifying the data path don't run concurrently with
+// the Beat. The returned function releases the lock.
+func (b *Beat) LockDataPath() (func() error, error) {
+	bl := newLocker(b)
+	if err := bl.lock(); err != nil {
+		return nil, err
+	}
+	return bl.unlock, nil
+}
+
 // Keystore return the configured keystore for this beat
 func (b *Beat) Keystore() keystore.Keystore {
 	return b.keystore
@@ -334,7 +355,7 @@ func (b *Beat) createBeater(bt beat.Creator) (beat.Beater, error) {
 			return nil, errors.New(msg)
 		}
 	}
-	pipeline, err := pipeline.Load(b.Info,
+	pipeline, err := pipeline.LoadWithSettings(b.Info,
 		pipeline.Monitors{
 			Metrics:   reg,
 			Telemetry: monitoring.GetNamespace("state").GetRegistry(),
@@ -342,8 +363,13 @@ func (b *Beat) createBeater(bt beat.Creator) (beat.Beater, error) {
 			Tracer:    b.Instrumentation.Tracer(),
 		},
 		b.Config.Pipeline,
-		b.processing,
 		b.makeOutputFactory(b.Config.Output),
+		pipeline.Settings{
+			WaitClose:          0,
+			WaitCloseMode:      pipeline.NoWaitOnClose,
+			Processors:         b.processing,
+			RouteOutputFactory: b.createOutput,
+		},
 	)
 
 	if err != nil {
@@ -365,6 +391,90 @@ func (b *Beat) createBeater(bt beat.Creator) (beat.Beater, error) {
 	return beater, nil
 }
 
+// CreateDryRunBeater creates the beater with a publisher pipeline that passes
+// all processed events to the given client instead of the configured output.
+// The configured output is disabled, so that the beater does not connect to it
+// either, e.g. for loading ingest pipelines.
+func (b *Beat) CreateDryRunBeater(bt beat.Creator, client outputs.Client) (beat.Beater, error) {
+	sub, err := b.BeatConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	b.Config.Output = common.ConfigNamespace{}
+	config := b.Config.Pipeline
+	config.Routes = nil
+	config.Mirrors = nil
+	config.ShutdownSpool.Enabled = false
+	pipeline, err := pipeline.Load(b.Info,
+		pipeline.Monitors{
+			Logger: logp.L().Named("publisher"),
+		},
+		config,
+		b.processing,
+		func(outputs.Observer) (string, outputs.Group, error) {
+			out, err := outputs.Success(1, 0, client)
+			return client.String(), out, err
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing publisher: %+v", err)
+	}
+
+	b.Publisher = pipeline
+	return bt(&b.Beat, sub)
+}
+
+// CreateReplayPipeline creates a publisher pipeline for the configured output,
+// for publishing events that have been processed already. No processors are
+// applied to the events, and the memory queue is used, so that the pipeline
+// does not interfere with the disk queue of the Beat.
+func (b *Beat) CreateReplayPipeline() (*pipeline.Pipeline, error) {
+	if !b.Config.Output.IsSet() || !b.Config.Output.Config().Enabled() {
+		return nil, errors.New("no outputs are defined, please define one under the output section")
+	}
+
+	config := b.Config.Pipeline
+	config.Queue = common.ConfigNamespace{}
+	config.ShutdownSpool.Enabled = false
+	return pipeline.LoadWithSettings(b.Info,
+		pipeline.Monitors{
+			Logger: logp.L().Named("publisher"),
+		},
+		config,
+		b.makeOutputFactory(b.Config.Output),
+		pipeline.Settings{
+			WaitCloseMode:      pipeline.NoWaitOnClose,
+			RouteOutputFactory: b.createOutput,
+		},
+	)
+}
+
+// CreateBenchPipeline creates a publisher pipeline with the configured queue,
+// processors and output, for publishing synthetic events. The pipeline and
+// output metrics are reported to the given registry.
+func (b *Beat) CreateBenchPipeline(metrics *monitoring.Registry) (*pipeline.Pipeline, error) {
+	if !b.Config.Output.IsSet() || !b.Config.Output.Config().Enabled() {
+		return nil, errors.New("no outputs are defined, please define one under the output section")
+	}
+
+	config := b.Config.Pipeline
+	config.ShutdownSpool.Enabled = false
+	return pipeline.LoadWithSettings(b.Info,
+		pipeline.Monitors{
+			Metrics: metrics,
+			Logger:  logp.L().Named("publisher"),
+		},
+		config,
+		b.makeOutputFactory(b.Config.Output),
+		pipeline.Settings{
+			WaitCloseMode:      pipeline.NoWaitOnClose,
+			Processors:         b.processing,
+			RouteOutputFactory: b.createOutput,
+		},
+	)
+}
+
 func (b *Beat) launch(settings Settings, bt beat.Creator) error {
 	defer logp.Sync()
 	defer logp.Info("%s stopped.", b.Info.Beat)
@@ -407,6 +517,7 @@ func (b *Beat) launch(settings Settings, bt beat.Creator) error {
 	// Start the API Server before the Seccomp lock down, we do this so we can create the unix socket
 	// set the appropriate permission on the unix domain file without having to whitelist anything
 	// that would be set at runtime.
+	var apiServer *api.Server
 	if b.Config.HTTP.Enabled() {
 		s, err := api.NewWithDefaultRoutes(logp.NewLogger(""), b.Config.HTTP, monitoring.GetNamespace)
 		if err != nil {
@@ -414,17 +525,36 @@ func (b *Beat) launch(settings Settings, bt beat.Creator) error {
 		}
 		s.Start()
 		defer s.Stop()
+		apiServer = s
 	}
 
 	if err = seccomp.LoadFilter(b.Config.Seccomp); err != nil {
 		return err
 	}
 
+	if err := memgov.Start(b.Config.MemoryGovernor); err != nil {
+		return fmt.Errorf("error starting the memory governor: %v", err)
+	}
+	defer memgov.Stop()
+
 	beater, err := b.createBeater(bt)
 	if err != nil {
 		return err
 	}
 
+	// The queue of the pipeline can be resized through the API, when it only
+	// accepts local connections or it is allowed explicitly.
+	if q, ok := b.Publisher.(api.QueueResizer); ok && apiServer != nil {
+		if err := apiServer.AttachHandler("/queue", api.MakeQueueHandler(q, apiServer.AllowQueueResize())); err != nil {
+			return err
+		}
+	}
+	if q, ok := b.Publisher.(api.QueueInspector); ok && apiServer != nil {
+		if err := apiServer.AttachHandler("/queue/state", api.MakeQueueStateHandler(q)); err != nil {
+			return err
+		}
+	}
+
 	r, err := b.setupMonitoring(settings)
 	if err != nil {
 		return err
@@ -453,26 +583,49 @@ func (b *Beat) launch(settings Settings, bt beat.Creator) error {
 		return err
 	}
 
+	updater, err := b.newOutputUpdater()
+	if err != nil {
+		return err
+	}
+	watcher, err := b.watchKeystore(updater)
+	if err != nil {
+		return err
+	}
+	if watcher != nil {
+		watcher.Start()
+		defer watcher.Stop()
+	}
+	cfgWatcher, err := b.watchOutputConfig(settings, updater)
+	if err != nil {
+		return err
+	}
+	if cfgWatcher != nil {
+		cfgWatcher.Start()
+		defer cfgWatcher.Stop()
+	}
+
 	logp.Info("%s start running.", b.Info.Beat)
 
 	// Launch config manager
 	b.Manager.Start(beater.Stop)
 	defer b.Manager.Stop()
 
-	return beater.Run(&b.Beat)
+	err = beater.Run(&b.Beat)
+
+	// Beats with inputs keeping track of the published events spool the
+	// pending events before they stop, it's a no-op then.
+	if spooler, ok := b.Publisher.(interface{ SpoolPending() error }); ok {
+		if serr := spooler.SpoolPending(); serr != nil {
+			logp.Err("Failed to spool the pending events: %v", serr)
+		}
+	}
+	return err
 }
 
 // TestConfig check all settings are ok and the beat can be run
 func (b *Beat) TestConfig(settings Settings, bt beat.Creator) error {
 	return handleError(func() error {
-		err := b.InitWithSettings(settings)
-		if err != nil {
-			return err
-		}
-
-		// Create beater to ensure all settings are OK
-		_, err = b.createBeater(bt)
-		if err != nil {
+		if err := b.CheckConfig(settings, bt); err != nil {
 			return err
 		}
 
@@ -481,6 +634,18 @@ func (b *Beat) TestConfig(settings Settings, bt beat.Creator) error {
 	}())
 }
 
+// CheckConfig loads the configuration and creates the beater to ensure all
+// settings are OK. The errors are returned without being reported.
+func (b *Beat) CheckConfig(settings Settings, bt beat.Creator) error {
+	err := b.InitWithSettings(settings)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.createBeater(bt)
+	return err
+}
+
 //SetupSettings holds settings necessary for beat setup
 type SetupSettings struct {
 	Dashboard       bool
@@ -631,6 +796,14 @@ func (b *Beat) configure(settings Settings) error {
 		return fmt.Errorf("error initializing logging: %v", err)
 	}
 
+	if err := audit.Configure(b.Config.Audit); err != nil {
+		return fmt.Errorf("error initializing the audit log: %v", err)
+	}
+
+	if err := privacy.Configure(b.Config.Privacy); err != nil {
+		return fmt.Errorf("error initializing the privacy mode: %v", err)
+	}
+
 	// log paths values to help with troubleshooting
 	logp.Info(paths.Paths.String())
 
@@ -833,6 +1006,143 @@ func (b *Beat) makeOutputReloader(outReloader pipeline.OutputReloader) reload.Re
 	})
 }
 
+// outputUpdater recreates the output each time its settings change. The new
+// output connects with the new settings, the batches of the old output that
+// are not acknowledged yet are retried with the new output.
+type outputUpdater struct {
+	beat     *Beat
+	reloader pipeline.OutputReloader
+
+	mu      sync.Mutex
+	output  common.ConfigNamespace
+	current map[string]interface{}
+}
+
+// newOutputUpdater returns the updater of the output, or nil if the output
+// cannot be reloaded.
+func (b *Beat) newOutputUpdater() (*outputUpdater, error) {
+	if b.Manager.Enabled() {
+		logp.Info("Output reload is ignored, the output is configured through Central Management")
+		return nil, nil
+	}
+
+	p, ok := b.Publisher.(*pipeline.Pipeline)
+	if !ok || !b.Config.Output.IsSet() {
+		return nil, nil
+	}
+
+	u := &outputUpdater{
+		beat:     b,
+		reloader: p.OutputReloader(),
+		output:   b.Config.Output,
+	}
+	cfg, err := u.namespaceConfig(b.Config.Output)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Unpack(&u.current); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// update reloads the output if the settings of output differ from the settings
+// of the current output. Reason is logged to tell why the output was reloaded.
+func (u *outputUpdater) update(output common.ConfigNamespace, reason string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !output.IsSet() {
+		logp.Warn("The output cannot be removed without a restart, keeping the %s output", u.output.Name())
+		return
+	}
+
+	name := output.Name()
+	cfg, err := u.namespaceConfig(output)
+	if err != nil {
+		logp.Err("Failed to reload the %s output: %v", name, err)
+		return
+	}
+	var updated map[string]interface{}
+	if err := cfg.Unpack(&updated); err != nil {
+		logp.Err("Failed to read the settings of the %s output: %v", name, err)
+		return
+	}
+	if reflect.DeepEqual(u.current, updated) {
+		return
+	}
+
+	if err := u.reloader.Reload(&reload.ConfigWithMeta{Config: cfg}, u.beat.createOutput); err != nil {
+		logp.Err("Failed to reload the %s output: %v", name, err)
+		return
+	}
+	logp.Info("Reloaded the %s output %s", name, reason)
+	u.output = output
+	u.current = updated
+}
+
+// reloadSecrets reloads the current output if its settings changed because
+// secrets of the keystore were rotated.
+func (u *outputUpdater) reloadSecrets() {
+	u.mu.Lock()
+	output := u.output
+	u.mu.Unlock()
+
+	u.update(output, "with the rotated secrets of the keystore")
+}
+
+func (u *outputUpdater) namespaceConfig(output common.ConfigNamespace) (*common.Config, error) {
+	cfg := common.NewConfig()
+	if err := cfg.SetChild(output.Name(), -1, output.Config()); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// watchKeystore returns a watcher recreating the output each time its
+// settings change because secrets of the keystore were rotated, or nil if
+// keystore.reload.enabled is not set.
+func (b *Beat) watchKeystore(updater *outputUpdater) (*keystore.Watcher, error) {
+	settings, err := keystore.ReloadSettings(b.Config.Keystore)
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled || updater == nil {
+		return nil, nil
+	}
+
+	return keystore.NewWatcher(b.keystore, settings.Period, updater.reloadSecrets)
+}
+
+// watchOutputConfig returns a watcher recreating the output each time its
+// settings are changed in the configuration files, or nil if
+// config.output.reload.enabled is not set.
+func (b *Beat) watchOutputConfig(settings Settings, updater *outputUpdater) (*cfgfile.ConfigWatcher, error) {
+	reloadSettings, err := cfgfile.ReloadSettings(b.Config.OutputReload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid config.output.reload settings: %v", err)
+	}
+	if !reloadSettings.Enabled || updater == nil {
+		return nil, nil
+	}
+
+	return cfgfile.NewConfigWatcher(reloadSettings.Period, settings.ConfigOverrides, func(cfg *common.Config) {
+		if err := cloudid.OverwriteSettings(cfg); err != nil {
+			logp.Err("Failed to read the output settings: %v", err)
+			return
+		}
+
+		var config struct {
+			Output common.ConfigNamespace `config:"output"`
+		}
+		if err := cfg.Unpack(&config); err != nil {
+			logp.Err("Failed to read the output settings: %v", err)
+			return
+		}
+		updater.update(config.Output, "with the settings changed in the configuration files")
+	}), nil
+}
+
 func (b *Beat) makeOutputFactory(
 	cfg common.ConfigNamespace,
 ) func(outputs.Observer) (string, outputs.Group, error) {
@@ -847,6 +1157,10 @@ func (b *Beat) createOutput(stats outputs.Observer, cfg common.ConfigNamespace)
 		return outputs.Group{}, nil
 	}
 
+	if err := outputs.ApplyProxyDefaults(cfg.Config(), b.Config.Proxy); err != nil {
+		return outputs.Group{}, err
+	}
+
 	return outputs.Load(b.IdxSupporter, b.Info, stats, cfg.Name(), cfg.Config())
 }
 
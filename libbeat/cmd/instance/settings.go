@@ -21,6 +21,7 @@ import (
 	"github.com/spf13/pflag"
 
 	"github.com/elastic/beats/v7/libbeat/cfgfile"
+	"github.com/elastic/beats/v7/libbeat/cmd/wizard"
 	"github.com/elastic/beats/v7/libbeat/idxmgmt"
 	"github.com/elastic/beats/v7/libbeat/idxmgmt/ilm"
 	"github.com/elastic/beats/v7/libbeat/monitoring/report"
@@ -46,5 +47,9 @@ type Settings struct {
 
 	Processing processing.SupportFactory
 
+	// ModuleHints are used by `setup --interactive` to suggest modules for
+	// the services detected locally.
+	ModuleHints []wizard.ModuleHint
+
 	Umask *int
 }
@@ -21,14 +21,17 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
 	"syscall"
 
 	"github.com/spf13/cobra"
 	tml "golang.org/x/crypto/ssh/terminal"
 
+	"github.com/elastic/beats/v7/libbeat/audit"
 	"github.com/elastic/beats/v7/libbeat/cmd/instance"
 	"github.com/elastic/beats/v7/libbeat/common/cli"
 	"github.com/elastic/beats/v7/libbeat/common/terminal"
@@ -50,6 +53,8 @@ func getKeystore(settings instance.Settings) (keystore.Keystore, error) {
 //  - add
 //  - remove
 //  - list
+//  - export
+//  - import
 func genKeystoreCmd(settings instance.Settings) *cobra.Command {
 	keystoreCmd := cobra.Command{
 		Use:   "keystore",
@@ -60,6 +65,8 @@ func genKeystoreCmd(settings instance.Settings) *cobra.Command {
 	keystoreCmd.AddCommand(genAddKeystoreCmd(settings))
 	keystoreCmd.AddCommand(genRemoveKeystoreCmd(settings))
 	keystoreCmd.AddCommand(genListKeystoreCmd(settings))
+	keystoreCmd.AddCommand(genExportKeystoreCmd(settings))
+	keystoreCmd.AddCommand(genImportKeystoreCmd(settings))
 
 	return &keystoreCmd
 }
@@ -119,11 +126,55 @@ func genListKeystoreCmd(settings instance.Settings) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			return list(store)
+			return list(store, cli.GetOutputFormat(cmd))
 		}),
 	}
 }
 
+func genExportKeystoreCmd(settings instance.Settings) *cobra.Command {
+	var flagOutput string
+	var flagPasswordStdin bool
+	command := &cobra.Command{
+		Use:   "export",
+		Short: "Export secrets to an encrypted archive",
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			store, err := getKeystore(settings)
+			if err != nil {
+				return err
+			}
+			return exportKeys(store, flagOutput, flagPasswordStdin)
+		}),
+	}
+	command.Flags().StringVarP(&flagOutput, "output", "o", "", "Write the archive to the given file instead of stdout")
+	command.Flags().BoolVar(&flagPasswordStdin, "password-stdin", false, "Use the stdin as the source of the archive password")
+	return command
+}
+
+func genImportKeystoreCmd(settings instance.Settings) *cobra.Command {
+	var flagForce bool
+	var flagStdin bool
+	var flagPasswordStdin bool
+	command := &cobra.Command{
+		Use:   "import [ARCHIVE]",
+		Short: "Import secrets from an encrypted archive or from KEY=VALUE lines in the stdin",
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			secrets, err := readImportedKeys(args, flagStdin, flagPasswordStdin)
+			if err != nil {
+				return err
+			}
+			store, err := getKeystore(settings)
+			if err != nil {
+				return err
+			}
+			return importKeys(store, secrets, flagForce)
+		}),
+	}
+	command.Flags().BoolVar(&flagStdin, "stdin", false, "Read the secrets from KEY=VALUE lines in the stdin")
+	command.Flags().BoolVar(&flagPasswordStdin, "password-stdin", false, "Use the stdin as the source of the archive password")
+	command.Flags().BoolVar(&flagForce, "force", false, "Override the existing keys")
+	return command
+}
+
 func createKeystore(settings instance.Settings, force bool) error {
 	store, err := getKeystore(settings)
 	if err != nil {
@@ -152,6 +203,7 @@ func createKeystore(settings instance.Settings, force bool) error {
 			return fmt.Errorf("Error creating the keystore: %s", err)
 		}
 	}
+	audit.Log(audit.ActionKeystoreCreate)
 	fmt.Printf("Created %s keystore\n", settings.Name)
 	return nil
 }
@@ -181,6 +233,7 @@ func addKey(store keystore.Keystore, keys []string, force, stdin bool) error {
 		if err != nil {
 			return fmt.Errorf("could not create keystore, error: %s", err)
 		}
+		audit.Log(audit.ActionKeystoreCreate)
 		fmt.Println("Created keystore")
 	}
 
@@ -218,6 +271,7 @@ func addKey(store keystore.Keystore, keys []string, force, stdin bool) error {
 	if err = writableKeystore.Save(); err != nil {
 		return fmt.Errorf("fail to save the keystore: %s", err)
 	} else {
+		audit.Log(audit.ActionKeystoreAdd, "keystore.key", key)
 		fmt.Println("Successfully updated the keystore")
 	}
 	return nil
@@ -249,12 +303,13 @@ func removeKey(store keystore.Keystore, keys []string) error {
 		if err != nil {
 			return fmt.Errorf("could not update the keystore with the changes, key: %s, error: %v", key, err)
 		}
+		audit.Log(audit.ActionKeystoreRemove, "keystore.key", key)
 		fmt.Printf("successfully removed key: %s\n", key)
 	}
 	return nil
 }
 
-func list(store keystore.Keystore) error {
+func list(store keystore.Keystore, format cli.OutputFormat) error {
 	listingKeystore, err := keystore.AsListingKeystore(store)
 	if err != nil {
 		return fmt.Errorf("error listing the keystore: %s", err)
@@ -263,8 +318,180 @@ func list(store keystore.Keystore) error {
 	if err != nil {
 		return fmt.Errorf("could not read values from the keystore, error: %s", err)
 	}
+	audit.Log(audit.ActionKeystoreList, "keystore.keys", len(keys))
+	if format == cli.OutputJSON {
+		if keys == nil {
+			keys = []string{}
+		}
+		return cli.WriteJSON(os.Stdout, keys)
+	}
 	for _, key := range keys {
 		fmt.Println(key)
 	}
 	return nil
 }
+
+func exportKeys(store keystore.Keystore, output string, passwordStdin bool) error {
+	if store.IsPersisted() == false {
+		return errors.New("the keystore doesn't exist. Use the 'create' command to create one")
+	}
+
+	password, err := readArchivePassword(passwordStdin, true)
+	if err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if output != "" {
+		w, err = os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("could not create the archive, error: %s", err)
+		}
+		defer w.Close()
+	}
+
+	if err := keystore.WriteArchive(w, store, password); err != nil {
+		return fmt.Errorf("could not export the keystore, error: %s", err)
+	}
+	audit.Log(audit.ActionKeystoreExport)
+	if output != "" {
+		fmt.Printf("Exported the keystore to %s\n", output)
+	}
+	return nil
+}
+
+func readImportedKeys(args []string, stdin, passwordStdin bool) (map[string][]byte, error) {
+	if stdin {
+		if len(args) > 0 {
+			return nil, errors.New("you cannot supply an archive when reading the secrets from stdin")
+		}
+		if passwordStdin {
+			return nil, errors.New("the secrets and the archive password cannot both be read from stdin")
+		}
+		return parseKeyValues(os.Stdin)
+	}
+
+	if len(args) != 1 {
+		return nil, errors.New("you must supply one archive to import, or use `--stdin`")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("could not open the archive, error: %s", err)
+	}
+	defer f.Close()
+
+	password, err := readArchivePassword(passwordStdin, false)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := keystore.ReadArchive(f, password)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the archive, error: %s", err)
+	}
+	return secrets, nil
+}
+
+// parseKeyValues reads secrets from KEY=VALUE lines. Empty lines and lines
+// starting with # are ignored.
+func parseKeyValues(r io.Reader) (map[string][]byte, error) {
+	secrets := map[string][]byte{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		i := strings.Index(line, "=")
+		key := ""
+		if i > 0 {
+			key = strings.TrimSpace(line[:i])
+		}
+		if key == "" {
+			return nil, fmt.Errorf("invalid secret at line %d, expected KEY=VALUE", n)
+		}
+		secrets[key] = []byte(line[i+1:])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read input from stdin")
+	}
+	return secrets, nil
+}
+
+func importKeys(store keystore.Keystore, secrets map[string][]byte, force bool) error {
+	writableKeystore, err := keystore.AsWritableKeystore(store)
+	if err != nil {
+		return fmt.Errorf("error importing into the keystore: %s", err)
+	}
+
+	keys := make([]string, 0, len(secrets))
+	for key := range secrets {
+		if value, _ := store.Retrieve(key); value != nil && force == false {
+			return fmt.Errorf("the settings %s already exist in the keystore use `--force` to replace it", key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if store.IsPersisted() == false {
+		if err := writableKeystore.Create(true); err != nil {
+			return fmt.Errorf("could not create keystore, error: %s", err)
+		}
+		audit.Log(audit.ActionKeystoreCreate)
+		fmt.Println("Created keystore")
+	}
+
+	for _, key := range keys {
+		if err := writableKeystore.Store(key, secrets[key]); err != nil {
+			return fmt.Errorf("could not add the key in the keystore, error: %s", err)
+		}
+	}
+	if err := writableKeystore.Save(); err != nil {
+		return fmt.Errorf("fail to save the keystore: %s", err)
+	}
+	for _, key := range keys {
+		audit.Log(audit.ActionKeystoreAdd, "keystore.key", key)
+	}
+	fmt.Printf("Successfully imported %d keys\n", len(keys))
+	return nil
+}
+
+// readArchivePassword reads the password of an archive from the terminal, or
+// from the first line of stdin. Prompts are written to stderr, as the archive
+// can be exported to stdout.
+func readArchivePassword(stdin, confirm bool) (*keystore.SecureString, error) {
+	var password []byte
+	if stdin {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("could not read the password from stdin")
+		}
+		password = []byte(strings.TrimRight(line, "\r\n"))
+	} else {
+		var err error
+		fmt.Fprint(os.Stderr, "Enter password for the archive: ")
+		password, err = tml.ReadPassword(int(syscall.Stdin))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the password from the input, error: %s", err)
+		}
+
+		if confirm {
+			fmt.Fprint(os.Stderr, "Confirm password: ")
+			confirmation, err := tml.ReadPassword(int(syscall.Stdin))
+			fmt.Fprintln(os.Stderr)
+			if err != nil {
+				return nil, fmt.Errorf("could not read the password from the input, error: %s", err)
+			}
+			if string(confirmation) != string(password) {
+				return nil, errors.New("the passwords don't match")
+			}
+		}
+	}
+
+	if confirm && len(password) == 0 {
+		return nil, errors.New("the archive password cannot be empty")
+	}
+	return keystore.NewSecureString(password), nil
+}
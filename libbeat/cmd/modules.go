@@ -23,9 +23,11 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/elastic/beats/v7/libbeat/audit"
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/cfgfile"
 	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/common/cli"
 )
 
 // ModulesManager interface provides all actions needed to implement modules command
@@ -82,6 +84,18 @@ func genListModulesCmd(settings instance.Settings, modulesFactory modulesManager
 		Run: func(cmd *cobra.Command, args []string) {
 			modules := getModules(settings, modulesFactory)
 
+			if cli.GetOutputFormat(cmd) == cli.OutputJSON {
+				err := cli.WriteJSON(os.Stdout, map[string][]string{
+					"enabled":  moduleNames(modules.ListEnabled()),
+					"disabled": moduleNames(modules.ListDisabled()),
+				})
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing modules list: %s\n", err)
+					os.Exit(1)
+				}
+				return
+			}
+
 			fmt.Println("Enabled:")
 			for _, module := range modules.ListEnabled() {
 				fmt.Println(module.Name)
@@ -95,6 +109,14 @@ func genListModulesCmd(settings instance.Settings, modulesFactory modulesManager
 	}
 }
 
+func moduleNames(modules []*cfgfile.CfgFile) []string {
+	names := make([]string, 0, len(modules))
+	for _, module := range modules {
+		names = append(names, module.Name)
+	}
+	return names
+}
+
 func genEnableModulesCmd(settings instance.Settings, modulesFactory modulesManagerFactory) *cobra.Command {
 	return &cobra.Command{
 		Use:   "enable MODULE...",
@@ -118,6 +140,7 @@ func genEnableModulesCmd(settings instance.Settings, modulesFactory modulesManag
 					os.Exit(1)
 				}
 
+				audit.Log(audit.ActionModulesEnable, "module", module)
 				fmt.Printf("Enabled %s\n", module)
 			}
 		},
@@ -147,6 +170,7 @@ func genDisableModulesCmd(settings instance.Settings, modulesFactory modulesMana
 					os.Exit(1)
 				}
 
+				audit.Log(audit.ActionModulesDisable, "module", module)
 				fmt.Printf("Disabled %s\n", module)
 			}
 		},
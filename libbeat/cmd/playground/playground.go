@@ -0,0 +1,280 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package playground implements the `processors test` command, running
+// events through a chain of processors outside of the publishing pipeline.
+package playground
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cli"
+	"github.com/elastic/beats/v7/libbeat/common/jsontransform"
+	"github.com/elastic/beats/v7/libbeat/processors"
+)
+
+// GenProcessorsCmd generates the command for working with processors.
+func GenProcessorsCmd(settings instance.Settings) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "processors",
+		Short: "Work with processors",
+	}
+	command.AddCommand(genTestCmd(settings))
+	return command
+}
+
+func genTestCmd(settings instance.Settings) *cobra.Command {
+	var (
+		adhoc  string
+		pretty bool
+	)
+
+	command := &cobra.Command{
+		Use:   "test [FILE]",
+		Short: "Run JSON events through the processors and print the results",
+		Long: `Run the JSON events read from FILE, or from stdin, through the processors
+defined in the configuration, or through the processors given with
+--processors, and print the resulting events. The time spent in each processor
+is written to stderr.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			b, err := instance.NewInitializedBeat(settings)
+			if err != nil {
+				return fmt.Errorf("error initializing beat: %v", err)
+			}
+
+			config, err := processorsConfig(b.RawConfig, adhoc)
+			if err != nil {
+				return err
+			}
+			c, err := newChain(config)
+			if err != nil {
+				return fmt.Errorf("error initializing processors: %v", err)
+			}
+			defer c.close()
+
+			in := os.Stdin
+			if len(args) == 1 {
+				if in, err = os.Open(args[0]); err != nil {
+					return err
+				}
+				defer in.Close()
+			}
+
+			err = c.runAll(in, os.Stdout, os.Stderr, pretty)
+			c.report(os.Stderr)
+			return err
+		}),
+	}
+
+	command.Flags().StringVar(&adhoc, "processors", "", "YAML list of processors to use instead of the configured ones")
+	command.Flags().BoolVar(&pretty, "pretty", false, "Pretty print the events")
+	return command
+}
+
+// processorsConfig returns the ad-hoc processors if given, or the processors
+// defined in the configuration.
+func processorsConfig(config *common.Config, adhoc string) (processors.PluginConfig, error) {
+	if adhoc != "" {
+		var list []interface{}
+		if err := yaml.Unmarshal([]byte(adhoc), &list); err != nil {
+			return nil, fmt.Errorf("invalid --processors, a YAML list of processors is expected: %v", err)
+		}
+		var err error
+		config, err = common.NewConfigFrom(map[string]interface{}{"processors": list})
+		if err != nil {
+			return nil, fmt.Errorf("invalid --processors: %v", err)
+		}
+	}
+
+	var settings struct {
+		Processors processors.PluginConfig `config:"processors"`
+	}
+	if err := config.Unpack(&settings); err != nil {
+		return nil, err
+	}
+	return settings.Processors, nil
+}
+
+// chain runs the events through a list of processors, keeping statistics of
+// each processor.
+type chain struct {
+	list  []processors.Processor
+	stats []processorStats
+}
+
+type processorStats struct {
+	events  int
+	dropped int
+	failed  int
+	elapsed time.Duration
+}
+
+func newChain(config processors.PluginConfig) (*chain, error) {
+	procs, err := processors.New(config)
+	if err != nil {
+		return nil, err
+	}
+	return &chain{
+		list:  procs.List,
+		stats: make([]processorStats, len(procs.List)),
+	}, nil
+}
+
+// run applies the processors to the event as the publishing pipeline does, a
+// failed processor doesn't stop the chain unless it drops the event.
+func (c *chain) run(event *beat.Event) (*beat.Event, []error) {
+	var errs []error
+	for i, p := range c.list {
+		stats := &c.stats[i]
+
+		start := time.Now()
+		out, err := p.Run(event)
+		stats.elapsed += time.Since(start)
+		stats.events++
+
+		if err != nil {
+			stats.failed++
+			errs = append(errs, fmt.Errorf("processor %v failed: %v", p, err))
+		}
+		if out == nil {
+			stats.dropped++
+			return nil, errs
+		}
+		event = out
+	}
+	return event, errs
+}
+
+// runAll runs the events read from in through the processors, and writes the
+// resulting events to out. Failures and dropped events are reported to log.
+func (c *chain) runAll(in io.Reader, out, log io.Writer, pretty bool) error {
+	enc := json.NewEncoder(out)
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+
+	n := 0
+	return readEvents(in, func(event *beat.Event) error {
+		n++
+		result, errs := c.run(event)
+		for _, err := range errs {
+			fmt.Fprintf(log, "event %d: %v\n", n, err)
+		}
+		if result == nil {
+			fmt.Fprintf(log, "event %d: dropped\n", n)
+			return nil
+		}
+		return enc.Encode(encodeEvent(result))
+	})
+}
+
+// report writes the statistics of each processor.
+func (c *chain) report(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROCESSOR\tEVENTS\tDROPPED\tFAILED\tTOTAL\tAVERAGE")
+	for i, p := range c.list {
+		stats := c.stats[i]
+		var avg time.Duration
+		if stats.events > 0 {
+			avg = stats.elapsed / time.Duration(stats.events)
+		}
+		fmt.Fprintf(tw, "%v\t%d\t%d\t%d\t%v\t%v\n", p, stats.events, stats.dropped, stats.failed, stats.elapsed, avg)
+	}
+	tw.Flush()
+}
+
+func (c *chain) close() {
+	for _, p := range c.list {
+		processors.Close(p)
+	}
+}
+
+// readEvents decodes a stream of JSON objects, or arrays of objects, and
+// passes them to fn as events.
+func readEvents(r io.Reader, fn func(*beat.Event) error) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	for n := 1; ; n++ {
+		var value interface{}
+		err := dec.Decode(&value)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error decoding JSON value %d: %v", n, err)
+		}
+
+		values, isArray := value.([]interface{})
+		if !isArray {
+			values = []interface{}{value}
+		}
+		for _, v := range values {
+			fields, ok := v.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("JSON value %d is not an event object", n)
+			}
+			event, err := newEvent(fields)
+			if err != nil {
+				return fmt.Errorf("invalid event in JSON value %d: %v", n, err)
+			}
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func newEvent(fields common.MapStr) (*beat.Event, error) {
+	jsontransform.TransformNumbers(fields)
+
+	event := &beat.Event{Timestamp: time.Now()}
+	if ts, ok := fields["@timestamp"].(string); ok {
+		t, err := common.ParseTime(ts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid @timestamp: %v", err)
+		}
+		event.Timestamp = time.Time(t)
+	}
+	if meta, ok := fields["@metadata"].(map[string]interface{}); ok {
+		event.Meta = common.MapStr(meta)
+	}
+	delete(fields, "@timestamp")
+	delete(fields, "@metadata")
+	event.Fields = fields
+	return event, nil
+}
+
+func encodeEvent(event *beat.Event) common.MapStr {
+	doc := event.Fields.Clone()
+	doc["@timestamp"] = common.Time(event.Timestamp)
+	if len(event.Meta) > 0 {
+		doc["@metadata"] = event.Meta
+	}
+	return doc
+}
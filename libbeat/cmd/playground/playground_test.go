@@ -0,0 +1,120 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package playground
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	_ "github.com/elastic/beats/v7/libbeat/processors/actions"
+)
+
+func TestReadEvents(t *testing.T) {
+	input := `
+{"@timestamp": "2020-11-05T10:00:00.000Z", "message": "one", "count": 1, "ratio": 0.5}
+[{"message": "two", "@metadata": {"pipeline": "p"}}, {"message": "three"}]
+`
+	var events []*beat.Event
+	err := readEvents(strings.NewReader(input), func(event *beat.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, time.Date(2020, 11, 5, 10, 0, 0, 0, time.UTC), events[0].Timestamp.UTC())
+	assert.Equal(t, common.MapStr{"message": "one", "count": int64(1), "ratio": 0.5}, events[0].Fields)
+	assert.Equal(t, common.MapStr{"pipeline": "p"}, events[1].Meta)
+	assert.Equal(t, common.MapStr{"message": "three"}, events[2].Fields)
+
+	err = readEvents(strings.NewReader(`"not an event"`), func(*beat.Event) error { return nil })
+	assert.Error(t, err)
+}
+
+func TestChain(t *testing.T) {
+	config, err := processorsConfig(common.NewConfig(), `
+- add_fields:
+    target: ""
+    fields: {env: test}
+- drop_event.when.equals.message: drop
+- rename.fields: [{from: missing, to: other}]
+`)
+	require.NoError(t, err)
+
+	c, err := newChain(config)
+	require.NoError(t, err)
+	defer c.close()
+	require.Len(t, c.list, 3)
+
+	input := `{"@timestamp": "2020-11-05T10:00:00.000Z", "message": "keep"} {"message": "drop"}`
+	var out, log bytes.Buffer
+	require.NoError(t, c.runAll(strings.NewReader(input), &out, &log, false))
+
+	assert.Equal(t, `{"@timestamp":"2020-11-05T10:00:00.000Z","env":"test",`+
+		`"error":{"message":"Failed to rename fields in processor: could not fetch value for key: missing, Error: key not found"},`+
+		`"message":"keep"}`+"\n", out.String())
+	assert.Contains(t, log.String(), "event 1: processor rename=")
+	assert.Contains(t, log.String(), "event 2: dropped")
+
+	assert.Equal(t, []processorStats{
+		{events: 2},
+		{events: 2, dropped: 1},
+		{events: 1, failed: 1},
+	}, withoutElapsed(c.stats))
+
+	var report bytes.Buffer
+	c.report(&report)
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.True(t, strings.HasPrefix(lines[0], "PROCESSOR"))
+}
+
+func TestProcessorsConfig(t *testing.T) {
+	config := common.MustNewConfigFrom(map[string]interface{}{
+		"processors": []interface{}{
+			map[string]interface{}{"drop_fields.fields": []string{"a"}},
+		},
+	})
+
+	list, err := processorsConfig(config, "")
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	list, err = processorsConfig(config, "[{drop_event: {}}, {drop_fields.fields: [b]}]")
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	_, err = processorsConfig(config, "drop_event: {}")
+	assert.Error(t, err)
+}
+
+func withoutElapsed(stats []processorStats) []processorStats {
+	result := make([]processorStats, len(stats))
+	for i, s := range stats {
+		s.elapsed = 0
+		result[i] = s
+	}
+	return result
+}
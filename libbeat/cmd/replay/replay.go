@@ -0,0 +1,167 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package replay
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/time/rate"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/acker"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/common/cli"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/diskqueue"
+)
+
+// GenReplayCmd generates the command for publishing the events stored in a
+// disk queue or in files written by the file output.
+func GenReplayCmd(settings instance.Settings) *cobra.Command {
+	var (
+		eventsPerSecond float64
+		timeout         time.Duration
+	)
+	fields := common.NewConfig()
+
+	command := &cobra.Command{
+		Use:   "replay PATH",
+		Short: "Publish the events stored on disk to the configured output",
+		Long: `Publish the events stored on disk to the configured output. PATH is either the
+directory of a disk queue, or a file written by the file output. The events are
+published as they were stored, the processors are not applied again.`,
+		Args: cobra.ExactArgs(1),
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			var overrides common.MapStr
+			if err := fields.Unpack(&overrides); err != nil {
+				return fmt.Errorf("invalid field overrides: %v", err)
+			}
+
+			b, err := instance.NewInitializedBeat(settings)
+			if err != nil {
+				return fmt.Errorf("error initializing beat: %v", err)
+			}
+			queueSettings, err := diskQueueSettings(b.Config.Pipeline.Queue)
+			if err != nil {
+				return fmt.Errorf("invalid disk queue settings: %v", err)
+			}
+
+			pipeline, err := b.CreateReplayPipeline()
+			if err != nil {
+				return fmt.Errorf("error initializing publisher: %v", err)
+			}
+			defer pipeline.Close()
+
+			r := replayer{
+				fields:        overrides,
+				limit:         eventsPerSecond,
+				waitClose:     timeout,
+				queueSettings: queueSettings,
+			}
+			published, acked, err := r.run(pipeline, args[0])
+			fmt.Fprintf(os.Stdout, "Published %d events, %d acknowledged by the output\n", published, acked)
+			if err != nil {
+				return err
+			}
+			if acked < published {
+				return fmt.Errorf("%d events were not acknowledged within %v", published-acked, timeout)
+			}
+			return nil
+		}),
+	}
+
+	command.Flags().Var(common.NewSettingsFlag(fields), "field", "Set a field in every event, e.g. --field tags=replayed")
+	command.Flags().Float64Var(&eventsPerSecond, "rate", 0, "Maximum number of events published per second, 0 disables the rate limiting")
+	command.Flags().DurationVar(&timeout, "timeout", 30*time.Second, "Maximum time to wait for the output to acknowledge the events")
+	return command
+}
+
+// replayer publishes the events read from disk.
+type replayer struct {
+	// fields are set in every event, overwriting existing values.
+	fields common.MapStr
+
+	// limit is the maximum number of events published per second, or 0 to
+	// publish as fast as possible.
+	limit float64
+
+	// waitClose is the time to wait for the events to be acknowledged.
+	waitClose time.Duration
+
+	// queueSettings are the settings to read the segments of a disk queue
+	// with, for decrypting them.
+	queueSettings diskqueue.Settings
+}
+
+// diskQueueSettings returns the settings of the disk queue configured in the
+// Beat, which has the keys of encrypted disk queues.
+func diskQueueSettings(queue common.ConfigNamespace) (diskqueue.Settings, error) {
+	if queue.Name() != "disk" {
+		return diskqueue.DefaultSettings(), nil
+	}
+	return diskqueue.SettingsForUserConfig(queue.Config())
+}
+
+// run publishes all events found at path and waits for them to be
+// acknowledged. It returns the number of published and acknowledged events.
+func (r replayer) run(pipeline beat.PipelineConnector, path string) (int, int, error) {
+	var acked atomic.Int
+	client, err := pipeline.ConnectWith(beat.ClientConfig{
+		PublishMode: beat.GuaranteedSend,
+		WaitClose:   r.waitClose,
+		ACKHandler:  acker.RawCounting(func(n int) { acked.Add(n) }),
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var limiter *rate.Limiter
+	if r.limit > 0 {
+		limiter = rate.NewLimiter(rate.Limit(r.limit), 1)
+	}
+
+	published := 0
+	err = readEvents(path, r.queueSettings, func(event publisher.Event) error {
+		if limiter != nil {
+			if err := limiter.Wait(context.Background()); err != nil {
+				return err
+			}
+		}
+
+		content := event.Content
+		if len(r.fields) > 0 {
+			if content.Fields == nil {
+				content.Fields = common.MapStr{}
+			}
+			content.Fields.DeepUpdate(r.fields.Clone())
+		}
+		client.Publish(content)
+		published++
+		return nil
+	})
+
+	// Close waits for the pending events to be acknowledged.
+	client.Close()
+	return published, acked.Load(), err
+}
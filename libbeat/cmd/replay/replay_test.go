@@ -0,0 +1,135 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package replay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	pubtest "github.com/elastic/beats/v7/libbeat/publisher/testing"
+)
+
+func TestReplayFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.ndjson")
+	err = ioutil.WriteFile(path, []byte(
+		`{"@timestamp":"2020-10-07T18:34:10.000Z","message":"first","tags":["a"]}`+"\n"+
+			"\n"+
+			`{"@timestamp":"2020-10-07T18:34:11.000Z","message":"second","host":{"name":"h"}}`+"\n"), 0600)
+	require.NoError(t, err)
+
+	var (
+		config    beat.ClientConfig
+		events    []beat.Event
+		closed    bool
+		overrides = common.MapStr{"tags": []string{"replayed"}, "host": common.MapStr{"id": "x"}}
+	)
+	connector := pubtest.FakeConnector{ConnectFunc: func(cfg beat.ClientConfig) (beat.Client, error) {
+		config = cfg
+		return &pubtest.FakeClient{
+			PublishFunc: func(event beat.Event) {
+				events = append(events, event)
+				cfg.ACKHandler.ACKEvents(1)
+			},
+			CloseFunc: func() error { closed = true; return nil },
+		}, nil
+	}}
+
+	r := replayer{fields: overrides, waitClose: time.Second}
+	published, acked, err := r.run(connector, path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	assert.Equal(t, 2, acked)
+	assert.True(t, closed)
+	assert.Equal(t, beat.GuaranteedSend, config.PublishMode)
+
+	require.Len(t, events, 2)
+	assert.Equal(t, "first", events[0].Fields["message"])
+	assert.Equal(t, []string{"replayed"}, events[0].Fields["tags"])
+	name, _ := events[1].Fields.GetValue("host.name")
+	id, _ := events[1].Fields.GetValue("host.id")
+	assert.Equal(t, "h", name)
+	assert.Equal(t, "x", id)
+}
+
+func TestReplayRateLimit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "events.ndjson")
+	err = ioutil.WriteFile(path, []byte("{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n"), 0600)
+	require.NoError(t, err)
+
+	connector := pubtest.FakeConnector{ConnectFunc: func(cfg beat.ClientConfig) (beat.Client, error) {
+		return &pubtest.FakeClient{}, nil
+	}}
+
+	start := time.Now()
+	r := replayer{limit: 20}
+	published, acked, err := r.run(connector, path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, published)
+	assert.Equal(t, 0, acked)
+	// The first event is published immediately, the other two are delayed.
+	assert.True(t, time.Since(start) >= 90*time.Millisecond)
+}
+
+func TestReplayCompressedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(`{"@timestamp":"2020-10-07T18:34:10.000Z","message":"first"}` + "\n"))
+	require.NoError(t, zw.Close())
+	path := filepath.Join(dir, "events.ndjson.gz")
+	require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0600))
+
+	var events []beat.Event
+	connector := pubtest.FakeConnector{ConnectFunc: func(cfg beat.ClientConfig) (beat.Client, error) {
+		return &pubtest.FakeClient{
+			PublishFunc: func(event beat.Event) {
+				events = append(events, event)
+				cfg.ACKHandler.ACKEvents(1)
+			},
+		}, nil
+	}}
+
+	r := replayer{waitClose: time.Second}
+	published, acked, err := r.run(connector, path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, 1, acked)
+	require.Len(t, events, 1)
+	assert.Equal(t, "first", events[0].Fields["message"])
+}
@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package replay
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/diskqueue"
+)
+
+// maxLineSize limits the size of an event in the files of the file output.
+const maxLineSize = 10 * 1024 * 1024
+
+// readEvents reads the events stored at path and passes them to fn. If path is
+// a directory, it is read as the directory of a disk queue with the given
+// settings, otherwise as a file of the file output, with one JSON encoded
+// event per line. Files with the .gz extension are decompressed.
+func readEvents(path string, queueSettings diskqueue.Settings, fn func(publisher.Event) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		queueSettings.Path = path
+		return diskqueue.ReadSegmentsWithSettings(queueSettings, fn)
+	}
+	return readFile(path, fn)
+}
+
+func readFile(path string, fn func(publisher.Event) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("error decompressing %s: %v", path, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		event, err := json.Decode(scanner.Bytes())
+		if err != nil {
+			return fmt.Errorf("error decoding event at line %d of %s: %v", line, path, err)
+		}
+		if err := fn(publisher.Event{Content: event}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -27,7 +27,11 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/cfgfile"
+	"github.com/elastic/beats/v7/libbeat/cmd/bench"
 	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/cmd/playground"
+	"github.com/elastic/beats/v7/libbeat/cmd/replay"
+	"github.com/elastic/beats/v7/libbeat/common/cli"
 )
 
 func init() {
@@ -50,6 +54,9 @@ type BeatsRootCmd struct {
 	ExportCmd     *cobra.Command
 	TestCmd       *cobra.Command
 	KeystoreCmd   *cobra.Command
+	ReplayCmd     *cobra.Command
+	ProcessorsCmd *cobra.Command
+	BenchCmd      *cobra.Command
 }
 
 // GenRootCmdWithSettings returns the root command to use for your beat. It take the
@@ -76,6 +83,9 @@ func GenRootCmdWithSettings(beatCreator beat.Creator, settings instance.Settings
 	rootCmd.TestCmd = genTestCmd(settings, beatCreator)
 	rootCmd.SetupCmd = genSetupCmd(settings, beatCreator)
 	rootCmd.KeystoreCmd = genKeystoreCmd(settings)
+	rootCmd.ReplayCmd = replay.GenReplayCmd(settings)
+	rootCmd.ProcessorsCmd = playground.GenProcessorsCmd(settings)
+	rootCmd.BenchCmd = bench.GenBenchCmd(settings)
 	rootCmd.VersionCmd = GenVersionCmd(settings)
 	rootCmd.CompletionCmd = genCompletionCmd(settings, rootCmd)
 
@@ -97,6 +107,7 @@ func GenRootCmdWithSettings(beatCreator beat.Creator, settings instance.Settings
 	if f := flag.CommandLine.Lookup("plugin"); f != nil {
 		rootCmd.PersistentFlags().AddGoFlag(f)
 	}
+	cli.AddOutputFlag(&rootCmd.Command)
 
 	// Inherit root flags from run command
 	// TODO deprecate when root command no longer executes run (7.0)
@@ -110,6 +121,9 @@ func GenRootCmdWithSettings(beatCreator beat.Creator, settings instance.Settings
 	rootCmd.AddCommand(rootCmd.ExportCmd)
 	rootCmd.AddCommand(rootCmd.TestCmd)
 	rootCmd.AddCommand(rootCmd.KeystoreCmd)
+	rootCmd.AddCommand(rootCmd.ReplayCmd)
+	rootCmd.AddCommand(rootCmd.ProcessorsCmd)
+	rootCmd.AddCommand(rootCmd.BenchCmd)
 
 	return rootCmd
 }
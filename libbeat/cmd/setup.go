@@ -44,6 +44,9 @@ const (
 	//
 	//Deprecated: use IndexManagementKey instead
 	ILMPolicyKey = "ilm-policy"
+
+	//InteractiveKey used for running the setup wizard before the setup
+	InteractiveKey = "interactive"
 )
 
 func genSetupCmd(settings instance.Settings, beatCreator beat.Creator) *cobra.Command {
@@ -56,8 +59,21 @@ func genSetupCmd(settings instance.Settings, beatCreator beat.Creator) *cobra.Co
  * Kibana dashboards (where available).
  * Ingest pipelines (where available).
  * ILM policy (for Elasticsearch 6.5 and newer).
+
+With --interactive, a wizard writes the configuration first.
 `,
 		Run: func(cmd *cobra.Command, args []string) {
+			if interactive, _ := cmd.Flags().GetBool(InteractiveKey); interactive {
+				proceed, err := runSetupWizard(settings)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error running the setup wizard: %s\n", err)
+					os.Exit(1)
+				}
+				if !proceed {
+					return
+				}
+			}
+
 			beat, err := instance.NewBeat(settings.Name, settings.IndexPrefix, settings.Version, settings.ElasticLicensed)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error initializing beat: %s\n", err)
@@ -122,6 +138,7 @@ func genSetupCmd(settings instance.Settings, beatCreator beat.Creator) *cobra.Co
 	setup.Flags().MarkDeprecated(TemplateKey, fmt.Sprintf("please use --%s instead", IndexManagementKey))
 	setup.Flags().Bool(ILMPolicyKey, false, "Setup ILM policy")
 	setup.Flags().MarkDeprecated(ILMPolicyKey, fmt.Sprintf("please use --%s instead", IndexManagementKey))
+	setup.Flags().Bool(InteractiveKey, false, "Configure the Elasticsearch output, Kibana and modules interactively before the setup")
 
 	return &setup
 }
@@ -0,0 +1,65 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/elastic/beats/v7/libbeat/cfgfile"
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/cmd/wizard"
+)
+
+// runSetupWizard asks the user for the Elasticsearch and Kibana endpoints and
+// the modules to enable, and writes the configuration. It returns whether the
+// setup should continue with the new configuration.
+func runSetupWizard(settings instance.Settings) (bool, error) {
+	b, err := instance.NewInitializedBeat(settings)
+	if err != nil {
+		return false, fmt.Errorf("error initializing beat: %s", err)
+	}
+
+	w := wizard.New(os.Stdin, os.Stdout)
+	w.Hints = settings.ModuleHints
+	w.Keystore = b.Keystore()
+	if len(w.Hints) > 0 {
+		if glob, err := b.Beat.BeatConfig.String("config.modules.path", -1); err == nil {
+			if w.Modules, err = cfgfile.NewGlobManager(glob, ".yml", ".disabled"); err != nil {
+				return false, fmt.Errorf("error in modules manager: %s", err)
+			}
+		}
+	}
+
+	fmt.Printf("This wizard configures %s to send data to Elasticsearch.\n", settings.Name)
+	res, err := w.Run(b.RawConfig)
+	if err != nil {
+		return false, err
+	}
+
+	path := cfgfile.GetDefaultCfgfile()
+	write, err := w.Confirm(fmt.Sprintf("Write the configuration to %s?", path), true)
+	if err != nil || !write {
+		return false, err
+	}
+	if err := w.Apply(path, res); err != nil {
+		return false, err
+	}
+
+	return w.Confirm("Load the index template, dashboards and ingest pipelines now?", true)
+}
@@ -25,6 +25,8 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/common/cli"
+	"github.com/elastic/beats/v7/libbeat/testing"
 )
 
 func GenTestConfigCmd(settings instance.Settings, beatCreator beat.Creator) *cobra.Command {
@@ -38,6 +40,10 @@ func GenTestConfigCmd(settings instance.Settings, beatCreator beat.Creator) *cob
 				os.Exit(1)
 			}
 
+			if cli.GetOutputFormat(cmd) == cli.OutputJSON {
+				os.Exit(testConfigJSON(b, settings, beatCreator))
+			}
+
 			if err = b.TestConfig(settings, beatCreator); err != nil {
 				os.Exit(1)
 			}
@@ -46,3 +52,22 @@ func GenTestConfigCmd(settings instance.Settings, beatCreator beat.Creator) *cob
 
 	return &configTestCmd
 }
+
+// testConfigJSON checks the configuration and writes the result to stdout as
+// JSON, it returns the exit code of the command.
+func testConfigJSON(b *instance.Beat, settings instance.Settings, beatCreator beat.Creator) int {
+	result := testing.JSONResult{Name: "config", Status: testing.StatusOK}
+	if err := b.CheckConfig(settings, beatCreator); err != nil {
+		result.Status = testing.StatusError
+		result.Message = err.Error()
+	}
+
+	if err := cli.WriteJSON(os.Stdout, result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the result: %s\n", err)
+		return 1
+	}
+	if result.Status != testing.StatusOK {
+		return 1
+	}
+	return 0
+}
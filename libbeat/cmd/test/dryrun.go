@@ -0,0 +1,163 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/paths"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// DryRun runs a beater without publishing any events. The events passing the
+// configured processors are printed as JSON instead.
+type DryRun struct {
+	// Events is the number of events to print before the beater is stopped.
+	Events int
+
+	// Timeout is the maximum time to wait for the events.
+	Timeout time.Duration
+
+	// Prepare is called after the beat configuration has been loaded, with the
+	// temporary data directory of the run. It can be used to adjust the beat
+	// configuration so that the beater does not modify any persistent state.
+	Prepare func(b *instance.Beat, dataDir string) error
+}
+
+// Run creates the beater, runs it until enough events have been printed to w,
+// the timeout expires or the beater stops by itself, and returns the number of
+// printed events. The data path of the beat is replaced by a temporary
+// directory that is removed afterwards.
+func (d DryRun) Run(settings instance.Settings, create beat.Creator, w io.Writer) (int, error) {
+	b, err := instance.NewInitializedBeat(settings)
+	if err != nil {
+		return 0, fmt.Errorf("error initializing beat: %v", err)
+	}
+
+	dataDir, err := ioutil.TempDir("", b.Info.Beat+"-dry-run")
+	if err != nil {
+		return 0, fmt.Errorf("error creating temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dataDir)
+	paths.Paths.Data = dataDir
+
+	if d.Prepare != nil {
+		if err := d.Prepare(b, dataDir); err != nil {
+			return 0, err
+		}
+	}
+
+	client := newEventPrinter(w, b.Info.Beat, b.Info.Version, d.Events)
+	bt, err := b.CreateDryRunBeater(create, client)
+	if err != nil {
+		return 0, fmt.Errorf("error initializing %s: %v", b.Info.Beat, err)
+	}
+
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- bt.Run(&b.Beat)
+	}()
+
+	timer := time.NewTimer(d.Timeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-runErr:
+		return client.Printed(), err
+	case <-client.Done():
+	case <-timer.C:
+	}
+
+	bt.Stop()
+	return client.Printed(), <-runErr
+}
+
+// eventPrinter is an output client that prints the events it receives as
+// pretty printed JSON. All batches are acknowledged, the events exceeding
+// the limit are dropped.
+type eventPrinter struct {
+	index   string
+	encoder *json.Encoder
+
+	mu      sync.Mutex
+	w       io.Writer
+	limit   int
+	printed int
+	done    chan struct{}
+}
+
+func newEventPrinter(w io.Writer, index, version string, limit int) *eventPrinter {
+	return &eventPrinter{
+		index:   index,
+		encoder: json.New(version, json.Config{Pretty: true}),
+		w:       w,
+		limit:   limit,
+		done:    make(chan struct{}),
+	}
+}
+
+func (p *eventPrinter) Publish(_ context.Context, batch publisher.Batch) error {
+	defer batch.ACK()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, event := range batch.Events() {
+		if p.printed >= p.limit {
+			break
+		}
+
+		serialized, err := p.encoder.Encode(p.index, &event.Content)
+		if err != nil {
+			fmt.Fprintf(p.w, "Error encoding event: %v\n", err)
+		} else {
+			p.w.Write(serialized)
+			io.WriteString(p.w, "\n")
+		}
+
+		p.printed++
+		if p.printed == p.limit {
+			close(p.done)
+		}
+	}
+	return nil
+}
+
+// Done returns a channel that is closed once the limit of events is reached.
+func (p *eventPrinter) Done() <-chan struct{} {
+	return p.done
+}
+
+// Printed returns the number of events printed so far.
+func (p *eventPrinter) Printed() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.printed
+}
+
+func (p *eventPrinter) Close() error   { return nil }
+func (p *eventPrinter) String() string { return "dry-run" }
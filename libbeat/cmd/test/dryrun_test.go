@@ -0,0 +1,85 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+)
+
+func TestEventPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	printer := newEventPrinter(&buf, "test", "1.2.3", 3)
+
+	newBatch := func(n int) *outest.Batch {
+		var events []beat.Event
+		for i := 0; i < n; i++ {
+			events = append(events, beat.Event{
+				Timestamp: time.Now(),
+				Fields:    common.MapStr{"message": "hello", "n": i},
+			})
+		}
+		return outest.NewBatch(events...)
+	}
+
+	first := newBatch(2)
+	require.NoError(t, printer.Publish(context.Background(), first))
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, first.Signals)
+	assert.Equal(t, 2, printer.Printed())
+
+	select {
+	case <-printer.Done():
+		t.Fatal("done before the limit was reached")
+	default:
+	}
+
+	// events exceeding the limit are acknowledged, but not printed
+	second := newBatch(2)
+	require.NoError(t, printer.Publish(context.Background(), second))
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, second.Signals)
+	assert.Equal(t, 3, printer.Printed())
+
+	select {
+	case <-printer.Done():
+	default:
+		t.Fatal("not done after the limit was reached")
+	}
+
+	require.NoError(t, printer.Publish(context.Background(), newBatch(1)))
+	assert.Equal(t, 3, printer.Printed())
+
+	decoder := json.NewDecoder(strings.NewReader(buf.String()))
+	for i := 0; i < 3; i++ {
+		var event map[string]interface{}
+		require.NoError(t, decoder.Decode(&event))
+		assert.Equal(t, "hello", event["message"])
+		assert.Equal(t, "1.2.3", event["@metadata"].(map[string]interface{})["version"])
+	}
+	assert.False(t, decoder.More())
+}
@@ -0,0 +1,197 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/testing"
+)
+
+// loadSettings configures the burst of synthetic events published by
+// `test output`.
+type loadSettings struct {
+	events    int
+	batchSize int
+	timeout   time.Duration
+}
+
+// loadResult summarizes the publishing of the synthetic events.
+type loadResult struct {
+	published int
+	failed    int
+	duration  time.Duration
+	latencies []time.Duration
+}
+
+// runLoadTest connects the client, publishes the synthetic events in batches
+// and reports the connect time, the latency percentiles of the batches and the
+// achieved throughput.
+func runLoadTest(d testing.Driver, client outputs.Client, settings loadSettings) {
+	d.Run(fmt.Sprintf("publish %d events", settings.events), func(d testing.Driver) {
+		if nc, ok := client.(outputs.NetworkClient); ok {
+			start := time.Now()
+			err := nc.Connect()
+			d.Fatal("connect", err)
+			d.Info("connect time", formatDuration(time.Since(start)))
+		}
+		defer client.Close()
+
+		result := publishEvents(client, settings)
+		if result.failed > 0 {
+			d.Error("publish", fmt.Errorf("%d of %d events failed", result.failed, settings.events))
+		} else {
+			d.Error("publish", nil)
+		}
+
+		d.Info("published events", fmt.Sprint(result.published))
+		d.Info("duration", formatDuration(result.duration))
+		d.Info("throughput", fmt.Sprintf("%.1f events/s", throughput(result.published, result.duration)))
+		for _, p := range []float64{50, 90, 99} {
+			d.Info(fmt.Sprintf("batch latency p%v", p), formatDuration(percentile(result.latencies, p)))
+		}
+	})
+}
+
+func publishEvents(client outputs.Client, settings loadSettings) loadResult {
+	var result loadResult
+
+	start := time.Now()
+	for sent := 0; sent < settings.events; {
+		n := settings.batchSize
+		if remaining := settings.events - sent; remaining < n {
+			n = remaining
+		}
+		sent += n
+
+		batch := newLoadBatch(makeEvents(sent-n, n))
+		batchStart := time.Now()
+		err := client.Publish(context.Background(), batch)
+		if err == nil {
+			err = batch.wait(settings.timeout)
+		}
+		if err != nil {
+			result.failed += n
+			continue
+		}
+
+		result.latencies = append(result.latencies, time.Since(batchStart))
+		result.failed += batch.failed
+		result.published += n - batch.failed
+	}
+	result.duration = time.Since(start)
+	return result
+}
+
+func makeEvents(offset, n int) []publisher.Event {
+	now := time.Now()
+	events := make([]publisher.Event, n)
+	for i := range events {
+		events[i] = publisher.Event{
+			Content: beat.Event{
+				Timestamp: now,
+				Fields: common.MapStr{
+					"message": fmt.Sprintf("test output event %d", offset+i),
+					"event": common.MapStr{
+						"kind":     "event",
+						"category": "test",
+					},
+				},
+			},
+			Flags: publisher.GuaranteedSend,
+		}
+	}
+	return events
+}
+
+var errBatchTimeout = errors.New("timeout waiting for the output to acknowledge the events")
+
+// loadBatch is a publisher.Batch signaling when the output is done with it.
+// Events to be retried are counted as failed, they are not published again.
+type loadBatch struct {
+	events []publisher.Event
+	failed int
+	once   sync.Once
+	done   chan struct{}
+}
+
+func newLoadBatch(events []publisher.Event) *loadBatch {
+	return &loadBatch{events: events, done: make(chan struct{})}
+}
+
+func (b *loadBatch) wait(timeout time.Duration) error {
+	select {
+	case <-b.done:
+		return nil
+	case <-time.After(timeout):
+		return errBatchTimeout
+	}
+}
+
+func (b *loadBatch) finish(failed int) {
+	b.once.Do(func() {
+		b.failed = failed
+		close(b.done)
+	})
+}
+
+func (b *loadBatch) Events() []publisher.Event                { return b.events }
+func (b *loadBatch) ACK()                                     { b.finish(0) }
+func (b *loadBatch) Drop()                                    { b.finish(len(b.events)) }
+func (b *loadBatch) Retry()                                   { b.finish(len(b.events)) }
+func (b *loadBatch) RetryEvents(events []publisher.Event)     { b.finish(len(events)) }
+func (b *loadBatch) Cancelled()                               { b.finish(len(b.events)) }
+func (b *loadBatch) CancelledEvents(events []publisher.Event) { b.finish(len(events)) }
+
+func throughput(events int, duration time.Duration) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	return float64(events) / duration.Seconds()
+}
+
+// percentile returns the p-th percentile of the durations, using the nearest
+// rank method.
+func percentile(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,91 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+type mockClient struct {
+	publish func(publisher.Batch) error
+}
+
+func (c *mockClient) Close() error   { return nil }
+func (c *mockClient) String() string { return "mock" }
+func (c *mockClient) Publish(_ context.Context, batch publisher.Batch) error {
+	return c.publish(batch)
+}
+
+func TestPublishEvents(t *testing.T) {
+	settings := loadSettings{events: 25, batchSize: 10, timeout: time.Second}
+
+	t.Run("all events acknowledged", func(t *testing.T) {
+		var sizes []int
+		client := &mockClient{publish: func(batch publisher.Batch) error {
+			sizes = append(sizes, len(batch.Events()))
+			// acknowledge asynchronously, like the logstash output
+			go batch.ACK()
+			return nil
+		}}
+
+		result := publishEvents(client, settings)
+		assert.Equal(t, []int{10, 10, 5}, sizes)
+		assert.Equal(t, 25, result.published)
+		assert.Equal(t, 0, result.failed)
+		assert.Len(t, result.latencies, 3)
+	})
+
+	t.Run("partial failures", func(t *testing.T) {
+		client := &mockClient{publish: func(batch publisher.Batch) error {
+			events := batch.Events()
+			batch.RetryEvents(events[:1])
+			return nil
+		}}
+
+		result := publishEvents(client, settings)
+		assert.Equal(t, 22, result.published)
+		assert.Equal(t, 3, result.failed)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		client := &mockClient{publish: func(batch publisher.Batch) error { return nil }}
+
+		result := publishEvents(client, loadSettings{events: 1, batchSize: 1, timeout: time.Millisecond})
+		assert.Equal(t, 0, result.published)
+		assert.Equal(t, 1, result.failed)
+	})
+}
+
+func TestPercentile(t *testing.T) {
+	var durations []time.Duration
+	for i := 100; i > 0; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+
+	assert.Equal(t, 50*time.Millisecond, percentile(durations, 50))
+	assert.Equal(t, 90*time.Millisecond, percentile(durations, 90))
+	assert.Equal(t, 99*time.Millisecond, percentile(durations, 99))
+	assert.Equal(t, 100*time.Millisecond, percentile(durations, 100))
+	assert.Equal(t, time.Duration(0), percentile(nil, 50))
+}
@@ -20,20 +20,29 @@ package test
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/elastic/beats/v7/libbeat/cmd/instance"
+	"github.com/elastic/beats/v7/libbeat/common/cli"
 	"github.com/elastic/beats/v7/libbeat/idxmgmt"
 	"github.com/elastic/beats/v7/libbeat/outputs"
 	"github.com/elastic/beats/v7/libbeat/testing"
 )
 
 func GenTestOutputCmd(settings instance.Settings) *cobra.Command {
-	return &cobra.Command{
+	var load loadSettings
+
+	command := &cobra.Command{
 		Use:   "output",
 		Short: "Test " + settings.Name + " can connect to the output by using the current settings",
 		Run: func(cmd *cobra.Command, args []string) {
+			if load.batchSize < 1 {
+				fmt.Fprintf(os.Stderr, "The batch size must be at least 1\n")
+				os.Exit(1)
+			}
+
 			b, err := instance.NewInitializedBeat(settings)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error initializing beat: %s\n", err)
@@ -47,16 +56,32 @@ func GenTestOutputCmd(settings instance.Settings) *cobra.Command {
 				os.Exit(1)
 			}
 
+			var driver testing.Driver = testing.NewConsoleDriver(os.Stdout)
+			if cli.GetOutputFormat(cmd) == cli.OutputJSON {
+				jsonDriver := testing.NewJSONDriver(os.Stdout)
+				defer jsonDriver.Flush()
+				driver = jsonDriver
+			}
+
 			for _, client := range output.Clients {
 				tClient, ok := client.(testing.Testable)
 				if !ok {
-					fmt.Printf("%s output doesn't support testing\n", b.Config.Output.Name())
+					fmt.Fprintf(os.Stderr, "%s output doesn't support testing\n", b.Config.Output.Name())
 					os.Exit(1)
 				}
 
 				// Perform test:
-				tClient.Test(testing.NewConsoleDriver(os.Stdout))
+				tClient.Test(driver)
+
+				if load.events > 0 {
+					runLoadTest(driver, client, load)
+				}
 			}
 		},
 	}
+
+	command.Flags().IntVar(&load.events, "events", 0, "Number of synthetic events to publish to measure latency and throughput, 0 disables publishing")
+	command.Flags().IntVar(&load.batchSize, "batch-size", 50, "Number of synthetic events per batch")
+	command.Flags().DurationVar(&load.timeout, "timeout", 30*time.Second, "Maximum time to wait for the output to acknowledge a batch")
+	return command
 }
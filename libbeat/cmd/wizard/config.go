@@ -0,0 +1,150 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package wizard
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// updateConfig replaces the outputs and the Kibana settings of the YAML
+// document by the given ones. The settings are written in dotted form, at the
+// position of the settings they replace, so the order and the comments of the
+// rest of the document are kept.
+func updateConfig(doc *yaml.Node, output, kibana *yaml.Node) {
+	root := doc.Content[0]
+
+	outputAt, comment := removeSetting(root, "output")
+	insertSetting(root, outputAt, scalar("output.elasticsearch", comment), output)
+
+	kibanaAt, comment := removeSetting(root, "setup.kibana")
+	insertSetting(root, kibanaAt, scalar("setup.kibana", comment), kibana)
+}
+
+// removeSetting removes the setting with the given dotted name from the
+// mapping, no matter how it is nested. It returns the position where the
+// setting was first found at the top level, or -1 if it is not found, and the
+// comment of the setting removed at this position.
+func removeSetting(mapping *yaml.Node, name string) (int, string) {
+	at, comment := -1, ""
+	content := make([]*yaml.Node, 0, len(mapping.Content))
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		switch {
+		case key.Value == name || strings.HasPrefix(key.Value, name+"."):
+			if at < 0 {
+				at, comment = len(content)/2, key.HeadComment
+			}
+			continue
+
+		case strings.HasPrefix(name, key.Value+".") && value.Kind == yaml.MappingNode:
+			subAt, _ := removeSetting(value, strings.TrimPrefix(name, key.Value+"."))
+			if len(value.Content) == 0 {
+				if subAt >= 0 && at < 0 {
+					at, comment = len(content)/2, key.HeadComment
+				}
+				continue
+			}
+			if subAt >= 0 && at < 0 {
+				at = len(content)/2 + 1
+			}
+		}
+		content = append(content, key, value)
+	}
+	mapping.Content = content
+	return at, comment
+}
+
+// insertSetting inserts the setting in the mapping at the given position, or
+// at the end if the position is out of range.
+func insertSetting(mapping *yaml.Node, at int, key, value *yaml.Node) {
+	if at < 0 || 2*at > len(mapping.Content) {
+		at = len(mapping.Content) / 2
+	}
+	mapping.Content = append(mapping.Content, nil, nil)
+	copy(mapping.Content[2*at+2:], mapping.Content[2*at:])
+	mapping.Content[2*at], mapping.Content[2*at+1] = key, value
+}
+
+func scalar(value, comment string) *yaml.Node {
+	node := &yaml.Node{HeadComment: comment}
+	node.SetString(value)
+	return node
+}
+
+func newMapping(content ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: content}
+}
+
+func newFlowSequence(values ...string) *yaml.Node {
+	seq := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Style: yaml.FlowStyle}
+	for _, value := range values {
+		seq.Content = append(seq.Content, scalar(value, ""))
+	}
+	return seq
+}
+
+// readConfig reads the YAML document of the configuration file. A missing or
+// empty file is read as an empty document.
+func readConfig(path string) (*yaml.Node, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %s", path)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{newMapping()}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.Errorf("failed to parse %s: the configuration is not a mapping", path)
+	}
+	return &doc, nil
+}
+
+// writeConfig writes the YAML document to the configuration file, keeping
+// its permissions. An existing file is renamed with the `.bak` suffix.
+func writeConfig(path string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+		if err := os.Rename(path, path+".bak"); err != nil {
+			return errors.Wrap(err, "failed to back up the configuration file")
+		}
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), mode)
+}
@@ -0,0 +1,69 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package wizard
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ModuleHint describes how to detect a local service that can be monitored
+// by a module.
+type ModuleHint struct {
+	// Module is the name of the module suggested when the service is found.
+	Module string
+
+	// Ports are TCP ports on localhost the service usually listens on.
+	Ports []int
+
+	// Paths are glob patterns of files installed by the service, like
+	// binaries, configuration files or logs.
+	Paths []string
+}
+
+// detection is a module suggested to the user with the reason it was
+// suggested.
+type detection struct {
+	module string
+	reason string
+}
+
+// detect returns the first evidence of the service described by the hint
+// being installed or running locally, or an empty string if none is found.
+func detect(hint ModuleHint, timeout time.Duration) string {
+	for _, pattern := range hint.Paths {
+		matches, err := filepath.Glob(pattern)
+		if err == nil && len(matches) > 0 {
+			return fmt.Sprintf("found %s", matches[0])
+		}
+	}
+
+	for _, port := range hint.Ports {
+		addr := net.JoinHostPort("localhost", strconv.Itoa(port))
+		conn, err := net.DialTimeout("tcp", addr, timeout)
+		if err == nil {
+			conn.Close()
+			return fmt.Sprintf("port %d is open", port)
+		}
+	}
+
+	return ""
+}
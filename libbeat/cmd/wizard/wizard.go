@@ -0,0 +1,400 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package wizard implements the guided configuration of `setup --interactive`.
+// It asks for the Elasticsearch and Kibana endpoints, checking they can be
+// reached, suggests modules for the services detected locally and writes the
+// resulting configuration.
+package wizard
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh/terminal"
+
+	"github.com/elastic/beats/v7/libbeat/audit"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/esleg/eslegclient"
+	"github.com/elastic/beats/v7/libbeat/keystore"
+	"github.com/elastic/beats/v7/libbeat/kibana"
+)
+
+// passwordKey is the keystore key the Elasticsearch password is stored with.
+const passwordKey = "ES_PWD"
+
+var errNoInput = errors.New("error reading user input")
+
+// ModulesManager gives access to the modules that can be enabled.
+type ModulesManager interface {
+	Exists(name string) bool
+	Enabled(name string) bool
+	Enable(name string) error
+}
+
+// Wizard guides the user through the configuration of the Elasticsearch
+// output, the Kibana endpoint and the modules to enable.
+type Wizard struct {
+	// Hints are used to suggest modules for the services detected locally.
+	Hints []ModuleHint
+
+	// Modules manages the modules to enable. No module is suggested if nil.
+	Modules ModulesManager
+
+	// Keystore stores the Elasticsearch password. If it is not writable, the
+	// user is asked to confirm writing the password to the configuration file.
+	Keystore keystore.Keystore
+
+	// Timeout of the connections to Elasticsearch, Kibana and the local
+	// services.
+	Timeout time.Duration
+
+	in           *bufio.Scanner
+	out          io.Writer
+	readPassword func() (string, error)
+}
+
+// Result contains the settings chosen by the user.
+type Result struct {
+	Host       string
+	Username   string
+	Password   string
+	KibanaHost string
+	Modules    []string
+}
+
+// New creates a wizard reading the answers from in and writing the questions
+// to out. Passwords are not echoed when in is a terminal.
+func New(in io.Reader, out io.Writer) *Wizard {
+	w := &Wizard{
+		Timeout: 5 * time.Second,
+		in:      bufio.NewScanner(in),
+		out:     out,
+	}
+	w.readPassword = w.readLine
+	if f, ok := in.(*os.File); ok && terminal.IsTerminal(int(f.Fd())) {
+		w.readPassword = func() (string, error) {
+			password, err := terminal.ReadPassword(int(f.Fd()))
+			fmt.Fprintln(out)
+			return string(password), err
+		}
+	}
+	return w
+}
+
+// Run asks the user for the settings, using the current configuration for
+// the defaults.
+func (w *Wizard) Run(config *common.Config) (Result, error) {
+	res, err := readDefaults(config)
+	if err != nil {
+		return res, err
+	}
+
+	if err := w.askElasticsearch(&res); err != nil {
+		return res, err
+	}
+	if err := w.askKibana(&res); err != nil {
+		return res, err
+	}
+	if res.Modules, err = w.askModules(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// Apply writes the settings to the configuration file and enables the
+// selected modules.
+func (w *Wizard) Apply(path string, res Result) error {
+	doc, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+
+	output := newMapping(scalar("hosts", ""), newFlowSequence(res.Host))
+	if res.Username != "" {
+		password, err := w.storePassword(res.Password)
+		if err != nil {
+			return err
+		}
+		output.Content = append(output.Content,
+			scalar("username", ""), scalar(res.Username, ""),
+			scalar("password", ""), scalar(password, ""),
+		)
+	}
+	kibana := newMapping(scalar("host", ""), scalar(res.KibanaHost, ""))
+	updateConfig(doc, output, kibana)
+
+	if err := writeConfig(path, doc); err != nil {
+		return err
+	}
+	audit.Log(audit.ActionConfigWrite, "file.path", path)
+	fmt.Fprintf(w.out, "Configuration written to %s.\n", path)
+
+	for _, module := range res.Modules {
+		if err := w.Modules.Enable(module); err != nil {
+			return errors.Wrapf(err, "failed to enable module %s", module)
+		}
+		audit.Log(audit.ActionModulesEnable, "module", module)
+		fmt.Fprintf(w.out, "Enabled %s\n", module)
+	}
+	return nil
+}
+
+// storePassword stores the password in the keystore and returns the reference
+// to write in the configuration. If the keystore is not writable, the password
+// is only written in clear text to the configuration if the user confirms it.
+func (w *Wizard) storePassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+
+	store, err := keystore.AsWritableKeystore(w.Keystore)
+	if err != nil {
+		fmt.Fprintf(w.out, "The keystore is not writable: %v\n", err)
+		cleartext, err := w.Confirm("Write the Elasticsearch password in clear text to the configuration file?", false)
+		if err != nil {
+			return "", err
+		}
+		if !cleartext {
+			return "", errors.New("the Elasticsearch password cannot be stored in the keystore, create the keystore or fix its permissions and try again")
+		}
+		return password, nil
+	}
+
+	if err := store.Store(passwordKey, []byte(password)); err != nil {
+		return "", errors.Wrap(err, "failed to store the password in the keystore")
+	}
+	if err := store.Save(); err != nil {
+		return "", errors.Wrap(err, "failed to save the keystore")
+	}
+	audit.Log(audit.ActionKeystoreAdd, "keystore.key", passwordKey)
+	fmt.Fprintf(w.out, "The Elasticsearch password is stored in the keystore as %s.\n", passwordKey)
+	return "${" + passwordKey + "}", nil
+}
+
+func readDefaults(config *common.Config) (Result, error) {
+	res := Result{Host: "localhost:9200", KibanaHost: "localhost:5601"}
+
+	var settings struct {
+		Output common.ConfigNamespace `config:"output"`
+		Kibana struct {
+			Host string `config:"host"`
+		} `config:"setup.kibana"`
+	}
+	if err := config.Unpack(&settings); err != nil {
+		return res, err
+	}
+
+	if settings.Output.Name() == "elasticsearch" {
+		var es struct {
+			Hosts    []string `config:"hosts"`
+			Username string   `config:"username"`
+		}
+		if err := settings.Output.Config().Unpack(&es); err != nil {
+			return res, err
+		}
+		if len(es.Hosts) > 0 {
+			res.Host = es.Hosts[0]
+		}
+		res.Username = es.Username
+	}
+	if settings.Kibana.Host != "" {
+		res.KibanaHost = settings.Kibana.Host
+	}
+	return res, nil
+}
+
+func (w *Wizard) askElasticsearch(res *Result) error {
+	for {
+		var err error
+		if res.Host, err = w.ask("Elasticsearch host", res.Host); err != nil {
+			return err
+		}
+		if res.Username, err = w.ask("Elasticsearch username (leave empty for none)", res.Username); err != nil {
+			return err
+		}
+		res.Password = ""
+		if res.Username != "" {
+			fmt.Fprint(w.out, "Elasticsearch password: ")
+			if res.Password, err = w.readPassword(); err != nil {
+				return err
+			}
+		}
+
+		version, err := probeElasticsearch(res, w.Timeout)
+		if err == nil {
+			fmt.Fprintf(w.out, "Connected to Elasticsearch %s.\n", version)
+			return nil
+		}
+		fmt.Fprintf(w.out, "Could not connect to Elasticsearch: %v\n", err)
+		if retry, err := w.Confirm("Try again?", true); err != nil || !retry {
+			return err
+		}
+	}
+}
+
+func (w *Wizard) askKibana(res *Result) error {
+	for {
+		var err error
+		if res.KibanaHost, err = w.ask("Kibana host", res.KibanaHost); err != nil {
+			return err
+		}
+
+		version, err := probeKibana(res, w.Timeout)
+		if err == nil {
+			fmt.Fprintf(w.out, "Connected to Kibana %s.\n", version)
+			return nil
+		}
+		fmt.Fprintf(w.out, "Could not connect to Kibana: %v\n", err)
+		if retry, err := w.Confirm("Try again?", true); err != nil || !retry {
+			return err
+		}
+	}
+}
+
+func (w *Wizard) askModules() ([]string, error) {
+	if w.Modules == nil {
+		return nil, nil
+	}
+
+	var found []detection
+	for _, hint := range w.Hints {
+		if !w.Modules.Exists(hint.Module) || w.Modules.Enabled(hint.Module) {
+			continue
+		}
+		if reason := detect(hint, w.Timeout); reason != "" {
+			found = append(found, detection{module: hint.Module, reason: reason})
+		}
+	}
+	if len(found) == 0 {
+		fmt.Fprintln(w.out, "No local services to monitor were detected.")
+		return nil, nil
+	}
+
+	var modules []string
+	for _, d := range found {
+		enable, err := w.Confirm(fmt.Sprintf("Enable the %s module (%s)?", d.module, d.reason), true)
+		if err != nil {
+			return nil, err
+		}
+		if enable {
+			modules = append(modules, d.module)
+		}
+	}
+	return modules, nil
+}
+
+func probeElasticsearch(res *Result, timeout time.Duration) (string, error) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"hosts":    []string{res.Host},
+		"username": res.Username,
+		"password": res.Password,
+		"timeout":  timeout,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	conn, err := eslegclient.NewConnectedClient(cfg)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	version := conn.GetVersion()
+	return version.String(), nil
+}
+
+func probeKibana(res *Result, timeout time.Duration) (string, error) {
+	cfg, err := common.NewConfigFrom(map[string]interface{}{
+		"host":     res.KibanaHost,
+		"username": res.Username,
+		"password": res.Password,
+		"timeout":  timeout,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	client, err := kibana.NewKibanaClient(cfg)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+	version := client.GetVersion()
+	return version.String(), nil
+}
+
+// ask shows the question and returns the answer, or the default value if the
+// answer is empty.
+func (w *Wizard) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+
+	answer, err := w.readLine()
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// Confirm shows the question and asks the user to answer (y/n), the default
+// is selected if the answer is empty.
+func (w *Wizard) Confirm(question string, def bool) (bool, error) {
+	options := "[Y/n]"
+	if !def {
+		options = "[y/N]"
+	}
+
+	for {
+		fmt.Fprintf(w.out, "%s %s: ", question, options)
+		answer, err := w.readLine()
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		default:
+			fmt.Fprintln(w.out, "Please write 'y' or 'n'")
+		}
+	}
+}
+
+func (w *Wizard) readLine() (string, error) {
+	if !w.in.Scan() {
+		if err := w.in.Err(); err != nil {
+			return "", err
+		}
+		return "", errNoInput
+	}
+	return strings.TrimSpace(w.in.Text()), nil
+}
@@ -0,0 +1,237 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package wizard
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+type testModules map[string]bool
+
+func (m testModules) Exists(name string) bool  { _, ok := m[name]; return ok }
+func (m testModules) Enabled(name string) bool { return m[name] }
+func (m testModules) Enable(name string) error { m[name] = true; return nil }
+
+func versionServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"version": {"number": "7.10.0"}}`)
+	}))
+}
+
+func TestWizard(t *testing.T) {
+	es := versionServer(t)
+	defer es.Close()
+	kb := versionServer(t)
+	defer kb.Close()
+
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer l.Close()
+	port := l.Addr().(*net.TCPAddr).Port
+
+	dir, err := ioutil.TempDir("", "wizard")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "access.log"), nil, 0644))
+
+	config := common.MustNewConfigFrom(map[string]interface{}{
+		"output.elasticsearch.hosts": []string{"localhost:1"},
+	})
+
+	answers := []string{
+		"localhost:1", "", // unreachable Elasticsearch, no credentials
+		"y",                           // try again
+		es.URL, "elastic", "changeme", // Elasticsearch
+		kb.URL, // Kibana
+		"y",    // enable the detected nginx module
+		"n",    // do not enable the detected redis module
+	}
+	var out bytes.Buffer
+	w := New(strings.NewReader(strings.Join(answers, "\n")+"\n"), &out)
+	w.Timeout = time.Second
+	w.Modules = testModules{"nginx": false, "redis": false, "mysql": false, "system": true}
+	w.Hints = []ModuleHint{
+		{Module: "nginx", Paths: []string{filepath.Join(dir, "*.log")}},
+		{Module: "redis", Ports: []int{port}},
+		{Module: "mysql", Paths: []string{filepath.Join(dir, "missing")}},
+		{Module: "system", Paths: []string{filepath.Join(dir, "*.log")}},
+		{Module: "unknown", Paths: []string{filepath.Join(dir, "*.log")}},
+	}
+
+	res, err := w.Run(config)
+	require.NoError(t, err, out.String())
+	assert.Equal(t, Result{
+		Host:       es.URL,
+		Username:   "elastic",
+		Password:   "changeme",
+		KibanaHost: kb.URL,
+		Modules:    []string{"nginx"},
+	}, res)
+	assert.Contains(t, out.String(), "Elasticsearch host [localhost:1]:")
+	assert.Contains(t, out.String(), "Could not connect to Elasticsearch")
+	assert.Contains(t, out.String(), "Connected to Elasticsearch 7.10.0.")
+	assert.Contains(t, out.String(), "Connected to Kibana 7.10.0.")
+	assert.Contains(t, out.String(), fmt.Sprintf("Enable the redis module (port %d is open)?", port))
+
+	path := filepath.Join(dir, "beat.yml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`# Test configuration
+name: test
+
+# Output section
+output.logstash:
+  hosts: ["localhost:5044"]
+setup:
+  dashboards.enabled: true # load the dashboards
+  kibana.host: "localhost:5601"
+logging.level: debug
+`), 0640))
+
+	// The keystore is not writable, the password is only written to the
+	// configuration after confirming it.
+	w.in = bufio.NewScanner(strings.NewReader("n\n"))
+	assert.Error(t, w.Apply(path, res))
+	assert.NoFileExists(t, path+".bak")
+
+	w.in = bufio.NewScanner(strings.NewReader("y\n"))
+	require.NoError(t, w.Apply(path, res))
+	assert.True(t, w.Modules.Enabled("nginx"))
+	assert.False(t, w.Modules.Enabled("redis"))
+	assert.FileExists(t, path+".bak")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `# Test configuration
+name: test
+# Output section
+output.elasticsearch:
+  hosts: ['`+es.URL+`']
+  username: elastic
+  password: changeme
+setup:
+  dashboards.enabled: true # load the dashboards
+setup.kibana:
+  host: `+kb.URL+`
+logging.level: debug
+`, string(content))
+}
+
+func TestUpdateConfig(t *testing.T) {
+	tests := map[string]struct {
+		doc      string
+		expected string
+	}{
+		"empty": {
+			expected: `output.elasticsearch:
+  hosts: ['es:9200']
+setup.kibana:
+  host: kibana:5601
+`,
+		},
+		"nested settings": {
+			doc: `output:
+  kafka: {}
+setup:
+  kibana: {}
+name: test
+`,
+			expected: `output.elasticsearch:
+  hosts: ['es:9200']
+setup.kibana:
+  host: kibana:5601
+name: test
+`,
+		},
+		"dotted settings": {
+			doc: `setup.kibana.host: localhost
+name: test
+output.elasticsearch.hosts: [localhost]
+output.elasticsearch.username: elastic
+`,
+			expected: `setup.kibana:
+  host: kibana:5601
+name: test
+output.elasticsearch:
+  hosts: ['es:9200']
+`,
+		},
+		"comments": {
+			doc: `# ---- Kibana ----
+setup.kibana:
+  # The Kibana host
+  host: localhost
+
+# ---- Outputs ----
+#output.elasticsearch:
+output.logstash:
+  hosts: [localhost]
+`,
+			expected: `# ---- Kibana ----
+setup.kibana:
+  host: kibana:5601
+# ---- Outputs ----
+#output.elasticsearch:
+output.elasticsearch:
+  hosts: ['es:9200']
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "wizard")
+			require.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			path := filepath.Join(dir, "beat.yml")
+			require.NoError(t, ioutil.WriteFile(path, []byte(test.doc), 0600))
+
+			doc, err := readConfig(path)
+			require.NoError(t, err)
+			updateConfig(doc,
+				newMapping(scalar("hosts", ""), newFlowSequence("es:9200")),
+				newMapping(scalar("host", ""), scalar("kibana:5601", "")),
+			)
+			require.NoError(t, writeConfig(path, doc))
+
+			content, err := ioutil.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(content))
+		})
+	}
+}
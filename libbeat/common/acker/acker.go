@@ -65,11 +65,7 @@ func (countACKer) Close()                        {}
 // If there is no event currently tracked by this ACKer and the next event is dropped by the processors,
 // then `fn` will be called immediately with acked=0 and total=1.
 func TrackingCounter(fn func(acked, total int)) beat.ACKer {
-	a := &trackingACKer{fn: fn}
-	init := &gapInfo{}
-	a.lst.head = init
-	a.lst.tail = init
-	return a
+	return &trackingACKer{fn: fn, gaps: newGapQueue()}
 }
 
 // Counting returns an ACK count for all events a client has tried to publish.
@@ -80,130 +76,177 @@ func Counting(fn func(n int)) beat.ACKer {
 	})
 }
 
+// trackingACKer counts the published and dropped events without sharing a
+// lock between the clients adding events and the outputs ACKing them. The
+// events dropped after a number of published events are recorded in a queue of
+// gaps, that is read when the published events are ACKed. Reports to fn are
+// serialized, and include each event exactly once, in order. They are queued
+// under the locks, and delivered once the locks are released, so fn can
+// publish events.
 type trackingACKer struct {
-	fn     func(acked, total int)
-	events atomic.Uint32
-	lst    gapList
+	// published is the number of published events, set by AddEvent.
+	published atomic.Uint64
+
+	// acked is the number of published events ACKed, set by ACKEvents.
+	acked atomic.Uint64
+
+	fn func(acked, total int)
+
+	// dataFn is called instead of fn with the private data of the events, if
+	// data is set.
+	dataFn func(acked int, data []interface{})
+	data   *dataQueue
+
+	// addMu serializes AddEvent, and protects the producer side of gaps and
+	// data.
+	addMu    sync.Mutex
+	dropped  uint64 // number of dropped events
+	gapStart uint64 // number of dropped events before the last gap
+
+	// mu serializes the reports, and protects the consumer side of gaps and
+	// data.
+	mu       sync.Mutex
+	gaps     gapQueue
+	current  *gap   // last gap covered by the ACKed events
+	reported uint64 // number of events reported
+
+	// pending are the reports not delivered yet, delivering is set while a
+	// goroutine delivers them. Both are protected by mu.
+	pending    []ackReport
+	delivering bool
 }
 
-type gapList struct {
-	sync.Mutex
-	head, tail *gapInfo
+type ackReport struct {
+	acked, total int
+	data         []interface{}
 }
 
-type gapInfo struct {
-	sync.Mutex
-	next          *gapInfo
-	send, dropped int
+func (a *trackingACKer) AddEvent(event beat.Event, published bool) {
+	if a.addEvent(event, published) {
+		a.deliver()
+	}
 }
 
-func (a *trackingACKer) AddEvent(_ beat.Event, published bool) {
-	a.events.Inc()
+// addEvent records the event, and returns true if a report of the dropped
+// events has been queued.
+func (a *trackingACKer) addEvent(event beat.Event, published bool) bool {
+	a.addMu.Lock()
+	defer a.addMu.Unlock()
+
+	if a.data != nil {
+		a.data.push(event.Private)
+	}
+
+	n := a.published.Load()
 	if published {
-		a.addPublishedEvent()
-	} else {
-		a.addDropEvent()
+		a.published.Store(n + 1)
+		return false
+	}
+
+	if a.gaps.last == nil || a.gaps.last.published != n {
+		a.gapStart = a.dropped
+	}
+	a.dropped++
+	a.gaps.drop(n, a.dropped)
+
+	// The dropped event is reported immediately if all published events have
+	// been ACKed. Otherwise it's reported with the next ACK.
+	return a.acked.Load() == n && a.reportDropped(n)
+}
+
+// reportDropped reports the events dropped after the last of the given number
+// of published events, unless an ACK of the published events is still being
+// reported, which then includes them. It returns true if a report is queued.
+func (a *trackingACKer) reportDropped(published uint64) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.reported < published+a.gapStart {
+		return false
 	}
+	total := published + a.dropped - a.reported
+	if total == 0 {
+		return false
+	}
+	a.reported += total
+	a.report(0, int(total))
+	return true
 }
 
-// addPublishedEvent increments the 'send' counter in the current gapInfo
-// element in the tail of the list. If events have been dropped, we append a
-// new empty gapInfo element.
-func (a *trackingACKer) addPublishedEvent() {
-	a.lst.Lock()
-
-	current := a.lst.tail
-	current.Lock()
-	if current.dropped > 0 {
-		tmp := &gapInfo{}
-		tmp.Lock()
-
-		a.lst.tail.next = tmp
-		a.lst.tail = tmp
-		current.Unlock()
-		current = tmp
+func (a *trackingACKer) ACKEvents(n int) {
+	if a.acked.Add(uint64(n)) > a.published.Load() {
+		panic("too many events acked")
 	}
-	a.lst.Unlock()
 
-	current.send++
-	current.Unlock()
+	a.ackEvents(n)
+	a.deliver()
 }
 
-// addDropEvent increments the 'dropped' counter in the gapInfo element in the
-// tail of the list.  The callback will be run with total=1 and acked=0 if the
-// acker state is empty and no events have been send yet.
-func (a *trackingACKer) addDropEvent() {
-	a.lst.Lock()
+func (a *trackingACKer) ackEvents(n int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 
-	current := a.lst.tail
-	current.Lock()
+	// Concurrent ACKs are reported up to the latest ACKed event.
+	acked := a.acked.Load()
 
-	if current.send == 0 && current.next == nil {
-		// send can only be 0 if no no events/gaps present yet
-		if a.lst.head != a.lst.tail {
-			panic("gap list expected to be empty")
-		}
+	if g := a.gaps.advance(acked); g != nil {
+		a.current = g
+	}
+	reached := acked
+	if a.current != nil {
+		reached += a.current.dropped.Load()
+	}
 
-		a.fn(0, 1)
-		a.lst.Unlock()
-		current.Unlock()
+	// All events can already have been reported by a concurrent ACK, or by
+	// AddEvent for the dropped events.
+	var total uint64
+	if reached > a.reported {
+		total = reached - a.reported
+		a.reported = reached
+	}
+	a.report(n, int(total))
+}
 
-		a.events.Dec()
+// report queues a report, mu must be held.
+func (a *trackingACKer) report(acked, total int) {
+	if a.data == nil {
+		a.pending = append(a.pending, ackReport{acked: acked, total: total})
 		return
 	}
 
-	a.lst.Unlock()
-	current.dropped++
-	current.Unlock()
+	if total == 0 {
+		return
+	}
+	a.pending = append(a.pending, ackReport{acked: acked, data: a.data.pop(total)})
 }
 
-func (a *trackingACKer) ACKEvents(n int) {
-	var (
-		total    = 0
-		acked    = n
-		emptyLst bool
-	)
-
-	for n > 0 {
-		if emptyLst {
-			panic("too many events acked")
-		}
-
-		a.lst.Lock()
-		current := a.lst.head
-		current.Lock()
-
-		// advance list if we detect that the current head will be completely consumed
-		// by this ACK event.
-		if n >= current.send {
-			next := current.next
-			emptyLst = next == nil
-			if !emptyLst {
-				// advance list all event in current entry have been send and list as
-				// more then 1 gapInfo entry. If only 1 entry is present, list item will be
-				// reset and reused
-				a.lst.head = next
+// deliver calls the callbacks with the queued reports, unless another
+// goroutine is already delivering them. A callback publishing events can
+// queue more reports, that are delivered by the same loop.
+func (a *trackingACKer) deliver() {
+	a.mu.Lock()
+	if a.delivering {
+		a.mu.Unlock()
+		return
+	}
+	a.delivering = true
+	for len(a.pending) > 0 {
+		reports := a.pending
+		a.pending = nil
+		a.mu.Unlock()
+
+		for _, r := range reports {
+			if a.data == nil {
+				a.fn(r.acked, r.total)
+			} else {
+				a.dataFn(r.acked, r.data)
 			}
 		}
-		// hand over lock list-entry, so ACK handler and producer can operate
-		// on potentially different list ends
-		a.lst.Unlock()
-
-		if n < current.send {
-			current.send -= n
-			total += n
-			n = 0
-		} else {
-			total += current.send + current.dropped
-			n -= current.send
-			current.dropped = 0
-			current.send = 0
-		}
-		current.Unlock()
-	}
 
-	a.events.Sub(uint32(total))
-	a.fn(acked, total)
+		a.mu.Lock()
+	}
+	a.delivering = false
+	a.mu.Unlock()
 }
 
 func (a *trackingACKer) Close() {}
@@ -224,38 +267,7 @@ func (a *trackingACKer) Close() {}
 // - the drop sequence for events 2 and 3 is inbetween the number of forwarded and ACKed events
 // - events 5-6 have been dropped as well, but event 7 is not ACKed yet
 func EventPrivateReporter(fn func(acked int, data []interface{})) beat.ACKer {
-	a := &eventDataACKer{fn: fn}
-	a.ACKer = TrackingCounter(a.onACK)
-	return a
-}
-
-type eventDataACKer struct {
-	beat.ACKer
-	mu   sync.Mutex
-	data []interface{}
-	fn   func(acked int, data []interface{})
-}
-
-func (a *eventDataACKer) AddEvent(event beat.Event, published bool) {
-	a.mu.Lock()
-	a.data = append(a.data, event.Private)
-	a.mu.Unlock()
-	a.ACKer.AddEvent(event, published)
-}
-
-func (a *eventDataACKer) onACK(acked, total int) {
-	if total == 0 {
-		return
-	}
-
-	a.mu.Lock()
-	data := a.data[:total]
-	a.data = a.data[total:]
-	a.mu.Unlock()
-
-	if len(data) > 0 {
-		a.fn(acked, data)
-	}
+	return &trackingACKer{dataFn: fn, data: newDataQueue(), gaps: newGapQueue()}
 }
 
 // LastEventPrivateReporter reports only the 'latest' published and acked
@@ -18,7 +18,9 @@
 package acker
 
 import (
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -115,6 +117,111 @@ func TestTracking(t *testing.T) {
 	})
 }
 
+func TestTrackingConcurrent(t *testing.T) {
+	const events = 100000
+
+	var mu sync.Mutex
+	var acked int
+	var data []interface{}
+	acker := EventPrivateReporter(func(a int, d []interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		acked += a
+		data = append(data, d...)
+	})
+
+	queue := make(chan int, 1024)
+	published := 0
+	go func() {
+		defer close(queue)
+		for i := 0; i < events; i++ {
+			// Include runs of dropped events longer than the chunks of the queues.
+			dropped := i%3 == 0 || (i > events/2 && i < events/2+2*chunkSize)
+			acker.AddEvent(beat.Event{Private: i}, !dropped)
+			if !dropped {
+				published++
+				queue <- 1
+			}
+		}
+	}()
+
+	for n := range queue {
+		acker.ACKEvents(n)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, published, acked)
+	require.Len(t, data, events)
+	for i, d := range data {
+		require.Equal(t, i, d)
+	}
+}
+
+func TestTrackingPublishFromCallback(t *testing.T) {
+	// run fails the test if fn does not return, the callbacks publishing
+	// events must not deadlock the ACKer.
+	run := func(t *testing.T, fn func()) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			fn()
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("publishing from the ACK callback deadlocks")
+		}
+	}
+
+	t.Run("published events are acked", func(t *testing.T) {
+		var acker beat.ACKer
+		var reports [][2]int
+		acker = TrackingCounter(func(acked, total int) {
+			if len(reports) == 0 {
+				acker.AddEvent(beat.Event{}, false)
+				acker.AddEvent(beat.Event{}, true)
+			}
+			reports = append(reports, [2]int{acked, total})
+		})
+
+		run(t, func() {
+			acker.AddEvent(beat.Event{}, true)
+			acker.ACKEvents(1)
+			acker.ACKEvents(1)
+		})
+		require.Equal(t, [][2]int{{1, 1}, {0, 1}, {1, 1}}, reports)
+	})
+
+	t.Run("dropped events are reported", func(t *testing.T) {
+		var acker beat.ACKer
+		var data []interface{}
+		acker = EventPrivateReporter(func(_ int, d []interface{}) {
+			if len(data) == 0 {
+				acker.AddEvent(beat.Event{Private: 2}, false)
+			}
+			data = append(data, d...)
+		})
+
+		run(t, func() {
+			acker.AddEvent(beat.Event{Private: 1}, false)
+		})
+		require.Equal(t, []interface{}{1, 2}, data)
+	})
+}
+
+func BenchmarkTracking(b *testing.B) {
+	acker := TrackingCounter(func(_, _ int) {})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		acker.AddEvent(beat.Event{}, i%4 != 0)
+		if i%256 == 255 {
+			acker.ACKEvents(192)
+		}
+	}
+}
+
 func TestEventPrivateReporter(t *testing.T) {
 	t.Run("dropped event is acked immediately if empty", func(t *testing.T) {
 		var acked int
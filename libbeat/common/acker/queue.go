@@ -0,0 +1,155 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package acker
+
+import (
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+)
+
+// chunkSize is the number of entries in each chunk of the queues.
+const chunkSize = 256
+
+// The queues in this file have a single producer and a single consumer, that
+// don't need to share a lock. Entries are added to fixed size chunks, and are
+// made visible to the consumer by the atomic update of the number of entries
+// in the chunk. The next chunk is linked before the last entry of a chunk is
+// published, so the consumer can follow it once it has read all entries.
+
+// gap records the events dropped after a number of published events.
+type gap struct {
+	// published is the number of events published before the gap.
+	published uint64
+
+	// dropped is the total number of events dropped up to the end of the gap.
+	// It's updated while the gap is the last one.
+	dropped atomic.Uint64
+}
+
+type gapChunk struct {
+	gaps [chunkSize]gap
+	n    atomic.Uint32
+	next *gapChunk
+}
+
+// gapQueue is a queue of the gaps of a trackingACKer.
+type gapQueue struct {
+	// producer side
+	tail *gapChunk
+	last *gap
+
+	// consumer side
+	head    *gapChunk
+	headIdx int
+}
+
+func newGapQueue() gapQueue {
+	c := &gapChunk{}
+	return gapQueue{tail: c, head: c}
+}
+
+// drop records an event dropped after the given number of published events,
+// with total the total number of dropped events.
+func (q *gapQueue) drop(published, total uint64) {
+	if q.last != nil && q.last.published == published {
+		q.last.dropped.Store(total)
+		return
+	}
+
+	c := q.tail
+	n := c.n.Load()
+	g := &c.gaps[n]
+	g.published = published
+	g.dropped.Store(total)
+	if n+1 == chunkSize {
+		c.next = &gapChunk{}
+		q.tail = c.next
+	}
+	c.n.Store(n + 1)
+	q.last = g
+}
+
+// advance returns the last gap found after at most published events, in the
+// gaps following the gap returned by the previous call. It returns nil if
+// there is none.
+func (q *gapQueue) advance(published uint64) *gap {
+	var last *gap
+	for {
+		if q.headIdx == chunkSize {
+			q.head, q.headIdx = q.head.next, 0
+		}
+		if q.headIdx >= int(q.head.n.Load()) {
+			return last
+		}
+		g := &q.head.gaps[q.headIdx]
+		if g.published > published {
+			return last
+		}
+		last = g
+		q.headIdx++
+	}
+}
+
+type dataChunk struct {
+	data [chunkSize]interface{}
+	n    atomic.Uint32
+	next *dataChunk
+}
+
+// dataQueue is a queue of the private data of the events.
+type dataQueue struct {
+	// producer side
+	tail *dataChunk
+
+	// consumer side
+	head    *dataChunk
+	headIdx int
+}
+
+func newDataQueue() *dataQueue {
+	c := &dataChunk{}
+	return &dataQueue{tail: c, head: c}
+}
+
+func (q *dataQueue) push(v interface{}) {
+	c := q.tail
+	n := c.n.Load()
+	c.data[n] = v
+	if n+1 == chunkSize {
+		c.next = &dataChunk{}
+		q.tail = c.next
+	}
+	c.n.Store(n + 1)
+}
+
+// pop removes the next n entries from the queue. The queue must have at least
+// n entries.
+func (q *dataQueue) pop(n int) []interface{} {
+	data := make([]interface{}, n)
+	for i := range data {
+		if q.headIdx == chunkSize {
+			q.head, q.headIdx = q.head.next, 0
+		}
+		if q.headIdx >= int(q.head.n.Load()) {
+			panic("not enough events in the queue")
+		}
+		data[i] = q.head.data[q.headIdx]
+		q.head.data[q.headIdx] = nil
+		q.headIdx++
+	}
+	return data
+}
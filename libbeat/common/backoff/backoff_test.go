@@ -43,6 +43,9 @@ func testCloseChannel(t *testing.T) {
 		"EqualJitterBackoff": func(done <-chan struct{}) Backoff {
 			return NewEqualJitterBackoff(done, init, max)
 		},
+		"JitterBackoff": func(done <-chan struct{}) Backoff {
+			return NewJitterBackoff(done, init, max, 0.5)
+		},
 	}
 
 	for name, f := range tests {
@@ -66,6 +69,9 @@ func testUnblockAfterInit(t *testing.T) {
 		"EqualJitterBackoff": func(done <-chan struct{}) Backoff {
 			return NewEqualJitterBackoff(done, init, max)
 		},
+		"JitterBackoff": func(done <-chan struct{}) Backoff {
+			return NewJitterBackoff(done, init, max, 0.5)
+		},
 	}
 
 	for name, f := range tests {
@@ -81,3 +87,22 @@ func testUnblockAfterInit(t *testing.T) {
 		})
 	}
 }
+
+func TestJitterBackoffBounds(t *testing.T) {
+	init := 2 * time.Millisecond
+	max := 20 * time.Millisecond
+
+	c := make(chan struct{})
+	defer close(c)
+
+	b := NewJitterBackoff(c, init, max, 0.5).(*JitterBackoff)
+	for i := 0; i < 6; i++ {
+		startedAt := time.Now()
+		assert.True(t, b.Wait())
+		assert.True(t, time.Since(startedAt) >= init)
+		assert.True(t, b.duration <= time.Duration(float64(max)/1.5))
+	}
+
+	b.Reset()
+	assert.Equal(t, init, b.duration)
+}
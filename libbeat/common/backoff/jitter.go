@@ -0,0 +1,79 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+// JitterBackoff implements an exponential backoff with a configurable jitter.
+// Each wait lasts the current backoff duration plus a random part of up to
+// jitter times that duration, so the waits of clients failing at the same time
+// are spread. The waits are never shorter than init nor longer than max.
+type JitterBackoff struct {
+	duration time.Duration
+	done     <-chan struct{}
+
+	init   time.Duration
+	max    time.Duration
+	jitter float64
+}
+
+// NewJitterBackoff returns a new exponential backoff with jitter. jitter is
+// the maximum fraction of the backoff duration added at random to each wait,
+// 0 disables the jitter.
+func NewJitterBackoff(done <-chan struct{}, init, max time.Duration, jitter float64) Backoff {
+	return &JitterBackoff{
+		duration: init,
+		done:     done,
+		init:     init,
+		max:      max,
+		jitter:   jitter,
+	}
+}
+
+// Reset resets the duration of the backoff.
+func (b *JitterBackoff) Reset() {
+	b.duration = b.init
+}
+
+// Wait block until either the timer is completed or channel is done.
+func (b *JitterBackoff) Wait() bool {
+	backoff := b.duration + time.Duration(rand.Float64()*b.jitter*float64(b.duration))
+	if backoff > b.max {
+		backoff = b.max
+	}
+
+	// Increase duration for next wait, keeping room for the jitter below max.
+	b.duration *= 2
+	limit := time.Duration(float64(b.max) / (1 + b.jitter))
+	if limit < b.init {
+		limit = b.init
+	}
+	if b.duration > limit {
+		b.duration = limit
+	}
+
+	select {
+	case <-b.done:
+		return false
+	case <-time.After(backoff):
+		return true
+	}
+}
@@ -214,6 +214,22 @@ func (c *Cache) CleanUp() int {
 	return count
 }
 
+// Clear removes all elements from the cache, expired or not. If a
+// RemovalListener is registered it will be invoked for each element removed.
+// The RemovalListener is invoked on the caller's goroutine.
+func (c *Cache) Clear() int {
+	c.Lock()
+	defer c.Unlock()
+	count := len(c.elements)
+	for k, v := range c.elements {
+		delete(c.elements, k)
+		if c.listener != nil {
+			c.listener(k, v.value)
+		}
+	}
+	return count
+}
+
 // Entries returns a shallow copy of the non-expired elements in the cache.
 func (c *Cache) Entries() map[Key]Value {
 	c.RLock()
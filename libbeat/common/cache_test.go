@@ -77,6 +77,18 @@ func TestExpireWithoutRemovalListener(t *testing.T) {
 	assert.Equal(t, 2, c.CleanUp())
 }
 
+// Test that Clear removes the elements that have not expired too.
+func TestClear(t *testing.T) {
+	callbackKey = nil
+	callbackValue = nil
+	c := newCache(Timeout, true, InitalSize, removalListener, fakeClock)
+	c.Put(alphaKey, alphaValue)
+	assert.Equal(t, 1, c.Clear())
+	assert.Equal(t, 0, c.Size())
+	assert.Equal(t, alphaKey, callbackKey)
+	assert.Equal(t, alphaValue, callbackValue)
+}
+
 func TestPutIfAbsent(t *testing.T) {
 	c := newCache(Timeout, true, InitalSize, nil, fakeClock)
 	oldValue := c.PutIfAbsent(alphaKey, alphaValue)
@@ -0,0 +1,85 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// OutputFormat is the format used by the commands to write their results. It
+// can be used as a command line flag.
+type OutputFormat string
+
+const (
+	// OutputText writes the results in a human readable form.
+	OutputText OutputFormat = "text"
+
+	// OutputJSON writes the results as a JSON document.
+	OutputJSON OutputFormat = "json"
+)
+
+const outputFlag = "output"
+
+func (f *OutputFormat) String() string {
+	return string(*f)
+}
+
+// Set sets the format from its name, only text and json are accepted.
+func (f *OutputFormat) Set(v string) error {
+	switch format := OutputFormat(v); format {
+	case OutputText, OutputJSON:
+		*f = format
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q, must be text or json", v)
+	}
+}
+
+// Type returns the type of the flag for the usage message.
+func (f *OutputFormat) Type() string {
+	return "format"
+}
+
+// AddOutputFlag adds the --output flag to the persistent flags of the command,
+// so it is available in all its subcommands.
+func AddOutputFlag(cmd *cobra.Command) {
+	format := OutputText
+	cmd.PersistentFlags().Var(&format, outputFlag, "Format of the command results, text or json")
+}
+
+// GetOutputFormat returns the output format selected for the command, text is
+// returned if the command doesn't have the --output flag.
+func GetOutputFormat(cmd *cobra.Command) OutputFormat {
+	if f := cmd.Flags().Lookup(outputFlag); f != nil {
+		if format, ok := f.Value.(*OutputFormat); ok {
+			return *format
+		}
+	}
+	return OutputText
+}
+
+// WriteJSON writes v to w as an indented JSON document.
+func WriteJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,52 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputFormat(t *testing.T) {
+	var format OutputFormat
+	sub := &cobra.Command{
+		Use: "sub",
+		Run: func(cmd *cobra.Command, args []string) { format = GetOutputFormat(cmd) },
+	}
+	root := &cobra.Command{Use: "root"}
+	root.AddCommand(sub)
+	AddOutputFlag(root)
+
+	root.SetArgs([]string{"sub"})
+	require.NoError(t, root.Execute())
+	assert.Equal(t, OutputText, format)
+
+	root.SetArgs([]string{"sub", "--output", "json"})
+	require.NoError(t, root.Execute())
+	assert.Equal(t, OutputJSON, format)
+
+	root.SetArgs([]string{"sub", "--output", "yaml"})
+	assert.Error(t, root.Execute())
+}
+
+func TestGetOutputFormatWithoutFlag(t *testing.T) {
+	assert.Equal(t, OutputText, GetOutputFormat(&cobra.Command{Use: "cmd"}))
+}
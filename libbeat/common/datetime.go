@@ -21,7 +21,9 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"hash"
+	"strings"
 	"time"
 )
 
@@ -29,6 +31,52 @@ import (
 // The timezone must always be UTC.
 const TsLayout = "2006-01-02T15:04:05.000Z"
 
+// tsLayoutNanos parses timestamps with up to nanosecond precision.
+const tsLayoutNanos = "2006-01-02T15:04:05.999999999Z"
+
+// TimestampPrecision is the precision of the fraction of second of encoded
+// timestamps.
+type TimestampPrecision uint8
+
+const (
+	// TimestampMillisecond encodes timestamps with 3 fractional digits.
+	TimestampMillisecond TimestampPrecision = iota
+	// TimestampMicrosecond encodes timestamps with 6 fractional digits.
+	TimestampMicrosecond
+	// TimestampNanosecond encodes timestamps with 9 fractional digits.
+	TimestampNanosecond
+)
+
+var timestampPrecisions = map[string]TimestampPrecision{
+	"millisecond": TimestampMillisecond,
+	"microsecond": TimestampMicrosecond,
+	"nanosecond":  TimestampNanosecond,
+}
+
+// Unpack parses the name of the precision.
+func (p *TimestampPrecision) Unpack(s string) error {
+	precision, ok := timestampPrecisions[strings.ToLower(s)]
+	if !ok {
+		return fmt.Errorf("invalid timestamp precision '%v', expected millisecond, microsecond or nanosecond", s)
+	}
+	*p = precision
+	return nil
+}
+
+func (p TimestampPrecision) String() string {
+	for name, precision := range timestampPrecisions {
+		if precision == p {
+			return name
+		}
+	}
+	return fmt.Sprintf("TimestampPrecision(%d)", uint8(p))
+}
+
+// Digits returns the number of fractional digits of the precision.
+func (p TimestampPrecision) Digits() int {
+	return 3 * (int(p) + 1)
+}
+
 // Time is an abstraction for the time.Time type
 type Time time.Time
 
@@ -54,9 +102,15 @@ func (t Time) Hash32(h hash.Hash32) error {
 	return err
 }
 
-// ParseTime parses a time in the TsLayout format.
+// ParseTime parses a time in the TsLayout format. Times with up to nanosecond
+// precision are accepted too.
 func ParseTime(timespec string) (Time, error) {
 	t, err := time.Parse(TsLayout, timespec)
+	if err != nil {
+		if tn, errNanos := time.Parse(tsLayoutNanos, timespec); errNanos == nil {
+			return Time(tn), nil
+		}
+	}
 	return Time(t), err
 }
 
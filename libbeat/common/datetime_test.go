@@ -21,6 +21,7 @@ package common
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 
@@ -46,6 +47,14 @@ func TestParseTime(t *testing.T) {
 			Input:  "2015-02-28T11:19:05.112Z",
 			Output: time.Date(2015, time.February, 28, 11, 19, 05, 112*1e6, time.UTC),
 		},
+		{
+			Input:  "2015-02-28T11:19:05.112345Z",
+			Output: time.Date(2015, time.February, 28, 11, 19, 05, 112345*1e3, time.UTC),
+		},
+		{
+			Input:  "2015-02-28T11:19:05.112345678Z",
+			Output: time.Date(2015, time.February, 28, 11, 19, 05, 112345678, time.UTC),
+		},
 	}
 
 	for _, test := range tests {
@@ -103,3 +112,28 @@ func TestTimeMarshal(t *testing.T) {
 		assert.Equal(t, test.Output, string(result))
 	}
 }
+
+func TestTimestampPrecision(t *testing.T) {
+	tests := []struct {
+		name      string
+		precision TimestampPrecision
+		digits    int
+	}{
+		{"millisecond", TimestampMillisecond, 3},
+		{"microsecond", TimestampMicrosecond, 6},
+		{"nanosecond", TimestampNanosecond, 9},
+		{"Nanosecond", TimestampNanosecond, 9},
+	}
+
+	for _, test := range tests {
+		var p TimestampPrecision
+		if assert.NoError(t, p.Unpack(test.name)) {
+			assert.Equal(t, test.precision, p)
+			assert.Equal(t, test.digits, p.Digits())
+			assert.Equal(t, strings.ToLower(test.name), p.String())
+		}
+	}
+
+	var p TimestampPrecision
+	assert.Error(t, p.Unpack("second"))
+}
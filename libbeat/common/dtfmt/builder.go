@@ -109,9 +109,11 @@ func (b *builder) millisOfSecond(digits int) {
 		b.appendExtDecimal(ftMillisOfSecond, 10, 2, 2)
 	case 3:
 		b.appendExtDecimal(ftMillisOfSecond, 0, 3, 3)
+	case 4, 5, 6, 7, 8, 9:
+		b.appendFraction(digits)
 	default:
-		b.appendExtDecimal(ftMillisOfSecond, 0, 3, 3)
-		b.appendZeros(digits - 3)
+		b.appendFraction(9)
+		b.appendZeros(digits - 9)
 	}
 }
 
@@ -249,6 +251,10 @@ func (b *builder) appendSigned(ft fieldType, minDigits, maxDigits int) {
 	b.appendDecimalValue(ft, minDigits, maxDigits, true)
 }
 
+func (b *builder) appendFraction(digits int) {
+	b.add(fractionOfSecond{digits})
+}
+
 func (b *builder) appendZeros(count int) {
 	b.add(paddingZeros{count})
 }
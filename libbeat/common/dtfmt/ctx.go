@@ -32,6 +32,7 @@ type ctx struct {
 
 	hour, min, sec int
 	millis         int
+	nanos          int
 
 	tzOffset int
 
@@ -44,6 +45,7 @@ type ctxConfig struct {
 	weekday  bool
 	yearday  bool
 	millis   bool
+	nanos    bool
 	iso      bool
 	tzOffset bool
 }
@@ -63,6 +65,10 @@ func (c *ctx) initTime(config *ctxConfig, t time.Time) {
 		c.millis = t.Nanosecond() / 1000000
 	}
 
+	if config.nanos {
+		c.nanos = t.Nanosecond()
+	}
+
 	if config.yearday {
 		c.yearday = t.YearDay()
 	}
@@ -88,6 +94,10 @@ func (c *ctxConfig) enableMillis() {
 	c.millis = true
 }
 
+func (c *ctxConfig) enableNanos() {
+	c.nanos = true
+}
+
 func (c *ctxConfig) enableWeekday() {
 	c.weekday = true
 }
@@ -72,7 +72,9 @@
 //                  text type. Otherwise number type
 //                  formatting rules are applied.
 //
-//   millis         Not yet supported
+//   millis         Number of digits of the fraction of second. Up to 9
+//                  digits are printed, giving nanosecond precision. Longer
+//                  patterns are padded with zeros.
 //
 //   zone           Not yet supported
 //
@@ -86,6 +86,9 @@ func TestFormat(t *testing.T) {
 		{mkTime(1, 2, 3, 123), "SS", "12"},
 		{mkTime(1, 2, 3, 123), "SSS", "123"},
 		{mkTime(1, 2, 3, 123), "SSSS", "1230"},
+		{mkTime(1, 2, 3, 123).Add(456789), "SSSSSS", "123456"},
+		{mkTime(1, 2, 3, 123).Add(456789), "SSSSSSSSS", "123456789"},
+		{mkTime(1, 2, 3, 123).Add(456789), "SSSSSSSSSS", "1234567890"},
 
 		// literals
 		{time.Now(), "--=++,_!/?\\[]{}@#$%^&*()", "--=++,_!/?\\[]{}@#$%^&*()"},
@@ -63,6 +63,12 @@ type paddingZeros struct {
 	count int
 }
 
+// fractionOfSecond prints the fraction of second with up to 9 digits, giving
+// nanosecond precision.
+type fractionOfSecond struct {
+	digits int
+}
+
 func (runeLiteral) requires(*ctxConfig) error { return nil }
 func (runeLiteral) estimateSize() int         { return 1 }
 
@@ -213,3 +219,12 @@ func (p paddingZeros) estimateSize() int           { return p.count }
 func (p paddingZeros) compile() (prog, error) {
 	return makeProg(opZeros, byte(p.count))
 }
+
+func (f fractionOfSecond) requires(c *ctxConfig) error {
+	c.enableNanos()
+	return nil
+}
+func (f fractionOfSecond) estimateSize() int { return f.digits }
+func (f fractionOfSecond) compile() (prog, error) {
+	return makeProg(opFraction, byte(f.digits))
+}
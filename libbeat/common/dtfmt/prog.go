@@ -38,6 +38,7 @@ const (
 	opNumPadded         // [op, ft, digits]
 	opExtNumPadded      // [op, ft, div, digits]
 	opZeros             // [op, count]
+	opFraction          // [op, digits]
 	opTwoDigit          // [op, ft]
 	opTextShort         // [op, ft]
 	opTextLong          // [op, ft]
@@ -97,6 +98,14 @@ func (p prog) eval(bytes []byte, ctx *ctx, t time.Time) ([]byte, error) {
 				return bytes, err
 			}
 			bytes = appendPadded(bytes, v/div, digits)
+		case opFraction:
+			digits := int(p.p[i])
+			i++
+			v := ctx.nanos
+			for x := digits; x < 9; x++ {
+				v /= 10
+			}
+			bytes = appendPadded(bytes, v, digits)
 		case opZeros:
 			digits := int(p.p[i])
 			i++
@@ -68,6 +68,72 @@ func (e *GenericEventConverter) Convert(m MapStr) MapStr {
 	return event
 }
 
+// CheckGenericEvent returns an error if m holds values that need to be
+// normalized before being published, like structs, pointers or values of
+// custom types. Null values in maps are only accepted if keepNull is set.
+func CheckGenericEvent(m MapStr, keepNull bool) error {
+	return checkGenericMap(m, keepNull)
+}
+
+func checkGenericMap(m map[string]interface{}, keepNull bool, keys ...string) error {
+	for key, value := range m {
+		if value == nil {
+			if !keepNull {
+				return fmt.Errorf("key=%v: null value", joinKeys(append(keys, key)...))
+			}
+			continue
+		}
+		if err := checkGenericValue(value, keepNull, append(keys, key)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkGenericValue(value interface{}, keepNull bool, keys ...string) error {
+	switch v := value.(type) {
+	case nil:
+	case string, []string:
+	case bool, []bool:
+	case int, int8, int16, int32, int64:
+	case []int, []int8, []int16, []int32, []int64:
+	case uint, uint8, uint16, uint32:
+	case []uint, []uint8, []uint16, []uint32:
+	case uint64:
+		if v >= (1 << 63) {
+			return fmt.Errorf("key=%v: uint64 value %v out of range", joinKeys(keys...), v)
+		}
+	case float32, float64, Float:
+	case []float32, []float64:
+	case time.Time, Time, []Time:
+	case MapStr:
+		return checkGenericMap(v, keepNull, keys...)
+	case map[string]interface{}:
+		return checkGenericMap(v, keepNull, keys...)
+	case []MapStr:
+		for i, m := range v {
+			if err := checkGenericMap(m, keepNull, append(keys, strconv.Itoa(i))...); err != nil {
+				return err
+			}
+		}
+	case []map[string]interface{}:
+		for i, m := range v {
+			if err := checkGenericMap(m, keepNull, append(keys, strconv.Itoa(i))...); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, elem := range v {
+			if err := checkGenericValue(elem, keepNull, append(keys, strconv.Itoa(i))...); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("key=%v: type %T needs to be normalized", joinKeys(keys...), value)
+	}
+	return nil
+}
+
 // normalizeMap normalizes each element contained in the given map. If an error
 // occurs during normalization, processing of m will continue, and all errors
 // are returned at the end.
@@ -349,6 +349,37 @@ func TestNormalizeMapError(t *testing.T) {
 	}
 }
 
+func TestCheckGenericEvent(t *testing.T) {
+	var decoded MapStr
+	err := json.Unmarshal([]byte(`{"a": {"b": [1.5, "c", {"d": true}]}, "e": null}`), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, CheckGenericEvent(decoded, true))
+	assert.Error(t, CheckGenericEvent(decoded, false))
+
+	valid := MapStr{
+		"str":   "x",
+		"ints":  []int{1, 2},
+		"time":  time.Now(),
+		"float": 1.5,
+		"maps":  []MapStr{{"a": uint64(1)}},
+		"map":   map[string]interface{}{"b": []string{"c"}},
+	}
+	assert.NoError(t, CheckGenericEvent(valid, false))
+
+	invalid := []MapStr{
+		{"struct": struct{ A int }{1}},
+		{"pointer": new(string)},
+		{"uint64": uint64(1 << 63)},
+		{"list": []interface{}{"a", struct{}{}}},
+		{"nested": MapStr{"uuid": uuid.Must(uuid.NewV4())}},
+	}
+	for i, in := range invalid {
+		assert.Error(t, CheckGenericEvent(in, false), "Test case %v", i)
+	}
+}
+
 func TestJoinKeys(t *testing.T) {
 	assert.Equal(t, "", joinKeys(""))
 	assert.Equal(t, "co", joinKeys("co"))
@@ -0,0 +1,363 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package uring
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Definitions of the io_uring ABI, see include/uapi/linux/io_uring.h.
+const (
+	opReadv = 1
+
+	enterGetEvents = 1
+
+	offSQRing = 0
+	offCQRing = 0x8000000
+	offSQEs   = 0x10000000
+)
+
+type params struct {
+	sqEntries    uint32
+	cqEntries    uint32
+	flags        uint32
+	sqThreadCPU  uint32
+	sqThreadIdle uint32
+	features     uint32
+	wqFD         uint32
+	resv         [3]uint32
+	sqOff        sqringOffsets
+	cqOff        cqringOffsets
+}
+
+type sqringOffsets struct {
+	head        uint32
+	tail        uint32
+	ringMask    uint32
+	ringEntries uint32
+	flags       uint32
+	dropped     uint32
+	array       uint32
+	resv1       uint32
+	resv2       uint64
+}
+
+type cqringOffsets struct {
+	head        uint32
+	tail        uint32
+	ringMask    uint32
+	ringEntries uint32
+	overflow    uint32
+	cqes        uint32
+	flags       uint32
+	resv1       uint32
+	resv2       uint64
+}
+
+// sqe is a submission queue entry.
+type sqe struct {
+	opcode      uint8
+	flags       uint8
+	ioprio      uint16
+	fd          int32
+	off         uint64
+	addr        uint64
+	len         uint32
+	rwFlags     uint32
+	userData    uint64
+	bufIndex    uint16
+	personality uint16
+	spliceFDIn  int32
+	pad         [2]uint64
+}
+
+// cqe is a completion queue entry.
+type cqe struct {
+	userData uint64
+	res      int32
+	flags    uint32
+}
+
+// Ring is an io_uring instance. Its methods can be used concurrently.
+type Ring struct {
+	fd int
+
+	// memory shared with the kernel
+	sqRing, cqRing, sqeMem []byte
+
+	sqHead, sqTail *uint32
+	sqMask         uint32
+	sqArray        []uint32
+	sqes           []sqe
+
+	cqHead, cqTail *uint32
+	cqMask         uint32
+	cqes           []cqe
+
+	// entries is the maximum number of reads in flight.
+	entries int
+
+	requests  chan *request
+	done      chan struct{}
+	closeOnce sync.Once
+
+	// stopped is closed when the ring does not accept reads any more, err
+	// is the reason.
+	stopped chan struct{}
+	err     error
+}
+
+// request is a read waiting for its completion. Its fields are kept alive
+// while the kernel may access them.
+type request struct {
+	buf []byte
+	iov unix.Iovec
+	fd  int
+	off int64
+
+	res       int32
+	completed chan struct{}
+}
+
+var requestPool = sync.Pool{
+	New: func() interface{} {
+		return &request{completed: make(chan struct{}, 1)}
+	},
+}
+
+// New creates a ring able to have up to entries reads in flight. It returns
+// ErrNotSupported if the kernel does not support io_uring.
+func New(entries uint32) (*Ring, error) {
+	if entries == 0 || entries > MaxEntries {
+		return nil, fmt.Errorf("invalid number of entries %v, must be between 1 and %v", entries, MaxEntries)
+	}
+
+	var p params
+	fd, _, errno := unix.Syscall(unix.SYS_IO_URING_SETUP, uintptr(entries), uintptr(unsafe.Pointer(&p)), 0)
+	if errno != 0 {
+		if errno == unix.ENOSYS || errno == unix.EPERM {
+			return nil, ErrNotSupported
+		}
+		return nil, fmt.Errorf("io_uring_setup failed: %v", errno)
+	}
+
+	r := &Ring{
+		fd:       int(fd),
+		entries:  int(p.sqEntries),
+		requests: make(chan *request),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if err := r.mmap(&p); err != nil {
+		r.unmap()
+		unix.Close(r.fd)
+		return nil, err
+	}
+
+	go func() {
+		defer close(r.stopped)
+		r.err = r.run()
+	}()
+	return r, nil
+}
+
+func (r *Ring) mmap(p *params) error {
+	var err error
+	const prot, flags = unix.PROT_READ | unix.PROT_WRITE, unix.MAP_SHARED | unix.MAP_POPULATE
+
+	sqSize := int(p.sqOff.array) + int(p.sqEntries)*4
+	if r.sqRing, err = unix.Mmap(r.fd, offSQRing, sqSize, prot, flags); err != nil {
+		return fmt.Errorf("failed to map the submission queue: %v", err)
+	}
+	cqSize := int(p.cqOff.cqes) + int(p.cqEntries)*int(unsafe.Sizeof(cqe{}))
+	if r.cqRing, err = unix.Mmap(r.fd, offCQRing, cqSize, prot, flags); err != nil {
+		return fmt.Errorf("failed to map the completion queue: %v", err)
+	}
+	sqesSize := int(p.sqEntries) * int(unsafe.Sizeof(sqe{}))
+	if r.sqeMem, err = unix.Mmap(r.fd, offSQEs, sqesSize, prot, flags); err != nil {
+		return fmt.Errorf("failed to map the submission queue entries: %v", err)
+	}
+
+	r.sqHead = (*uint32)(unsafe.Pointer(&r.sqRing[p.sqOff.head]))
+	r.sqTail = (*uint32)(unsafe.Pointer(&r.sqRing[p.sqOff.tail]))
+	r.sqMask = *(*uint32)(unsafe.Pointer(&r.sqRing[p.sqOff.ringMask]))
+	r.sqArray = (*[MaxEntries]uint32)(unsafe.Pointer(&r.sqRing[p.sqOff.array]))[:p.sqEntries:p.sqEntries]
+	r.sqes = (*[MaxEntries]sqe)(unsafe.Pointer(&r.sqeMem[0]))[:p.sqEntries:p.sqEntries]
+
+	r.cqHead = (*uint32)(unsafe.Pointer(&r.cqRing[p.cqOff.head]))
+	r.cqTail = (*uint32)(unsafe.Pointer(&r.cqRing[p.cqOff.tail]))
+	r.cqMask = *(*uint32)(unsafe.Pointer(&r.cqRing[p.cqOff.ringMask]))
+	r.cqes = (*[2 * MaxEntries]cqe)(unsafe.Pointer(&r.cqRing[p.cqOff.cqes]))[:p.cqEntries:p.cqEntries]
+	return nil
+}
+
+func (r *Ring) unmap() {
+	for _, m := range [][]byte{r.sqRing, r.cqRing, r.sqeMem} {
+		if m != nil {
+			unix.Munmap(m)
+		}
+	}
+}
+
+// ReadAt reads up to len(buf) bytes from the file descriptor fd at offset,
+// without changing the offset of the file. Like the read system call it can
+// read less bytes than requested, it returns io.EOF if no byte has been read
+// because offset is at the end of the file.
+func (r *Ring) ReadAt(fd int, buf []byte, offset int64) (int, error) {
+	if len(buf) == 0 {
+		return 0, nil
+	}
+
+	req := requestPool.Get().(*request)
+	req.buf, req.fd, req.off = buf, fd, offset
+	req.iov.Base = &buf[0]
+	req.iov.SetLen(len(buf))
+	defer func() {
+		req.buf, req.iov.Base = nil, nil
+		requestPool.Put(req)
+	}()
+
+	for {
+		select {
+		case r.requests <- req:
+		case <-r.stopped:
+			return 0, r.err
+		}
+		<-req.completed
+
+		switch {
+		case req.res > 0:
+			return int(req.res), nil
+		case req.res == 0:
+			return 0, io.EOF
+		}
+		if errno := unix.Errno(-req.res); errno != unix.EINTR && errno != unix.EAGAIN {
+			return 0, errno
+		}
+	}
+}
+
+// Close stops the ring. It waits for the reads in flight to complete, the
+// reads not submitted yet fail with ErrClosed.
+func (r *Ring) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		<-r.stopped
+		r.unmap()
+		unix.Close(r.fd)
+	})
+	return nil
+}
+
+// run submits the requested reads and dispatches their completions. The reads
+// requested while the previous batch is in flight are collected and submitted
+// with a single system call, together with the wait for the next completion.
+// It returns ErrClosed once the ring is closed and the reads in flight are
+// completed, or the error that made the ring unusable.
+func (r *Ring) run() error {
+	inflight := map[uint64]*request{}
+	var nextID uint64
+	unsubmitted := 0
+	closed := false
+
+	queue := func(req *request) {
+		nextID++
+		tail := atomic.LoadUint32(r.sqTail)
+		idx := tail & r.sqMask
+		r.sqes[idx] = sqe{
+			opcode:   opReadv,
+			fd:       int32(req.fd),
+			off:      uint64(req.off),
+			addr:     uint64(uintptr(unsafe.Pointer(&req.iov))),
+			len:      1,
+			userData: nextID,
+		}
+		r.sqArray[idx] = idx
+		atomic.StoreUint32(r.sqTail, tail+1)
+		inflight[nextID] = req
+		unsubmitted++
+	}
+
+	for {
+		if len(inflight) == 0 {
+			if closed {
+				return ErrClosed
+			}
+			select {
+			case req := <-r.requests:
+				queue(req)
+			case <-r.done:
+				return ErrClosed
+			}
+		}
+
+	collect:
+		for !closed && len(inflight) < r.entries {
+			select {
+			case req := <-r.requests:
+				queue(req)
+			case <-r.done:
+				closed = true
+			default:
+				break collect
+			}
+		}
+
+		n, _, errno := unix.Syscall6(unix.SYS_IO_URING_ENTER, uintptr(r.fd), uintptr(unsubmitted), 1, enterGetEvents, 0, 0)
+		if errno == 0 {
+			unsubmitted -= int(n)
+		} else if errno != unix.EINTR && errno != unix.EAGAIN && errno != unix.EBUSY {
+			r.fail(inflight, errno)
+			return fmt.Errorf("io_uring_enter failed: %v", errno)
+		}
+
+		r.reap(inflight)
+	}
+}
+
+// reap dispatches the completed reads.
+func (r *Ring) reap(inflight map[uint64]*request) {
+	head := atomic.LoadUint32(r.cqHead)
+	tail := atomic.LoadUint32(r.cqTail)
+	for ; head != tail; head++ {
+		c := r.cqes[head&r.cqMask]
+		if req, ok := inflight[c.userData]; ok {
+			delete(inflight, c.userData)
+			req.res = c.res
+			req.completed <- struct{}{}
+		}
+	}
+	atomic.StoreUint32(r.cqHead, head)
+}
+
+// fail completes the reads in flight with errno after the ring failed. The
+// ring can not be used any more, so the reads are not submitted again.
+func (r *Ring) fail(inflight map[uint64]*request, errno unix.Errno) {
+	r.reap(inflight)
+	for id, req := range inflight {
+		delete(inflight, id)
+		req.res = -int32(errno)
+		req.completed <- struct{}{}
+	}
+}
@@ -0,0 +1,106 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package uring
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRing(t *testing.T, entries uint32) *Ring {
+	r, err := New(entries)
+	if err == ErrNotSupported {
+		t.Skip("io_uring is not supported")
+	}
+	require.NoError(t, err)
+	return r
+}
+
+func TestRingReadAt(t *testing.T) {
+	r := newTestRing(t, 4)
+	defer r.Close()
+
+	dir, err := ioutil.TempDir("", "uring")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	const files = 64
+	var wg sync.WaitGroup
+	for i := 0; i < files; i++ {
+		content := bytes.Repeat([]byte(fmt.Sprintf("line %d\n", i)), 1000)
+		path := filepath.Join(dir, fmt.Sprintf("%d.log", i))
+		require.NoError(t, ioutil.WriteFile(path, content, 0600))
+
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		defer f.Close()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var read []byte
+			buf := make([]byte, 1000)
+			for {
+				n, err := r.ReadAt(int(f.Fd()), buf, int64(len(read)))
+				read = append(read, buf[:n]...)
+				if err == io.EOF {
+					break
+				}
+				if !assert.NoError(t, err) {
+					return
+				}
+			}
+			assert.Equal(t, content, read)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRingReadAtInvalidFD(t *testing.T) {
+	r := newTestRing(t, 4)
+	defer r.Close()
+
+	_, err := r.ReadAt(-1, make([]byte, 10), 0)
+	assert.Error(t, err)
+}
+
+func TestRingClose(t *testing.T) {
+	r := newTestRing(t, 4)
+	require.NoError(t, r.Close())
+	require.NoError(t, r.Close())
+
+	_, err := r.ReadAt(0, make([]byte, 10), 0)
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestNewInvalidEntries(t *testing.T) {
+	_, err := New(0)
+	assert.Error(t, err)
+	_, err = New(MaxEntries + 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,38 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build !linux
+
+package uring
+
+// Ring is not supported on this OS.
+type Ring struct{}
+
+// New returns ErrNotSupported on this OS.
+func New(entries uint32) (*Ring, error) {
+	return nil, ErrNotSupported
+}
+
+// ReadAt returns ErrNotSupported on this OS.
+func (r *Ring) ReadAt(fd int, buf []byte, offset int64) (int, error) {
+	return 0, ErrNotSupported
+}
+
+// Close does nothing on this OS.
+func (r *Ring) Close() error {
+	return nil
+}
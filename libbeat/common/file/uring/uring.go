@@ -0,0 +1,37 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package uring reads files through the io_uring interface of Linux. A single
+// ring is shared by many readers, the reads requested concurrently are
+// submitted to the kernel in batches with a single system call, and the
+// goroutines waiting for their reads do not block an OS thread each.
+package uring
+
+import "errors"
+
+var (
+	// ErrNotSupported is returned by New if io_uring is not available, either
+	// because the OS is not Linux, the kernel is too old, or its use is
+	// forbidden, e.g. by a seccomp policy.
+	ErrNotSupported = errors.New("io_uring is not supported")
+
+	// ErrClosed is returned by ReadAt if the ring is closed.
+	ErrClosed = errors.New("io_uring ring closed")
+)
+
+// MaxEntries is the maximum number of entries of a ring.
+const MaxEntries = 32768
@@ -0,0 +1,230 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package intern deduplicates the strings repeated in many events, like the
+// field names and the values of low cardinality fields, so the events kept in
+// memory share them instead of holding copies.
+package intern
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// DefaultLimit is the number of strings kept by the Default pool.
+const DefaultLimit = 64 * 1024
+
+// maxValueLength is the length of the longest value interned by MapStr. Longer
+// values are rarely repeated.
+const maxValueLength = 128
+
+// Default is the pool shared by the decoders of the events.
+var Default = NewPool(DefaultLimit)
+
+// lowCardinalityFields are the fields whose values are interned by MapStr,
+// including their subfields.
+var lowCardinalityFields = []string{
+	"agent",
+	"cloud",
+	"container.image",
+	"container.runtime",
+	"data_stream",
+	"ecs",
+	"event.dataset",
+	"event.kind",
+	"event.module",
+	"fileset",
+	"host",
+	"input",
+	"kubernetes.container.name",
+	"kubernetes.labels",
+	"kubernetes.namespace",
+	"kubernetes.node",
+	"log.level",
+	"metricset",
+	"service",
+}
+
+// Pool interns strings. It keeps up to a limited number of strings, and is
+// cleared once full, so high cardinality values don't make it grow without
+// bounds. A Pool is safe for concurrent use.
+type Pool struct {
+	mu sync.RWMutex
+
+	// strings are kept boxed, so the interned values can be set in the
+	// events without allocating.
+	strings map[string]interface{}
+	limit   int
+}
+
+// NewPool creates a pool keeping up to limit strings.
+func NewPool(limit int) *Pool {
+	return &Pool{strings: map[string]interface{}{}, limit: limit}
+}
+
+// String returns the interned copy of s.
+func (p *Pool) String(s string) string {
+	return p.value(s).(string)
+}
+
+// value returns the interned copy of s, boxed.
+func (p *Pool) value(s string) interface{} {
+	p.mu.RLock()
+	interned, found := p.strings[s]
+	p.mu.RUnlock()
+	if found {
+		return interned
+	}
+	return p.add(s)
+}
+
+// Bytes returns the interned string with the contents of b, it only allocates
+// the first time the string is seen.
+func (p *Pool) Bytes(b []byte) string {
+	p.mu.RLock()
+	interned, found := p.strings[string(b)]
+	p.mu.RUnlock()
+	if found {
+		return interned.(string)
+	}
+	return p.add(string(b)).(string)
+}
+
+func (p *Pool) add(s string) interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if interned, found := p.strings[s]; found {
+		return interned
+	}
+	if len(p.strings) >= p.limit {
+		p.strings = map[string]interface{}{}
+	}
+	var boxed interface{} = s
+	p.strings[s] = boxed
+	return boxed
+}
+
+// Len returns the number of strings in the pool.
+func (p *Pool) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.strings)
+}
+
+// MapStr interns in place the keys of m and of its nested objects, and the
+// short string values of the fields known to have a low cardinality, like the
+// dataset, host and Kubernetes labels fields.
+func (p *Pool) MapStr(m common.MapStr) {
+	p.internMap(m, lowCardinality, false)
+}
+
+// MapStrValues interns in place the keys of m and of its nested objects, and
+// all its short string values. It's intended for objects whose values are
+// known to have a low cardinality.
+func (p *Pool) MapStrValues(m common.MapStr) {
+	p.internMap(m, nil, true)
+}
+
+func (p *Pool) internMap(m map[string]interface{}, node *fieldNode, values bool) {
+	for k, v := range m {
+		key := p.String(k)
+
+		child, childValues := node.child(key), values
+		if child != nil && child.children == nil {
+			childValues = true
+		}
+
+		switch vv := v.(type) {
+		case common.MapStr:
+			p.internMap(vv, child, childValues)
+		case map[string]interface{}:
+			p.internMap(vv, child, childValues)
+		case []interface{}:
+			p.internArray(vv, child, childValues)
+		case string:
+			if childValues && len(vv) <= maxValueLength {
+				v = p.value(vv)
+			}
+		case []string:
+			if childValues {
+				for i, s := range vv {
+					if len(s) <= maxValueLength {
+						vv[i] = p.String(s)
+					}
+				}
+			}
+		}
+
+		// Assigning a value with an equal key replaces the key stored in the
+		// map, so the map doesn't need to be rebuilt.
+		m[key] = v
+	}
+}
+
+func (p *Pool) internArray(arr []interface{}, node *fieldNode, values bool) {
+	for i, v := range arr {
+		switch vv := v.(type) {
+		case common.MapStr:
+			p.internMap(vv, node, values)
+		case map[string]interface{}:
+			p.internMap(vv, node, values)
+		case string:
+			if values && len(vv) <= maxValueLength {
+				arr[i] = p.value(vv)
+			}
+		}
+	}
+}
+
+// fieldNode is a node of the tree of the low cardinality fields, a node without
+// children matches all its subfields.
+type fieldNode struct {
+	children map[string]*fieldNode
+}
+
+var lowCardinality = newFieldTree(lowCardinalityFields)
+
+func newFieldTree(fields []string) *fieldNode {
+	root := &fieldNode{children: map[string]*fieldNode{}}
+	for _, field := range fields {
+		node := root
+		for _, name := range strings.Split(field, ".") {
+			if node.children == nil {
+				// A parent already matches all its subfields.
+				break
+			}
+			child, found := node.children[name]
+			if !found {
+				child = &fieldNode{children: map[string]*fieldNode{}}
+				node.children[name] = child
+			}
+			node = child
+		}
+		node.children = nil
+	}
+	return root
+}
+
+func (n *fieldNode) child(name string) *fieldNode {
+	if n == nil {
+		return nil
+	}
+	return n.children[name]
+}
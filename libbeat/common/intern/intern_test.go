@@ -0,0 +1,136 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package intern
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestPoolString(t *testing.T) {
+	p := NewPool(2)
+
+	a := p.String(copyString("first"))
+	assert.True(t, sameString(a, p.String(copyString("first"))))
+	assert.True(t, sameString(a, p.Bytes([]byte("first"))))
+	assert.Equal(t, 1, p.Len())
+
+	// The pool is cleared once full.
+	p.String("second")
+	p.String("third")
+	assert.Equal(t, 1, p.Len())
+	assert.False(t, sameString(a, p.String(copyString("first"))))
+}
+
+func TestPoolMapStr(t *testing.T) {
+	p := NewPool(DefaultLimit)
+	newEvent := func() common.MapStr {
+		return common.MapStr{
+			copyString("message"): copyString("hello"),
+			copyString("event"): map[string]interface{}{
+				copyString("dataset"):  copyString("nginx.access"),
+				copyString("original"): copyString("raw"),
+			},
+			copyString("host"): common.MapStr{
+				copyString("name"): copyString("localhost"),
+				copyString("ip"):   []interface{}{copyString("127.0.0.1")},
+			},
+			copyString("tags"): []string{copyString("tag")},
+		}
+	}
+
+	first, second := newEvent(), newEvent()
+	p.MapStr(first)
+	p.MapStr(second)
+	assert.Equal(t, newEvent(), second)
+
+	keys := func(m map[string]interface{}) map[string]string {
+		keys := map[string]string{}
+		for k := range m {
+			keys[k] = k
+		}
+		return keys
+	}
+	for k := range keys(first) {
+		assert.True(t, sameString(keys(first)[k], keys(second)[k]), "key %v", k)
+	}
+	for k := range keys(first["event"].(map[string]interface{})) {
+		assert.True(t, sameString(keys(first["event"].(map[string]interface{}))[k], keys(second["event"].(map[string]interface{}))[k]), "key event.%v", k)
+	}
+
+	value := func(m common.MapStr, key string) string {
+		v, _ := m.GetValue(key)
+		return v.(string)
+	}
+	assert.True(t, sameString(value(first, "event.dataset"), value(second, "event.dataset")))
+	assert.True(t, sameString(value(first, "host.name"), value(second, "host.name")))
+	assert.True(t, sameString(first["host"].(common.MapStr)["ip"].([]interface{})[0].(string), second["host"].(common.MapStr)["ip"].([]interface{})[0].(string)))
+
+	// Values of other fields are not interned.
+	assert.False(t, sameString(value(first, "message"), value(second, "message")))
+	assert.False(t, sameString(value(first, "event.original"), value(second, "event.original")))
+	assert.False(t, sameString(first["tags"].([]string)[0], second["tags"].([]string)[0]))
+}
+
+func TestPoolMapStrLongValues(t *testing.T) {
+	p := NewPool(DefaultLimit)
+	long := strings.Repeat("a", maxValueLength+1)
+	p.MapStr(common.MapStr{"host": common.MapStr{"name": long}})
+	assert.Equal(t, 2, p.Len())
+}
+
+func TestPoolMapStrValues(t *testing.T) {
+	p := NewPool(DefaultLimit)
+	first := common.MapStr{"app": copyString("nginx")}
+	second := common.MapStr{"app": copyString("nginx")}
+	p.MapStrValues(first)
+	p.MapStrValues(second)
+	assert.True(t, sameString(first["app"].(string), second["app"].(string)))
+}
+
+func BenchmarkPoolMapStr(b *testing.B) {
+	p := NewPool(DefaultLimit)
+	event := common.MapStr{
+		"message": "hello",
+		"event":   common.MapStr{"dataset": "nginx.access", "module": "nginx"},
+		"host":    common.MapStr{"name": "localhost", "hostname": "localhost"},
+		"kubernetes": common.MapStr{
+			"pod":    common.MapStr{"name": "pod", "uid": "uid"},
+			"labels": common.MapStr{"app": "nginx"},
+		},
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.MapStr(event)
+	}
+}
+
+func copyString(s string) string {
+	return string([]byte(s))
+}
+
+func sameString(a, b string) bool {
+	return (*reflect.StringHeader)(unsafe.Pointer(&a)).Data == (*reflect.StringHeader)(unsafe.Pointer(&b)).Data
+}
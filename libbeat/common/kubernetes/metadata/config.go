@@ -17,7 +17,11 @@
 
 package metadata
 
-import "github.com/elastic/beats/v7/libbeat/common"
+import (
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
 
 // Config declares supported configuration for metadata generation
 type Config struct {
@@ -36,6 +40,33 @@ type Config struct {
 type AddResourceMetadataConfig struct {
 	Node      *common.Config `config:"node"`
 	Namespace *common.Config `config:"namespace"`
+	Owners    *OwnersConfig  `config:"owners"`
+}
+
+// OwnersConfig configures the resolution of the controllers owning the
+// controllers of a resource.
+type OwnersConfig struct {
+	Enabled   bool          `config:"enabled"`
+	CacheSize int           `config:"cache.size" validate:"min=1"`
+	CacheTTL  time.Duration `config:"cache.ttl" validate:"min=0"`
+}
+
+// InitDefaults initializes the defaults for the owners config.
+func (c *OwnersConfig) InitDefaults() {
+	c.Enabled = true
+	c.CacheSize = 1000
+	c.CacheTTL = 10 * time.Minute
+}
+
+// GetOwnersConfig returns the owners config, or the defaults if it is not
+// set.
+func (c *AddResourceMetadataConfig) GetOwnersConfig() OwnersConfig {
+	if c.Owners != nil {
+		return *c.Owners
+	}
+	var config OwnersConfig
+	config.InitDefaults()
+	return config
 }
 
 // InitDefaults initializes the defaults for the config.
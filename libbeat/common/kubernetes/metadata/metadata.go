@@ -52,12 +52,14 @@ func WithLabels(kind string) FieldOptions {
 }
 
 // GetPodMetaGen is a wrapper function that creates a metaGen for pod resource and has embeeded
-// nodeMetaGen and namespaceMetaGen
+// nodeMetaGen and namespaceMetaGen. The owners resolver is optional, when set the top level
+// controllers of the pods are included.
 func GetPodMetaGen(
 	cfg *common.Config,
 	podWatcher kubernetes.Watcher,
 	nodeWatcher kubernetes.Watcher,
 	namespaceWatcher kubernetes.Watcher,
+	owners OwnerResolver,
 	metaConf *AddResourceMetadataConfig) MetaGen {
 
 	var nodeMetaGen, namespaceMetaGen MetaGen
@@ -67,7 +69,7 @@ func GetPodMetaGen(
 	if namespaceWatcher != nil {
 		namespaceMetaGen = NewNamespaceMetadataGenerator(metaConf.Namespace, namespaceWatcher.Store())
 	}
-	metaGen := NewPodMetadataGenerator(cfg, podWatcher.Store(), nodeMetaGen, namespaceMetaGen)
+	metaGen := newPodMetadataGenerator(cfg, podWatcher.Store(), nodeMetaGen, namespaceMetaGen, owners)
 
 	return metaGen
 }
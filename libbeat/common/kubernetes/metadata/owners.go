@@ -0,0 +1,227 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	lru "k8s.io/apimachinery/pkg/util/cache"
+	"k8s.io/apimachinery/pkg/watch"
+	k8s "k8s.io/client-go/kubernetes"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+// maxOwnerDepth is the maximum number of owners followed from a resource to
+// its top level controller.
+const maxOwnerDepth = 3
+
+// OwnerResolver returns the owner references of the controllers owning a
+// resource, so the top level controllers can be found, like the Deployment
+// of the ReplicaSet of a Pod, or the CronJob of a Job.
+type OwnerResolver interface {
+	// Owners returns the owner references of the given owner.
+	Owners(namespace string, owner metav1.OwnerReference) []metav1.OwnerReference
+}
+
+// OwnerCache is an OwnerResolver that gets the owners from the Kubernetes API
+// and keeps them in a bounded cache. The ReplicaSets and Jobs are watched to
+// remove the entries of the modified or deleted ones.
+type OwnerCache struct {
+	client    k8s.Interface
+	namespace string
+	config    OwnersConfig
+	cache     *lru.LRUExpireCache
+	logger    *logp.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type ownerEntry struct {
+	uid    string
+	owners []metav1.OwnerReference
+}
+
+// ownerGetter returns the object meta of an owner of the given kind.
+type ownerGetter func(ctx context.Context, client k8s.Interface, namespace, name string) (metav1.Object, error)
+
+// ownerWatcher watches the owners of the given kind.
+type ownerWatcher func(ctx context.Context, client k8s.Interface, namespace string, options metav1.ListOptions) (watch.Interface, error)
+
+var ownerKinds = map[string]struct {
+	get   ownerGetter
+	watch ownerWatcher
+}{
+	"ReplicaSet": {
+		get: func(ctx context.Context, client k8s.Interface, namespace, name string) (metav1.Object, error) {
+			return client.AppsV1().ReplicaSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		},
+		watch: func(ctx context.Context, client k8s.Interface, namespace string, options metav1.ListOptions) (watch.Interface, error) {
+			list, err := client.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{Limit: 1})
+			if err != nil {
+				return nil, err
+			}
+			options.ResourceVersion = list.ResourceVersion
+			return client.AppsV1().ReplicaSets(namespace).Watch(ctx, options)
+		},
+	},
+	"Job": {
+		get: func(ctx context.Context, client k8s.Interface, namespace, name string) (metav1.Object, error) {
+			return client.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		},
+		watch: func(ctx context.Context, client k8s.Interface, namespace string, options metav1.ListOptions) (watch.Interface, error) {
+			list, err := client.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{Limit: 1})
+			if err != nil {
+				return nil, err
+			}
+			options.ResourceVersion = list.ResourceVersion
+			return client.BatchV1().Jobs(namespace).Watch(ctx, options)
+		},
+	},
+}
+
+// NewOwnerCache creates an OwnerCache for the resources of the given
+// namespace, or of all namespaces if empty. Start must be called to watch
+// the owners.
+func NewOwnerCache(client k8s.Interface, namespace string, config OwnersConfig) *OwnerCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &OwnerCache{
+		client:    client,
+		namespace: namespace,
+		config:    config,
+		cache:     lru.NewLRUExpireCache(config.CacheSize),
+		logger:    logp.NewLogger("kubernetes.owners"),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start watches the owners to invalidate the cached entries.
+func (c *OwnerCache) Start() {
+	for kind, k := range ownerKinds {
+		go c.watch(kind, k.watch)
+	}
+}
+
+// Stop stops watching the owners.
+func (c *OwnerCache) Stop() {
+	c.cancel()
+}
+
+// Owners returns the owner references of the given owner. Only ReplicaSets
+// and Jobs are resolved, the owners of other kinds are top level controllers.
+func (c *OwnerCache) Owners(namespace string, owner metav1.OwnerReference) []metav1.OwnerReference {
+	kind, ok := ownerKinds[owner.Kind]
+	if !ok {
+		return nil
+	}
+
+	key := ownerKey(owner.Kind, namespace, owner.Name)
+	if v, ok := c.cache.Get(key); ok {
+		if entry := v.(ownerEntry); entry.uid == string(owner.UID) {
+			return entry.owners
+		}
+	}
+
+	obj, err := kind.get(c.ctx, c.client, namespace, owner.Name)
+	if err != nil {
+		// Unknown owners are also cached to avoid querying them on every
+		// event, they are invalidated when they are created.
+		c.logger.Debugf("Failed to get %s %s/%s: %v", owner.Kind, namespace, owner.Name, err)
+		c.cache.Add(key, ownerEntry{uid: string(owner.UID)}, c.config.CacheTTL)
+		return nil
+	}
+
+	entry := ownerEntry{uid: string(obj.GetUID()), owners: obj.GetOwnerReferences()}
+	c.cache.Add(key, entry, c.config.CacheTTL)
+	if entry.uid != string(owner.UID) {
+		return nil
+	}
+	return entry.owners
+}
+
+// watch removes the cached entries of the owners of the given kind when they
+// change. When the watch is interrupted all the entries are removed, as some
+// changes may have been missed.
+func (c *OwnerCache) watch(kind string, watchOwners ownerWatcher) {
+	backoff := time.Second
+	for {
+		w, err := watchOwners(c.ctx, c.client, c.namespace, metav1.ListOptions{})
+		if err != nil {
+			c.logger.Debugf("Failed to watch %s: %v", kind, err)
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < time.Minute {
+				backoff *= 2
+			}
+			continue
+		}
+		backoff = time.Second
+
+		c.process(kind, w)
+		c.purge(kind)
+
+		select {
+		case <-c.ctx.Done():
+			return
+		default:
+		}
+	}
+}
+
+func (c *OwnerCache) process(kind string, w watch.Interface) {
+	defer w.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case event, ok := <-w.ResultChan():
+			if !ok || event.Type == watch.Error {
+				return
+			}
+			obj, ok := event.Object.(metav1.Object)
+			if !ok {
+				continue
+			}
+			c.cache.Remove(ownerKey(kind, obj.GetNamespace(), obj.GetName()))
+		}
+	}
+}
+
+// purge removes the cached entries of the owners of the given kind.
+func (c *OwnerCache) purge(kind string) {
+	for _, key := range c.cache.Keys() {
+		if k, ok := key.(ownerCacheKey); ok && k.kind == kind {
+			c.cache.Remove(key)
+		}
+	}
+}
+
+type ownerCacheKey struct {
+	kind, namespace, name string
+}
+
+func ownerKey(kind, namespace, name string) ownerCacheKey {
+	return ownerCacheKey{kind: kind, namespace: namespace, name: name}
+}
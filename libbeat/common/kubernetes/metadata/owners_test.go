@@ -0,0 +1,162 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package metadata
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func controllerRef(kind, name, uid string) metav1.OwnerReference {
+	controller := true
+	return metav1.OwnerReference{Kind: kind, Name: name, UID: types.UID(uid), Controller: &controller}
+}
+
+func TestOwnerCache_Generate(t *testing.T) {
+	client := k8sfake.NewSimpleClientset(
+		&appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "nginx-7d8b49557c",
+				Namespace:       "default",
+				UID:             "rs-uid",
+				OwnerReferences: []metav1.OwnerReference{controllerRef("Deployment", "nginx", "deploy-uid")},
+			},
+		},
+		&batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "backup-1600000000",
+				Namespace:       "default",
+				UID:             "job-uid",
+				OwnerReferences: []metav1.OwnerReference{controllerRef("CronJob", "backup", "cronjob-uid")},
+			},
+		},
+	)
+
+	owners := NewOwnerCache(client, "", OwnersConfig{CacheSize: 10, CacheTTL: time.Minute})
+	metagen := newPodMetadataGenerator(common.NewConfig(), nil, nil, nil, owners)
+
+	cases := map[string]struct {
+		owner    metav1.OwnerReference
+		expected common.MapStr
+	}{
+		"deployment": {
+			owner: controllerRef("ReplicaSet", "nginx-7d8b49557c", "rs-uid"),
+			expected: common.MapStr{
+				"replicaset": common.MapStr{"name": "nginx-7d8b49557c"},
+				"deployment": common.MapStr{"name": "nginx"},
+			},
+		},
+		"cronjob": {
+			owner: controllerRef("Job", "backup-1600000000", "job-uid"),
+			expected: common.MapStr{
+				"job":     common.MapStr{"name": "backup-1600000000"},
+				"cronjob": common.MapStr{"name": "backup"},
+			},
+		},
+		"replaced owner": {
+			owner: controllerRef("ReplicaSet", "nginx-7d8b49557c", "other-uid"),
+			expected: common.MapStr{
+				"replicaset": common.MapStr{"name": "nginx-7d8b49557c"},
+			},
+		},
+		"missing owner": {
+			owner: controllerRef("ReplicaSet", "missing", "missing-uid"),
+			expected: common.MapStr{
+				"replicaset": common.MapStr{"name": "missing"},
+			},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "pod",
+					Namespace:       "default",
+					UID:             "pod-uid",
+					OwnerReferences: []metav1.OwnerReference{test.owner},
+				},
+				Spec: v1.PodSpec{NodeName: "node"},
+			}
+
+			meta := metagen.Generate(pod)
+			for kind := range test.expected {
+				assert.Equal(t, test.expected[kind], meta[kind], kind)
+			}
+			for _, kind := range []string{"replicaset", "deployment", "job", "cronjob"} {
+				if _, expected := test.expected[kind]; !expected {
+					assert.NotContains(t, meta, kind)
+				}
+			}
+		})
+	}
+}
+
+func TestOwnerCache_Invalidation(t *testing.T) {
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "nginx-7d8b49557c",
+			Namespace:       "default",
+			UID:             "rs-uid",
+			OwnerReferences: []metav1.OwnerReference{controllerRef("Deployment", "nginx", "deploy-uid")},
+		},
+	}
+	client := k8sfake.NewSimpleClientset(rs)
+
+	owners := NewOwnerCache(client, "default", OwnersConfig{CacheSize: 10, CacheTTL: time.Hour})
+	owners.Start()
+	defer owners.Stop()
+
+	ref := controllerRef("ReplicaSet", rs.Name, "rs-uid")
+	require.Equal(t, rs.OwnerReferences, owners.Owners("default", ref))
+
+	// The ReplicaSet is adopted by another Deployment.
+	updated := rs.DeepCopy()
+	updated.OwnerReferences = []metav1.OwnerReference{controllerRef("Deployment", "nginx-v2", "deploy-v2-uid")}
+	assert.Eventually(t, func() bool {
+		// Update until the watch is started and the change is seen.
+		_, err := client.AppsV1().ReplicaSets("default").Update(context.Background(), updated, metav1.UpdateOptions{})
+		require.NoError(t, err)
+		owners := owners.Owners("default", ref)
+		return len(owners) == 1 && owners[0].Name == "nginx-v2"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestOwnersConfig(t *testing.T) {
+	var config AddResourceMetadataConfig
+	assert.Equal(t, OwnersConfig{Enabled: true, CacheSize: 1000, CacheTTL: 10 * time.Minute}, config.GetOwnersConfig())
+
+	cfg := common.MustNewConfigFrom(map[string]interface{}{
+		"owners.enabled":   false,
+		"owners.cache.ttl": "1m",
+	})
+	require.NoError(t, cfg.Unpack(&config))
+	assert.Equal(t, OwnersConfig{Enabled: false, CacheSize: 1000, CacheTTL: time.Minute}, config.GetOwnersConfig())
+}
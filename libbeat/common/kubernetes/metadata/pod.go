@@ -33,8 +33,14 @@ type pod struct {
 
 // NewPodMetadataGenerator creates a metagen for pod resources
 func NewPodMetadataGenerator(cfg *common.Config, pods cache.Store, node MetaGen, namespace MetaGen) MetaGen {
+	return newPodMetadataGenerator(cfg, pods, node, namespace, nil)
+}
+
+func newPodMetadataGenerator(cfg *common.Config, pods cache.Store, node MetaGen, namespace MetaGen, owners OwnerResolver) MetaGen {
+	resource := NewResourceMetadataGenerator(cfg)
+	resource.owners = owners
 	return &pod{
-		resource:  NewResourceMetadataGenerator(cfg),
+		resource:  resource,
 		store:     pods,
 		node:      node,
 		namespace: namespace,
@@ -21,8 +21,10 @@ import (
 	"strings"
 
 	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/intern"
 	"github.com/elastic/beats/v7/libbeat/common/kubernetes"
 	"github.com/elastic/beats/v7/libbeat/common/safemapstr"
 )
@@ -30,6 +32,7 @@ import (
 // Resource generates metadata for any kubernetes resource
 type Resource struct {
 	config *Config
+	owners OwnerResolver
 }
 
 // NewResourceMetadataGenerator creates a metadata generator for a generic resource
@@ -77,20 +80,12 @@ func (r *Resource) Generate(kind string, obj kubernetes.Resource, options ...Fie
 
 	// Add controller metadata if present
 	if r.config.IncludeCreatorMetadata {
-		for _, ref := range accessor.GetOwnerReferences() {
-			if ref.Controller != nil && *ref.Controller {
-				switch ref.Kind {
-				// TODO grow this list as we keep adding more `state_*` metricsets
-				case "Deployment",
-					"ReplicaSet",
-					"StatefulSet":
-					safemapstr.Put(meta, strings.ToLower(ref.Kind)+".name", ref.Name)
-				}
-			}
-		}
+		r.addControllers(meta, accessor.GetNamespace(), accessor.GetOwnerReferences(), 0)
 	}
 
 	if len(labelMap) != 0 {
+		// The same labels are usually set in many resources.
+		intern.Default.MapStrValues(labelMap)
 		safemapstr.Put(meta, "labels", labelMap)
 	}
 
@@ -105,6 +100,30 @@ func (r *Resource) Generate(kind string, obj kubernetes.Resource, options ...Fie
 	return meta
 }
 
+// addControllers adds the names of the controllers in the given owner
+// references, and of their own controllers if an owner resolver is set.
+func (r *Resource) addControllers(meta common.MapStr, namespace string, refs []metav1.OwnerReference, depth int) {
+	for _, ref := range refs {
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+
+		switch ref.Kind {
+		// TODO grow this list as we keep adding more `state_*` metricsets
+		case "Deployment",
+			"ReplicaSet",
+			"StatefulSet",
+			"Job",
+			"CronJob":
+			safemapstr.Put(meta, strings.ToLower(ref.Kind)+".name", ref.Name)
+		}
+
+		if r.owners != nil && depth < maxOwnerDepth {
+			r.addControllers(meta, namespace, r.owners.Owners(namespace, ref), depth+1)
+		}
+	}
+}
+
 func generateMapSubset(input map[string]string, keys []string, dedot bool) common.MapStr {
 	output := common.MapStr{}
 	if input == nil {
@@ -85,6 +85,21 @@ func (m MapStr) deepUpdateMap(d MapStr, overwrite bool) {
 			m[k] = deepUpdateValue(m[k], MapStr(val), overwrite)
 		case MapStr:
 			m[k] = deepUpdateValue(m[k], val, overwrite)
+		case RawJSON:
+			// The raw object is decoded only if there is an object to merge
+			// it with, otherwise it replaces the old value like maps do.
+			_, isMap := tryToMapStr(m[k])
+			_, isRaw := m[k].(RawJSON)
+			if !isMap && !isRaw {
+				m[k] = val
+				continue
+			}
+			sub, err := val.Decode()
+			if err != nil {
+				m[k] = val
+				continue
+			}
+			m[k] = deepUpdateValue(m[k], sub, overwrite)
 		default:
 			if overwrite {
 				m[k] = v
@@ -112,6 +127,13 @@ func deepUpdateValue(old interface{}, val MapStr, overwrite bool) interface{} {
 		tmp := MapStr(sub)
 		tmp.deepUpdateMap(val, overwrite)
 		return tmp
+	case RawJSON:
+		tmp, err := sub.Decode()
+		if err != nil {
+			return val
+		}
+		tmp.deepUpdateMap(val, overwrite)
+		return tmp
 	default:
 		// We reach the default branch if old is no map or if old == nil.
 		// In either case we return `val`, such that the old value is completely
@@ -172,13 +194,23 @@ func (m MapStr) HasKey(key string) (bool, error) {
 // GetValue gets a value from the map. If the key does not exist then an error
 // is returned.
 func (m MapStr) GetValue(key string) (interface{}, error) {
-	_, _, v, found, err := mapFind(key, m, false)
+	k, subMap, v, found, err := mapFind(key, m, false)
 	if err != nil {
 		return nil, err
 	}
 	if !found {
 		return nil, ErrKeyNotFound
 	}
+	if raw, ok := v.(RawJSON); ok {
+		// Objects are returned decoded, the decoded value replaces the raw
+		// one so it is decoded only once.
+		m, err := raw.Decode()
+		if err != nil {
+			return nil, err
+		}
+		subMap[k] = m
+		return m, nil
+	}
 	return v, nil
 }
 
@@ -281,6 +313,12 @@ func flatten(prefix string, in, out MapStr) MapStr {
 			fullKey = prefix + "." + k
 		}
 
+		if raw, ok := v.(RawJSON); ok {
+			if m, err := raw.Decode(); err == nil {
+				v = m
+			}
+		}
+
 		if m, ok := tryToMapStr(v); ok {
 			flatten(fullKey, m, out)
 		} else {
@@ -470,6 +508,17 @@ func mapFind(
 			}
 		}
 
+		if raw, ok := d.(RawJSON); ok {
+			// Decode the raw object to look into it, the decoded object
+			// replaces the raw one so it can be modified.
+			m, err := raw.Decode()
+			if err != nil {
+				return "", nil, nil, false, err
+			}
+			d = m
+			data[k] = m
+		}
+
 		v, err := toMapStr(d)
 		if err != nil {
 			return "", nil, nil, false, err
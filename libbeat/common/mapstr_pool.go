@@ -0,0 +1,51 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package common
+
+import "sync"
+
+// maxPooledMapStrSize is the maximum number of keys of a MapStr returned to
+// the pool. Bigger maps are left to the garbage collector, so a few unusually
+// big events do not keep their memory alive.
+const maxPooledMapStrSize = 128
+
+var mapStrPool = sync.Pool{
+	New: func() interface{} {
+		return MapStr{}
+	},
+}
+
+// GetMapStr returns an empty MapStr from the pool of MapStr released by
+// ReleaseMapStr.
+func GetMapStr() MapStr {
+	return mapStrPool.Get().(MapStr)
+}
+
+// ReleaseMapStr removes all keys of m and returns it to the pool for being
+// reused by GetMapStr. The caller must own m exclusively, m must not be used
+// anymore once released. Only m itself is released, the objects nested in
+// m might be shared with other events and are left untouched.
+func ReleaseMapStr(m MapStr) {
+	if m == nil || len(m) > maxPooledMapStrSize {
+		return
+	}
+	for k := range m {
+		delete(m, k)
+	}
+	mapStrPool.Put(m)
+}
@@ -0,0 +1,55 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleaseMapStr(t *testing.T) {
+	nested := MapStr{"name": "test"}
+	m := GetMapStr()
+	m["message"] = "hello"
+	m["host"] = nested
+
+	ReleaseMapStr(m)
+	assert.Empty(t, m)
+	assert.Equal(t, MapStr{"name": "test"}, nested, "nested objects must not be released")
+
+	big := MapStr{}
+	for i := 0; i <= maxPooledMapStrSize; i++ {
+		big[string(rune('a'+i%26))+string(rune('a'+i/26))] = i
+	}
+	ReleaseMapStr(big)
+	assert.Len(t, big, maxPooledMapStrSize+1, "big maps are not pooled")
+
+	ReleaseMapStr(nil)
+	assert.NotNil(t, GetMapStr())
+}
+
+func BenchmarkGetMapStr(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := GetMapStr()
+		m["message"] = "hello"
+		m["offset"] = i
+		ReleaseMapStr(m)
+	}
+}
@@ -0,0 +1,115 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package common
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/elastic/go-structform"
+	structjson "github.com/elastic/go-structform/json"
+)
+
+// RawJSON is a JSON encoded object kept as is in an event. Its fields are
+// only decoded when they are accessed through the MapStr methods, so events
+// that are shipped mostly unchanged aren't decoded and encoded again. The
+// contents of a RawJSON must not be modified.
+type RawJSON []byte
+
+var errRawJSONNotObject = errors.New("raw JSON value is not an object")
+
+// MarshalJSON returns the encoded object.
+func (r RawJSON) MarshalJSON() ([]byte, error) {
+	if len(r) == 0 {
+		return []byte("null"), nil
+	}
+	return r, nil
+}
+
+// Fold parses the encoded object into v, so the encoders based on structform
+// write it like the decoded object.
+func (r RawJSON) Fold(v structform.ExtVisitor) error {
+	if len(r) == 0 {
+		return v.OnNil()
+	}
+	return structjson.Parse(r, v)
+}
+
+// Decode decodes the first level of the object. Nested objects are kept as
+// RawJSON, other values are decoded, with numbers converted to int64 where
+// possible, and to float64 otherwise.
+func (r RawJSON) Decode() (MapStr, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(r, &raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, errRawJSONNotObject
+	}
+
+	m := make(MapStr, len(raw))
+	for k, v := range raw {
+		if len(v) > 0 && v[0] == '{' {
+			m[k] = RawJSON(v)
+			continue
+		}
+
+		value, err := decodeJSONValue(v)
+		if err != nil {
+			return nil, err
+		}
+		m[k] = value
+	}
+	return m, nil
+}
+
+func decodeJSONValue(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return convertJSONNumbers(v), nil
+}
+
+// convertJSONNumbers replaces the json.Number values found in v like
+// jsontransform.TransformNumbers does.
+func convertJSONNumbers(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case json.Number:
+		if i, err := vv.Int64(); err == nil {
+			return i
+		}
+		if f, err := vv.Float64(); err == nil {
+			return f
+		}
+		return vv.String()
+	case map[string]interface{}:
+		for k, e := range vv {
+			vv[k] = convertJSONNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range vv {
+			vv[i] = convertJSONNumbers(e)
+		}
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRawJSONDecode(t *testing.T) {
+	raw := RawJSON(`{"str": "a", "int": 1, "float": 1.5, "null": null, "list": [1, {"a": 2}], "nested": {"key": "value"}}`)
+
+	m, err := raw.Decode()
+	require.NoError(t, err)
+	assert.Equal(t, MapStr{
+		"str":    "a",
+		"int":    int64(1),
+		"float":  1.5,
+		"null":   nil,
+		"list":   []interface{}{int64(1), map[string]interface{}{"a": int64(2)}},
+		"nested": RawJSON(`{"key": "value"}`),
+	}, m)
+
+	for _, invalid := range []string{`null`, `[1]`, `{"a":`, ``} {
+		_, err := RawJSON(invalid).Decode()
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRawJSONMarshal(t *testing.T) {
+	m := MapStr{"raw": RawJSON(`{"key":"value"}`), "empty": RawJSON(nil)}
+	data, err := json.Marshal(m)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"raw": {"key": "value"}, "empty": null}`, string(data))
+}
+
+func TestRawJSONMapStr(t *testing.T) {
+	newMap := func() MapStr {
+		return MapStr{"json": RawJSON(`{"a": {"b": 1, "c": "x"}, "d": true}`)}
+	}
+
+	t.Run("get value", func(t *testing.T) {
+		m := newMap()
+		v, err := m.GetValue("json.a.b")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), v)
+		assert.Equal(t, MapStr{"b": int64(1), "c": "x"}, m["json"].(MapStr)["a"])
+
+		v, err = newMap().GetValue("json.a")
+		require.NoError(t, err)
+		assert.Equal(t, MapStr{"b": int64(1), "c": "x"}, v)
+
+		_, err = newMap().GetValue("json.missing.b")
+		assert.Equal(t, ErrKeyNotFound, err)
+	})
+
+	t.Run("put and delete", func(t *testing.T) {
+		m := newMap()
+		_, err := m.Put("json.a.b", 2)
+		require.NoError(t, err)
+		require.NoError(t, m.Delete("json.d"))
+		assert.Equal(t, MapStr{"json": MapStr{"a": MapStr{"b": 2, "c": "x"}}}, m)
+	})
+
+	t.Run("clone keeps it encoded", func(t *testing.T) {
+		m := newMap()
+		clone := m.Clone()
+		assert.Equal(t, m, clone)
+
+		_, err := clone.Put("json.d", false)
+		require.NoError(t, err)
+		assert.IsType(t, RawJSON{}, m["json"])
+	})
+
+	t.Run("deep update", func(t *testing.T) {
+		m := newMap()
+		m.DeepUpdate(MapStr{"json": MapStr{"a": MapStr{"c": "y"}}})
+		assert.Equal(t, MapStr{"json": MapStr{"a": MapStr{"b": int64(1), "c": "y"}, "d": true}}, m)
+
+		m = MapStr{"json": MapStr{"d": false, "e": 1}}
+		m.DeepUpdateNoOverwrite(newMap())
+		assert.Equal(t, MapStr{"json": MapStr{"a": RawJSON(`{"b": 1, "c": "x"}`), "d": false, "e": 1}}, m)
+
+		m = MapStr{}
+		m.DeepUpdate(newMap())
+		assert.Equal(t, newMap(), m)
+	})
+
+	t.Run("flatten", func(t *testing.T) {
+		assert.Equal(t, MapStr{"json.a.b": int64(1), "json.a.c": "x", "json.d": true}, newMap().Flatten())
+	})
+}
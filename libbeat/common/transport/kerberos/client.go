@@ -20,46 +20,208 @@ package kerberos
 import (
 	"fmt"
 	"net/http"
+	"os"
+	"sync"
+	"time"
 
 	krbclient "gopkg.in/jcmturner/gokrb5.v7/client"
 	krbconfig "gopkg.in/jcmturner/gokrb5.v7/config"
+	"gopkg.in/jcmturner/gokrb5.v7/credentials"
 	"gopkg.in/jcmturner/gokrb5.v7/keytab"
 	"gopkg.in/jcmturner/gokrb5.v7/spnego"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
 )
 
+// credentialsCheckInterval is the minimal interval between checks for
+// changes of the keytab or credential cache files.
+const credentialsCheckInterval = 1 * time.Minute
+
+// Client is an HTTP client authenticating requests using SPNEGO.
+//
+// Tickets are renewed in the background by the Kerberos client. If a keytab
+// or a credential cache is used, the file is reloaded whenever it changes, so
+// rotated keytabs or caches refreshed by external tools (e.g. k5start) are
+// picked up without restarting the Beat.
 type Client struct {
-	spClient *spnego.Client
+	config     *Config
+	krbConf    *krbconfig.Config
+	httpClient *http.Client
+	log        *logp.Logger
+
+	checkInterval time.Duration
+
+	mu        sync.Mutex
+	current   *clientRef
+	lastCheck time.Time
+	modTime   time.Time
+}
+
+// clientRef is a Kerberos client shared by the requests in flight. When the
+// credentials are reloaded, the replaced client is destroyed once the last
+// request using it is done. The fields are protected by Client.mu.
+type clientRef struct {
+	krbClient *krbclient.Client
+	spClient  *spnego.Client
+	refs      int
+	replaced  bool
+}
+
+// release decrements the number of requests using the client, and destroys
+// it if it has been replaced.
+func (r *clientRef) release() {
+	r.refs--
+	if r.replaced && r.refs == 0 {
+		r.krbClient.Destroy()
+	}
 }
 
 func NewClient(config *Config, httpClient *http.Client, esurl string) (*Client, error) {
-	var krbClient *krbclient.Client
 	krbConf, err := krbconfig.Load(config.ConfigPath)
 	if err != nil {
 		return nil, fmt.Errorf("error creating Kerberos client: %+v", err)
 	}
 
-	switch config.AuthType {
+	// Additional domain to realm mappings are used to resolve the realm of
+	// services in trusted realms, so cross-realm tickets are requested.
+	for domain, realm := range config.DomainRealm {
+		krbConf.DomainRealm[domain] = realm
+	}
+
+	c := &Client{
+		config:        config,
+		krbConf:       krbConf,
+		httpClient:    httpClient,
+		log:           logp.NewLogger("kerberos"),
+		checkInterval: credentialsCheckInterval,
+	}
+
+	c.modTime, _ = c.credentialsModTime()
+	c.lastCheck = time.Now()
+	krbClient, err := c.newKrbClient()
+	if err != nil {
+		return nil, err
+	}
+	c.current = c.newClientRef(krbClient)
+
+	return c, nil
+}
+
+func (c *Client) newKrbClient() (*krbclient.Client, error) {
+	settings := krbclient.DisablePAFXFAST(!c.config.EnableFAST)
+
+	switch c.config.AuthType {
 	case authKeytab:
-		kTab, err := keytab.Load(config.KeyTabPath)
+		kTab, err := keytab.Load(c.config.KeyTabPath)
 		if err != nil {
-			return nil, fmt.Errorf("cannot load keytab file %s: %+v", config.KeyTabPath, err)
+			return nil, fmt.Errorf("cannot load keytab file %s: %+v", c.config.KeyTabPath, err)
 		}
-		krbClient = krbclient.NewClientWithKeytab(config.Username, config.Realm, kTab, krbConf)
+		return krbclient.NewClientWithKeytab(c.config.Username, c.config.Realm, kTab, c.krbConf, settings), nil
 	case authPassword:
-		krbClient = krbclient.NewClientWithPassword(config.Username, config.Realm, config.Password, krbConf)
+		return krbclient.NewClientWithPassword(c.config.Username, c.config.Realm, c.config.Password, c.krbConf, settings), nil
+	case authCCache:
+		path := c.config.ccachePath()
+		ccache, err := credentials.LoadCCache(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load credential cache %s: %+v", path, err)
+		}
+		client, err := krbclient.NewClientFromCCache(ccache, c.krbConf, settings)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create Kerberos client from credential cache %s: %+v", path, err)
+		}
+		return client, nil
 	default:
 		return nil, InvalidAuthType
 	}
+}
 
-	return &Client{
-		spClient: spnego.NewClient(krbClient, httpClient, ""),
-	}, nil
+// newClientRef creates a SPNEGO client using a copy of the configured HTTP
+// client, as the SPNEGO client installs its own cookie jar and redirect
+// handling on the HTTP client.
+func (c *Client) newClientRef(krbClient *krbclient.Client) *clientRef {
+	httpClient := *c.httpClient
+	return &clientRef{
+		krbClient: krbClient,
+		spClient:  spnego.NewClient(krbClient, &httpClient, ""),
+	}
+}
+
+// credentialsFile returns the path of the file the credentials are read
+// from, if any.
+func (c *Client) credentialsFile() string {
+	switch c.config.AuthType {
+	case authKeytab:
+		return c.config.KeyTabPath
+	case authCCache:
+		return c.config.ccachePath()
+	default:
+		return ""
+	}
+}
+
+func (c *Client) credentialsModTime() (time.Time, error) {
+	path := c.credentialsFile()
+	if path == "" {
+		return time.Time{}, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// reloadCredentials creates a new Kerberos client if the credentials file
+// changed. On error the current client is kept.
+func (c *Client) reloadCredentials() {
+	if c.credentialsFile() == "" {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(c.lastCheck) < c.checkInterval {
+		return
+	}
+	c.lastCheck = now
+
+	modTime, err := c.credentialsModTime()
+	if err != nil || modTime.Equal(c.modTime) {
+		return
+	}
+
+	c.log.Infof("Kerberos credentials file %s changed, reloading", c.credentialsFile())
+	krbClient, err := c.newKrbClient()
+	if err != nil {
+		c.log.Errorf("Failed to reload Kerberos credentials, keeping the previous ones: %+v", err)
+		return
+	}
+
+	old := c.current
+	old.replaced = true
+	if old.refs == 0 {
+		old.krbClient.Destroy()
+	}
+	c.current = c.newClientRef(krbClient)
+	c.modTime = modTime
 }
 
 func (c *Client) Do(req *http.Request) (*http.Response, error) {
-	return c.spClient.Do(req)
+	c.mu.Lock()
+	c.reloadCredentials()
+	ref := c.current
+	ref.refs++
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		ref.release()
+		c.mu.Unlock()
+	}()
+	return ref.spClient.Do(req)
 }
 
 func (c *Client) CloseIdleConnections() {
-	c.spClient.CloseIdleConnections()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.current.spClient.CloseIdleConnections()
 }
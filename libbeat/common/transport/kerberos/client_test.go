@@ -0,0 +1,209 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kerberos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/jcmturner/gokrb5.v7/keytab"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+const testKrb5Conf = `
+[libdefaults]
+  default_realm = ELASTIC
+
+[realms]
+  ELASTIC = {
+    kdc = localhost:88
+  }
+  OTHER.ELASTIC = {
+    kdc = other:88
+  }
+
+[domain_realm]
+  .elastic = ELASTIC
+`
+
+func TestConfigValidate(t *testing.T) {
+	cases := map[string]struct {
+		settings map[string]interface{}
+		valid    bool
+	}{
+		"keytab": {
+			settings: map[string]interface{}{
+				"auth_type":   "keytab",
+				"keytab":      "/etc/elastic.keytab",
+				"config_path": "/etc/krb5.conf",
+				"realm":       "ELASTIC",
+			},
+			valid: true,
+		},
+		"keytab without realm": {
+			settings: map[string]interface{}{
+				"auth_type":   "keytab",
+				"keytab":      "/etc/elastic.keytab",
+				"config_path": "/etc/krb5.conf",
+			},
+		},
+		"ccache without realm": {
+			settings: map[string]interface{}{
+				"auth_type":   "ccache",
+				"ccache":      "/tmp/krb5cc_1000",
+				"config_path": "/etc/krb5.conf",
+			},
+			valid: true,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			var config Config
+			err := common.MustNewConfigFrom(test.settings).Unpack(&config)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestCCachePathFromEnvironment(t *testing.T) {
+	defer os.Setenv("KRB5CCNAME", os.Getenv("KRB5CCNAME"))
+	os.Setenv("KRB5CCNAME", "FILE:/tmp/krb5cc_1000")
+
+	config := Config{AuthType: authCCache}
+	assert.Equal(t, "/tmp/krb5cc_1000", config.ccachePath())
+	assert.NoError(t, config.Validate())
+
+	os.Unsetenv("KRB5CCNAME")
+	assert.Error(t, config.Validate())
+}
+
+func TestClientReloadsKeytab(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kerberos")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	confPath := filepath.Join(dir, "krb5.conf")
+	require.NoError(t, ioutil.WriteFile(confPath, []byte(testKrb5Conf), 0600))
+
+	keytabPath := filepath.Join(dir, "elastic.keytab")
+	writeKeytab(t, keytabPath)
+
+	client, err := NewClient(&Config{
+		AuthType:    authKeytab,
+		KeyTabPath:  keytabPath,
+		ConfigPath:  confPath,
+		Username:    "elastic",
+		Realm:       "ELASTIC",
+		DomainRealm: map[string]string{".other.elastic": "OTHER.ELASTIC"},
+	}, &http.Client{}, "http://localhost:9200")
+	require.NoError(t, err)
+	client.checkInterval = 0
+
+	assert.Equal(t, "OTHER.ELASTIC", client.krbConf.ResolveRealm("es.other.elastic"))
+	assert.Equal(t, "ELASTIC", client.krbConf.ResolveRealm("es.elastic"))
+
+	previous := client.current
+	client.reloadCredentials()
+	assert.True(t, previous == client.current, "client must not change if the keytab did not change")
+
+	writeKeytab(t, keytabPath)
+	ts := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(keytabPath, ts, ts))
+
+	client.reloadCredentials()
+	assert.False(t, previous == client.current, "client must be recreated after the keytab changed")
+	assert.Equal(t, "", previous.krbClient.Credentials.UserName(), "unused client must be destroyed")
+}
+
+func TestClientReloadWaitsForRequests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kerberos")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	confPath := filepath.Join(dir, "krb5.conf")
+	require.NoError(t, ioutil.WriteFile(confPath, []byte(testKrb5Conf), 0600))
+
+	keytabPath := filepath.Join(dir, "elastic.keytab")
+	writeKeytab(t, keytabPath)
+
+	client, err := NewClient(&Config{
+		AuthType:   authKeytab,
+		KeyTabPath: keytabPath,
+		ConfigPath: confPath,
+		Username:   "elastic",
+		Realm:      "ELASTIC",
+	}, &http.Client{}, "http://localhost:9200")
+	require.NoError(t, err)
+	client.checkInterval = 0
+
+	// a request in flight uses the current client
+	inFlight := client.current
+	inFlight.refs++
+
+	writeKeytab(t, keytabPath)
+	ts := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(keytabPath, ts, ts))
+	client.reloadCredentials()
+
+	require.False(t, inFlight == client.current)
+	assert.Equal(t, "elastic", inFlight.krbClient.Credentials.UserName(), "client in use must not be destroyed")
+
+	inFlight.release()
+	assert.Equal(t, "", inFlight.krbClient.Credentials.UserName(), "client must be destroyed once released")
+}
+
+// writeKeytab writes a keytab file with a single entry for elastic@ELASTIC.
+func writeKeytab(t *testing.T, path string) {
+	writeString := func(buf *bytes.Buffer, s string) {
+		binary.Write(buf, binary.BigEndian, int16(len(s)))
+		buf.WriteString(s)
+	}
+
+	var entry bytes.Buffer
+	binary.Write(&entry, binary.BigEndian, int16(1)) // number of components
+	writeString(&entry, "ELASTIC")
+	writeString(&entry, "elastic")
+	binary.Write(&entry, binary.BigEndian, int32(1)) // name type
+	binary.Write(&entry, binary.BigEndian, int32(time.Now().Unix()))
+	entry.WriteByte(1)                                // kvno
+	binary.Write(&entry, binary.BigEndian, int16(17)) // aes128-cts-hmac-sha1-96
+	writeString(&entry, string(make([]byte, 16)))
+
+	var data bytes.Buffer
+	data.Write([]byte{0x05, 0x02})
+	binary.Write(&data, binary.BigEndian, int32(entry.Len()))
+	data.Write(entry.Bytes())
+	require.NoError(t, ioutil.WriteFile(path, data.Bytes(), 0600))
+
+	_, err := keytab.Load(path)
+	require.NoError(t, err)
+}
@@ -20,6 +20,8 @@ package kerberos
 import (
 	"errors"
 	"fmt"
+	"os"
+	"strings"
 )
 
 type AuthType uint
@@ -27,9 +29,11 @@ type AuthType uint
 const (
 	authPassword = 1
 	authKeytab   = 2
+	authCCache   = 3
 
 	authPasswordStr = "password"
 	authKeytabStr   = "keytab"
+	authCCacheStr   = "ccache"
 )
 
 var (
@@ -38,18 +42,37 @@ var (
 	authTypes = map[string]AuthType{
 		authPasswordStr: authPassword,
 		authKeytabStr:   authKeytab,
+		authCCacheStr:   authCCache,
 	}
 )
 
 type Config struct {
-	Enabled     *bool    `config:"enabled" yaml:"enabled,omitempty"`
-	AuthType    AuthType `config:"auth_type" validate:"required"`
-	KeyTabPath  string   `config:"keytab"`
-	ConfigPath  string   `config:"config_path" validate:"required"`
-	ServiceName string   `config:"service_name"`
-	Username    string   `config:"username"`
-	Password    string   `config:"password"`
-	Realm       string   `config:"realm" validate:"required"`
+	Enabled     *bool             `config:"enabled" yaml:"enabled,omitempty"`
+	AuthType    AuthType          `config:"auth_type" validate:"required"`
+	KeyTabPath  string            `config:"keytab"`
+	CCachePath  string            `config:"ccache"`
+	ConfigPath  string            `config:"config_path" validate:"required"`
+	ServiceName string            `config:"service_name"`
+	Username    string            `config:"username"`
+	Password    string            `config:"password"`
+	Realm       string            `config:"realm"`
+	EnableFAST  bool              `config:"enable_krb5_fast"`
+	DomainRealm map[string]string `config:"domain_realm"`
+}
+
+// IsCCache returns true if the credentials are read from a credential cache.
+func (c *Config) IsCCache() bool {
+	return c.AuthType == authCCache
+}
+
+// ccachePath returns the path of the credential cache to use. If no path is
+// configured the cache referenced by the KRB5CCNAME environment variable is
+// used.
+func (c *Config) ccachePath() string {
+	if c.CCachePath != "" {
+		return c.CCachePath
+	}
+	return strings.TrimPrefix(os.Getenv("KRB5CCNAME"), "FILE:")
 }
 
 // IsEnabled returns true if the `enable` field is set to true in the yaml.
@@ -70,6 +93,10 @@ func (t *AuthType) Unpack(value string) error {
 }
 
 func (c *Config) Validate() error {
+	if c.AuthType != authCCache && c.Realm == "" {
+		return fmt.Errorf("realm is not configured for Kerberos")
+	}
+
 	switch c.AuthType {
 	case authPassword:
 		if c.Username == "" {
@@ -83,6 +110,11 @@ func (c *Config) Validate() error {
 		if c.KeyTabPath == "" {
 			return fmt.Errorf("keytab authentication is selected for Kerberos, but path to keytab is not configured")
 		}
+
+	case authCCache:
+		if c.ccachePath() == "" {
+			return fmt.Errorf("ccache authentication is selected for Kerberos, but neither the path to the credential cache nor KRB5CCNAME is configured")
+		}
 	default:
 		return InvalidAuthType
 	}
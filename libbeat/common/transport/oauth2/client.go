@@ -0,0 +1,69 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package oauth2
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// Client is an HTTP client that authenticates all requests with an OAuth2
+// access token. Tokens are requested from the configured token endpoint using
+// the client credentials grant and are refreshed automatically before they
+// expire.
+type Client struct {
+	client *http.Client
+	base   *http.Client
+}
+
+// NewClient wraps httpClient, adding OAuth2 authentication to all requests.
+// The token endpoint is queried using httpClient too, so TLS and proxy
+// settings apply to token requests as well.
+func NewClient(config *Config, httpClient *http.Client) *Client {
+	creds := clientcredentials.Config{
+		ClientID:       config.ClientID,
+		ClientSecret:   config.ClientSecret,
+		TokenURL:       config.TokenURL,
+		Scopes:         config.Scopes,
+		EndpointParams: config.endpointParams(),
+	}
+
+	// only required to let the oauth2 library find our custom client in the context
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+
+	client := creds.Client(ctx)
+	client.Timeout = httpClient.Timeout
+
+	return &Client{
+		client: client,
+		base:   httpClient,
+	}
+}
+
+// Do sends an HTTP request, adding the current access token.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	return c.client.Do(req)
+}
+
+// CloseIdleConnections closes the idle connections of the underlying client.
+func (c *Client) CloseIdleConnections() {
+	c.base.CloseIdleConnections()
+}
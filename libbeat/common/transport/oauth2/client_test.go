@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package oauth2
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cases := map[string]struct {
+		settings map[string]interface{}
+		valid    bool
+	}{
+		"complete": {
+			settings: map[string]interface{}{
+				"token_url":     "https://localhost/token",
+				"client.id":     "id",
+				"client.secret": "secret",
+			},
+			valid: true,
+		},
+		"missing token url": {
+			settings: map[string]interface{}{
+				"client.id":     "id",
+				"client.secret": "secret",
+			},
+		},
+		"missing secret": {
+			settings: map[string]interface{}{
+				"token_url": "https://localhost/token",
+				"client.id": "id",
+			},
+		},
+		"disabled": {
+			settings: map[string]interface{}{
+				"enabled": false,
+			},
+			valid: true,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			var config Config
+			err := common.MustNewConfigFrom(test.settings).Unpack(&config)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	var tokenRequests int32
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&tokenRequests, 1)
+
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		assert.Equal(t, "read write", r.Form.Get("scope"))
+		assert.Equal(t, "elasticsearch", r.Form.Get("audience"))
+
+		id, secret, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "beat", id)
+		assert.Equal(t, "s3cr3t", secret)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token-" + strconv.Itoa(int(n)),
+			"token_type":   "Bearer",
+			// tokens expiring within 10 seconds are considered expired and
+			// refreshed on every request
+			"expires_in": 1,
+		})
+	}))
+	defer tokenServer.Close()
+
+	var authHeaders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	client := NewClient(&Config{
+		TokenURL:     tokenServer.URL,
+		ClientID:     "beat",
+		ClientSecret: "s3cr3t",
+		Scopes:       []string{"read", "write"},
+		Audience:     "elasticsearch",
+	}, &http.Client{})
+	defer client.CloseIdleConnections()
+
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequest("GET", server.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(&tokenRequests))
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2"}, authHeaders)
+}
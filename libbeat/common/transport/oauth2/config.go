@@ -0,0 +1,65 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package oauth2
+
+import (
+	"errors"
+)
+
+// Config defines the settings used to obtain access tokens with the OAuth2
+// client credentials grant.
+type Config struct {
+	Enabled        *bool               `config:"enabled" yaml:"enabled,omitempty"`
+	TokenURL       string              `config:"token_url" yaml:"token_url"`
+	ClientID       string              `config:"client.id" yaml:"client.id"`
+	ClientSecret   string              `config:"client.secret" yaml:"client.secret"`
+	Scopes         []string            `config:"scopes" yaml:"scopes,omitempty"`
+	Audience       string              `config:"audience" yaml:"audience,omitempty"`
+	EndpointParams map[string][]string `config:"endpoint_params" yaml:"endpoint_params,omitempty"`
+}
+
+// IsEnabled returns true if the `enable` field is set to true in the yaml.
+func (c *Config) IsEnabled() bool {
+	return c != nil && (c.Enabled == nil || *c.Enabled)
+}
+
+// Validate checks that the token URL and the client credentials are configured.
+func (c *Config) Validate() error {
+	if !c.IsEnabled() {
+		return nil
+	}
+	if c.TokenURL == "" || c.ClientID == "" || c.ClientSecret == "" {
+		return errors.New("both token_url and client credentials must be configured for OAuth2 authentication")
+	}
+	return nil
+}
+
+// endpointParams returns the additional parameters sent to the token
+// endpoint, including the audience if configured.
+func (c *Config) endpointParams() map[string][]string {
+	if c.Audience == "" {
+		return c.EndpointParams
+	}
+
+	params := make(map[string][]string, len(c.EndpointParams)+1)
+	for k, v := range c.EndpointParams {
+		params[k] = v
+	}
+	params["audience"] = []string{c.Audience}
+	return params
+}
@@ -18,7 +18,12 @@
 package transport
 
 import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 
 	"golang.org/x/net/proxy"
@@ -26,11 +31,17 @@ import (
 	"github.com/elastic/beats/v7/libbeat/logp"
 )
 
+func init() {
+	proxy.RegisterDialerType("http", newHTTPConnectDialer)
+	proxy.RegisterDialerType("https", newHTTPConnectDialer)
+}
+
 // ProxyConfig holds the configuration information required to proxy
-// connections through a SOCKS5 proxy server.
+// connections through a SOCKS5 or an HTTP proxy server.
 type ProxyConfig struct {
-	// URL of the SOCKS proxy. Scheme must be socks5. Username and password can be
-	// embedded in the URL.
+	// URL of the proxy. Scheme must be socks5, http or https. Username and
+	// password can be embedded in the URL. HTTP proxies must support the
+	// CONNECT method.
 	URL string `config:"proxy_url"`
 
 	// Resolve names locally instead of on the SOCKS server.
@@ -97,3 +108,97 @@ func ProxyDialer(log *logp.Logger, config *ProxyConfig, forward Dialer) (Dialer,
 		return DialWith(dialer, network, host, addresses, port)
 	}), nil
 }
+
+// httpConnectDialer tunnels connections through an HTTP proxy using the
+// CONNECT method.
+type httpConnectDialer struct {
+	url     *url.URL
+	forward proxy.Dialer
+}
+
+func newHTTPConnectDialer(u *url.URL, forward proxy.Dialer) (proxy.Dialer, error) {
+	if forward == nil {
+		forward = proxy.Direct
+	}
+	return &httpConnectDialer{url: u, forward: forward}, nil
+}
+
+func (d *httpConnectDialer) proxyAddress() string {
+	if d.url.Port() != "" {
+		return d.url.Host
+	}
+	if d.url.Scheme == "https" {
+		return net.JoinHostPort(d.url.Hostname(), "443")
+	}
+	return net.JoinHostPort(d.url.Hostname(), "80")
+}
+
+func (d *httpConnectDialer) Dial(network, address string) (net.Conn, error) {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("unsupported network type %v for HTTP proxy", network)
+	}
+
+	conn, err := d.forward.Dial(network, d.proxyAddress())
+	if err != nil {
+		return nil, err
+	}
+
+	if d.url.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.url.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if user := d.url.User; user != nil {
+		password, _ := user.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// The response body must not be read or closed, as the response to a
+	// successful CONNECT has no length and the tunnel follows immediately.
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %v refused connection to %v: %v", d.url.Host, address, resp.Status)
+	}
+
+	if reader.Buffered() > 0 {
+		// the server already sent data through the tunnel
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a net.Conn that first returns data already buffered
+// while reading the proxy response.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,136 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package transport
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+func TestProxyConfigValidate(t *testing.T) {
+	for url, valid := range map[string]bool{
+		"":                        true,
+		"socks5://localhost:1080": true,
+		"http://localhost:3128":   true,
+		"https://localhost:3128":  true,
+		"ftp://localhost:21":      false,
+	} {
+		config := ProxyConfig{URL: url}
+		if valid {
+			assert.NoError(t, config.Validate(), url)
+		} else {
+			assert.Error(t, config.Validate(), url)
+		}
+	}
+}
+
+func TestHTTPConnectProxyDialer(t *testing.T) {
+	echo := startEchoServer(t)
+	defer echo.Close()
+
+	var proxyAuth, proxyTarget string
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyAuth = r.Header.Get("Proxy-Authorization")
+		proxyTarget = r.Host
+		if r.Method != "CONNECT" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+
+		w.WriteHeader(http.StatusOK)
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		defer conn.Close()
+		buf.Flush()
+
+		go io.Copy(upstream, buf)
+		io.Copy(conn, upstream)
+	}))
+	defer proxyServer.Close()
+
+	proxyURL, err := url.Parse(proxyServer.URL)
+	require.NoError(t, err)
+	proxyURL.User = url.UserPassword("beat", "secret")
+
+	dialer, err := ProxyDialer(logp.NewLogger("test"), &ProxyConfig{URL: proxyURL.String()}, NetDialer(time.Second))
+	require.NoError(t, err)
+
+	conn, err := dialer.Dial("tcp", echo.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("hello\n"))
+	require.NoError(t, err)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+
+	assert.Equal(t, "hello\n", line)
+	assert.Equal(t, echo.Addr().String(), proxyTarget)
+	assert.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("beat:secret")), proxyAuth)
+}
+
+func TestHTTPConnectProxyDialerRefused(t *testing.T) {
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusProxyAuthRequired)
+	}))
+	defer proxyServer.Close()
+
+	dialer, err := ProxyDialer(logp.NewLogger("test"), &ProxyConfig{URL: proxyServer.URL}, NetDialer(time.Second))
+	require.NoError(t, err)
+
+	_, err = dialer.Dial("tcp", "localhost:5044")
+	assert.Error(t, err)
+}
+
+func startEchoServer(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l
+}
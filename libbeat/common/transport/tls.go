@@ -97,7 +97,9 @@ func tlsDialWith(
 		}
 	}
 
-	if tlsConfig.InsecureSkipVerify {
+	// When certificate authorities are reloaded, the chain is fully verified by
+	// VerifyPeerCertificate instead of the standard verification.
+	if tlsConfig.InsecureSkipVerify && (config == nil || config.Verification != tlscommon.VerifyFull) {
 		d.Warn("security", "server's certificate chain verification is disabled")
 	} else {
 		d.Info("security", "server's certificate chain verification is enabled")
@@ -28,6 +28,10 @@ import (
 // ErrCAPinMissmatch is returned when no pin is matched in the verified chain.
 var ErrCAPinMissmatch = errors.New("provided CA certificate pins doesn't match any of the certificate authorities used to validate the certificate")
 
+// ErrPeerPinMissmatch is returned when the public key of the peer certificate matches none of the
+// configured pins.
+var ErrPeerPinMissmatch = errors.New("provided certificate pins doesn't match the public key of the peer certificate")
+
 // verifyPeerCertFunc is a callback defined on the tls.Config struct that will called when a
 // TLS connection is used.
 type verifyPeerCertFunc func([][]byte, [][]*x509.Certificate) error
@@ -49,6 +53,26 @@ func verifyCAPin(hashes []string, verifiedChains [][]*x509.Certificate) error {
 	return ErrCAPinMissmatch
 }
 
+// verifyPeerPin checks that the public key of the leaf certificate presented by the peer matches
+// one of the pins.
+//
+// Unlike the CA pin, the peer pin is checked on the certificates as presented during the
+// handshake, so it is also enforced when the verification mode is `none` or `certificate`.
+func verifyPeerPin(hashes []string, rawCerts [][]byte) error {
+	if len(rawCerts) == 0 {
+		return ErrPeerPinMissmatch
+	}
+
+	cert, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return errors.Wrap(err, "tls: failed to parse certificate from peer")
+	}
+	if matches(hashes, Fingerprint(cert)) {
+		return nil
+	}
+	return ErrPeerPinMissmatch
+}
+
 // Fingerprint takes a certificate and create a hash of the DER encoded public key.
 func Fingerprint(certificate *x509.Certificate) string {
 	hash := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
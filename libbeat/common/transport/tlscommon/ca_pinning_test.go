@@ -313,7 +313,7 @@ func genCA() (tls.Certificate, error) {
 		NotAfter:              time.Now().Add(1 * time.Hour),
 		IsCA:                  true,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
 		BasicConstraintsValid: true,
 	}
 
@@ -343,7 +343,7 @@ func genCA() (tls.Certificate, error) {
 func genSignedCert(ca tls.Certificate, keyUsage x509.KeyUsage, isCA bool) (tls.Certificate, error) {
 	// Create another Cert/key
 	cert := &x509.Certificate{
-		SerialNumber: big.NewInt(2000),
+		SerialNumber: serial(),
 		Subject: pkix.Name{
 			CommonName:    "localhost",
 			Organization:  []string{"TESTING"},
@@ -355,6 +355,7 @@ func genSignedCert(ca tls.Certificate, keyUsage x509.KeyUsage, isCA bool) (tls.C
 		},
 		NotBefore:             time.Now(),
 		NotAfter:              time.Now().Add(1 * time.Hour),
+		DNSNames:              []string{"localhost"},
 		IsCA:                  isCA,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
 		KeyUsage:              keyUsage,
@@ -394,3 +395,78 @@ func serial() *big.Int {
 	ser = ser + 1
 	return big.NewInt(ser)
 }
+
+func TestPeerPinning(t *testing.T) {
+	ca, err := genCA()
+	require.NoError(t, err)
+	serverCert, err := genSignedCert(ca, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+	otherCert, err := genSignedCert(ca, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+
+	server := serveTLS(t, serverCert)
+	defer server.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.Leaf)
+
+	testCases := map[string]struct {
+		verification TLSVerificationMode
+		pins         []string
+		success      bool
+	}{
+		"full verification and matching pin": {
+			verification: VerifyFull,
+			pins:         []string{Fingerprint(serverCert.Leaf)},
+			success:      true,
+		},
+		"full verification and one of the pins matches": {
+			verification: VerifyFull,
+			pins:         []string{Fingerprint(otherCert.Leaf), Fingerprint(serverCert.Leaf)},
+			success:      true,
+		},
+		"full verification and wrong pin": {
+			verification: VerifyFull,
+			pins:         []string{Fingerprint(otherCert.Leaf)},
+		},
+		"the CA pin does not match the server key": {
+			verification: VerifyFull,
+			pins:         []string{Fingerprint(ca.Leaf)},
+		},
+		"certificate verification and matching pin": {
+			verification: VerifyCertificate,
+			pins:         []string{Fingerprint(serverCert.Leaf)},
+			success:      true,
+		},
+		"certificate verification and wrong pin": {
+			verification: VerifyCertificate,
+			pins:         []string{Fingerprint(otherCert.Leaf)},
+		},
+		"no verification and matching pin": {
+			verification: VerifyNone,
+			pins:         []string{Fingerprint(serverCert.Leaf)},
+			success:      true,
+		},
+		"no verification and wrong pin": {
+			verification: VerifyNone,
+			pins:         []string{Fingerprint(otherCert.Leaf)},
+		},
+	}
+
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tlsC := &TLSConfig{
+				Verification: test.verification,
+				RootCAs:      rootCAs,
+				PinSha256:    test.pins,
+			}
+
+			err := dialTLS(server.Addr().String(), tlsC.BuildModuleConfig("localhost"))
+			if test.success {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -34,6 +34,8 @@ type Config struct {
 	CurveTypes       []tlsCurveType          `config:"curve_types" yaml:"curve_types,omitempty"`
 	Renegotiation    tlsRenegotiationSupport `config:"renegotiation" yaml:"renegotiation"`
 	CASha256         []string                `config:"ca_sha256" yaml:"ca_sha256,omitempty"`
+	PinSha256        []string                `config:"pin_sha256" yaml:"pin_sha256,omitempty"`
+	Reload           ReloadConfig            `config:"reload" yaml:"reload,omitempty"`
 }
 
 // LoadTLSConfig will load a certificate from config with all TLS based keys
@@ -80,6 +82,11 @@ func LoadTLSConfig(config *Config) (*TLSConfig, error) {
 		certs = []tls.Certificate{*cert}
 	}
 
+	var reloader *certReloader
+	if config.Reload.IsEnabled() {
+		reloader = newCertReloader(config.Reload, config.Certificate, config.CAs, cert, cas)
+	}
+
 	// return config if no error occurred
 	return &TLSConfig{
 		Versions:         config.Versions,
@@ -90,6 +97,8 @@ func LoadTLSConfig(config *Config) (*TLSConfig, error) {
 		CurvePreferences: curves,
 		Renegotiation:    tls.RenegotiationSupport(config.Renegotiation),
 		CASha256:         config.CASha256,
+		PinSha256:        config.PinSha256,
+		reloader:         reloader,
 	}, nil
 }
 
@@ -0,0 +1,196 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tlscommon
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/joeshaw/multierror"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+const defaultReloadPeriod = 1 * time.Minute
+
+// ReloadConfig configures the periodic reloading of certificate, key and
+// certificate authority files.
+type ReloadConfig struct {
+	Enabled bool          `config:"enabled" yaml:"enabled"`
+	Period  time.Duration `config:"period" yaml:"period,omitempty"`
+}
+
+// IsEnabled returns true if reloading of the TLS files has been enabled.
+func (c *ReloadConfig) IsEnabled() bool {
+	return c != nil && c.Enabled
+}
+
+func (c *ReloadConfig) period() time.Duration {
+	if c.Period <= 0 {
+		return defaultReloadPeriod
+	}
+	return c.Period
+}
+
+// fileStamp is used to detect changes to a file on disk.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// certReloader keeps track of the certificate and certificate authorities
+// files in use and reloads them when they change on disk.
+//
+// Files are not watched in the background. Instead a check is done lazily
+// when the certificates are requested during a TLS handshake, but not more
+// often than once per configured period. Secrets mounted by orchestrators are
+// often replaced by swapping symlinks, which is handled by comparing the
+// stat information of the resolved files.
+type certReloader struct {
+	log    *logp.Logger
+	period time.Duration
+	now    func() time.Time
+
+	certConfig CertificateConfig
+	cas        []string
+
+	// server is set if the certificate authorities are used to verify the
+	// certificates of clients.
+	server bool
+
+	mu        sync.Mutex
+	lastCheck time.Time
+	stamps    map[string]fileStamp
+	cert      *tls.Certificate
+	roots     *x509.CertPool
+}
+
+func newCertReloader(
+	cfg ReloadConfig,
+	certConfig CertificateConfig,
+	cas []string,
+	cert *tls.Certificate,
+	roots *x509.CertPool,
+) *certReloader {
+	r := &certReloader{
+		log:        logp.NewLogger(logSelector),
+		period:     cfg.period(),
+		now:        time.Now,
+		certConfig: certConfig,
+		cas:        cas,
+		cert:       cert,
+		roots:      roots,
+	}
+	r.stamps = statFiles(r.files())
+	r.lastCheck = r.now()
+	return r
+}
+
+// files returns the list of files that are checked for changes. Certificates
+// and keys embedded into the configuration are ignored.
+func (r *certReloader) files() []string {
+	var files []string
+	for _, f := range append([]string{r.certConfig.Certificate, r.certConfig.Key}, r.cas...) {
+		if f != "" && !IsPEMString(f) {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// statFiles returns the stat information of the files that exist.
+func statFiles(files []string) map[string]fileStamp {
+	stamps := map[string]fileStamp{}
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue
+		}
+		stamps[f] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return stamps
+}
+
+func stampsChanged(old, stamps map[string]fileStamp) bool {
+	if len(stamps) != len(old) {
+		return true
+	}
+	for f, stamp := range stamps {
+		if prev, exists := old[f]; !exists || prev != stamp {
+			return true
+		}
+	}
+	return false
+}
+
+// reload checks if any of the files changed since the last check, and loads
+// the new certificates if so. If loading a changed file fails, the previously
+// loaded certificates are kept and the reload is retried on the next check.
+func (r *certReloader) reload() {
+	now := r.now()
+	if now.Sub(r.lastCheck) < r.period {
+		return
+	}
+	r.lastCheck = now
+
+	stamps := statFiles(r.files())
+	if !stampsChanged(r.stamps, stamps) {
+		return
+	}
+
+	r.log.Info("TLS certificate files changed, reloading")
+
+	fail := multierror.Errors{}
+	cert, err := LoadCertificate(&r.certConfig)
+	if err != nil {
+		fail = append(fail, err)
+	}
+	roots, errs := LoadCertificateAuthorities(r.cas)
+	for _, err := range errs {
+		fail = append(fail, err)
+	}
+	if err := fail.Err(); err != nil {
+		r.log.Errorf("Failed to reload TLS certificates, keeping the previous ones: %+v", err)
+		return
+	}
+
+	r.stamps = stamps
+	r.cert = cert
+	r.roots = roots
+}
+
+// certificate returns the current client or server certificate, if any.
+func (r *certReloader) certificate() *tls.Certificate {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reload()
+	return r.cert
+}
+
+// rootCAs returns the current pool of certificate authorities, if any. These
+// verify the server certificates in clients, and the client certificates in
+// servers.
+func (r *certReloader) rootCAs() *x509.CertPool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reload()
+	return r.roots
+}
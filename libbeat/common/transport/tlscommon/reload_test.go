@@ -0,0 +1,420 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tlscommon
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestReloadConfig(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		cfg, err := load(`certificate_authorities: [ca_test.pem]`)
+		require.NoError(t, err)
+
+		tlsC, err := LoadTLSConfig(cfg)
+		require.NoError(t, err)
+		assert.Nil(t, tlsC.reloader)
+	})
+
+	t.Run("default period", func(t *testing.T) {
+		cfg, err := load(`
+certificate_authorities: [ca_test.pem]
+reload.enabled: true
+`)
+		require.NoError(t, err)
+
+		tlsC, err := LoadTLSConfig(cfg)
+		require.NoError(t, err)
+		require.NotNil(t, tlsC.reloader)
+		assert.Equal(t, defaultReloadPeriod, tlsC.reloader.period)
+	})
+}
+
+func TestReloadCertificateAuthorities(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	caPath := filepath.Join(dir, "ca.pem")
+
+	ca1, err := genCA()
+	require.NoError(t, err)
+	ca2, err := genCA()
+	require.NoError(t, err)
+
+	server1 := startTLSServer(t, ca1)
+	defer server1.Close()
+	server2 := startTLSServer(t, ca2)
+	defer server2.Close()
+
+	writeCertPEM(t, caPath, ca1)
+
+	cfg := mustLoadReload(t, map[string]interface{}{
+		"certificate_authorities": []string{caPath},
+	})
+	tlsC, err := LoadTLSConfig(cfg)
+	require.NoError(t, err)
+
+	config := tlsC.BuildModuleConfig("localhost")
+	require.NoError(t, dialTLS(server1.Addr().String(), config))
+	require.Error(t, dialTLS(server2.Addr().String(), config))
+
+	writeCertPEM(t, caPath, ca2)
+	ts := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(caPath, ts, ts))
+
+	// connections are verified against the new CA without rebuilding the config
+	require.Error(t, dialTLS(server1.Addr().String(), config))
+	require.NoError(t, dialTLS(server2.Addr().String(), config))
+}
+
+func TestReloadCertificateAuthoritiesVerifiesServerName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	caPath := filepath.Join(dir, "ca.pem")
+
+	ca, err := genCA()
+	require.NoError(t, err)
+	server := startTLSServer(t, ca)
+	defer server.Close()
+
+	writeCertPEM(t, caPath, ca)
+
+	t.Run("known host", func(t *testing.T) {
+		tlsC, err := LoadTLSConfig(mustLoadReload(t, map[string]interface{}{
+			"certificate_authorities": []string{caPath},
+		}))
+		require.NoError(t, err)
+
+		require.NoError(t, dialTLS(server.Addr().String(), tlsC.BuildModuleConfig("localhost")))
+		require.Error(t, dialTLS(server.Addr().String(), tlsC.BuildModuleConfig("other.example.com")))
+	})
+
+	t.Run("verification mode certificate", func(t *testing.T) {
+		tlsC, err := LoadTLSConfig(mustLoadReload(t, map[string]interface{}{
+			"certificate_authorities": []string{caPath},
+			"verification_mode":       "certificate",
+		}))
+		require.NoError(t, err)
+
+		require.NoError(t, dialTLS(server.Addr().String(), tlsC.BuildModuleConfig("other.example.com")))
+	})
+
+	t.Run("unknown host", func(t *testing.T) {
+		tlsC, err := LoadTLSConfig(mustLoadReload(t, map[string]interface{}{
+			"certificate_authorities": []string{caPath},
+		}))
+		require.NoError(t, err)
+
+		// Without a server name the standard verification is kept, it verifies
+		// the name set on the config when it's used.
+		config := tlsC.ToConfig()
+		assert.False(t, config.InsecureSkipVerify)
+		assert.NotNil(t, config.RootCAs)
+
+		config.ServerName = "other.example.com"
+		require.Error(t, dialTLS(server.Addr().String(), config))
+		config.ServerName = "localhost"
+		require.NoError(t, dialTLS(server.Addr().String(), config))
+	})
+}
+
+func TestReloadKeepsCertificatesOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	caPath := filepath.Join(dir, "ca.pem")
+
+	ca, err := genCA()
+	require.NoError(t, err)
+	server := startTLSServer(t, ca)
+	defer server.Close()
+
+	writeCertPEM(t, caPath, ca)
+
+	cfg := mustLoadReload(t, map[string]interface{}{
+		"certificate_authorities": []string{caPath},
+	})
+	tlsC, err := LoadTLSConfig(cfg)
+	require.NoError(t, err)
+
+	config := tlsC.BuildModuleConfig("localhost")
+	require.NoError(t, dialTLS(server.Addr().String(), config))
+
+	require.NoError(t, ioutil.WriteFile(caPath, []byte("not a certificate"), 0600))
+	ts := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(caPath, ts, ts))
+
+	require.NoError(t, dialTLS(server.Addr().String(), config))
+}
+
+func TestReloadClientCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+
+	ca, err := genCA()
+	require.NoError(t, err)
+	cert1, err := genSignedCert(ca, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+	cert2, err := genSignedCert(ca, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+
+	writeCertPEM(t, certPath, cert1)
+	writeKeyPEM(t, keyPath, cert1)
+
+	cfg := mustLoadReload(t, map[string]interface{}{
+		"certificate": certPath,
+		"key":         keyPath,
+	})
+	tlsC, err := LoadTLSConfig(cfg)
+	require.NoError(t, err)
+
+	config := tlsC.ToConfig()
+	require.Nil(t, config.Certificates)
+	require.NotNil(t, config.GetClientCertificate)
+
+	current, err := config.GetClientCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, cert1.Certificate, current.Certificate)
+
+	writeCertPEM(t, certPath, cert2)
+	writeKeyPEM(t, keyPath, cert2)
+	ts := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(certPath, ts, ts))
+	require.NoError(t, os.Chtimes(keyPath, ts, ts))
+
+	current, err = config.GetClientCertificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, cert2.Certificate, current.Certificate)
+}
+
+func TestReloadServerCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+
+	ca1, err := genCA()
+	require.NoError(t, err)
+	ca2, err := genCA()
+	require.NoError(t, err)
+	cert1, err := genSignedCert(ca1, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+	cert2, err := genSignedCert(ca2, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+
+	writeCertPEM(t, certPath, cert1)
+	writeKeyPEM(t, keyPath, cert1)
+
+	tlsC, err := LoadTLSServerConfig(mustLoadServerReload(t, map[string]interface{}{
+		"certificate": certPath,
+		"key":         keyPath,
+	}))
+	require.NoError(t, err)
+
+	server := serveTLSConfig(t, tlsC.ToConfig())
+	defer server.Close()
+
+	trustCA1 := &tls.Config{ServerName: "localhost", RootCAs: certPool(t, ca1)}
+	trustCA2 := &tls.Config{ServerName: "localhost", RootCAs: certPool(t, ca2)}
+	require.NoError(t, dialTLS(server.Addr().String(), trustCA1))
+	require.Error(t, dialTLS(server.Addr().String(), trustCA2))
+
+	writeCertPEM(t, certPath, cert2)
+	writeKeyPEM(t, keyPath, cert2)
+	ts := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(certPath, ts, ts))
+	require.NoError(t, os.Chtimes(keyPath, ts, ts))
+
+	// new connections get the new certificate without restarting the server
+	require.Error(t, dialTLS(server.Addr().String(), trustCA1))
+	require.NoError(t, dialTLS(server.Addr().String(), trustCA2))
+}
+
+func TestReloadServerClientCertificateAuthorities(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	caPath := filepath.Join(dir, "ca.pem")
+
+	serverCA, err := genCA()
+	require.NoError(t, err)
+	serverCert, err := genSignedCert(serverCA, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	writeCertPEM(t, certPath, serverCert)
+	writeKeyPEM(t, keyPath, serverCert)
+
+	ca1, err := genCA()
+	require.NoError(t, err)
+	ca2, err := genCA()
+	require.NoError(t, err)
+	client1, err := genSignedCert(ca1, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+	client2, err := genSignedCert(ca2, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+
+	writeCertPEM(t, caPath, ca1)
+
+	tlsC, err := LoadTLSServerConfig(mustLoadServerReload(t, map[string]interface{}{
+		"certificate":             certPath,
+		"key":                     keyPath,
+		"certificate_authorities": []string{caPath},
+	}))
+	require.NoError(t, err)
+
+	server := serveTLSConfig(t, tlsC.ToConfig())
+	defer server.Close()
+
+	clientConfig := func(cert tls.Certificate) *tls.Config {
+		return &tls.Config{
+			ServerName:   "localhost",
+			RootCAs:      certPool(t, serverCA),
+			Certificates: []tls.Certificate{cert},
+		}
+	}
+	require.NoError(t, dialTLSRead(server.Addr().String(), clientConfig(client1)))
+	require.Error(t, dialTLSRead(server.Addr().String(), clientConfig(client2)))
+
+	writeCertPEM(t, caPath, ca2)
+	ts := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(caPath, ts, ts))
+
+	// client certificates are verified against the new CA
+	require.Error(t, dialTLSRead(server.Addr().String(), clientConfig(client1)))
+	require.NoError(t, dialTLSRead(server.Addr().String(), clientConfig(client2)))
+}
+
+func mustLoadServerReload(t *testing.T, settings map[string]interface{}) *ServerConfig {
+	settings["reload.enabled"] = true
+	settings["reload.period"] = time.Nanosecond
+
+	config := &ServerConfig{}
+	require.NoError(t, common.MustNewConfigFrom(settings).Unpack(config))
+	return config
+}
+
+func mustLoadReload(t *testing.T, settings map[string]interface{}) *Config {
+	settings["reload.enabled"] = true
+	settings["reload.period"] = time.Nanosecond
+
+	config := &Config{}
+	require.NoError(t, common.MustNewConfigFrom(settings).Unpack(config))
+	return config
+}
+
+func startTLSServer(t *testing.T, ca tls.Certificate) net.Listener {
+	serverCert, err := genSignedCert(ca, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+	return serveTLS(t, serverCert)
+}
+
+func serveTLS(t *testing.T, serverCert tls.Certificate) net.Listener {
+	return serveTLSConfig(t, &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+	})
+}
+
+func serveTLSConfig(t *testing.T, config *tls.Config) net.Listener {
+	l, err := tls.Listen("tcp", "localhost:0", config)
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.(*tls.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+	return l
+}
+
+func dialTLS(addr string, config *tls.Config) error {
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// dialTLSRead connects to addr and waits for the server to close the
+// connection. With TLS 1.3 client certificates are verified after the client
+// completed the handshake, so errors are only reported on the next read.
+func dialTLSRead(addr string, config *tls.Config) error {
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Read(make([]byte, 1))
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func certPool(t *testing.T, ca tls.Certificate) *x509.CertPool {
+	cert, err := x509.ParseCertificate(ca.Certificate[0])
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return pool
+}
+
+func writeCertPEM(t *testing.T, path string, cert tls.Certificate) {
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600))
+}
+
+func writeKeyPEM(t *testing.T, path string, cert tls.Certificate) {
+	key := x509.MarshalPKCS1PrivateKey(cert.PrivateKey.(*rsa.PrivateKey))
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: key}
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600))
+}
@@ -0,0 +1,362 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tlscommon
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+const (
+	defaultOCSPTimeout = 5 * time.Second
+
+	// defaultOCSPCacheTTL is used to cache OCSP responses that don't define
+	// when the next update will be available.
+	defaultOCSPCacheTTL = 1 * time.Hour
+
+	// defaultOCSPCacheSize is the maximum number of cached OCSP responses.
+	defaultOCSPCacheSize = 1000
+
+	// crlCheckInterval is the minimum time between checks for changes to the
+	// CRL files.
+	crlCheckInterval = 1 * time.Minute
+)
+
+// ErrCertificateRevoked is returned when a peer certificate has been revoked by its issuer.
+var ErrCertificateRevoked = errors.New("certificate has been revoked")
+
+// RevocationConfig configures the checking of the revocation status of the client
+// certificates presented to a server.
+type RevocationConfig struct {
+	// CRLs is a list of PEM or DER encoded certificate revocation list files.
+	CRLs []string   `config:"crl" yaml:"crl,omitempty"`
+	OCSP OCSPConfig `config:"ocsp" yaml:"ocsp,omitempty"`
+}
+
+// OCSPConfig configures the checking of certificates against OCSP responders.
+type OCSPConfig struct {
+	Enabled bool `config:"enabled" yaml:"enabled"`
+
+	// Responder overwrites the OCSP responder URLs defined in the certificates.
+	Responder string        `config:"responder" yaml:"responder,omitempty"`
+	Timeout   time.Duration `config:"timeout" yaml:"timeout,omitempty"`
+
+	// SoftFail accepts certificates whose status can not be determined, because
+	// the responder is not reachable or doesn't know the certificate.
+	SoftFail bool `config:"soft_fail" yaml:"soft_fail,omitempty"`
+}
+
+// IsEnabled returns true if any revocation check is configured.
+func (c *RevocationConfig) IsEnabled() bool {
+	return c != nil && (len(c.CRLs) > 0 || c.OCSP.Enabled)
+}
+
+// revocationChecker checks the certificates of verified chains against
+// certificate revocation lists and OCSP responders.
+type revocationChecker struct {
+	log    *logp.Logger
+	config RevocationConfig
+	now    func() time.Time
+	client *http.Client
+
+	mu        sync.Mutex
+	lastCheck time.Time
+	stamps    map[string]fileStamp
+	crls      []*crl
+
+	// responses caches the OCSP responses by issuer and serial number, until
+	// the next update of the responder. At most cacheSize responses are kept.
+	responses map[string]cachedOCSPResponse
+	cacheSize int
+}
+
+type cachedOCSPResponse struct {
+	resp     *ocsp.Response
+	deadline time.Time
+}
+
+// crl is a parsed certificate revocation list with an index of the revoked
+// serial numbers.
+type crl struct {
+	list    *pkix.CertificateList
+	issuer  string
+	revoked map[string]struct{}
+}
+
+func newRevocationChecker(config RevocationConfig) (*revocationChecker, error) {
+	timeout := config.OCSP.Timeout
+	if timeout <= 0 {
+		timeout = defaultOCSPTimeout
+	}
+
+	r := &revocationChecker{
+		log:       logp.NewLogger(logSelector),
+		config:    config,
+		now:       time.Now,
+		client:    &http.Client{Timeout: timeout},
+		responses: map[string]cachedOCSPResponse{},
+		cacheSize: defaultOCSPCacheSize,
+	}
+
+	crls, err := loadCRLs(config.CRLs)
+	if err != nil {
+		return nil, err
+	}
+	r.crls = crls
+	r.stamps = statFiles(config.CRLs)
+	r.lastCheck = r.now()
+	return r, nil
+}
+
+func loadCRLs(files []string) ([]*crl, error) {
+	var crls []*crl
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CRL file %v: %v", file, err)
+		}
+
+		list, err := x509.ParseCRL(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CRL file %v: %v", file, err)
+		}
+
+		c := &crl{
+			list:    list,
+			issuer:  list.TBSCertList.Issuer.String(),
+			revoked: map[string]struct{}{},
+		}
+		for _, revoked := range list.TBSCertList.RevokedCertificates {
+			c.revoked[revoked.SerialNumber.String()] = struct{}{}
+		}
+		crls = append(crls, c)
+	}
+	return crls, nil
+}
+
+// currentCRLs returns the loaded CRLs, reloading the files if they changed.
+// The files are checked at most once per crlCheckInterval, unless force is set.
+func (r *revocationChecker) currentCRLs(force bool) []*crl {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if !force && now.Sub(r.lastCheck) < crlCheckInterval {
+		return r.crls
+	}
+	r.lastCheck = now
+
+	stamps := statFiles(r.config.CRLs)
+	if !stampsChanged(r.stamps, stamps) {
+		return r.crls
+	}
+
+	crls, err := loadCRLs(r.config.CRLs)
+	if err != nil {
+		r.log.Errorf("Failed to reload the CRL files, keeping the previous ones: %v", err)
+		return r.crls
+	}
+	r.log.Info("CRL files changed, reloaded")
+	r.crls = crls
+	r.stamps = stamps
+	return r.crls
+}
+
+// verify checks that no certificate of the verified chains has been revoked.
+func (r *revocationChecker) verify(chains [][]*x509.Certificate) error {
+	for _, chain := range chains {
+		for i := 0; i < len(chain)-1; i++ {
+			cert, issuer := chain[i], chain[i+1]
+			if err := r.verifyCRL(cert, issuer); err != nil {
+				return err
+			}
+			// Only the leaf certificate is checked against OCSP responders, to avoid
+			// multiple requests per handshake.
+			if i == 0 && r.config.OCSP.Enabled {
+				if err := r.verifyOCSP(cert, issuer); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// verifyCRL checks the certificate against the CRLs of its issuer. If a CRL
+// is expired, the files are reloaded, and the certificate is rejected if the
+// CRL is still expired.
+func (r *revocationChecker) verifyCRL(cert, issuer *x509.Certificate) error {
+	err := r.checkCRLs(r.currentCRLs(false), cert, issuer)
+	if err == errCRLExpired {
+		err = r.checkCRLs(r.currentCRLs(true), cert, issuer)
+	}
+	if err == errCRLExpired {
+		return fmt.Errorf("the CRL of issuer '%v' is expired", issuer.Subject)
+	}
+	return err
+}
+
+var errCRLExpired = errors.New("CRL expired")
+
+func (r *revocationChecker) checkCRLs(crls []*crl, cert, issuer *x509.Certificate) error {
+	issuerName := issuer.Subject.String()
+	for _, c := range crls {
+		if c.issuer != issuerName {
+			continue
+		}
+		if err := issuer.CheckCRLSignature(c.list); err != nil {
+			continue
+		}
+		if _, revoked := c.revoked[cert.SerialNumber.String()]; revoked {
+			return errors.Wrapf(ErrCertificateRevoked, "certificate '%v' revoked by CRL", cert.Subject)
+		}
+		if c.list.HasExpired(r.now()) {
+			return errCRLExpired
+		}
+	}
+	return nil
+}
+
+func (r *revocationChecker) verifyOCSP(cert, issuer *x509.Certificate) error {
+	resp, err := r.ocspResponse(cert, issuer)
+	if err != nil {
+		if r.config.OCSP.SoftFail {
+			r.log.Warnf("Failed to check the OCSP status of certificate '%v', accepting it: %v", cert.Subject, err)
+			return nil
+		}
+		return errors.Wrapf(err, "failed to check the OCSP status of certificate '%v'", cert.Subject)
+	}
+
+	switch resp.Status {
+	case ocsp.Good:
+		return nil
+	case ocsp.Revoked:
+		return errors.Wrapf(ErrCertificateRevoked, "certificate '%v' revoked by OCSP responder", cert.Subject)
+	default:
+		if r.config.OCSP.SoftFail {
+			return nil
+		}
+		return fmt.Errorf("OCSP responder doesn't know the status of certificate '%v'", cert.Subject)
+	}
+}
+
+// ocspResponse returns the OCSP response for the certificate. Responses are cached
+// until the responder publishes the next update.
+func (r *revocationChecker) ocspResponse(cert, issuer *x509.Certificate) (*ocsp.Response, error) {
+	key := string(issuer.RawSubjectPublicKeyInfo) + cert.SerialNumber.String()
+	if resp, exists := r.cachedResponse(key); exists {
+		return resp, nil
+	}
+
+	responder := r.config.OCSP.Responder
+	if responder == "" {
+		if len(cert.OCSPServer) == 0 {
+			return nil, errors.New("certificate doesn't define an OCSP responder")
+		}
+		responder = cert.OCSPServer[0]
+	}
+
+	req, err := ocsp.CreateRequest(cert, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := r.client.Post(responder, "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCSP responder %v returned status %v", responder, httpResp.Status)
+	}
+
+	body, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ocsp.ParseResponseForCert(body, cert, issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cacheResponse(key, resp)
+	return resp, nil
+}
+
+// cachedResponse returns the cached OCSP response for key, if it's not expired.
+func (r *revocationChecker) cachedResponse(key string) (*ocsp.Response, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cached, exists := r.responses[key]
+	if !exists {
+		return nil, false
+	}
+	if !r.now().Before(cached.deadline) {
+		delete(r.responses, key)
+		return nil, false
+	}
+	return cached.resp, true
+}
+
+// cacheResponse caches an OCSP response. When the cache is full, the expired
+// responses are removed, then the ones expiring first.
+func (r *revocationChecker) cacheResponse(key string, resp *ocsp.Response) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.responses[key]; !exists && len(r.responses) >= r.cacheSize {
+		now := r.now()
+		for k, cached := range r.responses {
+			if !now.Before(cached.deadline) {
+				delete(r.responses, k)
+			}
+		}
+		for len(r.responses) > 0 && len(r.responses) >= r.cacheSize {
+			var first string
+			var firstDeadline time.Time
+			for k, cached := range r.responses {
+				if first == "" || cached.deadline.Before(firstDeadline) {
+					first, firstDeadline = k, cached.deadline
+				}
+			}
+			delete(r.responses, first)
+		}
+	}
+	r.responses[key] = cachedOCSPResponse{resp: resp, deadline: cacheDeadline(resp)}
+}
+
+func cacheDeadline(resp *ocsp.Response) time.Time {
+	if resp.NextUpdate.IsZero() {
+		return resp.ThisUpdate.Add(defaultOCSPCacheTTL)
+	}
+	return resp.NextUpdate
+}
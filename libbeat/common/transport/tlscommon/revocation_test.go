@@ -0,0 +1,328 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package tlscommon
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestRevocationCRL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-revocation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca, err := genCA()
+	require.NoError(t, err)
+	otherCA, err := genCA()
+	require.NoError(t, err)
+
+	good, err := genSignedCert(ca, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+	revoked, err := genSignedCert(ca, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+
+	caPath := filepath.Join(dir, "ca.pem")
+	writeCertPEM(t, caPath, ca)
+
+	crlPath := filepath.Join(dir, "ca.crl")
+	writeCRL(t, crlPath, ca, revoked.Leaf.SerialNumber)
+
+	// a CRL of another CA revoking the same serial number must not be used
+	otherCRLPath := filepath.Join(dir, "other.crl")
+	writeCRL(t, otherCRLPath, otherCA, good.Leaf.SerialNumber)
+
+	server := startMutualTLSServer(t, dir, map[string]interface{}{
+		"certificate_authorities": []string{caPath},
+		"revocation.crl":          []string{crlPath, otherCRLPath},
+	}, ca)
+	defer server.Close()
+
+	assert.NoError(t, dialWithCertificate(server.Addr().String(), ca, good))
+	assert.Error(t, dialWithCertificate(server.Addr().String(), ca, revoked))
+}
+
+func TestRevocationCRLExpired(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-revocation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca, err := genCA()
+	require.NoError(t, err)
+	good, err := genSignedCert(ca, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+
+	caPath := filepath.Join(dir, "ca.pem")
+	writeCertPEM(t, caPath, ca)
+
+	crlPath := filepath.Join(dir, "ca.crl")
+	writeCRLWithNextUpdate(t, crlPath, ca, time.Now().Add(-time.Second))
+
+	server := startMutualTLSServer(t, dir, map[string]interface{}{
+		"certificate_authorities": []string{caPath},
+		"revocation.crl":          []string{crlPath},
+	}, ca)
+	defer server.Close()
+
+	assert.Error(t, dialWithCertificate(server.Addr().String(), ca, good))
+
+	// the file is reloaded when the CRL is expired, without waiting for the
+	// next periodic check
+	writeCRL(t, crlPath, ca)
+	ts := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(crlPath, ts, ts))
+	assert.NoError(t, dialWithCertificate(server.Addr().String(), ca, good))
+}
+
+func TestRevocationCRLInvalidFile(t *testing.T) {
+	cfg := &ServerConfig{
+		Revocation: RevocationConfig{CRLs: []string{"ca_test.pem"}},
+	}
+	_, err := LoadTLSServerConfig(cfg)
+	assert.Error(t, err)
+}
+
+func TestRevocationOCSP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls-revocation")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca, err := genCA()
+	require.NoError(t, err)
+	good, err := genSignedCert(ca, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+	revoked, err := genSignedCert(ca, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+	unknown, err := genSignedCert(ca, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+
+	var requests int32
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		req, err := ocsp.ParseRequest(body)
+		require.NoError(t, err)
+
+		status := ocsp.Good
+		switch {
+		case req.SerialNumber.Cmp(revoked.Leaf.SerialNumber) == 0:
+			status = ocsp.Revoked
+		case req.SerialNumber.Cmp(unknown.Leaf.SerialNumber) == 0:
+			status = ocsp.Unknown
+		}
+
+		resp, err := ocsp.CreateResponse(ca.Leaf, ca.Leaf, ocsp.Response{
+			Status:       status,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, ca.PrivateKey.(crypto.Signer))
+		require.NoError(t, err)
+		w.Write(resp)
+	}))
+	defer responder.Close()
+
+	caPath := filepath.Join(dir, "ca.pem")
+	writeCertPEM(t, caPath, ca)
+
+	t.Run("responder", func(t *testing.T) {
+		server := startMutualTLSServer(t, dir, map[string]interface{}{
+			"certificate_authorities":   []string{caPath},
+			"revocation.ocsp.enabled":   true,
+			"revocation.ocsp.responder": responder.URL,
+		}, ca)
+		defer server.Close()
+
+		assert.NoError(t, dialWithCertificate(server.Addr().String(), ca, good))
+		assert.Error(t, dialWithCertificate(server.Addr().String(), ca, revoked))
+		assert.Error(t, dialWithCertificate(server.Addr().String(), ca, unknown))
+
+		// the response for the good certificate is cached
+		before := atomic.LoadInt32(&requests)
+		assert.NoError(t, dialWithCertificate(server.Addr().String(), ca, good))
+		assert.Equal(t, before, atomic.LoadInt32(&requests))
+	})
+
+	t.Run("soft fail", func(t *testing.T) {
+		server := startMutualTLSServer(t, dir, map[string]interface{}{
+			"certificate_authorities":   []string{caPath},
+			"revocation.ocsp.enabled":   true,
+			"revocation.ocsp.responder": responder.URL,
+			"revocation.ocsp.soft_fail": true,
+		}, ca)
+		defer server.Close()
+
+		assert.NoError(t, dialWithCertificate(server.Addr().String(), ca, unknown))
+		assert.Error(t, dialWithCertificate(server.Addr().String(), ca, revoked))
+	})
+
+	t.Run("unreachable responder", func(t *testing.T) {
+		server := startMutualTLSServer(t, dir, map[string]interface{}{
+			"certificate_authorities":   []string{caPath},
+			"revocation.ocsp.enabled":   true,
+			"revocation.ocsp.responder": "http://127.0.0.1:1",
+		}, ca)
+		defer server.Close()
+
+		assert.Error(t, dialWithCertificate(server.Addr().String(), ca, good))
+	})
+}
+
+func TestOCSPResponseCache(t *testing.T) {
+	now := time.Now()
+	r := &revocationChecker{
+		now:       func() time.Time { return now },
+		responses: map[string]cachedOCSPResponse{},
+		cacheSize: 2,
+	}
+	response := func(nextUpdate time.Time) *ocsp.Response {
+		return &ocsp.Response{ThisUpdate: now, NextUpdate: nextUpdate}
+	}
+
+	t.Run("expired responses are dropped", func(t *testing.T) {
+		r.cacheResponse("a", response(now.Add(time.Minute)))
+		_, exists := r.cachedResponse("a")
+		assert.True(t, exists)
+
+		now = now.Add(time.Minute)
+		_, exists = r.cachedResponse("a")
+		assert.False(t, exists)
+		assert.Len(t, r.responses, 0)
+	})
+
+	t.Run("size is bounded", func(t *testing.T) {
+		r.cacheResponse("a", response(now.Add(3*time.Minute)))
+		r.cacheResponse("b", response(now.Add(time.Minute)))
+		r.cacheResponse("c", response(now.Add(2*time.Minute)))
+		assert.Len(t, r.responses, 2)
+
+		// the response expiring first is evicted
+		_, exists := r.cachedResponse("b")
+		assert.False(t, exists)
+		_, exists = r.cachedResponse("a")
+		assert.True(t, exists)
+		_, exists = r.cachedResponse("c")
+		assert.True(t, exists)
+
+		// expired responses are evicted before valid ones
+		now = now.Add(2 * time.Minute)
+		r.cacheResponse("d", response(now.Add(time.Minute)))
+		assert.Len(t, r.responses, 2)
+		_, exists = r.cachedResponse("a")
+		assert.True(t, exists)
+		_, exists = r.cachedResponse("d")
+		assert.True(t, exists)
+	})
+}
+
+func writeCRL(t *testing.T, path string, ca tls.Certificate, serials ...*big.Int) {
+	writeCRLWithNextUpdate(t, path, ca, time.Now().Add(time.Hour), serials...)
+}
+
+func writeCRLWithNextUpdate(t *testing.T, path string, ca tls.Certificate, nextUpdate time.Time, serials ...*big.Int) {
+	var revoked []pkix.RevokedCertificate
+	for _, serial := range serials {
+		revoked = append(revoked, pkix.RevokedCertificate{SerialNumber: serial, RevocationTime: time.Now()})
+	}
+
+	der, err := ca.Leaf.CreateCRL(rand.Reader, ca.PrivateKey, revoked, time.Now().Add(-time.Minute), nextUpdate)
+	require.NoError(t, err)
+
+	block := &pem.Block{Type: "X509 CRL", Bytes: der}
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600))
+}
+
+// startMutualTLSServer starts a server requiring client certificates. The server
+// writes a single byte to the client after a successful handshake.
+func startMutualTLSServer(t *testing.T, dir string, settings map[string]interface{}, ca tls.Certificate) net.Listener {
+	serverCert, err := genSignedCert(ca, x509.KeyUsageDigitalSignature, false)
+	require.NoError(t, err)
+
+	settings["certificate"] = filepath.Join(dir, "server.pem")
+	settings["key"] = filepath.Join(dir, "server.key")
+	writeCertPEM(t, filepath.Join(dir, "server.pem"), serverCert)
+	writeKeyPEM(t, filepath.Join(dir, "server.key"), serverCert)
+
+	config := &ServerConfig{}
+	require.NoError(t, common.MustNewConfigFrom(settings).Unpack(config))
+	tlsC, err := LoadTLSServerConfig(config)
+	require.NoError(t, err)
+
+	l, err := tls.Listen("tcp", "localhost:0", tlsC.BuildModuleConfig(""))
+	require.NoError(t, err)
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				if err := conn.(*tls.Conn).Handshake(); err == nil {
+					conn.Write([]byte{1})
+				}
+			}()
+		}
+	}()
+	return l
+}
+
+func dialWithCertificate(addr string, ca, cert tls.Certificate) error {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		RootCAs:      roots,
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{cert},
+	})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// With TLS 1.3 the client certificate is verified after the client handshake
+	// completed, failures are only reported when reading.
+	buf := make([]byte, 1)
+	_, err = conn.Read(buf)
+	return err
+}
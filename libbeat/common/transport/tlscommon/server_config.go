@@ -35,6 +35,8 @@ type ServerConfig struct {
 	Certificate      CertificateConfig   `config:",inline"`
 	CurveTypes       []tlsCurveType      `config:"curve_types"`
 	ClientAuth       tlsClientAuth       `config:"client_authentication"` //`none`, `optional` or `required`
+	Revocation       RevocationConfig    `config:"revocation"`
+	Reload           ReloadConfig        `config:"reload"`
 }
 
 // LoadTLSServerConfig tranforms a ServerConfig into a `tls.Config` to be used directly with golang
@@ -69,6 +71,12 @@ func LoadTLSServerConfig(config *ServerConfig) (*TLSConfig, error) {
 	cas, errs := LoadCertificateAuthorities(config.CAs)
 	logFail(errs...)
 
+	var revocation *revocationChecker
+	if config.Revocation.IsEnabled() {
+		revocation, err = newRevocationChecker(config.Revocation)
+		logFail(err)
+	}
+
 	// fail, if any error occurred when loading certificate files
 	if err = fail.Err(); err != nil {
 		return nil, err
@@ -79,6 +87,12 @@ func LoadTLSServerConfig(config *ServerConfig) (*TLSConfig, error) {
 		certs = []tls.Certificate{*cert}
 	}
 
+	var reloader *certReloader
+	if config.Reload.IsEnabled() {
+		reloader = newCertReloader(config.Reload, config.Certificate, config.CAs, cert, cas)
+		reloader.server = true
+	}
+
 	// return config if no error occurred
 	return &TLSConfig{
 		Versions:         config.Versions,
@@ -88,6 +102,8 @@ func LoadTLSServerConfig(config *ServerConfig) (*TLSConfig, error) {
 		CipherSuites:     cipherSuites,
 		CurvePreferences: curves,
 		ClientAuth:       tls.ClientAuthType(config.ClientAuth),
+		reloader:         reloader,
+		revocation:       revocation,
 	}, nil
 }
 
@@ -70,9 +70,21 @@ type TLSConfig struct {
 	// the server certificate.
 	CASha256 []string
 
+	// PinSha256 is the list of pins of the public key the server certificate must use. It is
+	// checked in every verification mode.
+	PinSha256 []string
+
 	// time returns the current time as the number of seconds since the epoch.
 	// If time is nil, TLS uses time.Now.
 	time func() time.Time
+
+	// reloader is set if the certificate and CA files should be reloaded when
+	// they change on disk.
+	reloader *certReloader
+
+	// revocation is set if the verified peer certificates must be checked
+	// against CRLs or OCSP responders.
+	revocation *revocationChecker
 }
 
 // ToConfig generates a tls.Config object. Note, you must use BuildModuleConfig to generate a config with
@@ -81,6 +93,12 @@ func (c *TLSConfig) ToConfig() *tls.Config {
 	if c == nil {
 		return &tls.Config{}
 	}
+	return c.toConfig("")
+}
+
+// toConfig generates a tls.Config object for connections to serverName. The
+// server name is empty if it's not known when the config is built.
+func (c *TLSConfig) toConfig(serverName string) *tls.Config {
 
 	minVersion, maxVersion := extractMinMaxVersion(c.Versions)
 
@@ -93,7 +111,7 @@ func (c *TLSConfig) ToConfig() *tls.Config {
 		logp.NewLogger("tls").Warn("SSL/TLS verifications disabled.")
 	}
 
-	return &tls.Config{
+	config := &tls.Config{
 		MinVersion:            minVersion,
 		MaxVersion:            maxVersion,
 		Certificates:          c.Certificates,
@@ -107,6 +125,24 @@ func (c *TLSConfig) ToConfig() *tls.Config {
 		VerifyPeerCertificate: verifyPeerCertFn,
 		Time:                  c.time,
 	}
+
+	if c.reloader != nil {
+		c.setupReload(config, serverName)
+	}
+	if len(c.PinSha256) > 0 {
+		appendVerifyPeerCertificate(config, func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyPeerPin(c.PinSha256, rawCerts)
+		})
+	}
+	if c.revocation != nil {
+		appendVerifyPeerCertificate(config, func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+			return c.revocation.verify(verifiedChains)
+		})
+		// The peer certificates are not verified again when sessions are
+		// resumed.
+		config.SessionTicketsDisabled = true
+	}
+	return config
 }
 
 // BuildModuleConfig takes the TLSConfig and transform it into a `tls.Config`.
@@ -116,11 +152,118 @@ func (c *TLSConfig) BuildModuleConfig(host string) *tls.Config {
 		return &tls.Config{ServerName: host}
 	}
 
-	config := c.ToConfig()
+	config := c.toConfig(host)
 	config.ServerName = host
 	return config
 }
 
+// setupReload replaces the static certificates and certificate authorities in
+// config with callbacks that always use the most recently loaded files.
+func (c *TLSConfig) setupReload(config *tls.Config, serverName string) {
+	r := c.reloader
+
+	if r.server {
+		c.setupServerReload(config)
+		return
+	}
+
+	if r.certConfig.Certificate != "" {
+		config.Certificates = nil
+		config.GetClientCertificate = func(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			if cert := r.certificate(); cert != nil {
+				return cert, nil
+			}
+			// no certificate will be sent to the server
+			return &tls.Certificate{}, nil
+		}
+	}
+
+	// tls.Config.RootCAs can not be changed once the config is in use. The
+	// standard chain verification is replaced by a VerifyPeerCertificate
+	// callback that checks the chain against the current certificate
+	// authorities. The callback doesn't know the server name of the
+	// connection, so with full verification the standard verification is
+	// only replaced if the config is built for a known host.
+	if len(r.cas) > 0 && c.Verification != VerifyNone {
+		if c.Verification == VerifyFull && serverName == "" {
+			r.log.Warn("The certificate authorities are not reloaded for connections to hosts not known in advance.")
+			return
+		}
+		config.RootCAs = nil
+		config.InsecureSkipVerify = true
+		config.VerifyPeerCertificate = makeVerifyReloadedChain(c, serverName)
+	}
+}
+
+// setupServerReload makes servers use the most recently loaded certificate and
+// client certificate authorities for each new connection.
+func (c *TLSConfig) setupServerReload(config *tls.Config) {
+	r := c.reloader
+
+	// tls.Config.Certificates and ClientCAs can not be changed once the config
+	// is in use. Instead each handshake uses a copy of the config, updated with
+	// the current files.
+	config.GetConfigForClient = func(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+		connConfig := config.Clone()
+		connConfig.GetConfigForClient = nil
+		if r.certConfig.Certificate != "" {
+			if cert := r.certificate(); cert != nil {
+				connConfig.Certificates = []tls.Certificate{*cert}
+			}
+		}
+		if len(r.cas) > 0 {
+			connConfig.ClientCAs = r.rootCAs()
+		}
+		return connConfig, nil
+	}
+}
+
+// appendVerifyPeerCertificate adds a check to the verification of the peer certificates, after
+// the checks already configured.
+func appendVerifyPeerCertificate(config *tls.Config, verify verifyPeerCertFunc) {
+	prev := config.VerifyPeerCertificate
+	if prev == nil {
+		config.VerifyPeerCertificate = verify
+		return
+	}
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if err := prev(rawCerts, verifiedChains); err != nil {
+			return err
+		}
+		return verify(rawCerts, verifiedChains)
+	}
+}
+
+// rootCAs returns the certificate authorities used to verify server
+// certificates.
+func (c *TLSConfig) rootCAs() *x509.CertPool {
+	if c.reloader != nil && len(c.reloader.cas) > 0 {
+		return c.reloader.rootCAs()
+	}
+	return c.RootCAs
+}
+
+// makeVerifyReloadedChain creates a callback that verifies the certificate
+// chain presented by the server against the current certificate authorities,
+// the server name (depending on the verification mode) and the CA pins.
+func makeVerifyReloadedChain(cfg *TLSConfig, serverName string) verifyPeerCertFunc {
+	if cfg.Verification == VerifyCertificate {
+		serverName = ""
+	}
+
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		_, chains, err := verifyCertificate(rawCerts, cfg, serverName)
+		if err != nil {
+			return err
+		}
+
+		if len(cfg.CASha256) > 0 {
+			return verifyCAPin(cfg.CASha256, chains)
+		}
+		return nil
+	}
+}
+
 // makeVerifyPeerCertificate creates the verification combination of checking certificate pins and skipping host name validation depending on the config
 func makeVerifyPeerCertificate(cfg *TLSConfig) verifyPeerCertFunc {
 	pin := len(cfg.CASha256) > 0
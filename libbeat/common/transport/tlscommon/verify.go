@@ -61,6 +61,17 @@ import (
 func verifyCertificateExceptServerName(
 	rawCerts [][]byte,
 	c *TLSConfig,
+) ([]*x509.Certificate, [][]*x509.Certificate, error) {
+	return verifyCertificate(rawCerts, c, "")
+}
+
+// verifyCertificate parses the certificates presented by the peer, and
+// verifies their chain against the root CAs in c. The server name is only
+// verified if dnsName is not empty.
+func verifyCertificate(
+	rawCerts [][]byte,
+	c *TLSConfig,
+	dnsName string,
 ) ([]*x509.Certificate, [][]*x509.Certificate, error) {
 	// this is where we're a bit suboptimal, as we have to re-parse the certificates that have been presented
 	// during the handshake.
@@ -74,6 +85,18 @@ func verifyCertificateExceptServerName(
 		certs[i] = cert
 	}
 
+	chains, err := verifyPeerChain(certs, c, dnsName)
+	return certs, chains, err
+}
+
+// verifyPeerChain verifies the chain of certificates presented by the peer
+// against the root CAs in c. The server name is only verified if dnsName is
+// not empty.
+func verifyPeerChain(certs []*x509.Certificate, c *TLSConfig, dnsName string) ([][]*x509.Certificate, error) {
+	if len(certs) == 0 {
+		return nil, errors.New("tls: no certificates presented by peer")
+	}
+
 	var t time.Time
 	if c.time != nil {
 		t = c.time()
@@ -81,10 +104,10 @@ func verifyCertificateExceptServerName(
 		t = time.Now()
 	}
 
-	// DNSName omitted in VerifyOptions in order to skip ServerName verification
 	opts := x509.VerifyOptions{
-		Roots:         c.RootCAs,
+		Roots:         c.rootCAs(),
 		CurrentTime:   t,
+		DNSName:       dnsName,
 		Intermediates: x509.NewCertPool(),
 	}
 
@@ -95,6 +118,5 @@ func verifyCertificateExceptServerName(
 	headCert := certs[0]
 
 	// defer to the default verification performed
-	chains, err := headCert.Verify(opts)
-	return certs, chains, err
+	return headCert.Verify(opts)
 }
@@ -32,6 +32,17 @@ type Config struct {
 	OnlyIndex      bool   `config:"only_index"`
 	AlwaysKibana   bool   `config:"always_kibana"`
 	Retry          *Retry `config:"retry"`
+
+	// Spaces are the Kibana spaces the dashboards are imported into. When
+	// empty, the space of the Kibana configuration is used.
+	Spaces []string `config:"spaces"`
+
+	// Tags are applied to the imported saved objects.
+	Tags []string `config:"tags"`
+
+	// Incremental skips the saved objects that were modified in Kibana since
+	// they were imported.
+	Incremental bool `config:"incremental"`
 }
 
 // Retry handles query retries
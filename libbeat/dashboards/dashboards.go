@@ -60,6 +60,25 @@ func ImportDashboards(
 func setupAndImportDashboardsViaKibana(ctx context.Context, hostname string, kibanaConfig *common.Config,
 	dashboardsConfig *Config, msgOutputter MessageOutputter, fields common.MapStr) error {
 
+	if len(dashboardsConfig.Spaces) == 0 {
+		return importDashboardsToSpace(ctx, hostname, kibanaConfig, dashboardsConfig, msgOutputter, fields)
+	}
+
+	for _, space := range dashboardsConfig.Spaces {
+		spaceConfig, err := common.MergeConfigs(kibanaConfig, common.MustNewConfigFrom(common.MapStr{"space.id": space}))
+		if err != nil {
+			return err
+		}
+		if err := importDashboardsToSpace(ctx, hostname, spaceConfig, dashboardsConfig, msgOutputter, fields); err != nil {
+			return errw.Wrapf(err, "fail to import the dashboards in space %s", space)
+		}
+	}
+	return nil
+}
+
+func importDashboardsToSpace(ctx context.Context, hostname string, kibanaConfig *common.Config,
+	dashboardsConfig *Config, msgOutputter MessageOutputter, fields common.MapStr) error {
+
 	kibanaLoader, err := NewKibanaLoader(ctx, kibanaConfig, dashboardsConfig, hostname, msgOutputter)
 	if err != nil {
 		return fmt.Errorf("fail to create the Kibana loader: %v", err)
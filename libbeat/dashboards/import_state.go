@@ -0,0 +1,108 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package dashboards
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/file"
+	"github.com/elastic/beats/v7/libbeat/paths"
+)
+
+// importStateFile is the file in the data path where the versions of the
+// imported saved objects are stored.
+const importStateFile = "kibana_saved_objects.json"
+
+// importState keeps the versions of the saved objects imported in Kibana, to
+// detect the objects modified since they were imported.
+type importState struct {
+	path     string
+	Versions map[string]string `json:"versions"`
+}
+
+func importStatePath() string {
+	return paths.Resolve(paths.Data, importStateFile)
+}
+
+// loadImportState reads the import state from path. A missing file results
+// in an empty state.
+func loadImportState(path string) (*importState, error) {
+	state := &importState{path: path, Versions: map[string]string{}}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to read the state of the imported saved objects: %v", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("fail to decode the state of the imported saved objects %s: %v", path, err)
+	}
+	if state.Versions == nil {
+		state.Versions = map[string]string{}
+	}
+	return state, nil
+}
+
+func stateKey(space, objType, id string) string {
+	if space == "" {
+		space = "default"
+	}
+	return space + "/" + objType + "/" + id
+}
+
+// version returns the version of the saved object when it was imported.
+func (s *importState) version(space, objType, id string) (string, bool) {
+	v, ok := s.Versions[stateKey(space, objType, id)]
+	return v, ok
+}
+
+func (s *importState) setVersion(space, objType, id, version string) {
+	s.Versions[stateKey(space, objType, id)] = version
+}
+
+// save writes the state to disk, replacing the previous file.
+func (s *importState) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0750); err != nil {
+		return err
+	}
+	tmp := s.path + ".new"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return file.SafeFileRotate(s.path, tmp)
+}
+
+// objectVersion returns the version of a saved object as a string.
+func objectVersion(object common.MapStr) string {
+	if v, ok := object["version"]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
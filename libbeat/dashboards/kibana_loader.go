@@ -43,6 +43,13 @@ type KibanaLoader struct {
 	hostname      string
 	msgOutputter  MessageOutputter
 	defaultLogger *logp.Logger
+
+	// space is the Kibana space the objects are imported into.
+	space string
+	// tagIDs are the ids of the tags applied to the imported objects.
+	tagIDs []string
+	// state keeps the versions of the imported objects.
+	state *importState
 }
 
 // NewKibanaLoader creates a new loader to load Kibana files
@@ -69,6 +76,30 @@ func NewKibanaLoader(ctx context.Context, cfg *common.Config, dashboardsConfig *
 	version := client.GetVersion()
 	loader.statusMsg("Initialize the Kibana %s loader", version.String())
 
+	var spaceConfig struct {
+		SpaceID string `config:"space.id"`
+	}
+	if err := cfg.Unpack(&spaceConfig); err != nil {
+		return nil, err
+	}
+	loader.space = spaceConfig.SpaceID
+
+	if len(dashboardsConfig.Tags) > 0 {
+		loader.tagIDs, err = loader.ensureTags(dashboardsConfig.Tags)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	loader.state, err = loadImportState(importStatePath())
+	if err != nil {
+		if dashboardsConfig.Incremental {
+			return nil, err
+		}
+		loader.defaultLogger.Warnf("Ignoring the state of the imported saved objects: %v", err)
+		loader.state = &importState{path: importStatePath(), Versions: map[string]string{}}
+	}
+
 	return &loader, nil
 }
 
@@ -145,7 +176,75 @@ func (loader KibanaLoader) ImportDashboard(file string) error {
 		return fmt.Errorf("fail to replace the hostname in dashboard %s: %v", file, err)
 	}
 
-	return loader.client.ImportJSON(importAPI, params, content)
+	if len(loader.tagIDs) > 0 {
+		addTagReferences(content, loader.tagIDs)
+	}
+
+	if loader.config.Incremental {
+		if err := loader.skipModified(content); err != nil {
+			return fmt.Errorf("fail to check the saved objects of dashboard %s: %v", file, err)
+		}
+		if objects, _ := content["objects"].([]interface{}); len(objects) == 0 {
+			return nil
+		}
+	}
+
+	imported, err := loader.client.ImportJSONObjects(importAPI, params, content)
+	if err != nil {
+		return err
+	}
+	loader.recordVersions(imported)
+	return nil
+}
+
+// skipModified removes from the dashboard content the saved objects that were
+// modified in Kibana since they were imported. Existing objects that were not
+// imported by the Beat are also kept as they are.
+func (loader KibanaLoader) skipModified(content common.MapStr) error {
+	objects, _ := content["objects"].([]interface{})
+	selected := make([]interface{}, 0, len(objects))
+	for _, o := range objects {
+		object, ok := o.(map[string]interface{})
+		if !ok {
+			selected = append(selected, o)
+			continue
+		}
+
+		objType, id := fmt.Sprint(object["type"]), fmt.Sprint(object["id"])
+		existing, err := loader.client.GetSavedObject(objType, id)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			version, imported := loader.state.version(loader.space, objType, id)
+			if !imported || version != objectVersion(existing) {
+				loader.statusMsg("Skipping %s %s, it was modified in Kibana", objType, id)
+				continue
+			}
+		}
+		selected = append(selected, o)
+	}
+	content["objects"] = selected
+	return nil
+}
+
+// recordVersions stores the versions of the imported saved objects.
+func (loader KibanaLoader) recordVersions(objects []common.MapStr) {
+	if len(objects) == 0 {
+		return
+	}
+
+	for _, object := range objects {
+		if _, failed := object["error"]; failed {
+			continue
+		}
+		if version := objectVersion(object); version != "" {
+			loader.state.setVersion(loader.space, fmt.Sprint(object["type"]), fmt.Sprint(object["id"]), version)
+		}
+	}
+	if err := loader.state.save(); err != nil {
+		loader.defaultLogger.Warnf("Failed to save the state of the imported saved objects: %v", err)
+	}
 }
 
 // Close closes the client
@@ -0,0 +1,195 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package dashboards
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/paths"
+)
+
+// fakeKibana implements the saved objects APIs used by the Kibana loader.
+type fakeKibana struct {
+	sync.Mutex
+	// objects are the versions of the saved objects by path.
+	objects  map[string]string
+	imported []map[string]interface{}
+	lastID   int
+}
+
+func (k *fakeKibana) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.Lock()
+	defer k.Unlock()
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/api/status"):
+		w.Write([]byte(`{"version":{"number":"7.10.0"}}`))
+	case strings.HasSuffix(r.URL.Path, "/api/kibana/dashboards/import"):
+		var body struct {
+			Objects []map[string]interface{} `json:"objects"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		var result []common.MapStr
+		for _, o := range body.Objects {
+			k.lastID++
+			version := fmt.Sprintf("v%d", k.lastID)
+			k.objects[k.key(r.URL.Path, o["type"], o["id"])] = version
+			k.imported = append(k.imported, o)
+			result = append(result, common.MapStr{"type": o["type"], "id": o["id"], "version": version})
+		}
+		json.NewEncoder(w).Encode(common.MapStr{"objects": result})
+	case strings.Contains(r.URL.Path, "/api/saved_objects/"):
+		version, exists := k.objects[r.URL.Path]
+		switch {
+		case r.Method == http.MethodGet && exists:
+			json.NewEncoder(w).Encode(common.MapStr{"version": version})
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{}`))
+		case exists:
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{}`))
+		default:
+			k.objects[r.URL.Path] = "v0"
+			w.Write([]byte(`{}`))
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (k *fakeKibana) key(importPath string, objType, id interface{}) string {
+	prefix := strings.TrimSuffix(importPath, "/api/kibana/dashboards/import")
+	return fmt.Sprintf("%s/api/saved_objects/%v/%v", prefix, objType, id)
+}
+
+func (k *fakeKibana) reset() []map[string]interface{} {
+	k.Lock()
+	defer k.Unlock()
+	imported := k.imported
+	k.imported = nil
+	return imported
+}
+
+func TestKibanaLoaderImportDashboard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dashboards")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer func(data string) { paths.Paths.Data = data }(paths.Paths.Data)
+	paths.Paths.Data = dir
+
+	dashboard := filepath.Join(dir, "dashboard.json")
+	require.NoError(t, ioutil.WriteFile(dashboard, []byte(`{
+		"objects": [
+			{"id": "d1", "type": "dashboard", "attributes": {"title": "Dashboard"}, "references": [{"id": "v1", "name": "panel_0", "type": "visualization"}]},
+			{"id": "v1", "type": "visualization", "attributes": {"title": "Visualization"}},
+			{"id": "p1", "type": "index-pattern", "attributes": {"title": "beat-*"}}
+		]
+	}`), 0644))
+
+	kibana := &fakeKibana{objects: map[string]string{}}
+	server := httptest.NewServer(kibana)
+	defer server.Close()
+
+	newLoader := func(t *testing.T, cfg Config) *KibanaLoader {
+		cfg.Retry = defaultConfig.Retry
+		kibanaConfig := common.MustNewConfigFrom(common.MapStr{"host": server.URL, "space.id": "team"})
+		loader, err := NewKibanaLoader(context.Background(), kibanaConfig, &cfg, "", nil)
+		require.NoError(t, err)
+		return loader
+	}
+
+	t.Run("tags", func(t *testing.T) {
+		loader := newLoader(t, Config{Tags: []string{"Team A"}})
+		require.NoError(t, loader.ImportDashboard(dashboard))
+
+		assert.Contains(t, kibana.objects, "/s/team/api/saved_objects/tag/team-a")
+		imported := kibana.reset()
+		require.Len(t, imported, 3)
+		tagRef := map[string]interface{}{"id": "team-a", "name": "tag-ref-team-a", "type": "tag"}
+		assert.Contains(t, imported[0]["references"], tagRef)
+		assert.Contains(t, imported[1]["references"], tagRef)
+		assert.Nil(t, imported[2]["references"])
+	})
+
+	t.Run("incremental", func(t *testing.T) {
+		// The user modifies the visualization after the previous import.
+		kibana.objects["/s/team/api/saved_objects/visualization/v1"] = "modified"
+
+		loader := newLoader(t, Config{Incremental: true})
+		require.NoError(t, loader.ImportDashboard(dashboard))
+
+		var ids []interface{}
+		for _, o := range kibana.reset() {
+			ids = append(ids, o["id"])
+		}
+		assert.Equal(t, []interface{}{"d1", "p1"}, ids)
+
+		// Without incremental updates all the objects are imported.
+		loader = newLoader(t, Config{})
+		require.NoError(t, loader.ImportDashboard(dashboard))
+		assert.Len(t, kibana.reset(), 3)
+
+		// The visualization imported again is updated incrementally.
+		loader = newLoader(t, Config{Incremental: true})
+		require.NoError(t, loader.ImportDashboard(dashboard))
+		assert.Len(t, kibana.reset(), 3)
+	})
+}
+
+func TestAddTagReferences(t *testing.T) {
+	content := common.MapStr{
+		"objects": []interface{}{
+			map[string]interface{}{
+				"id":   "d1",
+				"type": "dashboard",
+				"references": []interface{}{
+					map[string]interface{}{"id": "a", "name": "tag-ref-a", "type": "tag"},
+				},
+			},
+		},
+	}
+
+	addTagReferences(content, []string{"a", "b"})
+	object := content["objects"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"id": "a", "name": "tag-ref-a", "type": "tag"},
+		map[string]interface{}{"id": "b", "name": "tag-ref-b", "type": "tag"},
+	}, object["references"])
+}
+
+func TestTagID(t *testing.T) {
+	assert.Equal(t, "team-a", tagID("Team A"))
+	assert.Equal(t, "prod_eu-1", tagID("prod_eu/1"))
+}
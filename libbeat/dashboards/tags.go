@@ -0,0 +1,116 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package dashboards
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+var (
+	// minTagsVersion is the first version of Kibana with saved object tags.
+	minTagsVersion = common.MustNewVersion("7.10.0")
+
+	defaultTagColor = "#54B399"
+
+	// taggableTypes are the types of saved objects that can be tagged.
+	taggableTypes = map[string]bool{
+		"dashboard":     true,
+		"visualization": true,
+		"search":        true,
+		"lens":          true,
+		"map":           true,
+	}
+)
+
+// tagID returns the id of the saved object of a tag, derived from its name.
+func tagID(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, name)
+}
+
+// ensureTags creates the saved objects of the tags that don't exist yet, and
+// returns the ids of the tags.
+func (loader KibanaLoader) ensureTags(tags []string) ([]string, error) {
+	if loader.version.LessThan(minTagsVersion) {
+		return nil, fmt.Errorf("tags require Kibana %v or newer, found %v", minTagsVersion, loader.version.String())
+	}
+
+	ids := make([]string, 0, len(tags))
+	for _, name := range tags {
+		id := tagID(name)
+		created, err := loader.client.CreateSavedObject("tag", id, common.MapStr{
+			"name":        name,
+			"description": "",
+			"color":       defaultTagColor,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("fail to create tag %s: %v", name, err)
+		}
+		if created {
+			loader.statusMsg("Created tag %s", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// addTagReferences adds references to the given tags to the taggable saved
+// objects of the dashboard content.
+func addTagReferences(content common.MapStr, tagIDs []string) {
+	objects, _ := content["objects"].([]interface{})
+	for _, o := range objects {
+		object, ok := o.(map[string]interface{})
+		if !ok || !taggableTypes[fmt.Sprint(object["type"])] {
+			continue
+		}
+
+		references, _ := object["references"].([]interface{})
+		for _, id := range tagIDs {
+			if hasReference(references, "tag", id) {
+				continue
+			}
+			references = append(references, map[string]interface{}{
+				"type": "tag",
+				"id":   id,
+				"name": "tag-ref-" + id,
+			})
+		}
+		object["references"] = references
+	}
+}
+
+func hasReference(references []interface{}, refType, id string) bool {
+	for _, r := range references {
+		ref, ok := r.(map[string]interface{})
+		if ok && ref["type"] == refType && ref["id"] == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ecs
+
+import (
+	"errors"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// Config declares the ECS version to migrate the fields to.
+type Config struct {
+	// Version is the target ECS version. The fields are not migrated if it is
+	// not set.
+	Version string `config:"version"`
+
+	Migration struct {
+		// Aliases adds alias fields with the names of the target version to
+		// the index template.
+		Aliases bool `config:"aliases"`
+
+		// Rename renames the fields of the events to the names of the target
+		// version.
+		Rename bool `config:"rename"`
+	} `config:"migration"`
+}
+
+// Validate checks that the target version is valid and only one migration
+// mode is enabled.
+func (c *Config) Validate() error {
+	if c.Version != "" {
+		if _, err := common.NewVersion(c.Version); err != nil {
+			return err
+		}
+	}
+	if c.Migration.Aliases && c.Migration.Rename {
+		return errors.New("ECS migration can use aliases or rename the fields, not both")
+	}
+	return nil
+}
+
+// Enabled returns true if a target version different from the one of the
+// Beat is configured.
+func (c Config) Enabled() bool {
+	return c.Version != "" && c.Version != Version
+}
+
+// FromConfig unpacks the ECS settings of the Beat from the root config.
+func FromConfig(root *common.Config) (Config, error) {
+	settings := struct {
+		ECS Config `config:"ecs"`
+	}{}
+	if root != nil {
+		if err := root.Unpack(&settings); err != nil {
+			return Config{}, err
+		}
+	}
+	return settings.ECS, nil
+}
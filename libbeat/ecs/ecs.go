@@ -0,0 +1,55 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package ecs supports running a Beat against a version of the Elastic Common
+// Schema (ECS) different from the one its fields follow. It detects the fields
+// of the Beat that changed between the ECS versions, and can map or rename
+// them to the target version.
+package ecs
+
+import (
+	ecsfields "github.com/elastic/ecs/code/go/ecs"
+)
+
+// Version is the ECS version the fields of the Beats follow.
+const Version = ecsfields.Version
+
+// Change describes a breaking change of a field in an ECS version.
+type Change struct {
+	// Version is the first ECS version with the change.
+	Version string
+
+	// Field is the changed field. If the field is an object, the change
+	// applies to all its fields.
+	Field string
+
+	// Replacement is the field to use instead, if the field was removed.
+	Replacement string
+
+	// Type is the new type of the field, if the type changed.
+	Type string
+}
+
+// changes lists the known breaking changes of ECS fields, ordered by version.
+var changes = []Change{
+	{Version: "8.0.0", Field: "log.original", Replacement: "event.original"},
+	{Version: "8.0.0", Field: "host.user", Replacement: "user"},
+	{Version: "8.0.0", Field: "error.stack_trace", Type: "wildcard"},
+	{Version: "8.0.0", Field: "process.command_line", Type: "wildcard"},
+	{Version: "8.0.0", Field: "url.full", Type: "wildcard"},
+	{Version: "8.0.0", Field: "url.original", Type: "wildcard"},
+}
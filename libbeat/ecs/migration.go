@@ -0,0 +1,264 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ecs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/mapping"
+)
+
+// FieldChange is a change between the ECS version of the Beat and the target
+// version that affects a field of the Beat.
+type FieldChange struct {
+	// Version is the ECS version introducing the change.
+	Version string `json:"version"`
+
+	// Field is the field of the Beat.
+	Field string `json:"field"`
+
+	// Target is the name of the field in the target version, if the field
+	// was removed.
+	Target string `json:"target,omitempty"`
+
+	// Type is the type of the field of the Beat, and TargetType its type in
+	// the target version, if the type changed. TargetType is empty if the
+	// target version precedes the change.
+	Type       string `json:"type,omitempty"`
+	TargetType string `json:"target_type,omitempty"`
+}
+
+func (c FieldChange) String() string {
+	switch {
+	case c.Target != "":
+		return fmt.Sprintf("%s is removed in ECS %s, use %s", c.Field, c.Version, c.Target)
+	case c.TargetType != "":
+		return fmt.Sprintf("type of %s is changed from %s to %s in ECS %s", c.Field, c.Type, c.TargetType, c.Version)
+	default:
+		return fmt.Sprintf("type of %s is %s since ECS %s", c.Field, c.Type, c.Version)
+	}
+}
+
+// Migration holds the changes between the ECS version of the Beat and a target
+// version that affect the fields of the Beat.
+type Migration struct {
+	From    string        `json:"from"`
+	To      string        `json:"to"`
+	Changes []FieldChange `json:"changes"`
+
+	fields  map[string]mapping.Field
+	renames []FieldChange
+}
+
+// NewMigration detects the changes of the fields between the ECS version of
+// the Beat and the target version.
+func NewMigration(target string, fields mapping.Fields) (*Migration, error) {
+	from := common.MustNewVersion(Version)
+	to, err := common.NewVersion(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ECS version '%v': %v", target, err)
+	}
+
+	m := &Migration{From: Version, To: target, fields: map[string]mapping.Field{}}
+	m.index(fields, "")
+	upgrade := from.LessThan(to)
+	for _, c := range changes {
+		v := common.MustNewVersion(c.Version)
+		if upgrade && (!from.LessThan(v) || to.LessThan(v)) {
+			continue
+		}
+		if !upgrade && (!to.LessThan(v) || from.LessThan(v)) {
+			continue
+		}
+		m.Changes = append(m.Changes, m.fieldChanges(c, upgrade)...)
+	}
+
+	for _, c := range m.Changes {
+		if c.Target != "" {
+			m.renames = append(m.renames, c)
+		}
+	}
+	return m, nil
+}
+
+func (m *Migration) fieldChanges(c Change, upgrade bool) []FieldChange {
+	if c.Replacement != "" {
+		// The replacements exist in the versions preceding the removal too.
+		if !upgrade {
+			return nil
+		}
+
+		var result []FieldChange
+		for _, key := range m.keys(c.Field) {
+			result = append(result, FieldChange{
+				Version: c.Version,
+				Field:   key,
+				Target:  c.Replacement + strings.TrimPrefix(key, c.Field),
+			})
+		}
+		return result
+	}
+
+	field, exists := m.fields[c.Field]
+	if !exists {
+		return nil
+	}
+	if upgrade && field.Type != c.Type {
+		return []FieldChange{{Version: c.Version, Field: c.Field, Type: field.Type, TargetType: c.Type}}
+	}
+	if !upgrade && field.Type == c.Type {
+		return []FieldChange{{Version: c.Version, Field: c.Field, Type: field.Type}}
+	}
+	return nil
+}
+
+// index collects the leaf fields by key. Names of fields can contain dots.
+func (m *Migration) index(fields mapping.Fields, namespace string) {
+	for _, f := range fields {
+		key := f.Name
+		if namespace != "" {
+			key = namespace + "." + f.Name
+		}
+		if len(f.Fields) > 0 {
+			m.index(f.Fields, key)
+			continue
+		}
+		m.fields[key] = f
+	}
+}
+
+// keys returns the sorted non-alias keys of the Beat that are the field or
+// belong to it.
+func (m *Migration) keys(field string) []string {
+	var keys []string
+	for key, f := range m.fields {
+		if key != field && !strings.HasPrefix(key, field+".") {
+			continue
+		}
+		if f.Type != "alias" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hasNode returns true if the key is a field or an object of the Beat.
+func (m *Migration) hasNode(key string) bool {
+	for k := range m.fields {
+		if k == key || strings.HasPrefix(k, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Renames returns the changes of fields removed in the target version.
+func (m *Migration) Renames() []FieldChange {
+	return m.renames
+}
+
+// AliasFields returns alias fields with the names of the target version,
+// pointing to the fields of the Beat. Names already used by the Beat are
+// skipped.
+func (m *Migration) AliasFields() mapping.Fields {
+	var fields mapping.Fields
+	for _, c := range m.Renames() {
+		if m.hasNode(c.Target) {
+			continue
+		}
+		fields = append(fields, mapping.Field{
+			Name:        c.Target,
+			Type:        "alias",
+			AliasPath:   c.Field,
+			Description: fmt.Sprintf("Alias of %s for ECS %s.", c.Field, m.To),
+		})
+	}
+	return fields
+}
+
+// TargetFields returns the definitions of the fields of the Beat with the
+// names of the target version, for indexing renamed events. Names already used
+// by the Beat are skipped.
+func (m *Migration) TargetFields() mapping.Fields {
+	var fields mapping.Fields
+	for _, c := range m.Renames() {
+		if m.hasNode(c.Target) {
+			continue
+		}
+		field := m.fields[c.Field]
+		field.Name = c.Target
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// Rename renames the fields of the event to the names of the target version,
+// and sets ecs.version to it. Fields are not renamed if the event already
+// has the target field.
+func (m *Migration) Rename(event *beat.Event) {
+	if event.Fields == nil {
+		return
+	}
+
+	for _, c := range m.renames {
+		value, err := event.Fields.GetValue(c.Field)
+		if err != nil {
+			continue
+		}
+		if exists, _ := event.Fields.HasKey(c.Target); exists {
+			continue
+		}
+		event.Fields.Delete(c.Field)
+		event.Fields.Put(c.Target, value)
+		deleteEmptyParents(event.Fields, c.Field)
+	}
+
+	if v, err := event.Fields.GetValue("ecs.version"); err == nil && v == m.From {
+		event.Fields.Put("ecs.version", m.To)
+	}
+}
+
+func deleteEmptyParents(fields common.MapStr, key string) {
+	for i := strings.LastIndex(key, "."); i > 0; i = strings.LastIndex(key, ".") {
+		key = key[:i]
+		value, err := fields.GetValue(key)
+		if err != nil {
+			return
+		}
+		if obj, ok := tryToMapStr(value); !ok || len(obj) > 0 {
+			return
+		}
+		fields.Delete(key)
+	}
+}
+
+func tryToMapStr(v interface{}) (common.MapStr, bool) {
+	switch m := v.(type) {
+	case common.MapStr:
+		return m, true
+	case map[string]interface{}:
+		return common.MapStr(m), true
+	default:
+		return nil, false
+	}
+}
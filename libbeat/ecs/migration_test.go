@@ -0,0 +1,183 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package ecs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/mapping"
+)
+
+const testFields = `
+- key: ecs
+  title: ECS
+  fields:
+    - name: ecs.version
+      type: keyword
+    - name: log
+      type: group
+      fields:
+        - name: original
+          type: keyword
+    - name: host
+      type: group
+      fields:
+        - name: name
+          type: keyword
+        - name: user
+          type: group
+          fields:
+            - name: name
+              type: keyword
+            - name: id
+              type: keyword
+    - name: user.name
+      type: keyword
+    - name: process.command_line
+      type: keyword
+`
+
+func loadTestFields(t *testing.T) mapping.Fields {
+	fields, err := mapping.LoadFields([]byte(testFields))
+	require.NoError(t, err)
+	return fields
+}
+
+func TestNewMigration(t *testing.T) {
+	fields := loadTestFields(t)
+
+	t.Run("upgrade", func(t *testing.T) {
+		m, err := NewMigration("8.0.0", fields)
+		require.NoError(t, err)
+		assert.Equal(t, []FieldChange{
+			{Version: "8.0.0", Field: "log.original", Target: "event.original"},
+			{Version: "8.0.0", Field: "host.user.id", Target: "user.id"},
+			{Version: "8.0.0", Field: "host.user.name", Target: "user.name"},
+			{Version: "8.0.0", Field: "process.command_line", Type: "keyword", TargetType: "wildcard"},
+		}, m.Changes)
+	})
+
+	t.Run("same major", func(t *testing.T) {
+		m, err := NewMigration("1.9.0", fields)
+		require.NoError(t, err)
+		assert.Empty(t, m.Changes)
+	})
+
+	t.Run("downgrade", func(t *testing.T) {
+		m, err := NewMigration("1.2.0", fields)
+		require.NoError(t, err)
+		assert.Empty(t, m.Changes)
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		_, err := NewMigration("latest", fields)
+		assert.Error(t, err)
+	})
+}
+
+func TestMigration_Fields(t *testing.T) {
+	m, err := NewMigration("8.0.0", loadTestFields(t))
+	require.NoError(t, err)
+
+	aliases := m.AliasFields()
+	require.Len(t, aliases, 2)
+	assert.Equal(t, "event.original", aliases[0].Name)
+	assert.Equal(t, "alias", aliases[0].Type)
+	assert.Equal(t, "log.original", aliases[0].AliasPath)
+	assert.Equal(t, "user.id", aliases[1].Name)
+	assert.Equal(t, "host.user.id", aliases[1].AliasPath)
+
+	targets := m.TargetFields()
+	require.Len(t, targets, 2)
+	assert.Equal(t, "event.original", targets[0].Name)
+	assert.Equal(t, "keyword", targets[0].Type)
+	assert.Equal(t, "user.id", targets[1].Name)
+}
+
+func TestMigration_Rename(t *testing.T) {
+	m, err := NewMigration("8.0.0", loadTestFields(t))
+	require.NoError(t, err)
+
+	event := &beat.Event{Fields: common.MapStr{
+		"ecs": common.MapStr{"version": Version},
+		"log": common.MapStr{"original": "raw"},
+		"host": common.MapStr{
+			"name": "host",
+			"user": common.MapStr{"name": "alice", "id": "1000"},
+		},
+		"user": common.MapStr{"name": "bob"},
+	}}
+	m.Rename(event)
+
+	assert.Equal(t, common.MapStr{
+		"ecs":   common.MapStr{"version": "8.0.0"},
+		"event": common.MapStr{"original": "raw"},
+		"host": common.MapStr{
+			"name": "host",
+			"user": common.MapStr{"name": "alice"},
+		},
+		"user": common.MapStr{"name": "bob", "id": "1000"},
+	}, event.Fields)
+}
+
+func TestConfig(t *testing.T) {
+	cases := map[string]struct {
+		config  map[string]interface{}
+		enabled bool
+		err     bool
+	}{
+		"not configured": {},
+		"same version": {
+			config: map[string]interface{}{"ecs.version": Version},
+		},
+		"target version": {
+			config:  map[string]interface{}{"ecs.version": "8.0.0", "ecs.migration.rename": true},
+			enabled: true,
+		},
+		"invalid version": {
+			config: map[string]interface{}{"ecs.version": "latest"},
+			err:    true,
+		},
+		"aliases and rename": {
+			config: map[string]interface{}{
+				"ecs.version":           "8.0.0",
+				"ecs.migration.aliases": true,
+				"ecs.migration.rename":  true,
+			},
+			err: true,
+		},
+	}
+
+	for name, test := range cases {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			cfg, err := FromConfig(common.MustNewConfigFrom(test.config))
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.enabled, cfg.Enabled())
+		})
+	}
+}
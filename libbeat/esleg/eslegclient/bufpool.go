@@ -0,0 +1,89 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package eslegclient
+
+import (
+	"bytes"
+	"sync"
+)
+
+const (
+	// bufferPoolHistory is the number of recent bulk requests whose sizes are
+	// used to size the buffers of the pool.
+	bufferPoolHistory = 16
+
+	// minBufferSize is the initial capacity of the buffers when there is no
+	// history yet.
+	minBufferSize = 16 * 1024
+)
+
+// bulkBuffers is shared by all connections, so the buffers of idle connections
+// can be reused by the busy ones.
+var bulkBuffers = &bufferPool{}
+
+// bufferPool recycles the buffers the bulk requests are encoded into. New
+// buffers are preallocated with the size of the largest recent request, so they
+// rarely grow while a batch is encoded, and the buffers much larger than the
+// recent requests are dropped, releasing the memory of an unusually large batch.
+type bufferPool struct {
+	mu    sync.Mutex
+	sizes [bufferPoolHistory]int
+	next  int
+	free  []*bytes.Buffer
+}
+
+// get returns an empty buffer.
+func (p *bufferPool) get() *bytes.Buffer {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if n := len(p.free); n > 0 {
+		buf := p.free[n-1]
+		p.free[n-1] = nil
+		p.free = p.free[:n-1]
+		return buf
+	}
+	return bytes.NewBuffer(make([]byte, 0, p.recentSize()))
+}
+
+// put records the size of the request encoded in buf, and keeps buf for reuse
+// unless it's more than twice the size of the recent requests.
+func (p *bufferPool) put(buf *bytes.Buffer) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.sizes[p.next] = buf.Len()
+	p.next = (p.next + 1) % len(p.sizes)
+
+	if buf.Cap() > 2*p.recentSize() {
+		return
+	}
+	buf.Reset()
+	p.free = append(p.free, buf)
+}
+
+// recentSize returns the size of the largest recent request.
+func (p *bufferPool) recentSize() int {
+	size := minBufferSize
+	for _, s := range p.sizes {
+		if s > size {
+			size = s
+		}
+	}
+	return size
+}
@@ -0,0 +1,52 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package eslegclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferPool(t *testing.T) {
+	pool := &bufferPool{}
+
+	buf := pool.get()
+	assert.Equal(t, minBufferSize, buf.Cap())
+
+	// New buffers are sized by the largest recent request.
+	buf.Write(make([]byte, 4*minBufferSize))
+	pool.put(buf)
+	reused := pool.get()
+	assert.Same(t, buf, reused)
+	assert.Equal(t, 0, reused.Len())
+	assert.GreaterOrEqual(t, pool.get().Cap(), 4*minBufferSize)
+
+	// Buffers much larger than the recent requests are dropped once the large
+	// request leaves the history.
+	for i := 0; i < bufferPoolHistory; i++ {
+		small := pool.get()
+		small.Write([]byte("small"))
+		pool.put(small)
+	}
+	large := pool.get()
+	large.Write(make([]byte, 8*minBufferSize))
+	large.Truncate(10)
+	pool.put(large)
+	assert.NotSame(t, large, pool.get())
+}
@@ -26,6 +26,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	"go.elastic.co/apm"
 	"go.elastic.co/apm/module/apmhttp"
@@ -49,6 +50,28 @@ type BulkDeleteAction struct {
 	Delete BulkMeta `json:"delete" struct:"delete"`
 }
 
+// BulkAction is the action of an item of a bulk request.
+type BulkAction uint8
+
+const (
+	BulkActionIndex BulkAction = iota
+	BulkActionCreate
+	BulkActionDelete
+)
+
+func (a BulkAction) String() string {
+	switch a {
+	case BulkActionIndex:
+		return "index"
+	case BulkActionCreate:
+		return "create"
+	case BulkActionDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
 type BulkMeta struct {
 	Index    string `json:"_index" struct:"_index"`
 	DocType  string `json:"_type,omitempty" struct:"_type,omitempty"`
@@ -81,6 +104,47 @@ func (conn *Connection) Bulk(
 		return 0, nil, err
 	}
 
+	return conn.sendBulk(ctx, index, docType, params, enc)
+}
+
+// BulkWith performs a bulk request whose items are encoded by fn directly
+// into the body of the request. fn returns the number of items it added, the
+// request isn't sent if it's 0.
+func (conn *Connection) BulkWith(
+	ctx context.Context,
+	index, docType string,
+	params map[string]string,
+	fn func(BulkItemWriter) int,
+) (int, BulkResult, error) {
+	enc := conn.Encoder
+	enc.Reset()
+
+	var w BulkItemWriter = enc
+	calibrate := conn.calibrator != nil && conn.calibrator.due(time.Now())
+	if calibrate {
+		w = conn.calibrator.sampler(enc)
+	}
+	if fn(w) == 0 {
+		return 0, nil, nil
+	}
+
+	status, result, err := conn.sendBulk(ctx, index, docType, params, enc)
+	if calibrate && err == nil {
+		if level, ok := conn.calibrator.selectLevel(time.Now()); ok {
+			if err := conn.setCompressionLevel(level); err != nil {
+				conn.log.Errorf("Failed to change the compression level to %v: %v", level, err)
+			}
+		}
+	}
+	return status, result, err
+}
+
+func (conn *Connection) sendBulk(
+	ctx context.Context,
+	index, docType string,
+	params map[string]string,
+	enc BodyEncoder,
+) (int, BulkResult, error) {
 	mergedParams := mergeParams(conn.ConnectionSettings.Parameters, params)
 
 	requ, err := newBulkRequest(conn.URL, index, docType, mergedParams, enc)
@@ -90,7 +154,18 @@ func (conn *Connection) Bulk(
 	}
 	requ.requ = apmhttp.RequestWithContext(ctx, requ.requ)
 
-	return conn.sendBulkRequest(requ)
+	start := time.Now()
+	status, result, err := conn.sendBulkRequest(requ)
+	if conn.calibrator != nil && err == nil {
+		conn.calibrator.observe(requ.requ.ContentLength, time.Since(start))
+	}
+
+	// The transport might still read the body after a failed request, so the
+	// buffer is only returned to the pool if the request has completed.
+	if r, ok := enc.(bufferReleaser); ok {
+		r.releaseBuffer(err == nil)
+	}
+	return status, result, err
 }
 
 func newBulkRequest(
@@ -0,0 +1,204 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package eslegclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+// AutoCompressionConfig configures the selection of the compression level of
+// the bulk requests from measurements of the compression cost and of the
+// throughput of the link to Elasticsearch.
+type AutoCompressionConfig struct {
+	Enabled bool `config:"enabled"`
+
+	// Interval between two selections. The level is selected once after
+	// the first bulk request if it is 0.
+	Interval time.Duration `config:"interval" validate:"min=0"`
+
+	// Levels are the gzip levels the selection chooses from, 0 disables the
+	// compression. All levels are considered if empty.
+	Levels []int `config:"levels"`
+}
+
+// Validate checks that all levels are valid gzip levels.
+func (c *AutoCompressionConfig) Validate() error {
+	for _, level := range c.Levels {
+		if level < 0 || level > 9 {
+			return fmt.Errorf("invalid compression level %v, must be between 0 and 9", level)
+		}
+	}
+	return nil
+}
+
+// maxCalibrationSample limits the size of the bulk body compressed with every
+// level when selecting the compression level.
+const maxCalibrationSample = 1024 * 1024
+
+var allCompressionLevels = []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+// compressionCalibrator selects the compression level that maximizes the
+// number of events sent per second. Every interval the body of a bulk request
+// is sampled and compressed with every level, and the level with the lowest
+// total cost, the compression time plus the time required to send the
+// compressed body, is chosen. The time to send a body is estimated from the
+// duration of the bulk requests sent since the last selection, so the
+// estimation includes the time Elasticsearch takes to process the requests,
+// and favours stronger compression on slow clusters.
+type compressionCalibrator struct {
+	log      *logp.Logger
+	levels   []int
+	interval time.Duration
+
+	// next selection, no selection is pending if zero
+	next time.Time
+
+	// bytes sent and time spent sending them since the last selection
+	sentBytes int64
+	sendTime  time.Duration
+
+	sample     *jsonEncoder
+	compressed bytes.Buffer
+	gzip       map[int]*gzip.Writer
+}
+
+func newCompressionCalibrator(config AutoCompressionConfig, settings ConnectionSettings) *compressionCalibrator {
+	levels := config.Levels
+	if len(levels) == 0 {
+		levels = allCompressionLevels
+	}
+
+	return &compressionCalibrator{
+		log:      logp.NewLogger("esclientleg"),
+		levels:   levels,
+		interval: config.Interval,
+		next:     time.Now(),
+		sample:   newJSONEncoder(nil, nil, settings.EscapeHTML, settings.TimestampPrecision),
+		gzip:     map[int]*gzip.Writer{},
+	}
+}
+
+// due reports if the next bulk request must be sampled.
+func (c *compressionCalibrator) due(now time.Time) bool {
+	return !c.next.IsZero() && !now.Before(c.next)
+}
+
+// sampler returns a BulkItemWriter adding the bulk items to w and to the
+// sample of the calibrator.
+func (c *compressionCalibrator) sampler(w BulkItemWriter) BulkItemWriter {
+	c.sample.Reset()
+	return &sampleWriter{BulkItemWriter: w, sample: c.sample}
+}
+
+// observe records that a request body of size bytes has been sent in d.
+func (c *compressionCalibrator) observe(size int64, d time.Duration) {
+	if size <= 0 {
+		return
+	}
+	c.sentBytes += size
+	c.sendTime += d
+}
+
+// selectLevel returns the compression level with the highest throughput for
+// the current sample, or ok=false if no request has been observed yet.
+func (c *compressionCalibrator) selectLevel(now time.Time) (level int, ok bool) {
+	sample := c.sample.buf.Bytes()
+	if c.sendTime <= 0 || len(sample) == 0 {
+		return 0, false
+	}
+	bytesPerSecond := float64(c.sentBytes) / c.sendTime.Seconds()
+
+	bestCost := -1.0
+	for _, l := range c.levels {
+		size, elapsed, err := c.compress(l, sample)
+		if err != nil {
+			c.log.Errorf("Failed to compress the sample with level %v: %v", l, err)
+			continue
+		}
+
+		cost := elapsed.Seconds() + float64(size)/bytesPerSecond
+		c.log.Debugf("Compression level %v: %d bytes compressed to %d in %v, estimated cost %.6fs",
+			l, len(sample), size, elapsed, cost)
+		if bestCost < 0 || cost < bestCost {
+			bestCost, level = cost, l
+		}
+	}
+	if bestCost < 0 {
+		return 0, false
+	}
+
+	c.sentBytes, c.sendTime = 0, 0
+	c.sample.Reset()
+	if c.interval > 0 {
+		c.next = now.Add(c.interval)
+	} else {
+		c.next = time.Time{}
+	}
+	return level, true
+}
+
+func (c *compressionCalibrator) compress(level int, sample []byte) (int, time.Duration, error) {
+	if level == 0 {
+		return len(sample), 0, nil
+	}
+
+	start := time.Now()
+	c.compressed.Reset()
+	w := c.gzip[level]
+	if w == nil {
+		var err error
+		if w, err = gzip.NewWriterLevel(&c.compressed, level); err != nil {
+			return 0, 0, err
+		}
+		c.gzip[level] = w
+	} else {
+		w.Reset(&c.compressed)
+	}
+
+	if _, err := w.Write(sample); err != nil {
+		return 0, 0, err
+	}
+	if err := w.Close(); err != nil {
+		return 0, 0, err
+	}
+	return c.compressed.Len(), time.Since(start), nil
+}
+
+// sampleWriter copies the bulk items added to a bulk request into the sample
+// of the calibrator, up to maxCalibrationSample bytes.
+type sampleWriter struct {
+	BulkItemWriter
+	sample *jsonEncoder
+}
+
+func (w *sampleWriter) AddItem(action BulkAction, meta BulkMeta, event *beat.Event) error {
+	if err := w.BulkItemWriter.AddItem(action, meta, event); err != nil {
+		return err
+	}
+	if w.sample.buf.Len() < maxCalibrationSample {
+		// the sample is best effort, items failing to encode are skipped
+		w.sample.AddItem(action, meta, event)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build !integration
+
+package eslegclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+func TestAutoCompressionConfigValidate(t *testing.T) {
+	assert.NoError(t, (&AutoCompressionConfig{Levels: []int{0, 5, 9}}).Validate())
+	assert.Error(t, (&AutoCompressionConfig{Levels: []int{10}}).Validate())
+	assert.Error(t, (&AutoCompressionConfig{Levels: []int{-1}}).Validate())
+}
+
+func TestCompressionCalibratorSelectLevel(t *testing.T) {
+	logp.TestingSetup(logp.WithSelectors("elasticsearch"))
+
+	newCalibrator := func() *compressionCalibrator {
+		c := newCompressionCalibrator(AutoCompressionConfig{Levels: []int{0, 1, 9}}, ConnectionSettings{})
+		w := c.sampler(newJSONEncoder(nil, nil, false, common.TimestampPrecision(0)))
+		for i := 0; i < 1000; i++ {
+			err := w.AddItem(BulkActionIndex, BulkMeta{Index: "test"}, &beat.Event{
+				Timestamp: time.Unix(0, 0),
+				Fields:    common.MapStr{"message": "the same message compresses very well"},
+			})
+			require.NoError(t, err)
+		}
+		return c
+	}
+
+	t.Run("nothing observed", func(t *testing.T) {
+		c := newCalibrator()
+		_, ok := c.selectLevel(time.Now())
+		assert.False(t, ok)
+	})
+
+	t.Run("fast link disables the compression", func(t *testing.T) {
+		c := newCalibrator()
+		c.observe(1024*1024*1024, time.Microsecond)
+		level, ok := c.selectLevel(time.Now())
+		require.True(t, ok)
+		assert.Equal(t, 0, level)
+	})
+
+	t.Run("slow link compresses", func(t *testing.T) {
+		c := newCalibrator()
+		c.observe(1024, time.Second)
+		level, ok := c.selectLevel(time.Now())
+		require.True(t, ok)
+		assert.NotEqual(t, 0, level)
+	})
+}
+
+func TestCompressionCalibratorSchedule(t *testing.T) {
+	logp.TestingSetup(logp.WithSelectors("elasticsearch"))
+
+	sample := func(c *compressionCalibrator) {
+		w := c.sampler(newJSONEncoder(nil, nil, false, common.TimestampPrecision(0)))
+		w.AddItem(BulkActionIndex, BulkMeta{Index: "test"}, &beat.Event{Fields: common.MapStr{"a": 1}})
+		c.observe(100, time.Millisecond)
+	}
+
+	t.Run("with interval", func(t *testing.T) {
+		c := newCompressionCalibrator(AutoCompressionConfig{Interval: time.Minute}, ConnectionSettings{})
+		now := time.Now()
+		assert.True(t, c.due(now))
+
+		sample(c)
+		_, ok := c.selectLevel(now)
+		require.True(t, ok)
+		assert.False(t, c.due(now.Add(30*time.Second)))
+		assert.True(t, c.due(now.Add(time.Minute)))
+	})
+
+	t.Run("without interval", func(t *testing.T) {
+		c := newCompressionCalibrator(AutoCompressionConfig{}, ConnectionSettings{})
+		now := time.Now()
+		assert.True(t, c.due(now))
+
+		sample(c)
+		_, ok := c.selectLevel(now)
+		require.True(t, ok)
+		assert.False(t, c.due(now.Add(24*time.Hour)))
+	})
+}
+
+func TestBulkWithAutoCompression(t *testing.T) {
+	logp.TestingSetup(logp.WithSelectors("elasticsearch"))
+
+	server := ElasticsearchMock(200, []byte(`{"took":7,"errors":false,"items":[{"index":{"status":201}}]}`))
+	defer server.Close()
+
+	conn, err := NewConnection(ConnectionSettings{
+		URL: server.URL,
+		AutoCompression: AutoCompressionConfig{
+			Enabled: true,
+			Levels:  []int{5},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, conn.CompressionLevel)
+
+	_, _, err = conn.BulkWith(context.Background(), "test", "", nil, func(w BulkItemWriter) int {
+		w.AddItem(BulkActionIndex, BulkMeta{}, &beat.Event{Fields: common.MapStr{"message": "test"}})
+		return 1
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, conn.CompressionLevel)
+	assert.IsType(t, &gzipEncoder{}, conn.Encoder)
+	assert.False(t, conn.calibrator.due(time.Now()))
+}
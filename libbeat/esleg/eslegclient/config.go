@@ -23,6 +23,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/transport/kerberos"
+	"github.com/elastic/beats/v7/libbeat/common/transport/oauth2"
 	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
 )
 
@@ -35,9 +36,11 @@ type config struct {
 
 	TLS      *tlscommon.Config `config:"ssl"`
 	Kerberos *kerberos.Config  `config:"kerberos"`
+	OAuth2   *oauth2.Config    `config:"auth.oauth2"`
 
-	ProxyURL     string `config:"proxy_url"`
-	ProxyDisable bool   `config:"proxy_disable"`
+	ProxyURL          string `config:"proxy_url"`
+	ProxyDisable      bool   `config:"proxy_disable"`
+	ProxyLocalResolve bool   `config:"proxy_use_local_resolver"`
 
 	Username string `config:"username"`
 	Password string `config:"password"`
@@ -76,5 +79,9 @@ func (c *config) Validate() error {
 		return fmt.Errorf("cannot set both api_key and username/password")
 	}
 
+	if c.OAuth2.IsEnabled() && (c.APIKey != "" || c.Username != "" || c.Kerberos.IsEnabled()) {
+		return fmt.Errorf("cannot set auth.oauth2 together with api_key, username/password or kerberos")
+	}
+
 	return nil
 }
@@ -32,6 +32,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/transport"
 	"github.com/elastic/beats/v7/libbeat/common/transport/kerberos"
+	"github.com/elastic/beats/v7/libbeat/common/transport/oauth2"
 	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/testing"
@@ -52,6 +53,9 @@ type Connection struct {
 	apiKeyAuthHeader string // Authorization HTTP request header with base64-encoded API key
 	version          common.Version
 	log              *logp.Logger
+
+	// calibrator selects the compression level if auto compression is enabled.
+	calibrator *compressionCalibrator
 }
 
 // ConnectionSettings are the settings needed for a Connection
@@ -60,6 +64,10 @@ type ConnectionSettings struct {
 	Proxy        *url.URL
 	ProxyDisable bool
 
+	// ProxyLocalResolve resolves the names of the hosts locally instead of
+	// on the server of a SOCKS5 proxy.
+	ProxyLocalResolve bool
+
 	Username string
 	Password string
 	APIKey   string // Raw API key, NOT base64-encoded
@@ -67,6 +75,7 @@ type ConnectionSettings struct {
 
 	TLS      *tlscommon.TLSConfig
 	Kerberos *kerberos.Config
+	OAuth2   *oauth2.Config
 
 	OnConnectCallback func() error
 	Observer          transport.IOStatser
@@ -75,6 +84,14 @@ type ConnectionSettings struct {
 	CompressionLevel int
 	EscapeHTML       bool
 
+	// AutoCompression enables the selection of the compression level, the
+	// CompressionLevel is used until the first selection.
+	AutoCompression AutoCompressionConfig
+
+	// TimestampPrecision is the number of fractional digits of the timestamps
+	// of the events.
+	TimestampPrecision common.TimestampPrecision
+
 	Timeout         time.Duration
 	IdleConnTimeout time.Duration
 }
@@ -98,10 +115,24 @@ func NewConnection(s ConnectionSettings) (*Connection, error) {
 	}
 	logp.Info("elasticsearch url: %s", s.URL)
 
-	// TODO: add socks5 proxy support
 	var dialer, tlsDialer transport.Dialer
 
 	dialer = transport.NetDialer(s.Timeout)
+
+	// HTTP proxies are handled by the HTTP transport, SOCKS5 proxies are
+	// dialed like by the other network outputs, so TLS is established with
+	// Elasticsearch through the tunnel.
+	socksProxy := !s.ProxyDisable && s.Proxy != nil && isSOCKSProxy(s.Proxy)
+	if socksProxy {
+		dialer, err = transport.ProxyDialer(logp.NewLogger("esclientleg"), &transport.ProxyConfig{
+			URL:          s.Proxy.String(),
+			LocalResolve: s.ProxyLocalResolve,
+		}, dialer)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	tlsDialer, err = transport.TLSDialer(dialer, s.TLS, s.Timeout)
 	if err != nil {
 		return nil, err
@@ -112,19 +143,13 @@ func NewConnection(s ConnectionSettings) (*Connection, error) {
 		tlsDialer = transport.StatsDialer(tlsDialer, st)
 	}
 
-	var encoder BodyEncoder
-	compression := s.CompressionLevel
-	if compression == 0 {
-		encoder = NewJSONEncoder(nil, s.EscapeHTML)
-	} else {
-		encoder, err = NewGzipEncoder(compression, nil, s.EscapeHTML)
-		if err != nil {
-			return nil, err
-		}
+	encoder, err := newBodyEncoder(s, s.CompressionLevel)
+	if err != nil {
+		return nil, err
 	}
 
 	var proxy func(*http.Request) (*url.URL, error)
-	if !s.ProxyDisable {
+	if !s.ProxyDisable && !socksProxy {
 		proxy = http.ProxyFromEnvironment
 		if s.Proxy != nil {
 			proxy = http.ProxyURL(s.Proxy)
@@ -159,6 +184,14 @@ func NewConnection(s ConnectionSettings) (*Connection, error) {
 		logp.Info("kerberos client created")
 	}
 
+	if s.OAuth2.IsEnabled() {
+		httpClient = oauth2.NewClient(s.OAuth2, &http.Client{
+			Transport: transp,
+			Timeout:   s.Timeout,
+		})
+		logp.Info("oauth2 client created")
+	}
+
 	conn := Connection{
 		ConnectionSettings: s,
 		HTTP:               httpClient,
@@ -169,10 +202,44 @@ func NewConnection(s ConnectionSettings) (*Connection, error) {
 	if s.APIKey != "" {
 		conn.apiKeyAuthHeader = "ApiKey " + base64.StdEncoding.EncodeToString([]byte(s.APIKey))
 	}
+	if s.AutoCompression.Enabled {
+		conn.calibrator = newCompressionCalibrator(s.AutoCompression, s)
+	}
 
 	return &conn, nil
 }
 
+// isSOCKSProxy checks if the proxy is a SOCKS5 server.
+func isSOCKSProxy(u *url.URL) bool {
+	return u.Scheme == "socks5" || u.Scheme == "socks5h"
+}
+
+// newBodyEncoder creates the encoder of the bulk requests for the compression
+// level.
+func newBodyEncoder(s ConnectionSettings, level int) (BodyEncoder, error) {
+	if level == 0 {
+		return newJSONEncoder(nil, bulkBuffers, s.EscapeHTML, s.TimestampPrecision), nil
+	}
+	return newGzipEncoder(level, nil, bulkBuffers, s.EscapeHTML, s.TimestampPrecision)
+}
+
+// setCompressionLevel replaces the encoder of the bulk requests with one using
+// level.
+func (conn *Connection) setCompressionLevel(level int) error {
+	if level == conn.CompressionLevel {
+		return nil
+	}
+
+	encoder, err := newBodyEncoder(conn.ConnectionSettings, level)
+	if err != nil {
+		return err
+	}
+	conn.log.Infof("Compression level of the bulk requests changed from %v to %v", conn.CompressionLevel, level)
+	conn.Encoder = encoder
+	conn.CompressionLevel = level
+	return nil
+}
+
 func settingsWithDefaults(s ConnectionSettings) ConnectionSettings {
 	settings := s
 	if settings.IdleConnTimeout == 0 {
@@ -222,18 +289,20 @@ func NewClients(cfg *common.Config) ([]Connection, error) {
 		}
 
 		client, err := NewConnection(ConnectionSettings{
-			URL:              esURL,
-			Proxy:            proxyURL,
-			ProxyDisable:     config.ProxyDisable,
-			TLS:              tlsConfig,
-			Kerberos:         config.Kerberos,
-			Username:         config.Username,
-			Password:         config.Password,
-			APIKey:           config.APIKey,
-			Parameters:       params,
-			Headers:          config.Headers,
-			Timeout:          config.Timeout,
-			CompressionLevel: config.CompressionLevel,
+			URL:               esURL,
+			Proxy:             proxyURL,
+			ProxyDisable:      config.ProxyDisable,
+			ProxyLocalResolve: config.ProxyLocalResolve,
+			TLS:               tlsConfig,
+			Kerberos:          config.Kerberos,
+			OAuth2:            config.OAuth2,
+			Username:          config.Username,
+			Password:          config.Password,
+			APIKey:            config.APIKey,
+			Parameters:        params,
+			Headers:           config.Headers,
+			Timeout:           config.Timeout,
+			CompressionLevel:  config.CompressionLevel,
 		})
 		if err != nil {
 			return clients, err
@@ -20,10 +20,16 @@ package eslegclient
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/base64"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
+	socks5 "github.com/armon/go-socks5"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -64,3 +70,60 @@ func (c *mockClient) CloseIdleConnections() {}
 func newMockClient() *mockClient {
 	return &mockClient{}
 }
+
+// recordingResolver resolves all names to the loopback address, recording
+// them.
+type recordingResolver struct {
+	names []string
+}
+
+func (r *recordingResolver) Resolve(ctx context.Context, name string) (context.Context, net.IP, error) {
+	r.names = append(r.names, name)
+	return ctx, net.IPv4(127, 0, 0, 1), nil
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"version":{"number":"7.10.0"}}`))
+	}))
+	defer server.Close()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	resolver := &recordingResolver{}
+	proxyServer, err := socks5.New(&socks5.Config{Resolver: resolver})
+	require.NoError(t, err)
+	l, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go proxyServer.Serve(l)
+
+	proxyURL, err := url.Parse("socks5://" + l.Addr().String())
+	require.NoError(t, err)
+
+	// The name of the host can only be resolved by the proxy.
+	conn, err := NewConnection(ConnectionSettings{
+		URL:   "http://elasticsearch.invalid:" + port,
+		Proxy: proxyURL,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	version, err := conn.Ping()
+	require.NoError(t, err)
+	assert.Equal(t, "7.10.0", version)
+	assert.Equal(t, []string{"elasticsearch.invalid"}, resolver.names)
+
+	// With the local resolver the proxy gets the address.
+	conn, err = NewConnection(ConnectionSettings{
+		URL:               "http://localhost:" + port,
+		Proxy:             proxyURL,
+		ProxyLocalResolve: true,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Ping()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"elasticsearch.invalid"}, resolver.names)
+}
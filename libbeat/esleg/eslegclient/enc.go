@@ -23,6 +23,7 @@ import (
 	"io"
 	"net/http"
 	"time"
+	"unicode/utf8"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
@@ -39,6 +40,7 @@ type BodyEncoder interface {
 
 type bulkBodyEncoder interface {
 	BulkWriter
+	BulkItemWriter
 
 	AddHeader(*http.Header)
 	Reset()
@@ -49,19 +51,49 @@ type BulkWriter interface {
 	AddRaw(raw interface{}) error
 }
 
+// BulkItemWriter encodes the items of a bulk request directly into the body of
+// the request.
+type BulkItemWriter interface {
+	// AddItem encodes the action and metadata of an item, followed by the
+	// event unless the action is a delete. Nothing is added if the encoding
+	// fails.
+	AddItem(action BulkAction, meta BulkMeta, event *beat.Event) error
+}
+
+// bufferReleaser is implemented by the encoders whose buffers come from a
+// pool, to release the buffer once the request has been sent. The buffer is
+// returned to the pool if reuse is set, the next request gets a new buffer in
+// any case.
+type bufferReleaser interface {
+	releaseBuffer(reuse bool)
+}
+
 type jsonEncoder struct {
 	buf    *bytes.Buffer
+	out    bufferWriter
+	pool   *bufferPool
 	folder *gotype.Iterator
 
+	// ev is reused to fold the events of the bulk items without allocating.
+	ev event
+
 	escapeHTML bool
+	precision  common.TimestampPrecision
 }
 
 type gzipEncoder struct {
 	buf    *bytes.Buffer
+	pool   *bufferPool
 	gzip   *gzip.Writer
 	folder *gotype.Iterator
 
+	// item buffers the encoding of a value, so nothing is compressed when the
+	// encoding fails.
+	item bytes.Buffer
+	ev   event
+
 	escapeHTML bool
+	precision  common.TimestampPrecision
 }
 
 type event struct {
@@ -69,28 +101,71 @@ type event struct {
 	Fields    common.MapStr `struct:",inline"`
 }
 
+// bufferWriter writes to the current buffer of an encoder, so the buffer can be
+// replaced without creating a new visitor.
+type bufferWriter struct {
+	buf *bytes.Buffer
+}
+
+func (w *bufferWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
 func NewJSONEncoder(buf *bytes.Buffer, escapeHTML bool) *jsonEncoder {
+	return newJSONEncoder(buf, nil, escapeHTML, common.TimestampMillisecond)
+}
+
+// newJSONEncoder creates a JSON encoder writing to buf, or to the buffers of
+// pool if buf is nil and pool is set.
+func newJSONEncoder(buf *bytes.Buffer, pool *bufferPool, escapeHTML bool, precision common.TimestampPrecision) *jsonEncoder {
+	e := &jsonEncoder{pool: pool, escapeHTML: escapeHTML, precision: precision}
 	if buf == nil {
-		buf = bytes.NewBuffer(nil)
+		buf = e.newBuffer()
 	}
-	e := &jsonEncoder{buf: buf, escapeHTML: escapeHTML}
+	e.setBuffer(buf)
 	e.resetState()
 	return e
 }
 
+func (b *jsonEncoder) newBuffer() *bytes.Buffer {
+	if b.pool != nil {
+		return b.pool.get()
+	}
+	return bytes.NewBuffer(nil)
+}
+
+func (b *jsonEncoder) setBuffer(buf *bytes.Buffer) {
+	b.buf = buf
+	b.out.buf = buf
+}
+
 func (b *jsonEncoder) Reset() {
+	if b.buf == nil {
+		b.setBuffer(b.newBuffer())
+		return
+	}
 	b.buf.Reset()
 }
 
+func (b *jsonEncoder) releaseBuffer(reuse bool) {
+	if b.pool == nil || b.buf == nil {
+		return
+	}
+	if reuse {
+		b.pool.put(b.buf)
+	}
+	b.setBuffer(nil)
+}
+
 func (b *jsonEncoder) resetState() {
 	var err error
-	visitor := json.NewVisitor(b.buf)
+	visitor := json.NewVisitor(&b.out)
 	visitor.SetEscapeHTML(b.escapeHTML)
 
 	b.folder, err = gotype.NewIterator(visitor,
 		gotype.Folders(
-			codec.MakeTimestampEncoder(),
-			codec.MakeBCTimestampEncoder()))
+			codec.MakeTimestampEncoderWithPrecision(false, b.precision),
+			codec.MakeBCTimestampEncoderWithPrecision(b.precision)))
 	if err != nil {
 		panic(err)
 	}
@@ -142,39 +217,85 @@ func (b *jsonEncoder) Add(meta, obj interface{}) error {
 	return nil
 }
 
+func (b *jsonEncoder) AddItem(action BulkAction, meta BulkMeta, event *beat.Event) error {
+	pos := b.buf.Len()
+	writeBulkMeta(b.buf, action, &meta)
+	if event == nil {
+		return nil
+	}
+
+	b.ev.Timestamp, b.ev.Fields = event.Timestamp, event.Fields
+	err := b.folder.Fold(&b.ev)
+	b.ev.Fields = nil
+	if err != nil {
+		b.resetState()
+		b.buf.Truncate(pos)
+		return err
+	}
+	b.buf.WriteByte('\n')
+	return nil
+}
+
 func NewGzipEncoder(level int, buf *bytes.Buffer, escapeHTML bool) (*gzipEncoder, error) {
+	return newGzipEncoder(level, buf, nil, escapeHTML, common.TimestampMillisecond)
+}
+
+// newGzipEncoder creates a gzip encoder writing to buf, or to the buffers of
+// pool if buf is nil and pool is set.
+func newGzipEncoder(level int, buf *bytes.Buffer, pool *bufferPool, escapeHTML bool, precision common.TimestampPrecision) (*gzipEncoder, error) {
+	g := &gzipEncoder{pool: pool, escapeHTML: escapeHTML, precision: precision}
 	if buf == nil {
-		buf = bytes.NewBuffer(nil)
+		buf = g.newBuffer()
 	}
 	w, err := gzip.NewWriterLevel(buf, level)
 	if err != nil {
 		return nil, err
 	}
 
-	g := &gzipEncoder{buf: buf, gzip: w, escapeHTML: escapeHTML}
+	g.buf, g.gzip = buf, w
 	g.resetState()
 	return g, nil
 }
 
+func (g *gzipEncoder) newBuffer() *bytes.Buffer {
+	if g.pool != nil {
+		return g.pool.get()
+	}
+	return bytes.NewBuffer(nil)
+}
+
 func (g *gzipEncoder) resetState() {
 	var err error
-	visitor := json.NewVisitor(g.gzip)
+	visitor := json.NewVisitor(&g.item)
 	visitor.SetEscapeHTML(g.escapeHTML)
 
 	g.folder, err = gotype.NewIterator(visitor,
 		gotype.Folders(
-			codec.MakeTimestampEncoder(),
-			codec.MakeBCTimestampEncoder()))
+			codec.MakeTimestampEncoderWithPrecision(false, g.precision),
+			codec.MakeBCTimestampEncoderWithPrecision(g.precision)))
 	if err != nil {
 		panic(err)
 	}
 }
 
 func (b *gzipEncoder) Reset() {
+	if b.buf == nil {
+		b.buf = b.newBuffer()
+	}
 	b.buf.Reset()
 	b.gzip.Reset(b.buf)
 }
 
+func (b *gzipEncoder) releaseBuffer(reuse bool) {
+	if b.pool == nil || b.buf == nil {
+		return
+	}
+	if reuse {
+		b.pool.put(b.buf)
+	}
+	b.buf = nil
+}
+
 func (b *gzipEncoder) Reader() io.Reader {
 	b.gzip.Close()
 	return b.buf
@@ -190,9 +311,33 @@ func (b *gzipEncoder) Marshal(obj interface{}) error {
 	return b.AddRaw(obj)
 }
 
-var nl = []byte("\n")
-
 func (b *gzipEncoder) AddRaw(obj interface{}) error {
+	b.item.Reset()
+	if err := b.foldItem(obj); err != nil {
+		return err
+	}
+	_, err := b.gzip.Write(b.item.Bytes())
+	return err
+}
+
+func (b *gzipEncoder) Add(meta, obj interface{}) error {
+	b.item.Reset()
+	if err := b.foldItem(meta); err != nil {
+		return err
+	}
+	if err := b.foldItem(obj); err != nil {
+		return err
+	}
+	if _, err := b.gzip.Write(b.item.Bytes()); err != nil {
+		return err
+	}
+
+	b.gzip.Flush()
+	return nil
+}
+
+// foldItem appends the encoding of obj to the item buffer.
+func (b *gzipEncoder) foldItem(obj interface{}) error {
 	var err error
 	switch v := obj.(type) {
 	case beat.Event:
@@ -205,27 +350,98 @@ func (b *gzipEncoder) AddRaw(obj interface{}) error {
 
 	if err != nil {
 		b.resetState()
+		return err
 	}
 
-	_, err = b.gzip.Write(nl)
-	if err != nil {
-		b.resetState()
+	b.item.WriteByte('\n')
+	return nil
+}
+
+func (b *gzipEncoder) AddItem(action BulkAction, meta BulkMeta, event *beat.Event) error {
+	b.item.Reset()
+	writeBulkMeta(&b.item, action, &meta)
+	if event != nil {
+		b.ev.Timestamp, b.ev.Fields = event.Timestamp, event.Fields
+		err := b.folder.Fold(&b.ev)
+		b.ev.Fields = nil
+		if err != nil {
+			b.resetState()
+			return err
+		}
+		b.item.WriteByte('\n')
 	}
 
-	return nil
+	_, err := b.gzip.Write(b.item.Bytes())
+	return err
 }
 
-func (b *gzipEncoder) Add(meta, obj interface{}) error {
-	pos := b.buf.Len()
-	if err := b.AddRaw(meta); err != nil {
-		b.buf.Truncate(pos)
-		return err
+// writeBulkMeta writes the action line of a bulk item, with the same encoding
+// as the BulkIndexAction, BulkCreateAction and BulkDeleteAction types.
+func writeBulkMeta(buf *bytes.Buffer, action BulkAction, meta *BulkMeta) {
+	buf.WriteString(`{"`)
+	buf.WriteString(action.String())
+	buf.WriteString(`":{"_index":`)
+	writeJSONString(buf, meta.Index)
+	if meta.DocType != "" {
+		buf.WriteString(`,"_type":`)
+		writeJSONString(buf, meta.DocType)
 	}
-	if err := b.AddRaw(obj); err != nil {
-		b.buf.Truncate(pos)
-		return err
+	if meta.Pipeline != "" {
+		buf.WriteString(`,"pipeline":`)
+		writeJSONString(buf, meta.Pipeline)
 	}
+	if meta.ID != "" {
+		buf.WriteString(`,"_id":`)
+		writeJSONString(buf, meta.ID)
+	}
+	buf.WriteString("}}\n")
+}
 
-	b.gzip.Flush()
-	return nil
+const hexDigits = "0123456789abcdef"
+
+// writeJSONString writes s as a quoted JSON string, replacing invalid UTF-8
+// with the replacement character.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c >= utf8.RuneSelf {
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				buf.WriteString(s[start:i])
+				buf.WriteString("\ufffd")
+				i += size
+				start = i
+				continue
+			}
+			i += size
+			continue
+		}
+		if c >= 0x20 && c != '"' && c != '\\' {
+			i++
+			continue
+		}
+
+		buf.WriteString(s[start:i])
+		switch c {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hexDigits[c>>4])
+			buf.WriteByte(hexDigits[c&0xf])
+		}
+		i++
+		start = i
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
 }
@@ -18,10 +18,13 @@
 package eslegclient
 
 import (
+	"compress/gzip"
+	"io/ioutil"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
@@ -45,6 +48,23 @@ func TestJSONEncoderMarshalBeatEvent(t *testing.T) {
 		"Unexpected marshaled format of beat.Event")
 }
 
+func TestJSONEncoderMarshalNanosecondPrecision(t *testing.T) {
+	encoder := newJSONEncoder(nil, nil, true, common.TimestampNanosecond)
+	event := beat.Event{
+		Timestamp: time.Date(2017, time.November, 7, 12, 0, 0, 123456789, time.UTC),
+		Fields: common.MapStr{
+			"field1": "value1",
+		},
+	}
+
+	err := encoder.Marshal(event)
+	if err != nil {
+		t.Errorf("Error while marshaling beat.Event using JSONEncoder: %v", err)
+	}
+	assert.Equal(t, "{\"@timestamp\":\"2017-11-07T12:00:00.123456789Z\",\"field1\":\"value1\"}\n", encoder.buf.String(),
+		"Unexpected marshaled format of beat.Event")
+}
+
 func TestJSONEncoderMarshalMonitoringEvent(t *testing.T) {
 	encoder := NewJSONEncoder(nil, true)
 	event := report.Event{
@@ -61,3 +81,107 @@ func TestJSONEncoderMarshalMonitoringEvent(t *testing.T) {
 	assert.Equal(t, encoder.buf.String(), "{\"timestamp\":\"2017-11-07T12:00:00.000Z\",\"field1\":\"value1\"}\n",
 		"Unexpected marshaled format of report.Event")
 }
+
+func TestEncoderAddItem(t *testing.T) {
+	ts := time.Date(2017, time.November, 7, 12, 0, 0, 0, time.UTC)
+	event := &beat.Event{Timestamp: ts, Fields: common.MapStr{"message": "hello"}}
+
+	items := []struct {
+		action BulkAction
+		meta   BulkMeta
+		event  *beat.Event
+	}{
+		{BulkActionIndex, BulkMeta{Index: "test"}, event},
+		{BulkActionCreate, BulkMeta{Index: "test", DocType: "doc", Pipeline: "p", ID: "1"}, event},
+		{BulkActionDelete, BulkMeta{Index: "test", ID: "2"}, nil},
+		{BulkActionIndex, BulkMeta{Index: "te\"st\n\x01", ID: "\xff"}, event},
+		{BulkActionIndex, BulkMeta{Index: "test"}, &beat.Event{Timestamp: ts, Fields: common.MapStr{"invalid": func() {}}}},
+		{BulkActionIndex, BulkMeta{Index: "test"}, event},
+	}
+	expected := `{"index":{"_index":"test"}}
+{"@timestamp":"2017-11-07T12:00:00.000Z","message":"hello"}
+{"create":{"_index":"test","_type":"doc","pipeline":"p","_id":"1"}}
+{"@timestamp":"2017-11-07T12:00:00.000Z","message":"hello"}
+{"delete":{"_index":"test","_id":"2"}}
+{"index":{"_index":"te\"st\n\u0001","_id":"�"}}
+{"@timestamp":"2017-11-07T12:00:00.000Z","message":"hello"}
+{"index":{"_index":"test"}}
+{"@timestamp":"2017-11-07T12:00:00.000Z","message":"hello"}
+`
+
+	gzipEncoder, err := NewGzipEncoder(gzip.DefaultCompression, nil, false)
+	require.NoError(t, err)
+	encoders := map[string]BodyEncoder{
+		"json": NewJSONEncoder(nil, false),
+		"gzip": gzipEncoder,
+	}
+
+	for name, enc := range encoders {
+		t.Run(name, func(t *testing.T) {
+			enc.Reset()
+			for i, item := range items {
+				err := enc.AddItem(item.action, item.meta, item.event)
+				if i == 4 {
+					assert.Error(t, err)
+				} else {
+					assert.NoError(t, err)
+				}
+			}
+
+			body := enc.Reader()
+			if name == "gzip" {
+				body, err = gzip.NewReader(body)
+				require.NoError(t, err)
+			}
+			actual, err := ioutil.ReadAll(body)
+			require.NoError(t, err)
+			assert.Equal(t, expected, string(actual))
+		})
+	}
+}
+
+func TestEncoderPooledBuffer(t *testing.T) {
+	pool := &bufferPool{}
+	enc := newJSONEncoder(nil, pool, false, common.TimestampMillisecond)
+
+	enc.Reset()
+	require.NoError(t, enc.AddItem(BulkActionDelete, BulkMeta{Index: "test", ID: "1"}, nil))
+	buf := enc.buf
+
+	enc.releaseBuffer(true)
+	assert.Nil(t, enc.buf)
+
+	// The released buffer is reused by the next request.
+	enc.Reset()
+	assert.Same(t, buf, enc.buf)
+	assert.Equal(t, 0, enc.buf.Len())
+
+	// A buffer released after a failed request isn't reused.
+	enc.releaseBuffer(false)
+	enc.Reset()
+	assert.NotSame(t, buf, enc.buf)
+}
+
+func BenchmarkJSONEncoderAddItem(b *testing.B) {
+	enc := newJSONEncoder(nil, &bufferPool{}, false, common.TimestampMillisecond)
+	event := &beat.Event{
+		Timestamp: time.Now(),
+		Fields: common.MapStr{
+			"message": "hello world",
+			"host":    common.MapStr{"name": "localhost"},
+		},
+	}
+	meta := BulkMeta{Index: "test", Pipeline: "pipeline"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if i%1000 == 0 {
+			enc.releaseBuffer(true)
+			enc.Reset()
+		}
+		if err := enc.AddItem(BulkActionCreate, meta, event); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package idxmgmt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/template"
+)
+
+// The data stream of an event is named after the data_stream.type,
+// data_stream.dataset and data_stream.namespace fields of the event. These
+// defaults are used for the fields missing in the event.
+const (
+	defaultDataStreamType      = "logs"
+	defaultDataStreamDataset   = "generic"
+	defaultDataStreamNamespace = "default"
+)
+
+// dataStreamSelector selects the data stream of the events, named
+// type-dataset-namespace after the data stream fields of the event.
+type dataStreamSelector struct{}
+
+// dataStreamsEnabled returns true if the data stream mode is enabled in the
+// configuration of the Elasticsearch output.
+func dataStreamsEnabled(cfg *common.Config) (bool, error) {
+	if cfg == nil || !cfg.HasField("data_streams") {
+		return false, nil
+	}
+	return cfg.Bool("data_streams", -1)
+}
+
+// checkDataStreamOutputSettings refuses the settings of the Elasticsearch
+// output selecting indices, the data streams are selected by the fields of
+// the events.
+func checkDataStreamOutputSettings(out *common.Config) error {
+	for _, key := range []string{"index", "indices"} {
+		if out.HasField(key) {
+			return fmt.Errorf("%v can not be used with data_streams, the data streams are selected by the data_stream fields of the events", out.PathOf(key))
+		}
+	}
+	return nil
+}
+
+// checkDataStreamSettings refuses the settings of the template and of ILM
+// that would break writing to data streams.
+func checkDataStreamSettings(tmpl, ilmCfg, out *common.Config) error {
+	if err := checkDataStreamOutputSettings(out); err != nil {
+		return err
+	}
+
+	if tmpl != nil {
+		var tmplCfg struct {
+			Type       *string `config:"type"`
+			DataStream *bool   `config:"data_stream"`
+		}
+		if err := tmpl.Unpack(&tmplCfg); err != nil {
+			return err
+		}
+		if tmplCfg.Type != nil && *tmplCfg.Type != "index" {
+			return errors.New("data_streams requires setup.template.type to be index")
+		}
+		if tmplCfg.DataStream != nil && !*tmplCfg.DataStream {
+			return errors.New("data_streams can not be used with setup.template.data_stream disabled")
+		}
+	}
+
+	if ilmCfg != nil && ilmCfg.HasField("rollover_alias") {
+		return errors.New("setup.ilm.rollover_alias can not be used with data_streams, data streams are rolled over without an alias")
+	}
+	return nil
+}
+
+// dataStreamTemplateConfig configures the template to be a composable index
+// template for data streams. Unless a pattern is configured, the template
+// applies to the data streams of the default type.
+func dataStreamTemplateConfig(tmpl template.TemplateConfig, info beat.Info) template.TemplateConfig {
+	tmpl.Type = template.IndexTemplateIndex
+	tmpl.DataStream = true
+	if tmpl.Name == "" {
+		// The templates of the datasets are named after the template.
+		tmpl.Name = fmt.Sprintf("%s-%s", info.IndexPrefix, info.Version)
+	}
+	if tmpl.Pattern == "" {
+		tmpl.Pattern = defaultDataStreamType + "-*-*"
+	}
+	return tmpl
+}
+
+// applyDataStreamILMSettings sets the ILM policy of the data streams. Unlike
+// applyILMSettings, the name and the pattern of the template are not changed,
+// the data streams are not named after the rollover alias.
+func applyDataStreamILMSettings(tmpl template.TemplateConfig, policy string) (template.TemplateConfig, error) {
+	if !tmpl.Enabled {
+		return tmpl, nil
+	}
+
+	idxSettings := make(map[string]interface{}, len(tmpl.Settings.Index)+1)
+	for k, v := range tmpl.Settings.Index {
+		idxSettings[k] = v
+	}
+
+	lifecycle := map[string]interface{}{}
+	if ifcLifecycle := idxSettings["lifecycle"]; ifcLifecycle != nil {
+		tmp, ok := ifcLifecycle.(map[string]interface{})
+		if !ok {
+			return tmpl, errors.New("settings.index.lifecycle must be an object")
+		}
+		for k, v := range tmp {
+			lifecycle[k] = v
+		}
+	}
+	if _, exists := lifecycle["name"]; !exists {
+		lifecycle["name"] = policy
+	}
+	idxSettings["lifecycle"] = lifecycle
+	tmpl.Settings.Index = idxSettings
+	return tmpl, nil
+}
+
+func (dataStreamSelector) Select(evt *beat.Event) (string, error) {
+	return fmt.Sprintf("%s-%s-%s",
+		dataStreamField(evt, "type", defaultDataStreamType),
+		dataStreamField(evt, "dataset", defaultDataStreamDataset),
+		dataStreamField(evt, "namespace", defaultDataStreamNamespace),
+	), nil
+}
+
+func dataStreamField(evt *beat.Event, name, fallback string) string {
+	v, err := evt.GetValue("data_stream." + name)
+	if err != nil {
+		return fallback
+	}
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return fallback
+	}
+	return strings.ToLower(s)
+}
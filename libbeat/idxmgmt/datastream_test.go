@@ -0,0 +1,127 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package idxmgmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/idxmgmt/ilm"
+	"github.com/elastic/beats/v7/libbeat/template"
+)
+
+func TestDataStreamSettingsConflicts(t *testing.T) {
+	cases := map[string]common.MapStr{
+		"index":                        {"output.elasticsearch.index": "test-%{+yyyy.MM.dd}"},
+		"indices":                      {"output.elasticsearch.indices": []common.MapStr{{"index": "test"}}},
+		"legacy template":              {"setup.template.type": "legacy"},
+		"template without data stream": {"setup.template.data_stream": false},
+		"rollover alias":               {"setup.ilm.rollover_alias": "test"},
+	}
+
+	info := beat.Info{Beat: "test", Version: "9.9.9"}
+	for name, settings := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := common.MustNewConfigFrom(common.MapStr{"output.elasticsearch.data_streams": true})
+			require.NoError(t, cfg.Merge(settings))
+
+			_, err := MakeDefaultSupport(ilm.StdSupport)(nil, info, cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDataStreamSelector(t *testing.T) {
+	info := beat.Info{Beat: "test", Version: "9.9.9"}
+	support, err := MakeDefaultSupport(ilm.StdSupport)(nil, info, common.NewConfig())
+	require.NoError(t, err)
+
+	selector, err := support.BuildSelector(common.MustNewConfigFrom(common.MapStr{"data_streams": true}))
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		fields common.MapStr
+		want   string
+	}{
+		"defaults": {
+			want: "logs-generic-default",
+		},
+		"all fields": {
+			fields: common.MapStr{"data_stream": common.MapStr{"type": "metrics", "dataset": "system.cpu", "namespace": "prod"}},
+			want:   "metrics-system.cpu-prod",
+		},
+		"lowercase": {
+			fields: common.MapStr{"data_stream": common.MapStr{"dataset": "Nginx.Access"}},
+			want:   "logs-nginx.access-default",
+		},
+		"invalid field type": {
+			fields: common.MapStr{"data_stream": common.MapStr{"namespace": 1}},
+			want:   "logs-generic-default",
+		},
+	}
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			index, err := selector.Select(&beat.Event{Fields: test.fields})
+			require.NoError(t, err)
+			assert.Equal(t, test.want, index)
+		})
+	}
+}
+
+func TestIndexManager_SetupDataStreams(t *testing.T) {
+	info := beat.Info{Beat: "test", IndexPrefix: "test", Version: "9.9.9"}
+	factory := MakeDefaultSupport(ilm.StdSupport)
+	im, err := factory(nil, info, common.MustNewConfigFrom(common.MapStr{
+		"output.elasticsearch.data_streams": true,
+		"setup.ilm.datasets": []common.MapStr{
+			{"dataset": "nginx.access", "delete.min_age": "7d"},
+		},
+	}))
+	require.NoError(t, err)
+
+	clientHandler := newMockClientHandler()
+	manager := im.Manager(clientHandler, BeatsAssets([]byte("testbeat fields")))
+	err = manager.Setup(LoadModeEnabled, LoadModeEnabled)
+	require.NoError(t, err)
+	clientHandler.assertInvariants(t)
+
+	// data streams are created on the first write, no alias is created
+	assert.Equal(t, "", clientHandler.alias)
+	assert.Equal(t, "test-nginx.access", clientHandler.policy)
+
+	require.Len(t, clientHandler.templates, 2)
+	tmpl := clientHandler.templates[0]
+	assert.Equal(t, template.IndexTemplateIndex, tmpl.Type)
+	assert.True(t, tmpl.DataStream)
+	assert.Equal(t, "test-9.9.9", tmpl.Name)
+	assert.Equal(t, "logs-*-*", tmpl.Pattern)
+	assert.Equal(t, map[string]interface{}{
+		"lifecycle": map[string]interface{}{"name": "test"},
+	}, tmpl.Settings.Index)
+
+	dsCfg := clientHandler.templates[1]
+	assert.Equal(t, "test-9.9.9-nginx.access", dsCfg.Name)
+	assert.Equal(t, "*-nginx.access-*", dsCfg.Pattern)
+	assert.Equal(t, map[string]interface{}{
+		"lifecycle": map[string]interface{}{"name": "test-nginx.access"},
+	}, dsCfg.Settings.Index)
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/ecs"
 	"github.com/elastic/beats/v7/libbeat/idxmgmt/ilm"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/outputs"
@@ -112,6 +113,7 @@ func MakeDefaultSupport(ilmSupport ilm.SupportFactory) SupportFactory {
 			Template  *common.Config         `config:"setup.template"`
 			Output    common.ConfigNamespace `config:"output"`
 			Migration *common.Config         `config:"migration.6_to_7"`
+			ECS       ecs.Config             `config:"ecs"`
 		}{}
 		if configRoot != nil {
 			if err := configRoot.Unpack(&cfg); err != nil {
@@ -125,11 +127,33 @@ func MakeDefaultSupport(ilmSupport ilm.SupportFactory) SupportFactory {
 			log = log.Named(logName)
 		}
 
-		if err := checkTemplateESSettings(cfg.Template, cfg.Output); err != nil {
+		var dataStreams bool
+		if cfg.Output.Name() == "elasticsearch" {
+			var err error
+			dataStreams, err = dataStreamsEnabled(cfg.Output.Config())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if dataStreams {
+			if err := checkDataStreamSettings(cfg.Template, cfg.ILM, cfg.Output.Config()); err != nil {
+				return nil, err
+			}
+		} else if err := checkTemplateESSettings(cfg.Template, cfg.Output); err != nil {
 			return nil, err
 		}
 
-		return newIndexSupport(log, info, ilmSupport, cfg.Template, cfg.ILM, cfg.Migration.Enabled())
+		support, err := newIndexSupport(log, info, ilmSupport, cfg.Template, cfg.ILM, cfg.Migration.Enabled())
+		if err != nil {
+			return nil, err
+		}
+		support.ecs = cfg.ECS
+		if dataStreams {
+			support.dataStreams = true
+			support.templateCfg = dataStreamTemplateConfig(support.templateCfg, info)
+		}
+		return support, nil
 	}
 }
 
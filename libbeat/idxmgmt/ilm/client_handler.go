@@ -34,6 +34,7 @@ type ClientHandler interface {
 	CreateAlias(alias Alias) error
 
 	HasILMPolicy(name string) (bool, error)
+	GetILMPolicy(name string) (body common.MapStr, exists bool, err error)
 	CreateILMPolicy(policy Policy) error
 }
 
@@ -150,6 +151,32 @@ func (h *ESClientHandler) HasILMPolicy(name string) (bool, error) {
 	return status == 200, nil
 }
 
+// GetILMPolicy queries Elasticsearch for the policy with the given name. It
+// returns the policy body in the format used for creating the policy.
+func (h *ESClientHandler) GetILMPolicy(name string) (common.MapStr, bool, error) {
+	path := path.Join(esILMPath, name)
+	status, b, err := h.client.Request("GET", path, "", nil, nil)
+	if status == 404 {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, wrapErrf(err, ErrRequestFailed,
+			"failed to get policy '%v': (status=%v) %s", name, status, b)
+	}
+
+	var response map[string]struct {
+		Policy common.MapStr `json:"policy"`
+	}
+	if err := json.Unmarshal(b, &response); err != nil {
+		return nil, false, wrapErrf(err, ErrInvalidResponse, "failed to parse JSON response")
+	}
+	stored, exists := response[name]
+	if !exists {
+		return nil, false, nil
+	}
+	return common.MapStr{"policy": stored.Policy}, true, nil
+}
+
 // HasAlias queries Elasticsearch to see if alias exists. If other resource
 // with the same name exists, it returns an error.
 func (h *ESClientHandler) HasAlias(name string) (bool, error) {
@@ -275,6 +302,11 @@ func (h *FileClientHandler) HasILMPolicy(name string) (bool, error) {
 	return false, nil
 }
 
+// GetILMPolicy always reports the policy as missing.
+func (h *FileClientHandler) GetILMPolicy(name string) (common.MapStr, bool, error) {
+	return nil, false, nil
+}
+
 // CreateAlias is a noop implementation.
 func (h *FileClientHandler) CreateAlias(alias Alias) error {
 	return nil
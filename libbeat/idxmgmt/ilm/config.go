@@ -18,6 +18,7 @@
 package ilm
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -35,6 +36,14 @@ type Config struct {
 	RolloverAlias fmtstr.EventFormatString `config:"rollover_alias"`
 	Pattern       string                   `config:"pattern"`
 
+	// Policy configures the phases of the generated policy. It is ignored if
+	// PolicyFile is set.
+	Policy PolicyConfig `config:"policy"`
+
+	// Datasets configures additional policies for single datasets. Settings
+	// not configured for a dataset are taken from Policy.
+	Datasets []*common.Config `config:"datasets"`
+
 	// CheckExists can disable the check for an existing policy. Check required
 	// read_ilm privileges.  If check is disabled the policy will only be
 	// installed if Overwrite is enabled.
@@ -44,6 +53,33 @@ type Config struct {
 	Overwrite bool `config:"overwrite"`
 }
 
+// PolicyConfig configures the phases of a generated policy.
+type PolicyConfig struct {
+	Rollover RolloverConfig `config:"rollover"`
+	Delete   DeleteConfig   `config:"delete"`
+}
+
+// RolloverConfig configures the rollover conditions of the hot phase. A
+// condition is disabled if it is not set.
+type RolloverConfig struct {
+	MaxSize string `config:"max_size"`
+	MaxAge  string `config:"max_age"`
+	MaxDocs int64  `config:"max_docs" validate:"min=0"`
+}
+
+// DeleteConfig configures the delete phase. The phase is disabled if MinAge is
+// not set.
+type DeleteConfig struct {
+	MinAge string `config:"min_age"`
+}
+
+// DatasetConfig configures the policy of a single dataset.
+type DatasetConfig struct {
+	Dataset    string       `config:"dataset" validate:"required"`
+	PolicyName string       `config:"policy_name"`
+	Policy     PolicyConfig `config:",inline"`
+}
+
 //Mode is used for enumerating the ilm mode.
 type Mode uint8
 
@@ -64,19 +100,58 @@ const ilmDefaultPattern = "{now/d}-000001"
 // configured.
 // By default the policy contains not warm, cold, or delete phase.
 // The index is configured to rollover every 50GB or after 30d.
-var DefaultPolicy = common.MapStr{
-	"policy": common.MapStr{
-		"phases": common.MapStr{
-			"hot": common.MapStr{
-				"actions": common.MapStr{
-					"rollover": common.MapStr{
-						"max_size": "50gb",
-						"max_age":  "30d",
-					},
-				},
+var DefaultPolicy = defaultPolicyConfig.body()
+
+var defaultPolicyConfig = PolicyConfig{
+	Rollover: RolloverConfig{
+		MaxSize: "50gb",
+		MaxAge:  "30d",
+	},
+}
+
+// body generates the policy body with the configured phases.
+func (p PolicyConfig) body() common.MapStr {
+	rollover := common.MapStr{}
+	if p.Rollover.MaxSize != "" {
+		rollover["max_size"] = p.Rollover.MaxSize
+	}
+	if p.Rollover.MaxAge != "" {
+		rollover["max_age"] = p.Rollover.MaxAge
+	}
+	if p.Rollover.MaxDocs > 0 {
+		rollover["max_docs"] = p.Rollover.MaxDocs
+	}
+
+	phases := common.MapStr{}
+	if len(rollover) > 0 {
+		phases["hot"] = common.MapStr{
+			"actions": common.MapStr{
+				"rollover": rollover,
 			},
+		}
+	}
+	if p.Delete.MinAge != "" {
+		phases["delete"] = common.MapStr{
+			"min_age": p.Delete.MinAge,
+			"actions": common.MapStr{
+				"delete": common.MapStr{},
+			},
+		}
+	}
+
+	return common.MapStr{
+		"policy": common.MapStr{
+			"phases": phases,
 		},
-	},
+	}
+}
+
+// Validate checks that the policy has at least one phase.
+func (p *PolicyConfig) Validate() error {
+	if p.Rollover == (RolloverConfig{}) && p.Delete.MinAge == "" {
+		return errors.New("the policy requires a rollover condition or a delete phase")
+	}
+	return nil
 }
 
 //Unpack creates enumeration value true, false or auto
@@ -120,6 +195,7 @@ func defaultConfig(info beat.Info) Config {
 		RolloverAlias: *aliasFmt,
 		Pattern:       ilmDefaultPattern,
 		PolicyFile:    "",
+		Policy:        defaultPolicyConfig,
 		CheckExists:   true,
 	}
 }
@@ -40,6 +40,7 @@ type Supporter interface {
 	Mode() Mode
 	Alias() Alias
 	Policy() Policy
+	DatasetPolicies() []DatasetPolicy
 	Overwrite() bool
 
 	// Manager creates a new Manager instance for checking and installing
@@ -53,8 +54,9 @@ type Manager interface {
 
 	EnsureAlias() error
 
-	// EnsurePolicy installs a policy if it does not exist. The policy is always
-	// written if overwrite is set.
+	// EnsurePolicy installs the policy and the dataset policies if they do not
+	// exist. If overwrite is set, existing policies are updated if they differ
+	// from the configured ones.
 	// The created flag is set to true only if a new policy is created. `created`
 	// is false if an existing policy gets overwritten.
 	EnsurePolicy(overwrite bool) (created bool, err error)
@@ -67,6 +69,12 @@ type Policy struct {
 	Body common.MapStr
 }
 
+// DatasetPolicy describes the policy for the indices of a single dataset.
+type DatasetPolicy struct {
+	Dataset string
+	Policy
+}
+
 // Alias describes the alias to be created in Elasticsearch.
 type Alias struct {
 	Name    string
@@ -121,7 +129,7 @@ func StdSupport(log *logp.Logger, info beat.Info, config *common.Config) (Suppor
 
 	policy := Policy{
 		Name: name,
-		Body: DefaultPolicy,
+		Body: cfg.Policy.body(),
 	}
 	if path := cfg.PolicyFile; path != "" {
 		contents, err := ioutil.ReadFile(path)
@@ -137,7 +145,41 @@ func StdSupport(log *logp.Logger, info beat.Info, config *common.Config) (Suppor
 		policy.Body = body
 	}
 
-	return NewStdSupport(log, cfg.Mode, alias, policy, cfg.Overwrite, cfg.CheckExists), nil
+	datasets, err := datasetPolicies(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	support := NewStdSupport(log, cfg.Mode, alias, policy, cfg.Overwrite, cfg.CheckExists).(*stdSupport)
+	support.datasets = datasets
+	return support, nil
+}
+
+// datasetPolicies creates the policies configured for single datasets, named
+// after the policy and the dataset by default.
+func datasetPolicies(policyName string, cfg Config) ([]DatasetPolicy, error) {
+	var policies []DatasetPolicy
+	seen := map[string]bool{}
+	for _, c := range cfg.Datasets {
+		dsCfg := DatasetConfig{Policy: cfg.Policy}
+		if err := c.Unpack(&dsCfg); err != nil {
+			return nil, errors.Wrap(err, "invalid ilm dataset policy")
+		}
+		if seen[dsCfg.Dataset] {
+			return nil, errors.Errorf("duplicate ilm policy for dataset '%v'", dsCfg.Dataset)
+		}
+		seen[dsCfg.Dataset] = true
+
+		name := dsCfg.PolicyName
+		if name == "" {
+			name = policyName + "-" + dsCfg.Dataset
+		}
+		policies = append(policies, DatasetPolicy{
+			Dataset: dsCfg.Dataset,
+			Policy:  Policy{Name: name, Body: dsCfg.Policy.body()},
+		})
+	}
+	return policies, nil
 }
 
 // NoopSupport configures a new noop ILM support implementation,
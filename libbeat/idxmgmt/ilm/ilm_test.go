@@ -128,6 +128,72 @@ func TestDefaultSupport_Init(t *testing.T) {
 		assert.Equal(Alias{Name: "test-9.9.9", Pattern: "01"}, s.Alias())
 	})
 
+	t.Run("with policy config", func(t *testing.T) {
+		s, err := DefaultSupport(nil, info, common.MustNewConfigFrom(
+			map[string]interface{}{
+				"policy.rollover.max_size": "",
+				"policy.rollover.max_docs": 1000,
+				"policy.delete.min_age":    "90d",
+				"datasets": []map[string]interface{}{
+					{"dataset": "nginx.access", "policy_name": "nginx", "rollover.max_age": "1d"},
+					{"dataset": "nginx.error"},
+				},
+			},
+		))
+		require.NoError(t, err)
+
+		expected := common.MapStr{
+			"policy": common.MapStr{
+				"phases": common.MapStr{
+					"hot": common.MapStr{
+						"actions": common.MapStr{
+							"rollover": common.MapStr{"max_age": "30d", "max_docs": int64(1000)},
+						},
+					},
+					"delete": common.MapStr{
+						"min_age": "90d",
+						"actions": common.MapStr{"delete": common.MapStr{}},
+					},
+				},
+			},
+		}
+		assert.Equal(t, expected, s.Policy().Body)
+
+		policies := s.DatasetPolicies()
+		require.Len(t, policies, 2)
+		assert.Equal(t, "nginx.access", policies[0].Dataset)
+		assert.Equal(t, "nginx", policies[0].Name)
+		assert.Equal(t, PolicyConfig{
+			Rollover: RolloverConfig{MaxAge: "1d", MaxDocs: 1000},
+			Delete:   DeleteConfig{MinAge: "90d"},
+		}.body(), policies[0].Body)
+		assert.Equal(t, "nginx.error", policies[1].Dataset)
+		assert.Equal(t, "test-nginx.error", policies[1].Name)
+		assert.Equal(t, expected, policies[1].Body)
+	})
+
+	t.Run("with duplicate dataset", func(t *testing.T) {
+		_, err := DefaultSupport(nil, info, common.MustNewConfigFrom(
+			map[string]interface{}{
+				"datasets": []map[string]interface{}{
+					{"dataset": "nginx.access"},
+					{"dataset": "nginx.access"},
+				},
+			},
+		))
+		require.Error(t, err)
+	})
+
+	t.Run("with empty policy", func(t *testing.T) {
+		_, err := DefaultSupport(nil, info, common.MustNewConfigFrom(
+			map[string]interface{}{
+				"policy.rollover.max_size": "",
+				"policy.rollover.max_age":  "",
+			},
+		))
+		require.Error(t, err)
+	})
+
 	t.Run("load external policy", func(t *testing.T) {
 		s, err := DefaultSupport(nil, info, common.MustNewConfigFrom(
 			common.MapStr{"policy_file": "testfiles/custom.json"},
@@ -290,9 +356,54 @@ func TestDefaultSupport_Manager_EnsurePolicy(t *testing.T) {
 		"overwrite existing": {
 			overwrite: true,
 			calls: []onCall{
+				onGetILMPolicy(testPolicy.Name).Return(common.MapStr{"policy": common.MapStr{}}, true, nil),
+				onCreateILMPolicy(testPolicy).Return(nil),
+			},
+		},
+		"overwrite unchanged": {
+			overwrite: true,
+			calls: []onCall{
+				onGetILMPolicy(testPolicy.Name).Return(common.MapStr{
+					"policy": common.MapStr{
+						"phases": common.MapStr{
+							"hot": common.MapStr{
+								"min_age": "0ms",
+								"actions": common.MapStr{
+									"rollover": common.MapStr{"max_size": "50gb", "max_age": "30d"},
+								},
+							},
+						},
+					},
+				}, true, nil),
+			},
+		},
+		"overwrite missing": {
+			overwrite: true,
+			create:    true,
+			calls: []onCall{
+				onGetILMPolicy(testPolicy.Name).Return(nil, false, nil),
 				onCreateILMPolicy(testPolicy).Return(nil),
 			},
 		},
+		"create dataset policies": {
+			create: true,
+			cfg: map[string]interface{}{
+				"datasets": []map[string]interface{}{
+					{"dataset": "nginx.access", "delete.min_age": "7d"},
+				},
+			},
+			calls: []onCall{
+				onHasILMPolicy(testPolicy.Name).Return(true, nil),
+				onHasILMPolicy("test-nginx.access").Return(false, nil),
+				onCreateILMPolicy(Policy{
+					Name: "test-nginx.access",
+					Body: PolicyConfig{
+						Rollover: RolloverConfig{MaxSize: "50gb", MaxAge: "30d"},
+						Delete:   DeleteConfig{MinAge: "7d"},
+					}.body(),
+				}).Return(nil),
+			},
+		},
 		"fail": {
 			calls: []onCall{
 				onHasILMPolicy(testPolicy.Name).Return(false, nil),
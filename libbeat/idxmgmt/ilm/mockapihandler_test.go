@@ -19,6 +19,8 @@ package ilm
 
 import (
 	"github.com/stretchr/testify/mock"
+
+	"github.com/elastic/beats/v7/libbeat/common"
 )
 
 type mockHandler struct {
@@ -68,6 +70,13 @@ func (h *mockHandler) HasILMPolicy(name string) (bool, error) {
 	return args.Bool(0), args.Error(1)
 }
 
+func onGetILMPolicy(name string) onCall { return makeOnCall("GetILMPolicy", name) }
+func (h *mockHandler) GetILMPolicy(name string) (common.MapStr, bool, error) {
+	args := h.Called(name)
+	body, _ := args.Get(0).(common.MapStr)
+	return body, args.Bool(1), args.Error(2)
+}
+
 func onCreateILMPolicy(policy Policy) onCall { return makeOnCall("CreateILMPolicy", policy) }
 func (h *mockHandler) CreateILMPolicy(policy Policy) error {
 	args := h.Called(policy)
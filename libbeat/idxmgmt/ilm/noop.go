@@ -31,11 +31,12 @@ func NewNoopSupport(info beat.Info, config *common.Config) (Supporter, error) {
 	return (*noopSupport)(nil), nil
 }
 
-func (*noopSupport) Mode() Mode                      { return ModeDisabled }
-func (*noopSupport) Alias() Alias                    { return Alias{} }
-func (*noopSupport) Policy() Policy                  { return Policy{} }
-func (*noopSupport) Overwrite() bool                 { return false }
-func (*noopSupport) Manager(_ ClientHandler) Manager { return (*noopManager)(nil) }
+func (*noopSupport) Mode() Mode                       { return ModeDisabled }
+func (*noopSupport) Alias() Alias                     { return Alias{} }
+func (*noopSupport) Policy() Policy                   { return Policy{} }
+func (*noopSupport) DatasetPolicies() []DatasetPolicy { return nil }
+func (*noopSupport) Overwrite() bool                  { return false }
+func (*noopSupport) Manager(_ ClientHandler) Manager  { return (*noopManager)(nil) }
 
 func (*noopManager) CheckEnabled() (bool, error)       { return false, nil }
 func (*noopManager) EnsureAlias() error                { return errOf(ErrOpNotAvailable) }
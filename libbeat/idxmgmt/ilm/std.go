@@ -18,8 +18,12 @@
 package ilm
 
 import (
+	"encoding/json"
+	"reflect"
 	"time"
 
+	"github.com/elastic/beats/v7/libbeat/common"
+
 	"github.com/elastic/beats/v7/libbeat/logp"
 )
 
@@ -30,8 +34,9 @@ type stdSupport struct {
 	overwrite   bool
 	checkExists bool
 
-	alias  Alias
-	policy Policy
+	alias    Alias
+	policy   Policy
+	datasets []DatasetPolicy
 }
 
 type stdManager struct {
@@ -72,6 +77,8 @@ func (s *stdSupport) Alias() Alias    { return s.alias }
 func (s *stdSupport) Policy() Policy  { return s.policy }
 func (s *stdSupport) Overwrite() bool { return s.overwrite }
 
+func (s *stdSupport) DatasetPolicies() []DatasetPolicy { return s.datasets }
+
 func (s *stdSupport) Manager(h ClientHandler) Manager {
 	return &stdManager{
 		client:     h,
@@ -120,25 +127,90 @@ func (m *stdManager) EnsureAlias() error {
 }
 
 func (m *stdManager) EnsurePolicy(overwrite bool) (bool, error) {
-	log := m.log
 	overwrite = overwrite || m.Overwrite()
 
-	exists := true
-	if m.checkExists && !overwrite {
-		b, err := m.client.HasILMPolicy(m.policy.Name)
+	created, err := m.ensurePolicy(m.policy, overwrite)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range m.datasets {
+		datasetCreated, err := m.ensurePolicy(p.Policy, overwrite)
 		if err != nil {
 			return false, err
 		}
-		exists = b
+		created = created || datasetCreated
+	}
+	return created, nil
+}
+
+func (m *stdManager) ensurePolicy(policy Policy, overwrite bool) (bool, error) {
+	log := m.log
+
+	if !m.checkExists {
+		if overwrite {
+			return false, m.client.CreateILMPolicy(policy)
+		}
+		log.Infof("do not generate ilm policy %v: check_exists=false, overwrite=false", policy.Name)
+		return false, nil
 	}
 
-	if !exists || overwrite {
-		return !exists, m.client.CreateILMPolicy(m.policy)
+	if !overwrite {
+		exists, err := m.client.HasILMPolicy(policy.Name)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			return true, m.client.CreateILMPolicy(policy)
+		}
+		log.Infof("do not generate ilm policy %v: exists=true, overwrite=false", policy.Name)
+		return false, nil
 	}
 
-	log.Infof("do not generate ilm policy: exists=%v, overwrite=%v",
-		exists, overwrite)
-	return false, nil
+	// Only write the policy if it is new or has changed, so running the setup
+	// repeatedly does not create new policy versions.
+	current, exists, err := m.client.GetILMPolicy(policy.Name)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return true, m.client.CreateILMPolicy(policy)
+	}
+	if equalPolicies(current, policy.Body) {
+		log.Infof("ilm policy %v is up to date", policy.Name)
+		return false, nil
+	}
+	log.Infof("update ilm policy %v", policy.Name)
+	return false, m.client.CreateILMPolicy(policy)
+}
+
+// equalPolicies compares a policy stored in Elasticsearch with a configured
+// one. Elasticsearch sets the min_age of phases to 0ms if not configured.
+func equalPolicies(stored, configured common.MapStr) bool {
+	s, c := normalizePolicy(stored), normalizePolicy(configured)
+	return s != nil && c != nil && reflect.DeepEqual(s, c)
+}
+
+func normalizePolicy(body common.MapStr) map[string]interface{} {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil
+	}
+	var policy map[string]interface{}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil
+	}
+
+	phases, _ := common.MapStr(policy).GetValue("policy.phases")
+	if phases, ok := phases.(map[string]interface{}); ok {
+		for _, phase := range phases {
+			if phase, ok := phase.(map[string]interface{}); ok {
+				if _, exists := phase["min_age"]; !exists {
+					phase["min_age"] = "0ms"
+				}
+			}
+		}
+	}
+	return policy
 }
 
 func (c *infoCache) Valid() bool {
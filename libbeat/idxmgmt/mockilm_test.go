@@ -69,6 +69,13 @@ func (m *mockILMSupport) Policy() ilm.Policy {
 	return args.Get(0).(ilm.Policy)
 }
 
+func onDatasetPolicies() onCall { return makeOnCall("DatasetPolicies") }
+func (m *mockILMSupport) DatasetPolicies() []ilm.DatasetPolicy {
+	args := m.Called()
+	policies, _ := args.Get(0).([]ilm.DatasetPolicy)
+	return policies
+}
+
 func onOverwrite() onCall { return makeOnCall("Overwrite") }
 func (m *mockILMSupport) Overwrite() bool {
 	return m.Called().Bool(0)
@@ -26,8 +26,10 @@ import (
 	"github.com/elastic/beats/v7/libbeat/beat/events"
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/ecs"
 	"github.com/elastic/beats/v7/libbeat/idxmgmt/ilm"
 	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/mapping"
 	"github.com/elastic/beats/v7/libbeat/outputs"
 	"github.com/elastic/beats/v7/libbeat/outputs/outil"
 	"github.com/elastic/beats/v7/libbeat/template"
@@ -38,9 +40,14 @@ type indexSupport struct {
 	ilm          ilm.Supporter
 	info         beat.Info
 	migration    bool
+	ecs          ecs.Config
 	templateCfg  template.TemplateConfig
 	defaultIndex string
 
+	// dataStreams is set if the Elasticsearch output writes to the data
+	// streams selected by the fields of the events.
+	dataStreams bool
+
 	st indexState
 }
 
@@ -157,6 +164,18 @@ func (s *indexSupport) BuildSelector(cfg *common.Config) (outputs.IndexSelector,
 	var err error
 	log := s.log
 
+	dataStreams, err := dataStreamsEnabled(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if dataStreams {
+		if err := checkDataStreamOutputSettings(cfg); err != nil {
+			return nil, err
+		}
+		log.Info("Events are written to the data streams selected by their data_stream fields.")
+		return dataStreamSelector{}, nil
+	}
+
 	// we construct our own configuration object based on the available settings
 	// in cfg and defaultIndex. The configuration object provided must not be
 	// modified.
@@ -284,22 +303,42 @@ func (m *indexManager) Setup(loadTemplate, loadILM LoadMode) error {
 		tmplCfg.Overwrite, tmplCfg.Enabled = templateComponent.overwrite, templateComponent.enabled
 
 		if ilmComponent.enabled {
-			tmplCfg, err = applyILMSettings(log, tmplCfg, m.support.ilm.Policy(), m.support.ilm.Alias())
+			if m.support.dataStreams {
+				tmplCfg, err = applyDataStreamILMSettings(tmplCfg, m.support.ilm.Policy().Name)
+			} else {
+				tmplCfg, err = applyILMSettings(log, tmplCfg, m.support.ilm.Policy(), m.support.ilm.Alias())
+			}
 			if err != nil {
 				return err
 			}
 		}
 		fields := m.assets.Fields(m.support.info.Beat)
+		tmplCfg, err = applyECSMigration(log, tmplCfg, m.support.ecs, fields)
+		if err != nil {
+			return err
+		}
 		err = m.clientHandler.Load(tmplCfg, m.support.info, fields, m.support.migration)
 		if err != nil {
 			return fmt.Errorf("error loading template: %v", err)
 		}
 
 		log.Info("Loaded index template.")
+
+		var policies []ilm.DatasetPolicy
+		if ilmComponent.enabled {
+			policies = m.support.ilm.DatasetPolicies()
+		}
+		datasets, dsCfgs := datasetTemplateConfigs(tmplCfg, policies, m.support.dataStreams)
+		for _, dataset := range datasets {
+			if err := m.clientHandler.Load(dsCfgs[dataset], m.support.info, nil, m.support.migration); err != nil {
+				return fmt.Errorf("error loading template for dataset %v: %v", dataset, err)
+			}
+		}
 	}
 
-	if ilmComponent.load {
-		// ensure alias is created after the template is created
+	if ilmComponent.load && !m.support.templateCfg.DataStream {
+		// ensure alias is created after the template is created. Data
+		// streams are created by Elasticsearch on the first write.
 		if err := m.ilm.EnsureAlias(); err != nil {
 			if ilm.ErrReason(err) != ilm.ErrAliasAlreadyExists {
 				return err
@@ -384,6 +423,111 @@ func unpackTemplateConfig(cfg *common.Config) (config template.TemplateConfig, e
 	return config, err
 }
 
+// applyECSMigration appends the fields with the names of the target ECS version
+// to the template, as aliases or as copies of the fields of the Beat for
+// renamed events.
+func applyECSMigration(
+	log *logp.Logger,
+	tmpl template.TemplateConfig,
+	cfg ecs.Config,
+	fields []byte,
+) (template.TemplateConfig, error) {
+	if !cfg.Enabled() || !(cfg.Migration.Aliases || cfg.Migration.Rename) || len(fields) == 0 {
+		return tmpl, nil
+	}
+
+	beatFields, err := mapping.LoadFields(fields)
+	if err != nil {
+		return tmpl, err
+	}
+	migration, err := ecs.NewMigration(cfg.Version, beatFields)
+	if err != nil {
+		return tmpl, err
+	}
+
+	targetFields := migration.AliasFields()
+	if cfg.Migration.Rename {
+		targetFields = migration.TargetFields()
+	}
+	if len(targetFields) == 0 {
+		return tmpl, nil
+	}
+
+	log.Infof("Add %d fields of ECS %v to the template.", len(targetFields), cfg.Version)
+	appended := make(mapping.Fields, 0, len(tmpl.AppendFields)+len(targetFields))
+	appended = append(appended, tmpl.AppendFields...)
+	tmpl.AppendFields = append(appended, targetFields...)
+	return tmpl, nil
+}
+
+// datasetTemplateConfigs configures the templates of the datasets with their
+// own ILM policy or timestamp precision. It returns the datasets in the order
+// they are configured, and their templates.
+func datasetTemplateConfigs(
+	tmpl template.TemplateConfig,
+	policies []ilm.DatasetPolicy,
+	dataStreams bool,
+) ([]string, map[string]template.TemplateConfig) {
+	var datasets []string
+	cfgs := map[string]template.TemplateConfig{}
+	get := func(dataset string) template.TemplateConfig {
+		if cfg, exists := cfgs[dataset]; exists {
+			return cfg
+		}
+		datasets = append(datasets, dataset)
+		return datasetTemplateConfig(tmpl, dataset, dataStreams)
+	}
+
+	for _, policy := range policies {
+		cfg := get(policy.Dataset)
+		lifecycle := map[string]interface{}{"name": policy.Name}
+		if !tmpl.DataStream {
+			// Indices of a dataset are not written through the rollover alias.
+			lifecycle["rollover_alias"] = ""
+		}
+		cfg.Settings.Index = map[string]interface{}{"lifecycle": lifecycle}
+		cfgs[policy.Dataset] = cfg
+	}
+
+	for _, ts := range tmpl.Timestamp.Datasets {
+		cfg := get(ts.Dataset)
+		precision := ts.Precision
+		cfg.Timestamp.Precision = &precision
+		cfgs[ts.Dataset] = cfg
+	}
+
+	return datasets, cfgs
+}
+
+// datasetTemplateConfig configures the template of the indices named after the
+// template and the dataset, or of the data streams of the dataset if
+// dataStreams is set. The template only holds the settings of the dataset and
+// takes precedence over the template of the Beat.
+func datasetTemplateConfig(tmpl template.TemplateConfig, dataset string, dataStreams bool) template.TemplateConfig {
+	name := fmt.Sprintf("%s-%s", tmpl.Name, dataset)
+	pattern := name + "*"
+	if dataStreams {
+		pattern = fmt.Sprintf("*-%s-*", dataset)
+	}
+	cfg := template.TemplateConfig{
+		Enabled:    true,
+		Name:       name,
+		Pattern:    pattern,
+		Overwrite:  tmpl.Overwrite,
+		Type:       tmpl.Type,
+		DataStream: tmpl.DataStream,
+		Order:      tmpl.Order + 1,
+		Priority:   tmpl.Priority + 1,
+		Timestamp:  template.TimestampConfig{Precision: tmpl.Timestamp.Precision},
+	}
+	if tmpl.Type == template.IndexTemplateIndex {
+		// Only one index template is applied, the mappings and settings of
+		// the Beat are included from its component templates.
+		cfg.ComposedOf = []string{tmpl.Name + "-settings", tmpl.Name + "-mappings"}
+	}
+	return cfg
+}
+
 func applyILMSettings(
 	log *logp.Logger,
 	tmpl template.TemplateConfig,
@@ -408,6 +552,10 @@ func applyILMSettings(
 	}
 
 	tmpl.Pattern = fmt.Sprintf("%s-*", alias.Name)
+	if tmpl.DataStream {
+		// Events are written to the data stream named after the alias.
+		tmpl.Pattern = fmt.Sprintf("%s*", alias.Name)
+	}
 	if log != nil {
 		log.Infof("Set setup.template.pattern to '%s' as ILM is enabled.", tmpl.Pattern)
 	}
@@ -441,8 +589,9 @@ func applyILMSettings(
 	}
 	idxSettings["lifecycle"] = lifecycle
 
-	// add rollover_alias and name to index.lifecycle settings
-	if _, exists := lifecycle["rollover_alias"]; !exists {
+	// add rollover_alias and name to index.lifecycle settings. Data streams
+	// are rolled over without an alias.
+	if _, exists := lifecycle["rollover_alias"]; !exists && !tmpl.DataStream {
 		log.Infof("Set settings.index.lifecycle.rollover_alias in template to %s as ILM is enabled.", alias)
 		lifecycle["rollover_alias"] = alias.Name
 	}
@@ -38,6 +38,7 @@ type mockClientHandler struct {
 
 	tmplCfg   *template.TemplateConfig
 	tmplForce bool
+	templates []template.TemplateConfig
 
 	operations []mockCreateOp
 }
@@ -391,6 +392,21 @@ func TestIndexManager_Setup(t *testing.T) {
 			alias:  "mocktest",
 			policy: "policy-keep",
 		},
+		"template data stream ilm default": {
+			cfg: common.MapStr{
+				"setup.template.type":        "index",
+				"setup.template.data_stream": true,
+			},
+			tmplCfg: cfgWith(template.DefaultConfig(), map[string]interface{}{
+				"overwrite":                     "true",
+				"type":                          "index",
+				"data_stream":                   true,
+				"name":                          "test-9.9.9",
+				"pattern":                       "test-9.9.9*",
+				"settings.index.lifecycle.name": "test",
+			}),
+			policy: "test",
+		},
 		"template default ilm disabled": {
 			cfg: common.MapStr{
 				"setup.ilm.enabled": false,
@@ -493,6 +509,70 @@ func TestIndexManager_Setup(t *testing.T) {
 	}
 }
 
+func TestIndexManager_SetupDatasetPolicies(t *testing.T) {
+	info := beat.Info{Beat: "test", Version: "9.9.9"}
+	factory := MakeDefaultSupport(ilm.StdSupport)
+	im, err := factory(nil, info, common.MustNewConfigFrom(common.MapStr{
+		"setup.template.type": "index",
+		"setup.ilm.datasets": []common.MapStr{
+			{"dataset": "nginx.access", "delete.min_age": "7d"},
+		},
+	}))
+	require.NoError(t, err)
+
+	clientHandler := newMockClientHandler()
+	manager := im.Manager(clientHandler, BeatsAssets([]byte("testbeat fields")))
+	err = manager.Setup(LoadModeEnabled, LoadModeEnabled)
+	require.NoError(t, err)
+	clientHandler.assertInvariants(t)
+
+	require.Len(t, clientHandler.templates, 2)
+	dsCfg := clientHandler.templates[1]
+	assert.Equal(t, "test-9.9.9-nginx.access", dsCfg.Name)
+	assert.Equal(t, "test-9.9.9-nginx.access*", dsCfg.Pattern)
+	assert.Equal(t, []string{"test-9.9.9-settings", "test-9.9.9-mappings"}, dsCfg.ComposedOf)
+	assert.Equal(t, map[string]interface{}{
+		"lifecycle": map[string]interface{}{"name": "test-nginx.access", "rollover_alias": ""},
+	}, dsCfg.Settings.Index)
+}
+
+func TestIndexManager_SetupDatasetTimestamps(t *testing.T) {
+	info := beat.Info{Beat: "test", Version: "9.9.9"}
+	factory := MakeDefaultSupport(ilm.StdSupport)
+	im, err := factory(nil, info, common.MustNewConfigFrom(common.MapStr{
+		"setup.template.type": "index",
+		"setup.template.timestamp.datasets": []common.MapStr{
+			{"dataset": "nginx.access", "precision": "nanosecond"},
+			{"dataset": "system.syslog", "precision": "millisecond"},
+		},
+		"setup.ilm.datasets": []common.MapStr{
+			{"dataset": "nginx.access", "delete.min_age": "7d"},
+		},
+	}))
+	require.NoError(t, err)
+
+	clientHandler := newMockClientHandler()
+	manager := im.Manager(clientHandler, BeatsAssets([]byte("testbeat fields")))
+	err = manager.Setup(LoadModeEnabled, LoadModeEnabled)
+	require.NoError(t, err)
+	clientHandler.assertInvariants(t)
+
+	require.Len(t, clientHandler.templates, 3)
+	assert.Nil(t, clientHandler.templates[0].Timestamp.Precision)
+
+	nginx := clientHandler.templates[1]
+	assert.Equal(t, "test-9.9.9-nginx.access", nginx.Name)
+	assert.Equal(t, common.TimestampNanosecond, *nginx.Timestamp.Precision)
+	assert.Equal(t, map[string]interface{}{
+		"lifecycle": map[string]interface{}{"name": "test-nginx.access", "rollover_alias": ""},
+	}, nginx.Settings.Index)
+
+	syslog := clientHandler.templates[2]
+	assert.Equal(t, "test-9.9.9-system.syslog", syslog.Name)
+	assert.Equal(t, common.TimestampMillisecond, *syslog.Timestamp.Precision)
+	assert.Nil(t, syslog.Settings.Index)
+}
+
 func (op mockCreateOp) String() string {
 	names := []string{"create-policy", "create-template", "create-alias"}
 	if int(op) > len(names) {
@@ -507,8 +587,11 @@ func newMockClientHandler() *mockClientHandler {
 
 func (h *mockClientHandler) Load(config template.TemplateConfig, _ beat.Info, fields []byte, migration bool) error {
 	h.recordOp(mockCreateTemplate)
-	h.tmplForce = config.Overwrite
-	h.tmplCfg = &config
+	h.templates = append(h.templates, config)
+	if h.tmplCfg == nil {
+		h.tmplForce = config.Overwrite
+		h.tmplCfg = &config
+	}
 	return nil
 }
 
@@ -530,6 +613,10 @@ func (h *mockClientHandler) HasILMPolicy(name string) (bool, error) {
 	return h.policy == name, nil
 }
 
+func (h *mockClientHandler) GetILMPolicy(name string) (common.MapStr, bool, error) {
+	return nil, h.policy == name, nil
+}
+
 func (h *mockClientHandler) CreateILMPolicy(policy ilm.Policy) error {
 	h.recordOp(mockCreatePolicy)
 	h.policy = policy.Name
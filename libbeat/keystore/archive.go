@@ -0,0 +1,83 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package keystore
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// WriteArchive writes the secrets of the keystore to w, encrypted with the
+// given password. The archive uses the format of the file keystore, so an
+// archive encrypted with an empty password is a valid keystore file.
+func WriteArchive(w io.Writer, store Keystore, password *SecureString) error {
+	listing, err := AsListingKeystore(store)
+	if err != nil {
+		return err
+	}
+	keys, err := listing.List()
+	if err != nil {
+		return err
+	}
+
+	archive := newArchive(password)
+	for _, key := range keys {
+		secret, err := store.Retrieve(key)
+		if err != nil {
+			return fmt.Errorf("could not retrieve key '%s': %v", key, err)
+		}
+		value, err := secret.Get()
+		if err != nil {
+			return fmt.Errorf("could not retrieve key '%s': %v", key, err)
+		}
+		archive.secrets[key] = serializableSecureString{Value: value}
+	}
+
+	return archive.encode(w)
+}
+
+// ReadArchive reads the secrets of an archive written by WriteArchive, or of
+// a keystore file, encrypted with the given password.
+func ReadArchive(r io.Reader, password *SecureString) (map[string][]byte, error) {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkVersion(raw); err != nil {
+		return nil, err
+	}
+
+	archive := newArchive(password)
+	if err := archive.decode(raw); err != nil {
+		return nil, err
+	}
+
+	secrets := make(map[string][]byte, len(archive.secrets))
+	for key, secret := range archive.secrets {
+		secrets[key] = secret.Value
+	}
+	return secrets, nil
+}
+
+func newArchive(password *SecureString) *FileKeystore {
+	return &FileKeystore{
+		password: password,
+		secrets:  make(map[string]serializableSecureString),
+	}
+}
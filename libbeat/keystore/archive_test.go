@@ -0,0 +1,64 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package keystore
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveRoundTrip(t *testing.T) {
+	path := GetTemporaryKeystoreFile()
+	defer os.Remove(path)
+
+	store := CreateAnExistingKeystore(path)
+	password := NewSecureString([]byte("archive password"))
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteArchive(&buf, store, password))
+
+	secrets, err := ReadArchive(bytes.NewReader(buf.Bytes()), password)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{keyValue: secretValue}, secrets)
+
+	_, err = ReadArchive(bytes.NewReader(buf.Bytes()), NewSecureString([]byte("wrong")))
+	assert.Error(t, err)
+
+	_, err = ReadArchive(bytes.NewReader([]byte("v")), password)
+	assert.Error(t, err)
+}
+
+func TestArchiveIsAKeystore(t *testing.T) {
+	path := GetTemporaryKeystoreFile()
+	defer os.Remove(path)
+
+	CreateAnExistingKeystore(path)
+
+	// A keystore file can be read as an archive with an empty password.
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	secrets, err := ReadArchive(f, NewSecureString([]byte("")))
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{keyValue: secretValue}, secrets)
+}
@@ -17,11 +17,25 @@
 
 package keystore
 
+import "time"
+
 // Config Define keystore configurable options
 type Config struct {
-	Path string `config:"path"`
+	Path   string       `config:"path"`
+	Reload ReloadConfig `config:"reload"`
+}
+
+// ReloadConfig defines if and how often the keystore file is checked for
+// changes, so rotated secrets are picked up without a restart.
+type ReloadConfig struct {
+	Enabled bool          `config:"enabled"`
+	Period  time.Duration `config:"period" validate:"positive,nonzero"`
 }
 
 var defaultConfig = Config{
 	Path: "",
+	Reload: ReloadConfig{
+		Enabled: false,
+		Period:  10 * time.Second,
+	},
 }
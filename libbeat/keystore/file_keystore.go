@@ -21,6 +21,7 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base64"
 	"encoding/json"
@@ -64,6 +65,7 @@ type FileKeystore struct {
 	secrets  map[string]serializableSecureString
 	dirty    bool
 	password *SecureString
+	checksum [sha256.Size]byte
 }
 
 // Allow the original SecureString type to be correctly serialized to json.
@@ -220,17 +222,6 @@ func (k *FileKeystore) doSave(override bool) error {
 
 	temporaryPath := fmt.Sprintf("%s.tmp", k.Path)
 
-	w := new(bytes.Buffer)
-	jsonEncoder := json.NewEncoder(w)
-	if err := jsonEncoder.Encode(k.secrets); err != nil {
-		return fmt.Errorf("cannot serialize the keystore before saving it to disk: %v", err)
-	}
-
-	encrypted, err := k.encrypt(w)
-	if err != nil {
-		return fmt.Errorf("cannot encrypt the keystore: %v", err)
-	}
-
 	flags := os.O_RDWR | os.O_CREATE
 	if override {
 		flags |= os.O_TRUNC
@@ -243,12 +234,19 @@ func (k *FileKeystore) doSave(override bool) error {
 		return fmt.Errorf("cannot open file to save the keystore to '%s', error: %s", k.Path, err)
 	}
 
-	f.Write(version)
-	base64Encoder := base64.NewEncoder(base64.StdEncoding, f)
-	io.Copy(base64Encoder, encrypted)
-	base64Encoder.Close()
+	// Keep the checksum of the saved content, so the keystore is not
+	// considered changed by the next reload.
+	buf := new(bytes.Buffer)
+	err = k.encode(buf)
+	if err == nil {
+		_, err = f.Write(buf.Bytes())
+	}
 	f.Sync()
 	f.Close()
+	if err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
 
 	err = file.SafeFileRotate(k.Path, temporaryPath)
 	if err != nil {
@@ -258,6 +256,7 @@ func (k *FileKeystore) doSave(override bool) error {
 	os.Remove(temporaryPath)
 
 	k.dirty = false
+	k.checksum = sha256.Sum256(buf.Bytes())
 	return nil
 }
 
@@ -282,16 +281,26 @@ func (k *FileKeystore) loadRaw() ([]byte, error) {
 		return nil, err
 	}
 
+	if err := checkVersion(raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func checkVersion(raw []byte) error {
+	if len(raw) < len(version) {
+		return fmt.Errorf("corrupt or empty keystore")
+	}
+
 	v := raw[0:len(version)]
 	if !bytes.Equal(v, version) {
-		return nil, fmt.Errorf("keystore format doesn't match expected version: '%s' got '%s'", version, v)
+		return fmt.Errorf("keystore format doesn't match expected version: '%s' got '%s'", version, v)
 	}
 
 	if len(raw) <= len(version) {
-		return nil, fmt.Errorf("corrupt or empty keystore")
+		return fmt.Errorf("corrupt or empty keystore")
 	}
-
-	return raw, nil
+	return nil
 }
 
 func (k *FileKeystore) load() error {
@@ -303,10 +312,75 @@ func (k *FileKeystore) load() error {
 		return err
 	}
 
-	if len(raw) == 0 {
-		return nil
+	if len(raw) > 0 {
+		if err := k.decode(raw); err != nil {
+			return err
+		}
 	}
+	k.checksum = sha256.Sum256(raw)
+	return nil
+}
+
+// Reload reads the keystore file again if it changed since it was loaded,
+// replacing all the secrets in memory. Unsaved changes are kept, the file is
+// not read while the keystore is dirty.
+func (k *FileKeystore) Reload() (bool, error) {
+	k.Lock()
+	defer k.Unlock()
+
+	if k.dirty {
+		return false, nil
+	}
+
+	raw, err := k.loadRaw()
+	if err != nil {
+		return false, err
+	}
+
+	checksum := sha256.Sum256(raw)
+	if checksum == k.checksum {
+		return false, nil
+	}
+
+	previous := k.secrets
+	k.secrets = make(map[string]serializableSecureString)
+	if len(raw) > 0 {
+		if err := k.decode(raw); err != nil {
+			k.secrets = previous
+			return false, err
+		}
+	}
+	k.checksum = checksum
+	return true, nil
+}
+
+// encode writes the encrypted secrets in the keystore format: the version
+// followed by the base64 encoded encrypted payload.
+func (k *FileKeystore) encode(w io.Writer) error {
+	buf := new(bytes.Buffer)
+	jsonEncoder := json.NewEncoder(buf)
+	if err := jsonEncoder.Encode(k.secrets); err != nil {
+		return fmt.Errorf("cannot serialize the keystore before saving it to disk: %v", err)
+	}
+
+	encrypted, err := k.encrypt(buf)
+	if err != nil {
+		return fmt.Errorf("cannot encrypt the keystore: %v", err)
+	}
+
+	if _, err := w.Write(version); err != nil {
+		return err
+	}
+	base64Encoder := base64.NewEncoder(base64.StdEncoding, w)
+	if _, err := io.Copy(base64Encoder, encrypted); err != nil {
+		return err
+	}
+	return base64Encoder.Close()
+}
 
+// decode reads the secrets from the raw keystore data, the version must be
+// checked by the caller.
+func (k *FileKeystore) decode(raw []byte) error {
 	base64Decoder := base64.NewDecoder(base64.StdEncoding, bytes.NewReader(raw[len(version):]))
 	plaintext, err := k.decrypt(base64Decoder)
 	if err != nil {
@@ -19,6 +19,7 @@ package keystore
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -316,6 +317,70 @@ func createAndReadKeystoreWithPassword(t *testing.T, password []byte) {
 
 // CreateAnExistingKeystore creates a keystore with an existing key
 /// `output.elasticsearch.password` with the value `secret`.
+func TestReloadReadsRotatedSecrets(t *testing.T) {
+	path := GetTemporaryKeystoreFile()
+	defer os.Remove(path)
+
+	CreateAnExistingKeystore(path)
+
+	keyStore, err := NewFileKeystore(path)
+	assert.NoError(t, err)
+	reloadable, err := AsReloadableKeystore(keyStore)
+	assert.NoError(t, err)
+
+	changed, err := reloadable.Reload()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// Rotate the secret from another process.
+	other, err := NewFileKeystore(path)
+	assert.NoError(t, err)
+	writableKeystore, _ := AsWritableKeystore(other)
+	assert.NoError(t, writableKeystore.Store(keyValue, []byte("rotated")))
+	assert.NoError(t, writableKeystore.Store("new.key", []byte("new")))
+	assert.NoError(t, writableKeystore.Save())
+
+	changed, err = reloadable.Reload()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	secure, err := keyStore.Retrieve(keyValue)
+	assert.NoError(t, err)
+	v, _ := secure.Get()
+	assert.Equal(t, []byte("rotated"), v)
+
+	changed, err = reloadable.Reload()
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	assert.NoError(t, writableKeystore.Delete("new.key"))
+	assert.NoError(t, writableKeystore.Save())
+
+	changed, err = reloadable.Reload()
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	_, err = keyStore.Retrieve("new.key")
+	assert.Equal(t, ErrKeyDoesntExists, err)
+}
+
+func TestReloadKeepsSecretsWhenTheFileIsInvalid(t *testing.T) {
+	path := GetTemporaryKeystoreFile()
+	defer os.Remove(path)
+
+	keyStore := CreateAnExistingKeystore(path)
+	reloadable, _ := AsReloadableKeystore(keyStore)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("invalid"), 0600))
+
+	_, err := reloadable.Reload()
+	assert.Error(t, err)
+
+	secure, err := keyStore.Retrieve(keyValue)
+	assert.NoError(t, err)
+	v, _ := secure.Get()
+	assert.Equal(t, secretValue, v)
+}
+
 func CreateAnExistingKeystore(path string) Keystore {
 	keyStore, err := NewFileKeystore(path)
 	// Fail fast in the test suite
@@ -20,6 +20,7 @@ package keystore
 import (
 	"errors"
 
+	"github.com/elastic/beats/v7/libbeat/audit"
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/bus"
 	"github.com/elastic/go-ucfg"
@@ -38,6 +39,9 @@ var (
 
 	// ErrNotWritable is returned when the keystore is not writable
 	ErrNotListing = errors.New("the configured keystore is not listing")
+
+	// ErrNotReloadable is returned when the keystore cannot be reloaded.
+	ErrNotReloadable = errors.New("the configured keystore is not reloadable")
 )
 
 // Keystore implement a way to securely saves and retrieves secrets to be used in the configuration
@@ -74,6 +78,14 @@ type ListingKeystore interface {
 	List() ([]string, error)
 }
 
+// ReloadableKeystore is a keystore whose secrets can be read again from their
+// source, to pick up secrets rotated by another process.
+type ReloadableKeystore interface {
+	// Reload reads the secrets again, it returns true if they changed since
+	// they were last loaded.
+	Reload() (bool, error)
+}
+
 // Provider for keystore
 type Provider interface {
 	GetKeystore(event bus.Event) Keystore
@@ -97,6 +109,7 @@ func ResolverWrap(keystore Keystore) func(string) (string, parse.Config, error)
 		if err != nil {
 			return "", parse.DefaultConfig, err
 		}
+		audit.Log(audit.ActionKeystoreRead, "keystore.key", keyName)
 
 		return string(v), parse.DefaultConfig, nil
 	}
@@ -121,3 +134,13 @@ func AsListingKeystore(store Keystore) (ListingKeystore, error) {
 	}
 	return w, nil
 }
+
+// AsReloadableKeystore casts a keystore to ReloadableKeystore, returning an ErrNotReloadable error if the given keystore does not implement
+// ReloadableKeystore interface
+func AsReloadableKeystore(store Keystore) (ReloadableKeystore, error) {
+	r, ok := store.(ReloadableKeystore)
+	if !ok {
+		return nil, ErrNotReloadable
+	}
+	return r, nil
+}
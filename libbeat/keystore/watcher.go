@@ -0,0 +1,109 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package keystore
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+// ReloadSettings returns the settings to reload the keystore, read from the
+// keystore section of the configuration.
+func ReloadSettings(cfg *common.Config) (ReloadConfig, error) {
+	config := defaultConfig
+	if cfg == nil {
+		return config.Reload, nil
+	}
+	if err := cfg.Unpack(&config); err != nil {
+		return config.Reload, fmt.Errorf("could not read keystore configuration, err: %v", err)
+	}
+	return config.Reload, nil
+}
+
+// Watcher periodically reloads a keystore and calls a function each time its
+// secrets changed.
+type Watcher struct {
+	store    ReloadableKeystore
+	period   time.Duration
+	onChange func()
+	log      *logp.Logger
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewWatcher returns a watcher checking the keystore for changes every period.
+// An ErrNotReloadable error is returned if the keystore cannot be reloaded.
+func NewWatcher(store Keystore, period time.Duration, onChange func()) (*Watcher, error) {
+	reloadable, err := AsReloadableKeystore(store)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{
+		store:    reloadable,
+		period:   period,
+		onChange: onChange,
+		log:      logp.NewLogger("keystore"),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Start starts watching the keystore in the background.
+func (w *Watcher) Start() {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.run()
+	}()
+}
+
+// Stop stops watching the keystore and waits for a pending call to the change
+// function to return.
+func (w *Watcher) Stop() {
+	close(w.done)
+	w.wg.Wait()
+}
+
+func (w *Watcher) run() {
+	ticker := time.NewTicker(w.period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := w.store.Reload()
+		if err != nil {
+			// Keep the current secrets, the file may be in the middle of
+			// being replaced.
+			w.log.Warnf("Failed to reload the keystore: %v", err)
+			continue
+		}
+		if changed {
+			w.log.Info("The secrets of the keystore changed")
+			w.onChange()
+		}
+	}
+}
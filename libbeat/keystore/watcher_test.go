@@ -0,0 +1,81 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package keystore
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestWatcherNotifiesChanges(t *testing.T) {
+	path := GetTemporaryKeystoreFile()
+	defer os.Remove(path)
+
+	keyStore := CreateAnExistingKeystore(path)
+
+	changes := make(chan struct{}, 1)
+	watcher, err := NewWatcher(keyStore, 10*time.Millisecond, func() {
+		changes <- struct{}{}
+	})
+	require.NoError(t, err)
+	watcher.Start()
+	defer watcher.Stop()
+
+	other, err := NewFileKeystore(path)
+	require.NoError(t, err)
+	writableKeystore, _ := AsWritableKeystore(other)
+	require.NoError(t, writableKeystore.Store(keyValue, []byte("rotated")))
+	require.NoError(t, writableKeystore.Save())
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the change of the keystore was not notified")
+	}
+
+	secure, err := keyStore.Retrieve(keyValue)
+	require.NoError(t, err)
+	v, _ := secure.Get()
+	assert.Equal(t, []byte("rotated"), v)
+}
+
+func TestReloadSettings(t *testing.T) {
+	settings, err := ReloadSettings(nil)
+	require.NoError(t, err)
+	assert.False(t, settings.Enabled)
+	assert.Equal(t, 10*time.Second, settings.Period)
+
+	settings, err = ReloadSettings(common.MustNewConfigFrom(map[string]interface{}{
+		"reload.enabled": true,
+		"reload.period":  "1m",
+	}))
+	require.NoError(t, err)
+	assert.True(t, settings.Enabled)
+	assert.Equal(t, time.Minute, settings.Period)
+
+	_, err = ReloadSettings(common.MustNewConfigFrom(map[string]interface{}{
+		"reload.period": 0,
+	}))
+	assert.Error(t, err)
+}
@@ -282,21 +282,42 @@ func (client *Client) readVersion() error {
 func (client *Client) GetVersion() common.Version { return client.Version }
 
 func (client *Client) ImportJSON(url string, params url.Values, jsonBody map[string]interface{}) error {
+	_, err := client.importJSON(url, params, jsonBody)
+	return err
+}
+
+// ImportJSONObjects imports the given saved objects like ImportJSON, and
+// returns the imported objects as reported by Kibana, with their versions.
+func (client *Client) ImportJSONObjects(url string, params url.Values, jsonBody map[string]interface{}) ([]common.MapStr, error) {
+	response, err := client.importJSON(url, params, jsonBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Objects []common.MapStr `json:"objects"`
+	}
+	if err := json.Unmarshal(response, &result); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal the imported objects: %v", err)
+	}
+	return result.Objects, nil
+}
 
+func (client *Client) importJSON(url string, params url.Values, jsonBody map[string]interface{}) ([]byte, error) {
 	body, err := json.Marshal(jsonBody)
 	if err != nil {
 		client.log.Debugf("Failed to json encode body (%v): %#v", err, jsonBody)
-		return fmt.Errorf("fail to marshal the json content: %v", err)
+		return nil, fmt.Errorf("fail to marshal the json content: %v", err)
 	}
 
 	statusCode, response, err := client.Connection.Request("POST", url, params, nil, bytes.NewBuffer(body))
 	if err != nil {
-		return fmt.Errorf("%v. Response: %s", err, truncateString(response))
+		return nil, fmt.Errorf("%v. Response: %s", err, truncateString(response))
 	}
 	if statusCode >= 300 {
-		return fmt.Errorf("returned %d to import file: %v. Response: %s", statusCode, err, response)
+		return nil, fmt.Errorf("returned %d to import file: %v. Response: %s", statusCode, err, response)
 	}
-	return nil
+	return response, nil
 }
 
 func (client *Client) Close() error { return nil }
@@ -318,6 +339,53 @@ func (client *Client) GetDashboard(id string) (common.MapStr, error) {
 	return result, nil
 }
 
+// GetSavedObject returns the saved object with the given type and id, or nil
+// if it doesn't exist.
+func (client *Client) GetSavedObject(objType, id string) (common.MapStr, error) {
+	statusCode, response, err := client.Request("GET", savedObjectPath(objType, id), nil, nil, nil)
+	if statusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting %s %s: %v", objType, id, err)
+	}
+	if statusCode >= 300 {
+		return nil, fmt.Errorf("returned %d getting %s %s. Response: %s", statusCode, objType, id, truncateString(response))
+	}
+
+	var object common.MapStr
+	if err := json.Unmarshal(response, &object); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal %s %s: %v", objType, id, err)
+	}
+	return object, nil
+}
+
+// CreateSavedObject creates a saved object with the given type, id and
+// attributes. Existing objects are not overwritten, it returns false if the
+// object already exists.
+func (client *Client) CreateSavedObject(objType, id string, attributes common.MapStr) (bool, error) {
+	body, err := json.Marshal(common.MapStr{"attributes": attributes})
+	if err != nil {
+		return false, fmt.Errorf("fail to marshal the json content: %v", err)
+	}
+
+	statusCode, response, err := client.Request("POST", savedObjectPath(objType, id), nil, nil, bytes.NewBuffer(body))
+	if statusCode == http.StatusConflict {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error creating %s %s: %v", objType, id, err)
+	}
+	if statusCode >= 300 {
+		return false, fmt.Errorf("returned %d creating %s %s. Response: %s", statusCode, objType, id, truncateString(response))
+	}
+	return true, nil
+}
+
+func savedObjectPath(objType, id string) string {
+	return "/api/saved_objects/" + url.PathEscape(objType) + "/" + url.PathEscape(id)
+}
+
 // truncateString returns a truncated string if the length is greater than 250
 // runes. If the string is truncated "... (truncated)" is appended. Newlines are
 // replaced by spaces in the returned string.
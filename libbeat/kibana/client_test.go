@@ -28,6 +28,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
 )
 
 func TestErrorJson(t *testing.T) {
@@ -119,3 +120,45 @@ headers:
 	assert.Equal(t, []string{"1"}, requests[1].Header.Values("kbn-xsrf"))
 
 }
+
+func TestSavedObjects(t *testing.T) {
+	var created []string
+	kibanaTs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/saved_objects/dashboard/existing":
+			w.Write([]byte(`{"id":"existing","type":"dashboard","version":"WzEsMV0=","attributes":{"title":"Existing"}}`))
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"statusCode":404,"error":"Not Found","message":"Saved object not found"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/saved_objects/tag/existing":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"statusCode":409,"error":"Conflict","message":"Saved object conflict"}`))
+		case r.Method == http.MethodPost:
+			created = append(created, r.URL.Path)
+			w.Write([]byte(`{"id":"new","type":"tag"}`))
+		}
+	}))
+	defer kibanaTs.Close()
+
+	client := &Client{
+		Connection: Connection{URL: kibanaTs.URL, HTTP: http.DefaultClient},
+		log:        logp.NewLogger("kibana"),
+	}
+
+	object, err := client.GetSavedObject("dashboard", "existing")
+	require.NoError(t, err)
+	assert.Equal(t, "WzEsMV0=", object["version"])
+
+	object, err = client.GetSavedObject("dashboard", "missing")
+	require.NoError(t, err)
+	assert.Nil(t, object)
+
+	ok, err := client.CreateSavedObject("tag", "existing", common.MapStr{"name": "existing"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = client.CreateSavedObject("tag", "new tag", common.MapStr{"name": "new tag"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"/api/saved_objects/tag/new tag"}, created)
+}
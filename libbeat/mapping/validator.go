@@ -0,0 +1,302 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mapping
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// Violation describes a value of an event that doesn't match the fields.
+type Violation struct {
+	// Field is the full name of the field.
+	Field string
+
+	// Reason explains why the value doesn't match.
+	Reason string
+}
+
+func (v Violation) String() string {
+	return v.Field + ": " + v.Reason
+}
+
+// Validator checks that the events only hold the fields defined in fields.yml,
+// with values matching their types.
+type Validator struct {
+	fields   map[string]*Field
+	patterns []*Field
+	prefixes []string
+}
+
+// NewValidator compiles the fields into a Validator. Fields starting with any
+// of the prefixes are accepted without being checked.
+func NewValidator(fields Fields, prefixes []string) *Validator {
+	v := &Validator{
+		fields:   map[string]*Field{},
+		prefixes: prefixes,
+	}
+	v.compile(fields, "")
+	return v
+}
+
+func (v *Validator) compile(fields Fields, prefix string) {
+	for i := range fields {
+		f := fields[i]
+		if f.Name == "" {
+			continue
+		}
+		name := f.Name
+		if prefix != "" {
+			name = prefix + "." + f.Name
+		}
+		f.Path = prefix
+
+		if f.Type == "group" {
+			if existing, ok := v.fields[name]; !ok || existing.Type == "group" {
+				v.fields[name] = &f
+			}
+			v.compile(f.Fields, name)
+			continue
+		}
+
+		if strings.Contains(name, "*") {
+			v.patterns = append(v.patterns, &f)
+		}
+		v.fields[name] = &f
+		v.addParents(name)
+	}
+}
+
+// addParents registers the intermediate objects of field names with dots, so
+// they are accepted as groups.
+func (v *Validator) addParents(name string) {
+	for i := strings.LastIndex(name, "."); i > 0; i = strings.LastIndex(name[:i], ".") {
+		parent := name[:i]
+		if _, ok := v.fields[parent]; ok {
+			return
+		}
+		v.fields[parent] = &Field{Name: parent, Type: "group"}
+	}
+}
+
+// Validate returns the violations found in the fields of an event.
+func (v *Validator) Validate(fields common.MapStr) []Violation {
+	var violations []Violation
+	v.validateMap(fields, "", &violations)
+	return violations
+}
+
+func (v *Validator) validateMap(m map[string]interface{}, prefix string, violations *[]Violation) {
+	for key, value := range m {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		if v.allowed(name) {
+			continue
+		}
+
+		f := v.lookup(name)
+		if f == nil {
+			*violations = append(*violations, Violation{Field: name, Reason: "field is not defined"})
+			continue
+		}
+
+		if f.Type == "group" {
+			v.validateObject(value, name, violations)
+			continue
+		}
+		if sub, ok := toMap(value); ok && f.Type == "object" {
+			// Subfields are checked against the object type.
+			v.validateMap(sub, name, violations)
+			continue
+		}
+
+		if reason := checkValue(f, value); reason != "" {
+			*violations = append(*violations, Violation{Field: name, Reason: reason})
+		}
+	}
+}
+
+// validateObject validates the value of a group, which can be an object or an
+// array of objects.
+func (v *Validator) validateObject(value interface{}, name string, violations *[]Violation) {
+	if value == nil {
+		return
+	}
+	if m, ok := toMap(value); ok {
+		v.validateMap(m, name, violations)
+		return
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice {
+		for i := 0; i < rv.Len(); i++ {
+			v.validateObject(rv.Index(i).Interface(), name, violations)
+		}
+		return
+	}
+	*violations = append(*violations, Violation{Field: name, Reason: fmt.Sprintf("%T value for an object", value)})
+}
+
+func (v *Validator) allowed(name string) bool {
+	for _, prefix := range v.prefixes {
+		if name == prefix || strings.HasPrefix(name, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Validator) lookup(name string) *Field {
+	if f, ok := v.fields[name]; ok {
+		return f
+	}
+	for _, f := range v.patterns {
+		pattern := f.Name
+		if f.Path != "" {
+			pattern = f.Path + "." + f.Name
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			return f
+		}
+	}
+
+	// Subfields of objects are accepted with the values of their object type.
+	for i := strings.LastIndex(name, "."); i > 0; i = strings.LastIndex(name[:i], ".") {
+		if f, ok := v.fields[name[:i]]; ok {
+			switch f.Type {
+			case "object", "flattened", "nested":
+				return &Field{Name: name[i+1:], Path: name[:i], Type: "object", ObjectType: f.ObjectType}
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// checkValue returns the reason why a value doesn't match the type of a field,
+// or an empty string if it matches.
+func checkValue(f *Field, value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	switch f.Type {
+	case "object":
+		if f.ObjectType == "" {
+			return ""
+		}
+		return checkValue(&Field{Type: f.ObjectType}, value)
+	case "flattened", "nested", "alias", "geo_point", "geo_shape", "histogram":
+		if f.Type == "alias" {
+			return "values can't be written to aliases"
+		}
+		return ""
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < rv.Len(); i++ {
+			if reason := checkValue(f, rv.Index(i).Interface()); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	}
+	if _, ok := toMap(value); ok {
+		return fmt.Sprintf("object value for a field of type %v", typeName(f))
+	}
+
+	var valid bool
+	switch f.Type {
+	case "", "keyword", "text", "wildcard", "match_only_text", "constant_keyword", "version":
+		valid = true
+	case "long", "integer", "short", "byte", "unsigned_long":
+		valid = isNumber(rv) || isNumericString(value)
+	case "float", "double", "half_float", "scaled_float":
+		valid = isNumber(rv) || isNumericString(value)
+	case "boolean":
+		switch b := value.(type) {
+		case bool:
+			valid = true
+		case string:
+			valid = b == "true" || b == "false" || b == ""
+		}
+	case "date", "date_nanos":
+		switch value.(type) {
+		case time.Time, common.Time, string:
+			valid = true
+		default:
+			valid = isNumber(rv)
+		}
+	case "ip":
+		if s, ok := value.(string); ok {
+			valid = net.ParseIP(s) != nil
+		}
+	default:
+		valid = true
+	}
+	if !valid {
+		return fmt.Sprintf("%T value %v doesn't match type %v", value, value, typeName(f))
+	}
+	return ""
+}
+
+func typeName(f *Field) string {
+	if f.Type == "" {
+		return "keyword"
+	}
+	return f.Type
+}
+
+func toMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case common.MapStr:
+		return m, true
+	case map[string]interface{}:
+		return m, true
+	}
+	return nil, false
+}
+
+func isNumber(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func isNumericString(value interface{}) bool {
+	s, ok := value.(string)
+	if !ok {
+		return false
+	}
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package mapping
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestValidator(t *testing.T) {
+	fields, err := LoadFields([]byte(`
+- key: test
+  title: Test
+  fields:
+    - name: "@timestamp"
+      type: date
+    - name: message
+      type: text
+    - name: labels
+      type: object
+      object_type: keyword
+    - name: metrics
+      type: object
+      object_type: long
+    - name: source.ip
+      type: ip
+    - name: process
+      type: group
+      fields:
+        - name: pid
+          type: long
+        - name: args
+          type: keyword
+        - name: parent.name
+    - name: kubernetes.annotations.*
+      type: object
+      object_type: keyword
+    - name: host.os
+      type: alias
+      path: os
+    - name: enabled
+      type: boolean
+`))
+	require.NoError(t, err)
+	v := NewValidator(fields, []string{"custom"})
+
+	cases := map[string]struct {
+		event common.MapStr
+		want  []Violation
+	}{
+		"valid event": {
+			event: common.MapStr{
+				"@timestamp": time.Now(),
+				"message":    "hello",
+				"labels":     common.MapStr{"env": "production"},
+				"metrics":    common.MapStr{"requests": common.MapStr{"count": 10}},
+				"source":     common.MapStr{"ip": "192.168.0.1"},
+				"process": common.MapStr{
+					"pid":    1234,
+					"args":   []string{"-v", "-f"},
+					"parent": map[string]interface{}{"name": "init"},
+				},
+				"kubernetes": common.MapStr{"annotations": common.MapStr{"app": "nginx"}},
+				"enabled":    "true",
+				"custom":     common.MapStr{"anything": 1},
+			},
+		},
+		"undefined field": {
+			event: common.MapStr{"process": common.MapStr{"name": "x"}},
+			want:  []Violation{{Field: "process.name", Reason: "field is not defined"}},
+		},
+		"dotted keys": {
+			event: common.MapStr{"process.pid": 1, "process.parent.name": "init"},
+		},
+		"type mismatch": {
+			event: common.MapStr{"process": common.MapStr{"pid": "abc"}},
+			want:  []Violation{{Field: "process.pid", Reason: "string value abc doesn't match type long"}},
+		},
+		"invalid ip": {
+			event: common.MapStr{"source.ip": "localhost"},
+			want:  []Violation{{Field: "source.ip", Reason: "string value localhost doesn't match type ip"}},
+		},
+		"object for a keyword": {
+			event: common.MapStr{"process": common.MapStr{"args": common.MapStr{"a": "b"}}},
+			want:  []Violation{{Field: "process.args", Reason: "object value for a field of type keyword"}},
+		},
+		"value for a group": {
+			event: common.MapStr{"process": "x"},
+			want:  []Violation{{Field: "process", Reason: "string value for an object"}},
+		},
+		"object type mismatch": {
+			event: common.MapStr{"metrics": common.MapStr{"requests": common.MapStr{"count": "many"}}},
+			want:  []Violation{{Field: "metrics.requests.count", Reason: "string value many doesn't match type long"}},
+		},
+		"alias": {
+			event: common.MapStr{"host": common.MapStr{"os": "linux"}},
+			want:  []Violation{{Field: "host.os", Reason: "values can't be written to aliases"}},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.want, v.Validate(test.event))
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memgov
+
+import (
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+)
+
+// Names of the budgets of the subsystems of libbeat.
+const (
+	// QueueBudget limits the memory used by the events in the queue.
+	QueueBudget = "queue"
+
+	// HarvestersBudget limits the memory used by the buffers of the
+	// harvesters reading files.
+	HarvestersBudget = "harvesters"
+)
+
+// registry of the budgets by name.
+var budgets = struct {
+	sync.Mutex
+	m map[string]*Budget
+}{m: map[string]*Budget{}}
+
+// Budget limits the memory reserved by a subsystem. Memory is reserved
+// before it is used, and released once it can be collected. Reservations
+// exceeding the limit of the budget block or fail until enough memory is
+// released. All reservations wait while the memory governor reports that the
+// memory usage of the Beat is too high.
+//
+// A Budget without a limit only tracks the reserved memory.
+type Budget struct {
+	name   string
+	parent *Budget
+
+	limit atomic.Int64
+
+	mu   sync.Mutex
+	used int64
+
+	// released is closed when memory is released and a reservation is
+	// waiting for it.
+	released chan struct{}
+	waiting  bool
+}
+
+// Get returns the budget with the given name. The budget is created if it
+// does not exist, its limit is configured by the memory governor.
+func Get(name string) *Budget {
+	budgets.Lock()
+	defer budgets.Unlock()
+
+	b := budgets.m[name]
+	if b == nil {
+		b = newBudget(name, nil, 0)
+		budgets.m[name] = b
+	}
+	return b
+}
+
+func newBudget(name string, parent *Budget, limit int64) *Budget {
+	b := &Budget{name: name, parent: parent, released: make(chan struct{})}
+	b.limit.Store(limit)
+	return b
+}
+
+// Child returns a new budget limited to limit bytes, a limit of 0 disables the
+// limit. The memory reserved in a child budget is reserved in b too. Child
+// budgets are not registered, so they can be created for every input.
+func (b *Budget) Child(name string, limit int64) *Budget {
+	return newBudget(b.name+"."+name, b, limit)
+}
+
+// Name returns the name of the budget.
+func (b *Budget) Name() string {
+	return b.name
+}
+
+// Limit returns the limit of the budget, or 0 if it has no limit.
+func (b *Budget) Limit() int64 {
+	return b.limit.Load()
+}
+
+// Used returns the memory reserved in the budget.
+func (b *Budget) Used() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used
+}
+
+// Active returns true if the reservations can be limited, either because the
+// memory governor is running, or because b or one of its parents have a
+// limit. Subsystems can skip estimating the memory they use if the budget is
+// not active.
+func (b *Budget) Active() bool {
+	if running.Load() {
+		return true
+	}
+	for cur := b; cur != nil; cur = cur.parent {
+		if cur.Limit() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Budget) setLimit(limit int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limit.Store(limit)
+	b.notify()
+}
+
+// TryReserve reserves n bytes if they are available, it returns false
+// otherwise.
+func (b *Budget) TryReserve(n int64) bool {
+	ok, _ := b.tryReserve(n)
+	return ok
+}
+
+// Reserve reserves n bytes, waiting for them to be available. It returns
+// false if done is closed before the memory could be reserved.
+func (b *Budget) Reserve(done <-chan struct{}, n int64) bool {
+	for {
+		ok, wait := b.tryReserve(n)
+		if ok {
+			return true
+		}
+
+		select {
+		case <-wait:
+		case <-done:
+			return false
+		}
+	}
+}
+
+// tryReserve reserves n bytes in b and all its parents. If a budget does not
+// have enough memory available, the reservation is rolled back and a channel
+// closed once memory is released in that budget is returned.
+//
+// A reservation is always accepted by an empty budget, so a reservation larger
+// than the limit does not block forever.
+func (b *Budget) tryReserve(n int64) (bool, <-chan struct{}) {
+	if wait := pressureWait(); wait != nil {
+		return false, wait
+	}
+
+	for cur := b; cur != nil; cur = cur.parent {
+		cur.mu.Lock()
+		if limit := cur.limit.Load(); limit > 0 && cur.used > 0 && cur.used+n > limit {
+			cur.waiting = true
+			wait := cur.released
+			cur.mu.Unlock()
+
+			for r := b; r != cur; r = r.parent {
+				r.release(n)
+			}
+			return false, wait
+		}
+		cur.used += n
+		cur.mu.Unlock()
+	}
+	return true, nil
+}
+
+// Release releases n bytes reserved in the budget.
+func (b *Budget) Release(n int64) {
+	if n <= 0 {
+		return
+	}
+	for cur := b; cur != nil; cur = cur.parent {
+		cur.release(n)
+	}
+}
+
+func (b *Budget) release(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.used -= n
+	if b.used < 0 {
+		b.used = 0
+	}
+	b.notify()
+}
+
+// notify wakes up the reservations waiting for memory, b.mu must be held.
+func (b *Budget) notify() {
+	if b.waiting {
+		close(b.released)
+		b.released = make(chan struct{})
+		b.waiting = false
+	}
+}
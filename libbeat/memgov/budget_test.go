@@ -0,0 +1,115 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memgov
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetReserve(t *testing.T) {
+	b := newBudget("test", nil, 100)
+
+	assert.True(t, b.TryReserve(60))
+	assert.True(t, b.TryReserve(40))
+	assert.False(t, b.TryReserve(1))
+	assert.Equal(t, int64(100), b.Used())
+
+	b.Release(50)
+	assert.True(t, b.TryReserve(50))
+	b.Release(100)
+	assert.Equal(t, int64(0), b.Used())
+
+	// an empty budget accepts reservations larger than its limit
+	assert.True(t, b.TryReserve(1000))
+	assert.False(t, b.TryReserve(1))
+}
+
+func TestBudgetUnlimited(t *testing.T) {
+	b := newBudget("test", nil, 0)
+	assert.False(t, b.Active())
+	assert.True(t, b.TryReserve(1<<40))
+	assert.True(t, b.TryReserve(1<<40))
+}
+
+func TestBudgetChild(t *testing.T) {
+	parent := newBudget("parent", nil, 100)
+	a := parent.Child("a", 60)
+	b := parent.Child("b", 0)
+	assert.Equal(t, "parent.a", a.Name())
+	assert.True(t, b.Active())
+
+	assert.True(t, a.TryReserve(60))
+	assert.False(t, a.TryReserve(10))
+	assert.True(t, b.TryReserve(40))
+	assert.False(t, b.TryReserve(10))
+	assert.Equal(t, int64(100), parent.Used())
+
+	// a failed reservation is rolled back in the child
+	a.Release(60)
+	assert.False(t, b.TryReserve(70))
+	assert.Equal(t, int64(40), b.Used())
+	assert.Equal(t, int64(40), parent.Used())
+}
+
+func TestBudgetReserveWaits(t *testing.T) {
+	b := newBudget("test", nil, 100)
+	assert.True(t, b.TryReserve(100))
+
+	reserved := make(chan bool)
+	go func() {
+		reserved <- b.Reserve(nil, 50)
+	}()
+
+	select {
+	case <-reserved:
+		t.Fatal("reservation did not wait for the memory to be released")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	b.Release(50)
+	assert.True(t, <-reserved)
+	assert.Equal(t, int64(100), b.Used())
+}
+
+func TestBudgetReserveDone(t *testing.T) {
+	b := newBudget("test", nil, 100)
+	assert.True(t, b.TryReserve(100))
+
+	done := make(chan struct{})
+	close(done)
+	assert.False(t, b.Reserve(done, 50))
+	assert.Equal(t, int64(100), b.Used())
+}
+
+func TestBudgetPressure(t *testing.T) {
+	b := newBudget("test", nil, 0)
+
+	setPressure(true)
+	assert.True(t, Pressure())
+	assert.False(t, b.TryReserve(1))
+
+	reserved := make(chan bool)
+	go func() {
+		reserved <- b.Reserve(nil, 1)
+	}()
+	setPressure(false)
+	assert.True(t, <-reserved)
+}
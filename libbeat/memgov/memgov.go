@@ -0,0 +1,335 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package memgov implements the memory governor of the Beat. The governor
+// reads the memory limit of the cgroup of the Beat and divides it in budgets
+// for the subsystems using most of the memory, like the queue or the buffers
+// of the harvesters. The memory usage is checked periodically, when it
+// exceeds a high watermark the registered caches are shrunk and all new
+// reservations wait, applying backpressure to the inputs, until the usage
+// goes below a low watermark. This keeps the Beat from being killed for
+// exceeding its memory limit.
+package memgov
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/common/cfgtype"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+)
+
+// Config configures the memory governor.
+type Config struct {
+	Enabled bool `config:"enabled"`
+
+	// Limit is the memory available to the Beat, the limit of its cgroup is
+	// used if 0.
+	Limit cfgtype.ByteSize `config:"limit" validate:"min=0"`
+
+	CheckInterval time.Duration `config:"check_interval" validate:"positive,nonzero"`
+
+	// HighWatermark and LowWatermark are fractions of the limit, the memory
+	// pressure starts above the high watermark and ends below the low one.
+	HighWatermark float64 `config:"high_watermark"`
+	LowWatermark  float64 `config:"low_watermark"`
+
+	// Budgets are the fractions of the limit available to every subsystem.
+	Budgets map[string]float64 `config:"budgets"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		CheckInterval: 5 * time.Second,
+		HighWatermark: 0.9,
+		LowWatermark:  0.8,
+		Budgets: map[string]float64{
+			QueueBudget:      0.5,
+			HarvestersBudget: 0.1,
+		},
+	}
+}
+
+// Validate checks the watermarks and the budgets.
+func (c *Config) Validate() error {
+	if c.LowWatermark <= 0 || c.LowWatermark >= c.HighWatermark || c.HighWatermark > 1 {
+		return fmt.Errorf("invalid watermarks, 0 < low_watermark (%v) < high_watermark (%v) <= 1 is required",
+			c.LowWatermark, c.HighWatermark)
+	}
+	for name, fraction := range c.Budgets {
+		if fraction < 0 || fraction > 1 {
+			return fmt.Errorf("invalid budget %v for %v, must be between 0 and 1", fraction, name)
+		}
+	}
+	return nil
+}
+
+// governor checks the memory usage of the Beat.
+type governor struct {
+	log    *logp.Logger
+	config Config
+	limit  int64
+	source memorySource
+
+	usage    atomic.Int64
+	done     chan struct{}
+	wg       sync.WaitGroup
+	pressure bool
+}
+
+var (
+	// current is the running governor, or nil.
+	current struct {
+		sync.Mutex
+		g *governor
+	}
+
+	// running is set while a governor is running.
+	running atomic.Bool
+
+	registerMetrics sync.Once
+)
+
+// Start starts the memory governor configured by cfg, if it is enabled. A
+// governor already running is stopped first.
+func Start(cfg *common.Config) error {
+	config := defaultConfig()
+	if cfg != nil {
+		if err := cfg.Unpack(&config); err != nil {
+			return err
+		}
+	}
+
+	Stop()
+	if !config.Enabled {
+		return nil
+	}
+
+	log := logp.NewLogger("memgov")
+	source := newCgroupSource()
+	limit := int64(config.Limit)
+	if limit == 0 {
+		if source == nil {
+			return fmt.Errorf("the memory governor requires a limit, set memory_governor.limit or run the Beat in a cgroup with a memory limit")
+		}
+		limit = source.limit()
+	}
+	if source == nil {
+		log.Info("No cgroup found, the Go runtime statistics are used to measure the memory usage")
+		source = runtimeSource{}
+	}
+
+	g := &governor{log: log, config: config, limit: limit, source: source, done: make(chan struct{})}
+	for name, fraction := range config.Budgets {
+		Get(name).setLimit(int64(fraction * float64(limit)))
+	}
+	registerMetrics.Do(func() {
+		reg := monitoring.Default.GetRegistry("libbeat")
+		if reg == nil {
+			reg = monitoring.Default.NewRegistry("libbeat")
+		}
+		monitoring.NewFunc(reg, "memgov", reportMetrics, monitoring.Report)
+	})
+
+	current.Lock()
+	current.g = g
+	current.Unlock()
+	running.Store(true)
+
+	log.Infof("Memory governor started with a limit of %d bytes", limit)
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		g.run()
+	}()
+	return nil
+}
+
+// Stop stops the memory governor. The budgets are not limited any more, and
+// the reservations waiting for the memory pressure to end are resumed.
+func Stop() {
+	current.Lock()
+	g := current.g
+	current.g = nil
+	current.Unlock()
+	if g == nil {
+		return
+	}
+
+	close(g.done)
+	g.wg.Wait()
+	running.Store(false)
+	setPressure(false)
+	for name := range g.config.Budgets {
+		Get(name).setLimit(0)
+	}
+}
+
+func (g *governor) run() {
+	ticker := time.NewTicker(g.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		g.check()
+
+		select {
+		case <-g.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check measures the memory usage and starts or ends the memory pressure.
+func (g *governor) check() {
+	usage, err := g.source.usage()
+	if err != nil {
+		g.log.Errorf("Failed to read the memory usage: %v", err)
+		return
+	}
+	g.usage.Store(usage)
+
+	high := int64(g.config.HighWatermark * float64(g.limit))
+	low := int64(g.config.LowWatermark * float64(g.limit))
+	switch {
+	case usage >= high:
+		if !g.pressure {
+			g.log.Warnf("Memory usage of %d bytes exceeds the high watermark of %d bytes, pausing the reservations", usage, high)
+			g.pressure = true
+			setPressure(true)
+		}
+		shrink()
+		debug.FreeOSMemory()
+
+	case g.pressure && usage < low:
+		g.log.Infof("Memory usage of %d bytes is below the low watermark of %d bytes, resuming the reservations", usage, low)
+		g.pressure = false
+		setPressure(false)
+	}
+}
+
+// pressure is the state of the memory pressure. wait is closed when the
+// pressure ends.
+var pressure struct {
+	sync.Mutex
+	on   atomic.Bool
+	wait chan struct{}
+}
+
+func setPressure(on bool) {
+	pressure.Lock()
+	defer pressure.Unlock()
+
+	if on == pressure.on.Load() {
+		return
+	}
+	if on {
+		pressure.wait = make(chan struct{})
+	} else {
+		close(pressure.wait)
+		pressure.wait = nil
+	}
+	pressure.on.Store(on)
+}
+
+// pressureWait returns a channel closed when the memory pressure ends, or nil
+// if there is no memory pressure.
+func pressureWait() <-chan struct{} {
+	if !pressure.on.Load() {
+		return nil
+	}
+
+	pressure.Lock()
+	defer pressure.Unlock()
+	if pressure.wait == nil {
+		return nil
+	}
+	return pressure.wait
+}
+
+// Pressure returns true if the memory usage of the Beat exceeds the high
+// watermark of the memory governor.
+func Pressure() bool {
+	return pressure.on.Load()
+}
+
+// shrinkers are called under memory pressure.
+var shrinkers = struct {
+	sync.Mutex
+	next int
+	m    map[int]func()
+}{m: map[int]func(){}}
+
+// RegisterShrinker registers fn to be called when the memory usage exceeds
+// the high watermark. fn must free memory that can be recovered later, like
+// the entries of a cache. The returned function unregisters fn.
+func RegisterShrinker(fn func()) (unregister func()) {
+	shrinkers.Lock()
+	defer shrinkers.Unlock()
+
+	id := shrinkers.next
+	shrinkers.next++
+	shrinkers.m[id] = fn
+	return func() {
+		shrinkers.Lock()
+		defer shrinkers.Unlock()
+		delete(shrinkers.m, id)
+	}
+}
+
+func shrink() {
+	shrinkers.Lock()
+	fns := make([]func(), 0, len(shrinkers.m))
+	for _, fn := range shrinkers.m {
+		fns = append(fns, fn)
+	}
+	shrinkers.Unlock()
+
+	for _, fn := range fns {
+		fn()
+	}
+}
+
+func reportMetrics(_ monitoring.Mode, V monitoring.Visitor) {
+	V.OnRegistryStart()
+	defer V.OnRegistryFinished()
+
+	current.Lock()
+	g := current.g
+	current.Unlock()
+	if g == nil {
+		return
+	}
+
+	monitoring.ReportInt(V, "limit", g.limit)
+	monitoring.ReportInt(V, "usage", g.usage.Load())
+	monitoring.ReportBool(V, "pressure", Pressure())
+	monitoring.ReportNamespace(V, "budgets", func() {
+		for name := range g.config.Budgets {
+			b := Get(name)
+			monitoring.ReportNamespace(V, name, func() {
+				monitoring.ReportInt(V, "limit", b.Limit())
+				monitoring.ReportInt(V, "used", b.Used())
+			})
+		}
+	})
+}
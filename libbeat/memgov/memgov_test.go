@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memgov
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+type testSource struct {
+	used int64
+}
+
+func (s *testSource) limit() int64 { return 0 }
+
+func (s *testSource) usage() (int64, error) { return s.used, nil }
+
+func TestConfigValidate(t *testing.T) {
+	cases := map[string]struct {
+		config common.MapStr
+		valid  bool
+	}{
+		"default":               {config: common.MapStr{}, valid: true},
+		"low above high":        {config: common.MapStr{"low_watermark": 0.95}},
+		"high above 1":          {config: common.MapStr{"high_watermark": 1.5}},
+		"negative low":          {config: common.MapStr{"low_watermark": -0.1}},
+		"invalid budget":        {config: common.MapStr{"budgets.queue": 2}},
+		"custom budget":         {config: common.MapStr{"budgets.queue": 0.3}, valid: true},
+		"zero check interval":   {config: common.MapStr{"check_interval": 0}},
+		"limit with unit":       {config: common.MapStr{"limit": "512MiB"}, valid: true},
+		"negative limit":        {config: common.MapStr{"limit": -1}},
+		"high watermark at 1.0": {config: common.MapStr{"high_watermark": 1.0}, valid: true},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := defaultConfig()
+			err := common.MustNewConfigFrom(test.config).Unpack(&config)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	err := Start(common.MustNewConfigFrom(common.MapStr{
+		"enabled":       true,
+		"limit":         1000,
+		"budgets.queue": 0.5,
+	}))
+	require.NoError(t, err)
+	assert.True(t, Get(QueueBudget).Active())
+	assert.Equal(t, int64(500), Get(QueueBudget).Limit())
+
+	Stop()
+	assert.False(t, Get(QueueBudget).Active())
+	assert.Equal(t, int64(0), Get(QueueBudget).Limit())
+}
+
+func TestStartDisabled(t *testing.T) {
+	require.NoError(t, Start(nil))
+	assert.False(t, running.Load())
+}
+
+func TestGovernorCheck(t *testing.T) {
+	defer setPressure(false)
+
+	source := &testSource{}
+	g := &governor{
+		log:    logp.NewLogger("memgov"),
+		config: defaultConfig(),
+		limit:  1000,
+		source: source,
+	}
+
+	shrunk := 0
+	unregister := RegisterShrinker(func() { shrunk++ })
+	defer unregister()
+
+	source.used = 500
+	g.check()
+	assert.False(t, Pressure())
+	assert.Equal(t, 0, shrunk)
+
+	source.used = 950
+	g.check()
+	assert.True(t, Pressure())
+	assert.Equal(t, 1, shrunk)
+
+	// the pressure continues until the usage is below the low watermark
+	source.used = 850
+	g.check()
+	assert.True(t, Pressure())
+
+	source.used = 700
+	g.check()
+	assert.False(t, Pressure())
+
+	unregister()
+	source.used = 950
+	g.check()
+	assert.Equal(t, 1, shrunk)
+}
@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memgov
+
+import (
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// Approximate sizes of the Go values in an event.
+const (
+	eventOverhead     = 128
+	mapOverhead       = 48
+	mapEntryOverhead  = 16
+	stringOverhead    = 16
+	sliceOverhead     = 24
+	interfaceOverhead = 16
+)
+
+// EventSize estimates the memory used by an event. The estimation is cheap
+// enough to be done for every published event, and is accurate enough to
+// limit the memory used by many events.
+func EventSize(event *beat.Event) int64 {
+	return eventOverhead + valueSize(event.Fields) + valueSize(event.Meta)
+}
+
+func valueSize(v interface{}) int64 {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return stringOverhead + int64(len(v))
+	case []byte:
+		return sliceOverhead + int64(len(v))
+	case common.MapStr:
+		return mapSize(v)
+	case map[string]interface{}:
+		return mapSize(v)
+	case []interface{}:
+		size := int64(sliceOverhead)
+		for _, elem := range v {
+			size += interfaceOverhead + valueSize(elem)
+		}
+		return size
+	case []string:
+		size := int64(sliceOverhead)
+		for _, s := range v {
+			size += stringOverhead + int64(len(s))
+		}
+		return size
+	case []common.MapStr:
+		size := int64(sliceOverhead)
+		for _, m := range v {
+			size += mapSize(m)
+		}
+		return size
+	case time.Time, common.Time:
+		return 24
+	default:
+		return interfaceOverhead
+	}
+}
+
+func mapSize(m map[string]interface{}) int64 {
+	size := int64(mapOverhead)
+	for k, v := range m {
+		size += mapEntryOverhead + stringOverhead + int64(len(k)) + interfaceOverhead + valueSize(v)
+	}
+	return size
+}
@@ -0,0 +1,65 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memgov
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestEventSize(t *testing.T) {
+	small := EventSize(&beat.Event{
+		Timestamp: time.Now(),
+		Fields:    common.MapStr{"message": "short"},
+	})
+	large := EventSize(&beat.Event{
+		Timestamp: time.Now(),
+		Fields: common.MapStr{
+			"message": string(make([]byte, 10000)),
+			"host":    common.MapStr{"name": "test", "ip": []string{"127.0.0.1", "::1"}},
+			"tags":    []interface{}{"a", "b"},
+		},
+		Meta: common.MapStr{"pipeline": "test"},
+	})
+
+	assert.True(t, small > eventOverhead)
+	assert.True(t, large > 10000)
+	assert.True(t, large < 11000)
+}
+
+func BenchmarkEventSize(b *testing.B) {
+	event := &beat.Event{
+		Timestamp: time.Now(),
+		Fields: common.MapStr{
+			"message": "a log message of a typical size, with some more words to make it longer",
+			"host":    common.MapStr{"name": "test", "ip": []string{"127.0.0.1", "::1"}},
+			"log":     common.MapStr{"offset": 12345, "file": common.MapStr{"path": "/var/log/messages"}},
+			"input":   common.MapStr{"type": "filestream"},
+		},
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		EventSize(event)
+	}
+}
@@ -0,0 +1,194 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memgov
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/gosigar/cgroup"
+)
+
+// memorySource measures the memory usage of the Beat.
+type memorySource interface {
+	// limit returns the memory limit, or 0 if there is no limit.
+	limit() int64
+
+	// usage returns the memory used, not counting the memory the kernel can
+	// reclaim without killing the Beat, like inactive file pages.
+	usage() (int64, error)
+}
+
+// unlimited is the smallest memory limit considered as no limit, cgroups v1
+// report the maximum page aligned int64 if no limit is set.
+const unlimited = 1 << 62
+
+// newCgroupSource returns the source reading the memory cgroup of the Beat,
+// or nil if the Beat does not run in a memory cgroup with a limit.
+func newCgroupSource() memorySource {
+	if s, err := newCgroupV2Source("/proc/self/cgroup", "/sys/fs/cgroup"); err == nil {
+		return s
+	}
+	if s, err := newCgroupV1Source(); err == nil {
+		return s
+	}
+	return nil
+}
+
+// cgroupV1Source reads the memory controller of cgroups v1.
+type cgroupV1Source struct {
+	reader *cgroup.Reader
+	max    int64
+}
+
+func newCgroupV1Source() (*cgroupV1Source, error) {
+	reader, err := cgroup.NewReader("", true)
+	if err != nil {
+		return nil, err
+	}
+	s := &cgroupV1Source{reader: reader}
+	mem, err := s.memory()
+	if err != nil {
+		return nil, err
+	}
+	if mem.Mem.Limit == 0 || mem.Mem.Limit >= unlimited {
+		return nil, errors.New("no memory limit")
+	}
+	s.max = int64(mem.Mem.Limit)
+	return s, nil
+}
+
+func (s *cgroupV1Source) memory() (*cgroup.MemorySubsystem, error) {
+	stats, err := s.reader.GetStatsForProcess(os.Getpid())
+	if err != nil {
+		return nil, err
+	}
+	if stats == nil || stats.Memory == nil {
+		return nil, errors.New("no memory cgroup")
+	}
+	return stats.Memory, nil
+}
+
+func (s *cgroupV1Source) limit() int64 { return s.max }
+
+func (s *cgroupV1Source) usage() (int64, error) {
+	mem, err := s.memory()
+	if err != nil {
+		return 0, err
+	}
+	return workingSet(mem.Mem.Usage, mem.Stats.InactiveFile), nil
+}
+
+// cgroupV2Source reads the memory controller of the unified hierarchy of
+// cgroups v2.
+type cgroupV2Source struct {
+	path string
+	max  int64
+}
+
+func newCgroupV2Source(procCgroup, mountpoint string) (*cgroupV2Source, error) {
+	content, err := ioutil.ReadFile(procCgroup)
+	if err != nil {
+		return nil, err
+	}
+
+	// The unified hierarchy is the only one listed with ID 0.
+	var path string
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			path = filepath.Join(mountpoint, strings.TrimPrefix(line, "0::"))
+			break
+		}
+	}
+	if path == "" {
+		return nil, errors.New("no cgroup v2 hierarchy")
+	}
+
+	max, err := ioutil.ReadFile(filepath.Join(path, "memory.max"))
+	if err != nil {
+		return nil, err
+	}
+	value := string(bytes.TrimSpace(max))
+	if value == "max" {
+		return nil, errors.New("no memory limit")
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid memory.max: %v", err)
+	}
+	return &cgroupV2Source{path: path, max: limit}, nil
+}
+
+func (s *cgroupV2Source) limit() int64 { return s.max }
+
+func (s *cgroupV2Source) usage() (int64, error) {
+	current, err := ioutil.ReadFile(filepath.Join(s.path, "memory.current"))
+	if err != nil {
+		return 0, err
+	}
+	usage, err := strconv.ParseUint(string(bytes.TrimSpace(current)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory.current: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(s.path, "memory.stat"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var inactiveFile uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "inactive_file" {
+			inactiveFile, _ = strconv.ParseUint(fields[1], 10, 64)
+			break
+		}
+	}
+	return workingSet(usage, inactiveFile), scanner.Err()
+}
+
+// workingSet returns the memory that can not be reclaimed by the kernel, as
+// used by the kubelet to decide which containers to evict.
+func workingSet(usage, inactiveFile uint64) int64 {
+	if inactiveFile > usage {
+		return 0
+	}
+	return int64(usage - inactiveFile)
+}
+
+// runtimeSource measures the memory obtained from the OS by the Go runtime,
+// it is used if the Beat does not run in a cgroup.
+type runtimeSource struct{}
+
+func (runtimeSource) limit() int64 { return 0 }
+
+func (runtimeSource) usage() (int64, error) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return int64(stats.Sys - stats.HeapReleased), nil
+}
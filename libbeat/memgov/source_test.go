@@ -0,0 +1,66 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memgov
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCgroupV2Source(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memgov")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(path, content string) {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0700))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+	write("proc/self/cgroup", "0::/system.slice/filebeat.service\n")
+	write("cgroup/system.slice/filebeat.service/memory.max", "1073741824\n")
+	write("cgroup/system.slice/filebeat.service/memory.current", "536870912\n")
+	write("cgroup/system.slice/filebeat.service/memory.stat", "anon 268435456\nfile 268435456\ninactive_file 134217728\n")
+
+	s, err := newCgroupV2Source(filepath.Join(dir, "proc/self/cgroup"), filepath.Join(dir, "cgroup"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1073741824), s.limit())
+
+	usage, err := s.usage()
+	require.NoError(t, err)
+	assert.Equal(t, int64(536870912-134217728), usage)
+
+	write("cgroup/system.slice/filebeat.service/memory.max", "max\n")
+	_, err = newCgroupV2Source(filepath.Join(dir, "proc/self/cgroup"), filepath.Join(dir, "cgroup"))
+	assert.Error(t, err)
+
+	write("proc/self/cgroup", "4:memory:/docker/abc\n")
+	_, err = newCgroupV2Source(filepath.Join(dir, "proc/self/cgroup"), filepath.Join(dir, "cgroup"))
+	assert.Error(t, err)
+}
+
+func TestRuntimeSource(t *testing.T) {
+	usage, err := runtimeSource{}.usage()
+	require.NoError(t, err)
+	assert.True(t, usage > 0)
+}
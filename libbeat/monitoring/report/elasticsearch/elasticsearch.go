@@ -177,6 +177,11 @@ func makeReporter(beat beat.Info, settings report.Settings, cfg *common.Config)
 			Clients:   []outputs.Client{outClient},
 			BatchSize: windowSize,
 			Retry:     0, // no retry. Drop event on error.
+			Backoff: &outputs.BackoffConfig{
+				Init:   config.Backoff.Init,
+				Max:    config.Backoff.Max,
+				Jitter: outputs.DefaultBackoffConfig().Jitter,
+			},
 		},
 		pipeline.Settings{
 			WaitClose:     0,
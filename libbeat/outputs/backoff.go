@@ -27,6 +27,31 @@ import (
 	"github.com/elastic/beats/v7/libbeat/testing"
 )
 
+// BackoffConfig configures the wait between the attempts of the publisher
+// pipeline to connect a network client.
+type BackoffConfig struct {
+	// Init is the wait after the first failed attempt, it doubles after each
+	// new failure.
+	Init time.Duration `config:"init" validate:"nonzero"`
+
+	// Max is the maximum wait between attempts.
+	Max time.Duration `config:"max" validate:"nonzero"`
+
+	// Jitter is the maximum fraction of the wait added at random, so clients
+	// don't all reconnect at the same time.
+	Jitter float64 `config:"jitter" validate:"min=0, max=1"`
+}
+
+// DefaultBackoffConfig returns the backoff used by network clients of outputs
+// that don't configure one.
+func DefaultBackoffConfig() BackoffConfig {
+	return BackoffConfig{
+		Init:   1 * time.Second,
+		Max:    60 * time.Second,
+		Jitter: 0.5,
+	}
+}
+
 type backoffClient struct {
 	client NetworkClient
 
@@ -34,7 +59,8 @@ type backoffClient struct {
 	backoff backoff.Backoff
 }
 
-// WithBackoff wraps a NetworkClient, adding exponential backoff support to a network client if connection/publishing failed.
+// WithBackoff wraps a NetworkClient, adding exponential backoff support to a network client if publishing failed.
+// The backoff of failed connection attempts is handled by the publisher pipeline, see BackoffConfig.
 func WithBackoff(client NetworkClient, init, max time.Duration) NetworkClient {
 	done := make(chan struct{})
 	backoff := backoff.NewEqualJitterBackoff(done, init, max)
@@ -47,7 +73,9 @@ func WithBackoff(client NetworkClient, init, max time.Duration) NetworkClient {
 
 func (b *backoffClient) Connect() error {
 	err := b.client.Connect()
-	backoff.WaitOnError(b.backoff, err)
+	if err == nil {
+		b.backoff.Reset()
+	}
 	return err
 }
 
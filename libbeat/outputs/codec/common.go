@@ -18,6 +18,7 @@
 package codec
 
 import (
+	"strings"
 	"time"
 
 	"github.com/elastic/beats/v7/libbeat/common"
@@ -34,18 +35,14 @@ func MakeTimestampEncoder() func(*time.Time, structform.ExtVisitor) error {
 // MakeUTCOrLocalTimestampEncoder creates encoder function that formats time into RFC3339 representation
 // with UTC or local timezone in the output (based on localTime boolean parameter).
 func MakeUTCOrLocalTimestampEncoder(localTime bool) func(*time.Time, structform.ExtVisitor) error {
-	var dtPattern string
-	if localTime {
-		dtPattern = "yyyy-MM-dd'T'HH:mm:ss.SSSz"
-	} else {
-		dtPattern = "yyyy-MM-dd'T'HH:mm:ss.SSS'Z'"
-	}
-
-	formatter, err := dtfmt.NewFormatter(dtPattern)
-	if err != nil {
-		panic(err)
-	}
+	return MakeTimestampEncoderWithPrecision(localTime, common.TimestampMillisecond)
+}
 
+// MakeTimestampEncoderWithPrecision creates encoder function that formats time
+// into RFC3339 representation with UTC or local timezone in the output, and
+// as many fractional digits as required by precision.
+func MakeTimestampEncoderWithPrecision(localTime bool, precision common.TimestampPrecision) func(*time.Time, structform.ExtVisitor) error {
+	formatter := NewTimestampFormatter(localTime, precision)
 	buf := make([]byte, 0, formatter.EstimateSize())
 	return func(t *time.Time, v structform.ExtVisitor) error {
 		outTime := *t
@@ -62,10 +59,36 @@ func MakeUTCOrLocalTimestampEncoder(localTime bool) func(*time.Time, structform.
 	}
 }
 
+// NewTimestampFormatter creates the formatter used by the timestamp encoders.
+// The formatter does not convert the time to UTC, callers must do it if
+// localTime is false.
+func NewTimestampFormatter(localTime bool, precision common.TimestampPrecision) *dtfmt.Formatter {
+	fraction := strings.Repeat("S", precision.Digits())
+	var dtPattern string
+	if localTime {
+		dtPattern = "yyyy-MM-dd'T'HH:mm:ss." + fraction + "z"
+	} else {
+		dtPattern = "yyyy-MM-dd'T'HH:mm:ss." + fraction + "'Z'"
+	}
+
+	formatter, err := dtfmt.NewFormatter(dtPattern)
+	if err != nil {
+		panic(err)
+	}
+	return formatter
+}
+
 // MakeBCTimestampEncoder creates encoder function that formats beats common time
 // into RFC3339 representation with UTC timezone in the output.
 func MakeBCTimestampEncoder() func(*common.Time, structform.ExtVisitor) error {
-	enc := MakeTimestampEncoder()
+	return MakeBCTimestampEncoderWithPrecision(common.TimestampMillisecond)
+}
+
+// MakeBCTimestampEncoderWithPrecision creates encoder function that formats
+// beats common time into RFC3339 representation with UTC timezone in the
+// output, and as many fractional digits as required by precision.
+func MakeBCTimestampEncoderWithPrecision(precision common.TimestampPrecision) func(*common.Time, structform.ExtVisitor) error {
+	enc := MakeTimestampEncoderWithPrecision(false, precision)
 	return func(t *common.Time, v structform.ExtVisitor) error {
 		return enc((*time.Time)(t), v)
 	}
@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/jsontransform"
+)
+
+// Decode decodes an event encoded by the Encoder. The metadata added by the
+// Encoder is removed, as it's added again when the event is encoded. Integer
+// numbers are decoded as int64 so they don't lose precision.
+func Decode(data []byte) (beat.Event, error) {
+	var fields common.MapStr
+	dec := stdjson.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return beat.Event{}, err
+	}
+	jsontransform.TransformNumbers(fields)
+
+	var event beat.Event
+	if ts, ok := fields["@timestamp"].(string); ok {
+		t, err := common.ParseTime(ts)
+		if err != nil {
+			return beat.Event{}, fmt.Errorf("invalid @timestamp: %v", err)
+		}
+		event.Timestamp = time.Time(t)
+	}
+	if meta, ok := fields["@metadata"].(map[string]interface{}); ok {
+		for _, k := range []string{"beat", "type", "version"} {
+			delete(meta, k)
+		}
+		if len(meta) > 0 {
+			event.Meta = common.MapStr(meta)
+		}
+	}
+	delete(fields, "@timestamp")
+	delete(fields, "@metadata")
+	event.Fields = fields
+	return event, nil
+}
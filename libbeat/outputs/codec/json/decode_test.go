@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package json
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestDecode(t *testing.T) {
+	event, err := Decode([]byte(`{"@timestamp":"2020-10-07T18:34:10.123Z","@metadata":{"beat":"test","pipeline":"p"},"message":"hello","log":{"offset":42}}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Date(2020, 10, 7, 18, 34, 10, 123000000, time.UTC), event.Timestamp.UTC())
+	assert.Equal(t, common.MapStr{"pipeline": "p"}, event.Meta)
+	assert.Equal(t, "hello", event.Fields["message"])
+	offset, err := event.Fields.GetValue("log.offset")
+	require.NoError(t, err)
+	assert.EqualValues(t, 42, offset)
+	assert.NotContains(t, event.Fields, "@timestamp")
+	assert.NotContains(t, event.Fields, "@metadata")
+
+	_, err = Decode([]byte(`{"@timestamp":"yesterday"}`))
+	assert.Error(t, err)
+}
+
+func TestDecodeRoundTrip(t *testing.T) {
+	event := beat.Event{
+		Timestamp: time.Date(2020, 10, 7, 18, 34, 10, 123000000, time.UTC),
+		Meta:      common.MapStr{"id": int64(math.MaxInt64 - 1)},
+		Fields: common.MapStr{
+			"counter": int64(math.MaxInt64 - 1),
+			"ratio":   0.5,
+			"nested":  common.MapStr{"values": []interface{}{int64(math.MinInt64 + 1), 1.5}},
+		},
+	}
+
+	data, err := New("1.2.3", Config{}).Encode("test", &event)
+	require.NoError(t, err)
+	decoded, err := Decode(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, event.Timestamp, decoded.Timestamp.UTC())
+	assert.Equal(t, int64(math.MaxInt64-1), decoded.Meta["id"])
+	assert.Equal(t, int64(math.MaxInt64-1), decoded.Fields["counter"])
+	assert.Equal(t, 0.5, decoded.Fields["ratio"])
+	values, err := decoded.Fields.GetValue("nested.values")
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{int64(math.MinInt64 + 1), 1.5}, values)
+}
@@ -0,0 +1,321 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package json
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/dtfmt"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+)
+
+// The escape sets match the ones of the structform JSON visitor, so both
+// encoders escape the same characters.
+var (
+	htmlEscapeSet = [utf8.RuneSelf]bool{}
+	jsonEscapeSet = [utf8.RuneSelf]bool{}
+)
+
+func init() {
+	// control characters must be escaped
+	for i := 0; i < 0x20; i++ {
+		htmlEscapeSet[i] = true
+		jsonEscapeSet[i] = true
+	}
+	for _, c := range "\"\\" {
+		htmlEscapeSet[c] = true
+		jsonEscapeSet[c] = true
+	}
+	for _, c := range "&<>" {
+		htmlEscapeSet[c] = true
+	}
+}
+
+const hexDigits = "0123456789abcdef"
+
+func (e *Encoder) initDirect() {
+	e.direct = true
+	if e.config.EscapeHTML {
+		e.escapeSet = htmlEscapeSet[:]
+	} else {
+		e.escapeSet = jsonEscapeSet[:]
+	}
+
+	// common.Time is always encoded in UTC.
+	e.timestamp = codec.NewTimestampFormatter(e.config.LocalTime, e.config.TimestampPrecision)
+	e.bcTimestamp = e.timestamp
+	if e.config.LocalTime {
+		e.bcTimestamp = codec.NewTimestampFormatter(false, e.config.TimestampPrecision)
+	}
+	e.scratch = make([]byte, 0, 64)
+}
+
+// encodeDirect writes the event into the buffer with the same layout as the
+// event type folded by structform. Values of types not handled by writeValue
+// are folded by structform into the same buffer.
+func (e *Encoder) encodeDirect(index string, event *beat.Event) error {
+	e.buf.WriteString(`{"@timestamp":`)
+	if err := e.writeTime(event.Timestamp, e.timestamp, e.config.LocalTime); err != nil {
+		return err
+	}
+	e.buf.WriteString(`,"@metadata":{"beat":`)
+	e.writeString(index)
+	e.buf.WriteString(`,"type":"_doc","version":`)
+	e.writeString(e.version)
+	if err := e.writeFields(event.Meta); err != nil {
+		return err
+	}
+	e.buf.WriteByte('}')
+	if err := e.writeFields(event.Fields); err != nil {
+		return err
+	}
+	e.buf.WriteByte('}')
+	return nil
+}
+
+// writeFields writes the fields of m as members of an object that already
+// has other members.
+func (e *Encoder) writeFields(m common.MapStr) error {
+	for k, v := range m {
+		e.buf.WriteByte(',')
+		e.writeString(k)
+		e.buf.WriteByte(':')
+		if err := e.writeValue(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) writeValue(v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		e.buf.WriteString("null")
+	case string:
+		e.writeString(v)
+	case bool:
+		if v {
+			e.buf.WriteString("true")
+		} else {
+			e.buf.WriteString("false")
+		}
+	case int:
+		e.writeInt(int64(v))
+	case int8:
+		e.writeInt(int64(v))
+	case int16:
+		e.writeInt(int64(v))
+	case int32:
+		e.writeInt(int64(v))
+	case int64:
+		e.writeInt(v)
+	case uint:
+		e.writeUint(uint64(v))
+	case uint8:
+		e.writeUint(uint64(v))
+	case uint16:
+		e.writeUint(uint64(v))
+	case uint32:
+		e.writeUint(uint64(v))
+	case uint64:
+		e.writeUint(v)
+	case float32:
+		return e.writeFloat(float64(v), 32)
+	case float64:
+		return e.writeFloat(v, 64)
+	case common.MapStr:
+		return e.writeMap(v)
+	case map[string]interface{}:
+		return e.writeMap(v)
+	case []interface{}:
+		e.buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			if err := e.writeValue(elem); err != nil {
+				return err
+			}
+		}
+		e.buf.WriteByte(']')
+	case []string:
+		e.buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				e.buf.WriteByte(',')
+			}
+			e.writeString(elem)
+		}
+		e.buf.WriteByte(']')
+	case map[string]string:
+		e.buf.WriteByte('{')
+		first := true
+		for k, elem := range v {
+			if !first {
+				e.buf.WriteByte(',')
+			}
+			first = false
+			e.writeString(k)
+			e.buf.WriteByte(':')
+			e.writeString(elem)
+		}
+		e.buf.WriteByte('}')
+	case time.Time:
+		return e.writeTime(v, e.timestamp, e.config.LocalTime)
+	case common.Time:
+		return e.writeTime(time.Time(v), e.bcTimestamp, false)
+	case common.RawJSON:
+		return e.writeRawJSON(v)
+	default:
+		return e.folder.Fold(v)
+	}
+	return nil
+}
+
+func (e *Encoder) writeMap(m map[string]interface{}) error {
+	e.buf.WriteByte('{')
+	first := true
+	for k, v := range m {
+		if !first {
+			e.buf.WriteByte(',')
+		}
+		first = false
+		e.writeString(k)
+		e.buf.WriteByte(':')
+		if err := e.writeValue(v); err != nil {
+			return err
+		}
+	}
+	e.buf.WriteByte('}')
+	return nil
+}
+
+// writeRawJSON copies the raw object into the buffer. It's compacted only if it
+// spans multiple lines, as line based outputs require the events to be on a
+// single line.
+func (e *Encoder) writeRawJSON(r common.RawJSON) error {
+	if len(r) == 0 {
+		e.buf.WriteString("null")
+		return nil
+	}
+	if bytes.IndexAny(r, "\r\n") < 0 {
+		e.buf.Write(r)
+		return nil
+	}
+	return stdjson.Compact(&e.buf, r)
+}
+
+func (e *Encoder) writeInt(i int64) {
+	e.scratch = strconv.AppendInt(e.scratch[:0], i, 10)
+	e.buf.Write(e.scratch)
+}
+
+func (e *Encoder) writeUint(u uint64) {
+	e.scratch = strconv.AppendUint(e.scratch[:0], u, 10)
+	e.buf.Write(e.scratch)
+}
+
+func (e *Encoder) writeFloat(f float64, bits int) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		// let structform report the unsupported value
+		return e.folder.Fold(f)
+	}
+	e.scratch = strconv.AppendFloat(e.scratch[:0], f, 'g', -1, bits)
+	e.buf.Write(e.scratch)
+	return nil
+}
+
+func (e *Encoder) writeTime(t time.Time, formatter *dtfmt.Formatter, localTime bool) error {
+	if !localTime {
+		t = t.UTC()
+	}
+	tmp, err := formatter.AppendTo(e.scratch[:0], t)
+	if err != nil {
+		return err
+	}
+	e.scratch = tmp
+	e.buf.WriteByte('"')
+	e.buf.Write(tmp)
+	e.buf.WriteByte('"')
+	return nil
+}
+
+// writeString writes s as a quoted JSON string, escaping the same characters
+// as structform: invalid UTF-8 is replaced with the escaped replacement
+// character, and the line and paragraph separators are always escaped.
+func (e *Encoder) writeString(s string) {
+	escapeSet := e.escapeSet
+
+	e.buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if !escapeSet[b] {
+				i++
+				continue
+			}
+
+			e.buf.WriteString(s[start:i])
+			switch b {
+			case '\\', '"':
+				e.buf.WriteByte('\\')
+				e.buf.WriteByte(b)
+			case '\n':
+				e.buf.WriteString(`\n`)
+			case '\r':
+				e.buf.WriteString(`\r`)
+			case '\t':
+				e.buf.WriteString(`\t`)
+			default:
+				e.buf.WriteString(`\u00`)
+				e.buf.WriteByte(hexDigits[b>>4])
+				e.buf.WriteByte(hexDigits[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			e.buf.WriteString(s[start:i])
+			e.buf.WriteString(`\ufffd`)
+			i += size
+			start = i
+			continue
+		}
+		if c == '\u2028' || c == '\u2029' {
+			e.buf.WriteString(s[start:i])
+			e.buf.WriteString(`\u202`)
+			e.buf.WriteByte(hexDigits[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	e.buf.WriteString(s[start:])
+	e.buf.WriteByte('"')
+}
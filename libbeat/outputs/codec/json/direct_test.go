@@ -0,0 +1,195 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package json
+
+import (
+	stdjson "encoding/json"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+type directTestStruct struct {
+	Name  string `struct:"name"`
+	Count int    `struct:"count"`
+}
+
+type directTestString string
+
+// directTestValues holds values of all types handled by the direct encoder,
+// and of some types it leaves to structform.
+var directTestValues = map[string]interface{}{
+	"nil":               nil,
+	"string":            "message",
+	"escaped string":    "\"quoted\"\\\n\r\t\x01\x1f",
+	"html":              "<hello>&world</hello>",
+	"unicode":           "héllo wörld ✓",
+	"invalid utf8":      "bad \xff byte",
+	"separators":        "line\u2028paragraph\u2029",
+	"empty string":      "",
+	"true":              true,
+	"false":             false,
+	"int":               -42,
+	"int8":              int8(math.MinInt8),
+	"int16":             int16(math.MaxInt16),
+	"int32":             int32(math.MinInt32),
+	"int64":             int64(math.MinInt64),
+	"uint":              uint(42),
+	"uint8":             uint8(math.MaxUint8),
+	"uint16":            uint16(math.MaxUint16),
+	"uint32":            uint32(math.MaxUint32),
+	"uint64":            uint64(math.MaxUint64),
+	"float32":           float32(3.14),
+	"float64":           1.0 / 3.0,
+	"big float":         1e21,
+	"small float":       1e-7,
+	"integral float":    float64(10),
+	"mapstr":            common.MapStr{"nested": common.MapStr{"key": "value"}},
+	"empty mapstr":      common.MapStr{},
+	"nil mapstr":        common.MapStr(nil),
+	"map":               map[string]interface{}{"key": 1},
+	"interface slice":   []interface{}{"a", 1, nil, common.MapStr{"key": false}},
+	"empty slice":       []interface{}{},
+	"nil slice":         []interface{}(nil),
+	"string slice":      []string{"a", "b\n"},
+	"nil string slice":  []string(nil),
+	"string map":        map[string]string{"key": "value"},
+	"time":              time.Date(2020, time.November, 2, 10, 4, 5, 123456789, time.FixedZone("CET", 60*60)),
+	"common time":       common.Time(time.Date(2020, time.November, 2, 10, 4, 5, 123456789, time.FixedZone("CET", 60*60))),
+	"bytes":             []byte("bytes"),
+	"ip":                net.ParseIP("192.168.0.1"),
+	"struct":            directTestStruct{Name: "name", Count: 1},
+	"struct pointer":    &directTestStruct{Name: "name", Count: 1},
+	"named string":      directTestString("named"),
+	"mapstr slice":      []common.MapStr{{"key": "value"}},
+	"int slice":         []int{1, 2, 3},
+	"float slice":       []float64{1.5, 2},
+	"duration":          time.Second,
+	"time pointer":      func() *time.Time { t := time.Unix(1, 0); return &t }(),
+	"nested fallback":   common.MapStr{"struct": directTestStruct{Name: "nested"}},
+	"unknown in slice":  []interface{}{directTestStruct{}, []byte("x")},
+	"raw json":          common.RawJSON(`{"key":"value","list":[1,-2.5,null,{"a":true}]}`),
+	"raw json lines":    common.RawJSON("{\n  \"key\": \"value\",\r\n  \"nested\": {}\n}"),
+	"key \"escaped\"\n": "value",
+}
+
+var directTestConfigs = map[string]Config{
+	"default":     defaultConfig,
+	"escape html": Config{EscapeHTML: true},
+	"local time":  Config{LocalTime: true},
+	"nanosecond":  Config{TimestampPrecision: common.TimestampNanosecond},
+	"pretty":      Config{Pretty: true},
+}
+
+func TestDirectEncoderOutputIsIdentical(t *testing.T) {
+	ts := time.Date(2020, time.November, 2, 10, 4, 5, 123456789, time.FixedZone("CET", 60*60))
+
+	for cfgName, cfg := range directTestConfigs {
+		structform, direct := newTestEncoders(cfg)
+
+		// Every value is encoded in an event of its own, so the order of the
+		// keys is deterministic and the outputs can be compared byte by byte.
+		for name, value := range directTestValues {
+			event := &beat.Event{
+				Timestamp: ts,
+				Meta:      common.MapStr{"pipeline": "test"},
+				Fields:    common.MapStr{name: value},
+			}
+
+			expected, err := structform.Encode("test", event)
+			require.NoError(t, err, "%v/%v", cfgName, name)
+			expectedStr := string(expected)
+
+			actual, err := direct.Encode("test", event)
+			require.NoError(t, err, "%v/%v", cfgName, name)
+			assert.Equal(t, expectedStr, string(actual), "%v/%v", cfgName, name)
+		}
+	}
+}
+
+func TestDirectEncoderSameDocument(t *testing.T) {
+	// Maps with multiple keys are encoded in random order, compare the decoded
+	// documents.
+	fields := common.MapStr{}
+	for name, value := range directTestValues {
+		fields[name] = value
+	}
+	event := &beat.Event{
+		Timestamp: time.Now(),
+		Meta:      common.MapStr{"pipeline": "test", "_id": "abc"},
+		Fields:    common.MapStr{"all": fields, "top": fields},
+	}
+
+	for cfgName, cfg := range directTestConfigs {
+		structform, direct := newTestEncoders(cfg)
+
+		expected, err := structform.Encode("test", event)
+		require.NoError(t, err)
+		actual, err := direct.Encode("test", event)
+		require.NoError(t, err)
+		assert.Equal(t, decodeTestDocument(t, expected), decodeTestDocument(t, actual), cfgName)
+	}
+}
+
+func TestDirectEncoderErrors(t *testing.T) {
+	structform, direct := newTestEncoders(defaultConfig)
+	for _, value := range []interface{}{math.NaN(), math.Inf(1), float32(math.Inf(-1))} {
+		event := &beat.Event{Fields: common.MapStr{"value": common.MapStr{"nested": value}}}
+
+		_, errStructform := structform.Encode("test", event)
+		_, errDirect := direct.Encode("test", event)
+		assert.Error(t, errStructform)
+		assert.Equal(t, errStructform, errDirect)
+
+		// the encoder is usable after an error
+		actual, err := direct.Encode("test", &beat.Event{Fields: common.MapStr{"msg": "message"}})
+		require.NoError(t, err)
+		assert.Equal(t, `{"@timestamp":"0001-01-01T00:00:00.000Z","@metadata":{"beat":"test","type":"_doc","version":"1.2.3"},"msg":"message"}`, string(actual))
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, encoder := range []string{"", encoderStructform, encoderDirect} {
+		cfg := Config{Encoder: encoder}
+		assert.NoError(t, cfg.Validate(), encoder)
+	}
+
+	cfg := Config{Encoder: "simd"}
+	assert.Error(t, cfg.Validate())
+}
+
+func newTestEncoders(cfg Config) (*Encoder, *Encoder) {
+	cfg.Encoder = encoderStructform
+	structform := New("1.2.3", cfg)
+	cfg.Encoder = encoderDirect
+	direct := New("1.2.3", cfg)
+	return structform, direct
+}
+
+func decodeTestDocument(t *testing.T, data []byte) interface{} {
+	var doc interface{}
+	require.NoError(t, stdjson.Unmarshal(data, &doc), string(data))
+	return doc
+}
@@ -20,9 +20,11 @@ package json
 import (
 	"bytes"
 	stdjson "encoding/json"
+	"fmt"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/dtfmt"
 	"github.com/elastic/beats/v7/libbeat/outputs/codec"
 	"github.com/elastic/go-structform/gotype"
 	"github.com/elastic/go-structform/json"
@@ -33,6 +35,13 @@ type Encoder struct {
 	buf    bytes.Buffer
 	folder *gotype.Iterator
 
+	// direct encoder state, only set if the direct encoder is used.
+	direct      bool
+	escapeSet   []bool
+	timestamp   *dtfmt.Formatter
+	bcTimestamp *dtfmt.Formatter
+	scratch     []byte
+
 	version string
 	config  Config
 }
@@ -42,12 +51,39 @@ type Config struct {
 	Pretty     bool
 	EscapeHTML bool
 	LocalTime  bool
+
+	// TimestampPrecision is the number of fractional digits of the encoded
+	// timestamps.
+	TimestampPrecision common.TimestampPrecision `config:"timestamp_precision"`
+
+	// Encoder selects the implementation used to encode the events. The
+	// structform encoder supports any value, the direct encoder writes the
+	// common types found in events without reflection, and falls back to
+	// structform for the other ones. Both produce the same output.
+	Encoder string `config:"encoder"`
 }
 
+const (
+	encoderStructform = "structform"
+	encoderDirect     = "direct"
+)
+
 var defaultConfig = Config{
-	Pretty:     false,
-	EscapeHTML: false,
-	LocalTime:  false,
+	Pretty:             false,
+	EscapeHTML:         false,
+	LocalTime:          false,
+	TimestampPrecision: common.TimestampMillisecond,
+	Encoder:            encoderStructform,
+}
+
+// Validate checks that the configured encoder is known.
+func (c *Config) Validate() error {
+	switch c.Encoder {
+	case "", encoderStructform, encoderDirect:
+		return nil
+	default:
+		return fmt.Errorf("unknown JSON encoder '%v', use '%v' or '%v'", c.Encoder, encoderStructform, encoderDirect)
+	}
 }
 
 func init() {
@@ -66,6 +102,9 @@ func init() {
 // New creates a new json Encoder.
 func New(version string, config Config) *Encoder {
 	e := &Encoder{version: version, config: config}
+	if config.Encoder == encoderDirect {
+		e.initDirect()
+	}
 	e.reset()
 	return e
 }
@@ -79,8 +118,8 @@ func (e *Encoder) reset() {
 	// create new encoder with custom time.Time encoding
 	e.folder, err = gotype.NewIterator(visitor,
 		gotype.Folders(
-			codec.MakeUTCOrLocalTimestampEncoder(e.config.LocalTime),
-			codec.MakeBCTimestampEncoder(),
+			codec.MakeTimestampEncoderWithPrecision(e.config.LocalTime, e.config.TimestampPrecision),
+			codec.MakeBCTimestampEncoderWithPrecision(e.config.TimestampPrecision),
 		),
 	)
 	if err != nil {
@@ -92,7 +131,12 @@ func (e *Encoder) reset() {
 // `@metadata` namespace.
 func (e *Encoder) Encode(index string, event *beat.Event) ([]byte, error) {
 	e.buf.Reset()
-	err := e.folder.Fold(makeEvent(index, e.version, event))
+	var err error
+	if e.direct {
+		err = e.encodeDirect(index, event)
+	} else {
+		err = e.folder.Fold(makeEvent(index, e.version, event))
+	}
 	if err != nil {
 		e.reset()
 		return nil, err
@@ -58,3 +58,45 @@ func BenchmarkLocalTime(b *testing.B) {
 	}
 	result = r
 }
+
+func BenchmarkEncoders(b *testing.B) {
+	event := &beat.Event{
+		Timestamp: time.Now(),
+		Fields: common.MapStr{
+			"message": "2020-11-02 10:04:05.123 INFO [main] service started on port 8080",
+			"log": common.MapStr{
+				"offset": int64(12345),
+				"file":   common.MapStr{"path": "/var/log/service/service.log"},
+			},
+			"host": common.MapStr{
+				"name":     "host-1",
+				"hostname": "host-1",
+				"ip":       []string{"10.0.0.1", "fe80::1"},
+			},
+			"agent": common.MapStr{
+				"type":    "filebeat",
+				"version": "7.11.0",
+				"id":      "4d6f3b1e-6b4c-4ddb-8c7e-5a1a7f8b6c9d",
+			},
+			"event": common.MapStr{
+				"dataset":  "service.log",
+				"duration": int64(1500000),
+				"created":  common.Time(time.Now()),
+			},
+			"tags":  []interface{}{"production", "service"},
+			"score": 0.75,
+		},
+	}
+
+	for _, encoder := range []string{encoderStructform, encoderDirect} {
+		b.Run(encoder, func(b *testing.B) {
+			codec := New("1.2.3", Config{Encoder: encoder})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				r, _ := codec.Encode("test", event)
+				result = r
+			}
+		})
+	}
+}
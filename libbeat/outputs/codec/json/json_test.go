@@ -73,20 +73,36 @@ func TestJsonCodec(t *testing.T) {
 			in:       common.MapStr{"msg": "message"},
 			expected: `{"@timestamp":"0000-12-31T16:00:00.000-08:00","@metadata":{"beat":"test","type":"_doc","version":"1.2.3"},"msg":"message"}`,
 		},
+		"nanosecond precision": testCase{
+			config:   Config{TimestampPrecision: common.TimestampNanosecond},
+			ts:       time.Date(2020, time.November, 2, 10, 4, 5, 123456789, time.UTC),
+			in:       common.MapStr{"created": common.Time(time.Date(2020, time.November, 2, 10, 4, 5, 1, time.UTC))},
+			expected: `{"@timestamp":"2020-11-02T10:04:05.123456789Z","@metadata":{"beat":"test","type":"_doc","version":"1.2.3"},"created":"2020-11-02T10:04:05.000000001Z"}`,
+		},
+		"microsecond precision": testCase{
+			config:   Config{TimestampPrecision: common.TimestampMicrosecond, LocalTime: true},
+			ts:       time.Date(2020, time.November, 2, 10, 4, 5, 123456789, time.FixedZone("CET", 60*60)),
+			in:       common.MapStr{"msg": "message"},
+			expected: `{"@timestamp":"2020-11-02T10:04:05.123456+01:00","@metadata":{"beat":"test","type":"_doc","version":"1.2.3"},"msg":"message"}`,
+		},
 	}
 
 	for name, test := range cases {
 		cfg, ts, fields, expected := test.config, test.ts, test.in, test.expected
 
-		t.Run(name, func(t *testing.T) {
+		for _, encoder := range []string{encoderStructform, encoderDirect} {
+			cfg.Encoder = encoder
 			codec := New("1.2.3", cfg)
-			actual, err := codec.Encode("test", &beat.Event{Fields: fields, Timestamp: ts})
 
-			if err != nil {
-				t.Errorf("Error during event write %v", err)
-			} else if string(actual) != expected {
-				t.Errorf("Expected value (%s) does not equal with output (%s)", expected, actual)
-			}
-		})
+			t.Run(name+"/"+encoder, func(t *testing.T) {
+				actual, err := codec.Encode("test", &beat.Event{Fields: fields, Timestamp: ts})
+
+				if err != nil {
+					t.Errorf("Error during event write %v", err)
+				} else if string(actual) != expected {
+					t.Errorf("Expected value (%s) does not equal with output (%s)", expected, actual)
+				}
+			})
+		}
 	}
 }
@@ -31,6 +31,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/outputs"
 	"github.com/elastic/beats/v7/libbeat/outputs/codec"
 	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/privacy"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 )
 
@@ -135,7 +136,7 @@ func (c *console) publishEvent(event *publisher.Event) bool {
 		}
 
 		c.log.Errorf("Unable to encode event: %+v", err)
-		c.log.Debugf("Failed event: %v", event)
+		c.log.Debugf("Failed event: %v", privacy.LazyEvent(&event.Content))
 		return false
 	}
 
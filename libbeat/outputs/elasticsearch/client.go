@@ -34,6 +34,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/outputs"
 	"github.com/elastic/beats/v7/libbeat/outputs/outil"
+	"github.com/elastic/beats/v7/libbeat/privacy"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/testing"
 )
@@ -45,6 +46,9 @@ type Client struct {
 	index    outputs.IndexSelector
 	pipeline *outil.Selector
 
+	// dataStreams forces the create op_type required by data streams.
+	dataStreams bool
+
 	observer outputs.Observer
 
 	log *logp.Logger
@@ -53,9 +57,10 @@ type Client struct {
 // ClientSettings contains the settings for a client.
 type ClientSettings struct {
 	eslegclient.ConnectionSettings
-	Index    outputs.IndexSelector
-	Pipeline *outil.Selector
-	Observer outputs.Observer
+	Index       outputs.IndexSelector
+	Pipeline    *outil.Selector
+	Observer    outputs.Observer
+	DataStreams bool
 }
 
 type bulkResultStats struct {
@@ -64,12 +69,30 @@ type bulkResultStats struct {
 	fails        int // number of failed events (can be retried)
 	nonIndexable int // number of failed events (not indexable -> must be dropped)
 	tooMany      int // number of events receiving HTTP 429 Too Many Requests
+
+	// rejected are the not indexable events, with the reason of the failure.
+	rejected []rejectedEvent
+}
+
+// rejectedEvent is an event Elasticsearch failed to index with a
+// non-retryable error.
+type rejectedEvent struct {
+	event  publisher.Event
+	reason string
 }
 
 const (
 	defaultEventType = "doc"
 )
 
+// errPayloadTooLarge is returned when Elasticsearch rejects a bulk request
+// larger than its http.max_content_length.
+var errPayloadTooLarge = errors.New("bulk request too large")
+
+// minDataStreamVersion is the first Elasticsearch version supporting data
+// streams.
+var minDataStreamVersion = common.MustNewVersion("7.9.0")
+
 // NewClient instantiates a new client.
 func NewClient(
 	s ClientSettings,
@@ -81,20 +104,24 @@ func NewClient(
 	}
 
 	conn, err := eslegclient.NewConnection(eslegclient.ConnectionSettings{
-		URL:              s.URL,
-		Username:         s.Username,
-		Password:         s.Password,
-		APIKey:           s.APIKey,
-		Headers:          s.Headers,
-		TLS:              s.TLS,
-		Kerberos:         s.Kerberos,
-		Proxy:            s.Proxy,
-		ProxyDisable:     s.ProxyDisable,
-		Observer:         s.Observer,
-		Parameters:       s.Parameters,
-		CompressionLevel: s.CompressionLevel,
-		EscapeHTML:       s.EscapeHTML,
-		Timeout:          s.Timeout,
+		URL:                s.URL,
+		Username:           s.Username,
+		Password:           s.Password,
+		APIKey:             s.APIKey,
+		Headers:            s.Headers,
+		TLS:                s.TLS,
+		Kerberos:           s.Kerberos,
+		OAuth2:             s.OAuth2,
+		Proxy:              s.Proxy,
+		ProxyDisable:       s.ProxyDisable,
+		ProxyLocalResolve:  s.ProxyLocalResolve,
+		Observer:           s.Observer,
+		Parameters:         s.Parameters,
+		CompressionLevel:   s.CompressionLevel,
+		EscapeHTML:         s.EscapeHTML,
+		TimestampPrecision: s.TimestampPrecision,
+		AutoCompression:    s.AutoCompression,
+		Timeout:            s.Timeout,
 	})
 	if err != nil {
 		return nil, err
@@ -126,9 +153,10 @@ func NewClient(
 	}
 
 	client := &Client{
-		conn:     *conn,
-		index:    s.Index,
-		pipeline: pipeline,
+		conn:        *conn,
+		index:       s.Index,
+		pipeline:    pipeline,
+		dataStreams: s.DataStreams,
 
 		observer: s.Observer,
 
@@ -153,22 +181,27 @@ func (client *Client) Clone() *Client {
 				// Without the following nil check on proxyURL, a nil Proxy field will try
 				// reloading proxy settings from the environment instead of leaving them
 				// empty.
-				ProxyDisable:      client.conn.Proxy == nil,
-				TLS:               client.conn.TLS,
-				Kerberos:          client.conn.Kerberos,
-				Username:          client.conn.Username,
-				Password:          client.conn.Password,
-				APIKey:            client.conn.APIKey,
-				Parameters:        nil, // XXX: do not pass params?
-				Headers:           client.conn.Headers,
-				Timeout:           client.conn.Timeout,
-				CompressionLevel:  client.conn.CompressionLevel,
-				OnConnectCallback: nil,
-				Observer:          nil,
-				EscapeHTML:        false,
+				ProxyDisable:       client.conn.Proxy == nil,
+				ProxyLocalResolve:  client.conn.ProxyLocalResolve,
+				TLS:                client.conn.TLS,
+				Kerberos:           client.conn.Kerberos,
+				OAuth2:             client.conn.OAuth2,
+				Username:           client.conn.Username,
+				Password:           client.conn.Password,
+				APIKey:             client.conn.APIKey,
+				Parameters:         nil, // XXX: do not pass params?
+				Headers:            client.conn.Headers,
+				Timeout:            client.conn.Timeout,
+				CompressionLevel:   client.conn.CompressionLevel,
+				OnConnectCallback:  nil,
+				Observer:           nil,
+				EscapeHTML:         false,
+				TimestampPrecision: client.conn.TimestampPrecision,
+				AutoCompression:    client.conn.AutoCompression,
 			},
-			Index:    client.index,
-			Pipeline: client.pipeline,
+			Index:       client.index,
+			Pipeline:    client.pipeline,
+			DataStreams: client.dataStreams,
 		},
 		nil, // XXX: do not pass connection callback?
 	)
@@ -177,7 +210,30 @@ func (client *Client) Clone() *Client {
 
 func (client *Client) Publish(ctx context.Context, batch publisher.Batch) error {
 	events := batch.Events()
-	rest, err := client.publishEvents(ctx, events)
+	rest, rejected, err := client.publishEvents(ctx, events)
+	for _, r := range rejected {
+		publisher.DeadLetter(batch, r.event, r.reason)
+	}
+
+	if errors.Is(err, errPayloadTooLarge) {
+		// Retry the events in smaller requests, and drop the events too
+		// large to be indexed in their own request. Only the encoded events
+		// are split, the others can't be published.
+		if publisher.SplitRetry(batch, rest) {
+			client.log.Infof("Bulk request of %d events too large, retrying them in two batches", len(rest))
+			return nil
+		}
+		if len(rest) == 1 {
+			client.log.Errorf("Dropping event too large to be indexed: %v", err)
+			publisher.DeadLetter(batch, rest[0], err.Error())
+			if client.observer != nil {
+				client.observer.Dropped(1)
+			}
+			batch.ACK()
+			return nil
+		}
+	}
+
 	if len(rest) == 0 {
 		batch.ACK()
 	} else {
@@ -189,7 +245,9 @@ func (client *Client) Publish(ctx context.Context, batch publisher.Batch) error
 // PublishEvents sends all events to elasticsearch. On error a slice with all
 // events not published or confirmed to be processed by elasticsearch will be
 // returned. The input slice backing memory will be reused by return the value.
-func (client *Client) publishEvents(ctx context.Context, data []publisher.Event) ([]publisher.Event, error) {
+// The events elasticsearch failed to index with non-retryable errors are
+// returned with the reason of the failure.
+func (client *Client) publishEvents(ctx context.Context, data []publisher.Event) ([]publisher.Event, []rejectedEvent, error) {
 	span, ctx := apm.StartSpan(ctx, "publishEvents", "output")
 	defer span.End()
 	begin := time.Now()
@@ -200,29 +258,34 @@ func (client *Client) publishEvents(ctx context.Context, data []publisher.Event)
 	}
 
 	if len(data) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	// encode events into bulk request buffer, dropping failed elements from
 	// events slice
 	origCount := len(data)
 	span.Context.SetLabel("events_original", origCount)
-	data, bulkItems := bulkEncodePublishRequest(client.log, client.conn.GetVersion(), client.index, client.pipeline, data)
+	status, result, sendErr := client.conn.BulkWith(ctx, "", "", nil, func(w eslegclient.BulkItemWriter) int {
+		data = bulkEncodePublishRequest(client.log, client.conn.GetVersion(), client.index, client.pipeline, client.dataStreams, w, data)
+		return len(data)
+	})
 	newCount := len(data)
 	span.Context.SetLabel("events_encoded", newCount)
 	if st != nil && origCount > newCount {
 		st.Dropped(origCount - newCount)
 	}
 	if newCount == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
-	status, result, sendErr := client.conn.Bulk(ctx, "", "", nil, bulkItems)
 	if sendErr != nil {
+		if status == http.StatusRequestEntityTooLarge {
+			return data, nil, fmt.Errorf("%w: %v", errPayloadTooLarge, sendErr)
+		}
 		err := apm.CaptureError(ctx, fmt.Errorf("failed to perform any bulk index operations: %w", sendErr))
 		err.Send()
 		client.log.Error(err)
-		return data, sendErr
+		return data, nil, sendErr
 	}
 	pubCount := len(data)
 	span.Context.SetLabel("events_published", pubCount)
@@ -259,39 +322,42 @@ func (client *Client) publishEvents(ctx context.Context, data []publisher.Event)
 		if sendErr == nil {
 			sendErr = eslegclient.ErrTempBulkFailure
 		}
-		return failedEvents, sendErr
+		return failedEvents, stats.rejected, sendErr
 	}
-	return nil, nil
+	return nil, stats.rejected, nil
 }
 
-// bulkEncodePublishRequest encodes all bulk requests and returns slice of events
-// successfully added to the list of bulk items and the list of bulk items.
+// bulkEncodePublishRequest encodes all events into the bulk request and
+// returns the slice of events successfully added to the request.
 func bulkEncodePublishRequest(
 	log *logp.Logger,
 	version common.Version,
 	index outputs.IndexSelector,
 	pipeline *outil.Selector,
+	dataStreams bool,
+	w eslegclient.BulkItemWriter,
 	data []publisher.Event,
-) ([]publisher.Event, []interface{}) {
+) []publisher.Event {
 
 	okEvents := data[:0]
-	bulkItems := []interface{}{}
 	for i := range data {
 		event := &data[i].Content
-		meta, err := createEventBulkMeta(log, version, index, pipeline, event)
+		action, meta, err := createEventBulkMeta(log, version, index, pipeline, dataStreams, event)
 		if err != nil {
 			log.Errorf("Failed to encode event meta data: %+v", err)
 			continue
 		}
-		if opType := events.GetOpType(*event); opType == events.OpTypeDelete {
+		if action == eslegclient.BulkActionDelete {
 			// We don't include the event source in a bulk DELETE
-			bulkItems = append(bulkItems, meta)
-		} else {
-			bulkItems = append(bulkItems, meta, event)
+			event = nil
+		}
+		if err := w.AddItem(action, meta, event); err != nil {
+			log.Errorf("Failed to encode event: %+v", err)
+			continue
 		}
 		okEvents = append(okEvents, data[i])
 	}
-	return okEvents, bulkItems
+	return okEvents
 }
 
 func createEventBulkMeta(
@@ -299,8 +365,9 @@ func createEventBulkMeta(
 	version common.Version,
 	indexSel outputs.IndexSelector,
 	pipelineSel *outil.Selector,
+	dataStreams bool,
 	event *beat.Event,
-) (interface{}, error) {
+) (eslegclient.BulkAction, eslegclient.BulkMeta, error) {
 	eventType := ""
 	if version.Major < 7 {
 		eventType = defaultEventType
@@ -309,13 +376,13 @@ func createEventBulkMeta(
 	pipeline, err := getPipeline(event, pipelineSel)
 	if err != nil {
 		err := fmt.Errorf("failed to select pipeline: %v", err)
-		return nil, err
+		return 0, eslegclient.BulkMeta{}, err
 	}
 
 	index, err := indexSel.Select(event)
 	if err != nil {
 		err := fmt.Errorf("failed to select event index: %v", err)
-		return nil, err
+		return 0, eslegclient.BulkMeta{}, err
 	}
 
 	id, _ := events.GetMetaStringValue(*event, events.FieldMetaID)
@@ -328,20 +395,28 @@ func createEventBulkMeta(
 		ID:       id,
 	}
 
+	if dataStreams {
+		// Data streams are append-only, documents can only be created.
+		if opType == events.OpTypeDelete {
+			return 0, meta, fmt.Errorf("%s %s is not supported by data streams", events.FieldMetaOpType, events.OpTypeDelete)
+		}
+		return eslegclient.BulkActionCreate, meta, nil
+	}
+
 	if opType == events.OpTypeDelete {
 		if id != "" {
-			return eslegclient.BulkDeleteAction{Delete: meta}, nil
+			return eslegclient.BulkActionDelete, meta, nil
 		} else {
-			return nil, fmt.Errorf("%s %s requires _id", events.FieldMetaOpType, events.OpTypeDelete)
+			return 0, meta, fmt.Errorf("%s %s requires _id", events.FieldMetaOpType, events.OpTypeDelete)
 		}
 	}
 	if id != "" || version.Major > 7 || (version.Major == 7 && version.Minor >= 5) {
 		if opType == events.OpTypeIndex {
-			return eslegclient.BulkIndexAction{Index: meta}, nil
+			return eslegclient.BulkActionIndex, meta, nil
 		}
-		return eslegclient.BulkCreateAction{Create: meta}, nil
+		return eslegclient.BulkActionCreate, meta, nil
 	}
-	return eslegclient.BulkIndexAction{Index: meta}, nil
+	return eslegclient.BulkActionIndex, meta, nil
 }
 
 func getPipeline(event *beat.Event, pipelineSel *outil.Selector) (string, error) {
@@ -405,8 +480,12 @@ func bulkCollectPublishFails(
 				stats.tooMany++
 			} else {
 				// hard failure, don't collect
-				log.Warnf("Cannot index event %#v (status=%v): %s", data[i], status, msg)
+				log.Warnf("Cannot index event %#v (status=%v): %s", privacy.LazyEvent(&data[i].Content), status, msg)
 				stats.nonIndexable++
+				stats.rejected = append(stats.rejected, rejectedEvent{
+					event:  data[i],
+					reason: fmt.Sprintf("elasticsearch failed to index the event (status=%v): %s", status, msg),
+				})
 				continue
 			}
 		}
@@ -420,7 +499,14 @@ func bulkCollectPublishFails(
 }
 
 func (client *Client) Connect() error {
-	return client.conn.Connect()
+	if err := client.conn.Connect(); err != nil {
+		return err
+	}
+	if version := client.conn.GetVersion(); client.dataStreams && version.LessThan(minDataStreamVersion) {
+		client.conn.Close()
+		return fmt.Errorf("data streams require Elasticsearch %v or newer, found %v", minDataStreamVersion, version)
+	}
+	return nil
 }
 
 func (client *Client) Close() error {
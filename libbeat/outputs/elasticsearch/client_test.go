@@ -34,6 +34,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/beat"
 	e "github.com/elastic/beats/v7/libbeat/beat/events"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/transport/oauth2"
 	"github.com/elastic/beats/v7/libbeat/esleg/eslegclient"
 	"github.com/elastic/beats/v7/libbeat/idxmgmt"
 	"github.com/elastic/beats/v7/libbeat/logp"
@@ -79,6 +80,30 @@ func TestCollectPublishFailMiddle(t *testing.T) {
 	assert.Equal(t, stats, bulkResultStats{acked: 2, fails: 1, tooMany: 1})
 }
 
+func TestCollectPublishFailRejected(t *testing.T) {
+	response := []byte(`
+    { "items": [
+      {"create": {"status": 400, "error": "mapper_parsing_exception"}},
+      {"create": {"status": 429, "error": "ups"}},
+      {"create": {"status": 200}}
+    ]}
+  `)
+
+	eventRejected := publisher.Event{Content: beat.Event{Fields: common.MapStr{"field": "x"}}}
+	eventFail := publisher.Event{Content: beat.Event{Fields: common.MapStr{"field": 2}}}
+	event := publisher.Event{Content: beat.Event{Fields: common.MapStr{"field": 1}}}
+	events := []publisher.Event{eventRejected, eventFail, event}
+
+	res, stats := bulkCollectPublishFails(logp.L(), response, events)
+	assert.Equal(t, []publisher.Event{eventFail}, res)
+	assert.Equal(t, 1, stats.nonIndexable)
+	if assert.Len(t, stats.rejected, 1) {
+		assert.Equal(t, eventRejected, stats.rejected[0].event)
+		assert.Contains(t, stats.rejected[0].reason, "status=400")
+		assert.Contains(t, stats.rejected[0].reason, "mapper_parsing_exception")
+	}
+}
+
 func TestCollectPublishFailAll(t *testing.T) {
 	response := []byte(`
     { "items": [
@@ -249,6 +274,52 @@ func TestClientWithHeaders(t *testing.T) {
 	assert.Equal(t, 2, requestCount)
 }
 
+func TestClientPublishPayloadTooLarge(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			fmt.Fprintln(w, `{ "version": { "number": "7.6.0" } }`)
+			return
+		}
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ClientSettings{
+		ConnectionSettings: eslegclient.ConnectionSettings{URL: ts.URL},
+		Index:              outil.MakeSelector(outil.ConstSelectorExpr("test", outil.SelectorLowerCase)),
+	}, nil)
+	require.NoError(t, err)
+	require.NoError(t, client.Connect())
+
+	event := beat.Event{Fields: common.MapStr{
+		"@timestamp": common.Time(time.Now()),
+		"message":    "Test message from libbeat",
+	}}
+
+	// batches are split
+	batch := outest.NewBatch(event, event, event)
+	assert.NoError(t, client.Publish(context.Background(), batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchSplitRetry, batch.Signals[0].Tag)
+
+	// only the encoded events are split
+	unencodable := beat.Event{Fields: common.MapStr{"message": make(chan int)}}
+	other := beat.Event{Fields: common.MapStr{"message": "other"}}
+	batch = outest.NewBatch(unencodable, event, other)
+	assert.NoError(t, client.Publish(context.Background(), batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchSplitRetry, batch.Signals[0].Tag)
+	require.Len(t, batch.Signals[0].Events, 2)
+	assert.Equal(t, event, batch.Signals[0].Events[0].Content)
+	assert.Equal(t, other, batch.Signals[0].Events[1].Content)
+
+	// single events are dropped
+	batch = outest.NewBatch(event)
+	assert.NoError(t, client.Publish(context.Background(), batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+}
+
 func TestBulkEncodeEvents(t *testing.T) {
 	cases := map[string]struct {
 		version string
@@ -295,22 +366,16 @@ func TestBulkEncodeEvents(t *testing.T) {
 				}
 			}
 
-			encoded, bulkItems := bulkEncodePublishRequest(logp.L(), *common.MustNewVersion(test.version), index, pipeline, events)
+			var items bulkRecorder
+			encoded := bulkEncodePublishRequest(logp.L(), *common.MustNewVersion(test.version), index, pipeline, false, &items, events)
 			assert.Equal(t, len(events), len(encoded), "all events should have been encoded")
-			assert.Equal(t, 2*len(events), len(bulkItems), "incomplete bulk")
+			assert.Equal(t, len(events), len(items), "incomplete bulk")
 
 			// check meta-data for each event
-			for i := 0; i < len(bulkItems); i += 2 {
-				var meta eslegclient.BulkMeta
-				switch v := bulkItems[i].(type) {
-				case eslegclient.BulkCreateAction:
-					meta = v.Create
-				case eslegclient.BulkIndexAction:
-					meta = v.Index
-				default:
-					panic("unknown type")
-				}
-
+			for _, item := range items {
+				meta := item.meta
+				assert.Contains(t, []eslegclient.BulkAction{eslegclient.BulkActionCreate, eslegclient.BulkActionIndex}, item.action)
+				assert.NotNil(t, item.event)
 				assert.NotEqual(t, "", meta.Index)
 				assert.Equal(t, test.docType, meta.DocType)
 			}
@@ -323,11 +388,11 @@ func TestBulkEncodeEvents(t *testing.T) {
 func TestBulkEncodeEventsWithOpType(t *testing.T) {
 	cases := []common.MapStr{
 		{"_id": "111", "op_type": e.OpTypeIndex, "message": "test 1", "bulkIndex": 0},
-		{"_id": "112", "message": "test 2", "bulkIndex": 2},
+		{"_id": "112", "message": "test 2", "bulkIndex": 1},
 		{"_id": "", "op_type": e.OpTypeDelete, "message": "test 6", "bulkIndex": -1}, // this won't get encoded due to missing _id
-		{"_id": "", "message": "test 3", "bulkIndex": 4},
-		{"_id": "114", "op_type": e.OpTypeDelete, "message": "test 4", "bulkIndex": 6},
-		{"_id": "115", "op_type": e.OpTypeIndex, "message": "test 5", "bulkIndex": 7},
+		{"_id": "", "message": "test 3", "bulkIndex": 2},
+		{"_id": "114", "op_type": e.OpTypeDelete, "message": "test 4", "bulkIndex": 3},
+		{"_id": "115", "op_type": e.OpTypeIndex, "message": "test 5", "bulkIndex": 4},
 	}
 
 	cfg := common.MustNewConfigFrom(common.MapStr{})
@@ -361,9 +426,10 @@ func TestBulkEncodeEventsWithOpType(t *testing.T) {
 		}
 	}
 
-	encoded, bulkItems := bulkEncodePublishRequest(logp.L(), *common.MustNewVersion(version.GetDefaultVersion()), index, pipeline, events)
+	var items bulkRecorder
+	encoded := bulkEncodePublishRequest(logp.L(), *common.MustNewVersion(version.GetDefaultVersion()), index, pipeline, false, &items, events)
 	require.Equal(t, len(events)-1, len(encoded), "all events should have been encoded")
-	require.Equal(t, 9, len(bulkItems), "incomplete bulk")
+	require.Equal(t, 5, len(items), "incomplete bulk")
 
 	for i := 0; i < len(cases); i++ {
 		bulkEventIndex, _ := cases[i]["bulkIndex"].(int)
@@ -372,14 +438,16 @@ func TestBulkEncodeEventsWithOpType(t *testing.T) {
 		}
 		caseOpType, _ := cases[i]["op_type"]
 		caseMessage, _ := cases[i]["message"].(string)
-		switch bulkItems[bulkEventIndex].(type) {
-		case eslegclient.BulkCreateAction:
+		switch items[bulkEventIndex].action {
+		case eslegclient.BulkActionCreate:
 			validOpTypes := []interface{}{e.OpTypeCreate, nil}
 			require.Contains(t, validOpTypes, caseOpType, caseMessage)
-		case eslegclient.BulkIndexAction:
+		case eslegclient.BulkActionIndex:
 			require.Equal(t, e.OpTypeIndex, caseOpType, caseMessage)
-		case eslegclient.BulkDeleteAction:
+		case eslegclient.BulkActionDelete:
 			require.Equal(t, e.OpTypeDelete, caseOpType, caseMessage)
+			// We don't include the event source in a bulk DELETE
+			require.Nil(t, items[bulkEventIndex].event, caseMessage)
 		default:
 			require.FailNow(t, "unknown type")
 		}
@@ -387,6 +455,102 @@ func TestBulkEncodeEventsWithOpType(t *testing.T) {
 
 }
 
+func TestBulkEncodeEventsWithDataStreams(t *testing.T) {
+	cfg := common.MustNewConfigFrom(common.MapStr{"data_streams": true})
+	info := beat.Info{
+		IndexPrefix: "test",
+		Version:     version.GetDefaultVersion(),
+	}
+
+	im, err := idxmgmt.DefaultSupport(nil, info, common.MustNewConfigFrom(common.MapStr{
+		"output.elasticsearch.data_streams": true,
+	}))
+	require.NoError(t, err)
+
+	index, pipeline, err := buildSelectors(im, info, cfg)
+	require.NoError(t, err)
+
+	events := []publisher.Event{
+		{Content: beat.Event{
+			Meta: common.MapStr{"_id": "111", e.FieldMetaOpType: e.OpTypeIndex},
+			Fields: common.MapStr{
+				"data_stream": common.MapStr{"type": "logs", "dataset": "nginx.access", "namespace": "prod"},
+			},
+		}},
+		{Content: beat.Event{
+			Fields: common.MapStr{"message": "no data stream fields"},
+		}},
+		{Content: beat.Event{
+			Meta:   common.MapStr{"_id": "112", e.FieldMetaOpType: e.OpTypeDelete},
+			Fields: common.MapStr{"message": "deletes are not supported"},
+		}},
+	}
+
+	var items bulkRecorder
+	encoded := bulkEncodePublishRequest(logp.L(), *common.MustNewVersion(version.GetDefaultVersion()), index, pipeline, true, &items, events)
+	require.Len(t, encoded, 2)
+	require.Len(t, items, 2)
+
+	assert.Equal(t, eslegclient.BulkActionCreate, items[0].action)
+	assert.Equal(t, "logs-nginx.access-prod", items[0].meta.Index)
+	assert.Equal(t, "111", items[0].meta.ID)
+	assert.Equal(t, eslegclient.BulkActionCreate, items[1].action)
+	assert.Equal(t, "logs-generic-default", items[1].meta.Index)
+}
+
+func TestBuildSelectorsDataStreamsRefusesIndex(t *testing.T) {
+	info := beat.Info{IndexPrefix: "test", Version: version.GetDefaultVersion()}
+	im, err := idxmgmt.DefaultSupport(nil, info, common.NewConfig())
+	require.NoError(t, err)
+
+	cfg := common.MustNewConfigFrom(common.MapStr{"data_streams": true, "index": "test-%{+yyyy.MM.dd}"})
+	_, _, err = buildSelectors(im, info, cfg)
+	assert.Error(t, err)
+}
+
+// bulkRecorder records the items added to a bulk request.
+type bulkRecorder []bulkRecord
+
+type bulkRecord struct {
+	action eslegclient.BulkAction
+	meta   eslegclient.BulkMeta
+	event  *beat.Event
+}
+
+func (r *bulkRecorder) AddItem(action eslegclient.BulkAction, meta eslegclient.BulkMeta, event *beat.Event) error {
+	*r = append(*r, bulkRecord{action, meta, event})
+	return nil
+}
+
+func BenchmarkBulkEncodePublishRequest(b *testing.B) {
+	info := beat.Info{IndexPrefix: "test", Version: version.GetDefaultVersion()}
+	im, err := idxmgmt.DefaultSupport(nil, info, common.NewConfig())
+	require.NoError(b, err)
+	index, pipeline, err := buildSelectors(im, info, common.NewConfig())
+	require.NoError(b, err)
+
+	data := make([]publisher.Event, 1000)
+	for i := range data {
+		data[i] = publisher.Event{Content: beat.Event{
+			Timestamp: time.Now(),
+			Fields:    common.MapStr{"message": "test", "host": common.MapStr{"name": "localhost"}},
+		}}
+	}
+	events := make([]publisher.Event, len(data))
+	enc := eslegclient.NewJSONEncoder(nil, false)
+	esVersion := *common.MustNewVersion(version.GetDefaultVersion())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		enc.Reset()
+		copy(events, data)
+		if encoded := bulkEncodePublishRequest(logp.L(), esVersion, index, pipeline, false, enc, events); len(encoded) != len(data) {
+			b.Fail()
+		}
+	}
+}
+
 func TestClientWithAPIKey(t *testing.T) {
 	var headers http.Header
 
@@ -407,3 +571,34 @@ func TestClientWithAPIKey(t *testing.T) {
 	client.Connect()
 	assert.Equal(t, "ApiKey aHlva0hHNEJmV2s1dmlLWjE3Mlg6bzQ1SlVreXVTLS15aVNBdXV4bDhVdw==", headers.Get("Authorization"))
 }
+
+func TestClientWithOAuth2(t *testing.T) {
+	var headers http.Header
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "abc", "token_type": "Bearer", "expires_in": 3600}`))
+	}))
+	defer tokenServer.Close()
+
+	// Start a mock HTTP server, save request headers
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ClientSettings{
+		ConnectionSettings: eslegclient.ConnectionSettings{
+			URL: ts.URL,
+			OAuth2: &oauth2.Config{
+				TokenURL:     tokenServer.URL,
+				ClientID:     "id",
+				ClientSecret: "secret",
+			},
+		},
+	}, nil)
+	assert.NoError(t, err)
+
+	client.Connect()
+	assert.Equal(t, "Bearer abc", headers.Get("Authorization"))
+}
@@ -23,33 +23,45 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/transport/kerberos"
+	"github.com/elastic/beats/v7/libbeat/common/transport/oauth2"
 	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+	"github.com/elastic/beats/v7/libbeat/esleg/eslegclient"
+	"github.com/elastic/beats/v7/libbeat/outputs"
 )
 
 type elasticsearchConfig struct {
-	Protocol         string            `config:"protocol"`
-	Path             string            `config:"path"`
-	Params           map[string]string `config:"parameters"`
-	Headers          map[string]string `config:"headers"`
-	Username         string            `config:"username"`
-	Password         string            `config:"password"`
-	APIKey           string            `config:"api_key"`
-	ProxyURL         string            `config:"proxy_url"`
-	ProxyDisable     bool              `config:"proxy_disable"`
-	LoadBalance      bool              `config:"loadbalance"`
-	CompressionLevel int               `config:"compression_level" validate:"min=0, max=9"`
-	EscapeHTML       bool              `config:"escape_html"`
-	TLS              *tlscommon.Config `config:"ssl"`
-	Kerberos         *kerberos.Config  `config:"kerberos"`
-	BulkMaxSize      int               `config:"bulk_max_size"`
-	MaxRetries       int               `config:"max_retries"`
-	Timeout          time.Duration     `config:"timeout"`
-	Backoff          Backoff           `config:"backoff"`
-}
+	Protocol          string                `config:"protocol"`
+	Path              string                `config:"path"`
+	Params            map[string]string     `config:"parameters"`
+	Headers           map[string]string     `config:"headers"`
+	Username          string                `config:"username"`
+	Password          string                `config:"password"`
+	APIKey            string                `config:"api_key"`
+	ProxyURL          string                `config:"proxy_url"`
+	ProxyDisable      bool                  `config:"proxy_disable"`
+	ProxyLocalResolve bool                  `config:"proxy_use_local_resolver"`
+	LoadBalance       bool                  `config:"loadbalance"`
+	CompressionLevel  int                   `config:"compression_level" validate:"min=0, max=9"`
+	EscapeHTML        bool                  `config:"escape_html"`
+	TLS               *tlscommon.Config     `config:"ssl"`
+	Kerberos          *kerberos.Config      `config:"kerberos"`
+	OAuth2            *oauth2.Config        `config:"auth.oauth2"`
+	BulkMaxSize       int                   `config:"bulk_max_size"`
+	MaxRetries        int                   `config:"max_retries"`
+	Timeout           time.Duration         `config:"timeout"`
+	Backoff           outputs.BackoffConfig `config:"backoff"`
+
+	// TimestampPrecision is the number of fractional digits of the timestamps
+	// of the events.
+	TimestampPrecision common.TimestampPrecision `config:"timestamp_precision"`
+
+	// AutoCompression selects the compression level from the measured
+	// compression cost and link throughput.
+	AutoCompression eslegclient.AutoCompressionConfig `config:"compression_auto"`
 
-type Backoff struct {
-	Init time.Duration
-	Max  time.Duration
+	// DataStreams writes the events to the data streams selected by the
+	// data_stream fields of the events.
+	DataStreams bool `config:"data_streams"`
 }
 
 const (
@@ -72,10 +84,12 @@ var (
 		EscapeHTML:       false,
 		TLS:              nil,
 		Kerberos:         nil,
+		OAuth2:           nil,
 		LoadBalance:      true,
-		Backoff: Backoff{
-			Init: 1 * time.Second,
-			Max:  60 * time.Second,
+		Backoff:          outputs.DefaultBackoffConfig(),
+		AutoCompression: eslegclient.AutoCompressionConfig{
+			Enabled:  false,
+			Interval: 10 * time.Minute,
 		},
 	}
 )
@@ -91,5 +105,9 @@ func (c *elasticsearchConfig) Validate() error {
 		return fmt.Errorf("cannot set both api_key and username/password")
 	}
 
+	if c.OAuth2.IsEnabled() && (c.APIKey != "" || c.Username != "" || c.Kerberos.IsEnabled()) {
+		return fmt.Errorf("cannot set auth.oauth2 together with api_key, username/password or kerberos")
+	}
+
 	return nil
 }
@@ -93,24 +93,29 @@ func makeES(
 		var client outputs.NetworkClient
 		client, err = NewClient(ClientSettings{
 			ConnectionSettings: eslegclient.ConnectionSettings{
-				URL:              esURL,
-				Proxy:            proxyURL,
-				ProxyDisable:     config.ProxyDisable,
-				TLS:              tlsConfig,
-				Kerberos:         config.Kerberos,
-				Username:         config.Username,
-				Password:         config.Password,
-				APIKey:           config.APIKey,
-				Parameters:       params,
-				Headers:          config.Headers,
-				Timeout:          config.Timeout,
-				CompressionLevel: config.CompressionLevel,
-				Observer:         observer,
-				EscapeHTML:       config.EscapeHTML,
+				URL:                esURL,
+				Proxy:              proxyURL,
+				ProxyDisable:       config.ProxyDisable,
+				ProxyLocalResolve:  config.ProxyLocalResolve,
+				TLS:                tlsConfig,
+				Kerberos:           config.Kerberos,
+				OAuth2:             config.OAuth2,
+				Username:           config.Username,
+				Password:           config.Password,
+				APIKey:             config.APIKey,
+				Parameters:         params,
+				Headers:            config.Headers,
+				Timeout:            config.Timeout,
+				CompressionLevel:   config.CompressionLevel,
+				Observer:           observer,
+				EscapeHTML:         config.EscapeHTML,
+				TimestampPrecision: config.TimestampPrecision,
+				AutoCompression:    config.AutoCompression,
 			},
-			Index:    index,
-			Pipeline: pipeline,
-			Observer: observer,
+			Index:       index,
+			Pipeline:    pipeline,
+			Observer:    observer,
+			DataStreams: config.DataStreams,
 		}, &connectCallbackRegistry)
 		if err != nil {
 			return outputs.Fail(err)
@@ -120,7 +125,9 @@ func makeES(
 		clients[i] = client
 	}
 
-	return outputs.SuccessNet(config.LoadBalance, config.BulkMaxSize, config.MaxRetries, clients)
+	grp, err := outputs.SuccessNet(config.LoadBalance, config.BulkMaxSize, config.MaxRetries, clients)
+	grp.Backoff = &config.Backoff
+	return grp, err
 }
 
 func buildSelectors(
@@ -151,6 +158,7 @@ func buildPipelineSelector(cfg *common.Config) (outil.Selector, error) {
 		MultiKey:         "pipelines",
 		EnableSingleOnly: true,
 		FailEmpty:        false,
+		FallbackKey:      "fallback_pipeline",
 		Case:             outil.SelectorLowerCase,
 	})
 }
@@ -111,6 +111,40 @@ func TestPipelineSelection(t *testing.T) {
 			},
 			want: "test",
 		},
+		"pipeline format string per event": {
+			cfg:   map[string]interface{}{"pipeline": "%{[event.dataset]}-pipeline"},
+			event: beat.Event{Fields: common.MapStr{"event": common.MapStr{"dataset": "nginx.access"}}},
+			want:  "nginx.access-pipeline",
+		},
+		"fallback pipeline for missing fields": {
+			cfg: map[string]interface{}{
+				"pipeline":          "%{[event.dataset]}-pipeline",
+				"fallback_pipeline": "Fallback",
+			},
+			want: "fallback",
+		},
+		"fallback pipeline if no rule matches": {
+			cfg: map[string]interface{}{
+				"pipelines": []map[string]interface{}{{
+					"pipeline":         "nginx",
+					"when.equals.test": "nginx",
+				}},
+				"fallback_pipeline": "fallback",
+			},
+			event: beat.Event{Fields: common.MapStr{"test": "apache"}},
+			want:  "fallback",
+		},
+		"matching rule before fallback pipeline": {
+			cfg: map[string]interface{}{
+				"pipelines": []map[string]interface{}{{
+					"pipeline":         "nginx",
+					"when.equals.test": "nginx",
+				}},
+				"fallback_pipeline": "fallback",
+			},
+			event: beat.Event{Fields: common.MapStr{"test": "nginx"}},
+			want:  "nginx",
+		},
 	}
 
 	for name, test := range cases {
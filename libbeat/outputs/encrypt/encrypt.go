@@ -0,0 +1,209 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package encrypt provides the encryption of payloads written by outputs
+// storing events at rest, like the file and S3 outputs.
+package encrypt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/openpgp"
+)
+
+// Config configures the recipients payloads are encrypted for.
+type Config struct {
+	PGP PGPConfig `config:"pgp"`
+	Age AgeConfig `config:"age"`
+}
+
+// PGPConfig configures OpenPGP encryption.
+type PGPConfig struct {
+	// Recipients is a list of ASCII armored public keys, given either as the
+	// path to a key file or as the key itself.
+	Recipients []string `config:"recipients"`
+}
+
+// AgeConfig configures age encryption.
+type AgeConfig struct {
+	// Recipients is a list of age public keys, given either as the path to a
+	// recipients file or as the key itself.
+	Recipients []string `config:"recipients"`
+}
+
+// IsEnabled returns true if any recipient is configured.
+func (c *Config) IsEnabled() bool {
+	return c != nil && (len(c.PGP.Recipients) > 0 || len(c.Age.Recipients) > 0)
+}
+
+// Validate checks that all configured recipients can be loaded.
+func (c *Config) Validate() error {
+	if !c.IsEnabled() {
+		return nil
+	}
+	_, err := NewEncrypter(c)
+	return err
+}
+
+// Encrypter encrypts payloads for a fixed set of recipients.
+type Encrypter struct {
+	pgp openpgp.EntityList
+	age []age.Recipient
+}
+
+// NewEncrypter loads the recipients public keys.
+func NewEncrypter(config *Config) (*Encrypter, error) {
+	if !config.IsEnabled() {
+		return nil, errors.New("no encryption recipients configured")
+	}
+	if len(config.PGP.Recipients) > 0 && len(config.Age.Recipients) > 0 {
+		return nil, errors.New("PGP and age recipients can not be used together")
+	}
+
+	if len(config.Age.Recipients) > 0 {
+		recipients, err := loadAgeRecipients(config.Age.Recipients)
+		if err != nil {
+			return nil, err
+		}
+		return &Encrypter{age: recipients}, nil
+	}
+
+	recipients, err := loadRecipients(config.PGP.Recipients)
+	if err != nil {
+		return nil, err
+	}
+	return &Encrypter{pgp: recipients}, nil
+}
+
+// Encrypt returns a writer encrypting all data written to it into an OpenPGP
+// or age message written to w. The message is complete only after the
+// returned writer has been closed. Closing the writer does not close w.
+func (e *Encrypter) Encrypt(w io.Writer) (io.WriteCloser, error) {
+	if len(e.age) > 0 {
+		return age.Encrypt(w, e.age...)
+	}
+	hints := &openpgp.FileHints{IsBinary: true}
+	return openpgp.Encrypt(w, e.pgp, nil, hints, nil)
+}
+
+// Extension returns the file extension of the messages, `.age` for age and
+// `.gpg` for OpenPGP.
+func (e *Encrypter) Extension() string {
+	if len(e.age) > 0 {
+		return ".age"
+	}
+	return ".gpg"
+}
+
+func loadRecipients(keys []string) (openpgp.EntityList, error) {
+	var recipients openpgp.EntityList
+	for _, key := range keys {
+		entities, err := readArmoredKey(key)
+		if err != nil {
+			return nil, err
+		}
+		for _, entity := range entities {
+			if !canEncrypt(entity) {
+				return nil, fmt.Errorf("PGP key %X can not be used for encryption", entity.PrimaryKey.Fingerprint)
+			}
+		}
+		recipients = append(recipients, entities...)
+	}
+	return recipients, nil
+}
+
+func readArmoredKey(key string) (openpgp.EntityList, error) {
+	if isArmoredKey(key) {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read embedded PGP key: %v", err)
+		}
+		return entities, nil
+	}
+
+	f, err := os.Open(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PGP key file: %v", err)
+	}
+	defer f.Close()
+
+	entities, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PGP key file %v: %v", key, err)
+	}
+	return entities, nil
+}
+
+func loadAgeRecipients(keys []string) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+	for _, key := range keys {
+		if isAgeKey(key) {
+			recipient, err := age.ParseX25519Recipient(strings.TrimSpace(key))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read embedded age key: %v", err)
+			}
+			recipients = append(recipients, recipient)
+			continue
+		}
+
+		parsed, err := readAgeRecipientsFile(key)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, parsed...)
+	}
+	return recipients, nil
+}
+
+func readAgeRecipientsFile(path string) ([]age.Recipient, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open age recipients file: %v", err)
+	}
+	defer f.Close()
+
+	recipients, err := age.ParseRecipients(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read age recipients file %v: %v", path, err)
+	}
+	return recipients, nil
+}
+
+func isAgeKey(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "age1")
+}
+
+func isArmoredKey(s string) bool {
+	return strings.HasPrefix(strings.TrimSpace(s), "-----BEGIN PGP")
+}
+
+// canEncrypt checks if the entity has a key usable for encryption, as
+// openpgp.Encrypt fails late, when the first payload is written.
+func canEncrypt(e *openpgp.Entity) bool {
+	w, err := openpgp.Encrypt(ioutil.Discard, openpgp.EntityList{e}, nil, nil, nil)
+	if err != nil {
+		return false
+	}
+	w.Close()
+	return true
+}
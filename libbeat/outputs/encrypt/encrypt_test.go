@@ -0,0 +1,113 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package encrypt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/outputs/encrypt/encrypttest"
+)
+
+func TestEncrypt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "encrypt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pgpKey := encrypttest.NewPGPKey(t)
+	pgpPath := filepath.Join(dir, "key.asc")
+	require.NoError(t, ioutil.WriteFile(pgpPath, []byte(pgpKey.Recipient()), 0600))
+
+	ageKey := encrypttest.NewAgeKey(t)
+	agePath := filepath.Join(dir, "recipients.txt")
+	require.NoError(t, ioutil.WriteFile(agePath, []byte("# archive\n"+ageKey.Recipient()+"\n"), 0600))
+
+	cases := map[string]struct {
+		config Config
+		key    encrypttest.Key
+	}{
+		"PGP key file": {
+			config: Config{PGP: PGPConfig{Recipients: []string{pgpPath}}},
+			key:    pgpKey,
+		},
+		"embedded PGP key": {
+			config: Config{PGP: PGPConfig{Recipients: []string{pgpKey.Recipient()}}},
+			key:    pgpKey,
+		},
+		"age recipients file": {
+			config: Config{Age: AgeConfig{Recipients: []string{agePath}}},
+			key:    ageKey,
+		},
+		"embedded age key": {
+			config: Config{Age: AgeConfig{Recipients: []string{ageKey.Recipient()}}},
+			key:    ageKey,
+		},
+	}
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, test.config.Validate())
+
+			encrypter, err := NewEncrypter(&test.config)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			w, err := encrypter.Encrypt(&buf)
+			require.NoError(t, err)
+			w.Write([]byte("hello world"))
+			require.NoError(t, w.Close())
+
+			assert.NotContains(t, buf.String(), "hello world")
+			assert.Equal(t, "hello world", test.key.Decrypt(t, &buf))
+		})
+	}
+}
+
+func TestInvalidRecipients(t *testing.T) {
+	cases := map[string]Config{
+		"missing PGP file": {PGP: PGPConfig{Recipients: []string{"/does/not/exist.asc"}}},
+		"invalid PGP key": {PGP: PGPConfig{Recipients: []string{
+			"-----BEGIN PGP PUBLIC KEY BLOCK-----\n\ninvalid\n-----END PGP PUBLIC KEY BLOCK-----",
+		}}},
+		"missing age file": {Age: AgeConfig{Recipients: []string{"/does/not/exist.txt"}}},
+		"invalid age key":  {Age: AgeConfig{Recipients: []string{"age1invalid"}}},
+		"PGP and age": {
+			PGP: PGPConfig{Recipients: []string{encrypttest.NewPGPKey(t).Recipient()}},
+			Age: AgeConfig{Recipients: []string{encrypttest.NewAgeKey(t).Recipient()}},
+		},
+	}
+	for name, config := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, config.Validate())
+		})
+	}
+}
+
+func TestNotEnabled(t *testing.T) {
+	var config *Config
+	assert.False(t, config.IsEnabled())
+	assert.NoError(t, config.Validate())
+
+	_, err := NewEncrypter(&Config{})
+	assert.Error(t, err)
+}
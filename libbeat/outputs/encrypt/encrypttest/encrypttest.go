@@ -0,0 +1,126 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package encrypttest provides keys to test the encryption of payloads by the
+// outputs.
+package encrypttest
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"filippo.io/age"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// Key is a key pair payloads can be encrypted for.
+type Key interface {
+	// Recipient returns the public key, as configured in the outputs.
+	Recipient() string
+
+	// Decrypt returns the plaintext of the message, failing the test if it
+	// can not be decrypted.
+	Decrypt(t testing.TB, message io.Reader) string
+}
+
+type pgpKey struct {
+	entity  *openpgp.Entity
+	armored string
+}
+
+type ageKey struct {
+	identity *age.X25519Identity
+}
+
+// hashSHA256 is the OpenPGP identifier of SHA-256 (RFC 4880, section 9.4).
+const hashSHA256 = 8
+
+// NewPGPKey generates an OpenPGP key, its recipient is the ASCII armored
+// public key.
+func NewPGPKey(t testing.TB) Key {
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// keys generated by gpg advertise the hashes they support, do the same
+	for _, id := range entity.Identities {
+		id.SelfSignature.PreferredHash = []uint8{hashSHA256}
+	}
+
+	// self-signatures are only computed when serializing the private key
+	if err := entity.SerializePrivate(ioutil.Discard, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return pgpKey{entity: entity, armored: buf.String()}
+}
+
+func (k pgpKey) Recipient() string {
+	return k.armored
+}
+
+func (k pgpKey) Decrypt(t testing.TB, message io.Reader) string {
+	md, err := openpgp.ReadMessage(message, openpgp.EntityList{k.entity}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt PGP message: %v", err)
+	}
+	plain, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		t.Fatalf("failed to decrypt PGP message: %v", err)
+	}
+	return string(plain)
+}
+
+// NewAgeKey generates an age X25519 key, its recipient is the public key.
+func NewAgeKey(t testing.TB) Key {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ageKey{identity}
+}
+
+func (k ageKey) Recipient() string {
+	return k.identity.Recipient().String()
+}
+
+func (k ageKey) Decrypt(t testing.TB, message io.Reader) string {
+	r, err := age.Decrypt(message, k.identity)
+	if err != nil {
+		t.Fatalf("failed to decrypt age message: %v", err)
+	}
+	plain, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to decrypt age message: %v", err)
+	}
+	return string(plain)
+}
@@ -0,0 +1,263 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fileout
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+// archiver writes to files named after a strftime-style template, expanded
+// with the current local time. A new file is started when the expanded name
+// changes, e.g. every hour for a template with %H, and when the current file
+// reaches its maximum size. Files that are no longer written can be
+// compressed with gzip, they are removed when they are older than maxAge or
+// when there are more than maxFiles. Write and Close are safe for concurrent
+// use.
+type archiver struct {
+	log         *logp.Logger
+	template    string
+	maxSize     uint
+	maxFiles    uint
+	maxAge      time.Duration
+	compress    bool
+	permissions os.FileMode
+	now         func() time.Time
+
+	mutex sync.Mutex
+	file  *os.File
+	name  string // template expanded for the current file
+	path  string // path of the current file, name with an index if it is taken
+	size  uint
+}
+
+func newArchiver(template string, c config, log *logp.Logger) (*archiver, error) {
+	a := &archiver{
+		log:         log,
+		template:    template,
+		maxSize:     c.RotateEveryKb * 1024,
+		maxFiles:    c.NumberOfFiles,
+		maxAge:      c.MaxAge,
+		compress:    c.Compress,
+		permissions: os.FileMode(c.Permissions),
+		now:         time.Now,
+	}
+	if err := os.MkdirAll(filepath.Dir(template), a.dirMode()); err != nil {
+		return nil, fmt.Errorf("failed to make directories for new file: %v", err)
+	}
+	a.purge()
+	return a, nil
+}
+
+// Write writes data to the current file, rotating it before if the interval
+// of the template has changed or if data doesn't fit in the file.
+func (a *archiver) Write(data []byte) (int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	dataLen := uint(len(data))
+	if dataLen > a.maxSize {
+		return 0, fmt.Errorf("data size (%d bytes) is greater than "+
+			"the max file size (%d bytes)", dataLen, a.maxSize)
+	}
+
+	name := strftime(a.template, a.now())
+	if a.file == nil || name != a.name || a.size+dataLen > a.maxSize {
+		if err := a.rotate(name); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := a.file.Write(data)
+	a.size += uint(n)
+	if err != nil {
+		return n, fmt.Errorf("failed to write to file: %v", err)
+	}
+	return n, nil
+}
+
+// Close closes the current file, and compresses it if enabled. A new file is
+// started on the next write.
+func (a *archiver) Close() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.closeFile()
+}
+
+// rotate closes the current file and opens a new one for the expanded
+// template name, then removes the files past their retention.
+func (a *archiver) rotate(name string) error {
+	if err := a.closeFile(); err != nil {
+		a.log.Errorf("Failed to close file '%s': %v", a.path, err)
+	}
+
+	path := a.nextPath(name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, a.permissions)
+	if err != nil {
+		return fmt.Errorf("failed to open new file '%s': %v", path, err)
+	}
+	a.file, a.name, a.path, a.size = f, name, path, 0
+	a.log.Debugf("Rotated to file '%s'", path)
+
+	a.purge()
+	return nil
+}
+
+// nextPath returns the first path for name that is not taken by a file,
+// compressed or not. Files written before a restart, or before the file
+// reached its maximum size in the same interval, get an index appended.
+func (a *archiver) nextPath(name string) string {
+	for i := 0; ; i++ {
+		path := name
+		if i > 0 {
+			path += "." + strconv.Itoa(i)
+		}
+		if !exists(path) && !exists(path+".gz") {
+			return path
+		}
+	}
+}
+
+func (a *archiver) closeFile() error {
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	if err == nil && a.compress {
+		if cerr := compressFile(a.path, a.permissions); cerr != nil {
+			a.log.Errorf("Failed to compress file '%s': %v", a.path, cerr)
+		}
+	}
+	return err
+}
+
+// purge removes the files written by previous rotations that are older than
+// maxAge, and the oldest ones when there are more than maxFiles, counting
+// the current file.
+func (a *archiver) purge() {
+	type archived struct {
+		path    string
+		modTime time.Time
+	}
+
+	pattern := strftimeGlob(a.template)
+	var files []archived
+	for _, p := range []string{pattern, pattern + ".*"} {
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			a.log.Errorf("Failed to list archived files: %v", err)
+			return
+		}
+		for _, path := range matches {
+			if path == a.path {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			files = append(files, archived{path: path, modTime: info.ModTime()})
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	keep := int(a.maxFiles)
+	if a.file != nil {
+		keep--
+	}
+	now := a.now()
+	for i, f := range files {
+		if i < keep && (a.maxAge <= 0 || now.Sub(f.modTime) <= a.maxAge) {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			a.log.Errorf("Failed to remove archived file '%s': %v", f.path, err)
+			continue
+		}
+		a.log.Debugf("Removed archived file '%s'", f.path)
+	}
+}
+
+func (a *archiver) dirMode() os.FileMode {
+	mode := 0700
+	if a.permissions&0070 > 0 {
+		mode |= 0050
+	}
+	if a.permissions&0007 > 0 {
+		mode |= 0005
+	}
+	return os.FileMode(mode)
+}
+
+// compressFile replaces the file at path with a gzip compressed copy, named
+// path.gz. The copy is written to a temporary file first, so an interrupted
+// compression never leaves behind a truncated archive.
+func compressFile(path string, perm os.FileMode) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(path)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
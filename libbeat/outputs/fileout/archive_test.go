@@ -0,0 +1,193 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build !integration
+
+package fileout
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+func TestStrftime(t *testing.T) {
+	ts := time.Date(2020, 2, 3, 4, 5, 6, 0, time.UTC)
+	assert.Equal(t, "events-2020-02-03-04.ndjson", strftime("events-%Y-%m-%d-%H.ndjson", ts))
+	assert.Equal(t, "20200203040506", strftime("%y%Y%m%d%H%M%S", ts)[2:])
+	assert.Equal(t, "034-100%", strftime("%j-100%%", ts))
+	assert.Equal(t, "events-*-*.ndjson", strftimeGlob("events-%Y%m-%d.ndjson"))
+	assert.Equal(t, "100%-*", strftimeGlob("100%%-%H"))
+
+	assert.True(t, hasStrftime("events-%Y"))
+	assert.False(t, hasStrftime("events-100%%"))
+	assert.False(t, hasStrftime("events"))
+
+	assert.NoError(t, validateStrftime("events-%Y-%j-%%"))
+	assert.Error(t, validateStrftime("events-%s"))
+	assert.Error(t, validateStrftime("events-%"))
+}
+
+func TestArchiverRotatesOnInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := newTestArchiver(t, dir, config{RotateEveryKb: 1, NumberOfFiles: 7, Permissions: 0600})
+	now := time.Date(2020, 2, 3, 4, 5, 6, 0, time.Local)
+	a.now = func() time.Time { return now }
+
+	write(t, a, "first\n")
+	now = now.Add(30 * time.Minute)
+	write(t, a, "second\n")
+	now = now.Add(time.Hour)
+	write(t, a, "third\n")
+	require.NoError(t, a.Close())
+
+	assert.Equal(t, []string{"events-2020-02-03-04", "events-2020-02-03-05"}, listDir(t, dir))
+	assert.Equal(t, "first\nsecond\n", readFile(t, filepath.Join(dir, "events-2020-02-03-04")))
+	assert.Equal(t, "third\n", readFile(t, filepath.Join(dir, "events-2020-02-03-05")))
+
+	// A restart continues in a new file of the same interval.
+	write(t, a, "fourth\n")
+	require.NoError(t, a.Close())
+	assert.Equal(t, "fourth\n", readFile(t, filepath.Join(dir, "events-2020-02-03-05.1")))
+}
+
+func TestArchiverRotatesOnSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := newTestArchiver(t, dir, config{RotateEveryKb: 1, NumberOfFiles: 7, Permissions: 0600})
+	a.now = func() time.Time { return time.Date(2020, 2, 3, 4, 5, 6, 0, time.Local) }
+
+	line := string(make([]byte, 600))
+	write(t, a, line)
+	write(t, a, line)
+	require.NoError(t, a.Close())
+
+	assert.Equal(t, []string{"events-2020-02-03-04", "events-2020-02-03-04.1"}, listDir(t, dir))
+
+	_, err = a.Write(make([]byte, 2048))
+	assert.Error(t, err)
+}
+
+func TestArchiverCompress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	a := newTestArchiver(t, dir, config{RotateEveryKb: 1, NumberOfFiles: 7, Permissions: 0600, Compress: true})
+	now := time.Date(2020, 2, 3, 4, 5, 6, 0, time.Local)
+	a.now = func() time.Time { return now }
+
+	write(t, a, "first\n")
+	now = now.Add(time.Hour)
+	write(t, a, "second\n")
+	assert.Equal(t, []string{"events-2020-02-03-04.gz", "events-2020-02-03-05"}, listDir(t, dir))
+
+	require.NoError(t, a.Close())
+	assert.Equal(t, []string{"events-2020-02-03-04.gz", "events-2020-02-03-05.gz"}, listDir(t, dir))
+
+	f, err := os.Open(filepath.Join(dir, "events-2020-02-03-04.gz"))
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", string(content))
+
+	// The compressed file takes the name, so a restart uses a new one.
+	write(t, a, "third\n")
+	require.NoError(t, a.Close())
+	assert.Contains(t, listDir(t, dir), "events-2020-02-03-05.1.gz")
+}
+
+func TestArchiverRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fileout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	for i, name := range []string{"events-2020-02-03-01", "events-2020-02-03-02.gz", "events-2020-02-03-03", "other"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, ioutil.WriteFile(path, []byte("old\n"), 0600))
+		modTime := now.Add(-time.Duration(4-i) * time.Hour)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	// Files older than max_age are removed at startup.
+	a := newTestArchiver(t, dir, config{RotateEveryKb: 1, NumberOfFiles: 3, Permissions: 0600, MaxAge: 210 * time.Minute})
+	assert.Equal(t, []string{"events-2020-02-03-02.gz", "events-2020-02-03-03", "other"}, listDir(t, dir))
+
+	// The oldest files are removed when there are more than number_of_files.
+	a.now = func() time.Time { return now }
+	write(t, a, "new\n")
+	require.NoError(t, a.Close())
+	assert.Equal(t, []string{"events-2020-02-03-03", "other", "events-" + now.Format("2006-01-02-15")}, listDirByTime(t, dir))
+}
+
+func newTestArchiver(t *testing.T, dir string, c config) *archiver {
+	a, err := newArchiver(filepath.Join(dir, "events-%Y-%m-%d-%H"), c, logp.NewLogger("archiver"))
+	require.NoError(t, err)
+	return a
+}
+
+func write(t *testing.T, a *archiver, s string) {
+	_, err := a.Write([]byte(s))
+	require.NoError(t, err)
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func listDir(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names
+}
+
+func listDirByTime(t *testing.T, dir string) []string {
+	infos, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+	return names
+}
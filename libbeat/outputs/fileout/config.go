@@ -18,19 +18,25 @@
 package fileout
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/elastic/beats/v7/libbeat/common/file"
 	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/outputs/encrypt"
 )
 
 type config struct {
-	Path          string       `config:"path"`
-	Filename      string       `config:"filename"`
-	RotateEveryKb uint         `config:"rotate_every_kb" validate:"min=1"`
-	NumberOfFiles uint         `config:"number_of_files"`
-	Codec         codec.Config `config:"codec"`
-	Permissions   uint32       `config:"permissions"`
+	Path          string          `config:"path"`
+	Filename      string          `config:"filename"`
+	RotateEveryKb uint            `config:"rotate_every_kb" validate:"min=1"`
+	NumberOfFiles uint            `config:"number_of_files"`
+	Codec         codec.Config    `config:"codec"`
+	Permissions   uint32          `config:"permissions"`
+	Encryption    *encrypt.Config `config:"encryption"`
+	Compress      bool            `config:"compress"`
+	MaxAge        time.Duration   `config:"max_age" validate:"min=0"`
 }
 
 var (
@@ -47,5 +53,12 @@ func (c *config) Validate() error {
 			file.MaxBackupsLimit)
 	}
 
+	if err := validateStrftime(c.Filename); err != nil {
+		return fmt.Errorf("invalid filename: %v", err)
+	}
+	if (c.Compress || c.MaxAge > 0) && !hasStrftime(c.Filename) {
+		return errors.New("compress and max_age require a filename with a time based template, like %Y-%m-%d")
+	}
+
 	return nil
 }
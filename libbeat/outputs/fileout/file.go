@@ -18,9 +18,13 @@
 package fileout
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
@@ -28,6 +32,8 @@ import (
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/outputs"
 	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/outputs/encrypt"
+	"github.com/elastic/beats/v7/libbeat/privacy"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 )
 
@@ -40,8 +46,11 @@ type fileOutput struct {
 	filePath string
 	beat     beat.Info
 	observer outputs.Observer
-	rotator  *file.Rotator
+	rotator  io.WriteCloser
 	codec    codec.Codec
+
+	// encrypter is set if the events must be encrypted before being written.
+	encrypter *encrypt.Encrypter
 }
 
 // makeFileout instantiates a new file output instance.
@@ -82,13 +91,21 @@ func (out *fileOutput) init(beat beat.Info, c config) error {
 	out.filePath = path
 
 	var err error
-	out.rotator, err = file.NewFileRotator(
-		path,
-		file.MaxSizeBytes(c.RotateEveryKb*1024),
-		file.MaxBackups(c.NumberOfFiles),
-		file.Permissions(os.FileMode(c.Permissions)),
-		file.WithLogger(logp.NewLogger("rotator").With(logp.Namespace("rotator"))),
-	)
+	if hasStrftime(c.Filename) {
+		// The name of the file follows the time, files are rotated when
+		// the interval of the template changes. Only the filename is a
+		// template, the directory is taken as is.
+		template := filepath.Join(strings.ReplaceAll(c.Path, "%", "%%"), c.Filename)
+		out.rotator, err = newArchiver(template, c, logp.NewLogger("archiver").With(logp.Namespace("archiver")))
+	} else {
+		out.rotator, err = file.NewFileRotator(
+			path,
+			file.MaxSizeBytes(c.RotateEveryKb*1024),
+			file.MaxBackups(c.NumberOfFiles),
+			file.Permissions(os.FileMode(c.Permissions)),
+			file.WithLogger(logp.NewLogger("rotator").With(logp.Namespace("rotator"))),
+		)
+	}
 	if err != nil {
 		return err
 	}
@@ -98,9 +115,18 @@ func (out *fileOutput) init(beat beat.Info, c config) error {
 		return err
 	}
 
+	if c.Encryption.IsEnabled() {
+		out.encrypter, err = encrypt.NewEncrypter(c.Encryption)
+		if err != nil {
+			return err
+		}
+	}
+
 	out.log.Infof("Initialized file output. "+
-		"path=%v max_size_bytes=%v max_backups=%v permissions=%v",
-		path, c.RotateEveryKb*1024, c.NumberOfFiles, os.FileMode(c.Permissions))
+		"path=%v max_size_bytes=%v max_backups=%v permissions=%v encrypted=%v "+
+		"compress=%v max_age=%v",
+		path, c.RotateEveryKb*1024, c.NumberOfFiles, os.FileMode(c.Permissions),
+		out.encrypter != nil, c.Compress, c.MaxAge)
 
 	return nil
 }
@@ -117,6 +143,11 @@ func (out *fileOutput) Publish(_ context.Context, batch publisher.Batch) error {
 	events := batch.Events()
 	st.NewBatch(len(events))
 
+	if out.encrypter != nil {
+		out.publishEncrypted(events)
+		return nil
+	}
+
 	dropped := 0
 	for i := range events {
 		event := &events[i]
@@ -128,7 +159,7 @@ func (out *fileOutput) Publish(_ context.Context, batch publisher.Batch) error {
 			} else {
 				out.log.Warnf("Failed to serialize the event: %+v", err)
 			}
-			out.log.Debugf("Failed event: %v", event)
+			out.log.Debugf("Failed event: %v", privacy.LazyEvent(&event.Content))
 
 			dropped++
 			continue
@@ -156,6 +187,70 @@ func (out *fileOutput) Publish(_ context.Context, batch publisher.Batch) error {
 	return nil
 }
 
+// publishEncrypted writes all events of a batch as a single OpenPGP or age
+// message.
+// The message is base64 encoded and terminated by a newline, so every line
+// in the file can be decrypted on its own, and a crash can not leave behind
+// more than one incomplete message.
+func (out *fileOutput) publishEncrypted(events []publisher.Event) {
+	st := out.observer
+
+	var msg bytes.Buffer
+	b64 := base64.NewEncoder(base64.StdEncoding, &msg)
+	w, err := out.encrypter.Encrypt(b64)
+	if err != nil {
+		out.log.Errorf("Failed to encrypt events: %+v", err)
+		st.Dropped(len(events))
+		return
+	}
+
+	dropped := 0
+	for i := range events {
+		event := &events[i]
+
+		serializedEvent, err := out.codec.Encode(out.beat.Beat, &event.Content)
+		if err != nil {
+			if event.Guaranteed() {
+				out.log.Errorf("Failed to serialize the event: %+v", err)
+			} else {
+				out.log.Warnf("Failed to serialize the event: %+v", err)
+			}
+			out.log.Debugf("Failed event: %v", privacy.LazyEvent(&event.Content))
+
+			dropped++
+			continue
+		}
+
+		w.Write(serializedEvent)
+		w.Write([]byte{'\n'})
+	}
+
+	if dropped == len(events) {
+		st.Dropped(dropped)
+		return
+	}
+
+	if err := w.Close(); err != nil {
+		out.log.Errorf("Failed to encrypt events: %+v", err)
+		st.Dropped(len(events))
+		return
+	}
+	b64.Close()
+	msg.WriteByte('\n')
+
+	n, err := out.rotator.Write(msg.Bytes())
+	if err != nil {
+		st.WriteError(err)
+		out.log.Errorf("Writing encrypted events to file failed with: %+v", err)
+		st.Dropped(len(events))
+		return
+	}
+
+	st.WriteBytes(n)
+	st.Dropped(dropped)
+	st.Acked(len(events) - dropped)
+}
+
 func (out *fileOutput) String() string {
 	return "file(" + out.filePath + ")"
 }
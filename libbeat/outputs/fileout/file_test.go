@@ -18,3 +18,81 @@
 // +build !integration
 
 package fileout
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	_ "github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/outputs/encrypt/encrypttest"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+)
+
+func TestPublishEncrypted(t *testing.T) {
+	keys := map[string]encrypttest.Key{
+		"pgp": encrypttest.NewPGPKey(t),
+		"age": encrypttest.NewAgeKey(t),
+	}
+	for kind, key := range keys {
+		t.Run(kind, func(t *testing.T) {
+			testPublishEncrypted(t, "encryption."+kind+".recipients", key)
+		})
+	}
+}
+
+func testPublishEncrypted(t *testing.T, setting string, key encrypttest.Key) {
+	dir, err := ioutil.TempDir("", "fileout")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := common.MustNewConfigFrom(map[string]interface{}{
+		"path":     dir,
+		"filename": "out",
+		setting:    []string{key.Recipient()},
+	})
+	group, err := makeFileout(nil, beat.Info{Beat: "test"}, outputs.NewNilObserver(), cfg)
+	require.NoError(t, err)
+	out := group.Clients[0]
+	defer out.Close()
+
+	for _, msg := range []string{"first", "second"} {
+		batch := outest.NewBatch(beat.Event{
+			Fields: common.MapStr{"message": msg},
+		})
+		require.NoError(t, out.Publish(context.Background(), batch))
+	}
+
+	f, err := os.Open(filepath.Join(dir, "out"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		assert.NotContains(t, scanner.Text(), "message")
+
+		raw, err := base64.StdEncoding.DecodeString(scanner.Text())
+		require.NoError(t, err)
+		plain := key.Decrypt(t, bytes.NewReader(raw))
+		lines = append(lines, strings.TrimSpace(plain))
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"message":"first"`)
+	assert.Contains(t, lines[1], `"message":"second"`)
+}
@@ -0,0 +1,112 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package fileout
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// strftimeLayouts maps the supported strftime conversion specifications to
+// the layouts of the time package.
+var strftimeLayouts = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'H': "15",
+	'M': "04",
+	'S': "05",
+}
+
+// hasStrftime checks if s contains strftime conversion specifications.
+func hasStrftime(s string) bool {
+	for i := 0; i < len(s)-1; i++ {
+		if s[i] == '%' {
+			if s[i+1] != '%' {
+				return true
+			}
+			i++
+		}
+	}
+	return false
+}
+
+// validateStrftime checks that layout only contains supported conversion
+// specifications: %Y, %y, %m, %d, %H, %M, %S, %j (day of the year) and %% for
+// a literal %.
+func validateStrftime(layout string) error {
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			continue
+		}
+		if i == len(layout)-1 {
+			return fmt.Errorf("incomplete conversion specification at the end of '%v'", layout)
+		}
+		i++
+		if _, ok := strftimeLayouts[layout[i]]; !ok && layout[i] != 'j' && layout[i] != '%' {
+			return fmt.Errorf("unsupported conversion specification %%%c in '%v'", layout[i], layout)
+		}
+	}
+	return nil
+}
+
+// strftime formats t according to the layout, which must be valid.
+func strftime(layout string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' || i == len(layout)-1 {
+			b.WriteByte(layout[i])
+			continue
+		}
+		i++
+		switch c := layout[i]; c {
+		case '%':
+			b.WriteByte('%')
+		case 'j':
+			day := strconv.Itoa(t.YearDay())
+			b.WriteString(strings.Repeat("0", 3-len(day)) + day)
+		default:
+			b.WriteString(t.Format(strftimeLayouts[c]))
+		}
+	}
+	return b.String()
+}
+
+// strftimeGlob returns a glob pattern matching the strings formatted with the
+// layout.
+func strftimeGlob(layout string) string {
+	var b strings.Builder
+	for i := 0; i < len(layout); i++ {
+		switch {
+		case layout[i] != '%' || i == len(layout)-1:
+			b.WriteByte(layout[i])
+		case layout[i+1] == '%':
+			b.WriteByte('%')
+			i++
+		default:
+			if !strings.HasSuffix(b.String(), "*") {
+				b.WriteByte('*')
+			}
+			i++
+		}
+	}
+	return b.String()
+}
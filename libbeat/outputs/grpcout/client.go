@@ -0,0 +1,316 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpcout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	protobuf "github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/outputs/grpcout/proto"
+	"github.com/elastic/beats/v7/libbeat/privacy"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// client publishes the batches over a single stream. Up to pipelining
+// batches wait for their acknowledgement at the same time, further calls to
+// Publish block until the collector acknowledges a batch.
+type client struct {
+	log        *logp.Logger
+	observer   outputs.Observer
+	host       string
+	index      string
+	codec      codec.Codec
+	timeout    time.Duration
+	pipelining int
+	dialOpts   []grpc.DialOption
+
+	conn   *grpc.ClientConn
+	stream proto.EventStream_PublishClient
+	cancel context.CancelFunc
+	slots  chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]*pendingBatch
+	closed  bool // set once the stream can not receive acknowledgements
+}
+
+// pendingBatch is a batch waiting for its acknowledgement.
+type pendingBatch struct {
+	batch publisher.Batch
+
+	// events are the events sent to the collector, in the order of the
+	// request. It lacks the events that could not be encoded.
+	events []publisher.Event
+
+	// timer closes the stream if the batch is not acknowledged in time.
+	timer *time.Timer
+}
+
+var (
+	errNotConnected = errors.New("grpc client is not connected")
+	errStreamClosed = errors.New("grpc stream is closed")
+)
+
+func newClient(
+	observer outputs.Observer,
+	host string,
+	index string,
+	writer codec.Codec,
+	config *grpcConfig,
+	dialOpts ...grpc.DialOption,
+) *client {
+	return &client{
+		log:        logp.NewLogger(logSelector),
+		observer:   observer,
+		host:       host,
+		index:      strings.ToLower(index),
+		codec:      writer,
+		timeout:    config.Timeout,
+		pipelining: config.Pipelining,
+		dialOpts:   dialOpts,
+	}
+}
+
+func (c *client) Connect() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, c.host, append(c.dialOpts, grpc.WithBlock())...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %v: %v", c.host, err)
+	}
+
+	streamCtx, streamCancel := context.WithCancel(context.Background())
+	stream, err := proto.NewEventStreamClient(conn).Publish(streamCtx)
+	if err != nil {
+		streamCancel()
+		conn.Close()
+		return fmt.Errorf("failed to open the stream to %v: %v", c.host, err)
+	}
+
+	c.conn = conn
+	c.stream = stream
+	c.cancel = streamCancel
+	c.slots = make(chan struct{}, c.pipelining)
+	c.pending = map[uint64]*pendingBatch{}
+	c.closed = false
+
+	c.wg.Add(1)
+	go c.ackLoop(stream)
+	return nil
+}
+
+// Close closes the stream. The batches waiting for their acknowledgement are
+// retried.
+func (c *client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	c.cancel()
+	c.wg.Wait()
+	err := c.conn.Close()
+	c.conn = nil
+	c.stream = nil
+	return err
+}
+
+func (c *client) String() string {
+	return "grpc(" + c.host + ")"
+}
+
+func (c *client) Publish(ctx context.Context, batch publisher.Batch) error {
+	if c.stream == nil {
+		return errNotConnected
+	}
+
+	events := batch.Events()
+	c.observer.NewBatch(len(events))
+
+	req, encoded := c.makeRequest(batch, events)
+	if dropped := len(events) - len(encoded); dropped > 0 {
+		c.observer.Dropped(dropped)
+	}
+	if len(encoded) == 0 {
+		batch.ACK()
+		return nil
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		batch.Cancelled()
+		return ctx.Err()
+	}
+
+	cancel := c.cancel
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		<-c.slots
+		c.observer.Failed(len(encoded))
+		batch.RetryEvents(encoded)
+		return errStreamClosed
+	}
+	c.nextID++
+	req.Id = c.nextID
+	c.pending[req.Id] = &pendingBatch{
+		batch:  batch,
+		events: encoded,
+		timer: time.AfterFunc(c.timeout, func() {
+			c.log.Errorf("Batch %v was not acknowledged by %v within %v, closing the stream.", req.Id, c.host, c.timeout)
+			cancel()
+		}),
+	}
+	c.mu.Unlock()
+
+	if err := c.stream.Send(req); err != nil {
+		c.observer.WriteError(err)
+		if p := c.take(req.Id); p != nil {
+			c.observer.Failed(len(p.events))
+			batch.RetryEvents(p.events)
+		}
+		return fmt.Errorf("failed to send batch to %v: %v", c.host, err)
+	}
+	c.observer.WriteBytes(protobuf.Size(req))
+	return nil
+}
+
+// makeRequest encodes the events of a batch. Events that can not be encoded
+// are dropped. It returns the request and the encoded events.
+func (c *client) makeRequest(batch publisher.Batch, events []publisher.Event) (*proto.Batch, []publisher.Event) {
+	req := &proto.Batch{Events: make([]*proto.Event, 0, len(events))}
+	encoded := make([]publisher.Event, 0, len(events))
+	for i := range events {
+		event := &events[i]
+		serializedEvent, err := c.codec.Encode(c.index, &event.Content)
+		if err != nil {
+			c.log.Errorf("Dropping event: %+v", err)
+			if c.log.IsDebug() {
+				c.log.Debugf("failed event: %v", privacy.LazyEvent(&event.Content))
+			}
+			publisher.DeadLetter(batch, *event, fmt.Sprintf("failed to encode the event: %v", err))
+			continue
+		}
+
+		payload := make([]byte, len(serializedEvent))
+		copy(payload, serializedEvent)
+		req.Events = append(req.Events, &proto.Event{
+			Timestamp: event.Content.Timestamp.UnixNano(),
+			Payload:   payload,
+		})
+		encoded = append(encoded, *event)
+	}
+	return req, encoded
+}
+
+// ackLoop receives the acknowledgements until the stream is closed, then
+// retries the batches that were not acknowledged.
+func (c *client) ackLoop(stream proto.EventStream_PublishClient) {
+	defer c.wg.Done()
+	for {
+		ack, err := stream.Recv()
+		if err != nil {
+			if err != io.EOF && status.Code(err) != codes.Canceled {
+				c.log.Errorf("Failed to receive acknowledgements from %v: %v", c.host, err)
+				c.observer.ReadError(err)
+			}
+			c.retryPending()
+			return
+		}
+
+		p := c.take(ack.Id)
+		if p == nil {
+			c.log.Warnf("Ignoring the acknowledgement of unknown batch %v from %v.", ack.Id, c.host)
+			continue
+		}
+		c.handleAck(p, ack)
+	}
+}
+
+func (c *client) handleAck(p *pendingBatch, ack *proto.Ack) {
+	if ack.Retry {
+		c.log.Warnf("%v requested to retry a batch of %v events: %v", c.host, len(p.events), ack.Error)
+		c.observer.Failed(len(p.events))
+		p.batch.RetryEvents(p.events)
+		return
+	}
+
+	if len(ack.RetryEvents) == 0 {
+		c.observer.Acked(len(p.events))
+		p.batch.ACK()
+		return
+	}
+
+	seen := make([]bool, len(p.events))
+	var retry []publisher.Event
+	for _, i := range ack.RetryEvents {
+		if int(i) < len(p.events) && !seen[i] {
+			seen[i] = true
+			retry = append(retry, p.events[i])
+		}
+	}
+	c.log.Warnf("%v requested to retry %v of %v events: %v", c.host, len(retry), len(p.events), ack.Error)
+	c.observer.Acked(len(p.events) - len(retry))
+	c.observer.Failed(len(retry))
+	p.batch.RetryEvents(retry)
+}
+
+// take removes the pending batch with the given id, and frees its slot.
+func (c *client) take(id uint64) *pendingBatch {
+	c.mu.Lock()
+	p := c.pending[id]
+	delete(c.pending, id)
+	c.mu.Unlock()
+
+	if p != nil {
+		p.timer.Stop()
+		<-c.slots
+	}
+	return p
+}
+
+func (c *client) retryPending() {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = map[uint64]*pendingBatch{}
+	c.closed = true
+	c.mu.Unlock()
+
+	for _, p := range pending {
+		p.timer.Stop()
+		<-c.slots
+		c.observer.Failed(len(p.events))
+		p.batch.RetryEvents(p.events)
+	}
+}
@@ -0,0 +1,219 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpcout
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	jsoncodec "github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/outputs/grpcout/proto"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+)
+
+// testCollector passes the received batches to the test, which sends the
+// acknowledgements.
+type testCollector struct {
+	proto.UnimplementedEventStreamServer
+	batches chan *proto.Batch
+	acks    chan *proto.Ack
+}
+
+func (s *testCollector) Publish(stream proto.EventStream_PublishServer) error {
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			batch, err := stream.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			s.batches <- batch
+		}
+	}()
+	for {
+		select {
+		case ack := <-s.acks:
+			if err := stream.Send(ack); err != nil {
+				return err
+			}
+		case err := <-errs:
+			return err
+		}
+	}
+}
+
+func startCollector(t *testing.T) (*testCollector, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	collector := &testCollector{
+		batches: make(chan *proto.Batch, 10),
+		acks:    make(chan *proto.Ack, 10),
+	}
+	server := grpc.NewServer()
+	proto.RegisterEventStreamServer(server, collector)
+	go server.Serve(l)
+	t.Cleanup(server.Stop)
+	return collector, l.Addr().String()
+}
+
+func connectTestClient(t *testing.T, host string, timeout time.Duration, pipelining int) *client {
+	config := defaultConfig()
+	config.Timeout = timeout
+	config.Pipelining = pipelining
+	c := newClient(outputs.NewNilObserver(), host, "testbeat", jsoncodec.New("1.2.3", jsoncodec.Config{}), &config, grpc.WithInsecure())
+	require.NoError(t, c.Connect())
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// signalBatch returns a batch of n events, and a channel receiving its
+// signals.
+func signalBatch(n int) (*outest.Batch, chan outest.BatchSignal) {
+	events := make([]beat.Event, n)
+	for i := range events {
+		events[i] = beat.Event{Timestamp: time.Now(), Fields: common.MapStr{"n": i}}
+	}
+	signals := make(chan outest.BatchSignal, 1)
+	batch := outest.NewBatch(events...)
+	batch.OnSignal = func(sig outest.BatchSignal) { signals <- sig }
+	return batch, signals
+}
+
+func waitSignal(t *testing.T, signals chan outest.BatchSignal) outest.BatchSignal {
+	select {
+	case sig := <-signals:
+		return sig
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for the batch signal")
+		return outest.BatchSignal{}
+	}
+}
+
+func TestPublishACK(t *testing.T) {
+	collector, host := startCollector(t)
+	c := connectTestClient(t, host, time.Minute, 2)
+
+	batch, signals := signalBatch(3)
+	require.NoError(t, c.Publish(context.Background(), batch))
+
+	req := <-collector.batches
+	require.Len(t, req.Events, 3)
+	var fields common.MapStr
+	require.NoError(t, json.Unmarshal(req.Events[2].Payload, &fields))
+	assert.EqualValues(t, 2, fields["n"])
+	assert.Equal(t, batch.Events()[2].Content.Timestamp.UnixNano(), req.Events[2].Timestamp)
+
+	collector.acks <- &proto.Ack{Id: req.Id}
+	assert.Equal(t, outest.BatchACK, waitSignal(t, signals).Tag)
+}
+
+func TestPublishRetry(t *testing.T) {
+	collector, host := startCollector(t)
+	c := connectTestClient(t, host, time.Minute, 2)
+
+	batch, signals := signalBatch(3)
+	require.NoError(t, c.Publish(context.Background(), batch))
+	req := <-collector.batches
+	collector.acks <- &proto.Ack{Id: req.Id, Retry: true, Error: "overloaded"}
+
+	sig := waitSignal(t, signals)
+	assert.Equal(t, outest.BatchRetryEvents, sig.Tag)
+	assert.Len(t, sig.Events, 3)
+}
+
+func TestPublishRetryEvents(t *testing.T) {
+	collector, host := startCollector(t)
+	c := connectTestClient(t, host, time.Minute, 2)
+
+	batch, signals := signalBatch(3)
+	require.NoError(t, c.Publish(context.Background(), batch))
+	req := <-collector.batches
+	collector.acks <- &proto.Ack{Id: req.Id, RetryEvents: []uint32{1, 1, 7}, Error: "invalid"}
+
+	sig := waitSignal(t, signals)
+	assert.Equal(t, outest.BatchRetryEvents, sig.Tag)
+	require.Len(t, sig.Events, 1)
+	assert.Equal(t, 1, sig.Events[0].Content.Fields["n"])
+}
+
+func TestPublishPipelining(t *testing.T) {
+	collector, host := startCollector(t)
+	c := connectTestClient(t, host, time.Minute, 2)
+
+	first, firstSignals := signalBatch(1)
+	require.NoError(t, c.Publish(context.Background(), first))
+	second, _ := signalBatch(1)
+	require.NoError(t, c.Publish(context.Background(), second))
+
+	// The third batch waits until a batch is acknowledged.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	third, thirdSignals := signalBatch(1)
+	assert.Error(t, c.Publish(ctx, third))
+	assert.Equal(t, outest.BatchCancelled, waitSignal(t, thirdSignals).Tag)
+
+	req := <-collector.batches
+	collector.acks <- &proto.Ack{Id: req.Id}
+	assert.Equal(t, outest.BatchACK, waitSignal(t, firstSignals).Tag)
+
+	third, thirdSignals = signalBatch(1)
+	require.NoError(t, c.Publish(context.Background(), third))
+}
+
+func TestCloseRetriesPending(t *testing.T) {
+	collector, host := startCollector(t)
+	c := connectTestClient(t, host, time.Minute, 2)
+
+	batch, signals := signalBatch(2)
+	require.NoError(t, c.Publish(context.Background(), batch))
+	<-collector.batches
+
+	require.NoError(t, c.Close())
+	sig := waitSignal(t, signals)
+	assert.Equal(t, outest.BatchRetryEvents, sig.Tag)
+	assert.Len(t, sig.Events, 2)
+}
+
+func TestAckTimeout(t *testing.T) {
+	collector, host := startCollector(t)
+	c := connectTestClient(t, host, 200*time.Millisecond, 2)
+
+	batch, signals := signalBatch(2)
+	require.NoError(t, c.Publish(context.Background(), batch))
+	<-collector.batches
+
+	sig := waitSignal(t, signals)
+	assert.Equal(t, outest.BatchRetryEvents, sig.Tag)
+
+	// Publishing fails once the stream is closed, so that the client is
+	// reconnected.
+	next, _ := signalBatch(1)
+	assert.Error(t, c.Publish(context.Background(), next))
+}
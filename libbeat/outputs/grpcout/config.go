@@ -0,0 +1,48 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package grpcout
+
+import (
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+)
+
+type grpcConfig struct {
+	LoadBalance bool                  `config:"loadbalance"`
+	BulkMaxSize int                   `config:"bulk_max_size"`
+	Timeout     time.Duration         `config:"timeout"       validate:"min=1"`
+	Pipelining  int                   `config:"pipelining"    validate:"min=1"`
+	MaxRetries  int                   `config:"max_retries"   validate:"min=-1"`
+	TLS         *tlscommon.Config     `config:"ssl"`
+	Backoff     outputs.BackoffConfig `config:"backoff"`
+	Codec       codec.Config          `config:"codec"`
+}
+
+func defaultConfig() grpcConfig {
+	return grpcConfig{
+		LoadBalance: false,
+		BulkMaxSize: 2048,
+		Timeout:     30 * time.Second,
+		Pipelining:  2,
+		MaxRetries:  3,
+		Backoff:     outputs.DefaultBackoffConfig(),
+	}
+}
@@ -0,0 +1,375 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package httpout
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/privacy"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// maxResponseSize limits the part of the response body that is read for
+// logging the reason of rejected requests.
+const maxResponseSize = 1024
+
+type client struct {
+	log      *logp.Logger
+	observer outputs.Observer
+	http     httpClient
+	index    string
+	codec    codec.Codec
+
+	method           string
+	url              *fmtstr.EventFormatString
+	headers          map[string]*fmtstr.EventFormatString
+	body             *fmtstr.EventFormatString
+	format           string
+	compressionLevel int
+	retryOnStatus    map[int]bool
+	username         string
+	password         string
+}
+
+// request holds the events that are sent to the same URL with the same
+// headers.
+type request struct {
+	url    string
+	header http.Header
+	events []publisher.Event
+	bodies [][]byte
+}
+
+func newClient(
+	observer outputs.Observer,
+	http httpClient,
+	index string,
+	writer codec.Codec,
+	config *httpConfig,
+) *client {
+	retryOnStatus := make(map[int]bool, len(config.RetryOnStatus))
+	for _, status := range config.RetryOnStatus {
+		retryOnStatus[status] = true
+	}
+
+	return &client{
+		log:              logp.NewLogger(logSelector),
+		observer:         observer,
+		http:             http,
+		index:            strings.ToLower(index),
+		codec:            writer,
+		method:           strings.ToUpper(config.Method),
+		url:              config.URL,
+		headers:          config.Headers,
+		body:             config.Body,
+		format:           config.Format,
+		compressionLevel: config.CompressionLevel,
+		retryOnStatus:    retryOnStatus,
+		username:         config.Username,
+		password:         config.Password,
+	}
+}
+
+// Connect does nothing, connections are established by the requests.
+func (c *client) Connect() error {
+	return nil
+}
+
+func (c *client) Close() error {
+	c.http.CloseIdleConnections()
+	return nil
+}
+
+func (c *client) String() string {
+	return "http"
+}
+
+func (c *client) Publish(ctx context.Context, batch publisher.Batch) error {
+	events := batch.Events()
+	c.observer.NewBatch(len(events))
+
+	requests, encoded := c.makeRequests(batch, events)
+	if dropped := len(events) - encoded; dropped > 0 {
+		c.observer.Dropped(dropped)
+	}
+
+	acked, retry, err := c.sendRequests(ctx, batch, requests)
+	c.observer.Acked(acked)
+	if len(retry) > 0 {
+		c.observer.Failed(len(retry))
+		batch.RetryEvents(retry)
+		return err
+	}
+
+	batch.ACK()
+	return nil
+}
+
+// makeRequests encodes the events and groups them by the URL and headers
+// formatted for each event, keeping the order of the events. Events that can
+// not be formatted or encoded are dropped. It returns the requests and the
+// number of events in them.
+func (c *client) makeRequests(batch publisher.Batch, events []publisher.Event) ([]*request, int) {
+	var requests []*request
+	byKey := map[string]*request{}
+	count := 0
+	for i := range events {
+		event := &events[i]
+		body, err := c.encode(&event.Content)
+		if err != nil {
+			c.dropEvent(batch, event, fmt.Errorf("failed to encode the event: %v", err))
+			continue
+		}
+		rawURL, header, err := c.target(&event.Content)
+		if err != nil {
+			c.dropEvent(batch, event, err)
+			continue
+		}
+
+		key := requestKey(rawURL, header)
+		r := byKey[key]
+		if r == nil {
+			r = &request{url: rawURL, header: header}
+			byKey[key] = r
+			requests = append(requests, r)
+		}
+		r.events = append(r.events, *event)
+		r.bodies = append(r.bodies, body)
+		count++
+	}
+	return requests, count
+}
+
+func (c *client) dropEvent(batch publisher.Batch, event *publisher.Event, err error) {
+	c.log.Errorf("Dropping event: %+v", err)
+	if c.log.IsDebug() {
+		c.log.Debugf("failed event: %v", privacy.LazyEvent(&event.Content))
+	}
+	publisher.DeadLetter(batch, *event, err.Error())
+}
+
+// encode returns the body of the event in the request, formatted by the body
+// template if configured, or encoded by the codec otherwise.
+func (c *client) encode(event *beat.Event) ([]byte, error) {
+	if c.body != nil {
+		return c.body.RunBytes(event)
+	}
+
+	serializedEvent, err := c.codec.Encode(c.index, event)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, len(serializedEvent))
+	copy(buf, serializedEvent)
+	return buf, nil
+}
+
+// target returns the URL and the headers of the request of the event.
+func (c *client) target(event *beat.Event) (string, http.Header, error) {
+	rawURL, err := c.url.Run(event)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to format the url: %v", err)
+	}
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", nil, fmt.Errorf("invalid url '%v', the url must be an absolute http or https URL", redactURL(rawURL))
+	}
+
+	header := make(http.Header, len(c.headers))
+	for name, value := range c.headers {
+		v, err := value.Run(event)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to format the %v header: %v", name, err)
+		}
+		header.Set(name, v)
+	}
+	return rawURL, header, nil
+}
+
+// requestKey returns the key of the request with the url and the headers.
+func requestKey(rawURL string, header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var key strings.Builder
+	key.WriteString(rawURL)
+	for _, name := range names {
+		key.WriteString("\n" + name + ": " + header.Get(name))
+	}
+	return key.String()
+}
+
+// sendRequests sends the requests in order. It returns the number of
+// acknowledged events and the events that need to be retried. Events
+// rejected with a status that is not retried are dropped.
+func (c *client) sendRequests(ctx context.Context, batch publisher.Batch, requests []*request) (int, []publisher.Event, error) {
+	var (
+		acked   int
+		retry   []publisher.Event
+		lastErr error
+	)
+	for i, r := range requests {
+		status, response, err := c.send(ctx, r)
+		if err != nil {
+			// Do not send the remaining requests, the endpoint is likely to
+			// be unavailable for them too.
+			c.observer.WriteError(err)
+			for _, r := range requests[i:] {
+				retry = append(retry, r.events...)
+			}
+			return acked, retry, fmt.Errorf("failed to send events to %v: %v", redactURL(r.url), err)
+		}
+
+		switch {
+		case status >= 200 && status < 300:
+			acked += len(r.events)
+		case c.retryOnStatus[status]:
+			c.log.Warnf("%v events were not accepted by %v with HTTP status %v, they will be retried: %s",
+				len(r.events), redactURL(r.url), status, response)
+			retry = append(retry, r.events...)
+			lastErr = fmt.Errorf("events were not accepted by %v with HTTP status %v", redactURL(r.url), status)
+		default:
+			c.log.Errorf("Dropping %v events rejected by %v with HTTP status %v: %s",
+				len(r.events), redactURL(r.url), status, response)
+			reason := fmt.Sprintf("rejected with HTTP status %v: %s", status, response)
+			for _, event := range r.events {
+				publisher.DeadLetter(batch, event, reason)
+			}
+			c.observer.Dropped(len(r.events))
+		}
+	}
+	return acked, retry, lastErr
+}
+
+// send sends the request and returns the status code and the beginning of the
+// body of the response.
+func (c *client) send(ctx context.Context, r *request) (int, []byte, error) {
+	body, err := c.encodeBody(r.bodies)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req, err := http.NewRequest(c.method, r.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", c.contentType())
+	}
+	if c.compressionLevel > 0 {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	response, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err == nil {
+		// read the rest of the body, so the connection can be reused
+		_, err = io.Copy(ioutil.Discard, resp.Body)
+	}
+	if err != nil {
+		c.observer.ReadError(err)
+	}
+	return resp.StatusCode, bytes.TrimSpace(response), nil
+}
+
+// encodeBody joins the bodies of the events in the configured format,
+// compressing the result if enabled.
+func (c *client) encodeBody(bodies [][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if c.compressionLevel > 0 {
+		var err error
+		gz, err = gzip.NewWriterLevel(&buf, c.compressionLevel)
+		if err != nil {
+			return nil, err
+		}
+		w = gz
+	}
+
+	switch c.format {
+	case formatJSONArray:
+		w.Write([]byte{'['})
+		for i, b := range bodies {
+			if i > 0 {
+				w.Write([]byte{','})
+			}
+			w.Write(b)
+		}
+		w.Write([]byte{']'})
+	default:
+		for _, b := range bodies {
+			w.Write(b)
+			w.Write([]byte{'\n'})
+		}
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *client) contentType() string {
+	if c.format == formatJSONArray {
+		return "application/json"
+	}
+	return "application/x-ndjson"
+}
+
+// redactURL removes the credentials and the query, which may contain secrets,
+// of the URL for logging.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "<invalid url>"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
@@ -0,0 +1,217 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package httpout
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+)
+
+// receivedRequest is a request received by the test server.
+type receivedRequest struct {
+	method string
+	path   string
+	header http.Header
+	body   string
+}
+
+// testServer records the requests and responds with the status returned by
+// status.
+type testServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []receivedRequest
+}
+
+func newTestServer(t *testing.T, status func(r *http.Request) int) *testServer {
+	s := &testServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+			body = gz
+		}
+		data, err := ioutil.ReadAll(body)
+		require.NoError(t, err)
+
+		s.mu.Lock()
+		s.requests = append(s.requests, receivedRequest{
+			method: r.Method,
+			path:   r.URL.Path,
+			header: r.Header,
+			body:   string(data),
+		})
+		s.mu.Unlock()
+
+		code := http.StatusOK
+		if status != nil {
+			code = status(r)
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestClient(t *testing.T, settings map[string]interface{}) *client {
+	config := defaultConfig()
+	require.NoError(t, common.MustNewConfigFrom(settings).Unpack(&config))
+	return newClient(outputs.NewNilObserver(), &http.Client{}, "testbeat", json.New("1.2.3", json.Config{}), &config)
+}
+
+func TestPublishGroupsRequests(t *testing.T) {
+	server := newTestServer(t, nil)
+	client := newTestClient(t, map[string]interface{}{
+		"url": server.URL + "/%{[service]}",
+		"headers": map[string]interface{}{
+			"X-Tenant": "%{[tenant]}",
+		},
+		"compression_level": 5,
+	})
+
+	batch := outest.NewBatch(
+		beat.Event{Fields: common.MapStr{"service": "a", "tenant": "1", "n": 0}},
+		beat.Event{Fields: common.MapStr{"service": "b", "tenant": "1", "n": 1}},
+		beat.Event{Fields: common.MapStr{"service": "a", "tenant": "2", "n": 2}},
+		beat.Event{Fields: common.MapStr{"service": "a", "tenant": "1", "n": 3}},
+	)
+	require.NoError(t, client.Publish(context.Background(), batch))
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+
+	require.Len(t, server.requests, 3)
+	expected := []struct {
+		path, tenant string
+		events       []string
+	}{
+		{"/a", "1", []string{`"n":0`, `"n":3`}},
+		{"/b", "1", []string{`"n":1`}},
+		{"/a", "2", []string{`"n":2`}},
+	}
+	for i, e := range expected {
+		r := server.requests[i]
+		assert.Equal(t, http.MethodPost, r.method)
+		assert.Equal(t, e.path, r.path)
+		assert.Equal(t, e.tenant, r.header.Get("X-Tenant"))
+		assert.Equal(t, "application/x-ndjson", r.header.Get("Content-Type"))
+
+		lines := strings.Split(strings.TrimSuffix(r.body, "\n"), "\n")
+		require.Len(t, lines, len(e.events))
+		for j, line := range lines {
+			assert.Contains(t, line, e.events[j])
+		}
+	}
+}
+
+func TestPublishBodyTemplateJSONArray(t *testing.T) {
+	server := newTestServer(t, nil)
+	client := newTestClient(t, map[string]interface{}{
+		"url":      server.URL,
+		"method":   "put",
+		"body":     `{"text":"%{[message]}"}`,
+		"format":   "json_array",
+		"username": "beat",
+		"password": "secret",
+	})
+
+	batch := outest.NewBatch(
+		beat.Event{Fields: common.MapStr{"message": "hello"}},
+		beat.Event{Fields: common.MapStr{"message": "world"}},
+	)
+	require.NoError(t, client.Publish(context.Background(), batch))
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+
+	require.Len(t, server.requests, 1)
+	r := server.requests[0]
+	assert.Equal(t, http.MethodPut, r.method)
+	assert.Equal(t, "application/json", r.header.Get("Content-Type"))
+	assert.Equal(t, `[{"text":"hello"},{"text":"world"}]`, r.body)
+
+	req := http.Request{Header: r.header}
+	username, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "beat", username)
+	assert.Equal(t, "secret", password)
+}
+
+func TestPublishResponseStatus(t *testing.T) {
+	server := newTestServer(t, func(r *http.Request) int {
+		switch r.URL.Path {
+		case "/busy":
+			return http.StatusServiceUnavailable
+		case "/invalid":
+			return http.StatusBadRequest
+		}
+		return http.StatusAccepted
+	})
+	client := newTestClient(t, map[string]interface{}{
+		"url": server.URL + "/%{[path]}",
+	})
+
+	batch := outest.NewBatch(
+		beat.Event{Fields: common.MapStr{"path": "ok"}},
+		beat.Event{Fields: common.MapStr{"path": "busy"}},
+		beat.Event{Fields: common.MapStr{"path": "invalid"}},
+		beat.Event{Fields: common.MapStr{"n": 3}},
+	)
+	err := client.Publish(context.Background(), batch)
+	assert.Error(t, err)
+
+	// the event without path can not be sent, the event rejected with 400
+	// is dropped and only the event rejected with 503 is retried
+	assert.Len(t, server.requests, 3)
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	require.Len(t, batch.Signals[0].Events, 1)
+	assert.Equal(t, "busy", batch.Signals[0].Events[0].Content.Fields["path"])
+}
+
+func TestPublishConnectionError(t *testing.T) {
+	server := newTestServer(t, nil)
+	client := newTestClient(t, map[string]interface{}{
+		"url": server.URL,
+	})
+	server.Close()
+
+	batch := outest.NewBatch(
+		beat.Event{Fields: common.MapStr{"n": 0}},
+		beat.Event{Fields: common.MapStr{"n": 1}},
+	)
+	err := client.Publish(context.Background(), batch)
+	assert.Error(t, err)
+
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	assert.Len(t, batch.Signals[0].Events, 2)
+}
@@ -0,0 +1,107 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package httpout
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/common/transport/oauth2"
+	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+)
+
+type httpConfig struct {
+	URL              *fmtstr.EventFormatString            `config:"url"               validate:"required"`
+	Method           string                               `config:"method"`
+	Headers          map[string]*fmtstr.EventFormatString `config:"headers"`
+	Body             *fmtstr.EventFormatString            `config:"body"`
+	Format           string                               `config:"format"`
+	CompressionLevel int                                  `config:"compression_level" validate:"min=0, max=9"`
+	RetryOnStatus    []int                                `config:"retry_on_status"`
+
+	Username string            `config:"username"`
+	Password string            `config:"password"`
+	OAuth2   *oauth2.Config    `config:"auth.oauth2"`
+	TLS      *tlscommon.Config `config:"ssl"`
+
+	ProxyURL     string `config:"proxy_url"`
+	ProxyDisable bool   `config:"proxy_disable"`
+
+	Worker      int                   `config:"worker"        validate:"min=1"`
+	BulkMaxSize int                   `config:"bulk_max_size"`
+	Timeout     time.Duration         `config:"timeout"       validate:"min=1"`
+	MaxRetries  int                   `config:"max_retries"   validate:"min=-1"`
+	Backoff     outputs.BackoffConfig `config:"backoff"`
+	Codec       codec.Config          `config:"codec"`
+}
+
+const (
+	formatNDJSON    = "ndjson"
+	formatJSONArray = "json_array"
+)
+
+func defaultConfig() httpConfig {
+	return httpConfig{
+		Method:        http.MethodPost,
+		Format:        formatNDJSON,
+		RetryOnStatus: []int{429, 500, 502, 503, 504},
+		Worker:        1,
+		BulkMaxSize:   50,
+		Timeout:       90 * time.Second,
+		MaxRetries:    3,
+		Backoff:       outputs.DefaultBackoffConfig(),
+	}
+}
+
+func (c *httpConfig) Validate() error {
+	switch strings.ToUpper(c.Method) {
+	case http.MethodPost, http.MethodPut:
+	default:
+		return fmt.Errorf("invalid method '%v', only POST and PUT are supported", c.Method)
+	}
+
+	switch c.Format {
+	case formatNDJSON, formatJSONArray:
+	default:
+		return fmt.Errorf("invalid format '%v', the format must be %v or %v", c.Format, formatNDJSON, formatJSONArray)
+	}
+
+	for _, status := range c.RetryOnStatus {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("invalid HTTP status code %v in retry_on_status", status)
+		}
+	}
+
+	if c.OAuth2.IsEnabled() && (c.Username != "" || c.Password != "") {
+		return errors.New("cannot set auth.oauth2 together with username/password")
+	}
+
+	if c.ProxyURL != "" && !c.ProxyDisable {
+		if _, err := common.ParseURL(c.ProxyURL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package httpout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cases := map[string]struct {
+		settings map[string]interface{}
+		valid    bool
+	}{
+		"url only": {
+			settings: map[string]interface{}{"url": "https://localhost/events"},
+			valid:    true,
+		},
+		"missing url": {
+			settings: map[string]interface{}{"method": "POST"},
+		},
+		"unsupported method": {
+			settings: map[string]interface{}{"url": "https://localhost", "method": "GET"},
+		},
+		"unknown format": {
+			settings: map[string]interface{}{"url": "https://localhost", "format": "xml"},
+		},
+		"invalid retry status": {
+			settings: map[string]interface{}{"url": "https://localhost", "retry_on_status": []int{429, 1000}},
+		},
+		"invalid compression level": {
+			settings: map[string]interface{}{"url": "https://localhost", "compression_level": 10},
+		},
+		"oauth2 and basic auth": {
+			settings: map[string]interface{}{
+				"url":      "https://localhost",
+				"username": "beat",
+				"auth.oauth2": map[string]interface{}{
+					"token_url":     "https://localhost/token",
+					"client.id":     "id",
+					"client.secret": "secret",
+				},
+			},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := defaultConfig()
+			err := common.MustNewConfigFrom(test.settings).Unpack(&config)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
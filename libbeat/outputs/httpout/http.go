@@ -0,0 +1,122 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package httpout implements the HTTP output, which sends the events to an
+// HTTP endpoint, e.g. a webhook or the ingest API of a third party service.
+package httpout
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/v7/libbeat/common/transport"
+	"github.com/elastic/beats/v7/libbeat/common/transport/oauth2"
+	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+)
+
+const logSelector = "http"
+
+func init() {
+	outputs.RegisterType("http", makeHTTP)
+}
+
+func makeHTTP(
+	_ outputs.IndexManager,
+	beat beat.Info,
+	observer outputs.Observer,
+	cfg *common.Config,
+) (outputs.Group, error) {
+	cfgwarn.Beta("The http output is beta.")
+
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return outputs.Fail(err)
+	}
+
+	tls, err := tlscommon.LoadTLSConfig(config.TLS)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	clients := make([]outputs.NetworkClient, config.Worker)
+	for i := range clients {
+		httpClient, err := newHTTPClient(&config, tls, observer)
+		if err != nil {
+			return outputs.Fail(err)
+		}
+
+		enc, err := codec.CreateEncoder(beat, config.Codec)
+		if err != nil {
+			return outputs.Fail(err)
+		}
+
+		client := newClient(observer, httpClient, beat.IndexPrefix, enc, &config)
+		clients[i] = outputs.WithBackoff(client, config.Backoff.Init, config.Backoff.Max)
+	}
+
+	grp, err := outputs.SuccessNet(true, config.BulkMaxSize, config.MaxRetries, clients)
+	grp.Backoff = &config.Backoff
+	return grp, err
+}
+
+// httpClient sends the requests of the output, it is implemented by
+// *http.Client and *oauth2.Client.
+type httpClient interface {
+	Do(req *http.Request) (*http.Response, error)
+	CloseIdleConnections()
+}
+
+func newHTTPClient(config *httpConfig, tls *tlscommon.TLSConfig, observer outputs.Observer) (httpClient, error) {
+	dialer := transport.NetDialer(config.Timeout)
+	tlsDialer, err := transport.TLSDialer(dialer, tls, config.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	dialer = transport.StatsDialer(dialer, observer)
+	tlsDialer = transport.StatsDialer(tlsDialer, observer)
+
+	var proxy func(*http.Request) (*url.URL, error)
+	if !config.ProxyDisable {
+		proxy = http.ProxyFromEnvironment
+		if config.ProxyURL != "" {
+			proxyURL, err := common.ParseURL(config.ProxyURL)
+			if err != nil {
+				return nil, err
+			}
+			proxy = http.ProxyURL(proxyURL)
+		}
+	}
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			Dial:            dialer.Dial,
+			DialTLS:         tlsDialer.Dial,
+			TLSClientConfig: tls.ToConfig(),
+			Proxy:           proxy,
+		},
+		Timeout: config.Timeout,
+	}
+	if config.OAuth2.IsEnabled() {
+		return oauth2.NewClient(config.OAuth2, client), nil
+	}
+	return client, nil
+}
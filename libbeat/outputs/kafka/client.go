@@ -24,6 +24,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/Shopify/sarama"
 
@@ -33,6 +34,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/outputs"
 	"github.com/elastic/beats/v7/libbeat/outputs/codec"
 	"github.com/elastic/beats/v7/libbeat/outputs/outil"
+	"github.com/elastic/beats/v7/libbeat/privacy"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/testing"
 )
@@ -43,12 +45,23 @@ type client struct {
 	hosts    []string
 	topic    outil.Selector
 	key      *fmtstr.EventFormatString
+	headers  []headerConfig
 	index    string
 	codec    codec.Codec
 	config   sarama.Config
 	mux      sync.Mutex
 
-	producer sarama.AsyncProducer
+	// publishMu keeps the producer from being replaced while Publish is
+	// sending to it.
+	publishMu sync.RWMutex
+	producer  sarama.AsyncProducer
+
+	// renewInterval is how often the producer is replaced, so that its
+	// connections authenticate again with new Kerberos tickets. Zero disables
+	// the renewal.
+	renewInterval time.Duration
+	renewDone     chan struct{}
+	renewWG       sync.WaitGroup
 
 	wg sync.WaitGroup
 }
@@ -72,9 +85,11 @@ func newKafkaClient(
 	hosts []string,
 	index string,
 	key *fmtstr.EventFormatString,
+	headers []headerConfig,
 	topic outil.Selector,
 	writer codec.Codec,
 	cfg *sarama.Config,
+	renewInterval time.Duration,
 ) (*client, error) {
 	c := &client{
 		log:      logp.NewLogger(logSelector),
@@ -82,9 +97,12 @@ func newKafkaClient(
 		hosts:    hosts,
 		topic:    topic,
 		key:      key,
+		headers:  headers,
 		index:    strings.ToLower(index),
 		codec:    writer,
 		config:   *cfg,
+
+		renewInterval: renewInterval,
 	}
 	return c, nil
 }
@@ -108,10 +126,18 @@ func (c *client) Connect() error {
 	go c.successWorker(producer.Successes())
 	go c.errorWorker(producer.Errors())
 
+	if c.renewInterval > 0 {
+		c.renewDone = make(chan struct{})
+		c.renewWG.Add(1)
+		go c.renewWorker(c.renewDone)
+	}
+
 	return nil
 }
 
 func (c *client) Close() error {
+	c.stopRenew()
+
 	c.mux.Lock()
 	defer c.mux.Unlock()
 	c.log.Debug("closed kafka client")
@@ -139,12 +165,16 @@ func (c *client) Publish(_ context.Context, batch publisher.Batch) error {
 		batch:  batch,
 	}
 
+	c.publishMu.RLock()
+	defer c.publishMu.RUnlock()
+
 	ch := c.producer.Input()
 	for i := range events {
 		d := &events[i]
 		msg, err := c.getEventMessage(d)
 		if err != nil {
 			c.log.Errorf("Dropping event: %+v", err)
+			publisher.DeadLetter(batch, *d, fmt.Sprintf("failed to create the kafka message: %v", err))
 			ref.done()
 			c.observer.Dropped(1)
 			continue
@@ -158,6 +188,65 @@ func (c *client) Publish(_ context.Context, batch publisher.Batch) error {
 	return nil
 }
 
+// renewWorker replaces the producer every renewInterval until done is closed.
+func (c *client) renewWorker(done <-chan struct{}) {
+	defer c.renewWG.Done()
+
+	ticker := time.NewTicker(c.renewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			c.renewProducer()
+		}
+	}
+}
+
+// renewProducer connects a new producer and swaps it in place of the current
+// one. The old producer is closed after the swap, flushing the messages it
+// still holds. If the new producer can't connect, the current one is kept.
+func (c *client) renewProducer() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	if c.producer == nil {
+		return
+	}
+
+	c.log.Debugf("renew kafka producer: %v", c.hosts)
+
+	producer, err := sarama.NewAsyncProducer(c.hosts, &c.config)
+	if err != nil {
+		c.log.Errorf("Kafka producer renewal fails with: %+v", err)
+		return
+	}
+
+	c.wg.Add(2)
+	go c.successWorker(producer.Successes())
+	go c.errorWorker(producer.Errors())
+
+	c.publishMu.Lock()
+	old := c.producer
+	c.producer = producer
+	c.publishMu.Unlock()
+
+	old.AsyncClose()
+}
+
+func (c *client) stopRenew() {
+	c.mux.Lock()
+	done := c.renewDone
+	c.renewDone = nil
+	c.mux.Unlock()
+
+	if done != nil {
+		close(done)
+		c.renewWG.Wait()
+	}
+}
+
 func (c *client) String() string {
 	return "kafka(" + strings.Join(c.hosts, ",") + ")"
 }
@@ -203,7 +292,7 @@ func (c *client) getEventMessage(data *publisher.Event) (*message, error) {
 	serializedEvent, err := c.codec.Encode(c.index, event)
 	if err != nil {
 		if c.log.IsDebug() {
-			c.log.Debugf("failed event: %v", event)
+			c.log.Debugf("failed event: %v", privacy.LazyEvent(event))
 		}
 		return nil, err
 	}
@@ -223,6 +312,20 @@ func (c *client) getEventMessage(data *publisher.Event) (*message, error) {
 		}
 	}
 
+	for _, h := range c.headers {
+		value, err := h.Value.RunBytes(event)
+		if err != nil {
+			if c.log.IsDebug() {
+				c.log.Debugf("skipping header %v: %v", h.Key, err)
+			}
+			continue
+		}
+		msg.headers = append(msg.headers, sarama.RecordHeader{
+			Key:   []byte(h.Key),
+			Value: value,
+		})
+	}
+
 	return msg, nil
 }
 
@@ -255,12 +358,14 @@ func (r *msgRef) fail(msg *message, err error) {
 	case sarama.ErrInvalidMessage:
 		r.client.log.Errorf("Kafka (topic=%v): dropping invalid message", msg.topic)
 		r.client.observer.Dropped(1)
+		publisher.DeadLetter(r.batch, msg.data, fmt.Sprintf("kafka rejected the message (topic=%v): %v", msg.topic, err))
 
 	case sarama.ErrMessageSizeTooLarge, sarama.ErrInvalidMessageSize:
 		r.client.log.Errorf("Kafka (topic=%v): dropping too large message of size %v.",
 			msg.topic,
 			len(msg.key)+len(msg.value))
 		r.client.observer.Dropped(1)
+		publisher.DeadLetter(r.batch, msg.data, fmt.Sprintf("kafka rejected the message (topic=%v): %v", msg.topic, err))
 
 	default:
 		r.failed = append(r.failed, msg.data)
@@ -0,0 +1,138 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+func TestEventMessageHeaders(t *testing.T) {
+	cfg, err := readConfig(common.MustNewConfigFrom(common.MapStr{
+		"hosts": []string{"localhost"},
+		"topic": "test",
+		"headers": []common.MapStr{
+			{"key": "agent.id", "value": "%{[agent.id]}"},
+			{"key": "env", "value": "production"},
+		},
+	}))
+	require.NoError(t, err)
+	libCfg, err := newSaramaConfig(logp.L(), cfg)
+	require.NoError(t, err)
+	topic, err := buildTopicSelector(common.MustNewConfigFrom(common.MapStr{"topic": "test"}))
+	require.NoError(t, err)
+
+	c, err := newKafkaClient(outputs.NewNilObserver(), cfg.Hosts, "testbeat", cfg.Key, cfg.Headers, topic, json.New("1.2.3", json.Config{}), libCfg, 0)
+	require.NoError(t, err)
+
+	cases := map[string]struct {
+		fields common.MapStr
+		want   []sarama.RecordHeader
+	}{
+		"field and static value": {
+			fields: common.MapStr{"agent": common.MapStr{"id": "abc"}},
+			want: []sarama.RecordHeader{
+				{Key: []byte("agent.id"), Value: []byte("abc")},
+				{Key: []byte("env"), Value: []byte("production")},
+			},
+		},
+		"missing field is skipped": {
+			fields: common.MapStr{"message": "hello"},
+			want: []sarama.RecordHeader{
+				{Key: []byte("env"), Value: []byte("production")},
+			},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			event := &publisher.Event{Content: beat.Event{Fields: test.fields}}
+			msg, err := c.getEventMessage(event)
+			require.NoError(t, err)
+
+			msg.initProducerMessage()
+			assert.Equal(t, test.want, msg.msg.Headers)
+		})
+	}
+}
+
+func TestRenewProducer(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("test", 0, broker.BrokerID()),
+		"ProduceRequest": sarama.NewMockProduceResponse(t),
+	})
+
+	cfg, err := readConfig(common.MustNewConfigFrom(common.MapStr{
+		"hosts": []string{broker.Addr()},
+		"topic": "test",
+		// The mock broker answers produce requests with version 0.
+		"version": "0.8.2.0",
+	}))
+	require.NoError(t, err)
+	libCfg, err := newSaramaConfig(logp.L(), cfg)
+	require.NoError(t, err)
+	topic, err := buildTopicSelector(common.MustNewConfigFrom(common.MapStr{"topic": "test"}))
+	require.NoError(t, err)
+
+	c, err := newKafkaClient(outputs.NewNilObserver(), cfg.Hosts, "testbeat", cfg.Key, cfg.Headers, topic, json.New("1.2.3", json.Config{}), libCfg, 10*time.Millisecond)
+	require.NoError(t, err)
+	require.NoError(t, c.Connect())
+	defer c.Close()
+
+	currentProducer := func() sarama.AsyncProducer {
+		c.publishMu.RLock()
+		defer c.publishMu.RUnlock()
+		return c.producer
+	}
+	first := currentProducer()
+	require.Eventually(t, func() bool {
+		return currentProducer() != first
+	}, 5*time.Second, 10*time.Millisecond, "the producer is not renewed")
+
+	signals := make(chan outest.BatchSignal, 1)
+	batch := outest.NewBatch(beat.Event{
+		Timestamp: time.Now(),
+		Fields:    common.MapStr{"message": "hello"},
+	})
+	batch.OnSignal = func(sig outest.BatchSignal) { signals <- sig }
+	require.NoError(t, c.Publish(context.Background(), batch))
+
+	select {
+	case sig := <-signals:
+		assert.Equal(t, outest.BatchACK, sig.Tag)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the batch is not acknowledged after renewing the producer")
+	}
+}
@@ -22,15 +22,18 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"net"
 	"strings"
 	"time"
 
 	"github.com/Shopify/sarama"
+	krbconfig "gopkg.in/jcmturner/gokrb5.v7/config"
 
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/cfgwarn"
 	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
 	"github.com/elastic/beats/v7/libbeat/common/kafka"
+	"github.com/elastic/beats/v7/libbeat/common/transport"
 	"github.com/elastic/beats/v7/libbeat/common/transport/kerberos"
 	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
 	"github.com/elastic/beats/v7/libbeat/logp"
@@ -69,6 +72,16 @@ type kafkaConfig struct {
 	Password           string                    `config:"password"`
 	Codec              codec.Config              `config:"codec"`
 	Sasl               saslConfig                `config:"sasl"`
+	Proxy              transport.ProxyConfig     `config:",inline"`
+	Idempotent         bool                      `config:"idempotent"`
+	Headers            []headerConfig            `config:"headers"`
+}
+
+// headerConfig configures a record header set in every message. The value is
+// evaluated per event and can reference event fields.
+type headerConfig struct {
+	Key   string                    `config:"key"   validate:"required"`
+	Value *fmtstr.EventFormatString `config:"value" validate:"required"`
 }
 
 type saslConfig struct {
@@ -198,6 +211,35 @@ func (c *kafkaConfig) Validate() error {
 			return fmt.Errorf("compression_level must be between 0 and 9")
 		}
 	}
+
+	if c.Idempotent {
+		// Kafka de-duplicates the messages of idempotent producers since
+		// 0.11, and only when they are written to all in-sync replicas.
+		if version, ok := c.Version.Get(); ok && !version.IsAtLeast(sarama.V0_11_0_0) {
+			return fmt.Errorf("idempotent requires Kafka version 0.11.0 or later, not %v", c.Version)
+		}
+		if c.RequiredACKs != nil && sarama.RequiredAcks(*c.RequiredACKs) != sarama.WaitForAll {
+			return errors.New("idempotent requires required_acks to be -1")
+		}
+	}
+
+	if len(c.Headers) > 0 {
+		// Record headers have been added to kafka with version 0.11.0.0
+		if version, ok := c.Version.Get(); ok && !version.IsAtLeast(sarama.V0_11_0_0) {
+			return fmt.Errorf("headers require Kafka version 0.11.0 or later, not %v", c.Version)
+		}
+	}
+
+	if c.Kerberos.IsEnabled() {
+		// The Kafka client reads the Kerberos configuration on its own, and
+		// always authenticates with a keytab or a password.
+		if c.Kerberos.IsCCache() {
+			return errors.New("Kerberos ccache authentication is not supported by the Kafka output")
+		}
+		if len(c.Kerberos.DomainRealm) > 0 {
+			return errors.New("Kerberos domain_realm is not supported by the Kafka output, configure the mappings in the Kerberos configuration file")
+		}
+	}
 	return nil
 }
 
@@ -216,6 +258,18 @@ func newSaramaConfig(log *logp.Logger, config *kafkaConfig) (*sarama.Config, err
 	k.Net.WriteTimeout = timeout
 	k.Net.KeepAlive = config.KeepAlive
 	k.Producer.Timeout = config.BrokerTimeout
+
+	if config.Proxy.URL != "" {
+		dialer, err := transport.ProxyDialer(log, &config.Proxy, &net.Dialer{
+			Timeout:   timeout,
+			KeepAlive: config.KeepAlive,
+		})
+		if err != nil {
+			return nil, err
+		}
+		k.Net.Proxy.Enable = true
+		k.Net.Proxy.Dialer = dialer
+	}
 	k.Producer.CompressionLevel = config.CompressionLevel
 
 	tls, err := tlscommon.LoadTLSConfig(config.TLS)
@@ -286,6 +340,15 @@ func newSaramaConfig(log *logp.Logger, config *kafkaConfig) (*sarama.Config, err
 	k.Producer.Retry.Max = retryMax
 	k.Producer.Retry.BackoffFunc = makeBackoffFunc(config.Backoff)
 
+	if config.Idempotent {
+		// The brokers discard the messages retried by the producer that
+		// they already wrote, this requires a single request in flight per
+		// broker to keep the messages in order.
+		k.Producer.Idempotent = true
+		k.Producer.RequiredAcks = sarama.WaitForAll
+		k.Net.MaxOpenRequests = 1
+	}
+
 	// configure per broker go channel buffering
 	k.ChannelBufferSize = config.ChanBufferSize
 
@@ -320,6 +383,17 @@ func newSaramaConfig(log *logp.Logger, config *kafkaConfig) (*sarama.Config, err
 	return k, nil
 }
 
+// kerberosRenewInterval returns how often the Kafka producer is replaced to
+// authenticate its connections again, half the lifetime of the tickets set in
+// the Kerberos configuration.
+func kerberosRenewInterval(config *kerberos.Config) (time.Duration, error) {
+	krbConf, err := krbconfig.Load(config.ConfigPath)
+	if err != nil {
+		return 0, fmt.Errorf("error reading Kerberos configuration: %+v", err)
+	}
+	return krbConf.LibDefaults.TicketLifetime / 2, nil
+}
+
 // makeBackoffFunc returns a stateless implementation of exponential-backoff-with-jitter. It is conceptually
 // equivalent to the stateful implementation used by other outputs, EqualJitterBackoff.
 func makeBackoffFunc(cfg backoffConfig) func(retries, maxRetries int) time.Duration {
@@ -86,7 +86,15 @@ func makeKafka(
 		return outputs.Fail(err)
 	}
 
-	client, err := newKafkaClient(observer, hosts, beat.IndexPrefix, config.Key, topic, codec, libCfg)
+	var renewInterval time.Duration
+	if config.Kerberos.IsEnabled() {
+		renewInterval, err = kerberosRenewInterval(config.Kerberos)
+		if err != nil {
+			return outputs.Fail(err)
+		}
+	}
+
+	client, err := newKafkaClient(observer, hosts, beat.IndexPrefix, config.Key, config.Headers, topic, codec, libCfg, renewInterval)
 	if err != nil {
 		return outputs.Fail(err)
 	}
@@ -77,7 +77,7 @@ func newAsyncClient(
 		log.Warn(`The async Logstash client does not support the "ttl" option`)
 	}
 
-	enc := makeLogstashEventEncoder(log, beat, config.EscapeHTML, config.Index)
+	enc := makeLogstashEventEncoder(log, beat, config)
 
 	queueSize := config.Pipelining - 1
 	timeout := config.Timeout
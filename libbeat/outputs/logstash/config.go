@@ -27,6 +27,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/common/cfgwarn"
 	"github.com/elastic/beats/v7/libbeat/common/transport"
 	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+	"github.com/elastic/beats/v7/libbeat/outputs"
 )
 
 type Config struct {
@@ -41,13 +42,12 @@ type Config struct {
 	MaxRetries       int                   `config:"max_retries"       validate:"min=-1"`
 	TLS              *tlscommon.Config     `config:"ssl"`
 	Proxy            transport.ProxyConfig `config:",inline"`
-	Backoff          Backoff               `config:"backoff"`
+	Backoff          outputs.BackoffConfig `config:"backoff"`
 	EscapeHTML       bool                  `config:"escape_html"`
-}
 
-type Backoff struct {
-	Init time.Duration
-	Max  time.Duration
+	// TimestampPrecision is the number of fractional digits of the timestamps
+	// of the events.
+	TimestampPrecision common.TimestampPrecision `config:"timestamp_precision"`
 }
 
 func defaultConfig() Config {
@@ -60,11 +60,8 @@ func defaultConfig() Config {
 		Timeout:          30 * time.Second,
 		MaxRetries:       3,
 		TTL:              0 * time.Second,
-		Backoff: Backoff{
-			Init: 1 * time.Second,
-			Max:  60 * time.Second,
-		},
-		EscapeHTML: false,
+		Backoff:          outputs.DefaultBackoffConfig(),
+		EscapeHTML:       false,
 	}
 }
 
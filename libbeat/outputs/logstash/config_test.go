@@ -23,6 +23,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -46,12 +47,9 @@ func TestConfig(t *testing.T) {
 				Timeout:          30 * time.Second,
 				MaxRetries:       3,
 				TTL:              0 * time.Second,
-				Backoff: Backoff{
-					Init: 1 * time.Second,
-					Max:  60 * time.Second,
-				},
-				EscapeHTML: false,
-				Index:      "bar",
+				Backoff:          outputs.DefaultBackoffConfig(),
+				EscapeHTML:       false,
+				Index:            "bar",
 			},
 		},
 		"config given": {
@@ -70,12 +68,9 @@ func TestConfig(t *testing.T) {
 				Timeout:          30 * time.Second,
 				MaxRetries:       3,
 				TTL:              0 * time.Second,
-				Backoff: Backoff{
-					Init: 1 * time.Second,
-					Max:  60 * time.Second,
-				},
-				EscapeHTML: false,
-				Index:      "beat-index",
+				Backoff:          outputs.DefaultBackoffConfig(),
+				EscapeHTML:       false,
+				Index:            "beat-index",
 			},
 		},
 		"removed config setting": {
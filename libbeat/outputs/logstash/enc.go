@@ -23,18 +23,21 @@ import (
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/privacy"
 )
 
-func makeLogstashEventEncoder(log *logp.Logger, info beat.Info, escapeHTML bool, index string) func(interface{}) ([]byte, error) {
+func makeLogstashEventEncoder(log *logp.Logger, info beat.Info, config *Config) func(interface{}) ([]byte, error) {
 	enc := json.New(info.Version, json.Config{
-		Pretty:     false,
-		EscapeHTML: escapeHTML,
+		Pretty:             false,
+		EscapeHTML:         config.EscapeHTML,
+		TimestampPrecision: config.TimestampPrecision,
 	})
+	index := config.Index
 	index = strings.ToLower(index)
 	return func(event interface{}) (d []byte, err error) {
 		d, err = enc.Encode(index, event.(*beat.Event))
 		if err != nil {
-			log.Debugf("Failed to encode event: %v", event)
+			log.Debugf("Failed to encode event: %v", privacy.LazyEvent(event.(*beat.Event)))
 		}
 		return
 	}
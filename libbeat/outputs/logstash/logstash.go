@@ -85,5 +85,7 @@ func makeLogstash(
 		clients[i] = client
 	}
 
-	return outputs.SuccessNet(config.LoadBalance, config.BulkMaxSize, config.MaxRetries, clients)
+	grp, err := outputs.SuccessNet(config.LoadBalance, config.BulkMaxSize, config.MaxRetries, clients)
+	grp.Backoff = &config.Backoff
+	return grp, err
 }
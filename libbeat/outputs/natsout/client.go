@@ -0,0 +1,272 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package natsout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/outputs/outil"
+	"github.com/elastic/beats/v7/libbeat/privacy"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// client publishes the events of a batch to JetStream and waits up to ackWait
+// for the acknowledgements of the streams. The events that are not
+// acknowledged in time, or that a stream refused, are retried.
+type client struct {
+	log      *logp.Logger
+	observer outputs.Observer
+	host     string
+	index    string
+	codec    codec.Codec
+	subject  outil.Selector
+	ackWait  time.Duration
+	opts     []nats.Option
+
+	conn *nats.Conn
+
+	// The acknowledgements are replies to inbox.<seq>, where seq is the
+	// sequence number of the event on this connection.
+	inbox string
+	acks  *nats.Subscription
+	seq   uint64
+}
+
+// pubAck is the reply of JetStream to a published message.
+type pubAck struct {
+	Stream string `json:"stream"`
+	Seq    uint64 `json:"seq"`
+	Error  *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error"`
+}
+
+var errNotConnected = errors.New("nats client is not connected")
+
+func newClient(
+	observer outputs.Observer,
+	host string,
+	index string,
+	writer codec.Codec,
+	subject outil.Selector,
+	config *natsConfig,
+	opts ...nats.Option,
+) *client {
+	return &client{
+		log:      logp.NewLogger(logSelector),
+		observer: observer,
+		host:     host,
+		index:    strings.ToLower(index),
+		codec:    writer,
+		subject:  subject,
+		ackWait:  config.AckWait,
+		opts:     opts,
+	}
+}
+
+func (c *client) Connect() error {
+	conn, err := nats.Connect(c.host, c.opts...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %v: %v", c.host, err)
+	}
+
+	inbox := nats.NewInbox()
+	acks, err := conn.SubscribeSync(inbox + ".*")
+	if err == nil {
+		// Make sure the server knows the subscription before any event is
+		// published.
+		err = conn.FlushTimeout(c.ackWait)
+	}
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to subscribe to the acknowledgements from %v: %v", c.host, err)
+	}
+
+	c.conn = conn
+	c.inbox = inbox
+	c.acks = acks
+	c.seq = 0
+	return nil
+}
+
+func (c *client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	c.conn.Close()
+	c.conn = nil
+	c.acks = nil
+	return nil
+}
+
+func (c *client) String() string {
+	return "nats(" + c.host + ")"
+}
+
+func (c *client) Publish(ctx context.Context, batch publisher.Batch) error {
+	if c.conn == nil {
+		return errNotConnected
+	}
+
+	events := batch.Events()
+	c.observer.NewBatch(len(events))
+
+	first := c.seq + 1
+	sent := make([]publisher.Event, 0, len(events))
+	dropped := 0
+	for i := range events {
+		event := &events[i]
+		subject, err := c.subject.Select(&event.Content)
+		if err == nil && !validSubject(subject) {
+			err = fmt.Errorf("invalid subject '%v'", subject)
+		}
+		if err != nil {
+			c.dropEvent(batch, event, fmt.Sprintf("failed to select the subject: %v", err))
+			dropped++
+			continue
+		}
+		serializedEvent, err := c.codec.Encode(c.index, &event.Content)
+		if err != nil {
+			c.dropEvent(batch, event, fmt.Sprintf("failed to encode the event: %v", err))
+			dropped++
+			continue
+		}
+
+		c.seq++
+		reply := c.inbox + "." + strconv.FormatUint(c.seq, 10)
+		if err := c.conn.PublishRequest(subject, reply, serializedEvent); err != nil {
+			c.observer.WriteError(err)
+			c.observer.Dropped(dropped)
+			retry := append(sent, events[i:]...)
+			c.observer.Failed(len(retry))
+			batch.RetryEvents(retry)
+			return fmt.Errorf("failed to publish to %v: %v", c.host, err)
+		}
+		c.observer.WriteBytes(len(serializedEvent))
+		sent = append(sent, *event)
+	}
+	c.observer.Dropped(dropped)
+
+	if len(sent) == 0 {
+		batch.ACK()
+		return nil
+	}
+
+	retry, err := c.waitAcks(ctx, first, sent)
+	c.observer.Acked(len(sent) - len(retry))
+	if len(retry) == 0 {
+		batch.ACK()
+		return nil
+	}
+
+	c.observer.Failed(len(retry))
+	batch.RetryEvents(retry)
+	if err == nil {
+		err = fmt.Errorf("%v of %v events were not acknowledged by %v", len(retry), len(sent), c.host)
+	}
+	return err
+}
+
+// validSubject checks that the subject can be published to: it is made of
+// non empty tokens separated by dots, without whitespace or wildcards.
+func validSubject(subject string) bool {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n*>") {
+		return false
+	}
+	for _, token := range strings.Split(subject, ".") {
+		if token == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *client) dropEvent(batch publisher.Batch, event *publisher.Event, reason string) {
+	c.log.Errorf("Dropping event: %+v", reason)
+	if c.log.IsDebug() {
+		c.log.Debugf("Failed event: %v", privacy.LazyEvent(&event.Content))
+	}
+	publisher.DeadLetter(batch, *event, reason)
+}
+
+// waitAcks waits up to ackWait for the acknowledgements of the sent events,
+// the first having the sequence number first. It returns the events that
+// were not acknowledged.
+func (c *client) waitAcks(ctx context.Context, first uint64, sent []publisher.Event) ([]publisher.Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.ackWait)
+	defer cancel()
+
+	replied := make([]bool, len(sent))
+	acked := make([]bool, len(sent))
+	pending := len(sent)
+	var lastErr error
+	for pending > 0 {
+		msg, err := c.acks.NextMsgWithContext(ctx)
+		if err != nil {
+			if err == context.DeadlineExceeded {
+				c.log.Errorf("%v events were not acknowledged by %v within %v", pending, c.host, c.ackWait)
+			} else {
+				lastErr = err
+			}
+			break
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimPrefix(msg.Subject, c.inbox+"."), 10, 64)
+		if err != nil || seq < first || seq >= first+uint64(len(sent)) {
+			// Late acknowledgement of an event that was already retried.
+			continue
+		}
+		i := seq - first
+		if replied[i] {
+			continue
+		}
+		replied[i] = true
+
+		var ack pubAck
+		if err := json.Unmarshal(msg.Data, &ack); err != nil {
+			c.log.Errorf("Invalid acknowledgement from %v: %v", c.host, err)
+			lastErr = err
+		} else if ack.Error != nil {
+			c.log.Errorf("Event was refused by %v: %v (code %v)", c.host, ack.Error.Description, ack.Error.Code)
+			lastErr = fmt.Errorf("event was refused by %v: %v", c.host, ack.Error.Description)
+		} else {
+			acked[i] = true
+		}
+		pending--
+	}
+
+	var retry []publisher.Event
+	for i, ok := range acked {
+		if !ok {
+			retry = append(retry, sent[i])
+		}
+	}
+	return retry, lastErr
+}
@@ -0,0 +1,267 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package natsout
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	jsoncodec "github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+	"github.com/elastic/beats/v7/libbeat/outputs/outil"
+)
+
+// testServer implements enough of the NATS protocol to receive the published
+// messages and reply to them like JetStream, with the reply returned by ack.
+// No reply is sent if ack returns nil.
+type testServer struct {
+	ack func(subject string, payload []byte) []byte
+
+	mu       sync.Mutex
+	subjects []string
+}
+
+func startServer(t *testing.T, ack func(subject string, payload []byte) []byte) (*testServer, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	server := &testServer{ack: ack}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server, l.Addr().String()
+}
+
+func (s *testServer) serve(conn net.Conn) {
+	defer conn.Close()
+	fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"version\":\"2.2.0\",\"proto\":1,\"max_payload\":1048576}\r\n")
+
+	var mu sync.Mutex
+	write := func(format string, args ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(conn, format, args...)
+	}
+
+	// sids of the subscriptions by subject prefix, the client only
+	// subscribes to <inbox>.*.
+	subs := map[string]string{}
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "PING":
+			write("PONG\r\n")
+		case "SUB":
+			subs[strings.TrimSuffix(fields[1], "*")] = fields[len(fields)-1]
+		case "PUB":
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			subject, reply := fields[1], fields[2]
+
+			s.mu.Lock()
+			s.subjects = append(s.subjects, subject)
+			s.mu.Unlock()
+
+			ack := s.ack(subject, payload[:size])
+			if ack == nil {
+				continue
+			}
+			for prefix, sid := range subs {
+				if strings.HasPrefix(reply, prefix) {
+					write("MSG %v %v %v\r\n%s\r\n", reply, sid, len(ack), ack)
+				}
+			}
+		}
+	}
+}
+
+func (s *testServer) published() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.subjects...)
+}
+
+func okAck(subject string, payload []byte) []byte {
+	return []byte(`{"stream":"EVENTS","seq":1}`)
+}
+
+func connectTestClient(t *testing.T, host string, settings map[string]interface{}) *client {
+	cfg := common.MustNewConfigFrom(settings)
+	config := defaultConfig()
+	require.NoError(t, cfg.Unpack(&config))
+	subject, err := buildSubjectSelector(cfg)
+	require.NoError(t, err)
+
+	opts, err := connectOptions(beat.Info{Beat: "testbeat"}, &config)
+	require.NoError(t, err)
+	c := newClient(outputs.NewNilObserver(), "nats://"+host, "testbeat",
+		jsoncodec.New("1.2.3", jsoncodec.Config{}), subject, &config, opts...)
+	require.NoError(t, c.Connect())
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func testBatch(services ...string) *outest.Batch {
+	events := make([]beat.Event, len(services))
+	for i, service := range services {
+		events[i] = beat.Event{
+			Timestamp: time.Now(),
+			Fields:    common.MapStr{"n": i, "service": service},
+		}
+		if service == "" {
+			delete(events[i].Fields, "service")
+		}
+	}
+	return outest.NewBatch(events...)
+}
+
+func TestPublishACK(t *testing.T) {
+	server, host := startServer(t, okAck)
+	c := connectTestClient(t, host, map[string]interface{}{
+		"subject": "events.%{[service]}",
+	})
+
+	batch := testBatch("a", "b")
+	require.NoError(t, c.Publish(context.Background(), batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+	assert.Equal(t, []string{"events.a", "events.b"}, server.published())
+
+	// The sequence numbers continue with the next batch.
+	batch = testBatch("c")
+	require.NoError(t, c.Publish(context.Background(), batch))
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+}
+
+func TestPublishDropsEventsWithoutSubject(t *testing.T) {
+	server, host := startServer(t, okAck)
+	c := connectTestClient(t, host, map[string]interface{}{
+		"subject": "events.%{[service]}",
+	})
+
+	batch := testBatch("a", "")
+	require.NoError(t, c.Publish(context.Background(), batch))
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchACK, batch.Signals[0].Tag)
+	assert.Equal(t, []string{"events.a"}, server.published())
+}
+
+func TestPublishRetriesRefusedEvents(t *testing.T) {
+	_, host := startServer(t, func(subject string, payload []byte) []byte {
+		var event struct{ N int }
+		json.Unmarshal(payload, &event)
+		if event.N == 1 {
+			return []byte(`{"error":{"code":503,"description":"maximum messages exceeded"}}`)
+		}
+		return okAck(subject, payload)
+	})
+	c := connectTestClient(t, host, map[string]interface{}{"subject": "events"})
+
+	batch := testBatch("a", "b", "c")
+	err := c.Publish(context.Background(), batch)
+	assert.Error(t, err)
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	require.Len(t, batch.Signals[0].Events, 1)
+	assert.Equal(t, "b", batch.Signals[0].Events[0].Content.Fields["service"])
+}
+
+func TestPublishRetriesOnAckTimeout(t *testing.T) {
+	_, host := startServer(t, func(subject string, payload []byte) []byte {
+		var event struct{ N int }
+		json.Unmarshal(payload, &event)
+		if event.N == 0 {
+			return nil
+		}
+		return okAck(subject, payload)
+	})
+	c := connectTestClient(t, host, map[string]interface{}{
+		"subject":  "events",
+		"ack_wait": "200ms",
+	})
+
+	batch := testBatch("a", "b")
+	err := c.Publish(context.Background(), batch)
+	assert.Error(t, err)
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	require.Len(t, batch.Signals[0].Events, 1)
+	assert.Equal(t, "a", batch.Signals[0].Events[0].Content.Fields["service"])
+}
+
+func TestPublishNotConnected(t *testing.T) {
+	c := newClient(outputs.NewNilObserver(), "nats://localhost:4222", "testbeat",
+		jsoncodec.New("1.2.3", jsoncodec.Config{}), buildTestSelector(t), &natsConfig{})
+	assert.Equal(t, errNotConnected, c.Publish(context.Background(), testBatch("a")))
+}
+
+func buildTestSelector(t *testing.T) outil.Selector {
+	selector, err := buildSubjectSelector(common.MustNewConfigFrom(map[string]interface{}{"subject": "events"}))
+	require.NoError(t, err)
+	return selector
+}
+
+func TestValidSubject(t *testing.T) {
+	for subject, valid := range map[string]bool{
+		"events":          true,
+		"events.service":  true,
+		"":                false,
+		"events.":         false,
+		".events":         false,
+		"events..service": false,
+		"events.*":        false,
+		"events.>":        false,
+		"events service":  false,
+	} {
+		assert.Equal(t, valid, validSubject(subject), subject)
+	}
+}
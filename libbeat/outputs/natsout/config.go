@@ -0,0 +1,72 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package natsout
+
+import (
+	"errors"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common/transport"
+	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+)
+
+type natsConfig struct {
+	LoadBalance  bool                  `config:"loadbalance"`
+	BulkMaxSize  int                   `config:"bulk_max_size"`
+	Timeout      time.Duration         `config:"timeout"        validate:"positive,nonzero"`
+	AckWait      time.Duration         `config:"ack_wait"       validate:"positive,nonzero"`
+	MaxRetries   int                   `config:"max_retries"    validate:"min=-1"`
+	Username     string                `config:"username"`
+	Password     string                `config:"password"`
+	Token        string                `config:"token"`
+	NKeySeedFile string                `config:"nkey_seed_file"`
+	TLS          *tlscommon.Config     `config:"ssl"`
+	Proxy        transport.ProxyConfig `config:",inline"`
+	Backoff      outputs.BackoffConfig `config:"backoff"`
+	Codec        codec.Config          `config:"codec"`
+}
+
+func defaultConfig() natsConfig {
+	return natsConfig{
+		LoadBalance: false,
+		BulkMaxSize: 2048,
+		Timeout:     30 * time.Second,
+		AckWait:     30 * time.Second,
+		MaxRetries:  3,
+		Backoff:     outputs.DefaultBackoffConfig(),
+	}
+}
+
+func (c *natsConfig) Validate() error {
+	methods := 0
+	if c.Username != "" || c.Password != "" {
+		methods++
+	}
+	if c.Token != "" {
+		methods++
+	}
+	if c.NKeySeedFile != "" {
+		methods++
+	}
+	if methods > 1 {
+		return errors.New("only one of username and password, token or nkey_seed_file can be set")
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package natsout
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestConfig(t *testing.T) {
+	tests := map[string]struct {
+		settings map[string]interface{}
+		valid    bool
+	}{
+		"defaults": {
+			settings: map[string]interface{}{},
+			valid:    true,
+		},
+		"username and password": {
+			settings: map[string]interface{}{"username": "beat", "password": "secret"},
+			valid:    true,
+		},
+		"token": {
+			settings: map[string]interface{}{"token": "secret"},
+			valid:    true,
+		},
+		"token and nkey": {
+			settings: map[string]interface{}{"token": "secret", "nkey_seed_file": "user.nk"},
+		},
+		"username and token": {
+			settings: map[string]interface{}{"username": "beat", "token": "secret"},
+		},
+		"no ack_wait": {
+			settings: map[string]interface{}{"ack_wait": 0},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := defaultConfig()
+			err := common.MustNewConfigFrom(test.settings).Unpack(&config)
+			assert.Equal(t, test.valid, err == nil, "%v", err)
+		})
+	}
+}
+
+func TestConnectOptionsMissingSeedFile(t *testing.T) {
+	config := defaultConfig()
+	config.NKeySeedFile = "does-not-exist.nk"
+	_, err := connectOptions(beat.Info{Beat: "testbeat"}, &config)
+	assert.Error(t, err)
+}
+
+func TestMakeNATSRequiresSubject(t *testing.T) {
+	cfg := common.MustNewConfigFrom(map[string]interface{}{"hosts": []string{"localhost:4222"}})
+	_, err := makeNATS(nil, beat.Info{Beat: "testbeat"}, nil, cfg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,162 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package natsout implements the NATS output, which publishes the events to
+// the subjects of NATS JetStream streams.
+package natsout
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/v7/libbeat/common/transport"
+	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/outputs/outil"
+)
+
+const logSelector = "nats"
+
+func init() {
+	outputs.RegisterType("nats", makeNATS)
+}
+
+func makeNATS(
+	_ outputs.IndexManager,
+	beat beat.Info,
+	observer outputs.Observer,
+	cfg *common.Config,
+) (outputs.Group, error) {
+	cfgwarn.Experimental("The nats output is experimental.")
+
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return outputs.Fail(err)
+	}
+
+	subject, err := buildSubjectSelector(cfg)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	hosts, err := outputs.ReadHostList(cfg)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	tls, err := tlscommon.LoadTLSConfig(config.TLS)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	opts, err := connectOptions(beat, &config)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	clients := make([]outputs.NetworkClient, len(hosts))
+	for i, host := range hosts {
+		hostURL, err := parseHost(host)
+		if err != nil {
+			return outputs.Fail(err)
+		}
+
+		hostOpts := append([]nats.Option{}, opts...)
+		if tls != nil {
+			hostOpts = append(hostOpts, nats.Secure(tls.BuildModuleConfig(hostURL.Hostname())))
+		}
+
+		enc, err := codec.CreateEncoder(beat, config.Codec)
+		if err != nil {
+			return outputs.Fail(err)
+		}
+
+		client := newClient(observer, hostURL.String(), beat.IndexPrefix, enc, subject, &config, hostOpts...)
+		clients[i] = outputs.WithBackoff(client, config.Backoff.Init, config.Backoff.Max)
+	}
+
+	grp, err := outputs.SuccessNet(config.LoadBalance, config.BulkMaxSize, config.MaxRetries, clients)
+	grp.Backoff = &config.Backoff
+	return grp, err
+}
+
+func buildSubjectSelector(cfg *common.Config) (outil.Selector, error) {
+	return outil.BuildSelectorFromConfig(cfg, outil.Settings{
+		Key:              "subject",
+		MultiKey:         "subjects",
+		EnableSingleOnly: true,
+		FailEmpty:        true,
+		Case:             outil.SelectorKeepCase,
+	})
+}
+
+// connectOptions returns the options shared by the connections to all hosts.
+// The connections do not reconnect on their own, the publisher pipeline
+// reconnects the clients with backoff.
+func connectOptions(beat beat.Info, config *natsConfig) ([]nats.Option, error) {
+	opts := []nats.Option{
+		nats.Name(beat.Beat),
+		nats.Timeout(config.Timeout),
+		nats.NoReconnect(),
+	}
+
+	switch {
+	case config.Username != "" || config.Password != "":
+		opts = append(opts, nats.UserInfo(config.Username, config.Password))
+	case config.Token != "":
+		opts = append(opts, nats.Token(config.Token))
+	case config.NKeySeedFile != "":
+		opt, err := nats.NkeyOptionFromSeed(config.NKeySeedFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the nkey seed from %v: %v", config.NKeySeedFile, err)
+		}
+		opts = append(opts, opt)
+	}
+
+	if config.Proxy.URL != "" {
+		dialer, err := transport.ProxyDialer(logp.NewLogger(logSelector), &config.Proxy, transport.NetDialer(config.Timeout))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, nats.SetCustomDialer(dialer))
+	}
+	return opts, nil
+}
+
+// parseHost parses a host of the hosts setting, which defaults to the nats
+// scheme.
+func parseHost(host string) (*url.URL, error) {
+	if !strings.Contains(host, "://") {
+		host = "nats://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host %v: %v", host, err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid host %v, the host name is missing", host)
+	}
+	return u, nil
+}
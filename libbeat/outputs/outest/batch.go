@@ -42,6 +42,7 @@ const (
 	BatchRetryEvents
 	BatchCancelled
 	BatchCancelledEvents
+	BatchSplitRetry
 )
 
 func NewBatch(in ...beat.Event) *Batch {
@@ -80,6 +81,14 @@ func (b *Batch) CancelledEvents(events []publisher.Event) {
 	b.doSignal(BatchSignal{Tag: BatchCancelledEvents, Events: events})
 }
 
+func (b *Batch) SplitRetry(events []publisher.Event) bool {
+	if len(events) < 2 {
+		return false
+	}
+	b.doSignal(BatchSignal{Tag: BatchSplitRetry, Events: events})
+	return true
+}
+
 func (b *Batch) doSignal(sig BatchSignal) {
 	b.Signals = append(b.Signals, sig)
 	if b.OnSignal != nil {
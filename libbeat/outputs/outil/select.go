@@ -161,6 +161,19 @@ func BuildSelectorFromConfig(
 		}
 	}
 
+	if settings.FallbackKey != "" && cfg.HasField(settings.FallbackKey) {
+		found = true
+
+		str, err := cfg.String(settings.FallbackKey, -1)
+		if err != nil {
+			return Selector{}, err
+		}
+
+		if fallback := ConstSelectorExpr(str, settings.Case); fallback != nilSelector {
+			sel = append(sel, fallback)
+		}
+	}
+
 	if settings.FailEmpty && !found {
 		if settings.EnableSingleOnly {
 			return Selector{}, fmt.Errorf("missing required '%v' or '%v' in %v",
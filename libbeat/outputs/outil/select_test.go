@@ -34,6 +34,9 @@ func TestSelector(t *testing.T) {
 	useLowerCase := func(s Settings) Settings {
 		return s.WithSelectorCase(SelectorLowerCase)
 	}
+	useFallback := func(s Settings) Settings {
+		return s.WithFallbackKey("fallback")
+	}
 
 	tests := map[string]struct {
 		config   string
@@ -257,6 +260,32 @@ func TestSelector(t *testing.T) {
 			event: common.MapStr{"test": "test"},
 			want:  "value",
 		},
+		"missing format string key with fallback": {
+			config:   `{key: '%{[key]}', fallback: value}`,
+			event:    common.MapStr{},
+			want:     "value",
+			settings: useFallback,
+		},
+		"failing rules with fallback": {
+			config:   `{fallback: value, keys: [{key: wrong, when.equals.test: test}, {key: '%{[key]}'}]}`,
+			event:    common.MapStr{"test": "x"},
+			want:     "value",
+			settings: useFallback,
+		},
+		"lowercase fallback": {
+			config: `{key: '%{[key]}', fallback: vAlUe}`,
+			event:  common.MapStr{},
+			want:   "value",
+			settings: func(s Settings) Settings {
+				return useFallback(s).WithSelectorCase(SelectorLowerCase)
+			},
+		},
+		"fallback is not used if key matches": {
+			config:   `{key: '%{[key]}', fallback: wrong}`,
+			event:    common.MapStr{"key": "value"},
+			want:     "value",
+			settings: useFallback,
+		},
 		"failing condition": {
 			config: `keys:
 						       - key: wrong
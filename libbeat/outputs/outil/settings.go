@@ -34,6 +34,10 @@ type Settings struct {
 	// Fail building selector if `key` and `multiKey` are missing
 	FailEmpty bool
 
+	// optional key of a constant string that is selected if neither `key`
+	// nor the rules in `multiKey` select a string for an event
+	FallbackKey string
+
 	// Case configures the case-sensitivity of generated strings.
 	Case SelectorCase
 }
@@ -78,6 +82,12 @@ func (s Settings) WithFailEmpty(b bool) Settings {
 	return s
 }
 
+// WithFallbackKey returns a new Settings struct with updated `FallbackKey` setting.
+func (s Settings) WithFallbackKey(key string) Settings {
+	s.FallbackKey = key
+	return s
+}
+
 // WithSelectorCase returns a new Settings struct with updated `Case` setting.
 func (s Settings) WithSelectorCase(c SelectorCase) Settings {
 	s.Case = c
@@ -53,6 +53,14 @@ type Group struct {
 	Clients   []Client
 	BatchSize int
 	Retry     int
+
+	// Backoff configures the reconnection attempts of network clients,
+	// DefaultBackoffConfig is used if it's nil.
+	Backoff *BackoffConfig
+
+	// RateLimit limits the rate of the events passed to the clients, it's
+	// read by Load from the rate_limit setting of the output.
+	RateLimit *RateLimitConfig
 }
 
 // RegisterType registers a new output type.
@@ -84,5 +92,18 @@ func Load(
 	if stats == nil {
 		stats = NewNilObserver()
 	}
-	return factory(im, info, stats, config)
+
+	rateLimit, err := loadRateLimit(config)
+	if err != nil {
+		return Group{}, fmt.Errorf("invalid rate_limit of output %v: %w", name, err)
+	}
+
+	grp, err := factory(im, info, stats, config)
+	if err != nil {
+		return grp, err
+	}
+	if rateLimit != nil {
+		grp.RateLimit = rateLimit
+	}
+	return grp, nil
 }
@@ -0,0 +1,57 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package outputs
+
+import (
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// proxyDefaults holds the proxy settings shared by all network outputs.
+type proxyDefaults struct {
+	URL          string `config:"url"`
+	LocalResolve bool   `config:"use_local_resolver"`
+}
+
+// ApplyProxyDefaults configures the proxy settings in proxy as the default
+// `proxy_url` and `proxy_use_local_resolver` of an output configuration.
+// Outputs with their own proxy settings, or with `proxy_disable` set, are
+// not modified.
+func ApplyProxyDefaults(output, proxy *common.Config) error {
+	if output == nil || proxy == nil {
+		return nil
+	}
+
+	var defaults proxyDefaults
+	if err := proxy.Unpack(&defaults); err != nil {
+		return err
+	}
+	if defaults.URL == "" || output.HasField("proxy_url") {
+		return nil
+	}
+	if disabled, err := output.Bool("proxy_disable", -1); err == nil && disabled {
+		return nil
+	}
+
+	if err := output.SetString("proxy_url", -1, defaults.URL); err != nil {
+		return err
+	}
+	if defaults.LocalResolve && !output.HasField("proxy_use_local_resolver") {
+		return output.SetBool("proxy_use_local_resolver", -1, true)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package outputs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestApplyProxyDefaults(t *testing.T) {
+	proxy := common.MustNewConfigFrom(map[string]interface{}{
+		"url":                "socks5://localhost:1080",
+		"use_local_resolver": true,
+	})
+
+	cases := map[string]struct {
+		output       map[string]interface{}
+		url          string
+		localResolve bool
+	}{
+		"no proxy settings": {
+			output:       map[string]interface{}{"hosts": []string{"localhost:5044"}},
+			url:          "socks5://localhost:1080",
+			localResolve: true,
+		},
+		"output proxy is kept": {
+			output: map[string]interface{}{"proxy_url": "http://localhost:3128"},
+			url:    "http://localhost:3128",
+		},
+		"proxy disabled": {
+			output: map[string]interface{}{"proxy_disable": true},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			output := common.MustNewConfigFrom(test.output)
+			require.NoError(t, ApplyProxyDefaults(output, proxy))
+
+			var settings struct {
+				URL          string `config:"proxy_url"`
+				LocalResolve bool   `config:"proxy_use_local_resolver"`
+			}
+			require.NoError(t, output.Unpack(&settings))
+			assert.Equal(t, test.url, settings.URL)
+			assert.Equal(t, test.localResolve, settings.LocalResolve)
+		})
+	}
+}
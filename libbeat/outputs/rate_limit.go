@@ -0,0 +1,64 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package outputs
+
+import (
+	"math"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// RateLimitConfig configures the maximum rate of the events published by the
+// publisher pipeline to an output.
+type RateLimitConfig struct {
+	// EventsPerSecond is the maximum average rate, 0 disables rate limiting.
+	EventsPerSecond float64 `config:"events_per_second" validate:"min=0"`
+
+	// Burst is the maximum number of events published at once above the
+	// average rate. It defaults to the number of events per second.
+	Burst int `config:"burst" validate:"min=0"`
+}
+
+// Enabled returns true if the rate is limited.
+func (c RateLimitConfig) Enabled() bool {
+	return c.EventsPerSecond > 0
+}
+
+// BurstSize returns the configured burst, or the number of events per second
+// if it's not set.
+func (c RateLimitConfig) BurstSize() int {
+	if c.Burst > 0 {
+		return c.Burst
+	}
+	return int(math.Max(1, math.Ceil(c.EventsPerSecond)))
+}
+
+// loadRateLimit reads the rate_limit setting common to all outputs.
+func loadRateLimit(config *common.Config) (*RateLimitConfig, error) {
+	if config == nil || !config.HasField("rate_limit") {
+		return nil, nil
+	}
+
+	settings := struct {
+		RateLimit RateLimitConfig `config:"rate_limit"`
+	}{}
+	if err := config.Unpack(&settings); err != nil {
+		return nil, err
+	}
+	return &settings.RateLimit, nil
+}
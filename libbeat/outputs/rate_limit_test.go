@@ -0,0 +1,45 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package outputs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestLoadRateLimit(t *testing.T) {
+	rateLimit, err := loadRateLimit(common.MustNewConfigFrom(`hosts: [localhost]`))
+	require.NoError(t, err)
+	assert.Nil(t, rateLimit)
+
+	rateLimit, err = loadRateLimit(common.MustNewConfigFrom(`rate_limit.events_per_second: 2.5`))
+	require.NoError(t, err)
+	assert.True(t, rateLimit.Enabled())
+	assert.Equal(t, 3, rateLimit.BurstSize())
+
+	rateLimit, err = loadRateLimit(common.MustNewConfigFrom(`rate_limit: {events_per_second: 100, burst: 500}`))
+	require.NoError(t, err)
+	assert.Equal(t, 500, rateLimit.BurstSize())
+
+	_, err = loadRateLimit(common.MustNewConfigFrom(`rate_limit.events_per_second: -1`))
+	assert.Error(t, err)
+}
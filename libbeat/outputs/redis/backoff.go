@@ -64,8 +64,8 @@ func (b *backoffClient) Connect() error {
 	err := b.client.Connect()
 	if err != nil {
 		// give the client a chance to promote an internal error to a network error.
+		// The publisher pipeline waits before the next connection attempt.
 		b.updateFailReason(err)
-		b.backoff.Wait()
 	} else if b.reason != failRedis { // Only reset backoff duration if failure was due to IO errors.
 		b.resetFail()
 	}
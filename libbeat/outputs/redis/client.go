@@ -34,6 +34,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/outputs"
 	"github.com/elastic/beats/v7/libbeat/outputs/codec"
 	"github.com/elastic/beats/v7/libbeat/outputs/outil"
+	"github.com/elastic/beats/v7/libbeat/privacy"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 )
 
@@ -58,6 +59,12 @@ type client struct {
 	publish  publishFn
 	codec    codec.Codec
 	timeout  time.Duration
+	stream   streamConfig
+
+	// dialer is set if the hosts are nodes of a Redis Cluster, the cluster is
+	// set on connect.
+	dialer  *clusterDialer
+	cluster *clusterNodes
 }
 
 type redisDataType uint16
@@ -65,6 +72,7 @@ type redisDataType uint16
 const (
 	redisListType redisDataType = iota
 	redisChannelType
+	redisStreamType
 )
 
 func newClient(
@@ -74,6 +82,7 @@ func newClient(
 	pass string,
 	db int, key outil.Selector, dt redisDataType,
 	index string, codec codec.Codec,
+	stream streamConfig, dialer *clusterDialer,
 ) *client {
 	return &client{
 		log:      logp.NewLogger("redis"),
@@ -86,6 +95,8 @@ func newClient(
 		dataType: dt,
 		key:      key,
 		codec:    codec,
+		stream:   stream,
+		dialer:   dialer,
 	}
 }
 
@@ -132,6 +143,10 @@ func initRedisConn(c redis.Conn, pwd string, db int) error {
 
 func (c *client) Close() error {
 	c.log.Debug("close connection")
+	if c.cluster != nil {
+		c.cluster.Close()
+		c.cluster = nil
+	}
 	return c.Client.Close()
 }
 
@@ -163,8 +178,11 @@ func (c *client) String() string {
 func (c *client) makePublish(
 	conn redis.Conn,
 ) (publishFn, error) {
-	if c.dataType == redisChannelType {
+	switch c.dataType {
+	case redisChannelType:
 		return c.makePublishPUBLISH(conn)
+	case redisStreamType:
+		return c.makePublishXADD(conn)
 	}
 	return c.makePublishRPUSH(conn)
 }
@@ -175,27 +193,7 @@ func (c *client) makePublishRPUSH(conn redis.Conn) (publishFn, error) {
 		return c.publishEventsPipeline(conn, "RPUSH"), nil
 	}
 
-	var major, minor int
-	var versionRaw [][]byte
-
-	respRaw, err := conn.Do("INFO")
-	resp, err := redis.Bytes(respRaw, err)
-	if err != nil {
-		return nil, err
-	}
-
-	versionRaw = versionRegex.FindSubmatch(resp)
-	if versionRaw == nil {
-		err = errors.New("unable to read redis_version")
-		return nil, err
-	}
-
-	major, err = strconv.Atoi(string(versionRaw[1]))
-	if err != nil {
-		return nil, err
-	}
-
-	minor, err = strconv.Atoi(string(versionRaw[2]))
+	major, minor, err := redisVersion(conn)
 	if err != nil {
 		return nil, err
 	}
@@ -216,6 +214,31 @@ func (c *client) makePublishRPUSH(conn redis.Conn) (publishFn, error) {
 	return c.publishEventsPipeline(conn, "RPUSH"), nil
 }
 
+// redisVersion returns the major and minor version of the Redis server.
+func redisVersion(conn redis.Conn) (int, int, error) {
+	respRaw, err := conn.Do("INFO")
+	resp, err := redis.Bytes(respRaw, err)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	versionRaw := versionRegex.FindSubmatch(resp)
+	if versionRaw == nil {
+		return 0, 0, errors.New("unable to read redis_version")
+	}
+
+	major, err := strconv.Atoi(string(versionRaw[1]))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	minor, err := strconv.Atoi(string(versionRaw[2]))
+	if err != nil {
+		return 0, 0, err
+	}
+	return major, minor, nil
+}
+
 func (c *client) makePublishPUBLISH(conn redis.Conn) (publishFn, error) {
 	return c.publishEventsPipeline(conn, "PUBLISH"), nil
 }
@@ -317,7 +340,7 @@ func serializeEvents(
 		serializedEvent, err := codec.Encode(index, &d.Content)
 		if err != nil {
 			log.Errorf("Encoding event failed with error: %+v", err)
-			log.Debugf("Failed event: %v", d.Content)
+			log.Debugf("Failed event: %v", privacy.LazyEvent(&d.Content))
 			goto failLoop
 		}
 
@@ -335,7 +358,7 @@ failLoop:
 		serializedEvent, err := codec.Encode(index, &d.Content)
 		if err != nil {
 			log.Errorf("Encoding event failed with error: %+v", err)
-			log.Debugf("Failed event: %v", d.Content)
+			log.Debugf("Failed event: %v", privacy.LazyEvent(&d.Content))
 			i++
 			continue
 		}
@@ -0,0 +1,205 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	"github.com/elastic/beats/v7/libbeat/common/transport"
+)
+
+// clusterSlots is the number of hash slots of a Redis Cluster.
+const clusterSlots = 16384
+
+// clusterDialer connects to the nodes of a Redis Cluster with the settings of
+// the configured host.
+type clusterDialer struct {
+	transport transport.Config
+	timeout   time.Duration
+	password  string
+
+	// host is used for the nodes announced without an IP, which are the nodes
+	// the CLUSTER SLOTS command was sent to.
+	host string
+}
+
+func (d *clusterDialer) dial(addr string) (redis.Conn, error) {
+	tc, err := transport.NewClient(d.transport, "tcp", addr, defaultPort)
+	if err != nil {
+		return nil, err
+	}
+	if err := tc.Connect(); err != nil {
+		return nil, err
+	}
+
+	conn := redis.NewConn(tc, d.timeout, d.timeout)
+	if err := initRedisConn(conn, d.password, 0); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// clusterNodes routes the commands to the nodes of a Redis Cluster serving
+// the hash slots of their keys. The slots are read once, when the client
+// connects. A node replying with a MOVED or ASK redirection fails the publish,
+// and the slots are read again on reconnect.
+type clusterNodes struct {
+	dial  func(addr string) (redis.Conn, error)
+	slots []slotRange
+	conns map[string]redis.Conn
+}
+
+// slotRange is a range of hash slots served by the master at addr.
+type slotRange struct {
+	start, end int
+	addr       string
+}
+
+// loadClusterNodes reads the hash slots of the cluster with the CLUSTER SLOTS
+// command. The nodes announced without an IP are reached at host.
+func loadClusterNodes(
+	conn redis.Conn,
+	host string,
+	dial func(addr string) (redis.Conn, error),
+) (*clusterNodes, error) {
+	replies, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make([]slotRange, 0, len(replies))
+	for _, reply := range replies {
+		// Each reply is [start, end, [ip, port, id], replicas...].
+		fields, err := redis.Values(reply, nil)
+		if err != nil || len(fields) < 3 {
+			return nil, fmt.Errorf("invalid CLUSTER SLOTS reply: %v", reply)
+		}
+		start, err1 := redis.Int(fields[0], nil)
+		end, err2 := redis.Int(fields[1], nil)
+		master, err3 := redis.Values(fields[2], nil)
+		if err1 != nil || err2 != nil || err3 != nil || len(master) < 2 {
+			return nil, fmt.Errorf("invalid CLUSTER SLOTS reply: %v", reply)
+		}
+		ip, err1 := redis.String(master[0], nil)
+		port, err2 := redis.Int(master[1], nil)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid CLUSTER SLOTS reply: %v", reply)
+		}
+		if ip == "" {
+			ip = host
+		}
+
+		slots = append(slots, slotRange{
+			start: start,
+			end:   end,
+			addr:  net.JoinHostPort(ip, strconv.Itoa(port)),
+		})
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].start < slots[j].start })
+
+	return &clusterNodes{
+		dial:  dial,
+		slots: slots,
+		conns: map[string]redis.Conn{},
+	}, nil
+}
+
+// addrFor returns the address of the node serving the hash slot of key.
+func (n *clusterNodes) addrFor(key string) (string, error) {
+	slot := keySlot(key)
+	i := sort.Search(len(n.slots), func(i int) bool { return n.slots[i].end >= slot })
+	if i == len(n.slots) || n.slots[i].start > slot {
+		return "", fmt.Errorf("hash slot %d of key %v is not served by any node", slot, key)
+	}
+	return n.slots[i].addr, nil
+}
+
+// conn returns the connection to the node at addr, connecting if required.
+func (n *clusterNodes) conn(addr string) (redis.Conn, error) {
+	if conn, ok := n.conns[addr]; ok {
+		return conn, nil
+	}
+
+	conn, err := n.dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	n.conns[addr] = conn
+	return conn, nil
+}
+
+// drop closes the connection to the node at addr, after a network error.
+func (n *clusterNodes) drop(addr string) {
+	if conn, ok := n.conns[addr]; ok {
+		conn.Close()
+		delete(n.conns, addr)
+	}
+}
+
+// Close closes the connections to all nodes.
+func (n *clusterNodes) Close() {
+	for addr := range n.conns {
+		n.drop(addr)
+	}
+}
+
+// isRedirection checks if err is a MOVED or ASK redirection, returned by a
+// node that does not serve the hash slot of the key anymore.
+func isRedirection(err error) bool {
+	if err, ok := err.(redis.Error); ok {
+		msg := string(err)
+		return strings.HasPrefix(msg, "MOVED ") || strings.HasPrefix(msg, "ASK ")
+	}
+	return false
+}
+
+// keySlot returns the hash slot of key. If the key contains a hash tag, a non
+// empty substring between { and }, only the hash tag is hashed, so keys with
+// the same hash tag are in the same slot.
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key)) % clusterSlots
+}
+
+// crc16 implements the CRC16-CCITT (XMODEM) checksum used by Redis Cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
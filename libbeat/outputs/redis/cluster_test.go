@@ -0,0 +1,76 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slotsConn replies to CLUSTER SLOTS.
+type slotsConn struct {
+	fakeConn
+	reply interface{}
+}
+
+func (c *slotsConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return c.reply, nil
+}
+
+func TestKeySlot(t *testing.T) {
+	assert.Equal(t, uint16(0x31C3), crc16("123456789"))
+
+	assert.Equal(t, 12182, keySlot("foo"))
+	assert.Equal(t, keySlot("user1000"), keySlot("{user1000}.following"))
+	assert.Equal(t, keySlot("user1000"), keySlot("foo{user1000}{bar}"))
+	// Empty hash tags are ignored, the whole key is hashed.
+	assert.Equal(t, int(crc16("foo{}{bar}"))%clusterSlots, keySlot("foo{}{bar}"))
+}
+
+func TestLoadClusterNodes(t *testing.T) {
+	node := func(ip string, port int64) []interface{} {
+		return []interface{}{[]byte(ip), port, []byte("id")}
+	}
+	conn := &slotsConn{reply: []interface{}{
+		[]interface{}{int64(5461), int64(10922), node("10.0.0.2", 6379), node("10.0.0.5", 6379)},
+		[]interface{}{int64(0), int64(5460), node("", 7000)},
+		[]interface{}{int64(10923), int64(16383), node("10.0.0.3", 6379)},
+	}}
+
+	cluster, err := loadClusterNodes(conn, "seed", func(string) (redis.Conn, error) { return nil, nil })
+	require.NoError(t, err)
+
+	assert.Equal(t, []slotRange{
+		{start: 0, end: 5460, addr: "seed:7000"},
+		{start: 5461, end: 10922, addr: "10.0.0.2:6379"},
+		{start: 10923, end: 16383, addr: "10.0.0.3:6379"},
+	}, cluster.slots)
+
+	addr, err := cluster.addrFor("foo")
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.3:6379", addr)
+}
+
+func TestAddrForUncoveredSlot(t *testing.T) {
+	cluster := &clusterNodes{slots: []slotRange{{start: 0, end: 100, addr: "a:6379"}}}
+	_, err := cluster.addrFor("foo")
+	assert.Error(t, err)
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/common/transport"
 	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+	"github.com/elastic/beats/v7/libbeat/outputs"
 	"github.com/elastic/beats/v7/libbeat/outputs/codec"
 )
 
@@ -39,12 +40,21 @@ type redisConfig struct {
 	Codec       codec.Config          `config:"codec"`
 	Db          int                   `config:"db"`
 	DataType    string                `config:"datatype"`
-	Backoff     backoff               `config:"backoff"`
+	Stream      streamConfig          `config:"stream"`
+	Cluster     bool                  `config:"cluster"`
+	Backoff     outputs.BackoffConfig `config:"backoff"`
 }
 
-type backoff struct {
-	Init time.Duration
-	Max  time.Duration
+// streamConfig holds the settings of the XADD command used with the stream
+// data type.
+type streamConfig struct {
+	// Field is the name of the stream entry field set to the encoded event.
+	Field string `config:"field" validate:"required"`
+
+	// MaxLen trims the stream to about or exactly, if Approximate is false,
+	// MaxLen entries. 0 disables the trimming.
+	MaxLen      int  `config:"max_len" validate:"min=0"`
+	Approximate bool `config:"approximate"`
 }
 
 var (
@@ -56,19 +66,30 @@ var (
 		TLS:         nil,
 		Db:          0,
 		DataType:    "list",
-		Backoff: backoff{
-			Init: 1 * time.Second,
-			Max:  60 * time.Second,
+		Stream: streamConfig{
+			Field:       "event",
+			MaxLen:      0,
+			Approximate: true,
 		},
+		Backoff: outputs.DefaultBackoffConfig(),
 	}
 )
 
 func (c *redisConfig) Validate() error {
 	switch c.DataType {
-	case "", "list", "channel":
+	case "", "list", "channel", "stream":
 	default:
 		return fmt.Errorf("redis data type %v not supported", c.DataType)
 	}
 
+	if c.Cluster {
+		if c.DataType != "stream" {
+			return fmt.Errorf("redis cluster is only supported with the stream data type")
+		}
+		if c.Db != 0 {
+			return fmt.Errorf("redis cluster only supports the db 0")
+		}
+	}
+
 	return nil
 }
@@ -37,6 +37,10 @@ func TestValidate(t *testing.T) {
 		{"Invalid Datatype", redisConfig{Key: "test", DataType: "something"}, false},
 		{"List Datatype", redisConfig{Key: "test", DataType: "list"}, true},
 		{"Channel Datatype", redisConfig{Key: "test", DataType: "channel"}, true},
+		{"Stream Datatype", redisConfig{Key: "test", DataType: "stream"}, true},
+		{"Cluster with Stream Datatype", redisConfig{Key: "test", DataType: "stream", Cluster: true}, true},
+		{"Cluster with List Datatype", redisConfig{Key: "test", DataType: "list", Cluster: true}, false},
+		{"Cluster with db", redisConfig{Key: "test", DataType: "stream", Cluster: true, Db: 1}, false},
 	}
 
 	for _, test := range tests {
@@ -88,6 +88,8 @@ func makeRedis(
 		dataType = redisListType
 	case "channel":
 		dataType = redisChannelType
+	case "stream":
+		dataType = redisStreamType
 	default:
 		return outputs.Fail(errors.New("Bad Redis data type"))
 	}
@@ -162,12 +164,25 @@ func makeRedis(
 			return outputs.Fail(err)
 		}
 
+		var dialer *clusterDialer
+		if config.Cluster {
+			dialer = &clusterDialer{
+				transport: transp,
+				timeout:   config.Timeout,
+				password:  pass,
+				host:      hostUrl.Hostname(),
+			}
+		}
+
 		client := newClient(conn, observer, config.Timeout,
-			pass, config.Db, key, dataType, config.Index, enc)
+			pass, config.Db, key, dataType, config.Index, enc,
+			config.Stream, dialer)
 		clients[i] = newBackoffClient(client, config.Backoff.Init, config.Backoff.Max)
 	}
 
-	return outputs.SuccessNet(config.LoadBalance, config.BulkMaxSize, config.MaxRetries, clients)
+	grp, err := outputs.SuccessNet(config.LoadBalance, config.BulkMaxSize, config.MaxRetries, clients)
+	grp.Backoff = &config.Backoff
+	return grp, err
 }
 
 func buildKeySelector(cfg *common.Config) (outil.Selector, error) {
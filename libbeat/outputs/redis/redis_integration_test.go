@@ -166,6 +166,51 @@ func testPublishList(t *testing.T, cfg map[string]interface{}) {
 	}
 }
 
+func TestPublishStreamTCP(t *testing.T) {
+	key := "test_publish_stream_tcp"
+	out := newRedisTestingOutput(t, map[string]interface{}{
+		"hosts":              []string{getRedisAddr()},
+		"key":                key,
+		"datatype":           "stream",
+		"stream.max_len":     500,
+		"stream.approximate": false,
+		"stream.field":       "event",
+		"timeout":            "5s",
+	})
+
+	conn, err := redis.Dial("tcp", getRedisAddr())
+	if err != nil {
+		t.Fatalf("redis.Dial failed %v", err)
+	}
+	defer conn.Close()
+	conn.Do("DEL", key)
+
+	err = sendTestEvents(out, 10, 100)
+	assert.NoError(t, err)
+
+	// The stream is trimmed to the last 500 events.
+	entries, err := redis.Values(conn.Do("XRANGE", key, "-", "+"))
+	if err != nil {
+		t.Fatalf("XRANGE failed %v", err)
+	}
+	assert.Len(t, entries, 500)
+
+	for i, entry := range entries {
+		// Each entry is [id, [field, value]].
+		fields, err := redis.Values(entry, nil)
+		assert.NoError(t, err)
+		values, err := redis.ByteSlices(fields[1], nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "event", string(values[0]))
+
+		evt := struct{ Message int }{}
+		err = json.Unmarshal(values[1], &evt)
+		assert.NoError(t, err)
+		assert.Equal(t, 501+i, evt.Message)
+		validateMeta(t, values[1])
+	}
+}
+
 func TestPublishChannelTCP(t *testing.T) {
 	db := 0
 	key := "test_pubchan_tcp"
@@ -0,0 +1,210 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"fmt"
+
+	"github.com/garyburd/redigo/redis"
+
+	"github.com/elastic/beats/v7/libbeat/outputs/outil"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// streamEntries are the events to add to streams with XADD, with their stream
+// keys and encoded content.
+type streamEntries struct {
+	events     []publisher.Event
+	keys       []string
+	serialized []interface{}
+}
+
+func (c *client) makePublishXADD(conn redis.Conn) (publishFn, error) {
+	// Streams were added in Redis 5.0.
+	major, _, err := redisVersion(conn)
+	if err != nil {
+		return nil, err
+	}
+	if major < 5 {
+		return nil, fmt.Errorf("the stream data type requires Redis 5.0 or newer")
+	}
+
+	if c.dialer == nil {
+		return c.publishEventsStream(conn), nil
+	}
+
+	cluster, err := loadClusterNodes(conn, c.dialer.host, c.dialer.dial)
+	if err != nil {
+		return nil, err
+	}
+	c.cluster = cluster
+	return c.publishEventsCluster(cluster), nil
+}
+
+func (c *client) publishEventsStream(conn redis.Conn) publishFn {
+	return func(key outil.Selector, data []publisher.Event) ([]publisher.Event, error) {
+		entries := c.serializeStreamEntries(key, data)
+		if len(entries.events) == 0 {
+			return nil, nil
+		}
+
+		failed, err := c.sendXADD(conn, entries)
+		c.observer.Acked(len(entries.events) - len(failed))
+		return failed, err
+	}
+}
+
+// publishEventsCluster sends the events to the nodes serving the hash slots of
+// their stream keys. The events of each node are pipelined, keeping their
+// order.
+func (c *client) publishEventsCluster(cluster *clusterNodes) publishFn {
+	return func(key outil.Selector, data []publisher.Event) ([]publisher.Event, error) {
+		entries := c.serializeStreamEntries(key, data)
+		if len(entries.events) == 0 {
+			return nil, nil
+		}
+
+		var addrs []string
+		nodes := map[string]*streamEntries{}
+		var failed []publisher.Event
+		var lastErr error
+		for i, key := range entries.keys {
+			addr, err := cluster.addrFor(key)
+			if err != nil {
+				c.log.Errorf("Failed to XADD event to stream: %+v", err)
+				failed = append(failed, entries.events[i])
+				lastErr = err
+				continue
+			}
+
+			node, ok := nodes[addr]
+			if !ok {
+				node = &streamEntries{}
+				nodes[addr] = node
+				addrs = append(addrs, addr)
+			}
+			node.events = append(node.events, entries.events[i])
+			node.keys = append(node.keys, key)
+			node.serialized = append(node.serialized, entries.serialized[i])
+		}
+
+		for _, addr := range addrs {
+			node := nodes[addr]
+			conn, err := cluster.conn(addr)
+			if err != nil {
+				c.log.Errorf("Failed to connect to redis cluster node %v: %+v", addr, err)
+				failed = append(failed, node.events...)
+				lastErr = err
+				continue
+			}
+
+			nodeFailed, err := c.sendXADD(conn, *node)
+			failed = append(failed, nodeFailed...)
+			if err != nil {
+				lastErr = err
+				if isRedirection(err) {
+					c.log.Info("The hash slots of the redis cluster moved, they are read again on reconnect")
+				} else if _, ok := err.(redis.Error); !ok {
+					cluster.drop(addr)
+				}
+			}
+		}
+
+		c.observer.Acked(len(entries.events) - len(failed))
+		return failed, lastErr
+	}
+}
+
+// serializeStreamEntries encodes the events and selects their stream keys. The
+// events that cannot be encoded or have no key are dropped.
+func (c *client) serializeStreamEntries(key outil.Selector, data []publisher.Event) streamEntries {
+	serialized := make([]interface{}, 0, len(data))
+	okEvents, serialized := serializeEvents(c.log, serialized, 0, data, c.index, c.codec)
+	c.observer.Dropped(len(data) - len(okEvents))
+
+	entries := streamEntries{
+		events:     make([]publisher.Event, 0, len(okEvents)),
+		keys:       make([]string, 0, len(okEvents)),
+		serialized: make([]interface{}, 0, len(okEvents)),
+	}
+	dropped := 0
+	for i, serializedEvent := range serialized {
+		eventKey, err := key.Select(&okEvents[i].Content)
+		if err != nil {
+			c.log.Errorf("Failed to set redis key: %+v", err)
+			dropped++
+			continue
+		}
+
+		entries.events = append(entries.events, okEvents[i])
+		entries.keys = append(entries.keys, eventKey)
+		entries.serialized = append(entries.serialized, serializedEvent)
+	}
+	c.observer.Dropped(dropped)
+	return entries
+}
+
+// sendXADD pipelines the XADD commands of the entries, it returns the events
+// that failed.
+func (c *client) sendXADD(conn redis.Conn, entries streamEntries) ([]publisher.Event, error) {
+	for i, key := range entries.keys {
+		if err := conn.Send("XADD", c.xaddArgs(key, entries.serialized[i])...); err != nil {
+			c.log.Errorf("Failed to execute XADD: %+v", err)
+			return entries.events, err
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return entries.events, err
+	}
+
+	var failed []publisher.Event
+	var lastErr error
+	for i := range entries.events {
+		_, err := conn.Receive()
+		if err == nil {
+			continue
+		}
+
+		if _, ok := err.(redis.Error); ok {
+			c.log.Errorf("Failed to XADD event to stream with %+v", err)
+			failed = append(failed, entries.events[i])
+			lastErr = err
+		} else {
+			c.log.Errorf("Failed to XADD multiple events to stream with %+v", err)
+			failed = append(failed, entries.events[i:]...)
+			lastErr = err
+			break
+		}
+	}
+	return failed, lastErr
+}
+
+// xaddArgs returns the arguments of the XADD command adding an entry with the
+// encoded event to the stream key, with an ID generated by Redis.
+func (c *client) xaddArgs(key string, serializedEvent interface{}) []interface{} {
+	args := make([]interface{}, 0, 7)
+	args = append(args, key)
+	if c.stream.MaxLen > 0 {
+		args = append(args, "MAXLEN")
+		if c.stream.Approximate {
+			args = append(args, "~")
+		}
+		args = append(args, c.stream.MaxLen)
+	}
+	return append(args, "*", c.stream.Field, serializedEvent)
+}
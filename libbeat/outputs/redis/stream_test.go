@@ -0,0 +1,168 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// fakeConn records the pipelined commands and replies with the errors
+// configured per command.
+type fakeConn struct {
+	sent    [][]interface{}
+	errs    map[int]error
+	flushed int
+}
+
+func (c *fakeConn) Close() error { return nil }
+func (c *fakeConn) Err() error   { return nil }
+
+func (c *fakeConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	return nil, errors.New("unexpected command " + cmd)
+}
+
+func (c *fakeConn) Send(cmd string, args ...interface{}) error {
+	c.sent = append(c.sent, append([]interface{}{cmd}, args...))
+	return nil
+}
+
+func (c *fakeConn) Flush() error { return nil }
+
+func (c *fakeConn) Receive() (interface{}, error) {
+	i := c.flushed
+	c.flushed++
+	if err := c.errs[i]; err != nil {
+		return nil, err
+	}
+	return []byte("1-0"), nil
+}
+
+func newStreamTestClient(t *testing.T, cfg map[string]interface{}, stream streamConfig) *client {
+	key, err := buildKeySelector(common.MustNewConfigFrom(cfg))
+	require.NoError(t, err)
+	enc := json.New("1.2.3", json.Config{})
+	return newClient(nil, outputs.NewNilObserver(), 0, "", 0, key,
+		redisStreamType, "test", enc, stream, nil)
+}
+
+func streamEvents(services ...string) []publisher.Event {
+	events := make([]publisher.Event, len(services))
+	for i, service := range services {
+		events[i] = publisher.Event{Content: beat.Event{
+			Fields: common.MapStr{"service": service},
+		}}
+	}
+	return events
+}
+
+func TestXADDArgs(t *testing.T) {
+	tests := map[string]struct {
+		stream streamConfig
+		want   []interface{}
+	}{
+		"no trimming": {
+			stream: streamConfig{Field: "event"},
+			want:   []interface{}{"key", "*", "event", "data"},
+		},
+		"approximate trimming": {
+			stream: streamConfig{Field: "event", MaxLen: 1000, Approximate: true},
+			want:   []interface{}{"key", "MAXLEN", "~", 1000, "*", "event", "data"},
+		},
+		"exact trimming": {
+			stream: streamConfig{Field: "message", MaxLen: 10},
+			want:   []interface{}{"key", "MAXLEN", 10, "*", "message", "data"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &client{stream: test.stream}
+			assert.Equal(t, test.want, c.xaddArgs("key", "data"))
+		})
+	}
+}
+
+func TestPublishEventsStream(t *testing.T) {
+	c := newStreamTestClient(t,
+		map[string]interface{}{"key": "logs-%{[service]}"},
+		streamConfig{Field: "event", MaxLen: 100, Approximate: true})
+
+	conn := &fakeConn{errs: map[int]error{1: redis.Error("ERR something failed")}}
+	publish := c.publishEventsStream(conn)
+
+	events := streamEvents("a", "b", "a")
+	failed, err := publish(c.key, events)
+	assert.Error(t, err)
+	assert.Equal(t, []publisher.Event{events[1]}, failed)
+
+	require.Len(t, conn.sent, 3)
+	for i, key := range []string{"logs-a", "logs-b", "logs-a"} {
+		assert.Equal(t, []interface{}{"XADD", key, "MAXLEN", "~", 100, "*", "event"}, conn.sent[i][:7])
+	}
+}
+
+func TestPublishEventsCluster(t *testing.T) {
+	c := newStreamTestClient(t,
+		map[string]interface{}{"key": "%{[service]}"},
+		streamConfig{Field: "event"})
+
+	nodes := map[string]*fakeConn{
+		"10.0.0.1:6379": {},
+		"10.0.0.2:6379": {errs: map[int]error{0: redis.Error("MOVED 7638 10.0.0.1:6379")}},
+	}
+	cluster := &clusterNodes{
+		dial: func(addr string) (redis.Conn, error) {
+			return nodes[addr], nil
+		},
+		slots: []slotRange{
+			{start: 0, end: 8191, addr: "10.0.0.1:6379"},
+			{start: 8192, end: 16383, addr: "10.0.0.2:6379"},
+		},
+		conns: map[string]redis.Conn{},
+	}
+	publish := c.publishEventsCluster(cluster)
+
+	// The slot of "a" is 15495, of "b" is 3300 and of "c" is 7365.
+	events := streamEvents("a", "b", "c", "a")
+	failed, err := publish(c.key, events)
+	assert.True(t, isRedirection(err))
+	assert.Equal(t, []publisher.Event{events[0]}, failed)
+
+	var keys []interface{}
+	for _, cmd := range nodes["10.0.0.1:6379"].sent {
+		keys = append(keys, cmd[1])
+	}
+	assert.Equal(t, []interface{}{"b", "c"}, keys)
+
+	keys = nil
+	for _, cmd := range nodes["10.0.0.2:6379"].sent {
+		keys = append(keys, cmd[1])
+	}
+	assert.Equal(t, []interface{}{"a", "a"}, keys)
+}
@@ -0,0 +1,183 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package privacy implements the privacy mode of the Beat. When enabled, the
+// values of sensitive fields are hashed or redacted in events written to
+// debug logs, diagnostics and the dead letter queue, so these can be shared
+// safely.
+//
+// The privacy mode never modifies the events that are published.
+package privacy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+const (
+	methodRedact = "redact"
+	methodHash   = "hash"
+
+	redacted = "[REDACTED]"
+)
+
+// Config configures the privacy mode.
+type Config struct {
+	Enabled bool     `config:"enabled"`
+	Fields  []string `config:"fields"`
+	Method  string   `config:"method"`
+}
+
+var defaultConfig = Config{
+	Method: methodRedact,
+}
+
+// Validate checks the privacy mode settings.
+func (c *Config) Validate() error {
+	if c.Method != methodRedact && c.Method != methodHash {
+		return fmt.Errorf("invalid privacy method '%v', supported methods are '%v' and '%v'",
+			c.Method, methodRedact, methodHash)
+	}
+	if c.Enabled && len(c.Fields) == 0 {
+		return fmt.Errorf("privacy mode requires a list of fields")
+	}
+	return nil
+}
+
+// scrubber removes the values of the configured fields.
+type scrubber struct {
+	fields []string
+	method string
+}
+
+// current holds the *scrubber in use, or a nil *scrubber if the privacy
+// mode is disabled.
+var current atomic.Value
+
+func init() {
+	current.Store((*scrubber)(nil))
+}
+
+// Configure enables or disables the privacy mode. It is disabled by default.
+func Configure(cfg *common.Config) error {
+	config := defaultConfig
+	if cfg != nil {
+		if err := cfg.Unpack(&config); err != nil {
+			return err
+		}
+	}
+
+	var s *scrubber
+	if config.Enabled {
+		s = &scrubber{fields: config.Fields, method: config.Method}
+	}
+	current.Store(s)
+	return nil
+}
+
+// Enabled returns true if the privacy mode is enabled.
+func Enabled() bool {
+	return current.Load().(*scrubber) != nil
+}
+
+// Event returns the event to be used in logs and diagnostics. If the privacy
+// mode is enabled, a copy of the event with the sensitive fields scrubbed is
+// returned; otherwise the event itself is returned.
+func Event(event *beat.Event) *beat.Event {
+	s := current.Load().(*scrubber)
+	if s == nil || event == nil {
+		return event
+	}
+
+	scrubbed := *event
+	scrubbed.Fields = s.scrub(event.Fields)
+	scrubbed.Meta = s.scrub(event.Meta)
+	return &scrubbed
+}
+
+// LazyEvent returns a value that formats like Event(event), but only scrubs
+// the event when it is actually formatted. Use it in log calls, so that the
+// event is not copied if the message is discarded by the logger.
+func LazyEvent(event *beat.Event) fmt.Formatter {
+	return lazyEvent{event}
+}
+
+type lazyEvent struct {
+	event *beat.Event
+}
+
+func (l lazyEvent) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, formatDirective(f, verb), Event(l.event))
+}
+
+// formatDirective rebuilds the formatting directive used to format a value.
+func formatDirective(f fmt.State, verb rune) string {
+	directive := []byte{'%'}
+	for _, flag := range "+-# 0" {
+		if f.Flag(int(flag)) {
+			directive = append(directive, byte(flag))
+		}
+	}
+	if width, ok := f.Width(); ok {
+		directive = strconv.AppendInt(directive, int64(width), 10)
+	}
+	if precision, ok := f.Precision(); ok {
+		directive = append(directive, '.')
+		directive = strconv.AppendInt(directive, int64(precision), 10)
+	}
+	return string(append(directive, string(verb)...))
+}
+
+// Fields returns a copy of the fields with the sensitive fields scrubbed if the
+// privacy mode is enabled; otherwise the fields themselves are returned.
+func Fields(fields common.MapStr) common.MapStr {
+	s := current.Load().(*scrubber)
+	if s == nil {
+		return fields
+	}
+	return s.scrub(fields)
+}
+
+func (s *scrubber) scrub(fields common.MapStr) common.MapStr {
+	if fields == nil {
+		return nil
+	}
+
+	scrubbed := fields.Clone()
+	for _, field := range s.fields {
+		value, err := scrubbed.GetValue(field)
+		if err != nil {
+			continue
+		}
+		scrubbed.Put(field, s.replacement(value))
+	}
+	return scrubbed
+}
+
+func (s *scrubber) replacement(value interface{}) string {
+	if s.method == methodHash {
+		hash := sha256.Sum256([]byte(fmt.Sprint(value)))
+		return "sha256:" + hex.EncodeToString(hash[:])
+	}
+	return redacted
+}
@@ -0,0 +1,114 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package privacy
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestEvent(t *testing.T) {
+	defer Configure(nil)
+
+	newEvent := func() *beat.Event {
+		return &beat.Event{
+			Meta: common.MapStr{"pipeline": "test"},
+			Fields: common.MapStr{
+				"message": "user alice logged in",
+				"user":    common.MapStr{"name": "alice", "id": 1000},
+				"host":    common.MapStr{"name": "server"},
+			},
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		require.NoError(t, Configure(nil))
+		event := newEvent()
+		assert.True(t, event == Event(event))
+	})
+
+	t.Run("redact", func(t *testing.T) {
+		require.NoError(t, Configure(common.MustNewConfigFrom(map[string]interface{}{
+			"enabled": true,
+			"fields":  []string{"message", "user.name", "source.ip"},
+		})))
+
+		event := newEvent()
+		scrubbed := Event(event)
+		assert.Equal(t, common.MapStr{
+			"message": "[REDACTED]",
+			"user":    common.MapStr{"name": "[REDACTED]", "id": 1000},
+			"host":    common.MapStr{"name": "server"},
+		}, scrubbed.Fields)
+		assert.Equal(t, event.Meta, scrubbed.Meta)
+
+		// the original event is not modified
+		assert.Equal(t, newEvent(), event)
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		require.NoError(t, Configure(common.MustNewConfigFrom(map[string]interface{}{
+			"enabled": true,
+			"fields":  []string{"user"},
+			"method":  "hash",
+		})))
+
+		first := Event(newEvent())
+		second := Event(newEvent())
+		value, err := first.Fields.GetValue("user")
+		require.NoError(t, err)
+		assert.Contains(t, value, "sha256:")
+		assert.NotContains(t, value, "alice")
+		assert.Equal(t, first.Fields, second.Fields)
+	})
+}
+
+func TestLazyEvent(t *testing.T) {
+	defer Configure(nil)
+
+	require.NoError(t, Configure(common.MustNewConfigFrom(map[string]interface{}{
+		"enabled": true,
+		"fields":  []string{"user.name"},
+	})))
+
+	event := &beat.Event{
+		Fields: common.MapStr{"user": common.MapStr{"name": "alice"}},
+	}
+	for _, format := range []string{"%v", "%+v", "%#v"} {
+		assert.Equal(t, fmt.Sprintf(format, Event(event)), fmt.Sprintf(format, LazyEvent(event)))
+	}
+	assert.NotContains(t, fmt.Sprintf("%+v", LazyEvent(event)), "alice")
+}
+
+func TestConfigValidate(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"invalid method": {"enabled": true, "fields": []string{"message"}, "method": "encrypt"},
+		"no fields":      {"enabled": true},
+	}
+	for name, settings := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, Configure(common.MustNewConfigFrom(settings)))
+		})
+	}
+}
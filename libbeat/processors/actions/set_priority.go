@@ -0,0 +1,69 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package actions
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	"github.com/elastic/beats/v7/libbeat/processors/checks"
+)
+
+type setPriority struct {
+	priority beat.Priority
+}
+
+func init() {
+	processors.RegisterPlugin("set_priority",
+		checks.ConfigChecked(createSetPriority,
+			checks.RequireFields("priority"),
+			checks.AllowedFields("priority", "when")))
+}
+
+func createSetPriority(c *common.Config) (processors.Processor, error) {
+	config := struct {
+		Priority string `config:"priority" validate:"required"`
+	}{}
+
+	err := c.Unpack(&config)
+	if err != nil {
+		return nil, fmt.Errorf("fail to unpack the set_priority configuration: %s", err)
+	}
+
+	priority, err := beat.ParsePriority(config.Priority)
+	if err != nil {
+		return nil, fmt.Errorf("invalid set_priority configuration: %s", err)
+	}
+	return NewSetPriority(priority), nil
+}
+
+// NewSetPriority creates a new processor setting the priority of the events.
+func NewSetPriority(priority beat.Priority) processors.Processor {
+	return &setPriority{priority: priority}
+}
+
+func (sp *setPriority) Run(event *beat.Event) (*beat.Event, error) {
+	event.SetPriority(sp.priority)
+	return event, nil
+}
+
+func (sp *setPriority) String() string {
+	return fmt.Sprintf("set_priority=%v", sp.priority)
+}
@@ -0,0 +1,46 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package actions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestSetPriority(t *testing.T) {
+	p, err := createSetPriority(common.MustNewConfigFrom(map[string]interface{}{
+		"priority": "high",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "set_priority=high", p.String())
+
+	event, err := p.Run(&beat.Event{Fields: common.MapStr{"message": "alert"}})
+	require.NoError(t, err)
+	assert.Equal(t, beat.PriorityHigh, event.Priority())
+	assert.Equal(t, common.MapStr{"message": "alert"}, event.Fields)
+
+	_, err = createSetPriority(common.MustNewConfigFrom(map[string]interface{}{
+		"priority": "urgent",
+	}))
+	assert.Error(t, err)
+}
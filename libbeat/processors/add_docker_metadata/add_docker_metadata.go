@@ -35,6 +35,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/common/docker"
 	"github.com/elastic/beats/v7/libbeat/common/safemapstr"
 	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/memgov"
 	"github.com/elastic/beats/v7/libbeat/processors"
 	"github.com/elastic/beats/v7/libbeat/processors/actions"
 )
@@ -64,6 +65,10 @@ type addDockerMetadata struct {
 	hostFS          string        // Directory where /proc is found
 	dedot           bool          // If set to true, replace dots in labels with `_`.
 	dockerAvailable bool          // If Docker exists in env, then it is set to true
+
+	// unregisterShrinker stops clearing the cgroups cache under memory
+	// pressure.
+	unregisterShrinker func()
 }
 
 const selector = "add_docker_metadata"
@@ -128,6 +133,8 @@ func lazyCgroupCacheInit(d *addDockerMetadata) {
 		}
 		d.cgroups = common.NewCacheWithRemovalListener(cgroupCacheExpiration, 100, evictionListener)
 		d.cgroups.StartJanitor(5 * time.Second)
+		cgroups := d.cgroups
+		d.unregisterShrinker = memgov.RegisterShrinker(func() { cgroups.Clear() })
 	}
 }
 
@@ -211,6 +218,7 @@ func (d *addDockerMetadata) Run(event *beat.Event) (*beat.Event, error) {
 
 func (d *addDockerMetadata) Close() error {
 	if d.cgroups != nil {
+		d.unregisterShrinker()
 		d.cgroups.StopJanitor()
 	}
 	// Watcher can be nil if processor failed on creation
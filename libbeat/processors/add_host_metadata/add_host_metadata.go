@@ -48,6 +48,9 @@ type addHostMetadata struct {
 	geoData common.MapStr
 	config  Config
 	logger  *logp.Logger
+
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
 const (
@@ -65,8 +68,16 @@ func New(cfg *common.Config) (processors.Processor, error) {
 		config: config,
 		data:   common.NewMapStrPointer(nil),
 		logger: logp.NewLogger("add_host_metadata"),
+		done:   make(chan struct{}),
+	}
+	if config.RefreshPeriod > 0 {
+		if err := p.update(); err != nil {
+			return nil, err
+		}
+		go p.refresh()
+	} else {
+		p.loadData()
 	}
-	p.loadData()
 
 	if config.Geo != nil {
 		geoFields, err := util.GeoConfigToMap(*config.Geo)
@@ -86,9 +97,10 @@ func (p *addHostMetadata) Run(event *beat.Event) (*beat.Event, error) {
 		return event, nil
 	}
 
-	err := p.loadData()
-	if err != nil {
-		return nil, err
+	if p.config.RefreshPeriod <= 0 {
+		if err := p.loadData(); err != nil {
+			return nil, err
+		}
 	}
 
 	event.Fields.DeepUpdate(p.data.Get().Clone())
@@ -114,11 +126,38 @@ func (p *addHostMetadata) expired() bool {
 	return true
 }
 
+// Close stops the background refresh of the metadata.
+func (p *addHostMetadata) Close() error {
+	p.closeOnce.Do(func() { close(p.done) })
+	return nil
+}
+
+// refresh updates the metadata every refresh period until the processor is
+// closed.
+func (p *addHostMetadata) refresh() {
+	ticker := time.NewTicker(p.config.RefreshPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			if err := p.update(); err != nil {
+				p.logger.Warnf("Error refreshing host metadata: %v", err)
+			}
+		}
+	}
+}
+
 func (p *addHostMetadata) loadData() error {
 	if !p.expired() {
 		return nil
 	}
+	return p.update()
+}
 
+func (p *addHostMetadata) update() error {
 	h, err := sysinfo.Host()
 	if err != nil {
 		return err
@@ -140,6 +179,37 @@ func (p *addHostMetadata) loadData() error {
 		}
 	}
 
+	if p.config.NetInterfaces {
+		ifaces, err := util.GetNetInterfaces()
+		if err != nil {
+			p.logger.Infof("Error when getting network interfaces %v", err)
+		}
+		if len(ifaces) > 0 {
+			data.Put("host.network.interfaces", mapInterfaces(ifaces))
+		}
+	}
+
+	if p.config.HardwareEnabled || p.config.VirtualizationEnabled {
+		hw, err := readHardwareInfo()
+		if err != nil {
+			p.logger.Infof("Error when getting hardware information %v", err)
+		}
+		if p.config.HardwareEnabled {
+			putIfNotEmpty(data, "host.hardware.vendor", hw.Vendor)
+			putIfNotEmpty(data, "host.hardware.model", hw.Model)
+			putIfNotEmpty(data, "host.hardware.serial", hw.Serial)
+			putIfNotEmpty(data, "host.hardware.uuid", hw.UUID)
+		}
+		if p.config.VirtualizationEnabled {
+			putIfNotEmpty(data, "host.virtualization.hypervisor", hw.hypervisor())
+			putIfNotEmpty(data, "host.virtualization.cloud", hw.cloud())
+		}
+	}
+
+	if len(p.config.Fields) > 0 {
+		data = filterFields(data, p.config.Fields)
+	}
+
 	if p.config.Name != "" {
 		data.Put("host.name", p.config.Name)
 	}
@@ -148,8 +218,41 @@ func (p *addHostMetadata) loadData() error {
 }
 
 func (p *addHostMetadata) String() string {
-	return fmt.Sprintf("%v=[netinfo.enabled=[%v], cache.ttl=[%v]]",
-		processorName, p.config.NetInfoEnabled, p.config.CacheTTL)
+	return fmt.Sprintf("%v=[netinfo.enabled=[%v], cache.ttl=[%v], refresh_period=[%v]]",
+		processorName, p.config.NetInfoEnabled, p.config.CacheTTL, p.config.RefreshPeriod)
+}
+
+func mapInterfaces(ifaces []util.NetInterface) []common.MapStr {
+	list := make([]common.MapStr, 0, len(ifaces))
+	for _, iface := range ifaces {
+		m := common.MapStr{"name": iface.Name}
+		if iface.MAC != "" {
+			m["mac"] = iface.MAC
+		}
+		if len(iface.IPs) > 0 {
+			m["ip"] = iface.IPs
+		}
+		list = append(list, m)
+	}
+	return list
+}
+
+func putIfNotEmpty(data common.MapStr, key, value string) {
+	if value != "" {
+		data.Put(key, value)
+	}
+}
+
+// filterFields returns a copy of data with only the given fields and their
+// children.
+func filterFields(data common.MapStr, fields []string) common.MapStr {
+	filtered := common.MapStr{}
+	for _, f := range fields {
+		if v, err := data.GetValue(f); err == nil {
+			filtered.Put(f, v)
+		}
+	}
+	return filtered
 }
 
 func skipAddingHostMetadata(event *beat.Event) bool {
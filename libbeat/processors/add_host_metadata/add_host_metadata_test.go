@@ -28,6 +28,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/processors"
 	"github.com/elastic/go-sysinfo/types"
 )
 
@@ -458,3 +459,90 @@ func TestSkipAddingHostMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigNetInterfaces(t *testing.T) {
+	testConfig, err := common.NewConfigFrom(map[string]interface{}{
+		"netinfo.interfaces": true,
+	})
+	require.NoError(t, err)
+
+	p, err := New(testConfig)
+	require.NoError(t, err)
+
+	newEvent, err := p.Run(&beat.Event{Fields: common.MapStr{}, Timestamp: time.Now()})
+	require.NoError(t, err)
+
+	v, err := newEvent.GetValue("host.network.interfaces")
+	require.NoError(t, err)
+	ifaces, ok := v.([]common.MapStr)
+	require.True(t, ok)
+	require.NotEmpty(t, ifaces)
+	for _, iface := range ifaces {
+		assert.NotEmpty(t, iface["name"])
+	}
+}
+
+func TestConfigFields(t *testing.T) {
+	testConfig, err := common.NewConfigFrom(map[string]interface{}{
+		"fields": []string{"host.os.family", "host.hostname", "host.missing"},
+		"name":   "my-host",
+	})
+	require.NoError(t, err)
+
+	p, err := New(testConfig)
+	require.NoError(t, err)
+
+	newEvent, err := p.Run(&beat.Event{Fields: common.MapStr{}, Timestamp: time.Now()})
+	require.NoError(t, err)
+
+	host, err := newEvent.GetValue("host")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"os", "hostname", "name"}, keys(host.(common.MapStr)))
+
+	hostOS, err := newEvent.GetValue("host.os")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"family"}, keys(hostOS.(common.MapStr)))
+}
+
+func TestConfigFieldsInvalid(t *testing.T) {
+	testConfig, err := common.NewConfigFrom(map[string]interface{}{
+		"fields": []string{"agent.name"},
+	})
+	require.NoError(t, err)
+
+	_, err = New(testConfig)
+	assert.Error(t, err)
+}
+
+func TestRefreshPeriod(t *testing.T) {
+	testConfig, err := common.NewConfigFrom(map[string]interface{}{
+		"refresh_period": "10ms",
+	})
+	require.NoError(t, err)
+
+	p, err := New(testConfig)
+	require.NoError(t, err)
+	defer processors.Close(p)
+
+	first := p.(*addHostMetadata).data.Get()
+	assert.Eventually(t, func() bool {
+		// The data is replaced on every refresh.
+		return fmt.Sprintf("%p", p.(*addHostMetadata).data.Get()) != fmt.Sprintf("%p", first)
+	}, time.Second, 10*time.Millisecond)
+
+	newEvent, err := p.Run(&beat.Event{Fields: common.MapStr{}, Timestamp: time.Now()})
+	require.NoError(t, err)
+	v, err := newEvent.GetValue("host.os.family")
+	assert.NoError(t, err)
+	assert.NotNil(t, v)
+
+	assert.NoError(t, processors.Close(p))
+}
+
+func keys(m common.MapStr) []string {
+	var list []string
+	for k := range m {
+		list = append(list, k)
+	}
+	return list
+}
@@ -18,6 +18,8 @@
 package add_host_metadata
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/elastic/beats/v7/libbeat/processors/util"
@@ -25,11 +27,16 @@ import (
 
 // Config for add_host_metadata processor.
 type Config struct {
-	NetInfoEnabled bool            `config:"netinfo.enabled"` // Add IP and MAC to event
-	CacheTTL       time.Duration   `config:"cache.ttl"`
-	Geo            *util.GeoConfig `config:"geo"`
-	Name           string          `config:"name"`
-	ReplaceFields  bool            `config:"replace_fields"` // replace existing host fields with add_host_metadata
+	NetInfoEnabled        bool            `config:"netinfo.enabled"`        // Add IP and MAC to event
+	NetInterfaces         bool            `config:"netinfo.interfaces"`     // Add the addresses of each network interface
+	HardwareEnabled       bool            `config:"hardware.enabled"`       // Add the hardware vendor, model, serial and UUID
+	VirtualizationEnabled bool            `config:"virtualization.enabled"` // Add hypervisor and cloud provider hints
+	CacheTTL              time.Duration   `config:"cache.ttl"`
+	RefreshPeriod         time.Duration   `config:"refresh_period"` // refresh the metadata in the background
+	Fields                []string        `config:"fields"`         // allowlist of the host fields to add
+	Geo                   *util.GeoConfig `config:"geo"`
+	Name                  string          `config:"name"`
+	ReplaceFields         bool            `config:"replace_fields"` // replace existing host fields with add_host_metadata
 }
 
 func defaultConfig() Config {
@@ -39,3 +46,16 @@ func defaultConfig() Config {
 		ReplaceFields:  true,
 	}
 }
+
+// Validate validates the add_host_metadata configuration.
+func (c *Config) Validate() error {
+	if c.RefreshPeriod < 0 {
+		return fmt.Errorf("refresh_period must not be negative")
+	}
+	for _, f := range c.Fields {
+		if f != "host" && !strings.HasPrefix(f, "host.") {
+			return fmt.Errorf("field '%v' is not a host field", f)
+		}
+	}
+	return nil
+}
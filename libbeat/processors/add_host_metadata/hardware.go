@@ -0,0 +1,87 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package add_host_metadata
+
+import "strings"
+
+// hardwareInfo contains the information about the hardware of the host, as
+// reported by the firmware.
+type hardwareInfo struct {
+	Vendor     string
+	Model      string
+	Serial     string
+	UUID       string
+	BIOSVendor string
+	AssetTag   string
+}
+
+// azureAssetTag is the chassis asset tag of the Azure virtual machines.
+const azureAssetTag = "7783-7084-3265-9085-8269-3286-77"
+
+// hypervisor returns the hypervisor the host runs on, or an empty string if
+// the host doesn't seem to be virtualized.
+func (h hardwareInfo) hypervisor() string {
+	vendor := strings.ToLower(h.Vendor)
+	model := strings.ToLower(h.Model)
+	bios := strings.ToLower(h.BIOSVendor)
+	switch {
+	case strings.Contains(vendor, "vmware"):
+		return "vmware"
+	case strings.Contains(model, "virtualbox"):
+		return "virtualbox"
+	case strings.Contains(vendor, "qemu"), strings.Contains(model, "kvm"),
+		strings.Contains(model, "openstack"), strings.Contains(vendor, "digitalocean"),
+		strings.Contains(vendor, "alibaba"), strings.Contains(vendor, "google"):
+		return "kvm"
+	case strings.Contains(vendor, "xen"), strings.Contains(bios, "xen"):
+		return "xen"
+	case strings.Contains(vendor, "amazon ec2"):
+		// Nitro based instances.
+		return "kvm"
+	case strings.Contains(vendor, "microsoft") && strings.Contains(model, "virtual machine"):
+		return "hyperv"
+	case strings.Contains(vendor, "parallels"):
+		return "parallels"
+	}
+	return ""
+}
+
+// cloud returns the cloud provider the host seems to run on, or an empty
+// string if none is detected. The names match the ones used by
+// add_cloud_metadata.
+func (h hardwareInfo) cloud() string {
+	vendor := strings.ToLower(h.Vendor)
+	model := strings.ToLower(h.Model)
+	bios := strings.ToLower(h.BIOSVendor)
+	switch {
+	case strings.Contains(vendor, "amazon"), strings.Contains(bios, "amazon"),
+		strings.HasPrefix(strings.ToLower(h.UUID), "ec2"):
+		return "aws"
+	case strings.Contains(vendor, "google"):
+		return "gcp"
+	case h.AssetTag == azureAssetTag:
+		return "azure"
+	case strings.Contains(vendor, "digitalocean"):
+		return "digitalocean"
+	case strings.Contains(vendor, "alibaba"):
+		return "ecs"
+	case strings.Contains(model, "openstack"):
+		return "openstack"
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package add_host_metadata
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dmiPath is the directory where Linux exposes the DMI information.
+var dmiPath = "/sys/class/dmi/id"
+
+// readHardwareInfo reads the hardware information from the DMI tables. Some
+// of the files, like the serial number, can only be read by root, these are
+// left empty when not readable.
+func readHardwareInfo() (hardwareInfo, error) {
+	if _, err := os.Stat(dmiPath); err != nil {
+		if os.IsNotExist(err) {
+			return hardwareInfo{}, nil
+		}
+		return hardwareInfo{}, err
+	}
+
+	read := func(name string) string {
+		data, err := ioutil.ReadFile(filepath.Join(dmiPath, name))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	return hardwareInfo{
+		Vendor:     read("sys_vendor"),
+		Model:      read("product_name"),
+		Serial:     read("product_serial"),
+		UUID:       strings.ToLower(read("product_uuid")),
+		BIOSVendor: read("bios_vendor"),
+		AssetTag:   read("chassis_asset_tag"),
+	}, nil
+}
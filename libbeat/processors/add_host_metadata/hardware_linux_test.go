@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package add_host_metadata
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadHardwareInfo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dmi")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"sys_vendor":        "QEMU\n",
+		"product_name":      "Standard PC (Q35 + ICH9, 2009)\n",
+		"product_uuid":      "4C4C4544-0042-3510-8052-B7C04F4E4D32\n",
+		"bios_vendor":       "SeaBIOS\n",
+		"chassis_asset_tag": "\n",
+	}
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	defer func(path string) { dmiPath = path }(dmiPath)
+	dmiPath = dir
+
+	info, err := readHardwareInfo()
+	require.NoError(t, err)
+	assert.Equal(t, hardwareInfo{
+		Vendor:     "QEMU",
+		Model:      "Standard PC (Q35 + ICH9, 2009)",
+		UUID:       "4c4c4544-0042-3510-8052-b7c04f4e4d32",
+		BIOSVendor: "SeaBIOS",
+	}, info)
+	assert.Equal(t, "kvm", info.hypervisor())
+
+	dmiPath = filepath.Join(dir, "missing")
+	info, err = readHardwareInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, hardwareInfo{}, info)
+}
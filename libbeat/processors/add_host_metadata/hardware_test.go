@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package add_host_metadata
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHardwareInfoHints(t *testing.T) {
+	cases := []struct {
+		title      string
+		info       hardwareInfo
+		hypervisor string
+		cloud      string
+	}{
+		{
+			"physical",
+			hardwareInfo{Vendor: "Dell Inc.", Model: "PowerEdge R640"},
+			"", "",
+		},
+		{
+			"vmware",
+			hardwareInfo{Vendor: "VMware, Inc.", Model: "VMware Virtual Platform"},
+			"vmware", "",
+		},
+		{
+			"virtualbox",
+			hardwareInfo{Vendor: "innotek GmbH", Model: "VirtualBox"},
+			"virtualbox", "",
+		},
+		{
+			"aws nitro",
+			hardwareInfo{Vendor: "Amazon EC2", Model: "m5.large", BIOSVendor: "Amazon EC2"},
+			"kvm", "aws",
+		},
+		{
+			"aws xen",
+			hardwareInfo{Vendor: "Xen", Model: "HVM domU", UUID: "ec2e1916-9099-7caf-fd21-012345abcdef"},
+			"xen", "aws",
+		},
+		{
+			"gcp",
+			hardwareInfo{Vendor: "Google", Model: "Google Compute Engine"},
+			"kvm", "gcp",
+		},
+		{
+			"azure",
+			hardwareInfo{Vendor: "Microsoft Corporation", Model: "Virtual Machine", AssetTag: azureAssetTag},
+			"hyperv", "azure",
+		},
+		{
+			"hyper-v",
+			hardwareInfo{Vendor: "Microsoft Corporation", Model: "Virtual Machine"},
+			"hyperv", "",
+		},
+		{
+			"openstack",
+			hardwareInfo{Vendor: "OpenStack Foundation", Model: "OpenStack Nova"},
+			"kvm", "openstack",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			assert.Equal(t, c.hypervisor, c.info.hypervisor())
+			assert.Equal(t, c.cloud, c.info.cloud())
+		})
+	}
+}
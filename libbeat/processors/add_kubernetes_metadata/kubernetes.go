@@ -44,6 +44,7 @@ const (
 type kubernetesAnnotator struct {
 	log                 *logp.Logger
 	watcher             kubernetes.Watcher
+	owners              *metadata.OwnerCache
 	indexers            *Indexers
 	matchers            *Matchers
 	cache               *cache
@@ -193,8 +194,14 @@ func (k *kubernetesAnnotator) init(config kubeAnnotatorConfig, cfg *common.Confi
 		if err != nil {
 			k.log.Errorf("couldn't create watcher for %T due to error %+v", &kubernetes.Namespace{}, err)
 		}
+		var owners metadata.OwnerResolver
+		if ownersConfig := metaConf.GetOwnersConfig(); ownersConfig.Enabled {
+			k.owners = metadata.NewOwnerCache(client, config.Namespace, ownersConfig)
+			owners = k.owners
+		}
+
 		// TODO: refactor the above section to a common function to be used by NeWPodEventer too
-		metaGen := metadata.GetPodMetaGen(cfg, watcher, nodeWatcher, namespaceWatcher, metaConf)
+		metaGen := metadata.GetPodMetaGen(cfg, watcher, nodeWatcher, namespaceWatcher, owners, metaConf)
 
 		k.indexers = NewIndexers(config.Indexers, metaGen)
 		k.watcher = watcher
@@ -232,6 +239,9 @@ func (k *kubernetesAnnotator) init(config kubeAnnotatorConfig, cfg *common.Confi
 				return
 			}
 		}
+		if k.owners != nil {
+			k.owners.Start()
+		}
 		if err := watcher.Start(); err != nil {
 			k.log.Debugf("add_kubernetes_metadata", "Couldn't start pod watcher: %v", err)
 			return
@@ -284,6 +294,9 @@ func (k *kubernetesAnnotator) Close() error {
 	if k.watcher != nil {
 		k.watcher.Stop()
 	}
+	if k.owners != nil {
+		k.owners.Stop()
+	}
 	if k.cache != nil {
 		k.cache.stop()
 	}
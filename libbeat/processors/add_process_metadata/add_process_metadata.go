@@ -28,6 +28,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/atomic"
 	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/memgov"
 	"github.com/elastic/beats/v7/libbeat/processors"
 	jsprocessor "github.com/elastic/beats/v7/libbeat/processors/script/javascript/module/processor"
 	"github.com/elastic/gosigar/cgroup"
@@ -61,6 +62,10 @@ type addProcessMetadata struct {
 	cidProvider  cidProvider
 	log          *logp.Logger
 	mappings     common.MapStr
+
+	// unregisterShrinker stops clearing the cgroups cache under memory
+	// pressure.
+	unregisterShrinker func()
 }
 
 type processMetadata struct {
@@ -133,6 +138,8 @@ func newProcessMetadataProcessorWithProvider(cfg *common.Config, provider proces
 
 			p.cgroupsCache = common.NewCacheWithRemovalListener(config.CgroupCacheExpireTime, 100, evictionListener)
 			p.cgroupsCache.StartJanitor(config.CgroupCacheExpireTime)
+			cache := p.cgroupsCache
+			p.unregisterShrinker = memgov.RegisterShrinker(func() { cache.Clear() })
 			p.cidProvider = newCidProvider(config.HostPath, config.CgroupPrefixes, config.CgroupRegex, processCgroupPaths, p.cgroupsCache)
 		} else {
 			p.cidProvider = newCidProvider(config.HostPath, config.CgroupPrefixes, config.CgroupRegex, processCgroupPaths, nil)
@@ -270,6 +277,7 @@ type addProcessMetadataCloser struct {
 
 func (p *addProcessMetadataCloser) Close() error {
 	if p.addProcessMetadata.cgroupsCache != nil {
+		p.addProcessMetadata.unregisterShrinker()
 		p.addProcessMetadata.cgroupsCache.StopJanitor()
 	}
 	return nil
@@ -0,0 +1,480 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package aggregate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/conditions"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+	"github.com/elastic/beats/v7/libbeat/paths"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	jsprocessor "github.com/elastic/beats/v7/libbeat/processors/script/javascript/module/processor"
+)
+
+const logName = "processor.aggregate"
+
+// checkInterval is how often the groups whose window has ended are looked for.
+const checkInterval = time.Second
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID = atomic.MakeUint32(0)
+
+func init() {
+	processors.RegisterPlugin("aggregate", New)
+	jsprocessor.RegisterPlugin("Aggregate", New)
+}
+
+// emitted marks the events published by the processor, so they are passed
+// unchanged when they go through it again.
+type emitted struct{}
+
+type processor struct {
+	config
+	log        *logp.Logger
+	start, end conditions.Condition
+
+	mu       sync.Mutex
+	groups   map[string]*group
+	pending  []beat.Event
+	pipeline beat.PipelineConnector
+
+	// client publishes the emitted events, it is only used by the goroutine
+	// checking the groups, and on Close.
+	client beat.Client
+
+	// store keeps the groups between restarts, it is nil if the state is not
+	// persisted.
+	store *stateStore
+
+	// now returns the current time, it is replaced in tests.
+	now func() time.Time
+
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+
+	stats stats
+}
+
+type stats struct {
+	Groups  *monitoring.Int
+	Emitted *monitoring.Int
+	Skipped *monitoring.Int
+	Lost    *monitoring.Int
+}
+
+// group holds the state of the events with the same values in the group_by
+// fields.
+type group struct {
+	// values are the group_by fields of the events.
+	values common.MapStr
+
+	// created is the time the first event of the group was processed.
+	created time.Time
+
+	// count, first, last and metrics summarize the events in summarize mode.
+	count       int64
+	first, last time.Time
+	metrics     map[string]*metric
+
+	// event is the start event in correlate mode.
+	event *beat.Event
+}
+
+type metric struct {
+	Sum, Min, Max float64
+}
+
+// New returns a new aggregate processor.
+func New(cfg *common.Config) (processors.Processor, error) {
+	c := defaultConfig()
+	if err := cfg.Unpack(&c); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the aggregate configuration")
+	}
+
+	return newFromConfig(c, paths.Resolve(paths.Data, registryPath))
+}
+
+func newFromConfig(c config, registryRoot string) (*processor, error) {
+	id := int(instanceID.Inc())
+	log := logp.NewLogger(logName).With("instance_id", id)
+	metrics := monitoring.Default.NewRegistry(logName + "." + strconv.Itoa(id))
+
+	p := &processor{
+		config: c,
+		log:    log,
+		groups: map[string]*group{},
+		now:    time.Now,
+		done:   make(chan struct{}),
+		stats: stats{
+			Groups:  monitoring.NewInt(metrics, "groups"),
+			Emitted: monitoring.NewInt(metrics, "emitted"),
+			Skipped: monitoring.NewInt(metrics, "skipped"),
+			Lost:    monitoring.NewInt(metrics, "lost"),
+		},
+	}
+
+	if c.Mode == modeCorrelate {
+		var err error
+		if p.start, err = conditions.NewCondition(c.Start); err != nil {
+			return nil, errors.Wrap(err, "failed to initialize the start condition")
+		}
+		if p.end, err = conditions.NewCondition(c.End); err != nil {
+			return nil, errors.Wrap(err, "failed to initialize the end condition")
+		}
+	}
+
+	if c.Persist {
+		store, err := openStateStore(log, registryRoot, c.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.restore(store); err != nil {
+			store.Close()
+			return nil, err
+		}
+		p.store = store
+	}
+
+	p.wg.Add(1)
+	go p.run()
+	return p, nil
+}
+
+func (p *processor) String() string {
+	return fmt.Sprintf("aggregate=[id=%v, mode=%v, group_by=%v, window=%v]", p.ID, p.Mode, p.GroupBy, p.Window)
+}
+
+// SetPipeline sets the pipeline the emitted events are published to. The
+// first pipeline set is used.
+func (p *processor) SetPipeline(pipeline beat.PipelineConnector) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pipeline == nil {
+		p.pipeline = pipeline
+	}
+}
+
+// Run adds the event to its group. Events missing some of the group_by fields,
+// and the events emitted by the processor, are passed unchanged.
+func (p *processor) Run(event *beat.Event) (*beat.Event, error) {
+	if _, ok := event.Private.(emitted); ok {
+		return event, nil
+	}
+
+	key, values, err := p.groupKey(event.Fields)
+	if err != nil {
+		p.stats.Skipped.Inc()
+		p.log.Debugf("Not aggregating event: %v", err)
+		return event, nil
+	}
+
+	if p.Mode == modeCorrelate {
+		return p.correlate(event, key, values), nil
+	}
+	return p.summarize(event, key, values), nil
+}
+
+func (p *processor) groupKey(fields common.MapStr) (string, common.MapStr, error) {
+	var key strings.Builder
+	values := common.MapStr{}
+	for _, k := range p.GroupBy {
+		v, err := fields.GetValue(k)
+		if err != nil {
+			return "", nil, errors.Wrapf(err, "failed to get the field %v", k)
+		}
+
+		switch vv := v.(type) {
+		case map[string]interface{}, []interface{}, common.MapStr:
+			return "", nil, errors.Errorf("the field %v is not a scalar", k)
+		case time.Time:
+			// Ensure times in different locations make the same key.
+			v = vv.UTC()
+		}
+		fmt.Fprintf(&key, "|%v", v)
+		values.Put(k, v)
+	}
+	return key.String(), values, nil
+}
+
+func (p *processor) summarize(event *beat.Event, key string, values common.MapStr) *beat.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	g, found := p.groups[key]
+	if !found {
+		if len(p.groups) >= p.MaxGroups {
+			p.stats.Skipped.Inc()
+			return event
+		}
+		g = &group{values: values, created: p.now(), first: event.Timestamp, metrics: map[string]*metric{}}
+		p.addGroup(key, g)
+	}
+
+	g.count++
+	if event.Timestamp.Before(g.first) {
+		g.first = event.Timestamp
+	}
+	if event.Timestamp.After(g.last) {
+		g.last = event.Timestamp
+	}
+	for _, field := range p.Fields {
+		v, err := event.Fields.GetValue(field)
+		if err != nil {
+			continue
+		}
+		f, ok := toFloat(v)
+		if !ok {
+			continue
+		}
+		if m, found := g.metrics[field]; found {
+			m.Sum += f
+			if f < m.Min {
+				m.Min = f
+			}
+			if f > m.Max {
+				m.Max = f
+			}
+		} else {
+			g.metrics[field] = &metric{Sum: f, Min: f, Max: f}
+		}
+	}
+
+	if p.KeepEvents {
+		return event
+	}
+	return nil
+}
+
+func (p *processor) correlate(event *beat.Event, key string, values common.MapStr) *beat.Event {
+	switch {
+	case p.start.Check(event):
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		// A new start event ends the previous one of the group.
+		if g, found := p.groups[key]; found {
+			p.pending = append(p.pending, p.timedOut(g.event))
+			p.removeGroup(key)
+		} else if len(p.groups) >= p.MaxGroups {
+			p.stats.Skipped.Inc()
+			return event
+		}
+		p.addGroup(key, &group{values: values, created: p.now(), event: event})
+		return nil
+
+	case p.end.Check(event):
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		g, found := p.groups[key]
+		if !found {
+			return event
+		}
+		p.removeGroup(key)
+		return p.merge(g.event, event)
+	}
+	return event
+}
+
+// merge returns the event correlating start and end. The fields of the end
+// event overwrite the fields of the start event.
+func (p *processor) merge(start, end *beat.Event) *beat.Event {
+	start.Fields.DeepUpdate(end.Fields)
+	if end.Meta != nil {
+		if start.Meta == nil {
+			start.Meta = common.MapStr{}
+		}
+		start.Meta.DeepUpdate(end.Meta)
+	}
+	start.Fields.Put(p.TargetField+".start", start.Timestamp)
+	start.Fields.Put(p.TargetField+".end", end.Timestamp)
+	start.Fields.Put(p.TargetField+".duration", end.Timestamp.Sub(start.Timestamp).Nanoseconds())
+
+	// The correlated event replaces the end event in the pipeline.
+	start.Private = end.Private
+	return start
+}
+
+// timedOut returns the start event of a group whose end event was not seen.
+func (p *processor) timedOut(start *beat.Event) beat.Event {
+	start.Fields.Put(p.TargetField+".start", start.Timestamp)
+	start.Fields.Put(p.TargetField+".timed_out", true)
+	start.Private = emitted{}
+	return *start
+}
+
+// summary returns the event summarizing the events of a group.
+func (p *processor) summary(g *group) beat.Event {
+	fields := g.values
+	fields.Put(p.TargetField+".count", g.count)
+	fields.Put(p.TargetField+".start", g.first)
+	fields.Put(p.TargetField+".end", g.last)
+	for field, m := range g.metrics {
+		fields.Put(p.TargetField+"."+field, common.MapStr{
+			"sum": m.Sum,
+			"min": m.Min,
+			"max": m.Max,
+		})
+	}
+	return beat.Event{Timestamp: g.first, Fields: fields, Private: emitted{}}
+}
+
+func (p *processor) emit(g *group) beat.Event {
+	if p.Mode == modeCorrelate {
+		return p.timedOut(g.event)
+	}
+	return p.summary(g)
+}
+
+func (p *processor) addGroup(key string, g *group) {
+	p.groups[key] = g
+	p.stats.Groups.Set(int64(len(p.groups)))
+}
+
+func (p *processor) removeGroup(key string) {
+	delete(p.groups, key)
+	p.stats.Groups.Set(int64(len(p.groups)))
+}
+
+// run publishes the events of the groups when their window ends.
+func (p *processor) run() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.publish(p.expire(p.now(), false))
+		}
+	}
+}
+
+// expire removes the groups whose window has ended at now, or all of them if
+// all is set, and returns the events to emit for them.
+func (p *processor) expire(now time.Time, all bool) []beat.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	events := p.pending
+	p.pending = nil
+	for key, g := range p.groups {
+		if all || now.Sub(g.created) >= p.Window {
+			events = append(events, p.emit(g))
+			p.removeGroup(key)
+		}
+	}
+	return events
+}
+
+func (p *processor) publish(events []beat.Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	if p.client == nil {
+		p.mu.Lock()
+		pipeline := p.pipeline
+		p.mu.Unlock()
+		if pipeline == nil {
+			p.stats.Lost.Add(int64(len(events)))
+			p.log.Warnf("Dropping %d aggregated events, the processor is not connected to a pipeline", len(events))
+			return
+		}
+
+		client, err := pipeline.ConnectWith(beat.ClientConfig{})
+		if err != nil {
+			p.stats.Lost.Add(int64(len(events)))
+			p.log.Errorf("Dropping %d aggregated events, failed to connect to the pipeline: %v", len(events), err)
+			return
+		}
+		p.client = client
+	}
+
+	p.client.PublishAll(events)
+	p.stats.Emitted.Add(int64(len(events)))
+}
+
+// Close stops the processor. The open groups are saved in the registry if the
+// state is persisted, otherwise their events are emitted.
+func (p *processor) Close() error {
+	var err error
+	p.closeOnce.Do(func() {
+		close(p.done)
+		p.wg.Wait()
+
+		p.publish(p.expire(p.now(), p.store == nil))
+		if p.store != nil {
+			err = p.save(p.store)
+			if closeErr := p.store.Close(); err == nil {
+				err = closeErr
+			}
+		}
+
+		if p.client != nil {
+			p.client.Close()
+		}
+	})
+	return err
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case common.Float:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,262 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package aggregate
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/paths"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	pubtest "github.com/elastic/beats/v7/libbeat/publisher/testing"
+)
+
+type testProcessor struct {
+	*processor
+	published []beat.Event
+}
+
+// connect connects the processor to a client collecting the events
+// it publishes.
+func connect(t *testing.T, proc processors.Processor) *testProcessor {
+	p, ok := proc.(*processor)
+	require.True(t, ok)
+
+	tp := &testProcessor{processor: p}
+	p.SetPipeline(pubtest.ConstClient(&pubtest.FakeClient{
+		PublishFunc: func(event beat.Event) { tp.published = append(tp.published, event) },
+	}))
+	return tp
+}
+
+func (p *testProcessor) run(t *testing.T, ts time.Time, fields common.MapStr) *beat.Event {
+	event, err := p.Run(&beat.Event{Timestamp: ts, Fields: fields})
+	require.NoError(t, err)
+	return event
+}
+
+func (p *testProcessor) check(now time.Time) {
+	p.publish(p.expire(now, false))
+}
+
+func TestSummarize(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"id":       "test",
+		"group_by": []string{"host.name"},
+		"fields":   []string{"bytes"},
+		"window":   "1m",
+		"persist":  false,
+	}))
+	require.NoError(t, err)
+	p := connect(t, proc)
+	defer p.Close()
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	ts := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	event := func(host string, bytes interface{}) common.MapStr {
+		return common.MapStr{"host": common.MapStr{"name": host}, "bytes": bytes}
+	}
+	assert.Nil(t, p.run(t, ts, event("a", 10)))
+	assert.Nil(t, p.run(t, ts.Add(time.Second), event("a", 2.5)))
+	assert.Nil(t, p.run(t, ts.Add(2*time.Second), event("a", "not a number")))
+	assert.Nil(t, p.run(t, ts, event("b", uint64(1))))
+
+	missing := common.MapStr{"bytes": 1}
+	assert.Equal(t, missing, p.run(t, ts, missing).Fields)
+	assert.Equal(t, int64(1), p.stats.Skipped.Get())
+
+	p.check(now.Add(30 * time.Second))
+	assert.Empty(t, p.published)
+	assert.Equal(t, int64(2), p.stats.Groups.Get())
+
+	p.check(now.Add(time.Minute))
+	require.Len(t, p.published, 2)
+	var summary beat.Event
+	for _, e := range p.published {
+		if name, _ := e.Fields.GetValue("host.name"); name == "a" {
+			summary = e
+		}
+	}
+	assert.Equal(t, ts, summary.Timestamp)
+	assert.Equal(t, common.MapStr{
+		"host": common.MapStr{"name": "a"},
+		"aggregate": common.MapStr{
+			"count": int64(3),
+			"start": ts,
+			"end":   ts.Add(2 * time.Second),
+			"bytes": common.MapStr{"sum": 12.5, "min": 2.5, "max": 10.0},
+		},
+	}, summary.Fields)
+	assert.Equal(t, int64(0), p.stats.Groups.Get())
+	assert.Equal(t, int64(2), p.stats.Emitted.Get())
+
+	// emitted events are not aggregated again
+	event2, err := p.Run(&summary)
+	require.NoError(t, err)
+	assert.Equal(t, &summary, event2)
+}
+
+func TestSummarizeKeepEventsAndMaxGroups(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"id":          "test",
+		"group_by":    []string{"user"},
+		"keep_events": true,
+		"max_groups":  1,
+		"persist":     false,
+	}))
+	require.NoError(t, err)
+	p := connect(t, proc)
+	defer p.Close()
+
+	assert.NotNil(t, p.run(t, time.Now(), common.MapStr{"user": "a"}))
+	assert.NotNil(t, p.run(t, time.Now(), common.MapStr{"user": "b"}))
+	assert.Equal(t, int64(1), p.stats.Skipped.Get())
+	assert.Equal(t, int64(1), p.stats.Groups.Get())
+}
+
+func TestCorrelate(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"id":       "test",
+		"mode":     "correlate",
+		"group_by": []string{"flow.id"},
+		"window":   "5m",
+		"start":    map[string]interface{}{"equals": map[string]interface{}{"event.action": "open"}},
+		"end":      map[string]interface{}{"equals": map[string]interface{}{"event.action": "close"}},
+		"persist":  false,
+	}))
+	require.NoError(t, err)
+	p := connect(t, proc)
+	defer p.Close()
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	ts := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	event := func(id, action string) common.MapStr {
+		return common.MapStr{"flow": common.MapStr{"id": id}, "event": common.MapStr{"action": action}}
+	}
+
+	assert.Nil(t, p.run(t, ts, common.MapStr{"flow": common.MapStr{"id": "1"}, "event": common.MapStr{"action": "open"}, "source": "a"}))
+	assert.Nil(t, p.run(t, ts, event("2", "open")))
+
+	// events not matching a condition, or without start event, are passed
+	assert.NotNil(t, p.run(t, ts, event("1", "data")))
+	assert.NotNil(t, p.run(t, ts, event("3", "close")))
+
+	correlated := p.run(t, ts.Add(time.Second), event("1", "close"))
+	require.NotNil(t, correlated)
+	assert.Equal(t, ts, correlated.Timestamp)
+	assert.Equal(t, common.MapStr{
+		"flow":   common.MapStr{"id": "1"},
+		"event":  common.MapStr{"action": "close"},
+		"source": "a",
+		"aggregate": common.MapStr{
+			"start":    ts,
+			"end":      ts.Add(time.Second),
+			"duration": time.Second.Nanoseconds(),
+		},
+	}, correlated.Fields)
+
+	p.check(now.Add(5 * time.Minute))
+	require.Len(t, p.published, 1)
+	timedOut := p.published[0].Fields
+	assert.Equal(t, true, timedOut["aggregate"].(common.MapStr)["timed_out"])
+	assert.Equal(t, "open", timedOut["event"].(common.MapStr)["action"])
+}
+
+func TestPersist(t *testing.T) {
+	root, err := ioutil.TempDir("", "aggregate")
+	require.NoError(t, err)
+	defer os.RemoveAll(root)
+	defer func(data string) { paths.Paths.Data = data }(paths.Paths.Data)
+	paths.Paths.Data = root
+
+	settings := map[string]interface{}{
+		"id":       "test",
+		"group_by": []string{"user"},
+		"fields":   []string{"bytes"},
+	}
+	ts := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	proc, err := New(common.MustNewConfigFrom(settings))
+	require.NoError(t, err)
+	p := connect(t, proc)
+	assert.Nil(t, p.run(t, ts, common.MapStr{"user": "a", "bytes": 1}))
+	assert.Nil(t, p.run(t, ts, common.MapStr{"user": "a", "bytes": 2}))
+
+	// a second processor can not use the same id
+	_, err = New(common.MustNewConfigFrom(settings))
+	assert.Error(t, err)
+
+	require.NoError(t, p.Close())
+	assert.Empty(t, p.published)
+
+	proc, err = New(common.MustNewConfigFrom(settings))
+	require.NoError(t, err)
+	p = connect(t, proc)
+	assert.Nil(t, p.run(t, ts, common.MapStr{"user": "a", "bytes": 3}))
+	p.check(time.Now().Add(time.Minute))
+	require.Len(t, p.published, 1)
+	agg := p.published[0].Fields["aggregate"].(common.MapStr)
+	assert.EqualValues(t, 3, agg["count"])
+	assert.EqualValues(t, 6, agg["bytes"].(common.MapStr)["sum"])
+	require.NoError(t, p.Close())
+
+	// the restored groups are removed from the registry
+	proc, err = New(common.MustNewConfigFrom(settings))
+	require.NoError(t, err)
+	p = connect(t, proc)
+	assert.Empty(t, p.groups)
+	require.NoError(t, p.Close())
+}
+
+func TestCloseWithoutPersist(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"id":       "test",
+		"group_by": []string{"user"},
+		"persist":  false,
+	}))
+	require.NoError(t, err)
+	p := connect(t, proc)
+	assert.Nil(t, p.run(t, time.Now(), common.MapStr{"user": "a"}))
+	require.NoError(t, p.Close())
+	assert.Len(t, p.published, 1)
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"missing id":       {"group_by": []string{"a"}},
+		"invalid id":       {"id": "../x", "group_by": []string{"a"}},
+		"invalid mode":     {"id": "x", "group_by": []string{"a"}, "mode": "other"},
+		"correlate no end": {"id": "x", "group_by": []string{"a"}, "mode": "correlate", "start": map[string]interface{}{"has_fields": []string{"a"}}},
+		"summarize start":  {"id": "x", "group_by": []string{"a"}, "start": map[string]interface{}{"has_fields": []string{"a"}}},
+	}
+	for name, settings := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(common.MustNewConfigFrom(settings))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package aggregate
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/conditions"
+)
+
+const (
+	modeSummarize = "summarize"
+	modeCorrelate = "correlate"
+)
+
+type config struct {
+	// ID identifies the state of the processor in the registry. It must be
+	// unique and must not change between restarts.
+	ID string `config:"id" validate:"required"`
+
+	// Mode is either summarize, to emit a summary of the events of each group
+	// at the end of the window, or correlate, to merge the start and end
+	// events of each group.
+	Mode string `config:"mode"`
+
+	// GroupBy are the fields whose values make the key of a group.
+	GroupBy []string `config:"group_by" validate:"required"`
+
+	// Window is how long a group is kept after its first event. In correlate
+	// mode, it is the time to wait for the end event.
+	Window time.Duration `config:"window" validate:"positive,nonzero"`
+
+	// Fields are the numeric fields whose sum, minimum and maximum values are
+	// added to the summaries.
+	Fields []string `config:"fields"`
+
+	// TargetField is where the aggregated values are written in the emitted
+	// events.
+	TargetField string `config:"target_field"`
+
+	// MaxGroups is the maximum number of groups kept. The events of new groups
+	// are passed unchanged when it is reached.
+	MaxGroups int `config:"max_groups" validate:"min=1"`
+
+	// Start and End are the conditions matching the start and end events in
+	// correlate mode.
+	Start *conditions.Config `config:"start"`
+	End   *conditions.Config `config:"end"`
+
+	// KeepEvents passes the aggregated events unchanged in summarize mode,
+	// instead of dropping them.
+	KeepEvents bool `config:"keep_events"`
+
+	// Persist saves the open groups in the registry on shutdown, so they are
+	// restored on the next start.
+	Persist bool `config:"persist"`
+}
+
+func defaultConfig() config {
+	return config{
+		Mode:        modeSummarize,
+		Window:      time.Minute,
+		TargetField: "aggregate",
+		MaxGroups:   10000,
+		Persist:     true,
+	}
+}
+
+var validID = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+func (c *config) Validate() error {
+	if !validID.MatchString(c.ID) {
+		return errors.Errorf("invalid aggregate id '%v' (only letters, digits, '_' and '-' are allowed)", c.ID)
+	}
+
+	switch c.Mode {
+	case modeSummarize:
+		if c.Start != nil || c.End != nil {
+			return errors.New("start and end can only be used in correlate mode")
+		}
+	case modeCorrelate:
+		if c.Start == nil || c.End == nil {
+			return errors.New("start and end are required in correlate mode")
+		}
+		if len(c.Fields) > 0 || c.KeepEvents {
+			return errors.New("fields and keep_events can only be used in summarize mode")
+		}
+	default:
+		return errors.Errorf("invalid aggregate mode '%v' (valid values are: summarize, correlate)", c.Mode)
+	}
+	if c.TargetField == "" {
+		return errors.New("target_field can not be empty")
+	}
+	return nil
+}
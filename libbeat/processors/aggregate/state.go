@@ -0,0 +1,207 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package aggregate
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend/memlog"
+)
+
+// registryPath is the directory of the registry in the data path. Each
+// processor has its own store, named after its id.
+const registryPath = "registry/aggregate"
+
+// stateKey is the key of the groups in the store.
+const stateKey = "groups"
+
+// openIDs are the ids of the processors whose store is open, to prevent two
+// processors from sharing the same state.
+var openIDs = struct {
+	sync.Mutex
+	ids map[string]bool
+}{ids: map[string]bool{}}
+
+type stateStore struct {
+	id       string
+	registry *statestore.Registry
+	store    *statestore.Store
+}
+
+type savedState struct {
+	Groups []savedGroup `struct:"groups"`
+}
+
+// savedGroup is a group as saved in the store. Times are saved as Unix
+// timestamps in nanoseconds.
+type savedGroup struct {
+	Key     string        `struct:"key"`
+	Values  common.MapStr `struct:"values"`
+	Created int64         `struct:"created"`
+	Count   int64         `struct:"count"`
+	First   int64         `struct:"first"`
+	Last    int64         `struct:"last"`
+	Metrics []savedMetric `struct:"metrics"`
+	Event   *savedEvent   `struct:"event,omitempty"`
+}
+
+type savedMetric struct {
+	Field string  `struct:"field"`
+	Sum   float64 `struct:"sum"`
+	Min   float64 `struct:"min"`
+	Max   float64 `struct:"max"`
+}
+
+type savedEvent struct {
+	Timestamp int64         `struct:"timestamp"`
+	Meta      common.MapStr `struct:"meta"`
+	Fields    common.MapStr `struct:"fields"`
+}
+
+func openStateStore(log *logp.Logger, root, id string) (*stateStore, error) {
+	openIDs.Lock()
+	defer openIDs.Unlock()
+	if openIDs.ids[id] {
+		return nil, errors.Errorf("the aggregate id '%v' is already in use", id)
+	}
+
+	backend, err := memlog.New(log, memlog.Settings{
+		Root:     root,
+		FileMode: 0600,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open the aggregate registry")
+	}
+	registry := statestore.NewRegistry(backend)
+	store, err := registry.Get(id)
+	if err != nil {
+		registry.Close()
+		return nil, errors.Wrap(err, "failed to open the aggregate registry")
+	}
+
+	openIDs.ids[id] = true
+	return &stateStore{id: id, registry: registry, store: store}, nil
+}
+
+func (s *stateStore) Close() error {
+	openIDs.Lock()
+	delete(openIDs.ids, s.id)
+	openIDs.Unlock()
+
+	err := s.store.Close()
+	if regErr := s.registry.Close(); err == nil {
+		err = regErr
+	}
+	return err
+}
+
+// restore loads the groups saved in the store. They are removed from the
+// store, so they are not restored twice if the Beat does not stop cleanly.
+func (p *processor) restore(s *stateStore) error {
+	found, err := s.store.Has(stateKey)
+	if err != nil || !found {
+		return err
+	}
+
+	var st savedState
+	if err := s.store.Get(stateKey, &st); err != nil {
+		return errors.Wrap(err, "failed to read the aggregate state")
+	}
+	if err := s.store.Remove(stateKey); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, sg := range st.Groups {
+		if len(p.groups) >= p.MaxGroups {
+			break
+		}
+
+		g := &group{
+			values:  sg.Values,
+			created: fromUnixNano(sg.Created),
+			count:   sg.Count,
+			first:   fromUnixNano(sg.First),
+			last:    fromUnixNano(sg.Last),
+			metrics: make(map[string]*metric, len(sg.Metrics)),
+		}
+		for _, m := range sg.Metrics {
+			g.metrics[m.Field] = &metric{Sum: m.Sum, Min: m.Min, Max: m.Max}
+		}
+		if sg.Event != nil {
+			g.event = &beat.Event{
+				Timestamp: fromUnixNano(sg.Event.Timestamp),
+				Meta:      sg.Event.Meta,
+				Fields:    sg.Event.Fields,
+			}
+		}
+
+		// Groups of the other mode are lost if the configuration changed.
+		if (p.Mode == modeCorrelate) != (g.event != nil) {
+			continue
+		}
+		p.addGroup(sg.Key, g)
+	}
+	p.log.Debugf("Restored %d groups from the registry", len(p.groups))
+	return nil
+}
+
+// save writes the open groups in the store.
+func (p *processor) save(s *stateStore) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	st := savedState{Groups: make([]savedGroup, 0, len(p.groups))}
+	for key, g := range p.groups {
+		sg := savedGroup{
+			Key:     key,
+			Values:  g.values,
+			Created: g.created.UnixNano(),
+			Count:   g.count,
+			First:   g.first.UnixNano(),
+			Last:    g.last.UnixNano(),
+		}
+		for field, m := range g.metrics {
+			sg.Metrics = append(sg.Metrics, savedMetric{Field: field, Sum: m.Sum, Min: m.Min, Max: m.Max})
+		}
+		if g.event != nil {
+			sg.Event = &savedEvent{
+				Timestamp: g.event.Timestamp.UnixNano(),
+				Meta:      g.event.Meta,
+				Fields:    g.event.Fields,
+			}
+		}
+		st.Groups = append(st.Groups, sg)
+	}
+	if err := s.store.Set(stateKey, st); err != nil {
+		return errors.Wrap(err, "failed to save the aggregate state")
+	}
+	return nil
+}
+
+func fromUnixNano(ns int64) time.Time {
+	return time.Unix(0, ns).UTC()
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/joeshaw/multierror"
 	"github.com/pkg/errors"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
@@ -86,6 +87,11 @@ func (r *WhenProcessor) Run(event *beat.Event) (*beat.Event, error) {
 	return r.p.Run(event)
 }
 
+// Close closes the processor executed by this WhenProcessor.
+func (r *WhenProcessor) Close() error {
+	return Close(r.p)
+}
+
 func (r *WhenProcessor) String() string {
 	return fmt.Sprintf("%v, condition=%v", r.p.String(), r.condition.String())
 }
@@ -111,17 +117,32 @@ func addCondition(
 }
 
 type ifThenElseConfig struct {
+	Cond   conditions.Config `config:"if"      validate:"required"`
+	Then   *common.Config    `config:"then"    validate:"required"`
+	ElseIf []ifThenConfig    `config:"else_if"`
+	Else   *common.Config    `config:"else"`
+}
+
+type ifThenConfig struct {
 	Cond conditions.Config `config:"if"   validate:"required"`
 	Then *common.Config    `config:"then" validate:"required"`
-	Else *common.Config    `config:"else"`
 }
 
 // IfThenElseProcessor executes one set of processors (then) if the condition is
-// true and another set of processors (else) if the condition is false.
+// true and another set of processors (else) if the condition is false. The
+// conditions of the else if branches are checked in order before falling
+// back to else, the processors of the first matching branch are executed.
 type IfThenElseProcessor struct {
+	cond   conditions.Condition
+	then   *Processors
+	elseIf []ifThenBranch
+	els    *Processors
+}
+
+// ifThenBranch is an else if branch of an IfThenElseProcessor.
+type ifThenBranch struct {
 	cond conditions.Condition
 	then *Processors
-	els  *Processors
 }
 
 // NewIfElseThenProcessor construct a new IfThenElseProcessor.
@@ -155,30 +176,74 @@ func NewIfElseThenProcessor(cfg *common.Config) (*IfThenElseProcessor, error) {
 	if ifProcessors, err = newProcessors(config.Then); err != nil {
 		return nil, err
 	}
+
+	elseIf := make([]ifThenBranch, len(config.ElseIf))
+	for i, branchConfig := range config.ElseIf {
+		branch := &elseIf[i]
+		if branch.cond, err = conditions.NewCondition(&branchConfig.Cond); err != nil {
+			return nil, errors.Wrapf(err, "failed to initialize else_if condition %d", i)
+		}
+		if branch.then, err = newProcessors(branchConfig.Then); err != nil {
+			return nil, errors.Wrapf(err, "failed to make else_if processors %d", i)
+		}
+	}
+
 	if elseProcessors, err = newProcessors(config.Else); err != nil {
 		return nil, err
 	}
 
-	return &IfThenElseProcessor{cond, ifProcessors, elseProcessors}, nil
+	return &IfThenElseProcessor{cond, ifProcessors, elseIf, elseProcessors}, nil
 }
 
 // Run checks the if condition and executes the processors attached to the
-// then statement or the else statement based on the condition.
+// then statement or the else statement based on the condition. If the
+// condition is false, the else if conditions are checked first.
 func (p *IfThenElseProcessor) Run(event *beat.Event) (*beat.Event, error) {
 	if p.cond.Check(event) {
 		return p.then.Run(event)
-	} else if p.els != nil {
+	}
+	for _, branch := range p.elseIf {
+		if branch.cond.Check(event) {
+			return branch.then.Run(event)
+		}
+	}
+	if p.els != nil {
 		return p.els.Run(event)
 	}
 	return event, nil
 }
 
+// Close closes the processors of all branches.
+func (p *IfThenElseProcessor) Close() error {
+	var errs multierror.Errors
+	if err := p.then.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	for _, branch := range p.elseIf {
+		if err := branch.then.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if p.els != nil {
+		if err := p.els.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs.Err()
+}
+
 func (p *IfThenElseProcessor) String() string {
 	var sb strings.Builder
 	sb.WriteString("if ")
 	sb.WriteString(p.cond.String())
 	sb.WriteString(" then ")
 	sb.WriteString(p.then.String())
+	for _, branch := range p.elseIf {
+		sb.WriteString(" else if ")
+		sb.WriteString(branch.cond.String())
+		sb.WriteString(" then ")
+		sb.WriteString(branch.then.String())
+	}
 	if p.els != nil {
 		sb.WriteString(" else ")
 		sb.WriteString(p.els.String())
@@ -197,6 +197,24 @@ func TestIfElseThenProcessor(t *testing.T) {
       add_fields: {target: "", fields: {uid_type: "gt_500"}}
 `
 
+	const ifThenElseIfChain = `
+- if:
+    range.uid.lt: 500
+  then:
+    - add_fields: {target: "", fields: {uid_type: reserved}}
+  else_if:
+    - if:
+        equals.uid: 500
+      then:
+        add_fields: {target: "", fields: {uid_type: "eq_500"}}
+    - if:
+        range.uid.lt: 1000
+      then:
+        - add_fields: {target: "", fields: {uid_type: "lt_1000"}}
+  else:
+    add_fields: {target: "", fields: {uid_type: "gte_1000"}}
+`
+
 	testProcessors(t, map[string]testCase{
 		"if-then-true": {
 			event: common.MapStr{"uid": 411},
@@ -228,5 +246,25 @@ func TestIfElseThenProcessor(t *testing.T) {
 			want:  common.MapStr{"uid": 500, "uid_type": "eq_500"},
 			cfg:   ifThenElseIf,
 		},
+		"if-else-if-chain-then": {
+			event: common.MapStr{"uid": 411},
+			want:  common.MapStr{"uid": 411, "uid_type": "reserved"},
+			cfg:   ifThenElseIfChain,
+		},
+		"if-else-if-chain-first-match": {
+			event: common.MapStr{"uid": 500},
+			want:  common.MapStr{"uid": 500, "uid_type": "eq_500"},
+			cfg:   ifThenElseIfChain,
+		},
+		"if-else-if-chain-second-match": {
+			event: common.MapStr{"uid": 501},
+			want:  common.MapStr{"uid": 501, "uid_type": "lt_1000"},
+			cfg:   ifThenElseIfChain,
+		},
+		"if-else-if-chain-else": {
+			event: common.MapStr{"uid": 1000},
+			want:  common.MapStr{"uid": 1000, "uid_type": "gte_1000"},
+			cfg:   ifThenElseIfChain,
+		},
 	})
 }
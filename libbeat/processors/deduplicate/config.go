@@ -0,0 +1,44 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package deduplicate
+
+import "time"
+
+type config struct {
+	// Fields are the fields whose values make the fingerprint of an event.
+	Fields []string `config:"fields" validate:"required"`
+
+	// Window is how long after an event its duplicates are dropped.
+	Window time.Duration `config:"window" validate:"positive,nonzero"`
+
+	// MaxEntries is the maximum number of fingerprints that are remembered.
+	// The least recently seen fingerprint is forgotten when it is reached.
+	MaxEntries int `config:"max_entries" validate:"min=1"`
+
+	// IgnoreMissing computes the fingerprint of events missing some of the
+	// fields from the fields present. If false, these events are not
+	// deduplicated.
+	IgnoreMissing bool `config:"ignore_missing"`
+}
+
+func defaultConfig() config {
+	return config{
+		Window:     time.Minute,
+		MaxEntries: 100000,
+	}
+}
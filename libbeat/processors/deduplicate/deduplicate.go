@@ -0,0 +1,150 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package deduplicate
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	jsprocessor "github.com/elastic/beats/v7/libbeat/processors/script/javascript/module/processor"
+	"github.com/elastic/beats/v7/libbeat/processors/util"
+)
+
+const logName = "processor.deduplicate"
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID = atomic.MakeUint32(0)
+
+func init() {
+	processors.RegisterPlugin("deduplicate", New)
+	jsprocessor.RegisterPlugin("Deduplicate", New)
+}
+
+// fingerprint identifies the events with the same values in the configured
+// fields.
+type fingerprint [16]byte
+
+type processor struct {
+	config
+	fields []string
+	log    *logp.Logger
+
+	// seen maps the fingerprints of the kept events to the time they were
+	// processed.
+	mu   sync.Mutex
+	seen *lru.Cache
+
+	// now returns the current time, it is replaced in tests.
+	now func() time.Time
+
+	stats stats
+}
+
+type stats struct {
+	Processed *monitoring.Int
+	Dropped   *monitoring.Int
+	Skipped   *monitoring.Int
+}
+
+// New returns a new deduplicate processor.
+func New(cfg *common.Config) (processors.Processor, error) {
+	c := defaultConfig()
+	if err := cfg.Unpack(&c); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the deduplicate configuration")
+	}
+
+	return newFromConfig(c)
+}
+
+func newFromConfig(c config) (*processor, error) {
+	seen, err := lru.New(c.MaxEntries)
+	if err != nil {
+		return nil, err
+	}
+
+	id := int(instanceID.Inc())
+	metrics := monitoring.Default.NewRegistry(logName + "." + strconv.Itoa(id))
+
+	return &processor{
+		config: c,
+		// The fields are sorted, so the order of the configuration does not
+		// change the fingerprints.
+		fields: common.MakeStringSet(c.Fields...).ToSlice(),
+		log:    logp.NewLogger(logName).With("instance_id", id),
+		seen:   seen,
+		now:    time.Now,
+		stats: stats{
+			Processed: monitoring.NewInt(metrics, "processed"),
+			Dropped:   monitoring.NewInt(metrics, "dropped"),
+			Skipped:   monitoring.NewInt(metrics, "skipped"),
+		},
+	}, nil
+}
+
+func (p *processor) String() string {
+	return fmt.Sprintf("deduplicate=[fields=%v, window=%v, max_entries=%v]", p.fields, p.Window, p.MaxEntries)
+}
+
+// Run drops the event if an event with the same fingerprint was kept within
+// the window. Events whose fingerprint can not be computed are kept.
+func (p *processor) Run(event *beat.Event) (*beat.Event, error) {
+	p.stats.Processed.Inc()
+
+	fp, err := p.fingerprint(event.Fields)
+	if err != nil {
+		p.stats.Skipped.Inc()
+		p.log.Debugf("Not deduplicating event: %v", err)
+		return event, nil
+	}
+
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The time of an expired fingerprint is replaced, so the window starts
+	// again with the current event.
+	if v, found := p.seen.Get(fp); found && now.Sub(v.(time.Time)) < p.Window {
+		p.stats.Dropped.Inc()
+		return nil, nil
+	}
+	p.seen.Add(fp, now)
+	return event, nil
+}
+
+func (p *processor) fingerprint(fields common.MapStr) (fingerprint, error) {
+	h := fnv.New128a()
+	if err := util.WriteKey(h, fields, p.fields, p.IgnoreMissing); err != nil {
+		return fingerprint{}, err
+	}
+
+	var fp fingerprint
+	copy(fp[:], h.Sum(nil))
+	return fp, nil
+}
@@ -0,0 +1,147 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package deduplicate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func kept(t *testing.T, p *processor, fields common.MapStr) bool {
+	event, err := p.Run(&beat.Event{Fields: fields})
+	require.NoError(t, err)
+	return event != nil
+}
+
+func TestDeduplicateWindow(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"fields": []string{"message", "host.name"},
+		"window": "1m",
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	event := func(message, host string) common.MapStr {
+		return common.MapStr{"message": message, "host": common.MapStr{"name": host}, "offset": now.UnixNano()}
+	}
+
+	assert.True(t, kept(t, p, event("hello", "a")))
+	assert.True(t, kept(t, p, event("hello", "b")))
+	assert.True(t, kept(t, p, event("bye", "a")))
+
+	now = now.Add(30 * time.Second)
+	assert.False(t, kept(t, p, event("hello", "a")))
+
+	// duplicates do not extend the window
+	now = now.Add(31 * time.Second)
+	assert.True(t, kept(t, p, event("hello", "a")))
+	assert.False(t, kept(t, p, event("hello", "a")))
+
+	assert.Equal(t, int64(6), p.stats.Processed.Get())
+	assert.Equal(t, int64(2), p.stats.Dropped.Get())
+}
+
+func TestDeduplicateFieldOrder(t *testing.T) {
+	p1, err := New(common.MustNewConfigFrom(map[string]interface{}{"fields": []string{"a", "b"}}))
+	require.NoError(t, err)
+	p2, err := New(common.MustNewConfigFrom(map[string]interface{}{"fields": []string{"b", "a"}}))
+	require.NoError(t, err)
+
+	fields := common.MapStr{"a": 1, "b": "x"}
+	fp1, err := p1.(*processor).fingerprint(fields)
+	require.NoError(t, err)
+	fp2, err := p2.(*processor).fingerprint(fields)
+	require.NoError(t, err)
+	assert.Equal(t, fp1, fp2)
+
+	// the same values in other fields make another fingerprint
+	fp3, err := p1.(*processor).fingerprint(common.MapStr{"a": "x", "b": 1})
+	require.NoError(t, err)
+	assert.NotEqual(t, fp1, fp3)
+}
+
+func TestDeduplicateMissingFields(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"fields": []string{"message", "host.name"},
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+
+	fields := common.MapStr{"message": "hello"}
+	assert.True(t, kept(t, p, fields))
+	assert.True(t, kept(t, p, fields))
+	assert.Equal(t, int64(2), p.stats.Skipped.Get())
+
+	// non scalar values are not deduplicated either
+	fields = common.MapStr{"message": "hello", "host": common.MapStr{"name": []interface{}{"a"}}}
+	assert.True(t, kept(t, p, fields))
+	assert.True(t, kept(t, p, fields))
+
+	proc, err = New(common.MustNewConfigFrom(map[string]interface{}{
+		"fields":         []string{"message", "host.name"},
+		"ignore_missing": true,
+	}))
+	require.NoError(t, err)
+	p = proc.(*processor)
+	fields = common.MapStr{"message": "hello"}
+	assert.True(t, kept(t, p, fields))
+	assert.False(t, kept(t, p, fields))
+
+	// a value containing the other field does not make its fingerprint
+	assert.True(t, kept(t, p, common.MapStr{"host": common.MapStr{"name": "a|message|bye"}}))
+	assert.True(t, kept(t, p, common.MapStr{"message": "bye", "host": common.MapStr{"name": "a"}}))
+}
+
+func TestDeduplicateMaxEntries(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"fields":      []string{"message"},
+		"max_entries": 2,
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+
+	assert.True(t, kept(t, p, common.MapStr{"message": "a"}))
+	assert.True(t, kept(t, p, common.MapStr{"message": "b"}))
+	assert.False(t, kept(t, p, common.MapStr{"message": "a"}))
+	assert.True(t, kept(t, p, common.MapStr{"message": "c"}))
+
+	// b was the least recently seen fingerprint
+	assert.True(t, kept(t, p, common.MapStr{"message": "b"}))
+	assert.Equal(t, 2, p.seen.Len())
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, settings := range map[string]map[string]interface{}{
+		"missing fields": {},
+		"empty window":   {"fields": []string{"message"}, "window": 0},
+		"no entries":     {"fields": []string{"message"}, "max_entries": 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(common.MustNewConfigFrom(settings))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package geoip
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+)
+
+type config struct {
+	// Field holds the IP address to look up.
+	Field string `config:"field" validate:"required"`
+
+	// TargetField is where the result of the lookup is written. It defaults
+	// to the geo or as field next to Field.
+	TargetField string `config:"target_field"`
+
+	// DatabaseFile is the MaxMind database, either a City, Country or ASN
+	// database.
+	DatabaseFile string `config:"database_file" validate:"required"`
+
+	// Language of the names of the places.
+	Language string `config:"language" validate:"required"`
+
+	// ReloadPeriod is how often the database file is checked for changes, 0
+	// disables the reload.
+	ReloadPeriod time.Duration `config:"reload.period" validate:"min=0"`
+
+	Download downloadConfig `config:"download"`
+
+	IgnoreMissing bool     `config:"ignore_missing"`
+	TagOnFailure  []string `config:"tag_on_failure"`
+}
+
+type downloadConfig struct {
+	// URL the database is downloaded from, the download is disabled if it is
+	// empty. The database can be compressed with gzip, and be in a tar
+	// archive.
+	URL string `config:"url"`
+
+	// Interval between the downloads.
+	Interval time.Duration `config:"interval" validate:"positive,nonzero"`
+
+	Timeout time.Duration     `config:"timeout" validate:"positive,nonzero"`
+	Headers map[string]string `config:"headers"`
+	TLS     *tlscommon.Config `config:"ssl"`
+}
+
+func defaultConfig() config {
+	return config{
+		Language:     "en",
+		ReloadPeriod: time.Minute,
+		Download: downloadConfig{
+			Interval: 24 * time.Hour,
+			Timeout:  5 * time.Minute,
+		},
+		TagOnFailure: []string{"_geoip_lookup_failure"},
+	}
+}
+
+func (c *config) Validate() error {
+	if c.Download.URL != "" && c.ReloadPeriod == 0 {
+		return errors.New("the database is never reloaded after a download if reload.period is 0")
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package geoip
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+type databaseKind int
+
+const (
+	// kindGeo is the kind of the City and Country databases.
+	kindGeo databaseKind = iota
+
+	// kindASN is the kind of the ASN databases.
+	kindASN
+)
+
+// database is a loaded MaxMind database. The file is read in memory, instead
+// of being mapped, so a database can be replaced while lookups are still
+// running on the previous one.
+type database struct {
+	reader *maxminddb.Reader
+	kind   databaseKind
+}
+
+type names map[string]string
+
+type geoRecord struct {
+	City struct {
+		Names names `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Continent struct {
+		Names names `maxminddb:"names"`
+	} `maxminddb:"continent"`
+	Country struct {
+		IsoCode string `maxminddb:"iso_code"`
+		Names   names  `maxminddb:"names"`
+	} `maxminddb:"country"`
+	Location struct {
+		Latitude  *float64 `maxminddb:"latitude"`
+		Longitude *float64 `maxminddb:"longitude"`
+		TimeZone  string   `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+	Postal struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"postal"`
+	Subdivisions []struct {
+		IsoCode string `maxminddb:"iso_code"`
+		Names   names  `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+}
+
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+func loadDatabase(path string) (*database, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newDatabase(data)
+}
+
+func newDatabase(data []byte) (*database, error) {
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid MaxMind database")
+	}
+
+	dbType := reader.Metadata.DatabaseType
+	switch {
+	case strings.Contains(dbType, "ASN"):
+		return &database{reader: reader, kind: kindASN}, nil
+	case strings.Contains(dbType, "City"), strings.Contains(dbType, "Country"):
+		return &database{reader: reader, kind: kindGeo}, nil
+	default:
+		return nil, errors.Errorf("unsupported MaxMind database type %v, only the City, Country and ASN databases are supported", dbType)
+	}
+}
+
+// lookup returns the fields to add for ip, or nil if the ip is not in the
+// database.
+func (db *database) lookup(ip net.IP, language string) (common.MapStr, error) {
+	switch db.kind {
+	case kindASN:
+		var rec asnRecord
+		if _, found, err := db.reader.LookupNetwork(ip, &rec); err != nil || !found {
+			return nil, err
+		}
+		return asnFields(&rec), nil
+	default:
+		var rec geoRecord
+		if _, found, err := db.reader.LookupNetwork(ip, &rec); err != nil || !found {
+			return nil, err
+		}
+		return geoFields(&rec, language), nil
+	}
+}
+
+// geoFields returns the ECS geo fields of a record.
+func geoFields(rec *geoRecord, language string) common.MapStr {
+	fields := common.MapStr{}
+	put := func(key, value string) {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+
+	put("continent_name", rec.Continent.Names[language])
+	put("country_iso_code", rec.Country.IsoCode)
+	put("country_name", rec.Country.Names[language])
+	if len(rec.Subdivisions) > 0 {
+		region := rec.Subdivisions[0]
+		if region.IsoCode != "" && rec.Country.IsoCode != "" {
+			put("region_iso_code", rec.Country.IsoCode+"-"+region.IsoCode)
+		}
+		put("region_name", region.Names[language])
+	}
+	put("city_name", rec.City.Names[language])
+	put("postal_code", rec.Postal.Code)
+	put("timezone", rec.Location.TimeZone)
+	if rec.Location.Latitude != nil && rec.Location.Longitude != nil {
+		fields["location"] = common.MapStr{
+			"lat": *rec.Location.Latitude,
+			"lon": *rec.Location.Longitude,
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
+
+// asnFields returns the ECS as fields of a record.
+func asnFields(rec *asnRecord) common.MapStr {
+	if rec.Number == 0 {
+		return nil
+	}
+	fields := common.MapStr{"number": rec.Number}
+	if rec.Organization != "" {
+		fields["organization"] = common.MapStr{"name": rec.Organization}
+	}
+	return fields
+}
+
+// databaseFile loads a database from a file, and loads it again when the file
+// changes.
+//
+// The file is not watched in the background. Instead a check is done lazily
+// when the database is used, but not more often than once per period.
+type databaseFile struct {
+	path   string
+	period time.Duration
+	log    *logp.Logger
+	now    func() time.Time
+
+	mu        sync.Mutex
+	lastCheck time.Time
+	stamp     fileStamp
+	db        *database
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func newDatabaseFile(path string, period time.Duration, log *logp.Logger) (*databaseFile, error) {
+	f := &databaseFile{
+		path:   path,
+		period: period,
+		log:    log,
+		now:    time.Now,
+	}
+
+	stamp, err := statFile(path)
+	if err != nil {
+		return nil, err
+	}
+	db, err := loadDatabase(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load the database file %v", path)
+	}
+	f.stamp = stamp
+	f.db = db
+	f.lastCheck = f.now()
+	return f, nil
+}
+
+// get returns the current database, loading the file again if it changed.
+// If the changed file can not be loaded, the previous database is kept and
+// the reload is retried on the next check.
+func (f *databaseFile) get() *database {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.period <= 0 {
+		return f.db
+	}
+	now := f.now()
+	if now.Sub(f.lastCheck) < f.period {
+		return f.db
+	}
+	f.lastCheck = now
+
+	stamp, err := statFile(f.path)
+	if err != nil {
+		f.log.Errorf("Failed to check the database file for changes, keeping the previous database: %v", err)
+		return f.db
+	}
+	if stamp == f.stamp {
+		return f.db
+	}
+
+	db, err := loadDatabase(f.path)
+	if err != nil {
+		f.log.Errorf("Failed to reload the database file, keeping the previous database: %v", err)
+		return f.db
+	}
+	if db.kind != f.db.kind {
+		f.log.Errorf("Failed to reload the database file, keeping the previous database: the type of the database changed to %v", db.reader.Metadata.DatabaseType)
+		return f.db
+	}
+	f.log.Infof("Reloaded the database file %v", f.path)
+	f.stamp = stamp
+	f.db = db
+	return f.db
+}
+
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
@@ -0,0 +1,259 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package geoip
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/common/transport"
+	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+)
+
+// maxDownloadSize limits the size of the downloaded databases, before and
+// after decompression.
+const maxDownloadSize = 512 * 1024 * 1024
+
+// downloader periodically downloads the database to the database file. The
+// database file itself is reloaded by the databaseFile that uses it.
+type downloader struct {
+	downloadConfig
+	path   string
+	client *http.Client
+	log    *logp.Logger
+
+	downloads *monitoring.Int
+	failures  *monitoring.Int
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newDownloader(c downloadConfig, path string, metrics *monitoring.Registry, log *logp.Logger) (*downloader, error) {
+	tls, err := tlscommon.LoadTLSConfig(c.TLS)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid geoip download TLS configuration")
+	}
+
+	dialer := transport.NetDialer(c.Timeout)
+	tlsDialer, err := transport.TLSDialer(dialer, tls, c.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &downloader{
+		downloadConfig: c,
+		path:           path,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Dial:            dialer.Dial,
+				DialTLS:         tlsDialer.Dial,
+				TLSClientConfig: tls.ToConfig(),
+				Proxy:           http.ProxyFromEnvironment,
+			},
+			Timeout: c.Timeout,
+		},
+		log:       log,
+		downloads: monitoring.NewInt(metrics, "downloads"),
+		failures:  monitoring.NewInt(metrics, "download_failures"),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+// start downloads the database every interval until stop is called.
+func (d *downloader) start() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+
+		ticker := time.NewTicker(d.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.done:
+				return
+			case <-ticker.C:
+				if err := d.download(); err != nil {
+					d.log.Errorf("Failed to download the database, keeping the previous database: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (d *downloader) stop() {
+	close(d.done)
+	d.wg.Wait()
+	d.client.CloseIdleConnections()
+}
+
+// download downloads the database if it changed since the database file was
+// written. The new database is validated before replacing the file.
+func (d *downloader) download() error {
+	err := d.fetch()
+	if err != nil {
+		d.failures.Inc()
+	}
+	return err
+}
+
+func (d *downloader) fetch() error {
+	req, err := http.NewRequest(http.MethodGet, d.URL, nil)
+	if err != nil {
+		return err
+	}
+	for name, value := range d.Headers {
+		req.Header.Set(name, value)
+	}
+	if info, err := os.Stat(d.path); err == nil {
+		req.Header.Set("If-Modified-Since", info.ModTime().UTC().Format(http.TimeFormat))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		d.log.Debug("The database did not change since the last download")
+		return nil
+	default:
+		return errors.Errorf("unexpected response status %v", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return errors.Wrap(err, "failed to read the response")
+	}
+	if len(body) > maxDownloadSize {
+		return errors.Errorf("the database is larger than %v bytes", maxDownloadSize)
+	}
+
+	data, err := extractDatabase(body)
+	if err != nil {
+		return err
+	}
+	db, err := newDatabase(data)
+	if err != nil {
+		return err
+	}
+	if err := db.reader.Verify(); err != nil {
+		return errors.Wrap(err, "invalid MaxMind database")
+	}
+
+	modTime := time.Now()
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		modTime = lastModified
+	}
+	if err := writeFile(d.path, data, modTime); err != nil {
+		return errors.Wrap(err, "failed to write the database file")
+	}
+
+	d.downloads.Inc()
+	d.log.Infof("Downloaded the database %v (%v)", d.path, db.reader.Metadata.DatabaseType)
+	return nil
+}
+
+// extractDatabase returns the database in data, decompressing it if it is
+// compressed with gzip and extracting the first .mmdb file if it is a tar
+// archive.
+func extractDatabase(data []byte) ([]byte, error) {
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decompress the database")
+		}
+		data, err = ioutil.ReadAll(io.LimitReader(zr, maxDownloadSize+1))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decompress the database")
+		}
+		if len(data) > maxDownloadSize {
+			return nil, errors.Errorf("the decompressed database is larger than %v bytes", maxDownloadSize)
+		}
+	}
+
+	if !isTar(data) {
+		return data, nil
+	}
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("no .mmdb file found in the archive")
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the archive")
+		}
+		if hdr.Typeflag == tar.TypeReg && strings.HasSuffix(hdr.Name, ".mmdb") {
+			return ioutil.ReadAll(tr)
+		}
+	}
+}
+
+func isTar(data []byte) bool {
+	const magicOffset = 257
+	return len(data) > magicOffset+5 && string(data[magicOffset:magicOffset+5]) == "ustar"
+}
+
+// writeFile replaces the file at path with data. The data is written to a
+// temporary file in the same directory that is then renamed, so the file
+// is never partially written.
+func writeFile(path string, data []byte, modTime time.Time) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chtimes(tmp.Name(), modTime, modTime); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,177 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+	"github.com/elastic/beats/v7/libbeat/paths"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	jsprocessor "github.com/elastic/beats/v7/libbeat/processors/script/javascript/module/processor"
+)
+
+const logName = "processor.geoip"
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID = atomic.MakeUint32(0)
+
+func init() {
+	processors.RegisterPlugin("geoip", New)
+	jsprocessor.RegisterPlugin("GeoIP", New)
+}
+
+type processor struct {
+	config
+	file       *databaseFile
+	downloader *downloader
+	log        *logp.Logger
+
+	found    *monitoring.Int
+	notFound *monitoring.Int
+}
+
+// New returns a new geoip processor adding the location or the autonomous
+// system of an IP address from a MaxMind database.
+func New(cfg *common.Config) (processors.Processor, error) {
+	c := defaultConfig()
+	if err := cfg.Unpack(&c); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the geoip configuration")
+	}
+
+	return newFromConfig(c)
+}
+
+func newFromConfig(c config) (*processor, error) {
+	id := int(instanceID.Inc())
+	log := logp.NewLogger(logName).With("instance_id", id)
+	metrics := monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+
+	p := &processor{
+		config:   c,
+		log:      log,
+		found:    monitoring.NewInt(metrics, "found"),
+		notFound: monitoring.NewInt(metrics, "not_found"),
+	}
+
+	path := paths.Resolve(paths.Config, c.DatabaseFile)
+	if c.Download.URL != "" {
+		d, err := newDownloader(c.Download, path, metrics, log)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if err := d.download(); err != nil {
+				return nil, errors.Wrap(err, "failed to download the geoip database")
+			}
+		}
+		p.downloader = d
+	}
+
+	file, err := newDatabaseFile(path, c.ReloadPeriod, log)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load the geoip database")
+	}
+	p.file = file
+
+	if p.TargetField == "" {
+		p.TargetField = defaultTargetField(c.Field, file.db.kind)
+	}
+
+	if p.downloader != nil {
+		p.downloader.start()
+	}
+	return p, nil
+}
+
+// defaultTargetField returns the ECS field next to field for the results of
+// the kind of database, e.g. source.geo for source.ip.
+func defaultTargetField(field string, kind databaseKind) string {
+	name := "geo"
+	if kind == kindASN {
+		name = "as"
+	}
+	if strings.HasSuffix(field, ".ip") {
+		return strings.TrimSuffix(field, "ip") + name
+	}
+	return name
+}
+
+func (p *processor) String() string {
+	return fmt.Sprintf("geoip=[field=%v, target_field=%v, database_file=%v]",
+		p.Field, p.TargetField, p.DatabaseFile)
+}
+
+func (p *processor) Run(event *beat.Event) (*beat.Event, error) {
+	err := p.lookup(event)
+	if err == nil || (p.IgnoreMissing && errors.Cause(err) == common.ErrKeyNotFound) {
+		return event, nil
+	}
+
+	common.AddTags(event.Fields, p.TagOnFailure)
+	return event, err
+}
+
+// Close stops the download of the database.
+func (p *processor) Close() error {
+	if p.downloader != nil {
+		p.downloader.stop()
+	}
+	return nil
+}
+
+func (p *processor) lookup(event *beat.Event) error {
+	v, err := event.GetValue(p.Field)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the ip field %v", p.Field)
+	}
+
+	var ip net.IP
+	switch v := v.(type) {
+	case string:
+		ip = net.ParseIP(v)
+	case net.IP:
+		ip = v
+	}
+	if ip == nil {
+		return errors.Errorf("field %v is not a valid ip address: %v", p.Field, v)
+	}
+
+	fields, err := p.file.get().lookup(ip, p.Language)
+	if err != nil {
+		return errors.Wrapf(err, "failed to look up %v", ip)
+	}
+	if fields == nil {
+		p.notFound.Inc()
+		return nil
+	}
+
+	p.found.Inc()
+	_, err = event.PutValue(p.TargetField, fields)
+	return err
+}
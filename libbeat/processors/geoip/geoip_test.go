@@ -0,0 +1,521 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package geoip
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/processors"
+)
+
+func run(t *testing.T, p *processor, fields common.MapStr) (common.MapStr, error) {
+	event, err := p.Run(&beat.Event{Fields: fields})
+	require.NotNil(t, event)
+	return event.Fields, err
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "geoip")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func cityDatabase(city string) []byte {
+	return buildDatabase("GeoIP2-City", map[string]interface{}{
+		"81.2.69.0/24": map[string]interface{}{
+			"city":      map[string]interface{}{"names": map[string]interface{}{"en": city, "de": city + " (de)"}},
+			"continent": map[string]interface{}{"code": "EU", "names": map[string]interface{}{"en": "Europe"}},
+			"country":   map[string]interface{}{"iso_code": "GB", "names": map[string]interface{}{"en": "United Kingdom"}},
+			"location": map[string]interface{}{
+				"latitude":  51.5142,
+				"longitude": -0.0931,
+				"time_zone": "Europe/London",
+			},
+			"postal": map[string]interface{}{"code": "EC2V"},
+			"subdivisions": []interface{}{
+				map[string]interface{}{"iso_code": "ENG", "names": map[string]interface{}{"en": "England"}},
+			},
+		},
+	})
+}
+
+func asnDatabase() []byte {
+	return buildDatabase("GeoLite2-ASN", map[string]interface{}{
+		"8.8.8.0/24": map[string]interface{}{
+			"autonomous_system_number":       uint32(15169),
+			"autonomous_system_organization": "Google LLC",
+		},
+	})
+}
+
+func writeDatabase(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	return path
+}
+
+func TestGeoIPCity(t *testing.T) {
+	path := writeDatabase(t, tempDir(t), "city.mmdb", cityDatabase("London"))
+
+	t.Run("found", func(t *testing.T) {
+		proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":         "source.ip",
+			"database_file": path,
+		}))
+		require.NoError(t, err)
+		defer processors.Close(proc)
+		p := proc.(*processor)
+		fields, err := run(t, p, common.MapStr{"source": common.MapStr{"ip": "81.2.69.142"}})
+		require.NoError(t, err)
+		assert.Equal(t, common.MapStr{
+			"ip": "81.2.69.142",
+			"geo": common.MapStr{
+				"continent_name":   "Europe",
+				"country_iso_code": "GB",
+				"country_name":     "United Kingdom",
+				"region_iso_code":  "GB-ENG",
+				"region_name":      "England",
+				"city_name":        "London",
+				"postal_code":      "EC2V",
+				"timezone":         "Europe/London",
+				"location":         common.MapStr{"lat": 51.5142, "lon": -0.0931},
+			},
+		}, fields["source"])
+		assert.EqualValues(t, 1, p.found.Get())
+	})
+
+	t.Run("language and target field", func(t *testing.T) {
+		proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":         "client_ip",
+			"target_field":  "client_geo",
+			"database_file": path,
+			"language":      "de",
+		}))
+		require.NoError(t, err)
+		defer processors.Close(proc)
+		p := proc.(*processor)
+		fields, err := run(t, p, common.MapStr{"client_ip": "81.2.69.1"})
+		require.NoError(t, err)
+		geo := fields["client_geo"].(common.MapStr)
+		assert.Equal(t, "London (de)", geo["city_name"])
+		assert.NotContains(t, geo, "country_name")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":         "source.ip",
+			"database_file": path,
+		}))
+		require.NoError(t, err)
+		defer processors.Close(proc)
+		p := proc.(*processor)
+		fields, err := run(t, p, common.MapStr{"source": common.MapStr{"ip": "10.0.0.1"}})
+		require.NoError(t, err)
+		assert.Equal(t, common.MapStr{"source": common.MapStr{"ip": "10.0.0.1"}}, fields)
+		assert.EqualValues(t, 1, p.notFound.Get())
+	})
+
+	t.Run("invalid ip", func(t *testing.T) {
+		proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":         "source.ip",
+			"database_file": path,
+		}))
+		require.NoError(t, err)
+		defer processors.Close(proc)
+		p := proc.(*processor)
+		fields, err := run(t, p, common.MapStr{"source": common.MapStr{"ip": "not an ip"}})
+		assert.Error(t, err)
+		assert.Equal(t, []string{"_geoip_lookup_failure"}, fields["tags"])
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":         "source.ip",
+			"database_file": path,
+		}))
+		require.NoError(t, err)
+		defer processors.Close(proc)
+		p := proc.(*processor)
+		fields, err := run(t, p, common.MapStr{})
+		assert.Error(t, err)
+		assert.Equal(t, []string{"_geoip_lookup_failure"}, fields["tags"])
+
+		proc, err = New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":          "source.ip",
+			"database_file":  path,
+			"ignore_missing": true,
+		}))
+		require.NoError(t, err)
+		defer processors.Close(proc)
+		p = proc.(*processor)
+		fields, err = run(t, p, common.MapStr{})
+		assert.NoError(t, err)
+		assert.Equal(t, common.MapStr{}, fields)
+	})
+}
+
+func TestGeoIPASN(t *testing.T) {
+	path := writeDatabase(t, tempDir(t), "asn.mmdb", asnDatabase())
+
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"field":         "destination.ip",
+		"database_file": path,
+	}))
+	require.NoError(t, err)
+	defer processors.Close(proc)
+	p := proc.(*processor)
+	fields, err := run(t, p, common.MapStr{"destination": common.MapStr{"ip": "8.8.8.8"}})
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{
+		"number":       uint(15169),
+		"organization": common.MapStr{"name": "Google LLC"},
+	}, fields["destination"].(common.MapStr)["as"])
+}
+
+func TestGeoIPInvalidDatabase(t *testing.T) {
+	dir := tempDir(t)
+	for name, data := range map[string][]byte{
+		"garbage":     []byte("not a database"),
+		"unsupported": buildDatabase("GeoIP2-Anonymous-IP", nil),
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := defaultConfig()
+			c.Field = "source.ip"
+			c.DatabaseFile = writeDatabase(t, dir, name+".mmdb", data)
+			_, err := newFromConfig(c)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGeoIPReload(t *testing.T) {
+	path := writeDatabase(t, tempDir(t), "city.mmdb", cityDatabase("London"))
+
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"field":         "ip",
+		"database_file": path,
+		"reload.period": "1m",
+	}))
+	require.NoError(t, err)
+	defer processors.Close(proc)
+	p := proc.(*processor)
+	now := time.Now()
+	p.file.now = func() time.Time { return now }
+
+	city := func() interface{} {
+		fields, err := run(t, p, common.MapStr{"ip": "81.2.69.142"})
+		require.NoError(t, err)
+		return fields["geo"].(common.MapStr)["city_name"]
+	}
+	assert.Equal(t, "London", city())
+
+	writeDatabase(t, filepath.Dir(path), "city.mmdb", cityDatabase("Londinium"))
+	ts := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(path, ts, ts))
+
+	// the file is not checked before the period elapsed
+	assert.Equal(t, "London", city())
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, "Londinium", city())
+
+	// invalid changes keep the previous database
+	for _, data := range [][]byte{[]byte("garbage"), asnDatabase()} {
+		writeDatabase(t, filepath.Dir(path), "city.mmdb", data)
+		ts := time.Now().Add(time.Hour)
+		require.NoError(t, os.Chtimes(path, ts, ts))
+		now = now.Add(time.Minute)
+		assert.Equal(t, "Londinium", city())
+	}
+}
+
+func TestGeoIPDownload(t *testing.T) {
+	var (
+		body     []byte
+		modified = time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+		requests int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "secret", r.Header.Get("X-License-Key"))
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	body = targz(t, "GeoLite2-City_20200601/GeoLite2-City.mmdb", cityDatabase("London"))
+	path := filepath.Join(tempDir(t), "city.mmdb")
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"field":         "ip",
+		"database_file": path,
+		"download": map[string]interface{}{
+			"url":     server.URL,
+			"headers": map[string]interface{}{"X-License-Key": "secret"},
+		},
+	}))
+	require.NoError(t, err)
+	defer processors.Close(proc)
+	p := proc.(*processor)
+
+	// the missing database is downloaded when the processor is created
+	fields, err := run(t, p, common.MapStr{"ip": "81.2.69.142"})
+	require.NoError(t, err)
+	assert.Equal(t, "London", fields["geo"].(common.MapStr)["city_name"])
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.True(t, modified.Equal(info.ModTime()))
+
+	// unchanged databases are not downloaded again
+	require.NoError(t, p.downloader.download())
+	assert.Equal(t, 2, requests)
+	assert.EqualValues(t, 1, p.downloader.downloads.Get())
+
+	// invalid databases do not replace the file
+	modified = modified.Add(24 * time.Hour)
+	body = []byte("garbage")
+	assert.Error(t, p.downloader.download())
+	assert.EqualValues(t, 1, p.downloader.failures.Get())
+	_, err = loadDatabase(path)
+	assert.NoError(t, err)
+
+	body = cityDatabase("Londinium")
+	require.NoError(t, p.downloader.download())
+	assert.EqualValues(t, 2, p.downloader.downloads.Get())
+	db, err := loadDatabase(path)
+	require.NoError(t, err)
+	rec, err := db.lookup(net.ParseIP("81.2.69.142"), "en")
+	require.NoError(t, err)
+	assert.Equal(t, "Londinium", rec["city_name"])
+}
+
+func TestGeoIPDownloadFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	c := defaultConfig()
+	c.Field = "ip"
+	c.DatabaseFile = filepath.Join(tempDir(t), "city.mmdb")
+	c.Download.URL = server.URL
+	_, err := newFromConfig(c)
+	assert.Error(t, err)
+}
+
+func TestDefaultTargetField(t *testing.T) {
+	assert.Equal(t, "source.geo", defaultTargetField("source.ip", kindGeo))
+	assert.Equal(t, "client.nat.as", defaultTargetField("client.nat.ip", kindASN))
+	assert.Equal(t, "geo", defaultTargetField("remote_addr", kindGeo))
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, settings := range map[string]map[string]interface{}{
+		"missing field":         {"database_file": "city.mmdb"},
+		"missing database_file": {"field": "source.ip"},
+		"download without reload": {
+			"field":         "source.ip",
+			"database_file": "city.mmdb",
+			"reload.period": 0,
+			"download.url":  "https://example.com/city.tar.gz",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(common.MustNewConfigFrom(settings))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func targz(t *testing.T, name string, data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "LICENSE.txt", Mode: 0644, Size: 3, Typeflag: tar.TypeReg}))
+	_, err := tw.Write([]byte("ISC"))
+	require.NoError(t, err)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+	_, err = tw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+// buildDatabase returns an IPv4 MaxMind database with 24 bit records holding
+// the records of the networks.
+func buildDatabase(dbType string, networks map[string]interface{}) []byte {
+	const empty = -1
+	type node [2]int
+
+	// Records of the tree are either the index of the next node, empty, or
+	// the offset of the data encoded as -2-offset until all nodes are known.
+	nodes := []node{{empty, empty}}
+	var data []byte
+
+	cidrs := make([]string, 0, len(networks))
+	for cidr := range networks {
+		cidrs = append(cidrs, cidr)
+	}
+	sort.Strings(cidrs)
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		ones, _ := network.Mask.Size()
+		ip := binary.BigEndian.Uint32(network.IP.To4())
+
+		offset := len(data)
+		data = append(data, encodeData(networks[cidr])...)
+
+		n := 0
+		for depth := 0; depth < ones; depth++ {
+			bit := (ip >> uint(31-depth)) & 1
+			if depth == ones-1 {
+				nodes[n][bit] = -2 - offset
+				break
+			}
+			if nodes[n][bit] == empty {
+				nodes = append(nodes, node{empty, empty})
+				nodes[n][bit] = len(nodes) - 1
+			}
+			n = nodes[n][bit]
+		}
+	}
+
+	var db []byte
+	nodeCount := len(nodes)
+	for _, n := range nodes {
+		for _, record := range n {
+			var v int
+			switch {
+			case record == empty:
+				v = nodeCount
+			case record < 0:
+				v = nodeCount + 16 + (-2 - record)
+			default:
+				v = record
+			}
+			db = append(db, byte(v>>16), byte(v>>8), byte(v))
+		}
+	}
+	db = append(db, make([]byte, 16)...)
+	db = append(db, data...)
+
+	db = append(db, "\xAB\xCD\xEFMaxMind.com"...)
+	db = append(db, encodeData(map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1590969600),
+		"database_type":               dbType,
+		"description":                 map[string]interface{}{"en": "Test database"},
+		"ip_version":                  uint16(4),
+		"languages":                   []interface{}{"en", "de"},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	})...)
+	return db
+}
+
+// encodeData encodes v in the MaxMind DB data section format.
+func encodeData(v interface{}) []byte {
+	uintBytes := func(v uint64) []byte {
+		var b []byte
+		for ; v > 0; v >>= 8 {
+			b = append([]byte{byte(v)}, b...)
+		}
+		return b
+	}
+
+	// control returns the control byte of a type, followed by the extended
+	// type and size bytes if required.
+	control := func(typ, size int) []byte {
+		var b []byte
+		if size < 29 {
+			b = []byte{byte(size)}
+		} else {
+			b = []byte{29, byte(size - 29)}
+		}
+		if typ > 7 {
+			return append([]byte{b[0], byte(typ - 7)}, b[1:]...)
+		}
+		b[0] |= byte(typ << 5)
+		return b
+	}
+
+	switch v := v.(type) {
+	case string:
+		return append(control(2, len(v)), v...)
+	case float64:
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, math.Float64bits(v))
+		return append(control(3, 8), b...)
+	case uint16:
+		b := uintBytes(uint64(v))
+		return append(control(5, len(b)), b...)
+	case uint32:
+		b := uintBytes(uint64(v))
+		return append(control(6, len(b)), b...)
+	case uint64:
+		b := uintBytes(v)
+		return append(control(9, len(b)), b...)
+	case []interface{}:
+		b := control(11, len(v))
+		for _, e := range v {
+			b = append(b, encodeData(e)...)
+		}
+		return b
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b := control(7, len(keys))
+		for _, k := range keys {
+			b = append(b, encodeData(k)...)
+			b = append(b, encodeData(v[k])...)
+		}
+		return b
+	default:
+		panic("unsupported type")
+	}
+}
@@ -0,0 +1,90 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http_lookup
+
+import (
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+)
+
+// lookupResult is the outcome of a request to the endpoint. Fields is nil if
+// the endpoint did not know the requested key.
+type lookupResult struct {
+	fields  common.MapStr
+	err     error
+	expires time.Time
+}
+
+// lookupCache keeps the results of the most recently used requests, keyed by
+// their URL and body.
+type lookupCache struct {
+	results    *lru.Cache
+	ttl        time.Duration
+	failureTTL time.Duration
+	stats      cacheStats
+}
+
+type cacheStats struct {
+	Hit  *monitoring.Int
+	Miss *monitoring.Int
+}
+
+func newLookupCache(reg *monitoring.Registry, config cacheConfig) (*lookupCache, error) {
+	results, err := lru.New(config.Size)
+	if err != nil {
+		return nil, err
+	}
+	return &lookupCache{
+		results:    results,
+		ttl:        config.TTL,
+		failureTTL: config.FailureTTL,
+		stats: cacheStats{
+			Hit:  monitoring.NewInt(reg, "hits"),
+			Miss: monitoring.NewInt(reg, "misses"),
+		},
+	}, nil
+}
+
+// get returns the cached result for key, if it did not expire yet.
+func (c *lookupCache) get(now time.Time, key string) (lookupResult, bool) {
+	v, found := c.results.Get(key)
+	if found {
+		r := v.(lookupResult)
+		if now.Before(r.expires) {
+			c.stats.Hit.Inc()
+			return r, true
+		}
+		c.results.Remove(key)
+	}
+	c.stats.Miss.Inc()
+	return lookupResult{}, false
+}
+
+// set caches the result for key. Not found results and errors expire after
+// the failure TTL.
+func (c *lookupCache) set(now time.Time, key string, fields common.MapStr, err error) {
+	ttl := c.ttl
+	if fields == nil || err != nil {
+		ttl = c.failureTTL
+	}
+	c.results.Add(key, lookupResult{fields: fields, err: err, expires: now.Add(ttl)})
+}
@@ -0,0 +1,112 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http_lookup
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+)
+
+type config struct {
+	// URL of the endpoint, it can reference event fields, e.g.
+	// https://cmdb/hosts/%{[host.name]}. The values are not escaped.
+	URL *fmtstr.EventFormatString `config:"url" validate:"required"`
+
+	// Method is GET or POST. POST requests send the params as form values.
+	Method string `config:"method"`
+
+	// Params maps query parameters to the event fields holding their values.
+	Params map[string]string `config:"params"`
+
+	Headers map[string]string `config:"headers"`
+
+	// Fields maps fields of the JSON response to the event fields they are
+	// copied to.
+	Fields common.MapStr `config:"fields" validate:"required"`
+
+	OverwriteKeys bool     `config:"overwrite_keys"`
+	IgnoreMissing bool     `config:"ignore_missing"`
+	IgnoreFailure bool     `config:"ignore_failure"`
+	TagOnFailure  []string `config:"tag_on_failure"`
+
+	Timeout time.Duration     `config:"timeout" validate:"positive,nonzero"`
+	TLS     *tlscommon.Config `config:"ssl"`
+	Cache   cacheConfig       `config:"cache"`
+
+	fieldsFlat map[string]string
+}
+
+type cacheConfig struct {
+	// Size is the maximum number of responses kept in the cache. The least
+	// recently used response is evicted when the cache is full.
+	Size int `config:"size" validate:"min=1"`
+
+	// TTL is how long a response is used before the endpoint is queried
+	// again.
+	TTL time.Duration `config:"ttl" validate:"positive,nonzero"`
+
+	// FailureTTL is how long not found lookups and errors are cached.
+	FailureTTL time.Duration `config:"failure_ttl" validate:"positive,nonzero"`
+}
+
+func (c *config) Validate() error {
+	c.Method = strings.ToUpper(c.Method)
+	switch c.Method {
+	case http.MethodGet, http.MethodPost:
+	default:
+		return errors.Errorf("invalid http_lookup method '%v' (valid values are: GET, POST)", c.Method)
+	}
+
+	c.fieldsFlat = map[string]string{}
+	for from, v := range c.Fields.Flatten() {
+		target, ok := v.(string)
+		if !ok {
+			return errors.Errorf("target field for the response field %v must be a string but got %T", from, v)
+		}
+		c.fieldsFlat[from] = target
+	}
+	if len(c.fieldsFlat) == 0 {
+		return errors.New("at least one response field must be configured in fields")
+	}
+	return nil
+}
+
+func (c config) String() string {
+	return fmt.Sprintf("method=%v, params=%v, fields=%v, cache.size=%v, cache.ttl=%v, cache.failure_ttl=%v",
+		c.Method, c.Params, c.fieldsFlat, c.Cache.Size, c.Cache.TTL, c.Cache.FailureTTL)
+}
+
+func defaultConfig() config {
+	return config{
+		Method:  http.MethodGet,
+		Timeout: 5 * time.Second,
+		Cache: cacheConfig{
+			Size:       10000,
+			TTL:        10 * time.Minute,
+			FailureTTL: time.Minute,
+		},
+	}
+}
@@ -0,0 +1,25 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package http_lookup provides a Beat processor enriching events with the
+// fields of a JSON document fetched from a REST endpoint, e.g. the owner or
+// location of a host from a CMDB.
+//
+// The responses are kept in an LRU cache, failed and not found lookups are
+// cached too, with a shorter TTL, so unknown keys or an unavailable endpoint
+// do not add the request latency to every event.
+package http_lookup
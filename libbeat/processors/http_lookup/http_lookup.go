@@ -0,0 +1,264 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http_lookup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/common/jsontransform"
+	"github.com/elastic/beats/v7/libbeat/common/transport"
+	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	jsprocessor "github.com/elastic/beats/v7/libbeat/processors/script/javascript/module/processor"
+)
+
+const (
+	logName = "processor.http_lookup"
+
+	// maxResponseSize limits the size of the documents returned by the
+	// endpoint.
+	maxResponseSize = 10 * 1024 * 1024
+)
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID = atomic.MakeUint32(0)
+
+func init() {
+	processors.RegisterPlugin("http_lookup", New)
+	jsprocessor.RegisterPlugin("HTTPLookup", New)
+}
+
+type processor struct {
+	config
+	client *http.Client
+	cache  *lookupCache
+	log    *logp.Logger
+
+	// now returns the current time, it is replaced in tests.
+	now func() time.Time
+}
+
+// New returns a new http_lookup processor enriching events with the response
+// of an HTTP endpoint.
+func New(cfg *common.Config) (processors.Processor, error) {
+	c := defaultConfig()
+	if err := cfg.Unpack(&c); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the http_lookup configuration")
+	}
+
+	return newFromConfig(c)
+}
+
+func newFromConfig(c config) (*processor, error) {
+	id := int(instanceID.Inc())
+	log := logp.NewLogger(logName).With("instance_id", id)
+	metrics := monitoring.Default.NewRegistry(logName+"."+strconv.Itoa(id), monitoring.DoNotReport)
+
+	tls, err := tlscommon.LoadTLSConfig(c.TLS)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid http_lookup TLS configuration")
+	}
+
+	dialer := transport.NetDialer(c.Timeout)
+	tlsDialer, err := transport.TLSDialer(dialer, tls, c.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := newLookupCache(metrics.NewRegistry("cache"), c.Cache)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("http_lookup processor config: %v", c)
+	return &processor{
+		config: c,
+		client: &http.Client{
+			Transport: &http.Transport{
+				Dial:            dialer.Dial,
+				DialTLS:         tlsDialer.Dial,
+				TLSClientConfig: tls.ToConfig(),
+				Proxy:           http.ProxyFromEnvironment,
+			},
+			Timeout: c.Timeout,
+		},
+		cache: cache,
+		log:   log,
+		now:   time.Now,
+	}, nil
+}
+
+func (p *processor) String() string {
+	return fmt.Sprintf("http_lookup=[%v]", p.config)
+}
+
+func (p *processor) Run(event *beat.Event) (*beat.Event, error) {
+	err := p.lookup(event)
+	if err == nil || (p.IgnoreMissing && errors.Cause(err) == common.ErrKeyNotFound) {
+		return event, nil
+	}
+
+	common.AddTags(event.Fields, p.TagOnFailure)
+	if p.IgnoreFailure {
+		p.log.Debugf("http_lookup processor failed: %v", err)
+		return event, nil
+	}
+	return event, err
+}
+
+// Close closes the idle connections to the endpoint.
+func (p *processor) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
+
+func (p *processor) lookup(event *beat.Event) error {
+	req, key, err := p.newRequest(event)
+	if err != nil {
+		return err
+	}
+
+	now := p.now()
+	result, found := p.cache.get(now, key)
+	if !found {
+		result.fields, result.err = p.fetch(req)
+		p.cache.set(now, key, result.fields, result.err)
+	} else if result.err != nil {
+		result.err = errors.Wrap(result.err, "cached lookup failure")
+	}
+	if result.err != nil {
+		return result.err
+	}
+
+	for from, target := range p.fieldsFlat {
+		v, err := result.fields.GetValue(from)
+		if err != nil {
+			continue
+		}
+		if !p.OverwriteKeys {
+			if _, err := event.GetValue(target); err == nil {
+				return errors.Errorf("target field %v already exists", target)
+			}
+		}
+		if _, err := event.PutValue(target, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newRequest returns the request for the event, and the key of its response
+// in the cache. An error wrapping common.ErrKeyNotFound is returned if a field
+// referenced in the URL or the params is missing.
+func (p *processor) newRequest(event *beat.Event) (*http.Request, string, error) {
+	for _, field := range p.URL.Fields() {
+		if _, err := event.GetValue(field); err != nil {
+			return nil, "", errors.Wrapf(err, "failed to get the url field %v", field)
+		}
+	}
+	rawURL, err := p.URL.Run(event)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "failed to format the url")
+	}
+
+	params := url.Values{}
+	for name, field := range p.Params {
+		v, err := event.GetValue(field)
+		if err != nil {
+			return nil, "", errors.Wrapf(err, "failed to get the %v param field %v", name, field)
+		}
+		params.Set(name, fmt.Sprint(v))
+	}
+
+	var req *http.Request
+	key := rawURL
+	if p.Method == http.MethodPost {
+		body := params.Encode()
+		key += "\n" + body
+		req, err = http.NewRequest(http.MethodPost, rawURL, strings.NewReader(body))
+		if err == nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	} else {
+		req, err = http.NewRequest(http.MethodGet, rawURL, nil)
+		if err == nil && len(params) > 0 {
+			query := req.URL.Query()
+			for name := range params {
+				query.Set(name, params.Get(name))
+			}
+			req.URL.RawQuery = query.Encode()
+			key = req.URL.String()
+		}
+	}
+	if err != nil {
+		return nil, "", errors.Wrap(err, "invalid lookup request")
+	}
+
+	req.Header.Set("Accept", "application/json")
+	for name, value := range p.Headers {
+		req.Header.Set(name, value)
+	}
+	return req, key, nil
+}
+
+// fetch sends the request and decodes the returned JSON object. Nil fields
+// are returned without an error if the endpoint responded with 404.
+func (p *processor) fetch(req *http.Request) (common.MapStr, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "lookup request failed")
+	}
+	defer func() {
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxResponseSize))
+		resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, errors.Errorf("lookup request to %v failed with status %v", req.URL.Host, resp.Status)
+	}
+
+	var fields common.MapStr
+	dec := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		return nil, errors.Wrap(err, "failed to decode the lookup response")
+	}
+	if fields == nil {
+		fields = common.MapStr{}
+	}
+	jsontransform.TransformNumbers(fields)
+	return fields, nil
+}
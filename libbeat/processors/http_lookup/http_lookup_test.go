@@ -0,0 +1,276 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package http_lookup
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+)
+
+var hosts = map[string]common.MapStr{
+	"web-1": {"owner": "team-a", "location": common.MapStr{"site": "ams", "rack": 12}},
+	"web-2": {"owner": "team-b"},
+}
+
+// startCMDB starts a server returning the hosts by name, either from the last
+// path element or from the name param.
+func startCMDB(t *testing.T, requests *atomic.Int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Inc()
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		name := r.FormValue("name")
+		if name == "" {
+			name = r.URL.Path[len("/hosts/"):]
+		}
+		host, found := hosts[name]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(host)
+	}))
+}
+
+// hostFields maps the fields of the CMDB hosts to the fields of the events.
+var hostFields = map[string]string{
+	"owner":         "host.owner",
+	"location.site": "host.site",
+	"location.rack": "host.rack",
+}
+
+func hostEvent(name string) *beat.Event {
+	return &beat.Event{Fields: common.MapStr{"host": common.MapStr{"name": name}}}
+}
+
+func TestLookup(t *testing.T) {
+	var requests atomic.Int
+	server := startCMDB(t, &requests)
+	defer server.Close()
+
+	t.Run("url field", func(t *testing.T) {
+		proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"url":     server.URL + "/hosts/%{[host.name]}",
+			"headers": map[string]string{"X-Token": "secret"},
+			"fields":  hostFields,
+		}))
+		require.NoError(t, err)
+		p := proc.(*processor)
+		defer p.Close()
+
+		event, err := p.Run(hostEvent("web-1"))
+		require.NoError(t, err)
+		assert.Equal(t, common.MapStr{
+			"name":  "web-1",
+			"owner": "team-a",
+			"site":  "ams",
+			"rack":  int64(12),
+		}, event.Fields["host"])
+	})
+
+	for _, method := range []string{"GET", "POST"} {
+		t.Run(method+" params", func(t *testing.T) {
+			proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+				"url":     server.URL + "/hosts",
+				"method":  method,
+				"params":  map[string]string{"name": "host.name"},
+				"headers": map[string]string{"X-Token": "secret"},
+				"fields":  hostFields,
+			}))
+			require.NoError(t, err)
+			p := proc.(*processor)
+			defer p.Close()
+
+			event, err := p.Run(hostEvent("web-2"))
+			require.NoError(t, err)
+			assert.Equal(t, common.MapStr{"name": "web-2", "owner": "team-b"}, event.Fields["host"])
+		})
+	}
+}
+
+func TestLookupCache(t *testing.T) {
+	var requests atomic.Int
+	server := startCMDB(t, &requests)
+	defer server.Close()
+
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"url":               server.URL + "/hosts/%{[host.name]}",
+		"cache.ttl":         "10m",
+		"cache.failure_ttl": "1m",
+		"headers":           map[string]string{"X-Token": "secret"},
+		"fields":            hostFields,
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+	defer p.Close()
+
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		event, err := p.Run(hostEvent("web-1"))
+		require.NoError(t, err)
+		assert.Equal(t, "team-a", event.Fields["host"].(common.MapStr)["owner"])
+
+		// not found hosts are cached too
+		event, err = p.Run(hostEvent("unknown"))
+		require.NoError(t, err)
+		assert.Equal(t, common.MapStr{"name": "unknown"}, event.Fields["host"])
+	}
+	assert.Equal(t, 2, requests.Load())
+	assert.Equal(t, int64(4), p.cache.stats.Hit.Get())
+	assert.Equal(t, int64(2), p.cache.stats.Miss.Get())
+
+	// the not found result expires first
+	now = now.Add(2 * time.Minute)
+	p.Run(hostEvent("web-1"))
+	p.Run(hostEvent("unknown"))
+	assert.Equal(t, 3, requests.Load())
+
+	now = now.Add(10 * time.Minute)
+	p.Run(hostEvent("web-1"))
+	assert.Equal(t, 4, requests.Load())
+}
+
+func TestLookupCacheSize(t *testing.T) {
+	var requests atomic.Int
+	server := startCMDB(t, &requests)
+	defer server.Close()
+
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"url":        server.URL + "/hosts/%{[host.name]}",
+		"cache.size": 1,
+		"headers":    map[string]string{"X-Token": "secret"},
+		"fields":     hostFields,
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+	defer p.Close()
+
+	p.Run(hostEvent("web-1"))
+	p.Run(hostEvent("web-2"))
+	p.Run(hostEvent("web-1"))
+	assert.Equal(t, 3, requests.Load())
+}
+
+func TestLookupFailure(t *testing.T) {
+	var requests atomic.Int
+	server := startCMDB(t, &requests)
+	defer server.Close()
+
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"url":            server.URL + "/hosts/%{[host.name]}",
+		"headers":        map[string]string{"X-Token": "wrong"},
+		"tag_on_failure": []string{"_http_lookup_failure"},
+		"fields":         hostFields,
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+	defer p.Close()
+
+	for i := 0; i < 2; i++ {
+		event, err := p.Run(hostEvent("web-1"))
+		require.Error(t, err)
+		assert.Equal(t, []string{"_http_lookup_failure"}, event.Fields["tags"])
+	}
+	// errors are cached
+	assert.Equal(t, 1, requests.Load())
+
+	p.IgnoreFailure = true
+	event, err := p.Run(hostEvent("web-1"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"_http_lookup_failure"}, event.Fields["tags"])
+}
+
+func TestLookupMissingField(t *testing.T) {
+	var requests atomic.Int
+	server := startCMDB(t, &requests)
+	defer server.Close()
+
+	for _, settings := range []map[string]interface{}{
+		{"url": server.URL + "/hosts/%{[host.name]}", "fields": hostFields},
+		{"url": server.URL + "/hosts", "params": map[string]string{"name": "host.name"}, "fields": hostFields},
+	} {
+		proc, err := New(common.MustNewConfigFrom(settings))
+		require.NoError(t, err)
+		p := proc.(*processor)
+		_, err = p.Run(&beat.Event{Fields: common.MapStr{}})
+		assert.Error(t, err)
+
+		p.IgnoreMissing = true
+		event, err := p.Run(&beat.Event{Fields: common.MapStr{}})
+		assert.NoError(t, err)
+		assert.Equal(t, common.MapStr{}, event.Fields)
+		p.Close()
+	}
+	assert.Equal(t, 0, requests.Load())
+}
+
+func TestLookupOverwriteKeys(t *testing.T) {
+	var requests atomic.Int
+	server := startCMDB(t, &requests)
+	defer server.Close()
+
+	event := hostEvent("web-2")
+	event.Fields.Put("host.owner", "nobody")
+
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"url":     server.URL + "/hosts/%{[host.name]}",
+		"headers": map[string]string{"X-Token": "secret"},
+		"fields":  hostFields,
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+	defer p.Close()
+	_, err = p.Run(event)
+	assert.Error(t, err)
+	assert.Equal(t, "nobody", event.Fields["host"].(common.MapStr)["owner"])
+
+	p.OverwriteKeys = true
+	_, err = p.Run(event)
+	assert.NoError(t, err)
+	assert.Equal(t, "team-b", event.Fields["host"].(common.MapStr)["owner"])
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, settings := range map[string]map[string]interface{}{
+		"missing url":    {"fields": map[string]string{"a": "b"}},
+		"missing fields": {"url": "http://localhost"},
+		"invalid method": {"url": "http://localhost", "method": "PUT", "fields": map[string]string{"a": "b"}},
+		"invalid target": {"url": "http://localhost", "fields": map[string]interface{}{"a": 1}},
+		"invalid size":   {"url": "http://localhost", "fields": map[string]string{"a": "b"}, "cache.size": 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(common.MustNewConfigFrom(settings))
+			assert.Error(t, err)
+		})
+	}
+}
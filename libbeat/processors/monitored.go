@@ -0,0 +1,121 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package processors
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+	"github.com/elastic/beats/v7/libbeat/monitoring/adapter"
+)
+
+var (
+	// monitoredID is used to assign each configured processor a unique
+	// monitoring namespace.
+	monitoredID = atomic.MakeUint32(0)
+
+	// monitoredMu protects the creation and removal of the namespaces of the
+	// processors.
+	monitoredMu sync.Mutex
+)
+
+// monitoredProcessor reports the number of events a configured processor is
+// run with, how many of them fail or are dropped, and the time it takes to
+// process them. The metrics are reported in the
+// libbeat.processors.<name>.<id> namespace.
+type monitoredProcessor struct {
+	p    Processor
+	name string
+	id   string
+
+	events  *monitoring.Uint
+	errors  *monitoring.Uint
+	dropped *monitoring.Uint
+	latency metrics.Sample
+}
+
+func newMonitoredProcessor(name string, p Processor) *monitoredProcessor {
+	id := strconv.Itoa(int(monitoredID.Inc()))
+
+	monitoredMu.Lock()
+	reg := processorsRegistry(name).NewRegistry(id)
+	monitoredMu.Unlock()
+
+	m := &monitoredProcessor{
+		p:       p,
+		name:    name,
+		id:      id,
+		events:  monitoring.NewUint(reg, "events"),
+		errors:  monitoring.NewUint(reg, "errors"),
+		dropped: monitoring.NewUint(reg, "dropped"),
+		latency: metrics.NewUniformSample(2048),
+	}
+	adapter.NewGoMetrics(reg, "histogram", adapter.Accept).
+		Register("latency", metrics.NewHistogram(m.latency))
+	return m
+}
+
+// processorsRegistry returns the registry of the processors called name,
+// creating it if needed. monitoredMu must be held.
+func processorsRegistry(name string) *monitoring.Registry {
+	reg := monitoring.Default.GetRegistry("libbeat")
+	if reg == nil {
+		reg = monitoring.Default.NewRegistry("libbeat")
+	}
+	for _, ns := range []string{"processors", name} {
+		sub := reg.GetRegistry(ns)
+		if sub == nil {
+			sub = reg.NewRegistry(ns)
+		}
+		reg = sub
+	}
+	return reg
+}
+
+func (m *monitoredProcessor) Run(event *beat.Event) (*beat.Event, error) {
+	start := time.Now()
+	event, err := m.p.Run(event)
+	m.latency.Update(int64(time.Since(start)))
+
+	m.events.Inc()
+	if err != nil {
+		m.errors.Inc()
+	} else if event == nil {
+		m.dropped.Inc()
+	}
+	return event, err
+}
+
+func (m *monitoredProcessor) String() string {
+	return m.p.String()
+}
+
+// Close closes the processor, and removes its metrics.
+func (m *monitoredProcessor) Close() error {
+	monitoredMu.Lock()
+	processorsRegistry(m.name).Remove(m.id)
+	monitoredMu.Unlock()
+
+	return Close(m.p)
+}
@@ -0,0 +1,90 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package processors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+)
+
+type testProcessor struct {
+	fn     func(event *beat.Event) (*beat.Event, error)
+	closed bool
+}
+
+func (p *testProcessor) Run(event *beat.Event) (*beat.Event, error) { return p.fn(event) }
+func (p *testProcessor) String() string                             { return "test" }
+func (p *testProcessor) Close() error                               { p.closed = true; return nil }
+
+func TestMonitoredProcessor(t *testing.T) {
+	inner := &testProcessor{fn: func(event *beat.Event) (*beat.Event, error) {
+		switch event.Fields["action"] {
+		case "drop":
+			return nil, nil
+		case "fail":
+			return event, errors.New("failed")
+		}
+		return event, nil
+	}}
+	p := newMonitoredProcessor("test_monitored", inner)
+	assert.Equal(t, "test", p.String())
+
+	for _, action := range []string{"keep", "keep", "drop", "fail"} {
+		p.Run(&beat.Event{Fields: common.MapStr{"action": action}})
+	}
+
+	path := "libbeat.processors.test_monitored." + p.id
+	snapshot := monitoring.CollectFlatSnapshot(monitoring.Default, monitoring.Full, false)
+	assert.EqualValues(t, 4, snapshot.Ints[path+".events"])
+	assert.EqualValues(t, 1, snapshot.Ints[path+".errors"])
+	assert.EqualValues(t, 1, snapshot.Ints[path+".dropped"])
+	assert.EqualValues(t, 4, snapshot.Ints[path+".histogram.latency.count"])
+	assert.Contains(t, snapshot.Floats, path+".histogram.latency.p99")
+
+	require.NoError(t, p.Close())
+	assert.True(t, inner.closed)
+	assert.Nil(t, monitoring.Default.Get(path))
+}
+
+func TestNewMonitorsProcessors(t *testing.T) {
+	RegisterPlugin("test_monitored_plugin", func(*common.Config) (Processor, error) {
+		return &testProcessor{fn: func(event *beat.Event) (*beat.Event, error) { return event, nil }}, nil
+	})
+	defer delete(registry.reg, "test_monitored_plugin")
+
+	procs, err := New(PluginConfig{common.MustNewConfigFrom(map[string]interface{}{
+		"test_monitored_plugin": nil,
+	})})
+	require.NoError(t, err)
+	require.Len(t, procs.List, 1)
+
+	m, ok := procs.List[0].(*monitoredProcessor)
+	require.True(t, ok)
+	assert.Equal(t, "test_monitored_plugin", m.name)
+
+	_, err = procs.Run(&beat.Event{Fields: common.MapStr{}})
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, m.events.Get())
+}
@@ -59,6 +59,39 @@ func Close(p Processor) error {
 	return nil
 }
 
+// Emitter defines the interface for processors that publish events of their
+// own, besides the event they are run with, like summaries of the events seen
+// during a time window. The pipeline is passed to these processors when a
+// client using them is connected.
+type Emitter interface {
+	SetPipeline(pipeline beat.PipelineConnector)
+}
+
+// SetPipeline passes the pipeline to p, and to the processors contained in
+// it, if they implement the Emitter interface.
+func SetPipeline(p beat.Processor, pipeline beat.PipelineConnector) {
+	switch v := p.(type) {
+	case Emitter:
+		v.SetPipeline(pipeline)
+	case *monitoredProcessor:
+		SetPipeline(v.p, pipeline)
+	case *WhenProcessor:
+		SetPipeline(v.p, pipeline)
+	case *IfThenElseProcessor:
+		SetPipeline(v.then, pipeline)
+		for _, branch := range v.elseIf {
+			SetPipeline(branch.then, pipeline)
+		}
+		if v.els != nil {
+			SetPipeline(v.els, pipeline)
+		}
+	case beat.ProcessorList:
+		for _, sub := range v.All() {
+			SetPipeline(sub, pipeline)
+		}
+	}
+}
+
 // NewList creates a new empty processor list.
 // Additional processors can be added to the List field.
 func NewList(log *logp.Logger) *Processors {
@@ -79,7 +112,7 @@ func New(config PluginConfig) (*Processors, error) {
 			if err != nil {
 				return nil, errors.Wrap(err, "failed to make if/then/else processor")
 			}
-			procs.AddProcessor(p)
+			procs.AddProcessor(newMonitoredProcessor("if", p))
 			continue
 		}
 
@@ -113,7 +146,7 @@ func New(config PluginConfig) (*Processors, error) {
 			return nil, err
 		}
 
-		procs.AddProcessor(plugin)
+		procs.AddProcessor(newMonitoredProcessor(actionName, plugin))
 	}
 
 	if len(procs.List) > 0 {
@@ -0,0 +1,96 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package rate_limit
+
+import (
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type config struct {
+	// EventsPerSecond is the maximum average rate of the events of a key.
+	EventsPerSecond float64 `config:"events_per_second" validate:"required,positive,nonzero"`
+
+	// Burst is the maximum number of events of a key accepted at once above
+	// the average rate. It defaults to the number of events per second.
+	Burst int `config:"burst" validate:"min=0"`
+
+	// Fields are the fields whose values make the key of an event. All events
+	// share the same limit if not set.
+	Fields []string `config:"fields"`
+
+	// Action is what is done with the events above the limit.
+	Action action `config:"action"`
+
+	// Tags are added to the events above the limit by the tag action.
+	Tags []string `config:"tags"`
+
+	// MaxKeys is the maximum number of keys whose rate is tracked. The least
+	// recently seen key is forgotten when it is reached.
+	MaxKeys int `config:"max_keys" validate:"min=1"`
+}
+
+type action uint8
+
+const (
+	actionDrop action = iota
+	actionTag
+)
+
+var actions = map[string]action{
+	"drop": actionDrop,
+	"tag":  actionTag,
+}
+
+// Unpack creates the action from the given string.
+func (a *action) Unpack(str string) error {
+	v, found := actions[strings.ToLower(str)]
+	if !found {
+		return errors.Errorf("invalid rate_limit action '%v' (valid values are: drop, tag)", str)
+	}
+	*a = v
+	return nil
+}
+
+func (a action) String() string {
+	for name, v := range actions {
+		if v == a {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+func defaultConfig() config {
+	return config{
+		Action:  actionDrop,
+		Tags:    []string{"_rate_limited"},
+		MaxKeys: 10000,
+	}
+}
+
+// burst returns the configured burst, or the number of events per second if
+// it's not set.
+func (c *config) burst() int {
+	if c.Burst > 0 {
+		return c.Burst
+	}
+	return int(math.Max(1, math.Ceil(c.EventsPerSecond)))
+}
@@ -0,0 +1,159 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package rate_limit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	jsprocessor "github.com/elastic/beats/v7/libbeat/processors/script/javascript/module/processor"
+	"github.com/elastic/beats/v7/libbeat/processors/util"
+)
+
+const logName = "processor.rate_limit"
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID = atomic.MakeUint32(0)
+
+func init() {
+	processors.RegisterPlugin("rate_limit", New)
+	jsprocessor.RegisterPlugin("RateLimit", New)
+}
+
+type processor struct {
+	config
+	fields []string
+	log    *logp.Logger
+
+	// limiters maps the keys of the events to their rate limiter.
+	mu       sync.Mutex
+	limiters *lru.Cache
+
+	// now returns the current time, it is replaced in tests.
+	now func() time.Time
+
+	stats stats
+}
+
+type stats struct {
+	Processed *monitoring.Int
+	Dropped   *monitoring.Int
+	Tagged    *monitoring.Int
+}
+
+// New returns a new rate_limit processor.
+func New(cfg *common.Config) (processors.Processor, error) {
+	c := defaultConfig()
+	if err := cfg.Unpack(&c); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the rate_limit configuration")
+	}
+
+	return newFromConfig(c)
+}
+
+func newFromConfig(c config) (*processor, error) {
+	limiters, err := lru.New(c.MaxKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	id := int(instanceID.Inc())
+	metrics := monitoring.Default.NewRegistry(logName + "." + strconv.Itoa(id))
+
+	return &processor{
+		config: c,
+		// The fields are sorted, so the order of the configuration does not
+		// change the keys.
+		fields:   common.MakeStringSet(c.Fields...).ToSlice(),
+		log:      logp.NewLogger(logName).With("instance_id", id),
+		limiters: limiters,
+		now:      time.Now,
+		stats: stats{
+			Processed: monitoring.NewInt(metrics, "processed"),
+			Dropped:   monitoring.NewInt(metrics, "dropped"),
+			Tagged:    monitoring.NewInt(metrics, "tagged"),
+		},
+	}, nil
+}
+
+func (p *processor) String() string {
+	return fmt.Sprintf("rate_limit=[events_per_second=%v, burst=%v, fields=%v, action=%v]",
+		p.EventsPerSecond, p.burst(), p.fields, p.Action)
+}
+
+// Run drops or tags the event if the rate of the events with the same key is
+// above the limit.
+func (p *processor) Run(event *beat.Event) (*beat.Event, error) {
+	p.stats.Processed.Inc()
+
+	if p.allow(p.key(event.Fields)) {
+		return event, nil
+	}
+
+	switch p.Action {
+	case actionTag:
+		p.stats.Tagged.Inc()
+		if err := common.AddTags(event.Fields, p.Tags); err != nil {
+			return event, errors.Wrap(err, "failed to tag the rate limited event")
+		}
+		return event, nil
+	default:
+		p.stats.Dropped.Inc()
+		return nil, nil
+	}
+}
+
+func (p *processor) allow(key string) bool {
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var limiter *rate.Limiter
+	if v, found := p.limiters.Get(key); found {
+		limiter = v.(*rate.Limiter)
+	} else {
+		limiter = rate.NewLimiter(rate.Limit(p.EventsPerSecond), p.burst())
+		p.limiters.Add(key, limiter)
+	}
+	return limiter.AllowN(now, 1)
+}
+
+// key returns the values of the fields of the event. Missing fields are part
+// of the key too, so the events missing them share the same limit. The events
+// with values that are not scalars share the empty key.
+func (p *processor) key(fields common.MapStr) string {
+	var sb strings.Builder
+	if err := util.WriteKey(&sb, fields, p.fields, true); err != nil {
+		p.log.Debugf("Rate limiting the event with the empty key: %v", err)
+		return ""
+	}
+	return sb.String()
+}
@@ -0,0 +1,157 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package rate_limit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// count returns the number of events of the source kept by the processor.
+func count(t *testing.T, p *processor, source string, n int) int {
+	kept := 0
+	for i := 0; i < n; i++ {
+		event, err := p.Run(&beat.Event{Fields: common.MapStr{"source": source}})
+		require.NoError(t, err)
+		if event != nil {
+			kept++
+		}
+	}
+	return kept
+}
+
+func TestRateLimitDrop(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"events_per_second": 10,
+		"burst":             20,
+		"fields":            []string{"source"},
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	// the burst is accepted at once
+	assert.Equal(t, 20, count(t, p, "a", 30))
+	assert.EqualValues(t, 10, p.stats.Dropped.Get())
+
+	// each key has its own limit
+	assert.Equal(t, 20, count(t, p, "b", 20))
+
+	// the events are accepted again at the average rate
+	now = now.Add(500 * time.Millisecond)
+	assert.Equal(t, 5, count(t, p, "a", 10))
+	now = now.Add(time.Minute)
+	assert.Equal(t, 20, count(t, p, "a", 30))
+}
+
+func TestRateLimitDefaultBurst(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"events_per_second": 2.5,
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+	assert.Equal(t, 3, count(t, p, "a", 10))
+
+	// all events share the limit if no fields are set
+	assert.Equal(t, 0, count(t, p, "b", 10))
+
+	now = now.Add(time.Second)
+	assert.Equal(t, 2, count(t, p, "a", 10))
+}
+
+func TestRateLimitTag(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"events_per_second": 1,
+		"action":            "tag",
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+
+	event, err := p.Run(&beat.Event{Fields: common.MapStr{}})
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{}, event.Fields)
+
+	event, err = p.Run(&beat.Event{Fields: common.MapStr{}})
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{"tags": []string{"_rate_limited"}}, event.Fields)
+	assert.EqualValues(t, 1, p.stats.Tagged.Get())
+}
+
+func TestRateLimitMaxKeys(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"events_per_second": 1,
+		"fields":            []string{"source"},
+		"max_keys":          2,
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+	assert.Equal(t, 1, count(t, p, "a", 2))
+	assert.Equal(t, 1, count(t, p, "b", 2))
+	assert.Equal(t, 1, count(t, p, "c", 2))
+
+	// the limit of the least recently seen key starts again
+	assert.Equal(t, 1, count(t, p, "a", 2))
+	assert.Equal(t, 2, p.limiters.Len())
+}
+
+func TestRateLimitKey(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"events_per_second": 1,
+		"fields":            []string{"source", "host"},
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+
+	// missing fields and values containing separators make their own keys
+	keys := map[string]bool{}
+	for _, fields := range []common.MapStr{
+		{"source": "a|b"},
+		{"source": "a", "host": "b"},
+		{"source": "a", "host": "<nil>"},
+		{"source": "a"},
+	} {
+		keys[p.key(fields)] = true
+	}
+	assert.Len(t, keys, 4)
+
+	// values that are not scalars share the empty key
+	assert.Equal(t, "", p.key(common.MapStr{"source": []interface{}{"a"}}))
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, settings := range map[string]map[string]interface{}{
+		"missing rate":   {},
+		"zero rate":      {"events_per_second": 0},
+		"negative burst": {"events_per_second": 1, "burst": -1},
+		"invalid action": {"events_per_second": 1, "action": "delay"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(common.MustNewConfigFrom(settings))
+			assert.Error(t, err)
+		})
+	}
+}
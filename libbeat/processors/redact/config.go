@@ -0,0 +1,153 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redact
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type config struct {
+	// Fields are the fields scanned by the pattern and regex rules that do not
+	// set their own fields.
+	Fields []string `config:"fields"`
+
+	// Rules are applied in order.
+	Rules []ruleConfig `config:"rules" validate:"required"`
+
+	// HashKey is the key of the HMAC used by the hash action. It should be
+	// stored in the keystore.
+	HashKey string `config:"hash_key"`
+
+	// MaskChar replaces each character of the values masked.
+	MaskChar string `config:"mask_char" validate:"required"`
+}
+
+type ruleConfig struct {
+	// Name identifies the rule in the metrics. It defaults to the pattern or
+	// the field of the rule.
+	Name string `config:"name"`
+
+	// Pattern is the name of a built-in pattern.
+	Pattern string `config:"pattern"`
+
+	// Regex is a user-defined pattern.
+	Regex *regex `config:"regex"`
+
+	// Field is redacted entirely if set.
+	Field string `config:"field"`
+
+	// Fields are the fields scanned by the rule, instead of the fields of the
+	// processor.
+	Fields []string `config:"fields"`
+
+	// Action is what is done with the redacted values.
+	Action action `config:"action"`
+}
+
+type action uint8
+
+const (
+	actionMask action = iota
+	actionHash
+	actionRemove
+)
+
+var actions = map[string]action{
+	"mask":   actionMask,
+	"hash":   actionHash,
+	"remove": actionRemove,
+}
+
+// Unpack creates the action from the given string.
+func (a *action) Unpack(str string) error {
+	v, found := actions[strings.ToLower(str)]
+	if !found {
+		return errors.Errorf("invalid redact action '%v' (valid values are: mask, hash, remove)", str)
+	}
+	*a = v
+	return nil
+}
+
+type regex struct {
+	*regexp.Regexp
+}
+
+// Unpack compiles the regular expression.
+func (r *regex) Unpack(str string) error {
+	re, err := regexp.Compile(str)
+	if err != nil {
+		return err
+	}
+	r.Regexp = re
+	return nil
+}
+
+func defaultConfig() config {
+	return config{
+		MaskChar: "*",
+	}
+}
+
+func (c *config) Validate() error {
+	names := map[string]bool{}
+	for i := range c.Rules {
+		r := &c.Rules[i]
+
+		set := 0
+		for _, s := range []bool{r.Pattern != "", r.Regex != nil, r.Field != ""} {
+			if s {
+				set++
+			}
+		}
+		if set != 1 {
+			return errors.Errorf("rule %d: exactly one of pattern, regex or field must be set", i)
+		}
+
+		if r.Pattern != "" {
+			if _, found := builtinPatterns[r.Pattern]; !found {
+				return errors.Errorf("rule %d: unknown pattern '%v' (valid values are: %v)", i, r.Pattern, strings.Join(builtinPatternNames(), ", "))
+			}
+		}
+		if r.Field == "" && len(r.Fields) == 0 && len(c.Fields) == 0 {
+			return errors.Errorf("rule %d: no fields to scan, fields must be set in the rule or in the processor", i)
+		}
+		if r.Action == actionHash && c.HashKey == "" {
+			return errors.Errorf("rule %d: hash_key is required by the hash action", i)
+		}
+
+		if r.Name == "" {
+			switch {
+			case r.Pattern != "":
+				r.Name = r.Pattern
+			case r.Field != "":
+				r.Name = r.Field
+			default:
+				r.Name = "regex_" + strconv.Itoa(i)
+			}
+		}
+		if names[r.Name] {
+			return errors.Errorf("rule %d: duplicate rule name '%v'", i, r.Name)
+		}
+		names[r.Name] = true
+	}
+	return nil
+}
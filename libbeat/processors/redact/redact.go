@@ -0,0 +1,216 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	jsprocessor "github.com/elastic/beats/v7/libbeat/processors/script/javascript/module/processor"
+)
+
+const logName = "processor.redact"
+
+// instanceID is used to assign each instance a unique monitoring namespace.
+var instanceID = atomic.MakeUint32(0)
+
+func init() {
+	processors.RegisterPlugin("redact", New)
+	jsprocessor.RegisterPlugin("Redact", New)
+}
+
+type processor struct {
+	config
+	rules []rule
+	log   *logp.Logger
+
+	stats stats
+}
+
+type stats struct {
+	Events   *monitoring.Int
+	Redacted *monitoring.Int
+}
+
+type rule struct {
+	name   string
+	action action
+
+	// field is redacted entirely if set, otherwise the matches of regex are
+	// redacted in fields.
+	field  string
+	fields []string
+	regex  *regexp.Regexp
+	valid  func(string) bool
+
+	redacted *monitoring.Int
+}
+
+// New returns a new redact processor.
+func New(cfg *common.Config) (processors.Processor, error) {
+	c := defaultConfig()
+	if err := cfg.Unpack(&c); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the redact configuration")
+	}
+
+	return newFromConfig(c)
+}
+
+func newFromConfig(c config) (*processor, error) {
+	id := int(instanceID.Inc())
+	metrics := monitoring.Default.NewRegistry(logName + "." + strconv.Itoa(id))
+	ruleMetrics := metrics.NewRegistry("rules")
+
+	p := &processor{
+		config: c,
+		log:    logp.NewLogger(logName).With("instance_id", id),
+		stats: stats{
+			Events:   monitoring.NewInt(metrics, "events"),
+			Redacted: monitoring.NewInt(metrics, "redacted"),
+		},
+	}
+
+	for _, rc := range c.Rules {
+		r := rule{
+			name:     rc.Name,
+			action:   rc.Action,
+			field:    rc.Field,
+			fields:   rc.Fields,
+			redacted: monitoring.NewInt(ruleMetrics, rc.Name),
+		}
+		if len(r.fields) == 0 {
+			r.fields = c.Fields
+		}
+		switch {
+		case rc.Pattern != "":
+			pattern := builtinPatterns[rc.Pattern]
+			r.regex, r.valid = pattern.regex, pattern.valid
+		case rc.Regex != nil:
+			r.regex = rc.Regex.Regexp
+		}
+		p.rules = append(p.rules, r)
+	}
+	return p, nil
+}
+
+func (p *processor) String() string {
+	names := make([]string, len(p.rules))
+	for i, r := range p.rules {
+		names[i] = r.name
+	}
+	return fmt.Sprintf("redact=[rules=%v, fields=%v]", strings.Join(names, ","), p.Fields)
+}
+
+// Run applies the rules to the event. Missing fields, and fields that are not
+// strings in pattern and regex rules, are ignored.
+func (p *processor) Run(event *beat.Event) (*beat.Event, error) {
+	total := 0
+	for _, r := range p.rules {
+		var n int
+		if r.field != "" {
+			n = p.redactField(event, r)
+		} else {
+			n = p.redactMatches(event, r)
+		}
+		if n > 0 {
+			r.redacted.Add(int64(n))
+			total += n
+		}
+	}
+
+	if total > 0 {
+		p.stats.Events.Inc()
+		p.stats.Redacted.Add(int64(total))
+	}
+	return event, nil
+}
+
+func (p *processor) redactField(event *beat.Event, r rule) int {
+	v, err := event.GetValue(r.field)
+	if err != nil {
+		return 0
+	}
+
+	if r.action == actionRemove {
+		event.Delete(r.field)
+		return 1
+	}
+
+	switch v.(type) {
+	case map[string]interface{}, []interface{}, common.MapStr:
+		p.log.Debugf("Not redacting the field %v of rule %v, it is not a scalar", r.field, r.name)
+		return 0
+	}
+	event.PutValue(r.field, p.replace(r.action, fmt.Sprint(v)))
+	return 1
+}
+
+func (p *processor) redactMatches(event *beat.Event, r rule) int {
+	total := 0
+	for _, field := range r.fields {
+		v, err := event.GetValue(field)
+		if err != nil {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+
+		n := 0
+		out := r.regex.ReplaceAllStringFunc(s, func(match string) string {
+			if r.valid != nil && !r.valid(match) {
+				return match
+			}
+			n++
+			return p.replace(r.action, match)
+		})
+		if n > 0 {
+			event.PutValue(field, out)
+			total += n
+		}
+	}
+	return total
+}
+
+func (p *processor) replace(a action, value string) string {
+	switch a {
+	case actionHash:
+		mac := hmac.New(sha256.New, []byte(p.HashKey))
+		mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil))
+	case actionRemove:
+		return ""
+	default:
+		return strings.Repeat(p.MaskChar, utf8.RuneCountInString(value))
+	}
+}
@@ -0,0 +1,122 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func run(t *testing.T, p *processor, fields common.MapStr) common.MapStr {
+	event, err := p.Run(&beat.Event{Fields: fields})
+	require.NoError(t, err)
+	return event.Fields
+}
+
+func TestBuiltinPatterns(t *testing.T) {
+	tests := map[string]struct {
+		in, out string
+	}{
+		"credit_card": {
+			in:  "paid with 4111 1111 1111 1111, order 1234567890123",
+			out: "paid with *******************, order 1234567890123",
+		},
+		"email": {
+			in:  "sent to john.doe@example.com",
+			out: "sent to ********************",
+		},
+		"us_ssn": {
+			in:  "ssn 123-45-6789 not 000-12-3456",
+			out: "ssn *********** not 000-12-3456",
+		},
+		"uk_nino": {
+			in:  "nino AB123456C and GB123456A",
+			out: "nino ********* and GB123456A",
+		},
+	}
+
+	for pattern, test := range tests {
+		t.Run(pattern, func(t *testing.T) {
+			proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+				"fields": []string{"message"},
+				"rules":  []map[string]interface{}{{"pattern": pattern}},
+			}))
+			require.NoError(t, err)
+			p := proc.(*processor)
+			fields := run(t, p, common.MapStr{"message": test.in})
+			assert.Equal(t, test.out, fields["message"])
+		})
+	}
+}
+
+func TestActions(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"fields":   []string{"message"},
+		"hash_key": "secret",
+		"rules": []map[string]interface{}{
+			{"pattern": "email", "action": "hash"},
+			{"name": "token", "regex": `token=\w+`, "action": "remove"},
+			{"field": "user.password", "action": "remove"},
+			{"field": "user.id", "action": "mask", "fields": []string{"ignored"}},
+		},
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+
+	fields := run(t, p, common.MapStr{
+		"message": "login of a@b.io with token=abc123",
+		"user":    common.MapStr{"password": "hunter2", "id": 1234},
+	})
+	assert.Equal(t, common.MapStr{
+		"message": "login of 7c6d067a6eea2d29d190fea9510941cc2506765618cd3b13cd2ce602e859c801 with ",
+		"user":    common.MapStr{"id": "****"},
+	}, fields)
+
+	assert.Equal(t, int64(1), p.stats.Events.Get())
+	assert.Equal(t, int64(4), p.stats.Redacted.Get())
+	assert.Equal(t, int64(1), p.rules[1].redacted.Get())
+
+	// events without sensitive values are not counted
+	fields = run(t, p, common.MapStr{"message": "nothing here", "user": common.MapStr{"name": "x"}})
+	assert.Equal(t, "nothing here", fields["message"])
+	assert.Equal(t, int64(1), p.stats.Events.Get())
+}
+
+func TestConfigValidation(t *testing.T) {
+	tests := map[string]map[string]interface{}{
+		"no rules":          {"fields": []string{"message"}},
+		"unknown pattern":   {"fields": []string{"message"}, "rules": []map[string]interface{}{{"pattern": "phone"}}},
+		"pattern and field": {"fields": []string{"message"}, "rules": []map[string]interface{}{{"pattern": "email", "field": "a"}}},
+		"no fields":         {"rules": []map[string]interface{}{{"pattern": "email"}}},
+		"invalid regex":     {"fields": []string{"message"}, "rules": []map[string]interface{}{{"regex": "("}}},
+		"invalid action":    {"rules": []map[string]interface{}{{"field": "a", "action": "encrypt"}}},
+		"hash without key":  {"rules": []map[string]interface{}{{"field": "a", "action": "hash"}}},
+		"duplicate names":   {"rules": []map[string]interface{}{{"field": "a"}, {"field": "a"}}},
+	}
+	for name, settings := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(common.MustNewConfigFrom(settings))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,35 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sample
+
+type config struct {
+	// Rate keeps 1 in Rate events.
+	Rate int64 `config:"rate" validate:"required,min=1"`
+
+	// KeyFields sample the events by the values of these fields, so all events
+	// with the same values are either kept or dropped. Events are sampled
+	// randomly if not set.
+	KeyFields []string `config:"key_fields"`
+
+	// RateField is set to the rate in the kept events, if not empty.
+	RateField string `config:"rate_field"`
+}
+
+func defaultConfig() config {
+	return config{}
+}
@@ -0,0 +1,106 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sample
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	jsprocessor "github.com/elastic/beats/v7/libbeat/processors/script/javascript/module/processor"
+	"github.com/elastic/beats/v7/libbeat/processors/util"
+)
+
+func init() {
+	processors.RegisterPlugin("sample", New)
+	jsprocessor.RegisterPlugin("Sample", New)
+}
+
+type processor struct {
+	config
+	keyFields []string
+
+	// random returns a random number in [0, n), it is replaced in tests.
+	random func(n int64) int64
+}
+
+// New returns a new sample processor.
+func New(cfg *common.Config) (processors.Processor, error) {
+	c := defaultConfig()
+	if err := cfg.Unpack(&c); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the sample configuration")
+	}
+
+	return newFromConfig(c), nil
+}
+
+func newFromConfig(c config) *processor {
+	return &processor{
+		config: c,
+		// The fields are sorted, so the order of the configuration does not
+		// change which keys are sampled.
+		keyFields: common.MakeStringSet(c.KeyFields...).ToSlice(),
+		random:    rand.Int63n,
+	}
+}
+
+func (p *processor) String() string {
+	return fmt.Sprintf("sample=[rate=%v, key_fields=%v]", p.Rate, p.keyFields)
+}
+
+// Run drops the events that are not sampled. Events missing the key fields
+// are sampled randomly.
+func (p *processor) Run(event *beat.Event) (*beat.Event, error) {
+	if !p.sampled(event.Fields) {
+		return nil, nil
+	}
+
+	if p.RateField != "" {
+		if _, err := event.PutValue(p.RateField, p.Rate); err != nil {
+			return event, errors.Wrapf(err, "failed to set the sample rate in %v", p.RateField)
+		}
+	}
+	return event, nil
+}
+
+func (p *processor) sampled(fields common.MapStr) bool {
+	if p.Rate == 1 {
+		return true
+	}
+	if len(p.keyFields) > 0 {
+		if h, ok := p.hashKey(fields); ok {
+			return h%uint64(p.Rate) == 0
+		}
+	}
+	return p.random(p.Rate) == 0
+}
+
+// hashKey returns the hash of the values of the key fields, or false if one
+// of the fields is missing or not a scalar.
+func (p *processor) hashKey(fields common.MapStr) (uint64, bool) {
+	h := fnv.New64a()
+	if err := util.WriteKey(h, fields, p.keyFields, false); err != nil {
+		return 0, false
+	}
+	return h.Sum64(), true
+}
@@ -0,0 +1,141 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package sample
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestSampleRandom(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"rate":       4,
+		"rate_field": "event.sample_rate",
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+
+	var calls int64
+	p.random = func(n int64) int64 {
+		assert.Equal(t, int64(4), n)
+		calls++
+		return calls % n
+	}
+
+	kept := 0
+	for i := 0; i < 100; i++ {
+		event, err := p.Run(&beat.Event{Fields: common.MapStr{"message": "hello"}})
+		require.NoError(t, err)
+		if event != nil {
+			kept++
+			rate, err := event.GetValue("event.sample_rate")
+			require.NoError(t, err)
+			assert.Equal(t, int64(4), rate)
+		}
+	}
+	assert.Equal(t, 25, kept)
+}
+
+func TestSampleRateOne(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{"rate": 1}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+	p.random = func(n int64) int64 { return 1 }
+
+	event, err := p.Run(&beat.Event{Fields: common.MapStr{}})
+	require.NoError(t, err)
+	assert.NotNil(t, event)
+	assert.Equal(t, common.MapStr{}, event.Fields)
+}
+
+func TestSampleByKey(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"rate":       10,
+		"key_fields": []string{"trace.id"},
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+	p.random = func(n int64) int64 {
+		t.Fatal("events with a key must not be sampled randomly")
+		return 0
+	}
+
+	keptTraces := 0
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("trace-%d", i)
+
+		kept := 0
+		for j := 0; j < 5; j++ {
+			event, err := p.Run(&beat.Event{Fields: common.MapStr{
+				"trace":   common.MapStr{"id": id},
+				"message": fmt.Sprintf("span %d", j),
+			}})
+			require.NoError(t, err)
+			if event != nil {
+				kept++
+			}
+		}
+
+		// all events of a trace are kept or dropped
+		if kept == 5 {
+			keptTraces++
+		} else {
+			assert.Equal(t, 0, kept, id)
+		}
+	}
+	assert.InDelta(t, 100, keptTraces, 40)
+}
+
+func TestSampleMissingKey(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"rate":       10,
+		"key_fields": []string{"trace.id"},
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+
+	random := int64(0)
+	p.random = func(n int64) int64 { return random }
+
+	event, err := p.Run(&beat.Event{Fields: common.MapStr{}})
+	require.NoError(t, err)
+	assert.NotNil(t, event)
+
+	random = 1
+	event, err = p.Run(&beat.Event{Fields: common.MapStr{}})
+	require.NoError(t, err)
+	assert.Nil(t, event)
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, settings := range map[string]map[string]interface{}{
+		"missing rate": {},
+		"zero rate":    {"rate": 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(common.MustNewConfigFrom(settings))
+			assert.Error(t, err)
+		})
+	}
+}
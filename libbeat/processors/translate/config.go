@@ -0,0 +1,94 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package translate
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type config struct {
+	Field       string `config:"field" validate:"required"`
+	TargetField string `config:"target_field"`
+
+	// Dictionary is used if DictionaryPath is not set. It is a list, so keys
+	// can contain dots and regular expressions are tried in order.
+	Dictionary     []entryConfig `config:"dictionary"`
+	DictionaryPath string        `config:"dictionary_path"`
+
+	// Format of the dictionary file, csv, yaml or json. It is detected from
+	// the file extension by default.
+	Format string `config:"format"`
+
+	// ReloadPeriod is how often the dictionary file is checked for changes,
+	// 0 disables the reload.
+	ReloadPeriod time.Duration `config:"reload.period"`
+
+	// Regex is set if the keys of the dictionary are regular expressions.
+	Regex bool `config:"regex"`
+
+	// Default is set as translation if no key matches. The event is not
+	// modified if it is not set.
+	Default interface{} `config:"default"`
+
+	IgnoreMissing bool     `config:"ignore_missing"`
+	FailOnMissing bool     `config:"fail_on_missing"`
+	TagOnFailure  []string `config:"tag_on_failure"`
+}
+
+type entryConfig struct {
+	Key   string      `config:"key" validate:"required"`
+	Value interface{} `config:"value" validate:"required"`
+}
+
+func (c *config) Validate() error {
+	if c.DictionaryPath == "" && len(c.Dictionary) == 0 {
+		return errors.New("either dictionary or dictionary_path must be set")
+	}
+	if c.DictionaryPath != "" && len(c.Dictionary) > 0 {
+		return errors.New("dictionary and dictionary_path can not be used together")
+	}
+
+	if c.DictionaryPath != "" {
+		if c.Format == "" {
+			c.Format = strings.TrimPrefix(filepath.Ext(c.DictionaryPath), ".")
+		}
+		c.Format = strings.ToLower(c.Format)
+		switch c.Format {
+		case "csv", "json", "yaml":
+		case "yml":
+			c.Format = "yaml"
+		default:
+			return errors.Errorf("invalid translate dictionary format '%v' (valid values are: csv, yaml, json)", c.Format)
+		}
+	}
+
+	if c.ReloadPeriod < 0 {
+		return errors.New("reload.period can not be negative")
+	}
+	return nil
+}
+
+func defaultConfig() config {
+	return config{
+		ReloadPeriod: 1 * time.Minute,
+	}
+}
@@ -0,0 +1,317 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package translate
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+// dictionary maps keys to their translation. Once created it is not modified,
+// a reload creates a new dictionary.
+type dictionary struct {
+	exact    map[string]interface{}
+	patterns []pattern
+}
+
+type pattern struct {
+	regexp *regexp.Regexp
+	value  interface{}
+}
+
+type entry struct {
+	key   string
+	value interface{}
+}
+
+func newDictionary(entries []entry, regex bool) (*dictionary, error) {
+	d := &dictionary{}
+	if !regex {
+		d.exact = make(map[string]interface{}, len(entries))
+		for _, e := range entries {
+			d.exact[e.key] = e.value
+		}
+		return d, nil
+	}
+
+	for _, e := range entries {
+		re, err := regexp.Compile(e.key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid regular expression '%v'", e.key)
+		}
+		d.patterns = append(d.patterns, pattern{regexp: re, value: e.value})
+	}
+	return d, nil
+}
+
+// lookup returns the translation of key. Regular expressions are tried in
+// the order of the dictionary, the first matching one is used.
+func (d *dictionary) lookup(key string) (interface{}, bool) {
+	if d.exact != nil {
+		v, found := d.exact[key]
+		return v, found
+	}
+	for _, p := range d.patterns {
+		if p.regexp.MatchString(key) {
+			return p.value, true
+		}
+	}
+	return nil, false
+}
+
+// fileStamp is used to detect changes to a file on disk.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// dictionaryFile loads a dictionary from a file, and loads it again when the
+// file changes.
+//
+// The file is not watched in the background. Instead a check is done lazily
+// when the dictionary is used, but not more often than once per period.
+type dictionaryFile struct {
+	path   string
+	format string
+	regex  bool
+	period time.Duration
+	log    *logp.Logger
+	now    func() time.Time
+
+	mu        sync.Mutex
+	lastCheck time.Time
+	stamp     fileStamp
+	dict      *dictionary
+}
+
+func newDictionaryFile(path, format string, regex bool, period time.Duration, log *logp.Logger) (*dictionaryFile, error) {
+	f := &dictionaryFile{
+		path:   path,
+		format: format,
+		regex:  regex,
+		period: period,
+		log:    log,
+		now:    time.Now,
+	}
+
+	stamp, err := statFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dict, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	f.stamp = stamp
+	f.dict = dict
+	f.lastCheck = f.now()
+	return f, nil
+}
+
+// get returns the current dictionary, loading the file again if it changed.
+// If the changed file can not be loaded, the previous dictionary is kept and
+// the reload is retried on the next check.
+func (f *dictionaryFile) get() *dictionary {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.period <= 0 {
+		return f.dict
+	}
+	now := f.now()
+	if now.Sub(f.lastCheck) < f.period {
+		return f.dict
+	}
+	f.lastCheck = now
+
+	stamp, err := statFile(f.path)
+	if err != nil {
+		f.log.Errorf("Failed to check the dictionary file for changes, keeping the previous dictionary: %v", err)
+		return f.dict
+	}
+	if stamp == f.stamp {
+		return f.dict
+	}
+
+	dict, err := f.load()
+	if err != nil {
+		f.log.Errorf("Failed to reload the dictionary file, keeping the previous dictionary: %v", err)
+		return f.dict
+	}
+	f.log.Infof("Reloaded the dictionary file %v", f.path)
+	f.stamp = stamp
+	f.dict = dict
+	return f.dict
+}
+
+func (f *dictionaryFile) load() (*dictionary, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []entry
+	switch f.format {
+	case "csv":
+		entries, err = readCSV(file)
+	case "json":
+		entries, err = readJSON(file)
+	case "yaml":
+		entries, err = readYAML(file)
+	default:
+		err = errors.Errorf("unsupported format %v", f.format)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read the dictionary file %v", f.path)
+	}
+	return newDictionary(entries, f.regex)
+}
+
+func statFile(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// readCSV reads a dictionary with one key and one value per line. Lines
+// starting with # are ignored.
+func readCSV(r io.Reader) ([]entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.Comment = '#'
+
+	var entries []entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key: record[0], value: record[1]})
+	}
+}
+
+// readJSON reads a dictionary from a JSON object, keeping the order of its
+// keys.
+func readJSON(r io.Reader) ([]entry, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	if t, err := dec.Token(); err != nil {
+		return nil, err
+	} else if t != json.Delim('{') {
+		return nil, errors.New("the dictionary must be a JSON object")
+	}
+
+	var entries []entry
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{key: t.(string), value: normalizeJSON(value)})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// readYAML reads a dictionary from a YAML mapping, keeping the order of its
+// keys.
+func readYAML(r io.Reader) ([]entry, error) {
+	var mapping yaml.MapSlice
+	if err := yaml.NewDecoder(r).Decode(&mapping); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	entries := make([]entry, 0, len(mapping))
+	for _, item := range mapping {
+		entries = append(entries, entry{key: fmt.Sprint(item.Key), value: normalizeYAML(item.Value)})
+	}
+	return entries, nil
+}
+
+// normalizeJSON converts the numbers of a decoded JSON value to int64 or
+// float64, and its objects to common.MapStr.
+func normalizeJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		m := common.MapStr{}
+		for key, value := range v {
+			m[key] = normalizeJSON(value)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeJSON(v[i])
+		}
+	}
+	return value
+}
+
+// normalizeYAML converts the mappings of a decoded YAML value to
+// common.MapStr.
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		m := common.MapStr{}
+		for _, item := range v {
+			m[fmt.Sprint(item.Key)] = normalizeYAML(item.Value)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := common.MapStr{}
+		for key, value := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(value)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = normalizeYAML(v[i])
+		}
+	}
+	return value
+}
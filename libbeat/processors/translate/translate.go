@@ -0,0 +1,144 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package translate
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/paths"
+	"github.com/elastic/beats/v7/libbeat/processors"
+	jsprocessor "github.com/elastic/beats/v7/libbeat/processors/script/javascript/module/processor"
+)
+
+const logName = "processor.translate"
+
+func init() {
+	processors.RegisterPlugin("translate", New)
+	jsprocessor.RegisterPlugin("Translate", New)
+}
+
+type processor struct {
+	config
+	file *dictionaryFile
+	dict *dictionary
+	log  *logp.Logger
+}
+
+// New returns a new translate processor mapping the value of a field through
+// a dictionary.
+func New(cfg *common.Config) (processors.Processor, error) {
+	c := defaultConfig()
+	if err := cfg.Unpack(&c); err != nil {
+		return nil, errors.Wrap(err, "fail to unpack the translate configuration")
+	}
+
+	return newFromConfig(c)
+}
+
+func newFromConfig(c config) (*processor, error) {
+	if c.TargetField == "" {
+		c.TargetField = c.Field
+	}
+	c.Default = normalizeJSON(c.Default)
+
+	p := &processor{
+		config: c,
+		log:    logp.NewLogger(logName),
+	}
+
+	if c.DictionaryPath != "" {
+		file, err := newDictionaryFile(paths.Resolve(paths.Config, c.DictionaryPath), c.Format, c.Regex, c.ReloadPeriod, p.log)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load the translate dictionary")
+		}
+		p.file = file
+		return p, nil
+	}
+
+	entries := make([]entry, 0, len(c.Dictionary))
+	for _, e := range c.Dictionary {
+		entries = append(entries, entry{key: e.Key, value: normalizeJSON(e.Value)})
+	}
+	dict, err := newDictionary(entries, c.Regex)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid translate dictionary")
+	}
+	p.dict = dict
+	return p, nil
+}
+
+func (p *processor) String() string {
+	source := "inline"
+	if p.file != nil {
+		source = p.file.path
+	}
+	return fmt.Sprintf("translate=[field=%s, target_field=%s, dictionary=%s, regex=%v]",
+		p.Field, p.TargetField, source, p.Regex)
+}
+
+func (p *processor) Run(event *beat.Event) (*beat.Event, error) {
+	err := p.translate(event)
+	if err == nil || (p.IgnoreMissing && errors.Cause(err) == common.ErrKeyNotFound) {
+		return event, nil
+	}
+
+	common.AddTags(event.Fields, p.TagOnFailure)
+	return event, err
+}
+
+func (p *processor) translate(event *beat.Event) error {
+	v, err := event.GetValue(p.Field)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get the field %v", p.Field)
+	}
+
+	var key string
+	switch v.(type) {
+	case string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		key = fmt.Sprint(v)
+	default:
+		return errors.Errorf("unsupported type %T of the field %v", v, p.Field)
+	}
+
+	dict := p.dict
+	if p.file != nil {
+		dict = p.file.get()
+	}
+
+	translation, found := dict.lookup(key)
+	if !found {
+		if p.Default == nil {
+			if p.FailOnMissing {
+				return errors.Errorf("no translation found for the value '%v' of the field %v", key, p.Field)
+			}
+			return nil
+		}
+		translation = p.Default
+	}
+
+	if m, ok := translation.(common.MapStr); ok {
+		translation = m.Clone()
+	}
+	_, err = event.PutValue(p.TargetField, translation)
+	return err
+}
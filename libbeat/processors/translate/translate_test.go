@@ -0,0 +1,263 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package translate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func run(t *testing.T, p *processor, fields common.MapStr) (common.MapStr, error) {
+	event, err := p.Run(&beat.Event{Fields: fields})
+	require.NotNil(t, event)
+	return event.Fields, err
+}
+
+func TestTranslateInline(t *testing.T) {
+	dictionary := []map[string]interface{}{
+		{"key": "1000", "value": "alice"},
+		{"key": "1001", "value": "bob"},
+		{"key": "10.0.0.1", "value": map[string]interface{}{"site": "ams", "rack": 12}},
+	}
+
+	t.Run("replace field", func(t *testing.T) {
+		proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":      "user.id",
+			"dictionary": dictionary,
+		}))
+		require.NoError(t, err)
+		p := proc.(*processor)
+		fields, err := run(t, p, common.MapStr{"user": common.MapStr{"id": "1000"}})
+		require.NoError(t, err)
+		assert.Equal(t, common.MapStr{"user": common.MapStr{"id": "alice"}}, fields)
+	})
+
+	t.Run("target field", func(t *testing.T) {
+		proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":        "user.id",
+			"target_field": "user.name",
+			"dictionary":   dictionary,
+		}))
+		require.NoError(t, err)
+		p := proc.(*processor)
+		fields, err := run(t, p, common.MapStr{"user": common.MapStr{"id": 1001}})
+		require.NoError(t, err)
+		assert.Equal(t, common.MapStr{"user": common.MapStr{"id": 1001, "name": "bob"}}, fields)
+	})
+
+	t.Run("object value", func(t *testing.T) {
+		proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":        "source.ip",
+			"target_field": "source.site",
+			"dictionary":   dictionary,
+		}))
+		require.NoError(t, err)
+		p := proc.(*processor)
+		fields, err := run(t, p, common.MapStr{"source": common.MapStr{"ip": "10.0.0.1"}})
+		require.NoError(t, err)
+		site, err := fields.GetValue("source.site.site")
+		require.NoError(t, err)
+		assert.Equal(t, "ams", site)
+	})
+
+	t.Run("no translation", func(t *testing.T) {
+		proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":      "user.id",
+			"dictionary": dictionary,
+		}))
+		require.NoError(t, err)
+		p := proc.(*processor)
+		fields, err := run(t, p, common.MapStr{"user": common.MapStr{"id": "2000"}})
+		require.NoError(t, err)
+		assert.Equal(t, common.MapStr{"user": common.MapStr{"id": "2000"}}, fields)
+
+		p.FailOnMissing = true
+		p.TagOnFailure = []string{"_translate_failure"}
+		fields, err = run(t, p, common.MapStr{"user": common.MapStr{"id": "2000"}})
+		require.Error(t, err)
+		assert.Equal(t, []string{"_translate_failure"}, fields["tags"])
+	})
+
+	t.Run("default", func(t *testing.T) {
+		proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":      "user.id",
+			"dictionary": dictionary,
+			"default":    "unknown",
+		}))
+		require.NoError(t, err)
+		p := proc.(*processor)
+		fields, err := run(t, p, common.MapStr{"user": common.MapStr{"id": "2000"}})
+		require.NoError(t, err)
+		assert.Equal(t, common.MapStr{"user": common.MapStr{"id": "unknown"}}, fields)
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":      "user.id",
+			"dictionary": dictionary,
+		}))
+		require.NoError(t, err)
+		p := proc.(*processor)
+		_, err = run(t, p, common.MapStr{})
+		require.Error(t, err)
+
+		p.IgnoreMissing = true
+		fields, err := run(t, p, common.MapStr{})
+		require.NoError(t, err)
+		assert.Equal(t, common.MapStr{}, fields)
+	})
+}
+
+func TestTranslateRegex(t *testing.T) {
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"field":        "source.ip",
+		"target_field": "source.site",
+		"regex":        true,
+		"dictionary": []map[string]interface{}{
+			{"key": `^10\.1\.`, "value": "ams"},
+			{"key": `^10\.`, "value": "fra"},
+		},
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+
+	for ip, site := range map[string]string{"10.1.2.3": "ams", "10.2.2.3": "fra"} {
+		fields, err := run(t, p, common.MapStr{"source": common.MapStr{"ip": ip}})
+		require.NoError(t, err)
+		assert.Equal(t, site, fields["source"].(common.MapStr)["site"], ip)
+	}
+
+	_, err = New(common.MustNewConfigFrom(map[string]interface{}{
+		"field":      "source.ip",
+		"regex":      true,
+		"dictionary": []map[string]interface{}{{"key": "(", "value": "x"}},
+	}))
+	assert.Error(t, err)
+}
+
+func TestTranslateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "translate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"users.csv":  "# id,name\n1000,alice\n\"1001\",\"bob, jr\"\n",
+		"users.yml":  "1000: alice\n\"1001\": bob, jr\n",
+		"users.json": `{"1000": "alice", "1001": "bob, jr"}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+
+			proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+				"field":           "user.id",
+				"target_field":    "user.name",
+				"dictionary_path": path,
+			}))
+			require.NoError(t, err)
+			p := proc.(*processor)
+			for id, name := range map[string]string{"1000": "alice", "1001": "bob, jr"} {
+				fields, err := run(t, p, common.MapStr{"user": common.MapStr{"id": id}})
+				require.NoError(t, err)
+				assert.Equal(t, name, fields["user"].(common.MapStr)["name"])
+			}
+		})
+	}
+
+	t.Run("invalid file", func(t *testing.T) {
+		path := filepath.Join(dir, "invalid.csv")
+		require.NoError(t, ioutil.WriteFile(path, []byte("1000,alice,extra\n"), 0600))
+
+		_, err := New(common.MustNewConfigFrom(map[string]interface{}{
+			"field":           "user.id",
+			"dictionary_path": path,
+		}))
+		assert.Error(t, err)
+	})
+}
+
+func TestTranslateFileReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "translate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "users.csv")
+	require.NoError(t, ioutil.WriteFile(path, []byte("1000,alice\n"), 0600))
+
+	proc, err := New(common.MustNewConfigFrom(map[string]interface{}{
+		"field":           "user.id",
+		"dictionary_path": path,
+		"reload.period":   "1m",
+	}))
+	require.NoError(t, err)
+	p := proc.(*processor)
+	now := time.Now()
+	p.file.now = func() time.Time { return now }
+
+	translate := func() interface{} {
+		fields, err := run(t, p, common.MapStr{"user": common.MapStr{"id": "1000"}})
+		require.NoError(t, err)
+		return fields["user"].(common.MapStr)["id"]
+	}
+	assert.Equal(t, "alice", translate())
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("1000,carol\n"), 0600))
+	ts := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(path, ts, ts))
+
+	// the file is not checked before the period elapsed
+	assert.Equal(t, "alice", translate())
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, "carol", translate())
+
+	// invalid changes keep the previous dictionary
+	require.NoError(t, ioutil.WriteFile(path, []byte("1000,dave,extra\n"), 0600))
+	ts = ts.Add(time.Hour)
+	require.NoError(t, os.Chtimes(path, ts, ts))
+	now = now.Add(time.Minute)
+	assert.Equal(t, "carol", translate())
+}
+
+func TestConfigValidate(t *testing.T) {
+	for name, settings := range map[string]map[string]interface{}{
+		"missing field":      {"dictionary_path": "users.csv"},
+		"missing dictionary": {"field": "user.id"},
+		"both dictionaries": {
+			"field":           "user.id",
+			"dictionary_path": "users.csv",
+			"dictionary":      []map[string]interface{}{{"key": "a", "value": "b"}},
+		},
+		"unknown format": {"field": "user.id", "dictionary_path": "users.txt"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := New(common.MustNewConfigFrom(settings))
+			assert.Error(t, err)
+		})
+	}
+}
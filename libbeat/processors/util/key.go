@@ -0,0 +1,59 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package util
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// WriteKey writes the names and values of the fields to w, so events with the
+// same values get the same key when w is hashed. The names and values are
+// prefixed with their length, so values containing separators can not write
+// the key of other values. Missing fields are written with a marker if
+// ignoreMissing is set, otherwise an error is returned. Times are written in
+// UTC, and values that are not scalars return an error.
+func WriteKey(w io.Writer, fields common.MapStr, names []string, ignoreMissing bool) error {
+	for _, name := range names {
+		fmt.Fprintf(w, "%d:%s", len(name), name)
+
+		v, err := fields.GetValue(name)
+		if err != nil {
+			if !ignoreMissing {
+				return errors.Wrapf(err, "failed to get the field %v", name)
+			}
+			io.WriteString(w, "-")
+			continue
+		}
+
+		switch vv := v.(type) {
+		case map[string]interface{}, []interface{}, common.MapStr:
+			return errors.Errorf("the field %v is not a scalar", name)
+		case time.Time:
+			v = vv.UTC()
+		}
+		s := fmt.Sprint(v)
+		fmt.Fprintf(w, "%d:%s", len(s), s)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package util
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func key(t *testing.T, fields common.MapStr, names ...string) string {
+	var sb strings.Builder
+	require.NoError(t, WriteKey(&sb, fields, names, true))
+	return sb.String()
+}
+
+func TestWriteKey(t *testing.T) {
+	assert.Equal(t, "1:a1:x1:b-", key(t, common.MapStr{"a": "x"}, "a", "b"))
+
+	// values with separators do not make the key of other values
+	assert.NotEqual(t,
+		key(t, common.MapStr{"a": "x|b|y"}, "a", "b"),
+		key(t, common.MapStr{"a": "x", "b": "y"}, "a", "b"))
+	assert.NotEqual(t,
+		key(t, common.MapStr{"a": "1:x1:b-"}, "a", "b"),
+		key(t, common.MapStr{"a": "x"}, "a", "b"))
+	assert.NotEqual(t,
+		key(t, common.MapStr{"a": "-"}, "a"),
+		key(t, common.MapStr{}, "a"))
+
+	ts := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t,
+		key(t, common.MapStr{"ts": ts}, "ts"),
+		key(t, common.MapStr{"ts": ts.In(time.FixedZone("CET", 3600))}, "ts"))
+}
+
+func TestWriteKeyErrors(t *testing.T) {
+	var sb strings.Builder
+	assert.Error(t, WriteKey(&sb, common.MapStr{}, []string{"a"}, false))
+	for _, v := range []interface{}{common.MapStr{"b": 1}, map[string]interface{}{"b": 1}, []interface{}{1}} {
+		assert.Error(t, WriteKey(&sb, common.MapStr{"a": v}, []string{"a"}, true))
+	}
+}
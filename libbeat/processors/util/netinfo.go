@@ -65,3 +65,44 @@ func GetNetInfo() (ipList []string, hwList []string, err error) {
 
 	return ipList, hwList, errs.Err()
 }
+
+// NetInterface contains the addresses of a network interface.
+type NetInterface struct {
+	Name string
+	MAC  string
+	IPs  []string
+}
+
+// GetNetInterfaces returns the non-loopback network interfaces of the machine
+// it is executed on, with their MAC and IP addresses.
+func GetNetInterfaces() ([]NetInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var errs multierror.Errors
+	var list []NetInterface
+	for _, i := range ifaces {
+		if i.Flags&net.FlagLoopback == net.FlagLoopback {
+			continue
+		}
+
+		iface := NetInterface{Name: i.Name, MAC: i.HardwareAddr.String()}
+		addrs, err := i.Addrs()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, addr := range addrs {
+			switch v := addr.(type) {
+			case *net.IPNet:
+				iface.IPs = append(iface.IPs, v.IP.String())
+			case *net.IPAddr:
+				iface.IPs = append(iface.IPs, v.IP.String())
+			}
+		}
+		list = append(list, iface)
+	}
+
+	return list, errs.Err()
+}
@@ -20,6 +20,7 @@ package publisher
 import (
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/memgov"
 )
 
 // Batch is used to pass a batch of events to the outputs and asynchronously listening
@@ -37,12 +38,52 @@ type Batch interface {
 	CancelledEvents(events []Event)
 }
 
+// DeadLetterBatch is implemented by batches passing the events rejected by
+// the output with a non-retryable error to a dead letter queue.
+type DeadLetterBatch interface {
+	// DeadLetter stores the rejected event with the reason of the rejection.
+	// It must be called before the batch is ACKed or retried.
+	DeadLetter(event Event, reason string)
+}
+
+// DeadLetter passes an event rejected by the output to the dead letter queue
+// of batch, if it has one.
+func DeadLetter(batch Batch, event Event, reason string) {
+	if b, ok := batch.(DeadLetterBatch); ok {
+		b.DeadLetter(event, reason)
+	}
+}
+
+// SplitBatch is implemented by batches whose events can be retried in smaller
+// batches, e.g. when the request of the batch is too large for the output.
+type SplitBatch interface {
+	// SplitRetry splits the given events of the batch in two halves and
+	// retries both, the other events of the batch are dropped. It returns
+	// false without retrying if there are less than two events.
+	SplitRetry(events []Event) bool
+}
+
+// SplitRetry splits the given events of batch in two halves and retries
+// both, if the batch supports splitting. The events must be the events of the
+// batch, or a subset of them, like the events left after removing the ones
+// that can't be encoded. It returns false if batch was not split.
+func SplitRetry(batch Batch, events []Event) bool {
+	if b, ok := batch.(SplitBatch); ok {
+		return b.SplitRetry(events)
+	}
+	return false
+}
+
 // Event is used by the publisher pipeline and broker to pass additional
 // meta-data to the consumers/outputs.
 type Event struct {
 	Content beat.Event
 	Flags   EventFlags
 	Cache   EventCache
+
+	// Reserved is the memory reserved for the event in the queue budget of
+	// the memory governor.
+	Reserved int64
 }
 
 // EventFlags provides additional flags/option types  for used with the outputs.
@@ -78,10 +119,70 @@ const (
 	// GuaranteedSend requires an output to not drop the event on failure, but
 	// retry until ACK.
 	GuaranteedSend EventFlags = 0x01
+
+	// RecycleFields marks events whose fields are owned by the publisher
+	// pipeline. The fields are returned to the MapStr pool once the event has
+	// been handled by the queue or the output.
+	RecycleFields EventFlags = 0x02
 )
 
+var queueBudget = memgov.Get(memgov.QueueBudget)
+
 // Guaranteed checks if the event must not be dropped by the output or the
 // publisher pipeline.
 func (e *Event) Guaranteed() bool {
 	return (e.Flags & GuaranteedSend) == GuaranteedSend
 }
+
+// Release releases the resources held by the event once it has been handled
+// by the queue or the output. The fields are returned to the MapStr pool if
+// they are owned by the publisher pipeline, and must not be used afterwards.
+// The memory reserved in the queue budget is released.
+func (e *Event) Release() {
+	queueBudget.Release(e.release())
+}
+
+func (e *Event) release() int64 {
+	if (e.Flags & RecycleFields) != 0 {
+		common.ReleaseMapStr(e.Content.Fields)
+		e.Content.Fields = nil
+		e.Flags &^= RecycleFields
+	}
+	reserved := e.Reserved
+	e.Reserved = 0
+	return reserved
+}
+
+// Resources records the resources held by a set of events, so they are
+// released exactly once even if the events are filtered in place afterwards,
+// leaving duplicates in their slice.
+type Resources struct {
+	fields   []common.MapStr
+	reserved int64
+}
+
+// CollectResources records the resources held by events.
+func CollectResources(events []Event) Resources {
+	var r Resources
+	for i := range events {
+		e := &events[i]
+		if (e.Flags & RecycleFields) != 0 {
+			if r.fields == nil {
+				r.fields = make([]common.MapStr, 0, len(events)-i)
+			}
+			r.fields = append(r.fields, e.Content.Fields)
+		}
+		r.reserved += e.Reserved
+	}
+	return r
+}
+
+// Release returns the recorded fields to the MapStr pool and releases the
+// memory reserved in the queue budget. The fields must not be used afterwards.
+func (r *Resources) Release() {
+	for _, fields := range r.fields {
+		common.ReleaseMapStr(fields)
+	}
+	queueBudget.Release(r.reserved)
+	*r = Resources{}
+}
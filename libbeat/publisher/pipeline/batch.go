@@ -20,6 +20,7 @@ package pipeline
 import (
 	"sync"
 
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
 )
@@ -35,11 +36,33 @@ type batch struct {
 	ctx      *batchContext
 	ttl      int
 	events   []publisher.Event
+
+	// resources are held by the events of the original batch, and released
+	// once it's ACKed. They are recorded when the batch is created, as the
+	// events are filtered in place on retries.
+	resources publisher.Resources
+
+	// split is shared by the batches split from the same original batch, it's
+	// nil if the batch was not split.
+	split *batchSplit
+
+	// inFlight tracks the batch while an output worker publishes it, it's nil
+	// if no worker has the batch.
+	inFlight *inFlight
+}
+
+// batchSplit counts the batches split from an original batch not ACKed or
+// dropped yet. The original batch is ACKed once all of them are.
+type batchSplit struct {
+	original  queue.Batch
+	resources publisher.Resources
+	pending   atomic.Int
 }
 
 type batchContext struct {
-	observer outputObserver
-	retryer  *retryer
+	observer   outputObserver
+	retryer    *retryer
+	deadLetter *deadLetterQueue
 }
 
 var batchPool = sync.Pool{
@@ -60,6 +83,7 @@ func newBatch(ctx *batchContext, original queue.Batch, ttl int) *batch {
 		ttl:      ttl,
 		events:   original.Events(),
 	}
+	b.resources = publisher.CollectResources(b.events)
 	return b
 }
 
@@ -76,20 +100,73 @@ func (b *batch) ACK() {
 	if b.ctx != nil {
 		b.ctx.observer.outBatchACKed(len(b.events))
 	}
-	b.original.ACK()
-	releaseBatch(b)
+	b.done()
 }
 
 func (b *batch) Drop() {
-	b.original.ACK()
+	b.done()
+}
+
+// done ACKs the original batch, once all the batches split from it are done.
+func (b *batch) done() {
+	b.untrack()
+	if b.split == nil {
+		b.resources.Release()
+		b.original.ACK()
+	} else if b.split.pending.Dec() == 0 {
+		b.split.resources.Release()
+		b.original.ACK()
+	}
 	releaseBatch(b)
 }
 
+// SplitRetry implements publisher.SplitBatch. The halves are retried without
+// reducing their time to live, as the events did not fail.
+func (b *batch) SplitRetry(events []publisher.Event) bool {
+	if len(events) < 2 {
+		return false
+	}
+	b.events = events
+
+	if b.split == nil {
+		b.split = &batchSplit{original: b.original, resources: b.resources}
+		b.split.pending.Store(1)
+		b.resources = publisher.Resources{}
+	}
+	b.split.pending.Inc()
+
+	mid := len(b.events) / 2
+	other := batchPool.Get().(*batch)
+	*other = batch{
+		original: b.original,
+		ctx:      b.ctx,
+		ttl:      b.ttl,
+		events:   b.events[mid:],
+		split:    b.split,
+	}
+	b.events = b.events[:mid:mid]
+
+	b.untrack()
+	b.ctx.retryer.cancelled(b)
+	b.ctx.retryer.cancelled(other)
+	return true
+}
+
+// DeadLetter implements publisher.DeadLetterBatch, writing the event to the
+// dead letter queue if it's enabled.
+func (b *batch) DeadLetter(event publisher.Event, reason string) {
+	if b.ctx != nil {
+		b.ctx.deadLetter.write(event, reason)
+	}
+}
+
 func (b *batch) Retry() {
+	b.untrack()
 	b.ctx.retryer.retry(b)
 }
 
 func (b *batch) Cancelled() {
+	b.untrack()
 	b.ctx.retryer.cancelled(b)
 }
 
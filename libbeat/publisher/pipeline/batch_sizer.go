@@ -0,0 +1,127 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// AdaptiveBatchConfig configures the adaptive sizing of the batches passed
+// to the output workers. The batch size grows by Step events after each full
+// batch published within TargetLatency, up to the batch size of the output,
+// and it's halved after each failed or slower batch, down to MinSize.
+type AdaptiveBatchConfig struct {
+	Enabled       bool          `config:"enabled"`
+	MinSize       int           `config:"min_size" validate:"min=1"`
+	Step          int           `config:"step" validate:"min=1"`
+	TargetLatency time.Duration `config:"target_latency" validate:"positive,nonzero"`
+}
+
+func defaultAdaptiveBatchConfig() AdaptiveBatchConfig {
+	return AdaptiveBatchConfig{
+		Enabled:       false,
+		MinSize:       16,
+		Step:          32,
+		TargetLatency: time.Second,
+	}
+}
+
+// Unpack implements the config unpacker, setting the defaults.
+func (c *AdaptiveBatchConfig) Unpack(from *common.Config) error {
+	type tmpConfig AdaptiveBatchConfig
+	tmp := tmpConfig(defaultAdaptiveBatchConfig())
+	if err := from.Unpack(&tmp); err != nil {
+		return err
+	}
+
+	*c = AdaptiveBatchConfig(tmp)
+	return nil
+}
+
+// batchSizer adjusts the batch size of an output group to the latency and
+// the errors of the publish attempts of its workers, like the congestion
+// window of TCP: additive increase, multiplicative decrease. The batch size
+// starts at the maximum, so the throughput is the same as without adaptive
+// sizing until the output slows down.
+type batchSizer struct {
+	config   AdaptiveBatchConfig
+	max      int
+	observer outputObserver
+
+	mu   sync.Mutex
+	size int
+}
+
+// newBatchSizer returns nil if adaptive sizing is disabled, or if the batch
+// size of the output is not limited.
+func newBatchSizer(config AdaptiveBatchConfig, max int, observer outputObserver) *batchSizer {
+	if !config.Enabled || max <= 0 {
+		return nil
+	}
+	if config.MinSize > max {
+		config.MinSize = max
+	}
+	observer.batchSizeUpdated(max)
+	return &batchSizer{
+		config:   config,
+		max:      max,
+		observer: observer,
+		size:     max,
+	}
+}
+
+// current returns the size of the next batch.
+func (s *batchSizer) current() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// observe records a publish attempt of a batch of the given number of events.
+// The size grows only after full batches, so the size of an output receiving
+// few events doesn't grow without being tested.
+func (s *batchSizer) observe(events int, latency time.Duration, err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	size := s.size
+	switch {
+	case err != nil || latency > s.config.TargetLatency:
+		size /= 2
+		if size < s.config.MinSize {
+			size = s.config.MinSize
+		}
+	case events >= s.size:
+		size += s.config.Step
+		if size > s.max {
+			size = s.max
+		}
+	}
+
+	if size != s.size {
+		s.size = size
+		s.observer.batchSizeUpdated(size)
+	}
+}
@@ -0,0 +1,110 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+)
+
+func TestAdaptiveBatchConfigDefaults(t *testing.T) {
+	var config Config
+	require.NoError(t, common.MustNewConfigFrom(`pipeline.adaptive_batch.enabled: true`).Unpack(&config))
+	assert.Equal(t, AdaptiveBatchConfig{
+		Enabled:       true,
+		MinSize:       16,
+		Step:          32,
+		TargetLatency: time.Second,
+	}, config.AdaptiveBatch)
+
+	err := common.MustNewConfigFrom(`pipeline.adaptive_batch.min_size: 0`).Unpack(&config)
+	assert.Error(t, err)
+}
+
+func TestBatchSizer(t *testing.T) {
+	config := AdaptiveBatchConfig{
+		Enabled:       true,
+		MinSize:       10,
+		Step:          20,
+		TargetLatency: time.Second,
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Nil(t, newBatchSizer(AdaptiveBatchConfig{}, 100, nilObserver))
+		assert.Nil(t, newBatchSizer(config, 0, nilObserver))
+	})
+
+	t.Run("decrease and increase", func(t *testing.T) {
+		s := newBatchSizer(config, 100, nilObserver)
+		assert.Equal(t, 100, s.current())
+
+		// slow and failed batches halve the size
+		s.observe(100, 2*time.Second, nil)
+		assert.Equal(t, 50, s.current())
+		s.observe(50, time.Millisecond, errors.New("oops"))
+		assert.Equal(t, 25, s.current())
+		s.observe(25, 2*time.Second, nil)
+		s.observe(12, 2*time.Second, nil)
+		assert.Equal(t, 10, s.current())
+
+		// partial batches don't grow the size
+		s.observe(5, time.Millisecond, nil)
+		assert.Equal(t, 10, s.current())
+
+		// full batches grow it up to the maximum
+		for _, expected := range []int{30, 50, 70, 90, 100, 100} {
+			s.observe(s.current(), time.Millisecond, nil)
+			assert.Equal(t, expected, s.current())
+		}
+	})
+
+	t.Run("min above max", func(t *testing.T) {
+		s := newBatchSizer(config, 4, nilObserver)
+		s.observe(4, 2*time.Second, nil)
+		assert.Equal(t, 4, s.current())
+	})
+
+	t.Run("converges", func(t *testing.T) {
+		// the latency is proportional to the batch size, the target is
+		// reached at 400 events
+		s := newBatchSizer(config, 1600, nilObserver)
+		for i := 0; i < 1000; i++ {
+			size := s.current()
+			s.observe(size, time.Duration(size)*time.Second/400, nil)
+		}
+		assert.True(t, s.current() >= 200 && s.current() <= 420, "size %d", s.current())
+	})
+
+	t.Run("metrics", func(t *testing.T) {
+		reg := monitoring.NewRegistry()
+		s := newBatchSizer(config, 100, newMetricsObserver(reg))
+		s.observe(100, 2*time.Second, nil)
+		s.observe(50, time.Millisecond, nil)
+
+		snapshot := monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+		assert.Equal(t, int64(70), snapshot.Ints["pipeline.adaptive_batch.size"])
+		assert.Equal(t, int64(3), snapshot.Ints["pipeline.adaptive_batch.updates"])
+	})
+}
@@ -0,0 +1,123 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/acker"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/memgov"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+func TestBatchSplitRetry(t *testing.T) {
+	pipeline, err := New(beat.Info{},
+		Monitors{},
+		makeTestMemQueue,
+		outputs.Group{},
+		Settings{},
+	)
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	// the output accepts batches of up to 3 events, and drops the events
+	// with the drop field
+	var published, dropped, splits atomic.Int
+	pipeline.output.Set(outputs.Group{
+		BatchSize: 20,
+		Clients: []outputs.Client{
+			newMockClient(func(batch publisher.Batch) error {
+				events := batch.Events()
+				if len(events) > 3 {
+					require.True(t, publisher.SplitRetry(batch, events))
+					splits.Inc()
+					return nil
+				}
+				for _, event := range events {
+					if event.Content.Fields["drop"] == true {
+						if publisher.SplitRetry(batch, events) {
+							return nil
+						}
+						dropped.Inc()
+						batch.ACK()
+						return nil
+					}
+				}
+				published.Add(len(events))
+				batch.ACK()
+				return nil
+			}),
+		},
+	})
+
+	var acked atomic.Int
+	client, err := pipeline.ConnectWith(beat.ClientConfig{
+		ACKHandler: acker.RawCounting(func(n int) { acked.Add(n) }),
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	for i := 0; i < 20; i++ {
+		client.Publish(beat.Event{Fields: common.MapStr{"drop": i == 7}})
+	}
+
+	assert.Eventually(t, func() bool {
+		return acked.Load() == 20
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 19, published.Load())
+	assert.Equal(t, 1, dropped.Load())
+	assert.True(t, splits.Load() > 0)
+}
+
+func TestBatchReleasesEventsOnce(t *testing.T) {
+	budget := memgov.Get(memgov.QueueBudget)
+	used := budget.Used()
+	require.True(t, budget.TryReserve(1+2+4))
+
+	fields := []common.MapStr{{"n": 1}, {"n": 2}, {"n": 3}}
+	events := make([]publisher.Event, len(fields))
+	for i := range fields {
+		events[i] = publisher.Event{
+			Content:  beat.Event{Fields: fields[i]},
+			Flags:    publisher.RecycleFields,
+			Reserved: int64(1) << i,
+		}
+	}
+
+	b := newBatch(&batchContext{observer: nilObserver}, &mockBatch{events: events}, 3)
+
+	// retry the last two events, filtering them in place like the outputs
+	// do, this leaves the last event twice in the events of the queue batch
+	retry := b.Events()[:0]
+	retry = append(retry, events[1], events[2])
+	b.updEvents(retry)
+	b.ACK()
+
+	for _, m := range fields {
+		assert.Len(t, m, 0, "the fields of all events are released")
+	}
+	assert.Equal(t, used, budget.Used(), "the memory of each event is released once")
+}
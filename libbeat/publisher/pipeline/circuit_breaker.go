@@ -0,0 +1,121 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// CircuitBreakerConfig configures the circuit breaker of the output workers.
+// After Failures consecutive publish failures a worker opens its circuit and
+// stops consuming batches for Cooldown, leaving them to the other workers.
+type CircuitBreakerConfig struct {
+	Enabled  bool          `config:"enabled"`
+	Failures int           `config:"failures" validate:"min=1"`
+	Cooldown time.Duration `config:"cooldown" validate:"positive,nonzero"`
+}
+
+func defaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		Enabled:  false,
+		Failures: 5,
+		Cooldown: 30 * time.Second,
+	}
+}
+
+// Unpack implements the config unpacker, setting the defaults.
+func (c *CircuitBreakerConfig) Unpack(from *common.Config) error {
+	type tmpConfig CircuitBreakerConfig
+	tmp := tmpConfig(defaultCircuitBreakerConfig())
+	if err := from.Unpack(&tmp); err != nil {
+		return err
+	}
+
+	*c = CircuitBreakerConfig(tmp)
+	return nil
+}
+
+// circuitBreaker counts the consecutive publish failures of an output worker.
+// When the circuit is open the worker waits for the cooldown before it
+// consumes a new batch. After the cooldown the circuit is half-open: it
+// closes on the next success, and opens again on the next failure.
+type circuitBreaker struct {
+	config   CircuitBreakerConfig
+	observer outputObserver
+	logger   logger
+	client   string
+
+	failures int
+	halfOpen bool
+}
+
+func newCircuitBreaker(config CircuitBreakerConfig, observer outputObserver, logger logger, client string) *circuitBreaker {
+	if !config.Enabled {
+		return nil
+	}
+	return &circuitBreaker{
+		config:   config,
+		observer: observer,
+		logger:   logger,
+		client:   client,
+	}
+}
+
+// success records a successful publish attempt.
+func (b *circuitBreaker) success() {
+	if b == nil {
+		return
+	}
+	if b.halfOpen {
+		b.logger.Infof("Circuit breaker of %v closed", b.client)
+		b.halfOpen = false
+	}
+	b.failures = 0
+}
+
+// failure records a failed publish attempt. If the circuit opens, it blocks
+// until the end of the cooldown or until done is closed, and returns false if
+// done was closed.
+func (b *circuitBreaker) failure(done <-chan struct{}) bool {
+	if b == nil {
+		return true
+	}
+
+	b.failures++
+	if !b.halfOpen && b.failures < b.config.Failures {
+		return true
+	}
+
+	b.logger.Errorf("Circuit breaker of %v opened after %d consecutive failures, pausing for %v",
+		b.client, b.failures, b.config.Cooldown)
+	b.observer.circuitOpened()
+	defer b.observer.circuitCooledDown()
+
+	timer := time.NewTimer(b.config.Cooldown)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return false
+	case <-timer.C:
+	}
+
+	b.halfOpen = true
+	return true
+}
@@ -0,0 +1,154 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+func TestCircuitBreakerConfig(t *testing.T) {
+	var config Config
+	err := common.MustNewConfigFrom(map[string]interface{}{
+		"pipeline.circuit_breaker.enabled": true,
+	}).Unpack(&config)
+	require.NoError(t, err)
+	assert.Equal(t, CircuitBreakerConfig{Enabled: true, Failures: 5, Cooldown: 30 * time.Second}, config.CircuitBreaker)
+
+	err = common.MustNewConfigFrom(map[string]interface{}{
+		"pipeline.circuit_breaker.failures": 0,
+	}).Unpack(&config)
+	assert.Error(t, err)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	observer := &circuitObserver{}
+	config := CircuitBreakerConfig{Enabled: true, Failures: 3, Cooldown: 20 * time.Millisecond}
+	b := newCircuitBreaker(config, observer, makeBufLogger(t), "test")
+
+	done := make(chan struct{})
+	defer close(done)
+
+	// Failures are only counted if they are consecutive.
+	assert.True(t, b.failure(done))
+	assert.True(t, b.failure(done))
+	b.success()
+	assert.True(t, b.failure(done))
+	assert.True(t, b.failure(done))
+	assert.Equal(t, 0, observer.opened)
+
+	start := time.Now()
+	assert.True(t, b.failure(done))
+	assert.True(t, time.Since(start) >= config.Cooldown)
+	assert.Equal(t, 1, observer.opened)
+	assert.Equal(t, 0, observer.open)
+
+	// A failure after the cooldown opens the circuit again.
+	assert.True(t, b.failure(done))
+	assert.Equal(t, 2, observer.opened)
+
+	// A success after the cooldown closes it.
+	b.success()
+	assert.True(t, b.failure(done))
+	assert.Equal(t, 2, observer.opened)
+}
+
+func TestCircuitBreakerClose(t *testing.T) {
+	config := CircuitBreakerConfig{Enabled: true, Failures: 1, Cooldown: time.Hour}
+	b := newCircuitBreaker(config, nilObserver, makeBufLogger(t), "test")
+
+	done := make(chan struct{})
+	close(done)
+	assert.False(t, b.failure(done))
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := newCircuitBreaker(CircuitBreakerConfig{}, nilObserver, makeBufLogger(t), "test")
+	require.Nil(t, b)
+
+	// A disabled circuit breaker never opens.
+	for i := 0; i < 10; i++ {
+		assert.True(t, b.failure(nil))
+	}
+	b.success()
+}
+
+func TestNetClientWorkerCircuitBreaker(t *testing.T) {
+	config := workerConfig{
+		backoff: outputs.BackoffConfig{Init: time.Millisecond, Max: time.Millisecond},
+		circuitBreaker: CircuitBreakerConfig{
+			Enabled:  true,
+			Failures: 2,
+			Cooldown: 200 * time.Millisecond,
+		},
+	}
+
+	logger := makeBufLogger(t)
+	wqu := makeWorkQueue()
+
+	var attempts atomic.Int
+	client := newMockNetworkClient(func(batch publisher.Batch) error {
+		attempts.Inc()
+		return errors.New("publish failed")
+	})
+
+	worker := makeClientWorker(nilObserver, wqu, client, config, logger, nil)
+	defer worker.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case wqu <- randomBatch(1, 2):
+			}
+		}
+	}()
+
+	require.True(t, waitUntilTrue(time.Second, func() bool { return attempts.Load() == 2 }))
+
+	// The worker doesn't consume batches during the cooldown.
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 2, attempts.Load())
+
+	// It tries again after the cooldown.
+	assert.True(t, waitUntilTrue(time.Second, func() bool { return attempts.Load() > 2 }))
+}
+
+type circuitObserver struct {
+	*emptyObserver
+	opened, open int
+}
+
+func (o *circuitObserver) circuitOpened() {
+	o.opened++
+	o.open++
+}
+
+func (o *circuitObserver) circuitCooledDown() { o.open-- }
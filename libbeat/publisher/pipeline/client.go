@@ -24,11 +24,16 @@ import (
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common/atomic"
 	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/memgov"
+	"github.com/elastic/beats/v7/libbeat/privacy"
 	"github.com/elastic/beats/v7/libbeat/processors"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
 )
 
+// queueBudget limits the memory used by the events published to the queue.
+var queueBudget = memgov.Get(memgov.QueueBudget)
+
 // client connects a beat with the processors and pipeline queue.
 //
 // TODO: All ackers currently drop any late incoming ACK. Some beats still might
@@ -123,6 +128,11 @@ func (c *client) publish(e beat.Event) {
 		Flags:   c.eventFlags,
 	}
 
+	if queueBudget.Active() && !c.reserve(&pubEvent) {
+		c.onDroppedOnPublish(e)
+		return
+	}
+
 	if c.reportEvents {
 		c.pipeline.waitCloser.inc()
 	}
@@ -137,6 +147,7 @@ func (c *client) publish(e beat.Event) {
 	if published {
 		c.onPublished()
 	} else {
+		queueBudget.Release(pubEvent.Reserved)
 		c.onDroppedOnPublish(e)
 		if c.reportEvents {
 			c.pipeline.waitCloser.dec(1)
@@ -144,6 +155,24 @@ func (c *client) publish(e beat.Event) {
 	}
 }
 
+// reserve reserves the memory used by the event in the queue budget. Unless
+// the client can drop events, it waits for the memory to be available. It
+// returns false if the memory could not be reserved.
+func (c *client) reserve(event *publisher.Event) bool {
+	size := memgov.EventSize(&event.Content)
+
+	var ok bool
+	if c.canDrop {
+		ok = queueBudget.TryReserve(size)
+	} else {
+		ok = queueBudget.Reserve(c.done, size)
+	}
+	if ok {
+		event.Reserved = size
+	}
+	return ok
+}
+
 func (c *client) Close() error {
 	log := c.logger()
 
@@ -228,7 +257,7 @@ func (c *client) onPublished() {
 func (c *client) onFilteredOut(e beat.Event) {
 	log := c.logger()
 
-	log.Debugf("Pipeline client receives callback 'onFilteredOut' for event: %+v", e)
+	log.Debugf("Pipeline client receives callback 'onFilteredOut' for event: %+v", privacy.LazyEvent(&e))
 	c.pipeline.observer.filteredEvent()
 	if c.eventer != nil {
 		c.eventer.FilteredOut(e)
@@ -238,7 +267,7 @@ func (c *client) onFilteredOut(e beat.Event) {
 func (c *client) onDroppedOnPublish(e beat.Event) {
 	log := c.logger()
 
-	log.Debugf("Pipeline client receives callback 'onDroppedOnPublish' for event: %+v", e)
+	log.Debugf("Pipeline client receives callback 'onDroppedOnPublish' for event: %+v", privacy.LazyEvent(&e))
 	c.pipeline.observer.failedPublishEvent()
 	if c.eventer != nil {
 		c.eventer.DroppedOnPublish(e)
@@ -23,8 +23,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+
 	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/memgov"
 	"github.com/elastic/beats/v7/libbeat/outputs"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
@@ -205,3 +209,122 @@ func TestClientWaitClose(t *testing.T) {
 		}
 	})
 }
+
+func TestClientRecycleFields(t *testing.T) {
+	if testing.Verbose() {
+		logp.TestingSetup()
+	}
+
+	q := memqueue.NewQueue(logp.L(), memqueue.Settings{Events: 1})
+	pipeline, err := New(beat.Info{},
+		Monitors{},
+		func(queue.ACKListener) (queue.Queue, error) { return q, nil },
+		outputs.Group{},
+		Settings{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pipeline.Close()
+
+	published := make(chan publisher.Event, 1)
+	acked := make(chan struct{})
+	output := newMockClient(func(batch publisher.Batch) error {
+		for _, event := range batch.Events() {
+			published <- event
+		}
+		batch.ACK()
+		close(acked)
+		return nil
+	})
+	defer output.Close()
+	pipeline.output.Set(outputs.Group{Clients: []outputs.Client{output}})
+	defer pipeline.output.Set(outputs.Group{})
+
+	client, err := pipeline.ConnectWith(beat.ClientConfig{
+		RecycleFields: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	fields := common.MapStr{"message": "hello"}
+	client.Publish(beat.Event{Fields: fields})
+
+	select {
+	case event := <-published:
+		assert.Equal(t, publisher.RecycleFields, event.Flags&publisher.RecycleFields)
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the event to be published")
+	}
+
+	// The fields are released once the batch has been acknowledged.
+	select {
+	case <-acked:
+		assert.Len(t, fields, 0)
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the batch to be acknowledged")
+	}
+}
+
+func TestClientQueueBudget(t *testing.T) {
+	if testing.Verbose() {
+		logp.TestingSetup()
+	}
+
+	err := memgov.Start(common.MustNewConfigFrom(common.MapStr{
+		"enabled":       true,
+		"limit":         1 << 30,
+		"budgets.queue": 0.5,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer memgov.Stop()
+
+	q := memqueue.NewQueue(logp.L(), memqueue.Settings{Events: 1})
+	pipeline, err := New(beat.Info{},
+		Monitors{},
+		func(queue.ACKListener) (queue.Queue, error) { return q, nil },
+		outputs.Group{},
+		Settings{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pipeline.Close()
+
+	published := make(chan publisher.Event, 1)
+	output := newMockClient(func(batch publisher.Batch) error {
+		for _, event := range batch.Events() {
+			published <- event
+		}
+		assert.True(t, memgov.Get(memgov.QueueBudget).Used() > 0)
+		batch.ACK()
+		return nil
+	})
+	defer output.Close()
+	pipeline.output.Set(outputs.Group{Clients: []outputs.Client{output}})
+	defer pipeline.output.Set(outputs.Group{})
+
+	client, err := pipeline.Connect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Publish(beat.Event{Fields: common.MapStr{"message": "hello"}})
+
+	select {
+	case event := <-published:
+		assert.True(t, event.Reserved > 0)
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected the event to be published")
+	}
+
+	// The memory is released once the batch has been acknowledged.
+	assert.Eventually(t, func() bool {
+		return memgov.Get(memgov.QueueBudget).Used() == 0
+	}, 10*time.Second, 10*time.Millisecond)
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs"
 	"github.com/elastic/beats/v7/libbeat/processors"
 )
 
@@ -34,6 +35,49 @@ type Config struct {
 
 	// Event queue
 	Queue common.ConfigNamespace `config:"queue"`
+
+	// Shards is the number of queues the clients are distributed between.
+	Shards int `config:"pipeline.shards" validate:"min=0"`
+
+	// CircuitBreaker pauses the output workers failing to publish.
+	CircuitBreaker CircuitBreakerConfig `config:"pipeline.circuit_breaker"`
+
+	// AdaptiveBatch adjusts the batch size to the latency of the outputs.
+	AdaptiveBatch AdaptiveBatchConfig `config:"pipeline.adaptive_batch"`
+
+	// RateLimit limits the rate of the events published to all outputs.
+	RateLimit outputs.RateLimitConfig `config:"pipeline.rate_limit"`
+
+	// DeadLetterQueue stores the events rejected by the outputs.
+	DeadLetterQueue DeadLetterQueueConfig `config:"pipeline.dead_letter_queue"`
+
+	// ShutdownSpool writes the events not ACKed on shutdown to disk, and
+	// publishes them on the next start.
+	ShutdownSpool ShutdownSpoolConfig `config:"pipeline.shutdown_spool"`
+
+	// Priority publishes the events with a high priority before the other
+	// events.
+	Priority PriorityConfig `config:"pipeline.priority"`
+
+	// Routes publish the events matching their conditions to other outputs
+	// than the default one.
+	Routes []RouteConfig `config:"pipeline.routes"`
+
+	// Mirrors publish a copy of the events matching their conditions to
+	// other outputs, in addition to the default output or the output of
+	// their route.
+	Mirrors []MirrorConfig `config:"pipeline.mirrors"`
+}
+
+// Validate checks that sharding is only enabled with the memory queue, and
+// that the routes and mirrors are valid.
+func (c *Config) Validate() error {
+	if c.Shards > 1 {
+		if name := c.Queue.Name(); name != "" && name != defaultQueueType {
+			return fmt.Errorf("pipeline.shards is only supported by the '%v' queue, not by '%v'", defaultQueueType, name)
+		}
+	}
+	return validateRoutes(c.Routes, c.Mirrors)
 }
 
 // validateClientConfig checks a ClientConfig can be used with (*Pipeline).ConnectWith.
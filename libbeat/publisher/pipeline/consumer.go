@@ -18,6 +18,7 @@
 package pipeline
 
 import (
+	"context"
 	"errors"
 	"sync"
 
@@ -31,8 +32,8 @@ import (
 // from the retryer in case of too many events failing to be send or if retryer
 // is receiving cancelled batches from outputs to be closed on output reloading.
 type eventConsumer struct {
-	logger *logp.Logger
-	ctx    *batchContext
+	logger   *logp.Logger
+	batchCtx *batchContext
 
 	pause atomic.Bool
 	wait  atomic.Bool
@@ -43,6 +44,10 @@ type eventConsumer struct {
 	consumer queue.Consumer
 
 	out *outputGroup
+
+	// ctx is cancelled on close, to stop waiting for the rate limits.
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 type consumerSignal struct {
@@ -65,9 +70,10 @@ var errStopped = errors.New("stopped")
 func newEventConsumer(
 	log *logp.Logger,
 	queue queue.Queue,
-	ctx *batchContext,
+	batchCtx *batchContext,
 ) *eventConsumer {
 	consumer := queue.Consumer()
+	ctx, cancel := context.WithCancel(context.Background())
 	c := &eventConsumer{
 		logger: log,
 		sig:    make(chan consumerSignal, 3),
@@ -75,7 +81,10 @@ func newEventConsumer(
 
 		queue:    queue,
 		consumer: consumer,
-		ctx:      ctx,
+		batchCtx: batchCtx,
+
+		ctx:    ctx,
+		cancel: cancel,
 	}
 
 	c.pause.Store(true)
@@ -89,6 +98,7 @@ func newEventConsumer(
 }
 
 func (c *eventConsumer) close() {
+	c.cancel()
 	c.consumer.Close()
 	c.sig <- consumerSignal{tag: sigStop}
 	c.wg.Wait()
@@ -179,14 +189,18 @@ func (c *eventConsumer) loop(consumer queue.Consumer) {
 	for {
 		if !paused && c.out != nil && consumer != nil && batch == nil {
 			out = c.out.workQueue
-			queueBatch, err := consumer.Get(c.out.batchSize)
+			queueBatch, err := consumer.Get(c.out.nextBatchSize())
 			if err != nil {
 				out = nil
 				consumer = nil
 				continue
 			}
 			if queueBatch != nil {
-				batch = newBatch(c.ctx, queueBatch, c.out.timeToLive)
+				if err := c.out.waitRateLimits(c.ctx, len(queueBatch.Events())); err != nil {
+					// closing, the batch is not ACKed
+					continue
+				}
+				batch = newBatch(c.batchCtx, queueBatch, c.out.timeToLive)
 			}
 
 			paused = c.paused()
@@ -18,6 +18,9 @@
 package pipeline
 
 import (
+	"context"
+	"sync"
+
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/reload"
@@ -41,7 +44,18 @@ type outputController struct {
 
 	retryer  *retryer
 	consumer *eventConsumer
-	out      *outputGroup
+
+	mu  sync.Mutex // protects out, for inspecting the workers
+	out *outputGroup
+
+	// high consumes the queue of the high priority events, it's nil if the
+	// priorities are disabled.
+	high *priorityLevel
+
+	circuitBreaker CircuitBreakerConfig
+	adaptiveBatch  AdaptiveBatchConfig
+	rateLimit      *rateLimiter // shared by all outputs of the pipeline
+	deadLetter     *deadLetterQueue
 }
 
 // outputGroup configures a group of load balanced outputs with shared work queue.
@@ -50,7 +64,12 @@ type outputGroup struct {
 	outputs   []outputWorker
 
 	batchSize  int
-	timeToLive int // event lifetime
+	batchSizer *batchSizer // nil if the batch size is fixed
+	timeToLive int         // event lifetime
+
+	// rateLimits are the rate limiters of the pipeline and of the output,
+	// nil if the rate is not limited.
+	rateLimits []*rateLimiter
 }
 
 type workQueue chan publisher.Batch
@@ -59,6 +78,11 @@ type workQueue chan publisher.Batch
 // instances.
 type outputWorker interface {
 	Close() error
+	String() string
+
+	// inFlightState reports the batches published by the worker and not
+	// ACKed yet.
+	inFlightState() workerState
 }
 
 func newOutputController(
@@ -66,16 +90,24 @@ func newOutputController(
 	monitors Monitors,
 	observer outputObserver,
 	queue queue.Queue,
+	circuitBreaker CircuitBreakerConfig,
+	adaptiveBatch AdaptiveBatchConfig,
+	rateLimit *rateLimiter,
+	deadLetter *deadLetterQueue,
 ) *outputController {
 	c := &outputController{
-		beat:      beat,
-		monitors:  monitors,
-		observer:  observer,
-		queue:     queue,
-		workQueue: makeWorkQueue(),
+		beat:           beat,
+		monitors:       monitors,
+		observer:       observer,
+		queue:          queue,
+		workQueue:      makeWorkQueue(),
+		circuitBreaker: circuitBreaker,
+		adaptiveBatch:  adaptiveBatch,
+		rateLimit:      rateLimit,
+		deadLetter:     deadLetter,
 	}
 
-	ctx := &batchContext{}
+	ctx := &batchContext{deadLetter: deadLetter}
 	c.consumer = newEventConsumer(monitors.Logger, queue, ctx)
 	c.retryer = newRetryer(monitors.Logger, observer, c.workQueue, c.consumer)
 	ctx.observer = observer
@@ -91,6 +123,9 @@ func (c *outputController) Close() error {
 	c.consumer.close()
 	c.retryer.close()
 	close(c.workQueue)
+	if c.high != nil {
+		c.high.close()
+	}
 
 	if c.out != nil {
 		for _, out := range c.out.outputs {
@@ -104,29 +139,60 @@ func (c *outputController) Close() error {
 func (c *outputController) Set(outGrp outputs.Group) {
 	// create new output group with the shared work queue
 	clients := outGrp.Clients
+	sizer := newBatchSizer(c.adaptiveBatch, outGrp.BatchSize, c.observer)
+	config := workerConfig{
+		backoff:        outputs.DefaultBackoffConfig(),
+		circuitBreaker: c.circuitBreaker,
+		batchSizer:     sizer,
+	}
+	if outGrp.Backoff != nil {
+		config.backoff = *outGrp.Backoff
+	}
+	if c.high != nil {
+		config.highQueue = c.high.workQueue
+		config.highWeight = c.high.weight
+	}
 	worker := make([]outputWorker, len(clients))
 	for i, client := range clients {
 		logger := logp.NewLogger("publisher_pipeline_output")
-		worker[i] = makeClientWorker(c.observer, c.workQueue, client, logger, c.monitors.Tracer)
+		worker[i] = makeClientWorker(c.observer, c.workQueue, client, config, logger, c.monitors.Tracer)
 	}
 	grp := &outputGroup{
 		workQueue:  c.workQueue,
 		outputs:    worker,
 		timeToLive: outGrp.Retry + 1,
 		batchSize:  outGrp.BatchSize,
+		batchSizer: sizer,
+	}
+	for _, l := range []*rateLimiter{c.rateLimit, newRateLimiter(outGrp.RateLimit)} {
+		if l != nil {
+			grp.rateLimits = append(grp.rateLimits, l)
+		}
 	}
 
 	// update consumer and retryer
 	c.consumer.sigPause()
+	if c.high != nil {
+		c.high.consumer.sigPause()
+	}
 	if c.out != nil {
 		for range c.out.outputs {
 			c.retryer.sigOutputRemoved()
+			if c.high != nil {
+				c.high.retryer.sigOutputRemoved()
+			}
 		}
 	}
 	for range clients {
 		c.retryer.sigOutputAdded()
+		if c.high != nil {
+			c.high.retryer.sigOutputAdded()
+		}
 	}
 	c.consumer.updOutput(grp)
+	if c.high != nil {
+		c.high.updOutput(grp)
+	}
 
 	// close old group, so events are send to new workQueue via retryer
 	if c.out != nil {
@@ -135,14 +201,38 @@ func (c *outputController) Set(outGrp outputs.Group) {
 		}
 	}
 
+	c.mu.Lock()
 	c.out = grp
+	c.mu.Unlock()
 
 	// restart consumer (potentially blocked by retryer)
 	c.consumer.sigContinue()
+	if c.high != nil {
+		c.high.consumer.sigContinue()
+	}
 
 	c.observer.updateOutputGroup()
 }
 
+// nextBatchSize returns the maximum number of events of the next batch.
+func (g *outputGroup) nextBatchSize() int {
+	if g.batchSizer != nil {
+		return g.batchSizer.current()
+	}
+	return g.batchSize
+}
+
+// waitRateLimits blocks until n events can be passed to the workers of the
+// group, or until ctx is cancelled.
+func (g *outputGroup) waitRateLimits(ctx context.Context, n int) error {
+	for _, l := range g.rateLimits {
+		if err := l.wait(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func makeWorkQueue() workQueue {
 	return workQueue(make(chan publisher.Batch, 0))
 }
@@ -0,0 +1,176 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cfgtype"
+	"github.com/elastic/beats/v7/libbeat/common/file"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/paths"
+	"github.com/elastic/beats/v7/libbeat/privacy"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// DeadLetterQueueConfig configures the dead letter queue, storing the events
+// rejected by the outputs with non-retryable errors.
+type DeadLetterQueueConfig struct {
+	Enabled bool `config:"enabled"`
+
+	// Path is the directory of the files of the queue, relative to the data
+	// path.
+	Path string `config:"path"`
+
+	// MaxSize is the size of a file of the queue before it's rotated.
+	MaxSize cfgtype.ByteSize `config:"max_size" validate:"min=1"`
+
+	// MaxFiles is the number of files kept, the oldest ones are removed.
+	MaxFiles uint `config:"max_files" validate:"min=2"`
+
+	// Permissions are the permissions of the files of the queue.
+	Permissions uint32 `config:"permissions"`
+}
+
+// deadLetterFileName is the name of the active file of the queue, rotated
+// files get a numeric suffix.
+const deadLetterFileName = "dead_letter.ndjson"
+
+func defaultDeadLetterQueueConfig() DeadLetterQueueConfig {
+	return DeadLetterQueueConfig{
+		Enabled:     false,
+		Path:        "dead_letter_queue",
+		MaxSize:     10 * 1024 * 1024,
+		MaxFiles:    7,
+		Permissions: 0600,
+	}
+}
+
+// Unpack implements the config unpacker, setting the defaults.
+func (c *DeadLetterQueueConfig) Unpack(from *common.Config) error {
+	type tmpConfig DeadLetterQueueConfig
+	tmp := tmpConfig(defaultDeadLetterQueueConfig())
+	if err := from.Unpack(&tmp); err != nil {
+		return err
+	}
+
+	*c = DeadLetterQueueConfig(tmp)
+	return nil
+}
+
+// deadLetterQueue writes the rejected events to files, as JSON encoded lines
+// that can be published again with the replay command. The reason of the
+// rejection is stored in the dead_letter field of the event.
+type deadLetterQueue struct {
+	log      *logp.Logger
+	observer outputObserver
+	index    string
+
+	mu      sync.Mutex
+	closed  bool
+	rotator *file.Rotator
+	codec   *json.Encoder
+}
+
+func newDeadLetterQueue(log *logp.Logger, info beat.Info, observer outputObserver, config DeadLetterQueueConfig) (*deadLetterQueue, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	path := filepath.Join(paths.Resolve(paths.Data, config.Path), deadLetterFileName)
+	rotator, err := file.NewFileRotator(
+		path,
+		file.MaxSizeBytes(uint(config.MaxSize)),
+		file.MaxBackups(config.MaxFiles),
+		file.Permissions(os.FileMode(config.Permissions)),
+		file.WithLogger(logp.NewLogger("rotator").With(logp.Namespace("rotator"))),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the dead letter queue: %w", err)
+	}
+
+	log.Infof("Dead letter queue writing to %v", path)
+	return &deadLetterQueue{
+		log:      log,
+		observer: observer,
+		index:    info.Beat,
+		rotator:  rotator,
+		codec:    json.New(info.Version, json.Config{}),
+	}, nil
+}
+
+// write stores an event rejected by an output. The fields of the event are
+// not modified, so they can still be released with the batch. If the privacy
+// mode is enabled, the sensitive fields are scrubbed in the stored event.
+func (q *deadLetterQueue) write(event publisher.Event, reason string) {
+	if q == nil {
+		return
+	}
+
+	content := *privacy.Event(&event.Content)
+	fields := make(common.MapStr, len(content.Fields)+1)
+	for k, v := range content.Fields {
+		fields[k] = v
+	}
+	fields["dead_letter"] = common.MapStr{
+		"reason":    reason,
+		"timestamp": time.Now().UTC(),
+	}
+	content.Fields = fields
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		q.log.Errorf("Dropping a rejected event, the dead letter queue is closed")
+		q.observer.deadLetterFailed()
+		return
+	}
+
+	line, err := q.codec.Encode(q.index, &content)
+	if err != nil {
+		q.log.Errorf("Failed to encode an event for the dead letter queue: %v", err)
+		q.observer.deadLetterFailed()
+		return
+	}
+	line = append(line, '\n')
+	if _, err := q.rotator.Write(line); err != nil {
+		q.log.Errorf("Failed to write an event to the dead letter queue: %v", err)
+		q.observer.deadLetterFailed()
+		return
+	}
+	q.observer.deadLetterWritten(len(line))
+}
+
+func (q *deadLetterQueue) close() error {
+	if q == nil {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	return q.rotator.Close()
+}
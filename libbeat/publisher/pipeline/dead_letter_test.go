@@ -0,0 +1,156 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/privacy"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+func TestDeadLetterQueueConfig(t *testing.T) {
+	var config Config
+	err := common.MustNewConfigFrom(map[string]interface{}{
+		"pipeline.dead_letter_queue.enabled": true,
+	}).Unpack(&config)
+	require.NoError(t, err)
+
+	expected := defaultDeadLetterQueueConfig()
+	expected.Enabled = true
+	assert.Equal(t, expected, config.DeadLetterQueue)
+}
+
+func TestDeadLetterQueue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dead-letter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	observer := &deadLetterObserver{}
+	config := defaultDeadLetterQueueConfig()
+	config.Enabled = true
+	config.Path = dir
+
+	info := beat.Info{Beat: "testbeat", Version: "1.2.3"}
+	q, err := newDeadLetterQueue(logp.NewLogger("test"), info, observer, config)
+	require.NoError(t, err)
+
+	ts := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	event := publisher.Event{Content: beat.Event{
+		Timestamp: ts,
+		Fields:    common.MapStr{"message": "hello", "nested": common.MapStr{"a": 1}},
+	}}
+
+	b := &batch{ctx: &batchContext{deadLetter: q}}
+	publisher.DeadLetter(b, event, "mapping conflict")
+	require.NoError(t, q.close())
+
+	// The fields of the original event are not modified.
+	assert.Equal(t, common.MapStr{"message": "hello", "nested": common.MapStr{"a": 1}}, event.Content.Fields)
+	assert.Equal(t, 1, observer.written)
+
+	// Writes after close are counted as failures.
+	q.write(event, "closed")
+	assert.Equal(t, 1, observer.failed)
+
+	f, err := os.Open(filepath.Join(dir, deadLetterFileName))
+	require.NoError(t, err)
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	require.True(t, scanner.Scan())
+	assert.Equal(t, len(scanner.Bytes())+1, observer.bytes)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &doc))
+	assert.Equal(t, "2020-10-01T12:00:00.000Z", doc["@timestamp"])
+	assert.Equal(t, "hello", doc["message"])
+	assert.Equal(t, "testbeat", doc["@metadata"].(map[string]interface{})["beat"])
+	deadLetter := doc["dead_letter"].(map[string]interface{})
+	assert.Equal(t, "mapping conflict", deadLetter["reason"])
+	assert.Contains(t, deadLetter, "timestamp")
+	assert.False(t, scanner.Scan())
+}
+
+func TestDeadLetterQueuePrivacy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dead-letter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	require.NoError(t, privacy.Configure(common.MustNewConfigFrom(map[string]interface{}{
+		"enabled": true,
+		"fields":  []string{"user.name"},
+	})))
+	defer privacy.Configure(nil)
+
+	config := defaultDeadLetterQueueConfig()
+	config.Enabled = true
+	config.Path = dir
+	q, err := newDeadLetterQueue(logp.NewLogger("test"), beat.Info{Beat: "testbeat"}, nilObserver, config)
+	require.NoError(t, err)
+
+	event := publisher.Event{Content: beat.Event{
+		Fields: common.MapStr{"message": "hello", "user": common.MapStr{"name": "alice"}},
+	}}
+	q.write(event, "mapping conflict")
+	require.NoError(t, q.close())
+
+	// The sensitive fields are only scrubbed in the stored event.
+	assert.Equal(t, "alice", event.Content.Fields["user"].(common.MapStr)["name"])
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, deadLetterFileName))
+	require.NoError(t, err)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "hello", doc["message"])
+	assert.Equal(t, "[REDACTED]", doc["user"].(map[string]interface{})["name"])
+}
+
+func TestDeadLetterQueueDisabled(t *testing.T) {
+	q, err := newDeadLetterQueue(logp.NewLogger("test"), beat.Info{}, nilObserver, defaultDeadLetterQueueConfig())
+	require.NoError(t, err)
+	require.Nil(t, q)
+
+	// A disabled queue ignores the events.
+	q.write(publisher.Event{}, "ignored")
+	assert.NoError(t, q.close())
+}
+
+type deadLetterObserver struct {
+	*emptyObserver
+	written, bytes, failed int
+}
+
+func (o *deadLetterObserver) deadLetterWritten(bytes int) {
+	o.written++
+	o.bytes += bytes
+}
+
+func (o *deadLetterObserver) deadLetterFailed() { o.failed++ }
@@ -0,0 +1,167 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+)
+
+// inFlight tracks the batches passed to an output worker that were not ACKed,
+// dropped or returned to the pipeline yet, for reporting how long the worker
+// takes to ACK its batches.
+type inFlight struct {
+	mu      sync.Mutex
+	batches map[*batch]inFlightBatch
+}
+
+type inFlightBatch struct {
+	sent   time.Time
+	events int
+}
+
+// workerState is the state of the batches in flight of an output worker.
+type workerState struct {
+	batches, events int
+
+	// oldest is the time the oldest batch in flight was passed to the worker.
+	oldest time.Time
+}
+
+func newInFlight() *inFlight {
+	return &inFlight{batches: map[*batch]inFlightBatch{}}
+}
+
+// track adds the batch to the batches in flight, the batches not created by
+// the pipeline are ignored.
+func (f *inFlight) track(b publisher.Batch) {
+	tracked, ok := b.(*batch)
+	if !ok || f == nil {
+		return
+	}
+	tracked.inFlight = f
+
+	f.mu.Lock()
+	f.batches[tracked] = inFlightBatch{sent: time.Now(), events: len(tracked.events)}
+	f.mu.Unlock()
+}
+
+func (f *inFlight) remove(b *batch) {
+	f.mu.Lock()
+	delete(f.batches, b)
+	f.mu.Unlock()
+}
+
+func (f *inFlight) state() workerState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	state := workerState{batches: len(f.batches)}
+	for _, b := range f.batches {
+		state.events += b.events
+		if state.oldest.IsZero() || b.sent.Before(state.oldest) {
+			state.oldest = b.sent
+		}
+	}
+	return state
+}
+
+// untrack removes the batch from the batches in flight of its worker.
+func (b *batch) untrack() {
+	if b.inFlight != nil {
+		b.inFlight.remove(b)
+		b.inFlight = nil
+	}
+}
+
+// InspectQueue reports the state of the queues and of the output workers of
+// the pipeline, including its routes and mirrors.
+func (p *Pipeline) InspectQueue() common.MapStr {
+	now := time.Now()
+	state := p.output.inspect(now)
+	if routes := inspectRoutes(p.routes, now); len(routes) > 0 {
+		state["routes"] = routes
+	}
+	if mirrors := inspectRoutes(p.mirrors, now); len(mirrors) > 0 {
+		state["mirrors"] = mirrors
+	}
+	return state
+}
+
+func inspectRoutes(routes []*route, now time.Time) []common.MapStr {
+	var states []common.MapStr
+	for _, r := range routes {
+		state := r.output.inspect(now)
+		state["name"] = r.name
+		states = append(states, state)
+	}
+	return states
+}
+
+func (c *outputController) inspect(now time.Time) common.MapStr {
+	state := common.MapStr{"queue": inspectQueue(c.queue, now)}
+	if c.high != nil {
+		state["high_priority_queue"] = inspectQueue(c.high.queue, now)
+	}
+
+	c.mu.Lock()
+	out := c.out
+	c.mu.Unlock()
+
+	outputs := []common.MapStr{}
+	if out != nil {
+		for _, w := range out.outputs {
+			s := w.inFlightState()
+			output := common.MapStr{
+				"client":  w.String(),
+				"batches": s.batches,
+				"events":  s.events,
+			}
+			if !s.oldest.IsZero() {
+				output["ack_lag_ms"] = now.Sub(s.oldest).Milliseconds()
+			}
+			outputs = append(outputs, output)
+		}
+	}
+	state["outputs"] = outputs
+	return state
+}
+
+// inspectQueue reports the state of the queue, it's empty if the queue does
+// not implement queue.Inspector.
+func inspectQueue(q queue.Queue, now time.Time) common.MapStr {
+	state := common.MapStr{}
+	inspector, ok := q.(queue.Inspector)
+	if !ok {
+		return state
+	}
+
+	s := inspector.Inspect()
+	state.Update(s.Details)
+	if s.Events >= 0 {
+		state["events"] = s.Events
+	}
+	if !s.Oldest.IsZero() {
+		state["oldest_event_age_ms"] = now.Sub(s.Oldest).Milliseconds()
+	}
+	return state
+}
@@ -0,0 +1,110 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+func TestInspectQueue(t *testing.T) {
+	pipeline, err := New(beat.Info{},
+		Monitors{},
+		makeTestMemQueue,
+		outputs.Group{},
+		Settings{},
+	)
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	// the output keeps the batches until the test ACKs them
+	batches := make(chan publisher.Batch, 10)
+	pipeline.output.Set(outputs.Group{
+		BatchSize: 5,
+		Clients: []outputs.Client{
+			newMockClient(func(batch publisher.Batch) error {
+				batches <- batch
+				return nil
+			}),
+		},
+	})
+
+	client, err := pipeline.Connect()
+	require.NoError(t, err)
+	defer client.Close()
+	for i := 0; i < 5; i++ {
+		client.Publish(beat.Event{Fields: common.MapStr{"n": i}})
+	}
+
+	// the events can be split in several batches if the consumer gets them
+	// while they are published
+	var received []publisher.Batch
+	for events := 0; events < 5; {
+		select {
+		case batch := <-batches:
+			received = append(received, batch)
+			events += len(batch.Events())
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for the batches")
+		}
+	}
+
+	state := pipeline.InspectQueue()
+	queueState := state["queue"].(common.MapStr)
+	assert.Equal(t, 5, queueState["events"])
+	assert.Contains(t, queueState, "oldest_event_age_ms")
+
+	outputStates := state["outputs"].([]common.MapStr)
+	require.Len(t, outputStates, 1)
+	assert.Equal(t, "mock_client", outputStates[0]["client"])
+	assert.Equal(t, len(received), outputStates[0]["batches"])
+	assert.Equal(t, 5, outputStates[0]["events"])
+	assert.Contains(t, outputStates[0], "ack_lag_ms")
+
+	for _, batch := range received {
+		batch.ACK()
+	}
+	outputStates = pipeline.InspectQueue()["outputs"].([]common.MapStr)
+	assert.Equal(t, 0, outputStates[0]["batches"])
+	assert.NotContains(t, outputStates[0], "ack_lag_ms")
+	require.Eventually(t, func() bool {
+		return pipeline.InspectQueue()["queue"].(common.MapStr)["events"] == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestInFlight(t *testing.T) {
+	f := newInFlight()
+	b := &batch{events: make([]publisher.Event, 3)}
+	f.track(b)
+	state := f.state()
+	assert.Equal(t, 1, state.batches)
+	assert.Equal(t, 3, state.events)
+	assert.False(t, state.oldest.IsZero())
+
+	b.untrack()
+	assert.Equal(t, workerState{}, f.state())
+	assert.Nil(t, b.inFlight)
+}
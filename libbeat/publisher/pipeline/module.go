@@ -18,6 +18,7 @@
 package pipeline
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 
@@ -99,17 +100,53 @@ func LoadWithSettings(
 	if err != nil {
 		return nil, err
 	}
+	if config.Shards > 1 {
+		log.Infof("Publisher pipeline sharded in %d queues", config.Shards)
+		queueBuilder = makeShardedQueueFactory(config.Shards, queueBuilder)
+	}
 
 	out, err := loadOutput(monitors, makeOutput)
 	if err != nil {
 		return nil, err
 	}
 
+	settings.CircuitBreaker = config.CircuitBreaker
+	settings.AdaptiveBatch = config.AdaptiveBatch
+	settings.RateLimit = config.RateLimit
+	settings.DeadLetterQueue = config.DeadLetterQueue
+	settings.ShutdownSpool = config.ShutdownSpool
+	settings.Priority = config.Priority
+	settings.priorityQueueFactory = routeQueueBuilder(config.Queue, monitors)
 	p, err := New(beatInfo, monitors, queueBuilder, out, settings)
 	if err != nil {
 		return nil, err
 	}
 
+	if (len(config.Routes) > 0 || len(config.Mirrors) > 0) && !publishDisabled {
+		if settings.RouteOutputFactory == nil {
+			p.Close()
+			return nil, errors.New("pipeline.routes and pipeline.mirrors are not supported by this pipeline")
+		}
+		for _, r := range config.Routes {
+			if err := p.addRoute(r, routeQueueBuilder(config.Queue, monitors), settings.RouteOutputFactory); err != nil {
+				p.Close()
+				return nil, err
+			}
+			log.Infof("Route '%v' publishes to the %v output", r.Name, r.Output.Name())
+		}
+		for _, m := range config.Mirrors {
+			if err := p.addMirror(m, routeQueueBuilder(config.Queue, monitors), settings.RouteOutputFactory); err != nil {
+				p.Close()
+				return nil, err
+			}
+			log.Infof("Mirror '%v' publishes to the %v output", m.Name, m.Output.Name())
+		}
+	}
+
+	if p.spool != nil && !publishDisabled {
+		go p.spool.replay(p)
+	}
+
 	log.Infof("Beat name: %s", name)
 	return p, err
 }
@@ -194,3 +231,18 @@ func createQueueBuilder(
 		return queueFactory(ackListener, monitors.Logger, queueConfig)
 	}, nil
 }
+
+// routeQueueBuilder creates the queues of the routes. Routes always use the
+// memory queue, with the settings of the configured queue if it's a memory
+// queue too.
+func routeQueueBuilder(config common.ConfigNamespace, monitors Monitors) queueFactory {
+	queueConfig := common.NewConfig()
+	if name := config.Name(); (name == "" || name == defaultQueueType) && config.Config() != nil {
+		queueConfig = config.Config()
+	}
+
+	queueFactory := queue.FindFactory(defaultQueueType)
+	return func(ackListener queue.ACKListener) (queue.Queue, error) {
+		return queueFactory(ackListener, monitors.Logger, queueConfig)
+	}
+}
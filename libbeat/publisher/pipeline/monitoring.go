@@ -52,6 +52,11 @@ type outputObserver interface {
 	eventsRetry(int)
 	outBatchSend(int)
 	outBatchACKed(int)
+	circuitOpened()
+	circuitCooledDown()
+	batchSizeUpdated(size int)
+	deadLetterWritten(bytes int)
+	deadLetterFailed()
 }
 
 // metricsObserver is used by many component in the publisher pipeline, to report
@@ -73,6 +78,15 @@ type metricsObserver struct {
 
 	// queue metrics
 	ackedQueue *monitoring.Uint
+
+	// circuit breaker metrics
+	circuitsOpen, circuitTrips *monitoring.Uint
+
+	// adaptive batch size metrics
+	batchSize, batchSizeUpdates *monitoring.Uint
+
+	// dead letter queue metrics
+	deadLetterEvents, deadLetterBytes, deadLetterFailures *monitoring.Uint
 }
 
 func newMetricsObserver(metrics *monitoring.Registry) *metricsObserver {
@@ -95,6 +109,16 @@ func newMetricsObserver(metrics *monitoring.Registry) *metricsObserver {
 		ackedQueue: monitoring.NewUint(reg, "queue.acked"),
 
 		activeEvents: monitoring.NewUint(reg, "events.active"),
+
+		circuitsOpen: monitoring.NewUint(reg, "circuit_breaker.open"),
+		circuitTrips: monitoring.NewUint(reg, "circuit_breaker.trips"),
+
+		batchSize:        monitoring.NewUint(reg, "adaptive_batch.size"),
+		batchSizeUpdates: monitoring.NewUint(reg, "adaptive_batch.updates"),
+
+		deadLetterEvents:   monitoring.NewUint(reg, "dead_letter.events"),
+		deadLetterBytes:    monitoring.NewUint(reg, "dead_letter.bytes"),
+		deadLetterFailures: monitoring.NewUint(reg, "dead_letter.failed"),
 	}
 }
 
@@ -180,22 +204,51 @@ func (o *metricsObserver) outBatchSend(int) {}
 // (output) number of events acked by the output batch
 func (o *metricsObserver) outBatchACKed(int) {}
 
+// (output) circuit breaker of an output worker opened
+func (o *metricsObserver) circuitOpened() {
+	o.circuitTrips.Inc()
+	o.circuitsOpen.Inc()
+}
+
+// (output) cooldown of an opened circuit breaker finished
+func (o *metricsObserver) circuitCooledDown() { o.circuitsOpen.Dec() }
+
+// (output) adaptive batch size has been updated
+func (o *metricsObserver) batchSizeUpdated(size int) {
+	o.batchSize.Set(uint64(size))
+	o.batchSizeUpdates.Inc()
+}
+
+// (dead letter queue) rejected event has been written
+func (o *metricsObserver) deadLetterWritten(bytes int) {
+	o.deadLetterEvents.Inc()
+	o.deadLetterBytes.Add(uint64(bytes))
+}
+
+// (dead letter queue) rejected event could not be written
+func (o *metricsObserver) deadLetterFailed() { o.deadLetterFailures.Inc() }
+
 type emptyObserver struct{}
 
 var nilObserver observer = (*emptyObserver)(nil)
 
-func (*emptyObserver) cleanup()            {}
-func (*emptyObserver) clientConnected()    {}
-func (*emptyObserver) clientClosing()      {}
-func (*emptyObserver) clientClosed()       {}
-func (*emptyObserver) newEvent()           {}
-func (*emptyObserver) filteredEvent()      {}
-func (*emptyObserver) publishedEvent()     {}
-func (*emptyObserver) failedPublishEvent() {}
-func (*emptyObserver) queueACKed(n int)    {}
-func (*emptyObserver) updateOutputGroup()  {}
-func (*emptyObserver) eventsFailed(int)    {}
-func (*emptyObserver) eventsDropped(int)   {}
-func (*emptyObserver) eventsRetry(int)     {}
-func (*emptyObserver) outBatchSend(int)    {}
-func (*emptyObserver) outBatchACKed(int)   {}
+func (*emptyObserver) cleanup()              {}
+func (*emptyObserver) clientConnected()      {}
+func (*emptyObserver) clientClosing()        {}
+func (*emptyObserver) clientClosed()         {}
+func (*emptyObserver) newEvent()             {}
+func (*emptyObserver) filteredEvent()        {}
+func (*emptyObserver) publishedEvent()       {}
+func (*emptyObserver) failedPublishEvent()   {}
+func (*emptyObserver) queueACKed(n int)      {}
+func (*emptyObserver) updateOutputGroup()    {}
+func (*emptyObserver) eventsFailed(int)      {}
+func (*emptyObserver) eventsDropped(int)     {}
+func (*emptyObserver) eventsRetry(int)       {}
+func (*emptyObserver) outBatchSend(int)      {}
+func (*emptyObserver) outBatchACKed(int)     {}
+func (*emptyObserver) circuitOpened()        {}
+func (*emptyObserver) circuitCooledDown()    {}
+func (*emptyObserver) batchSizeUpdated(int)  {}
+func (*emptyObserver) deadLetterWritten(int) {}
+func (*emptyObserver) deadLetterFailed()     {}
@@ -20,11 +20,13 @@ package pipeline
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/elastic/beats/v7/libbeat/publisher"
 
 	"go.elastic.co/apm"
 
+	"github.com/elastic/beats/v7/libbeat/common/backoff"
 	"github.com/elastic/beats/v7/libbeat/outputs"
 )
 
@@ -33,6 +35,33 @@ type worker struct {
 	observer outputObserver
 	qu       workQueue
 	done     chan struct{}
+	inFlight *inFlight
+
+	// high passes the batches of the high priority events, it's nil if the
+	// priorities are disabled.
+	high            workQueue
+	weight          int
+	consecutiveHigh int
+}
+
+// workerConfig configures the output workers.
+type workerConfig struct {
+	// backoff configures the wait between failed connection attempts.
+	backoff outputs.BackoffConfig
+
+	// circuitBreaker configures the pause of a worker after consecutive
+	// publish failures.
+	circuitBreaker CircuitBreakerConfig
+
+	// batchSizer adjusts the batch size of the output group to the publish
+	// attempts of its workers, it's nil if the batch size is fixed.
+	batchSizer *batchSizer
+
+	// highQueue passes the batches of the high priority events to the
+	// workers, it's nil if the priorities are disabled. highWeight is the
+	// number of consecutive high priority batches before a normal one.
+	highQueue  workQueue
+	highWeight int
 }
 
 // clientWorker manages output client of type outputs.Client, not supporting reconnect.
@@ -46,18 +75,21 @@ type netClientWorker struct {
 	worker
 	client outputs.NetworkClient
 
-	batchSize  int
-	batchSizer func() int
-	logger     logger
+	logger logger
+
+	config workerConfig
 
 	tracer *apm.Tracer
 }
 
-func makeClientWorker(observer outputObserver, qu workQueue, client outputs.Client, logger logger, tracer *apm.Tracer) outputWorker {
+func makeClientWorker(observer outputObserver, qu workQueue, client outputs.Client, config workerConfig, logger logger, tracer *apm.Tracer) outputWorker {
 	w := worker{
 		observer: observer,
 		qu:       qu,
 		done:     make(chan struct{}),
+		inFlight: newInFlight(),
+		high:     config.highQueue,
+		weight:   config.highWeight,
 	}
 
 	var c interface {
@@ -70,6 +102,7 @@ func makeClientWorker(observer outputObserver, qu workQueue, client outputs.Clie
 			worker: w,
 			client: nc,
 			logger: logger,
+			config: config,
 			tracer: tracer,
 		}
 	} else {
@@ -84,6 +117,56 @@ func (w *worker) close() {
 	close(w.done)
 }
 
+func (w *worker) inFlightState() workerState {
+	return w.inFlight.state()
+}
+
+// next returns the next batch of the worker, or false if the worker is
+// closed. The batches of the high priority queue are returned first, but
+// after weight consecutive high priority batches, a pending batch of the
+// normal queue is returned.
+func (w *worker) next() (publisher.Batch, bool) {
+	if w.high == nil {
+		select {
+		case <-w.done:
+			return nil, false
+		case batch := <-w.qu:
+			return batch, true
+		}
+	}
+
+	if w.consecutiveHigh >= w.weight {
+		select {
+		case batch := <-w.qu:
+			w.consecutiveHigh = 0
+			return batch, true
+		default:
+		}
+	}
+
+	select {
+	case batch := <-w.high:
+		w.consecutiveHigh++
+		return batch, true
+	default:
+	}
+
+	select {
+	case <-w.done:
+		return nil, false
+	case batch := <-w.high:
+		w.consecutiveHigh++
+		return batch, true
+	case batch := <-w.qu:
+		w.consecutiveHigh = 0
+		return batch, true
+	}
+}
+
+func (w *clientWorker) String() string {
+	return w.client.String()
+}
+
 func (w *clientWorker) Close() error {
 	w.worker.close()
 	return w.client.Close()
@@ -93,23 +176,25 @@ func (w *clientWorker) run() {
 	for {
 		// We wait for either the worker to be closed or for there to be a batch of
 		// events to publish.
-		select {
-
-		case <-w.done:
+		batch, ok := w.next()
+		if !ok {
+			return
+		}
+		if batch == nil {
+			continue
+		}
+		w.inFlight.track(batch)
+		w.observer.outBatchSend(len(batch.Events()))
+		if err := w.client.Publish(context.TODO(), batch); err != nil {
 			return
-
-		case batch := <-w.qu:
-			if batch == nil {
-				continue
-			}
-			w.observer.outBatchSend(len(batch.Events()))
-			if err := w.client.Publish(context.TODO(), batch); err != nil {
-				return
-			}
 		}
 	}
 }
 
+func (w *netClientWorker) String() string {
+	return w.client.String()
+}
+
 func (w *netClientWorker) Close() error {
 	w.worker.close()
 	return w.client.Close()
@@ -119,48 +204,61 @@ func (w *netClientWorker) run() {
 	var (
 		connected         = false
 		reconnectAttempts = 0
+		connectBackoff    = backoff.NewJitterBackoff(w.done, w.config.backoff.Init, w.config.backoff.Max, w.config.backoff.Jitter)
+		breaker           = newCircuitBreaker(w.config.circuitBreaker, w.observer, w.logger, w.client.String())
 	)
 
 	for {
 		// We wait for either the worker to be closed or for there to be a batch of
 		// events to publish.
-		select {
-
-		case <-w.done:
+		batch, ok := w.next()
+		if !ok {
 			return
+		}
+		if batch == nil {
+			continue
+		}
+		w.inFlight.track(batch)
 
-		case batch := <-w.qu:
-			if batch == nil {
-				continue
+		// Try to (re)connect so we can publish batch
+		if !connected {
+			// Return batch to other output workers while we try to (re)connect
+			batch.Cancelled()
+
+			if reconnectAttempts == 0 {
+				w.logger.Infof("Connecting to %v", w.client)
+			} else {
+				w.logger.Infof("Attempting to reconnect to %v with %d reconnect attempt(s)", w.client, reconnectAttempts)
 			}
 
-			// Try to (re)connect so we can publish batch
-			if !connected {
-				// Return batch to other output workers while we try to (re)connect
-				batch.Cancelled()
-
-				if reconnectAttempts == 0 {
-					w.logger.Infof("Connecting to %v", w.client)
-				} else {
-					w.logger.Infof("Attempting to reconnect to %v with %d reconnect attempt(s)", w.client, reconnectAttempts)
-				}
-
-				err := w.client.Connect()
-				connected = err == nil
-				if connected {
-					w.logger.Infof("Connection to %v established", w.client)
-					reconnectAttempts = 0
-				} else {
-					w.logger.Errorf("Failed to connect to %v: %v", w.client, err)
-					reconnectAttempts++
-				}
-
-				continue
+			err := w.client.Connect()
+			connected = err == nil
+			if connected {
+				w.logger.Infof("Connection to %v established", w.client)
+				reconnectAttempts = 0
+				connectBackoff.Reset()
+			} else {
+				w.logger.Errorf("Failed to connect to %v: %v", w.client, err)
+				reconnectAttempts++
+
+				// Wait before the next attempt, so an unavailable endpoint
+				// isn't retried on every batch.
+				connectBackoff.Wait()
 			}
 
-			if err := w.publishBatch(batch); err != nil {
-				connected = false
+			continue
+		}
+
+		if err := w.publishBatch(batch); err != nil {
+			connected = false
+
+			// Stop consuming batches while the circuit is open, so they
+			// are published by the other workers.
+			if !breaker.failure(w.done) {
+				return
 			}
+		} else {
+			breaker.success()
 		}
 	}
 }
@@ -173,7 +271,10 @@ func (w *netClientWorker) publishBatch(batch publisher.Batch) error {
 		tx.Context.SetLabel("worker", "netclient")
 		ctx = apm.ContextWithTransaction(ctx, tx)
 	}
+	events := len(batch.Events())
+	start := time.Now()
 	err := w.client.Publish(ctx, batch)
+	w.config.batchSizer.observe(events, time.Since(start), err)
 	if err != nil {
 		err = fmt.Errorf("failed to publish events: %w", err)
 		apm.CaptureError(ctx, err).Send()
@@ -64,7 +64,7 @@ func TestMakeClientWorker(t *testing.T) {
 
 				client := ctor(publishFn)
 
-				worker := makeClientWorker(nilObserver, wqu, client, logger, nil)
+				worker := makeClientWorker(nilObserver, wqu, client, workerConfig{backoff: outputs.DefaultBackoffConfig()}, logger, nil)
 				defer worker.Close()
 
 				for i := uint(0); i < numBatches; i++ {
@@ -156,7 +156,7 @@ func TestReplaceClientWorker(t *testing.T) {
 				}
 
 				client := ctor(blockingPublishFn)
-				worker := makeClientWorker(nilObserver, wqu, client, logger, nil)
+				worker := makeClientWorker(nilObserver, wqu, client, workerConfig{backoff: outputs.DefaultBackoffConfig()}, logger, nil)
 
 				// Allow the worker to make *some* progress before we close it
 				timeout := 10 * time.Second
@@ -183,7 +183,7 @@ func TestReplaceClientWorker(t *testing.T) {
 				}
 
 				client = ctor(countingPublishFn)
-				makeClientWorker(nilObserver, wqu, client, logger, nil)
+				makeClientWorker(nilObserver, wqu, client, workerConfig{backoff: outputs.DefaultBackoffConfig()}, logger, nil)
 				wg.Wait()
 
 				// Make sure that all events have eventually been published
@@ -229,7 +229,7 @@ func TestMakeClientTracer(t *testing.T) {
 	recorder := apmtest.NewRecordingTracer()
 	defer recorder.Close()
 
-	worker := makeClientWorker(nilObserver, wqu, client, logger, recorder.Tracer)
+	worker := makeClientWorker(nilObserver, wqu, client, workerConfig{backoff: outputs.DefaultBackoffConfig()}, logger, recorder.Tracer)
 	defer worker.Close()
 
 	for i := 0; i < numBatches; i++ {
@@ -258,6 +258,80 @@ func TestMakeClientTracer(t *testing.T) {
 	}
 }
 
+func TestNetClientWorkerReconnectBackoff(t *testing.T) {
+	const failures = 3
+	backoff := outputs.BackoffConfig{
+		Init: 20 * time.Millisecond,
+		Max:  100 * time.Millisecond,
+	}
+
+	logger := makeBufLogger(t)
+	wqu := makeWorkQueue()
+
+	var published atomic.Uint
+	client := &failingNetworkClient{
+		Client: newMockClient(func(batch publisher.Batch) error {
+			published.Add(uint(len(batch.Events())))
+			return nil
+		}),
+		failures: failures,
+	}
+
+	worker := makeClientWorker(nilObserver, wqu, client, workerConfig{backoff: backoff}, logger, nil)
+	defer worker.Close()
+
+	// Keep sending batches, the batches cancelled while connecting are dropped.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case wqu <- randomBatch(1, 2):
+			}
+		}
+	}()
+
+	if !waitUntilTrue(5*time.Second, func() bool { return published.Load() > 0 }) {
+		logger.Flush()
+		t.Fatal("no events published after reconnecting")
+	}
+
+	attempts := client.attempts()
+	require.Len(t, attempts, failures+1)
+	for i := 1; i < len(attempts); i++ {
+		require.True(t, attempts[i].Sub(attempts[i-1]) >= backoff.Init,
+			"attempt %d was %v after the previous one", i, attempts[i].Sub(attempts[i-1]))
+	}
+}
+
+// failingNetworkClient is a network client whose first connection attempts
+// fail.
+type failingNetworkClient struct {
+	outputs.Client
+	failures int
+
+	mu       sync.Mutex
+	connects []time.Time
+}
+
+func (c *failingNetworkClient) Connect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connects = append(c.connects, time.Now())
+	if len(c.connects) <= c.failures {
+		return fmt.Errorf("connection attempt %d failed", len(c.connects))
+	}
+	return nil
+}
+
+func (c *failingNetworkClient) attempts() []time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Time(nil), c.connects...)
+}
+
 // bufLogger is a buffered logger. It does not immediately print out log lines; instead it
 // buffers them. To print them out, one must explicitly call it's Flush() method. This is
 // useful when you want to see the logs only when tests fail but not when they pass.
@@ -21,6 +21,7 @@
 package pipeline
 
 import (
+	"errors"
 	"reflect"
 	"sync"
 	"time"
@@ -32,6 +33,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/common/reload"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/processors"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/publisher/processing"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
@@ -50,6 +52,8 @@ import (
 // the output clients using a shared work queue for the active outputs.Group.
 // Processors in the pipeline are executed in the clients go-routine, before
 // entering the queue. No filtering/processing will occur on the output side.
+// If the pipeline is sharded, the queue is split into multiple queues, with
+// every client publishing to one of them, see shardedQueue.
 //
 // For client connecting to this pipeline, the default PublishMode is
 // OutputChooses.
@@ -61,6 +65,23 @@ type Pipeline struct {
 	queue  queue.Queue
 	output *outputController
 
+	// priorityQueue holds the events with a high priority, it's nil if the
+	// priorities are disabled.
+	priorityQueue queue.Queue
+
+	// routes publish the events matching their conditions to other outputs
+	// than the default one.
+	routes []*route
+
+	// mirrors publish a copy of the events matching their conditions to
+	// other outputs.
+	mirrors []*route
+
+	deadLetter *deadLetterQueue
+
+	// spool stores the pending events on shutdown, if enabled.
+	spool *shutdownSpool
+
 	observer observer
 
 	eventer pipelineEventer
@@ -89,6 +110,38 @@ type Settings struct {
 	WaitCloseMode WaitCloseMode
 
 	Processors processing.Supporter
+
+	// CircuitBreaker configures the circuit breaker of the output workers.
+	CircuitBreaker CircuitBreakerConfig
+
+	// AdaptiveBatch configures the adaptive sizing of the batches passed to
+	// the output workers.
+	AdaptiveBatch AdaptiveBatchConfig
+
+	// RateLimit limits the rate of the events published to all outputs,
+	// including the outputs of the routes and mirrors.
+	RateLimit outputs.RateLimitConfig
+
+	// DeadLetterQueue configures the storage of the events rejected by the
+	// outputs.
+	DeadLetterQueue DeadLetterQueueConfig
+
+	// ShutdownSpool configures the spooling of the pending events on
+	// shutdown, see (*Pipeline).SpoolPending.
+	ShutdownSpool ShutdownSpoolConfig
+
+	// Priority configures the priority classes of the events. The events
+	// with a high priority are stored in a memory queue, consumed before the
+	// queue of the pipeline.
+	Priority PriorityConfig
+
+	// priorityQueueFactory creates the queue of the high priority events,
+	// the memory queue with the default settings is used if it's nil.
+	priorityQueueFactory queueFactory
+
+	// RouteOutputFactory creates the outputs of the routes and mirrors
+	// configured in Config. Routes and mirrors are not supported if it's nil.
+	RouteOutputFactory RouteOutputFactory
 }
 
 // WaitCloseMode enumerates the possible behaviors of WaitClose in a pipeline.
@@ -127,8 +180,13 @@ type pipelineEventer struct {
 }
 
 type waitCloser struct {
+	mu sync.Mutex
+
 	// keep track of total number of active events (minus dropped by processors)
-	events sync.WaitGroup
+	events int
+
+	// idle is closed when there are no active events
+	idle chan struct{}
 }
 
 type queueFactory func(queue.ACKListener) (queue.Queue, error)
@@ -164,7 +222,11 @@ func New(
 	p.eventer.observer = p.observer
 	p.eventer.modifyable = true
 
-	if settings.WaitCloseMode == WaitOnPipelineClose && settings.WaitClose > 0 {
+	p.spool = newShutdownSpool(monitors.Logger, beat, settings.ShutdownSpool)
+
+	// The spool uses the counter of the active events to wait for the
+	// pending events.
+	if (settings.WaitCloseMode == WaitOnPipelineClose && settings.WaitClose > 0) || p.spool != nil {
 		p.waitCloser = &waitCloser{}
 
 		// waitCloser decrements counter on queue ACK (not per client)
@@ -184,7 +246,28 @@ func New(
 	}
 	p.eventSema = newSema(maxEvents)
 
-	p.output = newOutputController(beat, monitors, p.observer, p.queue)
+	deadLetter, err := newDeadLetterQueue(monitors.Logger, beat, p.observer, settings.DeadLetterQueue)
+	if err != nil {
+		p.queue.Close()
+		return nil, err
+	}
+
+	p.deadLetter = deadLetter
+	p.output = newOutputController(beat, monitors, p.observer, p.queue, settings.CircuitBreaker, settings.AdaptiveBatch, newRateLimiter(&settings.RateLimit), deadLetter)
+
+	if settings.Priority.Enabled {
+		factory := settings.priorityQueueFactory
+		if factory == nil {
+			factory = routeQueueBuilder(common.ConfigNamespace{}, monitors)
+		}
+		p.priorityQueue, err = factory(&p.eventer)
+		if err != nil {
+			p.output.Close()
+			p.queue.Close()
+			return nil, err
+		}
+		p.output.setPriorityQueue(p.priorityQueue, settings.Priority.Weight)
+	}
 	p.output.Set(out)
 
 	return p, nil
@@ -199,25 +282,22 @@ func (p *Pipeline) Close() error {
 
 	log.Debug("close pipeline")
 
-	if p.waitCloser != nil {
-		ch := make(chan struct{})
-		go func() {
-			p.waitCloser.wait()
-			ch <- struct{}{}
-		}()
-
-		select {
-		case <-ch:
-			// all events have been ACKed
-
-		case <-time.After(p.waitCloseTimeout):
-			// timeout -> close pipeline with pending events
-		}
+	p.spool.close()
 
+	if p.waitCloser != nil {
+		// on timeout the pipeline is closed with pending events
+		p.waitCloser.wait(p.waitCloseTimeout)
 	}
 
 	// TODO: close/disconnect still active clients
 
+	for _, r := range p.routes {
+		r.close()
+	}
+	for _, m := range p.mirrors {
+		m.close()
+	}
+
 	// close output before shutting down queue
 	p.output.Close()
 
@@ -226,6 +306,15 @@ func (p *Pipeline) Close() error {
 	if err != nil {
 		log.Error("pipeline queue shutdown error: ", err)
 	}
+	if p.priorityQueue != nil {
+		if err := p.priorityQueue.Close(); err != nil {
+			log.Error("pipeline priority queue shutdown error: ", err)
+		}
+	}
+
+	if err := p.deadLetter.close(); err != nil {
+		log.Errorf("Failed to close the dead letter queue: %v", err)
+	}
 
 	p.observer.cleanup()
 	if p.sigNewClient != nil {
@@ -265,6 +354,9 @@ func (p *Pipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
 	case beat.DropIfFull:
 		canDrop = true
 	}
+	if cfg.RecycleFields {
+		eventFlags |= publisher.RecycleFields
+	}
 
 	waitClose := cfg.WaitClose
 	reportEvents := p.waitCloser != nil
@@ -331,7 +423,23 @@ func (p *Pipeline) ConnectWith(cfg beat.ClientConfig) (beat.Client, error) {
 
 	client.acker = ackHandler
 	client.waiter = waiter
-	client.producer = p.queue.Producer(producerCfg)
+	makeProducer := func(producerCfg queue.ProducerConfig) queue.Producer {
+		if shards, ok := p.queue.(*shardedQueue); ok {
+			return shards.producerFor(cfg.ShardKey, producerCfg)
+		}
+		return p.queue.Producer(producerCfg)
+	}
+	if p.priorityQueue != nil {
+		makeQueueProducer := makeProducer
+		makeProducer = func(producerCfg queue.ProducerConfig) queue.Producer {
+			return newPriorityProducer(p.priorityQueue, producerCfg, makeQueueProducer)
+		}
+	}
+	if len(p.routes) > 0 || len(p.mirrors) > 0 {
+		client.producer = newRoutedProducer(p, producerCfg, makeProducer)
+	} else {
+		client.producer = makeProducer(producerCfg)
+	}
 
 	p.observer.clientConnected()
 
@@ -424,7 +532,15 @@ func (p *Pipeline) createEventProcessing(cfg beat.ProcessingConfig, noPublish bo
 	if p.processors == nil {
 		return nil, nil
 	}
-	return p.processors.Create(cfg, noPublish)
+	proc, err := p.processors.Create(cfg, noPublish)
+	if err != nil {
+		return nil, err
+	}
+
+	// processors publishing events of their own connect to the pipeline
+	// when needed.
+	processors.SetPipeline(proc, p)
+	return proc, nil
 }
 
 func (e *pipelineEventer) OnACK(n int) {
@@ -436,17 +552,69 @@ func (e *pipelineEventer) OnACK(n int) {
 }
 
 func (e *waitCloser) inc() {
-	e.events.Add(1)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.events == 0 {
+		e.idle = make(chan struct{})
+	}
+	e.events++
 }
 
 func (e *waitCloser) dec(n int) {
-	for i := 0; i < n; i++ {
-		e.events.Done()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if n > e.events {
+		panic("negative count of active events")
+	}
+	e.events -= n
+	if e.events == 0 && n > 0 {
+		close(e.idle)
 	}
 }
 
-func (e *waitCloser) wait() {
-	e.events.Wait()
+// wait waits until there are no active events, for up to the given timeout.
+// It returns false on timeout.
+func (e *waitCloser) wait(timeout time.Duration) bool {
+	e.mu.Lock()
+	idle := e.idle
+	events := e.events
+	e.mu.Unlock()
+	if events == 0 {
+		return true
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-idle:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// QueueSize returns the maximum number of events of the queue, or 0 if the
+// queue has no fixed limit.
+func (p *Pipeline) QueueSize() int {
+	return p.queue.BufferConfig().MaxEvents
+}
+
+// ResizeQueue changes the maximum number of events of the queue while the
+// pipeline is running. With multiple shards, the events are divided between
+// the shards. It fails if the queue can't be resized, like the disk queue.
+func (p *Pipeline) ResizeQueue(events int) error {
+	r, ok := p.queue.(queue.Resizer)
+	if !ok {
+		return errors.New("the queue of the pipeline can't be resized")
+	}
+	if err := r.Resize(events); err != nil {
+		return err
+	}
+	p.monitors.Logger.Infof("Queue resized to %d events", events)
+	return nil
 }
 
 // OutputReloader returns a reloadable object for the output section of this pipeline
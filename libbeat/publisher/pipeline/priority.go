@@ -0,0 +1,162 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+)
+
+// PriorityConfig configures the priority classes of the events. The events
+// with a high priority are published to their own queue, and the output
+// workers publish its batches before the batches of the other events.
+type PriorityConfig struct {
+	Enabled bool `config:"enabled"`
+
+	// Weight is the number of consecutive batches of high priority events an
+	// output worker publishes, before it publishes a pending batch of normal
+	// priority events. It prevents the high priority events from blocking
+	// the other events.
+	Weight int `config:"weight" validate:"min=1"`
+}
+
+func defaultPriorityConfig() PriorityConfig {
+	return PriorityConfig{
+		Enabled: false,
+		Weight:  4,
+	}
+}
+
+// Unpack implements the config unpacker, setting the defaults.
+func (c *PriorityConfig) Unpack(from *common.Config) error {
+	type tmpConfig PriorityConfig
+	tmp := tmpConfig(defaultPriorityConfig())
+	if err := from.Unpack(&tmp); err != nil {
+		return err
+	}
+
+	*c = PriorityConfig(tmp)
+	return nil
+}
+
+// priorityLevel consumes the queue of the high priority events, passing its
+// batches to the workers of the output controller via its own work queue.
+// The batches are retried to the same work queue, so they keep their
+// priority.
+type priorityLevel struct {
+	queue     queue.Queue
+	workQueue workQueue
+	consumer  *eventConsumer
+	retryer   *retryer
+	weight    int
+}
+
+// setPriorityQueue adds the queue of the high priority events to the output
+// controller. It must be called before the output is set.
+func (c *outputController) setPriorityQueue(q queue.Queue, weight int) {
+	ctx := &batchContext{observer: c.observer, deadLetter: c.deadLetter}
+	l := &priorityLevel{
+		queue:     q,
+		workQueue: makeWorkQueue(),
+		consumer:  newEventConsumer(c.monitors.Logger, q, ctx),
+		weight:    weight,
+	}
+	l.retryer = newRetryer(c.monitors.Logger, c.observer, l.workQueue, l.consumer)
+	ctx.retryer = l.retryer
+
+	l.consumer.sigContinue()
+	c.high = l
+}
+
+func (l *priorityLevel) close() {
+	l.consumer.sigPause()
+	l.consumer.close()
+	l.retryer.close()
+	close(l.workQueue)
+}
+
+// updOutput passes the output group to the consumer of the level, with the
+// work queue of the level.
+func (l *priorityLevel) updOutput(grp *outputGroup) {
+	levelGrp := *grp
+	levelGrp.workQueue = l.workQueue
+	l.consumer.updOutput(&levelGrp)
+}
+
+// priorityProducer publishes the high priority events to the producer of
+// the high priority queue, and the other events to the default producer.
+// The ACKs of both producers are merged, so the client receives them in
+// publishing order.
+type priorityProducer struct {
+	// producers contains the default producer, then the producer of the
+	// high priority queue.
+	producers [2]queue.Producer
+	acks      *routeACKs
+}
+
+func newPriorityProducer(high queue.Queue, cfg queue.ProducerConfig, makeDefault func(queue.ProducerConfig) queue.Producer) *priorityProducer {
+	var acks *routeACKs
+	if cfg.ACK != nil {
+		acks = &routeACKs{fn: cfg.ACK, acked: make([]int, 2)}
+	}
+	producerCfg := func(i int) queue.ProducerConfig {
+		c := cfg
+		if acks != nil {
+			c.ACK = func(count int) { acks.ack(i, count) }
+		}
+		return c
+	}
+
+	return &priorityProducer{
+		producers: [2]queue.Producer{
+			makeDefault(producerCfg(0)),
+			high.Producer(producerCfg(1)),
+		},
+		acks: acks,
+	}
+}
+
+func (p *priorityProducer) Publish(event publisher.Event) bool {
+	return p.publish(event, queue.Producer.Publish)
+}
+
+func (p *priorityProducer) TryPublish(event publisher.Event) bool {
+	return p.publish(event, queue.Producer.TryPublish)
+}
+
+func (p *priorityProducer) publish(event publisher.Event, fn func(queue.Producer, publisher.Event) bool) bool {
+	i := 0
+	if event.Content.Priority() == beat.PriorityHigh {
+		i = 1
+	}
+	if p.acks != nil {
+		p.acks.add(i, nil)
+	}
+
+	published := fn(p.producers[i], event)
+	if !published && p.acks != nil {
+		p.acks.settle(false, nil)
+	}
+	return published
+}
+
+func (p *priorityProducer) Cancel() int {
+	return p.producers[0].Cancel() + p.producers[1].Cancel()
+}
@@ -0,0 +1,155 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/acker"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+func TestPriorityConfig(t *testing.T) {
+	var config Config
+	err := common.MustNewConfigFrom(map[string]interface{}{
+		"pipeline.priority.enabled": true,
+	}).Unpack(&config)
+	require.NoError(t, err)
+
+	expected := defaultPriorityConfig()
+	expected.Enabled = true
+	assert.Equal(t, expected, config.Priority)
+
+	err = common.MustNewConfigFrom(map[string]interface{}{
+		"pipeline.priority.weight": 0,
+	}).Unpack(&config)
+	assert.Error(t, err)
+}
+
+func TestWorkerNextPriority(t *testing.T) {
+	high, normal := make(workQueue, 5), make(workQueue, 5)
+	batches := map[publisher.Batch]string{}
+	for i := 0; i < 5; i++ {
+		b := &mockBatch{}
+		batches[b] = "high"
+		high <- b
+
+		b = &mockBatch{}
+		batches[b] = "normal"
+		normal <- b
+	}
+
+	w := worker{qu: normal, high: high, weight: 2, done: make(chan struct{})}
+	var order []string
+	for i := 0; i < 10; i++ {
+		batch, ok := w.next()
+		require.True(t, ok)
+		order = append(order, batches[batch])
+	}
+
+	// After 2 consecutive high priority batches, a normal batch is
+	// published, until there are no more high priority batches.
+	expected := []string{"high", "high", "normal", "high", "high", "normal", "high", "normal", "normal", "normal"}
+	assert.Equal(t, expected, order)
+
+	w.close()
+	_, ok := w.next()
+	assert.False(t, ok)
+}
+
+func TestPriorityPipeline(t *testing.T) {
+	pipeline, err := New(beat.Info{},
+		Monitors{},
+		makeTestMemQueue,
+		outputs.Group{},
+		Settings{
+			Priority:             PriorityConfig{Enabled: true, Weight: 4},
+			priorityQueueFactory: makeTestMemQueue,
+		},
+	)
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	// The output blocks on the first batch, until the high priority events
+	// are published.
+	var (
+		mu      sync.Mutex
+		batches []beat.Priority
+	)
+	started, unblock := make(chan struct{}), make(chan struct{})
+	pipeline.output.Set(outputs.Group{BatchSize: 5, Clients: []outputs.Client{
+		newMockClient(func(batch publisher.Batch) error {
+			priority := batch.Events()[0].Content.Priority()
+			for _, event := range batch.Events()[1:] {
+				assert.Equal(t, priority, event.Content.Priority())
+			}
+
+			mu.Lock()
+			batches = append(batches, priority)
+			first := len(batches) == 1
+			mu.Unlock()
+
+			if first {
+				close(started)
+				<-unblock
+			}
+			batch.ACK()
+			return nil
+		}),
+	}})
+
+	var acked atomic.Int
+	client, err := pipeline.ConnectWith(beat.ClientConfig{
+		ACKHandler: acker.RawCounting(func(n int) { acked.Add(n) }),
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	for i := 0; i < 20; i++ {
+		client.Publish(beat.Event{Fields: common.MapStr{"n": i}})
+	}
+	<-started
+	for i := 0; i < 5; i++ {
+		event := beat.Event{Fields: common.MapStr{"alert": i}}
+		event.SetPriority(beat.PriorityHigh)
+		client.Publish(event)
+	}
+
+	// wait for the consumer of the high priority queue to pass its batch
+	time.Sleep(100 * time.Millisecond)
+	close(unblock)
+
+	assert.Eventually(t, func() bool {
+		return acked.Load() == 25
+	}, 10*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.True(t, len(batches) > 2)
+	assert.Equal(t, beat.PriorityNormal, batches[0])
+	assert.Equal(t, beat.PriorityHigh, batches[1])
+}
@@ -0,0 +1,66 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"context"
+
+	"golang.org/x/time/rate"
+
+	"github.com/elastic/beats/v7/libbeat/outputs"
+)
+
+// rateLimiter limits the rate of the events passed to the output workers.
+// The consumer waits before passing a batch to the workers, so the queue
+// fills up and applies backpressure to the inputs.
+type rateLimiter struct {
+	limiter *rate.Limiter
+	burst   int
+}
+
+// newRateLimiter returns nil if config is nil or doesn't limit the rate.
+func newRateLimiter(config *outputs.RateLimitConfig) *rateLimiter {
+	if config == nil || !config.Enabled() {
+		return nil
+	}
+	burst := config.BurstSize()
+	return &rateLimiter{
+		limiter: rate.NewLimiter(rate.Limit(config.EventsPerSecond), burst),
+		burst:   burst,
+	}
+}
+
+// wait blocks until n events can be published, or until ctx is cancelled.
+// Batches larger than the burst wait for the burst multiple times.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+
+	for n > 0 {
+		k := n
+		if k > l.burst {
+			k = l.burst
+		}
+		if err := l.limiter.WaitN(ctx, k); err != nil {
+			return err
+		}
+		n -= k
+	}
+	return nil
+}
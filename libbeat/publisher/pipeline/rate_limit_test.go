@@ -0,0 +1,90 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+func TestRateLimiter(t *testing.T) {
+	assert.Nil(t, newRateLimiter(nil))
+	assert.Nil(t, newRateLimiter(&outputs.RateLimitConfig{}))
+
+	l := newRateLimiter(&outputs.RateLimitConfig{EventsPerSecond: 100, Burst: 10})
+
+	// batches larger than the burst wait for it multiple times
+	start := time.Now()
+	require.NoError(t, l.wait(context.Background(), 30))
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 150*time.Millisecond, "elapsed %v", elapsed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, l.wait(ctx, 30))
+}
+
+func TestRateLimitedOutput(t *testing.T) {
+	pipeline, err := New(beat.Info{},
+		Monitors{},
+		makeTestMemQueue,
+		outputs.Group{},
+		Settings{RateLimit: outputs.RateLimitConfig{EventsPerSecond: 1000}},
+	)
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	var published atomic.Int
+	pipeline.output.Set(outputs.Group{
+		BatchSize: 10,
+		RateLimit: &outputs.RateLimitConfig{EventsPerSecond: 100, Burst: 10},
+		Clients: []outputs.Client{
+			newMockClient(func(batch publisher.Batch) error {
+				published.Add(len(batch.Events()))
+				batch.ACK()
+				return nil
+			}),
+		},
+	})
+
+	client, err := pipeline.ConnectWith(beat.ClientConfig{})
+	require.NoError(t, err)
+	defer client.Close()
+
+	start := time.Now()
+	for i := 0; i < 40; i++ {
+		client.Publish(beat.Event{Fields: common.MapStr{"message": "hello"}})
+	}
+	assert.Eventually(t, func() bool {
+		return published.Load() == 40
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// the lowest limit applies, after the initial burst
+	elapsed := time.Since(start)
+	assert.True(t, elapsed >= 250*time.Millisecond, "elapsed %v", elapsed)
+}
@@ -0,0 +1,463 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/conditions"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+)
+
+// RouteConfig configures a route of the pipeline, publishing the events
+// matching a condition to an output other than the default one.
+type RouteConfig struct {
+	Name      string                 `config:"name"   validate:"required"`
+	Condition *conditions.Config     `config:"when"   validate:"required"`
+	Output    common.ConfigNamespace `config:"output" validate:"required"`
+}
+
+// MirrorConfig configures a mirror of the pipeline, publishing a copy of the
+// events matching its optional condition to another output, in addition to
+// the default output or the output of their route.
+type MirrorConfig struct {
+	Name      string                 `config:"name"   validate:"required"`
+	Condition *conditions.Config     `config:"when"`
+	Output    common.ConfigNamespace `config:"output" validate:"required"`
+}
+
+// RouteOutputFactory creates the output of a route or of a mirror.
+type RouteOutputFactory func(outputs.Observer, common.ConfigNamespace) (outputs.Group, error)
+
+func validateRoutes(routes []RouteConfig, mirrors []MirrorConfig) error {
+	names := map[string]bool{}
+	for _, r := range routes {
+		if names[r.Name] {
+			return fmt.Errorf("duplicate route name '%v'", r.Name)
+		}
+		names[r.Name] = true
+
+		if !r.Output.IsSet() {
+			return fmt.Errorf("route '%v' has no output", r.Name)
+		}
+	}
+
+	names = map[string]bool{}
+	for _, m := range mirrors {
+		if names[m.Name] {
+			return fmt.Errorf("duplicate mirror name '%v'", m.Name)
+		}
+		names[m.Name] = true
+
+		if !m.Output.IsSet() {
+			return fmt.Errorf("mirror '%v' has no output", m.Name)
+		}
+	}
+	return nil
+}
+
+// route publishes the events matching its condition to its own queue and
+// output, so a blocked output only blocks the events routed to it. The
+// condition of a mirror is nil if it matches all events.
+type route struct {
+	name      string
+	condition conditions.Condition
+	queue     queue.Queue
+	output    *outputController
+
+	// routed counts the events published to the route.
+	routed *monitoring.Uint
+}
+
+// Registries of the metrics of the routes and mirrors, a registry per route
+// or mirror contains the output metrics and the number of routed events.
+const (
+	routeRegistry  = "output_routes"
+	mirrorRegistry = "output_mirrors"
+)
+
+// addRoute creates the queue and the output of a route. Routes must be added
+// before clients connect to the pipeline.
+func (p *Pipeline) addRoute(
+	config RouteConfig,
+	queueFactory queueFactory,
+	makeOutput RouteOutputFactory,
+) error {
+	condition, err := conditions.NewCondition(config.Condition)
+	if err != nil {
+		return fmt.Errorf("invalid condition of route '%v': %w", config.Name, err)
+	}
+
+	r, err := p.newRoute(routeRegistry, config.Name, config.Output, &p.eventer, queueFactory, makeOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create route '%v': %w", config.Name, err)
+	}
+	r.condition = condition
+	p.routes = append(p.routes, r)
+	return nil
+}
+
+// addMirror creates the queue and the output of a mirror. Mirrors must be
+// added before clients connect to the pipeline.
+func (p *Pipeline) addMirror(
+	config MirrorConfig,
+	queueFactory queueFactory,
+	makeOutput RouteOutputFactory,
+) error {
+	var condition conditions.Condition
+	if config.Condition != nil {
+		var err error
+		condition, err = conditions.NewCondition(config.Condition)
+		if err != nil {
+			return fmt.Errorf("invalid condition of mirror '%v': %w", config.Name, err)
+		}
+	}
+
+	// The events of a mirror are copies, they are not reported to the
+	// metrics of the queue and to the pipeline waiting on close.
+	listener := &pipelineEventer{observer: nilObserver}
+	m, err := p.newRoute(mirrorRegistry, config.Name, config.Output, listener, queueFactory, makeOutput)
+	if err != nil {
+		return fmt.Errorf("failed to create mirror '%v': %w", config.Name, err)
+	}
+	m.condition = condition
+	p.mirrors = append(p.mirrors, m)
+	return nil
+}
+
+func (p *Pipeline) newRoute(
+	registry, name string,
+	output common.ConfigNamespace,
+	listener queue.ACKListener,
+	queueFactory queueFactory,
+	makeOutput RouteOutputFactory,
+) (*route, error) {
+	var (
+		reg     *monitoring.Registry
+		stats   outputs.Observer
+		counter *monitoring.Uint
+	)
+	if p.monitors.Metrics != nil {
+		routes := p.monitors.Metrics.GetRegistry(registry)
+		if routes == nil {
+			routes = p.monitors.Metrics.NewRegistry(registry)
+		}
+		if reg = routes.GetRegistry(name); reg != nil {
+			reg.Clear()
+		} else {
+			reg = routes.NewRegistry(name)
+		}
+		stats = outputs.NewStats(reg)
+		counter = monitoring.NewUint(reg, "routed")
+		monitoring.NewString(reg, "type").Set(output.Name())
+	} else {
+		counter = &monitoring.Uint{}
+	}
+
+	out, err := makeOutput(stats, output)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := queueFactory(listener)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &route{
+		name:   name,
+		queue:  q,
+		output: newOutputController(p.beatInfo, p.monitors, p.observer, q, p.output.circuitBreaker, p.output.adaptiveBatch, p.output.rateLimit, p.output.deadLetter),
+		routed: counter,
+	}
+	r.output.Set(out)
+	return r, nil
+}
+
+func (r *route) close() {
+	r.output.Close()
+	r.queue.Close()
+}
+
+func (r *route) matches(event *publisher.Event) bool {
+	return r.condition == nil || r.condition.Check(&event.Content)
+}
+
+// routedProducer publishes each event to the producer of the first route
+// matching it, or to the producer of the default output, and a copy of the
+// event to the producers of the mirrors matching it. The ACKs of the routes
+// and mirrors are merged, so the client receives them in publishing order,
+// once all the outputs of an event have ACKed it.
+type routedProducer struct {
+	routes  []*route
+	mirrors []*route
+
+	// producers contains a producer per route, the default one, then a
+	// producer per mirror.
+	producers []queue.Producer
+
+	// allMirrors contains the indices of the producers of all mirrors, it's
+	// shared by the events matching all mirrors.
+	allMirrors []int
+
+	acks *routeACKs
+}
+
+func newRoutedProducer(p *Pipeline, cfg queue.ProducerConfig, makeDefault func(queue.ProducerConfig) queue.Producer) *routedProducer {
+	n := len(p.routes) + 1 + len(p.mirrors)
+
+	var acks *routeACKs
+	if cfg.ACK != nil {
+		acks = &routeACKs{fn: cfg.ACK, acked: make([]int, n)}
+	}
+	producerCfg := func(i int) queue.ProducerConfig {
+		c := cfg
+		if acks != nil {
+			c.ACK = func(count int) { acks.ack(i, count) }
+		}
+		return c
+	}
+
+	producers := make([]queue.Producer, 0, n)
+	for _, r := range p.routes {
+		producers = append(producers, r.queue.Producer(producerCfg(len(producers))))
+	}
+	producers = append(producers, makeDefault(producerCfg(len(producers))))
+
+	var allMirrors []int
+	for _, m := range p.mirrors {
+		// dropped copies are not reported to the client
+		c := producerCfg(len(producers))
+		c.OnDrop = nil
+		allMirrors = append(allMirrors, len(producers))
+		producers = append(producers, m.queue.Producer(c))
+	}
+
+	return &routedProducer{
+		routes:     p.routes,
+		mirrors:    p.mirrors,
+		producers:  producers,
+		allMirrors: allMirrors,
+		acks:       acks,
+	}
+}
+
+func (p *routedProducer) Publish(event publisher.Event) bool {
+	return p.publish(event, queue.Producer.Publish)
+}
+
+func (p *routedProducer) TryPublish(event publisher.Event) bool {
+	return p.publish(event, queue.Producer.TryPublish)
+}
+
+func (p *routedProducer) publish(event publisher.Event, fn func(queue.Producer, publisher.Event) bool) bool {
+	i := p.selectRoute(&event)
+	mirrors := p.selectMirrors(&event)
+	if len(mirrors) > 0 {
+		// The fields are shared by the copies, they can't be recycled
+		// when the first copy is ACKed.
+		event.Flags &^= publisher.RecycleFields
+	}
+	if p.acks != nil {
+		p.acks.add(i, mirrors)
+	}
+
+	published := fn(p.producers[i], event)
+	if published && i < len(p.routes) {
+		p.routes[i].routed.Inc()
+	}
+	if len(mirrors) == 0 {
+		if !published && p.acks != nil {
+			p.acks.settle(false, nil)
+		}
+		return published
+	}
+
+	// Only the original event holds the memory reserved in the queue budget.
+	reserved := event.Reserved
+	event.Reserved = 0
+
+	var failed []int
+	for _, j := range mirrors {
+		if fn(p.producers[j], event) {
+			p.mirrors[j-len(p.routes)-1].routed.Inc()
+		} else {
+			failed = append(failed, j)
+		}
+	}
+	if (!published || len(failed) > 0) && p.acks != nil {
+		p.acks.settle(published, failed)
+	}
+
+	if !published && len(failed) < len(mirrors) {
+		// Published by the mirrors only, the client must not release the
+		// reserved memory again.
+		queueBudget.Release(reserved)
+		return true
+	}
+	return published
+}
+
+// selectRoute returns the index of the producer of the event.
+func (p *routedProducer) selectRoute(event *publisher.Event) int {
+	for i, r := range p.routes {
+		if r.condition.Check(&event.Content) {
+			return i
+		}
+	}
+	return len(p.routes)
+}
+
+// selectMirrors returns the indices of the producers of the mirrors
+// matching the event.
+func (p *routedProducer) selectMirrors(event *publisher.Event) []int {
+	var selected []int
+	for j, m := range p.mirrors {
+		if m.matches(event) {
+			selected = append(selected, p.allMirrors[j])
+		}
+	}
+	if len(selected) == len(p.allMirrors) {
+		return p.allMirrors
+	}
+	return selected
+}
+
+// Cancel cancels all producers, and returns the number of events dropped by
+// the producers of the routes and the default output. The copies dropped
+// by the producers of the mirrors are not counted.
+func (p *routedProducer) Cancel() int {
+	n := 0
+	for i, producer := range p.producers {
+		dropped := producer.Cancel()
+		if i <= len(p.routes) {
+			n += dropped
+		}
+	}
+	return n
+}
+
+// routeTargets contains the indices of the producers of a published event,
+// primary is -1 if the producer of the route or the default output did not
+// accept the event.
+type routeTargets struct {
+	primary int
+	mirrors []int
+}
+
+// routeACKs tracks the producers of each published event, to pass the ACKs
+// of the routes and mirrors to the client in publishing order. An ACK is
+// passed on once all producers of the event ACKed it, and the ACKs of all
+// the events published before are passed on.
+type routeACKs struct {
+	mu    sync.Mutex
+	fn    func(int)
+	order []routeTargets // producers of the events not ACKed yet, in publishing order
+	acked []int          // number of events ACKed by each producer, not passed on yet
+}
+
+func (a *routeACKs) add(primary int, mirrors []int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.order = append(a.order, routeTargets{primary: primary, mirrors: mirrors})
+}
+
+// settle removes the producers that did not accept the last event added, or
+// the event if no producer accepted it. Events are added by a single client,
+// so the last event is always the one being published.
+func (a *routeACKs) settle(primary bool, failed []int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	last := &a.order[len(a.order)-1]
+	if !primary && len(failed) == len(last.mirrors) {
+		a.order = a.order[:len(a.order)-1]
+		return
+	}
+
+	if !primary {
+		last.primary = -1
+	}
+	if len(failed) > 0 {
+		// the mirrors might be shared with other events, they are copied
+		mirrors := make([]int, 0, len(last.mirrors)-len(failed))
+		for _, j := range last.mirrors {
+			if !containsInt(failed, j) {
+				mirrors = append(mirrors, j)
+			}
+		}
+		last.mirrors = mirrors
+	}
+	a.flush()
+}
+
+func (a *routeACKs) ack(producer, count int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.acked[producer] += count
+	a.flush()
+}
+
+// flush passes on the ACKs of the events at the head of order ACKed by all
+// their producers. It must be called with the lock held.
+func (a *routeACKs) flush() {
+	n := 0
+	for n < len(a.order) && a.isACKed(a.order[n]) {
+		t := a.order[n]
+		if t.primary >= 0 {
+			a.acked[t.primary]--
+		}
+		for _, j := range t.mirrors {
+			a.acked[j]--
+		}
+		n++
+	}
+	if n == 0 {
+		return
+	}
+
+	a.order = a.order[n:]
+	a.fn(n)
+}
+
+func (a *routeACKs) isACKed(t routeTargets) bool {
+	if t.primary >= 0 && a.acked[t.primary] == 0 {
+		return false
+	}
+	for _, j := range t.mirrors {
+		if a.acked[j] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,360 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/acker"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/monitoring"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/memqueue"
+)
+
+func makeTestMemQueue(ackListener queue.ACKListener) (queue.Queue, error) {
+	return memqueue.NewQueue(logp.L(), memqueue.Settings{
+		ACKListener: ackListener,
+		Events:      64,
+	}), nil
+}
+
+func mustRouteConfig(t *testing.T, config string) RouteConfig {
+	cfg, err := common.NewConfigFrom(config)
+	require.NoError(t, err)
+
+	var route RouteConfig
+	require.NoError(t, cfg.Unpack(&route))
+	return route
+}
+
+func TestRouteACKsOrder(t *testing.T) {
+	var acked []int
+	acks := &routeACKs{fn: func(n int) { acked = append(acked, n) }, acked: make([]int, 2)}
+
+	for _, r := range []int{0, 1, 1, 0, 1} {
+		acks.add(r, nil)
+	}
+	acks.add(0, nil)
+	acks.settle(false, nil)
+
+	// the events of route 1 are held back until the first event of route 0
+	// is ACKed
+	acks.ack(1, 2)
+	assert.Empty(t, acked)
+	acks.ack(0, 1)
+	assert.Equal(t, []int{3}, acked)
+	acks.ack(0, 1)
+	assert.Equal(t, []int{3, 1}, acked)
+	acks.ack(1, 1)
+	assert.Equal(t, []int{3, 1, 1}, acked)
+	assert.Empty(t, acks.order)
+}
+
+func TestRouteACKsMirrors(t *testing.T) {
+	var acked []int
+	acks := &routeACKs{fn: func(n int) { acked = append(acked, n) }, acked: make([]int, 3)}
+
+	all := []int{1, 2}
+	acks.add(0, all)
+	acks.add(0, []int{2})
+	acks.add(0, all)
+	acks.settle(true, []int{1})
+	assert.Equal(t, []int{1, 2}, all, "shared mirrors are not modified")
+	acks.add(0, all)
+	acks.settle(false, []int{1, 2})
+
+	// the first event is ACKed once all its copies are ACKed
+	acks.ack(0, 3)
+	acks.ack(2, 1)
+	assert.Empty(t, acked)
+	acks.ack(1, 1)
+	assert.Equal(t, []int{1}, acked)
+	acks.ack(2, 2)
+	assert.Equal(t, []int{1, 2}, acked)
+	assert.Empty(t, acks.order)
+
+	// events published by a mirror only
+	acks.add(0, all)
+	acks.settle(false, []int{2})
+	acks.ack(1, 1)
+	assert.Equal(t, []int{1, 2, 1}, acked)
+}
+
+func TestRoutedPipeline(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	pipeline, err := New(beat.Info{},
+		Monitors{Metrics: reg},
+		makeTestMemQueue,
+		outputs.Group{},
+		Settings{},
+	)
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	var defaultPublished atomic.Int
+	pipeline.output.Set(outputs.Group{Clients: []outputs.Client{
+		newMockClient(func(batch publisher.Batch) error {
+			defaultPublished.Add(len(batch.Events()))
+			batch.ACK()
+			return nil
+		}),
+	}})
+
+	var auditPublished atomic.Int
+	makeOutput := func(_ outputs.Observer, cfg common.ConfigNamespace) (outputs.Group, error) {
+		assert.Equal(t, "console", cfg.Name())
+		return outputs.Group{Clients: []outputs.Client{
+			newMockClient(func(batch publisher.Batch) error {
+				for _, event := range batch.Events() {
+					assert.Equal(t, "audit", event.Content.Fields["type"])
+				}
+				auditPublished.Add(len(batch.Events()))
+				batch.ACK()
+				return nil
+			}),
+		}}, nil
+	}
+	route := mustRouteConfig(t, `
+name: audit
+when.equals.type: audit
+output.console.enabled: true
+`)
+	require.NoError(t, pipeline.addRoute(route, makeTestMemQueue, makeOutput))
+
+	var acked atomic.Int
+	client, err := pipeline.ConnectWith(beat.ClientConfig{
+		ACKHandler: acker.RawCounting(func(n int) { acked.Add(n) }),
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	for i := 0; i < 30; i++ {
+		typ := "access"
+		if i%3 == 0 {
+			typ = "audit"
+		}
+		client.Publish(beat.Event{Fields: common.MapStr{"type": typ}})
+	}
+
+	assert.Eventually(t, func() bool {
+		return acked.Load() == 30
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 20, defaultPublished.Load())
+	assert.Equal(t, 10, auditPublished.Load())
+
+	snapshot := monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+	assert.Equal(t, int64(10), snapshot.Ints["output_routes.audit.routed"])
+	assert.Equal(t, "console", snapshot.Strings["output_routes.audit.type"])
+}
+
+func TestRouteBlockedOutput(t *testing.T) {
+	pipeline, err := New(beat.Info{},
+		Monitors{},
+		makeTestMemQueue,
+		outputs.Group{},
+		Settings{},
+	)
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	var defaultPublished atomic.Int
+	pipeline.output.Set(outputs.Group{Clients: []outputs.Client{
+		newMockClient(func(batch publisher.Batch) error {
+			defaultPublished.Add(len(batch.Events()))
+			batch.ACK()
+			return nil
+		}),
+	}})
+
+	// the output of the route never publishes its events
+	blocked := make(chan struct{})
+	defer close(blocked)
+	makeOutput := func(outputs.Observer, common.ConfigNamespace) (outputs.Group, error) {
+		return outputs.Group{Clients: []outputs.Client{
+			newMockClient(func(batch publisher.Batch) error {
+				<-blocked
+				batch.Cancelled()
+				return nil
+			}),
+		}}, nil
+	}
+	route := mustRouteConfig(t, `
+name: slow
+when.has_fields: [slow]
+output.console.enabled: true
+`)
+	require.NoError(t, pipeline.addRoute(route, makeTestMemQueue, makeOutput))
+
+	slow, err := pipeline.ConnectWith(beat.ClientConfig{PublishMode: beat.DropIfFull})
+	require.NoError(t, err)
+	defer slow.Close()
+	fast, err := pipeline.ConnectWith(beat.ClientConfig{})
+	require.NoError(t, err)
+	defer fast.Close()
+
+	// fill the queue of the route
+	for i := 0; i < 200; i++ {
+		slow.Publish(beat.Event{Fields: common.MapStr{"slow": true}})
+	}
+	for i := 0; i < 200; i++ {
+		fast.Publish(beat.Event{Fields: common.MapStr{"message": "hello"}})
+	}
+
+	assert.Eventually(t, func() bool {
+		return defaultPublished.Load() == 200
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestMirroredPipeline(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	pipeline, err := New(beat.Info{},
+		Monitors{Metrics: reg},
+		makeTestMemQueue,
+		outputs.Group{},
+		Settings{},
+	)
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	var defaultPublished atomic.Int
+	pipeline.output.Set(outputs.Group{Clients: []outputs.Client{
+		newMockClient(func(batch publisher.Batch) error {
+			defaultPublished.Add(len(batch.Events()))
+			batch.ACK()
+			return nil
+		}),
+	}})
+
+	// the archive ACKs its events once unblocked
+	var archived atomic.Int
+	unblock := make(chan struct{})
+	makeOutput := func(outputs.Observer, common.ConfigNamespace) (outputs.Group, error) {
+		return outputs.Group{Clients: []outputs.Client{
+			newMockClient(func(batch publisher.Batch) error {
+				<-unblock
+				archived.Add(len(batch.Events()))
+				batch.ACK()
+				return nil
+			}),
+		}}, nil
+	}
+	mirror := MirrorConfig{Name: "archive"}
+	require.NoError(t, common.MustNewConfigFrom(`output.console.enabled: true`).Unpack(&mirror))
+	require.NoError(t, pipeline.addMirror(mirror, makeTestMemQueue, makeOutput))
+
+	var acked atomic.Int
+	client, err := pipeline.ConnectWith(beat.ClientConfig{
+		ACKHandler: acker.RawCounting(func(n int) { acked.Add(n) }),
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	for i := 0; i < 30; i++ {
+		client.Publish(beat.Event{Fields: common.MapStr{"message": "hello"}})
+	}
+
+	// the events published by the default output are not ACKed while the
+	// mirror is blocked
+	assert.Eventually(t, func() bool {
+		return defaultPublished.Load() == 30
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, acked.Load())
+
+	close(unblock)
+	assert.Eventually(t, func() bool {
+		return acked.Load() == 30
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 30, archived.Load())
+
+	snapshot := monitoring.CollectFlatSnapshot(reg, monitoring.Full, false)
+	assert.Equal(t, int64(30), snapshot.Ints["output_mirrors.archive.routed"])
+}
+
+func TestConfigRoutesValidate(t *testing.T) {
+	cases := map[string]struct {
+		config string
+		valid  bool
+	}{
+		"no routes": {config: `pipeline.shards: 1`, valid: true},
+		"route": {
+			config: `pipeline.routes: [{name: audit, when.equals.type: audit, output.console.enabled: true}]`,
+			valid:  true,
+		},
+		"duplicate name": {
+			config: `pipeline.routes: [{name: a, when.has_fields: [x], output.console: {}}, {name: a, when.has_fields: [y], output.console: {}}]`,
+			valid:  false,
+		},
+		"no condition": {
+			config: `pipeline.routes: [{name: audit, output.console.enabled: true}]`,
+			valid:  false,
+		},
+		"no output": {
+			config: `pipeline.routes: [{name: audit, when.equals.type: audit}]`,
+			valid:  false,
+		},
+		"no name": {
+			config: `pipeline.routes: [{when.equals.type: audit, output.console.enabled: true}]`,
+			valid:  false,
+		},
+		"mirror": {
+			config: `pipeline.mirrors: [{name: archive, output.console.enabled: true}]`,
+			valid:  true,
+		},
+		"mirror with condition": {
+			config: `pipeline.mirrors: [{name: archive, when.equals.type: audit, output.console.enabled: true}]`,
+			valid:  true,
+		},
+		"mirror named as route": {
+			config: "pipeline.routes: [{name: a, when.has_fields: [x], output.console: {}}]\npipeline.mirrors: [{name: a, output.console: {}}]",
+			valid:  true,
+		},
+		"duplicate mirror name": {
+			config: `pipeline.mirrors: [{name: a, output.console: {}}, {name: a, output.console: {}}]`,
+			valid:  false,
+		},
+		"mirror without output": {
+			config: `pipeline.mirrors: [{name: archive}]`,
+			valid:  false,
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := common.NewConfigFrom(test.config)
+			require.NoError(t, err)
+
+			var config Config
+			err = cfg.Unpack(&config)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"errors"
+	"hash/fnv"
+	"io"
+	"sync"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+)
+
+// shardedQueue distributes the clients of the pipeline between multiple
+// queues, so producers publishing at high rates do not contend on a single
+// queue. All clients with the same shard key publish to the same shard, the
+// clients without key are distributed round robin. The events of a client are
+// kept in order, as a client publishes to a single shard only.
+//
+// Each shard has a worker moving batches from the shard to the consumers. The
+// workers are owned by the sharded queue, so a batch fetched by a worker is not
+// lost when its consumer is closed on output reloading, but passed to the next
+// consumer.
+type shardedQueue struct {
+	shards    []queue.Queue
+	consumers []queue.Consumer
+
+	// next shard for clients without shard key
+	next atomic.Uint
+
+	// requested batch size, as passed to the last call of Get
+	batchSize atomic.Int
+	batches   chan queue.Batch
+
+	startWorkers sync.Once
+	done         chan struct{}
+	wg           sync.WaitGroup
+}
+
+type shardedConsumer struct {
+	queue  *shardedQueue
+	closed atomic.Bool
+	done   chan struct{}
+}
+
+// makeShardedQueueFactory creates a queue factory creating a sharded queue
+// with n shards, each created by factory.
+func makeShardedQueueFactory(n int, factory queueFactory) queueFactory {
+	return func(ackListener queue.ACKListener) (queue.Queue, error) {
+		shards := make([]queue.Queue, 0, n)
+		for i := 0; i < n; i++ {
+			q, err := factory(ackListener)
+			if err != nil {
+				for _, q := range shards {
+					q.Close()
+				}
+				return nil, err
+			}
+			shards = append(shards, q)
+		}
+		return newShardedQueue(shards), nil
+	}
+}
+
+func newShardedQueue(shards []queue.Queue) *shardedQueue {
+	consumers := make([]queue.Consumer, len(shards))
+	for i, q := range shards {
+		consumers[i] = q.Consumer()
+	}
+
+	return &shardedQueue{
+		shards:    shards,
+		consumers: consumers,
+		batches:   make(chan queue.Batch),
+		done:      make(chan struct{}),
+	}
+}
+
+// Close stops the shard workers and closes all shards.
+func (q *shardedQueue) Close() error {
+	close(q.done)
+	for _, c := range q.consumers {
+		c.Close()
+	}
+	q.wg.Wait()
+
+	var err error
+	for _, shard := range q.shards {
+		if closeErr := shard.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// BufferConfig reports the combined capacity of all shards.
+func (q *shardedQueue) BufferConfig() queue.BufferConfig {
+	total := 0
+	for _, shard := range q.shards {
+		maxEvents := shard.BufferConfig().MaxEvents
+		if maxEvents <= 0 {
+			return queue.BufferConfig{}
+		}
+		total += maxEvents
+	}
+	return queue.BufferConfig{MaxEvents: total}
+}
+
+// Resize divides the given number of events between the shards, the first
+// shards get the remainder.
+func (q *shardedQueue) Resize(events int) error {
+	n := len(q.shards)
+	for i, shard := range q.shards {
+		r, ok := shard.(queue.Resizer)
+		if !ok {
+			return errors.New("the queue shards can't be resized")
+		}
+		size := events / n
+		if i < events%n {
+			size++
+		}
+		if err := r.Resize(size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Inspect reports the events of all shards, and the state of each shard.
+func (q *shardedQueue) Inspect() queue.State {
+	state := queue.State{}
+	shards := make([]common.MapStr, len(q.shards))
+	for i, shard := range q.shards {
+		s := queue.State{Events: -1}
+		if inspector, ok := shard.(queue.Inspector); ok {
+			s = inspector.Inspect()
+		}
+		if s.Events < 0 || state.Events < 0 {
+			state.Events = -1
+		} else {
+			state.Events += s.Events
+		}
+		if !s.Oldest.IsZero() && (state.Oldest.IsZero() || s.Oldest.Before(state.Oldest)) {
+			state.Oldest = s.Oldest
+		}
+
+		shards[i] = common.MapStr{}
+		shards[i].Update(s.Details)
+		if s.Events >= 0 {
+			shards[i]["events"] = s.Events
+		}
+	}
+	state.Details = common.MapStr{"shards": shards}
+	return state
+}
+
+// Producer creates a producer for the next shard.
+func (q *shardedQueue) Producer(cfg queue.ProducerConfig) queue.Producer {
+	i := (q.next.Inc() - 1) % uint(len(q.shards))
+	return q.shards[i].Producer(cfg)
+}
+
+// producerFor creates a producer for the shard assigned to key.
+func (q *shardedQueue) producerFor(key string, cfg queue.ProducerConfig) queue.Producer {
+	if key == "" {
+		return q.Producer(cfg)
+	}
+	return q.shards[shardIndex(key, len(q.shards))].Producer(cfg)
+}
+
+func shardIndex(key string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// Consumer creates a consumer receiving the batches of all shards.
+func (q *shardedQueue) Consumer() queue.Consumer {
+	return &shardedConsumer{queue: q, done: make(chan struct{})}
+}
+
+func (q *shardedQueue) runWorkers() {
+	q.wg.Add(len(q.consumers))
+	for _, c := range q.consumers {
+		go func(c queue.Consumer) {
+			defer q.wg.Done()
+			q.runWorker(c)
+		}(c)
+	}
+}
+
+func (q *shardedQueue) runWorker(c queue.Consumer) {
+	for {
+		batch, err := c.Get(q.batchSize.Load())
+		if err != nil {
+			return
+		}
+
+		select {
+		case q.batches <- batch:
+		case <-q.done:
+			return
+		}
+	}
+}
+
+func (c *shardedConsumer) Get(eventCount int) (queue.Batch, error) {
+	if c.closed.Load() {
+		return nil, io.EOF
+	}
+
+	q := c.queue
+	q.batchSize.Store(eventCount)
+	q.startWorkers.Do(q.runWorkers)
+
+	select {
+	case batch := <-q.batches:
+		return batch, nil
+	case <-c.done:
+		return nil, io.EOF
+	case <-q.done:
+		return nil, io.EOF
+	}
+}
+
+func (c *shardedConsumer) Close() error {
+	if c.closed.Swap(true) {
+		return errors.New("already closed")
+	}
+
+	close(c.done)
+	return nil
+}
@@ -0,0 +1,232 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/memqueue"
+)
+
+func makeTestShardedQueue(t *testing.T, n int) *shardedQueue {
+	factory := makeShardedQueueFactory(n, func(ackListener queue.ACKListener) (queue.Queue, error) {
+		return memqueue.NewQueue(logp.L(), memqueue.Settings{
+			ACKListener: ackListener,
+			Events:      64,
+		}), nil
+	})
+	q, err := factory(nil)
+	require.NoError(t, err)
+	return q.(*shardedQueue)
+}
+
+func TestShardedQueueProducers(t *testing.T) {
+	q := makeTestShardedQueue(t, 4)
+	defer q.Close()
+
+	assert.Equal(t, 4*64, q.BufferConfig().MaxEvents)
+
+	// the same key is always assigned to the same shard
+	for _, key := range []string{"a", "b", "input-1", "input-2"} {
+		i := shardIndex(key, len(q.shards))
+		for j := 0; j < 10; j++ {
+			assert.Equal(t, i, shardIndex(key, len(q.shards)))
+		}
+	}
+
+	// keys are spread between the shards
+	used := map[int]bool{}
+	for i := 0; i < 100; i++ {
+		used[shardIndex(fmt.Sprintf("input-%d", i), len(q.shards))] = true
+	}
+	assert.Len(t, used, len(q.shards))
+}
+
+func TestShardedQueueResize(t *testing.T) {
+	q := makeTestShardedQueue(t, 3)
+	defer q.Close()
+
+	require.NoError(t, q.Resize(200))
+	assert.Equal(t, 200, q.BufferConfig().MaxEvents)
+	assert.Equal(t, 67, q.shards[0].BufferConfig().MaxEvents)
+	assert.Equal(t, 66, q.shards[2].BufferConfig().MaxEvents)
+
+	// every shard needs the minimum size of the memory queue
+	assert.Error(t, q.Resize(60))
+}
+
+func TestShardedQueueInspect(t *testing.T) {
+	q := makeTestShardedQueue(t, 2)
+	defer q.Close()
+
+	producer := q.Producer(queue.ProducerConfig{})
+	for i := 0; i < 3; i++ {
+		require.True(t, producer.Publish(publisher.Event{}))
+	}
+	require.Eventually(t, func() bool { return q.Inspect().Events == 3 }, time.Second, time.Millisecond)
+
+	state := q.Inspect()
+	assert.False(t, state.Oldest.IsZero())
+	shards := state.Details["shards"].([]common.MapStr)
+	require.Len(t, shards, 2)
+	assert.Equal(t, 3, shards[0]["events"].(int)+shards[1]["events"].(int))
+}
+
+func TestShardedQueueConsumer(t *testing.T) {
+	const (
+		clients = 8
+		events  = 500
+	)
+
+	q := makeTestShardedQueue(t, 4)
+	defer q.Close()
+
+	var acked atomic.Int
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		var producer queue.Producer
+		cfg := queue.ProducerConfig{ACK: func(n int) { acked.Add(n) }}
+		if i%2 == 0 {
+			producer = q.producerFor(fmt.Sprintf("input-%d", i), cfg)
+		} else {
+			producer = q.Producer(cfg)
+		}
+
+		wg.Add(1)
+		go func(client int) {
+			defer wg.Done()
+			for j := 0; j < events; j++ {
+				producer.Publish(publisher.Event{Content: beat.Event{
+					Fields: common.MapStr{"client": client, "seq": j},
+				}})
+			}
+		}(i)
+	}
+
+	// The events of each client are received in order, also when the
+	// consumer is replaced.
+	last := map[int]int{}
+	received := 0
+	consumer := q.Consumer()
+	for received < clients*events {
+		batch, err := consumer.Get(50)
+		require.NoError(t, err)
+
+		for _, event := range batch.Events() {
+			client := event.Content.Fields["client"].(int)
+			seq := event.Content.Fields["seq"].(int)
+			if prev, ok := last[client]; ok {
+				assert.Equal(t, prev+1, seq, "client %d", client)
+			}
+			last[client] = seq
+		}
+		received += len(batch.Events())
+		batch.ACK()
+
+		if received%1000 < 50 {
+			require.NoError(t, consumer.Close())
+			consumer = q.Consumer()
+		}
+	}
+	wg.Wait()
+
+	assert.Equal(t, clients*events, received)
+	assert.Eventually(t, func() bool {
+		return acked.Load() == clients*events
+	}, 10*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, consumer.Close())
+	_, err := consumer.Get(50)
+	assert.Error(t, err)
+	assert.Error(t, consumer.Close())
+}
+
+func TestShardedPipeline(t *testing.T) {
+	q := makeTestShardedQueue(t, 3)
+	pipeline, err := New(beat.Info{},
+		Monitors{},
+		func(queue.ACKListener) (queue.Queue, error) { return q, nil },
+		outputs.Group{},
+		Settings{},
+	)
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	var published atomic.Int
+	output := newMockClient(func(batch publisher.Batch) error {
+		published.Add(len(batch.Events()))
+		batch.ACK()
+		return nil
+	})
+	pipeline.output.Set(outputs.Group{Clients: []outputs.Client{output}})
+
+	for i := 0; i < 6; i++ {
+		client, err := pipeline.ConnectWith(beat.ClientConfig{ShardKey: fmt.Sprintf("input-%d", i%2)})
+		require.NoError(t, err)
+		for j := 0; j < 10; j++ {
+			client.Publish(beat.Event{Fields: common.MapStr{"message": "hello"}})
+		}
+		defer client.Close()
+	}
+
+	assert.Eventually(t, func() bool {
+		return published.Load() == 60
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestConfigShardsValidate(t *testing.T) {
+	cases := map[string]struct {
+		config string
+		valid  bool
+	}{
+		"no sharding":        {config: `queue.spool: {}`, valid: true},
+		"default queue":      {config: `pipeline.shards: 4`, valid: true},
+		"mem queue":          {config: "pipeline.shards: 4\nqueue.mem.events: 1024", valid: true},
+		"disk queue":         {config: "pipeline.shards: 4\nqueue.disk.max_size: 1GB", valid: false},
+		"negative shards":    {config: `pipeline.shards: -1`, valid: false},
+		"single shard spool": {config: "pipeline.shards: 1\nqueue.spool: {}", valid: true},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := common.NewConfigFrom(test.config)
+			require.NoError(t, err)
+
+			var config Config
+			err = cfg.Unpack(&config)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
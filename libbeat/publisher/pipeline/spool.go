@@ -0,0 +1,355 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/acker"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/paths"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// ShutdownSpoolConfig configures the spooling of the events not ACKed by the
+// outputs on shutdown. The events are written to a file, ACKed, and
+// published again on the next start.
+type ShutdownSpoolConfig struct {
+	Enabled bool `config:"enabled"`
+
+	// Path is the directory of the spool files, relative to the data path.
+	Path string `config:"path"`
+
+	// Timeout is the maximum time to wait for the pending events to be
+	// written.
+	Timeout time.Duration `config:"timeout" validate:"positive,nonzero"`
+}
+
+func defaultShutdownSpoolConfig() ShutdownSpoolConfig {
+	return ShutdownSpoolConfig{
+		Enabled: false,
+		Path:    "shutdown_spool",
+		Timeout: 10 * time.Second,
+	}
+}
+
+// Unpack implements the config unpacker, setting the defaults.
+func (c *ShutdownSpoolConfig) Unpack(from *common.Config) error {
+	type tmpConfig ShutdownSpoolConfig
+	tmp := tmpConfig(defaultShutdownSpoolConfig())
+	if err := from.Unpack(&tmp); err != nil {
+		return err
+	}
+
+	*c = ShutdownSpoolConfig(tmp)
+	return nil
+}
+
+const (
+	spoolFilePattern = "spool-*.ndjson"
+
+	// spoolBatchSize is the batch size of the spool output.
+	spoolBatchSize = 2048
+
+	// spoolMaxLineSize limits the size of an event read from a spool file.
+	spoolMaxLineSize = 10 * 1024 * 1024
+)
+
+// shutdownSpool writes the pending events to a new file of its directory on
+// shutdown, and publishes the events of the files found on start.
+type shutdownSpool struct {
+	log    *logp.Logger
+	info   beat.Info
+	config ShutdownSpoolConfig
+	dir    string
+
+	once sync.Once
+	done chan struct{}
+}
+
+func newShutdownSpool(log *logp.Logger, info beat.Info, config ShutdownSpoolConfig) *shutdownSpool {
+	if !config.Enabled {
+		return nil
+	}
+	return &shutdownSpool{
+		log:    log,
+		info:   info,
+		config: config,
+		dir:    paths.Resolve(paths.Data, config.Path),
+		done:   make(chan struct{}),
+	}
+}
+
+func (s *shutdownSpool) close() {
+	if s != nil {
+		close(s.done)
+	}
+}
+
+// SpoolPending writes the events not ACKed by the outputs to a spool file, if
+// the shutdown spool is enabled. The outputs are replaced by the spool, so
+// the events written are ACKed, and the inputs can record their progress.
+// SpoolPending waits until all pending events are written, or for the timeout
+// of the spool. It must be called once the clients stopped publishing, and
+// only the first call spools the events.
+func (p *Pipeline) SpoolPending() error {
+	if p.spool == nil {
+		return nil
+	}
+
+	var err error
+	p.spool.once.Do(func() {
+		err = p.spoolPending()
+	})
+	return err
+}
+
+func (p *Pipeline) spoolPending() error {
+	s := p.spool
+	path := filepath.Join(s.dir, fmt.Sprintf("spool-%d.ndjson", time.Now().UnixNano()))
+	writer := &spoolWriter{
+		path:  path,
+		index: s.info.Beat,
+		codec: json.New(s.info.Version, json.Config{TimestampPrecision: common.TimestampNanosecond}),
+	}
+	group := outputs.Group{Clients: []outputs.Client{writer}, BatchSize: spoolBatchSize}
+
+	// The events of the routes are routed again when they are published
+	// from the spool, so the copies of the mirrors are not needed.
+	for _, r := range p.routes {
+		r.output.rateLimit = nil
+		r.output.Set(group)
+	}
+	for _, m := range p.mirrors {
+		m.output.rateLimit = nil
+		m.output.Set(outputs.Group{Clients: []outputs.Client{discardClient{}}, BatchSize: spoolBatchSize})
+	}
+	p.output.rateLimit = nil
+	p.output.Set(group)
+
+	var err error
+	if !p.waitCloser.wait(s.config.Timeout) {
+		err = fmt.Errorf("timeout after %v spooling the pending events", s.config.Timeout)
+	}
+
+	if cerr := writer.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if n := writer.events.Load(); n > 0 {
+		s.log.Infof("Spooled %d pending events to %v", n, path)
+	}
+	return err
+}
+
+// replay publishes the events of the spool files, and removes each file once
+// all its events are ACKed.
+func (s *shutdownSpool) replay(p *Pipeline) {
+	files, err := filepath.Glob(filepath.Join(s.dir, spoolFilePattern))
+	if err != nil || len(files) == 0 {
+		return
+	}
+	sort.Strings(files)
+
+	var acked atomic.Int
+	notify := make(chan struct{}, 1)
+	client, err := p.connectUnprocessed(beat.ClientConfig{
+		PublishMode: beat.GuaranteedSend,
+		ACKHandler: acker.RawCounting(func(n int) {
+			acked.Add(n)
+			select {
+			case notify <- struct{}{}:
+			default:
+			}
+		}),
+	})
+	if err != nil {
+		s.log.Errorf("Failed to publish the spooled events: %v", err)
+		return
+	}
+	defer client.Close()
+
+	published := 0
+	for _, path := range files {
+		n, err := s.publishFile(client, path)
+		if err != nil {
+			s.log.Errorf("Failed to read the spooled events of %v: %v", path, err)
+			continue
+		}
+		published += n
+		s.log.Infof("Publishing %d spooled events of %v", n, path)
+
+		for acked.Load() < published {
+			select {
+			case <-notify:
+			case <-s.done:
+				return
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			s.log.Errorf("Failed to remove the spool file %v: %v", path, err)
+		}
+	}
+}
+
+func (s *shutdownSpool) publishFile(client beat.Client, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	n := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, spoolMaxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		event, err := json.Decode(scanner.Bytes())
+		if err != nil {
+			// the last line might be truncated
+			s.log.Warnf("Skipping invalid event at line %d of %v: %v", line, path, err)
+			continue
+		}
+		client.Publish(event)
+		n++
+	}
+	return n, scanner.Err()
+}
+
+// connectUnprocessed connects a client whose events are not processed, as
+// they have been processed when they were published the first time.
+func (p *Pipeline) connectUnprocessed(cfg beat.ClientConfig) (beat.Client, error) {
+	c, err := p.ConnectWith(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.(*client).processors = nil
+	return c, nil
+}
+
+// spoolWriter is the output replacing the outputs of the pipeline on
+// shutdown. It ACKs the events once they are written to its file.
+type spoolWriter struct {
+	path  string
+	index string
+	codec *json.Encoder
+
+	mu     sync.Mutex
+	file   *os.File
+	buf    *bufio.Writer
+	err    error
+	events atomic.Int
+}
+
+func (w *spoolWriter) Publish(_ context.Context, batch publisher.Batch) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.write(batch.Events()); err != nil {
+		// The events are not ACKed, so the inputs publish them again on the
+		// next start.
+		batch.Cancelled()
+		return err
+	}
+	batch.ACK()
+	return nil
+}
+
+func (w *spoolWriter) write(events []publisher.Event) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.file == nil {
+		if err := os.MkdirAll(filepath.Dir(w.path), 0750); err != nil {
+			w.err = err
+			return err
+		}
+		f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			w.err = err
+			return err
+		}
+		w.file = f
+		w.buf = bufio.NewWriter(f)
+	}
+
+	written := 0
+	for i := range events {
+		line, err := w.codec.Encode(w.index, &events[i].Content)
+		if err != nil {
+			// not encodable events are not published by the outputs either
+			continue
+		}
+		w.buf.Write(line)
+		w.buf.WriteByte('\n')
+		written++
+	}
+	if err := w.buf.Flush(); err != nil {
+		w.err = fmt.Errorf("failed to write to %v: %w", w.path, err)
+		return w.err
+	}
+	w.events.Add(written)
+	return nil
+}
+
+// Close closes the file, it's called for each group of the pipeline the
+// writer was set for.
+func (w *spoolWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Sync()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	w.file = nil
+	if w.err == nil {
+		w.err = os.ErrClosed
+	}
+	return err
+}
+
+func (w *spoolWriter) String() string { return "shutdown_spool" }
+
+// discardClient ACKs the events without publishing them.
+type discardClient struct{}
+
+func (discardClient) Publish(_ context.Context, batch publisher.Batch) error {
+	batch.ACK()
+	return nil
+}
+
+func (discardClient) Close() error   { return nil }
+func (discardClient) String() string { return "discard" }
@@ -0,0 +1,146 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/acker"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+func TestShutdownSpoolConfig(t *testing.T) {
+	var config Config
+	err := common.MustNewConfigFrom(map[string]interface{}{
+		"pipeline.shutdown_spool.enabled": true,
+	}).Unpack(&config)
+	require.NoError(t, err)
+
+	expected := defaultShutdownSpoolConfig()
+	expected.Enabled = true
+	assert.Equal(t, expected, config.ShutdownSpool)
+}
+
+func TestShutdownSpoolDisabled(t *testing.T) {
+	pipeline, err := New(beat.Info{}, Monitors{}, makeTestMemQueue, outputs.Group{}, Settings{})
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	assert.Nil(t, pipeline.waitCloser)
+	assert.NoError(t, pipeline.SpoolPending())
+}
+
+func TestShutdownSpool(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shutdown-spool")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := defaultShutdownSpoolConfig()
+	config.Enabled = true
+	config.Path = dir
+	info := beat.Info{Beat: "testbeat", Version: "1.2.3"}
+
+	// The pipeline has no output, so that all events are pending on shutdown.
+	pipeline, err := New(info, Monitors{}, makeTestMemQueue, outputs.Group{}, Settings{ShutdownSpool: config})
+	require.NoError(t, err)
+
+	var acked atomic.Int
+	client, err := pipeline.ConnectWith(beat.ClientConfig{
+		PublishMode: beat.GuaranteedSend,
+		ACKHandler:  acker.RawCounting(func(n int) { acked.Add(n) }),
+	})
+	require.NoError(t, err)
+
+	ts := time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		client.Publish(beat.Event{Timestamp: ts, Fields: common.MapStr{"n": i}})
+	}
+
+	require.NoError(t, pipeline.SpoolPending())
+	assert.Eventually(t, func() bool { return acked.Load() == 10 }, 10*time.Second, 10*time.Millisecond)
+	client.Close()
+	pipeline.Close()
+
+	files, err := filepath.Glob(filepath.Join(dir, spoolFilePattern))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	// The next pipeline publishes the spooled events, and removes the file
+	// once they are ACKed.
+	pipeline, err = New(info, Monitors{}, makeTestMemQueue, outputs.Group{}, Settings{ShutdownSpool: config})
+	require.NoError(t, err)
+	defer pipeline.Close()
+
+	published := make(chan beat.Event, 10)
+	pipeline.output.Set(outputs.Group{Clients: []outputs.Client{
+		newMockClient(func(batch publisher.Batch) error {
+			for _, event := range batch.Events() {
+				published <- event.Content
+			}
+			batch.ACK()
+			return nil
+		}),
+	}})
+	go pipeline.spool.replay(pipeline)
+
+	for i := 0; i < 10; i++ {
+		select {
+		case event := <-published:
+			assert.True(t, ts.Equal(event.Timestamp))
+			assert.EqualValues(t, i, event.Fields["n"])
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timeout waiting for event %d", i)
+		}
+	}
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(files[0])
+		return os.IsNotExist(err)
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestWaitCloser(t *testing.T) {
+	var wc waitCloser
+	assert.True(t, wc.wait(time.Millisecond))
+
+	wc.inc()
+	wc.inc()
+	assert.False(t, wc.wait(time.Millisecond))
+
+	wc.dec(1)
+	assert.False(t, wc.wait(time.Millisecond))
+
+	go wc.dec(1)
+	assert.True(t, wc.wait(10*time.Second))
+
+	// The counter can increase again after reaching 0.
+	wc.inc()
+	assert.False(t, wc.wait(time.Millisecond))
+	wc.dec(1)
+	assert.True(t, wc.wait(time.Millisecond))
+}
@@ -20,11 +20,10 @@ package processing
 import (
 	"fmt"
 
-	"github.com/elastic/ecs/code/go/ecs"
-
 	"github.com/elastic/beats/v7/libbeat/asset"
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/ecs"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/mapping"
 	"github.com/elastic/beats/v7/libbeat/processors"
@@ -54,6 +53,13 @@ type builder struct {
 	timeSeries       bool
 	timeseriesFields mapping.Fields
 
+	// ecsMigration renames the fields of the events to the configured ECS
+	// version, if set.
+	ecsMigration *ecs.Migration
+
+	// validation checks the events against the fields of the Beat, if set.
+	validation *processorFn
+
 	// global pipeline processors
 	processors *group
 
@@ -102,7 +108,9 @@ func MakeDefaultSupport(
 			common.EventMetadata `config:",inline"`      // Fields and tags to add to each event.
 			Processors           processors.PluginConfig `config:"processors"`
 			TimeSeries           bool                    `config:"timeseries.enabled"`
-		}{}
+			ECS                  ecs.Config              `config:"ecs"`
+			Validation           validationConfig        `config:"validation"`
+		}{Validation: defaultValidationConfig}
 		if err := beatCfg.Unpack(&cfg); err != nil {
 			return nil, err
 		}
@@ -112,10 +120,54 @@ func MakeDefaultSupport(
 			return nil, fmt.Errorf("error initializing processors: %v", err)
 		}
 
-		return newBuilder(info, log, processors, cfg.EventMetadata, modifiers, !normalize, cfg.TimeSeries)
+		b, err := newBuilder(info, log, processors, cfg.EventMetadata, modifiers, !normalize, cfg.TimeSeries)
+		if err != nil {
+			return nil, err
+		}
+
+		if cfg.ECS.Enabled() {
+			migration, err := newECSMigration(info, log, cfg.ECS.Version)
+			if err != nil {
+				return nil, err
+			}
+			if cfg.ECS.Migration.Rename {
+				b.ecsMigration = migration
+			}
+		}
+
+		if cfg.Validation.Enabled {
+			validator, err := newValidator(info, cfg.Validation)
+			if err != nil {
+				return nil, fmt.Errorf("error loading the fields for validation: %v", err)
+			}
+			b.validation = newValidationProcessor(validator, cfg.Validation)
+		}
+		return b, nil
 	}
 }
 
+// newECSMigration detects the fields of the Beat changed in the target ECS
+// version, and reports them.
+func newECSMigration(info beat.Info, log *logp.Logger, target string) (*ecs.Migration, error) {
+	rawFields, err := asset.GetFields(info.Beat)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := mapping.LoadFields(rawFields)
+	if err != nil {
+		return nil, err
+	}
+
+	migration, err := ecs.NewMigration(target, fields)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range migration.Changes {
+		log.Warnf("Migrating from ECS %v to %v: %v", migration.From, migration.To, c)
+	}
+	return migration, nil
+}
+
 // WithFields creates a modifier with the given default builtin fields.
 func WithFields(fields common.MapStr) modifier {
 	return builtinModifier(func(_ beat.Info) common.MapStr {
@@ -242,7 +294,7 @@ func newBuilder(
 // in order to build the event processing pipeline.
 //
 // Processing order (C=client, P=pipeline)
-//  1. (P) generalize/normalize event
+//  1. (P) generalize/normalize event, unless skipped by the client
 //  2. (C) add Meta from client Config to event.Meta
 //  3. (C) add Fields from client config to event.Fields
 //  4. (P) add pipeline fields + tags
@@ -251,6 +303,8 @@ func newBuilder(
 //  7. (P) add builtins
 //  8. (P) pipeline processors list
 //  9. (P) timeseries mangling
+//     9.1 (P) ECS field renaming
+//     9.2 (P) validation against the fields of the Beat
 //  10. (P) (if publish/debug enabled) log event
 //  11. (P) (if output disabled) dropEvent
 func (b *builder) Create(cfg beat.ProcessingConfig, drop bool) (beat.Processor, error) {
@@ -263,7 +317,7 @@ func (b *builder) Create(cfg beat.ProcessingConfig, drop bool) (beat.Processor,
 		localProcessors = makeClientProcessors(b.log, cfg)
 	)
 
-	needsCopy := b.alwaysCopy || localProcessors != nil || b.processors != nil
+	needsCopy := b.alwaysCopy || localProcessors != nil || b.processors != nil || b.ecsMigration != nil || b.validation != nil
 
 	builtin := b.builtinMeta
 	if cfg.DisableHost {
@@ -289,8 +343,13 @@ func (b *builder) Create(cfg beat.ProcessingConfig, drop bool) (beat.Processor,
 	}
 
 	if !b.skipNormalize {
-		// setup 1: generalize/normalize output (P)
-		processors.add(newGeneralizeProcessor(cfg.KeepNull))
+		if cfg.SkipNormalization {
+			// setup 1: events are already normalized by the client (C)
+			processors.add(newCheckNormalizedProcessor(cfg.KeepNull, b.log.IsDebug()))
+		} else {
+			// setup 1: generalize/normalize output (P)
+			processors.add(newGeneralizeProcessor(cfg.KeepNull))
+		}
 	}
 
 	// setup 2: add Meta from client config (C)
@@ -348,6 +407,16 @@ func (b *builder) Create(cfg beat.ProcessingConfig, drop bool) (beat.Processor,
 		processors.add(timeseries.NewTimeSeriesProcessor(b.timeseriesFields))
 	}
 
+	// setup 9.1: rename fields to the configured ECS version
+	if m := b.ecsMigration; m != nil {
+		processors.add(newAnnotateProcessor("ecsMigration", m.Rename))
+	}
+
+	// setup 9.2: validate the events against the fields of the Beat
+	if b.validation != nil {
+		processors.add(b.validation)
+	}
+
 	// setup 10: debug print final event (P)
 	if b.log.IsDebug() {
 		processors.add(debugPrintProcessor(b.info, b.log))
@@ -26,12 +26,14 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/elastic/beats/v7/libbeat/asset"
 	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/beat/events"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/ecs"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/processors"
 	"github.com/elastic/beats/v7/libbeat/processors/actions"
-	"github.com/elastic/ecs/code/go/ecs"
 )
 
 func TestProcessorsConfigs(t *testing.T) {
@@ -325,6 +327,63 @@ func TestNormalization(t *testing.T) {
 	}
 }
 
+func TestSkipNormalization(t *testing.T) {
+	s, err := MakeDefaultSupport(true)(beat.Info{}, logp.L(), common.NewConfig())
+	require.NoError(t, err)
+	defer s.Close()
+
+	prog, err := s.Create(beat.ProcessingConfig{SkipNormalization: true}, false)
+	require.NoError(t, err)
+
+	fields := common.MapStr{"a": "b"}
+	actual, err := prog.Run(&beat.Event{Fields: fields})
+	require.NoError(t, err)
+	require.NotNil(t, actual)
+
+	// Fields are shared with the event of the client as they are not copied.
+	fields["change"] = "x"
+	assert.Equal(t, common.MapStr{"a": "b", "change": "x"}, actual.Fields)
+
+	actual, err = prog.Run(&beat.Event{})
+	require.NoError(t, err)
+	assert.Nil(t, actual)
+}
+
+func TestCheckNormalized(t *testing.T) {
+	value := "x"
+	cases := map[string]struct {
+		validate bool
+		in       common.MapStr
+		want     common.MapStr
+	}{
+		"normalized event": {
+			validate: true,
+			in:       common.MapStr{"a": "b"},
+			want:     common.MapStr{"a": "b"},
+		},
+		"normalize on validation": {
+			validate: true,
+			in:       common.MapStr{"a": &value},
+			want:     common.MapStr{"a": "x"},
+		},
+		"no validation": {
+			validate: false,
+			in:       common.MapStr{"a": &value},
+			want:     common.MapStr{"a": &value},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := newCheckNormalizedProcessor(false, test.validate)
+			actual, err := p.Run(&beat.Event{Fields: test.in})
+			require.NoError(t, err)
+			require.NotNil(t, actual)
+			assert.Equal(t, test.want, actual.Fields)
+		})
+	}
+}
+
 func TestAlwaysDrop(t *testing.T) {
 	s, err := MakeDefaultSupport(true)(beat.Info{}, logp.L(), common.NewConfig())
 	require.NoError(t, err)
@@ -363,6 +422,134 @@ func TestDynamicFields(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestECSMigrationRename(t *testing.T) {
+	fields, err := asset.EncodeData(`
+- key: ecs
+  title: ECS
+  fields:
+    - name: log.original
+      type: keyword
+`)
+	require.NoError(t, err)
+	asset.SetFields("ecsmigrationtest", "fields.yml", asset.LibbeatFieldsPri, func() string { return fields })
+
+	info := beat.Info{Beat: "ecsmigrationtest"}
+	factory, err := MakeDefaultSupport(true, WithECS)(info, logp.L(), common.MustNewConfigFrom(map[string]interface{}{
+		"ecs.version":          "8.0.0",
+		"ecs.migration.rename": true,
+	}))
+	require.NoError(t, err)
+
+	prog, err := factory.Create(beat.ProcessingConfig{}, false)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		actual, err := prog.Run(&beat.Event{Fields: common.MapStr{"log": common.MapStr{"original": "raw"}}})
+		require.NoError(t, err)
+		assert.Equal(t, common.MapStr{
+			"ecs":   common.MapStr{"version": "8.0.0"},
+			"event": common.MapStr{"original": "raw"},
+		}, actual.Fields)
+	}
+
+	// The builtin fields shared by all events are not modified.
+	version, _ := factory.(*builder).builtinMeta.GetValue("ecs.version")
+	assert.Equal(t, ecs.Version, version)
+}
+
+func TestValidation(t *testing.T) {
+	fields, err := asset.EncodeData(`
+- key: test
+  title: Test
+  fields:
+    - name: tags
+      type: keyword
+    - name: status
+      type: long
+    - name: labels
+      type: object
+      object_type: keyword
+`)
+	require.NoError(t, err)
+	asset.SetFields("validationtest", "fields.yml", asset.LibbeatFieldsPri, func() string { return fields })
+	info := beat.Info{Beat: "validationtest"}
+
+	cases := map[string]struct {
+		config   map[string]interface{}
+		in       common.MapStr
+		expected common.MapStr
+		index    string
+	}{
+		"valid event": {
+			config:   map[string]interface{}{"validation.enabled": true},
+			in:       common.MapStr{"status": 200, "labels": common.MapStr{"env": "prod"}},
+			expected: common.MapStr{"status": 200, "labels": common.MapStr{"env": "prod"}},
+		},
+		"tag": {
+			config: map[string]interface{}{"validation.enabled": true},
+			in:     common.MapStr{"status": "ok", "unknown": 1},
+			expected: common.MapStr{
+				"status":  "ok",
+				"unknown": 1,
+				"tags":    []string{"_field_violation"},
+			},
+		},
+		"allowed prefixes": {
+			config: map[string]interface{}{
+				"validation.enabled":          true,
+				"validation.allowed_prefixes": []string{"custom"},
+			},
+			in:       common.MapStr{"custom": common.MapStr{"a": 1}},
+			expected: common.MapStr{"custom": common.MapStr{"a": 1}},
+		},
+		"dead letter": {
+			config: map[string]interface{}{
+				"validation.enabled":           true,
+				"validation.action":            "dead_letter",
+				"validation.dead_letter_index": "dead-letter",
+			},
+			in: common.MapStr{"status": "ok"},
+			expected: common.MapStr{
+				"message": `{"status":"ok"}`,
+				"error": common.MapStr{
+					"type":    "field_violation",
+					"message": "status: string value ok doesn't match type long",
+				},
+			},
+			index: "dead-letter",
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			factory, err := MakeDefaultSupport(true)(info, logp.L(), common.MustNewConfigFrom(test.config))
+			require.NoError(t, err)
+
+			prog, err := factory.Create(beat.ProcessingConfig{}, false)
+			require.NoError(t, err)
+
+			actual, err := prog.Run(&beat.Event{Fields: test.in})
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, actual.Fields)
+
+			index, _ := actual.GetValue("@metadata." + events.FieldMetaRawIndex)
+			if test.index == "" {
+				assert.Nil(t, index)
+			} else {
+				assert.Equal(t, test.index, index)
+			}
+		})
+	}
+}
+
+func TestValidationConfig(t *testing.T) {
+	_, err := MakeDefaultSupport(true)(beat.Info{}, logp.L(), common.MustNewConfigFrom(map[string]interface{}{
+		"validation.enabled": true,
+		"validation.action":  "dead_letter",
+	}))
+	assert.Error(t, err)
+}
+
 func TestProcessingClose(t *testing.T) {
 	factory, err := MakeDefaultSupport(true)(beat.Info{}, logp.L(), common.NewConfig())
 	require.NoError(t, err)
@@ -28,6 +28,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/privacy"
 	"github.com/elastic/beats/v7/libbeat/processors"
 )
 
@@ -62,6 +63,34 @@ func newGeneralizeProcessor(keepNull bool) *processorFn {
 	})
 }
 
+// newCheckNormalizedProcessor replaces the generalize processor for clients
+// publishing events that don't need to be normalized. Empty events are dropped.
+// If validate is set, events holding values that need to be normalized are
+// reported and normalized.
+func newCheckNormalizedProcessor(keepNull, validate bool) *processorFn {
+	logger := logp.NewLogger("publisher_processing")
+	g := common.NewGenericEventConverter(keepNull)
+	return newProcessor("checkNormalized", func(event *beat.Event) (*beat.Event, error) {
+		if len(event.Fields) == 0 {
+			return nil, nil
+		}
+		if !validate {
+			return event, nil
+		}
+
+		if err := common.CheckGenericEvent(event.Fields, keepNull); err != nil {
+			logger.Errorf("Event of a client skipping normalization needs to be normalized: %v", err)
+			fields := g.Convert(event.Fields)
+			if fields == nil {
+				logger.Error("fail to convert to generic event")
+				return nil, nil
+			}
+			event.Fields = fields
+		}
+		return event, nil
+	})
+}
+
 var dropDisabledProcessor = newProcessor("dropDisabled", func(event *beat.Event) (*beat.Event, error) {
 	return nil, nil
 })
@@ -195,7 +224,7 @@ func debugPrintProcessor(info beat.Info, log *logp.Logger) *processorFn {
 		mux.Lock()
 		defer mux.Unlock()
 
-		b, err := encoder.Encode(info.Beat, event)
+		b, err := encoder.Encode(info.Beat, privacy.Event(event))
 		if err != nil {
 			return event, nil
 		}
@@ -0,0 +1,137 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package processing
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/beats/v7/libbeat/asset"
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/beat/events"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/mapping"
+)
+
+const (
+	validationActionTag        = "tag"
+	validationActionDeadLetter = "dead_letter"
+)
+
+// validationConfig configures the validation of the events against the fields
+// of the Beat.
+type validationConfig struct {
+	Enabled bool `config:"enabled"`
+
+	// AllowedPrefixes are the prefixes of the fields accepted without being
+	// defined.
+	AllowedPrefixes []string `config:"allowed_prefixes"`
+
+	// Action is applied to the events with violations, it can be tag or
+	// dead_letter.
+	Action string `config:"action"`
+
+	// Tag is added to the tags of the events with violations.
+	Tag string `config:"tag"`
+
+	// DeadLetterIndex is the index the events with violations are sent to
+	// with the dead_letter action.
+	DeadLetterIndex string `config:"dead_letter_index"`
+}
+
+var defaultValidationConfig = validationConfig{
+	Action: validationActionTag,
+	Tag:    "_field_violation",
+}
+
+// Validate checks the action and its settings.
+func (c *validationConfig) Validate() error {
+	switch c.Action {
+	case validationActionTag:
+		if c.Tag == "" {
+			return fmt.Errorf("validation action %v requires a tag", c.Action)
+		}
+	case validationActionDeadLetter:
+		if c.DeadLetterIndex == "" {
+			return fmt.Errorf("validation action %v requires a dead_letter_index", c.Action)
+		}
+	default:
+		return fmt.Errorf("invalid validation action '%v', expected %v or %v", c.Action, validationActionTag, validationActionDeadLetter)
+	}
+	return nil
+}
+
+// newValidator compiles the fields of the Beat into a validator of events.
+func newValidator(info beat.Info, cfg validationConfig) (*mapping.Validator, error) {
+	rawFields, err := asset.GetFields(info.Beat)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := mapping.LoadFields(rawFields)
+	if err != nil {
+		return nil, err
+	}
+	return mapping.NewValidator(fields, cfg.AllowedPrefixes), nil
+}
+
+// newValidationProcessor validates the events, and applies the configured
+// action to the events with violations.
+func newValidationProcessor(v *mapping.Validator, cfg validationConfig) *processorFn {
+	logger := logp.NewLogger("publisher_processing")
+	return newAnnotateProcessor("validateFields", func(event *beat.Event) {
+		violations := v.Validate(event.Fields)
+		if len(violations) == 0 {
+			return
+		}
+
+		reasons := make([]string, len(violations))
+		for i, violation := range violations {
+			reasons[i] = violation.String()
+		}
+		message := strings.Join(reasons, "; ")
+		if logger.IsDebug() {
+			logger.Debugf("Event doesn't match the fields: %v", message)
+		}
+
+		switch cfg.Action {
+		case validationActionTag:
+			common.AddTags(event.Fields, []string{cfg.Tag})
+		case validationActionDeadLetter:
+			deadLetter(event, cfg.DeadLetterIndex, message)
+		}
+	})
+}
+
+// deadLetter replaces the fields of the event by its JSON encoding, so it can
+// be indexed whatever its mapping, and sends it to the dead letter index.
+func deadLetter(event *beat.Event, index, message string) {
+	original, err := json.Marshal(event.Fields)
+	if err != nil {
+		original = []byte(fmt.Sprintf("%v", event.Fields))
+	}
+	event.Fields = common.MapStr{
+		"message": string(original),
+		"error": common.MapStr{
+			"type":    "field_violation",
+			"message": message,
+		},
+	}
+	event.PutValue("@metadata."+events.FieldMetaRawIndex, index)
+}
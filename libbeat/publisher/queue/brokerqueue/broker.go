@@ -0,0 +1,86 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package brokerqueue implements queues storing their events in an external
+// broker, for deployments requiring durability guarantees that neither the
+// memory queue nor the disk queue provide. A broker only stores opaque
+// messages, the queue encodes the events and implements the producers and
+// consumers of the pipeline on top of it.
+//
+// Third parties register their brokers with Register, the queue is then
+// configured under queue.<name> in the Beat configuration.
+package brokerqueue
+
+import (
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/feature"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+)
+
+// Message is a message stored in a broker.
+type Message struct {
+	// ID identifies the message in the broker, for acknowledging it.
+	ID string
+
+	Data []byte
+}
+
+// Broker stores the messages of a queue. Its methods are called
+// concurrently.
+type Broker interface {
+	// Push stores the messages, it returns once they are stored.
+	Push(messages [][]byte) error
+
+	// Fetch returns up to n messages that have not been fetched yet. The
+	// messages that were fetched but not acknowledged before the broker was
+	// closed are returned first. Fetch waits up to timeout for messages, and
+	// returns no messages if none are available.
+	Fetch(n int, timeout time.Duration) ([]Message, error)
+
+	// Ack removes the messages once their events have been published.
+	Ack(ids []string) error
+
+	Close() error
+}
+
+// Factory creates the broker of a queue, configured by the settings of the
+// queue.
+type Factory func(log *logp.Logger, cfg *common.Config) (Broker, error)
+
+// Register registers a queue type storing its events in the brokers created
+// by factory.
+func Register(name string, factory Factory, details feature.Details) {
+	queue.RegisterQueueType(name, makeQueueFactory(name, factory), details)
+}
+
+func makeQueueFactory(name string, factory Factory) queue.Factory {
+	return func(ackListener queue.ACKListener, log *logp.Logger, cfg *common.Config) (queue.Queue, error) {
+		if log == nil {
+			log = logp.L()
+		}
+		log = log.Named(name + "queue")
+
+		broker, err := factory(log, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewQueue(log, broker, ackListener), nil
+	}
+}
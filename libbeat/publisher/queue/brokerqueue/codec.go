@@ -0,0 +1,69 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package brokerqueue
+
+import (
+	"errors"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// eventCodec encodes the events as a byte with the publisher.EventFlags of
+// the event, followed by the event as written by the JSON codec.
+type eventCodec struct {
+	encoder *json.Encoder
+}
+
+func newEventCodec() *eventCodec {
+	return &eventCodec{
+		encoder: json.New("", json.Config{
+			// Keep the full precision for outputs encoding the timestamps
+			// with a precision finer than milliseconds.
+			TimestampPrecision: common.TimestampNanosecond,
+		}),
+	}
+}
+
+func (c *eventCodec) encode(event *publisher.Event) ([]byte, error) {
+	content, err := c.encoder.Encode("", &event.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	// The encoder reuses its buffer, copy the event.
+	data := make([]byte, len(content)+1)
+	data[0] = byte(event.Flags &^ publisher.RecycleFields)
+	copy(data[1:], content)
+	return data, nil
+}
+
+func decodeEvent(data []byte) (publisher.Event, error) {
+	if len(data) < 2 {
+		return publisher.Event{}, errors.New("message too short")
+	}
+	content, err := json.Decode(data[1:])
+	if err != nil {
+		return publisher.Event{}, err
+	}
+	return publisher.Event{
+		Content: content,
+		Flags:   publisher.EventFlags(data[0]),
+	}, nil
+}
@@ -0,0 +1,116 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package brokerqueue
+
+import (
+	"io"
+
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+)
+
+type brokerConsumer struct {
+	queue  *brokerQueue
+	closed atomic.Bool
+	done   chan struct{}
+}
+
+type brokerBatch struct {
+	queue  *brokerQueue
+	events []publisher.Event
+	ids    []string
+}
+
+func newConsumer(q *brokerQueue) *brokerConsumer {
+	return &brokerConsumer{queue: q, done: make(chan struct{})}
+}
+
+// Get waits for messages from the broker. The messages whose events can't be
+// decoded are removed from the broker, as they can never be published.
+func (c *brokerConsumer) Get(eventCount int) (queue.Batch, error) {
+	if eventCount <= 0 {
+		eventCount = defaultBatchSize
+	}
+
+	wait := retryInit
+	for {
+		if c.closed.Load() {
+			return nil, io.EOF
+		}
+		select {
+		case <-c.queue.done:
+			return nil, io.EOF
+		default:
+		}
+
+		messages, err := c.queue.broker.Fetch(eventCount, fetchTimeout)
+		if err != nil {
+			c.queue.log.Errorf("Failed to fetch events from the broker: %v", err)
+			if !waitRetry(&wait, c.queue.done, c.done) {
+				return nil, io.EOF
+			}
+			continue
+		}
+		wait = retryInit
+
+		batch := c.decode(messages)
+		if len(batch.events) > 0 {
+			return batch, nil
+		}
+	}
+}
+
+func (c *brokerConsumer) decode(messages []Message) *brokerBatch {
+	batch := &brokerBatch{
+		queue:  c.queue,
+		events: make([]publisher.Event, 0, len(messages)),
+		ids:    make([]string, 0, len(messages)),
+	}
+
+	var invalid []string
+	for _, msg := range messages {
+		event, err := decodeEvent(msg.Data)
+		if err != nil {
+			c.queue.log.Errorf("Dropping the event %v that could not be decoded: %v", msg.ID, err)
+			invalid = append(invalid, msg.ID)
+			continue
+		}
+		batch.events = append(batch.events, event)
+		batch.ids = append(batch.ids, msg.ID)
+	}
+	c.queue.ack(invalid)
+	return batch
+}
+
+func (c *brokerConsumer) Close() error {
+	if c.closed.Swap(true) {
+		return io.EOF
+	}
+	close(c.done)
+	return nil
+}
+
+func (b *brokerBatch) Events() []publisher.Event {
+	return b.events
+}
+
+// ACK removes the events of the batch from the broker.
+func (b *brokerBatch) ACK() {
+	b.queue.ack(b.ids)
+}
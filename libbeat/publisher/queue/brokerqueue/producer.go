@@ -0,0 +1,95 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package brokerqueue
+
+import (
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+)
+
+type brokerProducer struct {
+	queue  *brokerQueue
+	config queue.ProducerConfig
+	codec  *eventCodec
+
+	cancelled atomic.Bool
+	done      chan struct{}
+}
+
+func newProducer(q *brokerQueue, cfg queue.ProducerConfig) *brokerProducer {
+	return &brokerProducer{
+		queue:  q,
+		config: cfg,
+		codec:  newEventCodec(),
+		done:   make(chan struct{}),
+	}
+}
+
+func (p *brokerProducer) Publish(event publisher.Event) bool {
+	return p.publish(event, true)
+}
+
+// TryPublish tries to store the event once, without retrying if the broker
+// fails.
+func (p *brokerProducer) TryPublish(event publisher.Event) bool {
+	return p.publish(event, false)
+}
+
+func (p *brokerProducer) publish(event publisher.Event, retry bool) bool {
+	if p.cancelled.Load() {
+		return false
+	}
+	data, err := p.codec.encode(&event)
+	if err != nil {
+		p.queue.log.Errorf("Couldn't serialize incoming event: %v", err)
+		return false
+	}
+
+	wait := retryInit
+	for {
+		err := p.queue.broker.Push([][]byte{data})
+		if err == nil {
+			break
+		}
+		p.queue.log.Errorf("Failed to store the event in the broker: %v", err)
+		if !retry || !waitRetry(&wait, p.queue.done, p.done) {
+			return false
+		}
+	}
+	event.Release()
+
+	// The event is stored, the broker guarantees its delivery from now on.
+	if p.config.ACK != nil {
+		p.config.ACK(1)
+	}
+	if p.queue.ackListener != nil {
+		p.queue.ackListener.OnACK(1)
+	}
+	return true
+}
+
+// Cancel stops the retries of the pending publish calls. No events are
+// dropped, as the producer does not buffer them.
+func (p *brokerProducer) Cancel() int {
+	if p.cancelled.Swap(true) {
+		return 0
+	}
+	close(p.done)
+	return 0
+}
@@ -0,0 +1,153 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package brokerqueue
+
+import (
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+)
+
+const (
+	// fetchTimeout limits how long the consumers wait for messages, so they
+	// notice when they are closed.
+	fetchTimeout = time.Second
+
+	// defaultBatchSize is the number of messages fetched for the consumers
+	// not limiting the size of their batches.
+	defaultBatchSize = 2048
+
+	// The failed broker operations are retried with an exponential backoff.
+	retryInit = time.Second
+	retryMax  = time.Minute
+)
+
+type brokerQueue struct {
+	log         *logp.Logger
+	broker      Broker
+	ackListener queue.ACKListener
+
+	// acks are the IDs of the messages of the ACKed batches, removed from
+	// the broker by the ack loop.
+	acks chan []string
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewQueue creates a queue storing its events in broker. The events are
+// ACKed to the producers once they are stored in the broker, and removed
+// from the broker once the consumers ACK them. The queue closes the broker
+// when it's closed.
+func NewQueue(log *logp.Logger, broker Broker, ackListener queue.ACKListener) queue.Queue {
+	q := &brokerQueue{
+		log:         log,
+		broker:      broker,
+		ackListener: ackListener,
+		acks:        make(chan []string, 16),
+		done:        make(chan struct{}),
+	}
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		q.ackLoop()
+	}()
+	return q
+}
+
+// Close stops the queue and closes the broker. The events not ACKed yet are
+// published again by the next queue using the broker.
+func (q *brokerQueue) Close() error {
+	var err error
+	q.closeOnce.Do(func() {
+		close(q.done)
+		q.wg.Wait()
+		err = q.broker.Close()
+	})
+	return err
+}
+
+// BufferConfig reports no limit, the capacity of the queue is the one of
+// the broker.
+func (q *brokerQueue) BufferConfig() queue.BufferConfig {
+	return queue.BufferConfig{}
+}
+
+func (q *brokerQueue) Producer(cfg queue.ProducerConfig) queue.Producer {
+	return newProducer(q, cfg)
+}
+
+func (q *brokerQueue) Consumer() queue.Consumer {
+	return newConsumer(q)
+}
+
+func (q *brokerQueue) ackLoop() {
+	for {
+		select {
+		case <-q.done:
+			return
+		case ids := <-q.acks:
+			wait := retryInit
+			for {
+				err := q.broker.Ack(ids)
+				if err == nil {
+					break
+				}
+				q.log.Errorf("Failed to remove %d published events from the broker: %v", len(ids), err)
+				if !waitRetry(&wait, q.done, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// ack removes the messages from the broker, asynchronously.
+func (q *brokerQueue) ack(ids []string) {
+	if len(ids) == 0 {
+		return
+	}
+	select {
+	case q.acks <- ids:
+	case <-q.done:
+	}
+}
+
+// waitRetry waits for the retry interval, doubling it for the next retry.
+// It returns false if one of the channels is closed first.
+func waitRetry(wait *time.Duration, done, cancel <-chan struct{}) bool {
+	timer := time.NewTimer(*wait)
+	defer timer.Stop()
+
+	*wait *= 2
+	if *wait > retryMax {
+		*wait = retryMax
+	}
+
+	select {
+	case <-timer.C:
+		return true
+	case <-done:
+		return false
+	case <-cancel:
+		return false
+	}
+}
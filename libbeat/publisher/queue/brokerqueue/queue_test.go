@@ -0,0 +1,266 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package brokerqueue
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/queuetest"
+)
+
+// memoryBroker stores the messages in memory, for testing the queue.
+type memoryBroker struct {
+	mu      sync.Mutex
+	notify  chan struct{}
+	nextID  int
+	ready   []Message
+	pending map[string]Message
+
+	// failures is the number of calls to fail.
+	failures int
+}
+
+func newMemoryBroker() *memoryBroker {
+	return &memoryBroker{
+		notify:  make(chan struct{}, 1),
+		pending: map[string]Message{},
+	}
+}
+
+func (b *memoryBroker) fail() error {
+	if b.failures > 0 {
+		b.failures--
+		return errors.New("broker unavailable")
+	}
+	return nil
+}
+
+func (b *memoryBroker) Push(messages [][]byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.fail(); err != nil {
+		return err
+	}
+	for _, data := range messages {
+		b.nextID++
+		b.ready = append(b.ready, Message{ID: strconv.Itoa(b.nextID), Data: data})
+	}
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (b *memoryBroker) Fetch(n int, timeout time.Duration) ([]Message, error) {
+	// Don't block the tests waiting for the consumers to be closed.
+	if timeout > 50*time.Millisecond {
+		timeout = 50 * time.Millisecond
+	}
+	deadline := time.After(timeout)
+	for {
+		b.mu.Lock()
+		if err := b.fail(); err != nil {
+			b.mu.Unlock()
+			return nil, err
+		}
+		if len(b.ready) > 0 {
+			if n > len(b.ready) {
+				n = len(b.ready)
+			}
+			messages := b.ready[:n:n]
+			b.ready = b.ready[n:]
+			for _, msg := range messages {
+				b.pending[msg.ID] = msg
+			}
+			b.mu.Unlock()
+			return messages, nil
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-b.notify:
+		case <-deadline:
+			return nil, nil
+		}
+	}
+}
+
+func (b *memoryBroker) Ack(ids []string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, id := range ids {
+		delete(b.pending, id)
+	}
+	return nil
+}
+
+// Close makes the unacknowledged messages available again, as a broker
+// would do when a new queue connects to it.
+func (b *memoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var pending []Message
+	for _, msg := range b.pending {
+		pending = append(pending, msg)
+	}
+	b.ready = append(pending, b.ready...)
+	b.pending = map[string]Message{}
+	return nil
+}
+
+func (b *memoryBroker) size() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.ready) + len(b.pending)
+}
+
+type countingACKListener struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (l *countingACKListener) OnACK(n int) {
+	l.mu.Lock()
+	l.count += n
+	l.mu.Unlock()
+}
+
+func testEvent(n int) publisher.Event {
+	return publisher.Event{
+		Content: beat.Event{
+			Timestamp: time.Unix(1600000000, 123456789).UTC(),
+			Meta:      common.MapStr{"pipeline": "test"},
+			Fields:    common.MapStr{"n": n},
+		},
+		Flags: publisher.GuaranteedSend,
+	}
+}
+
+func TestProduceConsumer(t *testing.T) {
+	factory := func(_ *testing.T) queue.Queue {
+		return NewQueue(logp.NewLogger("test"), newMemoryBroker(), nil)
+	}
+	t.Run("single", func(t *testing.T) {
+		queuetest.TestSingleProducerConsumer(t, 200, 16, factory)
+	})
+	t.Run("multi", func(t *testing.T) {
+		queuetest.TestMultiProducerConsumer(t, 200, 16, factory)
+	})
+}
+
+func TestEncoding(t *testing.T) {
+	codec := newEventCodec()
+	event := testEvent(1)
+	data, err := codec.encode(&event)
+	require.NoError(t, err)
+
+	decoded, err := decodeEvent(data)
+	require.NoError(t, err)
+	assert.Equal(t, event.Flags, decoded.Flags)
+	assert.True(t, event.Content.Timestamp.Equal(decoded.Content.Timestamp))
+	assert.Equal(t, common.MapStr{"pipeline": "test"}, decoded.Content.Meta)
+	assert.EqualValues(t, 1, decoded.Content.Fields["n"])
+
+	_, err = decodeEvent([]byte{0})
+	assert.Error(t, err)
+}
+
+func TestACK(t *testing.T) {
+	broker := newMemoryBroker()
+	listener := &countingACKListener{}
+	q := NewQueue(logp.NewLogger("test"), broker, listener)
+	defer q.Close()
+
+	var producerACKs int
+	producer := q.Producer(queue.ProducerConfig{ACK: func(n int) { producerACKs += n }})
+	for i := 0; i < 3; i++ {
+		require.True(t, producer.Publish(testEvent(i)))
+	}
+	assert.Equal(t, 3, producerACKs)
+	assert.Equal(t, 3, listener.count)
+
+	// Undecodable messages are removed from the broker.
+	require.NoError(t, broker.Push([][]byte{[]byte("invalid")}))
+
+	consumer := q.Consumer()
+	batch, err := consumer.Get(10)
+	require.NoError(t, err)
+	require.Len(t, batch.Events(), 3)
+	for i, event := range batch.Events() {
+		assert.EqualValues(t, i, event.Content.Fields["n"])
+	}
+
+	require.Eventually(t, func() bool { return broker.size() == 3 }, time.Second, 10*time.Millisecond)
+	batch.ACK()
+	require.Eventually(t, func() bool { return broker.size() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestRedeliveryAfterClose(t *testing.T) {
+	broker := newMemoryBroker()
+	q := NewQueue(logp.NewLogger("test"), broker, nil)
+
+	producer := q.Producer(queue.ProducerConfig{})
+	for i := 0; i < 2; i++ {
+		require.True(t, producer.Publish(testEvent(i)))
+	}
+	batch, err := q.Consumer().Get(10)
+	require.NoError(t, err)
+	require.Len(t, batch.Events(), 2)
+	require.NoError(t, q.Close())
+
+	// The events were not ACKed, the next queue gets them again.
+	q = NewQueue(logp.NewLogger("test"), broker, nil)
+	defer q.Close()
+	batch, err = q.Consumer().Get(10)
+	require.NoError(t, err)
+	assert.Len(t, batch.Events(), 2)
+}
+
+func TestPublishRetries(t *testing.T) {
+	broker := newMemoryBroker()
+	q := NewQueue(logp.NewLogger("test"), broker, nil)
+	defer q.Close()
+	producer := q.Producer(queue.ProducerConfig{})
+
+	broker.failures = 1
+	assert.False(t, producer.TryPublish(testEvent(0)))
+	assert.Equal(t, 0, broker.size())
+
+	broker.failures = 1
+	assert.True(t, producer.Publish(testEvent(1)))
+	assert.Equal(t, 1, broker.size())
+
+	broker.failures = 1000
+	published := make(chan bool)
+	go func() { published <- producer.Publish(testEvent(2)) }()
+	producer.Cancel()
+	assert.False(t, <-published)
+}
@@ -0,0 +1,194 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package diskqueue
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+)
+
+// Compression is the compression of the data frames of a segment. It's
+// stored in the segment header, so segments written with a different
+// compression, or without compression, can still be read.
+type Compression uint32
+
+const (
+	// CompressionNone stores the serialized events as they are, in segments
+	// readable by previous versions of the queue.
+	CompressionNone Compression = iota
+
+	// CompressionLZ4 compresses each data frame with LZ4, which is fast but
+	// compresses less than zstd.
+	CompressionLZ4
+
+	// CompressionZstd compresses each data frame with zstd.
+	CompressionZstd
+)
+
+var compressionNames = map[Compression]string{
+	CompressionNone: "none",
+	CompressionLZ4:  "lz4",
+	CompressionZstd: "zstd",
+}
+
+func (c Compression) String() string {
+	if name, ok := compressionNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("compression(%d)", uint32(c))
+}
+
+// Unpack parses the name of a compression in the queue config.
+func (c *Compression) Unpack(name string) error {
+	for compression, n := range compressionNames {
+		if strings.EqualFold(name, n) {
+			*c = compression
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown disk queue compression '%v'", name)
+}
+
+// The data of a compressed frame starts with the 32-bit length of the
+// uncompressed data. If the compressed data would not be smaller, the data
+// is stored uncompressed after the length, so its length is the stored one.
+const compressedLengthSize = 4
+
+// frameCompressor compresses and decompresses the data of the frames. It's
+// shared by the producers and the reader loop of a queue, the codecs are
+// created when first used.
+type frameCompressor struct {
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+}
+
+// The LZ4 hash tables are large, they are reused between the frames of all
+// producers.
+var lz4HashTables = sync.Pool{
+	New: func() interface{} { return make([]int, 1<<16) },
+}
+
+func (fc *frameCompressor) initZstd() error {
+	fc.zstdOnce.Do(func() {
+		fc.zstdEncoder, fc.zstdErr = zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if fc.zstdErr != nil {
+			return
+		}
+		fc.zstdDecoder, fc.zstdErr = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+	})
+	return fc.zstdErr
+}
+
+// compress returns the data of a frame for the given compression.
+func (fc *frameCompressor) compress(c Compression, data []byte) ([]byte, error) {
+	if c == CompressionNone {
+		return data, nil
+	}
+
+	var out []byte
+	switch c {
+	case CompressionLZ4:
+		out = make([]byte, compressedLengthSize+lz4.CompressBlockBound(len(data)))
+		hashTable := lz4HashTables.Get().([]int)
+		n, err := lz4.CompressBlock(data, out[compressedLengthSize:], hashTable)
+		lz4HashTables.Put(hashTable)
+		if err != nil {
+			return nil, err
+		}
+		out = out[:compressedLengthSize+n]
+		if n == 0 {
+			// incompressible
+			out = out[:compressedLengthSize]
+		}
+
+	case CompressionZstd:
+		if err := fc.initZstd(); err != nil {
+			return nil, err
+		}
+		out = fc.zstdEncoder.EncodeAll(data, make([]byte, compressedLengthSize, compressedLengthSize+len(data)))
+
+	default:
+		return nil, fmt.Errorf("unknown compression %v", c)
+	}
+
+	if n := len(out) - compressedLengthSize; n == 0 || n >= len(data) {
+		out = append(out[:compressedLengthSize], data...)
+	}
+	binary.LittleEndian.PutUint32(out, uint32(len(data)))
+	return out, nil
+}
+
+// decompress decompresses the data of a frame into the buffer returned by
+// buffer for the length of the uncompressed data.
+func (fc *frameCompressor) decompress(
+	c Compression, data []byte, buffer func(n int) []byte,
+) ([]byte, error) {
+	if c == CompressionNone {
+		out := buffer(len(data))
+		copy(out, data)
+		return out, nil
+	}
+
+	if len(data) < compressedLengthSize {
+		return nil, fmt.Errorf("compressed data frame too short (%d bytes)", len(data))
+	}
+	length := int(binary.LittleEndian.Uint32(data))
+	data = data[compressedLengthSize:]
+	out := buffer(length)
+	if len(data) == length {
+		copy(out, data)
+		return out, nil
+	}
+
+	switch c {
+	case CompressionLZ4:
+		n, err := lz4.UncompressBlock(data, out)
+		if err != nil {
+			return nil, err
+		}
+		if n != length {
+			return nil, fmt.Errorf("decompressed %d bytes instead of %d", n, length)
+		}
+
+	case CompressionZstd:
+		if err := fc.initZstd(); err != nil {
+			return nil, err
+		}
+		decoded, err := fc.zstdDecoder.DecodeAll(data, out[:0])
+		if err != nil {
+			return nil, err
+		}
+		if len(decoded) != length {
+			return nil, fmt.Errorf("decompressed %d bytes instead of %d", len(decoded), length)
+		}
+		if length > 0 && &decoded[0] != &out[0] {
+			copy(out, decoded)
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown compression %v", c)
+	}
+	return out, nil
+}
@@ -0,0 +1,272 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package diskqueue
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+)
+
+var compressions = []Compression{CompressionNone, CompressionLZ4, CompressionZstd}
+
+func TestCompressionConfig(t *testing.T) {
+	settings, err := SettingsForUserConfig(common.MustNewConfigFrom(map[string]interface{}{
+		"max_size":    "1GB",
+		"compression": "zstd",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, CompressionZstd, settings.Compression)
+
+	settings, err = SettingsForUserConfig(common.MustNewConfigFrom(map[string]interface{}{
+		"max_size": "1GB",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, CompressionNone, settings.Compression)
+
+	_, err = SettingsForUserConfig(common.MustNewConfigFrom(map[string]interface{}{
+		"max_size":    "1GB",
+		"compression": "gzip",
+	}))
+	assert.Error(t, err)
+}
+
+func TestFrameCompressor(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+	inputs := map[string][]byte{
+		"event":          testFrameData(),
+		"short":          []byte(`{"a":1}`),
+		"incompressible": random,
+	}
+
+	fc := &frameCompressor{}
+	var buf []byte
+	buffer := func(n int) []byte {
+		if cap(buf) < n {
+			buf = make([]byte, n)
+		}
+		return buf[:n]
+	}
+	for _, compression := range compressions {
+		for name, data := range inputs {
+			t.Run(fmt.Sprintf("%v/%v", compression, name), func(t *testing.T) {
+				compressed, err := fc.compress(compression, data)
+				require.NoError(t, err)
+				if name == "incompressible" && compression != CompressionNone {
+					assert.Equal(t, compressedLengthSize+len(data), len(compressed))
+				}
+
+				decompressed, err := fc.decompress(compression, compressed, buffer)
+				require.NoError(t, err)
+				assert.Equal(t, data, decompressed)
+			})
+		}
+	}
+
+	compressed, err := fc.compress(CompressionZstd, testFrameData())
+	require.NoError(t, err)
+	assert.Less(t, len(compressed), len(testFrameData()))
+	_, err = fc.decompress(CompressionLZ4, compressed, buffer)
+	assert.Error(t, err)
+}
+
+// TestCompressedSegments writes the events of several sessions of the
+// queue, each with another compression, so the segments of the directory are
+// written with different compressions, then reads them with the queue.
+func TestCompressedSegments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskqueue")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	const count = 10
+	n := 0
+	for _, compression := range compressions {
+		settings := DefaultSettings()
+		settings.Path = dir
+		settings.MaxSegmentSize = 1000
+		settings.Compression = compression
+		q, err := NewQueue(logp.NewLogger("test"), settings)
+		require.NoError(t, err)
+		writeTestEvents(t, q, n, count)
+		require.NoError(t, q.Close())
+		n += count
+	}
+
+	segments, err := scanExistingSegments(dir)
+	require.NoError(t, err)
+	versions := map[uint32]bool{}
+	for _, segment := range segments {
+		versions[segment.schemaVersion] = true
+	}
+	assert.Equal(t, map[uint32]bool{0: true, 1: true}, versions)
+
+	settings := DefaultSettings()
+	settings.Path = dir
+	q, err := NewQueue(logp.NewLogger("test"), settings)
+	require.NoError(t, err)
+	defer q.Close()
+
+	consumer := q.Consumer()
+	for read := 0; read < n; {
+		batch, err := consumer.Get(n - read)
+		require.NoError(t, err)
+		for _, event := range batch.Events() {
+			assert.EqualValues(t, read, event.Content.Fields["n"])
+			assert.Equal(t, "message of the test event", event.Content.Fields["message"])
+			read++
+		}
+		batch.ACK()
+	}
+}
+
+func TestUnknownCompression(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Compression = Compression(42)
+	_, err := NewQueue(logp.NewLogger("test"), settings)
+	assert.Error(t, err)
+}
+
+func writeTestEvents(t *testing.T, q queue.Queue, first, count int) {
+	written := make(chan int, count)
+	producer := q.Producer(queue.ProducerConfig{ACK: func(n int) { written <- n }})
+	for i := first; i < first+count; i++ {
+		ok := producer.Publish(publisher.Event{
+			Content: beat.Event{
+				Timestamp: time.Now(),
+				Fields:    common.MapStr{"message": "message of the test event", "n": i},
+			},
+		})
+		require.True(t, ok)
+	}
+	for total := 0; total < count; {
+		select {
+		case n := <-written:
+			total += n
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for the events to be written")
+		}
+	}
+}
+
+// testFrameData returns the serialized data of a typical log event.
+func testFrameData() []byte {
+	event := publisher.Event{Content: beat.Event{
+		Timestamp: time.Date(2020, 10, 1, 12, 0, 0, 0, time.UTC),
+		Fields: common.MapStr{
+			"message": `127.0.0.1 - - [01/Oct/2020:12:00:00 +0000] "GET /api/v1/items?page=2 HTTP/1.1" 200 5120 "-" "Mozilla/5.0 (X11; Linux x86_64)"`,
+			"log":     common.MapStr{"file": common.MapStr{"path": "/var/log/nginx/access.log"}, "offset": 123456},
+			"host":    common.MapStr{"name": "web-01", "hostname": "web-01"},
+			"agent":   common.MapStr{"type": "filebeat", "version": "7.10.0", "hostname": "web-01"},
+			"ecs":     common.MapStr{"version": "1.6.0"},
+			"input":   common.MapStr{"type": "log"},
+			"fileset": common.MapStr{"name": "access"},
+			"event":   common.MapStr{"module": "nginx", "dataset": "nginx.access"},
+		},
+	}}
+	data, err := newEventEncoder().encode(&event)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// BenchmarkFrameCompression measures the time to compress and decompress
+// the data frame of a log event, and reports the compression ratio.
+func BenchmarkFrameCompression(b *testing.B) {
+	data := testFrameData()
+	for _, compression := range compressions {
+		b.Run(compression.String(), func(b *testing.B) {
+			fc := &frameCompressor{}
+			buf := make([]byte, len(data))
+			buffer := func(n int) []byte { return buf[:n] }
+
+			var compressed []byte
+			b.SetBytes(int64(len(data)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				var err error
+				compressed, err = fc.compress(compression, data)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := fc.decompress(compression, compressed, buffer); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(data))/float64(len(compressed)), "ratio")
+		})
+	}
+}
+
+// BenchmarkQueueWrite measures the throughput of the producers of the queue,
+// until the events are written to disk.
+func BenchmarkQueueWrite(b *testing.B) {
+	event := publisher.Event{Content: beat.Event{
+		Timestamp: time.Now(),
+		Fields: common.MapStr{
+			"message": `127.0.0.1 - - [01/Oct/2020:12:00:00 +0000] "GET /api/v1/items?page=2 HTTP/1.1" 200 5120 "-" "Mozilla/5.0 (X11; Linux x86_64)"`,
+			"host":    common.MapStr{"name": "web-01"},
+		},
+	}}
+	for _, compression := range compressions {
+		b.Run(compression.String(), func(b *testing.B) {
+			dir, err := ioutil.TempDir("", "diskqueue")
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			settings := DefaultSettings()
+			settings.Path = dir
+			settings.MaxBufferSize = 0
+			settings.Compression = compression
+			q, err := NewQueue(logp.NewLogger("bench"), settings)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			written := make(chan int, 1024)
+			producer := q.Producer(queue.ProducerConfig{ACK: func(n int) { written <- n }})
+			b.ResetTimer()
+			go func() {
+				for i := 0; i < b.N; i++ {
+					producer.Publish(event)
+				}
+			}()
+			for total := 0; total < b.N; {
+				total += <-written
+			}
+			b.StopTimer()
+			q.Close()
+		})
+	}
+}
@@ -68,6 +68,18 @@ type Settings struct {
 	// use exponential backoff up to the specified limit.
 	RetryInterval    time.Duration
 	MaxRetryInterval time.Duration
+
+	// Compression is the compression of the data frames of the segments
+	// written by the queue. Existing segments are read with the compression
+	// they were written with.
+	Compression Compression
+
+	// EncryptionKey is the key to encrypt the segments written by the queue,
+	// or empty to write unencrypted segments. PreviousEncryptionKeys are
+	// only used to read the segments written before the key was rotated, or
+	// before encryption was disabled.
+	EncryptionKey          string
+	PreviousEncryptionKeys []string
 }
 
 // userConfig holds the parameters for a disk queue that are configurable
@@ -82,6 +94,13 @@ type userConfig struct {
 
 	RetryInterval    *time.Duration `config:"retry_interval" validate:"positive"`
 	MaxRetryInterval *time.Duration `config:"max_retry_interval" validate:"positive"`
+
+	Compression Compression `config:"compression"`
+
+	Encryption struct {
+		Key          string   `config:"key"`
+		PreviousKeys []string `config:"previous_keys"`
+	} `config:"encryption"`
 }
 
 func (c *userConfig) Validate() error {
@@ -164,6 +183,9 @@ func SettingsForUserConfig(config *common.Config) (Settings, error) {
 	if userConfig.MaxRetryInterval != nil {
 		settings.MaxRetryInterval = *userConfig.RetryInterval
 	}
+	settings.Compression = userConfig.Compression
+	settings.EncryptionKey = userConfig.Encryption.Key
+	settings.PreviousEncryptionKeys = userConfig.Encryption.PreviousKeys
 
 	return settings, nil
 }
@@ -190,7 +212,8 @@ func (settings Settings) segmentPath(segmentID segmentID) string {
 }
 
 func (settings Settings) maxSegmentOffset() segmentOffset {
-	return segmentOffset(settings.MaxSegmentSize - segmentHeaderSize)
+	headerSize := headerSizeForVersion(settings.segmentSchemaVersion())
+	return segmentOffset(settings.MaxSegmentSize - headerSize)
 }
 
 // Given a retry interval, nextRetryInterval returns the next higher level
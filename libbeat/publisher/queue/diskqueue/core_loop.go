@@ -17,7 +17,10 @@
 
 package diskqueue
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // This file contains the queue's "core loop" -- the central goroutine
 // that owns all queue state that is not encapsulated in one of the
@@ -51,6 +54,9 @@ func (dq *diskQueue) run() {
 			dq.handleShutdown()
 			return
 
+		case response := <-dq.inspectChan:
+			response <- dq.state()
+
 		// Writer loop handling
 		case writerLoopResponse := <-dq.writerLoop.responseChan:
 			dq.handleWriterLoopResponse(writerLoopResponse)
@@ -438,17 +444,23 @@ func (dq *diskQueue) enqueueWriteFrame(frame *writeFrame) {
 	// we need to create a new writing segment.
 	if segment == nil ||
 		dq.segments.nextWriteOffset+frameLen > dq.settings.maxSegmentOffset() {
-		segment = &queueSegment{id: dq.segments.nextID}
+		segment = &queueSegment{
+			id:            dq.segments.nextID,
+			createdAt:     time.Now(),
+			schemaVersion: dq.settings.segmentSchemaVersion(),
+			keyID:         dq.cipher.keyID(),
+		}
 		dq.segments.writing = append(dq.segments.writing, segment)
 		dq.segments.nextID++
 		dq.segments.nextWriteOffset = 0
 	}
 
-	dq.segments.nextWriteOffset += frameLen
 	dq.pendingFrames = append(dq.pendingFrames, segmentedFrame{
 		frame:   frame,
 		segment: segment,
+		offset:  dq.segments.nextWriteOffset,
 	})
+	dq.segments.nextWriteOffset += frameLen
 }
 
 // canAcceptFrameOfSize checks whether there is enough free space in the queue
@@ -0,0 +1,203 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package diskqueue
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// minEncryptionKeyLength is the minimum length of the configured encryption
+// keys. The AES-256 key is derived from the configured key, which should be
+// a long random string stored in the keystore.
+const minEncryptionKeyLength = 16
+
+// segmentCipher encrypts the data frames of the segments with AES-GCM. The
+// segments are written with the current key, the previous keys are only
+// used to read the segments written before the key was rotated, or before
+// encryption was disabled if there is no current key. Each segment header
+// stores the ID of its key.
+//
+// The ID of the segment and the offset of the frame in it are authenticated
+// with the data of each frame, so a frame can't be moved to another position
+// or segment without being detected.
+type segmentCipher struct {
+	current *segmentKey
+	keys    map[uint64]cipher.AEAD
+}
+
+type segmentKey struct {
+	id   uint64
+	aead cipher.AEAD
+}
+
+// newSegmentCipher returns the cipher for the encryption keys of the
+// settings, or nil if no keys are configured.
+func newSegmentCipher(settings Settings) (*segmentCipher, error) {
+	if settings.EncryptionKey == "" && len(settings.PreviousEncryptionKeys) == 0 {
+		return nil, nil
+	}
+
+	sc := &segmentCipher{keys: map[uint64]cipher.AEAD{}}
+	if settings.EncryptionKey != "" {
+		k, err := newSegmentKey(settings.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		sc.current = &k
+		sc.keys[k.id] = k.aead
+	}
+	for _, key := range settings.PreviousEncryptionKeys {
+		k, err := newSegmentKey(key)
+		if err != nil {
+			return nil, err
+		}
+		sc.keys[k.id] = k.aead
+	}
+	return sc, nil
+}
+
+func newSegmentKey(key string) (segmentKey, error) {
+	if len(key) < minEncryptionKeyLength {
+		return segmentKey{}, fmt.Errorf(
+			"disk queue encryption keys must be at least %d characters long", minEncryptionKeyLength)
+	}
+
+	derived := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		return segmentKey{}, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return segmentKey{}, err
+	}
+
+	// The key ID identifies the key of a segment without revealing the key.
+	// It's never 0, which marks unencrypted segments.
+	sum := sha256.Sum256(derived[:])
+	id := binary.LittleEndian.Uint64(sum[:8])
+	if id == 0 {
+		id = 1
+	}
+	return segmentKey{id: id, aead: aead}, nil
+}
+
+// keyID returns the ID of the key new segments are encrypted with, or 0 if
+// encryption is disabled.
+func (sc *segmentCipher) keyID() uint64 {
+	if sc == nil || sc.current == nil {
+		return 0
+	}
+	return sc.current.id
+}
+
+// hasKey reports whether the segments encrypted with the given key can be
+// decrypted.
+func (sc *segmentCipher) hasKey(id uint64) bool {
+	if sc == nil {
+		return false
+	}
+	_, ok := sc.keys[id]
+	return ok
+}
+
+// overhead returns the number of bytes the encryption with the current key
+// adds to the data of a frame.
+func (sc *segmentCipher) overhead() int {
+	if sc == nil || sc.current == nil {
+		return 0
+	}
+	return sc.current.aead.NonceSize() + sc.current.aead.Overhead()
+}
+
+// encrypt returns the data of the frame at the given offset of the segment
+// encrypted with the current key. The random nonce precedes the encrypted
+// data and its authentication tag. Without current key the data is returned
+// as is.
+func (sc *segmentCipher) encrypt(
+	id segmentID, offset segmentOffset, data []byte,
+) ([]byte, error) {
+	if sc == nil || sc.current == nil {
+		return data, nil
+	}
+
+	aead := sc.current.aead
+	out := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+	if _, err := rand.Read(out); err != nil {
+		return nil, fmt.Errorf("couldn't generate nonce: %w", err)
+	}
+	return aead.Seal(out, out, data, frameAdditionalData(id, offset)), nil
+}
+
+// decrypt decrypts the data of the frame at the given offset of the segment,
+// encrypted with the given key, in place. It fails if the data was modified
+// or moved since it was encrypted.
+func (sc *segmentCipher) decrypt(
+	keyID uint64, id segmentID, offset segmentOffset, data []byte,
+) ([]byte, error) {
+	if !sc.hasKey(keyID) {
+		return nil, fmt.Errorf("unknown encryption key %x", keyID)
+	}
+
+	aead := sc.keys[keyID]
+	if len(data) < aead.NonceSize()+aead.Overhead() {
+		return nil, fmt.Errorf("encrypted data frame too short (%d bytes)", len(data))
+	}
+	nonce, sealed := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plain, err := aead.Open(sealed[:0], nonce, sealed, frameAdditionalData(id, offset))
+	if err != nil {
+		return nil, fmt.Errorf("data frame is corrupted or was encrypted with another key: %w", err)
+	}
+	return plain, nil
+}
+
+// frameAdditionalData returns the position of a frame, authenticated with its
+// data.
+func frameAdditionalData(id segmentID, offset segmentOffset) []byte {
+	var ad [16]byte
+	binary.LittleEndian.PutUint64(ad[:8], uint64(id))
+	binary.LittleEndian.PutUint64(ad[8:], uint64(offset))
+	return ad[:]
+}
+
+// checkSegmentKeys returns an error if one of the segments is encrypted
+// with a key that the cipher doesn't have. Segments with an unreadable
+// header are left to the reader loop, which reports them.
+func checkSegmentKeys(settings Settings, sc *segmentCipher, segments []*queueSegment) error {
+	for _, segment := range segments {
+		if segment.schemaVersion != 2 {
+			continue
+		}
+		handle, header, err := segment.getReader(settings)
+		if err != nil {
+			continue
+		}
+		handle.Close()
+		if !sc.hasKey(header.keyID) {
+			return fmt.Errorf(
+				"disk queue segment %d is encrypted with an unknown key (ID %x), "+
+					"add its key to the previous encryption keys", segment.id, header.keyID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package diskqueue
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+const (
+	testKey      = "the first key of the test queue"
+	testOtherKey = "the second key of the test queue"
+)
+
+func TestEncryptionConfig(t *testing.T) {
+	settings, err := SettingsForUserConfig(common.MustNewConfigFrom(map[string]interface{}{
+		"max_size":                 "1GB",
+		"encryption.key":           testOtherKey,
+		"encryption.previous_keys": []string{testKey},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, testOtherKey, settings.EncryptionKey)
+	assert.Equal(t, []string{testKey}, settings.PreviousEncryptionKeys)
+	assert.EqualValues(t, 2, settings.segmentSchemaVersion())
+
+	for name, settings := range map[string]Settings{
+		"short key":          {EncryptionKey: "short"},
+		"short previous key": {EncryptionKey: testKey, PreviousEncryptionKeys: []string{"short"}},
+	} {
+		_, err := newSegmentCipher(settings)
+		assert.Error(t, err, name)
+	}
+
+	sc, err := newSegmentCipher(Settings{})
+	require.NoError(t, err)
+	assert.Nil(t, sc)
+}
+
+func TestSegmentCipher(t *testing.T) {
+	old, err := newSegmentCipher(Settings{EncryptionKey: testKey})
+	require.NoError(t, err)
+	sc, err := newSegmentCipher(Settings{
+		EncryptionKey:          testOtherKey,
+		PreviousEncryptionKeys: []string{testKey},
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, old.keyID(), sc.keyID())
+	assert.True(t, sc.hasKey(old.keyID()))
+	assert.False(t, old.hasKey(sc.keyID()))
+
+	data := []byte(`{"message":"secret"}`)
+	encrypted, err := old.encrypt(3, 100, append([]byte(nil), data...))
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(encrypted, []byte("secret")))
+	assert.Len(t, encrypted, len(data)+old.overhead())
+
+	// The data encrypted with the previous key can still be decrypted.
+	decrypted, err := sc.decrypt(old.keyID(), 3, 100, append([]byte(nil), encrypted...))
+	require.NoError(t, err)
+	assert.Equal(t, data, decrypted)
+
+	_, err = old.decrypt(sc.keyID(), 3, 100, append([]byte(nil), encrypted...))
+	assert.Error(t, err)
+
+	// Modified data is detected.
+	corrupted := append([]byte(nil), encrypted...)
+	corrupted[len(corrupted)/2] ^= 1
+	_, err = sc.decrypt(old.keyID(), 3, 100, corrupted)
+	assert.Error(t, err)
+	_, err = sc.decrypt(old.keyID(), 3, 100, encrypted[:8])
+	assert.Error(t, err)
+
+	// Frames moved to another segment or offset are detected.
+	_, err = sc.decrypt(old.keyID(), 4, 100, append([]byte(nil), encrypted...))
+	assert.Error(t, err)
+	_, err = sc.decrypt(old.keyID(), 3, 0, append([]byte(nil), encrypted...))
+	assert.Error(t, err)
+
+	// Without current key the data is not encrypted.
+	var none *segmentCipher
+	decryptOnly, err := newSegmentCipher(Settings{PreviousEncryptionKeys: []string{testKey}})
+	require.NoError(t, err)
+	assert.True(t, decryptOnly.hasKey(old.keyID()))
+	for _, sc := range []*segmentCipher{none, decryptOnly} {
+		plain, err := sc.encrypt(3, 100, data)
+		require.NoError(t, err)
+		assert.Equal(t, data, plain)
+		assert.EqualValues(t, 0, sc.keyID())
+		assert.Zero(t, sc.overhead())
+	}
+}
+
+// TestEncryptedSegments writes events with a key, then without encryption,
+// then with a new key, and reads all of them with the new key and the
+// previous one.
+func TestEncryptedSegments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskqueue")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	settings := DefaultSettings()
+	settings.Path = dir
+	settings.MaxSegmentSize = 1000
+	settings.Compression = CompressionLZ4
+	settings.EncryptionKey = testKey
+	q, err := NewQueue(logp.NewLogger("test"), settings)
+	require.NoError(t, err)
+	writeTestEvents(t, q, 0, 10)
+	require.NoError(t, q.Close())
+
+	// Encryption is disabled, the previous key reads the encrypted segments.
+	settings.EncryptionKey = ""
+	_, err = NewQueue(logp.NewLogger("test"), settings)
+	assert.Error(t, err)
+	settings.PreviousEncryptionKeys = []string{testKey}
+	q, err = NewQueue(logp.NewLogger("test"), settings)
+	require.NoError(t, err)
+	writeTestEvents(t, q, 10, 10)
+	require.NoError(t, q.Close())
+
+	// The events are not readable from the encrypted segments.
+	segments, err := scanExistingSegments(dir)
+	require.NoError(t, err)
+	encrypted := 0
+	for _, segment := range segments {
+		if segment.schemaVersion != 2 {
+			continue
+		}
+		encrypted++
+		data, err := ioutil.ReadFile(settings.segmentPath(segment.id))
+		require.NoError(t, err)
+		assert.False(t, bytes.Contains(data, []byte("test event")))
+	}
+	assert.NotZero(t, encrypted)
+
+	// The queue doesn't start without the key of the existing segments.
+	settings.EncryptionKey = testOtherKey
+	settings.PreviousEncryptionKeys = nil
+	_, err = NewQueue(logp.NewLogger("test"), settings)
+	assert.Error(t, err)
+
+	settings.PreviousEncryptionKeys = []string{testKey}
+	q, err = NewQueue(logp.NewLogger("test"), settings)
+	require.NoError(t, err)
+	writeTestEvents(t, q, 20, 10)
+	require.NoError(t, q.Close())
+
+	var events []publisher.Event
+	err = ReadSegmentsWithSettings(settings, func(event publisher.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 30)
+	for i, event := range events {
+		assert.EqualValues(t, i, event.Content.Fields["n"])
+	}
+
+	err = ReadSegments(dir, func(publisher.Event) error { return nil })
+	assert.Error(t, err)
+
+	// A segment copied under another ID is not readable.
+	movedSettings := settings
+	movedSettings.Path, err = ioutil.TempDir("", "diskqueue")
+	require.NoError(t, err)
+	defer os.RemoveAll(movedSettings.Path)
+	data, err := ioutil.ReadFile(settings.segmentPath(segments[0].id))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(movedSettings.segmentPath(segments[0].id+1), data, 0600))
+	err = ReadSegmentsWithSettings(movedSettings, func(publisher.Event) error { return nil })
+	assert.Error(t, err)
+
+	q, err = NewQueue(logp.NewLogger("test"), settings)
+	require.NoError(t, err)
+	defer q.Close()
+	consumer := q.Consumer()
+	for read := 0; read < 30; {
+		batch, err := consumer.Get(30 - read)
+		require.NoError(t, err)
+		for _, event := range batch.Events() {
+			assert.EqualValues(t, read, event.Content.Fields["n"])
+			read++
+		}
+		batch.ACK()
+	}
+}
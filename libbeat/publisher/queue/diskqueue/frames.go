@@ -33,6 +33,10 @@ type writeFrame struct {
 	// header / footer.
 	serialized []byte
 
+	// The bytes added to the serialized data when the writer loop encrypts
+	// it, once the position of the frame in its segment is known.
+	overhead int
+
 	// The producer that created this frame. This is included in the
 	// frame structure itself because we may need the producer and / or
 	// its config at any time up until it has been completely written:
@@ -68,5 +72,5 @@ const frameFooterSize = 8
 const frameMetadataSize = frameHeaderSize + frameFooterSize
 
 func (frame writeFrame) sizeOnDisk() uint64 {
-	return uint64(len(frame.serialized) + frameMetadataSize)
+	return uint64(len(frame.serialized) + frame.overhead + frameMetadataSize)
 }
@@ -0,0 +1,76 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package diskqueue
+
+import (
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+)
+
+// Inspect reports the segments of the queue. The queue doesn't count the
+// events stored on disk, the oldest event is approximated by the creation
+// time of the oldest segment not completely ACKed.
+func (dq *diskQueue) Inspect() queue.State {
+	response := make(chan queue.State, 1)
+	select {
+	case dq.inspectChan <- response:
+		return <-response
+	case <-dq.done:
+		return queue.State{Events: -1}
+	}
+}
+
+// state should only be called from the core loop.
+func (dq *diskQueue) state() queue.State {
+	state := queue.State{Events: -1}
+
+	segments := []common.MapStr{}
+	lists := []struct {
+		name     string
+		segments []*queueSegment
+	}{
+		{"acked", dq.segments.acked},
+		{"acking", dq.segments.acking},
+		{"reading", dq.segments.reading},
+		{"writing", dq.segments.writing},
+	}
+	for _, list := range lists {
+		for _, segment := range list.segments {
+			if list.name != "acked" && state.Oldest.IsZero() {
+				state.Oldest = segment.createdAt
+			}
+			segments = append(segments, common.MapStr{
+				"id":          segment.id,
+				"state":       list.name,
+				"bytes":       segment.sizeOnDisk(),
+				"version":     segment.schemaVersion,
+				"encrypted":   segment.keyID != 0,
+				"frames_read": segment.framesRead,
+			})
+		}
+	}
+
+	state.Details = common.MapStr{
+		"bytes":             dq.segments.sizeOnDisk(),
+		"max_bytes":         dq.settings.MaxBufferSize,
+		"pending_frames":    len(dq.pendingFrames),
+		"blocked_producers": len(dq.blockedProducers),
+		"segments":          segments,
+	}
+	return state
+}
@@ -0,0 +1,63 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package diskqueue
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+)
+
+func TestInspect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskqueue")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	settings := DefaultSettings()
+	settings.Path = dir
+	settings.MaxSegmentSize = 1000
+	q, err := NewQueue(logp.NewLogger("test"), settings)
+	require.NoError(t, err)
+	defer q.Close()
+
+	start := time.Now()
+	writeTestEvents(t, q, 0, 20)
+
+	state := q.(queue.Inspector).Inspect()
+	assert.Equal(t, -1, state.Events)
+	assert.False(t, state.Oldest.Before(start))
+	assert.False(t, state.Oldest.After(time.Now()))
+
+	segments := state.Details["segments"].([]common.MapStr)
+	require.True(t, len(segments) > 1)
+	total := uint64(0)
+	for i, segment := range segments {
+		assert.Equal(t, segmentID(i), segment["id"])
+		total += segment["bytes"].(uint64)
+	}
+	assert.Equal(t, "writing", segments[len(segments)-1]["state"])
+	assert.Equal(t, total, state.Details["bytes"])
+}
@@ -65,14 +65,20 @@ func (producer *diskQueueProducer) publish(
 		return false
 	}
 	serialized, err := producer.encoder.encode(&event)
+	if err == nil {
+		serialized, err = producer.queue.compressor.compress(
+			producer.queue.settings.Compression, serialized)
+	}
 	if err != nil {
 		producer.queue.logger.Errorf(
 			"Couldn't serialize incoming event: %v", err)
 		return false
 	}
+	event.Release()
 	request := producerWriteRequest{
 		frame: &writeFrame{
 			serialized: serialized,
+			overhead:   producer.queue.cipher.overhead(),
 			producer:   producer,
 		},
 		shouldBlock: shouldBlock,
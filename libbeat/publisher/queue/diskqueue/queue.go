@@ -47,6 +47,13 @@ type diskQueue struct {
 	writerLoop  *writerLoop
 	deleterLoop *deleterLoop
 
+	// The compressor of the data frames written by the producers, shared
+	// by all producers.
+	compressor *frameCompressor
+
+	// The cipher encrypting the data frames, nil if encryption is disabled.
+	cipher *segmentCipher
+
 	// Wait group for shutdown of the goroutines associated with this queue:
 	// reader loop, writer loop, deleter loop, and core loop (diskQueue.run()).
 	waitGroup sync.WaitGroup
@@ -71,6 +78,9 @@ type diskQueue struct {
 	// The API channel used by diskQueueProducer to write events.
 	producerWriteRequestChan chan producerWriteRequest
 
+	// The API channel used by Inspect to report the state of the queue.
+	inspectChan chan chan queue.State
+
 	// pendingFrames is a list of all incoming data frames that have been
 	// accepted by the queue and are waiting to be sent to the writer loop.
 	// Segment ids in this list always appear in sorted order, even between
@@ -116,6 +126,14 @@ func NewQueue(logger *logp.Logger, settings Settings) (queue.Queue, error) {
 	logger.Debugf(
 		"Initializing disk queue at path %v", settings.directoryPath())
 
+	if _, ok := compressionNames[settings.Compression]; !ok {
+		return nil, fmt.Errorf("unknown disk queue compression %v", settings.Compression)
+	}
+	cipher, err := newSegmentCipher(settings)
+	if err != nil {
+		return nil, err
+	}
+
 	if settings.MaxBufferSize > 0 &&
 		settings.MaxBufferSize < settings.MaxSegmentSize*2 {
 		return nil, fmt.Errorf(
@@ -125,7 +143,7 @@ func NewQueue(logger *logp.Logger, settings Settings) (queue.Queue, error) {
 	}
 
 	// Create the given directory path if it doesn't exist.
-	err := os.MkdirAll(settings.directoryPath(), os.ModePerm)
+	err = os.MkdirAll(settings.directoryPath(), os.ModePerm)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create disk queue directory: %w", err)
 	}
@@ -177,6 +195,13 @@ func NewQueue(logger *logp.Logger, settings Settings) (queue.Queue, error) {
 		nextReadPosition = queuePosition{segmentID: initialSegments[0].id}
 	}
 
+	// Segments encrypted with a key that isn't configured anymore would be
+	// discarded as unreadable, so they must be decryptable before starting.
+	if err := checkSegmentKeys(settings, cipher, initialSegments); err != nil {
+		positionFile.Close()
+		return nil, err
+	}
+
 	queue := &diskQueue{
 		logger:   logger,
 		settings: settings,
@@ -189,11 +214,15 @@ func NewQueue(logger *logp.Logger, settings Settings) (queue.Queue, error) {
 
 		acks: newDiskQueueACKs(logger, nextReadPosition, positionFile),
 
-		readerLoop:  newReaderLoop(settings),
-		writerLoop:  newWriterLoop(logger, settings),
+		readerLoop:  newReaderLoop(settings, cipher),
+		writerLoop:  newWriterLoop(logger, settings, cipher),
 		deleterLoop: newDeleterLoop(settings),
 
+		compressor: &frameCompressor{},
+		cipher:     cipher,
+
 		producerWriteRequestChan: make(chan producerWriteRequest),
+		inspectChan:              make(chan chan queue.State),
 
 		done: make(chan struct{}),
 	}
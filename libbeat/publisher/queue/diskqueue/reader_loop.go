@@ -65,9 +65,16 @@ type readerLoop struct {
 	// The helper object to deserialize binary blobs from the queue into
 	// publisher.Event objects that can be returned in a readFrame.
 	decoder *eventDecoder
+
+	// The data frames of compressed or encrypted segments are read to
+	// frameBuf first, then decrypted with the cipher and decompressed with
+	// the compressor.
+	compressor *frameCompressor
+	cipher     *segmentCipher
+	frameBuf   []byte
 }
 
-func newReaderLoop(settings Settings) *readerLoop {
+func newReaderLoop(settings Settings, cipher *segmentCipher) *readerLoop {
 	return &readerLoop{
 		settings: settings,
 
@@ -75,6 +82,8 @@ func newReaderLoop(settings Settings) *readerLoop {
 		responseChan: make(chan readerLoopResponse),
 		output:       make(chan *readFrame, settings.ReadAheadLimit),
 		decoder:      newEventDecoder(),
+		compressor:   &frameCompressor{},
+		cipher:       cipher,
 	}
 }
 
@@ -97,13 +106,13 @@ func (rl *readerLoop) processRequest(request readerLoopRequest) readerLoopRespon
 	nextFrameID := request.startFrameID
 
 	// Open the file and seek to the starting position.
-	handle, err := request.segment.getReader(rl.settings)
+	handle, header, err := request.segment.getReader(rl.settings)
 	if err != nil {
 		return readerLoopResponse{err: err}
 	}
 	defer handle.Close()
 	_, err = handle.Seek(
-		segmentHeaderSize+int64(request.startOffset), os.SEEK_SET)
+		int64(request.segment.headerSize())+int64(request.startOffset), os.SEEK_SET)
 	if err != nil {
 		return readerLoopResponse{err: err}
 	}
@@ -115,7 +124,8 @@ func (rl *readerLoop) processRequest(request readerLoopRequest) readerLoopRespon
 		// Try to read the next frame, clipping to the given bound.
 		// If the next frame extends past this boundary, nextFrame will return
 		// an error.
-		frame, err := rl.nextFrame(handle, remainingLength)
+		offset := request.startOffset + segmentOffset(byteCount)
+		frame, err := rl.nextFrame(handle, remainingLength, request.segment.id, offset, header)
 		if frame != nil {
 			// Add the segment / frame ID, which nextFrame leaves blank.
 			frame.segment = request.segment
@@ -167,11 +177,14 @@ func (rl *readerLoop) processRequest(request readerLoopRequest) readerLoopRespon
 }
 
 // nextFrame reads and decodes one frame from the given file handle, as long
-// it does not exceed the given length bound. The returned frame leaves the
-// segment and frame IDs unset.
+// it does not exceed the given length bound. The data of the frame is
+// decrypted and decompressed as given by the header of its segment, the
+// segment ID and the offset of the frame are needed to authenticate the
+// encrypted data. The returned frame leaves the segment and frame IDs unset.
 // The returned error will be set if and only if the returned frame is nil.
 func (rl *readerLoop) nextFrame(
 	handle *os.File, maxLength uint64,
+	id segmentID, offset segmentOffset, header *segmentHeader,
 ) (*readFrame, error) {
 	// Ensure we are allowed to read the frame header.
 	if maxLength < frameHeaderSize {
@@ -200,9 +213,19 @@ func (rl *readerLoop) nextFrame(
 			"Data frame with no data (length %d)", frameLength)
 	}
 
-	// Read the actual frame data
+	// Read the actual frame data, directly to the decoder buffer if the
+	// segment is neither compressed nor encrypted.
 	dataLength := frameLength - frameMetadataSize
-	bytes := rl.decoder.Buffer(int(dataLength))
+	plain := header.compression == CompressionNone && header.keyID == 0
+	var bytes []byte
+	if plain {
+		bytes = rl.decoder.Buffer(int(dataLength))
+	} else {
+		if cap(rl.frameBuf) < int(dataLength) {
+			rl.frameBuf = make([]byte, dataLength)
+		}
+		bytes = rl.frameBuf[:dataLength]
+	}
 	_, err = reader.Read(bytes)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't read data frame content: %w", err)
@@ -231,6 +254,19 @@ func (rl *readerLoop) nextFrame(
 			frameLength, duplicateLength)
 	}
 
+	if header.keyID != 0 {
+		bytes, err = rl.cipher.decrypt(header.keyID, id, offset, bytes)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't decrypt data frame: %w", err)
+		}
+	}
+	if !plain {
+		_, err = rl.compressor.decompress(header.compression, bytes, rl.decoder.Buffer)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't decompress data frame: %w", err)
+		}
+	}
+
 	event, err := rl.decoder.Decode()
 	if err != nil {
 		// Unlike errors in the segment or frame metadata, this is entirely
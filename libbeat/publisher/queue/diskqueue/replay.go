@@ -0,0 +1,82 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package diskqueue
+
+import (
+	"fmt"
+
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// ReadSegments reads the events stored in the segment files of the queue
+// directory at path and passes them to fn, in the order they were written.
+// Events of segments that have been acknowledged but not deleted yet are
+// included. Reading stops at the first error returned by fn. The queue must
+// not be in use while its segments are read.
+func ReadSegments(path string, fn func(publisher.Event) error) error {
+	settings := DefaultSettings()
+	settings.Path = path
+	return ReadSegmentsWithSettings(settings, fn)
+}
+
+// ReadSegmentsWithSettings is like ReadSegments, for the queue directory of
+// the settings. The encryption keys of the settings are used to decrypt the
+// encrypted segments.
+func ReadSegmentsWithSettings(settings Settings, fn func(publisher.Event) error) error {
+	cipher, err := newSegmentCipher(settings)
+	if err != nil {
+		return err
+	}
+	segments, err := scanExistingSegments(settings.directoryPath())
+	if err != nil {
+		return err
+	}
+
+	rl := newReaderLoop(settings, cipher)
+	for _, segment := range segments {
+		if err := readSegment(rl, segment, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readSegment(
+	rl *readerLoop, segment *queueSegment, fn func(publisher.Event) error,
+) error {
+	handle, header, err := segment.getReader(rl.settings)
+	if err != nil {
+		return err
+	}
+	defer handle.Close()
+
+	remaining := uint64(segment.endOffset)
+	for remaining > 0 {
+		offset := segment.endOffset - segmentOffset(remaining)
+		frame, err := rl.nextFrame(handle, remaining, segment.id, offset, header)
+		if err != nil {
+			return fmt.Errorf("Couldn't read segment %d: %w", segment.id, err)
+		}
+		remaining -= frame.bytesOnDisk
+
+		if err := fn(frame.event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
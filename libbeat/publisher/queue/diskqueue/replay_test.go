@@ -0,0 +1,92 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package diskqueue
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
+)
+
+func TestReadSegments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "diskqueue")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	settings := DefaultSettings()
+	settings.Path = dir
+	// Small segments, so the events are spread over several segment files.
+	settings.MaxSegmentSize = 200
+	q, err := NewQueue(logp.NewLogger("test"), settings)
+	require.NoError(t, err)
+
+	const count = 10
+	written := make(chan int, count)
+	producer := q.Producer(queue.ProducerConfig{ACK: func(n int) { written <- n }})
+	for i := 0; i < count; i++ {
+		ok := producer.Publish(publisher.Event{
+			Content: beat.Event{
+				Timestamp: time.Now(),
+				Fields:    common.MapStr{"message": "event", "n": i},
+			},
+		})
+		require.True(t, ok)
+	}
+	for total := 0; total < count; {
+		select {
+		case n := <-written:
+			total += n
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for the events to be written")
+		}
+	}
+	require.NoError(t, q.Close())
+
+	var events []publisher.Event
+	err = ReadSegments(dir, func(event publisher.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, events, count)
+	for i, event := range events {
+		n, err := event.Content.Fields.GetValue("n")
+		require.NoError(t, err)
+		assert.EqualValues(t, i, n)
+	}
+
+	errStop := errors.New("stop")
+	read := 0
+	err = ReadSegments(dir, func(publisher.Event) error {
+		read++
+		return errStop
+	})
+	assert.Equal(t, errStop, err)
+	assert.Equal(t, 1, read)
+}
@@ -22,9 +22,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // diskQueueSegments encapsulates segment-related queue metadata.
@@ -110,14 +112,68 @@ type queueSegment struct {
 	//
 	// Used to count how many frames still need to be acknowledged by consumers.
 	framesRead uint64
+
+	// The schema version of the segment header. Segments without
+	// compression are written with version 0, readable by previous versions
+	// of the queue.
+	schemaVersion uint32
+
+	// The ID of the key the segment is encrypted with, 0 if the segment is
+	// not encrypted.
+	keyID uint64
+
+	// The time the segment was created. For the segments of a previous
+	// session, it's the time the segment file was last modified.
+	createdAt time.Time
 }
 
 type segmentHeader struct {
 	version uint32
+
+	// The compression of the data frames, only stored since version 1.
+	compression Compression
+
+	// The ID of the encryption key of the data frames, only stored in
+	// version 2.
+	keyID uint64
 }
 
-// Segment headers are currently just a 32-bit version.
-const segmentHeaderSize = 4
+// Segment headers of version 0 are just a 32-bit version, version 1 adds
+// the 32-bit compression of the data frames, and version 2 the 64-bit ID of
+// their encryption key.
+const (
+	segmentHeaderSize   = 4
+	segmentHeaderSizeV1 = 8
+	segmentHeaderSizeV2 = 16
+)
+
+// segmentSchemaVersion returns the schema version of the segments written
+// with the settings.
+func (settings Settings) segmentSchemaVersion() uint32 {
+	switch {
+	case settings.EncryptionKey != "":
+		return 2
+	case settings.Compression != CompressionNone:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func headerSizeForVersion(version uint32) uint64 {
+	switch version {
+	case 0:
+		return segmentHeaderSize
+	case 1:
+		return segmentHeaderSizeV1
+	default:
+		return segmentHeaderSizeV2
+	}
+}
+
+func (segment *queueSegment) headerSize() uint64 {
+	return headerSizeForVersion(segment.schemaVersion)
+}
 
 // Sort order: we store loaded segments in ascending order by their id.
 type bySegmentID []*queueSegment
@@ -147,10 +203,19 @@ func scanExistingSegments(path string) ([]*queueSegment, error) {
 			// Parse the id as base-10 64-bit unsigned int. We ignore file names that
 			// don't match the "[uint64].seg" pattern.
 			if id, err := strconv.ParseUint(components[0], 10, 64); err == nil {
+				// The header size depends on the schema version. Segments with an
+				// invalid version are kept, so that reading them reports the error.
+				version := readSegmentVersion(filepath.Join(path, file.Name()))
+				headerSize := headerSizeForVersion(version)
+				if uint64(file.Size()) <= headerSize {
+					continue
+				}
 				segments = append(segments,
 					&queueSegment{
-						id:        segmentID(id),
-						endOffset: segmentOffset(file.Size() - segmentHeaderSize),
+						id:            segmentID(id),
+						endOffset:     segmentOffset(uint64(file.Size()) - headerSize),
+						schemaVersion: version,
+						createdAt:     file.ModTime(),
 					})
 			}
 		}
@@ -160,28 +225,27 @@ func scanExistingSegments(path string) ([]*queueSegment, error) {
 }
 
 func (segment *queueSegment) sizeOnDisk() uint64 {
-	return uint64(segment.endOffset) + segmentHeaderSize
+	return uint64(segment.endOffset) + segment.headerSize()
 }
 
-// Should only be called from the reader loop.
+// Should only be called from the reader loop. The returned header contains
+// the compression of the data frames.
 func (segment *queueSegment) getReader(
 	queueSettings Settings,
-) (*os.File, error) {
+) (*os.File, *segmentHeader, error) {
 	path := queueSettings.segmentPath(segment.id)
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf(
+		return nil, nil, fmt.Errorf(
 			"Couldn't open segment %d: %w", segment.id, err)
 	}
-	// Right now there is only one valid header (indicating schema version
-	// zero) so we don't need the value itself.
-	_, err = readSegmentHeader(file)
+	header, err := readSegmentHeader(file)
 	if err != nil {
 		file.Close()
-		return nil, fmt.Errorf("Couldn't read segment header: %w", err)
+		return nil, nil, fmt.Errorf("Couldn't read segment header: %w", err)
 	}
 
-	return file, nil
+	return file, header, nil
 }
 
 // Should only be called from the writer loop.
@@ -193,7 +257,11 @@ func (segment *queueSegment) getWriter(
 	if err != nil {
 		return nil, err
 	}
-	header := &segmentHeader{version: 0}
+	header := &segmentHeader{
+		version:     segment.schemaVersion,
+		compression: queueSettings.Compression,
+		keyID:       segment.keyID,
+	}
 	err = writeSegmentHeader(file, header)
 	if err != nil {
 		return nil, fmt.Errorf("Couldn't write segment header: %w", err)
@@ -228,15 +296,53 @@ func readSegmentHeader(in *os.File) (*segmentHeader, error) {
 	if err != nil {
 		return nil, err
 	}
-	if header.version != 0 {
+	if header.version > 2 {
 		return nil, fmt.Errorf("Unrecognized schema version %d", header.version)
 	}
+	if header.version >= 1 {
+		err = binary.Read(in, binary.LittleEndian, &header.compression)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := compressionNames[header.compression]; !ok {
+			return nil, fmt.Errorf("Unrecognized compression %d", header.compression)
+		}
+	}
+	if header.version == 2 {
+		err = binary.Read(in, binary.LittleEndian, &header.keyID)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return header, nil
 }
 
+// readSegmentVersion returns the schema version of the segment at path, or
+// 0 if it can't be read.
+func readSegmentVersion(path string) uint32 {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	var version uint32
+	if err := binary.Read(f, binary.LittleEndian, &version); err != nil {
+		return 0
+	}
+	return version
+}
+
 func writeSegmentHeader(out *os.File, header *segmentHeader) error {
 	err := binary.Write(out, binary.LittleEndian, header.version)
-	return err
+	if err != nil || header.version == 0 {
+		return err
+	}
+	err = binary.Write(out, binary.LittleEndian, header.compression)
+	if err != nil || header.version == 1 {
+		return err
+	}
+	return binary.Write(out, binary.LittleEndian, header.keyID)
 }
 
 // The number of bytes occupied by all the queue's segment files. This
@@ -26,6 +26,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/intern"
 	"github.com/elastic/beats/v7/libbeat/outputs/codec"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/go-structform/gotype"
@@ -72,8 +73,10 @@ func (e *eventEncoder) reset() {
 	// guaranteed to be valid and we can safely proceed.
 	folder, _ := gotype.NewIterator(visitor,
 		gotype.Folders(
-			codec.MakeTimestampEncoder(),
-			codec.MakeBCTimestampEncoder(),
+			// Timestamps are stored with full precision for outputs
+			// encoding them with a precision finer than milliseconds.
+			codec.MakeTimestampEncoderWithPrecision(false, common.TimestampNanosecond),
+			codec.MakeBCTimestampEncoderWithPrecision(common.TimestampNanosecond),
 		),
 	)
 
@@ -143,6 +146,9 @@ func (d *eventDecoder) Decode() (publisher.Event, error) {
 		return publisher.Event{}, err
 	}
 
+	// Share the field names and low cardinality values between the decoded events.
+	intern.Default.MapStr(to.Fields)
+
 	return publisher.Event{
 		Flags: publisher.EventFlags(to.Flags),
 		Content: beat.Event{
@@ -33,6 +33,9 @@ type segmentedFrame struct {
 
 	// The segment to which this frame should be written.
 	segment *queueSegment
+
+	// The offset of the frame in the segment.
+	offset segmentOffset
 }
 
 // A writer loop request contains a list of writeFrames with the
@@ -64,6 +67,9 @@ type writerLoop struct {
 	// The logger for the writer loop, assigned when the queue creates it.
 	logger *logp.Logger
 
+	// The cipher encrypting the frames of the encrypted segments.
+	cipher *segmentCipher
+
 	// The writer loop listens on requestChan for frames to write, and
 	// writes them to disk immediately (all queue capacity checking etc. is
 	// done by the core loop before sending it to the writer).
@@ -86,10 +92,11 @@ type writerLoop struct {
 	currentRetryInterval time.Duration
 }
 
-func newWriterLoop(logger *logp.Logger, settings Settings) *writerLoop {
+func newWriterLoop(logger *logp.Logger, settings Settings, cipher *segmentCipher) *writerLoop {
 	return &writerLoop{
 		logger:   logger,
 		settings: settings,
+		cipher:   cipher,
 
 		requestChan:  make(chan writerLoopRequest, 1),
 		responseChan: make(chan writerLoopResponse),
@@ -157,6 +164,17 @@ outerLoop:
 		// Make sure our writer points to the current file handle.
 		retryWriter.wrapped = wl.outputFile
 
+		// Encrypt the data now that the position of the frame is known.
+		data := frameRequest.frame.serialized
+		if frameRequest.segment.keyID != 0 {
+			var err error
+			data, err = wl.cipher.encrypt(frameRequest.segment.id, frameRequest.offset, data)
+			if err != nil {
+				wl.logger.Errorf("Couldn't encrypt data frame: %v", err)
+				break
+			}
+		}
+
 		// We have the data and a file to write it to. We are now committed
 		// to writing this block unless the queue is closed in the meantime.
 		frameSize := uint32(frameRequest.frame.sizeOnDisk())
@@ -168,12 +186,12 @@ outerLoop:
 		if err != nil {
 			break
 		}
-		_, err = retryWriter.Write(frameRequest.frame.serialized)
+		_, err = retryWriter.Write(data)
 		if err != nil {
 			break
 		}
 		// Compute / write the frame's checksum
-		checksum := computeChecksum(frameRequest.frame.serialized)
+		checksum := computeChecksum(data)
 		err = binary.Write(wl.outputFile, binary.LittleEndian, checksum)
 		if err != nil {
 			break
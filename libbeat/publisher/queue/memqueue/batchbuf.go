@@ -68,6 +68,7 @@ func (b *batchBuffer) cancel(st *produceState) int {
 	removed := 0
 	for i := range b.clients {
 		if b.clients[i].state == st {
+			b.events[i].Release()
 			removed++
 			continue
 		}
@@ -18,10 +18,13 @@
 package memqueue
 
 import (
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/atomic"
 	"github.com/elastic/beats/v7/libbeat/feature"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
@@ -32,12 +35,15 @@ type broker struct {
 
 	logger logger
 
-	bufSize int
+	// bufSize is the current size of the queue, updated on resize.
+	bufSize atomic.Int
 
 	// api channels
 	events    chan pushRequest
 	requests  chan getRequest
 	pubCancel chan producerCancelRequest
+	resizes   chan int
+	inspects  chan chan queue.State
 
 	// internal channels
 	acks          chan int
@@ -141,6 +147,8 @@ func NewQueue(
 		events:    make(chan pushRequest, chanSize),
 		requests:  make(chan getRequest),
 		pubCancel: make(chan producerCancelRequest, 5),
+		resizes:   make(chan int),
+		inspects:  make(chan chan queue.State),
 
 		// internal broker and ACK handler channels
 		acks:          make(chan int),
@@ -162,7 +170,7 @@ func NewQueue(
 		eventLoop = newDirectEventLoop(b, sz)
 	}
 
-	b.bufSize = sz
+	b.bufSize.Store(sz)
 	ack := newACKLoop(b, eventLoop.processACK)
 
 	b.wg.Add(2)
@@ -188,7 +196,36 @@ func (b *broker) Close() error {
 
 func (b *broker) BufferConfig() queue.BufferConfig {
 	return queue.BufferConfig{
-		MaxEvents: b.bufSize,
+		MaxEvents: b.bufSize.Load(),
+	}
+}
+
+// Resize changes the maximum number of events in the queue. The queue grows
+// immediately. When shrinking, no new events are accepted until the events
+// in the queue fit in the new size.
+func (b *broker) Resize(events int) error {
+	if events < minQueueSize {
+		return fmt.Errorf("the memory queue size must be at least %d events, not %d", minQueueSize, events)
+	}
+
+	select {
+	case b.resizes <- events:
+		b.bufSize.Store(events)
+		return nil
+	case <-b.done:
+		return errors.New("the memory queue is closed")
+	}
+}
+
+// Inspect reports the events in the queue and the ones being published by the
+// outputs.
+func (b *broker) Inspect() queue.State {
+	resp := make(chan queue.State, 1)
+	select {
+	case b.inspects <- resp:
+		return <-resp
+	case <-b.done:
+		return queue.State{}
 	}
 }
 
@@ -28,6 +28,10 @@ type config struct {
 	FlushTimeout   time.Duration `config:"flush.timeout"`
 }
 
+// minQueueSize is the minimum number of events in the queue, as validated
+// for the events setting.
+const minQueueSize = 32
+
 var defaultConfig = config{
 	Events:         4 * 1024,
 	FlushMinEvents: 2 * 1024,
@@ -21,6 +21,9 @@ import (
 	"fmt"
 	"math"
 	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue"
 )
 
 // directEventLoop implements the broker main event loop. It buffers events,
@@ -34,6 +37,8 @@ type directEventLoop struct {
 	events    chan pushRequest
 	get       chan getRequest
 	pubCancel chan producerCancelRequest
+	resizes   chan int
+	inspects  chan chan queue.State
 
 	// ack handling
 	acks        chan int      // ackloop -> eventloop : total number of events ACKed by outputs
@@ -55,10 +60,16 @@ type bufferingEventLoop struct {
 	maxEvents    int
 	flushTimeout time.Duration
 
+	// flushMinEvents is the configured minEvents, which is limited to
+	// maxEvents when the queue is resized.
+	flushMinEvents int
+
 	// active broker API channels
 	events    chan pushRequest
 	get       chan getRequest
 	pubCancel chan producerCancelRequest
+	resizes   chan int
+	inspects  chan chan queue.State
 
 	// ack handling
 	acks        chan int      // ackloop -> eventloop : total number of events ACKed by outputs
@@ -66,11 +77,20 @@ type bufferingEventLoop struct {
 	pendingACKs chanList      // ordered list of active batches to be send to the ackloop
 	ackSeq      uint          // ack batch sequence number to validate ordering
 
+	// inFlight are the batches passed to the consumers and not ACKed yet,
+	// oldest first.
+	inFlight []inFlightBatch
+
 	// buffer flush timer state
 	timer *time.Timer
 	idleC <-chan time.Time
 }
 
+type inFlightBatch struct {
+	inserted int64 // insert time of the first event
+	count    int
+}
+
 type flushList struct {
 	head  *batchBuffer
 	tail  *batchBuffer
@@ -83,6 +103,8 @@ func newDirectEventLoop(b *broker, size int) *directEventLoop {
 		events:    b.events,
 		get:       nil,
 		pubCancel: b.pubCancel,
+		resizes:   b.resizes,
+		inspects:  b.inspects,
 		acks:      b.acks,
 	}
 	l.buf.init(b.logger, size)
@@ -110,6 +132,12 @@ func (l *directEventLoop) run() {
 		case req := <-l.get: // consumer asking for next batch
 			l.handleConsumer(&req)
 
+		case size := <-l.resizes: // queue size changed at runtime
+			l.handleResize(size)
+
+		case resp := <-l.inspects:
+			resp <- l.state()
+
 		case l.schedACKS <- l.pendingACKs:
 			// on send complete list of pending batches has been forwarded -> clear list and queue
 			l.schedACKS = nil
@@ -144,8 +172,9 @@ func (l *directEventLoop) insert(req *pushRequest) (int, bool) {
 	var avail int
 	log := l.broker.logger
 
+	now := time.Now().UnixNano()
 	if req.state == nil {
-		_, avail = l.buf.insert(req.event, clientState{})
+		_, avail = l.buf.insert(req.event, clientState{inserted: now})
 		return avail, true
 	}
 
@@ -156,8 +185,9 @@ func (l *directEventLoop) insert(req *pushRequest) (int, bool) {
 	}
 
 	_, avail = l.buf.insert(req.event, clientState{
-		seq:   req.seq,
-		state: st,
+		seq:      req.seq,
+		state:    st,
+		inserted: now,
 	})
 
 	return avail, true
@@ -213,9 +243,27 @@ func (l *directEventLoop) handleACK(count int) {
 
 	// Give broker/buffer a chance to clean up most recent ACKs
 	// After handling ACKs some buffer has been freed up
-	// -> always reenable producers
+	// -> reenable producers, unless the buffer is still being shrunk
 	l.buf.ack(count)
-	l.events = l.broker.events
+	if l.buf.Free() > 0 {
+		l.events = l.broker.events
+	}
+}
+
+func (l *directEventLoop) handleResize(size int) {
+	l.buf.resize(size)
+	if l.buf.Free() == 0 {
+		l.events = nil
+	} else {
+		l.events = l.broker.events
+	}
+}
+
+// state reports the events in the ring buffer, including the reserved events
+// not ACKed yet.
+func (l *directEventLoop) state() queue.State {
+	a, b := l.buf.RegionSizes()
+	return makeState(a+b, l.buf.reserved, l.buf.Size(), l.buf.Oldest())
 }
 
 // processACK is used by the ackLoop to process the list of acked batches
@@ -229,59 +277,56 @@ func (l *directEventLoop) processACK(lst chanList, N int) {
 		}()
 	}
 
-	acks := lst.front()
-	start := acks.start
-	states := acks.states
+	// The batches may reference different buffers if the buffer has been
+	// resized, so the states of every batch are processed on their own, from
+	// the most recent event to the oldest.
+	var batches []*ackChan
+	for current := lst.front(); current != nil; current = current.next {
+		batches = append(batches, current)
+	}
 
 	// TODO: global boolean to check if clients will need an ACK
 	//       no need to report ACKs if no client is interested in ACKs
 
-	idx := start + N - 1
-	if idx >= len(states) {
-		idx -= len(states)
-	}
-
 	total := 0
-	for i := N - 1; i >= 0; i-- {
-		if idx < 0 {
-			idx = len(states) - 1
-		}
+	for b := len(batches) - 1; b >= 0; b-- {
+		states := batches[b].states[batches[b].start : batches[b].start+batches[b].count]
+		for idx := len(states) - 1; idx >= 0; idx-- {
+			st := &states[idx]
+			log.Debugf("try ack index: (idx=%v, seq=%v)\n", idx, st.seq)
 
-		st := &states[idx]
-		log.Debugf("try ack index: (idx=%v, i=%v, seq=%v)\n", idx, i, st.seq)
+			if st.state == nil {
+				log.Debug("no state set")
+				continue
+			}
 
-		idx--
-		if st.state == nil {
-			log.Debug("no state set")
-			continue
-		}
+			count := (st.seq - st.state.lastACK)
+			if count == 0 || count > math.MaxUint32/2 {
+				// seq number comparison did underflow. This happens only if st.seq has
+				// already been acknowledged
+				// log.Debug("seq number already acked: ", st.seq)
 
-		count := (st.seq - st.state.lastACK)
-		if count == 0 || count > math.MaxUint32/2 {
-			// seq number comparison did underflow. This happens only if st.seq has
-			// already been acknowledged
-			// log.Debug("seq number already acked: ", st.seq)
+				st.state = nil
+				continue
+			}
 
-			st.state = nil
-			continue
-		}
+			log.Debugf("broker ACK events: count=%v, start-seq=%v, end-seq=%v\n",
+				count,
+				st.state.lastACK+1,
+				st.seq,
+			)
 
-		log.Debugf("broker ACK events: count=%v, start-seq=%v, end-seq=%v\n",
-			count,
-			st.state.lastACK+1,
-			st.seq,
-		)
-
-		total += int(count)
-		if total > N {
-			panic(fmt.Sprintf("Too many events acked (expected=%v, total=%v)",
-				N, total,
-			))
-		}
+			total += int(count)
+			if total > N {
+				panic(fmt.Sprintf("Too many events acked (expected=%v, total=%v)",
+					N, total,
+				))
+			}
 
-		st.state.cb(int(count))
-		st.state.lastACK = st.seq
-		st.state = nil
+			st.state.cb(int(count))
+			st.state.lastACK = st.seq
+			st.state = nil
+		}
 	}
 }
 
@@ -292,9 +337,13 @@ func newBufferingEventLoop(b *broker, size int, minEvents int, flushTimeout time
 		minEvents:    minEvents,
 		flushTimeout: flushTimeout,
 
+		flushMinEvents: minEvents,
+
 		events:    b.events,
 		get:       nil,
 		pubCancel: b.pubCancel,
+		resizes:   b.resizes,
+		inspects:  b.inspects,
 		acks:      b.acks,
 	}
 	l.buf = newBatchBuffer(l.minEvents)
@@ -326,6 +375,12 @@ func (l *bufferingEventLoop) run() {
 		case req := <-l.get: // consumer asking for next batch
 			l.handleConsumer(&req)
 
+		case size := <-l.resizes: // queue size changed at runtime
+			l.handleResize(size)
+
+		case resp := <-l.inspects:
+			resp <- l.state()
+
 		case l.schedACKS <- l.pendingACKs:
 			l.schedACKS = nil
 			l.pendingACKs = chanList{}
@@ -368,8 +423,9 @@ func (l *bufferingEventLoop) handleInsert(req *pushRequest) {
 }
 
 func (l *bufferingEventLoop) insert(req *pushRequest) bool {
+	now := time.Now().UnixNano()
 	if req.state == nil {
-		l.buf.add(req.event, clientState{})
+		l.buf.add(req.event, clientState{inserted: now})
 		return true
 	}
 
@@ -380,8 +436,9 @@ func (l *bufferingEventLoop) insert(req *pushRequest) bool {
 	}
 
 	l.buf.add(req.event, clientState{
-		seq:   req.seq,
-		state: st,
+		seq:      req.seq,
+		state:    st,
+		inserted: now,
 	})
 	return true
 }
@@ -453,6 +510,7 @@ func (l *bufferingEventLoop) handleConsumer(req *getRequest) {
 
 	req.resp <- getResponse{ackChan, events}
 	l.pendingACKs.append(ackChan)
+	l.inFlight = append(l.inFlight, inFlightBatch{inserted: clients[0].inserted, count: count})
 	l.schedACKS = l.broker.scheduledACKs
 
 	buf.events = buf.events[count:]
@@ -463,12 +521,69 @@ func (l *bufferingEventLoop) handleConsumer(req *getRequest) {
 }
 
 func (l *bufferingEventLoop) handleACK(count int) {
+	// the batches are ACKed in order
+	for n := count; n > 0 && len(l.inFlight) > 0; {
+		batch := &l.inFlight[0]
+		if batch.count > n {
+			batch.count -= n
+			break
+		}
+		n -= batch.count
+		l.inFlight = l.inFlight[1:]
+	}
+	if len(l.inFlight) == 0 {
+		l.inFlight = nil
+	}
+
 	l.eventCount -= count
 	if l.eventCount < l.maxEvents {
 		l.events = l.broker.events
 	}
 }
 
+// handleResize changes the maximum number of events. If the queue holds more
+// events than the new maximum, no events are inserted until enough events
+// have been ACKed.
+func (l *bufferingEventLoop) handleResize(size int) {
+	l.maxEvents = size
+	l.minEvents = l.flushMinEvents
+	if l.minEvents > size {
+		l.minEvents = size
+	}
+
+	// flush the current buffer if the lower minEvents has been reached
+	if !l.buf.flushed && l.buf.length() > 0 && l.buf.length() >= l.minEvents {
+		l.stopFlushTimer()
+		l.flushBuffer()
+		l.buf = newBatchBuffer(l.minEvents)
+	}
+
+	if l.eventCount >= l.maxEvents {
+		l.events = nil
+	} else {
+		l.events = l.broker.events
+	}
+}
+
+// state reports the events in the buffers and the batches not ACKed yet.
+func (l *bufferingEventLoop) state() queue.State {
+	inFlight := 0
+	for _, batch := range l.inFlight {
+		inFlight += batch.count
+	}
+
+	var oldest int64
+	switch {
+	case len(l.inFlight) > 0:
+		oldest = l.inFlight[0].inserted
+	case l.flushList.head != nil:
+		oldest = l.flushList.head.clients[0].inserted
+	case l.buf.length() > 0:
+		oldest = l.buf.clients[0].inserted
+	}
+	return makeState(l.eventCount, inFlight, l.maxEvents, oldest)
+}
+
 func (l *bufferingEventLoop) startFlushTimer() {
 	if l.idleC == nil {
 		l.timer.Reset(l.flushTimeout)
@@ -587,5 +702,20 @@ func reportCancelledState(log logger, req *pushRequest) {
 	if cb := st.dropCB; cb != nil {
 		cb(req.event.Content)
 	}
+	req.event.Release()
 
 }
+
+func makeState(events, inFlight, size int, oldest int64) queue.State {
+	state := queue.State{
+		Events: events,
+		Details: common.MapStr{
+			"in_flight":  inFlight,
+			"max_events": size,
+		},
+	}
+	if oldest != 0 {
+		state.Oldest = time.Unix(0, oldest)
+	}
+	return state
+}
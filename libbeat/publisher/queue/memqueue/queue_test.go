@@ -20,9 +20,16 @@ package memqueue
 import (
 	"flag"
 	"math/rand"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue"
 	"github.com/elastic/beats/v7/libbeat/publisher/queue/queuetest"
 )
@@ -82,3 +89,164 @@ func makeTestQueue(sz, minEvents int, flushTimeout time.Duration) queuetest.Queu
 		})
 	}
 }
+
+func TestRingBufferResize(t *testing.T) {
+	var b ringBuffer
+	b.init(nil, 4)
+
+	next, read := 0, 0
+	insert := func(n int) {
+		for i := 0; i < n; i++ {
+			ok, _ := b.insert(publisher.Event{Content: beat.Event{Fields: common.MapStr{"n": next}}}, clientState{})
+			require.True(t, ok)
+			next++
+		}
+	}
+	consume := func(n int) {
+		_, events := b.reserve(n)
+		require.Len(t, events, n)
+		for _, event := range events {
+			assert.Equal(t, read, event.Content.Fields["n"])
+			read++
+		}
+	}
+
+	// fill region A, free its start and fill region B
+	insert(4)
+	consume(2)
+	b.ack(2)
+	insert(2)
+	require.True(t, b.Full())
+	consume(1)
+
+	// growing merges region B into region A
+	b.resize(8)
+	assert.Equal(t, 8, b.Size())
+	assert.False(t, b.RegionBActive())
+	assert.False(t, b.Full())
+	insert(4)
+	assert.True(t, b.Full())
+
+	// shrinking waits for the events to fit in the new size
+	b.resize(4)
+	assert.True(t, b.Full())
+	assert.Equal(t, 0, b.Free())
+	ok, _ := b.insert(publisher.Event{}, clientState{})
+	assert.False(t, ok)
+	b.ack(1)
+	consume(3)
+	b.ack(3)
+	assert.Equal(t, 4, b.Size())
+	assert.True(t, b.Full())
+
+	assert.Equal(t, 0, b.Free())
+	consume(2)
+	b.ack(2)
+	assert.Equal(t, 2, b.Free())
+	insert(2)
+	consume(2)
+	b.ack(2)
+	consume(2)
+	assert.Equal(t, next, read)
+}
+
+func TestResize(t *testing.T) {
+	t.Run("direct", func(t *testing.T) {
+		testResize(t, Settings{Events: 32})
+	})
+	t.Run("flush", func(t *testing.T) {
+		testResize(t, Settings{Events: 32, FlushMinEvents: 16, FlushTimeout: 10 * time.Millisecond})
+	})
+}
+
+// testResize publishes events while the queue is grown and shrunk, all
+// events must be consumed in order and ACKed.
+func testResize(t *testing.T, settings Settings) {
+	const total = 1000
+	settings.WaitOnClose = true
+	q := NewQueue(nil, settings)
+	defer q.Close()
+
+	var (
+		mu    sync.Mutex
+		acked int
+	)
+	producer := q.Producer(queue.ProducerConfig{ACK: func(n int) {
+		mu.Lock()
+		acked += n
+		mu.Unlock()
+	}})
+	go func() {
+		for i := 0; i < total; i++ {
+			producer.Publish(publisher.Event{Content: beat.Event{Fields: common.MapStr{"n": i}}})
+		}
+	}()
+
+	r := q.(queue.Resizer)
+	assert.Error(t, r.Resize(8))
+	sizes := []int{64, 256, 32, 48, 128}
+	consumer := q.Consumer()
+	for read := 0; read < total; {
+		if len(sizes) > 0 && read > (5-len(sizes))*total/5 {
+			require.NoError(t, r.Resize(sizes[0]))
+			assert.Equal(t, sizes[0], q.BufferConfig().MaxEvents)
+			sizes = sizes[1:]
+		}
+
+		batch, err := consumer.Get(20)
+		require.NoError(t, err)
+		for _, event := range batch.Events() {
+			require.Equal(t, read, event.Content.Fields["n"])
+			read++
+		}
+		batch.ACK()
+	}
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return acked == total
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestInspect(t *testing.T) {
+	t.Run("direct", func(t *testing.T) {
+		testInspect(t, Settings{Events: 64})
+	})
+	t.Run("flush", func(t *testing.T) {
+		testInspect(t, Settings{Events: 64, FlushMinEvents: 8, FlushTimeout: 10 * time.Millisecond})
+	})
+}
+
+func testInspect(t *testing.T, settings Settings) {
+	settings.WaitOnClose = true
+	q := NewQueue(nil, settings)
+	defer q.Close()
+	inspector := q.(queue.Inspector)
+
+	state := inspector.Inspect()
+	assert.Equal(t, 0, state.Events)
+	assert.True(t, state.Oldest.IsZero())
+	assert.Equal(t, 64, state.Details["max_events"])
+
+	start := time.Now()
+	producer := q.Producer(queue.ProducerConfig{})
+	for i := 0; i < 10; i++ {
+		require.True(t, producer.Publish(publisher.Event{Content: beat.Event{Fields: common.MapStr{"n": i}}}))
+	}
+	require.Eventually(t, func() bool { return inspector.Inspect().Events == 10 }, time.Second, time.Millisecond)
+
+	batch, err := q.Consumer().Get(4)
+	require.NoError(t, err)
+	require.Len(t, batch.Events(), 4)
+	state = inspector.Inspect()
+	assert.Equal(t, 10, state.Events)
+	assert.Equal(t, 4, state.Details["in_flight"])
+	assert.False(t, state.Oldest.Before(start))
+
+	batch.ACK()
+	require.Eventually(t, func() bool { return inspector.Inspect().Events == 6 }, time.Second, time.Millisecond)
+	state = inspector.Inspect()
+	assert.Equal(t, 0, state.Details["in_flight"])
+	assert.False(t, state.Oldest.IsZero())
+}
@@ -35,6 +35,11 @@ type ringBuffer struct {
 
 	regA, regB region
 	reserved   int // amount of events in region A actively processed/reserved
+
+	// size is the requested size of the buffer. If it differs from the size
+	// of buf, the buffer is being shrunk: no events are inserted until the
+	// events not ACKed yet fit in the new size.
+	size int
 }
 
 type region struct {
@@ -50,8 +55,9 @@ type eventBuffer struct {
 }
 
 type clientState struct {
-	seq   uint32        // event sequence number
-	state *produceState // the producer it's state used to compute and signal the ACK count
+	seq      uint32        // event sequence number
+	state    *produceState // the producer it's state used to compute and signal the ACK count
+	inserted int64         // time the event was inserted, in Unix nanoseconds
 }
 
 func (b *eventBuffer) init(size int) {
@@ -77,11 +83,54 @@ func newRingBuffer(log logger, size int) *ringBuffer {
 }
 
 func (b *ringBuffer) init(log logger, size int) {
-	*b = ringBuffer{}
+	*b = ringBuffer{size: size}
 	b.buf.init(size)
 	b.buf.logger = log
 }
 
+// resize changes the size of the buffer. The events are moved to a buffer of
+// the new size as soon as they fit in it.
+func (b *ringBuffer) resize(size int) {
+	b.size = size
+	b.tryRelocate()
+}
+
+// tryRelocate moves the events to a buffer of the requested size, if it
+// differs from the current one and the events fit in it. Region B is
+// appended to region A in the new buffer.
+// The reserved events are only copied as empty slots: the batches of the
+// consumers and their ACK channels keep referencing the old buffer.
+func (b *ringBuffer) tryRelocate() {
+	used := b.regA.size + b.regB.size
+	if b.size == b.buf.Len() || used > b.size {
+		return
+	}
+
+	var buf eventBuffer
+	buf.init(b.size)
+	buf.logger = b.buf.logger
+
+	// keep the insert times of the reserved events, for reporting the
+	// oldest event of the queue
+	for i := 0; i < b.reserved; i++ {
+		buf.clients[i].inserted = b.buf.clients[b.regA.index+i].inserted
+	}
+
+	n := b.reserved
+	start, end := b.regA.index+b.reserved, b.regA.index+b.regA.size
+	copy(buf.events[n:], b.buf.events[start:end])
+	copy(buf.clients[n:], b.buf.clients[start:end])
+	n += end - start
+
+	start, end = b.regB.index, b.regB.index+b.regB.size
+	copy(buf.events[n:], b.buf.events[start:end])
+	copy(buf.clients[n:], b.buf.clients[start:end])
+
+	b.buf = buf
+	b.regA = region{index: 0, size: used}
+	b.regB = region{}
+}
+
 func (b *ringBuffer) insert(event publisher.Event, client clientState) (bool, int) {
 	// log := b.buf.logger
 	// log.Debug("insert:")
@@ -94,6 +143,11 @@ func (b *ringBuffer) insert(event publisher.Event, client clientState) (bool, in
 	// 	log.Debug("  -> reserved:", b.reserved)
 	// }()
 
+	// no inserts while the buffer is being shrunk
+	if b.size != b.buf.Len() {
+		return false, 0
+	}
+
 	// always insert into region B, if region B exists.
 	// That is, we have 2 regions and region A is currently processed by consumers
 	if b.regB.size > 0 {
@@ -182,6 +236,7 @@ func (b *ringBuffer) cancelRegion(st *produceState, reg region) (removed int) {
 	// filter loop
 	for i := 0; i < reg.size; i++ {
 		if clients[i].state == st {
+			events[i].Release()
 			continue // remove
 		}
 
@@ -273,6 +328,10 @@ func (b *ringBuffer) ack(sz int) {
 		b.regB.index = 0
 		b.regB.size = 0
 	}
+
+	if b.size != b.buf.Len() {
+		b.tryRelocate()
+	}
 }
 
 func (b *ringBuffer) Empty() bool {
@@ -296,6 +355,10 @@ func (b *ringBuffer) TotalAvail() int {
 }
 
 func (b *ringBuffer) Full() bool {
+	if b.size != b.buf.Len() {
+		return true
+	}
+
 	var avail int
 	if b.regB.size > 0 {
 		avail = b.regA.index - b.regB.index - b.regB.size
@@ -305,6 +368,31 @@ func (b *ringBuffer) Full() bool {
 	return avail == 0
 }
 
+// Free returns the number of events that can be inserted, 0 while the buffer
+// is being shrunk.
+func (b *ringBuffer) Free() int {
+	switch {
+	case b.size != b.buf.Len():
+		return 0
+	case b.regB.size > 0:
+		return b.regA.index - b.regB.index - b.regB.size
+	}
+	if avail := b.buf.Len() - b.regA.index - b.regA.size; avail > 0 {
+		return avail
+	}
+	// space for region B
+	return b.regA.index
+}
+
+// Oldest returns the insert time of the oldest event not ACKed yet, or 0 if
+// the buffer is empty.
+func (b *ringBuffer) Oldest() int64 {
+	if b.regA.size == 0 {
+		return 0
+	}
+	return b.buf.clients[b.regA.index].inserted
+}
+
 func (b *ringBuffer) Size() int {
 	return b.buf.Len()
 }
@@ -19,6 +19,7 @@ package queue
 
 import (
 	"io"
+	"time"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
@@ -63,6 +64,37 @@ type BufferConfig struct {
 	MaxEvents int
 }
 
+// Resizer is implemented by the queues whose size can be changed while
+// they are in use.
+type Resizer interface {
+	// Resize sets the maximum number of events the queue can hold. A queue
+	// holding more events than the new size accepts new events again once
+	// enough events have been ACKed.
+	Resize(events int) error
+}
+
+// Inspector is implemented by the queues reporting their internal state, for
+// diagnosing delayed events.
+type Inspector interface {
+	Inspect() State
+}
+
+// State is a snapshot of the state of a queue.
+type State struct {
+	// Events is the number of events in the queue, including the events
+	// being published by the outputs. It's -1 if the queue doesn't count its
+	// events, like the disk queue.
+	Events int
+
+	// Oldest is the time the oldest event in the queue was added to it. It's
+	// zero if the queue is empty or doesn't know.
+	Oldest time.Time
+
+	// Details are the state specific to the queue type, like the segments of
+	// the disk queue.
+	Details common.MapStr
+}
+
 // ProducerConfig as used by the Pipeline to configure some custom callbacks
 // between pipeline and queue.
 type ProducerConfig struct {
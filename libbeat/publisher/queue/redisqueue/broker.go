@@ -0,0 +1,237 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package redisqueue implements a queue storing the events in a Redis
+// stream, read through a consumer group.
+package redisqueue
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/feature"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/brokerqueue"
+)
+
+// eventField is the field of the stream entries holding the event.
+const eventField = "event"
+
+func init() {
+	brokerqueue.Register(
+		"redis",
+		newBroker,
+		feature.MakeDetails(
+			"Redis queue",
+			"Buffer events in a Redis stream before sending to the output.",
+			feature.Experimental))
+}
+
+type streamBroker struct {
+	log    *logp.Logger
+	config config
+	pool   *redis.Pool
+
+	// mu protects the recovery of the pending entries.
+	mu sync.Mutex
+
+	// recovered is set once the entries fetched by this consumer before a
+	// restart have been fetched again, lastPending is the ID of the last of
+	// them fetched so far.
+	recovered   bool
+	lastPending string
+}
+
+func newBroker(log *logp.Logger, cfg *common.Config) (brokerqueue.Broker, error) {
+	config := defaultConfig()
+	if cfg != nil {
+		if err := cfg.Unpack(&config); err != nil {
+			return nil, fmt.Errorf("redis queue: %w", err)
+		}
+	}
+
+	b := &streamBroker{
+		log:         log,
+		config:      config,
+		lastPending: "0",
+	}
+	b.pool = &redis.Pool{
+		Dial:        b.dial,
+		MaxIdle:     3,
+		IdleTimeout: 4 * time.Minute,
+	}
+
+	conn := b.pool.Get()
+	defer conn.Close()
+	_, err := conn.Do("XGROUP", "CREATE", config.Key, config.Group, "0", "MKSTREAM")
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		b.pool.Close()
+		return nil, fmt.Errorf("redis queue: failed to create the consumer group: %w", err)
+	}
+	return b, nil
+}
+
+func (b *streamBroker) dial() (redis.Conn, error) {
+	return redis.Dial("tcp", b.config.Host,
+		redis.DialPassword(b.config.Password),
+		redis.DialDatabase(b.config.DB),
+		redis.DialConnectTimeout(b.config.Timeout),
+		// The blocking reads wait up to the timeout for new entries.
+		redis.DialReadTimeout(2*b.config.Timeout),
+		redis.DialWriteTimeout(b.config.Timeout))
+}
+
+func (b *streamBroker) Push(messages [][]byte) error {
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	for _, data := range messages {
+		if err := conn.Send("XADD", b.config.Key, "*", eventField, data); err != nil {
+			return err
+		}
+	}
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+	for range messages {
+		if _, err := conn.Receive(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *streamBroker) Fetch(n int, timeout time.Duration) ([]brokerqueue.Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	if !b.recovered {
+		reply, err := conn.Do("XREADGROUP", "GROUP", b.config.Group, b.config.Consumer,
+			"COUNT", n, "STREAMS", b.config.Key, b.lastPending)
+		if err != nil {
+			return nil, err
+		}
+		messages, err := parseEntries(reply)
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) > 0 {
+			b.lastPending = messages[len(messages)-1].ID
+			return messages, nil
+		}
+		b.recovered = true
+	}
+
+	if timeout > b.config.Timeout {
+		timeout = b.config.Timeout
+	}
+	reply, err := conn.Do("XREADGROUP", "GROUP", b.config.Group, b.config.Consumer,
+		"COUNT", n, "BLOCK", int64(timeout/time.Millisecond), "STREAMS", b.config.Key, ">")
+	if err != nil {
+		return nil, err
+	}
+	return parseEntries(reply)
+}
+
+// Ack acknowledges the entries and deletes them from the stream.
+func (b *streamBroker) Ack(ids []string) error {
+	conn := b.pool.Get()
+	defer conn.Close()
+
+	ackArgs := make([]interface{}, 0, len(ids)+2)
+	ackArgs = append(ackArgs, b.config.Key, b.config.Group)
+	for _, id := range ids {
+		ackArgs = append(ackArgs, id)
+	}
+	if err := conn.Send("XACK", ackArgs...); err != nil {
+		return err
+	}
+
+	delArgs := append([]interface{}{b.config.Key}, ackArgs[2:]...)
+	_, err := conn.Do("XDEL", delArgs...)
+	return err
+}
+
+func (b *streamBroker) Close() error {
+	return b.pool.Close()
+}
+
+// parseEntries parses the reply of XREADGROUP, an array with one stream
+// holding an array of entries, each one an ID and an array of fields and
+// values. The entries already deleted from the stream have no fields, they
+// are returned without data.
+func parseEntries(reply interface{}) ([]brokerqueue.Message, error) {
+	streams, err := redis.Values(reply, nil)
+	if err == redis.ErrNil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []brokerqueue.Message
+	for _, stream := range streams {
+		nameAndEntries, err := redis.Values(stream, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(nameAndEntries) != 2 {
+			return nil, fmt.Errorf("invalid stream in reply: %v", nameAndEntries)
+		}
+		entries, err := redis.Values(nameAndEntries[1], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			idAndFields, err := redis.Values(entry, nil)
+			if err != nil {
+				return nil, err
+			}
+			if len(idAndFields) != 2 {
+				return nil, fmt.Errorf("invalid entry in reply: %v", idAndFields)
+			}
+			id, err := redis.String(idAndFields[0], nil)
+			if err != nil {
+				return nil, err
+			}
+
+			msg := brokerqueue.Message{ID: id}
+			if idAndFields[1] != nil {
+				fields, err := redis.ByteSlices(idAndFields[1], nil)
+				if err != nil {
+					return nil, err
+				}
+				for i := 0; i+1 < len(fields); i += 2 {
+					if string(fields[i]) == eventField {
+						msg.Data = fields[i+1]
+					}
+				}
+			}
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
@@ -0,0 +1,93 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// +build integration
+
+package redisqueue
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+func TestStreamBroker(t *testing.T) {
+	key := fmt.Sprintf("test-queue-%d", time.Now().UnixNano())
+	cfg := common.MustNewConfigFrom(map[string]interface{}{
+		"host":     getRedisAddr(),
+		"key":      key,
+		"consumer": "test",
+	})
+	defer func() {
+		conn, err := redis.Dial("tcp", getRedisAddr())
+		require.NoError(t, err)
+		defer conn.Close()
+		conn.Do("DEL", key)
+	}()
+
+	broker, err := newBroker(logp.NewLogger("test"), cfg)
+	require.NoError(t, err)
+	require.NoError(t, broker.Push([][]byte{[]byte("a"), []byte("b"), []byte("c")}))
+
+	messages, err := broker.Fetch(2, time.Second)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "a", string(messages[0].Data))
+	require.NoError(t, broker.Ack([]string{messages[0].ID}))
+	require.NoError(t, broker.Close())
+
+	// The entry fetched but not acknowledged is fetched again first.
+	broker, err = newBroker(logp.NewLogger("test"), cfg)
+	require.NoError(t, err)
+	defer broker.Close()
+
+	var data []string
+	for len(data) < 2 {
+		messages, err = broker.Fetch(10, time.Second)
+		require.NoError(t, err)
+		require.NotEmpty(t, messages)
+		for _, msg := range messages {
+			data = append(data, string(msg.Data))
+		}
+	}
+	assert.Equal(t, []string{"b", "c"}, data)
+
+	messages, err = broker.Fetch(10, 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func getRedisAddr() string {
+	return fmt.Sprintf("%v:%v",
+		getEnv("REDIS_HOST", "localhost"),
+		getEnv("REDIS_PORT", "6379"))
+}
+
+func getEnv(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
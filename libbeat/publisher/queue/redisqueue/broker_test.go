@@ -0,0 +1,73 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redisqueue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/publisher/queue/brokerqueue"
+)
+
+func TestParseEntries(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{
+			[]byte("beats-queue"),
+			[]interface{}{
+				[]interface{}{
+					[]byte("1-0"),
+					[]interface{}{[]byte("other"), []byte("x"), []byte(eventField), []byte("data")},
+				},
+				// Deleted entry of the pending entries.
+				[]interface{}{[]byte("2-0"), nil},
+			},
+		},
+	}
+	messages, err := parseEntries(reply)
+	require.NoError(t, err)
+	assert.Equal(t, []brokerqueue.Message{
+		{ID: "1-0", Data: []byte("data")},
+		{ID: "2-0"},
+	}, messages)
+
+	// Blocking read timed out.
+	messages, err = parseEntries(nil)
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	_, err = parseEntries([]interface{}{[]interface{}{[]byte("beats-queue")}})
+	assert.Error(t, err)
+}
+
+func TestConfig(t *testing.T) {
+	config := defaultConfig()
+	assert.Error(t, common.MustNewConfigFrom(map[string]interface{}{
+		"host":  "redis:6379",
+		"group": "",
+	}).Unpack(&config))
+
+	config = defaultConfig()
+	require.NoError(t, common.MustNewConfigFrom(map[string]interface{}{
+		"host":     "redis:6379",
+		"consumer": "beat-1",
+	}).Unpack(&config))
+	assert.Equal(t, "beats-queue", config.Key)
+}
@@ -0,0 +1,70 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package redisqueue
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+type config struct {
+	// Host is the address of the Redis server.
+	Host     string `config:"host"`
+	Password string `config:"password"`
+	DB       int    `config:"db"`
+
+	// Key is the key of the stream storing the events.
+	Key string `config:"key"`
+
+	// Group is the consumer group of the Beats publishing the events of the
+	// stream, and Consumer the name of this Beat in the group. The events
+	// fetched by a consumer are fetched again by the same consumer after a
+	// restart if they were not published.
+	Group    string `config:"group"`
+	Consumer string `config:"consumer"`
+
+	Timeout time.Duration `config:"timeout"`
+}
+
+func defaultConfig() config {
+	consumer, _ := os.Hostname()
+	return config{
+		Host:     "localhost:6379",
+		Key:      "beats-queue",
+		Group:    "beats",
+		Consumer: consumer,
+		Timeout:  5 * time.Second,
+	}
+}
+
+func (c *config) Validate() error {
+	switch {
+	case c.Host == "":
+		return errors.New("queue.redis.host must be set")
+	case c.Key == "":
+		return errors.New("queue.redis.key must be set")
+	case c.Group == "":
+		return errors.New("queue.redis.group must be set")
+	case c.Consumer == "":
+		return errors.New("queue.redis.consumer must be set")
+	case c.Timeout <= 0:
+		return errors.New("queue.redis.timeout must be positive")
+	}
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/intern"
 	"github.com/elastic/beats/v7/libbeat/outputs/codec"
 	"github.com/elastic/beats/v7/libbeat/publisher"
 	"github.com/elastic/go-structform"
@@ -98,8 +99,10 @@ func (e *encoder) reset() {
 
 	folder, err := gotype.NewIterator(visitor,
 		gotype.Folders(
-			codec.MakeTimestampEncoder(),
-			codec.MakeBCTimestampEncoder(),
+			// Timestamps are stored with full precision for outputs
+			// encoding them with a precision finer than milliseconds.
+			codec.MakeTimestampEncoderWithPrecision(false, common.TimestampNanosecond),
+			codec.MakeBCTimestampEncoderWithPrecision(common.TimestampNanosecond),
 		),
 	)
 	if err != nil {
@@ -187,6 +190,9 @@ func (d *decoder) Decode() (publisher.Event, error) {
 		return publisher.Event{}, err
 	}
 
+	// Share the field names and low cardinality values between the decoded events.
+	intern.Default.MapStr(to.Fields)
+
 	var flags publisher.EventFlags
 	if (to.Flags & flagGuaranteed) != 0 {
 		flags |= publisher.GuaranteedSend
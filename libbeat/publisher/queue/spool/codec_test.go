@@ -35,7 +35,7 @@ func TestEncodeDecode(t *testing.T) {
 		"cborl":  codecCBORL,
 	}
 
-	fieldTimeStr := "2020-01-14T20:33:23.779Z"
+	fieldTimeStr := "2020-01-14T20:33:23.779123456Z"
 	fieldTime, _ := time.Parse(time.RFC3339Nano, fieldTimeStr)
 	event := publisher.Event{
 		Content: beat.Event{
@@ -50,8 +50,8 @@ func TestEncodeDecode(t *testing.T) {
 		Content: beat.Event{
 			Timestamp: event.Content.Timestamp,
 			Fields: common.MapStr{
-				"time":       fieldTime.Format(time.RFC3339Nano),
-				"commontime": common.Time(fieldTime).String(),
+				"time":       fieldTimeStr,
+				"commontime": fieldTimeStr,
 			},
 		},
 	}
@@ -489,6 +489,7 @@ func (b *inBroker) encodeEvent(req *pushRequest) ([]byte, clientState, error) {
 	if err != nil {
 		return nil, clientState{}, err
 	}
+	req.event.Release()
 
 	if req.state == nil {
 		return buf, clientState{}, nil
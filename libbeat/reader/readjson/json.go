@@ -25,6 +25,7 @@ import (
 
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/intern"
 	"github.com/elastic/beats/v7/libbeat/common/jsontransform"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/reader"
@@ -51,7 +52,12 @@ func NewJSONReader(r reader.Reader, cfg *Config) *JSONReader {
 func (r *JSONReader) decode(text []byte) ([]byte, common.MapStr) {
 	var jsonFields map[string]interface{}
 
-	err := unmarshal(text, &jsonFields)
+	var err error
+	if r.cfg.Lazy {
+		err = unmarshalLazy(text, &jsonFields)
+	} else {
+		err = unmarshal(text, &jsonFields)
+	}
 	if err != nil || jsonFields == nil {
 		if !r.cfg.IgnoreDecodingError {
 			r.logger.Errorf("Error decoding JSON: %v", err)
@@ -95,6 +101,28 @@ func unmarshal(text []byte, fields *map[string]interface{}) error {
 		return err
 	}
 	jsontransform.TransformNumbers(*fields)
+	intern.Default.MapStr(*fields)
+	return nil
+}
+
+// unmarshalLazy is like unmarshal, but it only decodes the first level of the
+// object. Nested objects are kept as common.RawJSON, and are decoded only if
+// their fields are accessed. @metadata is always decoded, as it is merged into
+// the metadata of the event.
+func unmarshalLazy(text []byte, fields *map[string]interface{}) error {
+	m, err := common.RawJSON(text).Decode()
+	if err != nil {
+		return err
+	}
+	if raw, ok := m["@metadata"].(common.RawJSON); ok {
+		var meta map[string]interface{}
+		if err := unmarshal(raw, &meta); err != nil {
+			return err
+		}
+		m["@metadata"] = meta
+	}
+	intern.Default.MapStr(m)
+	*fields = m
 	return nil
 }
 
@@ -25,6 +25,7 @@ type Config struct {
 	OverwriteKeys       bool   `config:"overwrite_keys"`
 	AddErrorKey         bool   `config:"add_error_key"`
 	IgnoreDecodingError bool   `config:"ignore_decoding_error"`
+	Lazy                bool   `config:"lazy"`
 }
 
 // Validate validates the Config option for JSON reader.
@@ -194,6 +194,26 @@ func TestDecodeJSON(t *testing.T) {
 	}
 }
 
+func TestDecodeJSONLazy(t *testing.T) {
+	var p JSONReader
+	p.cfg = &Config{MessageKey: "message", Lazy: true}
+	p.logger = logp.NewLogger("json_test")
+
+	text, fields := p.decode([]byte(`{"message": "test", "value": 1, "list": [{"a": 2}], "nested": {"key": "value", "n": 1.5}, "@metadata": {"_id": "abc"}}`))
+	assert.Equal(t, "test", string(text))
+	assert.Equal(t, "test", fields["message"])
+	assert.Equal(t, int64(1), fields["value"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"a": int64(2)}}, fields["list"])
+	assert.Equal(t, common.RawJSON(`{"key": "value", "n": 1.5}`), fields["nested"])
+	assert.Equal(t, map[string]interface{}{"_id": "abc"}, fields["@metadata"])
+
+	// The nested object is decoded once it's accessed.
+	v, err := common.MapStr(fields).GetValue("nested.n")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, v)
+	assert.Equal(t, common.MapStr{"key": "value", "n": 1.5}, fields["nested"])
+}
+
 func TestAddJSONFields(t *testing.T) {
 	type io struct {
 	}
@@ -0,0 +1,129 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bolt
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend"
+)
+
+// Registry configures access to bolt based stores.
+type Registry struct {
+	log *logp.Logger
+
+	mu     sync.Mutex
+	active bool
+
+	settings Settings
+
+	wg sync.WaitGroup
+}
+
+// Settings configures a new Registry.
+type Settings struct {
+	// Registry root directory. Stores will be single database files.
+	Root string
+
+	// FileMode is used to configure the file mode for new files generated by the
+	// registry.  File mode 0600 will be used if this field is not set.
+	FileMode os.FileMode
+
+	// Timeout is the time to wait for the lock of a database file held by
+	// another process. Defaults to 1s if not set.
+	Timeout time.Duration
+}
+
+// fileExtension is the extension of the database files in the root directory.
+const fileExtension = ".db"
+
+const defaultFileMode os.FileMode = 0600
+
+const defaultTimeout = 1 * time.Second
+
+var errRegClosed = errors.New("registry has been closed")
+
+// New configures a bolt Registry that can be used to open stores.
+func New(log *logp.Logger, settings Settings) (*Registry, error) {
+	if settings.FileMode == 0 {
+		settings.FileMode = defaultFileMode
+	}
+	if settings.Timeout == 0 {
+		settings.Timeout = defaultTimeout
+	}
+
+	root, err := filepath.Abs(settings.Root)
+	if err != nil {
+		return nil, err
+	}
+
+	settings.Root = root
+	return &Registry{
+		log:      log,
+		active:   true,
+		settings: settings,
+	}, nil
+}
+
+// StorePath returns the path of the database file of a store.
+func StorePath(root, name string) string {
+	return filepath.Join(root, name+fileExtension)
+}
+
+// Access creates or opens a store. The root directory and the database file
+// are created if they don't exist.
+func (r *Registry) Access(name string) (backend.Store, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.active {
+		return nil, errRegClosed
+	}
+
+	if err := os.MkdirAll(r.settings.Root, os.ModeDir|0770); err != nil {
+		return nil, err
+	}
+
+	path := StorePath(r.settings.Root, name)
+	store, err := openStore(path, r.settings.FileMode, r.settings.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	r.log.Debugf("Opened store %v in %v", name, path)
+
+	r.wg.Add(1)
+	store.onClose = r.wg.Done
+	return store, nil
+}
+
+// Close closes the registry. No new store can be accessed during close.
+// Close blocks until all stores have been closed.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	r.active = false
+	r.mu.Unlock()
+
+	// block until all stores have been closed
+	r.wg.Wait()
+	return nil
+}
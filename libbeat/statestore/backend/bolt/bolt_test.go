@@ -0,0 +1,152 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bolt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.etcd.io/bbolt"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend"
+	"github.com/elastic/beats/v7/libbeat/statestore/internal/storecompliance"
+)
+
+func init() {
+	logp.DevelopmentSetup()
+}
+
+func TestCompliance(t *testing.T) {
+	storecompliance.TestBackendCompliance(t, func(testPath string) (backend.Registry, error) {
+		return New(logp.NewLogger("test"), Settings{Root: testPath})
+	})
+}
+
+func TestStoreFile(t *testing.T) {
+	dir := tempDir(t)
+	reg, err := New(logp.NewLogger("test"), Settings{Root: dir})
+	require.NoError(t, err)
+	store, err := reg.Access("test")
+	require.NoError(t, err)
+	require.NoError(t, store.Set("key", map[string]interface{}{"offset": 10}))
+	require.NoError(t, store.Close())
+	require.NoError(t, reg.Close())
+
+	info, err := os.Stat(StorePath(dir, "test"))
+	require.NoError(t, err)
+	if os.PathSeparator == '/' {
+		assert.Equal(t, defaultFileMode, info.Mode())
+	}
+
+	_, err = reg.Access("test")
+	assert.Equal(t, errRegClosed, err)
+}
+
+func TestRepair(t *testing.T) {
+	dir := tempDir(t)
+	reg, err := New(logp.NewLogger("test"), Settings{Root: dir})
+	require.NoError(t, err)
+	defer reg.Close()
+
+	st, err := reg.Access("test")
+	require.NoError(t, err)
+	require.NoError(t, st.Set("a", map[string]interface{}{"offset": 1}))
+	require.NoError(t, st.(*store).db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketName).Put([]byte("b"), []byte("{"))
+	}))
+	require.NoError(t, st.Close())
+
+	problems, err := reg.Verify("test")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invalid value of key b"}, problems)
+
+	n, err := reg.Repair("test")
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.FileExists(t, StorePath(dir, "test")+corruptedSuffix)
+
+	problems, err = reg.Verify("test")
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestRepairInvalidFile(t *testing.T) {
+	dir := tempDir(t)
+	reg, err := New(logp.NewLogger("test"), Settings{Root: dir})
+	require.NoError(t, err)
+	defer reg.Close()
+
+	require.NoError(t, ioutil.WriteFile(StorePath(dir, "test"), []byte("not a database"), 0600))
+	problems, err := reg.Verify("test")
+	require.NoError(t, err)
+	assert.Len(t, problems, 1)
+
+	n, err := reg.Repair("test")
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	store, err := reg.Access("test")
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+}
+
+func TestEachUpdatesStore(t *testing.T) {
+	reg, err := New(logp.NewLogger("test"), Settings{Root: tempDir(t)})
+	require.NoError(t, err)
+	defer reg.Close()
+	store, err := reg.Access("test")
+	require.NoError(t, err)
+	defer store.Close()
+
+	// Use more entries than read in a single transaction.
+	n := 2*eachBatchSize + 10
+	for i := 0; i < n; i++ {
+		require.NoError(t, store.Set(fmt.Sprintf("key%05d", i), map[string]interface{}{"i": i}))
+	}
+
+	seen := 0
+	err = store.Each(func(key string, dec backend.ValueDecoder) (bool, error) {
+		var value struct{ I int }
+		if err := dec.Decode(&value); err != nil {
+			return false, err
+		}
+		assert.Equal(t, fmt.Sprintf("key%05d", value.I), key)
+		seen++
+		return true, store.Remove(key)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, n, seen)
+
+	err = store.Each(func(key string, _ backend.ValueDecoder) (bool, error) {
+		t.Errorf("unexpected key %v", key)
+		return true, nil
+	})
+	assert.NoError(t, err)
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "bolt-test")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
@@ -0,0 +1,28 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package bolt implements a statestore backend based on bbolt.
+//
+// Each store is a single bolt database file named after the store in the
+// registry root directory. All key-value pairs are stored in one bucket, with
+// the values encoded as JSON documents. Every update operation is written in
+// its own transaction, which is synced to disk before the operation returns.
+// In comparison to memlog, the states are not held in memory and the store
+// never needs to rewrite all states at once, which keeps the memory usage and
+// the IO low for registries with many entries. A crash can not leave a partial
+// update behind, as bolt only applies complete transactions.
+package bolt
@@ -0,0 +1,131 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// corruptedSuffix is appended to the name of a database file found to be
+// corrupted by Repair. The file is kept for inspection.
+const corruptedSuffix = ".corrupted"
+
+// Verify checks the consistency of the database file of a store, and that
+// all values can be decoded. It returns a description of every problem found.
+// The store must not be accessed while it is verified.
+func (r *Registry) Verify(name string) ([]string, error) {
+	path := StorePath(r.settings.Root, name)
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, r.settings.FileMode, &bolt.Options{Timeout: r.settings.Timeout, ReadOnly: true})
+	if err != nil {
+		return []string{fmt.Sprintf("%v: %v", path, err)}, nil
+	}
+	defer db.Close()
+
+	var problems []string
+	err = db.View(func(tx *bolt.Tx) error {
+		for err := range tx.Check() {
+			problems = append(problems, fmt.Sprintf("%v: %v", path, err))
+		}
+
+		bucket := tx.Bucket(bucketName)
+		if bucket == nil {
+			problems = append(problems, fmt.Sprintf("%v: bucket %s not found", path, bucketName))
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if !json.Valid(v) {
+				problems = append(problems, fmt.Sprintf("invalid value of key %s", k))
+			}
+			return nil
+		})
+	})
+	return problems, err
+}
+
+// Repair copies all valid key-value pairs of a store into a new database
+// file, which replaces the current one. The current file is kept with the
+// .corrupted suffix. If the database can not be opened at all, the store is
+// reset. It returns the number of states in the repaired store. The store must
+// not be accessed while it is repaired.
+func (r *Registry) Repair(name string) (int, error) {
+	path := StorePath(r.settings.Root, name)
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.RemoveAll(tmpPath); err != nil {
+		return 0, err
+	}
+	dst, err := openStore(tmpPath, r.settings.FileMode, r.settings.Timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := r.copyValid(path, dst)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return 0, err
+	}
+
+	if err := os.Rename(path, path+corruptedSuffix); err != nil {
+		return 0, err
+	}
+	return n, os.Rename(tmpPath, path)
+}
+
+// copyValid copies the key-value pairs with valid values from the database at
+// path to dst.
+func (r *Registry) copyValid(path string, dst *store) (int, error) {
+	src, err := bolt.Open(path, r.settings.FileMode, &bolt.Options{Timeout: r.settings.Timeout, ReadOnly: true})
+	if err != nil {
+		r.log.Warnf("Failed to open %v, the states can not be recovered: %v", path, err)
+		return 0, nil
+	}
+	defer src.Close()
+
+	n := 0
+	err = dst.db.Update(func(dstTx *bolt.Tx) error {
+		return src.View(func(tx *bolt.Tx) error {
+			bucket := tx.Bucket(bucketName)
+			if bucket == nil {
+				return nil
+			}
+			return bucket.ForEach(func(k, v []byte) error {
+				if !json.Valid(v) {
+					r.log.Warnf("Dropping invalid value of key %s", k)
+					return nil
+				}
+				n++
+				return dstTx.Bucket(bucketName).Put(k, v)
+			})
+		})
+	})
+	return n, err
+}
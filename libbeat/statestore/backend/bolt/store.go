@@ -0,0 +1,185 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package bolt
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/transform/typeconv"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend"
+)
+
+// store is a key-value store backed by a bolt database.
+type store struct {
+	db      *bolt.DB
+	onClose func()
+	once    sync.Once
+}
+
+type entry struct {
+	value []byte
+}
+
+// eachBatchSize is the maximum number of key-value pairs read in a single
+// transaction by Each.
+const eachBatchSize = 1000
+
+var bucketName = []byte("states")
+
+var errKeyUnknown = errors.New("key unknown")
+
+func openStore(path string, mode os.FileMode, timeout time.Duration) (*store, error) {
+	db, err := bolt.Open(path, mode, &bolt.Options{Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &store{db: db}, nil
+}
+
+// Close closes the database file.
+func (s *store) Close() error {
+	err := s.db.Close()
+	s.once.Do(func() {
+		if s.onClose != nil {
+			s.onClose()
+		}
+	})
+	return err
+}
+
+// Has checks if the key is known.
+func (s *store) Has(key string) (bool, error) {
+	var exists bool
+	err := s.db.View(func(tx *bolt.Tx) error {
+		exists = tx.Bucket(bucketName).Get([]byte(key)) != nil
+		return nil
+	})
+	return exists, err
+}
+
+// Get retrieves and decodes the key-value pair into to.
+func (s *store) Get(key string, to interface{}) error {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// The value is only valid during the transaction, it must be copied.
+		if v := tx.Bucket(bucketName).Get([]byte(key)); v != nil {
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		return errKeyUnknown
+	}
+	return entry{value: value}.Decode(to)
+}
+
+// Set inserts or overwrites a key-value pair. The value is normalized like in
+// the memlog store, before being encoded.
+func (s *store) Set(key string, value interface{}) error {
+	var tmp common.MapStr
+	if err := typeconv.Convert(&tmp, value); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(tmp)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put([]byte(key), encoded)
+	})
+}
+
+// Remove removes a key from the store. The operation does not check if the
+// key exists.
+func (s *store) Remove(key string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Delete([]byte(key))
+	})
+}
+
+// Each iterates over all key-value pairs in the store, in key order. The pairs
+// are read in batches, and fn is called outside of the read transaction, so
+// that it can update the store.
+func (s *store) Each(fn func(string, backend.ValueDecoder) (bool, error)) error {
+	var (
+		keys    []string
+		entries []entry
+		last    []byte
+	)
+	for {
+		keys, entries = keys[:0], entries[:0]
+		err := s.db.View(func(tx *bolt.Tx) error {
+			c := tx.Bucket(bucketName).Cursor()
+			k, v := c.First()
+			if last != nil {
+				k, v = c.Seek(last)
+				if k != nil && string(k) == string(last) {
+					k, v = c.Next()
+				}
+			}
+			for ; k != nil && len(keys) < eachBatchSize; k, v = c.Next() {
+				keys = append(keys, string(k))
+				entries = append(entries, entry{value: append([]byte{}, v...)})
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+
+		for i, key := range keys {
+			cont, err := fn(key, entries[i])
+			if !cont || err != nil {
+				return err
+			}
+		}
+		last = []byte(keys[len(keys)-1])
+	}
+}
+
+func (e entry) Decode(to interface{}) error {
+	var value common.MapStr
+	if err := json.Unmarshal(e.value, &value); err != nil {
+		return err
+	}
+	return typeconv.Convert(to, value)
+}
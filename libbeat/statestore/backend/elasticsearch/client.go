@@ -0,0 +1,241 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// client sends the requests of the backend to Elasticsearch.
+type client struct {
+	// mu serializes the requests, as the connection is not thread safe.
+	mu    sync.Mutex
+	conn  Connection
+	index string
+}
+
+// document is the source of the documents stored in the index.
+type document struct {
+	Type      string        `json:"type"`
+	Store     string        `json:"store"`
+	Key       string        `json:"key,omitempty"`
+	Value     common.MapStr `json:"value,omitempty"`
+	Owner     string        `json:"owner,omitempty"`
+	ExpiresAt *time.Time    `json:"expires_at,omitempty"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// version of a document, used for optimistic concurrency control.
+type version struct {
+	SeqNo       int64 `json:"_seq_no"`
+	PrimaryTerm int64 `json:"_primary_term"`
+}
+
+const (
+	docTypeState = "state"
+	docTypeLease = "lease"
+)
+
+// searchPageSize is the number of states read by a single search request.
+const searchPageSize = 1000
+
+var indexMapping = common.MapStr{
+	"settings": common.MapStr{
+		"number_of_shards":     1,
+		"auto_expand_replicas": "0-1",
+	},
+	"mappings": common.MapStr{
+		"dynamic": false,
+		"properties": common.MapStr{
+			"type":       common.MapStr{"type": "keyword"},
+			"store":      common.MapStr{"type": "keyword"},
+			"key":        common.MapStr{"type": "keyword"},
+			"owner":      common.MapStr{"type": "keyword"},
+			"expires_at": common.MapStr{"type": "date"},
+			"updated_at": common.MapStr{"type": "date"},
+			"value":      common.MapStr{"type": "object", "enabled": false},
+		},
+	},
+}
+
+func stateID(store, key string) string {
+	return docTypeState + "::" + store + "::" + key
+}
+
+func leaseID(store string) string {
+	return docTypeLease + "::" + store
+}
+
+func (c *client) request(method, path string, params map[string]string, body interface{}) (int, []byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.Request(method, path, "", params, body)
+}
+
+func (c *client) docPath(id string) string {
+	return "/" + c.index + "/_doc/" + url.PathEscape(id)
+}
+
+// ensureIndex creates the index if it doesn't exist yet.
+func (c *client) ensureIndex() error {
+	status, _, err := c.request(http.MethodHead, "/"+c.index, nil, nil)
+	if status == http.StatusOK {
+		return nil
+	}
+	if status != http.StatusNotFound {
+		return fmt.Errorf("failed to check index %v: %v", c.index, err)
+	}
+
+	status, resp, err := c.request(http.MethodPut, "/"+c.index, nil, indexMapping)
+	if status == http.StatusBadRequest && bytes.Contains(resp, []byte("resource_already_exists_exception")) {
+		// Created concurrently by another instance.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create index %v: %v", c.index, err)
+	}
+	return nil
+}
+
+// refresh makes the recent updates of other instances visible to searches.
+func (c *client) refresh() error {
+	_, _, err := c.request(http.MethodPost, "/"+c.index+"/_refresh", nil, nil)
+	return err
+}
+
+// get reads a document, found is false if the document doesn't exist.
+func (c *client) get(id string) (doc document, v version, found bool, err error) {
+	status, resp, err := c.request(http.MethodGet, c.docPath(id), nil, nil)
+	if status == http.StatusNotFound {
+		return doc, v, false, nil
+	}
+	if err != nil {
+		return doc, v, false, err
+	}
+
+	var result struct {
+		version
+		Source document `json:"_source"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return doc, v, false, fmt.Errorf("failed to parse document %v: %v", id, err)
+	}
+	return result.Source, result.version, true, nil
+}
+
+// put writes a document. The document must not exist if prev is nil,
+// otherwise its current version must be prev. errConflict is returned if the
+// document has been modified in the meantime.
+func (c *client) put(id string, doc document, prev *version) (version, error) {
+	params := map[string]string{"op_type": "create"}
+	if prev != nil {
+		params = prev.params()
+	}
+
+	status, resp, err := c.request(http.MethodPut, c.docPath(id), params, doc)
+	if status == http.StatusConflict {
+		return version{}, errConflict
+	}
+	if err != nil {
+		return version{}, err
+	}
+
+	var v version
+	if err := json.Unmarshal(resp, &v); err != nil {
+		return version{}, fmt.Errorf("failed to parse response: %v", err)
+	}
+	return v, nil
+}
+
+// delete removes a document if its current version is v.
+func (c *client) delete(id string, v version) error {
+	status, _, err := c.request(http.MethodDelete, c.docPath(id), v.params(), nil)
+	switch status {
+	case http.StatusNotFound:
+		return nil
+	case http.StatusConflict:
+		return errConflict
+	}
+	return err
+}
+
+// eachState calls fn for all states of the store, in key order.
+func (c *client) eachState(store string, fn func(document, version)) error {
+	var searchAfter []interface{}
+	for {
+		body := common.MapStr{
+			"size":                searchPageSize,
+			"seq_no_primary_term": true,
+			"query": common.MapStr{
+				"bool": common.MapStr{
+					"filter": []common.MapStr{
+						{"term": common.MapStr{"type": docTypeState}},
+						{"term": common.MapStr{"store": store}},
+					},
+				},
+			},
+			"sort": []common.MapStr{{"key": "asc"}},
+		}
+		if searchAfter != nil {
+			body["search_after"] = searchAfter
+		}
+
+		_, resp, err := c.request(http.MethodPost, "/"+c.index+"/_search", nil, body)
+		if err != nil {
+			return err
+		}
+
+		var result struct {
+			Hits struct {
+				Hits []struct {
+					version
+					Source document      `json:"_source"`
+					Sort   []interface{} `json:"sort"`
+				} `json:"hits"`
+			} `json:"hits"`
+		}
+		if err := json.Unmarshal(resp, &result); err != nil {
+			return fmt.Errorf("failed to parse search response: %v", err)
+		}
+
+		hits := result.Hits.Hits
+		for _, hit := range hits {
+			fn(hit.Source, hit.version)
+		}
+		if len(hits) < searchPageSize {
+			return nil
+		}
+		searchAfter = hits[len(hits)-1].Sort
+	}
+}
+
+func (v version) params() map[string]string {
+	return map[string]string{
+		"if_seq_no":       strconv.FormatInt(v.SeqNo, 10),
+		"if_primary_term": strconv.FormatInt(v.PrimaryTerm, 10),
+	}
+}
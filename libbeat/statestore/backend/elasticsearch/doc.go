@@ -0,0 +1,34 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+// Package elasticsearch implements a statestore backend that keeps the states
+// in an Elasticsearch index, so that multiple Beats instances can share them.
+//
+// The backend is meant for active/standby pairs of Beats reading the same
+// shared storage. A store can only be accessed by one instance at a time: the
+// instance that holds the lease of the store. The lease is a document in the
+// index that is renewed periodically by its owner. When the lease expires,
+// because the active instance stopped or lost the connection, the standby
+// instance acquires it and continues from the last states written.
+//
+// All states of a store are loaded into memory when the store is accessed.
+// Updates are written with optimistic concurrency control, using the sequence
+// number and primary term of the last version of the document known to the
+// instance. An update fails if the document has been modified by another
+// instance, or if the lease has been lost, so that an instance that is no
+// longer active can not overwrite the states written by its peer.
+package elasticsearch
@@ -0,0 +1,349 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend"
+	"github.com/elastic/beats/v7/libbeat/statestore/internal/storecompliance"
+)
+
+func init() {
+	logp.DevelopmentSetup()
+}
+
+func TestCompliance(t *testing.T) {
+	var mu sync.Mutex
+	clusters := map[string]*fakeES{}
+	storecompliance.TestBackendCompliance(t, func(testPath string) (backend.Registry, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		es := clusters[testPath]
+		if es == nil {
+			es = newFakeES()
+			clusters[testPath] = es
+		}
+		return New(logp.NewLogger("test"), es, Settings{Index: "test-registry", Owner: "test"})
+	})
+}
+
+func TestFailover(t *testing.T) {
+	es := newFakeES()
+	active := newTestRegistry(t, es, "active")
+	standby := newTestRegistry(t, es, "standby")
+
+	store, err := active.Access("filebeat")
+	require.NoError(t, err)
+	require.NoError(t, store.Set("key", map[string]interface{}{"offset": 10}))
+
+	accessed := make(chan backend.Store)
+	go func() {
+		s, err := standby.Access("filebeat")
+		assert.NoError(t, err)
+		accessed <- s
+	}()
+
+	select {
+	case <-accessed:
+		t.Fatal("store accessed while the lease is held by another instance")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, store.Set("key", map[string]interface{}{"offset": 20}))
+	require.NoError(t, store.Close())
+
+	var standbyStore backend.Store
+	select {
+	case standbyStore = <-accessed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lease not acquired after the store was closed")
+	}
+	defer standbyStore.Close()
+
+	var state struct{ Offset int }
+	require.NoError(t, standbyStore.Get("key", &state))
+	assert.Equal(t, 20, state.Offset)
+}
+
+func TestExpiredLease(t *testing.T) {
+	es := newFakeES()
+	expired := time.Now().Add(-time.Minute)
+	es.setDoc(leaseID("filebeat"), document{Type: docTypeLease, Store: "filebeat", Owner: "crashed", ExpiresAt: &expired})
+
+	reg := newTestRegistry(t, es, "standby")
+	store, err := reg.Access("filebeat")
+	require.NoError(t, err)
+	defer store.Close()
+
+	doc, _, _ := es.getDoc(leaseID("filebeat"))
+	assert.Equal(t, "standby", doc.Owner)
+}
+
+func TestLostLease(t *testing.T) {
+	es := newFakeES()
+	reg := newTestRegistry(t, es, "active")
+	store, err := reg.Access("filebeat")
+	require.NoError(t, err)
+	defer store.Close()
+
+	// Another instance took the lease over, for example after a network
+	// partition that prevented the lease from being renewed.
+	expires := time.Now().Add(time.Hour)
+	es.setDoc(leaseID("filebeat"), document{Type: docTypeLease, Store: "filebeat", Owner: "other", ExpiresAt: &expires})
+
+	assert.Eventually(t, func() bool {
+		return store.Set("key", map[string]interface{}{"offset": 10}) == errLeaseLost
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, errLeaseLost, store.Remove("key"))
+}
+
+func TestConflict(t *testing.T) {
+	es := newFakeES()
+	reg := newTestRegistry(t, es, "active")
+	store, err := reg.Access("filebeat")
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.Set("key", map[string]interface{}{"offset": 10}))
+	es.setDoc(stateID("filebeat", "key"), document{Type: docTypeState, Store: "filebeat", Key: "key"})
+	assert.Equal(t, errConflict, store.Set("key", map[string]interface{}{"offset": 20}))
+}
+
+func TestLoadStates(t *testing.T) {
+	es := newFakeES()
+	n := searchPageSize + 10
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key%05d", i)
+		es.setDoc(stateID("filebeat", key), document{Type: docTypeState, Store: "filebeat", Key: key})
+	}
+	es.setDoc(stateID("other", "key"), document{Type: docTypeState, Store: "other", Key: "key"})
+
+	reg := newTestRegistry(t, es, "active")
+	store, err := reg.Access("filebeat")
+	require.NoError(t, err)
+	defer store.Close()
+
+	count := 0
+	require.NoError(t, store.Each(func(string, backend.ValueDecoder) (bool, error) {
+		count++
+		return true, nil
+	}))
+	assert.Equal(t, n, count)
+}
+
+func TestCloseStopsAccess(t *testing.T) {
+	es := newFakeES()
+	expires := time.Now().Add(time.Hour)
+	es.setDoc(leaseID("filebeat"), document{Type: docTypeLease, Store: "filebeat", Owner: "other", ExpiresAt: &expires})
+
+	reg, err := New(logp.NewLogger("test"), es, Settings{Index: "test-registry", Owner: "standby"})
+	require.NoError(t, err)
+
+	errC := make(chan error)
+	go func() {
+		_, err := reg.Access("filebeat")
+		errC <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, reg.Close())
+	assert.Equal(t, errRegClosed, <-errC)
+}
+
+func newTestRegistry(t *testing.T, es *fakeES, owner string) *Registry {
+	reg, err := New(logp.NewLogger(owner), es, Settings{
+		Index:         "test-registry",
+		Owner:         owner,
+		LeaseDuration: 300 * time.Millisecond,
+		RetryInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { reg.Close() })
+	return reg
+}
+
+// fakeES implements the subset of the Elasticsearch API used by the backend.
+type fakeES struct {
+	mu      sync.Mutex
+	created bool
+	seqNo   int64
+	docs    map[string]fakeDoc
+}
+
+type fakeDoc struct {
+	source json.RawMessage
+	seqNo  int64
+}
+
+func newFakeES() *fakeES {
+	return &fakeES{docs: map[string]fakeDoc{}}
+}
+
+func (es *fakeES) setDoc(id string, doc document) {
+	source, _ := json.Marshal(doc)
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	es.seqNo++
+	es.docs[id] = fakeDoc{source: source, seqNo: es.seqNo}
+}
+
+func (es *fakeES) getDoc(id string) (document, int64, bool) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	d, found := es.docs[id]
+	var doc document
+	json.Unmarshal(d.source, &doc)
+	return doc, d.seqNo, found
+}
+
+func (es *fakeES) Request(method, path string, _ string, params map[string]string, body interface{}) (int, []byte, error) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	status, resp := es.handle(method, path, params, body)
+	if status >= 300 {
+		return status, resp, fmt.Errorf("%d: %s", status, resp)
+	}
+	return status, resp, nil
+}
+
+func (es *fakeES) handle(method, path string, params map[string]string, body interface{}) (int, []byte) {
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	switch {
+	case len(parts) == 1 && method == http.MethodHead:
+		if es.created {
+			return http.StatusOK, nil
+		}
+		return http.StatusNotFound, nil
+	case len(parts) == 1 && method == http.MethodPut:
+		es.created = true
+		return http.StatusOK, []byte(`{"acknowledged":true}`)
+	case len(parts) == 2 && parts[1] == "_refresh":
+		return http.StatusOK, []byte(`{}`)
+	case len(parts) == 2 && parts[1] == "_search":
+		return es.search(body)
+	case len(parts) == 3 && parts[1] == "_doc":
+		id, err := url.PathUnescape(parts[2])
+		if err != nil {
+			return http.StatusBadRequest, []byte(err.Error())
+		}
+		return es.handleDoc(method, id, params, body)
+	}
+	return http.StatusBadRequest, []byte("unsupported request " + method + " " + path)
+}
+
+func (es *fakeES) handleDoc(method, id string, params map[string]string, body interface{}) (int, []byte) {
+	doc, exists := es.docs[id]
+	if method == http.MethodGet {
+		if !exists {
+			return http.StatusNotFound, []byte(`{"found":false}`)
+		}
+		return http.StatusOK, []byte(fmt.Sprintf(`{"found":true,"_seq_no":%d,"_primary_term":1,"_source":%s}`, doc.seqNo, doc.source))
+	}
+
+	if params["op_type"] == "create" && exists {
+		return http.StatusConflict, []byte(`{"error":"version_conflict_engine_exception"}`)
+	}
+	if seqNo, ok := params["if_seq_no"]; ok {
+		if !exists {
+			if method == http.MethodDelete {
+				return http.StatusNotFound, nil
+			}
+			return http.StatusConflict, []byte(`{"error":"version_conflict_engine_exception"}`)
+		}
+		if seqNo != strconv.FormatInt(doc.seqNo, 10) || params["if_primary_term"] != "1" {
+			return http.StatusConflict, []byte(`{"error":"version_conflict_engine_exception"}`)
+		}
+	}
+
+	switch method {
+	case http.MethodDelete:
+		delete(es.docs, id)
+		return http.StatusOK, []byte(`{"result":"deleted"}`)
+	case http.MethodPut:
+		source, err := json.Marshal(body)
+		if err != nil {
+			return http.StatusBadRequest, []byte(err.Error())
+		}
+		es.seqNo++
+		es.docs[id] = fakeDoc{source: source, seqNo: es.seqNo}
+		return http.StatusOK, []byte(fmt.Sprintf(`{"_seq_no":%d,"_primary_term":1}`, es.seqNo))
+	}
+	return http.StatusBadRequest, []byte("unsupported method " + method)
+}
+
+func (es *fakeES) search(body interface{}) (int, []byte) {
+	raw, _ := json.Marshal(body)
+	var req struct {
+		Size  int `json:"size"`
+		Query struct {
+			Bool struct {
+				Filter []map[string]map[string]string `json:"filter"`
+			} `json:"bool"`
+		} `json:"query"`
+		SearchAfter []string `json:"search_after"`
+	}
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return http.StatusBadRequest, []byte(err.Error())
+	}
+
+	type hit struct {
+		SeqNo       int64           `json:"_seq_no"`
+		PrimaryTerm int64           `json:"_primary_term"`
+		Source      json.RawMessage `json:"_source"`
+		Sort        []string        `json:"sort"`
+	}
+	var hits []hit
+	for _, d := range es.docs {
+		var doc document
+		if err := json.Unmarshal(d.source, &doc); err != nil {
+			return http.StatusInternalServerError, []byte(err.Error())
+		}
+		match := true
+		for _, filter := range req.Query.Bool.Filter {
+			term := filter["term"]
+			match = match && (term["type"] == "" || term["type"] == doc.Type) && (term["store"] == "" || term["store"] == doc.Store)
+		}
+		if match && (len(req.SearchAfter) == 0 || doc.Key > req.SearchAfter[0]) {
+			hits = append(hits, hit{SeqNo: d.seqNo, PrimaryTerm: 1, Source: d.source, Sort: []string{doc.Key}})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Sort[0] < hits[j].Sort[0] })
+	if len(hits) > req.Size {
+		hits = hits[:req.Size]
+	}
+
+	resp, err := json.Marshal(map[string]interface{}{"hits": map[string]interface{}{"hits": hits}})
+	if err != nil {
+		return http.StatusInternalServerError, []byte(err.Error())
+	}
+	return http.StatusOK, resp
+}
@@ -0,0 +1,127 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"sync"
+	"time"
+)
+
+// lease of a store, held by at most one instance at a time.
+type lease struct {
+	client   *client
+	store    string
+	owner    string
+	duration time.Duration
+
+	mu      sync.Mutex
+	version version
+	renewed time.Time
+	lost    bool
+}
+
+// acquire creates the lease, or takes it over if it is expired or already
+// owned by this instance. If the lease is held by another instance, its owner
+// is returned.
+func (l *lease) acquire() (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id := leaseID(l.store)
+	doc, v, found, err := l.client.get(id)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	if found && doc.Owner != l.owner && doc.ExpiresAt != nil && doc.ExpiresAt.After(now) {
+		return doc.Owner, nil
+	}
+
+	var prev *version
+	if found {
+		prev = &v
+	}
+	v, err = l.client.put(id, l.document(now), prev)
+	if err != nil {
+		return "", err
+	}
+
+	l.version, l.renewed, l.lost = v, now, false
+	return "", nil
+}
+
+// renew extends the lease. errLeaseLost is returned if another instance took
+// the lease over.
+func (l *lease) renew() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lost {
+		return errLeaseLost
+	}
+
+	now := time.Now()
+	v, err := l.client.put(leaseID(l.store), l.document(now), &l.version)
+	if err == errConflict {
+		l.lost = true
+		return errLeaseLost
+	}
+	if err != nil {
+		return err
+	}
+
+	l.version, l.renewed = v, now
+	return nil
+}
+
+// check returns errLeaseLost if the lease is lost or expired, because it
+// couldn't be renewed in time.
+func (l *lease) check() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lost || time.Since(l.renewed) >= l.duration {
+		return errLeaseLost
+	}
+	return nil
+}
+
+// release removes the lease, so that another instance can acquire it
+// immediately.
+func (l *lease) release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lost {
+		return nil
+	}
+	l.lost = true
+	return l.client.delete(leaseID(l.store), l.version)
+}
+
+func (l *lease) document(now time.Time) document {
+	expires := now.Add(l.duration)
+	return document{
+		Type:      docTypeLease,
+		Store:     l.store,
+		Owner:     l.owner,
+		ExpiresAt: &expires,
+		UpdatedAt: now,
+	}
+}
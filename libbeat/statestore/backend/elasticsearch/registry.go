@@ -0,0 +1,172 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend"
+)
+
+// Connection sends requests to Elasticsearch. It is implemented by
+// eslegclient.Connection.
+type Connection interface {
+	Request(method, path string, pipeline string, params map[string]string, body interface{}) (int, []byte, error)
+}
+
+// Registry configures access to stores kept in an Elasticsearch index.
+type Registry struct {
+	log      *logp.Logger
+	client   *client
+	settings Settings
+
+	mu     sync.Mutex
+	active bool
+	done   chan struct{}
+
+	wg sync.WaitGroup
+}
+
+// Settings configures a new Registry.
+type Settings struct {
+	// Index is the name of the index holding the states and the leases.
+	Index string
+
+	// Owner identifies this instance in the leases. It must be unique among
+	// the instances sharing the index.
+	Owner string
+
+	// LeaseDuration is the time after which a lease that has not been renewed
+	// can be acquired by another instance. The lease is renewed every third of
+	// this duration. Defaults to 30s if not set.
+	LeaseDuration time.Duration
+
+	// RetryInterval is the time to wait between attempts to acquire a lease
+	// held by another instance. Defaults to a third of the lease duration.
+	RetryInterval time.Duration
+}
+
+const defaultLeaseDuration = 30 * time.Second
+
+var (
+	errRegClosed = errors.New("registry has been closed")
+	errLeaseLost = errors.New("the lease of the store has been lost, the store is in use by another instance")
+	errConflict  = errors.New("the state has been updated by another instance")
+)
+
+// New configures a Registry that keeps the stores in the Settings.Index index,
+// by using the given connection. The connection is closed with the registry,
+// if it implements io.Closer.
+func New(log *logp.Logger, conn Connection, settings Settings) (*Registry, error) {
+	if settings.Index == "" {
+		return nil, errors.New("no index configured")
+	}
+	if settings.Owner == "" {
+		return nil, errors.New("no owner configured")
+	}
+	if settings.LeaseDuration <= 0 {
+		settings.LeaseDuration = defaultLeaseDuration
+	}
+	if settings.RetryInterval <= 0 {
+		settings.RetryInterval = settings.LeaseDuration / 3
+	}
+
+	return &Registry{
+		log:      log,
+		client:   &client{conn: conn, index: settings.Index},
+		settings: settings,
+		active:   true,
+		done:     make(chan struct{}),
+	}, nil
+}
+
+// Access acquires the lease of the store and loads its states. If the lease
+// is held by another instance, Access blocks until the lease expires or the
+// registry is closed.
+func (r *Registry) Access(name string) (backend.Store, error) {
+	r.mu.Lock()
+	active := r.active
+	r.mu.Unlock()
+	if !active {
+		return nil, errRegClosed
+	}
+
+	if err := r.client.ensureIndex(); err != nil {
+		return nil, err
+	}
+
+	log := r.log.With("store", name)
+	lease := &lease{
+		client:   r.client,
+		store:    name,
+		owner:    r.settings.Owner,
+		duration: r.settings.LeaseDuration,
+	}
+	for {
+		holder, err := lease.acquire()
+		if err == nil && holder == "" {
+			break
+		}
+		if err != nil {
+			log.Errorf("Failed to acquire the lease of the store: %v", err)
+		} else {
+			log.Infof("Store is in use by %v, waiting for the lease to expire", holder)
+		}
+
+		select {
+		case <-r.done:
+			return nil, errRegClosed
+		case <-time.After(r.settings.RetryInterval):
+		}
+	}
+	log.Infof("Acquired the lease of the store as %v", r.settings.Owner)
+
+	store, err := openStore(log, r.client, name, lease)
+	if err != nil {
+		lease.release()
+		return nil, err
+	}
+
+	r.wg.Add(1)
+	store.onClose = r.wg.Done
+	return store, nil
+}
+
+// Close closes the registry. No new store can be accessed during close, and
+// pending Access calls waiting for a lease are stopped.
+// Close blocks until all stores have been closed.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	if r.active {
+		r.active = false
+		close(r.done)
+	}
+	r.mu.Unlock()
+
+	// block until all stores have been closed
+	r.wg.Wait()
+
+	if c, ok := r.client.conn.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package elasticsearch
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/transform/typeconv"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore/backend"
+)
+
+// store keeps the states of a store in memory, and writes the updates to
+// Elasticsearch while it holds the lease of the store.
+type store struct {
+	log    *logp.Logger
+	client *client
+	name   string
+	lease  *lease
+
+	mu     sync.RWMutex
+	states map[string]entry
+
+	done    chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+	onClose func()
+}
+
+type entry struct {
+	value   common.MapStr
+	version version
+}
+
+var errKeyUnknown = errors.New("key unknown")
+
+func openStore(log *logp.Logger, client *client, name string, lease *lease) (*store, error) {
+	if err := client.refresh(); err != nil {
+		return nil, fmt.Errorf("failed to refresh index: %v", err)
+	}
+
+	states := map[string]entry{}
+	err := client.eachState(name, func(doc document, v version) {
+		states[doc.Key] = entry{value: doc.Value, version: v}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load states: %v", err)
+	}
+	log.Infof("Loaded %d states from index %v", len(states), client.index)
+
+	s := &store{
+		log:    log,
+		client: client,
+		name:   name,
+		lease:  lease,
+		states: states,
+		done:   make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.renewLease()
+	return s, nil
+}
+
+// renewLease renews the lease every third of its duration, until the store is
+// closed.
+func (s *store) renewLease() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.lease.duration / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		switch err := s.lease.renew(); err {
+		case nil:
+		case errLeaseLost:
+			s.log.Error("The lease of the store has been acquired by another instance, updates will fail")
+			return
+		default:
+			s.log.Errorf("Failed to renew the lease of the store: %v", err)
+		}
+	}
+}
+
+// Close stops renewing the lease and releases it.
+func (s *store) Close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+
+		err = s.lease.release()
+		if s.onClose != nil {
+			s.onClose()
+		}
+	})
+	return err
+}
+
+// Has checks if the key is known. The states are held in memory, no error is
+// reported.
+func (s *store) Has(key string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, exists := s.states[key]
+	return exists, nil
+}
+
+// Get retrieves and decodes the key-value pair into to.
+func (s *store) Get(key string, to interface{}) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, exists := s.states[key]
+	if !exists {
+		return errKeyUnknown
+	}
+	return e.Decode(to)
+}
+
+// Set inserts or overwrites a key-value pair. The update fails if the lease
+// has been lost, or if the state has been modified by another instance.
+func (s *store) Set(key string, value interface{}) error {
+	var tmp common.MapStr
+	if err := typeconv.Convert(&tmp, value); err != nil {
+		return err
+	}
+	if err := s.lease.check(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var prev *version
+	if e, exists := s.states[key]; exists {
+		prev = &e.version
+	}
+	doc := document{
+		Type:      docTypeState,
+		Store:     s.name,
+		Key:       key,
+		Value:     tmp,
+		UpdatedAt: time.Now(),
+	}
+	v, err := s.client.put(stateID(s.name, key), doc, prev)
+	if err != nil {
+		return err
+	}
+	s.states[key] = entry{value: tmp, version: v}
+	return nil
+}
+
+// Remove removes a key from the store. The operation fails under the same
+// conditions as Set.
+func (s *store) Remove(key string) error {
+	if err := s.lease.check(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.states[key]
+	if !exists {
+		return nil
+	}
+	if err := s.client.delete(stateID(s.name, key), e.version); err != nil {
+		return err
+	}
+	delete(s.states, key)
+	return nil
+}
+
+// Each iterates over a snapshot of the key-value pairs in the store, so that
+// fn can update the store.
+func (s *store) Each(fn func(string, backend.ValueDecoder) (bool, error)) error {
+	s.mu.RLock()
+	keys := make([]string, 0, len(s.states))
+	entries := make([]entry, 0, len(s.states))
+	for k, e := range s.states {
+		keys = append(keys, k)
+		entries = append(entries, e)
+	}
+	s.mu.RUnlock()
+
+	for i, k := range keys {
+		cont, err := fn(k, entries[i])
+		if !cont || err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e entry) Decode(to interface{}) error {
+	return typeconv.Convert(to, e.value)
+}
@@ -21,6 +21,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/libbeat/statestore/backend"
@@ -57,6 +58,12 @@ type Settings struct {
 
 	// If set memlog will not check the version of the meta file.
 	IgnoreVersionCheck bool
+
+	// CheckpointInterval triggers a checkpoint periodically if updates have been
+	// logged since the last checkpoint, so that the log file is compacted into
+	// the data file even if the checkpoint predicate does not trigger. The
+	// periodic checkpoints are disabled if not set.
+	CheckpointInterval time.Duration
 }
 
 // CheckpointPredicate is the type for configurable checkpoint checks.
@@ -117,6 +124,9 @@ func (r *Registry) Access(name string) (backend.Store, error) {
 	if err != nil {
 		return nil, err
 	}
+	if interval := r.settings.CheckpointInterval; interval > 0 {
+		store.checkpointEvery(interval)
+	}
 
 	return store, nil
 }
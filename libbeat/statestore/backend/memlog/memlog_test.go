@@ -25,6 +25,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -56,6 +57,125 @@ func TestCompliance_AlwaysCheckpoint(t *testing.T) {
 	})
 }
 
+func TestCompliance_CheckpointInterval(t *testing.T) {
+	storecompliance.TestBackendCompliance(t, func(testPath string) (backend.Registry, error) {
+		return New(logp.NewLogger("test"), Settings{
+			Root:               testPath,
+			CheckpointInterval: time.Millisecond,
+		})
+	})
+}
+
+func TestCheckpointInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memlog-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	reg, err := New(logp.NewLogger("test"), Settings{
+		Root:               dir,
+		CheckpointInterval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer reg.Close()
+
+	store, err := reg.Access("test")
+	require.NoError(t, err)
+	defer store.Close()
+	require.NoError(t, store.Set("key", map[string]interface{}{"a": 1}))
+
+	logFile := filepath.Join(dir, "test", logFileName)
+	assert.Eventually(t, func() bool {
+		info, err := os.Stat(logFile)
+		return err == nil && info.Size() == 0
+	}, 5*time.Second, 10*time.Millisecond, "log file has not been compacted")
+
+	dataFiles, err := listDataFiles(filepath.Join(dir, "test"))
+	require.NoError(t, err)
+	require.Len(t, dataFiles, 1)
+	tbl := map[string]entry{}
+	require.NoError(t, loadDataFile(dataFiles[0].path, tbl))
+	assert.Contains(t, tbl, "key")
+}
+
+func TestRepairLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memlog-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	reg, err := New(logp.NewLogger("test"), Settings{Root: dir})
+	require.NoError(t, err)
+	defer reg.Close()
+
+	store, err := reg.Access("test")
+	require.NoError(t, err)
+	require.NoError(t, store.Set("a", map[string]interface{}{"offset": 1}))
+	require.NoError(t, store.Set("b", map[string]interface{}{"offset": 2}))
+	require.NoError(t, store.Close())
+
+	logFile := filepath.Join(dir, "test", logFileName)
+	f, err := os.OpenFile(logFile, os.O_WRONLY|os.O_APPEND, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"op":"set","id":3}{"K":"c","V":`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	problems, err := reg.Verify("test")
+	require.NoError(t, err)
+	assert.Len(t, problems, 1)
+
+	n, err := reg.Repair("test")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.FileExists(t, logFile+corruptedSuffix)
+
+	problems, err = reg.Verify("test")
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+}
+
+func TestRepairDataFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "memlog-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	reg, err := New(logp.NewLogger("test"), Settings{
+		Root:       dir,
+		Checkpoint: func(uint64) bool { return true },
+	})
+	require.NoError(t, err)
+	defer reg.Close()
+
+	store, err := reg.Access("test")
+	require.NoError(t, err)
+	require.NoError(t, store.Set("a", map[string]interface{}{"offset": 1}))
+	require.NoError(t, store.Close())
+
+	home := filepath.Join(dir, "test")
+	dataFiles, err := listDataFiles(home)
+	require.NoError(t, err)
+	require.Len(t, dataFiles, 1)
+	require.NoError(t, ioutil.WriteFile(dataFiles[0].path, []byte("[{"), 0600))
+	require.NoError(t, os.Remove(filepath.Join(home, metaFileName)))
+
+	problems, err := reg.Verify("test")
+	require.NoError(t, err)
+	assert.Len(t, problems, 2)
+	_, err = reg.Access("test")
+	require.Error(t, err)
+
+	n, err := reg.Repair("test")
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.FileExists(t, dataFiles[0].path+corruptedSuffix)
+
+	problems, err = reg.Verify("test")
+	require.NoError(t, err)
+	assert.Empty(t, problems)
+	store, err = reg.Access("test")
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+}
+
 func TestLoadVersion1(t *testing.T) {
 	dataHome := "testdata/1"
 
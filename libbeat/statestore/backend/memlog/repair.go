@@ -0,0 +1,146 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package memlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// corruptedSuffix is appended to the names of the files found to be corrupted
+// by Repair. The files are kept for inspection.
+const corruptedSuffix = ".corrupted"
+
+// Verify checks the files of a store for corruption, without modifying them.
+// It returns a description of every problem found. The store must not be
+// accessed while it is verified.
+func (r *Registry) Verify(name string) ([]string, error) {
+	home := filepath.Join(r.settings.Root, name)
+	if _, err := os.Stat(home); err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	if meta, err := readMetaFile(home); err != nil {
+		problems = append(problems, err.Error())
+	} else if err := checkMeta(meta); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	dataFiles, err := listDataFiles(home)
+	if err != nil {
+		return nil, err
+	}
+
+	tbl := map[string]entry{}
+	var txid uint64
+	if L := len(dataFiles); L > 0 {
+		active := dataFiles[L-1]
+		txid = active.txid
+		if err := loadDataFile(active.path, tbl); err != nil {
+			// The updates in the log file can not be applied without the data file.
+			return append(problems, fmt.Sprintf("%v: %v", active.path, err)), nil
+		}
+	}
+
+	if _, _, err := loadLogFile(&memstore{tbl}, txid, home); err != nil {
+		problems = append(problems, fmt.Sprintf("%v: %v", filepath.Join(home, logFileName), err))
+	}
+	return problems, nil
+}
+
+// Repair fixes the problems reported by Verify, so that the store can be
+// opened without losing updates silently. A missing or unreadable meta file
+// is replaced. A corrupted data file is renamed with the .corrupted suffix,
+// and the previous data file is used instead if it still exists. The updates
+// of the log file are applied up to the first invalid entry, and the
+// resulting states are written to a new data file. A corrupted log file is
+// kept with the .corrupted suffix. It returns the number of states in the
+// repaired store. The store must not be accessed while it is repaired.
+func (r *Registry) Repair(name string) (int, error) {
+	home := filepath.Join(r.settings.Root, name)
+	if _, err := os.Stat(home); err != nil {
+		return 0, err
+	}
+
+	if meta, err := readMetaFile(home); err != nil {
+		r.log.Warnf("Replacing meta file of %v: %v", home, err)
+		if err := writeMetaFile(home, r.settings.FileMode); err != nil {
+			return 0, err
+		}
+	} else if err := checkMeta(meta); err != nil {
+		return 0, err
+	}
+
+	for {
+		dataFiles, err := listDataFiles(home)
+		if err != nil {
+			return 0, err
+		}
+		if len(dataFiles) == 0 {
+			break
+		}
+
+		active := dataFiles[len(dataFiles)-1]
+		err = loadDataFile(active.path, map[string]entry{})
+		if err == nil {
+			break
+		}
+		r.log.Warnf("Ignoring data file %v: %v", active.path, err)
+		if err := os.Rename(active.path, active.path+corruptedSuffix); err != nil {
+			return 0, err
+		}
+	}
+
+	store, err := openStore(r.log, home, r.settings.FileMode, r.settings.BufferSize, r.settings.IgnoreVersionCheck, r.settings.Checkpoint)
+	if err != nil {
+		return 0, err
+	}
+	defer store.Close()
+
+	if store.disk.logInvalid {
+		logPath := filepath.Join(home, logFileName)
+		if err := backupFile(logPath, logPath+corruptedSuffix, r.settings.FileMode); err != nil {
+			return 0, err
+		}
+	}
+	if err := store.Checkpoint(); err != nil {
+		return 0, err
+	}
+	return len(store.mem.table), nil
+}
+
+func backupFile(from, to string, mode os.FileMode) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
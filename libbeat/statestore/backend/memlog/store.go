@@ -22,6 +22,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/transform/typeconv"
@@ -41,6 +42,10 @@ type store struct {
 	lock sync.RWMutex
 	disk *diskstore
 	mem  memstore
+
+	// done stops the periodic checkpoints, if enabled.
+	done chan struct{}
+	wg   sync.WaitGroup
 }
 
 // memstore is the in memory key value store
@@ -144,6 +149,12 @@ func openStore(log *logp.Logger, home string, mode os.FileMode, bufSz uint, igno
 // Close closes access to the update log file and clears the in memory key
 // value store. Access to the store after close can lead to a panic.
 func (s *store) Close() error {
+	if s.done != nil {
+		close(s.done)
+		s.wg.Wait()
+		s.done = nil
+	}
+
 	s.lock.Lock()
 	defer s.lock.Unlock()
 	s.mem = memstore{}
@@ -206,6 +217,40 @@ func (s *store) Checkpoint() error {
 	return s.disk.WriteCheckpoint(s.mem.table)
 }
 
+// checkpointEvery starts a go-routine that executes a checkpoint operation
+// every interval, if operations have been logged since the last checkpoint.
+// The go-routine is stopped by Close.
+func (s *store) checkpointEvery(interval time.Duration) {
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+				if err := s.checkpointIfUpdated(); err != nil {
+					s.disk.log.Errorf("Periodic checkpoint failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func (s *store) checkpointIfUpdated() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.disk.logEntries == 0 && !s.disk.logInvalid {
+		return nil
+	}
+	return s.disk.WriteCheckpoint(s.mem.table)
+}
+
 // lopOperation ensures that the diskstore reflects the recent changes to the
 // in memory store by either triggering a checkpoint operations or adding the
 // operation type to the update log file.
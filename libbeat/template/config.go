@@ -18,8 +18,13 @@
 package template
 
 import (
+	"errors"
 	"fmt"
+	"path"
+	"strings"
+	"text/template"
 
+	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/mapping"
 )
 
@@ -56,6 +61,56 @@ type TemplateConfig struct {
 	Order        int               `config:"order"`
 	Priority     int               `config:"priority"`
 	Type         IndexTemplateType `config:"type"`
+	DataStream   bool              `config:"data_stream"`
+	ComposedOf   []string          `config:"composed_of"`
+	RemoveLegacy bool              `config:"remove_legacy"`
+
+	RuntimeFields []RuntimeFieldConfig `config:"runtime_fields"`
+	Timestamp     TimestampConfig      `config:"timestamp"`
+}
+
+// TimestampConfig configures the mapping of @timestamp.
+type TimestampConfig struct {
+	// Precision of @timestamp. It is mapped as date_nanos if the precision is
+	// finer than milliseconds. When not set, the mapping of the fields is used.
+	Precision *common.TimestampPrecision `config:"precision"`
+
+	// Datasets overrides the precision of the indices or data streams of
+	// single datasets.
+	Datasets []DatasetTimestampConfig `config:"datasets"`
+}
+
+// DatasetTimestampConfig configures the precision of @timestamp for a single
+// dataset.
+type DatasetTimestampConfig struct {
+	Dataset   string                    `config:"dataset" validate:"required"`
+	Precision common.TimestampPrecision `config:"precision"`
+}
+
+// nanos returns true if @timestamp is mapped as date_nanos.
+func (c TimestampConfig) nanos() bool {
+	return c.Precision != nil && *c.Precision > common.TimestampMillisecond
+}
+
+// mapping returns the mapping of @timestamp for the configured precision.
+func (c TimestampConfig) mapping() common.MapStr {
+	if c.nanos() {
+		return common.MapStr{"type": "date_nanos"}
+	}
+	return common.MapStr{"type": "date"}
+}
+
+// RuntimeFieldConfig selects the fields that are mapped as runtime fields
+// instead of being indexed.
+type RuntimeFieldConfig struct {
+	// Field is the name of a field, or a pattern matching the names of
+	// several fields.
+	Field string `config:"field" validate:"required"`
+
+	// Script is the template of the script of the runtime field. It can
+	// refer to the name and runtime type of the field as {{.Field}} and
+	// {{.Type}}. When empty the value is read from _source.
+	Script string `config:"script"`
 }
 
 // TemplateSettings are part of the Elasticsearch template and hold index and source specific information.
@@ -67,14 +122,55 @@ type TemplateSettings struct {
 // DefaultConfig for index template
 func DefaultConfig() TemplateConfig {
 	return TemplateConfig{
-		Enabled:  true,
-		Fields:   "",
-		Type:     IndexTemplateLegacy,
-		Order:    1,
-		Priority: 150,
+		Enabled:      true,
+		Fields:       "",
+		Type:         IndexTemplateLegacy,
+		Order:        1,
+		Priority:     150,
+		RemoveLegacy: true,
 	}
 }
 
+// Validate checks that data streams are only configured for composable index
+// templates, and that the timestamp precision of a dataset is only configured
+// once.
+func (t *TemplateConfig) Validate() error {
+	if t.DataStream && t.Type != IndexTemplateIndex {
+		return errors.New("data_stream requires the template type to be index")
+	}
+	seen := map[string]bool{}
+	for _, ds := range t.Timestamp.Datasets {
+		if seen[ds.Dataset] {
+			return fmt.Errorf("duplicate timestamp precision for dataset %v", ds.Dataset)
+		}
+		seen[ds.Dataset] = true
+	}
+	return nil
+}
+
+// Validate checks the field pattern and the script template.
+func (c *RuntimeFieldConfig) Validate() error {
+	if _, err := path.Match(c.Field, ""); err != nil {
+		return fmt.Errorf("invalid runtime field pattern %s: %v", c.Field, err)
+	}
+	if _, err := template.New("script").Parse(c.Script); err != nil {
+		return fmt.Errorf("invalid script of runtime field %s: %v", c.Field, err)
+	}
+	return nil
+}
+
+// script renders the script template for the given field.
+func (c *RuntimeFieldConfig) script(field, runtimeType string) (string, error) {
+	tmpl, err := template.New("script").Option("missingkey=error").Parse(c.Script)
+	if err != nil {
+		return "", err
+	}
+
+	var buf strings.Builder
+	err = tmpl.Execute(&buf, struct{ Field, Type string }{field, runtimeType})
+	return buf.String(), err
+}
+
 func (t *IndexTemplateType) Unpack(v string) error {
 	if v == "" {
 		*t = IndexTemplateLegacy
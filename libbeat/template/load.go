@@ -37,6 +37,18 @@ var (
 		IndexTemplateComponent: "/_component_template/",
 		IndexTemplateIndex:     "/_index_template/",
 	}
+
+	// minComposableVersion is the first Elasticsearch version supporting
+	// component and composable index templates.
+	minComposableVersion = common.MustNewVersion("7.8.0")
+
+	// minDataStreamVersion is the first Elasticsearch version supporting data
+	// streams.
+	minDataStreamVersion = common.MustNewVersion("7.9.0")
+
+	// minDateNanosVersion is the first Elasticsearch version supporting the
+	// date_nanos type.
+	minDateNanosVersion = common.MustNewVersion("7.0.0")
 )
 
 //Loader interface for loading templates
@@ -98,6 +110,9 @@ func (l *ESLoader) Load(config TemplateConfig, info beat.Info, fields []byte, mi
 	if err != nil || tmpl == nil {
 		return err
 	}
+	if err := checkTemplateSupport(config, l.client.GetVersion()); err != nil {
+		return err
+	}
 
 	// Check if template already exist or should be overwritten
 	templateName := tmpl.GetName()
@@ -115,10 +130,44 @@ func (l *ESLoader) Load(config TemplateConfig, info beat.Info, fields []byte, mi
 	if err != nil {
 		return err
 	}
+	if config.Type == IndexTemplateIndex && !config.JSON.Enabled {
+		var components []componentTemplate
+		components, body = tmpl.compose(body)
+		for _, c := range components {
+			if err := l.loadTemplate(c.name, IndexTemplateComponent, c.body); err != nil {
+				return fmt.Errorf("could not load component template. Elasticsearch returned: %v. Template is: %s", err, c.body.StringToPrint())
+			}
+			l.log.Infof("component template with name '%s' loaded.", c.name)
+		}
+	}
 	if err := l.loadTemplate(templateName, config.Type, body); err != nil {
 		return fmt.Errorf("could not load template. Elasticsearch returned: %v. Template is: %s", err, body.StringToPrint())
 	}
 	l.log.Infof("template with name '%s' loaded.", templateName)
+
+	if config.Type == IndexTemplateIndex && config.RemoveLegacy {
+		if err := l.removeLegacyTemplate(templateName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTemplateSupport returns an error if the configured template type is not
+// supported by the Elasticsearch version.
+func checkTemplateSupport(config TemplateConfig, esVersion common.Version) error {
+	if !esVersion.IsValid() {
+		return nil
+	}
+	if config.Type != IndexTemplateLegacy && esVersion.LessThan(minComposableVersion) {
+		return fmt.Errorf("composable templates require Elasticsearch %v or newer, found %v", minComposableVersion, esVersion)
+	}
+	if config.DataStream && esVersion.LessThan(minDataStreamVersion) {
+		return fmt.Errorf("data streams require Elasticsearch %v or newer, found %v", minDataStreamVersion, esVersion)
+	}
+	if config.Timestamp.nanos() && esVersion.LessThan(minDateNanosVersion) {
+		return fmt.Errorf("timestamps with a precision finer than milliseconds require Elasticsearch %v or newer, found %v", minDateNanosVersion, esVersion)
+	}
 	return nil
 }
 
@@ -147,8 +196,8 @@ func (l *ESLoader) templateExists(templateName string, templateType IndexTemplat
 		return false
 	}
 
-	if templateType == IndexTemplateComponent {
-		status, _, _ := l.client.Request("GET", "/_component_template/"+templateName, "", nil, nil)
+	if templateType != IndexTemplateLegacy {
+		status, _, _ := l.client.Request("GET", templateLoaderPath[templateType]+templateName, "", nil, nil)
 		return status == http.StatusOK
 	}
 
@@ -157,6 +206,27 @@ func (l *ESLoader) templateExists(templateName string, templateType IndexTemplat
 	return status == http.StatusOK && strings.Contains(string(body), templateName)
 }
 
+// removeLegacyTemplate deletes the legacy template with the same name as a
+// composable index template, so it is not left behind after migrating to
+// composable templates.
+func (l *ESLoader) removeLegacyTemplate(templateName string) error {
+	path := templateLoaderPath[IndexTemplateLegacy] + templateName
+	status, _, _ := l.client.Request("GET", path, "", nil, nil)
+	if status != http.StatusOK {
+		return nil
+	}
+
+	status, body, err := l.client.Request("DELETE", path, "", nil, nil)
+	if err != nil {
+		return fmt.Errorf("couldn't remove legacy template: %v. Response body: %s", err, body)
+	}
+	if status > http.StatusMultipleChoices {
+		return fmt.Errorf("couldn't remove legacy template. Status: %v", status)
+	}
+	l.log.Infof("legacy template with name '%s' removed.", templateName)
+	return nil
+}
+
 // Load reads the template from the config, creates the template body and prints it to the configured file.
 func (l *FileLoader) Load(config TemplateConfig, info beat.Info, fields []byte, migration bool) error {
 	//build template from config
@@ -171,6 +241,17 @@ func (l *FileLoader) Load(config TemplateConfig, info beat.Info, fields []byte,
 		return err
 	}
 
+	if config.Type == IndexTemplateIndex && !config.JSON.Enabled {
+		var components []componentTemplate
+		components, body = tmpl.compose(body)
+		for _, c := range components {
+			str := fmt.Sprintf("%s\n", c.body.StringToPrint())
+			if err := l.client.Write("component_template", c.name, str); err != nil {
+				return fmt.Errorf("error printing component template: %v", err)
+			}
+		}
+	}
+
 	str := fmt.Sprintf("%s\n", body.StringToPrint())
 	if err := l.client.Write("template", tmpl.name, str); err != nil {
 		return fmt.Errorf("error printing template: %v", err)
@@ -19,6 +19,7 @@ package template
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/elastic/beats/v7/libbeat/beat"
@@ -84,8 +85,155 @@ func TestFileLoader_Load(t *testing.T) {
 	}
 }
 
+func TestFileLoader_LoadComposable(t *testing.T) {
+	ver := "7.9.0"
+	info := beat.Info{Version: ver, IndexPrefix: "mock"}
+
+	fc, err := newFileClient(ver)
+	require.NoError(t, err)
+	fl := NewFileLoader(fc)
+
+	cfg := DefaultConfig()
+	cfg.Type = IndexTemplateIndex
+	cfg.DataStream = true
+	cfg.Settings = TemplateSettings{Index: common.MapStr{"codec": "best_compression"}}
+
+	err = fl.Load(cfg, info, nil, false)
+	require.NoError(t, err)
+
+	settings := common.MapStr{
+		"template": common.MapStr{"settings": common.MapStr{"index": common.MapStr{"codec": "best_compression"}}},
+	}
+	index := common.MapStr{
+		"index_patterns": []string{"mock-7.9.0*"},
+		"priority":       150,
+		"data_stream":    common.MapStr{},
+		"composed_of":    []string{"mock-7.9.0-settings"},
+	}
+	assert.Equal(t, map[string]string{
+		"component_template/mock-7.9.0-settings": settings.StringToPrint() + "\n",
+		"template/mock-7.9.0":                    index.StringToPrint() + "\n",
+	}, fc.written)
+}
+
+func TestFileLoader_LoadComposedOf(t *testing.T) {
+	ver := "7.9.0"
+	info := beat.Info{Version: ver, IndexPrefix: "mock"}
+
+	fc, err := newFileClient(ver)
+	require.NoError(t, err)
+
+	cfg := DefaultConfig()
+	cfg.Type = IndexTemplateIndex
+	cfg.ComposedOf = []string{"base"}
+	err = NewFileLoader(fc).Load(cfg, info, nil, false)
+	require.NoError(t, err)
+
+	index := common.MapStr{
+		"index_patterns": []string{"mock-7.9.0-*"},
+		"priority":       150,
+		"composed_of":    []string{"base", "mock-7.9.0-settings"},
+	}
+	assert.Equal(t, index.StringToPrint()+"\n", fc.written["template/mock-7.9.0"])
+}
+
+func TestESLoader_LoadComposable(t *testing.T) {
+	info := beat.Info{Version: "7.9.0", IndexPrefix: "mock"}
+
+	for name, test := range map[string]struct {
+		removeLegacy bool
+		legacy       bool
+		requests     []string
+	}{
+		"no legacy template": {
+			removeLegacy: true,
+			requests: []string{
+				"GET /_index_template/mock-7.9.0",
+				"PUT /_component_template/mock-7.9.0-settings",
+				"PUT /_component_template/mock-7.9.0-mappings",
+				"PUT /_index_template/mock-7.9.0",
+				"GET /_template/mock-7.9.0",
+			},
+		},
+		"remove legacy template": {
+			removeLegacy: true,
+			legacy:       true,
+			requests: []string{
+				"GET /_index_template/mock-7.9.0",
+				"PUT /_component_template/mock-7.9.0-settings",
+				"PUT /_component_template/mock-7.9.0-mappings",
+				"PUT /_index_template/mock-7.9.0",
+				"GET /_template/mock-7.9.0",
+				"DELETE /_template/mock-7.9.0",
+			},
+		},
+		"keep legacy template": {
+			legacy: true,
+			requests: []string{
+				"GET /_index_template/mock-7.9.0",
+				"PUT /_component_template/mock-7.9.0-settings",
+				"PUT /_component_template/mock-7.9.0-mappings",
+				"PUT /_index_template/mock-7.9.0",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := &esClient{ver: "7.9.0", legacy: test.legacy}
+			cfg := DefaultConfig()
+			cfg.Type = IndexTemplateIndex
+			cfg.RemoveLegacy = test.removeLegacy
+			cfg.Settings = TemplateSettings{Source: common.MapStr{"enabled": false}}
+
+			err := NewESLoader(client).Load(cfg, info, nil, false)
+			require.NoError(t, err)
+			assert.Equal(t, test.requests, client.requests)
+		})
+	}
+}
+
+func TestESLoader_LoadUnsupported(t *testing.T) {
+	info := beat.Info{Version: "7.9.0", IndexPrefix: "mock"}
+
+	cfg := DefaultConfig()
+	cfg.Type = IndexTemplateIndex
+	err := NewESLoader(&esClient{ver: "7.7.0"}).Load(cfg, info, nil, false)
+	assert.Error(t, err)
+
+	cfg.DataStream = true
+	err = NewESLoader(&esClient{ver: "7.8.0"}).Load(cfg, info, nil, false)
+	assert.Error(t, err)
+
+	nanos := common.TimestampNanosecond
+	cfg = DefaultConfig()
+	cfg.Timestamp.Precision = &nanos
+	err = NewESLoader(&esClient{ver: "6.8.0"}).Load(cfg, info, nil, false)
+	assert.Error(t, err)
+}
+
+type esClient struct {
+	ver      string
+	legacy   bool
+	requests []string
+}
+
+func (c *esClient) GetVersion() common.Version {
+	return *common.MustNewVersion(c.ver)
+}
+
+func (c *esClient) Request(method, path string, _ string, _ map[string]string, _ interface{}) (int, []byte, error) {
+	c.requests = append(c.requests, method+" "+path)
+	if method == "GET" {
+		if c.legacy && strings.HasPrefix(path, "/_template/") {
+			return 200, []byte("{}"), nil
+		}
+		return 404, nil, nil
+	}
+	return 200, []byte("{}"), nil
+}
+
 type fileClient struct {
 	component, name, body, ver string
+	written                    map[string]string
 }
 
 func newFileClient(ver string) (*fileClient, error) {
@@ -98,5 +246,9 @@ func (c *fileClient) GetVersion() common.Version {
 
 func (c *fileClient) Write(component string, name string, body string) error {
 	c.component, c.name, c.body = component, name, body
+	if c.written == nil {
+		c.written = map[string]string{}
+	}
+	c.written[component+"/"+name] = body
 	return nil
 }
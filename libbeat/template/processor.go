@@ -20,6 +20,7 @@ package template
 import (
 	"errors"
 	"fmt"
+	"path"
 	"strings"
 
 	"github.com/elastic/beats/v7/libbeat/common"
@@ -31,6 +32,10 @@ type Processor struct {
 	EsVersion       common.Version
 	Migration       bool
 	ElasticLicensed bool
+	RuntimeFields   []RuntimeFieldConfig
+
+	// TimestampNanos maps @timestamp as date_nanos.
+	TimestampNanos bool
 }
 
 var (
@@ -40,9 +45,34 @@ var (
 
 const scalingFactorKey = "scalingFactor"
 
+var (
+	minVersionRuntimeFields = common.MustNewVersion("7.11.0")
+
+	// runtimeFieldTypes maps the field types to the types of runtime fields.
+	runtimeFieldTypes = map[string]string{
+		"":             "keyword",
+		"keyword":      "keyword",
+		"wildcard":     "keyword",
+		"ip":           "ip",
+		"date":         "date",
+		"date_nanos":   "date",
+		"boolean":      "boolean",
+		"long":         "long",
+		"integer":      "long",
+		"short":        "long",
+		"byte":         "long",
+		"double":       "double",
+		"float":        "double",
+		"half_float":   "double",
+		"scaled_float": "double",
+		"geo_point":    "geo_point",
+	}
+)
+
 type fieldState struct {
 	DefaultField bool
 	Path         string
+	MultiField   bool
 }
 
 // Process recursively processes the given fields and writes the template in the given output
@@ -61,6 +91,22 @@ func (p *Processor) Process(fields mapping.Fields, state *fieldState, output com
 		if field.DefaultField == nil {
 			field.DefaultField = &state.DefaultField
 		}
+
+		if p.TimestampNanos && field.Type == "date" && fullName(&field) == "@timestamp" {
+			field.Type = "date_nanos"
+		}
+
+		if !state.MultiField && field.Type != "group" {
+			runtimeMapping, err := p.runtime(&field)
+			if err != nil {
+				return err
+			}
+			if runtimeMapping != nil {
+				runtimeFields[fullName(&field)] = runtimeMapping
+				continue
+			}
+		}
+
 		var indexMapping common.MapStr
 
 		switch field.Type {
@@ -137,14 +183,52 @@ func (p *Processor) Process(fields mapping.Fields, state *fieldState, output com
 }
 
 func addToDefaultFields(f *mapping.Field) {
-	fullName := f.Name
+	if f.Index == nil || (f.Index != nil && *f.Index) {
+		defaultFields = append(defaultFields, fullName(f))
+	}
+}
+
+func fullName(f *mapping.Field) string {
 	if f.Path != "" {
-		fullName = f.Path + "." + f.Name
+		return f.Path + "." + f.Name
 	}
+	return f.Name
+}
 
-	if f.Index == nil || (f.Index != nil && *f.Index) {
-		defaultFields = append(defaultFields, fullName)
+// runtime returns the mapping of the field as a runtime field if it is
+// selected by the runtime fields configuration, or nil if the field must be
+// indexed. Runtime fields are only used from Elasticsearch 7.11.
+func (p *Processor) runtime(f *mapping.Field) (common.MapStr, error) {
+	if len(p.RuntimeFields) == 0 || p.EsVersion.LessThan(minVersionRuntimeFields) {
+		return nil, nil
+	}
+
+	name := fullName(f)
+	for _, cfg := range p.RuntimeFields {
+		if matched, _ := path.Match(cfg.Field, name); !matched {
+			continue
+		}
+
+		runtimeType, ok := runtimeFieldTypes[f.Type]
+		if !ok {
+			if cfg.Field == name {
+				return nil, fmt.Errorf("field %s of type %s can't be a runtime field", name, f.Type)
+			}
+			// Fields of other types matching a pattern are indexed.
+			return nil, nil
+		}
+
+		property := common.MapStr{"type": runtimeType}
+		if cfg.Script != "" {
+			source, err := cfg.script(name, runtimeType)
+			if err != nil {
+				return nil, fmt.Errorf("invalid script for runtime field %s: %v", name, err)
+			}
+			property["script"] = common.MapStr{"source": source}
+		}
+		return property, nil
 	}
+	return nil, nil
 }
 
 func (p *Processor) other(f *mapping.Field) common.MapStr {
@@ -230,7 +314,7 @@ func (p *Processor) keyword(f *mapping.Field) common.MapStr {
 
 	if len(f.MultiFields) > 0 {
 		fields := common.MapStr{}
-		p.Process(f.MultiFields, nil, fields)
+		p.Process(f.MultiFields, &fieldState{DefaultField: true, MultiField: true}, fields)
 		property["fields"] = fields
 	}
 
@@ -252,7 +336,7 @@ func (p *Processor) wildcard(f *mapping.Field) common.MapStr {
 
 	if len(f.MultiFields) > 0 {
 		fields := common.MapStr{}
-		p.Process(f.MultiFields, nil, fields)
+		p.Process(f.MultiFields, &fieldState{DefaultField: true, MultiField: true}, fields)
 		property["fields"] = fields
 	}
 
@@ -288,7 +372,7 @@ func (p *Processor) text(f *mapping.Field) common.MapStr {
 
 	if len(f.MultiFields) > 0 {
 		fields := common.MapStr{}
-		p.Process(f.MultiFields, nil, fields)
+		p.Process(f.MultiFields, &fieldState{DefaultField: true, MultiField: true}, fields)
 		properties["fields"] = fields
 	}
 
@@ -40,6 +40,9 @@ var (
 	// Array to store dynamicTemplate parts in
 	dynamicTemplates []common.MapStr
 
+	// Mappings of the fields that are not indexed but runtime fields
+	runtimeFields common.MapStr
+
 	defaultFields []string
 )
 
@@ -81,6 +84,10 @@ func New(
 	pattern := config.Pattern
 	if pattern == "" {
 		pattern = name + "-*"
+		if config.DataStream {
+			// The data streams are named after the template.
+			pattern = name + "*"
+		}
 	}
 
 	event := &beat.Event{
@@ -149,6 +156,7 @@ func (t *Template) load(fields mapping.Fields) (common.MapStr, error) {
 
 	dynamicTemplates = nil
 	defaultFields = nil
+	runtimeFields = common.MapStr{}
 
 	var err error
 	if len(t.config.AppendFields) > 0 {
@@ -160,7 +168,13 @@ func (t *Template) load(fields mapping.Fields) (common.MapStr, error) {
 
 	// Start processing at the root
 	properties := common.MapStr{}
-	processor := Processor{EsVersion: t.esVersion, ElasticLicensed: t.elasticLicensed, Migration: t.migration}
+	processor := Processor{
+		EsVersion:       t.esVersion,
+		ElasticLicensed: t.elasticLicensed,
+		Migration:       t.migration,
+		RuntimeFields:   t.config.RuntimeFields,
+		TimestampNanos:  t.config.Timestamp.nanos(),
+	}
 	if err := processor.Process(fields, nil, properties); err != nil {
 		return nil, err
 	}
@@ -203,11 +217,22 @@ func (t *Template) LoadMinimal() (common.MapStr, error) {
 		return nil, fmt.Errorf("unknown template type %v", t.templateType)
 	}
 
-	if t.config.Settings.Source != nil {
-		m["mappings"] = buildMappings(
+	var properties common.MapStr
+	if t.config.Timestamp.Precision != nil {
+		// Minimal templates of datasets override the mapping of @timestamp.
+		properties = common.MapStr{"@timestamp": t.config.Timestamp.mapping()}
+	}
+
+	if t.config.Settings.Source != nil || properties != nil {
+		mappings := buildMappings(
 			t.beatVersion, t.esVersion, t.beatName,
-			nil, nil,
+			properties, nil,
 			common.MapStr(t.config.Settings.Source))
+		if t.templateType == IndexTemplateLegacy {
+			m["mappings"] = mappings
+		} else {
+			m.Put("template.mappings", mappings)
+		}
 	}
 
 	return m, nil
@@ -237,6 +262,11 @@ func (t *Template) loadMinimalComponent() common.MapStr {
 func (t *Template) loadMinimalIndex() common.MapStr {
 	m := t.loadMinimalComponent()
 	m["priority"] = t.priority
+	keyPattern, patterns := buildPatternSettings(t.esVersion, t.GetPattern())
+	m[keyPattern] = patterns
+	if t.config.DataStream {
+		m["data_stream"] = common.MapStr{}
+	}
 	return m
 }
 
@@ -307,9 +337,53 @@ func (t *Template) generateIndex(properties common.MapStr) common.MapStr {
 	tmpl["priority"] = t.priority
 	keyPattern, patterns := buildPatternSettings(t.esVersion, t.GetPattern())
 	tmpl[keyPattern] = patterns
+	if t.config.DataStream {
+		tmpl["data_stream"] = common.MapStr{}
+	}
 	return tmpl
 }
 
+// componentTemplate is a component template an index template is composed of.
+type componentTemplate struct {
+	name string
+	body common.MapStr
+}
+
+// compose splits the body of an index template into a component template for
+// the index settings and one for the mappings. It returns the component
+// templates and the index template composed of them, after the configured
+// component templates.
+func (t *Template) compose(body common.MapStr) ([]componentTemplate, common.MapStr) {
+	inline, _ := body["template"].(common.MapStr)
+
+	var components []componentTemplate
+	for _, part := range []string{"settings", "mappings"} {
+		value, exists := inline[part]
+		if !exists {
+			continue
+		}
+		components = append(components, componentTemplate{
+			name: fmt.Sprintf("%s-%s", t.name, part),
+			body: common.MapStr{
+				"template": common.MapStr{part: value},
+			},
+		})
+	}
+
+	index := common.MapStr{}
+	for k, v := range body {
+		if k != "template" {
+			index[k] = v
+		}
+	}
+	composedOf := append([]string{}, t.config.ComposedOf...)
+	for _, c := range components {
+		composedOf = append(composedOf, c.name)
+	}
+	index["composed_of"] = composedOf
+	return components, index
+}
+
 func buildPatternSettings(ver common.Version, pattern string) (string, interface{}) {
 	if ver.Major < 6 {
 		return "template", pattern
@@ -338,6 +412,10 @@ func buildMappings(
 		mapping["_source"] = source
 	}
 
+	if len(runtimeFields) > 0 {
+		mapping["runtime"] = runtimeFields
+	}
+
 	major := esVersion.Major
 	switch {
 	case major == 2:
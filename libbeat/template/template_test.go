@@ -133,6 +133,175 @@ func TestTemplate(t *testing.T) {
 	})
 }
 
+func TestRuntimeFields(t *testing.T) {
+	fields := []byte(`
+- key: test
+  title: Test
+  fields:
+    - name: process
+      type: group
+      fields:
+        - name: name
+          type: keyword
+        - name: pid
+          type: long
+        - name: args
+          type: keyword
+        - name: title
+          type: text
+    - name: message
+      type: text
+`)
+	config := TemplateConfig{
+		RuntimeFields: []RuntimeFieldConfig{
+			{Field: "process.name", Script: "emit(doc['{{.Field}}.raw'].value)"},
+			{Field: "process.*"},
+		},
+	}
+
+	cases := map[string]struct {
+		esVersion string
+		runtime   bool
+	}{
+		"runtime fields":                  {esVersion: "7.11.0", runtime: true},
+		"indexed fields on older version": {esVersion: "7.10.0", runtime: false},
+	}
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			esVersion := common.MustNewVersion(test.esVersion)
+			tmpl, err := New(getVersion(""), "beatname", false, *esVersion, config, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := tmpl.LoadBytes(fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			template := &testTemplate{t: t, tmpl: tmpl, data: data}
+
+			template.Assert("mappings.properties.message.type", "text")
+			template.Assert("mappings.properties.process.properties.title.type", "text")
+			if !test.runtime {
+				template.AssertMissing("mappings.runtime")
+				template.Assert("mappings.properties.process.properties.pid.type", "long")
+				return
+			}
+
+			template.AssertMissing("mappings.properties.process.properties.name")
+			template.AssertMissing("mappings.properties.process.properties.pid")
+			template.AssertMissing("mappings.properties.process.properties.args")
+			assert.Equal(t, common.MapStr{
+				"process.name": common.MapStr{
+					"type":   "keyword",
+					"script": common.MapStr{"source": "emit(doc['process.name.raw'].value)"},
+				},
+				"process.pid":  common.MapStr{"type": "long"},
+				"process.args": common.MapStr{"type": "keyword"},
+			}, template.Get("mappings.runtime"))
+			assert.NotContains(t, template.Get("settings.index.query.default_field"), "process.name")
+		})
+	}
+}
+
+func TestRuntimeFieldsUnsupportedType(t *testing.T) {
+	config := TemplateConfig{
+		RuntimeFields: []RuntimeFieldConfig{{Field: "message"}},
+	}
+	tmpl, err := New(getVersion(""), "beatname", false, *common.MustNewVersion("7.11.0"), config, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tmpl.LoadBytes([]byte(`
+- key: test
+  title: Test
+  fields:
+    - name: message
+      type: text
+`))
+	assert.Error(t, err)
+}
+
+func TestTimestampPrecision(t *testing.T) {
+	fields := []byte(`
+- key: test
+  title: Test
+  fields:
+    - name: "@timestamp"
+      type: date
+    - name: event
+      type: group
+      fields:
+        - name: created
+          type: date
+`)
+	nanos := common.TimestampNanosecond
+	millis := common.TimestampMillisecond
+
+	cases := map[string]struct {
+		precision *common.TimestampPrecision
+		want      string
+	}{
+		"default":     {precision: nil, want: "date"},
+		"millisecond": {precision: &millis, want: "date"},
+		"nanosecond":  {precision: &nanos, want: "date_nanos"},
+	}
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := TemplateConfig{Timestamp: TimestampConfig{Precision: test.precision}}
+			tmpl, err := New(getVersion(""), "beatname", false, *common.MustNewVersion("7.10.0"), config, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := tmpl.LoadBytes(fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			template := &testTemplate{t: t, tmpl: tmpl, data: data}
+			template.Assert("mappings.properties.@timestamp.type", test.want)
+			template.Assert("mappings.properties.event.properties.created.type", "date")
+		})
+	}
+
+	t.Run("minimal template", func(t *testing.T) {
+		config := TemplateConfig{Timestamp: TimestampConfig{Precision: &nanos}}
+		tmpl, err := New(getVersion(""), "beatname", false, *common.MustNewVersion("7.10.0"), config, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := tmpl.LoadMinimal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		template := &testTemplate{t: t, tmpl: tmpl, data: data}
+		template.Assert("mappings.properties.@timestamp.type", "date_nanos")
+	})
+}
+
+func TestTimestampConfig(t *testing.T) {
+	cfg := common.MustNewConfigFrom(map[string]interface{}{
+		"timestamp.precision": "nanosecond",
+		"timestamp.datasets": []map[string]interface{}{
+			{"dataset": "nginx.access", "precision": "microsecond"},
+		},
+	})
+	config := DefaultConfig()
+	if assert.NoError(t, cfg.Unpack(&config)) {
+		assert.Equal(t, common.TimestampNanosecond, *config.Timestamp.Precision)
+		assert.Equal(t, []DatasetTimestampConfig{
+			{Dataset: "nginx.access", Precision: common.TimestampMicrosecond},
+		}, config.Timestamp.Datasets)
+	}
+
+	cfg = common.MustNewConfigFrom(map[string]interface{}{
+		"timestamp.datasets": []map[string]interface{}{
+			{"dataset": "nginx.access", "precision": "microsecond"},
+			{"dataset": "nginx.access", "precision": "nanosecond"},
+		},
+	})
+	config = DefaultConfig()
+	assert.Error(t, cfg.Unpack(&config))
+}
+
 func createTestTemplate(t *testing.T, beatVersion, esVersion string, config TemplateConfig) *testTemplate {
 	beatVersion = getVersion(beatVersion)
 	esVersion = getVersion(esVersion)
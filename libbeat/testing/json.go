@@ -0,0 +1,141 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package testing
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// Status of the checks reported by the JSONDriver.
+const (
+	StatusOK    = "ok"
+	StatusWarn  = "warn"
+	StatusError = "error"
+	StatusInfo  = "info"
+)
+
+// JSONResult is the result of a check, or of a group of checks, as written by
+// the JSONDriver.
+type JSONResult struct {
+	Name    string        `json:"name,omitempty"`
+	Status  string        `json:"status"`
+	Message string        `json:"message,omitempty"`
+	Result  string        `json:"result,omitempty"`
+	Checks  []*JSONResult `json:"checks,omitempty"`
+}
+
+// JSONDriver collects the test results and writes them to the given writer as
+// a single JSON document when Flush is called, or on fatal errors.
+type JSONDriver struct {
+	root   *JSONDriver
+	result *JSONResult
+	stdout io.Writer
+	killer func()
+}
+
+// NewJSONDriver initializes and returns a new JSON driver with output to the given writer
+func NewJSONDriver(stdout io.Writer) *JSONDriver {
+	return NewJSONDriverWithKiller(stdout, func() { os.Exit(1) })
+}
+
+// NewJSONDriverWithKiller initializes and returns a new JSON driver with output
+// to the given writer. Killer function will be called on fatal errors, after
+// the results have been written.
+func NewJSONDriverWithKiller(stdout io.Writer, killer func()) *JSONDriver {
+	d := &JSONDriver{
+		result: &JSONResult{Status: StatusOK},
+		stdout: stdout,
+		killer: killer,
+	}
+	d.root = d
+	return d
+}
+
+func (d *JSONDriver) Run(name string, f func(Driver)) {
+	driver := &JSONDriver{
+		root:   d.root,
+		result: d.add(name, StatusOK, ""),
+	}
+	f(driver)
+}
+
+func (d *JSONDriver) Info(field, value string) {
+	d.add(field, StatusInfo, value)
+}
+
+func (d *JSONDriver) Warn(field, reason string) {
+	d.add(field, StatusWarn, reason)
+}
+
+func (d *JSONDriver) Error(field string, err error) {
+	if err == nil {
+		d.add(field, StatusOK, "")
+		return
+	}
+	d.add(field, StatusError, err.Error())
+}
+
+func (d *JSONDriver) Fatal(field string, err error) {
+	d.Error(field, err)
+	if err != nil {
+		d.root.Flush()
+		d.root.killer()
+	}
+}
+
+func (d *JSONDriver) Result(data string) {
+	d.result.Result = data
+}
+
+// Flush writes the results collected so far.
+func (d *JSONDriver) Flush() error {
+	updateStatus(d.root.result)
+	enc := json.NewEncoder(d.root.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d.root.result)
+}
+
+func (d *JSONDriver) add(name, status, message string) *JSONResult {
+	result := &JSONResult{Name: name, Status: status, Message: message}
+	d.result.Checks = append(d.result.Checks, result)
+	return result
+}
+
+// updateStatus sets the status of the groups of checks to the most severe
+// status of their members, and returns it.
+func updateStatus(result *JSONResult) string {
+	if len(result.Checks) == 0 {
+		return result.Status
+	}
+
+	status := StatusOK
+	for _, check := range result.Checks {
+		switch updateStatus(check) {
+		case StatusError:
+			status = StatusError
+		case StatusWarn:
+			if status != StatusError {
+				status = StatusWarn
+			}
+		}
+	}
+	result.Status = status
+	return status
+}
@@ -0,0 +1,84 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package testing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONDriver(t *testing.T) {
+	var buffer bytes.Buffer
+	driver := NewJSONDriver(&buffer)
+
+	driver.Run("connection", func(d Driver) {
+		d.Info("host", "localhost")
+		d.Error("dial", nil)
+		d.Warn("tls", "secure connection disabled")
+	})
+	driver.Run("publish", func(d Driver) {
+		d.Run("batch", func(d Driver) {
+			d.Error("send", errors.New("This is an error"))
+		})
+		d.Result("This is a result")
+	})
+	require.NoError(t, driver.Flush())
+
+	var result JSONResult
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &result))
+	assert.Equal(t, JSONResult{
+		Status: StatusError,
+		Checks: []*JSONResult{
+			{Name: "connection", Status: StatusWarn, Checks: []*JSONResult{
+				{Name: "host", Status: StatusInfo, Message: "localhost"},
+				{Name: "dial", Status: StatusOK},
+				{Name: "tls", Status: StatusWarn, Message: "secure connection disabled"},
+			}},
+			{Name: "publish", Status: StatusError, Result: "This is a result", Checks: []*JSONResult{
+				{Name: "batch", Status: StatusError, Checks: []*JSONResult{
+					{Name: "send", Status: StatusError, Message: "This is an error"},
+				}},
+			}},
+		},
+	}, result)
+}
+
+func TestJSONDriverFatal(t *testing.T) {
+	var buffer bytes.Buffer
+	var killed bool
+	driver := NewJSONDriverWithKiller(&buffer, func() { killed = true })
+
+	driver.Run("test", func(d Driver) {
+		d.Fatal("no error", nil)
+		assert.False(t, killed)
+		assert.Zero(t, buffer.Len())
+
+		d.Fatal("error", errors.New("This is an error"))
+	})
+
+	assert.True(t, killed)
+	var result JSONResult
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &result))
+	assert.Equal(t, StatusError, result.Status)
+	assert.Equal(t, "This is an error", result.Checks[0].Checks[1].Message)
+}
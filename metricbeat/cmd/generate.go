@@ -0,0 +1,64 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/beats/v7/libbeat/common/cli"
+	"github.com/elastic/beats/v7/libbeat/paths"
+	"github.com/elastic/beats/v7/metricbeat/generator"
+)
+
+func genGenerateCmd() *cobra.Command {
+	generateCmd := cobra.Command{
+		Use:   "generate",
+		Short: "Generate Metricbeat modules and metricsets",
+	}
+	generateCmd.AddCommand(genGenerateMetricSetCmd())
+
+	return &generateCmd
+}
+
+func genGenerateMetricSetCmd() *cobra.Command {
+	defaultHomePath := paths.Resolve(paths.Home, "")
+
+	genMetricSetCmd := &cobra.Command{
+		Use:   "metricset [module] [metricset]",
+		Short: "Generates a new metricset, and its module if it does not exist",
+		Run: cli.RunWith(func(cmd *cobra.Command, args []string) error {
+			modulesPath, _ := cmd.Flags().GetString("modules-path")
+			esBeatsPath, _ := cmd.Flags().GetString("es-beats")
+
+			if len(args) != 2 {
+				fmt.Fprintf(os.Stderr, "Two parameters are required: module name, metricset name\n")
+				os.Exit(1)
+			}
+
+			return generator.GenerateMetricSet(args[0], args[1], modulesPath, esBeatsPath)
+		}),
+	}
+
+	genMetricSetCmd.Flags().String("modules-path", defaultHomePath, "Path to the directory containing the module directory")
+	genMetricSetCmd.Flags().String("es-beats", defaultHomePath, "Path to Elastic Beats")
+
+	return genMetricSetCmd
+}
@@ -0,0 +1,41 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package cmd
+
+import "github.com/elastic/beats/v7/libbeat/cmd/wizard"
+
+// moduleHints are used to suggest the modules of the services installed or
+// listening on their default ports.
+var moduleHints = []wizard.ModuleHint{
+	{Module: "apache", Paths: []string{"/usr/sbin/apache2", "/usr/sbin/httpd"}},
+	{Module: "consul", Ports: []int{8500}},
+	{Module: "couchdb", Ports: []int{5984}},
+	{Module: "docker", Paths: []string{"/var/run/docker.sock"}},
+	{Module: "etcd", Ports: []int{2379}},
+	{Module: "haproxy", Paths: []string{"/usr/sbin/haproxy"}},
+	{Module: "kafka", Ports: []int{9092}},
+	{Module: "memcached", Ports: []int{11211}},
+	{Module: "mongodb", Ports: []int{27017}},
+	{Module: "mysql", Paths: []string{"/usr/sbin/mysqld"}, Ports: []int{3306}},
+	{Module: "nginx", Paths: []string{"/usr/sbin/nginx"}},
+	{Module: "php_fpm", Paths: []string{"/usr/sbin/php-fpm*"}},
+	{Module: "postgresql", Ports: []int{5432}},
+	{Module: "rabbitmq", Ports: []int{15672}},
+	{Module: "redis", Ports: []int{6379}},
+	{Module: "zookeeper", Ports: []int{2181}},
+}
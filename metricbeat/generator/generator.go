@@ -0,0 +1,139 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+//go:generate go run ../../dev-tools/cmd/templates/templates.go -pkg generator -in ../scripts -out templates.go module
+
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var validName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// GenerateMetricSet creates the files of a new metricset. The module of the
+// metricset is created too, if it does not exist yet.
+func GenerateMetricSet(module, metricset, modulesPath, beatsPath string) error {
+	for _, name := range []string{module, metricset} {
+		if !validName.MatchString(name) {
+			return fmt.Errorf("invalid name, only lowercase letters, digits and underscores can be used: %s", name)
+		}
+	}
+
+	modulePath := path.Join(modulesPath, "module", module)
+	if !dirExists(modulePath) {
+		if err := generateModule(module, metricset, modulePath, beatsPath); err != nil {
+			return err
+		}
+	}
+
+	metricsetPath := path.Join(modulePath, metricset)
+	if dirExists(metricsetPath) {
+		return fmt.Errorf("metricset already exists: %s", metricset)
+	}
+
+	err := os.MkdirAll(path.Join(metricsetPath, "_meta"), 0750)
+	if err != nil {
+		return err
+	}
+
+	replace := map[string]string{"module": module, "metricset": metricset}
+	templatesPath := path.Join(beatsPath, "scripts", "module", "metricset")
+	return copyTemplates(templatesPath, metricsetPath, map[string]string{
+		"metricset.go.tmpl":      metricset + ".go",
+		"metricset_test.go.tmpl": metricset + "_test.go",
+		"fields.yml":             path.Join("_meta", "fields.yml"),
+		"docs.asciidoc":          path.Join("_meta", "docs.asciidoc"),
+		"data.json":              path.Join("_meta", "data.json"),
+	}, replace)
+}
+
+func generateModule(module, metricset, modulePath, beatsPath string) error {
+	err := os.MkdirAll(path.Join(modulePath, "_meta"), 0750)
+	if err != nil {
+		return err
+	}
+
+	replace := map[string]string{"module": module, "metricset": metricset}
+	templatesPath := path.Join(beatsPath, "scripts", "module")
+	return copyTemplates(templatesPath, modulePath, map[string]string{
+		"fields.yml":    path.Join("_meta", "fields.yml"),
+		"docs.asciidoc": path.Join("_meta", "docs.asciidoc"),
+		"config.yml":    path.Join("_meta", "config.yml"),
+		"doc.go.tmpl":   "doc.go",
+	}, replace)
+}
+
+func dirExists(dir string) bool {
+	_, err := os.Stat(dir)
+	return !os.IsNotExist(err)
+}
+
+// copyTemplates copies the templates from src to the given paths in dest,
+// replacing the variables in their content.
+func copyTemplates(src, dest string, templates map[string]string, replace map[string]string) error {
+	for template, file := range templates {
+		c, err := readTemplate(path.Join(src, template), replace)
+		if err != nil {
+			return err
+		}
+
+		err = ioutil.WriteFile(path.Join(dest, file), c, 0644)
+		if err != nil {
+			return fmt.Errorf("cannot copy template: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// readTemplate reads a template and replaces the variables in it. When the
+// template is not available, e.g. because Metricbeat was installed from a
+// package, the template built into the binary is used.
+func readTemplate(template string, replace map[string]string) ([]byte, error) {
+	c, err := ioutil.ReadFile(template)
+	if os.IsNotExist(err) {
+		if builtin, ok := builtinTemplate(template); ok {
+			c, err = []byte(builtin), nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read template: %v", err)
+	}
+
+	for oldV, newV := range replace {
+		c = bytes.Replace(c, []byte("{"+oldV+"}"), []byte(newV), -1)
+	}
+
+	return c, nil
+}
+
+func builtinTemplate(template string) (string, bool) {
+	template = path.Clean(template)
+	for name, content := range builtinTemplates {
+		if template == path.Join("scripts", name) || strings.HasSuffix(template, "/"+path.Join("scripts", name)) {
+			return content, true
+		}
+	}
+	return "", false
+}
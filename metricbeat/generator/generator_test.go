@@ -0,0 +1,82 @@
+// Licensed to Elasticsearch B.V. under one or more contributor
+// license agreements. See the NOTICE file distributed with
+// this work for additional information regarding copyright
+// ownership. Elasticsearch B.V. licenses this file to you under
+// the Apache License, Version 2.0 (the "License"); you may
+// not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing,
+// software distributed under the License is distributed on an
+// "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+// KIND, either express or implied.  See the License for the
+// specific language governing permissions and limitations
+// under the License.
+
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBuiltinTemplatesUpToDate checks that the templates built into the binary
+// match the ones in the scripts directory. Run `go generate` to update them.
+func TestBuiltinTemplatesUpToDate(t *testing.T) {
+	found := 0
+	err := filepath.Walk(filepath.Join("..", "scripts", "module"), func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		content, err := ioutil.ReadFile(p)
+		require.NoError(t, err)
+
+		name, err := filepath.Rel(filepath.Join("..", "scripts"), p)
+		require.NoError(t, err)
+		assert.Equal(t, string(content), builtinTemplates[filepath.ToSlash(name)], "template %s is outdated", name)
+		found++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, found, len(builtinTemplates))
+}
+
+func TestGenerateMetricSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "generator")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	beatsPath := filepath.Join(dir, "missing")
+	require.NoError(t, GenerateMetricSet("foo", "bar", dir, beatsPath))
+	require.NoError(t, GenerateMetricSet("foo", "baz", dir, beatsPath))
+
+	for _, file := range []string{
+		"doc.go",
+		"_meta/config.yml",
+		"_meta/docs.asciidoc",
+		"_meta/fields.yml",
+		"bar/bar.go",
+		"bar/bar_test.go",
+		"bar/_meta/data.json",
+		"bar/_meta/docs.asciidoc",
+		"bar/_meta/fields.yml",
+		"baz/baz.go",
+	} {
+		assert.FileExists(t, filepath.Join(dir, "module", "foo", filepath.FromSlash(file)))
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "module", "foo", "baz", "baz.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `mb.Registry.MustAddMetricSet("foo", "baz", New)`)
+
+	assert.Error(t, GenerateMetricSet("foo", "bar", dir, beatsPath), "metricset already exists")
+	assert.Error(t, GenerateMetricSet("foo", "bad-name", dir, beatsPath))
+}
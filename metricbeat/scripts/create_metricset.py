@@ -24,6 +24,10 @@ def generate_metricset(base_path, metricbeat_path, module, metricset):
     with open(metricset_path + "/" + metricset + ".go", "w") as f:
         f.write(content)
 
+    content = load_file(templates + "metricset_test.go.tmpl", module, metricset)
+    with open(metricset_path + "/" + metricset + "_test.go", "w") as f:
+        f.write(content)
+
     content = load_file(templates + "fields.yml", module, metricset)
     with open(meta_path + "/fields.yml", "w") as f:
         f.write(content)
@@ -11,10 +11,14 @@ import (
 	"github.com/elastic/beats/v7/libbeat/beat"
 	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/beats/v7/x-pack/filebeat/input/cloudfoundry"
+	"github.com/elastic/beats/v7/x-pack/filebeat/input/gcs"
 	"github.com/elastic/beats/v7/x-pack/filebeat/input/http_endpoint"
 	"github.com/elastic/beats/v7/x-pack/filebeat/input/httpjson"
 	"github.com/elastic/beats/v7/x-pack/filebeat/input/o365audit"
+	"github.com/elastic/beats/v7/x-pack/filebeat/input/osquery"
 	"github.com/elastic/beats/v7/x-pack/filebeat/input/s3"
+	"github.com/elastic/beats/v7/x-pack/filebeat/input/sql"
+	"github.com/elastic/beats/v7/x-pack/filebeat/input/websocket"
 )
 
 func Init(info beat.Info, log *logp.Logger, store beater.StateStore) []v2.Plugin {
@@ -27,9 +31,13 @@ func Init(info beat.Info, log *logp.Logger, store beater.StateStore) []v2.Plugin
 func xpackInputs(info beat.Info, log *logp.Logger, store beater.StateStore) []v2.Plugin {
 	return []v2.Plugin{
 		cloudfoundry.Plugin(),
+		gcs.Plugin(log, store),
 		http_endpoint.Plugin(),
 		httpjson.Plugin(log, store),
 		o365audit.Plugin(log, store),
+		osquery.Plugin(),
 		s3.Plugin(),
+		sql.Plugin(log, store),
+		websocket.Plugin(),
 	}
 }
@@ -0,0 +1,122 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gcs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"unicode/utf8"
+)
+
+type config struct {
+	// ProjectID is the project of the Pub/Sub subscription.
+	ProjectID string `config:"project_id"`
+
+	// Bucket is polled for new objects. When a subscription is configured, only
+	// the notifications of this bucket are processed.
+	Bucket string `config:"bucket"`
+
+	// Prefix limits the objects read to the names starting with it.
+	Prefix string `config:"prefix"`
+
+	// PollInterval is the time between two listings of the bucket.
+	PollInterval time.Duration `config:"poll_interval" validate:"positive,nonzero"`
+
+	// Subscription receives the notifications of the objects created in the
+	// buckets, instead of polling them.
+	Subscription subscriptionConfig `config:"subscription"`
+
+	// Format is the format of the content of the objects, it's detected from
+	// the content type and the name of every object by default.
+	Format format `config:"format"`
+
+	CSV csvConfig `config:"csv"`
+
+	// ExpandEventListFromField publishes every element of the array stored in
+	// this field of the JSON documents as an event.
+	ExpandEventListFromField string `config:"expand_event_list_from_field"`
+
+	// APITimeout is the maximum duration of the requests listing the objects.
+	APITimeout time.Duration `config:"api_timeout" validate:"positive,nonzero"`
+
+	// CredentialsFile is a JSON file containing the credentials and key.
+	CredentialsFile string `config:"credentials_file"`
+
+	// CredentialsJSON is a JSON blob containing the credentials and key.
+	CredentialsJSON []byte `config:"credentials_json"`
+}
+
+type subscriptionConfig struct {
+	Name                   string `config:"name"`
+	NumGoroutines          int    `config:"num_goroutines" validate:"positive,nonzero"`
+	MaxOutstandingMessages int    `config:"max_outstanding_messages" validate:"positive,nonzero"`
+}
+
+type csvConfig struct {
+	// Separator is the character separating the fields of the records.
+	Separator string `config:"separator"`
+}
+
+type format string
+
+const (
+	formatAuto   format = ""
+	formatLines  format = "lines"
+	formatNDJSON format = "ndjson"
+	formatCSV    format = "csv"
+)
+
+func (f *format) Unpack(s string) error {
+	switch v := format(s); v {
+	case formatAuto, "auto":
+		*f = formatAuto
+	case formatLines, formatNDJSON, formatCSV:
+		*f = v
+	default:
+		return fmt.Errorf("invalid format %q, valid values are auto, lines, ndjson and csv", s)
+	}
+	return nil
+}
+
+func defaultConfig() config {
+	return config{
+		PollInterval: time.Minute,
+		Subscription: subscriptionConfig{
+			NumGoroutines:          1,
+			MaxOutstandingMessages: 1000,
+		},
+		CSV:        csvConfig{Separator: ","},
+		APITimeout: 2 * time.Minute,
+	}
+}
+
+func (c *config) Validate() error {
+	if c.Bucket == "" && c.Subscription.Name == "" {
+		return errors.New("either bucket or subscription.name must be set")
+	}
+	if c.Subscription.Name != "" && c.ProjectID == "" {
+		return errors.New("project_id must be set to use a subscription")
+	}
+	if utf8.RuneCountInString(c.CSV.Separator) != 1 {
+		return fmt.Errorf("csv.separator must be a single character, got %q", c.CSV.Separator)
+	}
+	if c.CredentialsFile != "" {
+		if _, err := os.Stat(c.CredentialsFile); os.IsNotExist(err) {
+			return fmt.Errorf("credentials_file is configured, but the file %q cannot be found", c.CredentialsFile)
+		}
+	}
+	return nil
+}
+
+func (c *config) useSubscription() bool {
+	return c.Subscription.Name != ""
+}
+
+func (c *config) separator() rune {
+	r, _ := utf8.DecodeRuneInString(c.CSV.Separator)
+	return r
+}
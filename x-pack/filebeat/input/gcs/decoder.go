@@ -0,0 +1,278 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gcs
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/jsontransform"
+)
+
+// objectDecoder reads the content of the objects and creates their events.
+type objectDecoder struct {
+	format      format
+	separator   rune
+	expandField string
+}
+
+func newObjectDecoder(config config) *objectDecoder {
+	return &objectDecoder{
+		format:      config.Format,
+		separator:   config.separator(),
+		expandField: config.ExpandEventListFromField,
+	}
+}
+
+// decode reads the content of obj from r, and passes its events to publish.
+// Gzip compressed content is decompressed.
+func (d *objectDecoder) decode(obj objectInfo, r io.Reader, publish func(beat.Event) error) error {
+	reader := bufio.NewReader(r)
+	gzipped, err := isStreamGzipped(reader)
+	if err != nil {
+		return errors.Wrap(err, "failed to read object")
+	}
+	if gzipped {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return errors.Wrap(err, "failed to decompress object")
+		}
+		defer gz.Close()
+		reader = bufio.NewReader(gz)
+	}
+
+	hash := objectHash(obj)
+	switch d.formatOf(obj) {
+	case formatCSV:
+		return d.decodeCSV(obj, hash, reader, publish)
+	case formatNDJSON:
+		return d.decodeLines(obj, hash, reader, publish, d.jsonFields)
+	default:
+		return d.decodeLines(obj, hash, reader, publish, lineFields)
+	}
+}
+
+// formatOf returns the configured format, or the format detected from the
+// content type or the extension of obj.
+func (d *objectDecoder) formatOf(obj objectInfo) format {
+	if d.format != formatAuto {
+		return d.format
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(obj.ContentType); err == nil {
+		switch mediaType {
+		case "application/json", "application/x-ndjson", "application/x-json-stream", "application/jsonl":
+			return formatNDJSON
+		case "text/csv":
+			return formatCSV
+		}
+	}
+
+	switch path.Ext(strings.TrimSuffix(obj.Name, ".gz")) {
+	case ".json", ".ndjson", ".jsonl":
+		return formatNDJSON
+	case ".csv":
+		return formatCSV
+	default:
+		return formatLines
+	}
+}
+
+func (d *objectDecoder) decodeLines(obj objectInfo, hash string, reader *bufio.Reader, publish func(beat.Event) error, fields func(string) []common.MapStr) error {
+	var offset int64
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return errors.Wrapf(err, "failed to read line at offset %d", offset)
+		}
+
+		text := strings.TrimRight(line, "\r\n")
+		if text != "" {
+			list := fields(text)
+			for i, f := range list {
+				id := objectID(hash, offset)
+				if len(list) > 1 {
+					id += "-" + strconv.Itoa(i)
+				}
+				f.Put("log.offset", offset)
+				if err := publish(d.createEvent(obj, id, f)); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		offset += int64(len(line))
+	}
+}
+
+func lineFields(line string) []common.MapStr {
+	return []common.MapStr{{"message": line}}
+}
+
+// jsonFields decodes line as a JSON object. If the object can't be decoded,
+// the line is published in message with an error.
+func (d *objectDecoder) jsonFields(line string) []common.MapStr {
+	doc, err := decodeJSON(line)
+	if err != nil {
+		return []common.MapStr{jsonError(line, err)}
+	}
+	if d.expandField == "" {
+		return []common.MapStr{{"json": doc}}
+	}
+
+	values, ok := doc[d.expandField].([]interface{})
+	if !ok {
+		return []common.MapStr{jsonError(line, fmt.Errorf("field %s is not an array", d.expandField))}
+	}
+	list := make([]common.MapStr, 0, len(values))
+	for _, v := range values {
+		if m, ok := v.(map[string]interface{}); ok {
+			list = append(list, common.MapStr{"json": common.MapStr(m)})
+			continue
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return []common.MapStr{jsonError(line, err)}
+		}
+		list = append(list, common.MapStr{"message": string(encoded)})
+	}
+	return list
+}
+
+func decodeJSON(line string) (common.MapStr, error) {
+	var doc common.MapStr
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, errors.New("line is not a JSON object")
+	}
+	jsontransform.TransformNumbers(doc)
+	return doc, nil
+}
+
+func jsonError(line string, err error) common.MapStr {
+	return common.MapStr{
+		"message": line,
+		"error": common.MapStr{
+			"message": fmt.Sprintf("failed to decode JSON: %v", err),
+			"type":    "json",
+		},
+	}
+}
+
+// decodeCSV publishes an event for every record, with the values stored in
+// csv under the names of the header, the first record.
+func (d *objectDecoder) decodeCSV(obj objectInfo, hash string, reader io.Reader, publish func(beat.Event) error) error {
+	r := csv.NewReader(reader)
+	r.Comma = d.separator
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to read CSV header")
+	}
+
+	for n := int64(1); ; n++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "failed to read CSV record %d", n)
+		}
+
+		values := make(common.MapStr, len(record))
+		for i, v := range record {
+			name := "column" + strconv.Itoa(i+1)
+			if i < len(header) && header[i] != "" {
+				name = header[i]
+			}
+			values[name] = v
+		}
+		if err := publish(d.createEvent(obj, objectID(hash, n), common.MapStr{"csv": values})); err != nil {
+			return err
+		}
+	}
+}
+
+func (d *objectDecoder) createEvent(obj objectInfo, id string, fields common.MapStr) beat.Event {
+	fields.DeepUpdate(common.MapStr{
+		"log": common.MapStr{
+			"file": common.MapStr{
+				"path": objectURL(obj),
+			},
+		},
+		"gcs": common.MapStr{
+			"storage": common.MapStr{
+				"bucket": common.MapStr{
+					"name": obj.Bucket,
+				},
+				"object": common.MapStr{
+					"name":         obj.Name,
+					"content_type": obj.ContentType,
+				},
+			},
+		},
+		"cloud": common.MapStr{
+			"provider": "gcp",
+		},
+	})
+	event := beat.Event{
+		Timestamp: time.Now().UTC(),
+		Fields:    fields,
+	}
+	event.SetID(id)
+	return event
+}
+
+func isStreamGzipped(r *bufio.Reader) (bool, error) {
+	// Why 512? See https://godoc.org/net/http#DetectContentType
+	buf, err := r.Peek(512)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return http.DetectContentType(buf) == "application/x-gzip", nil
+}
+
+func objectURL(obj objectInfo) string {
+	return "gs://" + obj.Bucket + "/" + obj.Name
+}
+
+// objectHash returns a short sha256 hash of the bucket, the name and the
+// generation of obj, so a new version of an object gets new IDs.
+func objectHash(obj objectInfo) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s/%s#%d", obj.Bucket, obj.Name, obj.Generation)
+	return hex.EncodeToString(h.Sum(nil))[:10]
+}
+
+func objectID(hash string, offset int64) string {
+	return fmt.Sprintf("%s-%012d", hash, offset)
+}
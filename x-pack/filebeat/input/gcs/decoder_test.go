@@ -0,0 +1,154 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gcs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func decodeAll(t *testing.T, config config, obj objectInfo, content []byte) []beat.Event {
+	t.Helper()
+	var events []beat.Event
+	err := newObjectDecoder(config).decode(obj, bytes.NewReader(content), func(event beat.Event) error {
+		events = append(events, event)
+		return nil
+	})
+	require.NoError(t, err)
+	return events
+}
+
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestDecodeLines(t *testing.T) {
+	obj := objectInfo{Bucket: "logs", Name: "app/2021-01-01.log", ContentType: "text/plain"}
+	events := decodeAll(t, defaultConfig(), obj, []byte("first\r\n\nsecond\nthird"))
+	require.Len(t, events, 3)
+
+	var messages []interface{}
+	var offsets []interface{}
+	for _, event := range events {
+		message, _ := event.Fields.GetValue("message")
+		offset, _ := event.Fields.GetValue("log.offset")
+		messages = append(messages, message)
+		offsets = append(offsets, offset)
+	}
+	assert.Equal(t, []interface{}{"first", "second", "third"}, messages)
+	assert.Equal(t, []interface{}{int64(0), int64(8), int64(15)}, offsets)
+
+	assert.Equal(t, common.MapStr{
+		"message": "first",
+		"log": common.MapStr{
+			"offset": int64(0),
+			"file":   common.MapStr{"path": "gs://logs/app/2021-01-01.log"},
+		},
+		"gcs": common.MapStr{
+			"storage": common.MapStr{
+				"bucket": common.MapStr{"name": "logs"},
+				"object": common.MapStr{"name": "app/2021-01-01.log", "content_type": "text/plain"},
+			},
+		},
+		"cloud": common.MapStr{"provider": "gcp"},
+	}, events[0].Fields)
+	assert.Equal(t, objectID(objectHash(obj), 0), events[0].Meta["_id"])
+	assert.NotEqual(t, events[0].Meta["_id"], events[1].Meta["_id"])
+}
+
+func TestDecodeGzippedNDJSON(t *testing.T) {
+	obj := objectInfo{Bucket: "logs", Name: "events.json.gz"}
+	content := gzipped(t, `{"level":"info","count":1}`+"\n"+`not json`+"\n")
+	events := decodeAll(t, defaultConfig(), obj, content)
+	require.Len(t, events, 2)
+
+	level, _ := events[0].Fields.GetValue("json.level")
+	assert.Equal(t, "info", level)
+	count, _ := events[0].Fields.GetValue("json.count")
+	assert.Equal(t, int64(1), count)
+
+	message, _ := events[1].Fields.GetValue("message")
+	assert.Equal(t, "not json", message)
+	errorType, _ := events[1].Fields.GetValue("error.type")
+	assert.Equal(t, "json", errorType)
+}
+
+func TestDecodeExpandEventList(t *testing.T) {
+	config := defaultConfig()
+	config.Format = formatNDJSON
+	config.ExpandEventListFromField = "Records"
+
+	obj := objectInfo{Bucket: "logs", Name: "records"}
+	events := decodeAll(t, config, obj, []byte(`{"Records":[{"id":"a"},{"id":"b"},"c"]}`))
+	require.Len(t, events, 3)
+
+	id, _ := events[1].Fields.GetValue("json.id")
+	assert.Equal(t, "b", id)
+	message, _ := events[2].Fields.GetValue("message")
+	assert.Equal(t, `"c"`, message)
+	assert.Equal(t, objectID(objectHash(obj), 0)+"-1", events[1].Meta["_id"])
+}
+
+func TestDecodeCSV(t *testing.T) {
+	config := defaultConfig()
+	config.CSV.Separator = ";"
+
+	obj := objectInfo{Bucket: "reports", Name: "users.csv", ContentType: "text/csv; charset=utf-8"}
+	events := decodeAll(t, config, obj, []byte("name;role\nalice;admin\nbob;user;extra\n"))
+	require.Len(t, events, 2)
+
+	values, _ := events[0].Fields.GetValue("csv")
+	assert.Equal(t, common.MapStr{"name": "alice", "role": "admin"}, values)
+	values, _ = events[1].Fields.GetValue("csv")
+	assert.Equal(t, common.MapStr{"name": "bob", "role": "user", "column3": "extra"}, values)
+}
+
+func TestDecoderFormat(t *testing.T) {
+	tests := []struct {
+		name, contentType string
+		want              format
+	}{
+		{"app.log", "text/plain", formatLines},
+		{"app.log.gz", "application/gzip", formatLines},
+		{"events.ndjson.gz", "application/gzip", formatNDJSON},
+		{"events", "application/x-ndjson", formatNDJSON},
+		{"events.jsonl", "", formatNDJSON},
+		{"report.csv.gz", "", formatCSV},
+		{"report", "text/csv", formatCSV},
+	}
+	decoder := newObjectDecoder(defaultConfig())
+	for _, test := range tests {
+		obj := objectInfo{Name: test.name, ContentType: test.contentType}
+		assert.Equal(t, test.want, decoder.formatOf(obj), test.name)
+	}
+
+	config := defaultConfig()
+	config.Format = formatLines
+	assert.Equal(t, formatLines, newObjectDecoder(config).formatOf(objectInfo{Name: "events.json"}))
+}
+
+func TestDecodeStopsOnPublishError(t *testing.T) {
+	calls := 0
+	err := newObjectDecoder(defaultConfig()).decode(objectInfo{}, strings.NewReader("a\nb\n"), func(beat.Event) error {
+		calls++
+		return assert.AnError
+	})
+	assert.Equal(t, assert.AnError, err)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,188 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gcs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	v2 "github.com/elastic/beats/v7/filebeat/input/v2"
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/acker"
+	"github.com/elastic/beats/v7/libbeat/feature"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/elastic/go-concert/ctxtool"
+)
+
+const inputName = "gcs"
+
+// StateStore gives access to the registry, where the objects processed in the
+// polled buckets are stored.
+type StateStore interface {
+	Access() (*statestore.Store, error)
+}
+
+func Plugin(log *logp.Logger, store StateStore) v2.Plugin {
+	return v2.Plugin{
+		Name:       inputName,
+		Stability:  feature.Experimental,
+		Deprecated: false,
+		Info:       "Google Cloud Storage input",
+		Doc:        "The gcs input reads the objects stored in Google Cloud Storage buckets",
+		Manager: v2.ConfigureWith(func(cfg *common.Config) (v2.Input, error) {
+			config := defaultConfig()
+			if err := cfg.Unpack(&config); err != nil {
+				return nil, err
+			}
+			return &gcsInput{config: config, store: store}, nil
+		}),
+	}
+}
+
+type gcsInput struct {
+	config config
+	store  StateStore
+}
+
+func (*gcsInput) Name() string { return inputName }
+
+func (in *gcsInput) Test(ctx v2.TestContext) error {
+	storage, err := newGCSStorage(ctxtool.FromCanceller(ctx.Cancelation), in.config)
+	if err != nil {
+		return err
+	}
+	return storage.Close()
+}
+
+func (in *gcsInput) Run(ctx v2.Context, pipeline beat.Pipeline) error {
+	cancelCtx := ctxtool.FromCanceller(ctx.Cancelation)
+	log := ctx.Logger.With("bucket", in.config.Bucket)
+
+	client, err := pipeline.ConnectWith(beat.ClientConfig{
+		CloseRef:   ctx.Cancelation,
+		ACKHandler: newACKHandler(),
+	})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	storage, err := newGCSStorage(cancelCtx, in.config)
+	if err != nil {
+		return errors.Wrap(err, "failed to create storage client")
+	}
+	defer storage.Close()
+
+	processor := &objectProcessor{
+		storage: storage,
+		decoder: newObjectDecoder(in.config),
+		client:  client,
+	}
+
+	if in.config.useSubscription() {
+		log = log.With("subscription", in.config.Subscription.Name)
+		err = newReceiver(in.config, processor, log).run(cancelCtx)
+	} else {
+		var store *statestore.Store
+		store, err = in.store.Access()
+		if err != nil {
+			return errors.Wrap(err, "failed to access the registry")
+		}
+		defer store.Close()
+		err = newPoller(in.config, storage, processor, store, log).run(cancelCtx)
+	}
+
+	if cancelCtx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// objectProcessor publishes the events of the objects.
+type objectProcessor struct {
+	storage objectStorage
+	decoder *objectDecoder
+	client  beat.Client
+}
+
+// process reads obj and publishes its events. onDone is called once all the
+// events are acknowledged, it's not called if reading the object fails.
+func (p *objectProcessor) process(ctx context.Context, obj objectInfo, onDone func()) error {
+	r, contentType, err := p.storage.Open(ctx, obj)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open object %s", objectURL(obj))
+	}
+	defer r.Close()
+	if contentType != "" {
+		obj.ContentType = contentType
+	}
+
+	ack := &objectACK{onDone: onDone}
+	err = p.decoder.decode(obj, r, func(event beat.Event) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ack.add()
+		event.Private = ack
+		p.client.Publish(event)
+		return nil
+	})
+	ack.finish(err)
+	return errors.Wrapf(err, "failed to read object %s", objectURL(obj))
+}
+
+// objectACK counts the events of an object waiting for their acknowledgement.
+type objectACK struct {
+	mu       sync.Mutex
+	pending  int
+	finished bool
+	failed   bool
+	onDone   func()
+}
+
+func (a *objectACK) add() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending++
+}
+
+func (a *objectACK) acked() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending--
+	a.checkDone()
+}
+
+// finish is called once all the events of the object are published.
+func (a *objectACK) finish(err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.finished = true
+	a.failed = err != nil
+	a.checkDone()
+}
+
+func (a *objectACK) checkDone() {
+	if a.finished && !a.failed && a.pending == 0 && a.onDone != nil {
+		a.onDone()
+		a.onDone = nil
+	}
+}
+
+func newACKHandler() beat.ACKer {
+	return acker.ConnectionOnly(
+		acker.EventPrivateReporter(func(_ int, privates []interface{}) {
+			for _, private := range privates {
+				if ack, ok := private.(*objectACK); ok {
+					ack.acked()
+				}
+			}
+		}),
+	)
+}
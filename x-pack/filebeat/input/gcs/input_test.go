@@ -0,0 +1,309 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gcs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/elastic/beats/v7/libbeat/statestore/storetest"
+)
+
+type fakeObject struct {
+	info    objectInfo
+	content string
+	err     error
+}
+
+type fakeStorage struct {
+	objects []fakeObject
+	opened  []string
+}
+
+func (s *fakeStorage) List(_ context.Context, bucket, prefix string) ([]objectInfo, error) {
+	var objects []objectInfo
+	for _, o := range s.objects {
+		if o.info.Bucket == bucket && strings.HasPrefix(o.info.Name, prefix) {
+			objects = append(objects, o.info)
+		}
+	}
+	return objects, nil
+}
+
+func (s *fakeStorage) Open(_ context.Context, obj objectInfo) (io.ReadCloser, string, error) {
+	s.opened = append(s.opened, obj.Name)
+	for _, o := range s.objects {
+		if o.info.Bucket == obj.Bucket && o.info.Name == obj.Name {
+			if o.err != nil {
+				return nil, "", o.err
+			}
+			return ioutil.NopCloser(strings.NewReader(o.content)), o.info.ContentType, nil
+		}
+	}
+	return nil, "", errors.New("object not found")
+}
+
+// fakeClient acknowledges the events when they are published, or when ackAll
+// is called if hold is set.
+type fakeClient struct {
+	mu      sync.Mutex
+	hold    bool
+	events  []beat.Event
+	pending []*objectACK
+}
+
+func (c *fakeClient) Publish(event beat.Event) {
+	c.mu.Lock()
+	c.events = append(c.events, event)
+	ack := event.Private.(*objectACK)
+	if c.hold {
+		c.pending = append(c.pending, ack)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+	ack.acked()
+}
+
+func (c *fakeClient) PublishAll(events []beat.Event) {
+	for _, event := range events {
+		c.Publish(event)
+	}
+}
+
+func (c *fakeClient) Close() error { return nil }
+
+func (c *fakeClient) ackAll() {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.mu.Unlock()
+	for _, ack := range pending {
+		ack.acked()
+	}
+}
+
+func (c *fakeClient) messages() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var messages []string
+	for _, event := range c.events {
+		message, _ := event.Fields.GetValue("message")
+		messages = append(messages, message.(string))
+	}
+	return messages
+}
+
+func newTestStore(t *testing.T) *statestore.Store {
+	store, err := statestore.NewRegistry(storetest.NewMemoryStoreBackend()).Get("test")
+	require.NoError(t, err)
+	return store
+}
+
+func newTestPoller(storage objectStorage, client beat.Client, store *statestore.Store) *poller {
+	config := defaultConfig()
+	config.Bucket = "logs"
+	processor := &objectProcessor{
+		storage: storage,
+		decoder: newObjectDecoder(config),
+		client:  client,
+	}
+	return newPoller(config, storage, processor, store, logp.NewLogger("test"))
+}
+
+func storedState(t *testing.T, store *statestore.Store) pollState {
+	t.Helper()
+	var state pollState
+	has, err := store.Has("gcs::logs::")
+	require.NoError(t, err)
+	if has {
+		require.NoError(t, store.Get("gcs::logs::", &state))
+	}
+	return state
+}
+
+func TestPollerProcessesNewObjects(t *testing.T) {
+	t1 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Minute)
+	storage := &fakeStorage{objects: []fakeObject{
+		{info: objectInfo{Bucket: "logs", Name: "b.log", Updated: t2}, content: "b1\nb2\n"},
+		{info: objectInfo{Bucket: "logs", Name: "a.log", Updated: t1}, content: "a1\n"},
+		{info: objectInfo{Bucket: "other", Name: "c.log", Updated: t1}, content: "c1\n"},
+	}}
+	client := &fakeClient{}
+	store := newTestStore(t)
+
+	p := newTestPoller(storage, client, store)
+	p.poll(context.Background())
+	assert.Equal(t, []string{"a1", "b1", "b2"}, client.messages())
+	assert.Equal(t, pollState{Updated: t2, Names: []string{"b.log"}}, storedState(t, store))
+
+	storage.objects = append(storage.objects, fakeObject{
+		info:    objectInfo{Bucket: "logs", Name: "d.log", Updated: t2},
+		content: "d1\n",
+	})
+	p.poll(context.Background())
+	assert.Equal(t, []string{"a1", "b1", "b2", "d1"}, client.messages())
+	assert.Equal(t, pollState{Updated: t2, Names: []string{"b.log", "d.log"}}, storedState(t, store))
+
+	// A new poller resumes from the registry.
+	client = &fakeClient{}
+	p = newTestPoller(storage, client, store)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Equal(t, context.Canceled, p.run(ctx))
+	assert.Empty(t, client.messages())
+}
+
+func TestPollerStoresStateAfterACK(t *testing.T) {
+	t1 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	storage := &fakeStorage{objects: []fakeObject{
+		{info: objectInfo{Bucket: "logs", Name: "a.log", Updated: t1}, content: "a1\n"},
+		{info: objectInfo{Bucket: "logs", Name: "b.log", Updated: t1.Add(time.Minute)}, content: ""},
+	}}
+	client := &fakeClient{hold: true}
+	store := newTestStore(t)
+
+	p := newTestPoller(storage, client, store)
+	p.poll(context.Background())
+	assert.Equal(t, []string{"a1"}, client.messages())
+	// The empty object is done, but the events of the previous one are not
+	// acknowledged yet.
+	assert.Equal(t, pollState{}, storedState(t, store))
+
+	client.ackAll()
+	assert.Equal(t, pollState{Updated: t1.Add(time.Minute), Names: []string{"b.log"}}, storedState(t, store))
+}
+
+func TestPollerRetriesFailedObjects(t *testing.T) {
+	t1 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	storage := &fakeStorage{objects: []fakeObject{
+		{info: objectInfo{Bucket: "logs", Name: "a.log", Updated: t1}, content: "a1\n"},
+		{info: objectInfo{Bucket: "logs", Name: "b.log", Updated: t1.Add(time.Minute)}, err: errors.New("unavailable")},
+		{info: objectInfo{Bucket: "logs", Name: "c.log", Updated: t1.Add(2 * time.Minute)}, content: "c1\n"},
+	}}
+	client := &fakeClient{}
+	store := newTestStore(t)
+
+	p := newTestPoller(storage, client, store)
+	p.poll(context.Background())
+	assert.Equal(t, []string{"a1"}, client.messages())
+	assert.Equal(t, pollState{Updated: t1, Names: []string{"a.log"}}, storedState(t, store))
+
+	storage.objects[1].err = nil
+	storage.objects[1].content = "b1\n"
+	p.poll(context.Background())
+	assert.Equal(t, []string{"a1", "b1", "c1"}, client.messages())
+	assert.Equal(t, []string{"a.log", "b.log", "b.log", "c.log"}, storage.opened)
+}
+
+func TestReceiverHandle(t *testing.T) {
+	storage := &fakeStorage{objects: []fakeObject{
+		{info: objectInfo{Bucket: "logs", Name: "app/a.log"}, content: "a1\na2\n"},
+		{info: objectInfo{Bucket: "logs", Name: "app/b.log"}, err: errors.New("unavailable")},
+	}}
+	client := &fakeClient{hold: true}
+
+	config := defaultConfig()
+	config.Bucket = "logs"
+	config.Prefix = "app/"
+	r := newReceiver(config, &objectProcessor{
+		storage: storage,
+		decoder: newObjectDecoder(config),
+		client:  client,
+	}, logp.NewLogger("test"))
+
+	handle := func(attributes map[string]string) (acked, nacked *bool) {
+		acked, nacked = new(bool), new(bool)
+		r.handle(context.Background(), attributes, func() { *acked = true }, func() { *nacked = true })
+		return acked, nacked
+	}
+	notification := func(eventType, bucket, name string) map[string]string {
+		return map[string]string{"eventType": eventType, "bucketId": bucket, "objectId": name, "objectGeneration": "3"}
+	}
+
+	acked, nacked := handle(notification("OBJECT_FINALIZE", "logs", "app/a.log"))
+	assert.Equal(t, []string{"a1", "a2"}, client.messages())
+	assert.False(t, *acked)
+	client.ackAll()
+	assert.True(t, *acked)
+	assert.False(t, *nacked)
+	assert.Equal(t, objectID(objectHash(objectInfo{Bucket: "logs", Name: "app/a.log", Generation: 3}), 0), client.events[0].Meta["_id"])
+
+	acked, nacked = handle(notification("OBJECT_FINALIZE", "logs", "app/b.log"))
+	assert.False(t, *acked)
+	assert.True(t, *nacked)
+
+	for _, attributes := range []map[string]string{
+		notification("OBJECT_DELETE", "logs", "app/a.log"),
+		notification("OBJECT_FINALIZE", "other", "app/a.log"),
+		notification("OBJECT_FINALIZE", "logs", "other/a.log"),
+		{"eventType": "OBJECT_FINALIZE"},
+	} {
+		acked, nacked = handle(attributes)
+		assert.True(t, *acked, attributes)
+		assert.False(t, *nacked, attributes)
+	}
+	assert.Equal(t, []string{"app/a.log", "app/b.log"}, storage.opened)
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		config  map[string]interface{}
+		wantErr string
+	}{
+		"bucket": {
+			config: map[string]interface{}{"bucket": "logs"},
+		},
+		"subscription": {
+			config: map[string]interface{}{"project_id": "project", "subscription.name": "logs"},
+		},
+		"missing bucket": {
+			config:  map[string]interface{}{},
+			wantErr: "either bucket or subscription.name must be set",
+		},
+		"subscription without project": {
+			config:  map[string]interface{}{"subscription.name": "logs"},
+			wantErr: "project_id must be set",
+		},
+		"invalid separator": {
+			config:  map[string]interface{}{"bucket": "logs", "csv.separator": ";;"},
+			wantErr: "csv.separator must be a single character",
+		},
+		"invalid format": {
+			config:  map[string]interface{}{"bucket": "logs", "format": "xml"},
+			wantErr: "invalid format",
+		},
+		"missing credentials file": {
+			config:  map[string]interface{}{"bucket": "logs", "credentials_file": "/does/not/exist.json"},
+			wantErr: "cannot be found",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := defaultConfig()
+			err := common.MustNewConfigFrom(test.config).Unpack(&config)
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.wantErr)
+		})
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gcs
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/statestore"
+	"github.com/elastic/go-concert/timed"
+)
+
+// pollState is the state of a polled bucket stored in the registry. The
+// objects updated before Updated are processed, as the objects in Names,
+// updated at Updated.
+type pollState struct {
+	Updated time.Time `json:"updated" struct:"updated"`
+	Names   []string  `json:"names" struct:"names"`
+}
+
+func (s *pollState) processed(obj objectInfo) bool {
+	if obj.Updated.Before(s.Updated) {
+		return true
+	}
+	return obj.Updated.Equal(s.Updated) && s.contains(obj.Name)
+}
+
+func (s *pollState) add(obj objectInfo) {
+	switch {
+	case obj.Updated.After(s.Updated):
+		s.Updated = obj.Updated
+		s.Names = []string{obj.Name}
+	case obj.Updated.Equal(s.Updated) && !s.contains(obj.Name):
+		s.Names = append(s.Names, obj.Name)
+	}
+}
+
+func (s *pollState) contains(name string) bool {
+	for _, n := range s.Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s pollState) clone() pollState {
+	s.Names = append([]string(nil), s.Names...)
+	return s
+}
+
+// poller lists a bucket periodically and processes the objects in the order
+// they are updated.
+type poller struct {
+	config    config
+	storage   objectStorage
+	processor *objectProcessor
+	store     *statestore.Store
+	key       string
+	log       *logp.Logger
+
+	// listed contains the objects processed, including the objects whose
+	// events are not acknowledged yet.
+	listed pollState
+
+	mu sync.Mutex
+	// inflight contains the objects whose events are not acknowledged yet,
+	// in the order they are processed.
+	inflight []*inflightObject
+	acked    pollState
+}
+
+type inflightObject struct {
+	obj  objectInfo
+	done bool
+}
+
+func newPoller(config config, storage objectStorage, processor *objectProcessor, store *statestore.Store, log *logp.Logger) *poller {
+	return &poller{
+		config:    config,
+		storage:   storage,
+		processor: processor,
+		store:     store,
+		key:       inputName + "::" + config.Bucket + "::" + config.Prefix,
+		log:       log,
+	}
+}
+
+func (p *poller) run(ctx context.Context) error {
+	has, err := p.store.Has(p.key)
+	if err != nil {
+		return err
+	}
+	if has {
+		if err := p.store.Get(p.key, &p.acked); err != nil {
+			return err
+		}
+	}
+	p.listed = p.acked.clone()
+
+	p.poll(ctx)
+	return timed.Periodic(ctx, p.config.PollInterval, func() error {
+		p.poll(ctx)
+		return nil
+	})
+}
+
+// poll processes the objects not processed yet. It stops at the first object
+// that fails, the object is retried by the next poll.
+func (p *poller) poll(ctx context.Context) {
+	listCtx, cancel := context.WithTimeout(ctx, p.config.APITimeout)
+	objects, err := p.storage.List(listCtx, p.config.Bucket, p.config.Prefix)
+	cancel()
+	if err != nil {
+		p.log.Errorf("Failed to list objects: %v", err)
+		return
+	}
+
+	pending := objects[:0]
+	for _, obj := range objects {
+		if !p.listed.processed(obj) {
+			pending = append(pending, obj)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].Updated.Equal(pending[j].Updated) {
+			return pending[i].Updated.Before(pending[j].Updated)
+		}
+		return pending[i].Name < pending[j].Name
+	})
+
+	for _, obj := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		inflight := p.track(obj)
+		if err := p.processor.process(ctx, obj, func() { p.ack(inflight) }); err != nil {
+			p.untrack(inflight)
+			if ctx.Err() == nil {
+				p.log.Errorf("Failed to process object, it will be retried: %v", err)
+			}
+			return
+		}
+		p.listed.add(obj)
+	}
+}
+
+func (p *poller) track(obj objectInfo) *inflightObject {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	inflight := &inflightObject{obj: obj}
+	p.inflight = append(p.inflight, inflight)
+	return inflight
+}
+
+// ack is called once all the events of an object are acknowledged. The state
+// is stored once the objects processed before are acknowledged too, as an
+// object without events is done before the events of the previous objects are
+// acknowledged.
+func (p *poller) ack(inflight *inflightObject) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	inflight.done = true
+	p.advance()
+}
+
+// untrack removes an object that failed, so its events are never
+// acknowledged.
+func (p *poller) untrack(inflight *inflightObject) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, o := range p.inflight {
+		if o == inflight {
+			p.inflight = append(p.inflight[:i], p.inflight[i+1:]...)
+			break
+		}
+	}
+	p.advance()
+}
+
+func (p *poller) advance() {
+	updated := false
+	for len(p.inflight) > 0 && p.inflight[0].done {
+		p.acked.add(p.inflight[0].obj)
+		p.inflight = p.inflight[1:]
+		updated = true
+	}
+	if !updated {
+		return
+	}
+	if err := p.store.Set(p.key, p.acked); err != nil {
+		p.log.Errorf("Failed to store the state of the bucket: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gcs
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/pubsub"
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+// receiver processes the objects notified in a Pub/Sub subscription. The
+// notifications are acknowledged once all the events of their object are
+// acknowledged, so the objects that fail are notified again.
+type receiver struct {
+	config    config
+	processor *objectProcessor
+	log       *logp.Logger
+}
+
+func newReceiver(config config, processor *objectProcessor, log *logp.Logger) *receiver {
+	return &receiver{config: config, processor: processor, log: log}
+}
+
+func (r *receiver) run(ctx context.Context) error {
+	client, err := pubsub.NewClient(ctx, r.config.ProjectID, clientOptions(r.config)...)
+	if err != nil {
+		return errors.Wrap(err, "failed to create pub/sub client")
+	}
+	defer client.Close()
+
+	sub := client.Subscription(r.config.Subscription.Name)
+	sub.ReceiveSettings.NumGoroutines = r.config.Subscription.NumGoroutines
+	sub.ReceiveSettings.MaxOutstandingMessages = r.config.Subscription.MaxOutstandingMessages
+
+	return sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+		r.handle(ctx, msg.Attributes, msg.Ack, msg.Nack)
+	})
+}
+
+// handle processes the object of a notification. The notifications of other
+// events and of the objects not matching the bucket or the prefix are
+// acknowledged without being processed.
+func (r *receiver) handle(ctx context.Context, attributes map[string]string, ack, nack func()) {
+	if attributes["eventType"] != "OBJECT_FINALIZE" {
+		ack()
+		return
+	}
+
+	obj := objectInfo{
+		Bucket: attributes["bucketId"],
+		Name:   attributes["objectId"],
+	}
+	if obj.Bucket == "" || obj.Name == "" {
+		r.log.Warnf("Ignoring notification without bucketId or objectId: %v", attributes)
+		ack()
+		return
+	}
+	if (r.config.Bucket != "" && obj.Bucket != r.config.Bucket) || !strings.HasPrefix(obj.Name, r.config.Prefix) {
+		ack()
+		return
+	}
+	if generation, err := strconv.ParseInt(attributes["objectGeneration"], 10, 64); err == nil {
+		obj.Generation = generation
+	}
+
+	if err := r.processor.process(ctx, obj, ack); err != nil {
+		if ctx.Err() == nil {
+			r.log.Errorf("Failed to process object, it will be notified again: %v", err)
+		}
+		nack()
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gcs
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	"github.com/elastic/beats/v7/libbeat/common/useragent"
+)
+
+// objectInfo identifies an object stored in a bucket.
+type objectInfo struct {
+	Bucket      string
+	Name        string
+	ContentType string
+	Generation  int64
+	Updated     time.Time
+}
+
+// objectStorage lists and reads the objects of the buckets.
+type objectStorage interface {
+	// List returns the objects of bucket whose name starts with prefix.
+	List(ctx context.Context, bucket, prefix string) ([]objectInfo, error)
+
+	// Open returns a reader of the content of obj, and its content type.
+	Open(ctx context.Context, obj objectInfo) (io.ReadCloser, string, error)
+}
+
+type gcsStorage struct {
+	client *storage.Client
+}
+
+func newGCSStorage(ctx context.Context, config config) (*gcsStorage, error) {
+	client, err := storage.NewClient(ctx, clientOptions(config)...)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsStorage{client: client}, nil
+}
+
+func clientOptions(config config) []option.ClientOption {
+	opts := []option.ClientOption{option.WithUserAgent(useragent.UserAgent("Filebeat"))}
+	if config.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(config.CredentialsFile))
+	} else if len(config.CredentialsJSON) > 0 {
+		opts = append(opts, option.WithCredentialsJSON(config.CredentialsJSON))
+	}
+	return opts
+}
+
+func (s *gcsStorage) List(ctx context.Context, bucket, prefix string) ([]objectInfo, error) {
+	var objects []objectInfo
+	it := s.client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			return objects, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		// Skip the placeholders of the folders created by the console.
+		if strings.HasSuffix(attrs.Name, "/") && attrs.Size == 0 {
+			continue
+		}
+		objects = append(objects, objectInfo{
+			Bucket:      attrs.Bucket,
+			Name:        attrs.Name,
+			ContentType: attrs.ContentType,
+			Generation:  attrs.Generation,
+			Updated:     attrs.Updated,
+		})
+	}
+}
+
+func (s *gcsStorage) Open(ctx context.Context, obj objectInfo) (io.ReadCloser, string, error) {
+	handle := s.client.Bucket(obj.Bucket).Object(obj.Name)
+	if obj.Generation != 0 {
+		handle = handle.Generation(obj.Generation)
+	}
+	r, err := handle.NewReader(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return r, r.ContentType(), nil
+}
+
+func (s *gcsStorage) Close() error {
+	return s.client.Close()
+}
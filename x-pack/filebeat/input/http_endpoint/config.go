@@ -7,25 +7,31 @@ package http_endpoint
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 
 	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
 )
 
 // Config contains information about httpjson configuration
 type config struct {
-	TLS           *tlscommon.ServerConfig `config:"ssl"`
-	BasicAuth     bool                    `config:"basic_auth"`
-	Username      string                  `config:"username"`
-	Password      string                  `config:"password"`
-	ResponseCode  int                     `config:"response_code" validate:"positive"`
-	ResponseBody  string                  `config:"response_body"`
-	ListenAddress string                  `config:"listen_address"`
-	ListenPort    string                  `config:"listen_port"`
-	URL           string                  `config:"url"`
-	Prefix        string                  `config:"prefix"`
-	ContentType   string                  `config:"content_type"`
-	SecretHeader  string                  `config:"secret.header"`
-	SecretValue   string                  `config:"secret.value"`
+	TLS               *tlscommon.ServerConfig `config:"ssl"`
+	BasicAuth         bool                    `config:"basic_auth"`
+	Username          string                  `config:"username"`
+	Password          string                  `config:"password"`
+	ResponseCode      int                     `config:"response_code" validate:"positive"`
+	ResponseBody      string                  `config:"response_body"`
+	ErrorResponseBody string                  `config:"error_response_body"`
+	ListenAddress     string                  `config:"listen_address"`
+	ListenPort        string                  `config:"listen_port"`
+	URL               string                  `config:"url"`
+	Prefix            string                  `config:"prefix"`
+	ContentType       string                  `config:"content_type"`
+	SecretHeader      string                  `config:"secret.header"`
+	SecretValue       string                  `config:"secret.value"`
+	HMACHeader        string                  `config:"hmac.header"`
+	HMACKey           string                  `config:"hmac.key"`
+	HMACType          string                  `config:"hmac.type"`
+	HMACPrefix        string                  `config:"hmac.prefix"`
 }
 
 func defaultConfig() config {
@@ -42,6 +48,7 @@ func defaultConfig() config {
 		ContentType:   "application/json",
 		SecretHeader:  "",
 		SecretValue:   "",
+		HMACType:      "sha256",
 	}
 }
 
@@ -50,6 +57,10 @@ func (c *config) Validate() error {
 		return errors.New("response_body must be valid JSON")
 	}
 
+	if c.ErrorResponseBody != "" && !json.Valid([]byte(c.ErrorResponseBody)) {
+		return errors.New("error_response_body must be valid JSON")
+	}
+
 	if c.BasicAuth {
 		if c.Username == "" || c.Password == "" {
 			return errors.New("Username and password required when basicauth is enabled")
@@ -60,5 +71,13 @@ func (c *config) Validate() error {
 		return errors.New("Both secret.header and secret.value must be set")
 	}
 
+	if (c.HMACHeader != "" && c.HMACKey == "") || (c.HMACHeader == "" && c.HMACKey != "") {
+		return errors.New("Both hmac.header and hmac.key must be set")
+	}
+
+	if _, ok := hmacHashes[c.HMACType]; c.HMACHeader != "" && !ok {
+		return fmt.Errorf("Invalid hmac.type %q, valid values are sha1, sha256 and sha512", c.HMACType)
+	}
+
 	return nil
 }
@@ -21,37 +21,64 @@ import (
 )
 
 type httpHandler struct {
-	log       *logp.Logger
-	publisher stateless.Publisher
-
-	messageField string
-	responseCode int
-	responseBody string
+	log           *logp.Logger
+	publisher     stateless.Publisher
+	bodyValidator bodyValidator
+
+	messageField      string
+	responseCode      int
+	responseBody      string
+	errorResponseBody string
 }
 
 var errBodyEmpty = errors.New("Body cannot be empty")
-var errUnsupportedType = errors.New("Only JSON objects are accepted")
+var errUnsupportedType = errors.New("Only JSON objects or arrays of objects are accepted")
 
 // Triggers if middleware validation returns successful
 func (h *httpHandler) apiResponse(w http.ResponseWriter, r *http.Request) {
-	obj, status, err := httpReadJsonObject(r.Body)
+	body, status, err := httpReadBody(r.Body)
 	if err != nil {
-		w.Header().Add("Content-Type", "application/json")
-		sendErrorResponse(w, status, err)
+		h.sendErrorResponse(w, status, err)
 		return
 	}
 
-	h.publishEvent(obj)
-	w.Header().Add("Content-Type", "application/json")
+	if h.bodyValidator != nil {
+		if status, err := h.bodyValidator.ValidateBody(r, body); status != 0 && err != nil {
+			h.sendErrorResponse(w, status, err)
+			return
+		}
+	}
+
+	objs, status, err := httpReadJSON(body)
+	if err != nil {
+		h.sendErrorResponse(w, status, err)
+		return
+	}
+
+	for _, obj := range objs {
+		h.publishEvent(obj, r)
+	}
 	h.sendResponse(w, h.responseCode, h.responseBody)
 }
 
 func (h *httpHandler) sendResponse(w http.ResponseWriter, status int, message string) {
+	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(status)
 	io.WriteString(w, message)
 }
 
-func (h *httpHandler) publishEvent(obj common.MapStr) {
+// sendErrorResponse replies with the error, or with the configured error
+// response body.
+func (h *httpHandler) sendErrorResponse(w http.ResponseWriter, status int, err error) {
+	if h.errorResponseBody == "" {
+		sendErrorResponse(w, status, err)
+		return
+	}
+	h.log.Debugf("Rejecting request with status %d: %v", status, err)
+	h.sendResponse(w, status, h.errorResponseBody)
+}
+
+func (h *httpHandler) publishEvent(obj common.MapStr, r *http.Request) {
 	event := beat.Event{
 		Timestamp: time.Now().UTC(),
 		Fields: common.MapStr{
@@ -59,15 +86,26 @@ func (h *httpHandler) publishEvent(obj common.MapStr) {
 		},
 	}
 
+	// Identify the sender when a client certificate is verified.
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		cert := r.TLS.PeerCertificates[0]
+		event.Fields["tls"] = common.MapStr{
+			"client": common.MapStr{
+				"subject": cert.Subject.String(),
+				"issuer":  cert.Issuer.String(),
+			},
+		}
+	}
+
 	h.publisher.Publish(event)
 }
 
-func withValidator(v validator, handler http.HandlerFunc) http.HandlerFunc {
+func withValidator(v validator, h *httpHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if status, err := v.ValidateHeader(r); status != 0 && err != nil {
-			sendErrorResponse(w, status, err)
+			h.sendErrorResponse(w, status, err)
 		} else {
-			handler(w, r)
+			h.apiResponse(w, r)
 		}
 	}
 }
@@ -78,32 +116,52 @@ func sendErrorResponse(w http.ResponseWriter, status int, err error) {
 	fmt.Fprintf(w, `{"message": %q}`, err.Error())
 }
 
-func httpReadJsonObject(body io.Reader) (obj common.MapStr, status int, err error) {
+func httpReadBody(body io.Reader) (contents []byte, status int, err error) {
 	if body == http.NoBody {
 		return nil, http.StatusNotAcceptable, errBodyEmpty
 	}
 
-	contents, err := ioutil.ReadAll(body)
+	contents, err = ioutil.ReadAll(body)
 	if err != nil {
 		return nil, http.StatusInternalServerError, fmt.Errorf("failed reading body: %w", err)
 	}
+	return contents, 0, nil
+}
 
-	if !isObject(contents) {
-		return nil, http.StatusBadRequest, errUnsupportedType
-	}
+// httpReadJSON decodes a JSON object, or a batch of objects sent as an array.
+func httpReadJSON(contents []byte) (objs []common.MapStr, status int, err error) {
+	switch firstChar(contents) {
+	case '{':
+		obj := common.MapStr{}
+		if err := json.Unmarshal(contents, &obj); err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("Malformed JSON body: %w", err)
+		}
+		return []common.MapStr{obj}, 0, nil
 
-	obj = common.MapStr{}
-	if err := json.Unmarshal(contents, &obj); err != nil {
-		return nil, http.StatusBadRequest, fmt.Errorf("Malformed JSON body: %w", err)
-	}
+	case '[':
+		var list []interface{}
+		if err := json.Unmarshal(contents, &list); err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("Malformed JSON body: %w", err)
+		}
+		objs = make([]common.MapStr, 0, len(list))
+		for _, v := range list {
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return nil, http.StatusBadRequest, errUnsupportedType
+			}
+			objs = append(objs, common.MapStr(obj))
+		}
+		return objs, 0, nil
 
-	return obj, 0, nil
+	default:
+		return nil, http.StatusBadRequest, errUnsupportedType
+	}
 }
 
-func isObject(b []byte) bool {
-	obj := bytes.TrimLeft(b, " \t\r\n")
-	if len(obj) > 0 && obj[0] == '{' {
-		return true
+func firstChar(b []byte) byte {
+	b = bytes.TrimLeft(b, " \t\r\n")
+	if len(b) == 0 {
+		return 0
 	}
-	return false
+	return b[0]
 }
@@ -0,0 +1,187 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package http_endpoint
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+type publisher struct {
+	events []beat.Event
+}
+
+func (p *publisher) Publish(event beat.Event) {
+	p.events = append(p.events, event)
+}
+
+func newTestHandler(config config, pub *publisher) http.HandlerFunc {
+	return newHandler(config, logp.NewLogger("test"), pub)
+}
+
+func post(handler http.HandlerFunc, body string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestHandlerBatch(t *testing.T) {
+	pub := &publisher{}
+	handler := newTestHandler(defaultConfig(), pub)
+
+	w := post(handler, ` [{"id": 1}, {"id": 2, "tags": ["a"]}]`, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"message": "success"}`, w.Body.String())
+	require.Len(t, pub.events, 2)
+	assert.Equal(t, common.MapStr{"json": common.MapStr{"id": float64(1)}}, pub.events[0].Fields)
+	assert.Equal(t, common.MapStr{"json": common.MapStr{"id": float64(2), "tags": []interface{}{"a"}}}, pub.events[1].Fields)
+
+	w = post(handler, `{"id": 3}`, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, pub.events, 3)
+
+	for _, body := range []string{`[{"id": 4}, "text"]`, `"text"`, `[{"id": 4}`} {
+		w = post(handler, body, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	assert.Len(t, pub.events, 3)
+}
+
+func TestHandlerHMAC(t *testing.T) {
+	config := defaultConfig()
+	config.HMACHeader = "X-Hub-Signature-256"
+	config.HMACKey = "secret"
+	config.HMACPrefix = "sha256="
+	pub := &publisher{}
+	handler := newTestHandler(config, pub)
+
+	body := `{"action": "opened"}`
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(body))
+	signature := mac.Sum(nil)
+
+	tests := map[string]struct {
+		signature string
+		status    int
+	}{
+		"hex":            {"sha256=" + hex.EncodeToString(signature), http.StatusOK},
+		"base64":         {"sha256=" + base64.StdEncoding.EncodeToString(signature), http.StatusOK},
+		"missing":        {"", http.StatusUnauthorized},
+		"missing prefix": {hex.EncodeToString(signature), http.StatusUnauthorized},
+		"wrong":          {"sha256=" + hex.EncodeToString([]byte("wrong")), http.StatusUnauthorized},
+		"invalid":        {"sha256=not hex", http.StatusUnauthorized},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			header := http.Header{}
+			if test.signature != "" {
+				header.Set(config.HMACHeader, test.signature)
+			}
+			w := post(handler, body, header)
+			assert.Equal(t, test.status, w.Code)
+		})
+	}
+	assert.Len(t, pub.events, 2)
+}
+
+func TestHandlerErrorResponseBody(t *testing.T) {
+	pub := &publisher{}
+	w := post(newTestHandler(defaultConfig(), pub), `"text"`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"message": "Only JSON objects or arrays of objects are accepted"}`, w.Body.String())
+
+	config := defaultConfig()
+	config.ErrorResponseBody = `{"status": "rejected"}`
+	handler := newTestHandler(config, pub)
+	w = post(handler, `"text"`, nil)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"status": "rejected"}`, w.Body.String())
+	assert.Equal(t, []string{"application/json"}, w.Header()["Content-Type"])
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w = httptest.NewRecorder()
+	handler(w, r)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	assert.Equal(t, `{"status": "rejected"}`, w.Body.String())
+}
+
+func TestHandlerClientCertificate(t *testing.T) {
+	pub := &publisher{}
+	handler := newTestHandler(defaultConfig(), pub)
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"id": 1}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{
+			Subject: pkix.Name{CommonName: "webhooks.example.com", Organization: []string{"Example"}},
+			Issuer:  pkix.Name{CommonName: "Example CA"},
+		}},
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	require.Len(t, pub.events, 1)
+	subject, _ := pub.events[0].Fields.GetValue("tls.client.subject")
+	assert.Equal(t, "CN=webhooks.example.com,O=Example", subject)
+	issuer, _ := pub.events[0].Fields.GetValue("tls.client.issuer")
+	assert.Equal(t, "CN=Example CA", issuer)
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		config  map[string]interface{}
+		wantErr string
+	}{
+		"hmac": {
+			config: map[string]interface{}{"hmac.header": "X-Signature", "hmac.key": "secret", "hmac.type": "sha1"},
+		},
+		"hmac without key": {
+			config:  map[string]interface{}{"hmac.header": "X-Signature"},
+			wantErr: "Both hmac.header and hmac.key must be set",
+		},
+		"invalid hmac type": {
+			config:  map[string]interface{}{"hmac.header": "X-Signature", "hmac.key": "secret", "hmac.type": "md5"},
+			wantErr: "Invalid hmac.type",
+		},
+		"invalid error response body": {
+			config:  map[string]interface{}{"error_response_body": "rejected"},
+			wantErr: "error_response_body must be valid JSON",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := defaultConfig()
+			err := common.MustNewConfigFrom(test.config).Unpack(&config)
+			if test.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.wantErr)
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/elastic/beats/v7/libbeat/common"
 	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
 	"github.com/elastic/beats/v7/libbeat/feature"
+	"github.com/elastic/beats/v7/libbeat/logp"
 	"github.com/elastic/go-concert/ctxtool"
 )
 
@@ -82,26 +83,8 @@ func (e *httpEndpoint) Test(_ v2.TestContext) error {
 func (e *httpEndpoint) Run(ctx v2.Context, publisher stateless.Publisher) error {
 	log := ctx.Logger.With("address", e.addr)
 
-	validator := &apiValidator{
-		basicAuth:    e.config.BasicAuth,
-		username:     e.config.Username,
-		password:     e.config.Password,
-		method:       http.MethodPost,
-		contentType:  e.config.ContentType,
-		secretHeader: e.config.SecretHeader,
-		secretValue:  e.config.SecretValue,
-	}
-
-	handler := &httpHandler{
-		log:          log,
-		publisher:    publisher,
-		messageField: e.config.Prefix,
-		responseCode: e.config.ResponseCode,
-		responseBody: e.config.ResponseBody,
-	}
-
 	mux := http.NewServeMux()
-	mux.HandleFunc(e.config.URL, withValidator(validator, handler.apiResponse))
+	mux.HandleFunc(e.config.URL, newHandler(e.config, log, publisher))
 	server := &http.Server{Addr: e.addr, TLSConfig: e.tlsConfig, Handler: mux}
 	_, cancel := ctxtool.WithFunc(ctxtool.FromCanceller(ctx.Cancelation), func() {
 		server.Close()
@@ -123,3 +106,36 @@ func (e *httpEndpoint) Run(ctx v2.Context, publisher stateless.Publisher) error
 	}
 	return nil
 }
+
+// newHandler returns the handler of the requests, publishing their body once
+// they are validated.
+func newHandler(config config, log *logp.Logger, publisher stateless.Publisher) http.HandlerFunc {
+	validator := &apiValidator{
+		basicAuth:    config.BasicAuth,
+		username:     config.Username,
+		password:     config.Password,
+		method:       http.MethodPost,
+		contentType:  config.ContentType,
+		secretHeader: config.SecretHeader,
+		secretValue:  config.SecretValue,
+	}
+
+	handler := &httpHandler{
+		log:               log,
+		publisher:         publisher,
+		messageField:      config.Prefix,
+		responseCode:      config.ResponseCode,
+		responseBody:      config.ResponseBody,
+		errorResponseBody: config.ErrorResponseBody,
+	}
+	if config.HMACHeader != "" {
+		handler.bodyValidator = &hmacValidator{
+			header: config.HMACHeader,
+			key:    []byte(config.HMACKey),
+			hash:   hmacHashes[config.HMACType],
+			prefix: config.HMACPrefix,
+		}
+	}
+
+	return withValidator(validator, handler)
+}
@@ -5,9 +5,17 @@
 package http_endpoint
 
 import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"net/http"
+	"strings"
 )
 
 type validator interface {
@@ -52,3 +60,52 @@ func (v *apiValidator) ValidateHeader(r *http.Request) (int, error) {
 
 	return 0, nil
 }
+
+// bodyValidator checks the body of the requests, once it has been read.
+type bodyValidator interface {
+	ValidateBody(*http.Request, []byte) (int, error)
+}
+
+var hmacHashes = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+var errMissingHMACHeader = errors.New("Missing HMAC signature header")
+var errIncorrectHMAC = errors.New("Incorrect HMAC signature")
+
+// hmacValidator checks the HMAC signature of the body sent in a header, hex
+// or base64 encoded, after an optional prefix like the "sha256=" of GitHub.
+type hmacValidator struct {
+	header string
+	key    []byte
+	hash   func() hash.Hash
+	prefix string
+}
+
+func (v *hmacValidator) ValidateBody(r *http.Request, body []byte) (int, error) {
+	signature := r.Header.Get(v.header)
+	if signature == "" {
+		return http.StatusUnauthorized, errMissingHMACHeader
+	}
+	if !strings.HasPrefix(signature, v.prefix) {
+		return http.StatusUnauthorized, errIncorrectHMAC
+	}
+	signature = strings.TrimPrefix(signature, v.prefix)
+
+	decoded, err := hex.DecodeString(signature)
+	if err != nil {
+		decoded, err = base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			return http.StatusUnauthorized, errIncorrectHMAC
+		}
+	}
+
+	mac := hmac.New(v.hash, v.key)
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), decoded) {
+		return http.StatusUnauthorized, errIncorrectHMAC
+	}
+	return 0, nil
+}
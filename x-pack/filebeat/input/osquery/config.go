@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package osquery
+
+import (
+	"fmt"
+	"time"
+)
+
+type config struct {
+	// Socket is the path to the extensions socket of a running osqueryd. The
+	// input runs its own osqueryd if it's not set.
+	Socket string `config:"socket"`
+
+	// Osqueryd configures the osqueryd run by the input.
+	Osqueryd osquerydConfig `config:"osqueryd"`
+
+	// Timeout is the maximum duration of a query.
+	Timeout time.Duration `config:"timeout" validate:"positive,nonzero"`
+
+	// RetryInterval is the time to wait before running a query again after
+	// a failure.
+	RetryInterval time.Duration `config:"retry_interval" validate:"positive,nonzero"`
+
+	// Queries are the queries run by the input.
+	Queries []queryConfig `config:"queries" validate:"required"`
+}
+
+type osquerydConfig struct {
+	// Binary is the path to the osqueryd binary.
+	Binary string `config:"binary" validate:"required"`
+
+	// Flags are passed to osqueryd after the flags set by the input, so they
+	// can override them.
+	Flags []string `config:"flags"`
+}
+
+type queryConfig struct {
+	// Name identifies the results of the query.
+	Name string `config:"name" validate:"required"`
+
+	// Query is the SQL query.
+	Query string `config:"query" validate:"required"`
+
+	// Interval is the time between two executions of the query. If it's not
+	// set, the query runs once when the input starts.
+	Interval time.Duration `config:"interval" validate:"min=0"`
+
+	// Snapshot publishes all the rows returned by each execution of the query,
+	// instead of the rows added and removed since the previous execution.
+	Snapshot bool `config:"snapshot"`
+}
+
+func defaultConfig() config {
+	return config{
+		Osqueryd: osquerydConfig{
+			Binary: "osqueryd",
+		},
+		Timeout:       time.Minute,
+		RetryInterval: 10 * time.Second,
+	}
+}
+
+func (c *config) Validate() error {
+	names := map[string]bool{}
+	for _, q := range c.Queries {
+		if names[q.Name] {
+			return fmt.Errorf("duplicated query name '%s'", q.Name)
+		}
+		names[q.Name] = true
+	}
+	return nil
+}
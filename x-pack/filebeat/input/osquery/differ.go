@@ -0,0 +1,65 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package osquery
+
+import (
+	"encoding/json"
+)
+
+// differ computes the rows added and removed between consecutive results of
+// a query, like the differential results of osqueryd. Identical rows are
+// counted, so a result can contain duplicates.
+type differ struct {
+	previous map[string]*countedRow
+}
+
+type countedRow struct {
+	row   map[string]string
+	count int
+}
+
+// diff returns the rows of current that weren't in the previous result and
+// the rows of the previous result that aren't in current. All rows are added
+// in the first result.
+func (d *differ) diff(current []map[string]string) (added, removed []map[string]string) {
+	rows := make(map[string]*countedRow, len(current))
+	for _, row := range current {
+		key := rowKey(row)
+		if r, ok := rows[key]; ok {
+			r.count++
+			continue
+		}
+		rows[key] = &countedRow{row: row, count: 1}
+	}
+
+	for key, r := range rows {
+		n := r.count
+		if prev, ok := d.previous[key]; ok {
+			n -= prev.count
+		}
+		for i := 0; i < n; i++ {
+			added = append(added, r.row)
+		}
+	}
+	for key, prev := range d.previous {
+		n := prev.count
+		if r, ok := rows[key]; ok {
+			n -= r.count
+		}
+		for i := 0; i < n; i++ {
+			removed = append(removed, prev.row)
+		}
+	}
+
+	d.previous = rows
+	return added, removed
+}
+
+// rowKey returns a key identifying the values of a row. Maps are encoded with
+// sorted keys, so equal rows have the same key.
+func rowKey(row map[string]string) string {
+	b, _ := json.Marshal(row)
+	return string(b)
+}
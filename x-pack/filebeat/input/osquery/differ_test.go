@@ -0,0 +1,41 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package osquery
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffer(t *testing.T) {
+	root := map[string]string{"uid": "0", "username": "root"}
+	elastic := map[string]string{"uid": "1000", "username": "elastic"}
+	beats := map[string]string{"uid": "1001", "username": "beats"}
+
+	var d differ
+
+	added, removed := d.diff([]map[string]string{root, elastic})
+	assert.ElementsMatch(t, []map[string]string{root, elastic}, added)
+	assert.Empty(t, removed)
+
+	added, removed = d.diff([]map[string]string{elastic, root})
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+
+	added, removed = d.diff([]map[string]string{root, beats})
+	assert.Equal(t, []map[string]string{beats}, added)
+	assert.Equal(t, []map[string]string{elastic}, removed)
+
+	t.Run("duplicated rows", func(t *testing.T) {
+		added, removed = d.diff([]map[string]string{root, beats, beats})
+		assert.Equal(t, []map[string]string{beats}, added)
+		assert.Empty(t, removed)
+
+		added, removed = d.diff([]map[string]string{beats})
+		assert.Empty(t, added)
+		assert.ElementsMatch(t, []map[string]string{root, beats}, removed)
+	})
+}
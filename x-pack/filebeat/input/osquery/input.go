@@ -0,0 +1,251 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package osquery
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	v2 "github.com/elastic/beats/v7/filebeat/input/v2"
+	stateless "github.com/elastic/beats/v7/filebeat/input/v2/input-stateless"
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/feature"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/paths"
+	"github.com/elastic/go-concert/ctxtool"
+	"github.com/elastic/go-concert/timed"
+)
+
+const inputName = "osquery"
+
+// Actions of the results, as logged by osqueryd.
+const (
+	actionAdded    = "added"
+	actionRemoved  = "removed"
+	actionSnapshot = "snapshot"
+)
+
+type osqueryInput struct {
+	config config
+}
+
+// queryRunner runs SQL queries on osquery.
+type queryRunner interface {
+	Query(sql string) ([]map[string]string, error)
+}
+
+func Plugin() v2.Plugin {
+	return v2.Plugin{
+		Name:       inputName,
+		Stability:  feature.Experimental,
+		Deprecated: false,
+		Info:       "osquery input",
+		Doc:        "The osquery input runs queries on osquery and publishes their results",
+		Manager:    stateless.NewInputManager(configure),
+	}
+}
+
+func configure(cfg *common.Config) (stateless.Input, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, err
+	}
+	return &osqueryInput{config: config}, nil
+}
+
+func (*osqueryInput) Name() string { return inputName }
+
+func (inp *osqueryInput) Test(ctx v2.TestContext) error {
+	if inp.config.Socket == "" {
+		_, err := exec.LookPath(inp.config.Osqueryd.Binary)
+		return err
+	}
+
+	client, err := dialExtensions(inp.config.Socket, inp.config.Timeout)
+	if err != nil {
+		return err
+	}
+	return client.Close()
+}
+
+func (inp *osqueryInput) Run(ctx v2.Context, publisher stateless.Publisher) error {
+	cancelCtx := ctxtool.FromCanceller(ctx.Cancelation)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	socket := inp.config.Socket
+	if socket == "" {
+		daemon, err := newOsqueryd(ctx.Logger, inp.config.Osqueryd, dataDir(ctx.ID))
+		if err != nil {
+			return err
+		}
+		socket = daemon.socket()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			daemon.supervise(cancelCtx)
+		}()
+	}
+
+	runner := &socketRunner{path: socket, timeout: inp.config.Timeout}
+	defer runner.Close()
+
+	var queriesWG sync.WaitGroup
+	for _, query := range inp.config.Queries {
+		query := query
+		queriesWG.Add(1)
+		go func() {
+			defer queriesWG.Done()
+			inp.runQuery(cancelCtx, ctx.Logger.With("query", query.Name), runner, query, publisher)
+		}()
+	}
+	queriesWG.Wait()
+	return nil
+}
+
+// runQuery runs a query until ctx is cancelled. The first execution is retried
+// until it succeeds, so queries run once don't fail if osqueryd is starting.
+func (inp *osqueryInput) runQuery(
+	ctx context.Context,
+	log *logp.Logger,
+	runner queryRunner,
+	query queryConfig,
+	publisher stateless.Publisher,
+) {
+	var d differ
+	run := func() error {
+		rows, err := runner.Query(query.Query)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if query.Snapshot || query.Interval == 0 {
+			for _, row := range rows {
+				publisher.Publish(makeEvent(query.Name, actionSnapshot, row, now))
+			}
+			log.Debugf("Query returned %d rows", len(rows))
+			return nil
+		}
+
+		added, removed := d.diff(rows)
+		for _, row := range added {
+			publisher.Publish(makeEvent(query.Name, actionAdded, row, now))
+		}
+		for _, row := range removed {
+			publisher.Publish(makeEvent(query.Name, actionRemoved, row, now))
+		}
+		log.Debugf("Query returned %d rows, %d added and %d removed", len(rows), len(added), len(removed))
+		return nil
+	}
+
+	for {
+		err := run()
+		if err == nil {
+			break
+		}
+		log.Errorf("Query failed, retrying in %v: %v", inp.config.RetryInterval, err)
+		if timed.Wait(ctx, inp.config.RetryInterval) != nil {
+			return
+		}
+	}
+
+	if query.Interval == 0 {
+		return
+	}
+	timed.Periodic(ctx, query.Interval, func() error {
+		if err := run(); err != nil {
+			log.Errorf("Query failed: %v", err)
+		}
+		return nil
+	})
+}
+
+// makeEvent returns the event of a row of the results of a query, with the
+// fields of the results logged by osqueryd.
+func makeEvent(name, action string, row map[string]string, ts time.Time) beat.Event {
+	columns := make(common.MapStr, len(row))
+	for k, v := range row {
+		columns[k] = v
+	}
+
+	return beat.Event{
+		Timestamp: ts,
+		Fields: common.MapStr{
+			"event": common.MapStr{
+				"kind":   "event",
+				"type":   []string{"info"},
+				"action": action,
+			},
+			"osquery": common.MapStr{
+				"result": common.MapStr{
+					"name":      name,
+					"action":    action,
+					"unix_time": ts.Unix(),
+					"columns":   columns,
+				},
+			},
+		},
+	}
+}
+
+// socketRunner runs queries through the extensions socket. It connects when
+// a query runs and reconnects after errors. Queries are serialized, osquery
+// handles one call at a time for each connection.
+type socketRunner struct {
+	path    string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	client *extClient
+}
+
+func (r *socketRunner) Query(sql string) ([]map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client == nil {
+		client, err := dialExtensions(r.path, r.timeout)
+		if err != nil {
+			return nil, err
+		}
+		r.client = client
+	}
+
+	rows, err := r.client.Query(sql)
+	if err != nil {
+		r.client.Close()
+		r.client = nil
+	}
+	return rows, err
+}
+
+func (r *socketRunner) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client != nil {
+		r.client.Close()
+		r.client = nil
+	}
+}
+
+// dataDir returns the directory of the osqueryd run by an input.
+func dataDir(id string) string {
+	id = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, strings.TrimSpace(id))
+	return paths.Resolve(paths.Data, filepath.Join("osquery", id))
+}
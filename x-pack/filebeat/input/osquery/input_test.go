@@ -0,0 +1,139 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package osquery
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+type fakeRunner struct {
+	results [][]map[string]string
+	errs    []error
+	calls   int
+}
+
+func (r *fakeRunner) Query(sql string) ([]map[string]string, error) {
+	i := r.calls
+	r.calls++
+	if i < len(r.errs) && r.errs[i] != nil {
+		return nil, r.errs[i]
+	}
+	if i >= len(r.results) {
+		return r.results[len(r.results)-1], nil
+	}
+	return r.results[i], nil
+}
+
+type eventCollector struct {
+	mu     sync.Mutex
+	events []beat.Event
+}
+
+func (c *eventCollector) Publish(event beat.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, event)
+}
+
+func (c *eventCollector) actions() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var actions []string
+	for _, event := range c.events {
+		action, _ := event.Fields.GetValue("osquery.result.action")
+		actions = append(actions, action.(string))
+	}
+	return actions
+}
+
+func TestRunQueryOnce(t *testing.T) {
+	inp := &osqueryInput{config: defaultConfig()}
+	inp.config.RetryInterval = time.Millisecond
+
+	runner := &fakeRunner{
+		errs: []error{errors.New("osqueryd is starting")},
+		results: [][]map[string]string{
+			nil,
+			{{"name": "bash"}, {"name": "filebeat"}},
+		},
+	}
+	publisher := &eventCollector{}
+	query := queryConfig{Name: "processes", Query: "SELECT name FROM processes"}
+	inp.runQuery(context.Background(), logp.NewLogger("test"), runner, query, publisher)
+
+	assert.Equal(t, 2, runner.calls)
+	require.Equal(t, 2, len(publisher.events))
+	assert.Equal(t, []string{actionSnapshot, actionSnapshot}, publisher.actions())
+
+	event := publisher.events[0]
+	assert.Equal(t, "processes", event.Fields["osquery"].(common.MapStr)["result"].(common.MapStr)["name"])
+	columns, err := event.Fields.GetValue("osquery.result.columns")
+	require.NoError(t, err)
+	assert.Equal(t, common.MapStr{"name": "bash"}, columns)
+	action, err := event.Fields.GetValue("event.action")
+	require.NoError(t, err)
+	assert.Equal(t, actionSnapshot, action)
+}
+
+func TestRunQueryDifferential(t *testing.T) {
+	inp := &osqueryInput{config: defaultConfig()}
+
+	runner := &fakeRunner{
+		results: [][]map[string]string{
+			{{"name": "bash"}},
+			{{"name": "bash"}, {"name": "filebeat"}},
+			{{"name": "filebeat"}},
+		},
+	}
+	publisher := &eventCollector{}
+	query := queryConfig{Name: "processes", Query: "SELECT name FROM processes", Interval: time.Millisecond}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		inp.runQuery(ctx, logp.NewLogger("test"), runner, query, publisher)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return len(publisher.actions()) >= 3
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	assert.Equal(t, []string{actionAdded, actionAdded, actionRemoved}, publisher.actions())
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := common.MustNewConfigFrom(map[string]interface{}{
+		"queries": []map[string]interface{}{
+			{"name": "users", "query": "SELECT * FROM users"},
+			{"name": "users", "query": "SELECT * FROM groups"},
+		},
+	})
+	_, err := configure(cfg)
+	assert.Error(t, err)
+
+	_, err = configure(common.NewConfig())
+	assert.Error(t, err)
+}
+
+func TestDataDir(t *testing.T) {
+	assert.Equal(t, "6A3F_B", filepath.Base(dataDir("6A3F/B")))
+}
@@ -0,0 +1,135 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package osquery
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common/backoff"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+const (
+	// osquerydStopTimeout is the time osqueryd has to stop after being
+	// signaled, before being killed.
+	osquerydStopTimeout = 10 * time.Second
+
+	osquerydInitBackoff = time.Second
+	osquerydMaxBackoff  = time.Minute
+)
+
+// osqueryd runs osqueryd with an empty configuration, the input schedules
+// the queries itself through the extensions socket. All the files of osqueryd
+// are stored in dir.
+type osqueryd struct {
+	log    *logp.Logger
+	config osquerydConfig
+	dir    string
+}
+
+func newOsqueryd(log *logp.Logger, config osquerydConfig, dir string) (*osqueryd, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create osqueryd directory: %w", err)
+	}
+
+	// An empty configuration, so the schedule of the host isn't loaded.
+	if err := ioutil.WriteFile(filepath.Join(dir, "osquery.conf"), []byte("{}"), 0640); err != nil {
+		return nil, fmt.Errorf("failed to write osqueryd configuration: %w", err)
+	}
+
+	return &osqueryd{log: log.With("osqueryd", config.Binary), config: config, dir: dir}, nil
+}
+
+// socket returns the path to the extensions socket of osqueryd.
+func (o *osqueryd) socket() string {
+	return filepath.Join(o.dir, "osquery.em")
+}
+
+func (o *osqueryd) args() []string {
+	args := []string{
+		"--config_path=" + filepath.Join(o.dir, "osquery.conf"),
+		"--database_path=" + filepath.Join(o.dir, "osquery.db"),
+		"--pidfile=" + filepath.Join(o.dir, "osqueryd.pidfile"),
+		"--extensions_socket=" + o.socket(),
+		"--disable_extensions=false",
+		"--disable_logging",
+		"--logger_stderr",
+		"--force",
+	}
+	return append(args, o.config.Flags...)
+}
+
+// supervise runs osqueryd until ctx is cancelled, restarting it if it exits.
+func (o *osqueryd) supervise(ctx context.Context) {
+	b := backoff.NewEqualJitterBackoff(ctx.Done(), osquerydInitBackoff, osquerydMaxBackoff)
+	for ctx.Err() == nil {
+		started := time.Now()
+		err := o.run(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		o.log.Errorf("osqueryd exited: %v", err)
+		if time.Since(started) > osquerydMaxBackoff {
+			b.Reset()
+		}
+		b.Wait()
+	}
+}
+
+// run runs osqueryd until it exits or ctx is cancelled.
+func (o *osqueryd) run(ctx context.Context) error {
+	cmd := exec.Command(o.config.Binary, o.args()...)
+	cmd.Dir = o.dir
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+
+	o.log.Debugf("Starting osqueryd with args %v", cmd.Args)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start osqueryd: %w", err)
+	}
+	go o.logOutput(stderr)
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	select {
+	case err := <-exited:
+		return err
+	case <-ctx.Done():
+	}
+
+	o.log.Debug("Stopping osqueryd")
+	if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+		cmd.Process.Kill()
+	}
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(osquerydStopTimeout):
+		o.log.Warn("osqueryd didn't stop in time, killing it")
+		cmd.Process.Kill()
+		return <-exited
+	}
+}
+
+// logOutput logs the lines written by osqueryd.
+func (o *osqueryd) logOutput(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		o.log.Debug(scanner.Text())
+	}
+}
@@ -0,0 +1,492 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package osquery
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Types and message types of the Thrift binary protocol, used by the
+// extensions socket of osquery.
+// Ref: https://github.com/apache/thrift/blob/master/doc/specs/thrift-binary-protocol.md
+const (
+	thriftStop   byte = 0
+	thriftBool   byte = 2
+	thriftByte   byte = 3
+	thriftDouble byte = 4
+	thriftI16    byte = 6
+	thriftI32    byte = 8
+	thriftI64    byte = 10
+	thriftString byte = 11
+	thriftStruct byte = 12
+	thriftMap    byte = 13
+	thriftSet    byte = 14
+	thriftList   byte = 15
+
+	thriftCall      = 1
+	thriftReply     = 2
+	thriftException = 3
+
+	thriftVersion1    uint32 = 0x80010000
+	thriftVersionMask uint32 = 0xffff0000
+)
+
+// maxThriftSize limits the size of the strings and containers read from the
+// socket, so a corrupted response doesn't exhaust the memory.
+const maxThriftSize = 256 * 1024 * 1024
+
+var errThriftSize = errors.New("thrift value too large")
+
+// extClient queries osquery through its extensions socket. It implements the
+// query call of the ExtensionManager service.
+// Ref: https://github.com/osquery/osquery/blob/master/osquery/extensions/thrift/osquery.thrift
+type extClient struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	timeout time.Duration
+	seqID   int32
+}
+
+// dialExtensions connects to the extensions socket at path.
+func dialExtensions(path string, timeout time.Duration) (*extClient, error) {
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return newExtClient(conn, timeout), nil
+}
+
+func newExtClient(conn net.Conn, timeout time.Duration) *extClient {
+	return &extClient{
+		conn:    conn,
+		r:       bufio.NewReader(conn),
+		w:       bufio.NewWriter(conn),
+		timeout: timeout,
+	}
+}
+
+// Close closes the connection to the socket.
+func (c *extClient) Close() error {
+	return c.conn.Close()
+}
+
+// Query runs sql and returns the rows of the result.
+func (c *extClient) Query(sql string) ([]map[string]string, error) {
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+		defer c.conn.SetDeadline(time.Time{})
+	}
+
+	c.seqID++
+	if err := c.writeCall("query", c.seqID, sql); err != nil {
+		return nil, fmt.Errorf("error sending query: %w", err)
+	}
+
+	d := thriftDecoder{r: c.r}
+	resp, err := d.readReply("query", c.seqID)
+	if err != nil {
+		return nil, fmt.Errorf("error reading query response: %w", err)
+	}
+	if resp.code != 0 {
+		return nil, fmt.Errorf("query failed with code %d: %s", resp.code, resp.message)
+	}
+	return resp.rows, nil
+}
+
+// writeCall writes a call with a single string argument, with ID 1.
+func (c *extClient) writeCall(method string, seqID int32, arg string) error {
+	e := thriftEncoder{w: c.w}
+	version := thriftVersion1 | thriftCall
+	e.writeI32(int32(version))
+	e.writeString(method)
+	e.writeI32(seqID)
+	e.writeFieldHeader(thriftString, 1)
+	e.writeString(arg)
+	e.writeByte(thriftStop)
+	if e.err != nil {
+		return e.err
+	}
+	return c.w.Flush()
+}
+
+type thriftEncoder struct {
+	w   io.Writer
+	err error
+	buf [8]byte
+}
+
+func (e *thriftEncoder) write(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *thriftEncoder) writeByte(b byte) {
+	e.buf[0] = b
+	e.write(e.buf[:1])
+}
+
+func (e *thriftEncoder) writeI16(v int16) {
+	binary.BigEndian.PutUint16(e.buf[:2], uint16(v))
+	e.write(e.buf[:2])
+}
+
+func (e *thriftEncoder) writeI32(v int32) {
+	binary.BigEndian.PutUint32(e.buf[:4], uint32(v))
+	e.write(e.buf[:4])
+}
+
+func (e *thriftEncoder) writeI64(v int64) {
+	binary.BigEndian.PutUint64(e.buf[:8], uint64(v))
+	e.write(e.buf[:8])
+}
+
+func (e *thriftEncoder) writeString(s string) {
+	e.writeI32(int32(len(s)))
+	e.write([]byte(s))
+}
+
+func (e *thriftEncoder) writeFieldHeader(typ byte, id int16) {
+	e.writeByte(typ)
+	e.writeI16(id)
+}
+
+// queryResponse is the decoded ExtensionResponse of a query.
+type queryResponse struct {
+	code    int32
+	message string
+	rows    []map[string]string
+}
+
+type thriftDecoder struct {
+	r   io.Reader
+	buf [8]byte
+}
+
+func (d *thriftDecoder) read(n int) ([]byte, error) {
+	_, err := io.ReadFull(d.r, d.buf[:n])
+	return d.buf[:n], err
+}
+
+func (d *thriftDecoder) readByte() (byte, error) {
+	b, err := d.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *thriftDecoder) readI16() (int16, error) {
+	b, err := d.read(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (d *thriftDecoder) readI32() (int32, error) {
+	b, err := d.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (d *thriftDecoder) readI64() (int64, error) {
+	b, err := d.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (d *thriftDecoder) readSize() (int, error) {
+	n, err := d.readI32()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > maxThriftSize {
+		return 0, errThriftSize
+	}
+	return int(n), nil
+}
+
+func (d *thriftDecoder) readString() (string, error) {
+	n, err := d.readSize()
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// readFieldHeader reads the header of the next field of a struct, typ is
+// thriftStop at the end of the struct.
+func (d *thriftDecoder) readFieldHeader() (typ byte, id int16, err error) {
+	typ, err = d.readByte()
+	if err != nil || typ == thriftStop {
+		return typ, 0, err
+	}
+	id, err = d.readI16()
+	return typ, id, err
+}
+
+// readStruct calls fn for every field of a struct. fn returns false to skip
+// the value of the field.
+func (d *thriftDecoder) readStruct(fn func(typ byte, id int16) (bool, error)) error {
+	for {
+		typ, id, err := d.readFieldHeader()
+		if err != nil {
+			return err
+		}
+		if typ == thriftStop {
+			return nil
+		}
+
+		read, err := fn(typ, id)
+		if err != nil {
+			return err
+		}
+		if !read {
+			if err := d.skip(typ, 0); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// skip reads and discards a value of type typ.
+func (d *thriftDecoder) skip(typ byte, depth int) error {
+	if depth > 64 {
+		return errors.New("thrift value nested too deep")
+	}
+
+	var err error
+	switch typ {
+	case thriftBool, thriftByte:
+		_, err = d.read(1)
+	case thriftI16:
+		_, err = d.read(2)
+	case thriftI32:
+		_, err = d.read(4)
+	case thriftDouble, thriftI64:
+		_, err = d.read(8)
+	case thriftString:
+		_, err = d.readString()
+	case thriftStruct:
+		err = d.readStruct(func(typ byte, _ int16) (bool, error) {
+			return true, d.skip(typ, depth+1)
+		})
+	case thriftMap:
+		var ktype, vtype byte
+		var n int
+		if ktype, err = d.readByte(); err != nil {
+			return err
+		}
+		if vtype, err = d.readByte(); err != nil {
+			return err
+		}
+		if n, err = d.readSize(); err != nil {
+			return err
+		}
+		for i := 0; i < n && err == nil; i++ {
+			if err = d.skip(ktype, depth+1); err == nil {
+				err = d.skip(vtype, depth+1)
+			}
+		}
+	case thriftSet, thriftList:
+		var etype byte
+		var n int
+		if etype, err = d.readByte(); err != nil {
+			return err
+		}
+		if n, err = d.readSize(); err != nil {
+			return err
+		}
+		for i := 0; i < n && err == nil; i++ {
+			err = d.skip(etype, depth+1)
+		}
+	default:
+		err = fmt.Errorf("unknown thrift type %d", typ)
+	}
+	return err
+}
+
+// readReply reads the reply of a call to method, whose result is an
+// ExtensionResponse.
+func (d *thriftDecoder) readReply(method string, seqID int32) (*queryResponse, error) {
+	version, err := d.readI32()
+	if err != nil {
+		return nil, err
+	}
+	if uint32(version)&thriftVersionMask != thriftVersion1 {
+		return nil, fmt.Errorf("unsupported thrift protocol version %#x", uint32(version))
+	}
+	name, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+	id, err := d.readI32()
+	if err != nil {
+		return nil, err
+	}
+
+	switch msgType := version & 0xff; msgType {
+	case thriftReply:
+	case thriftException:
+		return nil, d.readException()
+	default:
+		return nil, fmt.Errorf("unexpected thrift message type %d", msgType)
+	}
+	if name != method || id != seqID {
+		return nil, fmt.Errorf("unexpected reply to %s (%d)", name, id)
+	}
+
+	var resp *queryResponse
+	err = d.readStruct(func(typ byte, id int16) (bool, error) {
+		if id != 0 || typ != thriftStruct {
+			return false, nil
+		}
+		r, err := d.readExtensionResponse()
+		resp = r
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp == nil {
+		return nil, errors.New("empty reply")
+	}
+	return resp, nil
+}
+
+// readException reads a TApplicationException and returns it as an error.
+func (d *thriftDecoder) readException() error {
+	var message string
+	var code int32
+	err := d.readStruct(func(typ byte, id int16) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftString:
+			message, err = d.readString()
+		case id == 2 && typ == thriftI32:
+			code, err = d.readI32()
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("thrift exception %d: %s", code, message)
+}
+
+func (d *thriftDecoder) readExtensionResponse() (*queryResponse, error) {
+	resp := &queryResponse{}
+	err := d.readStruct(func(typ byte, id int16) (bool, error) {
+		switch {
+		case id == 1 && typ == thriftStruct:
+			return true, d.readExtensionStatus(resp)
+		case id == 2 && typ == thriftList:
+			rows, err := d.readRows()
+			resp.rows = rows
+			return true, err
+		}
+		return false, nil
+	})
+	return resp, err
+}
+
+func (d *thriftDecoder) readExtensionStatus(resp *queryResponse) error {
+	return d.readStruct(func(typ byte, id int16) (bool, error) {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI32:
+			resp.code, err = d.readI32()
+		case id == 2 && typ == thriftString:
+			resp.message, err = d.readString()
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+// readRows reads a list<map<string, string>>.
+func (d *thriftDecoder) readRows() ([]map[string]string, error) {
+	etype, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	n, err := d.readSize()
+	if err != nil {
+		return nil, err
+	}
+	if etype != thriftMap {
+		return nil, fmt.Errorf("unexpected type %d of the rows", etype)
+	}
+
+	rows := make([]map[string]string, 0, capacity(n))
+	for i := 0; i < n; i++ {
+		row, err := d.readRow()
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (d *thriftDecoder) readRow() (map[string]string, error) {
+	ktype, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	vtype, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+	n, err := d.readSize()
+	if err != nil {
+		return nil, err
+	}
+	if ktype != thriftString || vtype != thriftString {
+		return nil, fmt.Errorf("unexpected types %d and %d of the columns", ktype, vtype)
+	}
+
+	row := make(map[string]string, capacity(n))
+	for i := 0; i < n; i++ {
+		k, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		row[k] = v
+	}
+	return row, nil
+}
+
+// capacity limits the preallocated size of containers to n, the size read
+// from the socket.
+func capacity(n int) int {
+	if n > 1024 {
+		return 1024
+	}
+	return n
+}
@@ -0,0 +1,182 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package osquery
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExtensionManager answers the query calls received on conn with reply,
+// which returns the type of the message it writes.
+func fakeExtensionManager(t *testing.T, conn net.Conn, reply func(e *thriftEncoder, sql string) uint32) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	for {
+		d := thriftDecoder{r: r}
+		version, err := d.readI32()
+		if err != nil {
+			return
+		}
+		assert.Equal(t, thriftVersion1|thriftCall, uint32(version))
+		name, _ := d.readString()
+		assert.Equal(t, "query", name)
+		seqID, _ := d.readI32()
+
+		var sql string
+		require.NoError(t, d.readStruct(func(typ byte, id int16) (bool, error) {
+			if id != 1 || typ != thriftString {
+				return false, nil
+			}
+			var err error
+			sql, err = d.readString()
+			return true, err
+		}))
+
+		var body bytes.Buffer
+		msgType := reply(&thriftEncoder{w: &body}, sql)
+
+		e := &thriftEncoder{w: w}
+		e.writeI32(int32(thriftVersion1 | msgType))
+		e.writeString(name)
+		e.writeI32(seqID)
+		e.write(body.Bytes())
+		require.NoError(t, e.err)
+		require.NoError(t, w.Flush())
+	}
+}
+
+func writeQueryResponse(e *thriftEncoder, code int32, message string, rows []map[string]string) {
+	e.writeFieldHeader(thriftStruct, 0)
+
+	e.writeFieldHeader(thriftStruct, 1)
+	e.writeFieldHeader(thriftI32, 1)
+	e.writeI32(code)
+	e.writeFieldHeader(thriftString, 2)
+	e.writeString(message)
+	e.writeFieldHeader(thriftI64, 3)
+	e.writeI64(42)
+	e.writeByte(thriftStop)
+
+	e.writeFieldHeader(thriftList, 2)
+	e.writeByte(thriftMap)
+	e.writeI32(int32(len(rows)))
+	for _, row := range rows {
+		e.writeByte(thriftString)
+		e.writeByte(thriftString)
+		e.writeI32(int32(len(row)))
+		for k, v := range row {
+			e.writeString(k)
+			e.writeString(v)
+		}
+	}
+	e.writeByte(thriftStop)
+
+	e.writeByte(thriftStop)
+}
+
+func TestExtClientQuery(t *testing.T) {
+	server, conn := net.Pipe()
+	go fakeExtensionManager(t, server, func(e *thriftEncoder, sql string) uint32 {
+		switch sql {
+		case "SELECT * FROM users":
+			writeQueryResponse(e, 0, "OK", []map[string]string{
+				{"uid": "0", "username": "root"},
+				{"uid": "1000", "username": "elastic"},
+			})
+		case "SELECT * FROM nothing":
+			writeQueryResponse(e, 1, "no such table: nothing", nil)
+		default:
+			e.writeFieldHeader(thriftString, 1)
+			e.writeString("internal error")
+			e.writeFieldHeader(thriftI32, 2)
+			e.writeI32(6)
+			e.writeByte(thriftStop)
+			return thriftException
+		}
+		return thriftReply
+	})
+
+	client := newExtClient(conn, 5*time.Second)
+	defer client.Close()
+
+	rows, err := client.Query("SELECT * FROM users")
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]string{
+		{"uid": "0", "username": "root"},
+		{"uid": "1000", "username": "elastic"},
+	}, rows)
+
+	_, err = client.Query("SELECT * FROM nothing")
+	assert.EqualError(t, err, "query failed with code 1: no such table: nothing")
+
+	// The connection can be reused after a failed query.
+	rows, err = client.Query("SELECT * FROM users")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(rows))
+
+	_, err = client.Query("SELECT crash()")
+	assert.EqualError(t, err, "error reading query response: thrift exception 6: internal error")
+}
+
+func TestThriftDecoderSkip(t *testing.T) {
+	server, conn := net.Pipe()
+	go fakeExtensionManager(t, server, func(e *thriftEncoder, sql string) uint32 {
+		// Unknown fields of all types are skipped.
+		e.writeFieldHeader(thriftStruct, 0)
+		e.writeFieldHeader(thriftBool, 10)
+		e.writeByte(1)
+		e.writeFieldHeader(thriftDouble, 11)
+		e.writeI64(0)
+		e.writeFieldHeader(thriftMap, 12)
+		e.writeByte(thriftI16)
+		e.writeByte(thriftList)
+		e.writeI32(1)
+		e.writeI16(7)
+		e.writeByte(thriftString)
+		e.writeI32(2)
+		e.writeString("a")
+		e.writeString("b")
+		e.writeFieldHeader(thriftStruct, 1)
+		e.writeFieldHeader(thriftI32, 1)
+		e.writeI32(0)
+		e.writeByte(thriftStop)
+		e.writeByte(thriftStop)
+		e.writeByte(thriftStop)
+		return thriftReply
+	})
+
+	client := newExtClient(conn, 5*time.Second)
+	defer client.Close()
+
+	rows, err := client.Query("SELECT 1")
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+func TestThriftDecoderLimits(t *testing.T) {
+	server, conn := net.Pipe()
+	go fakeExtensionManager(t, server, func(e *thriftEncoder, sql string) uint32 {
+		e.writeFieldHeader(thriftStruct, 0)
+		e.writeFieldHeader(thriftList, 2)
+		e.writeByte(thriftMap)
+		e.writeI32(-1)
+		return thriftReply
+	})
+
+	client := newExtClient(conn, 5*time.Second)
+	defer client.Close()
+
+	_, err := client.Query("SELECT 1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,81 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sql
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type config struct {
+	// ID identifies the cursor of the input in the registry. It defaults to
+	// a hash of the driver, the DSN and the query.
+	ID string `config:"id"`
+
+	Driver string `config:"driver" validate:"required"`
+	DSN    string `config:"dsn" validate:"required"`
+
+	// Query is run at every interval. If a cursor is configured, the value
+	// of the cursor is passed to it as the only parameter.
+	Query string `config:"sql_query" validate:"required"`
+
+	Interval time.Duration `config:"interval" validate:"positive,nonzero"`
+	Timeout  time.Duration `config:"timeout" validate:"positive,nonzero"`
+
+	// Cursor tracks the last row published, so the query only returns the
+	// new rows.
+	Cursor *cursorConfig `config:"cursor"`
+
+	// TargetField holds the columns of the rows in the events. The columns
+	// are at the root of the events if it is empty.
+	TargetField string `config:"target_field"`
+}
+
+type cursorConfig struct {
+	// Column is the column holding the value of the cursor in the rows.
+	Column string `config:"column" validate:"required"`
+
+	Type cursorType `config:"type"`
+
+	// Default is the value of the cursor when the input runs the first time.
+	Default string `config:"default" validate:"required"`
+}
+
+// drivers maps the supported drivers to the name of their database/sql
+// driver.
+var drivers = map[string]string{
+	"mysql":      "mysql",
+	"postgres":   "postgres",
+	"postgresql": "postgres",
+	"mssql":      "sqlserver",
+	"sqlserver":  "sqlserver",
+	"oracle":     "godror",
+}
+
+func defaultConfig() config {
+	return config{
+		Interval:    time.Minute,
+		Timeout:     time.Minute,
+		TargetField: "sql",
+	}
+}
+
+func (c *config) Validate() error {
+	if _, found := drivers[strings.ToLower(c.Driver)]; !found {
+		return fmt.Errorf("unsupported driver '%s' (valid values are: mysql, postgres, mssql, oracle)", c.Driver)
+	}
+	if c.Cursor != nil {
+		if _, err := c.Cursor.Type.parse(c.Cursor.Default); err != nil {
+			return fmt.Errorf("invalid cursor default: %v", err)
+		}
+	}
+	return nil
+}
+
+// driverName returns the name of the database/sql driver to use.
+func (c *config) driverName() string {
+	return drivers[strings.ToLower(c.Driver)]
+}
@@ -0,0 +1,153 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sql
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type cursorType uint8
+
+const (
+	cursorInteger cursorType = iota
+	cursorTimestamp
+	cursorString
+)
+
+var cursorTypes = map[string]cursorType{
+	"integer":   cursorInteger,
+	"timestamp": cursorTimestamp,
+	"string":    cursorString,
+}
+
+// timestampLayouts are the layouts of the timestamps returned as strings by
+// the drivers.
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// Unpack creates the cursor type from the given string.
+func (t *cursorType) Unpack(str string) error {
+	v, found := cursorTypes[strings.ToLower(str)]
+	if !found {
+		return fmt.Errorf("invalid cursor type '%s' (valid values are: integer, timestamp, string)", str)
+	}
+	*t = v
+	return nil
+}
+
+func (t cursorType) String() string {
+	for name, v := range cursorTypes {
+		if v == t {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// cursorState is the state of the cursor stored in the registry.
+type cursorState struct {
+	Value string `struct:"value"`
+}
+
+// parse returns the value of the cursor formatted in s, as returned by
+// format.
+func (t cursorType) parse(s string) (interface{}, error) {
+	switch t {
+	case cursorInteger:
+		return strconv.ParseInt(s, 10, 64)
+	case cursorTimestamp:
+		return parseTimestamp(s)
+	default:
+		return s, nil
+	}
+}
+
+// format returns the string representation of a value of the cursor.
+func (t cursorType) format(v interface{}) string {
+	switch t {
+	case cursorInteger:
+		return strconv.FormatInt(v.(int64), 10)
+	case cursorTimestamp:
+		return v.(time.Time).UTC().Format(time.RFC3339Nano)
+	default:
+		return v.(string)
+	}
+}
+
+// fromColumn converts the value of the cursor column of a row.
+func (t cursorType) fromColumn(v interface{}) (interface{}, error) {
+	if b, ok := v.([]byte); ok {
+		v = string(b)
+	}
+
+	switch t {
+	case cursorInteger:
+		switch v := v.(type) {
+		case int64:
+			return v, nil
+		case int32:
+			return int64(v), nil
+		case int:
+			return int64(v), nil
+		case uint64:
+			if v > math.MaxInt64 {
+				return nil, fmt.Errorf("value %d overflows the integer cursor", v)
+			}
+			return int64(v), nil
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("value %v is not an integer", v)
+			}
+			return int64(v), nil
+		case string:
+			return strconv.ParseInt(v, 10, 64)
+		}
+	case cursorTimestamp:
+		switch v := v.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			return parseTimestamp(v)
+		}
+	default:
+		switch v := v.(type) {
+		case nil:
+		case string:
+			return v, nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	}
+	return nil, fmt.Errorf("value %v of type %T can not be used as %v cursor", v, v, t)
+}
+
+// less reports whether the cursor value a is before b.
+func (t cursorType) less(a, b interface{}) bool {
+	switch t {
+	case cursorInteger:
+		return a.(int64) < b.(int64)
+	case cursorTimestamp:
+		return a.(time.Time).Before(b.(time.Time))
+	default:
+		return a.(string) < b.(string)
+	}
+}
+
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp '%s'", s)
+}
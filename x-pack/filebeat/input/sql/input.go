@@ -0,0 +1,264 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	// Register the supported drivers.
+	_ "github.com/denisenkom/go-mssqldb"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/godror/godror"
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	v2 "github.com/elastic/beats/v7/filebeat/input/v2"
+	cursor "github.com/elastic/beats/v7/filebeat/input/v2/input-cursor"
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/feature"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/go-concert/ctxtool"
+	"github.com/elastic/go-concert/timed"
+)
+
+const inputName = "sql"
+
+type sqlInput struct{}
+
+// source is the database and the query of an input, its cursor is stored
+// under its name in the registry.
+type source struct {
+	config config
+}
+
+func Plugin(log *logp.Logger, store cursor.StateStore) v2.Plugin {
+	return v2.Plugin{
+		Name:       inputName,
+		Stability:  feature.Experimental,
+		Deprecated: false,
+		Info:       "SQL input",
+		Doc:        "The sql input periodically runs a query on a database and publishes the rows",
+		Manager: &cursor.InputManager{
+			Logger:     log,
+			StateStore: store,
+			Type:       inputName,
+			Configure:  configure,
+		},
+	}
+}
+
+func configure(cfg *common.Config) ([]cursor.Source, cursor.Input, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, nil, err
+	}
+	return []cursor.Source{&source{config: config}}, &sqlInput{}, nil
+}
+
+// Name returns the configured id, or a hash of the connection and the query,
+// so the DSN does not appear in the registry.
+func (s *source) Name() string {
+	if s.config.ID != "" {
+		return s.config.ID
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s", s.config.driverName(), s.config.DSN, s.config.Query)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (*sqlInput) Name() string { return inputName }
+
+func (*sqlInput) Test(src cursor.Source, ctx v2.TestContext) error {
+	config := src.(*source).config
+	db, err := sql.Open(config.driverName(), config.DSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	pingCtx, cancel := context.WithTimeout(ctxtool.FromCanceller(ctx.Cancelation), config.Timeout)
+	defer cancel()
+	return db.PingContext(pingCtx)
+}
+
+func (*sqlInput) Run(
+	ctx v2.Context,
+	src cursor.Source,
+	cursor cursor.Cursor,
+	publisher cursor.Publisher,
+) error {
+	config := src.(*source).config
+	log := ctx.Logger.With("driver", config.Driver)
+
+	db, err := sql.Open(config.driverName(), config.DSN)
+	if err != nil {
+		return errors.Wrap(err, "failed to open the database")
+	}
+	defer db.Close()
+
+	p, err := newPoller(config, db, log)
+	if err != nil {
+		return err
+	}
+	if err := p.loadCursor(cursor); err != nil {
+		return err
+	}
+
+	cancelCtx := ctxtool.FromCanceller(ctx.Cancelation)
+	poll := func() error {
+		if err := p.poll(cancelCtx, publisher); err != nil {
+			if cancelCtx.Err() != nil {
+				return cancelCtx.Err()
+			}
+			log.Errorf("Query failed: %v", err)
+		}
+		return nil
+	}
+	if poll() == nil {
+		timed.Periodic(cancelCtx, config.Interval, poll)
+	}
+	return nil
+}
+
+// poller runs the query and publishes the rows.
+type poller struct {
+	config config
+	db     *sql.DB
+	log    *logp.Logger
+
+	// cursor is the value of the cursor column of the last row published.
+	cursor interface{}
+}
+
+func newPoller(config config, db *sql.DB, log *logp.Logger) (*poller, error) {
+	p := &poller{config: config, db: db, log: log}
+	if config.Cursor != nil {
+		v, err := config.Cursor.Type.parse(config.Cursor.Default)
+		if err != nil {
+			return nil, err
+		}
+		p.cursor = v
+	}
+	return p, nil
+}
+
+// loadCursor restores the value of the cursor stored in the registry.
+func (p *poller) loadCursor(c cursor.Cursor) error {
+	if p.config.Cursor == nil || c.IsNew() {
+		return nil
+	}
+
+	var st cursorState
+	if err := c.Unpack(&st); err != nil {
+		return errors.Wrap(err, "failed to read the cursor from the registry")
+	}
+	v, err := p.config.Cursor.Type.parse(st.Value)
+	if err != nil {
+		p.log.Errorf("Ignoring the invalid cursor stored in the registry, using the default: %v", err)
+		return nil
+	}
+	p.log.Infof("Resuming from the cursor %v", st.Value)
+	p.cursor = v
+	return nil
+}
+
+// poll runs the query once and publishes the rows. The cursor is updated with
+// the value of the cursor column of each published row, so the query must
+// return the rows ordered by this column.
+func (p *poller) poll(ctx context.Context, publisher cursor.Publisher) error {
+	queryCtx, cancel := context.WithTimeout(ctx, p.config.Timeout)
+	defer cancel()
+
+	var args []interface{}
+	if p.config.Cursor != nil {
+		args = append(args, p.cursor)
+	}
+	rows, err := p.db.QueryContext(queryCtx, p.config.Query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	cursorIndex := -1
+	if p.config.Cursor != nil {
+		for i, name := range columns {
+			if name == p.config.Cursor.Column {
+				cursorIndex = i
+			}
+		}
+		if cursorIndex < 0 {
+			return fmt.Errorf("the cursor column %s is not returned by the query", p.config.Cursor.Column)
+		}
+	}
+
+	values := make([]interface{}, len(columns))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+
+	published := 0
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return err
+		}
+
+		var update interface{}
+		if cursorIndex >= 0 {
+			v, err := p.config.Cursor.Type.fromColumn(*values[cursorIndex].(*interface{}))
+			if err != nil {
+				return errors.Wrapf(err, "invalid value of the cursor column %s", p.config.Cursor.Column)
+			}
+			if p.config.Cursor.Type.less(p.cursor, v) {
+				p.cursor = v
+				update = cursorState{Value: p.config.Cursor.Type.format(v)}
+			}
+		}
+
+		if err := publisher.Publish(p.makeEvent(columns, values), update); err != nil {
+			return err
+		}
+		published++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	p.log.Debugf("Published %d rows", published)
+	return nil
+}
+
+func (p *poller) makeEvent(columns []string, values []interface{}) beat.Event {
+	row := common.MapStr{}
+	for i, name := range columns {
+		v := *values[i].(*interface{})
+		switch vv := v.(type) {
+		case nil:
+			continue
+		case []byte:
+			v = string(vv)
+		}
+		row.Put(name, v)
+	}
+
+	fields := row
+	if p.config.TargetField != "" {
+		fields = common.MapStr{}
+		fields.Put(p.config.TargetField, row)
+	}
+	return beat.Event{
+		Timestamp: time.Now(),
+		Fields:    fields,
+	}
+}
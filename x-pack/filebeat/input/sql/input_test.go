@@ -0,0 +1,250 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+const testDriverName = "sqlinputtest"
+
+func init() {
+	sql.Register(testDriverName, &testDriver{})
+	drivers["test"] = testDriverName
+}
+
+// testTable is the table queried by the test driver. Queries return the rows
+// whose id is greater than the first argument, or all rows without
+// arguments.
+var testTable struct {
+	columns []string
+	rows    [][]driver.Value
+	args    [][]driver.NamedValue
+}
+
+type testDriver struct{}
+
+func (*testDriver) Open(string) (driver.Conn, error) { return &testConn{}, nil }
+
+type testConn struct{}
+
+func (*testConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (*testConn) Close() error                        { return nil }
+func (*testConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (*testConn) QueryContext(_ context.Context, _ string, args []driver.NamedValue) (driver.Rows, error) {
+	testTable.args = append(testTable.args, args)
+	rows := &testRows{columns: testTable.columns}
+	for _, row := range testTable.rows {
+		if len(args) == 0 || row[0].(int64) > args[0].Value.(int64) {
+			rows.rows = append(rows.rows, row)
+		}
+	}
+	return rows, nil
+}
+
+type testRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *testRows) Columns() []string { return r.columns }
+func (r *testRows) Close() error      { return nil }
+func (r *testRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+type testPublisher struct {
+	events  []beat.Event
+	updates []interface{}
+}
+
+func (p *testPublisher) Publish(event beat.Event, update interface{}) error {
+	p.events = append(p.events, event)
+	p.updates = append(p.updates, update)
+	return nil
+}
+
+func newTestPoller(t *testing.T, settings map[string]interface{}) *poller {
+	t.Helper()
+	c := defaultConfig()
+	require.NoError(t, common.MustNewConfigFrom(settings).Unpack(&c))
+
+	db, err := sql.Open(c.driverName(), c.DSN)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	p, err := newPoller(c, db, logp.NewLogger("sql_test"))
+	require.NoError(t, err)
+	return p
+}
+
+func TestPollCursor(t *testing.T) {
+	created := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	testTable.columns = []string{"id", "action", "user.name", "created", "comment"}
+	testTable.rows = [][]driver.Value{
+		{int64(1), []byte("login"), "alice", created, nil},
+		{int64(2), []byte("logout"), "alice", created.Add(time.Hour), nil},
+	}
+	testTable.args = nil
+
+	p := newTestPoller(t, map[string]interface{}{
+		"driver":    "test",
+		"dsn":       "test://",
+		"sql_query": "SELECT * FROM audit WHERE id > ? ORDER BY id",
+		"cursor":    map[string]interface{}{"column": "id", "default": "0"},
+	})
+
+	var pub testPublisher
+	require.NoError(t, p.poll(context.Background(), &pub))
+	require.Len(t, pub.events, 2)
+	assert.Equal(t, common.MapStr{
+		"sql": common.MapStr{
+			"id":      int64(1),
+			"action":  "login",
+			"user":    common.MapStr{"name": "alice"},
+			"created": created,
+		},
+	}, pub.events[0].Fields)
+	assert.Equal(t, []interface{}{cursorState{Value: "1"}, cursorState{Value: "2"}}, pub.updates)
+
+	// the next query starts after the last published row
+	testTable.rows = append(testTable.rows, []driver.Value{int64(3), []byte("login"), "bob", created, "new"})
+	pub = testPublisher{}
+	require.NoError(t, p.poll(context.Background(), &pub))
+	require.Len(t, pub.events, 1)
+	assert.Equal(t, "bob", pub.events[0].Fields["sql"].(common.MapStr)["user"].(common.MapStr)["name"])
+
+	require.Len(t, testTable.args, 2)
+	assert.Equal(t, int64(0), testTable.args[0][0].Value)
+	assert.Equal(t, int64(2), testTable.args[1][0].Value)
+}
+
+func TestPollWithoutCursor(t *testing.T) {
+	testTable.columns = []string{"id", "name"}
+	testTable.rows = [][]driver.Value{
+		{int64(1), "alice"},
+		{int64(2), "bob"},
+	}
+	testTable.args = nil
+
+	p := newTestPoller(t, map[string]interface{}{
+		"driver":       "test",
+		"dsn":          "test://",
+		"sql_query":    "SELECT id, name FROM users",
+		"target_field": "",
+	})
+
+	for i := 0; i < 2; i++ {
+		var pub testPublisher
+		require.NoError(t, p.poll(context.Background(), &pub))
+		require.Len(t, pub.events, 2)
+		assert.Equal(t, common.MapStr{"id": int64(2), "name": "bob"}, pub.events[1].Fields)
+		assert.Equal(t, []interface{}{nil, nil}, pub.updates)
+	}
+	assert.Equal(t, [][]driver.NamedValue{{}, {}}, testTable.args)
+}
+
+func TestPollMissingCursorColumn(t *testing.T) {
+	testTable.columns = []string{"name"}
+	testTable.rows = nil
+
+	p := newTestPoller(t, map[string]interface{}{
+		"driver":    "test",
+		"dsn":       "test://",
+		"sql_query": "SELECT name FROM users WHERE id > ?",
+		"cursor":    map[string]interface{}{"column": "id", "default": "0"},
+	})
+	assert.Error(t, p.poll(context.Background(), &testPublisher{}))
+}
+
+func TestCursorType(t *testing.T) {
+	ts := time.Date(2020, 6, 1, 12, 0, 0, 123000000, time.UTC)
+	for name, test := range map[string]struct {
+		typ    cursorType
+		column interface{}
+		value  interface{}
+		str    string
+	}{
+		"integer":             {cursorInteger, int64(42), int64(42), "42"},
+		"integer from bytes":  {cursorInteger, []byte("42"), int64(42), "42"},
+		"integer from float":  {cursorInteger, float64(42), int64(42), "42"},
+		"timestamp":           {cursorTimestamp, ts, ts, "2020-06-01T12:00:00.123Z"},
+		"timestamp from text": {cursorTimestamp, []byte("2020-06-01 12:00:00.123"), ts, "2020-06-01T12:00:00.123Z"},
+		"string":              {cursorString, "abc", "abc", "abc"},
+		"string from integer": {cursorString, int64(42), "42", "42"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			v, err := test.typ.fromColumn(test.column)
+			require.NoError(t, err)
+			assert.Equal(t, test.value, v)
+			assert.Equal(t, test.str, test.typ.format(v))
+
+			parsed, err := test.typ.parse(test.str)
+			require.NoError(t, err)
+			assert.False(t, test.typ.less(parsed, v) || test.typ.less(v, parsed))
+		})
+	}
+
+	_, err := cursorInteger.fromColumn(1.5)
+	assert.Error(t, err)
+	_, err = cursorTimestamp.fromColumn(int64(1))
+	assert.Error(t, err)
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := map[string]interface{}{
+		"driver":    "postgres",
+		"dsn":       "postgres://localhost/db",
+		"sql_query": "SELECT * FROM audit WHERE id > $1 ORDER BY id",
+		"cursor":    map[string]interface{}{"column": "id", "default": "0"},
+	}
+	c := defaultConfig()
+	require.NoError(t, common.MustNewConfigFrom(valid).Unpack(&c))
+	assert.Equal(t, "postgres", c.driverName())
+
+	for name, override := range map[string]map[string]interface{}{
+		"unknown driver":      {"driver": "sqlite"},
+		"missing query":       {"sql_query": ""},
+		"invalid cursor type": {"cursor.type": "uuid"},
+		"invalid default":     {"cursor.default": "yesterday"},
+		"missing column":      {"cursor.column": ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := common.MustNewConfigFrom(valid)
+			require.NoError(t, cfg.Merge(override))
+			c := defaultConfig()
+			assert.Error(t, cfg.Unpack(&c))
+		})
+	}
+}
+
+func TestSourceName(t *testing.T) {
+	c := defaultConfig()
+	c.Driver, c.DSN, c.Query = "mysql", "user:secret@tcp(localhost)/db", "SELECT 1"
+	name := (&source{config: c}).Name()
+	assert.Len(t, name, 64)
+	assert.NotContains(t, name, "secret")
+
+	c.ID = "audit"
+	assert.Equal(t, "audit", (&source{config: c}).Name())
+}
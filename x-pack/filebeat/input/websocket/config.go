@@ -0,0 +1,87 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+)
+
+type config struct {
+	// URL is the URL of the WebSocket API, with the ws or wss scheme.
+	URL string `config:"url" validate:"required"`
+
+	// Headers are sent in the handshake requests, for example to
+	// authenticate with an API key.
+	Headers map[string]string `config:"headers"`
+
+	// OAuth2 authenticates the handshake requests with the OAuth2 client
+	// credentials flow.
+	OAuth2 *oauth2Config `config:"oauth2"`
+
+	// SubscribeMessages are sent after every connection, so the
+	// subscriptions are restored when the input reconnects.
+	SubscribeMessages []string `config:"subscribe_messages"`
+
+	// PingInterval is the time between two pings, 0 disables them.
+	PingInterval time.Duration `config:"ping_interval" validate:"min=0"`
+
+	// PongTimeout is the maximum time to wait for the response to a ping
+	// before closing the connection.
+	PongTimeout time.Duration `config:"pong_timeout" validate:"positive,nonzero"`
+
+	// HandshakeTimeout is the maximum duration of the handshake.
+	HandshakeTimeout time.Duration `config:"handshake_timeout" validate:"positive,nonzero"`
+
+	// Retry configures the time to wait before reconnecting.
+	Retry retryConfig `config:"retry"`
+
+	TLS *tlscommon.Config `config:"ssl"`
+}
+
+type oauth2Config struct {
+	ClientID       string              `config:"client.id" validate:"required"`
+	ClientSecret   string              `config:"client.secret" validate:"required"`
+	TokenURL       string              `config:"token_url" validate:"required"`
+	Scopes         []string            `config:"scopes"`
+	EndpointParams map[string][]string `config:"endpoint_params"`
+}
+
+type retryConfig struct {
+	// WaitMin is the time to wait before the first reconnection, it's doubled
+	// after each failure up to WaitMax.
+	WaitMin time.Duration `config:"wait_min" validate:"positive,nonzero"`
+	WaitMax time.Duration `config:"wait_max" validate:"positive,nonzero"`
+}
+
+func defaultConfig() config {
+	return config{
+		PingInterval:     30 * time.Second,
+		PongTimeout:      10 * time.Second,
+		HandshakeTimeout: 30 * time.Second,
+		Retry: retryConfig{
+			WaitMin: time.Second,
+			WaitMax: time.Minute,
+		},
+	}
+}
+
+func (c *config) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %v", err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return fmt.Errorf("invalid url scheme '%s' (valid values are: ws, wss)", u.Scheme)
+	}
+	if c.Retry.WaitMin > c.Retry.WaitMax {
+		return errors.New("retry.wait_min must not be greater than retry.wait_max")
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	v2 "github.com/elastic/beats/v7/filebeat/input/v2"
+	stateless "github.com/elastic/beats/v7/filebeat/input/v2/input-stateless"
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/backoff"
+	"github.com/elastic/beats/v7/libbeat/common/transport/tlscommon"
+	"github.com/elastic/beats/v7/libbeat/feature"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/go-concert/ctxtool"
+)
+
+const inputName = "websocket"
+
+type websocketInput struct {
+	config config
+}
+
+func Plugin() v2.Plugin {
+	return v2.Plugin{
+		Name:       inputName,
+		Stability:  feature.Experimental,
+		Deprecated: false,
+		Info:       "WebSocket input",
+		Doc:        "The WebSocket input publishes the messages received from a WebSocket API",
+		Manager:    stateless.NewInputManager(configure),
+	}
+}
+
+func configure(cfg *common.Config) (stateless.Input, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return nil, err
+	}
+	return &websocketInput{config: config}, nil
+}
+
+func (*websocketInput) Name() string { return inputName }
+
+func (inp *websocketInput) Test(ctx v2.TestContext) error {
+	s, err := newStreamer(inp.config, ctx.Logger)
+	if err != nil {
+		return err
+	}
+	conn, err := s.connect(context.Background())
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// Run streams the messages until the input is stopped, reconnecting when the
+// connection is closed or fails.
+func (inp *websocketInput) Run(ctx v2.Context, publisher stateless.Publisher) error {
+	s, err := newStreamer(inp.config, ctx.Logger)
+	if err != nil {
+		return err
+	}
+
+	cancelCtx := ctxtool.FromCanceller(ctx.Cancelation)
+	wait := backoff.NewExpBackoff(cancelCtx.Done(), inp.config.Retry.WaitMin, inp.config.Retry.WaitMax)
+	for {
+		connected, err := s.stream(cancelCtx, publisher)
+		if cancelCtx.Err() != nil {
+			return nil
+		}
+		ctx.Logger.Warnf("Connection to %s ended, reconnecting: %v", inp.config.URL, err)
+
+		if connected {
+			wait.Reset()
+		}
+		if !wait.Wait() {
+			return nil
+		}
+	}
+}
+
+// streamer connects to the WebSocket API and publishes the received messages.
+type streamer struct {
+	config config
+	log    *logp.Logger
+	dialer *websocket.Dialer
+	tokens oauth2.TokenSource
+	now    func() time.Time
+}
+
+func newStreamer(config config, log *logp.Logger) (*streamer, error) {
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: config.HandshakeTimeout,
+	}
+	if config.TLS != nil {
+		tlsConfig, err := tlscommon.LoadTLSConfig(config.TLS)
+		if err != nil {
+			return nil, err
+		}
+		dialer.TLSClientConfig = tlsConfig.BuildModuleConfig("")
+	}
+
+	s := &streamer{
+		config: config,
+		log:    log,
+		dialer: dialer,
+		now:    time.Now,
+	}
+	if config.OAuth2 != nil {
+		creds := clientcredentials.Config{
+			ClientID:       config.OAuth2.ClientID,
+			ClientSecret:   config.OAuth2.ClientSecret,
+			TokenURL:       config.OAuth2.TokenURL,
+			Scopes:         config.OAuth2.Scopes,
+			EndpointParams: config.OAuth2.EndpointParams,
+		}
+		// The token source caches the token until it expires.
+		s.tokens = creds.TokenSource(context.Background())
+	}
+	return s, nil
+}
+
+// connect establishes a connection and sends the subscribe messages.
+func (s *streamer) connect(ctx context.Context) (*websocket.Conn, error) {
+	header := http.Header{}
+	for k, v := range s.config.Headers {
+		header.Set(k, v)
+	}
+	if s.tokens != nil {
+		token, err := s.tokens.Token()
+		if err != nil {
+			return nil, fmt.Errorf("error getting the oauth2 token: %v", err)
+		}
+		header.Set("Authorization", token.Type()+" "+token.AccessToken)
+	}
+
+	conn, resp, err := s.dialer.DialContext(ctx, s.config.URL, header)
+	if err != nil {
+		if resp != nil {
+			return nil, fmt.Errorf("error connecting to %s: %v (status: %s)", s.config.URL, err, resp.Status)
+		}
+		return nil, fmt.Errorf("error connecting to %s: %v", s.config.URL, err)
+	}
+
+	for _, msg := range s.config.SubscribeMessages {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error sending the subscribe message: %v", err)
+		}
+	}
+	return conn, nil
+}
+
+// stream publishes the messages of one connection until it fails or ctx is
+// cancelled. It reports whether the connection was established.
+func (s *streamer) stream(ctx context.Context, publisher stateless.Publisher) (bool, error) {
+	conn, err := s.connect(ctx)
+	if err != nil {
+		return false, err
+	}
+	s.log.Infof("Connected to %s", s.config.URL)
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		conn.Close()
+		wg.Wait()
+	}()
+
+	// The read deadline expires if neither messages nor pongs are received
+	// for longer than a ping interval.
+	extendDeadline := func(string) error {
+		if s.config.PingInterval == 0 {
+			return nil
+		}
+		return conn.SetReadDeadline(s.now().Add(s.config.PingInterval + s.config.PongTimeout))
+	}
+	conn.SetPongHandler(extendDeadline)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		s.keepalive(ctx, conn, done)
+	}()
+
+	for {
+		extendDeadline("")
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return true, err
+		}
+		publisher.Publish(beat.Event{
+			Timestamp: s.now(),
+			Fields: common.MapStr{
+				"message": string(msg),
+			},
+		})
+	}
+}
+
+// keepalive sends pings until done is closed, and closes the connection when
+// ctx is cancelled.
+func (s *streamer) keepalive(ctx context.Context, conn *websocket.Conn, done <-chan struct{}) {
+	var ticks <-chan time.Time
+	if s.config.PingInterval > 0 {
+		ticker := time.NewTicker(s.config.PingInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			conn.WriteControl(websocket.CloseMessage, msg, s.now().Add(time.Second))
+			conn.Close()
+			return
+		case <-ticks:
+			if err := conn.WriteControl(websocket.PingMessage, nil, s.now().Add(s.config.PongTimeout)); err != nil {
+				s.log.Debugf("Error sending ping: %v", err)
+			}
+		}
+	}
+}
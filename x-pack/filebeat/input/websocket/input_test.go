@@ -0,0 +1,239 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	v2 "github.com/elastic/beats/v7/filebeat/input/v2"
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+type eventCollector struct {
+	mu       sync.Mutex
+	events   []beat.Event
+	expected int
+	done     chan struct{}
+}
+
+func newEventCollector(expected int) *eventCollector {
+	return &eventCollector{expected: expected, done: make(chan struct{})}
+}
+
+func (c *eventCollector) Publish(event beat.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, event)
+	if len(c.events) == c.expected {
+		close(c.done)
+	}
+}
+
+func (c *eventCollector) messages() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var messages []string
+	for _, event := range c.events {
+		messages = append(messages, event.Fields["message"].(string))
+	}
+	return messages
+}
+
+func wsURL(server *httptest.Server) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestRunReconnects(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "secret-token", "token_type": "Bearer", "expires_in": 3600}`)
+	}))
+	defer tokenServer.Close()
+
+	var (
+		mu              sync.Mutex
+		connections     int
+		subscriptions   []string
+		upgrader        websocket.Upgrader
+		unauthenticated int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" || r.Header.Get("X-Tenant") != "acme" {
+			mu.Lock()
+			unauthenticated++
+			mu.Unlock()
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		connections++
+		n := connections
+		subscriptions = append(subscriptions, string(msg))
+		mu.Unlock()
+
+		// Every connection sends two messages and is closed by the server.
+		for i := 1; i <= 2; i++ {
+			conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("connection %d message %d", n, i)))
+		}
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+	}))
+	defer server.Close()
+
+	inp, err := configure(common.MustNewConfigFrom(common.MapStr{
+		"url":                wsURL(server),
+		"headers":            map[string]string{"X-Tenant": "acme"},
+		"subscribe_messages": []string{`{"subscribe": "alerts"}`},
+		"oauth2": common.MapStr{
+			"client.id":     "filebeat",
+			"client.secret": "secret",
+			"token_url":     tokenServer.URL,
+		},
+		"retry.wait_min": "1ms",
+		"retry.wait_max": "10ms",
+	}))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	collector := newEventCollector(4)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- inp.Run(v2.Context{Logger: logp.NewLogger("websocket_test"), Cancelation: ctx}, collector)
+	}()
+
+	select {
+	case <-collector.done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timeout waiting for the events, got: %v", collector.messages())
+	}
+	cancel()
+	require.NoError(t, <-errs)
+
+	assert.Equal(t, []string{
+		"connection 1 message 1",
+		"connection 1 message 2",
+		"connection 2 message 1",
+		"connection 2 message 2",
+	}, collector.messages()[:4])
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 0, unauthenticated)
+	assert.Equal(t, []string{`{"subscribe": "alerts"}`, `{"subscribe": "alerts"}`}, subscriptions[:2])
+}
+
+func TestStreamPongTimeout(t *testing.T) {
+	var upgrader websocket.Upgrader
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// The server doesn't read, so the pings are never answered.
+		conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	config := defaultConfig()
+	config.URL = wsURL(server)
+	config.PingInterval = 20 * time.Millisecond
+	config.PongTimeout = 20 * time.Millisecond
+	s, err := newStreamer(config, logp.NewLogger("websocket_test"))
+	require.NoError(t, err)
+
+	collector := newEventCollector(1)
+	connected, err := s.stream(context.Background(), collector)
+	assert.True(t, connected)
+	require.Error(t, err)
+	netErr, ok := err.(net.Error)
+	require.True(t, ok, "unexpected error: %v", err)
+	assert.True(t, netErr.Timeout())
+	assert.Equal(t, []string{"hello"}, collector.messages())
+}
+
+func TestStreamHandshakeError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	config := defaultConfig()
+	config.URL = wsURL(server)
+	s, err := newStreamer(config, logp.NewLogger("websocket_test"))
+	require.NoError(t, err)
+
+	connected, err := s.stream(context.Background(), newEventCollector(1))
+	assert.False(t, connected)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "403 Forbidden")
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := map[string]struct {
+		config common.MapStr
+		err    string
+	}{
+		"valid": {
+			config: common.MapStr{"url": "wss://example.com/stream"},
+		},
+		"missing url": {
+			config: common.MapStr{},
+			err:    "value is not set",
+		},
+		"http url": {
+			config: common.MapStr{"url": "https://example.com/stream"},
+			err:    "invalid url scheme",
+		},
+		"invalid retry": {
+			config: common.MapStr{"url": "ws://example.com", "retry.wait_min": "1m", "retry.wait_max": "1s"},
+			err:    "retry.wait_min",
+		},
+		"oauth2 without token url": {
+			config: common.MapStr{"url": "ws://example.com", "oauth2.client.id": "id", "oauth2.client.secret": "secret"},
+			err:    "value is not set",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := configure(common.MustNewConfigFrom(test.config))
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
@@ -7,6 +7,7 @@ package include
 import (
 	"github.com/elastic/beats/v7/libbeat/feature"
 	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/aws"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/azure"
 	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/gcp"
 	"github.com/elastic/beats/v7/x-pack/functionbeat/provider/local/local"
 )
@@ -14,6 +15,7 @@ import (
 // Bundle feature enabled.
 var Bundle = feature.MustBundle(
 	aws.Bundle,
+	azure.Bundle,
 	gcp.Bundle,
 	local.Bundle,
 )
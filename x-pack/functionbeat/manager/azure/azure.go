@@ -0,0 +1,24 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"github.com/elastic/beats/v7/libbeat/feature"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/function/provider"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/provider/azure/azure"
+)
+
+// Bundle exposes the trigger supported by the azure provider.
+var Bundle = provider.MustCreate(
+	"azure",
+	provider.NewDefaultProvider("azure", NewCLI, NewTemplateBuilder),
+	feature.MakeDetails("Azure Functions", "listen to events on Azure Functions", feature.Beta),
+).MustAddFunction("event_hub",
+	azure.NewEventHub,
+	azure.EventHubDetails(),
+).MustAddFunction("blob_storage",
+	azure.NewBlobStorage,
+	azure.BlobStorageDetails(),
+).Bundle()
@@ -0,0 +1,207 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"github.com/pkg/errors"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/function/provider"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/core"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/core/bundle"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/executor"
+)
+
+// defaultFunctionTimeout is the timeout of the functions in the packages
+// generated by the package command.
+const defaultFunctionTimeout = 5 * time.Minute
+
+// CLIManager interacts with Azure to deploy, update or remove a function.
+type CLIManager struct {
+	templateBuilder *defaultTemplateBuilder
+	authorizer      autorest.Authorizer
+	log             *logp.Logger
+	config          *Config
+}
+
+// Deploy uploads the function to Azure.
+func (c *CLIManager) Deploy(name string) error {
+	c.log.Debugf("Deploying function: %s", name)
+	defer c.log.Debugf("Deploy finish for function '%s'", name)
+
+	if err := c.deploy(false, name); err != nil {
+		return err
+	}
+
+	c.log.Debugf("Successfully created function '%s'", name)
+	return nil
+}
+
+// Update updates the function.
+func (c *CLIManager) Update(name string) error {
+	c.log.Debugf("Starting updating function '%s'", name)
+	defer c.log.Debugf("Update complete for function '%s'", name)
+
+	if err := c.deploy(true, name); err != nil {
+		return err
+	}
+
+	c.log.Debugf("Successfully updated function: '%s'", name)
+	return nil
+}
+
+// deploy uploads the package of the function and deploys the function app
+// running it. The package is stored under its checksum, so updating the
+// function changes its URL and the function app loads the new package.
+func (c *CLIManager) deploy(update bool, name string) error {
+	functionData, err := c.templateBuilder.execute(name)
+	if err != nil {
+		return err
+	}
+
+	authorizer, err := c.getAuthorizer()
+	if err != nil {
+		return err
+	}
+	client, err := newContainerClient(context.Background(), authorizer, c.config)
+	if err != nil {
+		return err
+	}
+
+	ctx := &deployContext{}
+	packageName := name + "/" + functionData.checksum + "/functionbeat.zip"
+
+	executer := executor.NewExecutor(c.log)
+	executer.Add(newOpEnsureContainer(c.log, client, c.config.DeployContainer))
+	executer.Add(newOpUploadToContainer(ctx, c.log, client, packageName, functionData.raw))
+	executer.Add(newOpDeployTemplate(ctx, c.log, authorizer, c.config, name, functionData.template, update))
+
+	if err := executer.Execute(nil); err != nil {
+		if rollbackErr := executer.Rollback(nil); rollbackErr != nil {
+			return errors.Wrapf(err, "could not rollback, error: %s", rollbackErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Remove removes the function app and the packages of the function.
+func (c *CLIManager) Remove(name string) error {
+	c.log.Debugf("Removing function: %s", name)
+	defer c.log.Debugf("Removal of function '%s' complete", name)
+
+	authorizer, err := c.getAuthorizer()
+	if err != nil {
+		return err
+	}
+	client, err := newContainerClient(context.Background(), authorizer, c.config)
+	if err != nil {
+		return err
+	}
+
+	executer := executor.NewExecutor(c.log)
+	executer.Add(newOpDeleteFunctionApp(c.log, authorizer, c.config, name))
+	executer.Add(newOpDeleteFromContainer(c.log, client, name+"/"))
+
+	if err := executer.Execute(nil); err != nil {
+		if rollbackErr := executer.Rollback(nil); rollbackErr != nil {
+			return errors.Wrapf(err, "could not rollback, error: %s", rollbackErr)
+		}
+		return err
+	}
+
+	c.log.Debugf("Successfully deleted function: '%s'", name)
+	return nil
+}
+
+// Export prints the exported function data.
+func (c *CLIManager) Export(name string) error {
+	tmpl, err := c.templateBuilder.RawTemplate(name)
+	if err != nil {
+		return err
+	}
+	fmt.Println(tmpl)
+
+	return nil
+}
+
+// Package packages functions for Azure. The package doesn't contain the
+// directory of a function, it's added when the function is deployed.
+func (c *CLIManager) Package(outputPattern string) error {
+	rawHost, err := json.Marshal(hostConfig(defaultFunctionTimeout))
+	if err != nil {
+		return err
+	}
+
+	resources := append(zipResources(), &bundle.MemoryFile{Path: "host.json", Raw: rawHost, FileMode: 0644})
+	content, err := core.MakeZip(packageUncompressedLimit, packageCompressedLimit, resources)
+	if err != nil {
+		return err
+	}
+
+	output := strings.ReplaceAll(outputPattern, "{{.Provider}}", "azure")
+	err = ioutil.WriteFile(output, content, 0644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Generated package for provider azure at: %s\n", output)
+	return nil
+}
+
+// getAuthorizer authenticates to the Resource Manager with the credentials
+// found in the environment.
+// Ref: https://docs.microsoft.com/en-us/azure/developer/go/azure-sdk-authorization
+func (c *CLIManager) getAuthorizer() (autorest.Authorizer, error) {
+	if c.authorizer != nil {
+		return c.authorizer, nil
+	}
+
+	var err error
+	c.authorizer, err = auth.NewAuthorizerFromEnvironment()
+	if err != nil {
+		return nil, fmt.Errorf("error while creating CLIManager: %+v", err)
+	}
+
+	return c.authorizer, nil
+}
+
+// NewCLI returns the interface to manage functions on Azure.
+func NewCLI(
+	log *logp.Logger,
+	cfg *common.Config,
+	provider provider.Provider,
+) (provider.CLIManager, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(config); err != nil {
+		return nil, err
+	}
+
+	builder, err := provider.TemplateBuilder()
+	if err != nil {
+		return nil, err
+	}
+	templateBuilder, ok := builder.(*defaultTemplateBuilder)
+	if !ok {
+		return nil, fmt.Errorf("not defaultTemplateBuilder")
+	}
+
+	return &CLIManager{
+		config:          config,
+		log:             logp.NewLogger("azure"),
+		templateBuilder: templateBuilder,
+	}, nil
+}
@@ -0,0 +1,23 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+// Config exposes the configuration options of the Azure provider.
+type Config struct {
+	SubscriptionID string `config:"subscription_id" validate:"required"`
+	ResourceGroup  string `config:"resource_group" validate:"required"`
+	Location       string `config:"location" validate:"required"`
+
+	// StorageAccount is used by the function apps, and to store the packages
+	// of the functions. It must be in the resource group of the functions.
+	StorageAccount  string `config:"storage_account" validate:"required"`
+	DeployContainer string `config:"deploy_container"`
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		DeployContainer: "functionbeat-deploy",
+	}
+}
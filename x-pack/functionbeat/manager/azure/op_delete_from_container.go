@@ -0,0 +1,50 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/executor"
+)
+
+type opDeleteFromContainer struct {
+	log    *logp.Logger
+	client *containerClient
+	prefix string
+}
+
+// newOpDeleteFromContainer deletes the blobs whose names start with prefix.
+func newOpDeleteFromContainer(log *logp.Logger, client *containerClient, prefix string) *opDeleteFromContainer {
+	return &opDeleteFromContainer{log: log, client: client, prefix: prefix}
+}
+
+// Execute deletes the packages of the function.
+func (o *opDeleteFromContainer) Execute(_ executor.Context) error {
+	o.log.Debugf("Removing files '%s' from container", o.prefix)
+
+	ctx := context.Background()
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		list, err := o.client.container.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: o.prefix})
+		if err != nil {
+			return fmt.Errorf("error while listing function files: %+v", err)
+		}
+
+		for _, item := range list.Segment.BlobItems {
+			blob := o.client.container.NewBlobURL(item.Name)
+			if _, err := blob.Delete(ctx, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{}); err != nil {
+				return fmt.Errorf("error while deleting function file '%s': %+v", item.Name, err)
+			}
+		}
+		marker = list.NextMarker
+	}
+
+	o.log.Debugf("Files '%s' removed from container", o.prefix)
+	return nil
+}
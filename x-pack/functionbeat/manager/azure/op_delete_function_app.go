@@ -0,0 +1,49 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/services/web/mgmt/2019-08-01/web"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/to"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/executor"
+)
+
+type opDeleteFunctionApp struct {
+	log        *logp.Logger
+	authorizer autorest.Authorizer
+	config     *Config
+	name       string
+}
+
+func newOpDeleteFunctionApp(log *logp.Logger, authorizer autorest.Authorizer, config *Config, name string) *opDeleteFunctionApp {
+	return &opDeleteFunctionApp{log: log, authorizer: authorizer, config: config, name: name}
+}
+
+// Execute deletes the function app, and its plan once it's empty.
+func (o *opDeleteFunctionApp) Execute(_ executor.Context) error {
+	o.log.Debugf("Removing function app: %s", o.name)
+
+	client := web.NewAppsClient(o.config.SubscriptionID)
+	client.Authorizer = o.authorizer
+
+	resp, err := client.Delete(context.Background(), o.config.ResourceGroup, o.name, to.BoolPtr(true), to.BoolPtr(true))
+	if err != nil {
+		if resp.Response != nil && resp.StatusCode == http.StatusNotFound {
+			o.log.Debugf("Function app '%s' doesn't exist", o.name)
+			return nil
+		}
+		return fmt.Errorf("error while deleting function app: %+v", err)
+	}
+
+	o.log.Debugf("Function app '%s' removed", o.name)
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2019-03-01/resources"
+	"github.com/Azure/go-autorest/autorest"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/executor"
+)
+
+type opDeployTemplate struct {
+	ctx        *deployContext
+	log        *logp.Logger
+	authorizer autorest.Authorizer
+	config     *Config
+	name       string
+	template   common.MapStr
+	update     bool
+}
+
+func newOpDeployTemplate(
+	ctx *deployContext,
+	log *logp.Logger,
+	authorizer autorest.Authorizer,
+	config *Config,
+	name string,
+	template common.MapStr,
+	update bool,
+) *opDeployTemplate {
+	return &opDeployTemplate{
+		ctx:        ctx,
+		log:        log,
+		authorizer: authorizer,
+		config:     config,
+		name:       name,
+		template:   template,
+		update:     update,
+	}
+}
+
+// Execute deploys the template and waits for the deployment to complete. The
+// deployment is incremental, so updating a function deploys the same template.
+func (o *opDeployTemplate) Execute(_ executor.Context) error {
+	deploymentName := "functionbeat-" + o.name
+	o.log.Debugf("Deploying template '%s' in resource group '%s'", deploymentName, o.config.ResourceGroup)
+
+	client := resources.NewDeploymentsClient(o.config.SubscriptionID)
+	client.Authorizer = o.authorizer
+
+	ctx := context.Background()
+	future, err := client.CreateOrUpdate(ctx, o.config.ResourceGroup, deploymentName, resources.Deployment{
+		Properties: &resources.DeploymentProperties{
+			Template: o.template,
+			Parameters: map[string]interface{}{
+				"packageUri": map[string]interface{}{"value": o.ctx.packageURI},
+			},
+			Mode: resources.Incremental,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error while deploying function: %+v", err)
+	}
+
+	if err := future.WaitForCompletionRef(ctx, client.Client); err != nil {
+		return fmt.Errorf("error while waiting for the deployment of the function: %+v", err)
+	}
+	if _, err := future.Result(client); err != nil {
+		return fmt.Errorf("deployment of the function failed: %+v", err)
+	}
+
+	o.log.Debugf("Template '%s' deployed successfully", deploymentName)
+	return nil
+}
+
+// Rollback removes the function app if it was created by the deployment.
+func (o *opDeployTemplate) Rollback(ctx executor.Context) error {
+	if o.update {
+		return nil
+	}
+	return newOpDeleteFunctionApp(o.log, o.authorizer, o.config, o.name).Execute(ctx)
+}
@@ -0,0 +1,53 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/executor"
+)
+
+type opEnsureContainer struct {
+	log     *logp.Logger
+	client  *containerClient
+	name    string
+	created bool
+}
+
+func newOpEnsureContainer(log *logp.Logger, client *containerClient, name string) *opEnsureContainer {
+	return &opEnsureContainer{log: log, client: client, name: name}
+}
+
+// Execute creates the deploy container if it doesn't exist.
+func (o *opEnsureContainer) Execute(_ executor.Context) error {
+	o.log.Debugf("Verifying presence of storage container: '%s'", o.name)
+
+	_, err := o.client.container.Create(context.Background(), nil, azblob.PublicAccessNone)
+	if err != nil {
+		if serr, ok := err.(azblob.StorageError); ok && serr.ServiceCode() == azblob.ServiceCodeContainerAlreadyExists {
+			o.log.Debugf("Storage container exists with name '%s'", o.name)
+			return nil
+		}
+		return fmt.Errorf("cannot create container for function: %+v", err)
+	}
+
+	o.created = true
+	o.log.Debugf("Storage container created with name '%s'", o.name)
+	return nil
+}
+
+// Rollback removes the container if it was created.
+func (o *opEnsureContainer) Rollback(_ executor.Context) error {
+	if !o.created {
+		return nil
+	}
+	_, err := o.client.container.Delete(context.Background(), azblob.ContainerAccessConditions{})
+	return err
+}
@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/executor"
+)
+
+// packageURIValidity is how long the function app can read its package. The
+// package is read every time the function app starts, so the signature of its
+// URL must not expire while the function is deployed.
+const packageURIValidity = 10 * 365 * 24 * time.Hour
+
+type opUploadToContainer struct {
+	ctx    *deployContext
+	log    *logp.Logger
+	client *containerClient
+	name   string
+	raw    []byte
+}
+
+func newOpUploadToContainer(
+	ctx *deployContext,
+	log *logp.Logger,
+	client *containerClient,
+	name string,
+	raw []byte,
+) *opUploadToContainer {
+	return &opUploadToContainer{
+		ctx:    ctx,
+		log:    log,
+		client: client,
+		name:   name,
+		raw:    raw,
+	}
+}
+
+// Execute uploads the package and signs its URL for the function app.
+func (o *opUploadToContainer) Execute(_ executor.Context) error {
+	o.log.Debugf("Uploading file '%s' to container with size %d bytes", o.name, len(o.raw))
+
+	blob := o.client.container.NewBlockBlobURL(o.name)
+	_, err := azblob.UploadBufferToBlockBlob(context.Background(), o.raw, blob, azblob.UploadToBlockBlobOptions{
+		BlobHTTPHeaders: azblob.BlobHTTPHeaders{ContentType: "application/zip"},
+	})
+	if err != nil {
+		return fmt.Errorf("error while uploading function: %+v", err)
+	}
+
+	parts := azblob.NewBlobURLParts(blob.URL())
+	parts.SAS, err = azblob.BlobSASSignatureValues{
+		Protocol:      azblob.SASProtocolHTTPS,
+		ExpiryTime:    time.Now().UTC().Add(packageURIValidity),
+		ContainerName: parts.ContainerName,
+		BlobName:      parts.BlobName,
+		Permissions:   azblob.BlobSASPermissions{Read: true}.String(),
+	}.NewSASQueryParameters(o.client.credential)
+	if err != nil {
+		return fmt.Errorf("error while signing the URL of the function: %+v", err)
+	}
+
+	u := parts.URL()
+	o.ctx.packageURI = u.String()
+
+	o.log.Debugf("Upload to container was successful")
+	return nil
+}
+
+// Rollback removes the uploaded package.
+func (o *opUploadToContainer) Rollback(ctx executor.Context) error {
+	err := newOpDeleteFromContainer(o.log, o.client, o.name).Execute(ctx)
+	if err != nil {
+		o.log.Debugf("Fail to delete file from container, error: %+v", err)
+	}
+	return nil
+}
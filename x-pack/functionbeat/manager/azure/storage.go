@@ -0,0 +1,60 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2019-06-01/storage"
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/Azure/go-autorest/autorest"
+)
+
+// deployContext holds the state shared by the operations of a deployment.
+type deployContext struct {
+	// packageURI is the URL of the uploaded package, signed to be readable
+	// by the function app.
+	packageURI string
+}
+
+// containerClient gives access to the container storing the packages of the
+// functions.
+type containerClient struct {
+	credential *azblob.SharedKeyCredential
+	container  azblob.ContainerURL
+}
+
+// newContainerClient uses the keys of the storage account to access the
+// deploy container, so no other credentials than the ones of the Resource
+// Manager are required.
+func newContainerClient(ctx context.Context, authorizer autorest.Authorizer, config *Config) (*containerClient, error) {
+	accounts := storage.NewAccountsClient(config.SubscriptionID)
+	accounts.Authorizer = authorizer
+
+	keys, err := accounts.ListKeys(ctx, config.ResourceGroup, config.StorageAccount, "")
+	if err != nil {
+		return nil, fmt.Errorf("error while listing the keys of storage account '%s': %+v", config.StorageAccount, err)
+	}
+	if keys.Keys == nil || len(*keys.Keys) == 0 || (*keys.Keys)[0].Value == nil {
+		return nil, fmt.Errorf("storage account '%s' has no keys", config.StorageAccount)
+	}
+
+	credential, err := azblob.NewSharedKeyCredential(config.StorageAccount, *(*keys.Keys)[0].Value)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s", config.StorageAccount, config.DeployContainer))
+	if err != nil {
+		return nil, err
+	}
+
+	return &containerClient{
+		credential: credential,
+		container:  azblob.NewContainerURL(*u, azblob.NewPipeline(credential, azblob.PipelineOptions{})),
+	}, nil
+}
@@ -0,0 +1,270 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/function/provider"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/core"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/core/bundle"
+	fnazure "github.com/elastic/beats/v7/x-pack/functionbeat/provider/azure/azure"
+)
+
+const (
+	handlerName = "functionbeat-azure"
+
+	templateSchema = "https://schema.management.azure.com/schemas/2019-04-01/deploymentTemplate.json#"
+	webAPIVersion  = "2019-08-01"
+
+	// storageConnectionString is the ARM expression of the connection string
+	// of the storage account.
+	storageConnectionString = "[concat('DefaultEndpointsProtocol=https;AccountName=', parameters('storageAccount'), " +
+		"';AccountKey=', listKeys(resourceId('Microsoft.Storage/storageAccounts', parameters('storageAccount')), '2019-06-01').keys[0].value, " +
+		"';EndpointSuffix=', environment().suffixes.storage)]"
+
+	// Package size limits for Azure, the package is mounted from a blob.
+	// Ref: https://docs.microsoft.com/en-us/azure/azure-functions/run-functions-from-deployment-package
+	packageCompressedLimit   = 100 * 1000 * 1000  // 100MB
+	packageUncompressedLimit = 1000 * 1000 * 1000 // 1GB
+)
+
+type installer interface {
+	Config() *fnazure.FunctionConfig
+	Binding() common.MapStr
+	AppSettings() map[string]string
+	Name() string
+}
+
+// defaultTemplateBuilder builds the Azure Resource Manager template deploying
+// a function app running a function.
+type defaultTemplateBuilder struct {
+	provider provider.Provider
+	log      *logp.Logger
+	config   *Config
+}
+
+// functionData stores the package of a function and the template to deploy it.
+type functionData struct {
+	raw      []byte
+	checksum string
+	template common.MapStr
+}
+
+// NewTemplateBuilder returns the requested template builder
+func NewTemplateBuilder(log *logp.Logger, cfg *common.Config, p provider.Provider) (provider.TemplateBuilder, error) {
+	config := defaultConfig()
+	if err := cfg.Unpack(config); err != nil {
+		return nil, err
+	}
+
+	return &defaultTemplateBuilder{log: log, config: config, provider: p}, nil
+}
+
+func (d *defaultTemplateBuilder) execute(name string) (*functionData, error) {
+	d.log.Debug("Compressing all assets into an artifact")
+
+	fn, err := findFunction(d.provider, name)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, err := zipResourcesOfFunc(fn)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := core.MakeZip(packageUncompressedLimit, packageCompressedLimit, resources)
+	if err != nil {
+		return nil, err
+	}
+
+	d.log.Debugf("Compression is successful (zip size: %d bytes)", len(raw))
+
+	return &functionData{
+		raw:      raw,
+		checksum: checksum(raw),
+		template: d.template(name, fn),
+	}, nil
+}
+
+func findFunction(p provider.Provider, name string) (installer, error) {
+	fn, err := p.FindFunctionByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	function, ok := fn.(installer)
+	if !ok {
+		return nil, errors.New("incompatible type received, expecting: 'functionManager'")
+	}
+
+	return function, nil
+}
+
+// template returns the template creating the function app and its
+// consumption plan. The URL of the package is passed as parameter.
+func (d *defaultTemplateBuilder) template(name string, fn installer) common.MapStr {
+	config := fn.Config()
+	planName := name + "-plan"
+
+	settings := map[string]string{
+		"FUNCTIONS_EXTENSION_VERSION": "~3",
+		"FUNCTIONS_WORKER_RUNTIME":    "custom",
+		"AzureWebJobsStorage":         storageConnectionString,
+		"WEBSITE_RUN_FROM_PACKAGE":    "[parameters('packageUri')]",
+		"ENABLED_FUNCTIONS":           name,
+		"BEAT_STRICT_PERMS":           "false", // Disable any check on disk, the package is mounted read-only.
+	}
+	for k, v := range fn.AppSettings() {
+		settings[k] = v
+	}
+
+	// Sort the settings, so the same template is generated for the same
+	// configuration.
+	keys := make([]string, 0, len(settings))
+	for k := range settings {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	appSettings := make([]common.MapStr, len(keys))
+	for i, k := range keys {
+		appSettings[i] = common.MapStr{"name": k, "value": settings[k]}
+	}
+
+	tags := map[string]string{}
+	for k, v := range config.Tags {
+		tags[k] = v
+	}
+	if config.Description != "" {
+		tags["description"] = config.Description
+	}
+
+	return common.MapStr{
+		"$schema":        templateSchema,
+		"contentVersion": "1.0.0.0",
+		"parameters": common.MapStr{
+			"packageUri": common.MapStr{"type": "securestring"},
+			"storageAccount": common.MapStr{
+				"type":         "string",
+				"defaultValue": d.config.StorageAccount,
+			},
+		},
+		"resources": []common.MapStr{
+			{
+				"type":       "Microsoft.Web/serverfarms",
+				"apiVersion": webAPIVersion,
+				"name":       planName,
+				"location":   d.config.Location,
+				"kind":       "linux",
+				"sku": common.MapStr{
+					"name": "Y1",
+					"tier": "Dynamic",
+				},
+				"properties": common.MapStr{
+					"reserved": true,
+				},
+			},
+			{
+				"type":       "Microsoft.Web/sites",
+				"apiVersion": webAPIVersion,
+				"name":       name,
+				"location":   d.config.Location,
+				"kind":       "functionapp,linux",
+				"tags":       tags,
+				"dependsOn": []string{
+					"[resourceId('Microsoft.Web/serverfarms', '" + planName + "')]",
+				},
+				"properties": common.MapStr{
+					"serverFarmId": "[resourceId('Microsoft.Web/serverfarms', '" + planName + "')]",
+					"reserved":     true,
+					"siteConfig": common.MapStr{
+						"appSettings": appSettings,
+					},
+				},
+			},
+		},
+	}
+}
+
+// RawTemplate returns the template deploying the function.
+func (d *defaultTemplateBuilder) RawTemplate(name string) (string, error) {
+	fn, err := findFunction(d.provider, name)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := json.MarshalIndent(d.template(name, fn), "", "  ")
+	return string(raw), err
+}
+
+// hostConfig returns the host.json of the function app, configuring
+// Functionbeat as its custom handler.
+// Ref: https://docs.microsoft.com/en-us/azure/azure-functions/functions-custom-handlers
+func hostConfig(timeout time.Duration) common.MapStr {
+	return common.MapStr{
+		"version":         "2.0",
+		"functionTimeout": formatTimeout(timeout),
+		"customHandler": common.MapStr{
+			"description": common.MapStr{
+				"defaultExecutablePath": handlerName,
+			},
+		},
+		"extensionBundle": common.MapStr{
+			"id":      "Microsoft.Azure.Functions.ExtensionBundle",
+			"version": "[1.*, 2.0.0)",
+		},
+	}
+}
+
+// formatTimeout formats a timeout as expected by host.json.
+func formatTimeout(d time.Duration) string {
+	d = d.Round(time.Second)
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+}
+
+func checksum(data []byte) string {
+	sha := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sha[:])
+}
+
+func zipResources() []bundle.Resource {
+	return []bundle.Resource{
+		&bundle.LocalFile{Path: "pkg/" + handlerName, FileMode: 0755},
+	}
+}
+
+// zipResourcesOfFunc returns the resources of the package of a function app
+// running fn. The directory of the function is named after the function, so
+// the Functions host forwards its invocations to /<name>.
+func zipResourcesOfFunc(fn installer) ([]bundle.Resource, error) {
+	config := fn.Config()
+
+	host, err := json.Marshal(hostConfig(config.Timeout))
+	if err != nil {
+		return nil, err
+	}
+	function, err := json.Marshal(common.MapStr{
+		"bindings": []common.MapStr{fn.Binding()},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(zipResources(),
+		&bundle.MemoryFile{Path: "host.json", Raw: host, FileMode: 0644},
+		&bundle.MemoryFile{Path: config.Name + "/function.json", Raw: function, FileMode: 0644},
+	), nil
+}
@@ -0,0 +1,119 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/manager/core/bundle"
+	fnazure "github.com/elastic/beats/v7/x-pack/functionbeat/provider/azure/azure"
+)
+
+type mockFunction struct {
+	config   fnazure.FunctionConfig
+	settings map[string]string
+}
+
+func (m *mockFunction) Config() *fnazure.FunctionConfig { return &m.config }
+func (m *mockFunction) Name() string                    { return "event_hub" }
+func (m *mockFunction) AppSettings() map[string]string  { return m.settings }
+func (m *mockFunction) Binding() common.MapStr {
+	return common.MapStr{"type": "eventHubTrigger", "name": "events", "direction": "in"}
+}
+
+func newMockFunction() *mockFunction {
+	return &mockFunction{
+		config: fnazure.FunctionConfig{
+			Name:        "my-function",
+			Description: "my description",
+			Timeout:     90 * time.Second,
+			Tags:        map[string]string{"team": "ops"},
+		},
+		settings: map[string]string{"FUNCTIONBEAT_EVENTHUB_CONNECTION": "secret"},
+	}
+}
+
+func TestFormatTimeout(t *testing.T) {
+	assert.Equal(t, "00:05:00", formatTimeout(5*time.Minute))
+	assert.Equal(t, "00:01:30", formatTimeout(90*time.Second))
+	assert.Equal(t, "01:00:01", formatTimeout(time.Hour+time.Second))
+}
+
+func TestTemplate(t *testing.T) {
+	builder := &defaultTemplateBuilder{config: &Config{
+		Location:       "westeurope",
+		StorageAccount: "functionbeat",
+	}}
+	template := builder.template("my-function", newMockFunction())
+
+	resources := template["resources"].([]common.MapStr)
+	require.Equal(t, 2, len(resources))
+	assert.Equal(t, "my-function-plan", resources[0]["name"])
+
+	site := resources[1]
+	assert.Equal(t, "my-function", site["name"])
+	assert.Equal(t, "westeurope", site["location"])
+	assert.Equal(t, map[string]string{"team": "ops", "description": "my description"}, site["tags"])
+
+	settings, err := site.GetValue("properties.siteConfig.appSettings")
+	require.NoError(t, err)
+
+	var names []string
+	values := map[string]interface{}{}
+	for _, setting := range settings.([]common.MapStr) {
+		name := setting["name"].(string)
+		names = append(names, name)
+		values[name] = setting["value"]
+	}
+	assert.Equal(t, []string{
+		"AzureWebJobsStorage",
+		"BEAT_STRICT_PERMS",
+		"ENABLED_FUNCTIONS",
+		"FUNCTIONBEAT_EVENTHUB_CONNECTION",
+		"FUNCTIONS_EXTENSION_VERSION",
+		"FUNCTIONS_WORKER_RUNTIME",
+		"WEBSITE_RUN_FROM_PACKAGE",
+	}, names)
+	assert.Equal(t, "my-function", values["ENABLED_FUNCTIONS"])
+	assert.Equal(t, "custom", values["FUNCTIONS_WORKER_RUNTIME"])
+	assert.Equal(t, "secret", values["FUNCTIONBEAT_EVENTHUB_CONNECTION"])
+}
+
+func TestZipResourcesOfFunc(t *testing.T) {
+	resources, err := zipResourcesOfFunc(newMockFunction())
+	require.NoError(t, err)
+
+	files := map[string][]byte{}
+	for _, r := range resources {
+		if f, ok := r.(*bundle.MemoryFile); ok {
+			files[f.Path] = f.Raw
+		}
+	}
+
+	var host struct {
+		FunctionTimeout string `json:"functionTimeout"`
+		CustomHandler   struct {
+			Description struct {
+				DefaultExecutablePath string `json:"defaultExecutablePath"`
+			} `json:"description"`
+		} `json:"customHandler"`
+	}
+	require.NoError(t, json.Unmarshal(files["host.json"], &host))
+	assert.Equal(t, "00:01:30", host.FunctionTimeout)
+	assert.Equal(t, handlerName, host.CustomHandler.Description.DefaultExecutablePath)
+
+	var function struct {
+		Bindings []map[string]interface{} `json:"bindings"`
+	}
+	require.NoError(t, json.Unmarshal(files["my-function/function.json"], &function))
+	require.Equal(t, 1, len(function.Bindings))
+	assert.Equal(t, "eventHubTrigger", function.Bindings[0]["type"])
+}
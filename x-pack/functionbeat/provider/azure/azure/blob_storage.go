@@ -0,0 +1,80 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"context"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/v7/libbeat/feature"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/function/core"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/function/provider"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/function/telemetry"
+)
+
+// BlobStorage reads the blobs written to a container and forwards their lines
+// to the output.
+type BlobStorage struct {
+	log    *logp.Logger
+	config *BlobStorageConfig
+}
+
+// NewBlobStorage creates a new function to read the blobs written to a container.
+func NewBlobStorage(provider provider.Provider, cfg *common.Config) (provider.Function, error) {
+	cfgwarn.Beta("Azure Functions support is in beta")
+
+	config := defaultBlobStorageConfig()
+	if err := cfg.Unpack(config); err != nil {
+		return nil, err
+	}
+	return &BlobStorage{log: logp.NewLogger("blob_storage"), config: config}, nil
+}
+
+// BlobStorageDetails returns the details of the feature.
+func BlobStorageDetails() feature.Details {
+	return feature.MakeDetails("Blob Storage trigger", "read the blobs written to an Azure Storage container", feature.Beta)
+}
+
+// Run starts the custom handler of the function and waits for invocations.
+func (b *BlobStorage) Run(ctx context.Context, client core.Client, t telemetry.T) error {
+	t.AddTriggeredFunction()
+
+	return serve(ctx, b.log, b.config.Name, func(inv *invocation) error {
+		events, err := transformBlob(inv)
+		if err != nil {
+			return err
+		}
+		b.log.Debugf("The handler reads %d lines", len(events))
+
+		if err := client.PublishAll(events); err != nil {
+			b.log.Errorf("Could not publish events to the pipeline, error: %+v", err)
+			return err
+		}
+		client.Wait()
+		return nil
+	})
+}
+
+// Name returns the name of the function.
+func (b *BlobStorage) Name() string {
+	return "blob_storage"
+}
+
+// Config returns the configuration common to the Azure functions.
+func (b *BlobStorage) Config() *FunctionConfig {
+	return &b.config.FunctionConfig
+}
+
+// Binding returns the trigger binding of the function.
+func (b *BlobStorage) Binding() common.MapStr {
+	return b.config.Binding()
+}
+
+// AppSettings returns the settings required by the trigger.
+func (b *BlobStorage) AppSettings() map[string]string {
+	return b.config.AppSettings()
+}
@@ -0,0 +1,130 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// Names of the app settings holding the connection strings used by the
+// triggers.
+const (
+	eventHubConnectionSetting = "FUNCTIONBEAT_EVENTHUB_CONNECTION"
+	blobConnectionSetting     = "FUNCTIONBEAT_BLOB_CONNECTION"
+
+	// webJobsStorageSetting holds the connection string of the storage
+	// account of the function app.
+	webJobsStorageSetting = "AzureWebJobsStorage"
+)
+
+// maxTimeout is the longest timeout of a function running in a consumption plan.
+// Ref: https://docs.microsoft.com/en-us/azure/azure-functions/functions-scale#timeout
+const maxTimeout = 10 * time.Minute
+
+// FunctionConfig stores the configuration common to the Azure functions.
+type FunctionConfig struct {
+	Name        string            `config:"name" validate:"nonzero,required"`
+	Description string            `config:"description"`
+	Timeout     time.Duration     `config:"timeout" validate:"nonzero,positive"`
+	Tags        map[string]string `config:"tags"`
+}
+
+func defaultFunctionConfig() FunctionConfig {
+	return FunctionConfig{
+		Timeout: 5 * time.Minute,
+	}
+}
+
+// Validate checks a function configuration.
+func (c *FunctionConfig) Validate() error {
+	if c.Timeout > maxTimeout {
+		return fmt.Errorf("'timeout' must be lower than %v", maxTimeout)
+	}
+	return nil
+}
+
+// EventHubConfig is the configuration of a function triggered by the events
+// of an Event Hub.
+type EventHubConfig struct {
+	FunctionConfig   `config:",inline"`
+	EventHub         string `config:"event_hub" validate:"required"`
+	ConsumerGroup    string `config:"consumer_group"`
+	ConnectionString string `config:"connection_string" validate:"required"`
+}
+
+func defaultEventHubConfig() *EventHubConfig {
+	return &EventHubConfig{
+		FunctionConfig: defaultFunctionConfig(),
+		ConsumerGroup:  "$Default",
+	}
+}
+
+// BlobStorageConfig is the configuration of a function triggered by the blobs
+// written to a container.
+type BlobStorageConfig struct {
+	FunctionConfig `config:",inline"`
+
+	// Path is the pattern of the blobs to read, starting with the name of their
+	// container, e.g. "logs/{name}".
+	Path string `config:"path" validate:"required"`
+
+	// ConnectionString of the storage account of the container. The storage
+	// account of the function app is used if it's not set.
+	ConnectionString string `config:"connection_string"`
+}
+
+func defaultBlobStorageConfig() *BlobStorageConfig {
+	return &BlobStorageConfig{
+		FunctionConfig: defaultFunctionConfig(),
+	}
+}
+
+// Binding returns the trigger binding of the function, as configured in its
+// function.json.
+func (c *EventHubConfig) Binding() common.MapStr {
+	return common.MapStr{
+		"type":          "eventHubTrigger",
+		"name":          eventsBinding,
+		"direction":     "in",
+		"eventHubName":  c.EventHub,
+		"consumerGroup": c.ConsumerGroup,
+		"connection":    eventHubConnectionSetting,
+		"cardinality":   "many",
+		"dataType":      "string",
+	}
+}
+
+// AppSettings returns the settings the function app needs to run the function.
+func (c *EventHubConfig) AppSettings() map[string]string {
+	return map[string]string{eventHubConnectionSetting: c.ConnectionString}
+}
+
+// Binding returns the trigger binding of the function, as configured in its
+// function.json.
+func (c *BlobStorageConfig) Binding() common.MapStr {
+	connection := webJobsStorageSetting
+	if c.ConnectionString != "" {
+		connection = blobConnectionSetting
+	}
+	return common.MapStr{
+		"type":       "blobTrigger",
+		"name":       blobBinding,
+		"direction":  "in",
+		"path":       c.Path,
+		"connection": connection,
+		"dataType":   "string",
+	}
+}
+
+// AppSettings returns the settings the function app needs to run the function.
+func (c *BlobStorageConfig) AppSettings() map[string]string {
+	if c.ConnectionString == "" {
+		return nil
+	}
+	return map[string]string{blobConnectionSetting: c.ConnectionString}
+}
@@ -0,0 +1,80 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestEventHubConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		cfg := common.MustNewConfigFrom(map[string]interface{}{
+			"name":              "hub",
+			"event_hub":         "my-hub",
+			"connection_string": "Endpoint=sb://my-namespace.servicebus.windows.net/",
+		})
+		config := defaultEventHubConfig()
+		require.NoError(t, cfg.Unpack(config))
+
+		assert.Equal(t, 5*time.Minute, config.Timeout)
+		assert.Equal(t, "$Default", config.Binding()["consumerGroup"])
+		assert.Equal(t, map[string]string{
+			eventHubConnectionSetting: "Endpoint=sb://my-namespace.servicebus.windows.net/",
+		}, config.AppSettings())
+	})
+
+	t.Run("missing event hub", func(t *testing.T) {
+		cfg := common.MustNewConfigFrom(map[string]interface{}{
+			"name":              "hub",
+			"connection_string": "Endpoint=sb://my-namespace.servicebus.windows.net/",
+		})
+		assert.Error(t, cfg.Unpack(defaultEventHubConfig()))
+	})
+
+	t.Run("timeout too long", func(t *testing.T) {
+		cfg := common.MustNewConfigFrom(map[string]interface{}{
+			"name":              "hub",
+			"event_hub":         "my-hub",
+			"connection_string": "Endpoint=sb://my-namespace.servicebus.windows.net/",
+			"timeout":           "15m",
+		})
+		assert.Error(t, cfg.Unpack(defaultEventHubConfig()))
+	})
+}
+
+func TestBlobStorageConfig(t *testing.T) {
+	t.Run("uses the storage account of the function app by default", func(t *testing.T) {
+		cfg := common.MustNewConfigFrom(map[string]interface{}{
+			"name": "blob",
+			"path": "logs/{name}",
+		})
+		config := defaultBlobStorageConfig()
+		require.NoError(t, cfg.Unpack(config))
+
+		assert.Equal(t, webJobsStorageSetting, config.Binding()["connection"])
+		assert.Empty(t, config.AppSettings())
+	})
+
+	t.Run("with a connection string", func(t *testing.T) {
+		cfg := common.MustNewConfigFrom(map[string]interface{}{
+			"name":              "blob",
+			"path":              "logs/{name}",
+			"connection_string": "DefaultEndpointsProtocol=https;AccountName=logs",
+		})
+		config := defaultBlobStorageConfig()
+		require.NoError(t, cfg.Unpack(config))
+
+		assert.Equal(t, blobConnectionSetting, config.Binding()["connection"])
+		assert.Equal(t, map[string]string{
+			blobConnectionSetting: "DefaultEndpointsProtocol=https;AccountName=logs",
+		}, config.AppSettings())
+	})
+}
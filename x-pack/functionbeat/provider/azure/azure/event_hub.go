@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"context"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/v7/libbeat/feature"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/function/core"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/function/provider"
+	"github.com/elastic/beats/v7/x-pack/functionbeat/function/telemetry"
+)
+
+// EventHub receives the events of an Event Hub and forwards them to the output.
+type EventHub struct {
+	log    *logp.Logger
+	config *EventHubConfig
+}
+
+// NewEventHub creates a new function to receive the events of an Event Hub.
+func NewEventHub(provider provider.Provider, cfg *common.Config) (provider.Function, error) {
+	cfgwarn.Beta("Azure Functions support is in beta")
+
+	config := defaultEventHubConfig()
+	if err := cfg.Unpack(config); err != nil {
+		return nil, err
+	}
+	return &EventHub{log: logp.NewLogger("event_hub"), config: config}, nil
+}
+
+// EventHubDetails returns the details of the feature.
+func EventHubDetails() feature.Details {
+	return feature.MakeDetails("Event Hub trigger", "receive events from an Azure Event Hub", feature.Beta)
+}
+
+// Run starts the custom handler of the function and waits for invocations.
+func (e *EventHub) Run(ctx context.Context, client core.Client, t telemetry.T) error {
+	t.AddTriggeredFunction()
+
+	return serve(ctx, e.log, e.config.Name, func(inv *invocation) error {
+		events, err := transformEventHub(inv)
+		if err != nil {
+			return err
+		}
+		e.log.Debugf("The handler receives %d events", len(events))
+
+		if err := client.PublishAll(events); err != nil {
+			e.log.Errorf("Could not publish events to the pipeline, error: %+v", err)
+			return err
+		}
+		client.Wait()
+		return nil
+	})
+}
+
+// Name returns the name of the function.
+func (e *EventHub) Name() string {
+	return "event_hub"
+}
+
+// Config returns the configuration common to the Azure functions.
+func (e *EventHub) Config() *FunctionConfig {
+	return &e.config.FunctionConfig
+}
+
+// Binding returns the trigger binding of the function.
+func (e *EventHub) Binding() common.MapStr {
+	return e.config.Binding()
+}
+
+// AppSettings returns the settings required by the trigger.
+func (e *EventHub) AppSettings() map[string]string {
+	return e.config.AppSettings()
+}
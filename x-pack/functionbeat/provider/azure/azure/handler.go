@@ -0,0 +1,106 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+const (
+	// portEnv is the environment variable holding the port the Functions host
+	// forwards the invocations to.
+	// Ref: https://docs.microsoft.com/en-us/azure/azure-functions/functions-custom-handlers
+	portEnv     = "FUNCTIONS_CUSTOMHANDLER_PORT"
+	defaultPort = "8080"
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// invocation is the request sent by the Functions host for every execution of
+// a function. Data holds the values of the bindings, Metadata holds the
+// properties of the trigger.
+type invocation struct {
+	Data     map[string]json.RawMessage `json:"Data"`
+	Metadata json.RawMessage            `json:"Metadata"`
+}
+
+// invocationResult is the response expected by the Functions host.
+type invocationResult struct {
+	Outputs     map[string]interface{} `json:"Outputs"`
+	Logs        []string               `json:"Logs"`
+	ReturnValue interface{}            `json:"ReturnValue"`
+}
+
+// invocationHandler processes one invocation. An error fails the execution of
+// the function, so the host retries it if the trigger supports it.
+type invocationHandler func(*invocation) error
+
+// serve runs the custom handler of the function until ctx is cancelled. The
+// Functions host starts the handler and forwards it the invocations of the
+// function through HTTP.
+func serve(ctx context.Context, log *logp.Logger, name string, handler invocationHandler) error {
+	port := os.Getenv(portEnv)
+	if port == "" {
+		port = defaultPort
+	}
+
+	l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", port))
+	if err != nil {
+		return fmt.Errorf("error listening for invocations: %+v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/"+name, newInvocationHandler(log, handler))
+	server := &http.Server{Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Infof("Waiting for invocations of function '%s' on %s", name, l.Addr())
+	if err := server.Serve(l); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+func newInvocationHandler(log *logp.Logger, handler invocationHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var inv invocation
+		if err := json.NewDecoder(r.Body).Decode(&inv); err != nil {
+			log.Errorf("Could not decode the invocation, error: %+v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result := invocationResult{Outputs: map[string]interface{}{}, Logs: []string{}}
+		status := http.StatusOK
+		if err := handler(&inv); err != nil {
+			log.Errorf("Invocation failed, error: %+v", err)
+			result.Logs = append(result.Logs, err.Error())
+			status = http.StatusInternalServerError
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(result)
+	})
+}
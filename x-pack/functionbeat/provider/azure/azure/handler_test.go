@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+)
+
+func TestInvocationHandler(t *testing.T) {
+	request := func(handler invocationHandler, method, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(method, "/my-function", strings.NewReader(body))
+		newInvocationHandler(logp.NewLogger("test"), handler).ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("passes the invocation to the handler", func(t *testing.T) {
+		var received *invocation
+		w := request(func(inv *invocation) error {
+			received = inv
+			return nil
+		}, http.MethodPost, `{"Data": {"events": "[]"}, "Metadata": {"sys": {}}}`)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.NotNil(t, received)
+		assert.Equal(t, json.RawMessage(`"[]"`), received.Data[eventsBinding])
+
+		var result invocationResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Empty(t, result.Logs)
+	})
+
+	t.Run("fails the invocation when the handler fails", func(t *testing.T) {
+		w := request(func(*invocation) error {
+			return errors.New("oops")
+		}, http.MethodPost, `{"Data": {}}`)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+		var result invocationResult
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, []string{"oops"}, result.Logs)
+	})
+
+	t.Run("rejects invalid invocations", func(t *testing.T) {
+		called := false
+		handler := func(*invocation) error {
+			called = true
+			return nil
+		}
+
+		assert.Equal(t, http.StatusBadRequest, request(handler, http.MethodPost, `{`).Code)
+		assert.Equal(t, http.StatusMethodNotAllowed, request(handler, http.MethodGet, "").Code)
+		assert.False(t, called)
+	})
+}
@@ -0,0 +1,180 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+// Names of the trigger bindings of the functions.
+const (
+	eventsBinding = "events"
+	blobBinding   = "blob"
+)
+
+// eventHubMetadata are the properties of a batch of Event Hub events.
+type eventHubMetadata struct {
+	PartitionContext struct {
+		ConsumerGroup      string `json:"ConsumerGroup"`
+		EventHubPath       string `json:"EventHubPath"`
+		RuntimeInformation struct {
+			PartitionID string `json:"PartitionId"`
+		} `json:"RuntimeInformation"`
+	} `json:"PartitionContext"`
+	EnqueuedTimeUtcArray []time.Time   `json:"EnqueuedTimeUtcArray"`
+	SequenceNumberArray  []int64       `json:"SequenceNumberArray"`
+	OffsetArray          []json.Number `json:"OffsetArray"`
+}
+
+// blobMetadata are the properties of a blob.
+type blobMetadata struct {
+	BlobTrigger string `json:"BlobTrigger"`
+	URI         string `json:"Uri"`
+}
+
+// transformEventHub takes a batch of events of an Event Hub and transforms
+// them into events, one per message.
+func transformEventHub(inv *invocation) ([]beat.Event, error) {
+	var messages []json.RawMessage
+	if err := decodeBinding(inv.Data[eventsBinding], &messages); err != nil {
+		return nil, fmt.Errorf("invalid events: %+v", err)
+	}
+
+	var meta eventHubMetadata
+	if len(inv.Metadata) > 0 {
+		if err := json.Unmarshal(inv.Metadata, &meta); err != nil {
+			return nil, fmt.Errorf("invalid metadata: %+v", err)
+		}
+	}
+
+	now := time.Now()
+	events := make([]beat.Event, len(messages))
+	for idx, msg := range messages {
+		eventHub := common.MapStr{
+			"name":           meta.PartitionContext.EventHubPath,
+			"consumer_group": meta.PartitionContext.ConsumerGroup,
+			"partition_id":   meta.PartitionContext.RuntimeInformation.PartitionID,
+		}
+
+		ts := now
+		if idx < len(meta.EnqueuedTimeUtcArray) {
+			ts = meta.EnqueuedTimeUtcArray[idx]
+		}
+		if idx < len(meta.SequenceNumberArray) {
+			eventHub["sequence_number"] = meta.SequenceNumberArray[idx]
+		}
+		if idx < len(meta.OffsetArray) {
+			eventHub["offset"] = meta.OffsetArray[idx].String()
+		}
+
+		events[idx] = beat.Event{
+			Timestamp: ts,
+			Fields: common.MapStr{
+				"event": common.MapStr{
+					"kind": "event",
+				},
+				"cloud": common.MapStr{
+					"provider": "azure",
+				},
+				"read_timestamp": now,
+				"message":        messageString(msg),
+				"azure": common.MapStr{
+					"event_hub": eventHub,
+				},
+			},
+		}
+	}
+	return events, nil
+}
+
+// transformBlob takes the content of a blob and transforms it into events,
+// one per line.
+func transformBlob(inv *invocation) ([]beat.Event, error) {
+	var content string
+	if err := json.Unmarshal(inv.Data[blobBinding], &content); err != nil {
+		return nil, fmt.Errorf("invalid blob: %+v", err)
+	}
+
+	var meta blobMetadata
+	if len(inv.Metadata) > 0 {
+		if err := json.Unmarshal(inv.Metadata, &meta); err != nil {
+			return nil, fmt.Errorf("invalid metadata: %+v", err)
+		}
+	}
+
+	// BlobTrigger is the path of the blob, starting with its container.
+	container, name := "", meta.BlobTrigger
+	if idx := strings.IndexByte(meta.BlobTrigger, '/'); idx >= 0 {
+		container, name = meta.BlobTrigger[:idx], meta.BlobTrigger[idx+1:]
+	}
+	blob := common.MapStr{
+		"container": container,
+		"name":      name,
+		"uri":       meta.URI,
+	}
+
+	now := time.Now()
+	var events []beat.Event
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		lineOffset := offset
+		offset += len(line)
+		line = strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		events = append(events, beat.Event{
+			Timestamp: now,
+			Fields: common.MapStr{
+				"event": common.MapStr{
+					"kind": "event",
+				},
+				"cloud": common.MapStr{
+					"provider": "azure",
+				},
+				"read_timestamp": now,
+				"message":        line,
+				"log": common.MapStr{
+					"offset": lineOffset,
+				},
+				"azure": common.MapStr{
+					"blob": blob.Clone(),
+				},
+			},
+		})
+	}
+	return events, nil
+}
+
+// decodeBinding decodes the value of a binding. The host sends some values
+// encoded as a JSON string, they are decoded twice.
+func decodeBinding(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 {
+		return fmt.Errorf("missing value")
+	}
+
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err == nil {
+		raw = json.RawMessage(encoded)
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// messageString returns the message of an event. Messages that aren't strings,
+// like JSON objects, are returned encoded.
+func messageString(msg json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(msg, &s); err == nil {
+		return s
+	}
+	return string(msg)
+}
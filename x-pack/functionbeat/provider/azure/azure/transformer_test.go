@@ -0,0 +1,83 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azure
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestTransformEventHub(t *testing.T) {
+	inv := &invocation{
+		Data: map[string]json.RawMessage{
+			// The host sends the batch encoded as a JSON string.
+			eventsBinding: json.RawMessage(`"[\"my interesting message\",{\"key\":\"value\"}]"`),
+		},
+		Metadata: json.RawMessage(`{
+			"PartitionContext": {
+				"ConsumerGroup": "$Default",
+				"EventHubPath": "my-hub",
+				"RuntimeInformation": {"PartitionId": "1"}
+			},
+			"EnqueuedTimeUtcArray": ["2020-10-14T12:24:51.193Z", "2020-10-14T12:24:52Z"],
+			"SequenceNumberArray": [41, 42],
+			"OffsetArray": ["4294967296", "4294967424"]
+		}`),
+	}
+
+	events, err := transformEventHub(inv)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(events))
+
+	expectedTime := time.Date(2020, 10, 14, 12, 24, 51, 193000000, time.UTC)
+	assert.True(t, expectedTime.Equal(events[0].Timestamp))
+	assert.Equal(t, "my interesting message", events[0].Fields["message"])
+	assert.Equal(t, `{"key":"value"}`, events[1].Fields["message"])
+	assert.Equal(t, common.MapStr{"provider": "azure"}, events[0].Fields["cloud"])
+	assert.Equal(t, common.MapStr{
+		"name":            "my-hub",
+		"consumer_group":  "$Default",
+		"partition_id":    "1",
+		"sequence_number": int64(42),
+		"offset":          "4294967424",
+	}, events[1].Fields["azure"].(common.MapStr)["event_hub"])
+
+	t.Run("invalid events", func(t *testing.T) {
+		_, err := transformEventHub(&invocation{Data: map[string]json.RawMessage{}})
+		assert.Error(t, err)
+	})
+}
+
+func TestTransformBlob(t *testing.T) {
+	inv := &invocation{
+		Data: map[string]json.RawMessage{
+			blobBinding: json.RawMessage(`"first line\r\n\nsecond line\n"`),
+		},
+		Metadata: json.RawMessage(`{
+			"BlobTrigger": "logs/2020/10/app.log",
+			"Uri": "https://account.blob.core.windows.net/logs/2020/10/app.log"
+		}`),
+	}
+
+	events, err := transformBlob(inv)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(events))
+
+	assert.Equal(t, "first line", events[0].Fields["message"])
+	assert.Equal(t, common.MapStr{"offset": 0}, events[0].Fields["log"])
+	assert.Equal(t, "second line", events[1].Fields["message"])
+	assert.Equal(t, common.MapStr{"offset": 13}, events[1].Fields["log"])
+	assert.Equal(t, common.MapStr{
+		"container": "logs",
+		"name":      "2020/10/app.log",
+		"uri":       "https://account.blob.core.windows.net/logs/2020/10/app.log",
+	}, events[1].Fields["azure"].(common.MapStr)["blob"])
+}
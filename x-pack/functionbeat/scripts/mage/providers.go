@@ -13,6 +13,7 @@ import (
 var (
 	availableProviders = []ProviderDetails{
 		{Name: "aws", Buildable: true, GOOS: "linux", GOARCH: "amd64"},
+		{Name: "azure", Buildable: true, GOOS: "linux", GOARCH: "amd64"},
 		{Name: "gcp", Buildable: false},
 	}
 )
@@ -13,6 +13,12 @@ import (
 
 	// Register fleet
 	_ "github.com/elastic/beats/v7/x-pack/libbeat/management/fleet"
+	// register outputs
+	_ "github.com/elastic/beats/v7/x-pack/libbeat/outputs/azureeventhubs"
+	_ "github.com/elastic/beats/v7/x-pack/libbeat/outputs/gcppubsub"
+	_ "github.com/elastic/beats/v7/x-pack/libbeat/outputs/kinesis"
+	_ "github.com/elastic/beats/v7/x-pack/libbeat/outputs/s3"
+
 	// register processors
 	_ "github.com/elastic/beats/v7/x-pack/libbeat/processors/add_cloudfoundry_metadata"
 
@@ -0,0 +1,236 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azureeventhubs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/privacy"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// batchSender sends batches of events to an event hub.
+type batchSender interface {
+	send(ctx context.Context, batch *eventhub.EventBatch) error
+	Close(ctx context.Context) error
+}
+
+type client struct {
+	log          *logp.Logger
+	observer     outputs.Observer
+	name         string
+	index        string
+	codec        codec.Codec
+	partitionKey *fmtstr.EventFormatString
+	timeout      time.Duration
+
+	connect func(context.Context) (batchSender, error)
+	sender  batchSender
+}
+
+// pendingBatch is a batch of the event hub with the events it contains.
+type pendingBatch struct {
+	batch  *eventhub.EventBatch
+	events []publisher.Event
+}
+
+func newClient(
+	observer outputs.Observer,
+	name string,
+	index string,
+	writer codec.Codec,
+	config *config,
+	connect func(context.Context) (batchSender, error),
+) *client {
+	return &client{
+		log:          logp.NewLogger(logSelector),
+		observer:     observer,
+		name:         name,
+		index:        strings.ToLower(index),
+		codec:        writer,
+		partitionKey: config.PartitionKey,
+		timeout:      config.Timeout,
+		connect:      connect,
+	}
+}
+
+func (c *client) Connect() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	sender, err := c.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create the event hub client: %v", err)
+	}
+	c.sender = sender
+	return nil
+}
+
+func (c *client) Close() error {
+	if c.sender == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	err := c.sender.Close(ctx)
+	c.sender = nil
+	return err
+}
+
+func (c *client) String() string {
+	return "azure_eventhubs(" + c.name + ")"
+}
+
+func (c *client) Publish(ctx context.Context, batch publisher.Batch) error {
+	events := batch.Events()
+	c.observer.NewBatch(len(events))
+
+	batches, dropped := c.makeBatches(batch, events)
+	if dropped > 0 {
+		c.observer.Dropped(dropped)
+	}
+
+	sent, retry, err := c.sendBatches(ctx, batches)
+	c.observer.Acked(sent)
+	if len(retry) > 0 {
+		c.observer.Failed(len(retry))
+		batch.RetryEvents(retry)
+		return err
+	}
+
+	batch.ACK()
+	return nil
+}
+
+// makeBatches encodes the events and adds them to batches of the event hub,
+// one or more per partition key. Events that can not be encoded or that are
+// larger than a batch can be are dropped. It returns the batches and the
+// number of dropped events.
+func (c *client) makeBatches(batch publisher.Batch, events []publisher.Event) ([]*pendingBatch, int) {
+	var (
+		batches []*pendingBatch
+		dropped int
+	)
+	// open has the last batch of every partition key, the events of a
+	// partition key are added in order.
+	open := map[string]*pendingBatch{}
+
+	drop := func(event publisher.Event, reason string) {
+		c.log.Errorf("Dropping event: %v", reason)
+		publisher.DeadLetter(batch, event, reason)
+		dropped++
+	}
+
+	for i := range events {
+		event := &events[i]
+		serializedEvent, err := c.codec.Encode(c.index, &event.Content)
+		if err != nil {
+			if c.log.IsDebug() {
+				c.log.Debugf("failed event: %v", privacy.LazyEvent(&event.Content))
+			}
+			drop(*event, fmt.Sprintf("failed to encode the event: %v", err))
+			continue
+		}
+
+		data := make([]byte, len(serializedEvent))
+		copy(data, serializedEvent)
+		key := c.eventPartitionKey(event)
+
+		pending := open[key]
+		if pending == nil {
+			pending = newPendingBatch(key)
+			open[key] = pending
+			batches = append(batches, pending)
+		}
+		ok, err := pending.batch.Add(eventhub.NewEvent(data))
+		if err == nil && !ok && len(pending.events) > 0 {
+			// The batch is full, continue with a new one.
+			pending = newPendingBatch(key)
+			open[key] = pending
+			batches = append(batches, pending)
+			ok, err = pending.batch.Add(eventhub.NewEvent(data))
+		}
+		if err != nil {
+			drop(*event, fmt.Sprintf("failed to add the event to a batch: %v", err))
+			continue
+		}
+		if !ok {
+			drop(*event, fmt.Sprintf("event of size %v exceeds the batch size limit of %v", len(data), eventhub.DefaultMaxMessageSizeInBytes))
+			continue
+		}
+		pending.events = append(pending.events, *event)
+	}
+
+	// Batches of events that were all dropped are not sent.
+	n := 0
+	for _, pending := range batches {
+		if len(pending.events) > 0 {
+			batches[n] = pending
+			n++
+		}
+	}
+	return batches[:n], dropped
+}
+
+func newPendingBatch(partitionKey string) *pendingBatch {
+	b := eventhub.NewEventBatch("", nil)
+	if partitionKey != "" {
+		key := partitionKey
+		b.PartitionKey = &key
+	}
+	return &pendingBatch{batch: b}
+}
+
+// eventPartitionKey returns the partition key of the event, or an empty
+// string if none is configured or it can not be formatted for the event. Event
+// Hubs distributes the events without partition key across the partitions.
+func (c *client) eventPartitionKey(event *publisher.Event) string {
+	if c.partitionKey == nil {
+		return ""
+	}
+	key, err := c.partitionKey.Run(&event.Content)
+	if err != nil {
+		if c.log.IsDebug() {
+			c.log.Debugf("sending event without partition key, the partition key can not be formatted: %v", err)
+		}
+		return ""
+	}
+	return key
+}
+
+// sendBatches sends the batches in order. It returns the number of sent
+// events, and the events that need to be retried.
+func (c *client) sendBatches(ctx context.Context, batches []*pendingBatch) (int, []publisher.Event, error) {
+	sent := 0
+	for i, pending := range batches {
+		sendCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := c.sender.send(sendCtx, pending.batch)
+		cancel()
+		if err != nil {
+			// Do not send the remaining batches, the next attempts are likely
+			// to fail the same way.
+			c.observer.WriteError(err)
+			var retry []publisher.Event
+			for _, failed := range batches[i:] {
+				retry = append(retry, failed.events...)
+			}
+			return sent, retry, fmt.Errorf("failed to send to event hub %v: %v", c.name, err)
+		}
+
+		c.observer.WriteBytes(pending.batch.Size())
+		sent += len(pending.events)
+	}
+	return sent, nil, nil
+}
@@ -0,0 +1,180 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azureeventhubs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+)
+
+// fakeSender records the sent batches and fails the sends selected by fail.
+type fakeSender struct {
+	batches []*eventhub.EventBatch
+	fail    func(n int) error
+	closed  bool
+}
+
+func (s *fakeSender) send(_ context.Context, batch *eventhub.EventBatch) error {
+	s.batches = append(s.batches, batch)
+	if s.fail != nil {
+		return s.fail(len(s.batches) - 1)
+	}
+	return nil
+}
+
+func (s *fakeSender) Close(context.Context) error {
+	s.closed = true
+	return nil
+}
+
+func newTestClient(t *testing.T, s *fakeSender, settings common.MapStr) *client {
+	cfg := common.MustNewConfigFrom(common.MapStr{
+		"connection_string": "Endpoint=sb://test.servicebus.windows.net/;SharedAccessKeyName=key;SharedAccessKey=secret",
+		"eventhub":          "beats",
+	})
+	require.NoError(t, cfg.Merge(settings))
+	config := defaultConfig()
+	require.NoError(t, cfg.Unpack(&config))
+
+	c := newClient(outputs.NewNilObserver(), config.EventHubName, "testbeat", json.New("1.2.3", json.Config{}), &config,
+		func(context.Context) (batchSender, error) { return s, nil })
+	require.NoError(t, c.Connect())
+	return c
+}
+
+func hostEvents(n int, host, message string) []beat.Event {
+	events := make([]beat.Event, n)
+	for i := range events {
+		events[i] = beat.Event{Fields: common.MapStr{
+			"host":    common.MapStr{"name": host},
+			"message": message,
+		}}
+	}
+	return events
+}
+
+func partitionKey(b *eventhub.EventBatch) string {
+	if b.PartitionKey == nil {
+		return ""
+	}
+	return *b.PartitionKey
+}
+
+func TestPublishGroupsByPartitionKey(t *testing.T) {
+	s := &fakeSender{}
+	c := newTestClient(t, s, common.MapStr{"partition_key": "%{[host.name]}"})
+
+	events := append(hostEvents(2, "a", ""), hostEvents(3, "b", "")...)
+	events = append(events, beat.Event{Fields: common.MapStr{"message": "no host"}})
+	batch := outest.NewBatch(events...)
+
+	require.NoError(t, c.Publish(context.Background(), batch))
+
+	var keys []string
+	for _, b := range s.batches {
+		keys = append(keys, partitionKey(b))
+	}
+	assert.Equal(t, []string{"a", "b", ""}, keys)
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+
+	require.NoError(t, c.Close())
+	assert.True(t, s.closed)
+}
+
+func TestPublishSplitsLargeBatches(t *testing.T) {
+	s := &fakeSender{}
+	c := newTestClient(t, s, nil)
+
+	// Two events fit into a batch of 1MB.
+	message := strings.Repeat("a", 400*1000)
+	batch := outest.NewBatch(hostEvents(5, "a", message)...)
+
+	require.NoError(t, c.Publish(context.Background(), batch))
+	assert.Len(t, s.batches, 3)
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+}
+
+func TestPublishDropsTooLargeEvents(t *testing.T) {
+	s := &fakeSender{}
+	c := newTestClient(t, s, nil)
+
+	events := append(hostEvents(1, "a", strings.Repeat("a", 2*1000*1000)), hostEvents(2, "a", "")...)
+	batch := outest.NewBatch(events...)
+
+	require.NoError(t, c.Publish(context.Background(), batch))
+	require.Len(t, s.batches, 1)
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+}
+
+func TestPublishRetriesAfterError(t *testing.T) {
+	s := &fakeSender{fail: func(n int) error {
+		if n == 1 {
+			return errors.New("link detached")
+		}
+		return nil
+	}}
+	c := newTestClient(t, s, common.MapStr{"partition_key": "%{[host.name]}"})
+
+	events := append(hostEvents(2, "a", ""), hostEvents(3, "b", "")...)
+	events = append(events, hostEvents(4, "c", "")...)
+	batch := outest.NewBatch(events...)
+
+	assert.Error(t, c.Publish(context.Background(), batch))
+
+	// The batch after the failed one is not sent.
+	assert.Len(t, s.batches, 2)
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	assert.Len(t, batch.Signals[0].Events, 7)
+}
+
+func TestConfigValidate(t *testing.T) {
+	cases := map[string]struct {
+		cfg   common.MapStr
+		valid bool
+	}{
+		"connection string": {
+			cfg:   common.MapStr{"connection_string": "Endpoint=sb://test.servicebus.windows.net/", "eventhub": "beats"},
+			valid: true,
+		},
+		"managed identity": {
+			cfg:   common.MapStr{"namespace": "test", "eventhub": "beats", "managed_identity_client_id": "id"},
+			valid: true,
+		},
+		"no event hub": {
+			cfg: common.MapStr{"namespace": "test"},
+		},
+		"no authentication": {
+			cfg: common.MapStr{"eventhub": "beats"},
+		},
+		"connection string and namespace": {
+			cfg: common.MapStr{"connection_string": "Endpoint=sb://test.servicebus.windows.net/", "namespace": "test", "eventhub": "beats"},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := defaultConfig()
+			err := common.MustNewConfigFrom(test.cfg).Unpack(&config)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azureeventhubs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+)
+
+type config struct {
+	// Shared access connection string of the namespace or of the event hub.
+	ConnectionString string `config:"connection_string"`
+
+	// Name of the namespace, used to authenticate with a managed identity
+	// when no connection string is configured.
+	Namespace string `config:"namespace"`
+
+	// Client ID of the user assigned managed identity. The system assigned
+	// identity is used if not set.
+	ManagedIdentityClientID string `config:"managed_identity_client_id"`
+
+	EventHubName string `config:"eventhub" validate:"required"`
+
+	// Partition key of the events, formatted for each event.
+	PartitionKey *fmtstr.EventFormatString `config:"partition_key"`
+
+	Timeout     time.Duration         `config:"timeout"       validate:"min=1"`
+	BulkMaxSize int                   `config:"bulk_max_size" validate:"min=1"`
+	MaxRetries  int                   `config:"max_retries"   validate:"min=-1"`
+	Backoff     outputs.BackoffConfig `config:"backoff"`
+	Codec       codec.Config          `config:"codec"`
+}
+
+func defaultConfig() config {
+	return config{
+		Timeout:     60 * time.Second,
+		BulkMaxSize: 1000,
+		MaxRetries:  3,
+		Backoff:     outputs.DefaultBackoffConfig(),
+	}
+}
+
+func (c *config) Validate() error {
+	if c.ConnectionString == "" && c.Namespace == "" {
+		return errors.New("either connection_string or namespace must be configured")
+	}
+	if c.ConnectionString != "" && c.Namespace != "" {
+		return errors.New("connection_string and namespace can not be configured together")
+	}
+	if c.ManagedIdentityClientID != "" && c.Namespace == "" {
+		return errors.New("managed_identity_client_id requires namespace to be configured")
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package azureeventhubs
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Azure/azure-amqp-common-go/v3/aad"
+	eventhub "github.com/Azure/azure-event-hubs-go/v3"
+	"github.com/Azure/go-autorest/autorest/adal"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/v7/libbeat/common/useragent"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+)
+
+const (
+	logSelector = "azure_eventhubs"
+
+	eventHubConnector = ";EntityPath="
+
+	// eventHubsResource is the resource of the tokens of managed identities.
+	eventHubsResource = "https://eventhubs.azure.net/"
+)
+
+func init() {
+	outputs.RegisterType("azure_eventhubs", makeEventHubs)
+}
+
+func makeEventHubs(
+	_ outputs.IndexManager,
+	beat beat.Info,
+	observer outputs.Observer,
+	cfg *common.Config,
+) (outputs.Group, error) {
+	cfgwarn.Beta("The azure_eventhubs output is beta.")
+
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return outputs.Fail(err)
+	}
+
+	enc, err := codec.CreateEncoder(beat, config.Codec)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	userAgent := useragent.UserAgent(beat.Beat)
+	connect := func(context.Context) (batchSender, error) {
+		hub, err := newHub(&config, userAgent)
+		if err != nil {
+			return nil, err
+		}
+		return &hubSender{hub: hub}, nil
+	}
+
+	client := newClient(observer, config.EventHubName, beat.IndexPrefix, enc, &config, connect)
+	clients := []outputs.NetworkClient{outputs.WithBackoff(client, config.Backoff.Init, config.Backoff.Max)}
+
+	grp, err := outputs.SuccessNet(false, config.BulkMaxSize, config.MaxRetries, clients)
+	grp.Backoff = &config.Backoff
+	return grp, err
+}
+
+// newHub creates the client of the event hub, authenticated with the
+// connection string or with the managed identity of the host.
+func newHub(config *config, userAgent string) (*eventhub.Hub, error) {
+	opts := []eventhub.HubOption{eventhub.HubWithUserAgent(userAgent)}
+	if config.ConnectionString != "" {
+		connStr := config.ConnectionString
+		if !strings.Contains(connStr, eventHubConnector) {
+			connStr += eventHubConnector + config.EventHubName
+		}
+		return eventhub.NewHubFromConnectionString(connStr, opts...)
+	}
+
+	msiEndpoint, err := adal.GetMSIVMEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	var token *adal.ServicePrincipalToken
+	if config.ManagedIdentityClientID != "" {
+		token, err = adal.NewServicePrincipalTokenFromMSIWithUserAssignedID(msiEndpoint, eventHubsResource, config.ManagedIdentityClientID)
+	} else {
+		token, err = adal.NewServicePrincipalTokenFromMSI(msiEndpoint, eventHubsResource)
+	}
+	if err != nil {
+		return nil, err
+	}
+	provider, err := aad.NewJWTProvider(aad.JWTProviderWithAADToken(token))
+	if err != nil {
+		return nil, err
+	}
+	return eventhub.NewHub(config.Namespace, config.EventHubName, provider, opts...)
+}
+
+// hubSender sends batches to the event hub.
+type hubSender struct {
+	hub *eventhub.Hub
+}
+
+func (s *hubSender) send(ctx context.Context, batch *eventhub.EventBatch) error {
+	return s.hub.SendBatch(ctx, &singleBatchIterator{batch: batch})
+}
+
+func (s *hubSender) Close(ctx context.Context) error {
+	return s.hub.Close(ctx)
+}
+
+// singleBatchIterator passes a batch to SendBatch, so that the client knows
+// which events were sent if sending fails.
+type singleBatchIterator struct {
+	batch *eventhub.EventBatch
+	done  bool
+}
+
+func (it *singleBatchIterator) Done() bool {
+	return it.done
+}
+
+func (it *singleBatchIterator) Next(messageID string, _ *eventhub.BatchOptions) (*eventhub.EventBatch, error) {
+	it.done = true
+	it.batch.ID = messageID
+	return it.batch, nil
+}
@@ -0,0 +1,252 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gcppubsub
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/proto"
+	pubsubpb "google.golang.org/genproto/googleapis/pubsub/v1"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/privacy"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// Limits of the Pub/Sub API.
+const (
+	maxRequestMessages     = 1000
+	maxRequestBytes        = 10 * 1000 * 1000
+	maxAttributes          = 100
+	maxAttributeKeyBytes   = 256
+	maxAttributeValueBytes = 1024
+	maxOrderingKeyBytes    = 1024
+
+	// messageOverhead is the maximum size of the encoding of a message in a
+	// request besides the message itself.
+	messageOverhead = 6
+)
+
+// messagePublisher publishes messages to a topic.
+type messagePublisher interface {
+	publish(ctx context.Context, topic string, messages []*pubsubpb.PubsubMessage) error
+	Close() error
+}
+
+type client struct {
+	log         *logp.Logger
+	observer    outputs.Observer
+	topic       string
+	index       string
+	codec       codec.Codec
+	orderingKey *fmtstr.EventFormatString
+	attributes  []attributeConfig
+	timeout     time.Duration
+
+	connect   func(context.Context) (messagePublisher, error)
+	publisher messagePublisher
+}
+
+// message is a Pub/Sub message of an event.
+type message struct {
+	event publisher.Event
+	msg   *pubsubpb.PubsubMessage
+	size  int
+}
+
+func newClient(
+	observer outputs.Observer,
+	topic string,
+	index string,
+	writer codec.Codec,
+	config *config,
+	connect func(context.Context) (messagePublisher, error),
+) *client {
+	return &client{
+		log:         logp.NewLogger(logSelector),
+		observer:    observer,
+		topic:       topic,
+		index:       strings.ToLower(index),
+		codec:       writer,
+		orderingKey: config.OrderingKey,
+		attributes:  config.Attributes,
+		timeout:     config.Timeout,
+		connect:     connect,
+	}
+}
+
+func (c *client) Connect() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	p, err := c.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create the Pub/Sub client: %v", err)
+	}
+	c.publisher = p
+	return nil
+}
+
+func (c *client) Close() error {
+	if c.publisher == nil {
+		return nil
+	}
+	err := c.publisher.Close()
+	c.publisher = nil
+	return err
+}
+
+func (c *client) String() string {
+	return "gcp_pubsub(" + c.topic + ")"
+}
+
+func (c *client) Publish(ctx context.Context, batch publisher.Batch) error {
+	events := batch.Events()
+	c.observer.NewBatch(len(events))
+
+	messages := c.makeMessages(batch, events)
+	if dropped := len(events) - len(messages); dropped > 0 {
+		c.observer.Dropped(dropped)
+	}
+
+	retry, err := c.publishMessages(ctx, messages)
+	c.observer.Acked(len(messages) - len(retry))
+	if len(retry) > 0 {
+		c.observer.Failed(len(retry))
+		batch.RetryEvents(retry)
+		return err
+	}
+
+	batch.ACK()
+	return nil
+}
+
+// makeMessages encodes the events. Events that can not be encoded or that are
+// larger than a request can be are dropped.
+func (c *client) makeMessages(batch publisher.Batch, events []publisher.Event) []message {
+	messages := make([]message, 0, len(events))
+	for i := range events {
+		event := &events[i]
+		serializedEvent, err := c.codec.Encode(c.index, &event.Content)
+		if err != nil {
+			c.log.Errorf("Dropping event: %+v", err)
+			if c.log.IsDebug() {
+				c.log.Debugf("failed event: %v", privacy.LazyEvent(&event.Content))
+			}
+			publisher.DeadLetter(batch, *event, fmt.Sprintf("failed to encode the event: %v", err))
+			continue
+		}
+
+		msg := &pubsubpb.PubsubMessage{
+			Data:       make([]byte, len(serializedEvent)),
+			Attributes: c.eventAttributes(&event.Content),
+		}
+		copy(msg.Data, serializedEvent)
+		if c.orderingKey != nil {
+			if key, err := c.orderingKey.Run(&event.Content); err == nil {
+				msg.OrderingKey = truncate(key, maxOrderingKeyBytes)
+			}
+		}
+
+		size := proto.Size(msg) + messageOverhead
+		if size > maxRequestBytes-len(c.topic) {
+			c.log.Errorf("Dropping too large event of size %v.", size)
+			publisher.DeadLetter(batch, *event, fmt.Sprintf("event of size %v exceeds the request size limit of Pub/Sub", size))
+			continue
+		}
+		messages = append(messages, message{event: *event, msg: msg, size: size})
+	}
+	return messages
+}
+
+// eventAttributes formats the attributes of the message of an event. An
+// attribute is not set if it can not be formatted for the event, or if its
+// value is too large.
+func (c *client) eventAttributes(event *beat.Event) map[string]string {
+	if len(c.attributes) == 0 {
+		return nil
+	}
+
+	attributes := make(map[string]string, len(c.attributes))
+	for _, attr := range c.attributes {
+		value, err := attr.Value.Run(event)
+		if err == nil && len(value) > maxAttributeValueBytes {
+			err = fmt.Errorf("value is longer than %v bytes", maxAttributeValueBytes)
+		}
+		if err != nil {
+			if c.log.IsDebug() {
+				c.log.Debugf("skipping attribute %v: %v", attr.Key, err)
+			}
+			continue
+		}
+		attributes[attr.Key] = value
+	}
+	return attributes
+}
+
+// publishMessages publishes the messages with as few requests as the limits
+// of the API allow. It returns the events of the messages that need to be
+// retried.
+func (c *client) publishMessages(ctx context.Context, messages []message) ([]publisher.Event, error) {
+	for len(messages) > 0 {
+		n := splitRequest(len(c.topic), messages)
+		request := make([]*pubsubpb.PubsubMessage, n)
+		size := 0
+		for i := range request {
+			request[i] = messages[i].msg
+			size += messages[i].size
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := c.publisher.publish(reqCtx, c.topic, request)
+		cancel()
+		if err != nil {
+			// Do not publish the remaining messages, so that the messages
+			// with the same ordering key stay in order.
+			c.observer.WriteError(err)
+			retry := make([]publisher.Event, len(messages))
+			for i := range messages {
+				retry[i] = messages[i].event
+			}
+			return retry, fmt.Errorf("failed to publish to %v: %v", c.topic, err)
+		}
+
+		c.observer.WriteBytes(size)
+		messages = messages[n:]
+	}
+	return nil, nil
+}
+
+// splitRequest returns the number of messages at the head of messages that
+// fit into a single request.
+func splitRequest(overhead int, messages []message) int {
+	size := overhead
+	for i := range messages {
+		size += messages[i].size
+		if i == maxRequestMessages || (i > 0 && size > maxRequestBytes) {
+			return i
+		}
+	}
+	return len(messages)
+}
+
+// truncate shortens s to at most n bytes without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,183 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gcppubsub
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pubsubpb "google.golang.org/genproto/googleapis/pubsub/v1"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+)
+
+const testTopic = "projects/test/topics/beats"
+
+// fakePublisher records the published requests and fails the requests
+// selected by fail.
+type fakePublisher struct {
+	requests [][]*pubsubpb.PubsubMessage
+	fail     func(request int) error
+	closed   bool
+}
+
+func (p *fakePublisher) publish(_ context.Context, topic string, messages []*pubsubpb.PubsubMessage) error {
+	if topic != testTopic {
+		return errors.New("unexpected topic " + topic)
+	}
+	p.requests = append(p.requests, messages)
+	if p.fail != nil {
+		return p.fail(len(p.requests) - 1)
+	}
+	return nil
+}
+
+func (p *fakePublisher) Close() error {
+	p.closed = true
+	return nil
+}
+
+func newTestClient(t *testing.T, p *fakePublisher, settings common.MapStr) *client {
+	cfg := common.MustNewConfigFrom(common.MapStr{
+		"project_id":       "test",
+		"topic":            "beats",
+		"credentials_json": "{}",
+	})
+	require.NoError(t, cfg.Merge(settings))
+	config := defaultConfig()
+	require.NoError(t, cfg.Unpack(&config))
+
+	c := newClient(outputs.NewNilObserver(), testTopic, "testbeat", json.New("1.2.3", json.Config{}), &config,
+		func(context.Context) (messagePublisher, error) { return p, nil })
+	require.NoError(t, c.Connect())
+	return c
+}
+
+func makeEvents(n int) []beat.Event {
+	events := make([]beat.Event, n)
+	for i := range events {
+		events[i] = beat.Event{Fields: common.MapStr{"n": i}}
+	}
+	return events
+}
+
+func TestPublishSplitsRequests(t *testing.T) {
+	p := &fakePublisher{}
+	c := newTestClient(t, p, nil)
+	batch := outest.NewBatch(makeEvents(2500)...)
+
+	require.NoError(t, c.Publish(context.Background(), batch))
+
+	var sizes []int
+	for _, request := range p.requests {
+		sizes = append(sizes, len(request))
+	}
+	assert.Equal(t, []int{1000, 1000, 500}, sizes)
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+
+	require.NoError(t, c.Close())
+	assert.True(t, p.closed)
+}
+
+func TestPublishRetriesAfterError(t *testing.T) {
+	p := &fakePublisher{fail: func(request int) error {
+		if request == 1 {
+			return errors.New("unavailable")
+		}
+		return nil
+	}}
+	c := newTestClient(t, p, nil)
+	batch := outest.NewBatch(makeEvents(2500)...)
+
+	assert.Error(t, c.Publish(context.Background(), batch))
+
+	// The messages after the failed request are not published.
+	assert.Len(t, p.requests, 2)
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	require.Len(t, batch.Signals[0].Events, 1500)
+	assert.Equal(t, 1000, batch.Signals[0].Events[0].Content.Fields["n"])
+}
+
+func TestMessageAttributes(t *testing.T) {
+	p := &fakePublisher{}
+	c := newTestClient(t, p, common.MapStr{
+		"ordering_key": "%{[host.name]}",
+		"attributes": []common.MapStr{
+			{"key": "agent_id", "value": "%{[agent.id]}"},
+			{"key": "environment", "value": "production"},
+		},
+	})
+	batch := outest.NewBatch(
+		beat.Event{Fields: common.MapStr{
+			"host":  common.MapStr{"name": "web-1"},
+			"agent": common.MapStr{"id": "abc"},
+		}},
+		beat.Event{Fields: common.MapStr{"message": "no fields"}},
+	)
+
+	require.NoError(t, c.Publish(context.Background(), batch))
+
+	require.Len(t, p.requests, 1)
+	messages := p.requests[0]
+	assert.Equal(t, "web-1", messages[0].OrderingKey)
+	assert.Equal(t, map[string]string{"agent_id": "abc", "environment": "production"}, messages[0].Attributes)
+	assert.Equal(t, "", messages[1].OrderingKey)
+	assert.Equal(t, map[string]string{"environment": "production"}, messages[1].Attributes)
+}
+
+func TestConfigValidate(t *testing.T) {
+	cases := map[string]struct {
+		cfg   common.MapStr
+		valid bool
+	}{
+		"credentials_json": {
+			cfg:   common.MapStr{"credentials_json": "{}"},
+			valid: true,
+		},
+		"missing credentials file": {
+			cfg: common.MapStr{"credentials_file": "/does/not/exist.json"},
+		},
+		"reserved attribute key": {
+			cfg: common.MapStr{
+				"credentials_json": "{}",
+				"attributes":       []common.MapStr{{"key": "googclient", "value": "x"}},
+			},
+		},
+		"too large bulk_max_size": {
+			cfg: common.MapStr{"credentials_json": "{}", "bulk_max_size": 2000},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			cfg := common.MustNewConfigFrom(common.MapStr{"project_id": "test", "topic": "beats"})
+			require.NoError(t, cfg.Merge(test.cfg))
+			config := defaultConfig()
+			err := cfg.Unpack(&config)
+			if test.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 5))
+	assert.Equal(t, "ab", truncate("abc", 2))
+	// "é" is encoded with two bytes, it is not split.
+	assert.Equal(t, "a", truncate("aé", 2))
+	assert.Equal(t, strings.Repeat("x", maxOrderingKeyBytes), truncate(strings.Repeat("x", 2000), maxOrderingKeyBytes))
+}
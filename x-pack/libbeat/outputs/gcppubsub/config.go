@@ -0,0 +1,102 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gcppubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/pubsub"
+	"golang.org/x/oauth2/google"
+
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+)
+
+type config struct {
+	// Google Cloud project name.
+	ProjectID string `config:"project_id" validate:"required"`
+
+	// Google Cloud Pub/Sub topic name.
+	Topic string `config:"topic" validate:"required"`
+
+	// JSON file containing authentication credentials and key.
+	CredentialsFile string `config:"credentials_file"`
+
+	// JSON blob containing authentication credentials and key.
+	CredentialsJSON string `config:"credentials_json"`
+
+	// Ordering key of the messages, formatted for each event.
+	OrderingKey *fmtstr.EventFormatString `config:"ordering_key"`
+
+	// Attributes of the messages, formatted for each event.
+	Attributes []attributeConfig `config:"attributes"`
+
+	Timeout     time.Duration         `config:"timeout"       validate:"min=1"`
+	BulkMaxSize int                   `config:"bulk_max_size" validate:"min=1,max=1000"`
+	MaxRetries  int                   `config:"max_retries"   validate:"min=-1"`
+	Backoff     outputs.BackoffConfig `config:"backoff"`
+	Codec       codec.Config          `config:"codec"`
+}
+
+type attributeConfig struct {
+	Key   string                    `config:"key"   validate:"required"`
+	Value *fmtstr.EventFormatString `config:"value" validate:"required"`
+}
+
+func defaultConfig() config {
+	return config{
+		Timeout:     60 * time.Second,
+		BulkMaxSize: maxRequestMessages,
+		MaxRetries:  3,
+		Backoff:     outputs.DefaultBackoffConfig(),
+	}
+}
+
+func (c *config) Validate() error {
+	if len(c.Attributes) > maxAttributes {
+		return fmt.Errorf("at most %v attributes can be configured", maxAttributes)
+	}
+	for _, attr := range c.Attributes {
+		if len(attr.Key) > maxAttributeKeyBytes {
+			return fmt.Errorf("attribute key %q is longer than %v bytes", attr.Key, maxAttributeKeyBytes)
+		}
+		if strings.HasPrefix(attr.Key, "goog") {
+			return fmt.Errorf("attribute key %q uses the reserved prefix goog", attr.Key)
+		}
+	}
+
+	// The emulator does not authenticate the clients.
+	if os.Getenv(emulatorHostEnv) != "" {
+		return nil
+	}
+
+	// credentials_file
+	if c.CredentialsFile != "" {
+		if _, err := os.Stat(c.CredentialsFile); os.IsNotExist(err) {
+			return fmt.Errorf("credentials_file is configured, but the file %q cannot be found", c.CredentialsFile)
+		}
+		return nil
+	}
+
+	// credentials_json
+	if len(c.CredentialsJSON) > 0 {
+		return nil
+	}
+
+	// Application Default Credentials (ADC)
+	ctx := context.Background()
+	if _, err := google.FindDefaultCredentials(ctx, pubsub.ScopePubSub); err == nil {
+		return nil
+	}
+
+	return errors.New("no authentication credentials were configured or detected " +
+		"(credentials_file, credentials_json, and application default credentials (ADC))")
+}
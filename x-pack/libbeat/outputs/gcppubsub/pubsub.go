@@ -0,0 +1,106 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package gcppubsub
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	pubsub "cloud.google.com/go/pubsub/apiv1"
+	"google.golang.org/api/option"
+	pubsubpb "google.golang.org/genproto/googleapis/pubsub/v1"
+	"google.golang.org/grpc"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/v7/libbeat/common/useragent"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+)
+
+const (
+	logSelector = "gcp_pubsub"
+
+	// emulatorHostEnv is the environment variable of the address of the
+	// Pub/Sub emulator, as used by the Google Cloud client libraries.
+	emulatorHostEnv = "PUBSUB_EMULATOR_HOST"
+)
+
+func init() {
+	outputs.RegisterType("gcp_pubsub", makePubsub)
+}
+
+func makePubsub(
+	_ outputs.IndexManager,
+	beat beat.Info,
+	observer outputs.Observer,
+	cfg *common.Config,
+) (outputs.Group, error) {
+	cfgwarn.Beta("The gcp_pubsub output is beta.")
+
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return outputs.Fail(err)
+	}
+
+	enc, err := codec.CreateEncoder(beat, config.Codec)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	opts := clientOptions(beat, &config)
+	connect := func(ctx context.Context) (messagePublisher, error) {
+		c, err := pubsub.NewPublisherClient(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return &topicPublisher{client: c}, nil
+	}
+
+	topic := fmt.Sprintf("projects/%s/topics/%s", config.ProjectID, config.Topic)
+	client := newClient(observer, topic, beat.IndexPrefix, enc, &config, connect)
+	clients := []outputs.NetworkClient{outputs.WithBackoff(client, config.Backoff.Init, config.Backoff.Max)}
+
+	grp, err := outputs.SuccessNet(false, config.BulkMaxSize, config.MaxRetries, clients)
+	grp.Backoff = &config.Backoff
+	return grp, err
+}
+
+func clientOptions(beat beat.Info, config *config) []option.ClientOption {
+	opts := []option.ClientOption{option.WithUserAgent(useragent.UserAgent(beat.Beat))}
+	if host := os.Getenv(emulatorHostEnv); host != "" {
+		return append(opts,
+			option.WithEndpoint(host),
+			option.WithoutAuthentication(),
+			option.WithGRPCDialOption(grpc.WithInsecure()),
+		)
+	}
+
+	if config.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(config.CredentialsFile))
+	} else if len(config.CredentialsJSON) > 0 {
+		opts = append(opts, option.WithCredentialsJSON([]byte(config.CredentialsJSON)))
+	}
+	return opts
+}
+
+// topicPublisher publishes the messages with the Pub/Sub API.
+type topicPublisher struct {
+	client *pubsub.PublisherClient
+}
+
+func (p *topicPublisher) publish(ctx context.Context, topic string, messages []*pubsubpb.PubsubMessage) error {
+	_, err := p.client.Publish(ctx, &pubsubpb.PublishRequest{
+		Topic:    topic,
+		Messages: messages,
+	})
+	return err
+}
+
+func (p *topicPublisher) Close() error {
+	return p.client.Close()
+}
@@ -0,0 +1,142 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package kinesis
+
+import (
+	"context"
+	"fmt"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+)
+
+const (
+	// maxRequestRecords is the maximum number of records of a request, for
+	// both Data Streams and Firehose.
+	maxRequestRecords = 500
+
+	// maxPartitionKeyLen is the maximum number of characters of a partition
+	// key of Data Streams.
+	maxPartitionKeyLen = 256
+)
+
+// requestLimits are the limits of the API of a stream.
+type requestLimits struct {
+	records      int
+	requestBytes int
+	recordBytes  int
+
+	// partitioned is set if the records have a partition key, whose size
+	// counts towards the size of the record.
+	partitioned bool
+}
+
+var (
+	// streamLimits are the limits of PutRecords of Data Streams.
+	streamLimits = requestLimits{
+		records:      maxRequestRecords,
+		requestBytes: 5 * 1024 * 1024,
+		recordBytes:  1024 * 1024,
+		partitioned:  true,
+	}
+
+	// firehoseLimits are the limits of PutRecordBatch of Firehose.
+	firehoseLimits = requestLimits{
+		records:      maxRequestRecords,
+		requestBytes: 4 * 1024 * 1024,
+		recordBytes:  1000 * 1024,
+	}
+)
+
+// recordAPI puts records into a Data Stream or a Firehose delivery stream.
+type recordAPI interface {
+	fmt.Stringer
+
+	// limits returns the limits of the requests of putRecords.
+	limits() requestLimits
+
+	// putRecords puts the records with one request. It returns the records
+	// that were not accepted, or an error if the request failed.
+	putRecords(ctx context.Context, records []record) ([]putFailure, error)
+}
+
+// putFailure is a record of a successful request that was not accepted, e.g.
+// because the throughput of the stream was exceeded.
+type putFailure struct {
+	index  int
+	reason string
+}
+
+type streamAPI struct {
+	client *kinesis.Client
+	name   string
+}
+
+type firehoseAPI struct {
+	client *firehose.Client
+	name   string
+}
+
+func (s *streamAPI) String() string { return "stream " + s.name }
+
+func (s *streamAPI) limits() requestLimits { return streamLimits }
+
+func (s *streamAPI) putRecords(ctx context.Context, records []record) ([]putFailure, error) {
+	entries := make([]kinesis.PutRecordsRequestEntry, len(records))
+	for i, r := range records {
+		entries[i] = kinesis.PutRecordsRequestEntry{
+			Data:         r.data,
+			PartitionKey: awssdk.String(r.partitionKey),
+		}
+	}
+
+	resp, err := s.client.PutRecordsRequest(&kinesis.PutRecordsInput{
+		StreamName: awssdk.String(s.name),
+		Records:    entries,
+	}).Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []putFailure
+	for i, entry := range resp.Records {
+		if entry.ErrorCode != nil {
+			failures = append(failures, putFailure{index: i, reason: failureReason(entry.ErrorCode, entry.ErrorMessage)})
+		}
+	}
+	return failures, nil
+}
+
+func (f *firehoseAPI) String() string { return "delivery stream " + f.name }
+
+func (f *firehoseAPI) limits() requestLimits { return firehoseLimits }
+
+func (f *firehoseAPI) putRecords(ctx context.Context, records []record) ([]putFailure, error) {
+	entries := make([]firehose.Record, len(records))
+	for i, r := range records {
+		entries[i] = firehose.Record{Data: r.data}
+	}
+
+	resp, err := f.client.PutRecordBatchRequest(&firehose.PutRecordBatchInput{
+		DeliveryStreamName: awssdk.String(f.name),
+		Records:            entries,
+	}).Send(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []putFailure
+	for i, entry := range resp.RequestResponses {
+		if entry.ErrorCode != nil {
+			failures = append(failures, putFailure{index: i, reason: failureReason(entry.ErrorCode, entry.ErrorMessage)})
+		}
+	}
+	return failures, nil
+}
+
+func failureReason(code, message *string) string {
+	return awssdk.StringValue(code) + ": " + awssdk.StringValue(message)
+}
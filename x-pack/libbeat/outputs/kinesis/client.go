@@ -0,0 +1,211 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package kinesis
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/privacy"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+type client struct {
+	log          *logp.Logger
+	observer     outputs.Observer
+	api          recordAPI
+	index        string
+	codec        codec.Codec
+	partitionKey *fmtstr.EventFormatString
+	timeout      time.Duration
+}
+
+// record is an encoded event.
+type record struct {
+	event        publisher.Event
+	data         []byte
+	partitionKey string
+}
+
+func newClient(
+	observer outputs.Observer,
+	api recordAPI,
+	index string,
+	writer codec.Codec,
+	partitionKey *fmtstr.EventFormatString,
+	timeout time.Duration,
+) *client {
+	return &client{
+		log:          logp.NewLogger(logSelector),
+		observer:     observer,
+		api:          api,
+		index:        strings.ToLower(index),
+		codec:        writer,
+		partitionKey: partitionKey,
+		timeout:      timeout,
+	}
+}
+
+// Connect does nothing, the requests to AWS are independent of each other.
+func (c *client) Connect() error {
+	return nil
+}
+
+func (c *client) Close() error {
+	return nil
+}
+
+func (c *client) String() string {
+	return "kinesis(" + c.api.String() + ")"
+}
+
+func (c *client) Publish(ctx context.Context, batch publisher.Batch) error {
+	events := batch.Events()
+	c.observer.NewBatch(len(events))
+
+	records := c.makeRecords(batch, events)
+	if dropped := len(events) - len(records); dropped > 0 {
+		c.observer.Dropped(dropped)
+	}
+
+	retry, err := c.putRecords(ctx, records)
+	c.observer.Acked(len(records) - len(retry))
+	if len(retry) > 0 {
+		c.observer.Failed(len(retry))
+		batch.RetryEvents(retry)
+		return err
+	}
+
+	batch.ACK()
+	return nil
+}
+
+// makeRecords encodes the events. Events that can not be encoded or that are
+// larger than a record can be are dropped.
+func (c *client) makeRecords(batch publisher.Batch, events []publisher.Event) []record {
+	limits := c.api.limits()
+	records := make([]record, 0, len(events))
+	for i := range events {
+		event := &events[i]
+		serializedEvent, err := c.codec.Encode(c.index, &event.Content)
+		if err != nil {
+			c.log.Errorf("Dropping event: %+v", err)
+			if c.log.IsDebug() {
+				c.log.Debugf("failed event: %v", privacy.LazyEvent(&event.Content))
+			}
+			publisher.DeadLetter(batch, *event, fmt.Sprintf("failed to encode the event: %v", err))
+			continue
+		}
+
+		r := record{
+			event: *event,
+			data:  make([]byte, len(serializedEvent)),
+		}
+		copy(r.data, serializedEvent)
+		if limits.partitioned {
+			r.partitionKey = c.eventPartitionKey(&event.Content)
+		}
+
+		if size := r.size(); size > limits.recordBytes {
+			c.log.Errorf("Dropping too large event of size %v, the limit of %v is %v.", size, c.api, limits.recordBytes)
+			publisher.DeadLetter(batch, *event, fmt.Sprintf("event of size %v exceeds the record size limit of %v", size, limits.recordBytes))
+			continue
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// eventPartitionKey returns the partition key of the event. A random key is
+// used if no partition key is configured or if it can not be formatted for
+// the event, which distributes the records evenly across the shards.
+func (c *client) eventPartitionKey(event *beat.Event) string {
+	if c.partitionKey != nil {
+		key, err := c.partitionKey.Run(event)
+		if err == nil && key != "" {
+			if runes := []rune(key); len(runes) > maxPartitionKeyLen {
+				key = string(runes[:maxPartitionKeyLen])
+			}
+			return key
+		}
+		if c.log.IsDebug() {
+			c.log.Debugf("using a random partition key, the partition key can not be formatted: %v", err)
+		}
+	}
+	return strconv.FormatUint(rand.Uint64(), 36)
+}
+
+// putRecords puts the records with as few requests as the limits of the API
+// allow. It returns the events of the records that need to be retried.
+func (c *client) putRecords(ctx context.Context, records []record) ([]publisher.Event, error) {
+	var (
+		retry   []publisher.Event
+		lastErr error
+	)
+	limits := c.api.limits()
+	for len(records) > 0 {
+		n := limits.split(records)
+		request := records[:n]
+		records = records[n:]
+
+		reqCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		failures, err := c.api.putRecords(reqCtx, request)
+		cancel()
+		if err != nil {
+			// Do not send the remaining records, the next requests are
+			// likely to fail the same way.
+			c.observer.WriteError(err)
+			for _, r := range append(request, records...) {
+				retry = append(retry, r.event)
+			}
+			return retry, fmt.Errorf("failed to put records into %v: %v", c.api, err)
+		}
+
+		c.observer.WriteBytes(requestSize(request))
+		if len(failures) > 0 {
+			c.log.Warnf("%v of %v records were not accepted by %v, they will be retried. First error: %v",
+				len(failures), len(request), c.api, failures[0].reason)
+			for _, f := range failures {
+				retry = append(retry, request[f.index].event)
+			}
+			lastErr = fmt.Errorf("%v records were not accepted by %v: %v", len(failures), c.api, failures[0].reason)
+		}
+	}
+	return retry, lastErr
+}
+
+// split returns the number of records at the head of records that fit into a
+// single request.
+func (l requestLimits) split(records []record) int {
+	size := 0
+	for i, r := range records {
+		size += r.size()
+		if i == l.records || (i > 0 && size > l.requestBytes) {
+			return i
+		}
+	}
+	return len(records)
+}
+
+func (r *record) size() int {
+	return len(r.data) + len(r.partitionKey)
+}
+
+func requestSize(records []record) int {
+	size := 0
+	for i := range records {
+		size += records[i].size()
+	}
+	return size
+}
@@ -0,0 +1,202 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package kinesis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// fakeAPI records the requests and fails the records or requests selected by
+// fail.
+type fakeAPI struct {
+	limitsValue requestLimits
+	requests    [][]record
+	fail        func(request int, records []record) ([]putFailure, error)
+}
+
+func (f *fakeAPI) String() string { return "fake" }
+
+func (f *fakeAPI) limits() requestLimits { return f.limitsValue }
+
+func (f *fakeAPI) putRecords(_ context.Context, records []record) ([]putFailure, error) {
+	f.requests = append(f.requests, records)
+	if f.fail != nil {
+		return f.fail(len(f.requests)-1, records)
+	}
+	return nil, nil
+}
+
+func newTestClient(api recordAPI, partitionKey *fmtstr.EventFormatString) *client {
+	return newClient(outputs.NewNilObserver(), api, "testbeat", json.New("1.2.3", json.Config{}), partitionKey, time.Minute)
+}
+
+func makeEvents(n int, message string) []beat.Event {
+	events := make([]beat.Event, n)
+	for i := range events {
+		events[i] = beat.Event{Fields: common.MapStr{"message": message, "n": i}}
+	}
+	return events
+}
+
+func TestPublishSplitsRequests(t *testing.T) {
+	cases := map[string]struct {
+		limits   requestLimits
+		events   int
+		message  string
+		requests []int
+	}{
+		"single request": {
+			limits:   streamLimits,
+			events:   10,
+			requests: []int{10},
+		},
+		"record count limit": {
+			limits:   requestLimits{records: 4, requestBytes: streamLimits.requestBytes, recordBytes: streamLimits.recordBytes},
+			events:   10,
+			requests: []int{4, 4, 2},
+		},
+		"request size limit": {
+			limits:   requestLimits{records: maxRequestRecords, requestBytes: 2500, recordBytes: 1000},
+			events:   5,
+			message:  strings.Repeat("a", 800),
+			requests: []int{2, 2, 1},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			api := &fakeAPI{limitsValue: test.limits}
+			batch := outest.NewBatch(makeEvents(test.events, test.message)...)
+
+			err := newTestClient(api, nil).Publish(context.Background(), batch)
+			require.NoError(t, err)
+
+			var sizes []int
+			for _, request := range api.requests {
+				sizes = append(sizes, len(request))
+			}
+			assert.Equal(t, test.requests, sizes)
+			assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+		})
+	}
+}
+
+func TestPublishRetriesFailedRecords(t *testing.T) {
+	api := &fakeAPI{
+		limitsValue: requestLimits{records: 3, requestBytes: streamLimits.requestBytes, recordBytes: streamLimits.recordBytes},
+		fail: func(request int, records []record) ([]putFailure, error) {
+			if request == 0 {
+				return []putFailure{{index: 1, reason: "ProvisionedThroughputExceededException: Rate exceeded"}}, nil
+			}
+			return nil, nil
+		},
+	}
+	batch := outest.NewBatch(makeEvents(5, "")...)
+
+	err := newTestClient(api, nil).Publish(context.Background(), batch)
+	assert.Error(t, err)
+
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	require.Len(t, batch.Signals[0].Events, 1)
+	assert.Equal(t, 1, batch.Signals[0].Events[0].Content.Fields["n"])
+}
+
+func TestPublishRetriesAfterRequestError(t *testing.T) {
+	api := &fakeAPI{
+		limitsValue: requestLimits{records: 2, requestBytes: streamLimits.requestBytes, recordBytes: streamLimits.recordBytes},
+		fail: func(request int, records []record) ([]putFailure, error) {
+			if request == 1 {
+				return nil, errors.New("connection reset")
+			}
+			return nil, nil
+		},
+	}
+	batch := outest.NewBatch(makeEvents(6, "")...)
+
+	err := newTestClient(api, nil).Publish(context.Background(), batch)
+	assert.Error(t, err)
+
+	// The records after the failed request are not sent.
+	assert.Len(t, api.requests, 2)
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	assert.Len(t, batch.Signals[0].Events, 4)
+}
+
+func TestPublishDropsTooLargeEvents(t *testing.T) {
+	api := &fakeAPI{
+		limitsValue: requestLimits{records: maxRequestRecords, requestBytes: 10000, recordBytes: 500},
+	}
+	events := append(makeEvents(2, ""), beat.Event{Fields: common.MapStr{"message": strings.Repeat("a", 1000)}})
+	var dropped []publisher.Event
+	batch := &deadLetterBatch{Batch: outest.NewBatch(events...), deadLetter: func(event publisher.Event, _ string) {
+		dropped = append(dropped, event)
+	}}
+
+	err := newTestClient(api, nil).Publish(context.Background(), batch)
+	require.NoError(t, err)
+
+	require.Len(t, api.requests, 1)
+	assert.Len(t, api.requests[0], 2)
+	assert.Len(t, dropped, 1)
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+}
+
+func TestPartitionKey(t *testing.T) {
+	api := &fakeAPI{limitsValue: streamLimits}
+	batch := outest.NewBatch(
+		beat.Event{Fields: common.MapStr{"host": common.MapStr{"name": "a"}}},
+		beat.Event{Fields: common.MapStr{"host": common.MapStr{"name": strings.Repeat("b", 300)}}},
+		beat.Event{Fields: common.MapStr{"message": "no host"}},
+	)
+
+	err := newTestClient(api, fmtstr.MustCompileEvent("%{[host.name]}")).Publish(context.Background(), batch)
+	require.NoError(t, err)
+
+	require.Len(t, api.requests, 1)
+	records := api.requests[0]
+	assert.Equal(t, "a", records[0].partitionKey)
+	assert.Equal(t, strings.Repeat("b", maxPartitionKeyLen), records[1].partitionKey)
+	assert.NotEmpty(t, records[2].partitionKey)
+}
+
+func TestFirehoseRecordsHaveNoPartitionKey(t *testing.T) {
+	api := &fakeAPI{limitsValue: firehoseLimits}
+	batch := outest.NewBatch(makeEvents(3, "")...)
+
+	err := newTestClient(api, nil).Publish(context.Background(), batch)
+	require.NoError(t, err)
+
+	require.Len(t, api.requests, 1)
+	for i, r := range api.requests[0] {
+		assert.Empty(t, r.partitionKey, fmt.Sprintf("record %d", i))
+	}
+}
+
+type deadLetterBatch struct {
+	*outest.Batch
+	deadLetter func(publisher.Event, string)
+}
+
+func (b *deadLetterBatch) DeadLetter(event publisher.Event, reason string) {
+	b.deadLetter(event, reason)
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package kinesis
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common/fmtstr"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	awscommon "github.com/elastic/beats/v7/x-pack/libbeat/common/aws"
+)
+
+type kinesisConfig struct {
+	AWS            awscommon.ConfigAWS       `config:",inline"`
+	Region         string                    `config:"region"`
+	Stream         string                    `config:"stream"`
+	DeliveryStream string                    `config:"delivery_stream"`
+	PartitionKey   *fmtstr.EventFormatString `config:"partition_key"`
+	Timeout        time.Duration             `config:"timeout"       validate:"min=1"`
+	BulkMaxSize    int                       `config:"bulk_max_size" validate:"min=1,max=500"`
+	MaxRetries     int                       `config:"max_retries"   validate:"min=-1"`
+	Backoff        outputs.BackoffConfig     `config:"backoff"`
+	Codec          codec.Config              `config:"codec"`
+}
+
+func defaultConfig() kinesisConfig {
+	return kinesisConfig{
+		Timeout:     90 * time.Second,
+		BulkMaxSize: maxRequestRecords,
+		MaxRetries:  3,
+		Backoff:     outputs.DefaultBackoffConfig(),
+	}
+}
+
+func (c *kinesisConfig) Validate() error {
+	if c.Stream == "" && c.DeliveryStream == "" {
+		return errors.New("either stream or delivery_stream must be configured")
+	}
+	if c.Stream != "" && c.DeliveryStream != "" {
+		return fmt.Errorf("stream %v and delivery_stream %v can not be configured together", c.Stream, c.DeliveryStream)
+	}
+	if c.DeliveryStream != "" && c.PartitionKey != nil {
+		return errors.New("partition_key is not supported by Firehose delivery streams")
+	}
+	return nil
+}
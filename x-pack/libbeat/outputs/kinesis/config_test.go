@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package kinesis
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cases := map[string]struct {
+		cfg   common.MapStr
+		valid bool
+	}{
+		"data stream": {
+			cfg:   common.MapStr{"stream": "logs", "partition_key": "%{[host.name]}"},
+			valid: true,
+		},
+		"delivery stream": {
+			cfg:   common.MapStr{"delivery_stream": "logs"},
+			valid: true,
+		},
+		"no stream": {
+			cfg: common.MapStr{"region": "us-east-1"},
+		},
+		"both streams": {
+			cfg: common.MapStr{"stream": "logs", "delivery_stream": "logs"},
+		},
+		"partition key of delivery stream": {
+			cfg: common.MapStr{"delivery_stream": "logs", "partition_key": "%{[host.name]}"},
+		},
+		"too large bulk_max_size": {
+			cfg: common.MapStr{"stream": "logs", "bulk_max_size": 1000},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := defaultConfig()
+			err := common.MustNewConfigFrom(test.cfg).Unpack(&config)
+			if test.valid && err != nil {
+				t.Fatalf("Can not create test configuration: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatalf("Can create test configuration from invalid input")
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package kinesis
+
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	awscommon "github.com/elastic/beats/v7/x-pack/libbeat/common/aws"
+)
+
+const logSelector = "kinesis"
+
+func init() {
+	outputs.RegisterType("kinesis", makeKinesis)
+}
+
+func makeKinesis(
+	_ outputs.IndexManager,
+	beat beat.Info,
+	observer outputs.Observer,
+	cfg *common.Config,
+) (outputs.Group, error) {
+	cfgwarn.Beta("The kinesis output is beta.")
+
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return outputs.Fail(err)
+	}
+
+	awsConfig, err := awscommon.GetAWSCredentials(config.AWS)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+	if config.Region != "" {
+		awsConfig.Region = config.Region
+	}
+	if awsConfig.Region == "" {
+		return outputs.Fail(errors.New("no region configured for the kinesis output"))
+	}
+
+	var api recordAPI
+	if config.Stream != "" {
+		api = &streamAPI{
+			client: kinesis.New(awscommon.EnrichAWSConfigWithEndpoint(config.AWS.Endpoint, "kinesis", awsConfig.Region, awsConfig)),
+			name:   config.Stream,
+		}
+	} else {
+		api = &firehoseAPI{
+			client: firehose.New(awscommon.EnrichAWSConfigWithEndpoint(config.AWS.Endpoint, "firehose", awsConfig.Region, awsConfig)),
+			name:   config.DeliveryStream,
+		}
+	}
+
+	enc, err := codec.CreateEncoder(beat, config.Codec)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	client := newClient(observer, api, beat.IndexPrefix, enc, config.PartitionKey, config.Timeout)
+	clients := []outputs.NetworkClient{outputs.WithBackoff(client, config.Backoff.Init, config.Backoff.Max)}
+
+	grp, err := outputs.SuccessNet(false, config.BulkMaxSize, config.MaxRetries, clients)
+	grp.Backoff = &config.Backoff
+	return grp, err
+}
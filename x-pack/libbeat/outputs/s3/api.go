@@ -0,0 +1,104 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package s3
+
+import (
+	"context"
+	"io"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// objectAPI uploads objects into a bucket.
+type objectAPI interface {
+	// String returns the name of the bucket.
+	String() string
+
+	// putObject uploads an object with a single request.
+	putObject(ctx context.Context, key string, body io.ReadSeeker) error
+
+	// createMultipartUpload starts a multipart upload and returns its id.
+	createMultipartUpload(ctx context.Context, key string) (string, error)
+
+	// uploadPart uploads a part of a multipart upload and returns its ETag.
+	uploadPart(ctx context.Context, key, uploadID string, number int64, body io.ReadSeeker) (string, error)
+
+	// completeMultipartUpload assembles the uploaded parts into the object.
+	completeMultipartUpload(ctx context.Context, key, uploadID string, etags []string) error
+
+	// abortMultipartUpload deletes the uploaded parts.
+	abortMultipartUpload(ctx context.Context, key, uploadID string) error
+}
+
+type bucketAPI struct {
+	client      *s3.Client
+	bucket      string
+	contentType string
+}
+
+func (b *bucketAPI) String() string { return "s3://" + b.bucket }
+
+func (b *bucketAPI) putObject(ctx context.Context, key string, body io.ReadSeeker) error {
+	_, err := b.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:      awssdk.String(b.bucket),
+		Key:         awssdk.String(key),
+		ContentType: awssdk.String(b.contentType),
+		Body:        body,
+	}).Send(ctx)
+	return err
+}
+
+func (b *bucketAPI) createMultipartUpload(ctx context.Context, key string) (string, error) {
+	resp, err := b.client.CreateMultipartUploadRequest(&s3.CreateMultipartUploadInput{
+		Bucket:      awssdk.String(b.bucket),
+		Key:         awssdk.String(key),
+		ContentType: awssdk.String(b.contentType),
+	}).Send(ctx)
+	if err != nil {
+		return "", err
+	}
+	return awssdk.StringValue(resp.UploadId), nil
+}
+
+func (b *bucketAPI) uploadPart(ctx context.Context, key, uploadID string, number int64, body io.ReadSeeker) (string, error) {
+	resp, err := b.client.UploadPartRequest(&s3.UploadPartInput{
+		Bucket:     awssdk.String(b.bucket),
+		Key:        awssdk.String(key),
+		UploadId:   awssdk.String(uploadID),
+		PartNumber: awssdk.Int64(number),
+		Body:       body,
+	}).Send(ctx)
+	if err != nil {
+		return "", err
+	}
+	return awssdk.StringValue(resp.ETag), nil
+}
+
+func (b *bucketAPI) completeMultipartUpload(ctx context.Context, key, uploadID string, etags []string) error {
+	parts := make([]s3.CompletedPart, len(etags))
+	for i, etag := range etags {
+		parts[i] = s3.CompletedPart{
+			ETag:       awssdk.String(etag),
+			PartNumber: awssdk.Int64(int64(i + 1)),
+		}
+	}
+	_, err := b.client.CompleteMultipartUploadRequest(&s3.CompleteMultipartUploadInput{
+		Bucket:          awssdk.String(b.bucket),
+		Key:             awssdk.String(key),
+		UploadId:        awssdk.String(uploadID),
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+	}).Send(ctx)
+	return err
+}
+
+func (b *bucketAPI) abortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	_, err := b.client.AbortMultipartUploadRequest(&s3.AbortMultipartUploadInput{
+		Bucket:   awssdk.String(b.bucket),
+		Key:      awssdk.String(key),
+		UploadId: awssdk.String(uploadID),
+	}).Send(ctx)
+	return err
+}
@@ -0,0 +1,385 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/logp"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/outputs/encrypt"
+	"github.com/elastic/beats/v7/libbeat/privacy"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// partitionLayout is the layout of the time partition of the object keys.
+const partitionLayout = "2006/01/02/15"
+
+// client buffers the events into one object per hour of their timestamp,
+// and uploads the objects when they are full, or when the flush timeout
+// expired. The batches are acknowledged only after all their events were
+// uploaded, so they hold their place in the queue until then.
+type client struct {
+	log        *logp.Logger
+	observer   outputs.Observer
+	api        objectAPI
+	name       string
+	index      string
+	codec      codec.Codec
+	prefix     string
+	compress   bool
+	encrypter  *encrypt.Encrypter
+	partSize   int64
+	flush      flushConfig
+	bufferPath string
+	timeout    time.Duration
+	now        func() time.Time
+
+	mutex   sync.Mutex
+	objects map[string]*object
+	events  int
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newClient(
+	observer outputs.Observer,
+	api objectAPI,
+	name, index string,
+	writer codec.Codec,
+	config s3Config,
+	bufferPath string,
+) *client {
+	return &client{
+		log:        logp.NewLogger(logSelector),
+		observer:   observer,
+		api:        api,
+		name:       name,
+		index:      strings.ToLower(index),
+		codec:      writer,
+		prefix:     strings.Trim(config.Prefix, "/"),
+		compress:   config.Compression == compressionGzip,
+		partSize:   int64(config.PartSize),
+		flush:      config.Flush,
+		bufferPath: bufferPath,
+		timeout:    config.Timeout,
+		now:        time.Now,
+		objects:    map[string]*object{},
+	}
+}
+
+// Connect starts the upload of the objects whose flush timeout expired. It
+// can be called again after a failed publish, the uploads are only started
+// once.
+func (c *client) Connect() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.done != nil {
+		return nil
+	}
+
+	if err := removeBufferFiles(c.bufferPath); err != nil {
+		return fmt.Errorf("failed to remove the buffer files of a previous run: %v", err)
+	}
+
+	c.done = make(chan struct{})
+	c.wg.Add(1)
+	go c.flushExpired(c.done)
+	return nil
+}
+
+// Close uploads all buffered objects.
+func (c *client) Close() error {
+	c.mutex.Lock()
+	done := c.done
+	c.done = nil
+	c.mutex.Unlock()
+	if done != nil {
+		close(done)
+		c.wg.Wait()
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.flushObjects(context.Background(), func(*object) bool { return true })
+}
+
+func (c *client) String() string {
+	return "s3(" + c.api.String() + ")"
+}
+
+func (c *client) Publish(ctx context.Context, batch publisher.Batch) error {
+	events := batch.Events()
+	c.observer.NewBatch(len(events))
+
+	partitions, dropped := c.encode(batch, events)
+	if dropped > 0 {
+		c.observer.Dropped(dropped)
+	}
+	if len(partitions) == 0 {
+		batch.ACK()
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// The batch is pending until all partitions are added, so it can not be
+	// completed by the failure of the first object.
+	pb := &pendingBatch{batch: batch, pending: 1}
+	keys := make([]string, 0, len(partitions))
+	for key := range partitions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var err error
+	for _, key := range keys {
+		p := partitions[key]
+		o, ok := c.objects[key]
+		if !ok {
+			o, err = newObject(c.bufferPath, c.objectKey(key), c.compress, c.encrypter, c.now())
+			if err != nil {
+				err = fmt.Errorf("failed to create the buffer of an object: %v", err)
+				c.log.Error(err)
+				c.observer.Failed(len(p.events))
+				pb.retry = append(pb.retry, p.events...)
+				continue
+			}
+			c.objects[key] = o
+		}
+
+		c.events += len(p.events)
+		if werr := o.add(pb, p.events, p.data); werr != nil {
+			err = fmt.Errorf("failed to buffer the object %v: %v", o.key, werr)
+			c.log.Error(err)
+			c.failObject(key, o)
+		}
+	}
+	c.complete(pb, nil)
+
+	if ferr := c.flushObjects(ctx, func(o *object) bool {
+		return c.events >= c.flush.MaxEvents || o.bytes >= int(c.flush.MaxBytes)
+	}); ferr != nil {
+		err = ferr
+	}
+	return err
+}
+
+// partition are the encoded events of a batch for one object.
+type partition struct {
+	events []publisher.Event
+	data   []byte
+}
+
+// encode encodes the events as lines and groups them by the hour of their
+// timestamp. Events that can not be encoded are dropped.
+func (c *client) encode(batch publisher.Batch, events []publisher.Event) (map[string]*partition, int) {
+	partitions := map[string]*partition{}
+	dropped := 0
+	for i := range events {
+		event := &events[i]
+		serializedEvent, err := c.codec.Encode(c.index, &event.Content)
+		if err != nil {
+			c.log.Errorf("Dropping event: %+v", err)
+			if c.log.IsDebug() {
+				c.log.Debugf("failed event: %v", privacy.LazyEvent(&event.Content))
+			}
+			publisher.DeadLetter(batch, *event, fmt.Sprintf("failed to encode the event: %v", err))
+			dropped++
+			continue
+		}
+
+		ts := event.Content.Timestamp
+		if ts.IsZero() {
+			ts = c.now()
+		}
+		key := ts.UTC().Format(partitionLayout)
+		p, ok := partitions[key]
+		if !ok {
+			p = &partition{}
+			partitions[key] = p
+		}
+		p.events = append(p.events, *event)
+		p.data = append(p.data, serializedEvent...)
+		p.data = append(p.data, '\n')
+	}
+	return partitions, dropped
+}
+
+// objectKey returns a unique key for a new object of the time partition.
+func (c *client) objectKey(partition string) string {
+	name := c.name + "-" + c.now().UTC().Format("20060102T150405Z") + "-" + strconv.FormatUint(rand.Uint64(), 36) + ".ndjson"
+	if c.compress {
+		name += ".gz"
+	}
+	if c.encrypter != nil {
+		name += c.encrypter.Extension()
+	}
+	return path.Join(c.prefix, partition, name)
+}
+
+// flushExpired periodically uploads the objects that are buffered for
+// longer than the flush timeout.
+func (c *client) flushExpired(done <-chan struct{}) {
+	defer c.wg.Done()
+
+	period := c.flush.Timeout / 10
+	if period > time.Second {
+		period = time.Second
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		c.mutex.Lock()
+		now := c.now()
+		c.flushObjects(context.Background(), func(o *object) bool {
+			return now.Sub(o.created) >= c.flush.Timeout
+		})
+		c.mutex.Unlock()
+	}
+}
+
+// flushObjects uploads the objects selected by fn. It returns the last upload
+// error. Must be called with the mutex held.
+func (c *client) flushObjects(ctx context.Context, fn func(*object) bool) error {
+	keys := make([]string, 0, len(c.objects))
+	for key := range c.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lastErr error
+	for _, key := range keys {
+		o := c.objects[key]
+		if !fn(o) {
+			continue
+		}
+		if err := c.upload(ctx, o); err != nil {
+			c.observer.WriteError(err)
+			lastErr = fmt.Errorf("failed to upload %v to %v: %v", o.key, c.api, err)
+			c.log.Errorf("%v, its events will be retried", lastErr)
+			c.failObject(key, o)
+			continue
+		}
+
+		c.removeObject(key, o)
+		c.observer.Acked(o.events)
+		for pb := range o.batches {
+			c.complete(pb, nil)
+		}
+	}
+	return lastErr
+}
+
+// failObject removes the object and retries its events.
+func (c *client) failObject(key string, o *object) {
+	c.removeObject(key, o)
+	c.observer.Failed(o.events)
+	for pb, events := range o.batches {
+		c.complete(pb, events)
+	}
+}
+
+func (c *client) removeObject(key string, o *object) {
+	delete(c.objects, key)
+	c.events -= o.events
+	if err := o.remove(); err != nil {
+		c.log.Warnf("Failed to remove the buffer file of %v: %v", o.key, err)
+	}
+}
+
+// complete releases an object of the batch, and acknowledges or retries the
+// batch if it was the last one.
+func (c *client) complete(pb *pendingBatch, retry []publisher.Event) {
+	if !pb.done(retry) {
+		return
+	}
+	if len(pb.retry) > 0 {
+		pb.batch.RetryEvents(pb.retry)
+		return
+	}
+	pb.batch.ACK()
+}
+
+// upload uploads the buffered object, with a multipart upload if it is larger
+// than the part size.
+func (c *client) upload(ctx context.Context, o *object) error {
+	size, err := o.finish()
+	if err != nil {
+		return err
+	}
+
+	if size <= c.partSize {
+		reqCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+		if err := c.api.putObject(reqCtx, o.key, io.NewSectionReader(o.file, 0, size)); err != nil {
+			return err
+		}
+		c.observer.WriteBytes(int(size))
+		return nil
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	uploadID, err := c.api.createMultipartUpload(reqCtx, o.key)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	var etags []string
+	for offset := int64(0); offset < size; offset += c.partSize {
+		partSize := c.partSize
+		if offset+partSize > size {
+			partSize = size - offset
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		etag, err := c.api.uploadPart(reqCtx, o.key, uploadID, int64(len(etags)+1), io.NewSectionReader(o.file, offset, partSize))
+		cancel()
+		if err != nil {
+			c.abortUpload(o.key, uploadID)
+			return err
+		}
+		etags = append(etags, etag)
+		c.observer.WriteBytes(int(partSize))
+	}
+
+	reqCtx, cancel = context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	if err := c.api.completeMultipartUpload(reqCtx, o.key, uploadID, etags); err != nil {
+		c.abortUpload(o.key, uploadID)
+		return err
+	}
+	return nil
+}
+
+// abortUpload deletes the parts of a failed multipart upload, so they are not
+// kept, and billed, by the bucket.
+func (c *client) abortUpload(key, uploadID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	if err := c.api.abortMultipartUpload(ctx, key, uploadID); err != nil {
+		c.log.Warnf("Failed to abort the multipart upload of %v: %v", key, err)
+	}
+}
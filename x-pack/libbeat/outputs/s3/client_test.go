@@ -0,0 +1,330 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec/json"
+	"github.com/elastic/beats/v7/libbeat/outputs/encrypt"
+	"github.com/elastic/beats/v7/libbeat/outputs/encrypt/encrypttest"
+	"github.com/elastic/beats/v7/libbeat/outputs/outest"
+)
+
+// fakeAPI stores the uploaded objects, and fails the requests selected by
+// fail.
+type fakeAPI struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+	parts   map[string][][]byte
+	aborted []string
+	fail    func(op string, key string) error
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{objects: map[string][]byte{}, parts: map[string][][]byte{}}
+}
+
+func (f *fakeAPI) String() string { return "fake" }
+
+func (f *fakeAPI) failed(op, key string) error {
+	if f.fail != nil {
+		return f.fail(op, key)
+	}
+	return nil
+}
+
+func (f *fakeAPI) putObject(_ context.Context, key string, body io.ReadSeeker) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.failed("put", key); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(body)
+	f.objects[key] = data
+	return err
+}
+
+func (f *fakeAPI) createMultipartUpload(_ context.Context, key string) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return "upload-" + key, f.failed("create", key)
+}
+
+func (f *fakeAPI) uploadPart(_ context.Context, key, uploadID string, number int64, body io.ReadSeeker) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.failed("part", key); err != nil {
+		return "", err
+	}
+	data, err := ioutil.ReadAll(body)
+	f.parts[uploadID] = append(f.parts[uploadID], data)
+	return uploadID + "-" + string(rune('0'+number)), err
+}
+
+func (f *fakeAPI) completeMultipartUpload(_ context.Context, key, uploadID string, etags []string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.failed("complete", key); err != nil {
+		return err
+	}
+	if len(etags) != len(f.parts[uploadID]) {
+		return errors.New("missing parts")
+	}
+	f.objects[key] = bytes.Join(f.parts[uploadID], nil)
+	return nil
+}
+
+func (f *fakeAPI) abortMultipartUpload(_ context.Context, key, uploadID string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.aborted = append(f.aborted, key)
+	return nil
+}
+
+func (f *fakeAPI) keys() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var keys []string
+	for key := range f.objects {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func newTestClient(t *testing.T, api objectAPI, update func(*s3Config)) *client {
+	dir, err := ioutil.TempDir("", "s3out")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	config := defaultConfig()
+	config.Bucket = "archive"
+	config.Prefix = "/events/"
+	if update != nil {
+		update(&config)
+	}
+	return newClient(outputs.NewNilObserver(), api, "testbeat", "testbeat", json.New("1.2.3", json.Config{}), config, dir)
+}
+
+var testTime = time.Date(2020, 10, 14, 8, 30, 0, 0, time.UTC)
+
+func makeEvents(n int, ts time.Time) []beat.Event {
+	events := make([]beat.Event, n)
+	for i := range events {
+		events[i] = beat.Event{Timestamp: ts, Fields: common.MapStr{"n": i}}
+	}
+	return events
+}
+
+func readLines(t *testing.T, data []byte, compressed bool) []string {
+	var r io.Reader = bytes.NewReader(data)
+	if compressed {
+		zr, err := gzip.NewReader(r)
+		require.NoError(t, err)
+		r = zr
+	}
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+	return lines
+}
+
+func TestPublishBuffersUntilFull(t *testing.T) {
+	api := newFakeAPI()
+	c := newTestClient(t, api, func(c *s3Config) { c.Flush.MaxEvents = 10 })
+
+	first := outest.NewBatch(makeEvents(4, testTime)...)
+	require.NoError(t, c.Publish(context.Background(), first))
+	assert.Empty(t, api.keys())
+	assert.Empty(t, first.Signals)
+
+	second := outest.NewBatch(makeEvents(6, testTime)...)
+	require.NoError(t, c.Publish(context.Background(), second))
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, first.Signals)
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, second.Signals)
+
+	keys := api.keys()
+	require.Len(t, keys, 1)
+	assert.True(t, strings.HasPrefix(keys[0], "events/2020/10/14/08/testbeat-"), keys[0])
+	assert.True(t, strings.HasSuffix(keys[0], ".ndjson.gz"), keys[0])
+	lines := readLines(t, api.objects[keys[0]], true)
+	require.Len(t, lines, 10)
+	assert.Contains(t, lines[0], `"n":0`)
+
+	// The buffer files are removed after the upload.
+	files, err := ioutil.ReadDir(c.bufferPath)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestPublishEncrypted(t *testing.T) {
+	keys := map[string]encrypttest.Key{
+		"pgp": encrypttest.NewPGPKey(t),
+		"age": encrypttest.NewAgeKey(t),
+	}
+	for kind, key := range keys {
+		t.Run(kind, func(t *testing.T) {
+			config := &encrypt.Config{}
+			if kind == "pgp" {
+				config.PGP.Recipients = []string{key.Recipient()}
+			} else {
+				config.Age.Recipients = []string{key.Recipient()}
+			}
+			encrypter, err := encrypt.NewEncrypter(config)
+			require.NoError(t, err)
+
+			api := newFakeAPI()
+			c := newTestClient(t, api, nil)
+			c.encrypter = encrypter
+
+			batch := outest.NewBatch(makeEvents(3, testTime)...)
+			require.NoError(t, c.Publish(context.Background(), batch))
+			require.NoError(t, c.Close())
+			assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+
+			// The objects are compressed before being encrypted.
+			keys := api.keys()
+			require.Len(t, keys, 1)
+			assert.True(t, strings.HasSuffix(keys[0], ".ndjson.gz"+encrypter.Extension()), keys[0])
+			data := key.Decrypt(t, bytes.NewReader(api.objects[keys[0]]))
+			lines := readLines(t, []byte(data), true)
+			require.Len(t, lines, 3)
+			assert.Contains(t, lines[2], `"n":2`)
+		})
+	}
+}
+
+func TestPublishPartitionsByHour(t *testing.T) {
+	api := newFakeAPI()
+	c := newTestClient(t, api, func(c *s3Config) { c.Compression = compressionNone })
+
+	events := append(makeEvents(2, testTime), makeEvents(3, testTime.Add(time.Hour))...)
+	batch := outest.NewBatch(events...)
+	require.NoError(t, c.Publish(context.Background(), batch))
+	assert.Empty(t, batch.Signals)
+
+	require.NoError(t, c.Close())
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+
+	counts := map[string]int{}
+	for key, data := range api.objects {
+		assert.True(t, strings.HasSuffix(key, ".ndjson"), key)
+		counts[filepath.Dir(key)] = len(readLines(t, data, false))
+	}
+	assert.Equal(t, map[string]int{"events/2020/10/14/08": 2, "events/2020/10/14/09": 3}, counts)
+}
+
+func TestPublishRetriesEventsOfFailedUploads(t *testing.T) {
+	api := newFakeAPI()
+	api.fail = func(op, key string) error {
+		if strings.Contains(key, "/09/") {
+			return errors.New("connection reset")
+		}
+		return nil
+	}
+	c := newTestClient(t, api, nil)
+
+	events := append(makeEvents(2, testTime), makeEvents(3, testTime.Add(time.Hour))...)
+	batch := outest.NewBatch(events...)
+	require.NoError(t, c.Publish(context.Background(), batch))
+	assert.Error(t, c.Close())
+
+	// Only the events of the failed object are retried.
+	assert.Len(t, api.keys(), 1)
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	assert.Len(t, batch.Signals[0].Events, 3)
+}
+
+func TestMultipartUpload(t *testing.T) {
+	api := newFakeAPI()
+	c := newTestClient(t, api, func(c *s3Config) {
+		c.Compression = compressionNone
+		c.Flush.MaxEvents = 100
+	})
+	c.partSize = 100
+
+	batch := outest.NewBatch(makeEvents(100, testTime)...)
+	require.NoError(t, c.Publish(context.Background(), batch))
+	assert.Equal(t, []outest.BatchSignal{{Tag: outest.BatchACK}}, batch.Signals)
+
+	keys := api.keys()
+	require.Len(t, keys, 1)
+	assert.Len(t, readLines(t, api.objects[keys[0]], false), 100)
+	parts := api.parts["upload-"+keys[0]]
+	assert.True(t, len(parts) > 1)
+	for _, part := range parts[:len(parts)-1] {
+		assert.Len(t, part, 100)
+	}
+}
+
+func TestMultipartUploadAbortsOnFailure(t *testing.T) {
+	api := newFakeAPI()
+	api.fail = func(op, key string) error {
+		if op == "complete" {
+			return errors.New("internal error")
+		}
+		return nil
+	}
+	c := newTestClient(t, api, func(c *s3Config) { c.Flush.MaxEvents = 100 })
+	c.partSize = 100
+
+	batch := outest.NewBatch(makeEvents(100, testTime)...)
+	assert.Error(t, c.Publish(context.Background(), batch))
+	assert.Empty(t, api.keys())
+	assert.Len(t, api.aborted, 1)
+	require.Len(t, batch.Signals, 1)
+	assert.Equal(t, outest.BatchRetryEvents, batch.Signals[0].Tag)
+	assert.Len(t, batch.Signals[0].Events, 100)
+}
+
+func TestFlushTimeout(t *testing.T) {
+	api := newFakeAPI()
+	c := newTestClient(t, api, func(c *s3Config) { c.Flush.Timeout = 50 * time.Millisecond })
+	require.NoError(t, c.Connect())
+	defer c.Close()
+
+	batch := outest.NewBatch(makeEvents(3, testTime)...)
+	require.NoError(t, c.Publish(context.Background(), batch))
+
+	assert.Eventually(t, func() bool {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		return len(batch.Signals) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, api.keys(), 1)
+}
+
+func TestConnectRemovesBufferFiles(t *testing.T) {
+	c := newTestClient(t, newFakeAPI(), nil)
+	leftover := filepath.Join(c.bufferPath, "object-1"+bufferFileSuffix)
+	require.NoError(t, ioutil.WriteFile(leftover, []byte("{}\n"), 0600))
+
+	require.NoError(t, c.Connect())
+	defer c.Close()
+	_, err := os.Stat(leftover)
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,85 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package s3
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/common/cfgtype"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/outputs/encrypt"
+	awscommon "github.com/elastic/beats/v7/x-pack/libbeat/common/aws"
+)
+
+const (
+	compressionGzip = "gzip"
+	compressionNone = "none"
+
+	// minPartSize is the minimum size of the parts of a multipart upload,
+	// except for the last one.
+	minPartSize = 5 * 1024 * 1024
+)
+
+type s3Config struct {
+	AWS         awscommon.ConfigAWS   `config:",inline"`
+	Region      string                `config:"region"`
+	Bucket      string                `config:"bucket"        validate:"required"`
+	Prefix      string                `config:"prefix"`
+	Compression string                `config:"compression"`
+	PartSize    cfgtype.ByteSize      `config:"part_size"`
+	BufferPath  string                `config:"buffer_path"`
+	Flush       flushConfig           `config:"flush"`
+	Timeout     time.Duration         `config:"timeout"       validate:"min=1"`
+	BulkMaxSize int                   `config:"bulk_max_size"`
+	MaxRetries  int                   `config:"max_retries"   validate:"min=-1"`
+	Backoff     outputs.BackoffConfig `config:"backoff"`
+	Codec       codec.Config          `config:"codec"`
+	Encryption  *encrypt.Config       `config:"encryption"`
+}
+
+// flushConfig sets when the buffered objects are uploaded.
+type flushConfig struct {
+	// Timeout is the maximum time an object is buffered.
+	Timeout time.Duration `config:"timeout"    validate:"positive,nonzero"`
+
+	// MaxEvents is the maximum number of events buffered in all objects,
+	// which are not acknowledged yet and so hold their place in the queue.
+	MaxEvents int `config:"max_events" validate:"min=1"`
+
+	// MaxBytes is the maximum size of an object before compression.
+	MaxBytes cfgtype.ByteSize `config:"max_bytes"  validate:"min=1"`
+}
+
+func defaultConfig() s3Config {
+	return s3Config{
+		Compression: compressionGzip,
+		PartSize:    16 * 1024 * 1024,
+		BufferPath:  "s3",
+		Flush: flushConfig{
+			Timeout:   time.Minute,
+			MaxEvents: 4096,
+			MaxBytes:  256 * 1024 * 1024,
+		},
+		Timeout:     90 * time.Second,
+		BulkMaxSize: 2048,
+		MaxRetries:  3,
+		Backoff:     outputs.DefaultBackoffConfig(),
+	}
+}
+
+func (c *s3Config) Validate() error {
+	switch c.Compression {
+	case compressionGzip, compressionNone:
+	default:
+		return fmt.Errorf("unsupported compression '%v', supported are %v and %v",
+			c.Compression, compressionGzip, compressionNone)
+	}
+	if c.PartSize < minPartSize {
+		return fmt.Errorf("part_size of %v bytes is lower than the minimum of %v bytes", c.PartSize, minPartSize)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package s3
+
+import (
+	"testing"
+
+	"github.com/elastic/beats/v7/libbeat/common"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cases := map[string]struct {
+		cfg   common.MapStr
+		valid bool
+	}{
+		"bucket": {
+			cfg:   common.MapStr{"bucket": "archive", "prefix": "beats"},
+			valid: true,
+		},
+		"uncompressed": {
+			cfg:   common.MapStr{"bucket": "archive", "compression": "none", "part_size": "5MiB"},
+			valid: true,
+		},
+		"no bucket": {
+			cfg: common.MapStr{"region": "us-east-1"},
+		},
+		"unsupported compression": {
+			cfg: common.MapStr{"bucket": "archive", "compression": "zstd"},
+		},
+		"too small part_size": {
+			cfg: common.MapStr{"bucket": "archive", "part_size": "1MiB"},
+		},
+		"zero flush timeout": {
+			cfg: common.MapStr{"bucket": "archive", "flush.timeout": 0},
+		},
+		"missing encryption key file": {
+			cfg: common.MapStr{"bucket": "archive", "encryption.pgp.recipients": []string{"/no/such/key.asc"}},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			config := defaultConfig()
+			err := common.MustNewConfigFrom(test.cfg).Unpack(&config)
+			if test.valid && err != nil {
+				t.Fatalf("Can not create test configuration: %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatalf("Can create test configuration from invalid input")
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package s3
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/elastic/beats/v7/libbeat/outputs/encrypt"
+	"github.com/elastic/beats/v7/libbeat/publisher"
+)
+
+// bufferFileSuffix is the suffix of the files objects are buffered in.
+const bufferFileSuffix = ".buffer"
+
+// object is an object being buffered on disk until it is uploaded. The
+// batches of its events are acknowledged once the upload succeeded.
+type object struct {
+	key    string
+	file   *os.File
+	writer io.Writer
+	gzip   *gzip.Writer
+
+	// encrypted is set if the object is encrypted, the events are compressed
+	// before being encrypted.
+	encrypted io.WriteCloser
+
+	// events and bytes are the number and the size of the buffered events,
+	// before compression.
+	events int
+	bytes  int
+
+	// batches are the events of every batch buffered in the object.
+	batches map[*pendingBatch][]publisher.Event
+
+	// created is when the first event was buffered, the object is uploaded
+	// at the latest flush.timeout later.
+	created time.Time
+}
+
+// pendingBatch is a batch whose events are buffered in one or more objects.
+// It is acknowledged, or its failed events retried, when all objects are
+// done.
+type pendingBatch struct {
+	batch   publisher.Batch
+	pending int
+	retry   []publisher.Event
+}
+
+func newObject(dir, key string, compress bool, encrypter *encrypt.Encrypter, created time.Time) (*object, error) {
+	f, err := ioutil.TempFile(dir, "object-*"+bufferFileSuffix)
+	if err != nil {
+		return nil, err
+	}
+	o := &object{
+		key:     key,
+		file:    f,
+		writer:  f,
+		batches: map[*pendingBatch][]publisher.Event{},
+		created: created,
+	}
+	if encrypter != nil {
+		if o.encrypted, err = encrypter.Encrypt(f); err != nil {
+			o.remove()
+			return nil, err
+		}
+		o.writer = o.encrypted
+	}
+	if compress {
+		o.gzip = gzip.NewWriter(o.writer)
+		o.writer = o.gzip
+	}
+	return o, nil
+}
+
+// add writes the encoded events of a batch.
+func (o *object) add(pb *pendingBatch, events []publisher.Event, data []byte) error {
+	if _, ok := o.batches[pb]; !ok {
+		pb.pending++
+	}
+	o.batches[pb] = append(o.batches[pb], events...)
+	o.events += len(events)
+	o.bytes += len(data)
+	_, err := o.writer.Write(data)
+	return err
+}
+
+// finish completes the content of the object and returns its size.
+func (o *object) finish() (int64, error) {
+	if o.gzip != nil {
+		if err := o.gzip.Close(); err != nil {
+			return 0, err
+		}
+	}
+	if o.encrypted != nil {
+		if err := o.encrypted.Close(); err != nil {
+			return 0, err
+		}
+	}
+	return o.file.Seek(0, io.SeekCurrent)
+}
+
+// remove deletes the buffer file.
+func (o *object) remove() error {
+	o.file.Close()
+	return os.Remove(o.file.Name())
+}
+
+// done releases an object of the batch, with the events to retry if the
+// object failed. It returns true when the batch has no pending objects.
+func (pb *pendingBatch) done(retry []publisher.Event) bool {
+	pb.retry = append(pb.retry, retry...)
+	pb.pending--
+	return pb.pending == 0
+}
+
+// removeBufferFiles deletes the buffer files left behind by a previous run.
+// Their events were not acknowledged, so they are published again.
+func removeBufferFiles(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "object-*"+bufferFileSuffix))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
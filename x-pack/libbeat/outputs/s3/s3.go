@@ -0,0 +1,88 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License;
+// you may not use this file except in compliance with the Elastic License.
+
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/elastic/beats/v7/libbeat/beat"
+	"github.com/elastic/beats/v7/libbeat/common"
+	"github.com/elastic/beats/v7/libbeat/common/cfgwarn"
+	"github.com/elastic/beats/v7/libbeat/outputs"
+	"github.com/elastic/beats/v7/libbeat/outputs/codec"
+	"github.com/elastic/beats/v7/libbeat/outputs/encrypt"
+	"github.com/elastic/beats/v7/libbeat/paths"
+	awscommon "github.com/elastic/beats/v7/x-pack/libbeat/common/aws"
+)
+
+const logSelector = "s3"
+
+func init() {
+	outputs.RegisterType("s3", makeS3)
+}
+
+func makeS3(
+	_ outputs.IndexManager,
+	beat beat.Info,
+	observer outputs.Observer,
+	cfg *common.Config,
+) (outputs.Group, error) {
+	cfgwarn.Beta("The s3 output is beta.")
+
+	config := defaultConfig()
+	if err := cfg.Unpack(&config); err != nil {
+		return outputs.Fail(err)
+	}
+
+	awsConfig, err := awscommon.GetAWSCredentials(config.AWS)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+	if config.Region != "" {
+		awsConfig.Region = config.Region
+	}
+	if awsConfig.Region == "" {
+		return outputs.Fail(errors.New("no region configured for the s3 output"))
+	}
+
+	bufferPath := paths.Resolve(paths.Data, config.BufferPath)
+	if err := os.MkdirAll(bufferPath, 0750); err != nil {
+		return outputs.Fail(fmt.Errorf("failed to create the buffer directory: %v", err))
+	}
+
+	contentType := "application/x-ndjson"
+	if config.Compression == compressionGzip {
+		contentType = "application/gzip"
+	}
+	if config.Encryption.IsEnabled() {
+		contentType = "application/octet-stream"
+	}
+	api := &bucketAPI{
+		client:      s3.New(awscommon.EnrichAWSConfigWithEndpoint(config.AWS.Endpoint, "s3", awsConfig.Region, awsConfig)),
+		bucket:      config.Bucket,
+		contentType: contentType,
+	}
+
+	enc, err := codec.CreateEncoder(beat, config.Codec)
+	if err != nil {
+		return outputs.Fail(err)
+	}
+
+	client := newClient(observer, api, beat.Beat, beat.IndexPrefix, enc, config, bufferPath)
+	if config.Encryption.IsEnabled() {
+		if client.encrypter, err = encrypt.NewEncrypter(config.Encryption); err != nil {
+			return outputs.Fail(err)
+		}
+	}
+	clients := []outputs.NetworkClient{outputs.WithBackoff(client, config.Backoff.Init, config.Backoff.Max)}
+
+	grp, err := outputs.SuccessNet(false, config.BulkMaxSize, config.MaxRetries, clients)
+	grp.Backoff = &config.Backoff
+	return grp, err
+}